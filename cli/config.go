@@ -3,6 +3,7 @@ package cli
 import (
 	"context"
 	"errors"
+	"os"
 	"path/filepath"
 
 	"go.keploy.io/server/v2/config"
@@ -22,7 +23,7 @@ func Config(ctx context.Context, logger *zap.Logger, cfg *config.Config, service
 	var cmd = &cobra.Command{
 		Use:     "config",
 		Short:   "manage keploy configuration file",
-		Example: "keploy config --generate --path /path/to/localdir",
+		Example: "keploy config --generate --path /path/to/localdir\nkeploy config --interactive",
 		PreRunE: func(cmd *cobra.Command, _ []string) error {
 			return cmdConfigurator.ValidateFlags(ctx, cmd)
 		},
@@ -33,7 +34,13 @@ func Config(ctx context.Context, logger *zap.Logger, cfg *config.Config, service
 				return err
 			}
 
-			if isGenerate {
+			isInteractive, err := cmd.Flags().GetBool("interactive")
+			if err != nil {
+				utils.LogError(logger, err, "failed to get interactive flag")
+				return err
+			}
+
+			if isGenerate || isInteractive {
 				filePath := filepath.Join(cfg.Path, "keploy.yml")
 				if !cfg.InCi && utils.CheckFileExists(filePath) {
 					override, err := utils.AskForConfirmation("Config file already exists. Do you want to override it?")
@@ -56,14 +63,25 @@ func Config(ctx context.Context, logger *zap.Logger, cfg *config.Config, service
 					utils.LogError(logger, nil, "service doesn't satisfy tools service interface")
 					return err
 				}
-				if err := tools.CreateConfig(ctx, filePath, ""); err != nil {
+				if isInteractive {
+					wd, err := os.Getwd()
+					if err != nil {
+						utils.LogError(logger, err, "failed to get the current working directory")
+						return err
+					}
+					err = tools.CreateConfigInteractive(ctx, wd, filePath)
+					if err != nil {
+						utils.LogError(logger, err, "failed to create config")
+						return err
+					}
+				} else if err := tools.CreateConfig(ctx, filePath, ""); err != nil {
 					utils.LogError(logger, err, "failed to create config")
 					return err
 				}
 				logger.Info("Config file generated successfully")
 				return nil
 			}
-			return errors.New("only generate flag is supported in the config command")
+			return errors.New("only the generate and interactive flags are supported in the config command")
 		},
 	}
 	if err := cmdConfigurator.AddFlags(cmd); err != nil {
@@ -0,0 +1,52 @@
+package cli
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+	"go.keploy.io/server/v2/config"
+	dedupSvc "go.keploy.io/server/v2/pkg/service/dedup"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+func init() {
+	Register("dedup", Dedup)
+}
+
+// Dedup finds the duplicate testcases across the test sets
+func Dedup(ctx context.Context, logger *zap.Logger, _ *config.Config, serviceFactory ServiceFactory, cmdConfigurator CmdConfigurator) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:     "dedup",
+		Short:   "find the duplicate testcases across the test sets",
+		Example: `keploy dedup to report the duplicate testcases and keploy dedup --remove to delete them, keeping the newest`,
+		PreRunE: func(cmd *cobra.Command, _ []string) error {
+			return cmdConfigurator.Validate(ctx, cmd)
+		},
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			svc, err := serviceFactory.GetService(ctx, cmd.Name())
+			if err != nil {
+				utils.LogError(logger, err, "failed to get service")
+				return nil
+			}
+			var dedup dedupSvc.Service
+			var ok bool
+			if dedup, ok = svc.(dedupSvc.Service); !ok {
+				utils.LogError(logger, nil, "service doesn't satisfy dedup service interface")
+				return nil
+			}
+			if _, err := dedup.Dedup(ctx); err != nil {
+				utils.LogError(logger, err, "failed to deduplicate the testcases")
+			}
+			return nil
+		},
+	}
+
+	err := cmdConfigurator.AddFlags(cmd)
+	if err != nil {
+		utils.LogError(logger, err, "failed to add dedup flags")
+		return nil
+	}
+
+	return cmd
+}
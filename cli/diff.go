@@ -0,0 +1,53 @@
+package cli
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+	"go.keploy.io/server/v2/config"
+	diffSvc "go.keploy.io/server/v2/pkg/service/diff"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+func init() {
+	Register("diff", Diff)
+}
+
+// Diff compares the testcases and mocks of two test sets
+func Diff(ctx context.Context, logger *zap.Logger, _ *config.Config, serviceFactory ServiceFactory, cmdConfigurator CmdConfigurator) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:     "diff <test-set> <test-set>",
+		Short:   "compare the testcases and mocks of two test sets",
+		Example: `keploy diff test-set-1 test-set-2 to review the changes of test-set-2 against test-set-1`,
+		Args:    cobra.ExactArgs(2),
+		PreRunE: func(cmd *cobra.Command, _ []string) error {
+			return cmdConfigurator.Validate(ctx, cmd)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			svc, err := serviceFactory.GetService(ctx, cmd.Name())
+			if err != nil {
+				utils.LogError(logger, err, "failed to get service")
+				return nil
+			}
+			var diff diffSvc.Service
+			var ok bool
+			if diff, ok = svc.(diffSvc.Service); !ok {
+				utils.LogError(logger, nil, "service doesn't satisfy diff service interface")
+				return nil
+			}
+			if _, err := diff.Diff(ctx, args[0], args[1]); err != nil {
+				utils.LogError(logger, err, "failed to compare the test sets", zap.String("base", args[0]), zap.String("other", args[1]))
+			}
+			return nil
+		},
+	}
+
+	err := cmdConfigurator.AddFlags(cmd)
+	if err != nil {
+		utils.LogError(logger, err, "failed to add diff flags")
+		return nil
+	}
+
+	return cmd
+}
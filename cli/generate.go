@@ -0,0 +1,93 @@
+package cli
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+	"go.keploy.io/server/v2/config"
+	generateSvc "go.keploy.io/server/v2/pkg/service/generate"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+func init() {
+	Register("generate", GenerateTests)
+}
+
+// GenerateTests generates test sets from api descriptions, without recording any traffic
+func GenerateTests(ctx context.Context, logger *zap.Logger, _ *config.Config, serviceFactory ServiceFactory, cmdConfigurator CmdConfigurator) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "generate",
+		Short: "Generate keploy test sets from api descriptions",
+	}
+
+	cmd.AddCommand(GenerateFromSpec(ctx, logger, serviceFactory, cmdConfigurator))
+	cmd.AddCommand(GenerateFromPostman(ctx, logger, serviceFactory, cmdConfigurator))
+	for _, subCmd := range cmd.Commands() {
+		err := cmdConfigurator.AddFlags(subCmd)
+		if err != nil {
+			utils.LogError(logger, err, "failed to add flags to command", zap.String("command", subCmd.Name()))
+		}
+	}
+	return cmd
+}
+
+func GenerateFromSpec(ctx context.Context, logger *zap.Logger, serviceFactory ServiceFactory, cmdConfigurator CmdConfigurator) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:     "tests",
+		Short:   "Generate a test set with a request for every operation of an OpenAPI spec, to be re-recorded against the application",
+		Example: `keploy generate tests --spec openapi.yaml --base-url http://localhost:8080`,
+		PreRunE: func(cmd *cobra.Command, _ []string) error {
+			return cmdConfigurator.Validate(ctx, cmd)
+		},
+		RunE: func(_ *cobra.Command, _ []string) error {
+			svc, err := serviceFactory.GetService(ctx, "generate")
+			if err != nil {
+				utils.LogError(logger, err, "failed to get service")
+				return nil
+			}
+			var generate generateSvc.Service
+			var ok bool
+			if generate, ok = svc.(generateSvc.Service); !ok {
+				utils.LogError(logger, nil, "service doesn't satisfy generate service interface")
+				return nil
+			}
+			if _, err := generate.FromOpenAPI(ctx); err != nil {
+				utils.LogError(logger, err, "failed to generate the test cases from the openapi spec")
+			}
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+func GenerateFromPostman(ctx context.Context, logger *zap.Logger, serviceFactory ServiceFactory, cmdConfigurator CmdConfigurator) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:     "postman",
+		Short:   "Generate a test set from a Postman collection, resolving its variables with a Postman environment",
+		Example: `keploy generate postman --collection orders.postman_collection.json --env local.postman_environment.json`,
+		PreRunE: func(cmd *cobra.Command, _ []string) error {
+			return cmdConfigurator.Validate(ctx, cmd)
+		},
+		RunE: func(_ *cobra.Command, _ []string) error {
+			svc, err := serviceFactory.GetService(ctx, "generate")
+			if err != nil {
+				utils.LogError(logger, err, "failed to get service")
+				return nil
+			}
+			var generate generateSvc.Service
+			var ok bool
+			if generate, ok = svc.(generateSvc.Service); !ok {
+				utils.LogError(logger, nil, "service doesn't satisfy generate service interface")
+				return nil
+			}
+			if _, err := generate.FromPostman(ctx); err != nil {
+				utils.LogError(logger, err, "failed to generate the test cases from the postman collection")
+			}
+			return nil
+		},
+	}
+
+	return cmd
+}
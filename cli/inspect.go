@@ -0,0 +1,53 @@
+package cli
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+	"go.keploy.io/server/v2/config"
+	inspectSvc "go.keploy.io/server/v2/pkg/service/inspect"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+func init() {
+	Register("inspect", Inspect)
+}
+
+// Inspect pretty-prints a recorded test case or mock
+func Inspect(ctx context.Context, logger *zap.Logger, _ *config.Config, serviceFactory ServiceFactory, cmdConfigurator CmdConfigurator) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:     "inspect <test-set>/<name>",
+		Short:   "pretty-print a recorded testcase or mock",
+		Example: `keploy inspect test-set-1/test-5 or keploy inspect test-set-1/mock-3`,
+		Args:    cobra.ExactArgs(1),
+		PreRunE: func(cmd *cobra.Command, _ []string) error {
+			return cmdConfigurator.Validate(ctx, cmd)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			svc, err := serviceFactory.GetService(ctx, cmd.Name())
+			if err != nil {
+				utils.LogError(logger, err, "failed to get service")
+				return nil
+			}
+			var inspect inspectSvc.Service
+			var ok bool
+			if inspect, ok = svc.(inspectSvc.Service); !ok {
+				utils.LogError(logger, nil, "service doesn't satisfy inspect service interface")
+				return nil
+			}
+			if err := inspect.Inspect(ctx, args[0]); err != nil {
+				utils.LogError(logger, err, "failed to inspect", zap.String("target", args[0]))
+			}
+			return nil
+		},
+	}
+
+	err := cmdConfigurator.AddFlags(cmd)
+	if err != nil {
+		utils.LogError(logger, err, "failed to add inspect flags")
+		return nil
+	}
+
+	return cmd
+}
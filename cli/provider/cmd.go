@@ -19,6 +19,7 @@ import (
 	"github.com/spf13/viper"
 	"go.keploy.io/server/v2/config"
 	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/pkg/platform/yaml/testdb"
 	"go.keploy.io/server/v2/pkg/service/tools"
 	"go.keploy.io/server/v2/utils"
 	"go.keploy.io/server/v2/utils/log"
@@ -194,6 +195,29 @@ func (c *CmdConfigurator) AddFlags(cmd *cobra.Command) error {
 	case "config":
 		cmd.Flags().StringP("path", "p", ".", "Path to local directory where generated config is stored")
 		cmd.Flags().Bool("generate", false, "Generate a new keploy configuration file")
+		cmd.Flags().Bool("interactive", false, "Generate the keploy configuration file by answering a few questions about the application")
+	case "ui":
+		cmd.Flags().StringP("path", "p", ".", "Path to local directory where generated testcases/mocks/reports are stored")
+	case "scan":
+		cmd.Flags().StringP("path", "p", ".", "Path to local directory where generated testcases/mocks are stored")
+		cmd.Flags().StringSliceP("test-sets", "t", utils.Keys(c.cfg.Test.SelectedTests), "Testsets to scan e.g. --testsets \"test-set-1, test-set-2\"")
+	case "stats":
+		cmd.Flags().StringP("path", "p", ".", "Path to local directory where generated testcases/mocks are stored")
+		cmd.Flags().StringSliceP("test-sets", "t", utils.Keys(c.cfg.Test.SelectedTests), "Testsets to summarize e.g. --testsets \"test-set-1, test-set-2\"")
+	case "dedup":
+		cmd.Flags().StringP("path", "p", ".", "Path to local directory where generated testcases/mocks are stored")
+		cmd.Flags().StringSliceP("test-sets", "t", utils.Keys(c.cfg.Test.SelectedTests), "Testsets to deduplicate e.g. --testsets \"test-set-1, test-set-2\"")
+		cmd.Flags().Bool("remove", c.cfg.Dedup.Remove, "Delete the duplicate testcases, keeping the newest of each group")
+	case "inspect", "diff":
+		cmd.Flags().StringP("path", "p", ".", "Path to local directory where generated testcases/mocks are stored")
+	case "tests":
+		cmd.Flags().StringP("path", "p", ".", "Path to local directory where generated testcases/mocks are stored")
+		cmd.Flags().String("spec", c.cfg.Generate.Spec, "Path to the OpenAPI (or swagger 2.0) spec, in yaml or json")
+		cmd.Flags().String("base-url", c.cfg.Generate.BaseURL, "Url of the application, overrides the servers of the spec")
+	case "postman":
+		cmd.Flags().StringP("path", "p", ".", "Path to local directory where generated testcases/mocks are stored")
+		cmd.Flags().String("collection", c.cfg.Generate.Collection, "Path to the Postman collection (v2.0 or v2.1)")
+		cmd.Flags().String("env", c.cfg.Generate.Environment, "Path to the Postman environment used to resolve the variables of the collection")
 	case "templatize":
 		cmd.Flags().StringP("path", "p", ".", "Path to local directory where generated testcases/mocks are stored")
 		cmd.Flags().StringSliceP("testsets", "t", c.cfg.Templatize.TestSets, "Testsets to run e.g. --testsets \"test-set-1, test-set-2\"")
@@ -230,6 +254,10 @@ func (c *CmdConfigurator) AddFlags(cmd *cobra.Command) error {
 		cmd.Flags().StringP("path", "p", ".", "Path to local directory where generated testcases/mocks are stored")
 		cmd.Flags().Uint32("proxy-port", c.cfg.ProxyPort, "Port used by the Keploy proxy server to intercept the outgoing dependency calls")
 		cmd.Flags().Uint32("dns-port", c.cfg.DNSPort, "Port used by the Keploy DNS server to intercept the DNS queries")
+		cmd.Flags().String("proxy-port-range", c.cfg.ProxyPortRange, "Range of ports (first-last) tried when the proxy port is already in use (the next 100 ports when empty)")
+		cmd.Flags().String("dns-port-range", c.cfg.DNSPortRange, "Range of ports (first-last) tried when the DNS port is already in use (the next 100 ports when empty)")
+		cmd.Flags().Uint32("proxy-admin-port", c.cfg.ProxyAdminPort, "Port of the proxy admin api listing the live proxied connections for debugging (disabled when 0)")
+		cmd.Flags().Uint32("max-proxy-conns", c.cfg.MaxProxyConns, "Maximum number of concurrent connections handled by the proxy, the connections over it are rejected (unlimited when 0)")
 		cmd.Flags().StringP("command", "c", c.cfg.Command, "Command to start the user application")
 		cmd.Flags().String("cmd-type", c.cfg.CommandType, "Type of command to start the user application (native/docker/docker-compose)")
 		cmd.Flags().Uint64P("build-delay", "b", c.cfg.BuildDelay, "User provided time to wait docker container build")
@@ -240,19 +268,17 @@ func (c *CmdConfigurator) AddFlags(cmd *cobra.Command) error {
 		cmd.Flags().String("app-name", c.cfg.AppName, "Name of the user's application")
 		cmd.Flags().Bool("generate-github-actions", c.cfg.GenerateGithubActions, "Generate Github Actions workflow file")
 		cmd.Flags().Bool("in-ci", c.cfg.InCi, "is CI Running or not")
+		cmd.Flags().String("plugin-dir", c.cfg.PluginDir, "Directory containing the protocol parser plugins to be loaded by the proxy")
 		//add rest of the uncommon flags for record, test, rerecord commands
 		c.AddUncommonFlags(cmd)
 
 	case "keploy":
 		cmd.PersistentFlags().Bool("debug", c.cfg.Debug, "Run in debug mode")
-		cmd.PersistentFlags().Bool("disable-tele", c.cfg.DisableTele, "Run in telemetry mode")
+		cmd.PersistentFlags().Bool("disable-tele", c.cfg.DisableTele, "Disable sending anonymous usage statistics to keploy")
+		cmd.PersistentFlags().Bool("offline", c.cfg.Offline, "Run without making any outbound calls (telemetry, update checks), e.g. in air-gapped environments")
+		cmd.PersistentFlags().String("telemetry-file", c.cfg.TelemetryFile, "Write the usage statistics to the given local file instead of sending them")
 		cmd.PersistentFlags().Bool("disable-ansi", c.cfg.DisableANSI, "Disable ANSI color in logs")
-		err = cmd.PersistentFlags().MarkHidden("disable-tele")
-		if err != nil {
-			errMsg := "failed to mark telemetry as hidden flag"
-			utils.LogError(c.logger, err, errMsg)
-			return errors.New(errMsg)
-		}
+		cmd.PersistentFlags().String("profile", c.cfg.Profile, "Name of the profile in the config file to be applied on top of the base config e.g. record, test, ci")
 		cmd.PersistentFlags().Bool("enable-testing", c.cfg.EnableTesting, "Enable testing keploy with keploy")
 		err = cmd.PersistentFlags().MarkHidden("enable-testing")
 		if err != nil {
@@ -271,11 +297,13 @@ func (c *CmdConfigurator) AddUncommonFlags(cmd *cobra.Command) {
 	switch cmd.Name() {
 	case "record":
 		cmd.Flags().Uint64("record-timer", 0, "User provided time to record its application")
+		cmd.Flags().StringSlice("bypass-domains", c.cfg.Record.BypassDomains, "Domains (and their subdomains) whose calls are forwarded without being recorded as mocks e.g. --bypass-domains \"sentry.io,segment.io\"")
 	case "test", "rerecord":
 		cmd.Flags().StringSliceP("test-sets", "t", utils.Keys(c.cfg.Test.SelectedTests), "Testsets to run e.g. --testsets \"test-set-1, test-set-2\"")
 		cmd.Flags().String("host", c.cfg.Test.Host, "Custom host to replace the actual host in the testcases")
 		cmd.Flags().Uint32("port", c.cfg.Test.Port, "Custom port to replace the actual port in the testcases")
 		if cmd.Name() == "test" {
+			cmd.Flags().String("tests", "", "Testcases to run e.g. --tests \"test-set-1:test-1 test-2,test-set-2:test-3\"")
 			cmd.Flags().Uint64P("delay", "d", 5, "User provided time to run its application")
 			cmd.Flags().Uint64("api-timeout", c.cfg.Test.APITimeout, "User provided timeout for calling its application")
 			cmd.Flags().String("mongo-password", c.cfg.Test.MongoPassword, "Authentication password for mocking MongoDB conn")
@@ -292,6 +320,10 @@ func (c *CmdConfigurator) AddUncommonFlags(cmd *cobra.Command) {
 			cmd.Flags().Bool("disableMockUpload", c.cfg.Test.DisableMockUpload, "Store/Fetch mocks locally")
 			cmd.Flags().Bool("useLocalMock", false, "Use local mocks instead of fetching from the cloud")
 			cmd.Flags().Bool("disable-line-coverage", c.cfg.Test.DisableLineCoverage, "Disable line coverage generation.")
+			cmd.Flags().Uint64("mock-mem-limit", c.cfg.Test.MockMemLimit, "Memory budget of the mocks in MB, the least recently used mocks over it are spilled to disk (unlimited when 0)")
+			cmd.Flags().Uint64("doc-cache-size", c.cfg.Test.DocCacheSize, "Memory budget in MB of the parsed test cases and mocks kept between the test sets (disabled when 0)")
+			cmd.Flags().String("websocket-timing", c.cfg.Test.WebSocketTiming, "Timing of the mocked websocket server frames, \"recorded\" keeps the recorded gaps between the frames and \"immediate\" sends them without waiting")
+			cmd.Flags().String("sse-timing", c.cfg.Test.SSETiming, "Timing of the mocked server-sent events, \"recorded\" sends the events at their recorded times and \"accelerated\" sends them without waiting")
 		}
 	}
 }
@@ -302,6 +334,10 @@ func aliasNormalizeFunc(_ *pflag.FlagSet, name string) pflag.NormalizedName {
 		"delay":                 "delay",
 		"apiTimeout":            "api-timeout",
 		"mongoPassword":         "mongo-password",
+		"mockMemLimit":          "mock-mem-limit",
+		"docCacheSize":          "doc-cache-size",
+		"websocketTiming":       "websocket-timing",
+		"sseTiming":             "sse-timing",
 		"coverageReportPath":    "coverage-report-path",
 		"language":              "language",
 		"ignoreOrdering":        "ignore-ordering",
@@ -327,6 +363,10 @@ func aliasNormalizeFunc(_ *pflag.FlagSet, name string) pflag.NormalizedName {
 		"port":                  "port",
 		"proxyPort":             "proxy-port",
 		"dnsPort":               "dns-port",
+		"proxyPortRange":        "proxy-port-range",
+		"dnsPortRange":          "dns-port-range",
+		"proxyAdminPort":        "proxy-admin-port",
+		"maxProxyConns":         "max-proxy-conns",
 		"command":               "command",
 		"cmdType":               "cmd-type",
 		"buildDelay":            "build-delay",
@@ -337,6 +377,9 @@ func aliasNormalizeFunc(_ *pflag.FlagSet, name string) pflag.NormalizedName {
 		"appName":               "app-name",
 		"generateGithubActions": "generate-github-actions",
 		"disableTele":           "disable-tele",
+		"disable-telemetry":     "disable-tele",
+		"disableTelemetry":      "disable-tele",
+		"telemetryFile":         "telemetry-file",
 		"disableANSI":           "disable-ansi",
 		"selectedTests":         "selected-tests",
 		"testReport":            "test-report",
@@ -345,8 +388,10 @@ func aliasNormalizeFunc(_ *pflag.FlagSet, name string) pflag.NormalizedName {
 		"keployContainer":       "keploy-container",
 		"keployNetwork":         "keploy-network",
 		"recordTimer":           "record-timer",
+		"bypassDomains":         "bypass-domains",
 		"urlMethods":            "url-methods",
 		"inCi":                  "in-ci",
+		"pluginDir":             "plugin-dir",
 	}
 
 	if newName, ok := flagNameMapping[name]; ok {
@@ -427,6 +472,19 @@ func (c *CmdConfigurator) PreProcessFlags(cmd *cobra.Command) error {
 		c.logger.Info("config file not found; proceeding with flags only")
 	}
 
+	if profile := viper.GetString("profile"); profile != "" {
+		if !IsConfigFileFound {
+			errMsg := fmt.Sprintf("profile %q can't be applied since the config file is not found", profile)
+			utils.LogError(c.logger, nil, errMsg)
+			return errors.New(errMsg)
+		}
+		err = c.applyProfile(profile)
+		if err != nil {
+			utils.LogError(c.logger, err, "failed to apply the profile", zap.String("profile", profile))
+			return err
+		}
+	}
+
 	if err := viper.Unmarshal(c.cfg); err != nil {
 		errMsg := "failed to unmarshal the config"
 		utils.LogError(c.logger, err, errMsg)
@@ -436,6 +494,23 @@ func (c *CmdConfigurator) PreProcessFlags(cmd *cobra.Command) error {
 	c.cfg.ConfigPath = configPath
 	return nil
 }
+
+// applyProfile merges the settings of the profile (and of the profiles it extends) over the config file.
+// The flags still take precedence over the merged settings.
+func (c *CmdConfigurator) applyProfile(name string) error {
+	chain, err := config.ResolveProfile(viper.GetStringMap("profiles"), name)
+	if err != nil {
+		return err
+	}
+	for _, settings := range chain {
+		if err := viper.MergeConfigMap(settings); err != nil {
+			return fmt.Errorf("failed to merge the settings of the profile: %w", err)
+		}
+	}
+	c.logger.Info("applied the config profile", zap.String("profile", name))
+	return nil
+}
+
 func (c *CmdConfigurator) ValidateFlags(ctx context.Context, cmd *cobra.Command) error {
 	disableAnsi, _ := (cmd.Flags().GetBool("disable-ansi"))
 	PrintLogo(disableAnsi)
@@ -461,6 +536,27 @@ func (c *CmdConfigurator) ValidateFlags(ctx context.Context, cmd *cobra.Command)
 		c.cfg.DisableTele = true
 	}
 
+	if c.cfg.Offline {
+		// mocks are never uploaded to (or fetched from) the keploy servers in offline mode
+		c.cfg.Test.DisableMockUpload = true
+		c.logger.Debug("running in offline mode, no outbound calls will be made by keploy")
+	}
+
+	if !c.cfg.Offline && !c.cfg.DisableTele {
+		// the panics are only reported to sentry when the outbound calls aren't disabled
+		utils.SentryInit(c.logger, utils.SentryDSN)
+	}
+
+	switch c.cfg.TestLayout {
+	case "":
+		c.cfg.TestLayout = testdb.LayoutFlat
+	case testdb.LayoutFlat, testdb.LayoutEndpoint:
+	default:
+		errMsg := fmt.Sprintf("invalid test layout %q, it should be %s or %s", c.cfg.TestLayout, testdb.LayoutFlat, testdb.LayoutEndpoint)
+		utils.LogError(c.logger, nil, errMsg)
+		return errors.New(errMsg)
+	}
+
 	if c.cfg.DisableANSI {
 		logger, err := log.ChangeColorEncoding()
 		models.IsAnsiDisabled = true
@@ -649,6 +745,39 @@ func (c *CmdConfigurator) ValidateFlags(ctx context.Context, cmd *cobra.Command)
 		}
 		config.SetByPassPorts(c.cfg, bypassPorts)
 
+		if c.cfg.PluginDir != "" {
+			c.cfg.PluginDir, err = utils.GetAbsPath(c.cfg.PluginDir)
+			if err != nil {
+				errMsg := "failed to get the absolute path of the plugin directory"
+				utils.LogError(c.logger, err, errMsg)
+				return errors.New(errMsg)
+			}
+		}
+		for i, rule := range c.cfg.Integrations.Framing {
+			if rule.Protobuf.DescriptorSet == "" {
+				continue
+			}
+			c.cfg.Integrations.Framing[i].Protobuf.DescriptorSet, err = utils.GetAbsPath(rule.Protobuf.DescriptorSet)
+			if err != nil {
+				errMsg := "failed to get the absolute path of the protobuf descriptor set"
+				utils.LogError(c.logger, err, errMsg, zap.String("path", rule.Protobuf.DescriptorSet))
+				return errors.New(errMsg)
+			}
+		}
+		for i, plugin := range c.cfg.Plugins {
+			if plugin.Path == "" {
+				errMsg := "the path of the plugin executable is missing in the config"
+				utils.LogError(c.logger, nil, errMsg, zap.String("plugin", plugin.Name))
+				return errors.New(errMsg)
+			}
+			c.cfg.Plugins[i].Path, err = utils.GetAbsPath(plugin.Path)
+			if err != nil {
+				errMsg := "failed to get the absolute path of the plugin executable"
+				utils.LogError(c.logger, err, errMsg, zap.String("path", plugin.Path))
+				return errors.New(errMsg)
+			}
+		}
+
 		if cmd.Name() == "test" || cmd.Name() == "rerecord" {
 			//check if the keploy folder exists
 			if _, err := os.Stat(c.cfg.Path); os.IsNotExist(err) {
@@ -665,6 +794,19 @@ func (c *CmdConfigurator) ValidateFlags(ctx context.Context, cmd *cobra.Command)
 				return errors.New(errMsg)
 			}
 			config.SetSelectedTests(c.cfg, testSets)
+			if cmd.Name() == "test" {
+				tests, err := cmd.Flags().GetString("tests")
+				if err != nil {
+					errMsg := "failed to get the tests"
+					utils.LogError(c.logger, err, errMsg)
+					return errors.New(errMsg)
+				}
+				if err := config.SetSelectedTestCases(c.cfg, tests); err != nil {
+					errMsg := "failed to set the selected test cases"
+					utils.LogError(c.logger, err, errMsg)
+					return errors.New(errMsg)
+				}
+			}
 			if cmd.Name() == "rerecord" {
 				c.cfg.Test.SkipCoverage = true
 				host, err := cmd.Flags().GetString("host")
@@ -716,6 +858,59 @@ func (c *CmdConfigurator) ValidateFlags(ctx context.Context, cmd *cobra.Command)
 
 	case "templatize":
 		c.cfg.Path = utils.ToAbsPath(c.logger, c.cfg.Path)
+	case "ui":
+		c.cfg.Path = utils.ToAbsPath(c.logger, c.cfg.Path)
+	case "scan", "stats", "dedup":
+		c.cfg.Path = utils.ToAbsPath(c.logger, c.cfg.Path)
+		testSets, err := cmd.Flags().GetStringSlice("testsets")
+		if err != nil {
+			errMsg := "failed to get the testsets"
+			utils.LogError(c.logger, err, errMsg)
+			return errors.New(errMsg)
+		}
+		config.SetSelectedTests(c.cfg, testSets)
+	case "inspect", "diff":
+		c.cfg.Path = utils.ToAbsPath(c.logger, c.cfg.Path)
+	case "tests":
+		c.cfg.Path = utils.ToAbsPath(c.logger, c.cfg.Path)
+		spec, err := cmd.Flags().GetString("spec")
+		if err != nil {
+			errMsg := "failed to get the spec"
+			utils.LogError(c.logger, err, errMsg)
+			return errors.New(errMsg)
+		}
+		if spec == "" {
+			errMsg := "missing the openapi spec, set it with --spec"
+			utils.LogError(c.logger, nil, errMsg)
+			return errors.New(errMsg)
+		}
+		c.cfg.Generate.Spec = spec
+		c.cfg.Generate.BaseURL, err = cmd.Flags().GetString("base-url")
+		if err != nil {
+			errMsg := "failed to get the base url"
+			utils.LogError(c.logger, err, errMsg)
+			return errors.New(errMsg)
+		}
+	case "postman":
+		c.cfg.Path = utils.ToAbsPath(c.logger, c.cfg.Path)
+		collection, err := cmd.Flags().GetString("collection")
+		if err != nil {
+			errMsg := "failed to get the collection"
+			utils.LogError(c.logger, err, errMsg)
+			return errors.New(errMsg)
+		}
+		if collection == "" {
+			errMsg := "missing the postman collection, set it with --collection"
+			utils.LogError(c.logger, nil, errMsg)
+			return errors.New(errMsg)
+		}
+		c.cfg.Generate.Collection = collection
+		c.cfg.Generate.Environment, err = cmd.Flags().GetString("env")
+		if err != nil {
+			errMsg := "failed to get the environment"
+			utils.LogError(c.logger, err, errMsg)
+			return errors.New(errMsg)
+		}
 	case "gen":
 		if os.Getenv("API_KEY") == "" {
 			utils.LogError(c.logger, nil, "API_KEY is not set")
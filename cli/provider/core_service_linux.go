@@ -17,6 +17,7 @@ import (
 	"go.keploy.io/server/v2/pkg/platform/docker"
 	"go.keploy.io/server/v2/pkg/platform/storage"
 	"go.keploy.io/server/v2/pkg/platform/telemetry"
+	"go.keploy.io/server/v2/pkg/platform/yaml"
 	"go.keploy.io/server/v2/pkg/platform/yaml/configdb/testset"
 	mockdb "go.keploy.io/server/v2/pkg/platform/yaml/mockdb"
 	openapidb "go.keploy.io/server/v2/pkg/platform/yaml/openapidb"
@@ -63,6 +64,12 @@ func Get(ctx context.Context, cmd string, cfg *config.Config, logger *zap.Logger
 
 func GetCommonServices(_ context.Context, c *config.Config, logger *zap.Logger) (*CommonInternalService, error) {
 
+	// the hooks and the proxy are created with the ports selected here
+	if err := proxy.SelectPorts(logger, c); err != nil {
+		utils.LogError(logger, err, "failed to select the proxy ports")
+		return nil, err
+	}
+
 	h := hooks.NewHooks(logger, c)
 	p := proxy.New(logger, h, c)
 	//for keploy test bench
@@ -98,7 +105,8 @@ func GetCommonServices(_ context.Context, c *config.Config, logger *zap.Logger)
 	}
 
 	instrumentation := core.New(logger, h, p, t, client)
-	testDB := testdb.New(logger, c.Path)
+	yaml.SetDocCacheSize(int(c.Test.DocCacheSize) << 20)
+	testDB := testdb.New(logger, c.Path, c.TestLayout)
 	mockDB := mockdb.New(logger, c.Path, "")
 	openAPIdb := openapidb.New(logger, filepath.Join(c.Path, "schema"))
 	reportDB := reportdb.New(logger, c.Path+"/reports")
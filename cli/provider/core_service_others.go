@@ -10,6 +10,7 @@ import (
 	"go.keploy.io/server/v2/pkg/core"
 	"go.keploy.io/server/v2/pkg/models"
 	"go.keploy.io/server/v2/pkg/platform/telemetry"
+	"go.keploy.io/server/v2/pkg/platform/yaml"
 	"go.keploy.io/server/v2/pkg/platform/yaml/configdb/testset"
 	mockdb "go.keploy.io/server/v2/pkg/platform/yaml/mockdb"
 	openapidb "go.keploy.io/server/v2/pkg/platform/yaml/openapidb"
@@ -48,7 +49,8 @@ func Get(ctx context.Context, cmd string, c *config.Config, logger *zap.Logger,
 
 func GetCommonServices(_ context.Context, c *config.Config, logger *zap.Logger) (*CommonInternalService, error) {
 	instrumentation := core.New(logger)
-	testDB := testdb.New(logger, c.Path)
+	yaml.SetDocCacheSize(int(c.Test.DocCacheSize) << 20)
+	testDB := testdb.New(logger, c.Path, c.TestLayout)
 	mockDB := mockdb.New(logger, c.Path, "")
 	openAPIdb := openapidb.New(logger, c.Path)
 	reportDB := reportdb.New(logger, c.Path+"/reports")
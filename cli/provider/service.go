@@ -6,10 +6,20 @@ import (
 
 	"go.keploy.io/server/v2/config"
 	"go.keploy.io/server/v2/pkg/platform/telemetry"
+	mockdb "go.keploy.io/server/v2/pkg/platform/yaml/mockdb"
+	reportdb "go.keploy.io/server/v2/pkg/platform/yaml/reportdb"
+	testdb "go.keploy.io/server/v2/pkg/platform/yaml/testdb"
 	"go.keploy.io/server/v2/pkg/service"
 	"go.keploy.io/server/v2/utils"
 
+	"go.keploy.io/server/v2/pkg/service/dedup"
+	"go.keploy.io/server/v2/pkg/service/diff"
+	"go.keploy.io/server/v2/pkg/service/generate"
+	"go.keploy.io/server/v2/pkg/service/inspect"
+	"go.keploy.io/server/v2/pkg/service/scan"
+	"go.keploy.io/server/v2/pkg/service/stats"
 	"go.keploy.io/server/v2/pkg/service/tools"
+	"go.keploy.io/server/v2/pkg/service/ui"
 	"go.keploy.io/server/v2/pkg/service/utgen"
 	"go.uber.org/zap"
 )
@@ -34,6 +44,8 @@ func (n *ServiceProvider) GetService(ctx context.Context, cmd string) (interface
 
 	tel := telemetry.NewTelemetry(n.logger, telemetry.Options{
 		Enabled:        !n.cfg.DisableTele,
+		Offline:        n.cfg.Offline,
+		LocalPath:      n.cfg.TelemetryFile,
 		Version:        utils.Version,
 		GlobalMap:      TeleGlobalMap,
 		InstallationID: n.cfg.InstallationID,
@@ -45,6 +57,20 @@ func (n *ServiceProvider) GetService(ctx context.Context, cmd string) (interface
 		return tools.NewTools(n.logger, tel, n.auth), nil
 	case "gen":
 		return utgen.NewUnitTestGenerator(n.cfg.Gen.SourceFilePath, n.cfg.Gen.TestFilePath, n.cfg.Gen.CoverageReportPath, n.cfg.Gen.TestCommand, n.cfg.Gen.TestDir, n.cfg.Gen.CoverageFormat, n.cfg.Gen.DesiredCoverage, n.cfg.Gen.MaxIterations, n.cfg.Gen.Model, n.cfg.Gen.APIBaseURL, n.cfg.Gen.APIVersion, n.cfg.APIServerURL, n.cfg.Gen.AdditionalPrompt, n.cfg, tel, n.auth, n.logger)
+	case "scan":
+		return scan.New(n.logger, testdb.New(n.logger, n.cfg.Path, n.cfg.TestLayout), mockdb.New(n.logger, n.cfg.Path, ""), n.cfg), nil
+	case "generate":
+		return generate.New(n.logger, testdb.New(n.logger, n.cfg.Path, n.cfg.TestLayout), n.cfg), nil
+	case "inspect":
+		return inspect.New(n.logger, testdb.New(n.logger, n.cfg.Path, n.cfg.TestLayout), mockdb.New(n.logger, n.cfg.Path, "")), nil
+	case "dedup":
+		return dedup.New(n.logger, testdb.New(n.logger, n.cfg.Path, n.cfg.TestLayout), n.cfg), nil
+	case "diff":
+		return diff.New(n.logger, testdb.New(n.logger, n.cfg.Path, n.cfg.TestLayout), mockdb.New(n.logger, n.cfg.Path, "")), nil
+	case "stats":
+		return stats.New(n.logger, testdb.New(n.logger, n.cfg.Path, n.cfg.TestLayout), mockdb.New(n.logger, n.cfg.Path, ""), n.cfg), nil
+	case "ui":
+		return ui.New(n.logger, testdb.New(n.logger, n.cfg.Path, n.cfg.TestLayout), reportdb.New(n.logger, n.cfg.Path+"/reports"), n.cfg), nil
 	case "record", "test", "mock", "normalize", "templatize", "rerecord", "contract":
 		return Get(ctx, cmd, n.cfg, n.logger, tel, n.auth)
 	default:
@@ -0,0 +1,58 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"go.keploy.io/server/v2/config"
+	scanSvc "go.keploy.io/server/v2/pkg/service/scan"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+func init() {
+	Register("scan", Scan)
+}
+
+// Scan audits the recorded test sets for leaked secrets
+func Scan(ctx context.Context, logger *zap.Logger, _ *config.Config, serviceFactory ServiceFactory, cmdConfigurator CmdConfigurator) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:     "scan",
+		Short:   "scan the recorded testcases and mocks for leaked secrets",
+		Example: `keploy scan -t "test-set-1,test-set-3" for particular testsets and keploy scan for all testsets`,
+		PreRunE: func(cmd *cobra.Command, _ []string) error {
+			return cmdConfigurator.Validate(ctx, cmd)
+		},
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			svc, err := serviceFactory.GetService(ctx, cmd.Name())
+			if err != nil {
+				utils.LogError(logger, err, "failed to get service")
+				return nil
+			}
+			var scan scanSvc.Service
+			var ok bool
+			if scan, ok = svc.(scanSvc.Service); !ok {
+				utils.LogError(logger, nil, "service doesn't satisfy scan service interface")
+				return nil
+			}
+			findings, err := scan.Scan(ctx)
+			if err != nil {
+				utils.LogError(logger, err, "failed to scan the test sets")
+				return nil
+			}
+			if len(findings) > 0 {
+				return fmt.Errorf("found %d secrets in the test sets", len(findings))
+			}
+			return nil
+		},
+	}
+
+	err := cmdConfigurator.AddFlags(cmd)
+	if err != nil {
+		utils.LogError(logger, err, "failed to add scan flags")
+		return nil
+	}
+
+	return cmd
+}
@@ -0,0 +1,52 @@
+package cli
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+	"go.keploy.io/server/v2/config"
+	statsSvc "go.keploy.io/server/v2/pkg/service/stats"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+func init() {
+	Register("stats", Stats)
+}
+
+// Stats summarizes the recorded test sets
+func Stats(ctx context.Context, logger *zap.Logger, _ *config.Config, serviceFactory ServiceFactory, cmdConfigurator CmdConfigurator) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:     "stats",
+		Short:   "summarize the recorded testcases and mocks",
+		Example: `keploy stats -t "test-set-1,test-set-3" for particular testsets and keploy stats for all testsets`,
+		PreRunE: func(cmd *cobra.Command, _ []string) error {
+			return cmdConfigurator.Validate(ctx, cmd)
+		},
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			svc, err := serviceFactory.GetService(ctx, cmd.Name())
+			if err != nil {
+				utils.LogError(logger, err, "failed to get service")
+				return nil
+			}
+			var stats statsSvc.Service
+			var ok bool
+			if stats, ok = svc.(statsSvc.Service); !ok {
+				utils.LogError(logger, nil, "service doesn't satisfy stats service interface")
+				return nil
+			}
+			if _, err := stats.Stats(ctx); err != nil {
+				utils.LogError(logger, err, "failed to collect the stats of the test sets")
+			}
+			return nil
+		},
+	}
+
+	err := cmdConfigurator.AddFlags(cmd)
+	if err != nil {
+		utils.LogError(logger, err, "failed to add stats flags")
+		return nil
+	}
+
+	return cmd
+}
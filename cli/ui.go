@@ -0,0 +1,54 @@
+package cli
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+	"go.keploy.io/server/v2/config"
+	uiSvc "go.keploy.io/server/v2/pkg/service/ui"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+func init() {
+	Register("ui", UI)
+}
+
+// UI opens the terminal ui for browsing and running the test sets
+func UI(ctx context.Context, logger *zap.Logger, _ *config.Config, serviceFactory ServiceFactory, cmdConfigurator CmdConfigurator) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:     "ui [-- test flags]",
+		Short:   "browse and run the recorded testcases in a terminal ui",
+		Example: `keploy ui -- -c "./my-app" --delay 10`,
+		PreRunE: func(cmd *cobra.Command, _ []string) error {
+			return cmdConfigurator.Validate(ctx, cmd)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			svc, err := serviceFactory.GetService(ctx, cmd.Name())
+			if err != nil {
+				utils.LogError(logger, err, "failed to get service")
+				return nil
+			}
+			var ui uiSvc.Service
+			var ok bool
+			if ui, ok = svc.(uiSvc.Service); !ok {
+				utils.LogError(logger, nil, "service doesn't satisfy ui service interface")
+				return nil
+			}
+			// the arguments after -- are passed to the keploy test command run from the ui
+			if err := ui.Start(ctx, args); err != nil {
+				utils.LogError(logger, err, "failed to run the ui")
+				return nil
+			}
+			return nil
+		},
+	}
+
+	err := cmdConfigurator.AddFlags(cmd)
+	if err != nil {
+		utils.LogError(logger, err, "failed to add ui flags")
+		return nil
+	}
+
+	return cmd
+}
@@ -16,7 +16,7 @@ func init() {
 }
 
 // Update retrieves the command to tools Keploy
-func Update(ctx context.Context, logger *zap.Logger, _ *config.Config, serviceFactory ServiceFactory, cmdConfigurator CmdConfigurator) *cobra.Command {
+func Update(ctx context.Context, logger *zap.Logger, conf *config.Config, serviceFactory ServiceFactory, cmdConfigurator CmdConfigurator) *cobra.Command {
 	var updateCmd = &cobra.Command{
 		Use:     "update",
 		Short:   "Update Keploy ",
@@ -24,6 +24,11 @@ func Update(ctx context.Context, logger *zap.Logger, _ *config.Config, serviceFa
 		RunE: func(cmd *cobra.Command, _ []string) error {
 			disableAnsi, _ := (cmd.Flags().GetBool("disable-ansi"))
 			provider.PrintLogo(disableAnsi)
+			offline, _ := cmd.Flags().GetBool("offline")
+			if offline || conf.Offline {
+				logger.Info("keploy is running in offline mode, skipping the update check")
+				return nil
+			}
 			svc, err := serviceFactory.GetService(ctx, "update")
 			if err != nil {
 				utils.LogError(logger, err, "failed to get service")
@@ -10,6 +10,7 @@ import (
 
 type Config struct {
 	Path                  string       `json:"path" yaml:"path" mapstructure:"path"`
+	TestLayout            string       `json:"testLayout" yaml:"testLayout" mapstructure:"testLayout"` // layout of the test cases of a test set, flat or endpoint
 	AppID                 uint64       `json:"appId" yaml:"appId" mapstructure:"appId"`
 	AppName               string       `json:"appName" yaml:"appName" mapstructure:"appName"`
 	Command               string       `json:"command" yaml:"command" mapstructure:"command"`
@@ -17,8 +18,14 @@ type Config struct {
 	Port                  uint32       `json:"port" yaml:"port" mapstructure:"port"`
 	DNSPort               uint32       `json:"dnsPort" yaml:"dnsPort" mapstructure:"dnsPort"`
 	ProxyPort             uint32       `json:"proxyPort" yaml:"proxyPort" mapstructure:"proxyPort"`
+	ProxyPortRange        string       `json:"proxyPortRange" yaml:"proxyPortRange" mapstructure:"proxyPortRange"` // ports (first-last) tried when the proxy port is in use
+	DNSPortRange          string       `json:"dnsPortRange" yaml:"dnsPortRange" mapstructure:"dnsPortRange"`       // ports (first-last) tried when the dns port is in use
+	ProxyAdminPort        uint32       `json:"proxyAdminPort" yaml:"proxyAdminPort" mapstructure:"proxyAdminPort"` // port of the proxy admin/debug api, disabled when 0
+	MaxProxyConns         uint32       `json:"maxProxyConns" yaml:"maxProxyConns" mapstructure:"maxProxyConns"`    // max concurrent proxied connections, unlimited when 0
 	Debug                 bool         `json:"debug" yaml:"debug" mapstructure:"debug"`
 	DisableTele           bool         `json:"disableTele" yaml:"disableTele" mapstructure:"disableTele"`
+	Offline               bool         `json:"offline" yaml:"offline" mapstructure:"offline"`                   // no outbound calls (telemetry, update checks) are made by keploy itself
+	TelemetryFile         string       `json:"telemetryFile" yaml:"telemetryFile" mapstructure:"telemetryFile"` // local file to which the usage stats are written
 	DisableANSI           bool         `json:"disableANSI" yaml:"disableANSI" mapstructure:"disableANSI"`
 	InDocker              bool         `json:"inDocker" yaml:"-" mapstructure:"inDocker"`
 	ContainerName         string       `json:"containerName" yaml:"containerName" mapstructure:"containerName"`
@@ -29,8 +36,17 @@ type Config struct {
 	Gen                   UtGen        `json:"gen" yaml:"-" mapstructure:"gen"`
 	Normalize             Normalize    `json:"normalize" yaml:"-" mapstructure:"normalize"`
 	ReRecord              ReRecord     `json:"rerecord" yaml:"-" mapstructure:"rerecord"`
+	Generate              Generate     `json:"generate" yaml:"-" mapstructure:"generate"`
+	Dedup                 Dedup        `json:"dedup" yaml:"-" mapstructure:"dedup"`
 	ConfigPath            string       `json:"configPath" yaml:"configPath" mapstructure:"configPath"`
+	Profile               string       `json:"profile" yaml:"-" mapstructure:"profile"` // profile of the config file applied on top of the base config
 	BypassRules           []BypassRule `json:"bypassRules" yaml:"bypassRules" mapstructure:"bypassRules"`
+	Defaults              Defaults     `json:"defaults" yaml:"defaults" mapstructure:"defaults"` // noise, filters and bypass rules shared by the whole repository
+	Redact                Redact       `json:"redact" yaml:"redact" mapstructure:"redact"`
+	PluginDir             string       `json:"pluginDir" yaml:"pluginDir" mapstructure:"pluginDir"` // directory containing the out-of-process protocol parser plugins
+	Plugins               []Plugin     `json:"plugins" yaml:"plugins" mapstructure:"plugins"`       // protocol parser plugins registered by their executable
+	Integrations          Integrations `json:"integrations" yaml:"integrations" mapstructure:"integrations"`
+	Resolver              Resolver     `json:"resolver" yaml:"resolver" mapstructure:"resolver"` // dns resolution of the destinations dialed by the proxy
 	EnableTesting         bool         `json:"enableTesting" yaml:"-" mapstructure:"enableTesting"`
 	GenerateGithubActions bool         `json:"generateGithubActions" yaml:"generateGithubActions" mapstructure:"generateGithubActions"`
 	KeployContainer       string       `json:"keployContainer" yaml:"keployContainer" mapstructure:"keployContainer"`
@@ -43,6 +59,8 @@ type Config struct {
 	Version        string `json:"-" yaml:"-" mapstructure:"-"`
 	APIServerURL   string `json:"-" yaml:"-" mapstructure:"-"`
 	GitHubClientID string `json:"-" yaml:"-" mapstructure:"-"`
+
+	defaultsApplied bool
 }
 
 type UtGen struct {
@@ -64,8 +82,30 @@ type Templatize struct {
 }
 
 type Record struct {
-	Filters     []Filter      `json:"filters" yaml:"filters" mapstructure:"filters"`
-	RecordTimer time.Duration `json:"recordTimer" yaml:"recordTimer" mapstructure:"recordTimer"`
+	Filters       []Filter      `json:"filters" yaml:"filters" mapstructure:"filters"`
+	RecordTimer   time.Duration `json:"recordTimer" yaml:"recordTimer" mapstructure:"recordTimer"`
+	BypassDomains []string      `json:"bypassDomains" yaml:"bypassDomains" mapstructure:"bypassDomains"` // domains (and their subdomains) whose calls are forwarded but not recorded
+	Capture       Capture       `json:"capture" yaml:"capture" mapstructure:"capture"`                   // timing and buffer sizes of the capture of the incoming calls
+	TLSIngress    []TLSIngress  `json:"tlsIngress" yaml:"tlsIngress" mapstructure:"tlsIngress"`          // https servers of the app whose calls are recorded through keploy
+}
+
+// TLSIngress is an https server of the app whose tls keploy terminates to record its calls. The clients call the
+// listen address, which serves certificates signed by the keploy CA, and the calls are forwarded to the app over tls.
+type TLSIngress struct {
+	Listen   string `json:"listen" yaml:"listen" mapstructure:"listen"`       // address the clients call, e.g. ":8443"
+	Upstream string `json:"upstream" yaml:"upstream" mapstructure:"upstream"` // address of the https server of the app, e.g. "localhost:443"
+}
+
+// Capture tunes the capture of the incoming calls from the socket events, the zero values keep the defaults
+type Capture struct {
+	InactivityTimeout time.Duration `json:"inactivityTimeout" yaml:"inactivityTimeout" mapstructure:"inactivityTimeout"` // how long an idle connection is tracked, 1m by default
+	CloseGracePeriod  time.Duration `json:"closeGracePeriod" yaml:"closeGracePeriod" mapstructure:"closeGracePeriod"`    // how long a closed connection waits for its late data events, 2s by default
+	Workers           uint32        `json:"workers" yaml:"workers" mapstructure:"workers"`                               // workers capturing the calls, the number of CPUs (up to 8) by default
+	QueueSize         uint32        `json:"queueSize" yaml:"queueSize" mapstructure:"queueSize"`                         // connections with complete calls a worker can hold, 256 by default
+	MaxConnections    uint32        `json:"maxConnections" yaml:"maxConnections" mapstructure:"maxConnections"`          // connections tracked at once, the events of the ones over it are dropped, 10000 by default
+	TestCaseBuffer    uint32        `json:"testCaseBuffer" yaml:"testCaseBuffer" mapstructure:"testCaseBuffer"`          // test cases captured and waiting to be saved, 500 by default
+	MaxRequestBody    uint32        `json:"maxRequestBody" yaml:"maxRequestBody" mapstructure:"maxRequestBody"`          // size in KB the request bodies are truncated to, unlimited when 0
+	MaxResponseBody   uint32        `json:"maxResponseBody" yaml:"maxResponseBody" mapstructure:"maxResponseBody"`       // size in KB the response bodies are truncated to, unlimited when 0
 }
 
 type ReRecord struct {
@@ -88,6 +128,17 @@ type Mappings struct {
 	Self            string              `json:"self" yaml:"self" mapstructure:"self"`
 }
 
+type Generate struct {
+	Spec        string `json:"spec" yaml:"spec" mapstructure:"spec"`                   // OpenAPI (or swagger 2.0) spec to generate the test cases from
+	BaseURL     string `json:"baseUrl" yaml:"baseUrl" mapstructure:"baseUrl"`          // overrides the server url of the spec
+	Collection  string `json:"collection" yaml:"collection" mapstructure:"collection"` // Postman collection to generate the test cases from
+	Environment string `json:"env" yaml:"env" mapstructure:"env"`                      // Postman environment used to resolve the variables of the collection
+}
+
+type Dedup struct {
+	Remove bool `json:"remove" yaml:"remove" mapstructure:"remove"` // delete the duplicate test cases, only reported otherwise
+}
+
 type Normalize struct {
 	SelectedTests []SelectedTests `json:"selectedTests" yaml:"selectedTests" mapstructure:"selectedTests"`
 	TestRun       string          `json:"testReport" yaml:"testReport" mapstructure:"testReport"`
@@ -99,6 +150,129 @@ type BypassRule struct {
 	Port uint   `json:"port" yaml:"port" mapstructure:"port"`
 }
 
+// Redact configures the secrets removed from the test cases and mocks before they are written
+type Redact struct {
+	Headers     []string `json:"headers" yaml:"headers" mapstructure:"headers"`             // names of the headers whose value is redacted
+	JSONPaths   []string `json:"jsonPaths" yaml:"jsonPaths" mapstructure:"jsonPaths"`       // fields of the json bodies, e.g. $.user.password or $.items[*].token
+	Patterns    []string `json:"patterns" yaml:"patterns" mapstructure:"patterns"`          // regular expressions whose matches are redacted
+	Detectors   []string `json:"detectors" yaml:"detectors" mapstructure:"detectors"`       // built-in detectors, e.g. jwt or aws-access-key
+	Replacement string   `json:"replacement" yaml:"replacement" mapstructure:"replacement"` // value written in place of the secrets
+}
+
+// Plugin registers an out-of-process protocol parser, it's started with its arguments and environment by the proxy.
+type Plugin struct {
+	Name string            `json:"name" yaml:"name" mapstructure:"name"` // overrides the name the plugin registers with
+	Path string            `json:"path" yaml:"path" mapstructure:"path"` // executable of the plugin
+	Args []string          `json:"args" yaml:"args" mapstructure:"args"`
+	Env  map[string]string `json:"env" yaml:"env" mapstructure:"env"` // set in addition to the environment of keploy
+}
+
+// Integrations configures which of the registered integrations are used by the proxy
+type Integrations struct {
+	Disabled []string            `json:"disabled" yaml:"disabled" mapstructure:"disabled"`
+	Ports    []PortIntegration   `json:"ports" yaml:"ports" mapstructure:"ports"`
+	Framing  []Framing           `json:"framing" yaml:"framing" mapstructure:"framing"`
+	Kafka    KafkaIntegration    `json:"kafka" yaml:"kafka" mapstructure:"kafka"`
+	MySQL    MySQLIntegration    `json:"mysql" yaml:"mysql" mapstructure:"mysql"`
+	Postgres PostgresIntegration `json:"postgres" yaml:"postgres" mapstructure:"postgres"`
+	Mongo    MongoIntegration    `json:"mongo" yaml:"mongo" mapstructure:"mongo"`
+	HTTP     HTTPIntegration     `json:"http" yaml:"http" mapstructure:"http"`
+}
+
+// KafkaIntegration configures the decoding of the kafka records, the avro values in the confluent wire format
+// are decoded with the schemas of the schema registry while recording.
+type KafkaIntegration struct {
+	SchemaRegistry string `json:"schemaRegistry" yaml:"schemaRegistry" mapstructure:"schemaRegistry"` // url of the schema registry, with its credentials in the userinfo if needed
+}
+
+// MySQLIntegration configures the differences of the mysql connections that don't change the mocks matched. The
+// connection attributes and the session variables set on connecting depend on the machine of the client, "*"
+// ignores all of them.
+type MySQLIntegration struct {
+	IgnoreConnectionAttributes []string `json:"ignoreConnectionAttributes" yaml:"ignoreConnectionAttributes" mapstructure:"ignoreConnectionAttributes"` // attributes of CLIENT_CONNECT_ATTRS whose values aren't matched
+	IgnoreSessionVariables     []string `json:"ignoreSessionVariables" yaml:"ignoreSessionVariables" mapstructure:"ignoreSessionVariables"`             // variables whose values aren't matched in the SET statements
+	// QueryMatching is the strictness of the fallback for the queries not recorded as they are sent: "exact" turns
+	// it off, "normalized" ignores the whitespace, the comments and the case, and "fingerprint" the values of the
+	// literals and the size of the IN lists as well
+	QueryMatching string `json:"queryMatching" yaml:"queryMatching" mapstructure:"queryMatching"`
+}
+
+// PostgresIntegration configures the authentication of the postgres connections during replay. The SCRAM
+// exchange is replayed with the password the application connects with, the connections are authenticated
+// without a password (trust) when it's empty. Pooler tolerates the differences a connection pooler such as
+// pgbouncer brings between the recording and the replay: the statements resetting the sessions, the startup
+// parameters and the statements prepared again.
+type PostgresIntegration struct {
+	Password string `json:"password" yaml:"password" mapstructure:"password"`
+	Pooler   bool   `json:"pooler" yaml:"pooler" mapstructure:"pooler"`
+}
+
+// MongoIntegration configures the matching of the mongo commands during replay. QueryMatching is the strictness of
+// the fallback for the commands not recorded as they are sent: "exact" turns it off and "shape" matches them with
+// the commands of the same collection and the same structure, whatever the values of their filters, their object
+// ids and their dates. The test sets override it with the mongoQueryMatching of their config.
+type MongoIntegration struct {
+	QueryMatching string `json:"queryMatching" yaml:"queryMatching" mapstructure:"queryMatching"`
+}
+
+// HTTPIntegration configures the matching of the http calls during replay. The headers of IgnoreHeaders, e.g.
+// Date, Traceparent or X-Request-Id, and the query params of IgnoreQueryParams, e.g. the timestamps and the nonces,
+// are left out of the matching. The URLRewrites rewrite the paths of the calls and of the mocks before they're
+// compared. The rules of the test sets, the httpMatching of their config, add to these.
+type HTTPIntegration struct {
+	IgnoreHeaders     []string     `json:"ignoreHeaders" yaml:"ignoreHeaders" mapstructure:"ignoreHeaders"`
+	IgnoreQueryParams []string     `json:"ignoreQueryParams" yaml:"ignoreQueryParams" mapstructure:"ignoreQueryParams"`
+	URLRewrites       []URLRewrite `json:"urlRewrites" yaml:"urlRewrites" mapstructure:"urlRewrites"`
+}
+
+// URLRewrite rewrites the paths matching the glob From into To. A * of From matches a segment of the path and a **
+// any number of them, each * of To is replaced by what the one of From in the same position matched, e.g. from
+// "/v1/users/*" to "/v2/users/*", or from "/reports/*" to "/reports/latest" to match the reports of any date.
+type URLRewrite struct {
+	From string `json:"from" yaml:"from" mapstructure:"from"`
+	To   string `json:"to" yaml:"to" mapstructure:"to"`
+}
+
+// Framing splits the streams of an unknown protocol on a port into its frames, so that the generic integration
+// records and matches whole frames instead of the chunks the conns happen to be read in.
+type Framing struct {
+	Port uint   `json:"port" yaml:"port" mapstructure:"port"`
+	Type string `json:"type" yaml:"type" mapstructure:"type"` // "length", "delimiter" or "fixed"
+	// the frames of the length type start with a header holding their length
+	LengthOffset int    `json:"lengthOffset" yaml:"lengthOffset" mapstructure:"lengthOffset"` // offset of the length field in the frame
+	LengthSize   int    `json:"lengthSize" yaml:"lengthSize" mapstructure:"lengthSize"`       // size of the length field: 1, 2, 4 or 8 bytes
+	Endianness   string `json:"endianness" yaml:"endianness" mapstructure:"endianness"`       // "big" (default) or "little"
+	LengthAdjust int    `json:"lengthAdjust" yaml:"lengthAdjust" mapstructure:"lengthAdjust"` // added to the length to get the size after the length field, e.g. -4 when the length counts itself
+	// the frames of the delimiter type end with the delimiter, e.g. "\r\n"
+	Delimiter string `json:"delimiter" yaml:"delimiter" mapstructure:"delimiter"`
+	// the frames of the fixed type are all of this size
+	Size int `json:"size" yaml:"size" mapstructure:"size"`
+	// Protobuf decodes the frames of the length type, after their length field, into their protobuf messages
+	Protobuf ProtobufFraming `json:"protobuf" yaml:"protobuf" mapstructure:"protobuf"`
+}
+
+// ProtobufFraming names the messages of the frames in a compiled FileDescriptorSet, as written by
+// protoc --include_imports --descriptor_set_out. The messages are recorded as json and matched on their fields.
+type ProtobufFraming struct {
+	DescriptorSet string `json:"descriptorSet" yaml:"descriptorSet" mapstructure:"descriptorSet"` // path of the FileDescriptorSet
+	Request       string `json:"request" yaml:"request" mapstructure:"request"`                   // full name of the message of the requests, e.g. acme.v1.Request
+	Response      string `json:"response" yaml:"response" mapstructure:"response"`                // full name of the message of the responses
+}
+
+// Resolver configures how the proxy resolves the destinations it dials in record mode.
+type Resolver struct {
+	Servers  []string          `json:"servers" yaml:"servers" mapstructure:"servers"`    // upstream dns servers (ip or ip:port), the system resolver is used when empty
+	Hosts    map[string]string `json:"hosts" yaml:"hosts" mapstructure:"hosts"`          // static host name to ip overrides
+	CacheTTL time.Duration     `json:"cacheTTL" yaml:"cacheTTL" mapstructure:"cacheTTL"` // how long the resolved addresses are reused, not cached when 0
+}
+
+// PortIntegration sets the detection order of the integrations for a destination port. The first
+// integration is used if none of them matches, so a single entry forces the integration for the port.
+type PortIntegration struct {
+	Port         uint     `json:"port" yaml:"port" mapstructure:"port"`
+	Integrations []string `json:"integrations" yaml:"integrations" mapstructure:"integrations"`
+}
+
 type Filter struct {
 	BypassRule `mapstructure:",squash"`
 	URLMethods []string          `json:"urlMethods" yaml:"urlMethods" mapstructure:"urlMethods"`
@@ -127,6 +301,26 @@ type Test struct {
 	DisableMockUpload   bool                `json:"disableMockUpload" yaml:"disableMockUpload" mapstructure:"disableMockUpload"`
 	UseLocalMock        bool                `json:"useLocalMock" yaml:"useLocalMock" mapstructure:"useLocalMock"`
 	UpdateTemplate      bool                `json:"updateTemplate" yaml:"updateTemplate" mapstructure:"updateTemplate"`
+	MockMemLimit        uint64              `json:"mockMemLimit" yaml:"mockMemLimit" mapstructure:"mockMemLimit"`          // memory budget of the mocks in MB, the mocks over it are spilled to disk, unlimited when 0
+	DocCacheSize        uint64              `json:"docCacheSize" yaml:"docCacheSize" mapstructure:"docCacheSize"`          // memory budget in MB of the parsed test case and mock documents kept between the test sets, disabled when 0
+	QualityGates        QualityGates        `json:"qualityGates" yaml:"qualityGates" mapstructure:"qualityGates"`          // decide the exit code of the test run in place of the test failures
+	WebSocketTiming     string              `json:"websocketTiming" yaml:"websocketTiming" mapstructure:"websocketTiming"` // "recorded" replays the server frames with their recorded gaps, "immediate" without waiting
+	SSETiming           string              `json:"sseTiming" yaml:"sseTiming" mapstructure:"sseTiming"`                   // "recorded" replays the server-sent events at their recorded times, "accelerated" without waiting
+}
+
+// QualityGates are the conditions a complete test run has to meet to exit with code 0, the run fails on any
+// failed test case when none of them is set.
+type QualityGates struct {
+	MinPassRate   float64 `json:"minPassRate" yaml:"minPassRate" mapstructure:"minPassRate"`       // min percentage of passed test cases, ignored test cases excluded, disabled when 0
+	NoNewFailures bool    `json:"noNewFailures" yaml:"noNewFailures" mapstructure:"noNewFailures"` // fail only on the test cases which didn't fail in the baseline run
+	BaselineRun   string  `json:"baselineRun" yaml:"baselineRun" mapstructure:"baselineRun"`       // test run compared against for noNewFailures, the previous run when empty
+	MaxFlaky      int     `json:"maxFlaky" yaml:"maxFlaky" mapstructure:"maxFlaky"`                // max test cases which both passed and failed within the flaky window, disabled when negative
+	FlakyWindow   int     `json:"flakyWindow" yaml:"flakyWindow" mapstructure:"flakyWindow"`       // number of latest test runs, the current one included, checked for flaky test cases
+}
+
+// Enabled reports whether any of the quality gates is set.
+func (q QualityGates) Enabled() bool {
+	return q.MinPassRate > 0 || q.NoNewFailures || q.MaxFlaky >= 0
 }
 
 type Language string
@@ -192,6 +386,27 @@ func SetSelectedTests(conf *Config, testSets []string) {
 		conf.Test.SelectedTests[testSet] = []string{}
 	}
 }
+
+// SetSelectedTestCases selects the given test cases, the value is of the form "test-set-1:test-1 test-2,test-set-2:test-3".
+// A test set without any test case selects all of its test cases.
+func SetSelectedTestCases(conf *Config, value string) error {
+	for _, ts := range strings.Split(value, ",") {
+		ts = strings.TrimSpace(ts)
+		if ts == "" {
+			continue
+		}
+		testSet, tests, _ := strings.Cut(ts, ":")
+		if testSet == "" {
+			return fmt.Errorf("invalid format: %s", ts)
+		}
+		if conf.Test.SelectedTests == nil {
+			conf.Test.SelectedTests = make(map[string][]string)
+		}
+		conf.Test.SelectedTests[testSet] = append(conf.Test.SelectedTests[testSet], strings.Fields(tests)...)
+	}
+	return nil
+}
+
 func SetSelectedServices(conf *Config, services []string) {
 	// string is "s1,s2" so i want to get s1,s2
 	conf.Contract.Services = services
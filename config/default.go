@@ -10,15 +10,22 @@ import (
 // defaultConfig is a variable to store the default configuration of the Keploy CLI. It is not a constant because enterprise need update the default configuration.
 var defaultConfig = `
 path: ""
+testLayout: "flat"
 appId: 0
 appName: ""
 command: ""
 port: 0
 proxyPort: 16789
+proxyPortRange: ""
+proxyAdminPort: 0
+maxProxyConns: 0
 dnsPort: 26789
+dnsPortRange: ""
 debug: false
 disableANSI: false
 disableTele: false
+offline: false
+telemetryFile: ""
 generateGithubActions: false
 containerName: ""
 networkName: ""
@@ -43,15 +50,73 @@ test:
   disableLineCoverage: false
   fallbackOnMiss: false
   disableMockUpload: true
+  mockMemLimit: 0
+  docCacheSize: 64
+  websocketTiming: "recorded"
+  sseTiming: "recorded"
+  qualityGates:
+    minPassRate: 0
+    noNewFailures: false
+    baselineRun: ""
+    maxFlaky: -1
+    flakyWindow: 5
 record:
   recordTimer: 0s
   filters: []
+  bypassDomains: []
+  capture:
+    inactivityTimeout: 1m
+    closeGracePeriod: 2s
+    workers: 0
+    queueSize: 256
+    maxConnections: 10000
+    testCaseBuffer: 500
+    maxRequestBody: 0
+    maxResponseBody: 0
+  tlsIngress: []
 contract:
   driven: "consumer"
   servicesMapping: {}
   self: "s1"
 configPath: ""
 bypassRules: []
+defaults:
+  version: 1
+  noise: {}
+  filters: []
+  bypassRules: []
+  localFile: "keploy.local.yml"
+redact:
+  headers: []
+  jsonPaths: []
+  patterns: []
+  detectors: []
+  replacement: "[REDACTED]"
+pluginDir: ""
+plugins: []
+integrations:
+  disabled: []
+  ports: []
+  framing: []
+  kafka:
+    schemaRegistry: ""
+  mysql:
+    ignoreConnectionAttributes: ["_pid", "_thread", "_os", "_platform", "_client_version", "_source_host", "_runtime_version", "hostname"]
+    ignoreSessionVariables: ["time_zone", "sql_mode", "names", "character_set_results"]
+    queryMatching: "fingerprint"
+  postgres:
+    password: ""
+    pooler: false
+  mongo:
+    queryMatching: "exact"
+  http:
+    ignoreHeaders: []
+    ignoreQueryParams: []
+    urlRewrites: []
+resolver:
+  servers: []
+  hosts: {}
+  cacheTTL: 0s
 `
 
 func GetDefaultConfig() string {
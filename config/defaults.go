@@ -0,0 +1,114 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	yaml3 "gopkg.in/yaml.v3"
+)
+
+// DefaultsVersion is the latest version of the defaults section understood by keploy
+const DefaultsVersion = 1
+
+// Defaults holds the noise, filters and bypass rules shared by every test set of the repository. They are
+// checked in along with keploy.yml, while the overrides of a single test set live in the (untracked) local file.
+type Defaults struct {
+	Version     int          `json:"version" yaml:"version" mapstructure:"version"`
+	Noise       GlobalNoise  `json:"noise" yaml:"noise" mapstructure:"noise"`
+	Filters     []Filter     `json:"filters" yaml:"filters" mapstructure:"filters"`
+	BypassRules []BypassRule `json:"bypassRules" yaml:"bypassRules" mapstructure:"bypassRules"`
+	LocalFile   string       `json:"localFile" yaml:"localFile" mapstructure:"localFile"` // relative to the directory of keploy.yml
+}
+
+// LocalDefaults is the content of the local defaults file.
+type LocalDefaults struct {
+	Version  int                        `json:"version" yaml:"version"`
+	TestSets map[string]TestSetDefaults `json:"testSets" yaml:"testSets"`
+}
+
+// TestSetDefaults overrides the repository defaults for a single test set.
+type TestSetDefaults struct {
+	Noise GlobalNoise `json:"noise" yaml:"noise"`
+}
+
+// ApplyDefaults merges the repository defaults and the local per test-set overrides into the config. The settings
+// of keploy.yml take precedence over the defaults, and the local overrides take precedence over both. It is safe
+// to call it more than once.
+func ApplyDefaults(conf *Config) error {
+	if conf.defaultsApplied {
+		return nil
+	}
+	d := conf.Defaults
+	if d.Version > DefaultsVersion {
+		return fmt.Errorf("defaults version %d is not supported by this version of keploy, the latest supported version is %d", d.Version, DefaultsVersion)
+	}
+
+	local, err := readLocalDefaults(conf)
+	if err != nil {
+		return err
+	}
+	if local.Version > DefaultsVersion {
+		return fmt.Errorf("version %d of the local defaults file is not supported, the latest supported version is %d", local.Version, DefaultsVersion)
+	}
+
+	conf.Test.GlobalNoise.Global = joinNoise(d.Noise, conf.Test.GlobalNoise.Global)
+	for testSet, ts := range local.TestSets {
+		if conf.Test.GlobalNoise.Testsets == nil {
+			conf.Test.GlobalNoise.Testsets = make(TestsetNoise)
+		}
+		conf.Test.GlobalNoise.Testsets[testSet] = joinNoise(conf.Test.GlobalNoise.Testsets[testSet], ts.Noise)
+	}
+
+	conf.Record.Filters = append(append([]Filter{}, d.Filters...), conf.Record.Filters...)
+	conf.BypassRules = append(append([]BypassRule{}, d.BypassRules...), conf.BypassRules...)
+	conf.defaultsApplied = true
+	return nil
+}
+
+func readLocalDefaults(conf *Config) (LocalDefaults, error) {
+	var local LocalDefaults
+	if conf.Defaults.LocalFile == "" {
+		return local, nil
+	}
+	path := conf.Defaults.LocalFile
+	if !filepath.IsAbs(path) {
+		dir := conf.ConfigPath
+		if dir == "" {
+			dir = "."
+		}
+		path = filepath.Join(dir, path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return local, nil
+		}
+		return local, fmt.Errorf("failed to read the local defaults file %s: %w", path, err)
+	}
+	if err := yaml3.Unmarshal(data, &local); err != nil {
+		return local, fmt.Errorf("failed to parse the local defaults file %s: %w", path, err)
+	}
+	return local, nil
+}
+
+// joinNoise returns the noise of base overridden by the fields of override, without modifying either of them.
+func joinNoise(base, override GlobalNoise) GlobalNoise {
+	noise := make(GlobalNoise, len(base))
+	for kind, fields := range base {
+		noise[kind] = make(map[string][]string, len(fields))
+		for field, regexes := range fields {
+			noise[kind][field] = regexes
+		}
+	}
+	for kind, fields := range override {
+		if noise[kind] == nil {
+			noise[kind] = make(map[string][]string, len(fields))
+		}
+		for field, regexes := range fields {
+			noise[kind][field] = regexes
+		}
+	}
+	return noise
+}
@@ -0,0 +1,69 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ProfileExtendsKey is the key used by a profile to inherit the settings of another profile
+const ProfileExtendsKey = "extends"
+
+// ResolveProfile returns the settings of the named profile along with the settings of the profiles it
+// extends. The settings are ordered from the root profile to the named one, so that applying them in
+// order lets the derived profiles override the inherited settings.
+func ResolveProfile(profiles map[string]interface{}, name string) ([]map[string]interface{}, error) {
+	var chain []map[string]interface{}
+	visited := make(map[string]bool)
+
+	for current := name; current != ""; {
+		if visited[current] {
+			return nil, fmt.Errorf("profile %q has a cyclic inheritance", current)
+		}
+		visited[current] = true
+
+		raw, ok := lookupProfile(profiles, current)
+		if !ok {
+			if current == name {
+				return nil, fmt.Errorf("profile %q is not defined in the config file", name)
+			}
+			return nil, fmt.Errorf("profile %q extended by %q is not defined in the config file", current, name)
+		}
+		if raw == nil {
+			raw = map[string]interface{}{}
+		}
+		settings, ok := raw.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("profile %q must be a map of settings", current)
+		}
+
+		parent := ""
+		overrides := make(map[string]interface{}, len(settings))
+		for k, v := range settings {
+			if strings.EqualFold(k, ProfileExtendsKey) {
+				parent, ok = v.(string)
+				if !ok {
+					return nil, fmt.Errorf("%s of profile %q must be the name of a profile", ProfileExtendsKey, current)
+				}
+				continue
+			}
+			overrides[k] = v
+		}
+
+		chain = append([]map[string]interface{}{overrides}, chain...)
+		current = parent
+	}
+	return chain, nil
+}
+
+// lookupProfile finds the profile case-insensitively, since viper lowercases all the keys.
+func lookupProfile(profiles map[string]interface{}, name string) (interface{}, bool) {
+	if p, ok := profiles[name]; ok {
+		return p, true
+	}
+	for k, p := range profiles {
+		if strings.EqualFold(k, name) {
+			return p, true
+		}
+	}
+	return nil, false
+}
@@ -48,6 +48,8 @@ func setVersion() {
 	}
 	utils.Version = version
 	utils.VersionIdenitfier = "version"
+	// sentry is initialised once the config is loaded, since the config can disable the outbound calls
+	utils.SentryDSN = dsn
 }
 
 func start(ctx context.Context) {
@@ -78,10 +80,6 @@ func start(ctx context.Context) {
 	oldMask := utils.SetUmask()
 	defer utils.RestoreUmask(oldMask)
 
-	if dsn != "" {
-		utils.SentryInit(logger, dsn)
-		//logger = utils.ModifyToSentryLogger(ctx, logger, sentry.CurrentHub().Client(), configDb)
-	}
 	conf := config.New()
 	conf.APIServerURL = apiServerURI
 	conf.GitHubClientID = gitHubClientID
@@ -292,6 +292,10 @@ func (a *App) extractMeta(ctx context.Context, e events.Message) (bool, error) {
 
 	a.inodeChan <- inode
 	a.logger.Debug("container started and successfully extracted inode", zap.Any("inode", inode))
+	go func() {
+		defer utils.Recover(a.logger)
+		a.watchQUIC(ctx, info.State.Pid)
+	}()
 	if info.NetworkSettings == nil || info.NetworkSettings.Networks == nil {
 		a.logger.Debug("container network settings not available", zap.Any("containerDetails.NetworkSettings", info.NetworkSettings))
 		return false, nil
@@ -421,6 +425,14 @@ func (a *App) Run(ctx context.Context, inodeChan chan uint64) models.AppError {
 	if utils.IsDockerCmd(a.kind) {
 		return a.runDocker(ctx)
 	}
+
+	// the app runs in the network namespace of keploy
+	quicCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		defer utils.Recover(a.logger)
+		a.watchQUIC(quicCtx, 0)
+	}()
 	return a.run(ctx)
 }
 func (a *App) waitTillExit() {
@@ -0,0 +1,105 @@
+//go:build linux
+
+package app
+
+import (
+	"context"
+	"encoding/hex"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// The HTTP/3 calls are made over QUIC (udp), which the hooks don't redirect to the proxy, so they reach their
+// servers without being recorded or mocked. The proxy keeps the clients on tcp by removing the h3 alternatives
+// from the responses and the dns answers, the udp sockets of the app connected to port 443 are watched for the
+// clients which use QUIC anyway, e.g. with HTTP/3 hardcoded. The sockets which send without being connected
+// can't be told apart from the other udp sockets and aren't reported.
+
+const (
+	quicPort         = 443
+	quicPollInterval = 2 * time.Second
+)
+
+// watchQUIC warns once for every server the app is seen talking QUIC to, until the context is done. The udp
+// sockets are read from the network namespace of the process with the pid, the one of keploy when it's 0.
+func (a *App) watchQUIC(ctx context.Context, pid int) {
+	netDir := filepath.Join("/proc", "self", "net")
+	if pid != 0 {
+		netDir = filepath.Join("/proc", strconv.Itoa(pid), "net")
+	}
+	seen := map[string]bool{}
+	ticker := time.NewTicker(quicPollInterval)
+	defer ticker.Stop()
+	for {
+		for _, table := range []string{"udp", "udp6"} {
+			servers, err := quicServers(filepath.Join(netDir, table))
+			if err != nil {
+				a.logger.Debug("stopped watching the QUIC conns of the app", zap.String("table", table), zap.Error(err))
+				return
+			}
+			for _, server := range servers {
+				if seen[server] {
+					continue
+				}
+				seen[server] = true
+				a.logger.Warn("a QUIC (HTTP/3) conn was seen in the network of the app, its calls are neither recorded nor mocked", zap.String("server", server))
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// quicServers returns the remote addresses of the sockets of the udp table (e.g. /proc/net/udp) connected to
+// port 443.
+func quicServers(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var servers []string
+	lines := strings.Split(string(data), "\n")
+	// the first line holds the names of the columns
+	for _, line := range lines[1:] {
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			continue
+		}
+		ip, port, ok := parseProcAddr(fields[2])
+		if !ok || port != quicPort {
+			continue
+		}
+		servers = append(servers, net.JoinHostPort(ip.String(), strconv.Itoa(port)))
+	}
+	return servers, nil
+}
+
+// parseProcAddr parses an address of the udp tables e.g. 0100007F:01BB, the ip is in hex with each of its 32 bit
+// words in the host byte order, little endian on the supported architectures.
+func parseProcAddr(addr string) (net.IP, int, bool) {
+	hexIP, hexPort, ok := strings.Cut(addr, ":")
+	if !ok {
+		return nil, 0, false
+	}
+	ip, err := hex.DecodeString(hexIP)
+	if err != nil || (len(ip) != net.IPv4len && len(ip) != net.IPv6len) {
+		return nil, 0, false
+	}
+	port, err := strconv.ParseUint(hexPort, 16, 16)
+	if err != nil {
+		return nil, 0, false
+	}
+	for i := 0; i < len(ip); i += 4 {
+		ip[i], ip[i+1], ip[i+2], ip[i+3] = ip[i+3], ip[i+2], ip[i+1], ip[i]
+	}
+	return net.IP(ip), int(port), true
+}
@@ -3,15 +3,20 @@
 package conn
 
 import (
+	"bufio"
 	"context"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"runtime"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"go.uber.org/zap"
 
+	"go.keploy.io/server/v2/config"
 	"go.keploy.io/server/v2/pkg"
 	"go.keploy.io/server/v2/pkg/models"
 	"go.keploy.io/server/v2/utils"
@@ -23,83 +28,309 @@ var Emoji = "\U0001F430" + " Keploy:"
 type Factory struct {
 	connections         map[ID]*Tracker
 	inactivityThreshold time.Duration
+	closeGracePeriod    time.Duration
+	maxConnections      int
 	mutex               *sync.RWMutex
 	logger              *zap.Logger
+	// queues are the signal queues of the workers, a conn is always processed by the same worker so that
+	// its ingress calls are captured in order.
+	queues []chan ID
+	stats  factoryStats
 }
 
-// NewFactory creates a new instance of the factory.
-func NewFactory(inactivityThreshold time.Duration, logger *zap.Logger) *Factory {
+// factoryStats counts what the factory dropped or delayed to keep its memory bounded.
+type factoryStats struct {
+	// droppedEvents is the number of events dropped because too many conns were tracked
+	droppedEvents int64
+	// overflows is the number of signals which didn't fit in the queues, their conns wait for the next sweep
+	overflows int64
+	// stalls is the number of test cases which had to wait for the consumer of the test cases
+	stalls int64
+}
+
+const (
+	// sweepInterval is how often the trackers are checked for inactivity.
+	sweepInterval = 500 * time.Millisecond
+	// defaultInactivityThreshold is how long an idle conn is tracked.
+	defaultInactivityThreshold = time.Minute
+	// defaultCloseGracePeriod is how long a closed conn is kept for the data events which arrive after its close event.
+	defaultCloseGracePeriod = 2 * time.Second
+	// defaultMaxConnections bounds the number of tracked conns, the events of the new conns over it are dropped.
+	defaultMaxConnections = 10000
+	// maxWorkers bounds the number of workers capturing the ingress calls, unless they're configured.
+	maxWorkers = 8
+	// defaultQueueSize is the number of signals a worker can hold.
+	defaultQueueSize = 256
+)
+
+// NewFactory creates a new instance of the factory, the options which aren't set keep their defaults.
+func NewFactory(opts config.Capture, logger *zap.Logger) *Factory {
+	inactivityThreshold := opts.InactivityTimeout
+	if inactivityThreshold <= 0 {
+		inactivityThreshold = defaultInactivityThreshold
+	}
+	closeGracePeriod := opts.CloseGracePeriod
+	if closeGracePeriod <= 0 {
+		closeGracePeriod = defaultCloseGracePeriod
+	}
+	maxConnections := int(opts.MaxConnections)
+	if maxConnections == 0 {
+		maxConnections = defaultMaxConnections
+	}
+	workers := int(opts.Workers)
+	if workers == 0 {
+		workers = min(runtime.NumCPU(), maxWorkers)
+	}
+	queueSize := int(opts.QueueSize)
+	if queueSize == 0 {
+		queueSize = defaultQueueSize
+	}
+	queues := make([]chan ID, workers)
+	for i := range queues {
+		queues[i] = make(chan ID, queueSize)
+	}
 	return &Factory{
 		connections:         make(map[ID]*Tracker),
 		mutex:               &sync.RWMutex{},
 		inactivityThreshold: inactivityThreshold,
+		closeGracePeriod:    closeGracePeriod,
+		maxConnections:      maxConnections,
 		logger:              logger,
+		queues:              queues,
 	}
 }
 
-// ProcessActiveTrackers iterates over all conn the trackers and checks if they are complete. If so, it captures the ingress call and
-// deletes the tracker. If the tracker is inactive for a long time, it deletes it.
-func (factory *Factory) ProcessActiveTrackers(ctx context.Context, t chan *models.TestCase, opts models.IncomingOptions) {
+// Run captures the ingress calls until the context is done. The trackers are processed by the workers as soon as the
+// data events complete a response to a request, they are only swept periodically to delete the closed or inactive
+// conns, along with the responses which end with them.
+func (factory *Factory) Run(ctx context.Context, t chan *models.TestCase, opts models.IncomingOptions) {
+	var wg sync.WaitGroup
+	for _, queue := range factory.queues {
+		wg.Add(1)
+		go func(queue chan ID) {
+			defer wg.Done()
+			defer utils.Recover(factory.logger)
+			factory.work(ctx, queue, t, opts)
+		}(queue)
+	}
+
+	ticker := time.NewTicker(sweepInterval)
+	defer ticker.Stop()
+	for ctx.Err() == nil {
+		select {
+		case <-ctx.Done():
+		case <-ticker.C:
+			factory.sweep(ctx)
+		}
+	}
+	wg.Wait()
+
+	// release the payloads spilled to the disk by the conns which are still open
 	factory.mutex.Lock()
-	defer factory.mutex.Unlock()
-	var trackersToDelete []ID
 	for connID, tracker := range factory.connections {
+		tracker.close()
+		delete(factory.connections, connID)
+	}
+	factory.mutex.Unlock()
+
+	stats := factory.loadStats()
+	if stats != (factoryStats{}) {
+		factory.logger.Debug("ingress capture was throttled", zap.Int64("dropped events", stats.droppedEvents), zap.Int64("overflowed signals", stats.overflows), zap.Int64("stalled test cases", stats.stalls))
+	}
+}
+
+func (factory *Factory) work(ctx context.Context, queue chan ID, t chan *models.TestCase, opts models.IncomingOptions) {
+	for {
 		select {
 		case <-ctx.Done():
 			return
-		default:
-			ok, requestBuf, responseBuf, reqTimestampTest, resTimestampTest := tracker.IsComplete()
-			if ok {
-
-				if len(requestBuf) == 0 || len(responseBuf) == 0 {
-					factory.logger.Warn("failed processing a request due to invalid request or response", zap.Any("Request Size", len(requestBuf)), zap.Any("Response Size", len(responseBuf)))
-					continue
+		case connID := <-queue:
+			factory.mutex.RLock()
+			tracker, ok := factory.connections[connID]
+			factory.mutex.RUnlock()
+			if !ok {
+				continue
+			}
+			factory.process(ctx, tracker, t, opts)
+			if (tracker.isClosed() && tracker.IsInactive(factory.closeGracePeriod)) || tracker.IsInactive(factory.inactivityThreshold) {
+				// the body of the last response may end with the conn
+				tracker.end()
+				factory.process(ctx, tracker, t, opts)
+				factory.mutex.Lock()
+				if factory.connections[connID] == tracker {
+					delete(factory.connections, connID)
 				}
+				factory.mutex.Unlock()
+				tracker.close()
+			}
+		}
+	}
+}
 
-				parsedHTTPReq, err := pkg.ParseHTTPRequest(requestBuf)
-				if err != nil {
-					utils.LogError(factory.logger, err, "failed to parse the http request from byte array", zap.Any("requestBuf", requestBuf))
-					continue
-				}
-				parsedHTTPRes, err := pkg.ParseHTTPResponse(responseBuf, parsedHTTPReq)
-				if err != nil {
-					utils.LogError(factory.logger, err, "failed to parse the http response from byte array", zap.Any("responseBuf", responseBuf))
-					continue
-				}
-				capture(ctx, factory.logger, t, parsedHTTPReq, parsedHTTPRes, reqTimestampTest, resTimestampTest, opts)
+// sweep queues all the trackers to their workers, which process them and delete the ones which are closed or
+// inactive for a long time. The sweep waits for the workers, so it doesn't pile up when they are behind.
+func (factory *Factory) sweep(ctx context.Context) {
+	factory.mutex.RLock()
+	connIDs := make([]ID, 0, len(factory.connections))
+	for connID := range factory.connections {
+		connIDs = append(connIDs, connID)
+	}
+	factory.mutex.RUnlock()
 
-			} else if tracker.IsInactive(factory.inactivityThreshold) {
-				trackersToDelete = append(trackersToDelete, connID)
-			}
+	for _, connID := range connIDs {
+		select {
+		case <-ctx.Done():
+			return
+		case factory.queue(connID) <- connID:
 		}
 	}
+}
+
+func (factory *Factory) queue(connID ID) chan ID {
+	h := connID.TsID ^ uint64(connID.FD) ^ uint64(connID.TGID)<<32
+	return factory.queues[h%uint64(len(factory.queues))]
+}
 
-	// Delete all the processed trackers.
-	for _, key := range trackersToDelete {
-		delete(factory.connections, key)
+// process captures the ingress calls which are complete on the tracker. A tracker is only processed by its worker,
+// and without holding the factory lock, so the event listeners aren't blocked by the capture.
+func (factory *Factory) process(ctx context.Context, tracker *Tracker, t chan *models.TestCase, opts models.IncomingOptions) {
+	tracker.mutex.RLock()
+	upstream := tracker.upstream
+	tracker.mutex.RUnlock()
+	send := func(tc *models.TestCase) {
+		if upstream != "" {
+			tc = overTLS(factory.logger, tc, upstream)
+		}
+		factory.send(ctx, t, tc)
+	}
+	for ctx.Err() == nil {
+		pending := tracker.pending()
+		if e, ok := tracker.nextExchange(); ok {
+			send(factory.capture(ctx, e, opts))
+		}
+		if call, ok := tracker.nextCall(); ok {
+			send(captureGRPC(factory.logger, call, opts))
+		}
+		if s, ok := tracker.nextSession(); ok {
+			send(captureWebSocket(factory.logger, s, opts))
+		}
+		if !pending {
+			return
+		}
+	}
+}
+
+func (factory *Factory) capture(ctx context.Context, e exchange, opts models.IncomingOptions) *models.TestCase {
+	defer e.close()
+	if e.req.data.size == 0 || e.resp.data.size == 0 {
+		factory.logger.Warn("failed processing a request due to invalid request or response", zap.Any("Request Size", e.req.data.size), zap.Any("Response Size", e.resp.data.size))
+		return nil
+	}
+	factory.logger.Debug("captured a request and its response", zap.Any("Request Size", e.req.data.size), zap.Any("Response Size", e.resp.data.size))
+
+	// the messages are parsed from their payloads as they're read, the spilled ones aren't read back into memory
+	reqReader := bufio.NewReader(e.req.data.Reader())
+	// the empty lines some clients send after the bodies are left out
+	for {
+		b, err := reqReader.Peek(1)
+		if err != nil || (b[0] != '\r' && b[0] != '\n') {
+			break
+		}
+		_, _ = reqReader.Discard(1)
+	}
+	parsedHTTPReq, err := pkg.ReadHTTPRequest(reqReader)
+	if err != nil {
+		utils.LogError(factory.logger, err, "failed to parse the http request", zap.Any("Request Size", e.req.data.size))
+		return nil
+	}
+	parsedHTTPRes, err := pkg.ReadHTTPResponse(bufio.NewReader(e.resp.data.Reader()), parsedHTTPReq)
+	if err != nil {
+		utils.LogError(factory.logger, err, "failed to parse the http response", zap.Any("Response Size", e.resp.data.size))
+		return nil
+	}
+	return capture(ctx, factory.logger, parsedHTTPReq, parsedHTTPRes, e.req.timestamp, e.resp.timestamp, opts)
+}
+
+// send sends the test case captured, unless it was filtered.
+func (factory *Factory) send(ctx context.Context, t chan *models.TestCase, tc *models.TestCase) {
+	if tc == nil {
+		return
+	}
+
+	select {
+	case t <- tc:
+		return
+	default:
+	}
+	// the test cases aren't consumed fast enough, wait for them instead of dropping the test case
+	if atomic.AddInt64(&factory.stats.stalls, 1) == 1 {
+		factory.logger.Warn("the recorded test cases aren't saved as fast as they are captured, the capture is slowed down (see record.capture.testCaseBuffer)")
+	}
+	select {
+	case <-ctx.Done():
+	case t <- tc:
+	}
+}
+
+func (factory *Factory) loadStats() factoryStats {
+	return factoryStats{
+		droppedEvents: atomic.LoadInt64(&factory.stats.droppedEvents),
+		overflows:     atomic.LoadInt64(&factory.stats.overflows),
+		stalls:        atomic.LoadInt64(&factory.stats.stalls),
 	}
 }
 
 // GetOrCreate returns a tracker that related to the given conn and transaction ids. If there is no such tracker
-// we create a new one.
+// we create a new one, unless too many conns are tracked already, in which case it returns nil.
 func (factory *Factory) GetOrCreate(connectionID ID) *Tracker {
 	factory.mutex.Lock()
 	defer factory.mutex.Unlock()
 	tracker, ok := factory.connections[connectionID]
 	if !ok {
+		if len(factory.connections) >= factory.maxConnections {
+			if atomic.AddInt64(&factory.stats.droppedEvents, 1) == 1 {
+				factory.logger.Warn(fmt.Sprintf("more than %d connections are open, the calls on the new connections are not recorded (see record.capture.maxConnections)", factory.maxConnections))
+			}
+			return nil
+		}
 		factory.connections[connectionID] = NewTracker(connectionID, factory.logger)
 		return factory.connections[connectionID]
 	}
 	return tracker
 }
 
-func capture(_ context.Context, logger *zap.Logger, t chan *models.TestCase, req *http.Request, resp *http.Response, reqTimeTest time.Time, resTimeTest time.Time, opts models.IncomingOptions) {
-	reqBody, err := io.ReadAll(req.Body)
-	if err != nil {
-		utils.LogError(logger, err, "failed to read the http request body")
+// AddOpenEvent adds the open event to the tracker of its conn.
+func (factory *Factory) AddOpenEvent(event SocketOpenEvent) {
+	if tracker := factory.GetOrCreate(event.ConnID); tracker != nil {
+		tracker.AddOpenEvent(event)
+	}
+}
+
+// AddDataEvent adds the data event to the tracker of its conn, and signals the conn once a request has its response.
+func (factory *Factory) AddDataEvent(event SocketDataEvent) {
+	tracker := factory.GetOrCreate(event.ConnID)
+	if tracker == nil {
 		return
 	}
+	tracker.AddDataEvent(event)
+	if tracker.pending() {
+		select {
+		case factory.queue(event.ConnID) <- event.ConnID:
+		default:
+			// the sweep picks up the pending requests, if the workers can't keep up
+			atomic.AddInt64(&factory.stats.overflows, 1)
+		}
+	}
+}
+
+// AddCloseEvent adds the close event to the tracker of its conn, the conn is deleted by the sweep after a grace period.
+func (factory *Factory) AddCloseEvent(event SocketCloseEvent) {
+	if tracker := factory.GetOrCreate(event.ConnID); tracker != nil {
+		tracker.AddCloseEvent(event)
+	}
+}
 
+func capture(_ context.Context, logger *zap.Logger, req *http.Request, resp *http.Response, reqTimeTest time.Time, resTimeTest time.Time, opts models.IncomingOptions) *models.TestCase {
 	defer func() {
 		err := resp.Body.Close()
 		if err != nil {
@@ -107,18 +338,41 @@ func capture(_ context.Context, logger *zap.Logger, t chan *models.TestCase, req
 		}
 	}()
 
-	respBody, err := io.ReadAll(resp.Body)
+	if isFiltered(logger, req, opts) {
+		logger.Debug("The request is a filtered request")
+		return nil
+	}
+
+	// the bodies over the size limits are stored truncated, along with the size and the digest of the whole body.
+	// The multipart forms are read whole to be parsed, they're truncated if they can't be.
+	reqLimit, respLimit := int(opts.Capture.MaxRequestBody)<<10, int(opts.Capture.MaxResponseBody)<<10
+	isForm := pkg.IsMultipartForm(req.Header.Get("Content-Type"))
+	if isForm {
+		reqLimit = 0
+	}
+	reqBody, reqTruncated, err := readBody(logger, req.Body, req.Header, reqLimit)
+	if err != nil {
+		utils.LogError(logger, err, "failed to read the http request body")
+		return nil
+	}
+	respBody, respTruncated, err := readBody(logger, resp.Body, resp.Header, respLimit)
 	if err != nil {
 		utils.LogError(logger, err, "failed to read the http response body")
-		return
+		return nil
 	}
 
-	if isFiltered(logger, req, opts) {
-		logger.Debug("The request is a filtered request")
-		return
+	// the multipart forms are stored by their parts, their boundary changes with every upload
+	var form []models.FormData
+	if isForm {
+		if parsed, err := pkg.ParseMultipartForm([]byte(reqBody), req.Header.Get("Content-Type")); err == nil {
+			form, reqBody = parsed, ""
+		} else {
+			logger.Debug("storing the multipart form as it is sent", zap.Error(err))
+			reqBody, reqTruncated = pkg.TruncateBody(reqBody, int(opts.Capture.MaxRequestBody)<<10)
+		}
 	}
 
-	t <- &models.TestCase{
+	return &models.TestCase{
 		Version: models.GetVersion(),
 		Name:    pkg.ToYamlHTTPHeader(req.Header)["Keploy-Test-Name"],
 		Kind:    models.HTTP,
@@ -132,14 +386,17 @@ func capture(_ context.Context, logger *zap.Logger, t chan *models.TestCase, req
 			URL: fmt.Sprintf("http://%s%s", req.Host, req.URL.RequestURI()),
 			//  URL: string(b),
 			Header:    pkg.ToYamlHTTPHeader(req.Header),
-			Body:      string(reqBody),
+			Body:      reqBody,
+			Form:      form,
 			URLParams: pkg.URLParams(req),
+			Truncated: reqTruncated,
 			Timestamp: reqTimeTest,
 		},
 		HTTPResp: models.HTTPResp{
 			StatusCode:    resp.StatusCode,
 			Header:        pkg.ToYamlHTTPHeader(resp.Header),
-			Body:          string(respBody),
+			Body:          respBody,
+			Truncated:     respTruncated,
 			Timestamp:     resTimeTest,
 			StatusMessage: http.StatusText(resp.StatusCode),
 		},
@@ -147,3 +404,116 @@ func capture(_ context.Context, logger *zap.Logger, t chan *models.TestCase, req
 		// Mocks: mocks,
 	}
 }
+
+// readBody reads the body decoded from its Content-Encoding and truncated to the limit. The bodies sent as they are,
+// like the file downloads, are streamed from their payloads so that only the part which is stored is held in
+// memory, the encoded ones are read whole to be decoded.
+func readBody(logger *zap.Logger, body io.Reader, header http.Header, limit int) (string, *models.BodyTruncation, error) {
+	encoding := header.Get("Content-Encoding")
+	if encoding == "" {
+		return pkg.ReadTruncatedBody(body, limit)
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return "", nil, err
+	}
+	// the bodies are stored decoded from their Content-Encoding, the ones which can't be decoded as they are sent
+	if decoded, err := pkg.DecodeBody(data, encoding); err == nil {
+		data = decoded
+	} else {
+		logger.Debug("storing the http body as it is sent", zap.Error(err))
+	}
+	stored, truncated := pkg.TruncateBody(string(data), limit)
+	return stored, truncated, nil
+}
+
+// captureGRPC returns the test case of the gRPC call. The http request of the test case is the HTTP/2 request of the
+// call, its url and its timestamps are the ones the test cases are ordered, filtered and replayed by.
+func captureGRPC(logger *zap.Logger, call grpcCall, opts models.IncomingOptions) *models.TestCase {
+	u, err := url.Parse(pkg.GrpcCallURL(call.req.Headers))
+	if err != nil {
+		utils.LogError(logger, err, "failed to parse the url of the grpc call")
+		return nil
+	}
+	header := http.Header{}
+	for key, value := range call.req.Headers.OrdinaryHeaders {
+		header.Set(key, value)
+	}
+	req := &http.Request{Method: http.MethodPost, URL: u, Host: u.Host, Header: header}
+	if isFiltered(logger, req, opts) {
+		logger.Debug("The grpc call is a filtered call")
+		return nil
+	}
+
+	// the unary calls are stored with their bodies only
+	pkg.CompactGrpcCall(&call.req, &call.resp)
+	return &models.TestCase{
+		Version: models.GetVersion(),
+		Name:    header.Get("Keploy-Test-Name"),
+		Kind:    models.GRPC_EXPORT,
+		Created: time.Now().Unix(),
+		HTTPReq: models.HTTPReq{
+			Method:     models.Method(http.MethodPost),
+			ProtoMajor: 2,
+			URL:        u.String(),
+			Timestamp:  call.reqTimestamp,
+		},
+		HTTPResp: models.HTTPResp{
+			Timestamp: call.resTimestamp,
+		},
+		GrpcReq:  call.req,
+		GrpcResp: call.resp,
+		Noise:    map[string][]string{},
+	}
+}
+
+// captureWebSocket returns the test case of the websocket, the http request and response of the test case are its
+// handshake. The response of the test case ends with the last frame, so that the mocks of the whole session are
+// kept for it.
+func captureWebSocket(logger *zap.Logger, s *wsSession, opts models.IncomingOptions) *models.TestCase {
+	req, err := pkg.ParseHTTPRequest(s.req)
+	if err != nil {
+		utils.LogError(logger, err, "failed to parse the websocket handshake from byte array", zap.Any("requestBuf", s.req))
+		return nil
+	}
+	resp, err := pkg.ParseHTTPResponse(s.resp, req)
+	if err != nil {
+		utils.LogError(logger, err, "failed to parse the websocket handshake response from byte array", zap.Any("responseBuf", s.resp))
+		return nil
+	}
+	defer func() {
+		err := resp.Body.Close()
+		if err != nil {
+			utils.LogError(logger, err, "failed to close the http response body")
+		}
+	}()
+
+	if isFiltered(logger, req, opts) {
+		logger.Debug("The websocket is a filtered request")
+		return nil
+	}
+
+	return &models.TestCase{
+		Version: models.GetVersion(),
+		Name:    pkg.ToYamlHTTPHeader(req.Header)["Keploy-Test-Name"],
+		Kind:    models.WebSocket,
+		Created: time.Now().Unix(),
+		HTTPReq: models.HTTPReq{
+			Method:     models.Method(req.Method),
+			ProtoMajor: req.ProtoMajor,
+			ProtoMinor: req.ProtoMinor,
+			URL:        fmt.Sprintf("http://%s%s", req.Host, req.URL.RequestURI()),
+			Header:     pkg.ToYamlHTTPHeader(req.Header),
+			URLParams:  pkg.URLParams(req),
+			Timestamp:  s.reqTimestamp,
+		},
+		HTTPResp: models.HTTPResp{
+			StatusCode:    resp.StatusCode,
+			Header:        pkg.ToYamlHTTPHeader(resp.Header),
+			Timestamp:     s.last,
+			StatusMessage: http.StatusText(resp.StatusCode),
+		},
+		WebSocketFrames: s.frames,
+		Noise:           map[string][]string{},
+	}
+}
@@ -0,0 +1,239 @@
+//go:build linux
+
+package conn
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// The requests and the responses on a kept-alive conn are told apart by their framing, as their data arrives:
+// the headers end with an empty line, the bodies end after the bytes of their Content-Length or after their last
+// chunk, the responses without either end with the conn. The interim responses are kept in front of the final
+// response, the responses to the HEAD requests and the 204 and 304 responses have no body.
+// See: https://www.rfc-editor.org/rfc/rfc9112#section-6.3
+
+type frameState int
+
+const (
+	frameHeaders frameState = iota
+	frameBody
+	frameChunkSize
+	frameChunkData
+	frameTrailer
+	frameUntilClose
+	frameUpgraded
+)
+
+// maxLineSize bounds the headers, the chunk size lines and the trailers, the data isn't http past it.
+const maxLineSize = 1 << 20
+
+// framer finds the ends of the http messages sent in one direction of a conn.
+type framer struct {
+	response bool
+	state    frameState
+	// line is the part of the headers, of the chunk size line or of the trailer section received so far
+	line []byte
+	// remaining is the number of bytes left in the body or in the chunk, with its CRLF
+	remaining int64
+	// methods are the methods of the requests framed and not answered yet
+	methods []string
+	// requests is the framer of the requests the responses answer
+	requests *framer
+}
+
+// newFramers returns the framers of the requests and of the responses of a conn.
+func newFramers() (*framer, *framer) {
+	requests := &framer{}
+	return requests, &framer{response: true, requests: requests}
+}
+
+// method returns the method of the request the response answers.
+func (f *framer) method() string {
+	if len(f.requests.methods) == 0 {
+		return ""
+	}
+	return f.requests.methods[0]
+}
+
+// ended records the end of a message, the request of a response is answered with it.
+func (f *framer) ended(ends []int, pos int) []int {
+	if f.response && len(f.requests.methods) > 0 {
+		f.requests.methods = f.requests.methods[1:]
+	}
+	return append(ends, pos)
+}
+
+// feed consumes the data of the direction and returns the offsets in the data at which messages end.
+func (f *framer) feed(data []byte) ([]int, error) {
+	var ends []int
+	pos := 0
+	for pos < len(data) {
+		switch f.state {
+		case frameHeaders:
+			// the empty lines before a request or a response are skipped
+			if len(f.line) == 0 && bytes.HasPrefix(data[pos:], []byte("\r\n")) {
+				pos += 2
+				continue
+			}
+			n, ok, err := f.scan(data[pos:], "\r\n\r\n")
+			pos += n
+			if err != nil || !ok {
+				return ends, err
+			}
+			end, err := f.head()
+			if err != nil {
+				return ends, err
+			}
+			if end {
+				ends = f.ended(ends, pos)
+			}
+		case frameBody:
+			n := int64(len(data) - pos)
+			if n > f.remaining {
+				n = f.remaining
+			}
+			pos += int(n)
+			f.remaining -= n
+			if f.remaining == 0 {
+				f.state = frameHeaders
+				ends = f.ended(ends, pos)
+			}
+		case frameChunkSize:
+			n, ok, err := f.scan(data[pos:], "\r\n")
+			pos += n
+			if err != nil || !ok {
+				return ends, err
+			}
+			sizeField, _, _ := strings.Cut(string(f.line[:len(f.line)-2]), ";")
+			size, err := strconv.ParseInt(strings.TrimSpace(sizeField), 16, 64)
+			if err != nil || size < 0 {
+				return ends, fmt.Errorf("invalid chunk size %q", sizeField)
+			}
+			f.line = f.line[:0]
+			if size == 0 {
+				// the trailer section ends with an empty line, which follows the last chunk when there is no trailer
+				f.line = append(f.line, "\r\n"...)
+				f.state = frameTrailer
+				break
+			}
+			f.remaining, f.state = size+2, frameChunkData
+		case frameChunkData:
+			n := int64(len(data) - pos)
+			if n > f.remaining {
+				n = f.remaining
+			}
+			pos += int(n)
+			f.remaining -= n
+			if f.remaining == 0 {
+				f.state = frameChunkSize
+			}
+		case frameTrailer:
+			n, ok, err := f.scan(data[pos:], "\r\n\r\n")
+			pos += n
+			if err != nil {
+				return ends, err
+			}
+			if ok {
+				f.line = f.line[:0]
+				f.state = frameHeaders
+				ends = f.ended(ends, pos)
+			}
+		case frameUntilClose, frameUpgraded:
+			pos = len(data)
+		}
+	}
+	return ends, nil
+}
+
+// scan appends the data to the line up to the end of the separator, it returns the number of bytes of the data
+// it consumed and whether the separator was found.
+func (f *framer) scan(data []byte, sep string) (int, bool, error) {
+	prev := len(f.line)
+	from := prev - len(sep) + 1
+	if from < 0 {
+		from = 0
+	}
+	f.line = append(f.line, data...)
+	i := bytes.Index(f.line[from:], []byte(sep))
+	if i == -1 {
+		if len(f.line) > maxLineSize {
+			return len(data), false, fmt.Errorf("no end of line in %d bytes", len(f.line))
+		}
+		return len(data), false, nil
+	}
+	f.line = f.line[:from+i+len(sep)]
+	return len(f.line) - prev, true, nil
+}
+
+// head frames the message of the complete headers in the line, it reports whether the message ends with them.
+func (f *framer) head() (bool, error) {
+	lines := strings.Split(string(f.line[:len(f.line)-4]), "\r\n")
+	f.line = f.line[:0]
+	var contentLength string
+	var chunked bool
+	for _, line := range lines[1:] {
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		switch http.CanonicalHeaderKey(strings.TrimSpace(name)) {
+		case "Content-Length":
+			contentLength = strings.TrimSpace(value)
+		case "Transfer-Encoding":
+			// the chunked coding is the last one applied to the body
+			codings := strings.Split(value, ",")
+			chunked = strings.EqualFold(strings.TrimSpace(codings[len(codings)-1]), "chunked")
+		}
+	}
+
+	fields := strings.Fields(lines[0])
+	if !f.response {
+		if len(fields) != 3 || !strings.HasPrefix(fields[2], "HTTP/1.") {
+			return false, fmt.Errorf("invalid request line %q", lines[0])
+		}
+		f.methods = append(f.methods, fields[0])
+	} else {
+		if len(fields) < 2 || !strings.HasPrefix(fields[0], "HTTP/1.") {
+			return false, fmt.Errorf("invalid status line %q", lines[0])
+		}
+		code, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return false, fmt.Errorf("invalid status line %q", lines[0])
+		}
+		switch {
+		case code == http.StatusSwitchingProtocols:
+			// the conn doesn't carry http anymore
+			f.state = frameUpgraded
+			return true, nil
+		case code >= 100 && code < 200:
+			// the interim responses are followed by the final one
+			return false, nil
+		case code == http.StatusNoContent || code == http.StatusNotModified || f.method() == http.MethodHead:
+			return true, nil
+		}
+	}
+
+	switch {
+	case chunked:
+		f.state = frameChunkSize
+		return false, nil
+	case contentLength != "":
+		n, err := strconv.ParseInt(contentLength, 10, 64)
+		if err != nil || n < 0 {
+			return false, fmt.Errorf("invalid content length %q", contentLength)
+		}
+		if n == 0 {
+			return true, nil
+		}
+		f.remaining, f.state = n, frameBody
+		return false, nil
+	case f.response:
+		f.state = frameUntilClose
+		return false, nil
+	}
+	return true, nil
+}
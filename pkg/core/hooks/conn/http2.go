@@ -0,0 +1,303 @@
+//go:build linux
+
+package conn
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
+
+	"go.keploy.io/server/v2/pkg"
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+// The gRPC clients send their calls over HTTP/2 with prior knowledge: the conn starts with the client preface and
+// carries the calls as streams of frames. The frames of both directions are read in order, the header blocks are
+// compressed with the state left by the previous ones of their direction, so a conn whose data was lost can't be
+// read anymore.
+// See: https://www.rfc-editor.org/rfc/rfc9113 and https://github.com/grpc/grpc/blob/master/doc/PROTOCOL-HTTP2.md
+
+const (
+	// h2FrameHeaderSize is the size of the header of the HTTP/2 frames.
+	h2FrameHeaderSize = 9
+	// h2MaxFrameSize bounds the frames read, it's the largest size the peers can agree on.
+	h2MaxFrameSize = 1<<24 - 1
+)
+
+// h2Preface is the preface the HTTP/2 conns start with.
+var h2Preface = []byte(http2.ClientPreface)
+
+// grpcCall is a gRPC call which ended, with the times the request started and the response ended.
+type grpcCall struct {
+	req          models.GrpcReq
+	resp         models.GrpcResp
+	reqTimestamp time.Time
+	resTimestamp time.Time
+}
+
+// h2Stream is a stream of the conn which didn't end yet.
+type h2Stream struct {
+	call models.GrpcStream
+	// grpc is set when the request is a gRPC call, the other streams are read but not captured
+	grpc bool
+	// reqMsg and respMsg are the parts of the messages which aren't complete yet
+	reqMsg  []byte
+	respMsg []byte
+	// responded is set once the response headers are read, the next header block is the trailers
+	responded    bool
+	reqTimestamp time.Time
+}
+
+// h2Direction is the state of one direction of the conn.
+type h2Direction struct {
+	// buf is the part of the next frame received so far
+	buf     []byte
+	decoder *hpack.Decoder
+	// block is the header block continued in CONTINUATION frames, of the stream blockID
+	block     []byte
+	blockID   uint32
+	blockEnds bool
+	// promised is set when the block is the one of a promised request
+	promised bool
+}
+
+// h2Capture reads the gRPC calls of an HTTP/2 conn from its frames.
+type h2Capture struct {
+	// prefaced is set once the client preface is read
+	prefaced bool
+	requests h2Direction
+	response h2Direction
+	streams  map[uint32]*h2Stream
+}
+
+func newH2Capture() *h2Capture {
+	return &h2Capture{
+		requests: h2Direction{decoder: hpack.NewDecoder(4096, nil)},
+		response: h2Direction{decoder: hpack.NewDecoder(4096, nil)},
+		streams:  make(map[uint32]*h2Stream),
+	}
+}
+
+// feed reads the frames of the data of the direction and returns the gRPC calls which ended with them.
+func (c *h2Capture) feed(data []byte, response bool, timestamp time.Time) ([]grpcCall, error) {
+	d := &c.requests
+	if response {
+		d = &c.response
+	}
+	d.buf = append(d.buf, data...)
+	if !response && !c.prefaced {
+		if len(d.buf) < len(h2Preface) {
+			return nil, nil
+		}
+		if !bytes.HasPrefix(d.buf, h2Preface) {
+			return nil, fmt.Errorf("invalid HTTP/2 client preface")
+		}
+		d.buf = d.buf[len(h2Preface):]
+		c.prefaced = true
+	}
+
+	var calls []grpcCall
+	for len(d.buf) >= h2FrameHeaderSize {
+		length := int(d.buf[0])<<16 | int(d.buf[1])<<8 | int(d.buf[2])
+		if length > h2MaxFrameSize {
+			return calls, fmt.Errorf("invalid HTTP/2 frame size %d", length)
+		}
+		if len(d.buf) < h2FrameHeaderSize+length {
+			break
+		}
+		frameType := http2.FrameType(d.buf[3])
+		flags := http2.Flags(d.buf[4])
+		streamID := binary.BigEndian.Uint32(d.buf[5:9]) & (1<<31 - 1)
+		payload := d.buf[h2FrameHeaderSize : h2FrameHeaderSize+length]
+		call, err := c.frame(d, response, frameType, flags, streamID, payload, timestamp)
+		if err != nil {
+			return calls, err
+		}
+		if call != nil {
+			calls = append(calls, *call)
+		}
+		d.buf = d.buf[h2FrameHeaderSize+length:]
+	}
+	// the next frame is kept apart from the frames read
+	d.buf = append([]byte{}, d.buf...)
+	return calls, nil
+}
+
+// frame reads a frame of the direction, it returns the gRPC call the frame ended.
+func (c *h2Capture) frame(d *h2Direction, response bool, frameType http2.FrameType, flags http2.Flags, streamID uint32, payload []byte, timestamp time.Time) (*grpcCall, error) {
+	if d.block != nil && frameType != http2.FrameContinuation {
+		return nil, fmt.Errorf("the header block of the stream %d isn't continued", d.blockID)
+	}
+	switch frameType {
+	case http2.FrameHeaders:
+		payload, err := unpad(payload, flags)
+		if err != nil {
+			return nil, err
+		}
+		if flags.Has(http2.FlagHeadersPriority) {
+			if len(payload) < 5 {
+				return nil, fmt.Errorf("invalid HTTP/2 headers frame")
+			}
+			payload = payload[5:]
+		}
+		d.block = append([]byte{}, payload...)
+		d.blockID, d.blockEnds, d.promised = streamID, flags.Has(http2.FlagHeadersEndStream), false
+		if !flags.Has(http2.FlagHeadersEndHeaders) {
+			return nil, nil
+		}
+		return c.headers(d, response, timestamp)
+	case http2.FrameContinuation:
+		if d.block == nil || d.blockID != streamID {
+			return nil, fmt.Errorf("unexpected HTTP/2 continuation frame")
+		}
+		d.block = append(d.block, payload...)
+		if !flags.Has(http2.FlagContinuationEndHeaders) {
+			return nil, nil
+		}
+		if d.promised {
+			_, err := d.decoder.DecodeFull(d.block)
+			d.block = nil
+			return nil, err
+		}
+		return c.headers(d, response, timestamp)
+	case http2.FramePushPromise:
+		// the promised requests aren't captured, their header block is decoded to keep the state of the decoder
+		payload, err := unpad(payload, flags)
+		if err != nil || len(payload) < 4 {
+			return nil, fmt.Errorf("invalid HTTP/2 push promise frame")
+		}
+		d.block = append([]byte{}, payload[4:]...)
+		d.blockID, d.blockEnds, d.promised = streamID, false, true
+		if !flags.Has(http2.FlagPushPromiseEndHeaders) {
+			return nil, nil
+		}
+		_, err = d.decoder.DecodeFull(d.block)
+		d.block = nil
+		return nil, err
+	case http2.FrameData:
+		payload, err := unpad(payload, flags)
+		if err != nil {
+			return nil, err
+		}
+		return c.data(streamID, response, payload, flags.Has(http2.FlagDataEndStream), timestamp), nil
+	case http2.FrameRSTStream:
+		delete(c.streams, streamID)
+	case http2.FrameSettings:
+		if flags.Has(http2.FlagSettingsAck) {
+			return nil, nil
+		}
+		// the table size is the one the decoder of the peer allows the encoder of this direction
+		peer := &c.response
+		if response {
+			peer = &c.requests
+		}
+		for i := 0; i+6 <= len(payload); i += 6 {
+			if http2.SettingID(binary.BigEndian.Uint16(payload[i:])) == http2.SettingHeaderTableSize {
+				peer.decoder.SetAllowedMaxDynamicTableSize(binary.BigEndian.Uint32(payload[i+2:]))
+			}
+		}
+	}
+	return nil, nil
+}
+
+// headers reads the complete header block of the direction.
+func (c *h2Capture) headers(d *h2Direction, response bool, timestamp time.Time) (*grpcCall, error) {
+	fields, err := d.decoder.DecodeFull(d.block)
+	streamID, ends := d.blockID, d.blockEnds
+	d.block = nil
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode the HTTP/2 header block: %w", err)
+	}
+
+	if !response {
+		if _, ok := c.streams[streamID]; ok {
+			// the trailers of the requests aren't captured
+			return nil, nil
+		}
+		s := &h2Stream{call: models.NewGrpcStream(streamID), reqTimestamp: timestamp}
+		addHeaders(s.call.GrpcReq.Headers, fields)
+		s.grpc = strings.HasPrefix(s.call.GrpcReq.Headers.OrdinaryHeaders["content-type"], "application/grpc")
+		c.streams[streamID] = s
+		return nil, nil
+	}
+
+	s, ok := c.streams[streamID]
+	if !ok {
+		return nil, nil
+	}
+	// the trailers end the response, the calls failed right away are answered with the trailers only
+	if s.responded || ends {
+		addHeaders(s.call.GrpcResp.Trailers, fields)
+	} else {
+		addHeaders(s.call.GrpcResp.Headers, fields)
+	}
+	s.responded = true
+	if !ends {
+		return nil, nil
+	}
+	return c.end(streamID, timestamp), nil
+}
+
+// data reads the messages of a DATA frame.
+func (c *h2Capture) data(streamID uint32, response bool, payload []byte, ends bool, timestamp time.Time) *grpcCall {
+	s, ok := c.streams[streamID]
+	if !ok {
+		return nil
+	}
+	var msgs []models.GrpcLengthPrefixedMessage
+	if response {
+		msgs, s.respMsg = pkg.SplitGrpcMessages(append(s.respMsg, payload...))
+		s.call.GrpcResp.Messages = append(s.call.GrpcResp.Messages, msgs...)
+	} else {
+		msgs, s.reqMsg = pkg.SplitGrpcMessages(append(s.reqMsg, payload...))
+		s.call.GrpcReq.Messages = append(s.call.GrpcReq.Messages, msgs...)
+	}
+	if response && ends {
+		return c.end(streamID, timestamp)
+	}
+	return nil
+}
+
+// end ends the stream once the server ended its response, the client can't send anything on it anymore.
+func (c *h2Capture) end(streamID uint32, timestamp time.Time) *grpcCall {
+	s := c.streams[streamID]
+	delete(c.streams, streamID)
+	if !s.grpc {
+		return nil
+	}
+	call := &grpcCall{req: s.call.GrpcReq, resp: s.call.GrpcResp, reqTimestamp: s.reqTimestamp, resTimestamp: timestamp}
+	if len(call.req.Messages) > 0 {
+		call.req.Body = call.req.Messages[0]
+	}
+	if len(call.resp.Messages) > 0 {
+		call.resp.Body = call.resp.Messages[0]
+	}
+	return call
+}
+
+// unpad removes the padding of the frame.
+func unpad(payload []byte, flags http2.Flags) ([]byte, error) {
+	if !flags.Has(http2.FlagDataPadded) {
+		return payload, nil
+	}
+	if len(payload) == 0 || int(payload[0]) >= len(payload) {
+		return nil, fmt.Errorf("invalid HTTP/2 frame padding")
+	}
+	return payload[1 : len(payload)-int(payload[0])], nil
+}
+
+// addHeaders adds the header fields to the headers.
+func addHeaders(headers models.GrpcHeaders, fields []hpack.HeaderField) {
+	for _, field := range fields {
+		if field.IsPseudo() {
+			headers.PseudoHeaders[field.Name] = field.Value
+		} else {
+			headers.OrdinaryHeaders[field.Name] = field.Value
+		}
+	}
+}
@@ -9,7 +9,6 @@ import (
 	"errors"
 	"fmt"
 	"os"
-	"time"
 	"unsafe"
 
 	"golang.org/x/sync/errgroup"
@@ -25,35 +24,30 @@ import (
 
 var eventAttributesSize = int(unsafe.Sizeof(SocketDataEvent{}))
 
+// defaultTestCaseBuffer is the number of captured test cases which can wait to be saved.
+const defaultTestCaseBuffer = 500
+
 // ListenSocket starts the socket event listeners
 func ListenSocket(ctx context.Context, l *zap.Logger, openMap, dataMap, closeMap *ebpf.Map, opts models.IncomingOptions) (<-chan *models.TestCase, error) {
-	t := make(chan *models.TestCase, 500)
+	testCaseBuffer := int(opts.Capture.TestCaseBuffer)
+	if testCaseBuffer == 0 {
+		testCaseBuffer = defaultTestCaseBuffer
+	}
+	t := make(chan *models.TestCase, testCaseBuffer)
 	err := initRealTimeOffset()
 	if err != nil {
 		utils.LogError(l, err, "failed to initialize real time offset")
 		return nil, errors.New("failed to start socket listeners")
 	}
-	c := NewFactory(time.Minute, l)
+	c := NewFactory(opts.Capture, l)
 	g, ok := ctx.Value(models.ErrGroupKey).(*errgroup.Group)
 	if !ok {
 		return nil, errors.New("failed to get the error group from the context")
 	}
 	g.Go(func() error {
 		defer utils.Recover(l)
-		go func() {
-			defer utils.Recover(l)
-			for {
-				select {
-				case <-ctx.Done():
-					return
-				default:
-					// TODO refactor this to directly consume the events from the maps
-					c.ProcessActiveTrackers(ctx, t, opts)
-					time.Sleep(100 * time.Millisecond)
-				}
-			}
-		}()
-		<-ctx.Done()
+		// the factory is the only sender on the channel, so it's closed once the factory stops
+		c.Run(ctx, t, opts)
 		close(t)
 		return nil
 	})
@@ -73,6 +67,12 @@ func ListenSocket(ctx context.Context, l *zap.Logger, openMap, dataMap, closeMap
 		utils.LogError(l, err, "failed to start close socket listener")
 		return nil, errors.New("failed to start socket listeners")
 	}
+	if len(opts.TLSIngress) > 0 {
+		err = listenTLS(ctx, l, c, opts.TLSIngress)
+		if err != nil {
+			return nil, errors.New("failed to start the tls ingress listeners")
+		}
+	}
 	return t, err
 }
 
@@ -115,7 +115,7 @@ func open(ctx context.Context, c *Factory, l *zap.Logger, m *ebpf.Map) error {
 				}
 
 				event.TimestampNano += getRealTimeOffset()
-				c.GetOrCreate(event.ConnID).AddOpenEvent(event)
+				c.AddOpenEvent(event)
 			}
 		}()
 		<-ctx.Done() // Check for context cancellation
@@ -176,7 +176,7 @@ func data(ctx context.Context, c *Factory, l *zap.Logger, m *ebpf.Map) error {
 					l.Debug(fmt.Sprintf("Request EntryTimestamp :%v\n", convertUnixNanoToTime(event.EntryTimestampNano)))
 				}
 
-				c.GetOrCreate(event.ConnID).AddDataEvent(event)
+				c.AddDataEvent(event)
 			}
 		}()
 		<-ctx.Done() // Check for context cancellation
@@ -227,7 +227,7 @@ func exit(ctx context.Context, c *Factory, l *zap.Logger, m *ebpf.Map) error {
 				}
 
 				event.TimestampNano += getRealTimeOffset()
-				c.GetOrCreate(event.ConnID).AddCloseEvent(event)
+				c.AddCloseEvent(event)
 			}
 		}()
 
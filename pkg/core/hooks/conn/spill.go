@@ -0,0 +1,94 @@
+//go:build linux
+
+package conn
+
+import (
+	"bytes"
+	"io"
+	"os"
+)
+
+// spillThreshold is the size after which a payload is moved out of memory into a temporary file.
+const spillThreshold = 1 << 20
+
+// payload accumulates the data of a request or a response. The data is kept in memory until it grows past
+// spillThreshold, and is streamed to a temporary file after that, so that the large uploads and downloads
+// don't stay in memory while their conns are tracked.
+type payload struct {
+	mem  []byte
+	file *os.File
+	size int
+}
+
+func newPayload() *payload {
+	return &payload{}
+}
+
+// Write appends the data to the payload, it falls back to memory if the temporary file can't be written.
+func (p *payload) Write(data []byte) {
+	if p.file == nil && len(p.mem)+len(data) > spillThreshold {
+		p.spill()
+	}
+	if p.file != nil {
+		if _, err := p.file.Write(data); err == nil {
+			p.size += len(data)
+			return
+		}
+		// move what was written so far back to memory
+		mem, err := p.Bytes()
+		p.Close()
+		if err != nil {
+			mem = nil
+		}
+		p.mem, p.size = mem, len(mem)
+	}
+	p.mem = append(p.mem, data...)
+	p.size += len(data)
+}
+
+// Bytes returns the whole payload, reading it back from the temporary file if it was spilled.
+func (p *payload) Bytes() ([]byte, error) {
+	if p.file == nil {
+		return p.mem, nil
+	}
+	data := make([]byte, p.size)
+	if _, err := p.file.ReadAt(data, 0); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return data, nil
+}
+
+// Reader returns a reader of the whole payload, which reads it from the temporary file if it was spilled rather
+// than reading it back into memory.
+func (p *payload) Reader() io.Reader {
+	if p.file == nil {
+		return bytes.NewReader(p.mem)
+	}
+	return io.NewSectionReader(p.file, 0, int64(p.size))
+}
+
+// Close releases the temporary file of the payload, if any.
+func (p *payload) Close() {
+	if p.file == nil {
+		return
+	}
+	name := p.file.Name()
+	_ = p.file.Close()
+	_ = os.Remove(name)
+	p.file = nil
+}
+
+// spill moves the payload to a temporary file, the payload stays in memory if the file can't be created.
+func (p *payload) spill() {
+	file, err := os.CreateTemp("", "keploy-payload-*")
+	if err != nil {
+		return
+	}
+	if _, err := file.Write(p.mem); err != nil {
+		_ = file.Close()
+		_ = os.Remove(file.Name())
+		return
+	}
+	p.file = file
+	p.mem = nil
+}
@@ -0,0 +1,228 @@
+//go:build linux
+
+package conn
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/url"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+
+	"go.keploy.io/server/v2/config"
+	"go.keploy.io/server/v2/pkg/core/proxy"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+)
+
+// The calls to the apps which serve https themselves are encrypted on their sockets. Such an app is recorded through
+// a listener of keploy, which terminates the tls of the clients with a certificate signed by the keploy CA and
+// forwards their calls to the app over tls. The plain text of the conns is captured as the data events of conns of
+// their own, the way the kernel would report them.
+
+// tlsDialTimeout bounds the dial and the handshake of the upstream of a conn.
+const tlsDialTimeout = 10 * time.Second
+
+// tlsConnSeq numbers the conns of the tls ingress, their ids don't collide with the ones of the sockets.
+var tlsConnSeq uint64
+
+// listenTLS starts the listeners of the tls ingress, they are closed once the context is done.
+func listenTLS(ctx context.Context, logger *zap.Logger, factory *Factory, ingress []config.TLSIngress) error {
+	g, ok := ctx.Value(models.ErrGroupKey).(*errgroup.Group)
+	if !ok {
+		return errors.New("failed to get the error group from the context")
+	}
+	for _, in := range ingress {
+		var lc net.ListenConfig
+		l, err := lc.Listen(ctx, "tcp", in.Listen)
+		if err != nil {
+			utils.LogError(logger, err, "failed to listen for the https calls", zap.String("listen", in.Listen))
+			return err
+		}
+		logger.Info("recording the https calls of the app", zap.String("listen", in.Listen), zap.String("upstream", in.Upstream))
+
+		g.Go(func() error {
+			defer utils.Recover(logger)
+			go func() {
+				defer utils.Recover(logger)
+				for {
+					client, err := l.Accept()
+					if err != nil {
+						if errors.Is(err, net.ErrClosed) {
+							return
+						}
+						utils.LogError(logger, err, "failed to accept the https conn")
+						continue
+					}
+					go func() {
+						defer utils.Recover(logger)
+						terminate(ctx, logger, factory, client, in.Upstream)
+					}()
+				}
+			}()
+			<-ctx.Done()
+			if err := l.Close(); err != nil {
+				utils.LogError(logger, err, "failed to close the https listener")
+			}
+			return nil
+		})
+	}
+	return nil
+}
+
+// terminate serves the tls of the client and forwards its conn to the upstream over tls. The upstream is dialed with
+// the server name and the application protocols (ALPN) of the client, and the client is offered the protocol the
+// upstream agreed to, so that the HTTP/2 clients stay on HTTP/2.
+func terminate(ctx context.Context, logger *zap.Logger, factory *Factory, client net.Conn, upstream string) {
+	var server *tls.Conn
+	tlsClient := tls.Server(client, &tls.Config{
+		GetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			host := hello.ServerName
+			if host == "" {
+				host, _, _ = net.SplitHostPort(upstream)
+			}
+			if host == "" {
+				host = "localhost"
+			}
+			dialer := tls.Dialer{
+				NetDialer: &net.Dialer{Timeout: tlsDialTimeout},
+				Config:    &tls.Config{InsecureSkipVerify: true, ServerName: host, NextProtos: hello.SupportedProtos},
+			}
+			conn, err := dialer.DialContext(ctx, "tcp", upstream)
+			if err != nil {
+				return nil, err
+			}
+			server = conn.(*tls.Conn)
+			cert, err := proxy.SignCert(host)
+			if err != nil {
+				return nil, err
+			}
+			cfg := &tls.Config{Certificates: []tls.Certificate{*cert}}
+			if protocol := server.ConnectionState().NegotiatedProtocol; protocol != "" {
+				cfg.NextProtos = []string{protocol}
+			}
+			return cfg, nil
+		},
+	})
+	defer func() {
+		if err := tlsClient.Close(); err != nil {
+			logger.Debug("failed to close the https conn of the client", zap.Error(err))
+		}
+		if server == nil {
+			return
+		}
+		if err := server.Close(); err != nil {
+			logger.Debug("failed to close the https conn of the app", zap.Error(err))
+		}
+	}()
+
+	handshakeCtx, cancel := context.WithTimeout(ctx, tlsDialTimeout)
+	err := tlsClient.HandshakeContext(handshakeCtx)
+	cancel()
+	if err != nil {
+		utils.LogError(logger, err, "failed to terminate the tls of the https client", zap.String("upstream", upstream))
+		return
+	}
+
+	feed := &tlsFeed{
+		factory: factory,
+		id:      ID{TsID: atomic.AddUint64(&tlsConnSeq, 1), FD: -1, TGID: uint32(os.Getpid())},
+	}
+	if tracker := factory.GetOrCreate(feed.id); tracker != nil {
+		tracker.mutex.Lock()
+		tracker.upstream = upstream
+		tracker.mutex.Unlock()
+	}
+
+	// the conns are closed as soon as either peer is done with its conn
+	done := make(chan struct{}, 2)
+	pipe := func(dst, src net.Conn, direction TrafficDirectionEnum) {
+		defer utils.Recover(logger)
+		defer func() { done <- struct{}{} }()
+		buf := make([]byte, EventBodyMaxSize)
+		for {
+			n, err := src.Read(buf)
+			if n > 0 {
+				// the data is captured before it's forwarded, so that the answer to it is captured after it
+				feed.add(direction, buf[:n])
+				if _, err := dst.Write(buf[:n]); err != nil {
+					return
+				}
+			}
+			if err != nil {
+				return
+			}
+		}
+	}
+	go pipe(server, tlsClient, IngressTraffic)
+	go pipe(tlsClient, server, EgressTraffic)
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+	factory.AddCloseEvent(SocketCloseEvent{TimestampNano: uint64(time.Now().UnixNano()), ConnID: feed.id})
+}
+
+// tlsFeed adds the plain text of a terminated conn to the factory as the data events of a conn of its own.
+type tlsFeed struct {
+	factory *Factory
+	id      ID
+
+	mutex sync.Mutex
+	// started, direction and run track the data sent in a direction until the switch, which the tracker verifies
+	started   bool
+	direction TrafficDirectionEnum
+	run       int64
+}
+
+// add adds the data sent in the direction, the data is at most the size of an event.
+func (f *tlsFeed) add(direction TrafficDirectionEnum, data []byte) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	now := uint64(time.Now().UnixNano())
+	event := SocketDataEvent{
+		EntryTimestampNano: now,
+		TimestampNano:      now,
+		ConnID:             f.id,
+		Direction:          direction,
+		MsgSize:            uint32(len(data)),
+	}
+	copy(event.Msg[:], data)
+	if f.started && direction != f.direction {
+		if direction == EgressTraffic {
+			event.ValidateReadBytes = f.run
+		} else {
+			event.ValidateWrittenBytes = f.run
+		}
+		f.run = 0
+	}
+	f.started, f.direction = true, direction
+	f.run += int64(len(data))
+	f.factory.AddDataEvent(event)
+}
+
+// overTLS makes the test case a call to the upstream over https, the clients called keploy in place of the app. The
+// host the clients called is kept in the Host header.
+func overTLS(logger *zap.Logger, tc *models.TestCase, upstream string) *models.TestCase {
+	if tc == nil {
+		return nil
+	}
+	u, err := url.Parse(tc.HTTPReq.URL)
+	if err != nil {
+		utils.LogError(logger, err, "failed to parse the url of the https call")
+		return nil
+	}
+	u.Scheme, u.Host = "https", upstream
+	if host, port, err := net.SplitHostPort(upstream); err == nil && host == "" {
+		u.Host = net.JoinHostPort("localhost", port)
+	}
+	tc.HTTPReq.URL = u.String()
+	return tc
+}
@@ -3,13 +3,12 @@
 package conn
 
 import (
+	"bytes"
 	"fmt"
-	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
-	"go.keploy.io/server/v2/pkg/models"
 	"go.keploy.io/server/v2/utils"
 	"go.uber.org/zap"
 	// "log"
@@ -25,71 +24,102 @@ type Tracker struct {
 	// Indicates the tracker stopped tracking due to closing the session.
 	lastActivityTimestamp uint64
 
-	// Queues to handle multiple ingress traffic on the same conn (keep-alive)
-
-	// kernelRespSizes is a slice of the total number of Response bytes received in the kernel side
-	kernelRespSizes []uint64
-
-	// kernelReqSizes is a slice of the total number of Request bytes received in the kernel side
-	kernelReqSizes []uint64
-
-	// userRespSizes is a slice of the total number of Response bytes received in the user side
-	userRespSizes []uint64
-
-	// userReqSizes is a slice of the total number of Request bytes received in the user side
-	userReqSizes []uint64
-	// userRespBufs is a slice of the Response data received in the user side on this conn
-	userResps [][]byte
-	// userReqBufs is a slice of the Request data received in the user side on this conn
-	userReqs [][]byte
-
-	// req and resp are the buffers to store the request and response data for the current request
-	// reset after 2 seconds of inactivity
-	respSize uint64
-	reqSize  uint64
-	resp     []byte
-	req      []byte
-
-	// Additional fields to know when to capture request or response info
-	// reset after 2 seconds of inactivity
-	lastChunkWasResp bool
-	lastChunkWasReq  bool
-	recTestCounter   int32 //atomic counter
-	// firstRequest is used to indicate if the current request is the first request on the conn
-	// reset after 2 seconds of inactivity
-	// Note: This is used to handle multiple requests on the same conn (keep-alive)
-	// Its different from isNewRequest which is used to indicate if the current request chunk is the first chunk of the request
-	firstRequest bool
+	// req and resp are the request and the response received so far, up to the end of their messages
+	req  *payload
+	resp *payload
+	// reqFramer and respFramer find the ends of the requests and of the responses on the conn (keep-alive)
+	reqFramer  *framer
+	respFramer *framer
+	// reqTimestamp is when the current request started
+	reqTimestamp time.Time
+
+	// requests and responses are the messages which ended and aren't paired yet, the responses answer the
+	// requests in order
+	requests  []message
+	responses []message
+	// exchanges are the requests paired with their responses, to capture
+	exchanges      []exchange
+	recTestCounter int32 //atomic counter
+
+	// started is set once the conn sent data, direction is the direction of its last data and runSize the size
+	// of the data sent in that direction since the last switch, which the kernel counts too
+	started   bool
+	direction TrafficDirectionEnum
+	runSize   uint64
+	// lost is set when data of the conn was lost, its messages are framed again from its next request
+	lost bool
+	// unframed is set once the conn doesn't carry http/1 messages, its data is dropped
+	unframed bool
+	// requested is set once the client sent data, the data the server sends first isn't a response
+	requested bool
+	// h2 reads the gRPC calls of the conn when it starts with the HTTP/2 preface, calls are the ones which ended
+	// and aren't captured yet
+	h2    *h2Capture
+	calls []grpcCall
+	// ws reads the frames of the conn once it's upgraded to a websocket, sessions are the websockets which ended
+	// and aren't captured yet
+	ws       *wsSession
+	sessions []*wsSession
+	// upstream is set for the conns of the tls ingress, to the app their calls are forwarded to over https
+	upstream string
 
 	mutex  sync.RWMutex
 	logger *zap.Logger
+}
+
+// message is a request or a response which ended.
+type message struct {
+	data      *payload
+	timestamp time.Time
+}
 
-	reqTimestamps []time.Time
-	isNewRequest  bool
+// exchange is a request and its response.
+type exchange struct {
+	req  message
+	resp message
+}
+
+// close releases the payloads of the exchange.
+func (e exchange) close() {
+	e.req.data.Close()
+	e.resp.data.Close()
 }
 
 func NewTracker(connID ID, logger *zap.Logger) *Tracker {
+	reqFramer, respFramer := newFramers()
 	return &Tracker{
-		connID:          connID,
-		req:             []byte{},
-		resp:            []byte{},
-		kernelRespSizes: []uint64{},
-		kernelReqSizes:  []uint64{},
-		userRespSizes:   []uint64{},
-		userReqSizes:    []uint64{},
-		userResps:       [][]byte{},
-		userReqs:        [][]byte{},
-		mutex:           sync.RWMutex{},
-		logger:          logger,
-		firstRequest:    true,
-		isNewRequest:    true,
+		reqFramer:  reqFramer,
+		respFramer: respFramer,
+		connID:     connID,
+		req:        newPayload(),
+		resp:       newPayload(),
+		mutex:      sync.RWMutex{},
+		logger:     logger,
 	}
 }
 
-func (conn *Tracker) ToBytes() ([]byte, []byte) {
-	conn.mutex.RLock()
-	defer conn.mutex.RUnlock()
-	return conn.req, conn.resp
+// bytes reads the whole payload, the payload is dropped if it can't be read back from its temporary file.
+func (conn *Tracker) bytes(p *payload) []byte {
+	data, err := p.Bytes()
+	if err != nil {
+		utils.LogError(conn.logger, err, "failed to read the payload back from the disk")
+		return []byte{}
+	}
+	return data
+}
+
+// close releases the payloads of the conn which weren't captured.
+func (conn *Tracker) close() {
+	conn.mutex.Lock()
+	defer conn.mutex.Unlock()
+	conn.req.Close()
+	conn.resp.Close()
+	for _, m := range append(conn.requests, conn.responses...) {
+		m.data.Close()
+	}
+	for _, e := range conn.exchanges {
+		e.close()
+	}
 }
 
 func (conn *Tracker) IsInactive(duration time.Duration) bool {
@@ -106,166 +136,108 @@ func (conn *Tracker) decRecordTestCount() {
 	atomic.AddInt32(&conn.recTestCounter, -1)
 }
 
-// IsComplete checks if the current conn has valid request & response info to capture and also returns the request and response data buffer.
-func (conn *Tracker) IsComplete() (bool, []byte, []byte, time.Time, time.Time) {
-	conn.mutex.Lock()
-	defer conn.mutex.Unlock()
-
-	// Get the current timestamp in nanoseconds.
-	currentTimestamp := uint64(time.Now().UnixNano())
-
-	// Calculate the time elapsed since the last activity in nanoseconds.
-	elapsedTime := currentTimestamp - conn.lastActivityTimestamp
-
-	//Caveat: Added a timeout of 4 seconds, after this duration we assume that the last response data event would have come.
-	// This will ensure that we capture the requests responses where Connection:keep-alive is enabled.
-
-	recordTraffic := false
-
-	requestBuf, responseBuf := []byte{}, []byte{}
-
-	var reqTimestamps, respTimestamp time.Time
-
-	//if recTestCounter > 0, it means that we have num(recTestCounter) of request and response present in the queues to record.
-	if conn.recTestCounter > 0 {
-		if (len(conn.userReqSizes) > 0 && len(conn.kernelReqSizes) > 0) &&
-			(len(conn.userRespSizes) > 0 && len(conn.kernelRespSizes) > 0) {
-			validReq, validRes := false, false
-
-			expectedRecvBytes := conn.userReqSizes[0]
-			actualRecvBytes := conn.kernelReqSizes[0]
-
-			if expectedRecvBytes == 0 || actualRecvBytes == 0 {
-				conn.logger.Warn("Malformed request", zap.Any("ExpectedRecvBytes", expectedRecvBytes), zap.Any("ActualRecvBytes", actualRecvBytes))
-			}
-
-			//popping out the current request info
-			conn.userReqSizes = conn.userReqSizes[1:]
-			conn.kernelReqSizes = conn.kernelReqSizes[1:]
-
-			if conn.verifyRequestData(expectedRecvBytes, actualRecvBytes) {
-				validReq = true
-			} else {
-				conn.logger.Debug("Malformed request", zap.Any("ExpectedRecvBytes", expectedRecvBytes), zap.Any("ActualRecvBytes", actualRecvBytes))
-			}
-
-			expectedSentBytes := conn.userRespSizes[0]
-			actualSentBytes := conn.kernelRespSizes[0]
-
-			//popping out the current response info
-			conn.userRespSizes = conn.userRespSizes[1:]
-			conn.kernelRespSizes = conn.kernelRespSizes[1:]
-
-			if conn.verifyResponseData(expectedSentBytes, actualSentBytes) {
-				validRes = true
-				respTimestamp = time.Now()
-			} else {
-				conn.logger.Debug("Malformed response", zap.Any("ExpectedSentBytes", expectedSentBytes), zap.Any("ActualSentBytes", actualSentBytes))
-			}
-
-			if len(conn.userReqs) > 0 && len(conn.userResps) > 0 { //validated request, response
-				requestBuf = conn.userReqs[0]
-				responseBuf = conn.userResps[0]
-
-				//popping out the current request & response data
-				conn.userReqs = conn.userReqs[1:]
-				conn.userResps = conn.userResps[1:]
-			} else {
-				conn.logger.Debug("no data buffer for request or response", zap.Any("Length of RecvBufQueue", len(conn.userReqs)), zap.Any("Length of SentBufQueue", len(conn.userResps)))
-			}
-
-			recordTraffic = validReq && validRes
-		} else {
-			utils.LogError(conn.logger, nil, "malformed request or response")
-			recordTraffic = false
-		}
-
-		conn.logger.Debug(fmt.Sprintf("recording traffic after verifying the request and reponse data:%v", recordTraffic))
-
-		// // decrease the recTestCounter
-		conn.decRecordTestCount()
-		conn.logger.Debug("verified recording", zap.Any("recordTraffic", recordTraffic))
-	} else if conn.lastChunkWasResp && elapsedTime >= uint64(time.Second*2) { // Check if 2 seconds has passed since the last activity.
-		conn.logger.Debug("might be last request on the conn")
-
-		if len(conn.userReqSizes) > 0 && len(conn.kernelReqSizes) > 0 {
-
-			expectedRecvBytes := conn.userReqSizes[0]
-			actualRecvBytes := conn.kernelReqSizes[0]
-
-			//popping out the current request info
-			conn.userReqSizes = conn.userReqSizes[1:]
-			conn.kernelReqSizes = conn.kernelReqSizes[1:]
-
-			if expectedRecvBytes == 0 || actualRecvBytes == 0 {
-				conn.logger.Warn("Malformed request", zap.Any("ExpectedRecvBytes", expectedRecvBytes), zap.Any("ActualRecvBytes", actualRecvBytes))
-			}
-
-			if conn.verifyRequestData(expectedRecvBytes, actualRecvBytes) {
-				recordTraffic = true
-			} else {
-				conn.logger.Debug("Malformed request", zap.Any("ExpectedRecvBytes", expectedRecvBytes), zap.Any("ActualRecvBytes", actualRecvBytes))
-				recordTraffic = false
-			}
-
-			if len(conn.userReqs) > 0 { //validated request, invalided response
-				requestBuf = conn.userReqs[0]
-				//popping out the current request data
-				conn.userReqs = conn.userReqs[1:]
-
-				responseBuf = conn.resp
-				respTimestamp = time.Now()
-			} else {
-				conn.logger.Debug("no data buffer for request", zap.Any("Length of RecvBufQueue", len(conn.userReqs)))
-				recordTraffic = false
-			}
+// pending reports whether the conn has complete requests which aren't captured yet.
+func (conn *Tracker) pending() bool {
+	return atomic.LoadInt32(&conn.recTestCounter) > 0
+}
 
-		} else {
-			utils.LogError(conn.logger, nil, "malformed request")
-			recordTraffic = false
-		}
+func (conn *Tracker) isClosed() bool {
+	conn.mutex.RLock()
+	defer conn.mutex.RUnlock()
+	return conn.closeTimestamp != 0
+}
 
-		conn.logger.Debug(fmt.Sprintf("recording traffic after verifying the request data (but not response data):%v", recordTraffic))
-		//treat immediate next request as first request (2 seconds after last activity)
-		// this can be to avoid potential corruption in the conn
-		conn.reset()
+// nextExchange returns the first request of the conn paired with its response, which isn't captured yet. The
+// payloads of the exchange are the caller's to close.
+func (conn *Tracker) nextExchange() (exchange, bool) {
+	conn.mutex.Lock()
+	defer conn.mutex.Unlock()
+	if len(conn.exchanges) == 0 {
+		return exchange{}, false
+	}
+	e := conn.exchanges[0]
+	conn.exchanges = conn.exchanges[1:]
+	conn.decRecordTestCount()
+	return e, true
+}
 
-		conn.logger.Debug("unverified recording", zap.Any("recordTraffic", recordTraffic))
+// nextCall returns the first gRPC call of the conn which isn't captured yet.
+func (conn *Tracker) nextCall() (grpcCall, bool) {
+	conn.mutex.Lock()
+	defer conn.mutex.Unlock()
+	if len(conn.calls) == 0 {
+		return grpcCall{}, false
 	}
+	call := conn.calls[0]
+	conn.calls = conn.calls[1:]
+	conn.decRecordTestCount()
+	return call, true
+}
 
-	// Checking if record traffic is recorded and request & response timestamp is captured or not.
-	if recordTraffic {
-		if len(conn.reqTimestamps) > 0 {
-			// Get the timestamp of current request
-			reqTimestamps = conn.reqTimestamps[0]
-			// Pop the timestamp of current request
-			conn.reqTimestamps = conn.reqTimestamps[1:]
-		} else {
-			conn.logger.Debug("no request timestamp found")
-			if len(requestBuf) > 0 {
-				reqLine := strings.Split(string(requestBuf), "\n")
-				if models.GetMode() == models.MODE_RECORD && len(reqLine) > 0 && reqLine[0] != "" {
-					conn.logger.Warn(fmt.Sprintf("failed to capture request timestamp for a request. Please record it again if important:%v", reqLine[0]))
-				}
-			}
-			recordTraffic = false
-		}
+// nextSession returns the first websocket of the conn which isn't captured yet.
+func (conn *Tracker) nextSession() (*wsSession, bool) {
+	conn.mutex.Lock()
+	defer conn.mutex.Unlock()
+	if len(conn.sessions) == 0 {
+		return nil, false
+	}
+	s := conn.sessions[0]
+	conn.sessions = conn.sessions[1:]
+	conn.decRecordTestCount()
+	return s, true
+}
 
-		conn.logger.Debug(fmt.Sprintf("TestRequestTimestamp:%v || TestResponseTimestamp:%v", reqTimestamps, respTimestamp))
+// resync drops the messages of the conn which aren't complete, its data was lost. The conn is framed again from
+// its next request, the clients send a new request once they got the response to the last one.
+func (conn *Tracker) resync() {
+	if conn.h2 != nil {
+		// the header blocks which follow can't be decoded without the lost ones
+		conn.logger.Debug("the data of the HTTP/2 conn was lost, its calls are not recorded anymore")
+		conn.h2, conn.unframed = nil, true
+	}
+	if conn.ws != nil {
+		// the frames which follow can't be told apart without the lost ones
+		conn.logger.Debug("the data of the websocket was lost, it's not recorded")
+		conn.ws, conn.unframed = nil, true
 	}
+	conn.req.Close()
+	conn.resp.Close()
+	conn.req, conn.resp = newPayload(), newPayload()
+	for _, m := range append(conn.requests, conn.responses...) {
+		m.data.Close()
+	}
+	conn.requests, conn.responses = nil, nil
+	conn.reqFramer, conn.respFramer = newFramers()
+	conn.lost = true
+}
 
-	return recordTraffic, requestBuf, responseBuf, reqTimestamps, respTimestamp
+// end ends the response whose body ends with the conn, or the websocket of the conn, once the conn is done.
+func (conn *Tracker) end() {
+	conn.mutex.Lock()
+	defer conn.mutex.Unlock()
+	if conn.ws != nil {
+		conn.sessions = append(conn.sessions, conn.ws)
+		conn.ws = nil
+		conn.incRecordTestCount()
+		return
+	}
+	if conn.lost || conn.respFramer.state != frameUntilClose || conn.resp.size == 0 {
+		return
+	}
+	conn.responses = append(conn.responses, message{data: conn.resp, timestamp: time.Now()})
+	conn.resp = newPayload()
+	conn.respFramer.state = frameHeaders
+	conn.respFramer.ended(nil, 0)
+	conn.pair()
 }
 
-// reset resets the conn's request and response data buffers.
-func (conn *Tracker) reset() {
-	conn.firstRequest = true
-	conn.lastChunkWasResp = false
-	conn.lastChunkWasReq = false
-	conn.reqSize = 0
-	conn.respSize = 0
-	conn.resp = []byte{}
-	conn.req = []byte{}
+// pair pairs the requests which ended with their responses.
+func (conn *Tracker) pair() {
+	for len(conn.requests) > 0 && len(conn.responses) > 0 {
+		conn.exchanges = append(conn.exchanges, exchange{req: conn.requests[0], resp: conn.responses[0]})
+		conn.requests, conn.responses = conn.requests[1:], conn.responses[1:]
+		conn.incRecordTestCount()
+	}
 }
 
 func (conn *Tracker) verifyRequestData(expectedRecvBytes, actualRecvBytes uint64) bool {
@@ -295,78 +267,168 @@ func (conn *Tracker) AddDataEvent(event SocketDataEvent) {
 
 	conn.logger.Debug(fmt.Sprintf("Got a data event from eBPF, Direction:%v || current Event Size:%v || ConnectionID:%v\n", event.Direction, event.MsgSize, event.ConnID))
 
-	switch event.Direction {
-	case EgressTraffic:
-		// Capturing the timestamp of response as the response just started to come.
-		// This is to ensure that we capture the response timestamp for the first chunk of the response.
-		if !conn.isNewRequest {
-			conn.isNewRequest = true
-		}
+	if conn.unframed {
+		return
+	}
 
-		// Assign the size of the message to the variable msgLengt
-		msgLength := event.MsgSize
-		// If the size of the message exceeds the maximum allowed size,
-		// set msgLength to the maximum allowed size instead
-		if event.MsgSize > EventBodyMaxSize {
-			msgLength = EventBodyMaxSize
+	if conn.started && event.Direction != conn.direction {
+		// the kernel counts the bytes sent in the other direction until the switch, the data lost on the way
+		// breaks the framing of the messages
+		verified := true
+		switch event.Direction {
+		case EgressTraffic:
+			verified = conn.verifyRequestData(uint64(event.ValidateReadBytes), conn.runSize)
+		case IngressTraffic:
+			verified = conn.verifyResponseData(uint64(event.ValidateWrittenBytes), conn.runSize)
 		}
-		// Append the message (up to msgLength) to the conn's sent buffer
-		conn.resp = append(conn.resp, event.Msg[:msgLength]...)
-		conn.respSize += uint64(event.MsgSize)
-
-		//Handling multiple request on same conn to support conn:keep-alive
-		if conn.firstRequest || conn.lastChunkWasReq {
-			conn.userReqSizes = append(conn.userReqSizes, conn.reqSize)
-			conn.reqSize = 0
-
-			conn.userReqs = append(conn.userReqs, conn.req)
-			conn.req = []byte{}
+		if !verified && !conn.lost {
+			conn.logger.Debug("Malformed request or response, the data of the conn was lost", zap.Any("Direction", conn.direction), zap.Any("ActualBytes", conn.runSize))
+			conn.resync()
+		}
+		if event.Direction == IngressTraffic {
+			// the next request starts
+			conn.lost = false
+		}
+		conn.runSize = 0
+	}
+	conn.started = true
+	conn.direction = event.Direction
+	conn.runSize += uint64(event.MsgSize)
 
-			conn.lastChunkWasReq = false
-			conn.lastChunkWasResp = true
+	if conn.lost {
+		return
+	}
+	if event.MsgSize > EventBodyMaxSize {
+		// the message is truncated to the maximum size of the event
+		conn.logger.Debug("the data event is truncated, the data of the conn was lost", zap.Any("Event Size", event.MsgSize))
+		conn.resync()
+		return
+	}
+	data := event.Msg[:event.MsgSize]
+
+	if event.Direction == IngressTraffic && !conn.requested {
+		conn.requested = true
+		// the data the server sent first is kept for the HTTP/2 conns, it's their settings
+		early := conn.bytes(conn.resp)
+		conn.resp.Close()
+		conn.resp = newPayload()
+		if isH2Preface(data) {
+			conn.h2 = newH2Capture()
+			conn.addH2Data(early, EgressTraffic, time.Now())
+		}
+	}
+	if conn.h2 != nil || conn.ws != nil {
+		timestamp := time.Now()
+		if event.Direction == IngressTraffic {
+			timestamp = ConvertUnixNanoToTime(event.EntryTimestampNano)
+		}
+		if conn.h2 != nil {
+			conn.addH2Data(data, event.Direction, timestamp)
+		} else {
+			conn.addWebSocketData(data, event.Direction, timestamp)
+		}
+		return
+	}
 
-			conn.kernelReqSizes = append(conn.kernelReqSizes, uint64(event.ValidateReadBytes))
-			conn.firstRequest = false
+	switch event.Direction {
+	case EgressTraffic:
+		if !conn.requested {
+			conn.resp.Write(data)
+			return
+		}
+		ends, err := conn.respFramer.feed(data)
+		start := 0
+		for _, end := range ends {
+			conn.resp.Write(data[start:end])
+			conn.responses = append(conn.responses, message{data: conn.resp, timestamp: time.Now()})
+			conn.resp = newPayload()
+			start = end
+		}
+		if err == nil && conn.respFramer.state == frameUpgraded {
+			// the rest of the data is the one of the protocol the conn switched to
+			err = conn.upgrade(data[start:])
+		} else {
+			conn.resp.Write(data[start:])
+		}
+		if err != nil {
+			conn.logger.Debug("the responses on the conn aren't http, they're not recorded anymore", zap.Error(err))
+			conn.unframed = true
 		}
 
 	case IngressTraffic:
-		// Capturing the timestamp of request as the request just started to come.
-		if conn.isNewRequest {
-			conn.reqTimestamps = append(conn.reqTimestamps, ConvertUnixNanoToTime(event.EntryTimestampNano))
-			conn.isNewRequest = false
+		timestamp := ConvertUnixNanoToTime(event.EntryTimestampNano)
+		if conn.req.size == 0 {
+			conn.reqTimestamp = timestamp
 		}
-
-		// Assign the size of the message to the variable msgLength
-		msgLength := event.MsgSize
-		// If the size of the message exceeds the maximum allowed size,
-		// set msgLength to the maximum allowed size instead
-		if event.MsgSize > EventBodyMaxSize {
-			msgLength = EventBodyMaxSize
+		ends, err := conn.reqFramer.feed(data)
+		start := 0
+		for _, end := range ends {
+			conn.req.Write(data[start:end])
+			conn.requests = append(conn.requests, message{data: conn.req, timestamp: conn.reqTimestamp})
+			// the next request may start in the same data, e.g. when the requests are pipelined
+			conn.req, conn.reqTimestamp = newPayload(), timestamp
+			start = end
 		}
-		// Append the message (up to msgLength) to the conn's receive buffer
-		conn.req = append(conn.req, event.Msg[:msgLength]...)
-		conn.reqSize += uint64(event.MsgSize)
-
-		//Handling multiple request on same conn to support conn:keep-alive
-		if conn.lastChunkWasResp {
-			// conn.userRespSizes is the total numner of bytes received in the user side
-			// consumer for the last response.
-			conn.userRespSizes = append(conn.userRespSizes, conn.respSize)
-			conn.respSize = 0
-
-			conn.userResps = append(conn.userResps, conn.resp)
-			conn.resp = []byte{}
+		conn.req.Write(data[start:])
+		if err != nil {
+			conn.logger.Debug("the requests on the conn aren't http, they're not recorded anymore", zap.Error(err))
+			conn.unframed = true
+		}
+	}
+	conn.pair()
+}
 
-			conn.lastChunkWasReq = true
-			conn.lastChunkWasResp = false
+// isH2Preface reports whether the first data of the client starts with the HTTP/2 preface, or with its start.
+func isH2Preface(data []byte) bool {
+	n := min(len(data), len(h2Preface))
+	return n >= len("PRI ") && bytes.Equal(data[:n], h2Preface[:n])
+}
 
-			conn.kernelRespSizes = append(conn.kernelRespSizes, uint64(event.ValidateWrittenBytes))
+// upgrade switches the conn to the websocket of the last request, which the server accepted. The conns upgraded to
+// the other protocols aren't recorded anymore.
+func (conn *Tracker) upgrade(data []byte) error {
+	conn.pair()
+	if len(conn.exchanges) == 0 {
+		return fmt.Errorf("the conn switched protocols")
+	}
+	// the responses answer the requests in order, the handshake is the last exchange
+	e := conn.exchanges[len(conn.exchanges)-1]
+	conn.exchanges = conn.exchanges[:len(conn.exchanges)-1]
+	conn.decRecordTestCount()
+	req, resp := bytes.TrimLeft(conn.bytes(e.req.data), "\r\n"), conn.bytes(e.resp.data)
+	e.close()
+	if !isWebSocketHandshake(req) {
+		return fmt.Errorf("the conn switched protocols")
+	}
+	conn.ws = newWsSession(e, req, resp)
+	conn.addWebSocketData(data, EgressTraffic, e.resp.timestamp)
+	return nil
+}
 
-			//Record a test case for the current request/
-			conn.incRecordTestCount()
-		}
+// addWebSocketData reads the frames of the data of the websocket.
+func (conn *Tracker) addWebSocketData(data []byte, direction TrafficDirectionEnum, timestamp time.Time) {
+	if conn.ws == nil || len(data) == 0 {
+		return
+	}
+	if err := conn.ws.feed(data, direction == EgressTraffic, timestamp); err != nil {
+		conn.logger.Debug("the conn doesn't carry websocket frames, the websocket is not recorded", zap.Error(err))
+		conn.ws, conn.unframed = nil, true
+	}
+}
 
-	default:
+// addH2Data reads the gRPC calls which end with the data of the HTTP/2 conn.
+func (conn *Tracker) addH2Data(data []byte, direction TrafficDirectionEnum, timestamp time.Time) {
+	if conn.h2 == nil || len(data) == 0 {
+		return
+	}
+	calls, err := conn.h2.feed(data, direction == EgressTraffic, timestamp)
+	for range calls {
+		conn.incRecordTestCount()
+	}
+	conn.calls = append(conn.calls, calls...)
+	if err != nil {
+		conn.logger.Debug("the conn doesn't carry HTTP/2 frames, its calls are not recorded anymore", zap.Error(err))
+		conn.h2, conn.unframed = nil, true
 	}
 }
 
@@ -0,0 +1,83 @@
+//go:build linux
+
+package conn
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"go.keploy.io/server/v2/pkg"
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+// A request upgraded to a websocket is followed by the frames of the websocket instead of http messages. The session
+// is captured from its handshake to the end of the conn, the frames are read from both directions as they arrive.
+
+// wsSession is the websocket of a conn.
+type wsSession struct {
+	// req and resp are the handshake, the request and its 101 response
+	req  []byte
+	resp []byte
+	// reqTimestamp is when the handshake started, start when it was accepted and last the time of the last frame
+	reqTimestamp time.Time
+	start        time.Time
+	last         time.Time
+	frames       []models.WebSocketFrame
+
+	requests  wsDirection
+	responses wsDirection
+}
+
+// wsDirection is the state of one direction of the websocket.
+type wsDirection struct {
+	// buf is the part of the next frame received so far
+	buf  []byte
+	text pkg.WebSocketText
+}
+
+// isWebSocketHandshake reports whether the request upgrades the conn to a websocket.
+func isWebSocketHandshake(req []byte) bool {
+	parsed, err := pkg.ParseHTTPRequest(req)
+	if err != nil {
+		return false
+	}
+	return parsed.Method == http.MethodGet && strings.EqualFold(parsed.Header.Get("Upgrade"), "websocket")
+}
+
+func newWsSession(handshake exchange, req, resp []byte) *wsSession {
+	return &wsSession{
+		req:          req,
+		resp:         resp,
+		reqTimestamp: handshake.req.timestamp,
+		start:        handshake.resp.timestamp,
+		last:         handshake.resp.timestamp,
+	}
+}
+
+// feed reads the frames of the data of the direction, the pings and the pongs are left out.
+func (s *wsSession) feed(data []byte, response bool, timestamp time.Time) error {
+	d, from := &s.requests, pkg.WsFromClient
+	if response {
+		d, from = &s.responses, pkg.WsFromServer
+	}
+	d.buf = append(d.buf, data...)
+	for {
+		f, n, err := pkg.ParseWebSocketFrame(d.buf)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			break
+		}
+		d.buf = d.buf[n:]
+		if f.Opcode == pkg.WsOpPing || f.Opcode == pkg.WsOpPong {
+			continue
+		}
+		s.frames = append(s.frames, pkg.WebSocketFrameModel(f, from, d.text.Of(f), max(timestamp.Sub(s.start), 0)))
+		s.last = timestamp
+	}
+	// the next frame is kept apart from the frames read
+	d.buf = append([]byte{}, d.buf...)
+	return nil
+}
@@ -0,0 +1,345 @@
+//go:build linux
+
+package proxy
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// maxTapSize is the number of the latest bytes kept per direction for every proxied connection
+const maxTapSize = 64 * 1024
+
+// ConnInfo describes a live proxied connection, it is exposed by the proxy admin api.
+type ConnInfo struct {
+	ID           string      `json:"id"`
+	Mode         models.Mode `json:"mode"`
+	SrcAddr      string      `json:"srcAddr"`
+	DstAddr      string      `json:"dstAddr"`
+	StartedAt    time.Time   `json:"startedAt"`
+	Protocol     string      `json:"protocol"`
+	MatchedMocks int         `json:"matchedMocks"`
+	LastError    string      `json:"lastError,omitempty"`
+	BytesIn      int64       `json:"bytesIn"`
+	BytesOut     int64       `json:"bytesOut"`
+}
+
+// liveConn holds the debug information of a proxied connection while it is being handled.
+type liveConn struct {
+	mu      sync.Mutex
+	info    ConnInfo
+	matched map[string]struct{} // names of the mocks matched on the connection
+	in      tapBuffer           // bytes read from the application
+	out     tapBuffer           // bytes written to the application
+}
+
+func (c *liveConn) setProtocol(protocol string) {
+	c.mu.Lock()
+	c.info.Protocol = protocol
+	c.mu.Unlock()
+}
+
+func (c *liveConn) addMatched(name string) {
+	c.mu.Lock()
+	c.matched[name] = struct{}{}
+	c.mu.Unlock()
+}
+
+func (c *liveConn) setLastError(msg string) {
+	c.mu.Lock()
+	c.info.LastError = msg
+	c.mu.Unlock()
+}
+
+// snapshot returns the current connection info.
+func (c *liveConn) snapshot() ConnInfo {
+	c.mu.Lock()
+	info := c.info
+	info.MatchedMocks = len(c.matched)
+	c.mu.Unlock()
+	info.BytesIn = c.in.total()
+	info.BytesOut = c.out.total()
+	return info
+}
+
+// tapBuffer keeps the latest maxTapSize bytes flowing in one direction of a connection.
+type tapBuffer struct {
+	mu    sync.Mutex
+	buf   []byte
+	count int64
+}
+
+func (t *tapBuffer) write(p []byte) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.count += int64(len(p))
+	t.buf = append(t.buf, p...)
+	if len(t.buf) > maxTapSize {
+		t.buf = append([]byte(nil), t.buf[len(t.buf)-maxTapSize:]...)
+	}
+}
+
+func (t *tapBuffer) bytes() []byte {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]byte(nil), t.buf...)
+}
+
+func (t *tapBuffer) total() int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.count
+}
+
+// tapConn records the bytes exchanged with the application on a proxied connection.
+type tapConn struct {
+	net.Conn
+	info *liveConn
+}
+
+func (t *tapConn) Read(p []byte) (int, error) {
+	n, err := t.Conn.Read(p)
+	if n > 0 {
+		t.info.in.write(p[:n])
+	}
+	return n, err
+}
+
+func (t *tapConn) Write(p []byte) (int, error) {
+	n, err := t.Conn.Write(p)
+	if n > 0 {
+		t.info.out.write(p[:n])
+	}
+	return n, err
+}
+
+// countingMockDb counts the mocks matched on a connection in test mode. The integrations either flag
+// or delete/update the matched mocks, so the mocks are counted by their name.
+type countingMockDb struct {
+	integrations.MockMemDb
+	info *liveConn
+}
+
+func (c *countingMockDb) FlagMockAsUsed(mock models.Mock) error {
+	err := c.MockMemDb.FlagMockAsUsed(mock)
+	if err == nil {
+		c.info.addMatched(mock.Name)
+	}
+	return err
+}
+
+func (c *countingMockDb) DeleteFilteredMock(mock models.Mock) bool {
+	ok := c.MockMemDb.DeleteFilteredMock(mock)
+	if ok {
+		c.info.addMatched(mock.Name)
+	}
+	return ok
+}
+
+func (c *countingMockDb) DeleteUnFilteredMock(mock models.Mock) bool {
+	ok := c.MockMemDb.DeleteUnFilteredMock(mock)
+	if ok {
+		c.info.addMatched(mock.Name)
+	}
+	return ok
+}
+
+func (c *countingMockDb) UpdateUnFilteredMock(old *models.Mock, new *models.Mock) bool {
+	ok := c.MockMemDb.UpdateUnFilteredMock(old, new)
+	if ok {
+		c.info.addMatched(old.Name)
+	}
+	return ok
+}
+
+func (p *Proxy) trackConn(id int64, srcConn net.Conn, dstAddr string, mode models.Mode) *liveConn {
+	c := &liveConn{
+		matched: make(map[string]struct{}),
+		info: ConnInfo{
+			ID:        strconv.FormatInt(id, 10),
+			Mode:      mode,
+			SrcAddr:   srcConn.RemoteAddr().String(),
+			DstAddr:   dstAddr,
+			StartedAt: time.Now(),
+		},
+	}
+	p.connections.Store(c.info.ID, c)
+	return c
+}
+
+func (p *Proxy) untrackConn(c *liveConn) {
+	p.connections.Delete(c.info.ID)
+}
+
+// connErrorCore records the latest error logged for a proxied connection, so that it can be
+// inspected with the admin api without enabling the debug logs.
+type connErrorCore struct {
+	zapcore.LevelEnabler
+	proxy  *Proxy
+	connID string
+}
+
+func (c *connErrorCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	if id := connIDFromFields(fields); id != "" {
+		clone.connID = id
+	}
+	return &clone
+}
+
+func (c *connErrorCore) Check(entry zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return ce.AddCore(entry, c)
+	}
+	return ce
+}
+
+func (c *connErrorCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	id := c.connID
+	if fid := connIDFromFields(fields); fid != "" {
+		id = fid
+	}
+	if id == "" {
+		return nil
+	}
+	v, ok := c.proxy.connections.Load(id)
+	if !ok {
+		return nil
+	}
+	msg := entry.Message
+	for _, f := range fields {
+		if err, ok := f.Interface.(error); ok && f.Type == zapcore.ErrorType {
+			msg = fmt.Sprintf("%s: %v", msg, err)
+		}
+	}
+	v.(*liveConn).setLastError(msg)
+	return nil
+}
+
+func (c *connErrorCore) Sync() error {
+	return nil
+}
+
+func connIDFromFields(fields []zapcore.Field) string {
+	for _, f := range fields {
+		if f.Key != "Client ConnectionID" {
+			continue
+		}
+		if f.Type == zapcore.StringType {
+			return f.String
+		}
+		if s, ok := f.Interface.(string); ok {
+			return s
+		}
+	}
+	return ""
+}
+
+// startAdminServer serves the proxy admin api on the loopback interface until the context is done.
+func (p *Proxy) startAdminServer(ctx context.Context) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /connections", p.handleListConnections)
+	mux.HandleFunc("GET /connections/{id}", p.handleGetConnection)
+	mux.HandleFunc("GET /connections/{id}/buffer", p.handleDumpConnection)
+	mux.HandleFunc("GET /stats", p.handleStats)
+
+	srv := &http.Server{
+		Addr:              fmt.Sprintf("127.0.0.1:%v", p.AdminPort),
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	go func() {
+		defer utils.Recover(p.logger)
+		<-ctx.Done()
+		if err := srv.Close(); err != nil {
+			utils.LogError(p.logger, err, "failed to stop the proxy admin server")
+		}
+	}()
+
+	p.logger.Info(fmt.Sprintf("Proxy admin api started at %s", srv.Addr))
+	err := srv.ListenAndServe()
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func (p *Proxy) handleListConnections(w http.ResponseWriter, _ *http.Request) {
+	conns := []ConnInfo{}
+	p.connections.Range(func(_, v interface{}) bool {
+		conns = append(conns, v.(*liveConn).snapshot())
+		return true
+	})
+	sort.Slice(conns, func(i, j int) bool {
+		return conns[i].StartedAt.Before(conns[j].StartedAt)
+	})
+	p.writeJSON(w, conns)
+}
+
+func (p *Proxy) handleStats(w http.ResponseWriter, _ *http.Request) {
+	p.writeJSON(w, p.snapshotConnStats())
+}
+
+func (p *Proxy) handleGetConnection(w http.ResponseWriter, r *http.Request) {
+	v, ok := p.connections.Load(r.PathValue("id"))
+	if !ok {
+		http.Error(w, "connection not found", http.StatusNotFound)
+		return
+	}
+	p.writeJSON(w, v.(*liveConn).snapshot())
+}
+
+// handleDumpConnection dumps the latest bytes exchanged with the application. The direction is selected with
+// ?direction=in|out (default in) and the raw bytes are returned with ?format=raw instead of a hex dump.
+func (p *Proxy) handleDumpConnection(w http.ResponseWriter, r *http.Request) {
+	v, ok := p.connections.Load(r.PathValue("id"))
+	if !ok {
+		http.Error(w, "connection not found", http.StatusNotFound)
+		return
+	}
+	info := v.(*liveConn)
+
+	var buf []byte
+	switch r.URL.Query().Get("direction") {
+	case "", "in":
+		buf = info.in.bytes()
+	case "out":
+		buf = info.out.bytes()
+	default:
+		http.Error(w, "direction must be one of in or out", http.StatusBadRequest)
+		return
+	}
+
+	if r.URL.Query().Get("format") == "raw" {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		_, _ = w.Write(buf)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, _ = w.Write([]byte(hex.Dump(buf)))
+}
+
+func (p *Proxy) writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		p.logger.Debug("failed to write the admin api response", zap.Error(err))
+	}
+}
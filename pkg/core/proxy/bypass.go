@@ -0,0 +1,65 @@
+//go:build linux
+
+package proxy
+
+import (
+	"net"
+	"strings"
+	"sync"
+)
+
+// dnsNames remembers the names resolved by the dns server of the proxy for the addresses it answered with,
+// so that the conns to those addresses can be matched against the bypassed domains.
+type dnsNames struct {
+	m sync.Map // ip string -> name
+}
+
+func (d *dnsNames) add(ip net.IP, name string) {
+	d.m.Store(ip.String(), normalizeHost(name))
+}
+
+func (d *dnsNames) get(ip net.IP) (string, bool) {
+	if ip == nil {
+		return "", false
+	}
+	name, ok := d.m.Load(ip.String())
+	if !ok {
+		return "", false
+	}
+	return name.(string), true
+}
+
+// matchDomain reports whether the host is one of the domains or a subdomain of one of them. The domains can
+// also be written as "*.example.com", which matches the same hosts as "example.com".
+func matchDomain(host string, domains []string) bool {
+	host = normalizeHost(host)
+	if host == "" {
+		return false
+	}
+	for _, domain := range domains {
+		domain = normalizeHost(strings.TrimPrefix(domain, "*."))
+		if domain == "" {
+			continue
+		}
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// bypassedHost returns the host of a conn if it belongs to the bypassed domains. The server name of the tls
+// conns is used when known, the name the dns server of the proxy resolved to the address otherwise.
+func (p *Proxy) bypassedHost(serverName string, ip net.IP, domains []string) (string, bool) {
+	if len(domains) == 0 {
+		return "", false
+	}
+	host := serverName
+	if host == "" {
+		host, _ = p.dnsNames.get(ip)
+	}
+	if !matchDomain(host, domains) {
+		return "", false
+	}
+	return host, true
+}
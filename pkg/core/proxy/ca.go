@@ -13,8 +13,10 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sync"
 
 	"github.com/cloudflare/cfssl/csr"
+	"github.com/cloudflare/cfssl/helpers"
 	cfsslLog "github.com/cloudflare/cfssl/log"
 	"github.com/cloudflare/cfssl/signer"
 	"github.com/cloudflare/cfssl/signer/local"
@@ -252,19 +254,50 @@ var (
 	caPrivKey    interface{}
 	caCertParsed *x509.Certificate
 	dstURL       string
+
+	caOnce sync.Once
+	caErr  error
 )
 
+// loadCA parses the embedded CA the certificates of the intercepted conns are signed with, once.
+func loadCA() error {
+	caOnce.Do(func() {
+		caPrivKey, caErr = helpers.ParsePrivateKeyPEM(caPKey)
+		if caErr != nil {
+			caErr = fmt.Errorf("failed to parse CA private key: %w", caErr)
+			return
+		}
+		caCertParsed, caErr = helpers.ParseCertificatePEM(caCrt)
+		if caErr != nil {
+			caErr = fmt.Errorf("failed to parse CA certificate: %w", caErr)
+		}
+	})
+	return caErr
+}
+
+// SignCert returns a certificate of the host signed by the keploy CA, the clients which trust the CA accept it
+// in place of the certificate of the host.
+func SignCert(host string) (*tls.Certificate, error) {
+	if err := loadCA(); err != nil {
+		return nil, err
+	}
+	return signCert(host)
+}
+
 func certForClient(clientHello *tls.ClientHelloInfo) (*tls.Certificate, error) {
-	// Generate a new server certificate and private key for the given hostname
 	dstURL = clientHello.ServerName
+	return signCert(clientHello.ServerName)
+}
 
+func signCert(host string) (*tls.Certificate, error) {
+	// Generate a new server certificate and private key for the given hostname
 	cfsslLog.Level = cfsslLog.LevelError
 
 	serverReq := &csr.CertificateRequest{
 		//Make the name accordng to the ip of the request
-		CN: clientHello.ServerName,
+		CN: host,
 		Hosts: []string{
-			clientHello.ServerName,
+			host,
 		},
 		KeyRequest: csr.NewKeyRequest(),
 	}
@@ -0,0 +1,180 @@
+//go:build linux
+
+package proxy
+
+import (
+	"context"
+	"net"
+	"sort"
+	"time"
+
+	"go.keploy.io/server/v2/config"
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	"go.uber.org/zap"
+)
+
+const (
+	// maxDetectBufSize is the maximum number of bytes buffered for detecting the protocol of a connection
+	maxDetectBufSize = 64 * 1024
+	// maxDetectRounds is the maximum number of times more bytes are read for detecting the protocol
+	maxDetectRounds = 3
+	// detectReadTimeout is the time for which the proxy waits for the bytes requested by an integration
+	detectReadTimeout = 200 * time.Millisecond
+)
+
+// detectProtocol scores every integration against the initial buffer and returns the name of the one with
+// the highest confidence, or an empty string if none of them matched. While no integration is certain and
+// some integration which can still beat the best one needs more bytes than buffered, more data is read from the
+// connection. The returned buffer contains every byte consumed from the connection.
+func (p *Proxy) detectProtocol(ctx context.Context, conn net.Conn, buf []byte) (string, []byte) {
+	for round := 0; ; round++ {
+		best, result, need := p.scoreIntegrations(ctx, buf)
+		p.logger.Debug("scored the integrations", zap.String("best", best), zap.Int("confidence", result.Confidence), zap.Int("bytes", len(buf)), zap.Int("needBytes", need))
+
+		if result.Confidence >= integrations.ConfidenceCertain || need <= len(buf) || round >= maxDetectRounds || len(buf) >= maxDetectBufSize {
+			if result.Confidence == integrations.ConfidenceNone {
+				return "", buf
+			}
+			return best, buf
+		}
+
+		more, err := readMore(conn, min(need, maxDetectBufSize)-len(buf))
+		buf = append(buf, more...)
+		if err != nil || len(more) == 0 {
+			p.logger.Debug("no more bytes available for detecting the protocol", zap.Error(err))
+			if result.Confidence == integrations.ConfidenceNone {
+				return "", buf
+			}
+			return best, buf
+		}
+	}
+}
+
+// scoreIntegrations returns the best matching integration and the number of bytes needed to tell whether it's
+// the best one. Only the bytes needed by the integrations which matched and are at least as confident as the best
+// one are counted: the ones which didn't match, like the binary protocols asking for their header, and the less
+// confident ones can't beat it, so the conn isn't kept waiting for their bytes.
+func (p *Proxy) scoreIntegrations(ctx context.Context, buf []byte) (string, integrations.MatchResult, int) {
+	// sort the names so that the ties are broken in the same way every time
+	names := make([]string, 0, len(p.Integrations))
+	for name := range p.Integrations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var (
+		best   string
+		result integrations.MatchResult
+		need   int
+	)
+	results := make([]integrations.MatchResult, len(names))
+	for i, name := range names {
+		results[i] = p.Integrations[name].MatchType(ctx, buf)
+		if results[i].Confidence > result.Confidence {
+			best, result = name, results[i]
+		}
+	}
+	if result.Confidence >= integrations.ConfidenceCertain {
+		return best, result, 0
+	}
+	for _, res := range results {
+		if res.Confidence > integrations.ConfidenceNone && res.Confidence >= result.Confidence && res.NeedBytes > need {
+			need = res.NeedBytes
+		}
+	}
+	return best, result, need
+}
+
+// readMore reads up to n bytes from the connection waiting at most detectReadTimeout for them.
+func readMore(conn net.Conn, n int) ([]byte, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	if err := conn.SetReadDeadline(time.Now().Add(detectReadTimeout)); err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = conn.SetReadDeadline(time.Time{})
+	}()
+
+	buf := make([]byte, n)
+	read := 0
+	for read < n {
+		m, err := conn.Read(buf[read:])
+		read += m
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Timeout() {
+				return buf[:read], nil
+			}
+			return buf[:read], err
+		}
+	}
+	return buf[:read], nil
+}
+
+// portIntegrations returns the integrations configured for the destination port in their detection order.
+// Mysql is used for its default port unless the port is configured explicitly, since the server speaks first,
+// and so is dns, whose queries carry no signature to tell them apart from the other protocols.
+func (p *Proxy) portIntegrations(port uint) []string {
+	for _, rule := range p.integrationsCfg.Ports {
+		if rule.Port == port && len(rule.Integrations) > 0 {
+			return rule.Integrations
+		}
+	}
+	if _, ok := p.Integrations[string(integrations.MYSQL)]; ok && port == 3306 {
+		return []string{string(integrations.MYSQL)}
+	}
+	if _, ok := p.Integrations[string(integrations.DNS)]; ok && port == 53 {
+		return []string{string(integrations.DNS)}
+	}
+	return nil
+}
+
+// framing returns the framing rule configured for the destination port, or nil if the port has none.
+func (p *Proxy) framing(port uint) *config.Framing {
+	for i, rule := range p.integrationsCfg.Framing {
+		if rule.Port == port {
+			return &p.integrationsCfg.Framing[i]
+		}
+	}
+	return nil
+}
+
+// matchPortIntegrations returns the first of the configured integrations that matches the initial buffer,
+// falling back to the first configured integration if none of them matches.
+func (p *Proxy) matchPortIntegrations(ctx context.Context, names []string, buf []byte) string {
+	for _, name := range names {
+		prs, ok := p.Integrations[name]
+		if !ok {
+			continue
+		}
+		if prs.MatchType(ctx, buf).Confidence > integrations.ConfidenceNone {
+			return name
+		}
+	}
+	return names[0]
+}
+
+func (p *Proxy) isDisabled(name string) bool {
+	if name == string(integrations.GENERIC) {
+		// generic is the fallback for the unsupported protocols and can't be disabled
+		return false
+	}
+	for _, disabled := range p.integrationsCfg.Disabled {
+		if disabled == name {
+			return true
+		}
+	}
+	return false
+}
+
+// validateIntegrationsCfg warns about the integrations in the config which are not registered.
+func (p *Proxy) validateIntegrationsCfg() {
+	for _, rule := range p.integrationsCfg.Ports {
+		for _, name := range rule.Integrations {
+			if _, ok := p.Integrations[name]; !ok {
+				p.logger.Warn("integration configured for the port is not available", zap.Uint("port", rule.Port), zap.String("integration", name))
+			}
+		}
+	}
+}
@@ -10,8 +10,12 @@ import (
 	"os"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/miekg/dns"
+	"go.keploy.io/server/v2/pkg/core"
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	kdns "go.keploy.io/server/v2/pkg/core/proxy/integrations/dns"
 	"go.keploy.io/server/v2/pkg/models"
 	"go.keploy.io/server/v2/utils"
 	"go.uber.org/zap"
@@ -79,24 +83,40 @@ func (p *Proxy) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
 	msg.SetReply(r)
 	msg.Authoritative = true
 	p.logger.Debug("Got some Dns queries")
+	session, mockDb := p.dnsSession()
 	for _, question := range r.Question {
 		p.logger.Debug("", zap.Any("Record Type", question.Qtype), zap.Any("Received Query", question.Name))
 
+		// the recorded answers are given while testing, so that the tests don't depend on the network
+		if session != nil && session.Mode == models.MODE_TEST && session.Mocking && p.mockDNSQuery(mockDb, msg, question) {
+			continue
+		}
+
 		key := generateCacheKey(question.Name, question.Qtype)
+		reqTimestampMock := time.Now()
 
 		// Check if the answer is cached
 		cache.RLock()
 		answers, found := cache.m[key]
 		cache.RUnlock()
 
+		resolved := found
 		if !found {
 			// If not found in cache, resolve the DNS query only in case of record mode
 			//TODO: Add support for passThrough here using the src<->dst mapping
 			if models.GetMode() == models.MODE_RECORD {
-				answers = resolveDNSQuery(p.logger, question.Name)
+				if question.Qtype == dns.TypeA || question.Qtype == dns.TypeAAAA {
+					answers, resolved = p.resolveDNSQuery(question.Name, question.Qtype)
+				} else {
+					answers, resolved = p.exchangeDNSQuery(question)
+					answers = withoutHTTP3Bindings(p.logger, answers)
+				}
+				p.rememberNames(answers)
 			}
 
-			if len(answers) == 0 {
+			// a host resolved without any address of the queried family is answered without records, so that
+			// a dual-stack client falls back to the other family instead of connecting to the proxy itself
+			if len(answers) == 0 && !resolved {
 				// If the resolution failed, return a default A record with Proxy IP
 				if question.Qtype == dns.TypeA {
 					answers = []dns.RR{&dns.A{
@@ -123,6 +143,11 @@ func (p *Proxy) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
 			p.logger.Debug(fmt.Sprintf("Answers[after caching it]:\n%v\n", answers))
 		}
 
+		// only the answers of the servers are recorded, not the proxy addresses given in their place
+		if session != nil && session.Mode == models.MODE_RECORD && resolved {
+			p.recordDNSQuery(session, question, answers, reqTimestampMock)
+		}
+
 		p.logger.Debug(fmt.Sprintf("Answers[before appending to msg]:\n%v\n", answers))
 		msg.Answer = append(msg.Answer, answers...)
 		p.logger.Debug(fmt.Sprintf("Answers[After appending to msg]:\n%v\n", msg.Answer))
@@ -137,30 +162,176 @@ func (p *Proxy) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
 	}
 }
 
+// dnsSession returns the session the dns queries are recorded in or mocked from, along with its mock manager. The
+// queries don't tell the app which made them, so they are attributed to the session of the app with the lowest id,
+// the same one for all the queries whatever the order the sessions are stored in.
+func (p *Proxy) dnsSession() (*core.Session, *MockManager) {
+	var (
+		session *core.Session
+		mockDb  *MockManager
+	)
+	p.MockManagers.Range(func(id, m interface{}) bool {
+		s, ok := p.sessions.Get(id.(uint64))
+		if !ok || (session != nil && session.ID < s.ID) {
+			return true
+		}
+		session, mockDb = s, m.(*MockManager)
+		return true
+	})
+	return session, mockDb
+}
+
+// mockDNSQuery adds the recorded answer to the question to the reply, it reports whether the question was recorded.
+// The questions which weren't recorded are answered as before the dns queries were recorded.
+func (p *Proxy) mockDNSQuery(mockDb *MockManager, msg *dns.Msg, question dns.Question) bool {
+	if mockDb == nil {
+		return false
+	}
+	mock, err := kdns.Lookup(mockDb, question)
+	if err != nil {
+		utils.LogError(p.logger, err, "error while matching dns mocks", zap.Any("Received Query", question.Name))
+		return false
+	}
+	if mock == nil {
+		p.logger.Debug("no dns mock found for the query", zap.Any("Received Query", question.Name), zap.Any("Record Type", question.Qtype))
+		return false
+	}
+
+	reply := new(dns.Msg)
+	if err := kdns.Answer(reply, mock.Spec.DNSResp); err != nil {
+		utils.LogError(p.logger, err, "failed to answer the dns query from the mock", zap.Any("mock name", mock.Name))
+		return false
+	}
+	msg.Rcode = reply.Rcode
+	msg.Answer = append(msg.Answer, reply.Answer...)
+	msg.Ns = append(msg.Ns, reply.Ns...)
+	msg.Extra = append(msg.Extra, reply.Extra...)
+	p.rememberNames(reply.Answer)
+	return true
+}
+
+// exchangeDNSQuery asks the question for the records other than the addresses to the dns servers, it reports
+// whether the servers answered.
+func (p *Proxy) exchangeDNSQuery(question dns.Question) ([]dns.RR, bool) {
+	resp, err := p.resolver.exchange(context.Background(), question)
+	if err != nil {
+		p.logger.Debug(fmt.Sprintf("failed to resolve the dns query for:%v", question.Name), zap.Any("Record Type", question.Qtype), zap.Error(err))
+		return nil, false
+	}
+	return resp.Answer, true
+}
+
+// withoutHTTP3Bindings removes HTTP/3 (alpn h3) from the service bindings (HTTPS and SVCB records) of the answers,
+// so that the application doesn't make QUIC (udp) conns which aren't redirected to the proxy. The bindings left
+// without any protocol are dropped.
+func withoutHTTP3Bindings(logger *zap.Logger, answers []dns.RR) []dns.RR {
+	kept := answers[:0]
+	for _, answer := range answers {
+		var binding *dns.SVCB
+		switch rr := answer.(type) {
+		case *dns.HTTPS:
+			binding = &rr.SVCB
+		case *dns.SVCB:
+			binding = rr
+		default:
+			kept = append(kept, answer)
+			continue
+		}
+
+		var values []dns.SVCBKeyValue
+		noDefaultAlpn, alpns := false, 0
+		for _, value := range binding.Value {
+			switch v := value.(type) {
+			case *dns.SVCBNoDefaultAlpn:
+				noDefaultAlpn = true
+			case *dns.SVCBAlpn:
+				var protocols []string
+				for _, protocol := range v.Alpn {
+					if !strings.HasPrefix(strings.ToLower(protocol), "h3") {
+						protocols = append(protocols, protocol)
+					}
+				}
+				if len(protocols) == 0 {
+					continue
+				}
+				alpns += len(protocols)
+				value = &dns.SVCBAlpn{Alpn: protocols}
+			}
+			values = append(values, value)
+		}
+		// the binding without the default protocol (http/1.1) and any other one is of no use over tcp
+		if noDefaultAlpn && alpns == 0 {
+			logger.Debug("dropped the service binding offering only HTTP/3", zap.Any("binding", answer.String()))
+			continue
+		}
+		binding.Value = values
+		kept = append(kept, answer)
+	}
+	return kept
+}
+
+// recordDNSQuery records the answer to the question, once per record session. The query is answered whether it's
+// recorded or not, so the mock is dropped rather than waited on when the mocks of the session aren't taken in time.
+func (p *Proxy) recordDNSQuery(session *core.Session, question dns.Question, answers []dns.RR, reqTimestampMock time.Time) {
+	req := kdns.Request(question)
+	if _, loaded := p.recordedDNS.LoadOrStore(integrations.DNSMockKey(req.Name, req.Type), true); loaded || session.MC == nil {
+		return
+	}
+	ctx := session.Ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	// the mock channels are closed once the proxy stops, which can race with the last queries
+	p.mcMutex.RLock()
+	defer p.mcMutex.RUnlock()
+	if p.mcClosed || ctx.Err() != nil {
+		p.logger.Debug("the dns query isn't recorded as the recording has stopped", zap.Any("Received Query", question.Name))
+		return
+	}
+
+	resp := new(dns.Msg)
+	resp.Answer = answers
+	select {
+	case session.MC <- kdns.NewMock(question, resp, kdns.TransportUDP, reqTimestampMock, time.Now(), ""):
+	case <-ctx.Done():
+	default:
+		// the question is recorded again by the next query
+		p.recordedDNS.Delete(integrations.DNSMockKey(req.Name, req.Type))
+		p.logger.Debug("the dns query isn't recorded as the mocks of the session aren't taken in time", zap.Any("Received Query", question.Name))
+	}
+}
+
 // TODO: passThrough the dns queries rather than resolving them.
-func resolveDNSQuery(logger *zap.Logger, domain string) []dns.RR {
+// resolveDNSQuery returns the A or AAAA records of the domain as per the query type, it reports whether
+// the domain could be resolved at all.
+func (p *Proxy) resolveDNSQuery(domain string, qtype uint16) ([]dns.RR, bool) {
+	logger := p.logger
 	// Remove the last dot from the domain name if it exists
 	domain = strings.TrimSuffix(domain, ".")
 
-	// Use the default system resolver
-	resolver := net.DefaultResolver
-
-	// Perform the lookup with the context
-	ips, err := resolver.LookupIPAddr(context.Background(), domain)
+	// Perform the lookup with the resolver of the proxy
+	ips, err := p.resolver.lookup(context.Background(), domain)
 	if err != nil {
 		logger.Debug(fmt.Sprintf("failed to resolve the dns query for:%v", domain), zap.Error(err))
-		return nil
+		return nil, false
 	}
 
 	// Convert the resolved IPs to dns.RR
 	var answers []dns.RR
 	for _, ip := range ips {
 		if ipv4 := ip.IP.To4(); ipv4 != nil {
+			if qtype != dns.TypeA {
+				continue
+			}
 			answers = append(answers, &dns.A{
 				Hdr: dns.RR_Header{Name: dns.Fqdn(domain), Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 3600},
 				A:   ipv4,
 			})
 		} else {
+			if qtype != dns.TypeAAAA {
+				continue
+			}
 			answers = append(answers, &dns.AAAA{
 				Hdr:  dns.RR_Header{Name: dns.Fqdn(domain), Rrtype: dns.TypeAAAA, Class: dns.ClassINET, Ttl: 3600},
 				AAAA: ip.IP,
@@ -172,7 +343,19 @@ func resolveDNSQuery(logger *zap.Logger, domain string) []dns.RR {
 		logger.Debug("net.LookupIP resolved the ip address...")
 	}
 
-	return answers
+	return answers, true
+}
+
+// rememberNames records the names of the resolved addresses, to match the conns against the bypassed domains.
+func (p *Proxy) rememberNames(answers []dns.RR) {
+	for _, answer := range answers {
+		switch rr := answer.(type) {
+		case *dns.A:
+			p.dnsNames.add(rr.A, rr.Hdr.Name)
+		case *dns.AAAA:
+			p.dnsNames.add(rr.AAAA, rr.Hdr.Name)
+		}
+	}
 }
 
 func (p *Proxy) stopDNSServers(_ context.Context) error {
@@ -0,0 +1,87 @@
+//go:build linux
+
+// Package amqp1 provides the integration for the amqp 1.0 protocol, spoken by azure service bus and activemq
+// artemis among others. The amqp 0.9.1 protocol is a different protocol, its conns aren't matched.
+package amqp1
+
+import (
+	"bytes"
+	"context"
+	"net"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	"go.keploy.io/server/v2/pkg/core/proxy/util"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+func init() {
+	integrations.Register("amqp1", NewAMQP1)
+}
+
+type AMQP1 struct {
+	logger *zap.Logger
+}
+
+func NewAMQP1(logger *zap.Logger) integrations.Integrations {
+	return &AMQP1{
+		logger: logger,
+	}
+}
+
+// MatchType checks for the protocol header the client sends first, "AMQP" followed by the protocol id and
+// the version 1.0.0. The amqp 0.9.1 header has the version 0.9.1 instead.
+func (a *AMQP1) MatchType(_ context.Context, buf []byte) integrations.MatchResult {
+	if len(buf) == 0 || buf[0] != 'A' {
+		return integrations.MatchResult{}
+	}
+	if len(buf) < headerSize {
+		if !bytes.HasPrefix([]byte("AMQP"), buf[:min(len(buf), 4)]) {
+			return integrations.MatchResult{}
+		}
+		return integrations.MatchResult{Confidence: integrations.ConfidenceLow, NeedBytes: headerSize}
+	}
+	if string(buf[:4]) != "AMQP" || !bytes.Equal(buf[5:8], []byte{1, 0, 0}) {
+		return integrations.MatchResult{}
+	}
+	if buf[4] != protoAMQP && buf[4] != protoSASL {
+		// the conns upgraded to tls by the protocol header can't be recorded
+		return integrations.MatchResult{}
+	}
+	return integrations.MatchResult{Confidence: integrations.ConfidenceCertain}
+}
+
+func (a *AMQP1) RecordOutgoing(ctx context.Context, src net.Conn, dst net.Conn, mocks chan<- *models.Mock, opts models.OutgoingOptions) error {
+	logger := a.logger.With(zap.Any("Client IP Address", src.RemoteAddr().String()), zap.Any("Client ConnectionID", ctx.Value(models.ClientConnectionIDKey).(string)), zap.Any("Destination ConnectionID", ctx.Value(models.DestConnectionIDKey).(string)))
+
+	reqBuf, err := util.ReadInitialBuf(ctx, logger, src)
+	if err != nil {
+		utils.LogError(logger, err, "failed to read the initial amqp message")
+		return err
+	}
+
+	err = encodeAMQP1(ctx, logger, reqBuf, src, dst, mocks, opts)
+	if err != nil {
+		utils.LogError(logger, err, "failed to encode the amqp message into the yaml")
+		return err
+	}
+	return nil
+}
+
+func (a *AMQP1) MockOutgoing(ctx context.Context, src net.Conn, dstCfg *integrations.ConditionalDstCfg, mockDb integrations.MockMemDb, opts models.OutgoingOptions) error {
+	logger := a.logger.With(zap.Any("Client IP Address", src.RemoteAddr().String()), zap.Any("Client ConnectionID", ctx.Value(models.ClientConnectionIDKey).(string)), zap.Any("Destination ConnectionID", ctx.Value(models.DestConnectionIDKey).(string)))
+
+	reqBuf, err := util.ReadInitialBuf(ctx, logger, src)
+	if err != nil {
+		utils.LogError(logger, err, "failed to read the initial amqp message")
+		return err
+	}
+
+	err = decodeAMQP1(ctx, logger, reqBuf, src, dstCfg, mockDb, opts)
+	if err != nil {
+		utils.LogError(logger, err, "failed to decode the amqp message")
+		return err
+	}
+	return nil
+}
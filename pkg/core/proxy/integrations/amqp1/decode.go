@@ -0,0 +1,446 @@
+//go:build linux
+
+package amqp1
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations/util"
+	pUtil "go.keploy.io/server/v2/pkg/core/proxy/util"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+const (
+	// deliveryInterval is how often the mocked broker looks for the messages to deliver to the links
+	deliveryInterval = 50 * time.Millisecond
+	// grantedCredit is the credit and the session window the mocked broker grants to the links sending to it
+	grantedCredit = 1000
+)
+
+// brokerSession is a session of the client with the mocked broker. The channels and the handles of the broker
+// are given out by the mocked broker, since the recorded ones can come from several recorded conns.
+type brokerSession struct {
+	channel uint16
+	// nextIncomingID is the transfer id of the next transfer of the client
+	nextIncomingID uint32
+	// nextOutgoingID and nextDeliveryID are the transfer and delivery ids of the next delivery of the broker
+	nextOutgoingID uint32
+	nextDeliveryID uint32
+	nextHandle     uint32
+}
+
+// brokerLink is a link of the client with the mocked broker.
+type brokerLink struct {
+	*link
+	handle        uint32 // the handle of the broker
+	deliveryCount uint32
+	credit        uint32
+}
+
+// session is the state of a client conn to the mocked broker.
+type session struct {
+	logger     *zap.Logger
+	clientConn net.Conn
+	mockDb     integrations.MockMemDb
+
+	// writeMu serializes the frames written to the client
+	writeMu   sync.Mutex
+	lastWrite time.Time
+	mu        sync.Mutex
+	sessions  map[uint16]*brokerSession // by the channels of the client
+	links     map[linkKey]*brokerLink   // by the channels and handles of the client
+	transfers map[linkKey][]*frame
+	// nextChannel is the channel of the next session begun by the client
+	nextChannel uint16
+	// idleTimeout is the time the client waits for a frame before closing the conn, 0 when it doesn't
+	idleTimeout time.Duration
+}
+
+// decodeAMQP1 acts as the broker for the client. The sasl exchange and the setup of the conn, its sessions and
+// links are answered from the mocks, the deliveries of the client are settled with the recorded dispositions,
+// and the messages recorded for the addresses the client receives from are delivered during the test cases
+// they were recorded in. The replies to the requests of the client, like the tokens of the claims based security,
+// are delivered along with the request. The flow control, the closing frames and the heartbeats are dealt with
+// by the mocked broker itself.
+func decodeAMQP1(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientConn net.Conn, _ *integrations.ConditionalDstCfg, mockDb integrations.MockMemDb, _ models.OutgoingOptions) error {
+	logger.Debug("Into the amqp 1.0 parser in test mode")
+	s := &session{
+		logger:     logger,
+		clientConn: clientConn,
+		mockDb:     mockDb,
+		lastWrite:  time.Now(),
+		sessions:   make(map[uint16]*brokerSession),
+		links:      make(map[linkKey]*brokerLink),
+		transfers:  make(map[linkKey][]*frame),
+	}
+	errCh := make(chan error, 1)
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		defer pUtil.Recover(logger, clientConn, nil)
+		defer close(errCh)
+		client := bufio.NewReader(io.MultiReader(bytes.NewReader(reqBuf), clientConn))
+		for {
+			f, err := readFrame(client)
+			if err != nil {
+				if err != io.EOF {
+					utils.LogError(logger, err, "failed to read the amqp frame from the client")
+				}
+				errCh <- err
+				return
+			}
+			if err := s.handle(ctx, f); err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				errCh <- err
+				return
+			}
+		}
+	}()
+
+	go func() {
+		defer pUtil.Recover(logger, clientConn, nil)
+		s.deliver(ctx, done)
+	}()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-errCh:
+		if err == io.EOF {
+			return nil
+		}
+		return err
+	}
+}
+
+func (s *session) handle(ctx context.Context, f *frame) error {
+	switch {
+	case f.header, f.is(codeSASLInit), f.is(codeSASLResponse), f.is(codeOpen), f.is(codeBegin), f.is(codeAttach):
+		return s.reply(ctx, f)
+	case f.is(codeTransfer):
+		handle, _ := f.uintField(0)
+		key := linkKey{channel: f.channel, handle: handle}
+		s.mu.Lock()
+		frames := append(s.transfers[key], f)
+		if f.more() {
+			s.transfers[key] = frames
+			s.mu.Unlock()
+			return nil
+		}
+		delete(s.transfers, key)
+		s.mu.Unlock()
+		return s.transfer(ctx, key, frames)
+	case f.is(codeDetach):
+		handle, _ := f.uintField(0)
+		key := linkKey{channel: f.channel, handle: handle}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		bs, l := s.sessions[f.channel], s.links[key]
+		delete(s.links, key)
+		if bs == nil || l == nil {
+			return nil
+		}
+		return s.write(performative(bs.channel, codeDetach, l.handle, f.boolField(1)))
+	case f.is(codeEnd):
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		bs := s.sessions[f.channel]
+		delete(s.sessions, f.channel)
+		for key := range s.links {
+			if key.channel == f.channel {
+				delete(s.links, key)
+			}
+		}
+		if bs == nil {
+			return nil
+		}
+		return s.write(performative(bs.channel, codeEnd))
+	case f.is(codeClose):
+		return s.write(performative(0, codeClose))
+	}
+	// the flows, the settlements of the client and the empty frames need no reply
+	return nil
+}
+
+// reply answers the frame with the recorded replies, pointed at the channels and handles of the mocked broker.
+func (s *session) reply(ctx context.Context, f *frame) error {
+	req := request{frame: f}
+	if f.is(codeAttach) {
+		req.link = attachedLink(f, true)
+	}
+	mock, err := match(ctx, req, s.mockDb)
+	if err != nil {
+		utils.LogError(s.logger, err, "error while matching amqp mocks")
+		return err
+	}
+	if mock == nil {
+		err := fmt.Errorf("no amqp mock found for the %s frame", f.name())
+		fields := []zap.Field{zap.Uint16("channel", f.channel)}
+		if req.link != nil {
+			fields = append(fields, zap.String("link", req.link.name), zap.String("address", req.link.address))
+		}
+		utils.LogError(s.logger, err, "failed to mock the amqp frame", fields...)
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, resp := range mock.Spec.AMQP1Responses {
+		rf, err := decodeRaw(resp.Raw)
+		if err != nil {
+			utils.LogError(s.logger, err, "failed to decode the recorded amqp frame")
+			return err
+		}
+		raw := rf.raw
+		var granted *brokerLink
+		switch {
+		case f.is(codeOpen):
+			if ms, ok := f.uintField(4); ok && ms > 0 {
+				s.idleTimeout = time.Duration(ms) * time.Millisecond
+			}
+		case f.is(codeBegin) && rf.is(codeBegin):
+			bs := &brokerSession{channel: s.nextChannel}
+			s.nextChannel++
+			bs.nextIncomingID, _ = f.uintField(1)
+			bs.nextOutgoingID, _ = rf.uintField(1)
+			s.sessions[f.channel] = bs
+			raw = rf.rewrite(bs.channel, map[int]any{0: f.channel})
+		case f.is(codeAttach) && rf.is(codeAttach):
+			bs := s.sessions[f.channel]
+			if bs == nil {
+				return fmt.Errorf("the amqp link %q is attached to the channel %d which isn't begun", req.link.name, f.channel)
+			}
+			l := &brokerLink{link: req.link, handle: bs.nextHandle}
+			bs.nextHandle++
+			l.deliveryCount, _ = f.uintField(9)
+			if handle, ok := f.uintField(1); ok {
+				s.links[linkKey{channel: f.channel, handle: handle}] = l
+			}
+			raw = rf.rewrite(bs.channel, map[int]any{0: f.perf.field(0), 1: l.handle})
+			if !l.receiving {
+				granted = l
+			}
+		}
+		if err := s.write(raw); err != nil {
+			return err
+		}
+		if granted != nil {
+			// the links sending to the broker wait for its credit
+			if err := s.write(s.flow(s.sessions[f.channel], granted)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// transfer settles the delivery of the client with the recorded disposition, and delivers the recorded replies
+// to its message.
+func (s *session) transfer(ctx context.Context, key linkKey, frames []*frame) error {
+	first, last := frames[0], frames[len(frames)-1]
+	payload := deliveryPayload(frames)
+
+	s.mu.Lock()
+	bs, l := s.sessions[key.channel], s.links[key]
+	if bs == nil || l == nil {
+		s.mu.Unlock()
+		return fmt.Errorf("the amqp transfer is sent on the handle %d which isn't attached", key.handle)
+	}
+	bs.nextIncomingID += uint32(len(frames))
+	l.deliveryCount++
+	if l.credit > 0 {
+		l.credit--
+	}
+	if l.credit <= grantedCredit/2 {
+		if err := s.write(s.flow(bs, l)); err != nil {
+			s.mu.Unlock()
+			return err
+		}
+	}
+	s.mu.Unlock()
+
+	req := request{frame: last, link: l.link, message: decodeMessage(payload)}
+	mock, err := match(ctx, req, s.mockDb)
+	if err != nil {
+		utils.LogError(s.logger, err, "error while matching amqp mocks")
+		return err
+	}
+	id, hasID := first.uintField(1)
+	settled := first.boolField(4) || !hasID
+	if mock == nil {
+		if settled {
+			s.logger.Debug("no amqp mock found for the settled delivery", zap.String("link", l.name), zap.String("address", l.address))
+			return nil
+		}
+		err := fmt.Errorf("no amqp mock found for the delivery to %s", l.address)
+		utils.LogError(s.logger, err, "failed to mock the amqp transfer", zap.String("link", l.name))
+		return err
+	}
+
+	s.mu.Lock()
+	if !settled {
+		for _, resp := range mock.Spec.AMQP1Responses {
+			rf, err := decodeRaw(resp.Raw)
+			if err != nil {
+				s.mu.Unlock()
+				utils.LogError(s.logger, err, "failed to decode the recorded amqp frame")
+				return err
+			}
+			if err := s.write(rf.rewrite(bs.channel, map[int]any{1: id, 2: id})); err != nil {
+				s.mu.Unlock()
+				return err
+			}
+		}
+	}
+	s.mu.Unlock()
+
+	recorded := mock.Spec.AMQP1Requests[len(mock.Spec.AMQP1Requests)-1].Message
+	if recorded == nil {
+		return nil
+	}
+	for _, reply := range replies(s.mockDb, recorded.MessageID) {
+		if err := s.deliverMock(reply, messageID(payload)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// flow grants the credit to the link sending to the broker. The caller holds mu.
+func (s *session) flow(bs *brokerSession, l *brokerLink) []byte {
+	l.credit = grantedCredit
+	return performative(bs.channel, codeFlow,
+		bs.nextIncomingID, uint32(grantedCredit), bs.nextOutgoingID, uint32(grantedCredit),
+		l.handle, l.deliveryCount, uint32(grantedCredit))
+}
+
+// deliver sends the messages recorded during the current test case to the links of the client, and keeps the
+// conn alive within the idle timeout of the client.
+func (s *session) deliver(ctx context.Context, done <-chan struct{}) {
+	ticker := time.NewTicker(deliveryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+
+		s.mu.Lock()
+		idleTimeout := s.idleTimeout
+		addresses := make(map[string]bool)
+		for _, l := range s.links {
+			if l.receiving {
+				addresses[l.address] = true
+			}
+		}
+		s.mu.Unlock()
+
+		if idleTimeout > 0 {
+			s.writeMu.Lock()
+			idle := time.Since(s.lastWrite) > idleTimeout/2
+			s.writeMu.Unlock()
+			if idle {
+				if err := s.write(emptyFrame()); err != nil {
+					return
+				}
+			}
+		}
+		if len(addresses) == 0 {
+			continue
+		}
+
+		deliveries, err := deliveries(s.mockDb, func(address string) bool { return addresses[address] })
+		if err != nil {
+			utils.LogError(s.logger, err, "failed to get the amqp deliveries")
+			return
+		}
+		for _, mock := range deliveries {
+			if err := s.deliverMock(mock, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// deliverMock sends the recorded delivery to the link of the client receiving from its address, the one of the
+// same name first. The replies get the message id of the request as their correlation id.
+func (s *session) deliverMock(mock *models.Mock, correlationID any) error {
+	recorded := mock.Spec.AMQP1Responses[0]
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var key linkKey
+	var l *brokerLink
+	for k, candidate := range s.links {
+		if !candidate.receiving || candidate.address != recorded.Address {
+			continue
+		}
+		if l == nil || candidate.name == recorded.Link {
+			key, l = k, candidate
+		}
+	}
+	bs := s.sessions[key.channel]
+	if l == nil || bs == nil {
+		s.logger.Debug("no amqp link receives the recorded delivery", zap.String("address", recorded.Address))
+		return nil
+	}
+
+	id := bs.nextDeliveryID
+	bs.nextDeliveryID++
+	for i, resp := range mock.Spec.AMQP1Responses {
+		rf, err := decodeRaw(resp.Raw)
+		if err != nil {
+			utils.LogError(s.logger, err, "failed to decode the recorded amqp frame")
+			return err
+		}
+		fields := map[int]any{0: l.handle}
+		if _, ok := rf.uintField(1); ok || i == 0 {
+			fields[1] = id
+		}
+		if correlationID != nil && len(mock.Spec.AMQP1Responses) == 1 {
+			rf.payload = withCorrelationID(rf.payload, correlationID)
+		}
+		if err := s.write(rf.rewrite(bs.channel, fields)); err != nil {
+			return err
+		}
+		bs.nextOutgoingID++
+	}
+	return nil
+}
+
+func (s *session) write(raw []byte) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	_, err := s.clientConn.Write(raw)
+	if err != nil {
+		utils.LogError(s.logger, err, "failed to write the amqp frame to the client application")
+		return err
+	}
+	s.lastWrite = time.Now()
+	return nil
+}
+
+// decodeRaw decodes a recorded frame.
+func decodeRaw(raw string) (*frame, error) {
+	data, err := util.DecodeBase64(raw)
+	if err != nil {
+		return nil, err
+	}
+	return readFrame(bufio.NewReader(bytes.NewReader(data)))
+}
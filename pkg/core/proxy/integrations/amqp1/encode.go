@@ -0,0 +1,332 @@
+//go:build linux
+
+package amqp1
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	pUtil "go.keploy.io/server/v2/pkg/core/proxy/util"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+// replyKey identifies the reply the broker is expected to send for a client frame.
+type replyKey struct {
+	kind    string
+	channel uint16
+	name    string
+}
+
+// linkKey identifies a link by the channel and the handle its peer sends its frames with.
+type linkKey struct {
+	channel uint16
+	handle  uint32
+}
+
+// deliveryKey identifies an unsettled delivery of the client waiting for its disposition.
+type deliveryKey struct {
+	channel uint16
+	id      uint32
+}
+
+// pendingFrames are client frames forwarded to the broker which are waiting for their replies.
+type pendingFrames struct {
+	requests         []models.AMQP1Frame
+	responses        []models.AMQP1Frame
+	expected         int
+	reqTimestampMock time.Time
+}
+
+// recorder is the state of a recorded conn, shared by the two directions.
+type recorder struct {
+	mu         sync.Mutex
+	pending    map[replyKey]*pendingFrames
+	unsettled  map[deliveryKey]*pendingFrames
+	links      map[linkKey]*link // the links by the channels and handles of the client
+	peerLinks  map[linkKey]*link // the links by the channels and handles of the broker
+	channels   map[uint16]uint16 // the channels of the client by the channels of the broker
+	transfers  map[linkKey][]*frame
+	deliveries map[linkKey][]*frame
+}
+
+// encodeAMQP1 forwards the frames between the client and the broker. The client frames of the sasl exchange
+// and of the setup of the conn, its sessions and links are recorded with the replies of the broker. The
+// deliveries of the client are recorded with their disposition, or alone when they are settled, and the
+// deliveries of the broker to the links of the client are recorded as mocks without a request. The flow
+// control, the settlements of the client and the closing frames are only forwarded, the mocked broker deals
+// with them while testing.
+func encodeAMQP1(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientConn, destConn net.Conn, mocks chan<- *models.Mock, _ models.OutgoingOptions) error {
+	g, ok := ctx.Value(models.ErrGroupKey).(*errgroup.Group)
+	if !ok {
+		return errors.New("failed to get the error group from the context")
+	}
+	connID, _ := ctx.Value(models.ClientConnectionIDKey).(string)
+
+	rec := &recorder{
+		pending:    make(map[replyKey]*pendingFrames),
+		unsettled:  make(map[deliveryKey]*pendingFrames),
+		links:      make(map[linkKey]*link),
+		peerLinks:  make(map[linkKey]*link),
+		channels:   make(map[uint16]uint16),
+		transfers:  make(map[linkKey][]*frame),
+		deliveries: make(map[linkKey][]*frame),
+	}
+	errCh := make(chan error, 2)
+
+	// Forward the frames from the client to the destination server
+	g.Go(func() error {
+		defer pUtil.Recover(logger, clientConn, destConn)
+		client := bufio.NewReader(io.MultiReader(bytes.NewReader(reqBuf), clientConn))
+		for {
+			f, err := readFrame(client)
+			if err != nil {
+				if err != io.EOF {
+					utils.LogError(logger, err, "failed to read the amqp frame from the client")
+				}
+				errCh <- err
+				return nil
+			}
+			logger.Debug("amqp frame from the client", zap.String("performative", f.name()), zap.Uint16("channel", f.channel))
+
+			// register the frame before forwarding it, so that the reply can't arrive first
+			settled := rec.clientFrame(f, time.Now())
+
+			_, err = destConn.Write(f.raw)
+			if err != nil {
+				utils.LogError(logger, err, "failed to write the amqp frame to the destination server")
+				errCh <- err
+				return nil
+			}
+			if settled != nil {
+				saveMock(settled.requests, nil, settled.reqTimestampMock, time.Now(), connID, mocks)
+			}
+		}
+	})
+
+	// Forward the frames from the destination server to the client
+	g.Go(func() error {
+		defer pUtil.Recover(logger, clientConn, destConn)
+		server := bufio.NewReader(destConn)
+		for {
+			f, err := readFrame(server)
+			if err != nil {
+				if err != io.EOF {
+					utils.LogError(logger, err, "failed to read the amqp frame from the destination server")
+				}
+				errCh <- err
+				return nil
+			}
+
+			_, err = clientConn.Write(f.raw)
+			if err != nil {
+				utils.LogError(logger, err, "failed to write the amqp frame to the client")
+				errCh <- err
+				return nil
+			}
+			resTimestampMock := time.Now()
+			logger.Debug("amqp frame from the server", zap.String("performative", f.name()), zap.Uint16("channel", f.channel))
+
+			for _, p := range rec.serverFrame(f) {
+				reqTimestampMock := p.reqTimestampMock
+				if len(p.requests) == 0 {
+					reqTimestampMock = resTimestampMock
+				}
+				saveMock(p.requests, p.responses, reqTimestampMock, resTimestampMock, connID, mocks)
+			}
+		}
+	})
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-errCh:
+		if err == io.EOF {
+			return nil
+		}
+		return err
+	}
+}
+
+// clientFrame registers the client frame waiting for its reply, and returns the settled deliveries which
+// are recorded without one.
+func (r *recorder) clientFrame(f *frame, now time.Time) *pendingFrames {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	expect := func(key replyKey, expected int, l *link) {
+		r.pending[key] = &pendingFrames{requests: []models.AMQP1Frame{f.model(l)}, expected: expected, reqTimestampMock: now}
+	}
+	switch {
+	case f.header:
+		expected := 1
+		if f.protoID == protoSASL {
+			// the broker offers its mechanisms right after its header
+			expected = 2
+		}
+		expect(replyKey{kind: nameHeader}, expected, nil)
+	case f.is(codeSASLInit), f.is(codeSASLResponse):
+		expect(replyKey{kind: "sasl"}, 1, nil)
+	case f.is(codeOpen):
+		expect(replyKey{kind: "open"}, 1, nil)
+	case f.is(codeBegin):
+		expect(replyKey{kind: "begin", channel: f.channel}, 1, nil)
+	case f.is(codeAttach):
+		l := attachedLink(f, true)
+		if handle, ok := f.uintField(1); ok {
+			r.links[linkKey{channel: f.channel, handle: handle}] = l
+		}
+		expect(replyKey{kind: "attach", name: l.name}, 1, l)
+	case f.is(codeTransfer):
+		handle, _ := f.uintField(0)
+		key := linkKey{channel: f.channel, handle: handle}
+		frames := append(r.transfers[key], f)
+		if f.more() {
+			r.transfers[key] = frames
+			return nil
+		}
+		delete(r.transfers, key)
+		return r.delivery(frames, r.links[key], now)
+	}
+	return nil
+}
+
+// delivery registers the delivery of the client waiting for its disposition, the settled ones are returned.
+func (r *recorder) delivery(frames []*frame, l *link, now time.Time) *pendingFrames {
+	p := &pendingFrames{expected: 1, reqTimestampMock: now}
+	for _, f := range frames {
+		p.requests = append(p.requests, f.model(l))
+	}
+	p.requests[len(p.requests)-1].Message = decodeMessage(deliveryPayload(frames))
+
+	first := frames[0]
+	id, hasID := first.uintField(1)
+	if first.boolField(4) || !hasID {
+		return p
+	}
+	r.unsettled[deliveryKey{channel: first.channel, id: id}] = p
+	return nil
+}
+
+// serverFrame pairs the broker frame with the client frames it replies to, and returns the frames completed
+// by it. The deliveries of the broker are returned on their own.
+func (r *recorder) serverFrame(f *frame) []*pendingFrames {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var key replyKey
+	var l *link
+	switch {
+	case f.header, f.is(codeSASLMechanisms):
+		key = replyKey{kind: nameHeader}
+	case f.is(codeSASLChallenge), f.is(codeSASLOutcome):
+		key = replyKey{kind: "sasl"}
+	case f.is(codeOpen):
+		key = replyKey{kind: "open"}
+	case f.is(codeBegin):
+		remote, ok := f.perf.field(0).(uint16)
+		if !ok {
+			// a session begun by the broker
+			return nil
+		}
+		r.channels[f.channel] = remote
+		key = replyKey{kind: "begin", channel: remote}
+	case f.is(codeAttach):
+		l = attachedLink(f, false)
+		if handle, ok := f.uintField(1); ok {
+			r.peerLinks[linkKey{channel: f.channel, handle: handle}] = l
+		}
+		key = replyKey{kind: "attach", name: l.name}
+	case f.is(codeDisposition):
+		return r.settled(f)
+	case f.is(codeTransfer):
+		handle, _ := f.uintField(0)
+		lk := linkKey{channel: f.channel, handle: handle}
+		frames := append(r.deliveries[lk], f)
+		if f.more() {
+			r.deliveries[lk] = frames
+			return nil
+		}
+		delete(r.deliveries, lk)
+		dl := r.peerLinks[lk]
+		p := &pendingFrames{}
+		for _, df := range frames {
+			p.responses = append(p.responses, df.model(dl))
+		}
+		p.responses[len(p.responses)-1].Message = decodeMessage(deliveryPayload(frames))
+		return []*pendingFrames{p}
+	default:
+		return nil
+	}
+
+	p, ok := r.pending[key]
+	if !ok {
+		return nil
+	}
+	p.responses = append(p.responses, f.model(l))
+	if len(p.responses) < p.expected {
+		return nil
+	}
+	delete(r.pending, key)
+	return []*pendingFrames{p}
+}
+
+// settled returns the deliveries of the client settled by the disposition of the broker.
+func (r *recorder) settled(f *frame) []*pendingFrames {
+	if !f.boolField(0) {
+		// the broker settles its own deliveries
+		return nil
+	}
+	first, ok := f.uintField(1)
+	if !ok {
+		return nil
+	}
+	last, ok := f.uintField(2)
+	if !ok {
+		last = first
+	}
+	channel := r.channels[f.channel]
+	var res []*pendingFrames
+	for key, p := range r.unsettled {
+		if key.channel != channel || key.id < first || key.id > last {
+			continue
+		}
+		delete(r.unsettled, key)
+		p.responses = []models.AMQP1Frame{f.model(nil)}
+		res = append(res, p)
+	}
+	return res
+}
+
+// saveMock records the client frames with the broker frames sent for them. The deliveries of the broker are
+// recorded with the broker frames only.
+func saveMock(requests, responses []models.AMQP1Frame, reqTimestampMock, resTimestampMock time.Time, connID string, mocks chan<- *models.Mock) {
+	metadata := make(map[string]string)
+	// the deliveries belong to the test cases, the rest is the setup of the client
+	if len(requests) > 0 && requests[0].Performative != performativeNames[codeTransfer] {
+		metadata["type"] = "config"
+	}
+
+	mocks <- &models.Mock{
+		Version: models.GetVersion(),
+		Name:    "mocks",
+		Kind:    models.AMQP1,
+		Spec: models.MockSpec{
+			Metadata:         metadata,
+			AMQP1Requests:    requests,
+			AMQP1Responses:   responses,
+			ReqTimestampMock: reqTimestampMock,
+			ResTimestampMock: resTimestampMock,
+		},
+		ConnectionID: connID,
+	}
+}
@@ -0,0 +1,380 @@
+//go:build linux
+
+package amqp1
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"unicode/utf8"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations/util"
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+const (
+	// headerSize is the size of the protocol header, "AMQP" followed by the protocol id and the version
+	headerSize = 8
+	// frameHeaderSize is the size of the size, data offset, type and channel of a frame
+	frameHeaderSize = 8
+	// maxFrameSize bounds the size of a frame, a larger size is taken as a corrupted stream
+	maxFrameSize = 64 << 20
+)
+
+// the protocol ids of the protocol headers, the tls upgrade isn't supported
+const (
+	protoAMQP byte = 0
+	protoSASL byte = 3
+)
+
+// the types of the frames
+const (
+	frameAMQP byte = 0
+	frameSASL byte = 1
+)
+
+// the descriptor codes of the performatives
+const (
+	codeOpen           uint64 = 0x10
+	codeBegin          uint64 = 0x11
+	codeAttach         uint64 = 0x12
+	codeFlow           uint64 = 0x13
+	codeTransfer       uint64 = 0x14
+	codeDisposition    uint64 = 0x15
+	codeDetach         uint64 = 0x16
+	codeEnd            uint64 = 0x17
+	codeClose          uint64 = 0x18
+	codeSASLMechanisms uint64 = 0x40
+	codeSASLInit       uint64 = 0x41
+	codeSASLChallenge  uint64 = 0x42
+	codeSASLResponse   uint64 = 0x43
+	codeSASLOutcome    uint64 = 0x44
+)
+
+// the descriptor codes of the sections of a message
+const (
+	codeProperties            uint64 = 0x73
+	codeApplicationProperties uint64 = 0x74
+	codeData                  uint64 = 0x75
+	codeSequence              uint64 = 0x76
+	codeValue                 uint64 = 0x77
+)
+
+// the names of the frames without a performative
+const (
+	nameHeader = "header"
+	nameEmpty  = "empty"
+)
+
+var performativeNames = map[uint64]string{
+	codeOpen:           "open",
+	codeBegin:          "begin",
+	codeAttach:         "attach",
+	codeFlow:           "flow",
+	codeTransfer:       "transfer",
+	codeDisposition:    "disposition",
+	codeDetach:         "detach",
+	codeEnd:            "end",
+	codeClose:          "close",
+	codeSASLMechanisms: "sasl-mechanisms",
+	codeSASLInit:       "sasl-init",
+	codeSASLChallenge:  "sasl-challenge",
+	codeSASLResponse:   "sasl-response",
+	codeSASLOutcome:    "sasl-outcome",
+}
+
+// frame is a frame or a protocol header, raw is the frame as it was sent. The performative is decoded, the
+// payload after it holds the message sections of the transfers.
+type frame struct {
+	header  bool
+	protoID byte
+	typ     byte
+	channel uint16
+	code    uint64
+	perf    described
+	payload []byte
+	raw     []byte
+}
+
+func (f *frame) name() string {
+	switch {
+	case f.header:
+		return nameHeader
+	case f.perf.descriptor == nil:
+		return nameEmpty
+	}
+	if name, ok := performativeNames[f.code]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%02x", f.code)
+}
+
+// is reports whether the frame is the performative of the code.
+func (f *frame) is(code uint64) bool {
+	return !f.header && f.perf.descriptor != nil && f.code == code
+}
+
+func (f *frame) uintField(i int) (uint32, bool) {
+	return uintValue(f.perf.field(i))
+}
+
+func (f *frame) boolField(i int) bool {
+	b, _ := f.perf.field(i).(bool)
+	return b
+}
+
+// more reports whether the transfer is followed by other transfers of the same delivery.
+func (f *frame) more() bool {
+	return f.boolField(5)
+}
+
+func readFrame(r *bufio.Reader) (*frame, error) {
+	start, err := r.Peek(4)
+	if err != nil {
+		if err == io.EOF && r.Buffered() > 0 {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	if string(start) == "AMQP" {
+		raw := make([]byte, headerSize)
+		if _, err := io.ReadFull(r, raw); err != nil {
+			return nil, unexpected(err)
+		}
+		return &frame{header: true, protoID: raw[4], raw: raw}, nil
+	}
+
+	size := binary.BigEndian.Uint32(start)
+	if size < frameHeaderSize || size > maxFrameSize {
+		return nil, fmt.Errorf("invalid amqp frame size %d", size)
+	}
+	raw := make([]byte, size)
+	if _, err := io.ReadFull(r, raw); err != nil {
+		return nil, unexpected(err)
+	}
+	offset := int(raw[4]) * 4
+	if offset < frameHeaderSize || offset > len(raw) {
+		return nil, fmt.Errorf("invalid amqp frame data offset %d", raw[4])
+	}
+	f := &frame{
+		typ:     raw[5],
+		channel: binary.BigEndian.Uint16(raw[6:8]),
+		raw:     raw,
+	}
+	body := raw[offset:]
+	if len(body) == 0 {
+		// the empty frames keep the conn alive
+		return f, nil
+	}
+	d := &reader{buf: body}
+	perf, ok := d.value().(described)
+	if d.err != nil {
+		return nil, fmt.Errorf("failed to decode the amqp performative: %w", d.err)
+	}
+	code, isCode := perf.code()
+	if !ok || !isCode {
+		return nil, fmt.Errorf("the amqp frame body isn't a performative")
+	}
+	f.code, f.perf, f.payload = code, perf, body[d.off:]
+	return f, nil
+}
+
+func unexpected(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// encodeFrame builds a frame of the performative followed by the payload.
+func encodeFrame(typ byte, channel uint16, perf described, payload []byte) []byte {
+	b := make([]byte, frameHeaderSize, 64+len(payload))
+	b[4] = 2 // the data offset in words, the frames have no extended header
+	b[5] = typ
+	binary.BigEndian.PutUint16(b[6:8], channel)
+	b = encode(b, perf)
+	b = append(b, payload...)
+	binary.BigEndian.PutUint32(b[0:4], uint32(len(b)))
+	return b
+}
+
+// emptyFrame is the frame sent to keep the conn alive.
+func emptyFrame() []byte {
+	return []byte{0, 0, 0, frameHeaderSize, 2, frameAMQP, 0, 0}
+}
+
+// rewrite returns the frame sent on the channel with the fields of the performative replaced, the payload is
+// kept as it is.
+func (f *frame) rewrite(channel uint16, fields map[int]any) []byte {
+	l, _ := f.perf.value.(list)
+	l = append(list(nil), l...)
+	for i, v := range fields {
+		for len(l) <= i {
+			l = append(l, nil)
+		}
+		l[i] = v
+	}
+	return encodeFrame(f.typ, channel, described{descriptor: f.perf.descriptor, value: l}, f.payload)
+}
+
+// performative builds a frame of the performative with the fields.
+func performative(channel uint16, code uint64, fields ...any) []byte {
+	return encodeFrame(frameAMQP, channel, described{descriptor: code, value: list(fields)}, nil)
+}
+
+// link is a link attached by the client, its role and address are seen from the client.
+type link struct {
+	name      string
+	receiving bool
+	address   string
+}
+
+// attachedLink returns the link of an attach frame. The role of the frame is the one of its sender, the links
+// receiving from the broker are named by their source address and the others by their target address.
+func attachedLink(f *frame, fromClient bool) *link {
+	role := f.boolField(2) // true for the receivers
+	l := &link{name: text(f.perf.field(0)), receiving: role == fromClient}
+	terminus := f.perf.field(6) // the target
+	if l.receiving {
+		terminus = f.perf.field(5) // the source
+	}
+	if t, ok := terminus.(described); ok {
+		l.address = text(t.field(0))
+	}
+	return l
+}
+
+func (l *link) role() string {
+	if l.receiving {
+		return "receiver"
+	}
+	return "sender"
+}
+
+// model returns the frame with its decoded fields, the link is the one of the frames of a link.
+func (f *frame) model(l *link) models.AMQP1Frame {
+	m := models.AMQP1Frame{
+		Performative: f.name(),
+		Channel:      f.channel,
+		Raw:          util.EncodeBase64(f.raw),
+	}
+	if l != nil {
+		m.Link, m.Role, m.Address = l.name, l.role(), l.address
+	}
+	if f.is(codeSASLInit) {
+		m.Mechanism = text(f.perf.field(0))
+	}
+	return m
+}
+
+// section is a section of a message, raw is its encoding.
+type section struct {
+	value described
+	raw   []byte
+}
+
+func sections(payload []byte) ([]section, error) {
+	var res []section
+	d := &reader{buf: payload}
+	for d.off < len(payload) && d.err == nil {
+		start := d.off
+		v, ok := d.value().(described)
+		if d.err != nil {
+			break
+		}
+		if !ok {
+			return res, fmt.Errorf("the amqp message section isn't described")
+		}
+		res = append(res, section{value: v, raw: payload[start:d.off]})
+	}
+	return res, d.err
+}
+
+// decodeMessage decodes the message of a delivery, the payloads of its transfers in order.
+func decodeMessage(payload []byte) *models.AMQP1Message {
+	secs, err := sections(payload)
+	if err != nil && len(secs) == 0 {
+		return nil
+	}
+	m := &models.AMQP1Message{}
+	var body []byte
+	for _, s := range secs {
+		code, _ := s.value.code()
+		switch code {
+		case codeProperties:
+			m.MessageID = text(s.value.field(0))
+			m.To = text(s.value.field(2))
+			m.Subject = text(s.value.field(3))
+			m.ReplyTo = text(s.value.field(4))
+			m.CorrelationID = text(s.value.field(5))
+			m.ContentType = text(s.value.field(6))
+		case codeApplicationProperties:
+			props, _ := s.value.value.(amqpMap)
+			for i := 0; i+1 < len(props); i += 2 {
+				if m.Properties == nil {
+					m.Properties = make(map[string]string)
+				}
+				m.Properties[text(props[i])] = text(props[i+1])
+			}
+		case codeData:
+			data, _ := s.value.value.([]byte)
+			body = append(body, data...)
+		case codeSequence, codeValue:
+			body = append(body, text(s.value.value)...)
+		}
+	}
+	if utf8.Valid(body) {
+		m.Body = string(body)
+	} else {
+		m.Body, m.Binary = util.EncodeBase64(body), true
+	}
+	return m
+}
+
+// messageID returns the message id of the message in its amqp type, nil when it has none.
+func messageID(payload []byte) any {
+	secs, _ := sections(payload)
+	for _, s := range secs {
+		if code, _ := s.value.code(); code == codeProperties {
+			return s.value.field(0)
+		}
+	}
+	return nil
+}
+
+// withCorrelationID returns the message with its correlation id replaced, the other sections are kept as they are.
+func withCorrelationID(payload []byte, id any) []byte {
+	secs, err := sections(payload)
+	if err != nil {
+		return payload
+	}
+	var res []byte
+	for _, s := range secs {
+		if code, _ := s.value.code(); code == codeProperties {
+			props, _ := s.value.value.(list)
+			props = append(list(nil), props...)
+			for len(props) <= 5 {
+				props = append(props, nil)
+			}
+			props[5] = id
+			res = encode(res, described{descriptor: s.value.descriptor, value: props})
+			continue
+		}
+		res = append(res, s.raw...)
+	}
+	return res
+}
+
+// deliveryPayload returns the payload of the message carried by the transfers of a delivery.
+func deliveryPayload(frames []*frame) []byte {
+	if len(frames) == 1 {
+		return frames[0].payload
+	}
+	var b bytes.Buffer
+	for _, f := range frames {
+		b.Write(f.payload)
+	}
+	return b.Bytes()
+}
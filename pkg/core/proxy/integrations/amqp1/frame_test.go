@@ -0,0 +1,75 @@
+//go:build linux
+
+package amqp1
+
+import (
+	"bufio"
+	"bytes"
+	"reflect"
+	"testing"
+
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+func TestFrameRoundTrip(t *testing.T) {
+	message := encode(nil, described{descriptor: codeProperties, value: list{"id-1", nil, "queue", "subject", "replies", "corr-1", symbol("text/plain")}})
+	message = encode(message, described{descriptor: codeApplicationProperties, value: amqpMap{"tenant", "a"}})
+	message = encode(message, described{descriptor: codeData, value: []byte("hello")})
+
+	stream := []byte("AMQP\x00\x01\x00\x00")
+	stream = append(stream, performative(0, codeOpen, "container", "host")...)
+	stream = append(stream, emptyFrame()...)
+	stream = append(stream, encodeFrame(frameAMQP, 3, described{descriptor: codeTransfer, value: list{uint32(1), uint32(7)}}, message)...)
+
+	r := bufio.NewReader(bytes.NewReader(stream))
+	header, err := readFrame(r)
+	if err != nil || !header.header || header.protoID != protoAMQP || header.name() != nameHeader {
+		t.Fatalf("readFrame() = %+v, %v, want the protocol header", header, err)
+	}
+	open, err := readFrame(r)
+	if err != nil || !open.is(codeOpen) || text(open.perf.field(0)) != "container" || text(open.perf.field(1)) != "host" {
+		t.Fatalf("readFrame() = %+v, %v, want the open frame", open, err)
+	}
+	empty, err := readFrame(r)
+	if err != nil || empty.name() != nameEmpty {
+		t.Fatalf("readFrame() = %+v, %v, want the empty frame", empty, err)
+	}
+	transfer, err := readFrame(r)
+	if err != nil || !transfer.is(codeTransfer) || transfer.channel != 3 {
+		t.Fatalf("readFrame() = %+v, %v, want the transfer on channel 3", transfer, err)
+	}
+	if handle, _ := transfer.uintField(0); handle != 1 {
+		t.Errorf("transfer handle = %d, want 1", handle)
+	}
+	if !bytes.Equal(transfer.payload, message) {
+		t.Errorf("transfer payload = % x, want % x", transfer.payload, message)
+	}
+	if rewritten := transfer.rewrite(3, nil); !bytes.Equal(rewritten, transfer.raw) {
+		t.Errorf("rewrite() = % x, want the frame as it was read % x", rewritten, transfer.raw)
+	}
+
+	want := &models.AMQP1Message{
+		MessageID:     "id-1",
+		To:            "queue",
+		Subject:       "subject",
+		ReplyTo:       "replies",
+		CorrelationID: "corr-1",
+		ContentType:   "text/plain",
+		Properties:    map[string]string{"tenant": "a"},
+		Body:          "hello",
+	}
+	if got := decodeMessage(transfer.payload); !reflect.DeepEqual(got, want) {
+		t.Errorf("decodeMessage() = %+v, want %+v", got, want)
+	}
+	if got := decodeMessage(withCorrelationID(transfer.payload, "corr-2")); got.CorrelationID != "corr-2" || got.Body != "hello" {
+		t.Errorf("decodeMessage() of the message with its correlation id replaced = %+v", got)
+	}
+}
+
+func TestReadFrameOversizedPerformative(t *testing.T) {
+	body := []byte{0x00, 0x53, byte(codeOpen), 0xd0, 0x7f, 0xff, 0xff, 0xff, 0x7f, 0xff, 0xff, 0xfe}
+	raw := append([]byte{0, 0, 0, byte(frameHeaderSize + len(body)), 2, frameAMQP, 0, 0}, body...)
+	if _, err := readFrame(bufio.NewReader(bytes.NewReader(raw))); err == nil {
+		t.Error("readFrame() of a performative larger than its frame didn't fail")
+	}
+}
@@ -0,0 +1,202 @@
+//go:build linux
+
+package amqp1
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"maps"
+	"math"
+	"sort"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations/util"
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+// request is a client frame to be answered from the mocks, the last transfer frame of a delivery carries
+// its message.
+type request struct {
+	frame   *frame
+	link    *link
+	message *models.AMQP1Message
+}
+
+// match finds the mock of the client frame among the mocks of the same performative, and of the same link
+// address for the frames of a link. The mocks recorded during the current test case are preferred, and the
+// frame closest to the client frame wins, the links of the same name and the messages of the same content
+// first. The setup of the conn is matched as many times as the client reconnects, the deliveries are moved
+// behind the rest once matched.
+func match(ctx context.Context, req request, mockDb integrations.MockMemDb) (*models.Mock, error) {
+	for {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		mocks, err := mockDb.GetUnFilteredMocksByKey(models.AMQP1, "")
+		if err != nil {
+			return nil, fmt.Errorf("error while getting unfiltered mocks %v", err)
+		}
+
+		var filteredMocks, unfilteredMocks []*models.Mock
+		for _, mock := range mocks {
+			if mock.Kind != models.AMQP1 || len(mock.Spec.AMQP1Requests) == 0 || !sameFrame(mock.Spec.AMQP1Requests[0], req) {
+				continue
+			}
+			if mock.TestModeInfo.IsFiltered {
+				filteredMocks = append(filteredMocks, mock)
+			} else {
+				unfilteredMocks = append(unfilteredMocks, mock)
+			}
+		}
+
+		mock := closest(filteredMocks, req)
+		if mock == nil {
+			mock = closest(unfilteredMocks, req)
+		}
+		if mock == nil || mock.Spec.Metadata["type"] == "config" {
+			return mock, nil
+		}
+		if !use(mockDb, mock) {
+			// the mock was used by another frame in the meantime
+			continue
+		}
+		return mock, nil
+	}
+}
+
+// sameFrame reports whether the recorded frame has the performative of the client frame and is about the
+// same link address or sasl mechanism.
+func sameFrame(recorded models.AMQP1Frame, req request) bool {
+	f := req.frame
+	if recorded.Performative != f.name() {
+		return false
+	}
+	switch {
+	case f.header:
+		raw, err := util.DecodeBase64(recorded.Raw)
+		return err == nil && bytes.Equal(raw, f.raw)
+	case f.is(codeSASLInit):
+		return recorded.Mechanism == text(f.perf.field(0))
+	case req.link != nil:
+		return recorded.Role == req.link.role() && recorded.Address == req.link.address
+	}
+	return true
+}
+
+// closest returns the first mock with the same frame as the client frame, or else the one with the most similar
+// frame. The link names and the message ids are usually generated anew on every run, so the links of the same
+// name and the messages of the same content are only preferred.
+func closest(mocks []*models.Mock, req request) *models.Mock {
+	var best *models.Mock
+	bestSim := -1.0
+	for _, mock := range mocks {
+		recorded := mock.Spec.AMQP1Requests[len(mock.Spec.AMQP1Requests)-1]
+		raw, err := util.DecodeBase64(recorded.Raw)
+		if err != nil {
+			continue
+		}
+		if bytes.Equal(raw, req.frame.raw) {
+			return mock
+		}
+		k := util.AdaptiveK(len(req.frame.raw), 3, 8, 5)
+		sim := util.JaccardSimilarity(util.CreateShingles(raw, k), util.CreateShingles(req.frame.raw, k))
+		if (req.message != nil && sameContent(recorded.Message, req.message)) || (req.message == nil && req.link != nil && recorded.Link == req.link.name) {
+			sim++
+		}
+		if sim > bestSim {
+			best, bestSim = mock, sim
+		}
+	}
+	return best
+}
+
+// sameContent reports whether the messages have the same body, subject and application properties.
+func sameContent(recorded, m *models.AMQP1Message) bool {
+	return recorded != nil && recorded.Body == m.Body && recorded.Subject == m.Subject && maps.Equal(recorded.Properties, m.Properties)
+}
+
+// use moves the mock behind the others, it reports false when the mock was used in the meantime.
+func use(mockDb integrations.MockMemDb, mock *models.Mock) bool {
+	originalMock := *mock
+	mock.TestModeInfo.IsFiltered = false
+	mock.TestModeInfo.SortOrder = math.MaxInt64
+	return mockDb.UpdateUnFilteredMock(&originalMock, mock)
+}
+
+// isDelivery reports whether the mock is a message the broker delivered to a link of the client.
+func isDelivery(mock *models.Mock) bool {
+	return mock.Kind == models.AMQP1 && len(mock.Spec.AMQP1Requests) == 0 && len(mock.Spec.AMQP1Responses) > 0 &&
+		mock.Spec.AMQP1Responses[0].Performative == performativeNames[codeTransfer]
+}
+
+// deliveryMessage returns the message of a recorded delivery.
+func deliveryMessage(mock *models.Mock) *models.AMQP1Message {
+	return mock.Spec.AMQP1Responses[len(mock.Spec.AMQP1Responses)-1].Message
+}
+
+// replies uses up the messages the broker recorded in reply to the message of the id, like the responses of
+// the claims based security and the management requests of azure service bus. They're answered whenever the
+// request is sent, the setup of the clients sends them outside of the test cases.
+func replies(mockDb integrations.MockMemDb, messageID string) []*models.Mock {
+	if messageID == "" {
+		return nil
+	}
+	mocks, err := mockDb.GetUnFilteredMocksByKey(models.AMQP1, "")
+	if err != nil {
+		return nil
+	}
+	var res []*models.Mock
+	for _, mock := range mocks {
+		if !isDelivery(mock) {
+			continue
+		}
+		if m := deliveryMessage(mock); m != nil && m.CorrelationID == messageID && use(mockDb, mock) {
+			res = append(res, mock)
+		}
+	}
+	return res
+}
+
+// deliveries uses up the messages recorded during the current test case for the addresses the client receives
+// from, and returns them in the order they were delivered. The replies to the messages of the client are left
+// to be sent with the replies to its requests.
+func deliveries(mockDb integrations.MockMemDb, receiving func(address string) bool) ([]*models.Mock, error) {
+	mocks, err := mockDb.GetUnFilteredMocksByKey(models.AMQP1, "")
+	if err != nil {
+		return nil, fmt.Errorf("error while getting unfiltered mocks %v", err)
+	}
+	requested := make(map[string]bool)
+	for _, mock := range mocks {
+		if mock.Kind != models.AMQP1 || len(mock.Spec.AMQP1Requests) == 0 {
+			continue
+		}
+		if m := mock.Spec.AMQP1Requests[len(mock.Spec.AMQP1Requests)-1].Message; m != nil && m.MessageID != "" {
+			requested[m.MessageID] = true
+		}
+	}
+
+	var pending []*models.Mock
+	for _, mock := range mocks {
+		if !mock.TestModeInfo.IsFiltered || !isDelivery(mock) || !receiving(mock.Spec.AMQP1Responses[0].Address) {
+			continue
+		}
+		if m := deliveryMessage(mock); m != nil && requested[m.CorrelationID] {
+			continue
+		}
+		pending = append(pending, mock)
+	}
+	sort.SliceStable(pending, func(i, j int) bool {
+		return pending[i].Spec.ResTimestampMock.Before(pending[j].Spec.ResTimestampMock)
+	})
+
+	var res []*models.Mock
+	for _, mock := range pending {
+		if !use(mockDb, mock) {
+			// delivered on another conn receiving from the same address
+			continue
+		}
+		res = append(res, mock)
+	}
+	return res, nil
+}
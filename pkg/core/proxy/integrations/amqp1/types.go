@@ -0,0 +1,402 @@
+//go:build linux
+
+package amqp1
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+var errShortBuffer = errors.New("amqp value is shorter than its encoding")
+
+// The values of the amqp type system are decoded into the go types below, so that the performatives can be
+// rewritten and encoded again:
+// nil, bool, uint8, int8, uint16, int16, uint32, int32, uint64, int64, float32, float64, char, timestamp,
+// uuid, decimal, []byte, string, symbol, list, amqpMap, array and described.
+type (
+	symbol    string
+	char      rune
+	timestamp int64 // milliseconds since the unix epoch
+	uuid      [16]byte
+	list      []any
+	array     []any
+	// amqpMap keeps the keys and the values in their order, the keys can be of any type
+	amqpMap []any
+	// decimal is a decimal32, decimal64 or decimal128 kept in its encoding
+	decimal struct {
+		code byte
+		data []byte
+	}
+	described struct {
+		descriptor any
+		value      any
+	}
+)
+
+// code returns the code of the descriptor, the symbolic descriptors have none.
+func (d described) code() (uint64, bool) {
+	c, ok := d.descriptor.(uint64)
+	return c, ok
+}
+
+// field returns the field of a described list, nil for the fields left out at the end of the list.
+func (d described) field(i int) any {
+	l, _ := d.value.(list)
+	if i >= len(l) {
+		return nil
+	}
+	return l[i]
+}
+
+// reader decodes the amqp type system, the first error stops the decoding and is kept in err.
+type reader struct {
+	buf []byte
+	off int
+	err error
+}
+
+func (r *reader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || r.off+n > len(r.buf) {
+		r.err = errShortBuffer
+		return nil
+	}
+	b := r.buf[r.off : r.off+n]
+	r.off += n
+	return b
+}
+
+func (r *reader) byte() byte {
+	b := r.next(1)
+	if b == nil {
+		return 0
+	}
+	return b[0]
+}
+
+func (r *reader) uint32() uint32 {
+	b := r.next(4)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint32(b)
+}
+
+func (r *reader) uint64() uint64 {
+	b := r.next(8)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint64(b)
+}
+
+// width reads a size or a count, encoded on one byte by the short forms and on four by the others.
+func (r *reader) width(short bool) int {
+	if short {
+		return int(r.byte())
+	}
+	return int(r.uint32())
+}
+
+// size reads the size of a compound or an array, which must fit in the rest of the buffer.
+func (r *reader) size(short bool) int {
+	n := r.width(short)
+	if r.err == nil && (n < 0 || n > len(r.buf)-r.off) {
+		r.err = errShortBuffer
+		return 0
+	}
+	return n
+}
+
+// minWidth returns the fewest bytes a value of the constructor code takes after its constructor, the codes are
+// grouped by their upper nibble: the fixed widths of 0 to 16 bytes, then the variable widths, the compounds and
+// the arrays in their short and long forms.
+func minWidth(code byte) int {
+	widths := [16]int{0x4: 0, 0x5: 1, 0x6: 2, 0x7: 4, 0x8: 8, 0x9: 16, 0xa: 1, 0xb: 4, 0xc: 2, 0xd: 8, 0xe: 2, 0xf: 8}
+	return widths[code>>4]
+}
+
+func (r *reader) value() any {
+	code := r.byte()
+	if r.err != nil {
+		return nil
+	}
+	if code == 0x00 {
+		descriptor := r.value()
+		return described{descriptor: descriptor, value: r.value()}
+	}
+	return r.valueOf(code)
+}
+
+// valueOf reads a value of the constructor code, the elements of the arrays share a single constructor.
+func (r *reader) valueOf(code byte) any {
+	switch code {
+	case 0x40:
+		return nil
+	case 0x41:
+		return true
+	case 0x42:
+		return false
+	case 0x43:
+		return uint32(0)
+	case 0x44:
+		return uint64(0)
+	case 0x45:
+		return list{}
+	case 0x50:
+		return r.byte()
+	case 0x51:
+		return int8(r.byte())
+	case 0x52:
+		return uint32(r.byte())
+	case 0x53:
+		return uint64(r.byte())
+	case 0x54:
+		return int32(int8(r.byte()))
+	case 0x55:
+		return int64(int8(r.byte()))
+	case 0x56:
+		return r.byte() != 0
+	case 0x60, 0x61:
+		b := r.next(2)
+		if b == nil {
+			return nil
+		}
+		if code == 0x60 {
+			return binary.BigEndian.Uint16(b)
+		}
+		return int16(binary.BigEndian.Uint16(b))
+	case 0x70:
+		return r.uint32()
+	case 0x71:
+		return int32(r.uint32())
+	case 0x72:
+		return math.Float32frombits(r.uint32())
+	case 0x73:
+		return char(r.uint32())
+	case 0x74, 0x84, 0x94:
+		size := map[byte]int{0x74: 4, 0x84: 8, 0x94: 16}[code]
+		return decimal{code: code, data: r.next(size)}
+	case 0x80:
+		return r.uint64()
+	case 0x81:
+		return int64(r.uint64())
+	case 0x82:
+		return math.Float64frombits(r.uint64())
+	case 0x83:
+		return timestamp(r.uint64())
+	case 0x98:
+		var u uuid
+		copy(u[:], r.next(16))
+		return u
+	case 0xa0, 0xb0:
+		return append([]byte(nil), r.next(r.width(code == 0xa0))...)
+	case 0xa1, 0xb1:
+		return string(r.next(r.width(code == 0xa1)))
+	case 0xa3, 0xb3:
+		return symbol(r.next(r.width(code == 0xa3)))
+	case 0xc0, 0xd0, 0xc1, 0xd1:
+		short := code == 0xc0 || code == 0xc1
+		size := r.size(short)
+		end := r.off + size
+		count := r.width(short)
+		if r.err != nil || count > size {
+			// every element takes a byte at least
+			r.err = errShortBuffer
+			return nil
+		}
+		elems := make([]any, 0, count)
+		for i := 0; i < count && r.err == nil; i++ {
+			elems = append(elems, r.value())
+		}
+		if r.err == nil && r.off != end {
+			r.err = errors.New("amqp compound doesn't end at its size")
+		}
+		if code == 0xc0 || code == 0xd0 {
+			return list(elems)
+		}
+		return amqpMap(elems)
+	case 0xe0, 0xf0:
+		short := code == 0xe0
+		size := r.size(short)
+		end := r.off + size
+		count := r.width(short)
+		if r.err != nil || count > size {
+			r.err = errShortBuffer
+			return nil
+		}
+		elemCode := r.byte()
+		var descriptor any
+		if elemCode == 0x00 {
+			descriptor = r.value()
+			elemCode = r.byte()
+		}
+		if w := minWidth(elemCode); r.err != nil || (w > 0 && count > (end-r.off)/w) {
+			r.err = errShortBuffer
+			return nil
+		}
+		elems := make(array, 0, count)
+		for i := 0; i < count && r.err == nil; i++ {
+			v := r.valueOf(elemCode)
+			if descriptor != nil {
+				v = described{descriptor: descriptor, value: v}
+			}
+			elems = append(elems, v)
+		}
+		if r.err == nil && r.off != end {
+			r.err = errors.New("amqp array doesn't end at its size")
+		}
+		return elems
+	}
+	if r.err == nil {
+		r.err = fmt.Errorf("unknown amqp type code 0x%02x", code)
+	}
+	return nil
+}
+
+// encode appends the encoding of the value. The compounds are always encoded in their 32-bit forms, and the
+// elements of the arrays with the constructor of their first element.
+func encode(b []byte, v any) []byte {
+	code, body := encodeValue(v)
+	b = append(b, code...)
+	return append(b, body...)
+}
+
+// encodeValue returns the constructor of the value and the rest of its encoding.
+func encodeValue(v any) ([]byte, []byte) {
+	switch v := v.(type) {
+	case nil:
+		return []byte{0x40}, nil
+	case bool:
+		if v {
+			return []byte{0x41}, nil
+		}
+		return []byte{0x42}, nil
+	case uint8:
+		return []byte{0x50}, []byte{v}
+	case int8:
+		return []byte{0x51}, []byte{byte(v)}
+	case uint16:
+		return []byte{0x60}, binary.BigEndian.AppendUint16(nil, v)
+	case int16:
+		return []byte{0x61}, binary.BigEndian.AppendUint16(nil, uint16(v))
+	case uint32:
+		return []byte{0x70}, binary.BigEndian.AppendUint32(nil, v)
+	case int32:
+		return []byte{0x71}, binary.BigEndian.AppendUint32(nil, uint32(v))
+	case float32:
+		return []byte{0x72}, binary.BigEndian.AppendUint32(nil, math.Float32bits(v))
+	case char:
+		return []byte{0x73}, binary.BigEndian.AppendUint32(nil, uint32(v))
+	case decimal:
+		return []byte{v.code}, v.data
+	case uint64:
+		return []byte{0x80}, binary.BigEndian.AppendUint64(nil, v)
+	case int64:
+		return []byte{0x81}, binary.BigEndian.AppendUint64(nil, uint64(v))
+	case float64:
+		return []byte{0x82}, binary.BigEndian.AppendUint64(nil, math.Float64bits(v))
+	case timestamp:
+		return []byte{0x83}, binary.BigEndian.AppendUint64(nil, uint64(v))
+	case uuid:
+		return []byte{0x98}, v[:]
+	case []byte:
+		return []byte{0xb0}, append(binary.BigEndian.AppendUint32(nil, uint32(len(v))), v...)
+	case string:
+		return []byte{0xb1}, append(binary.BigEndian.AppendUint32(nil, uint32(len(v))), v...)
+	case symbol:
+		return []byte{0xb3}, append(binary.BigEndian.AppendUint32(nil, uint32(len(v))), v...)
+	case list:
+		return []byte{0xd0}, compound(v)
+	case amqpMap:
+		return []byte{0xd1}, compound(v)
+	case array:
+		return []byte{0xf0}, encodeArray(v)
+	case described:
+		code, body := encodeValue(v.value)
+		return encode([]byte{0x00}, v.descriptor), append(code, body...)
+	}
+	panic(fmt.Sprintf("amqp1: the values of %T can't be encoded", v))
+}
+
+// compound encodes the size, the count and the elements of a list or a map.
+func compound(elems []any) []byte {
+	var body []byte
+	for _, e := range elems {
+		body = encode(body, e)
+	}
+	b := binary.BigEndian.AppendUint32(nil, uint32(4+len(body)))
+	b = binary.BigEndian.AppendUint32(b, uint32(len(elems)))
+	return append(b, body...)
+}
+
+func encodeArray(elems array) []byte {
+	var constructor, body []byte
+	for i, e := range elems {
+		var prefix []byte
+		if d, ok := e.(described); ok {
+			prefix = encode([]byte{0x00}, d.descriptor)
+			e = d.value
+		}
+		code, data := encodeValue(e)
+		if b, ok := e.(bool); ok {
+			// the elements share the constructor, so the booleans take a byte
+			code, data = []byte{0x56}, []byte{0}
+			if b {
+				data[0] = 1
+			}
+		}
+		if i == 0 {
+			constructor = append(prefix, code...)
+		}
+		body = append(body, data...)
+	}
+	if len(elems) == 0 {
+		// the constructor of an empty array is still sent
+		constructor = []byte{0x40}
+	}
+	b := binary.BigEndian.AppendUint32(nil, uint32(4+len(constructor)+len(body)))
+	b = binary.BigEndian.AppendUint32(b, uint32(len(elems)))
+	b = append(b, constructor...)
+	return append(b, body...)
+}
+
+// text returns the value as text for the decoded fields of the frames and the messages.
+func text(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case symbol:
+		return string(v)
+	case []byte:
+		return string(v)
+	case uuid:
+		return fmt.Sprintf("%x-%x-%x-%x-%x", v[0:4], v[4:6], v[6:8], v[8:10], v[10:16])
+	case char:
+		return string(v)
+	case described:
+		return text(v.value)
+	}
+	return fmt.Sprint(v)
+}
+
+// uintValue returns the value of the unsigned integer fields like the handles and the delivery ids.
+func uintValue(v any) (uint32, bool) {
+	switch v := v.(type) {
+	case uint32:
+		return v, true
+	case uint16:
+		return uint32(v), true
+	case uint8:
+		return uint32(v), true
+	case uint64:
+		return uint32(v), true
+	}
+	return 0, false
+}
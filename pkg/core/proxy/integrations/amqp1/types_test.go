@@ -0,0 +1,74 @@
+//go:build linux
+
+package amqp1
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValueRoundTrip(t *testing.T) {
+	values := []any{
+		nil,
+		true,
+		uint8(7),
+		int8(-7),
+		uint16(512),
+		int16(-512),
+		uint32(70000),
+		int32(-70000),
+		uint64(1 << 40),
+		int64(-1 << 40),
+		float32(1.5),
+		float64(-2.25),
+		char('é'),
+		timestamp(1700000000000),
+		uuid{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+		decimal{code: 0x74, data: []byte{1, 2, 3, 4}},
+		[]byte("payload"),
+		"text",
+		symbol("amqp:accepted:list"),
+		list{uint32(1), "two", list{}},
+		amqpMap{symbol("key"), "value", uint32(1), nil},
+		array{symbol("a"), symbol("b")},
+		array{int32(1), int32(-1)},
+		described{descriptor: codeOpen, value: list{"container", "host"}},
+	}
+	for _, v := range values {
+		r := &reader{buf: encode(nil, v)}
+		got := r.value()
+		if r.err != nil {
+			t.Errorf("value() of %#v failed: %v", v, r.err)
+			continue
+		}
+		if r.off != len(r.buf) {
+			t.Errorf("value() of %#v read %d of its %d bytes", v, r.off, len(r.buf))
+		}
+		if !reflect.DeepEqual(got, v) {
+			t.Errorf("value() = %#v, want %#v", got, v)
+		}
+	}
+}
+
+func TestValueOfOversizedCompound(t *testing.T) {
+	tests := []struct {
+		name string
+		buf  []byte
+	}{
+		{name: "list of a size past the buffer", buf: []byte{0xd0, 0x7f, 0xff, 0xff, 0xff, 0x7f, 0xff, 0xff, 0xfe}},
+		{name: "short list of a size past the buffer", buf: []byte{0xc0, 0xff, 0xfe}},
+		{name: "map of a size past the buffer", buf: []byte{0xd1, 0x7f, 0xff, 0xff, 0xff, 0x7f, 0xff, 0xff, 0xfe}},
+		{name: "array of a size past the buffer", buf: []byte{0xf0, 0x7f, 0xff, 0xff, 0xff, 0x7f, 0xff, 0xff, 0xfe, 0x40}},
+		{name: "array of more elements than its size holds", buf: []byte{0xe0, 0x05, 0x04, 0x70, 0, 0, 0, 1}},
+		{name: "list of more elements than its size", buf: []byte{0xc0, 0x02, 0x03, 0x40}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &reader{buf: tt.buf}
+			r.value()
+			if r.err == nil {
+				t.Errorf("value() of % x didn't fail", tt.buf)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+//go:build linux
+
+package dns
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"net"
+
+	"github.com/miekg/dns"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+// decodeDNS answers the queries of the client with the recorded answers. The questions which weren't
+// recorded are answered with a server failure, so that the client doesn't wait for the answer.
+func decodeDNS(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientConn net.Conn, _ *integrations.ConditionalDstCfg, mockDb integrations.MockMemDb, _ models.OutgoingOptions) error {
+	client := bufio.NewReader(io.MultiReader(bytes.NewReader(reqBuf), clientConn))
+	for {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		raw, err := readMsg(client)
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			utils.LogError(logger, err, "failed to read the dns query from the client")
+			return err
+		}
+
+		query := new(dns.Msg)
+		if err := query.Unpack(raw); err != nil {
+			utils.LogError(logger, err, "failed to unpack the dns query")
+			return err
+		}
+
+		reply := new(dns.Msg)
+		reply.SetReply(query)
+		for _, q := range query.Question {
+			mock, err := Lookup(mockDb, q)
+			if err != nil {
+				utils.LogError(logger, err, "error while matching dns mocks")
+				return err
+			}
+			if mock == nil {
+				logger.Debug("no dns mock found for the question", zap.String("name", q.Name), zap.String("type", dns.TypeToString[q.Qtype]))
+				reply.Rcode = dns.RcodeServerFailure
+				continue
+			}
+			if err := Answer(reply, mock.Spec.DNSResp); err != nil {
+				utils.LogError(logger, err, "failed to answer the dns query from the mock", zap.String("name", q.Name))
+				return err
+			}
+		}
+
+		packed, err := reply.Pack()
+		if err != nil {
+			utils.LogError(logger, err, "failed to pack the dns answer")
+			return err
+		}
+		if _, err := clientConn.Write(frameMsg(packed)); err != nil {
+			utils.LogError(logger, err, "failed to write the dns answer to the client application")
+			return err
+		}
+	}
+}
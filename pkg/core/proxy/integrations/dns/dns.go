@@ -0,0 +1,102 @@
+//go:build linux
+
+// Package dns provides the integration for the dns queries made over tcp, along with the recording and the
+// mocking of the answers shared with the dns server of the proxy.
+package dns
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+
+	"github.com/miekg/dns"
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	"go.keploy.io/server/v2/pkg/core/proxy/util"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+func init() {
+	integrations.Register("dns", NewDNS)
+}
+
+type DNS struct {
+	logger *zap.Logger
+}
+
+func NewDNS(logger *zap.Logger) integrations.Integrations {
+	return &DNS{
+		logger: logger,
+	}
+}
+
+// MatchType checks for a standard query prefixed by its length, as the queries are sent over tcp. A query
+// asks a single question and carries no answers.
+func (d *DNS) MatchType(_ context.Context, buf []byte) integrations.MatchResult {
+	if len(buf) < 2 {
+		return integrations.MatchResult{}
+	}
+	size := int(binary.BigEndian.Uint16(buf))
+	if size < headerSize+minQuestionSize || size > maxQuerySize {
+		return integrations.MatchResult{}
+	}
+	if len(buf) < 2+headerSize {
+		return integrations.MatchResult{Confidence: integrations.ConfidenceLow, NeedBytes: 2 + headerSize}
+	}
+
+	h := buf[2:]
+	isResponse := h[2]&0x80 != 0
+	opcode := (h[2] >> 3) & 0x0f
+	qdCount := binary.BigEndian.Uint16(h[4:])
+	anCount := binary.BigEndian.Uint16(h[6:])
+	nsCount := binary.BigEndian.Uint16(h[8:])
+	arCount := binary.BigEndian.Uint16(h[10:])
+	// the additional record is the edns option of the query
+	if isResponse || opcode != dns.OpcodeQuery || qdCount != 1 || anCount != 0 || nsCount != 0 || arCount > 1 {
+		return integrations.MatchResult{}
+	}
+	if len(buf) < 2+size {
+		return integrations.MatchResult{Confidence: integrations.ConfidenceLow, NeedBytes: 2 + size}
+	}
+
+	msg := new(dns.Msg)
+	if err := msg.Unpack(buf[2 : 2+size]); err != nil {
+		return integrations.MatchResult{}
+	}
+	return integrations.MatchResult{Confidence: integrations.ConfidenceHigh}
+}
+
+func (d *DNS) RecordOutgoing(ctx context.Context, src net.Conn, dst net.Conn, mocks chan<- *models.Mock, opts models.OutgoingOptions) error {
+	logger := d.logger.With(zap.Any("Client IP Address", src.RemoteAddr().String()), zap.Any("Client ConnectionID", ctx.Value(models.ClientConnectionIDKey).(string)), zap.Any("Destination ConnectionID", ctx.Value(models.DestConnectionIDKey).(string)))
+
+	reqBuf, err := util.ReadInitialBuf(ctx, logger, src)
+	if err != nil {
+		utils.LogError(logger, err, "failed to read the initial dns message")
+		return err
+	}
+
+	err = encodeDNS(ctx, logger, reqBuf, src, dst, mocks, opts)
+	if err != nil {
+		utils.LogError(logger, err, "failed to encode the dns message into the yaml")
+		return err
+	}
+	return nil
+}
+
+func (d *DNS) MockOutgoing(ctx context.Context, src net.Conn, dstCfg *integrations.ConditionalDstCfg, mockDb integrations.MockMemDb, opts models.OutgoingOptions) error {
+	logger := d.logger.With(zap.Any("Client IP Address", src.RemoteAddr().String()), zap.Any("Client ConnectionID", ctx.Value(models.ClientConnectionIDKey).(string)), zap.Any("Destination ConnectionID", ctx.Value(models.DestConnectionIDKey).(string)))
+
+	reqBuf, err := util.ReadInitialBuf(ctx, logger, src)
+	if err != nil {
+		utils.LogError(logger, err, "failed to read the initial dns message")
+		return err
+	}
+
+	err = decodeDNS(ctx, logger, reqBuf, src, dstCfg, mockDb, opts)
+	if err != nil {
+		utils.LogError(logger, err, "failed to decode the dns message")
+		return err
+	}
+	return nil
+}
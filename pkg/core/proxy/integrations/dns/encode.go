@@ -0,0 +1,129 @@
+//go:build linux
+
+package dns
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+	"golang.org/x/sync/errgroup"
+
+	pUtil "go.keploy.io/server/v2/pkg/core/proxy/util"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+// pendingQuery is a query forwarded to the server which is waiting for its answer.
+type pendingQuery struct {
+	questions        []dns.Question
+	reqTimestampMock time.Time
+}
+
+// encodeDNS forwards the messages between the client and the server. The client can send several queries
+// before reading the answers, so the answers are paired with the queries by their id. A mock is recorded
+// for every question of a query.
+func encodeDNS(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientConn, destConn net.Conn, mocks chan<- *models.Mock, _ models.OutgoingOptions) error {
+	g, ok := ctx.Value(models.ErrGroupKey).(*errgroup.Group)
+	if !ok {
+		return errors.New("failed to get the error group from the context")
+	}
+	connID, _ := ctx.Value(models.ClientConnectionIDKey).(string)
+
+	var (
+		mu      sync.Mutex
+		pending = make(map[uint16]pendingQuery)
+	)
+	errCh := make(chan error, 2)
+
+	// Forward the queries from the client to the destination server
+	g.Go(func() error {
+		defer pUtil.Recover(logger, clientConn, destConn)
+		client := bufio.NewReader(io.MultiReader(bytes.NewReader(reqBuf), clientConn))
+		for {
+			raw, err := readMsg(client)
+			if err != nil {
+				if err != io.EOF {
+					utils.LogError(logger, err, "failed to read the dns query from the client")
+				}
+				errCh <- err
+				return nil
+			}
+
+			query := new(dns.Msg)
+			if err := query.Unpack(raw); err != nil {
+				logger.Debug("failed to unpack the dns query, forwarding it without recording", zap.Error(err))
+			} else {
+				// register the query before forwarding it, so that the answer can't arrive first
+				mu.Lock()
+				pending[query.Id] = pendingQuery{questions: query.Question, reqTimestampMock: time.Now()}
+				mu.Unlock()
+			}
+
+			_, err = destConn.Write(frameMsg(raw))
+			if err != nil {
+				utils.LogError(logger, err, "failed to write the dns query to the destination server")
+				errCh <- err
+				return nil
+			}
+		}
+	})
+
+	// Forward the answers from the destination server to the client
+	g.Go(func() error {
+		defer pUtil.Recover(logger, clientConn, destConn)
+		server := bufio.NewReader(destConn)
+		for {
+			raw, err := readMsg(server)
+			if err != nil {
+				if err != io.EOF {
+					utils.LogError(logger, err, "failed to read the dns answer from the destination server")
+				}
+				errCh <- err
+				return nil
+			}
+
+			_, err = clientConn.Write(frameMsg(raw))
+			if err != nil {
+				utils.LogError(logger, err, "failed to write the dns answer to the client")
+				errCh <- err
+				return nil
+			}
+			resTimestampMock := time.Now()
+
+			resp := new(dns.Msg)
+			if err := resp.Unpack(raw); err != nil {
+				logger.Debug("failed to unpack the dns answer, it isn't recorded", zap.Error(err))
+				continue
+			}
+			mu.Lock()
+			query, ok := pending[resp.Id]
+			delete(pending, resp.Id)
+			mu.Unlock()
+			if !ok {
+				logger.Debug("no pending dns query for the answer", zap.Uint16("id", resp.Id))
+				continue
+			}
+			for _, q := range query.questions {
+				mocks <- NewMock(q, resp, TransportTCP, query.reqTimestampMock, resTimestampMock, connID)
+			}
+		}
+	})
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-errCh:
+		if err == io.EOF {
+			return nil
+		}
+		return err
+	}
+}
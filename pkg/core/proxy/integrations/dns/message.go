@@ -0,0 +1,161 @@
+//go:build linux
+
+package dns
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+const (
+	headerSize = 12
+	// minQuestionSize is the size of a question for the root, with its type and class
+	minQuestionSize = 5
+	// maxQuerySize bounds the size of the queries, which carry a single question
+	maxQuerySize = 4096
+
+	// the transports the queries are recorded from
+	TransportUDP = "udp"
+	TransportTCP = "tcp"
+)
+
+// readMsg reads a message prefixed by its length, as the messages are sent over tcp.
+func readMsg(r *bufio.Reader) ([]byte, error) {
+	var size [2]byte
+	if _, err := io.ReadFull(r, size[:]); err != nil {
+		return nil, err
+	}
+	raw := make([]byte, binary.BigEndian.Uint16(size[:]))
+	if _, err := io.ReadFull(r, raw); err != nil {
+		if err == io.EOF {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return raw, nil
+}
+
+// frameMsg prefixes the message with its length.
+func frameMsg(raw []byte) []byte {
+	framed := make([]byte, 2, 2+len(raw))
+	binary.BigEndian.PutUint16(framed, uint16(len(raw)))
+	return append(framed, raw...)
+}
+
+// Request returns the model of the question.
+func Request(q dns.Question) models.DNSRequest {
+	return models.DNSRequest{
+		Name:  dns.Fqdn(q.Name),
+		Type:  dns.TypeToString[q.Qtype],
+		Class: dns.ClassToString[q.Qclass],
+	}
+}
+
+// Response returns the model of the answer, the edns option isn't a record of the answer and is left out.
+func Response(resp *dns.Msg) models.DNSResponse {
+	return models.DNSResponse{
+		Rcode:       dns.RcodeToString[resp.Rcode],
+		Answers:     records(resp.Answer),
+		Authorities: records(resp.Ns),
+		Additionals: records(resp.Extra),
+	}
+}
+
+func records(rrs []dns.RR) []string {
+	var res []string
+	for _, rr := range rrs {
+		if _, ok := rr.(*dns.OPT); ok {
+			continue
+		}
+		// the fields are separated by tabs, the tabs within the data are escaped
+		res = append(res, strings.ReplaceAll(rr.String(), "\t", " "))
+	}
+	return res
+}
+
+// NewMock returns the mock of the answer to the question.
+func NewMock(q dns.Question, resp *dns.Msg, transport string, reqTimestampMock, resTimestampMock time.Time, connID string) *models.Mock {
+	req := Request(q)
+	res := Response(resp)
+	return &models.Mock{
+		Version: models.GetVersion(),
+		Name:    "mocks",
+		Kind:    models.DNS,
+		Spec: models.MockSpec{
+			Metadata:         map[string]string{"transport": transport},
+			DNSReq:           &req,
+			DNSResp:          &res,
+			ReqTimestampMock: reqTimestampMock,
+			ResTimestampMock: resTimestampMock,
+		},
+		ConnectionID: connID,
+	}
+}
+
+// Lookup returns the mock of the answer to the question, or nil if the question wasn't recorded. The mocks are
+// not used up, the same answer is given every time the question is asked. Among the answers recorded for the
+// question, the one recorded during the current test case is preferred.
+func Lookup(mockDb integrations.MockMemDb, q dns.Question) (*models.Mock, error) {
+	req := Request(q)
+	mocks, err := mockDb.GetUnFilteredMocksByKey(models.DNS, integrations.DNSMockKey(req.Name, req.Type))
+	if err != nil {
+		return nil, fmt.Errorf("error while getting unfiltered mocks %v", err)
+	}
+
+	var match *models.Mock
+	for _, mock := range mocks {
+		if mock.Kind != models.DNS || mock.Spec.DNSReq == nil || mock.Spec.DNSResp == nil {
+			continue
+		}
+		if mock.Spec.DNSReq.Class != "" && mock.Spec.DNSReq.Class != req.Class {
+			continue
+		}
+		if match == nil || (mock.TestModeInfo.IsFiltered && !match.TestModeInfo.IsFiltered) {
+			match = mock
+		}
+	}
+	if match != nil {
+		if err := mockDb.FlagMockAsUsed(*match); err != nil {
+			return nil, err
+		}
+	}
+	return match, nil
+}
+
+// Answer adds the recorded answer to the reply.
+func Answer(reply *dns.Msg, resp *models.DNSResponse) error {
+	if resp.Rcode != "" {
+		rcode, ok := dns.StringToRcode[resp.Rcode]
+		if !ok {
+			return fmt.Errorf("invalid dns rcode %q", resp.Rcode)
+		}
+		reply.Rcode = rcode
+	}
+	for _, section := range []struct {
+		records []string
+		rrs     *[]dns.RR
+	}{
+		{resp.Answers, &reply.Answer},
+		{resp.Authorities, &reply.Ns},
+		{resp.Additionals, &reply.Extra},
+	} {
+		for _, record := range section.records {
+			rr, err := dns.NewRR(record)
+			if err != nil {
+				return fmt.Errorf("invalid dns record %q: %v", record, err)
+			}
+			if rr != nil {
+				*section.rrs = append(*section.rrs, rr)
+			}
+		}
+	}
+	return nil
+}
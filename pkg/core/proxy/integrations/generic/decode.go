@@ -17,8 +17,13 @@ import (
 	"go.uber.org/zap"
 )
 
-func decodeGeneric(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientConn net.Conn, dstCfg *integrations.ConditionalDstCfg, mockDb integrations.MockMemDb, _ models.OutgoingOptions) error {
-	genericRequests := [][]byte{reqBuf}
+// decodeGeneric answers the requests of an unknown protocol with the recorded responses. The requests read
+// until the client pauses are matched together, when the port has a framing rule the client is waited for
+// until its last frame is whole. The recorded protobuf messages are matched on their fields.
+func decodeGeneric(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientConn net.Conn, dstCfg *integrations.ConditionalDstCfg, mockDb integrations.MockMemDb, opts models.OutgoingOptions) error {
+	reqFrames := newSplitter(logger, opts.Framing)
+	codec := newProtobufCodec(logger, opts.Framing)
+	genericRequests := reqFrames.push(reqBuf)
 	logger.Debug("Into the generic parser in test mode")
 	errCh := make(chan error, 1)
 	go func(errCh chan error, genericRequests [][]byte) {
@@ -40,11 +45,21 @@ func decodeGeneric(ctx context.Context, logger *zap.Logger, reqBuf []byte, clien
 					utils.LogError(logger, err, "failed to read the request message in proxy for generic dependency")
 					return
 				}
+				// the bytes read before a timeout are kept
+				genericRequests = append(genericRequests, reqFrames.push(buffer)...)
+				if netErr, ok := err.(net.Error); ok && netErr.Timeout() && reqFrames.partial() {
+					// the rest of the frame is yet to come
+					err := clientConn.SetReadDeadline(time.Now().Add(10 * time.Millisecond))
+					if err != nil {
+						utils.LogError(logger, err, "failed to set the read deadline for the client conn")
+						return
+					}
+					continue
+				}
 				if netErr, ok := err.(net.Error); (ok && netErr.Timeout()) || (err != nil && err.Error() == "EOF") {
 					logger.Debug("the timeout for the client read in generic or EOF")
 					break
 				}
-				genericRequests = append(genericRequests, buffer)
 			}
 
 			if len(genericRequests) == 0 {
@@ -54,7 +69,7 @@ func decodeGeneric(ctx context.Context, logger *zap.Logger, reqBuf []byte, clien
 
 			// bestMatchedIndx := 0
 			// fuzzy match gives the index for the best matched generic mock
-			matched, genericResponses, err := fuzzyMatch(ctx, genericRequests, mockDb)
+			matched, genericResponses, err := fuzzyMatch(ctx, genericRequests, mockDb, codec)
 			if err != nil {
 				utils.LogError(logger, err, "error while matching generic mocks")
 			}
@@ -80,14 +95,20 @@ func decodeGeneric(ctx context.Context, logger *zap.Logger, reqBuf []byte, clien
 				genericRequests = [][]byte{}
 				logger.Debug("the request buffer after pass through in generic", zap.Any("buffer", string(reqBuffer)))
 				if len(reqBuffer) > 0 {
-					genericRequests = [][]byte{reqBuffer}
+					genericRequests = reqFrames.push(reqBuffer)
 				}
 				logger.Debug("the length of genericRequests after passThrough ", zap.Any("length", len(genericRequests)))
 				continue
 			}
 			for _, genericResponse := range genericResponses {
 				encoded := []byte(genericResponse.Message[0].Data)
-				if genericResponse.Message[0].Type != models.String {
+				if isProtobuf(genericResponse) {
+					encoded, err = codec.frame(models.FromServer, genericResponse)
+					if err != nil {
+						utils.LogError(logger, err, "failed to encode the recorded protobuf response")
+						return
+					}
+				} else if genericResponse.Message[0].Type != models.String {
 					encoded, err = util.DecodeBase64(genericResponse.Message[0].Data)
 					if err != nil {
 						utils.LogError(logger, err, "failed to decode the base64 response")
@@ -4,7 +4,6 @@ package generic
 
 import (
 	"context"
-	"encoding/base64"
 	"fmt"
 	"io"
 	"net"
@@ -18,27 +17,20 @@ import (
 	"go.uber.org/zap"
 )
 
-func encodeGeneric(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientConn, destConn net.Conn, mocks chan<- *models.Mock, _ models.OutgoingOptions) error {
+// encodeGeneric records the exchanges of an unknown protocol. The requests and the responses are recorded
+// in the chunks they are read in, or as whole frames when the port has a framing rule. The frames of a rule
+// naming protobuf messages are recorded as the json of their messages.
+func encodeGeneric(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientConn, destConn net.Conn, mocks chan<- *models.Mock, opts models.OutgoingOptions) error {
 
 	var genericRequests []models.Payload
+	reqFrames := newSplitter(logger, opts.Framing)
+	respFrames := newSplitter(logger, opts.Framing)
+	codec := newProtobufCodec(logger, opts.Framing)
 
-	bufStr := string(reqBuf)
-	dataType := models.String
-	if !util.IsASCII(string(reqBuf)) {
-		bufStr = util.EncodeBase64(reqBuf)
-		dataType = "binary"
-	}
-
-	if bufStr != "" {
-		genericRequests = append(genericRequests, models.Payload{
-			Origin: models.FromClient,
-			Message: []models.OutputBinary{
-				{
-					Type: dataType,
-					Data: bufStr,
-				},
-			},
-		})
+	for _, frame := range reqFrames.push(reqBuf) {
+		if p, ok := framePayload(codec, models.FromClient, frame); ok {
+			genericRequests = append(genericRequests, p)
+		}
 	}
 	_, err := destConn.Write(reqBuf)
 	if err != nil {
@@ -133,23 +125,10 @@ func encodeGeneric(ctx context.Context, logger *zap.Logger, reqBuf []byte, clien
 				genericResponses = []models.Payload{}
 			}
 
-			bufStr := string(buffer)
-			buffDataType := models.String
-			if !util.IsASCII(string(buffer)) {
-				bufStr = util.EncodeBase64(buffer)
-				buffDataType = "binary"
-			}
-
-			if bufStr != "" {
-				genericRequests = append(genericRequests, models.Payload{
-					Origin: models.FromClient,
-					Message: []models.OutputBinary{
-						{
-							Type: buffDataType,
-							Data: bufStr,
-						},
-					},
-				})
+			for _, frame := range reqFrames.push(buffer) {
+				if p, ok := framePayload(codec, models.FromClient, frame); ok {
+					genericRequests = append(genericRequests, p)
+				}
 			}
 
 			prevChunkWasReq = true
@@ -165,23 +144,10 @@ func encodeGeneric(ctx context.Context, logger *zap.Logger, reqBuf []byte, clien
 				return err
 			}
 
-			bufStr := string(buffer)
-			buffDataType := models.String
-			if !util.IsASCII(string(buffer)) {
-				bufStr = base64.StdEncoding.EncodeToString(buffer)
-				buffDataType = "binary"
-			}
-
-			if bufStr != "" {
-				genericResponses = append(genericResponses, models.Payload{
-					Origin: models.FromServer,
-					Message: []models.OutputBinary{
-						{
-							Type: buffDataType,
-							Data: bufStr,
-						},
-					},
-				})
+			for _, frame := range respFrames.push(buffer) {
+				if p, ok := framePayload(codec, models.FromServer, frame); ok {
+					genericResponses = append(genericResponses, p)
+				}
 			}
 
 			resTimestampMock = time.Now()
@@ -196,3 +162,26 @@ func encodeGeneric(ctx context.Context, logger *zap.Logger, reqBuf []byte, clien
 		}
 	}
 }
+
+// framePayload returns the payload recording the frame, as the json of its protobuf message when the codec
+// decodes it, as text when it is ascii and as base64 otherwise.
+func framePayload(codec *protobufCodec, origin models.OriginType, frame []byte) (models.Payload, bool) {
+	if len(frame) == 0 {
+		return models.Payload{}, false
+	}
+	if codec != nil {
+		if p, ok := codec.payload(origin, frame); ok {
+			return p, true
+		}
+	}
+	dataType := models.String
+	bufStr := string(frame)
+	if !util.IsASCIIBytes(frame) {
+		bufStr = util.EncodeBase64(frame)
+		dataType = "binary"
+	}
+	return models.Payload{
+		Origin:  origin,
+		Message: []models.OutputBinary{{Type: dataType, Data: bufStr}},
+	}, true
+}
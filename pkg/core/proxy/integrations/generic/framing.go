@@ -0,0 +1,161 @@
+//go:build linux
+
+package generic
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"go.keploy.io/server/v2/config"
+	"go.uber.org/zap"
+)
+
+// maxFrameSize bounds the size of the frames, a larger length means the framing rule doesn't fit the protocol
+const maxFrameSize = 64 << 20
+
+// framer finds the frames of a framing rule in a stream.
+type framer struct {
+	cfg       config.Framing
+	order     binary.ByteOrder
+	delimiter []byte
+}
+
+func newFramer(cfg *config.Framing) (*framer, error) {
+	f := &framer{cfg: *cfg, order: binary.BigEndian}
+	switch cfg.Type {
+	case "length":
+		switch cfg.LengthSize {
+		case 1, 2, 4, 8:
+		default:
+			return nil, fmt.Errorf("invalid length size %d of the framing rule, it must be 1, 2, 4 or 8", cfg.LengthSize)
+		}
+		if cfg.LengthOffset < 0 {
+			return nil, fmt.Errorf("invalid length offset %d of the framing rule", cfg.LengthOffset)
+		}
+		switch cfg.Endianness {
+		case "", "big":
+		case "little":
+			f.order = binary.LittleEndian
+		default:
+			return nil, fmt.Errorf("invalid endianness %q of the framing rule, it must be big or little", cfg.Endianness)
+		}
+	case "delimiter":
+		if cfg.Delimiter == "" {
+			return nil, fmt.Errorf("the delimiter of the framing rule is empty")
+		}
+		f.delimiter = []byte(cfg.Delimiter)
+	case "fixed":
+		if cfg.Size <= 0 {
+			return nil, fmt.Errorf("invalid size %d of the framing rule", cfg.Size)
+		}
+	default:
+		return nil, fmt.Errorf("unknown framing type %q, it must be length, delimiter or fixed", cfg.Type)
+	}
+	return f, nil
+}
+
+// next returns the size of the first frame of the buffer, or 0 if the buffer doesn't hold a whole frame yet.
+func (f *framer) next(buf []byte) (int, error) {
+	switch f.cfg.Type {
+	case "length":
+		header := f.cfg.LengthOffset + f.cfg.LengthSize
+		if len(buf) < header {
+			return 0, nil
+		}
+		field := buf[f.cfg.LengthOffset:header]
+		var length uint64
+		switch f.cfg.LengthSize {
+		case 1:
+			length = uint64(field[0])
+		case 2:
+			length = uint64(f.order.Uint16(field))
+		case 4:
+			length = uint64(f.order.Uint32(field))
+		case 8:
+			length = f.order.Uint64(field)
+		}
+		if length > maxFrameSize {
+			return 0, fmt.Errorf("frame length %d is over the limit of %d bytes", length, maxFrameSize)
+		}
+		size := header + int(length) + f.cfg.LengthAdjust
+		if size < header {
+			return 0, fmt.Errorf("frame length %d is shorter than the frame header", length)
+		}
+		if len(buf) < size {
+			return 0, nil
+		}
+		return size, nil
+	case "delimiter":
+		idx := bytes.Index(buf, f.delimiter)
+		if idx < 0 {
+			return 0, nil
+		}
+		return idx + len(f.delimiter), nil
+	default:
+		if len(buf) < f.cfg.Size {
+			return 0, nil
+		}
+		return f.cfg.Size, nil
+	}
+}
+
+// splitter cuts the stream of a peer into frames. Without a framing rule, or once the stream doesn't fit the
+// rule, the chunks read from the conn are kept as they are.
+type splitter struct {
+	logger  *zap.Logger
+	framer  *framer
+	pending []byte
+}
+
+func newSplitter(logger *zap.Logger, cfg *config.Framing) *splitter {
+	s := &splitter{logger: logger}
+	if cfg == nil {
+		return s
+	}
+	f, err := newFramer(cfg)
+	if err != nil {
+		logger.Warn("ignoring the invalid framing rule of the port", zap.Uint("port", cfg.Port), zap.Error(err))
+		return s
+	}
+	s.framer = f
+	return s
+}
+
+// push adds the chunk to the stream and returns the frames completed by it.
+func (s *splitter) push(chunk []byte) [][]byte {
+	if s.framer == nil {
+		if len(chunk) == 0 {
+			return nil
+		}
+		return [][]byte{chunk}
+	}
+
+	s.pending = append(s.pending, chunk...)
+	var frames [][]byte
+	for len(s.pending) > 0 {
+		n, err := s.framer.next(s.pending)
+		if err != nil {
+			s.logger.Debug("the stream doesn't fit the framing rule, the rest of it isn't framed", zap.Error(err))
+			frames = append(frames, s.pending)
+			s.pending = nil
+			s.framer = nil
+			break
+		}
+		if n == 0 {
+			break
+		}
+		frames = append(frames, append([]byte(nil), s.pending[:n]...))
+		s.pending = s.pending[n:]
+	}
+	if len(s.pending) == 0 {
+		// release the consumed frames
+		s.pending = nil
+	}
+	return frames
+}
+
+// partial reports whether the stream ends within a frame.
+func (s *splitter) partial() bool {
+	return len(s.pending) > 0
+}
@@ -27,9 +27,9 @@ func NewGeneric(logger *zap.Logger) integrations.Integrations {
 	}
 }
 
-func (g *Generic) MatchType(_ context.Context, _ []byte) bool {
+func (g *Generic) MatchType(_ context.Context, _ []byte) integrations.MatchResult {
 	// generic is checked explicitly in the proxy
-	return false
+	return integrations.MatchResult{}
 }
 
 func (g *Generic) RecordOutgoing(ctx context.Context, src net.Conn, dst net.Conn, mocks chan<- *models.Mock, opts models.OutgoingOptions) error {
@@ -4,7 +4,6 @@ package generic
 
 import (
 	"context"
-	"encoding/base64"
 	"fmt"
 	"math"
 
@@ -20,13 +19,13 @@ import (
 // If a match is found, it returns the corresponding response mock and a boolean value indicating success.
 // If no match is found, it returns false and a nil response.
 // If an error occurs during the matching process, it returns an error.
-func fuzzyMatch(ctx context.Context, reqBuff [][]byte, mockDb integrations.MockMemDb) (bool, []models.Payload, error) {
+func fuzzyMatch(ctx context.Context, reqBuff [][]byte, mockDb integrations.MockMemDb, codec *protobufCodec) (bool, []models.Payload, error) {
 	for {
 		select {
 		case <-ctx.Done():
 			return false, nil, ctx.Err()
 		default:
-			mocks, err := mockDb.GetUnFilteredMocks()
+			mocks, err := mockDb.GetUnFilteredMocksByKey(models.GENERIC, "")
 			if err != nil {
 				return false, nil, fmt.Errorf("error while getting unfiltered mocks %v", err)
 			}
@@ -45,10 +44,10 @@ func fuzzyMatch(ctx context.Context, reqBuff [][]byte, mockDb integrations.MockM
 				}
 			}
 
-			index := findExactMatch(filteredMocks, reqBuff)
+			index := findExactMatch(filteredMocks, reqBuff, codec)
 
 			if index == -1 {
-				index = findBinaryMatch(filteredMocks, reqBuff, 0.9)
+				index = findBinaryMatch(filteredMocks, reqBuff, 0.9, codec)
 			}
 
 			if index != -1 {
@@ -64,7 +63,7 @@ func fuzzyMatch(ctx context.Context, reqBuff [][]byte, mockDb integrations.MockM
 				return true, responseMock, nil
 			}
 
-			index = findExactMatch(unfilteredMocks, reqBuff)
+			index = findExactMatch(unfilteredMocks, reqBuff, codec)
 
 			if index != -1 {
 				responseMock := make([]models.Payload, len(unfilteredMocks[index].Spec.GenericResponses))
@@ -73,7 +72,7 @@ func fuzzyMatch(ctx context.Context, reqBuff [][]byte, mockDb integrations.MockM
 			}
 
 			totalMocks := append(filteredMocks, unfilteredMocks...)
-			index = findBinaryMatch(totalMocks, reqBuff, 0.4)
+			index = findBinaryMatch(totalMocks, reqBuff, 0.4, codec)
 
 			if index != -1 {
 				responseMock := make([]models.Payload, len(totalMocks[index].Spec.GenericResponses))
@@ -95,14 +94,13 @@ func fuzzyMatch(ctx context.Context, reqBuff [][]byte, mockDb integrations.MockM
 }
 
 // TODO: need to generalize this function for different types of integrations.
-func findBinaryMatch(tcsMocks []*models.Mock, reqBuffs [][]byte, mxSim float64) int {
+func findBinaryMatch(tcsMocks []*models.Mock, reqBuffs [][]byte, mxSim float64, codec *protobufCodec) int {
 	// TODO: need find a proper similarity index to set a benchmark for matching or need to find another way to do approximate matching
 	mxIdx := -1
 	for idx, mock := range tcsMocks {
 		if len(mock.Spec.GenericRequests) == len(reqBuffs) {
 			for requestIndex, reqBuff := range reqBuffs {
-				_ = base64.StdEncoding.EncodeToString(reqBuff)
-				encoded, _ := util.DecodeBase64(mock.Spec.GenericRequests[requestIndex].Message[0].Data)
+				encoded := codec.raw(models.FromClient, mock.Spec.GenericRequests[requestIndex])
 
 				similarity := fuzzyCheck(encoded, reqBuff)
 
@@ -124,15 +122,26 @@ func fuzzyCheck(encoded, reqBuf []byte) float64 {
 	return similarity
 }
 
-func findExactMatch(tcsMocks []*models.Mock, reqBuffs [][]byte) int {
+// findExactMatch returns the index of the mock recording the requests, the protobuf messages are compared
+// on their fields rather than on their bytes.
+func findExactMatch(tcsMocks []*models.Mock, reqBuffs [][]byte, codec *protobufCodec) int {
 	for idx, mock := range tcsMocks {
 		if len(mock.Spec.GenericRequests) == len(reqBuffs) {
 			matched := true // Flag to track if all requests match
 
 			for requestIndex, reqBuff := range reqBuffs {
+				if recorded := mock.Spec.GenericRequests[requestIndex]; isProtobuf(recorded) {
+					if !codec.matches(models.FromClient, recorded, reqBuff) {
+						matched = false
+						break
+					}
+					continue
+				}
 
-				bufStr := string(reqBuff)
-				if !util.IsASCII(string(reqBuff)) {
+				var bufStr string
+				if util.IsASCIIBytes(reqBuff) {
+					bufStr = string(reqBuff)
+				} else {
 					bufStr = util.EncodeBase64(reqBuff)
 				}
 
@@ -0,0 +1,239 @@
+//go:build linux
+
+package generic
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"go.keploy.io/server/v2/config"
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations/util"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// protobufType is the type of the payloads holding the json of a protobuf message
+const protobufType = "protobuf"
+
+type codecKey struct {
+	descriptorSet string
+	request       string
+	response      string
+}
+
+// codecs caches the codecs of the framing rules, so that the descriptor sets are read once
+var codecs sync.Map
+
+// protobufCodec converts the frames of a length framing rule to the json of their protobuf messages and back.
+type protobufCodec struct {
+	framer   *framer
+	request  protoreflect.MessageDescriptor
+	response protoreflect.MessageDescriptor
+}
+
+// newProtobufCodec returns the codec of the framing rule, or nil if the rule names no protobuf messages.
+func newProtobufCodec(logger *zap.Logger, cfg *config.Framing) *protobufCodec {
+	if cfg == nil || cfg.Protobuf.DescriptorSet == "" {
+		return nil
+	}
+	c, err := loadProtobufCodec(cfg)
+	if err != nil {
+		logger.Warn("ignoring the protobuf messages of the framing rule of the port", zap.Uint("port", cfg.Port), zap.Error(err))
+		return nil
+	}
+	return c
+}
+
+func loadProtobufCodec(cfg *config.Framing) (*protobufCodec, error) {
+	if cfg.Type != "length" {
+		return nil, fmt.Errorf("the protobuf messages are only decoded from the frames of the length type")
+	}
+	key := codecKey{descriptorSet: cfg.Protobuf.DescriptorSet, request: cfg.Protobuf.Request, response: cfg.Protobuf.Response}
+	if c, ok := codecs.Load(key); ok {
+		return c.(*protobufCodec), nil
+	}
+
+	f, err := newFramer(cfg)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(cfg.Protobuf.DescriptorSet)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the protobuf descriptor set: %w", err)
+	}
+	set := &descriptorpb.FileDescriptorSet{}
+	if err := proto.Unmarshal(data, set); err != nil {
+		return nil, fmt.Errorf("failed to parse the protobuf descriptor set: %w", err)
+	}
+	files, err := protodesc.NewFiles(set)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the files of the protobuf descriptor set: %w", err)
+	}
+
+	c := &protobufCodec{framer: f}
+	for _, m := range []struct {
+		name string
+		desc *protoreflect.MessageDescriptor
+	}{{cfg.Protobuf.Request, &c.request}, {cfg.Protobuf.Response, &c.response}} {
+		name := m.name
+		if name == "" {
+			continue
+		}
+		d, err := files.FindDescriptorByName(protoreflect.FullName(name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to find the protobuf message %s: %w", name, err)
+		}
+		desc, ok := d.(protoreflect.MessageDescriptor)
+		if !ok {
+			return nil, fmt.Errorf("%s is not a protobuf message", name)
+		}
+		*m.desc = desc
+	}
+	codecs.Store(key, c)
+	return c, nil
+}
+
+func (c *protobufCodec) descriptor(origin models.OriginType) protoreflect.MessageDescriptor {
+	if c == nil {
+		return nil
+	}
+	if origin == models.FromClient {
+		return c.request
+	}
+	return c.response
+}
+
+// decode splits the frame into the bytes before its length field and its message.
+func (c *protobufCodec) decode(origin models.OriginType, frame []byte) ([]byte, *dynamicpb.Message, bool) {
+	md := c.descriptor(origin)
+	if md == nil {
+		return nil, nil, false
+	}
+	header := c.framer.cfg.LengthOffset + c.framer.cfg.LengthSize
+	if len(frame) < header {
+		return nil, nil, false
+	}
+	msg := dynamicpb.NewMessage(md)
+	if err := proto.Unmarshal(frame[header:], msg); err != nil {
+		return nil, nil, false
+	}
+	return frame[:c.framer.cfg.LengthOffset], msg, true
+}
+
+// payload returns the json of the message of the frame, along with the bytes before the length field. It
+// reports false when the frame doesn't hold a message of the rule.
+func (c *protobufCodec) payload(origin models.OriginType, frame []byte) (models.Payload, bool) {
+	prefix, msg, ok := c.decode(origin, frame)
+	if !ok {
+		return models.Payload{}, false
+	}
+	raw, err := protojson.Marshal(msg)
+	if err != nil {
+		return models.Payload{}, false
+	}
+	// protojson varies its whitespace on purpose, the json is indented again to be stable and readable
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, raw, "", "  "); err != nil {
+		return models.Payload{}, false
+	}
+
+	p := models.Payload{
+		Origin:  origin,
+		Message: []models.OutputBinary{{Type: protobufType, Data: indented.String()}},
+	}
+	if len(prefix) > 0 {
+		p.Message = append(p.Message, models.OutputBinary{Type: "binary", Data: util.EncodeBase64(prefix)})
+	}
+	return p, true
+}
+
+// message returns the recorded message of the payload.
+func (c *protobufCodec) message(origin models.OriginType, p models.Payload) (*dynamicpb.Message, error) {
+	md := c.descriptor(origin)
+	if md == nil {
+		return nil, fmt.Errorf("no protobuf message is configured for the %s payloads of the port", origin)
+	}
+	msg := dynamicpb.NewMessage(md)
+	if err := protojson.Unmarshal([]byte(p.Message[0].Data), msg); err != nil {
+		return nil, fmt.Errorf("failed to parse the recorded protobuf message: %w", err)
+	}
+	return msg, nil
+}
+
+// frame encodes the recorded payload back into a frame.
+func (c *protobufCodec) frame(origin models.OriginType, p models.Payload) ([]byte, error) {
+	msg, err := c.message(origin, p)
+	if err != nil {
+		return nil, err
+	}
+	body, err := proto.MarshalOptions{Deterministic: true}.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	var prefix []byte
+	if len(p.Message) > 1 {
+		prefix, err = util.DecodeBase64(p.Message[1].Data)
+		if err != nil {
+			return nil, err
+		}
+	}
+	cfg := c.framer.cfg
+	if len(prefix) != cfg.LengthOffset {
+		return nil, fmt.Errorf("the recorded frame header of %d bytes doesn't fit the length offset %d", len(prefix), cfg.LengthOffset)
+	}
+	length := len(body) - cfg.LengthAdjust
+	if length < 0 || (cfg.LengthSize < 8 && uint64(length) >= 1<<(8*cfg.LengthSize)) {
+		return nil, fmt.Errorf("the message of %d bytes doesn't fit the length field", len(body))
+	}
+
+	field := make([]byte, 8)
+	c.framer.order.PutUint64(field, uint64(length))
+	if c.framer.order == binary.BigEndian {
+		field = field[8-cfg.LengthSize:]
+	} else {
+		field = field[:cfg.LengthSize]
+	}
+
+	frame := make([]byte, 0, len(prefix)+len(field)+len(body))
+	frame = append(frame, prefix...)
+	frame = append(frame, field...)
+	return append(frame, body...), nil
+}
+
+// matches reports whether the frame holds the recorded message.
+func (c *protobufCodec) matches(origin models.OriginType, p models.Payload, frame []byte) bool {
+	recorded, err := c.message(origin, p)
+	if err != nil {
+		return false
+	}
+	_, msg, ok := c.decode(origin, frame)
+	return ok && proto.Equal(recorded, msg)
+}
+
+// raw returns the bytes of the recorded frame of the payload, for the similarity of the frames.
+func (c *protobufCodec) raw(origin models.OriginType, p models.Payload) []byte {
+	if isProtobuf(p) {
+		frame, err := c.frame(origin, p)
+		if err != nil {
+			return nil
+		}
+		return frame
+	}
+	encoded, _ := util.DecodeBase64(p.Message[0].Data)
+	return encoded
+}
+
+func isProtobuf(p models.Payload) bool {
+	return len(p.Message) > 0 && p.Message[0].Type == protobufType
+}
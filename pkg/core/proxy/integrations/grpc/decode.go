@@ -4,7 +4,9 @@
 package grpc
 
 import (
+	"bytes"
 	"context"
+	"io"
 	"net"
 
 	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
@@ -14,8 +16,9 @@ import (
 	"golang.org/x/net/http2"
 )
 
-func decodeGrpc(ctx context.Context, logger *zap.Logger, _ []byte, clientConn net.Conn, _ *integrations.ConditionalDstCfg, mockDb integrations.MockMemDb, _ models.OutgoingOptions) error {
-	framer := http2.NewFramer(clientConn, clientConn)
+func decodeGrpc(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientConn net.Conn, _ *integrations.ConditionalDstCfg, mockDb integrations.MockMemDb, _ models.OutgoingOptions) error {
+	// the frames following the preface in the initial buffer carry the first request
+	framer := http2.NewFramer(clientConn, io.MultiReader(bytes.NewReader(bytes.TrimPrefix(reqBuf, clientPreface)), clientConn))
 	srv := NewTranscoder(logger, framer, mockDb)
 	// fake server in the test mode
 	err := srv.ListenAndServe(ctx)
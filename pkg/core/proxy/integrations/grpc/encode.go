@@ -3,6 +3,7 @@
 package grpc
 
 import (
+	"bytes"
 	"context"
 	"io"
 	"net"
@@ -17,7 +18,8 @@ import (
 func encodeGrpc(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientConn, destConn net.Conn, mocks chan<- *models.Mock, _ models.OutgoingOptions) error {
 
 	// Send the client preface to the server. This should be the first thing sent from the client.
-	_, err := destConn.Write(reqBuf)
+	// The frames following it in the buffer are relayed like the rest, their headers update the hpack state.
+	_, err := destConn.Write(clientPreface)
 	if err != nil {
 		utils.LogError(logger, err, "Could not write preface onto the destination server")
 		return err
@@ -40,7 +42,8 @@ func encodeGrpc(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientCo
 	// Route requests from the client to the server.
 	g.Go(func() error {
 		defer pUtil.Recover(logger, clientConn, destConn)
-		err := transferFrame(ctx, destConn, clientConn, streamInfoCollection, reqFromClient, serverSideDecoder, mocks)
+		client := io.MultiReader(bytes.NewReader(reqBuf[len(clientPreface):]), clientConn)
+		err := transferFrame(ctx, destConn, client, streamInfoCollection, reqFromClient, serverSideDecoder, mocks)
 		if err != nil {
 			// check for EOF error
 			if err == io.EOF {
@@ -15,7 +15,7 @@ import (
 )
 
 // transferFrame reads one frame from rhs and writes it to lhs.
-func transferFrame(ctx context.Context, lhs net.Conn, rhs net.Conn, sic *StreamInfoCollection, reqFromClient bool, decoder *hpack.Decoder, mocks chan<- *models.Mock) error {
+func transferFrame(ctx context.Context, lhs net.Conn, rhs io.Reader, sic *StreamInfoCollection, reqFromClient bool, decoder *hpack.Decoder, mocks chan<- *models.Mock) error {
 	respFromServer := !reqFromClient
 	framer := http2.NewFramer(lhs, rhs)
 	for {
@@ -165,7 +165,11 @@ func transferFrame(ctx context.Context, lhs net.Conn, rhs net.Conn, sic *StreamI
 
 // constants for dynamic table size
 const (
-	KmaxDynamicTableSize = 2048
+	// KmaxDynamicTableSize is the initial size of the hpack dynamic table of an HTTP/2 conn, the encoders
+	// of the peers grow their tables to it after the first calls of the conn.
+	KmaxDynamicTableSize = 4096
+	// KmaxFrameSize is the default max size of the frames accepted by an HTTP/2 peer.
+	KmaxFrameSize = 16384
 )
 
 func extractHeaders(frame *http2.HeadersFrame, decoder *hpack.Decoder) (pseudoHeaders, ordinaryHeaders map[string]string, err error) {
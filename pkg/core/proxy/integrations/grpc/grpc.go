@@ -6,8 +6,10 @@ import (
 	"bytes"
 	"context"
 	"net"
+	"strings"
 
 	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	intgUtils "go.keploy.io/server/v2/pkg/core/proxy/integrations/util"
 	"go.keploy.io/server/v2/pkg/core/proxy/util"
 	"go.keploy.io/server/v2/pkg/models"
 	"go.keploy.io/server/v2/utils"
@@ -31,8 +33,29 @@ func NewGrpc(logger *zap.Logger) integrations.Integrations {
 
 // MatchType function determines if the outgoing network call is gRPC by comparing the
 // message format with that of an gRPC text message.
-func (g *Grpc) MatchType(_ context.Context, reqBuf []byte) bool {
-	return bytes.HasPrefix(reqBuf[:], []byte("PRI * HTTP/2"))
+// clientPreface is the connection preface sent by every HTTP/2 client
+var clientPreface = []byte("PRI * HTTP/2.0\r\n\r\nSM\r\n\r\n")
+
+func (g *Grpc) MatchType(_ context.Context, reqBuf []byte) integrations.MatchResult {
+	switch {
+	case bytes.HasPrefix(reqBuf, clientPreface):
+		// the conns of the other HTTP/2 clients are handled by the http2 integration
+		fields, complete := intgUtils.HTTP2RequestHeaders(reqBuf)
+		if !complete {
+			return integrations.MatchResult{Confidence: integrations.ConfidenceHigh, NeedBytes: len(reqBuf) + 1}
+		}
+		if contentType, _ := intgUtils.HTTP2Header(fields, "content-type"); fields != nil && !strings.HasPrefix(contentType, "application/grpc") {
+			return integrations.MatchResult{}
+		}
+		return integrations.MatchResult{Confidence: integrations.ConfidenceCertain}
+	case bytes.HasPrefix(reqBuf, []byte("PRI * HTTP/2")):
+		return integrations.MatchResult{Confidence: integrations.ConfidenceHigh, NeedBytes: len(clientPreface)}
+	case len(reqBuf) > 0 && bytes.HasPrefix(clientPreface, reqBuf):
+		// only a part of the preface has been received yet
+		return integrations.MatchResult{Confidence: integrations.ConfidenceLow, NeedBytes: len(clientPreface)}
+	default:
+		return integrations.MatchResult{}
+	}
 }
 
 func (g *Grpc) RecordOutgoing(ctx context.Context, src net.Conn, dst net.Conn, mocks chan<- *models.Mock, opts models.OutgoingOptions) error {
@@ -38,7 +38,7 @@ func FilterMocksBasedOnGrpcRequest(ctx context.Context, _ *zap.Logger, grpcReq m
 		case <-ctx.Done():
 			return nil, ctx.Err()
 		default:
-			mocks, err := mockDb.GetFilteredMocks()
+			mocks, err := mockDb.GetFilteredMocksByKey(models.GRPC_EXPORT, integrations.GRPCMockKey(grpcReq.Headers.PseudoHeaders[KLabelForPath]))
 			if err != nil {
 				return nil, fmt.Errorf("error while getting tsc mocks %v", err)
 			}
@@ -71,13 +71,8 @@ func FilterMocksBasedOnGrpcRequest(ctx context.Context, _ *zap.Logger, grpcReq m
 					continue
 				}
 
-				// Investigate the compression flag.
-				if have.Body.CompressionFlag != grpcReq.Body.CompressionFlag {
-					continue
-				}
-
-				// Investigate the body.
-				if have.Body.DecodedData != grpcReq.Body.DecodedData {
+				// Investigate the messages, the ones of the client streams are matched all together.
+				if !sameMessages(requestMessages(*have), requestMessages(grpcReq)) {
 					continue
 				}
 
@@ -97,3 +92,36 @@ func FilterMocksBasedOnGrpcRequest(ctx context.Context, _ *zap.Logger, grpcReq m
 		}
 	}
 }
+
+// requestMessages returns the messages sent by the client on the call.
+func requestMessages(req models.GrpcReq) []models.GrpcLengthPrefixedMessage {
+	msgs := req.Messages
+	if len(msgs) == 0 {
+		msgs = []models.GrpcLengthPrefixedMessage{req.Body}
+	}
+	// an empty body can't be told apart from a client stream without any message
+	if len(msgs) == 1 && msgs[0] == (models.GrpcLengthPrefixedMessage{}) {
+		return nil
+	}
+	return msgs
+}
+
+// responseMessages returns the messages to send back for the mock, in their recorded order.
+func responseMessages(mock *models.Mock) []models.GrpcLengthPrefixedMessage {
+	if len(mock.Spec.GRPCReq.Messages) > 0 || len(mock.Spec.GRPCResp.Messages) > 0 {
+		return mock.Spec.GRPCResp.Messages
+	}
+	return []models.GrpcLengthPrefixedMessage{mock.Spec.GRPCResp.Body}
+}
+
+func sameMessages(have, want []models.GrpcLengthPrefixedMessage) bool {
+	if len(have) != len(want) {
+		return false
+	}
+	for i := range have {
+		if have[i].CompressionFlag != want[i].CompressionFlag || have[i].DecodedData != want[i].DecodedData {
+			return false
+		}
+	}
+	return true
+}
@@ -4,13 +4,10 @@ package grpc
 
 import (
 	"context"
-	"encoding/binary"
-	"fmt"
 	"sync"
 	"time"
 
-	"github.com/protocolbuffers/protoscope"
-
+	"go.keploy.io/server/v2/pkg"
 	"go.keploy.io/server/v2/pkg/models"
 )
 
@@ -22,12 +19,29 @@ type StreamInfoCollection struct {
 	StreamInfo       map[uint32]models.GrpcStream
 	ReqTimestampMock time.Time
 	ResTimestampMock time.Time
+	// pending holds the bytes of the messages that are split across DATA frames.
+	pending map[uint32]*pendingPayload
+}
+
+type pendingPayload struct {
+	req  []byte
+	resp []byte
 }
 
 func NewStreamInfoCollection() *StreamInfoCollection {
 	return &StreamInfoCollection{
 		StreamInfo: make(map[uint32]models.GrpcStream),
+		pending:    make(map[uint32]*pendingPayload),
+	}
+}
+
+func (sic *StreamInfoCollection) pendingFor(streamID uint32) *pendingPayload {
+	p, ok := sic.pending[streamID]
+	if !ok {
+		p = &pendingPayload{}
+		sic.pending[streamID] = p
 	}
+	return p
 }
 
 func (sic *StreamInfoCollection) InitialiseStream(streamID uint32) {
@@ -78,31 +92,46 @@ func (sic *StreamInfoCollection) AddHeadersForResponse(streamID uint32, headers
 	}
 }
 
-// AddPayloadForRequest adds the DATA frame to the stream.
+// AddPayloadForRequest adds the messages of the DATA frame to the stream, a frame can carry a part of
+// a message or several of them.
 // A data frame always appears after at least one header frame. Hence, we implicitly
 // assume that the stream has been initialised.
 func (sic *StreamInfoCollection) AddPayloadForRequest(streamID uint32, payload []byte) {
 	sic.mutex.Lock()
 	defer sic.mutex.Unlock()
 
+	p := sic.pendingFor(streamID)
+	var msgs []models.GrpcLengthPrefixedMessage
+	msgs, p.req = pkg.SplitGrpcMessages(append(p.req, payload...))
+
 	// We cannot modify non pointer values in nested entries in map.
 	// Create a copy and overwrite it.
 	info := sic.StreamInfo[streamID]
-	info.GrpcReq.Body = createLengthPrefixedMessageFromPayload(payload)
+	info.GrpcReq.Messages = append(info.GrpcReq.Messages, msgs...)
+	if len(info.GrpcReq.Messages) > 0 {
+		info.GrpcReq.Body = info.GrpcReq.Messages[0]
+	}
 	sic.StreamInfo[streamID] = info
 }
 
-// AddPayloadForResponse adds the DATA frame to the stream.
+// AddPayloadForResponse adds the messages of the DATA frame to the stream.
 // A data frame always appears after at least one header frame. Hence, we implicitly
 // assume that the stream has been initialised.
 func (sic *StreamInfoCollection) AddPayloadForResponse(streamID uint32, payload []byte) {
 	sic.mutex.Lock()
 	defer sic.mutex.Unlock()
 
+	p := sic.pendingFor(streamID)
+	var msgs []models.GrpcLengthPrefixedMessage
+	msgs, p.resp = pkg.SplitGrpcMessages(append(p.resp, payload...))
+
 	// We cannot modify non pointer values in nested entries in map.
 	// Create a copy and overwrite it.
 	info := sic.StreamInfo[streamID]
-	info.GrpcResp.Body = createLengthPrefixedMessageFromPayload(payload)
+	info.GrpcResp.Messages = append(info.GrpcResp.Messages, msgs...)
+	if len(info.GrpcResp.Messages) > 0 {
+		info.GrpcResp.Body = info.GrpcResp.Messages[0]
+	}
 	sic.StreamInfo[streamID] = info
 }
 
@@ -111,6 +140,8 @@ func (sic *StreamInfoCollection) PersistMockForStream(_ context.Context, streamI
 	defer sic.mutex.Unlock()
 	grpcReq := sic.StreamInfo[streamID].GrpcReq
 	grpcResp := sic.StreamInfo[streamID].GrpcResp
+	// the unary calls are stored with their bodies only
+	pkg.CompactGrpcCall(&grpcReq, &grpcResp)
 	// save the mock
 	mocks <- &models.Mock{
 		Version: models.GetVersion(),
@@ -137,46 +168,5 @@ func (sic *StreamInfoCollection) ResetStream(streamID uint32) {
 	defer sic.mutex.Unlock()
 
 	delete(sic.StreamInfo, streamID)
-}
-
-func createLengthPrefixedMessageFromPayload(data []byte) models.GrpcLengthPrefixedMessage {
-	msg := models.GrpcLengthPrefixedMessage{}
-
-	// If the body is not length prefixed, we return the default value.
-	if len(data) < 5 {
-		return msg
-	}
-
-	// The first byte is the compression flag.
-	msg.CompressionFlag = uint(data[0])
-
-	// The next 4 bytes are message length.
-	msg.MessageLength = binary.BigEndian.Uint32(data[1:5])
-
-	// The payload could be empty. We only parse it if it is present.
-	if len(data) >= 5 {
-		// Use protoscope to decode the message.
-		msg.DecodedData = protoscope.Write(data[5:], protoscope.WriterOptions{})
-	}
-
-	return msg
-}
-
-func createPayloadFromLengthPrefixedMessage(msg models.GrpcLengthPrefixedMessage) ([]byte, error) {
-	scanner := protoscope.NewScanner(msg.DecodedData)
-	encodedData, err := scanner.Exec()
-	if err != nil {
-		return nil, fmt.Errorf("could not encode grpc msg using protoscope: %v", err)
-	}
-
-	// Note that the encoded length is present in the msg, but it is also equal to the len of encodedData.
-	// We should give the preference to the length of encodedData, since the mocks might have been altered.
-
-	// Reserve 1 byte for compression flag, 4 bytes for length capture.
-	payload := make([]byte, 1+4)
-	payload[0] = uint8(msg.CompressionFlag)
-	binary.BigEndian.PutUint32(payload[1:5], uint32(len(encodedData)))
-	payload = append(payload, encodedData...)
-
-	return payload, nil
+	delete(sic.pending, streamID)
 }
@@ -7,7 +7,9 @@ import (
 	"context"
 	"fmt"
 
+	"go.keploy.io/server/v2/pkg"
 	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	"go.keploy.io/server/v2/pkg/models"
 	"go.keploy.io/server/v2/utils"
 
 	"go.uber.org/zap"
@@ -73,9 +75,18 @@ func (srv *Transcoder) ProcessDataFrame(ctx context.Context, dataFrame *http2.Da
 	}
 	srv.sic.AddPayloadForRequest(id, dataFrame.Data())
 
-	if dataFrame.StreamEnded() {
-		defer srv.sic.ResetStream(dataFrame.StreamID)
+	// The request is matched once the client has sent all of its messages, which are
+	// aggregated for the client streams.
+	if !dataFrame.StreamEnded() {
+		return nil
 	}
+	return srv.respond(ctx, id)
+}
+
+// respond writes the response of the mock matching the request of the stream, the messages of the server
+// streams are written in their recorded order before the trailers.
+func (srv *Transcoder) respond(ctx context.Context, id uint32) error {
+	defer srv.sic.ResetStream(id)
 
 	grpcReq := srv.sic.FetchRequestForStream(id)
 
@@ -90,65 +101,50 @@ func (srv *Transcoder) ProcessDataFrame(ctx context.Context, dataFrame *http2.Da
 
 	grpcMockResp := mock.Spec.GRPCResp
 
-	// First, send the headers frame.
-	buf := new(bytes.Buffer)
-	encoder := hpack.NewEncoder(buf)
-
-	// The pseudo headers should be written before ordinary ones.
-	for key, value := range grpcMockResp.Headers.PseudoHeaders {
-		err := encoder.WriteField(hpack.HeaderField{
-			Name:  key,
-			Value: value,
-		})
-		if err != nil {
-			utils.LogError(srv.logger, err, "could not encode pseudo header", zap.Any("key", key), zap.Any("value", value))
-			return err
-		}
-	}
-	for key, value := range grpcMockResp.Headers.OrdinaryHeaders {
-		err := encoder.WriteField(hpack.HeaderField{
-			Name:  key,
-			Value: value,
-		})
+	// A response without headers is a trailers-only response, the server failed the call right away.
+	if len(grpcMockResp.Headers.PseudoHeaders) > 0 {
+		// First, send the headers frame.
+		srv.logger.Info("Writing the first set of headers in a new HEADER frame.")
+		err = srv.writeHeaders(id, grpcMockResp.Headers, false)
 		if err != nil {
-			utils.LogError(srv.logger, err, "could not encode ordinary header", zap.Any("key", key), zap.Any("value", value))
+			utils.LogError(srv.logger, err, "could not write the first set of headers onto client")
 			return err
 		}
-	}
 
-	// The headers are prepared. Write the frame.
-	srv.logger.Info("Writing the first set of headers in a new HEADER frame.")
-	err = srv.framer.WriteHeaders(http2.HeadersFrameParam{
-		StreamID:      id,
-		BlockFragment: buf.Bytes(),
-		EndStream:     false,
-		EndHeaders:    true,
-	})
-	if err != nil {
-		utils.LogError(srv.logger, err, "could not write the first set of headers onto client")
-		return err
-	}
+		for _, msg := range responseMessages(mock) {
+			payload, err := pkg.GrpcPayloadFromMessage(msg)
+			if err != nil {
+				utils.LogError(srv.logger, err, "could not create grpc payload from mocks")
+				return err
+			}
 
-	payload, err := createPayloadFromLengthPrefixedMessage(grpcMockResp.Body)
-	if err != nil {
-		utils.LogError(srv.logger, err, "could not create grpc payload from mocks")
-		return err
+			// Write the DATA frames with the payload.
+			err = srv.writeData(id, payload)
+			if err != nil {
+				utils.LogError(srv.logger, err, "could not write the data frame onto the client")
+				return err
+			}
+		}
 	}
 
-	// Write the DATA frame with the payload.
-	err = srv.framer.WriteData(id, false, payload)
+	// The trailer is prepared. Write the frame.
+	srv.logger.Info("Writing the trailers in a different HEADER frame")
+	err = srv.writeHeaders(id, grpcMockResp.Trailers, true)
 	if err != nil {
-		utils.LogError(srv.logger, err, "could not write the data frame onto the client")
+		utils.LogError(srv.logger, err, "could not write the trailers onto client")
 		return err
 	}
 
-	// Reset the buffer and start with a new encoding.
-	buf = new(bytes.Buffer)
-	encoder = hpack.NewEncoder(buf)
+	return nil
+}
+
+// writeHeaders encodes the headers and writes them in a HEADERS frame.
+func (srv *Transcoder) writeHeaders(id uint32, headers models.GrpcHeaders, endStream bool) error {
+	buf := new(bytes.Buffer)
+	encoder := hpack.NewEncoder(buf)
 
-	//Prepare the trailers.
-	//The pseudo headers should be written before ordinary ones.
-	for key, value := range grpcMockResp.Trailers.PseudoHeaders {
+	// The pseudo headers should be written before ordinary ones.
+	for key, value := range headers.PseudoHeaders {
 		err := encoder.WriteField(hpack.HeaderField{
 			Name:  key,
 			Value: value,
@@ -158,7 +154,7 @@ func (srv *Transcoder) ProcessDataFrame(ctx context.Context, dataFrame *http2.Da
 			return err
 		}
 	}
-	for key, value := range grpcMockResp.Trailers.OrdinaryHeaders {
+	for key, value := range headers.OrdinaryHeaders {
 		err := encoder.WriteField(hpack.HeaderField{
 			Name:  key,
 			Value: value,
@@ -169,19 +165,24 @@ func (srv *Transcoder) ProcessDataFrame(ctx context.Context, dataFrame *http2.Da
 		}
 	}
 
-	// The trailer is prepared. Write the frame.
-	srv.logger.Info("Writing the trailers in a different HEADER frame")
-	err = srv.framer.WriteHeaders(http2.HeadersFrameParam{
+	return srv.framer.WriteHeaders(http2.HeadersFrameParam{
 		StreamID:      id,
 		BlockFragment: buf.Bytes(),
-		EndStream:     true,
+		EndStream:     endStream,
 		EndHeaders:    true,
 	})
-	if err != nil {
-		utils.LogError(srv.logger, err, "could not write the trailers onto client")
-		return err
-	}
+}
 
+// writeData writes the payload in DATA frames of at most the default max frame size.
+func (srv *Transcoder) writeData(id uint32, payload []byte) error {
+	for len(payload) > 0 {
+		n := min(len(payload), KmaxFrameSize)
+		err := srv.framer.WriteData(id, false, payload[:n])
+		if err != nil {
+			return err
+		}
+		payload = payload[n:]
+	}
 	return nil
 }
 
@@ -220,7 +221,7 @@ func (srv *Transcoder) ProcessPriorityFrame(_ *http2.PriorityFrame) error {
 	return nil
 }
 
-func (srv *Transcoder) ProcessHeadersFrame(headersFrame *http2.HeadersFrame) error {
+func (srv *Transcoder) ProcessHeadersFrame(ctx context.Context, headersFrame *http2.HeadersFrame) error {
 	id := headersFrame.StreamID
 	// Streams initiated by a client MUST use odd-numbered stream identifiers
 	if id%2 != 1 {
@@ -235,6 +236,11 @@ func (srv *Transcoder) ProcessHeadersFrame(headersFrame *http2.HeadersFrame) err
 
 	srv.sic.AddHeadersForRequest(id, pseudoHeaders, true)
 	srv.sic.AddHeadersForRequest(id, ordinaryHeaders, false)
+
+	// the client stream is closed without any message
+	if headersFrame.StreamEnded() {
+		return srv.respond(ctx, id)
+	}
 	return nil
 }
 
@@ -271,7 +277,7 @@ func (srv *Transcoder) ProcessGenericFrame(ctx context.Context, frame http2.Fram
 	case *http2.PriorityFrame:
 		err = srv.ProcessPriorityFrame(frame)
 	case *http2.HeadersFrame:
-		err = srv.ProcessHeadersFrame(frame)
+		err = srv.ProcessHeadersFrame(ctx, frame)
 	case *http2.PushPromiseFrame:
 		err = srv.ProcessPushPromise(frame)
 	case *http2.ContinuationFrame:
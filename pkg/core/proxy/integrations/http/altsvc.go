@@ -0,0 +1,91 @@
+//go:build linux
+
+package http
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+)
+
+// The HTTP/3 calls are made over QUIC (udp), which isn't redirected to the proxy, so the application would
+// reach the server without being recorded or mocked. The clients switch to HTTP/3 only once a server
+// advertises it as an alternative service, the HTTP/3 alternatives are removed from the Alt-Svc headers
+// of the responses to keep the application on the tcp conns.
+
+// isHTTP3Alt reports whether the alternative service is reached over QUIC e.g. `h3=":443"; ma=86400`.
+func isHTTP3Alt(alt string) bool {
+	protocol, _, _ := strings.Cut(strings.TrimSpace(alt), "=")
+	protocol = strings.ToLower(strings.TrimSpace(protocol))
+	return strings.HasPrefix(protocol, "h3") || strings.HasPrefix(protocol, "quic")
+}
+
+// withoutHTTP3 returns the value of an Alt-Svc header without the HTTP/3 alternatives, it reports
+// whether any alternative was removed.
+func withoutHTTP3(value string) (string, bool) {
+	var kept []string
+	removed := false
+	for _, alt := range strings.Split(value, ",") {
+		if isHTTP3Alt(alt) {
+			removed = true
+			continue
+		}
+		kept = append(kept, strings.TrimSpace(alt))
+	}
+	return strings.Join(kept, ", "), removed
+}
+
+// stripHTTP3AltSvc removes the HTTP/3 alternatives from the Alt-Svc headers of a raw response, the
+// header is dropped when no alternative remains. The response is returned as is if its headers aren't complete.
+func stripHTTP3AltSvc(resp []byte) ([]byte, bool) {
+	end := bytes.Index(resp, []byte("\r\n\r\n"))
+	if end == -1 {
+		return resp, false
+	}
+
+	lines := bytes.Split(resp[:end], []byte("\r\n"))
+	kept := lines[:1]
+	stripped := false
+	for _, line := range lines[1:] {
+		name, value, ok := bytes.Cut(line, []byte(":"))
+		if !ok || !strings.EqualFold(string(bytes.TrimSpace(name)), "Alt-Svc") {
+			kept = append(kept, line)
+			continue
+		}
+		alts, removed := withoutHTTP3(string(value))
+		if !removed {
+			kept = append(kept, line)
+			continue
+		}
+		stripped = true
+		if alts != "" {
+			kept = append(kept, []byte(string(name)+": "+alts))
+		}
+	}
+	if !stripped {
+		return resp, false
+	}
+
+	out := bytes.Join(kept, []byte("\r\n"))
+	return append(out, resp[end:]...), true
+}
+
+// dropHTTP3AltSvc removes the HTTP/3 alternatives from the Alt-Svc header of the mocked responses.
+func dropHTTP3AltSvc(header http.Header) {
+	values := header.Values("Alt-Svc")
+	if len(values) == 0 {
+		return
+	}
+	header.Del("Alt-Svc")
+	for _, value := range values {
+		if alts, _ := withoutHTTP3(value); alts != "" {
+			header.Add("Alt-Svc", alts)
+		}
+	}
+}
+
+// advertisesHTTP3 reports whether an Alt-Svc header value advertises an HTTP/3 alternative.
+func advertisesHTTP3(value string) bool {
+	_, removed := withoutHTTP3(value)
+	return removed
+}
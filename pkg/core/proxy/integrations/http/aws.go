@@ -0,0 +1,177 @@
+//go:build linux
+
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations/util"
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+const sigV4Algorithm = "AWS4-HMAC-SHA256"
+
+// awsSigningParams are the query params of the presigned urls, they change on every run like the
+// Authorization and the X-Amz-Date headers of the signed requests.
+var awsSigningParams = map[string]bool{
+	"X-Amz-Algorithm":      true,
+	"X-Amz-Credential":     true,
+	"X-Amz-Date":           true,
+	"X-Amz-Expires":        true,
+	"X-Amz-Security-Token": true,
+	"X-Amz-Signature":      true,
+	"X-Amz-SignedHeaders":  true,
+}
+
+// awsCall is a request of an AWS SDK signed with SigV4.
+type awsCall struct {
+	service   string
+	region    string
+	operation string
+	// bucket and key are set for the calls of S3
+	bucket string
+	key    string
+	// query is the query without the signing params, with the range of the S3 downloads
+	query string
+	// body is the normalized body, or the digest of the content of the S3 uploads
+	body string
+}
+
+func (c *awsCall) canonical() string {
+	return c.query + "\n" + c.body
+}
+
+// parseAWSCall returns the call of a request signed with SigV4, either in its Authorization header or
+// in its presigned url, and nil for the other requests.
+func parseAWSCall(method string, u *url.URL, host string, header http.Header, body []byte) *awsCall {
+	query := u.Query()
+	credential := query.Get("X-Amz-Credential")
+	if auth := header.Get("Authorization"); strings.HasPrefix(auth, sigV4Algorithm+" ") {
+		for _, part := range strings.Split(strings.TrimPrefix(auth, sigV4Algorithm+" "), ",") {
+			if value, ok := strings.CutPrefix(strings.TrimSpace(part), "Credential="); ok {
+				credential = value
+			}
+		}
+	}
+	// the credential scope is access-key/date/region/service/aws4_request
+	scope := strings.Split(credential, "/")
+	if len(scope) != 5 || scope[4] != "aws4_request" {
+		return nil
+	}
+
+	call := &awsCall{
+		region:  scope[2],
+		service: scope[3],
+	}
+	contentType := header.Get("Content-Type")
+	form, isForm := url.Values(nil), strings.HasPrefix(contentType, "application/x-www-form-urlencoded")
+	if isForm {
+		form, _ = url.ParseQuery(string(body))
+	}
+	// the json services name the operation in X-Amz-Target, the query services in the Action param
+	switch {
+	case header.Get("X-Amz-Target") != "":
+		call.operation = header.Get("X-Amz-Target")
+	case form.Get("Action") != "":
+		call.operation = form.Get("Action")
+	case query.Get("Action") != "":
+		call.operation = query.Get("Action")
+	}
+
+	params := url.Values{}
+	for key, values := range query {
+		if !awsSigningParams[key] {
+			params[key] = values
+		}
+	}
+	if call.service == "s3" {
+		call.bucket, call.key = s3Object(host, u.Path)
+		// the parallel downloads fetch the ranges of the same object
+		if r := header.Get("Range"); r != "" {
+			params.Set("Range", r)
+		}
+	}
+	// url.Values encodes the params sorted by their keys
+	call.query = params.Encode()
+
+	call.body = string(body)
+	switch {
+	case isS3Upload(call, method, body):
+		call.body = s3Digest(header, body)
+	case isForm:
+		call.body = form.Encode()
+	case strings.Contains(contentType, "json"):
+		call.body = normalizeJSON(body)
+	}
+	return call
+}
+
+// normalizeJSON returns the json with its keys sorted, or as is if it isn't valid json.
+func normalizeJSON(body []byte) string {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return string(body)
+	}
+	normalized, err := json.Marshal(value)
+	if err != nil {
+		return string(body)
+	}
+	return string(normalized)
+}
+
+// awsMatch finds the mock of a signed AWS call among the mocks of the same path. The mock has to call the
+// same operation of the same service in the same region, and the S3 mocks the same object. Then the mock
+// with the same canonical query and body is preferred, or else the most similar one. The signatures and the dates are left out. The mocks
+// recorded during the current test case are tried first.
+func awsMatch(mocks []*models.Mock, call *awsCall) *models.Mock {
+	var best *models.Mock
+	bestSim := -1.0
+	for _, filtered := range []bool{true, false} {
+		for _, mock := range mocks {
+			if mock.TestModeInfo.IsFiltered != filtered {
+				continue
+			}
+			recorded := recordedAWSCall(mock)
+			if recorded == nil || recorded.service != call.service || recorded.region != call.region || recorded.operation != call.operation ||
+				recorded.bucket != call.bucket || recorded.key != call.key {
+				continue
+			}
+			if recorded.canonical() == call.canonical() {
+				return mock
+			}
+			k := util.AdaptiveK(len(call.canonical()), 3, 8, 5)
+			if sim := util.JaccardSimilarity(util.CreateShingles([]byte(recorded.canonical()), k), util.CreateShingles([]byte(call.canonical()), k)); sim > bestSim {
+				best, bestSim = mock, sim
+			}
+		}
+		if best != nil {
+			return best
+		}
+	}
+	return nil
+}
+
+// recordedAWSCall parses the signed AWS call of a mocked request. The host isn't part of the recorded
+// headers, it is kept in the metadata of the mock.
+func recordedAWSCall(mock *models.Mock) *awsCall {
+	req := mock.Spec.HTTPReq
+	header := http.Header{}
+	for key, value := range req.Header {
+		header.Set(key, value)
+	}
+	parsedURL, err := url.Parse(req.URL)
+	if err != nil {
+		return nil
+	}
+	call := parseAWSCall(string(req.Method), parsedURL, mock.Spec.Metadata["host"], header, []byte(req.Body))
+	if call != nil && req.BodyDigest != "" {
+		call.body = req.BodyDigest
+	}
+	return call
+}
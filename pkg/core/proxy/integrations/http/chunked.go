@@ -0,0 +1,196 @@
+//go:build linux
+
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
+
+	"go.keploy.io/server/v2/pkg"
+	"go.keploy.io/server/v2/pkg/core/proxy/util"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+// The chunked bodies are framed as
+//
+//	hex-size[;chunk-extension]\r\ndata\r\n
+//
+// up to a chunk of size 0, which is followed by the trailer fields, if any, and an empty line.
+// See: https://www.rfc-editor.org/rfc/rfc9112#section-7.1
+
+// chunkDecoder decodes a chunked body as it is received.
+type chunkDecoder struct {
+	buf  []byte
+	done bool
+	// sizes are the sizes of the chunks decoded, trailer the trailer section sent after the last chunk
+	sizes   []int
+	trailer []byte
+}
+
+// decode returns the data of the chunks completed by the received bytes, done is set once the trailer section
+// following the last chunk is received.
+func (d *chunkDecoder) decode(data []byte) ([]byte, error) {
+	d.buf = append(d.buf, data...)
+	var out []byte
+	for !d.done {
+		line := bytes.Index(d.buf, []byte("\r\n"))
+		if line == -1 {
+			break
+		}
+		sizeField, _, _ := strings.Cut(string(d.buf[:line]), ";")
+		size, err := strconv.ParseInt(strings.TrimSpace(sizeField), 16, 64)
+		if err != nil || size < 0 {
+			return out, fmt.Errorf("invalid chunk size %q", sizeField)
+		}
+		if size == 0 {
+			rest := d.buf[line+2:]
+			if bytes.HasPrefix(rest, []byte("\r\n")) {
+				d.buf = rest[2:]
+				d.done = true
+				break
+			}
+			end := bytes.Index(rest, []byte("\r\n\r\n"))
+			if end == -1 {
+				break
+			}
+			d.trailer = append(d.trailer, rest[:end+2]...)
+			d.buf = rest[end+4:]
+			d.done = true
+			break
+		}
+		end := line + 2 + int(size)
+		if len(d.buf) < end+2 {
+			break
+		}
+		out = append(out, d.buf[line+2:end]...)
+		d.sizes = append(d.sizes, int(size))
+		d.buf = d.buf[end+2:]
+	}
+	return out, nil
+}
+
+// trailerHeader returns the trailer fields decoded.
+func (d *chunkDecoder) trailerHeader() http.Header {
+	if len(d.trailer) == 0 {
+		return nil
+	}
+	fields, err := textproto.NewReader(bufio.NewReader(bytes.NewReader(append(d.trailer, "\r\n"...)))).ReadMIMEHeader()
+	if err != nil && err != io.EOF {
+		return nil
+	}
+	return http.Header(fields)
+}
+
+// framing returns the Content-Length of the message and whether its body is chunked, from its headers. The
+// Transfer-Encoding overrides the Content-Length, the names of the headers are case-insensitive.
+func framing(msg []byte) (contentLength string, chunked bool) {
+	msg = pkg.StripInterimHTTPResponses(msg)
+	end := bytes.Index(msg, []byte("\r\n\r\n"))
+	if end == -1 {
+		end = len(msg)
+	}
+	lines := strings.Split(string(msg[:end]), "\r\n")
+	for _, line := range lines[1:] {
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		switch http.CanonicalHeaderKey(strings.TrimSpace(name)) {
+		case "Content-Length":
+			contentLength = strings.TrimSpace(value)
+		case "Transfer-Encoding":
+			// the chunked coding is the last one applied to the body
+			codings := strings.Split(value, ",")
+			chunked = strings.EqualFold(strings.TrimSpace(codings[len(codings)-1]), "chunked")
+		}
+	}
+	return contentLength, chunked
+}
+
+// bodyOf returns the part of the body received along with the headers of the message.
+func bodyOf(msg []byte) []byte {
+	msg = pkg.StripInterimHTTPResponses(msg)
+	end := bytes.Index(msg, []byte("\r\n\r\n"))
+	if end == -1 {
+		return nil
+	}
+	return msg[end+4:]
+}
+
+// chunkFraming returns the sizes of the chunks and the trailer fields of the chunked body of the message.
+func chunkFraming(msg []byte) ([]int, http.Header) {
+	chunks := &chunkDecoder{}
+	if _, err := chunks.decode(bodyOf(msg)); err != nil || !chunks.done {
+		return nil, nil
+	}
+	return chunks.sizes, chunks.trailerHeader()
+}
+
+// readChunked reads the chunked body of the message from src up to its last chunk and its trailer section. The
+// bytes read are forwarded to dst, which is nil in test mode.
+func readChunked(ctx context.Context, logger *zap.Logger, msg *[]byte, src, dst net.Conn) error {
+	chunks := &chunkDecoder{}
+	if _, err := chunks.decode(bodyOf(*msg)); err != nil {
+		return err
+	}
+	for !chunks.done {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		data, err := util.ReadBytes(ctx, logger, src)
+		if len(data) > 0 {
+			*msg = append(*msg, data...)
+			if dst != nil {
+				if _, werr := dst.Write(data); werr != nil {
+					if ctx.Err() != nil {
+						return ctx.Err()
+					}
+					utils.LogError(logger, werr, "failed to forward the chunked body")
+					return werr
+				}
+			}
+			if _, derr := chunks.decode(data); derr != nil {
+				return derr
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeChunked frames the body in chunks of the sizes recorded, the data left past them is sent in one last chunk.
+func encodeChunked(body []byte, sizes []int, trailer http.Header) []byte {
+	var b bytes.Buffer
+	for _, size := range sizes {
+		if len(body) == 0 {
+			break
+		}
+		if size > len(body) {
+			size = len(body)
+		}
+		fmt.Fprintf(&b, "%x\r\n", size)
+		b.Write(body[:size])
+		b.WriteString("\r\n")
+		body = body[size:]
+	}
+	if len(body) > 0 {
+		fmt.Fprintf(&b, "%x\r\n", len(body))
+		b.Write(body)
+		b.WriteString("\r\n")
+	}
+	b.WriteString("0\r\n")
+	_ = trailer.Write(&b)
+	b.WriteString("\r\n")
+	return b.Bytes()
+}
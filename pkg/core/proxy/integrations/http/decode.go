@@ -6,13 +6,14 @@ package http
 import (
 	"bufio"
 	"bytes"
-	"compress/gzip"
 	"context"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
+	"sort"
 	"strconv"
+	"strings"
 
 	"go.keploy.io/server/v2/pkg"
 	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
@@ -29,11 +30,20 @@ func decodeHTTP(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientCo
 	go func(errCh chan error, reqBuf []byte, opts models.OutgoingOptions) {
 		defer pUtil.Recover(logger, clientConn, nil)
 		defer close(errCh)
+		// pending is the start of the next request, read along with the previous one
+		var pending []byte
 		for {
-			//Check if the expected header is present
-			if bytes.Contains(reqBuf, []byte("Expect: 100-continue")) {
+			err := readRequestHeaders(ctx, logger, &reqBuf, clientConn, nil)
+			if err != nil {
+				utils.LogError(logger, err, "failed to read the request headers")
+				errCh <- err
+				return
+			}
+
+			//Check if the client waits for the 100 continue response before sending the body
+			if pkg.ExpectsContinue(reqBuf) {
 				logger.Debug("The expect header is present in the request buffer and writing the 100 continue response to the client")
-				//Send the 100 continue response
+				//Send the 100 continue response, the body is read along with the rest of the request below
 				_, err := clientConn.Write([]byte("HTTP/1.1 100 Continue\r\n\r\n"))
 				if err != nil {
 					if ctx.Err() != nil {
@@ -44,24 +54,16 @@ func decodeHTTP(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientCo
 					return
 				}
 				logger.Debug("The 100 continue response has been sent to the user application")
-				//Read the request buffer again
-				newRequest, err := pUtil.ReadBytes(ctx, logger, clientConn)
-				if err != nil {
-					utils.LogError(logger, err, "failed to read the request buffer from the user application")
-					errCh <- err
-					return
-				}
-				//Append the new request buffer to the old request buffer
-				reqBuf = append(reqBuf, newRequest...)
 			}
 
 			logger.Debug("handling the chunked requests to read the complete request")
-			err := handleChunkedRequests(ctx, logger, &reqBuf, clientConn, nil)
+			err = handleChunkedRequests(ctx, logger, &reqBuf, clientConn, nil)
 			if err != nil {
 				utils.LogError(logger, err, "failed to handle chunked requests")
 				errCh <- err
 				return
 			}
+			reqBuf, pending = splitMessage(reqBuf, false, false)
 
 			logger.Debug(fmt.Sprintf("This is the complete request:\n%v", string(reqBuf)))
 
@@ -79,15 +81,18 @@ func decodeHTTP(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientCo
 				errCh <- err
 				return
 			}
+			// the bodies of the mocks are stored decoded
+			reqBody = decodedBody(logger, reqBody, request.Header.Get("Content-Encoding"))
 
 			input := &req{
 				method: request.Method,
 				url:    request.URL,
+				host:   request.Host,
 				header: request.Header,
 				body:   reqBody,
 				raw:    reqBuf,
 			}
-			ok, stub, err := match(ctx, logger, input, mockDb)
+			ok, stub, err := match(ctx, logger, input, mockDb, opts.HTTP)
 			if err != nil {
 				utils.LogError(logger, err, "error while matching http mocks", zap.Any("metadata", getReqMeta(request)))
 				errCh <- err
@@ -100,12 +105,13 @@ func decodeHTTP(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientCo
 					utils.LogError(logger, nil, "Didn't match any preExisting http mock", zap.Any("metadata", getReqMeta(request)))
 				}
 				if opts.FallBackOnMiss {
-					_, err = pUtil.PassThrough(ctx, logger, clientConn, dstCfg, [][]byte{reqBuf})
+					resp, err := pUtil.PassThrough(ctx, logger, clientConn, dstCfg, [][]byte{reqBuf})
 					if err != nil {
 						utils.LogError(logger, err, "failed to passThrough http request", zap.Any("metadata", getReqMeta(request)))
 						errCh <- err
 						return
 					}
+					bindPassedToken(logger, input, resp, mockDb)
 				}
 				errCh <- nil
 				return
@@ -119,34 +125,83 @@ func decodeHTTP(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientCo
 
 			// Fetching the response headers
 			header := pkg.ToHTTPHeader(stub.Spec.HTTPResp.Header)
+			dropHTTP3AltSvc(header)
 
-			//Check if the gzip encoding is present in the header
-			if header["Content-Encoding"] != nil && header["Content-Encoding"][0] == "gzip" {
-				var compressedBuffer bytes.Buffer
-				gw := gzip.NewWriter(&compressedBuffer)
-				_, err := gw.Write([]byte(body))
+			if len(stub.Spec.HTTPResp.Events) > 0 {
+				chunked := request.ProtoAtLeast(1, 1)
+				err = writeEventStream(ctx, clientConn, statusLine, header, stub.Spec.HTTPResp, chunked, opts)
 				if err != nil {
-					utils.LogError(logger, err, "failed to compress the response body", zap.Any("metadata", getReqMeta(request)))
+					if ctx.Err() != nil {
+						return
+					}
+					utils.LogError(logger, err, "failed to write the mocked event stream to the user application", zap.Any("metadata", getReqMeta(request)))
 					errCh <- err
 					return
 				}
-				err = gw.Close()
+				if !chunked {
+					// the HTTP/1.0 stream ends with the conn
+					errCh <- nil
+					return
+				}
+				if stub.Spec.Metadata["stream"] == "open" {
+					// the server hadn't ended the stream when it was recorded, it is kept open until the application closes the conn
+					_, _ = io.Copy(io.Discard, clientConn)
+					errCh <- nil
+					return
+				}
+				_, err = clientConn.Write([]byte("0\r\n\r\n"))
 				if err != nil {
-					utils.LogError(logger, err, "failed to close the gzip writer", zap.Any("metadata", getReqMeta(request)))
+					if ctx.Err() != nil {
+						return
+					}
+					utils.LogError(logger, err, "failed to write the end of the mocked event stream to the user application", zap.Any("metadata", getReqMeta(request)))
 					errCh <- err
 					return
 				}
-				logger.Debug("the length of the response body: " + strconv.Itoa(len(compressedBuffer.String())))
-				respBody = compressedBuffer.String()
-				// responseString = statusLine + headers + "\r\n" + compressedBuffer.String()
-			} else {
-				respBody = body
-				// responseString = statusLine + headers + "\r\n" + body
+
+				if pending != nil {
+					reqBuf, pending = pending, nil
+					continue
+				}
+				reqBuf, err = pUtil.ReadBytes(ctx, logger, clientConn)
+				if err != nil {
+					logger.Debug("failed to read the request buffer from the client", zap.Error(err))
+					errCh <- nil
+					return
+				}
+				continue
+			}
+
+			// the body is stored decoded, it's encoded again as the server sent it
+			encoded, err := encodedBody(logger, []byte(body), header.Get("Content-Encoding"))
+			if err != nil {
+				utils.LogError(logger, err, "failed to encode the response body", zap.Any("metadata", getReqMeta(request)))
+				errCh <- err
+				return
+			}
+			logger.Debug("the length of the response body: " + strconv.Itoa(len(encoded)))
+			respBody = string(encoded)
+
+			// the chunked responses are framed in the chunks recorded, the HTTP/1.0 clients get the body as it is
+			if len(stub.Spec.HTTPResp.Chunks) > 0 && request.ProtoAtLeast(1, 1) {
+				trailer := pkg.ToHTTPHeader(stub.Spec.HTTPResp.Trailer)
+				header.Del("Content-Length")
+				header.Set("Transfer-Encoding", "chunked")
+				if len(trailer) > 0 {
+					names := make([]string, 0, len(trailer))
+					for name := range trailer {
+						names = append(names, name)
+					}
+					sort.Strings(names)
+					header.Set("Trailer", strings.Join(names, ", "))
+				}
+				respBody = string(encodeChunked([]byte(respBody), stub.Spec.HTTPResp.Chunks, trailer))
 			}
 
 			var headers string
 			for key, values := range header {
-				if key == "Content-Length" {
+				// the responses to the HEAD requests keep the Content-Length of the body they'd have
+				if key == "Content-Length" && request.Method != http.MethodHead {
 					values = []string{strconv.Itoa(len(respBody))}
 				}
 				for _, value := range values {
@@ -168,6 +223,10 @@ func decodeHTTP(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientCo
 				return
 			}
 
+			if pending != nil {
+				reqBuf, pending = pending, nil
+				continue
+			}
 			reqBuf, err = pUtil.ReadBytes(ctx, logger, clientConn)
 			if err != nil {
 				logger.Debug("failed to read the request buffer from the client", zap.Error(err))
@@ -0,0 +1,167 @@
+//go:build linux
+
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations/util"
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+// elasticActions are the actions of the bulk api, all but delete are followed by a document line.
+var elasticActions = map[string]bool{
+	"index":  true,
+	"create": true,
+	"update": true,
+	"delete": true,
+}
+
+// elasticIgnoredFields change on every run, e.g. the ids generated by the clients for the indexed
+// documents, so they are left out when matching.
+var elasticIgnoredFields = []string{"_id", "took"}
+
+// isElasticBulk reports whether the request is a call of the bulk api of Elasticsearch.
+func isElasticBulk(method string, path string) bool {
+	return (method == "POST" || method == "PUT") && (path == "/_bulk" || strings.HasSuffix(path, "/_bulk"))
+}
+
+// isElasticSearch reports whether the request is a search of Elasticsearch, single or multiple.
+func isElasticSearch(method string, path string) bool {
+	if method != "GET" && method != "POST" {
+		return false
+	}
+	for _, endpoint := range []string{"_search", "_msearch"} {
+		if path == "/"+endpoint || strings.HasSuffix(path, "/"+endpoint) {
+			return true
+		}
+	}
+	return false
+}
+
+// elasticRequest is the body of a bulk call or a search, with every NDJSON line normalized.
+type elasticRequest struct {
+	// lines are the JSON lines with their keys sorted and without the ignored fields
+	lines []string
+	// actions are the bulk actions with their index, in the order of the call
+	actions []string
+}
+
+// parseElastic normalizes the NDJSON lines of a bulk call or the JSON body of a search.
+func parseElastic(body []byte, bulk bool) (*elasticRequest, error) {
+	req := &elasticRequest{}
+	expectDocument := false
+	for i, line := range bytes.Split(body, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		decoder := json.NewDecoder(bytes.NewReader(line))
+		decoder.UseNumber()
+		var value interface{}
+		if err := decoder.Decode(&value); err != nil {
+			return nil, fmt.Errorf("invalid json at line %d: %v", i+1, err)
+		}
+
+		if bulk && !expectDocument {
+			action, err := elasticAction(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid bulk action at line %d: %v", i+1, err)
+			}
+			req.actions = append(req.actions, action)
+			expectDocument = !strings.HasPrefix(action, "delete")
+		} else {
+			expectDocument = false
+		}
+
+		if obj, ok := value.(map[string]interface{}); ok {
+			removeIgnoredFields(obj)
+		}
+		// the keys of the maps are sorted by the encoder
+		normalized, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		req.lines = append(req.lines, string(normalized))
+	}
+	if len(req.lines) == 0 && bulk {
+		return nil, errors.New("no actions in the bulk call")
+	}
+	return req, nil
+}
+
+// elasticAction returns the action of a bulk action line with the index it applies to.
+func elasticAction(value interface{}) (string, error) {
+	obj, ok := value.(map[string]interface{})
+	if !ok || len(obj) != 1 {
+		return "", errors.New("the action line must hold a single action")
+	}
+	for action, meta := range obj {
+		if !elasticActions[action] {
+			return "", fmt.Errorf("unknown action %q", action)
+		}
+		if meta, ok := meta.(map[string]interface{}); ok {
+			if index, ok := meta["_index"].(string); ok {
+				return action + ":" + index, nil
+			}
+		}
+		return action, nil
+	}
+	return "", nil
+}
+
+// removeIgnoredFields removes the ignored fields from the top level of a line, and from the metadata of
+// a bulk action.
+func removeIgnoredFields(obj map[string]interface{}) {
+	for _, field := range elasticIgnoredFields {
+		delete(obj, field)
+	}
+	if len(obj) != 1 {
+		return
+	}
+	for action, meta := range obj {
+		if meta, ok := meta.(map[string]interface{}); ok && elasticActions[action] {
+			for _, field := range elasticIgnoredFields {
+				delete(meta, field)
+			}
+		}
+	}
+}
+
+// elasticMatch finds the mock of a bulk call or a search among the mocks of the same path. The bulk
+// mocks have to run the same actions on the same indices, then the mock with the same normalized lines
+// is preferred, or else the one with the most similar lines. The mocks recorded during the current test
+// case are tried first.
+func elasticMatch(mocks []*models.Mock, req *elasticRequest, bulk bool) *models.Mock {
+	body := strings.Join(req.lines, "\n")
+	var best *models.Mock
+	bestSim := -1.0
+	for _, filtered := range []bool{true, false} {
+		for _, mock := range mocks {
+			if mock.TestModeInfo.IsFiltered != filtered {
+				continue
+			}
+			recorded, err := parseElastic([]byte(mock.Spec.HTTPReq.Body), bulk)
+			if err != nil || !slices.Equal(recorded.actions, req.actions) {
+				continue
+			}
+			recordedBody := strings.Join(recorded.lines, "\n")
+			if recordedBody == body {
+				return mock
+			}
+			k := util.AdaptiveK(len(body), 3, 8, 5)
+			if sim := util.JaccardSimilarity(util.CreateShingles([]byte(recordedBody), k), util.CreateShingles([]byte(body), k)); sim > bestSim {
+				best, bestSim = mock, sim
+			}
+		}
+		if best != nil {
+			return best
+		}
+	}
+	return nil
+}
@@ -8,11 +8,11 @@ import (
 	"fmt"
 	"io"
 	"net"
-	"strings"
 	"time"
 
 	"golang.org/x/sync/errgroup"
 
+	"go.keploy.io/server/v2/pkg"
 	"go.keploy.io/server/v2/pkg/core/proxy/util"
 	pUtil "go.keploy.io/server/v2/pkg/core/proxy/util"
 	"go.keploy.io/server/v2/pkg/models"
@@ -53,76 +53,75 @@ func encodeHTTP(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientCo
 	g.Go(func() error {
 		defer pUtil.Recover(logger, clientConn, destConn)
 		defer close(errCh)
+		// pendingReq and pendingResp are the starts of the next request and response, read and forwarded along
+		// with the previous ones
+		var pendingReq, pendingResp []byte
 		for {
-			//check if expect : 100-continue header is present
-			lines := strings.Split(string(finalReq), "\n")
-			var expectHeader string
-			for _, line := range lines {
-				if strings.HasPrefix(line, "Expect:") {
-					expectHeader = strings.TrimSpace(strings.TrimPrefix(line, "Expect:"))
-					break
-				}
+			if err := readRequestHeaders(ctx, logger, &finalReq, clientConn, destConn); err != nil {
+				utils.LogError(logger, err, "failed to read the request headers")
+				errCh <- err
+				return nil
 			}
-			if expectHeader == "100-continue" {
+
+			// finalRespEarly is the final response of the server when it answers an "Expect: 100-continue"
+			// request without asking for the body, the client doesn't send the body then.
+			var finalRespEarly []byte
+			if pkg.ExpectsContinue(finalReq) {
 				//Read if the response from the server is 100-continue
-				resp, err := util.ReadBytes(ctx, logger, destConn)
+				resp, err := readContinue(ctx, logger, destConn)
 				if err != nil {
 					utils.LogError(logger, err, "failed to read the response message from the server after 100-continue request")
 					errCh <- err
 					return nil
 				}
-
-				// write the response message to the client
-				_, err = clientConn.Write(resp)
-				if err != nil {
-					if ctx.Err() != nil {
-						return ctx.Err()
-					}
-					utils.LogError(logger, err, "failed to write response message to the user client")
-					errCh <- err
-					return nil
-				}
-
 				logger.Debug("This is the response from the server after the expect header" + string(resp))
 
-				if string(resp) != "HTTP/1.1 100 Continue\r\n\r\n" {
-					utils.LogError(logger, nil, "failed to get the 100 continue response from the user client")
-					errCh <- err
-					return nil
-				}
-				//Reading the request buffer again
-				reqBuf, err = util.ReadBytes(ctx, logger, clientConn)
-				if err != nil {
-					utils.LogError(logger, err, "failed to read the request buffer from the user client")
-					errCh <- err
-					return nil
-				}
-				// write the request message to the actual destination server
-				_, err = destConn.Write(reqBuf)
-				if err != nil {
-					if ctx.Err() != nil {
-						return ctx.Err()
+				if len(resp) == 0 {
+					logger.Debug("the server didn't answer the expect header, the client sends the body anyway")
+				} else if pkg.IsInterimHTTPResponse(resp) && len(pkg.StripInterimHTTPResponses(resp)) == 0 {
+					// write the interim response to the client so that it sends the body
+					_, err = clientConn.Write(resp)
+					if err != nil {
+						if ctx.Err() != nil {
+							return ctx.Err()
+						}
+						utils.LogError(logger, err, "failed to write response message to the user client")
+						errCh <- err
+						return nil
 					}
-					utils.LogError(logger, err, "failed to write request message to the destination server")
-					errCh <- err
-					return nil
+				} else {
+					finalRespEarly = resp
 				}
-				finalReq = append(finalReq, reqBuf...)
 			}
 
 			// Capture the request timestamp
 			reqTimestampMock := time.Now()
 
-			err := handleChunkedRequests(ctx, logger, &finalReq, clientConn, destConn)
-			if err != nil {
-				utils.LogError(logger, err, "failed to handle chunked requests")
-				errCh <- err
-				return nil
+			if finalRespEarly == nil {
+				err := handleChunkedRequests(ctx, logger, &finalReq, clientConn, destConn)
+				if err != nil {
+					utils.LogError(logger, err, "failed to handle chunked requests")
+					errCh <- err
+					return nil
+				}
+				finalReq, pendingReq = splitMessage(finalReq, false, false)
 			}
 
 			logger.Debug(fmt.Sprintf("This is the complete request:\n%v", string(finalReq)))
 			// read the response from the actual server
-			resp, err := util.ReadBytes(ctx, logger, destConn)
+			resp := finalRespEarly
+			forwarded := false
+			var err error
+			if resp == nil && pendingResp != nil {
+				resp, pendingResp, forwarded = pendingResp, nil, true
+			} else if resp == nil {
+				resp, err = util.ReadBytes(ctx, logger, destConn)
+			}
+			// the application is kept off HTTP/3, its QUIC conns would bypass the proxy
+			if stripped, ok := stripHTTP3AltSvc(resp); ok {
+				logger.Debug("removed the http3 alternative services advertised by the server")
+				resp = stripped
+			}
 			if err != nil {
 				if err == io.EOF {
 					logger.Debug("Response complete, exiting the loop.")
@@ -167,20 +166,22 @@ func encodeHTTP(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientCo
 			resTimestampMock := time.Now()
 
 			// write the response message to the user client
-			_, err = clientConn.Write(resp)
-			if err != nil {
-				if ctx.Err() != nil {
-					return ctx.Err()
+			if !forwarded {
+				_, err = clientConn.Write(resp)
+				if err != nil {
+					if ctx.Err() != nil {
+						return ctx.Err()
+					}
+					utils.LogError(logger, err, "failed to write response message to the user client")
+					errCh <- err
+					return nil
 				}
-				utils.LogError(logger, err, "failed to write response message to the user client")
-				errCh <- err
-				return nil
 			}
 			var finalResp []byte
 			finalResp = append(finalResp, resp...)
 			logger.Debug("This is the initial response: " + string(resp))
 
-			err = handleChunkedResponses(ctx, logger, &finalResp, clientConn, destConn, resp)
+			err = handleChunkedResponses(ctx, logger, &finalResp, clientConn, destConn, resp, finalReq)
 			if err != nil {
 				if err == io.EOF {
 					logger.Debug("conn closed by the server", zap.Error(err))
@@ -203,8 +204,7 @@ func encodeHTTP(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientCo
 				errCh <- err
 				return nil
 			}
-
-			logger.Debug("This is the final response: " + string(finalResp))
+			finalResp, pendingResp = splitMessage(finalResp, true, isBodiless(finalReq, finalResp))
 
 			m := &finalHTTP{
 				req:              finalReq,
@@ -213,17 +213,39 @@ func encodeHTTP(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientCo
 				resTimestampMock: resTimestampMock,
 			}
 
-			err = ParseFinalHTTP(ctx, logger, m, destPort, mocks, opts)
-			if err != nil {
-				utils.LogError(logger, err, "failed to parse the final http request and response")
-				errCh <- err
-				return nil
+			if isEventStream(finalResp) {
+				// the events are forwarded and recorded as the server sends them
+				err = recordEventStream(ctx, logger, m, clientConn, destConn, destPort, mocks, opts)
+				if err != nil {
+					if ctx.Err() != nil {
+						return ctx.Err()
+					}
+					if err == io.EOF {
+						err = nil
+					}
+					errCh <- err
+					return nil
+				}
+			} else {
+				logger.Debug("This is the final response: " + string(finalResp))
+
+				err = ParseFinalHTTP(ctx, logger, m, destPort, mocks, opts)
+				if err != nil {
+					utils.LogError(logger, err, "failed to parse the final http request and response")
+					errCh <- err
+					return nil
+				}
 			}
 
 			//resetting for the new request and response.
 			finalReq = []byte("")
 			finalResp = []byte("")
 
+			if pendingReq != nil {
+				finalReq, pendingReq = pendingReq, nil
+				continue
+			}
+
 			finalReq, err = util.ReadBytes(ctx, logger, clientConn)
 			if err != nil {
 				if err != io.EOF {
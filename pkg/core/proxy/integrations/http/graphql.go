@@ -0,0 +1,40 @@
+//go:build linux
+
+package http
+
+import (
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations/util"
+	"go.keploy.io/server/v2/pkg/matcher"
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+// graphQLMatch finds the mock of a GraphQL request among the mocks of the same path. The mock has to run
+// the same operation with the same normalized query, then the mock with the same variables is preferred,
+// or else the one with the most similar variables. The mocks recorded during the current test case are
+// tried first.
+func graphQLMatch(mocks []*models.Mock, req *matcher.GraphQLRequest) *models.Mock {
+	var best *models.Mock
+	bestSim := -1.0
+	for _, filtered := range []bool{true, false} {
+		for _, mock := range mocks {
+			if mock.TestModeInfo.IsFiltered != filtered {
+				continue
+			}
+			recorded, err := matcher.ParseGraphQLRequest([]byte(mock.Spec.HTTPReq.Body))
+			if err != nil || recorded.OperationName != req.OperationName || recorded.Query != req.Query {
+				continue
+			}
+			if recorded.Variables == req.Variables {
+				return mock
+			}
+			k := util.AdaptiveK(len(req.Variables), 3, 8, 5)
+			if sim := util.JaccardSimilarity(util.CreateShingles([]byte(recorded.Variables), k), util.CreateShingles([]byte(req.Variables), k)); sim > bestSim {
+				best, bestSim = mock, sim
+			}
+		}
+		if best != nil {
+			return best
+		}
+	}
+	return nil
+}
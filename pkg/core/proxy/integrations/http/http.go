@@ -5,7 +5,6 @@ package http
 import (
 	"bufio"
 	"bytes"
-	"compress/gzip"
 	"context"
 	"fmt"
 	"io"
@@ -15,6 +14,7 @@ import (
 	"time"
 
 	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	intgUtils "go.keploy.io/server/v2/pkg/core/proxy/integrations/util"
 	"go.keploy.io/server/v2/pkg/core/proxy/util"
 	"go.keploy.io/server/v2/utils"
 
@@ -43,11 +43,14 @@ type finalHTTP struct {
 	resp             []byte
 	reqTimestampMock time.Time
 	resTimestampMock time.Time
+	// events are the events of a text/event-stream response, streamOpen is set if the server didn't end the stream
+	events     []models.ServerSentEvent
+	streamOpen bool
 }
 
 // MatchType function determines if the outgoing network call is HTTP by comparing the
 // message format with that of an HTTP text message.
-func (h *HTTP) MatchType(_ context.Context, buf []byte) bool {
+func (h *HTTP) MatchType(_ context.Context, buf []byte) integrations.MatchResult {
 	isHTTP := bytes.HasPrefix(buf[:], []byte("HTTP/")) ||
 		bytes.HasPrefix(buf[:], []byte("GET ")) ||
 		bytes.HasPrefix(buf[:], []byte("POST ")) ||
@@ -57,7 +60,27 @@ func (h *HTTP) MatchType(_ context.Context, buf []byte) bool {
 		bytes.HasPrefix(buf[:], []byte("OPTIONS ")) ||
 		bytes.HasPrefix(buf[:], []byte("HEAD "))
 	h.logger.Debug(fmt.Sprintf("is Http Protocol?: %v ", isHTTP))
-	return isHTTP
+	if !isHTTP {
+		return integrations.MatchResult{}
+	}
+
+	// the request line ends with the protocol version e.g. "GET / HTTP/1.1\r\n"
+	lineEnd := bytes.Index(buf, []byte("\r\n"))
+	if lineEnd == -1 {
+		return integrations.MatchResult{Confidence: integrations.ConfidenceMedium, NeedBytes: len(buf) + 1}
+	}
+	// the websocket handshakes are handled by the websocket integration
+	upgrade, complete := intgUtils.WebSocketUpgrade(buf)
+	if upgrade {
+		return integrations.MatchResult{}
+	}
+	if !complete {
+		return integrations.MatchResult{Confidence: integrations.ConfidenceHigh, NeedBytes: len(buf) + 1}
+	}
+	if bytes.Contains(buf[:lineEnd], []byte(" HTTP/1.")) || bytes.HasPrefix(buf, []byte("HTTP/")) {
+		return integrations.MatchResult{Confidence: integrations.ConfidenceCertain}
+	}
+	return integrations.MatchResult{Confidence: integrations.ConfidenceMedium}
 }
 
 func (h *HTTP) RecordOutgoing(ctx context.Context, src net.Conn, dst net.Conn, mocks chan<- *models.Mock, opts models.OutgoingOptions) error {
@@ -106,6 +129,14 @@ func ParseFinalHTTP(_ context.Context, logger *zap.Logger, mock *finalHTTP, dest
 		return err
 	}
 
+	// the trailer fields of the chunked requests are stored apart from their headers
+	var reqTrailer map[string]string
+	if _, chunked := framing(mock.req); chunked {
+		if _, trailer := chunkFraming(mock.req); trailer != nil {
+			reqTrailer = pkg.ToYamlHTTPHeader(trailer)
+		}
+	}
+
 	var reqBody []byte
 	if req.Body != nil { // Read
 		var err error
@@ -115,10 +146,12 @@ func ParseFinalHTTP(_ context.Context, logger *zap.Logger, mock *finalHTTP, dest
 			utils.LogError(logger, err, "failed to read the http request body", zap.Any("metadata", getReqMeta(req)))
 			return err
 		}
+		reqBody = decodedBody(logger, reqBody, req.Header.Get("Content-Encoding"))
 	}
 
 	// converts the response message buffer to http response
-	respParsed, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(mock.resp)), req)
+	// the interim responses are skipped, the mock keeps only the final response
+	respParsed, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(pkg.StripInterimHTTPResponses(mock.resp))), req)
 	if err != nil {
 		utils.LogError(logger, err, "failed to parse the http response message", zap.Any("metadata", getReqMeta(req)))
 		return err
@@ -128,28 +161,28 @@ func ParseFinalHTTP(_ context.Context, logger *zap.Logger, mock *finalHTTP, dest
 	var respBody []byte
 	//Checking if the body of the response is empty or does not exist.
 	if respParsed.Body != nil { // Read
-		if respParsed.Header.Get("Content-Encoding") == "gzip" {
-			check := respParsed.Body
-			ok, reader := isGZipped(check, logger)
-			logger.Debug("The body is gzip? " + strconv.FormatBool(ok))
-			logger.Debug("", zap.Any("isGzipped", ok))
-			if ok {
-				gzipReader, err := gzip.NewReader(reader)
-				if err != nil {
-					utils.LogError(logger, err, "failed to create a gzip reader", zap.Any("metadata", getReqMeta(req)))
-					return err
-				}
-				respParsed.Body = gzipReader
-			}
-		}
 		respBody, err = io.ReadAll(respParsed.Body)
 		if err != nil {
 			utils.LogError(logger, err, "failed to read the the http response body", zap.Any("metadata", getReqMeta(req)))
 			return err
 		}
+		respBody = decodedBody(logger, respBody, respParsed.Header.Get("Content-Encoding"))
 		logger.Debug("This is the response body: " + string(respBody))
-		//Set the content length to the headers.
-		respParsed.Header.Set("Content-Length", strconv.Itoa(len(respBody)))
+		//Set the content length to the headers, the bodiless responses keep the one of the body they'd have.
+		if mock.events == nil && !isBodiless(mock.req, mock.resp) {
+			respParsed.Header.Set("Content-Length", strconv.Itoa(len(respBody)))
+		}
+	}
+
+	// the chunked responses are replayed in the chunks they were sent in, some clients read them chunk by chunk
+	var respChunks []int
+	var respTrailer map[string]string
+	if _, chunked := framing(mock.resp); chunked && mock.events == nil {
+		sizes, trailer := chunkFraming(mock.resp)
+		respChunks = append([]int{}, sizes...)
+		if trailer != nil {
+			respTrailer = pkg.ToYamlHTTPHeader(trailer)
+		}
 	}
 
 	// store the request and responses as mocks
@@ -158,6 +191,39 @@ func ParseFinalHTTP(_ context.Context, logger *zap.Logger, mock *finalHTTP, dest
 		"type":      models.HTTPClient,
 		"operation": req.Method,
 	}
+	if mock.streamOpen {
+		meta["stream"] = "open"
+	}
+
+	// the content of the uploads to S3 is stored as its digest, the objects can be hundreds of MBs
+	var bodyDigest string
+	if call := parseAWSCall(req.Method, req.URL, req.Host, req.Header, reqBody); call != nil {
+		// the host names the bucket of the virtual-hosted S3 requests
+		meta["host"] = req.Host
+		if isS3Upload(call, req.Method, reqBody) {
+			bodyDigest, reqBody = call.body, nil
+		}
+	}
+
+	// the points written to InfluxDB are stored to be matched without their timestamps
+	var influxPts []models.InfluxPoint
+	if isInfluxWrite(req.Method, req.URL.Path) {
+		influxPts, err = influxPoints(reqBody, req.Header.Get("Content-Encoding"))
+		if err != nil {
+			logger.Debug("failed to parse the line protocol of the influxdb write", zap.Error(err), zap.Any("metadata", getReqMeta(req)))
+		}
+	}
+
+	// the multipart forms are stored by their parts, their boundary changes with every upload
+	var form []models.FormData
+	if contentType := req.Header.Get("Content-Type"); pkg.IsMultipartForm(contentType) && bodyDigest == "" {
+		form, err = pkg.ParseMultipartForm(reqBody, contentType)
+		if err == nil {
+			reqBody = nil
+		} else {
+			logger.Debug("failed to parse the multipart form of the request", zap.Error(err), zap.Any("metadata", getReqMeta(req)))
+		}
+	}
 
 	// Check if the request is a passThrough request
 	if IsPassThrough(logger, req, destPort, opts) {
@@ -172,18 +238,25 @@ func ParseFinalHTTP(_ context.Context, logger *zap.Logger, mock *finalHTTP, dest
 		Spec: models.MockSpec{
 			Metadata: meta,
 			HTTPReq: &models.HTTPReq{
-				Method:     models.Method(req.Method),
-				ProtoMajor: req.ProtoMajor,
-				ProtoMinor: req.ProtoMinor,
-				URL:        req.URL.String(),
-				Header:     pkg.ToYamlHTTPHeader(req.Header),
-				Body:       string(reqBody),
-				URLParams:  pkg.URLParams(req),
+				Method:       models.Method(req.Method),
+				ProtoMajor:   req.ProtoMajor,
+				ProtoMinor:   req.ProtoMinor,
+				URL:          req.URL.String(),
+				Header:       pkg.ToYamlHTTPHeader(req.Header),
+				Body:         string(reqBody),
+				BodyDigest:   bodyDigest,
+				Form:         form,
+				URLParams:    pkg.URLParams(req),
+				InfluxPoints: influxPts,
+				Trailer:      reqTrailer,
 			},
 			HTTPResp: &models.HTTPResp{
 				StatusCode: respParsed.StatusCode,
 				Header:     pkg.ToYamlHTTPHeader(respParsed.Header),
 				Body:       string(respBody),
+				Events:     mock.events,
+				Chunks:     respChunks,
+				Trailer:    respTrailer,
 			},
 			Created:          time.Now().Unix(),
 			ReqTimestampMock: mock.resTimestampMock,
@@ -0,0 +1,145 @@
+//go:build linux
+
+package http
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	intgUtils "go.keploy.io/server/v2/pkg/core/proxy/integrations/util"
+	"go.keploy.io/server/v2/pkg/core/proxy/util"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+	"golang.org/x/net/http2/hpack"
+)
+
+func init() {
+	integrations.Register("http2", NewHTTP2)
+}
+
+// HTTP2 records and mocks the HTTP/2 calls other than gRPC, the exchanges are stored as http mocks
+// so that they are matched like the HTTP/1 calls.
+type HTTP2 struct {
+	logger *zap.Logger
+}
+
+func NewHTTP2(logger *zap.Logger) integrations.Integrations {
+	return &HTTP2{
+		logger: logger,
+	}
+}
+
+// MatchType function determines if the outgoing network call is HTTP/2 by decoding the headers of its
+// first request, the conns with prior knowledge and the ones negotiated with ALPN both start with the preface.
+func (h *HTTP2) MatchType(_ context.Context, buf []byte) integrations.MatchResult {
+	switch {
+	case bytes.HasPrefix(buf, intgUtils.HTTP2ClientPreface):
+		fields, complete := intgUtils.HTTP2RequestHeaders(buf)
+		if !complete {
+			return integrations.MatchResult{Confidence: integrations.ConfidenceMedium, NeedBytes: len(buf) + 1}
+		}
+		if fields == nil {
+			return integrations.MatchResult{Confidence: integrations.ConfidenceLow}
+		}
+		// gRPC calls are handled by the grpc integration
+		if contentType, _ := intgUtils.HTTP2Header(fields, "content-type"); strings.HasPrefix(contentType, "application/grpc") {
+			return integrations.MatchResult{}
+		}
+		return integrations.MatchResult{Confidence: integrations.ConfidenceCertain}
+	case len(buf) > 0 && bytes.HasPrefix(intgUtils.HTTP2ClientPreface, buf):
+		// only a part of the preface has been received yet
+		return integrations.MatchResult{Confidence: integrations.ConfidenceLow, NeedBytes: len(intgUtils.HTTP2ClientPreface)}
+	default:
+		return integrations.MatchResult{}
+	}
+}
+
+func (h *HTTP2) RecordOutgoing(ctx context.Context, src net.Conn, dst net.Conn, mocks chan<- *models.Mock, opts models.OutgoingOptions) error {
+	logger := h.logger.With(zap.Any("Client IP Address", src.RemoteAddr().String()), zap.Any("Client ConnectionID", ctx.Value(models.ClientConnectionIDKey).(string)), zap.Any("Destination ConnectionID", ctx.Value(models.DestConnectionIDKey).(string)))
+
+	reqBuf, err := util.ReadInitialBuf(ctx, logger, src)
+	if err != nil {
+		utils.LogError(logger, err, "failed to read the initial http2 message")
+		return err
+	}
+
+	err = encodeHTTP2(ctx, logger, reqBuf, src, dst, mocks, opts)
+	if err != nil {
+		utils.LogError(logger, err, "failed to encode the http2 message into the yaml")
+		return err
+	}
+	return nil
+}
+
+func (h *HTTP2) MockOutgoing(ctx context.Context, src net.Conn, dstCfg *integrations.ConditionalDstCfg, mockDb integrations.MockMemDb, opts models.OutgoingOptions) error {
+	logger := h.logger.With(zap.Any("Client IP Address", src.RemoteAddr().String()), zap.Any("Client ConnectionID", ctx.Value(models.ClientConnectionIDKey).(string)), zap.Any("Destination ConnectionID", ctx.Value(models.DestConnectionIDKey).(string)))
+
+	reqBuf, err := util.ReadInitialBuf(ctx, logger, src)
+	if err != nil {
+		utils.LogError(logger, err, "failed to read the initial http2 message")
+		return err
+	}
+
+	err = decodeHTTP2(ctx, logger, reqBuf, src, dstCfg, mockDb, opts)
+	if err != nil {
+		utils.LogError(logger, err, "failed to decode the http2 message from the yaml")
+		return err
+	}
+	return nil
+}
+
+// http2Hop are the connection specific headers, they are not sent over HTTP/2.
+var http2Hop = map[string]bool{
+	"Connection":        true,
+	"Keep-Alive":        true,
+	"Proxy-Connection":  true,
+	"Transfer-Encoding": true,
+	"Upgrade":           true,
+	"Content-Length":    true,
+}
+
+// http1Message renders an HTTP/2 request or response as an HTTP/1 message with the given start line,
+// which is the form parsed into the http mocks. The body is framed with its content length.
+func http1Message(startLine string, header http.Header, body []byte) []byte {
+	var b bytes.Buffer
+	b.WriteString(startLine + "\r\n")
+
+	keys := make([]string, 0, len(header))
+	for key := range header {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if http2Hop[http.CanonicalHeaderKey(key)] {
+			continue
+		}
+		for _, value := range header[key] {
+			fmt.Fprintf(&b, "%s: %s\r\n", key, value)
+		}
+	}
+	b.WriteString("Content-Length: " + strconv.Itoa(len(body)) + "\r\n\r\n")
+	b.Write(body)
+	return b.Bytes()
+}
+
+// http2Header splits the decoded header fields into the pseudo headers and the regular ones.
+func http2Header(fields []hpack.HeaderField) (map[string]string, http.Header) {
+	pseudo := map[string]string{}
+	header := http.Header{}
+	for _, f := range fields {
+		if strings.HasPrefix(f.Name, ":") {
+			pseudo[f.Name] = f.Value
+			continue
+		}
+		header.Add(f.Name, f.Value)
+	}
+	return pseudo, header
+}
@@ -0,0 +1,115 @@
+//go:build linux
+
+package http
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+
+	"go.keploy.io/server/v2/pkg"
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	pUtil "go.keploy.io/server/v2/pkg/core/proxy/util"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+	"golang.org/x/net/http2"
+)
+
+// prefixedConn replays the initial buffer of the conn before reading from it.
+type prefixedConn struct {
+	net.Conn
+	r io.Reader
+}
+
+func (c *prefixedConn) Read(p []byte) (int, error) {
+	return c.r.Read(p)
+}
+
+// decodeHTTP2 serves the requests of the application from the http mocks, the HTTP/2 framing
+// and the hpack state of the conn are handled by an HTTP/2 server.
+func decodeHTTP2(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientConn net.Conn, dstCfg *integrations.ConditionalDstCfg, mockDb integrations.MockMemDb, opts models.OutgoingOptions) error {
+	conn := &prefixedConn{Conn: clientConn, r: io.MultiReader(bytes.NewReader(reqBuf), clientConn)}
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, request *http.Request) {
+		reqBody, err := io.ReadAll(request.Body)
+		if err != nil {
+			utils.LogError(logger, err, "failed to read from request body", zap.Any("metadata", getReqMeta(request)))
+			panic(http.ErrAbortHandler)
+		}
+		// the bodies of the mocks are stored decoded
+		reqBody = decodedBody(logger, reqBody, request.Header.Get("Content-Encoding"))
+
+		raw := http1Message(fmt.Sprintf("%s %s HTTP/2.0", request.Method, request.URL.RequestURI()), request.Header, reqBody)
+		input := &req{
+			method: request.Method,
+			url:    request.URL,
+			host:   request.Host,
+			header: request.Header,
+			body:   reqBody,
+			raw:    raw,
+		}
+		ok, stub, err := match(ctx, logger, input, mockDb, opts.HTTP)
+		if err != nil {
+			if ctx.Err() == nil {
+				utils.LogError(logger, err, "error while matching http2 mocks", zap.Any("metadata", getReqMeta(request)))
+			}
+			panic(http.ErrAbortHandler)
+		}
+		if !ok {
+			if !IsPassThrough(logger, request, dstCfg.Port, opts) {
+				utils.LogError(logger, nil, "Didn't match any preExisting http2 mock", zap.Any("metadata", getReqMeta(request)))
+			}
+			// the streams of the conn are multiplexed, only the unmatched stream is reset
+			panic(http.ErrAbortHandler)
+		}
+
+		body := []byte(stub.Spec.HTTPResp.Body)
+		header := pkg.ToHTTPHeader(stub.Spec.HTTPResp.Header)
+		dropHTTP3AltSvc(header)
+		// the body is stored decoded, it's encoded again as the server sent it
+		body, err = encodedBody(logger, body, header.Get("Content-Encoding"))
+		if err != nil {
+			utils.LogError(logger, err, "failed to encode the response body", zap.Any("metadata", getReqMeta(request)))
+			panic(http.ErrAbortHandler)
+		}
+
+		for key, values := range header {
+			if http2Hop[http.CanonicalHeaderKey(key)] {
+				continue
+			}
+			for _, value := range values {
+				w.Header().Add(key, value)
+			}
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		w.WriteHeader(stub.Spec.HTTPResp.StatusCode)
+		_, err = w.Write(body)
+		if err != nil && ctx.Err() == nil {
+			utils.LogError(logger, err, "failed to write the mock output to the user application", zap.Any("metadata", getReqMeta(request)))
+		}
+	})
+
+	logger.Debug("serving the http2 conn from the mocks")
+	done := make(chan struct{})
+	go func() {
+		defer pUtil.Recover(logger, clientConn, nil)
+		defer close(done)
+		// ServeConn returns once the application closes the conn
+		(&http2.Server{}).ServeConn(conn, &http2.ServeConnOpts{
+			Context: ctx,
+			Handler: handler,
+		})
+	}()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-done:
+		return nil
+	}
+}
@@ -0,0 +1,231 @@
+//go:build linux
+
+package http
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	intgUtils "go.keploy.io/server/v2/pkg/core/proxy/integrations/util"
+	pUtil "go.keploy.io/server/v2/pkg/core/proxy/util"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
+	"golang.org/x/sync/errgroup"
+)
+
+// maxHeaderTableSize bounds the dynamic table of the hpack decoders, the peers can announce
+// a larger table than the default one in their settings.
+const maxHeaderTableSize = 1 << 20
+
+// h2Stream collects a request and its response exchanged on an HTTP/2 stream.
+type h2Stream struct {
+	reqHeader  []hpack.HeaderField
+	respHeader []hpack.HeaderField
+	reqBody    bytes.Buffer
+	respBody   bytes.Buffer
+	reqTime    time.Time
+	resTime    time.Time
+}
+
+// altSvcFrame is the type of the ALTSVC frames (RFC 7838), they are read as unknown frames.
+const altSvcFrame http2.FrameType = 0xa
+
+// h2Streams holds the open streams of a conn, they are updated by the frames of both peers.
+type h2Streams struct {
+	mu          sync.Mutex
+	streams     map[uint32]*h2Stream
+	http3Warned bool
+}
+
+// warnHTTP3 warns once per conn that the server advertises HTTP/3. The frames are forwarded as they are,
+// so the advertisement can't be removed like it is from the HTTP/1 responses.
+func (s *h2Streams) warnHTTP3(logger *zap.Logger) {
+	if s.http3Warned {
+		return
+	}
+	s.http3Warned = true
+	logger.Warn("the server advertises HTTP/3, the calls the application makes over QUIC are neither recorded nor mocked")
+}
+
+func (s *h2Streams) get(id uint32) *h2Stream {
+	stream, ok := s.streams[id]
+	if !ok {
+		stream = &h2Stream{reqTime: time.Now()}
+		s.streams[id] = stream
+	}
+	return stream
+}
+
+// encodeHTTP2 forwards the frames between the client and the server as they are and records
+// every completed stream as an http mock.
+func encodeHTTP2(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientConn, destConn net.Conn, mocks chan<- *models.Mock, opts models.OutgoingOptions) error {
+	remoteAddr := destConn.RemoteAddr().(*net.TCPAddr)
+	destPort := uint(remoteAddr.Port)
+
+	preface := intgUtils.HTTP2ClientPreface
+	if !bytes.HasPrefix(reqBuf, preface) {
+		return errors.New("the http2 conn doesn't start with the client preface")
+	}
+	_, err := destConn.Write(preface)
+	if err != nil {
+		utils.LogError(logger, err, "failed to write the client preface to the destination server")
+		return err
+	}
+
+	streams := &h2Streams{streams: map[uint32]*h2Stream{}}
+
+	// get the error group from the context
+	g, ok := ctx.Value(models.ErrGroupKey).(*errgroup.Group)
+	if !ok {
+		return errors.New("failed to get the error group from the context")
+	}
+	errCh := make(chan error, 2)
+
+	// the frames of the client are forwarded to the server while they are read
+	g.Go(func() error {
+		defer pUtil.Recover(logger, clientConn, destConn)
+		client := io.TeeReader(io.MultiReader(bytes.NewReader(reqBuf[len(preface):]), clientConn), destConn)
+		errCh <- streams.read(ctx, logger, client, true, func(*h2Stream) {})
+		return nil
+	})
+
+	// the frames of the server are forwarded to the client, the streams are saved once the server ends them
+	g.Go(func() error {
+		defer pUtil.Recover(logger, clientConn, destConn)
+		server := io.TeeReader(destConn, clientConn)
+		errCh <- streams.read(ctx, logger, server, false, func(stream *h2Stream) {
+			err := saveHTTP2Mock(ctx, logger, stream, destPort, mocks, opts)
+			if err != nil {
+				utils.LogError(logger, err, "failed to save the http2 mock")
+			}
+		})
+		return nil
+	})
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-errCh:
+		if err == io.EOF {
+			return nil
+		}
+		return err
+	}
+}
+
+// read decodes the frames sent by a peer into the streams, ended is called with the streams ended by it.
+func (s *h2Streams) read(ctx context.Context, logger *zap.Logger, r io.Reader, fromClient bool, ended func(*h2Stream)) error {
+	framer := http2.NewFramer(nil, r)
+	decoder := hpack.NewDecoder(4096, nil)
+	decoder.SetAllowedMaxDynamicTableSize(maxHeaderTableSize)
+	framer.ReadMetaHeaders = decoder
+
+	for {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		frame, err := framer.ReadFrame()
+		if err != nil {
+			// the frame has already been forwarded, only its stream is dropped from the recording
+			var streamErr http2.StreamError
+			if errors.As(err, &streamErr) {
+				logger.Debug("dropping the http2 stream from the recording", zap.Uint32("stream", streamErr.StreamID), zap.Error(err))
+				s.mu.Lock()
+				delete(s.streams, streamErr.StreamID)
+				s.mu.Unlock()
+				continue
+			}
+			if err == io.EOF {
+				return err
+			}
+			return fmt.Errorf("error reading http2 frame: %v", err)
+		}
+
+		// the stream ended by the server, its exchange is complete
+		var done *h2Stream
+		s.mu.Lock()
+		switch f := frame.(type) {
+		case *http2.MetaHeadersFrame:
+			stream := s.get(f.StreamID)
+			switch {
+			case fromClient:
+				// the trailers of the request are not recorded
+				if stream.reqHeader == nil {
+					stream.reqHeader = f.Fields
+				}
+			case stream.respHeader == nil:
+				// the interim (1xx) responses are skipped, the mock keeps only the final response
+				if status := f.PseudoValue("status"); !strings.HasPrefix(status, "1") {
+					stream.respHeader = f.Fields
+					stream.resTime = time.Now()
+				}
+				for _, field := range f.RegularFields() {
+					if field.Name == "alt-svc" && advertisesHTTP3(field.Value) {
+						s.warnHTTP3(logger)
+					}
+				}
+			}
+			if f.StreamEnded() && !fromClient {
+				done = stream
+				delete(s.streams, f.StreamID)
+			}
+		case *http2.DataFrame:
+			stream := s.get(f.StreamID)
+			if fromClient {
+				stream.reqBody.Write(f.Data())
+			} else {
+				stream.respBody.Write(f.Data())
+			}
+			if f.StreamEnded() && !fromClient {
+				done = stream
+				delete(s.streams, f.StreamID)
+			}
+		case *http2.RSTStreamFrame:
+			delete(s.streams, f.StreamID)
+		case *http2.UnknownFrame:
+			if f.Type == altSvcFrame && !fromClient && advertisesHTTP3(string(f.Payload())) {
+				s.warnHTTP3(logger)
+			}
+		}
+		s.mu.Unlock()
+
+		if done != nil && done.reqHeader != nil && done.respHeader != nil {
+			ended(done)
+		}
+	}
+}
+
+// saveHTTP2Mock renders the exchange of the stream as HTTP/1 messages and stores it as an http mock.
+func saveHTTP2Mock(ctx context.Context, logger *zap.Logger, stream *h2Stream, destPort uint, mocks chan<- *models.Mock, opts models.OutgoingOptions) error {
+	pseudo, header := http2Header(stream.reqHeader)
+	if pseudo[":authority"] != "" && header.Get("Host") == "" {
+		header.Set("Host", pseudo[":authority"])
+	}
+	req := http1Message(fmt.Sprintf("%s %s HTTP/2.0", pseudo[":method"], pseudo[":path"]), header, stream.reqBody.Bytes())
+
+	pseudo, header = http2Header(stream.respHeader)
+	code, err := strconv.Atoi(pseudo[":status"])
+	if err != nil {
+		return fmt.Errorf("invalid status of the http2 response: %q", pseudo[":status"])
+	}
+	resp := http1Message(fmt.Sprintf("HTTP/2.0 %d %s", code, http.StatusText(code)), header, stream.respBody.Bytes())
+
+	return ParseFinalHTTP(ctx, logger, &finalHTTP{
+		req:              req,
+		resp:             resp,
+		reqTimestampMock: stream.reqTime,
+		resTimestampMock: stream.resTime,
+	}, destPort, mocks, opts)
+}
@@ -0,0 +1,220 @@
+//go:build linux
+
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"go.keploy.io/server/v2/pkg"
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations/util"
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+// isInfluxWrite reports whether the request writes points to InfluxDB, over the v1 or the v2 api.
+func isInfluxWrite(method string, path string) bool {
+	return method == "POST" && (path == "/write" || path == "/api/v2/write")
+}
+
+// influxTargetParams are the query params naming where the points are written, they have to be the same
+// for a mock to match. The precision is left out, as it only concerns the timestamps.
+var influxTargetParams = []string{"db", "rp", "org", "orgID", "bucket"}
+
+// influxPoints parses the body of an InfluxDB write, which the clients may send gzipped. The bodies are decoded
+// before they're stored, only the ones of the mocks recorded before are still gzipped.
+func influxPoints(body []byte, contentEncoding string) ([]models.InfluxPoint, error) {
+	if decoded, err := pkg.DecodeBody(body, contentEncoding); err == nil {
+		body = decoded
+	}
+	return parseLineProtocol(string(body))
+}
+
+// parseLineProtocol parses the points of the InfluxDB line protocol, one per line:
+//
+//	measurement[,tag=value...] field=value[,field=value...] [timestamp]
+//
+// The spaces, commas and equal signs are escaped with a backslash in the names, and the string field
+// values are double quoted.
+func parseLineProtocol(body string) ([]models.InfluxPoint, error) {
+	var points []models.InfluxPoint
+	for i, line := range strings.Split(body, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		sections := splitUnescaped(line, ' ')
+		if len(sections) < 2 || len(sections) > 3 {
+			return nil, fmt.Errorf("invalid line protocol at line %d", i+1)
+		}
+
+		series := splitUnescaped(sections[0], ',')
+		point := models.InfluxPoint{
+			Measurement: unescape(series[0]),
+			Fields:      make(map[string]string),
+		}
+		for _, tag := range series[1:] {
+			key, value, err := splitPair(tag)
+			if err != nil {
+				return nil, fmt.Errorf("invalid tag at line %d: %v", i+1, err)
+			}
+			if point.Tags == nil {
+				point.Tags = make(map[string]string)
+			}
+			point.Tags[key] = value
+		}
+		for _, field := range splitUnescaped(sections[1], ',') {
+			key, value, err := splitPair(field)
+			if err != nil {
+				return nil, fmt.Errorf("invalid field at line %d: %v", i+1, err)
+			}
+			point.Fields[key] = value
+		}
+		if point.Measurement == "" || len(point.Fields) == 0 {
+			return nil, fmt.Errorf("invalid line protocol at line %d", i+1)
+		}
+		if len(sections) == 3 {
+			point.Timestamp = sections[2]
+		}
+		points = append(points, point)
+	}
+	if len(points) == 0 {
+		return nil, errors.New("no points in the line protocol")
+	}
+	return points, nil
+}
+
+// splitUnescaped splits the string at the separators which are neither escaped nor in a quoted string.
+func splitUnescaped(s string, sep byte) []string {
+	var parts []string
+	quoted, start := false, 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			quoted = !quoted
+		case sep:
+			if !quoted {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// splitPair splits a tag or a field at its first unescaped equal sign, the keys and the tag values are unescaped.
+func splitPair(s string) (string, string, error) {
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '=':
+			if i == 0 || i == len(s)-1 {
+				return "", "", fmt.Errorf("%q has no key or value", s)
+			}
+			value := s[i+1:]
+			if !strings.HasPrefix(value, `"`) {
+				value = unescape(value)
+			}
+			return unescape(s[:i]), value, nil
+		}
+	}
+	return "", "", fmt.Errorf("%q has no value", s)
+}
+
+func unescape(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && strings.IndexByte(` ,="\`, s[i+1]) != -1 {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// influxSeries returns the points without their timestamps as sorted lines, so that neither the time of
+// the write nor the order of the series in the batch affect the matching.
+func influxSeries(points []models.InfluxPoint) string {
+	lines := make([]string, 0, len(points))
+	for _, p := range points {
+		var b strings.Builder
+		b.WriteString(p.Measurement)
+		for _, key := range sortedKeys(p.Tags) {
+			b.WriteString("," + key + "=" + p.Tags[key])
+		}
+		for i, key := range sortedKeys(p.Fields) {
+			if i == 0 {
+				b.WriteByte(' ')
+			} else {
+				b.WriteByte(',')
+			}
+			b.WriteString(key + "=" + p.Fields[key])
+		}
+		lines = append(lines, b.String())
+	}
+	sort.Strings(lines)
+	return strings.Join(lines, "\n")
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// influxMatch finds the mock of an InfluxDB write among the mocks of the same path. The mock has to write
+// to the same database or bucket, then the one with the same points is preferred, or else the one with
+// the most similar points. The mocks recorded during the current test case are tried first.
+func influxMatch(mocks []*models.Mock, query url.Values, points []models.InfluxPoint) *models.Mock {
+	series := influxSeries(points)
+	var best *models.Mock
+	bestSim := -1.0
+	for _, filtered := range []bool{true, false} {
+		for _, mock := range mocks {
+			if mock.TestModeInfo.IsFiltered != filtered || !sameInfluxTarget(mock.Spec.HTTPReq.URLParams, query) {
+				continue
+			}
+			recorded := mock.Spec.HTTPReq.InfluxPoints
+			if len(recorded) == 0 {
+				// the mocks recorded before the points were stored
+				var err error
+				recorded, err = influxPoints([]byte(mock.Spec.HTTPReq.Body), mock.Spec.HTTPReq.Header["Content-Encoding"])
+				if err != nil {
+					continue
+				}
+			}
+			recordedSeries := influxSeries(recorded)
+			if recordedSeries == series {
+				return mock
+			}
+			k := util.AdaptiveK(len(series), 3, 8, 5)
+			if sim := util.JaccardSimilarity(util.CreateShingles([]byte(recordedSeries), k), util.CreateShingles([]byte(series), k)); sim > bestSim {
+				best, bestSim = mock, sim
+			}
+		}
+		if best != nil {
+			return best
+		}
+	}
+	return nil
+}
+
+func sameInfluxTarget(recorded map[string]string, query url.Values) bool {
+	for _, key := range influxTargetParams {
+		if recorded[key] != strings.Join(query[key], ", ") {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,76 @@
+//go:build linux
+
+package http
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go.keploy.io/server/v2/pkg"
+)
+
+// The conns are kept alive for many requests, sent one after the other or pipelined, so that the reads of a
+// message can end with the start of the next one. The messages are cut at the end given by their framing, the
+// rest is the start of the next message of the conn.
+// See: https://www.rfc-editor.org/rfc/rfc9112#section-6.3 and https://www.rfc-editor.org/rfc/rfc9112#section-9.3
+
+// messageLen returns the length of the first message of the data, including the interim responses before a
+// response. It's false until the message is complete, and for the responses whose body ends with the conn.
+func messageLen(data []byte, response, bodiless bool) (int, bool) {
+	msg := data
+	if response {
+		msg = pkg.StripInterimHTTPResponses(data)
+	}
+	end := bytes.Index(msg, []byte("\r\n\r\n"))
+	if end == -1 {
+		return 0, false
+	}
+	start := len(data) - len(msg) + end + 4
+	if bodiless {
+		return start, true
+	}
+	contentLength, chunked := framing(msg)
+	switch {
+	case chunked:
+		chunks := &chunkDecoder{}
+		if _, err := chunks.decode(data[start:]); err != nil || !chunks.done {
+			return 0, false
+		}
+		return len(data) - len(chunks.buf), true
+	case contentLength != "":
+		n, err := strconv.Atoi(contentLength)
+		if err != nil || len(data) < start+n {
+			return 0, false
+		}
+		return start + n, true
+	case response:
+		return 0, false
+	}
+	return start, true
+}
+
+// splitMessage cuts the data at the end of its first message, rest is the start of the next message.
+func splitMessage(data []byte, response, bodiless bool) (msg, rest []byte) {
+	n, ok := messageLen(data, response, bodiless)
+	if !ok || n >= len(data) {
+		return data, nil
+	}
+	return data[:n], append([]byte{}, data[n:]...)
+}
+
+// isBodiless reports whether the response to the request has no body, whatever its headers say: the responses to
+// the HEAD requests and the 204 and 304 responses.
+func isBodiless(req, resp []byte) bool {
+	if method, _, ok := strings.Cut(string(req), " "); ok && method == http.MethodHead {
+		return true
+	}
+	line, _, _ := strings.Cut(string(pkg.StripInterimHTTPResponses(resp)), "\r\n")
+	fields := strings.Fields(line)
+	if len(fields) < 2 {
+		return false
+	}
+	code, err := strconv.Atoi(fields[1])
+	return err == nil && (code == http.StatusNoContent || code == http.StatusNotModified)
+}
@@ -14,8 +14,11 @@ import (
 	"strings"
 
 	"github.com/agnivade/levenshtein"
+	"go.keploy.io/server/v2/config"
+	"go.keploy.io/server/v2/pkg"
 	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
 	"go.keploy.io/server/v2/pkg/core/proxy/integrations/util"
+	"go.keploy.io/server/v2/pkg/matcher"
 	"go.keploy.io/server/v2/pkg/models"
 	"go.keploy.io/server/v2/utils"
 	"go.uber.org/zap"
@@ -24,25 +27,21 @@ import (
 type req struct {
 	method string
 	url    *url.URL
+	host   string
 	header http.Header
 	body   []byte
 	raw    []byte
 }
 
-func match(ctx context.Context, logger *zap.Logger, input *req, mockDb integrations.MockMemDb) (bool, *models.Mock, error) {
+func match(ctx context.Context, logger *zap.Logger, input *req, mockDb integrations.MockMemDb, rules config.HTTPIntegration) (bool, *models.Mock, error) {
+	reqPath := rewritePath(input.url.Path, rules.URLRewrites)
 	for {
 		if ctx.Err() != nil {
 			return false, nil, ctx.Err()
 		}
 
-		mocks, err := mockDb.GetUnFilteredMocks()
-		var unfilteredMocks []*models.Mock
-		for _, mock := range mocks {
-			if mock.Kind != "Http" {
-				continue
-			}
-			unfilteredMocks = append(unfilteredMocks, mock)
-		}
+		// only the mocks with the same method and path can match the request
+		unfilteredMocks, err := httpMocks(mockDb, input.method, input.url.Path, rules)
 		if err != nil {
 			utils.LogError(logger, err, "failed to get unfilteredMocks mocks")
 			return false, nil, errors.New("error while matching the request with the mocks")
@@ -62,14 +61,15 @@ func match(ctx context.Context, logger *zap.Logger, input *req, mockDb integrati
 
 			//if the content type is present in http request then we need to check for the same type in the mock
 			if input.header.Get("Content-Type") != "" {
-				if input.header.Get("Content-Type") != mock.Spec.HTTPReq.Header["Content-Type"] {
+				if !sameContentType(input.header.Get("Content-Type"), mock.Spec.HTTPReq.Header["Content-Type"]) {
 					logger.Debug("The content type of mock and request aren't the same")
 					continue
 				}
 			}
 
-			// check the type of the body if content type is not present
-			if !matchBodyType(mock.Spec.HTTPReq.Body, input.body) {
+			// check the type of the body if content type is not present, the uploads to S3 are recorded without it
+			// and the multipart forms by their parts
+			if mock.Spec.HTTPReq.BodyDigest == "" && len(mock.Spec.HTTPReq.Form) == 0 && !matchBodyType(mock.Spec.HTTPReq.Body, input.body) {
 				logger.Debug("The body of mock and request aren't of same type")
 				continue
 			}
@@ -82,7 +82,7 @@ func match(ctx context.Context, logger *zap.Logger, input *req, mockDb integrati
 			}
 
 			//Check if the path matches
-			if parsedURL.Path != input.url.Path {
+			if rewritePath(parsedURL.Path, rules.URLRewrites) != reqPath {
 				//If it is not the same, continue
 				logger.Debug("The url path of mock and request aren't the same")
 				continue
@@ -95,14 +95,14 @@ func match(ctx context.Context, logger *zap.Logger, input *req, mockDb integrati
 				continue
 			}
 
-			// Check if the header keys match
-			if !mapsHaveSameKeys(mock.Spec.HTTPReq.Header, input.header) {
+			// Check if the header keys match, but the ones ignored
+			if !mapsHaveSameKeys(withoutIgnored(mock.Spec.HTTPReq.Header, rules.IgnoreHeaders, true), withoutIgnored(input.header, rules.IgnoreHeaders, true)) {
 				// Different headers, so not a match
 				logger.Debug("The header keys of mock and request aren't the same")
 				continue
 			}
 
-			if !mapsHaveSameKeys(mock.Spec.HTTPReq.URLParams, input.url.Query()) {
+			if !mapsHaveSameKeys(withoutIgnored(mock.Spec.HTTPReq.URLParams, rules.IgnoreQueryParams, false), withoutIgnored(input.url.Query(), rules.IgnoreQueryParams, false)) {
 				// Different query params, so not a match
 				logger.Debug("The query params of mock and request aren't the same")
 				continue
@@ -114,6 +114,94 @@ func match(ctx context.Context, logger *zap.Logger, input *req, mockDb integrati
 			// basic schema is not matched with any mock hence returning false
 			return false, nil, nil
 		}
+		schemaMatched = bearerMatched(schemaMatched, input.header.Get("Authorization"))
+		// the mocks with the same headers and query params are tried first, e.g. by the exact body match of the GET calls
+		schemaMatched = rankByValues(schemaMatched, input, rules)
+
+		// the writes to InfluxDB are matched on their points, as the timestamps differ on every run
+		if isInfluxWrite(input.method, input.url.Path) {
+			points, err := influxPoints(input.body, input.header.Get("Content-Encoding"))
+			if err == nil {
+				bestMatch := influxMatch(schemaMatched, input.url.Query(), points)
+				if bestMatch == nil {
+					return false, nil, nil
+				}
+				if !updateMock(ctx, logger, bestMatch, mockDb) {
+					continue
+				}
+				return true, bestMatch, nil
+			}
+			logger.Debug("failed to parse the line protocol of the influxdb write", zap.Error(err))
+		}
+
+		// the bulk calls and the searches of Elasticsearch are matched on their normalized JSON lines
+		if bulk := isElasticBulk(input.method, input.url.Path); bulk || isElasticSearch(input.method, input.url.Path) {
+			elasticReq, err := parseElastic(input.body, bulk)
+			if err == nil {
+				bestMatch := elasticMatch(schemaMatched, elasticReq, bulk)
+				if bestMatch == nil {
+					return false, nil, nil
+				}
+				if !updateMock(ctx, logger, bestMatch, mockDb) {
+					continue
+				}
+				return true, bestMatch, nil
+			}
+			logger.Debug("failed to parse the body of the elasticsearch request", zap.Error(err))
+		}
+
+		// the OAuth2 token requests are matched on the token they ask for, their assertions are signed anew on every run
+		if params, ok := tokenParams(input.method, input.header.Get("Content-Type"), input.body); ok {
+			if bestMatch := tokenMatch(schemaMatched, params); bestMatch != nil {
+				if !updateMock(ctx, logger, bestMatch, mockDb) {
+					continue
+				}
+				return true, bestMatch, nil
+			}
+			logger.Debug("couldn't find any mock of a token request for the same token")
+		}
+
+		// the GraphQL calls are matched on their operation, normalized query and variables
+		if input.method == "POST" {
+			if gqlReq, err := matcher.ParseGraphQLRequest(input.body); err == nil {
+				bestMatch := graphQLMatch(schemaMatched, gqlReq)
+				if bestMatch == nil {
+					return false, nil, nil
+				}
+				if !updateMock(ctx, logger, bestMatch, mockDb) {
+					continue
+				}
+				return true, bestMatch, nil
+			}
+		}
+
+		// the calls of the AWS SDKs are signed anew on every run, so they are matched on their operation
+		if call := parseAWSCall(input.method, input.url, input.host, input.header, input.body); call != nil {
+			bestMatch := awsMatch(schemaMatched, call)
+			if bestMatch == nil {
+				return false, nil, nil
+			}
+			if !updateMock(ctx, logger, bestMatch, mockDb) {
+				continue
+			}
+			return true, bestMatch, nil
+		}
+
+		// the multipart forms are matched on their parts, their boundary changes with every upload
+		if contentType := input.header.Get("Content-Type"); pkg.IsMultipartForm(contentType) {
+			form, err := pkg.ParseMultipartForm(input.body, contentType)
+			if err == nil {
+				bestMatch := formMatch(schemaMatched, form)
+				if bestMatch == nil {
+					return false, nil, nil
+				}
+				if !updateMock(ctx, logger, bestMatch, mockDb) {
+					continue
+				}
+				return true, bestMatch, nil
+			}
+			logger.Debug("failed to parse the multipart form of the request", zap.Error(err))
+		}
 
 		// do exact body match
 		ok, bestMatch := exactBodyMatch(input.body, schemaMatched)
@@ -125,6 +213,22 @@ func match(ctx context.Context, logger *zap.Logger, input *req, mockDb integrati
 		}
 
 		shortlisted := schemaMatched
+		// the xml bodies, e.g. of the SOAP calls, are matched canonicalized and on their operation
+		if reqXML, err := matcher.CanonicalizeXML(string(input.body)); err == nil && !isJSON(input.body) {
+			xmlMatched, bestMatch := xmlBodyMatch(reqXML, schemaMatched)
+			if bestMatch != nil {
+				if !updateMock(ctx, logger, bestMatch, mockDb) {
+					continue
+				}
+				return true, bestMatch, nil
+			}
+			if len(xmlMatched) == 0 {
+				logger.Debug("couldn't find any mock with the same xml operation")
+				return false, nil, nil
+			}
+			shortlisted = xmlMatched
+		}
+
 		// If the body is JSON we do a schema match. we can add more custom type matching
 		if isJSON(input.body) {
 			var bodyMatched []*models.Mock
@@ -188,6 +292,37 @@ func exactBodyMatch(body []byte, schemaMatched []*models.Mock) (bool, *models.Mo
 	return false, nil
 }
 
+// xmlBodyMatch returns the mocks with an xml body of the same operation as the request, and the mock
+// whose canonical body is the same as the one of the request if there is one.
+func xmlBodyMatch(reqXML *matcher.XMLNode, schemaMatched []*models.Mock) ([]*models.Mock, *models.Mock) {
+	canonical, operation := reqXML.String(), xmlOperation(reqXML)
+	var xmlMatched []*models.Mock
+	for _, mock := range schemaMatched {
+		mockXML, err := matcher.CanonicalizeXML(mock.Spec.HTTPReq.Body)
+		if err != nil || xmlOperation(mockXML) != operation {
+			continue
+		}
+		if mockXML.String() == canonical {
+			return nil, mock
+		}
+		xmlMatched = append(xmlMatched, mock)
+	}
+	return xmlMatched, nil
+}
+
+// xmlOperation returns the name of the first element in the body of a SOAP envelope, or else the name of
+// the root element.
+func xmlOperation(root *matcher.XMLNode) string {
+	if root.Local == "Envelope" {
+		for _, child := range root.Children {
+			if child.Local == "Body" && len(child.Children) > 0 {
+				return "{" + child.Children[0].Space + "}" + child.Children[0].Local
+			}
+		}
+	}
+	return "{" + root.Space + "}" + root.Local
+}
+
 func bodyMatch(logger *zap.Logger, mockBody, reqBody []byte) (bool, error) {
 
 	var mockData map[string]interface{}
@@ -301,7 +436,7 @@ func fuzzyMatch(tcsMocks []*models.Mock, reqBuff []byte) (bool, *models.Mock) {
 		mockString[i] = tcsMocks[i].Spec.HTTPReq.Body
 	}
 	// find the closest match
-	if util.IsASCII(string(reqBuff)) {
+	if util.IsASCIIBytes(reqBuff) {
 		idx := findStringMatch(string(reqBuff), mockString)
 		if idx != -1 {
 			return true, tcsMocks[idx]
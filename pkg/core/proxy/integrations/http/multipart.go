@@ -0,0 +1,94 @@
+//go:build linux
+
+package http
+
+import (
+	"mime"
+
+	"go.keploy.io/server/v2/pkg"
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+// sameContentType reports whether the Content-Types of the request and of the mock are the same. The boundaries of
+// the multipart forms are left out, the clients pick them at random.
+func sameContentType(reqType, mockType string) bool {
+	if reqType == mockType {
+		return true
+	}
+	if !pkg.IsMultipartForm(reqType) || !pkg.IsMultipartForm(mockType) {
+		return false
+	}
+	reqMedia, _, _ := mime.ParseMediaType(reqType)
+	mockMedia, _, _ := mime.ParseMediaType(mockType)
+	return reqMedia == mockMedia
+}
+
+// mockForm returns the parts of the multipart form of the mock, the mocks recorded before the forms were stored by
+// their parts are parsed from their body.
+func mockForm(mock *models.Mock) []models.FormData {
+	if len(mock.Spec.HTTPReq.Form) > 0 || mock.Spec.HTTPReq.Body == "" {
+		return mock.Spec.HTTPReq.Form
+	}
+	form, err := pkg.ParseMultipartForm([]byte(mock.Spec.HTTPReq.Body), mock.Spec.HTTPReq.Header["Content-Type"])
+	if err != nil {
+		return nil
+	}
+	return form
+}
+
+// formMatch returns the mock whose form has the same fields as the form of the request, with the most of their
+// values and of their files the same. The files are compared by their hashes.
+func formMatch(mocks []*models.Mock, form []models.FormData) *models.Mock {
+	var best *models.Mock
+	bestScore := -1
+	for _, mock := range mocks {
+		recorded := mockForm(mock)
+		if !sameFormKeys(recorded, form) {
+			continue
+		}
+		score := formScore(recorded, form)
+		if score > bestScore {
+			best, bestScore = mock, score
+		}
+	}
+	return best
+}
+
+func sameFormKeys(a, b []models.FormData) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	keys := map[string]bool{}
+	for _, field := range a {
+		keys[field.Key] = true
+	}
+	for _, field := range b {
+		if !keys[field.Key] {
+			return false
+		}
+	}
+	return true
+}
+
+// formScore returns the number of the values and of the files of the forms which are the same, in the same order.
+func formScore(recorded, actual []models.FormData) int {
+	fields := map[string]models.FormData{}
+	for _, field := range recorded {
+		fields[field.Key] = field
+	}
+	score := 0
+	for _, field := range actual {
+		rec := fields[field.Key]
+		for i, value := range field.Values {
+			if i < len(rec.Values) && rec.Values[i] == value {
+				score++
+			}
+		}
+		for i, file := range field.Files {
+			if i < len(rec.Files) && rec.Files[i].Hash == file.Hash {
+				score++
+			}
+		}
+	}
+	return score
+}
@@ -0,0 +1,254 @@
+//go:build linux
+
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"go.keploy.io/server/v2/pkg"
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.uber.org/zap"
+)
+
+// The OAuth2 token requests carry a grant_type, in a form or in a JSON body. Their client assertions, the JWTs the
+// clients sign with their keys, and the refresh tokens, the codes and the verifiers they send change on every run,
+// so they're matched on the other parameters: the grant, the client, the scope and the audience. The tokens the
+// responses issue are sent as bearers in the next calls, the JWTs whose claims are the same but for their times
+// and their ids are the same token signed anew.
+// See: https://www.rfc-editor.org/rfc/rfc6749#section-4 and https://www.rfc-editor.org/rfc/rfc7523
+
+// volatileTokenParams are the parameters of the token requests left out of their matching.
+var volatileTokenParams = map[string]bool{
+	"client_assertion": true,
+	"assertion":        true,
+	"refresh_token":    true,
+	"code":             true,
+	"code_verifier":    true,
+	"client_secret":    true,
+	"subject_token":    true,
+	"actor_token":      true,
+	"nonce":            true,
+	"state":            true,
+}
+
+// volatileClaims are the claims of the JWTs set anew each time they're signed.
+var volatileClaims = []string{"iat", "exp", "nbf", "jti", "auth_time"}
+
+// tokenParams returns the parameters of the OAuth2 token request which identify the token it asks for, it's false
+// for the other requests.
+func tokenParams(method, contentType string, body []byte) (map[string]string, bool) {
+	if method != "POST" || len(body) == 0 {
+		return nil, false
+	}
+	params := map[string]string{}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "application/x-www-form-urlencoded":
+		values, err := url.ParseQuery(string(body))
+		if err != nil {
+			return nil, false
+		}
+		for key := range values {
+			params[key] = values.Get(key)
+		}
+	case "application/json":
+		var values map[string]interface{}
+		if err := json.Unmarshal(body, &values); err != nil {
+			return nil, false
+		}
+		for key, value := range values {
+			if s, ok := value.(string); ok {
+				params[key] = s
+			}
+		}
+	default:
+		return nil, false
+	}
+	if params["grant_type"] == "" {
+		return nil, false
+	}
+	for key := range params {
+		if volatileTokenParams[key] {
+			delete(params, key)
+		}
+	}
+	return params, true
+}
+
+// sameTokenParams reports whether the token requests ask for the same token.
+func sameTokenParams(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for key, value := range a {
+		if b[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// tokenMatch returns the first mock of a token request asking for the same token.
+func tokenMatch(mocks []*models.Mock, params map[string]string) *models.Mock {
+	for _, mock := range mocks {
+		recorded, ok := tokenParams(string(mock.Spec.HTTPReq.Method), mock.Spec.HTTPReq.Header["Content-Type"], []byte(mock.Spec.HTTPReq.Body))
+		if ok && sameTokenParams(recorded, params) {
+			return mock
+		}
+	}
+	return nil
+}
+
+// issuedTokens returns the tokens issued in the body of a token response.
+func issuedTokens(body []byte) []string {
+	var resp struct {
+		AccessToken string `json:"access_token"`
+		IDToken     string `json:"id_token"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil
+	}
+	var tokens []string
+	for _, token := range []string{resp.AccessToken, resp.IDToken} {
+		if token != "" {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens
+}
+
+// bearerToken returns the token of the bearer Authorization header.
+func bearerToken(authorization string) (string, bool) {
+	scheme, token, ok := strings.Cut(strings.TrimSpace(authorization), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
+
+// jwtClaims returns the claims of the JWT without the volatile ones, encoded to be compared. The signature isn't
+// verified, it's left out along with the header naming the signing key.
+func jwtClaims(token string) (string, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return "", false
+	}
+	var claims map[string]interface{}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return "", false
+	}
+	for _, claim := range volatileClaims {
+		delete(claims, claim)
+	}
+	// the keys of the maps are encoded sorted
+	encoded, err := json.Marshal(claims)
+	if err != nil {
+		return "", false
+	}
+	return string(encoded), true
+}
+
+// replayTokens binds the tokens issued in the replay by the token endpoints passed through to the tokens recorded
+// for the same requests, it's shared by the connections since the tokens are sent on any of them.
+var replayTokens = &tokens{recorded: map[string]string{}}
+
+type tokens struct {
+	mu sync.Mutex
+	// recorded are the recorded tokens by the tokens issued in the replay
+	recorded map[string]string
+}
+
+// bind binds the tokens issued by the response passed through to the ones of the recorded response of the same
+// token request.
+func (t *tokens) bind(issued, recorded []string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for i := range issued {
+		if i < len(recorded) {
+			t.recorded[issued[i]] = recorded[i]
+		}
+	}
+}
+
+// sameBearer reports whether the bearer of the request is the same as the one recorded: the same token, the token
+// recorded for the one issued in the replay, or the same JWT signed anew.
+func (t *tokens) sameBearer(actual, recorded string) bool {
+	if actual == recorded {
+		return true
+	}
+	t.mu.Lock()
+	bound, ok := t.recorded[actual]
+	t.mu.Unlock()
+	if ok && bound == recorded {
+		return true
+	}
+	actualClaims, ok := jwtClaims(actual)
+	if !ok {
+		return false
+	}
+	recordedClaims, ok := jwtClaims(recorded)
+	return ok && actualClaims == recordedClaims
+}
+
+// bearerMatched returns the mocks sent with the same bearer as the request, all of them if none of them is, since
+// the tokens don't decide the mock matched alone.
+func bearerMatched(mocks []*models.Mock, authorization string) []*models.Mock {
+	actual, ok := bearerToken(authorization)
+	if !ok {
+		return mocks
+	}
+	var matched []*models.Mock
+	for _, mock := range mocks {
+		if recorded, ok := bearerToken(mock.Spec.HTTPReq.Header["Authorization"]); ok && replayTokens.sameBearer(actual, recorded) {
+			matched = append(matched, mock)
+		}
+	}
+	if len(matched) == 0 {
+		return mocks
+	}
+	return matched
+}
+
+// bindPassedToken binds the tokens issued by the token endpoint the request was passed through to, to the tokens
+// recorded for the same request, so that the next calls sent with them match the calls recorded.
+func bindPassedToken(logger *zap.Logger, input *req, resp []byte, mockDb integrations.MockMemDb) {
+	params, ok := tokenParams(input.method, input.header.Get("Content-Type"), input.body)
+	if !ok {
+		return
+	}
+	mocks, err := mockDb.GetUnFilteredMocksByKey(models.HTTP, integrations.HTTPMockKey(input.method, input.url.Path))
+	if err != nil {
+		return
+	}
+	mock := tokenMatch(mocks, params)
+	if mock == nil {
+		return
+	}
+	parsed, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(pkg.StripInterimHTTPResponses(resp))), nil)
+	if err != nil {
+		logger.Debug("failed to parse the response of the token endpoint passed through", zap.Error(err))
+		return
+	}
+	defer parsed.Body.Close()
+	body, err := io.ReadAll(parsed.Body)
+	if err != nil {
+		return
+	}
+	body = decodedBody(logger, body, parsed.Header.Get("Content-Encoding"))
+	replayTokens.bind(issuedTokens(body), issuedTokens([]byte(mock.Spec.HTTPResp.Body)))
+	logger.Debug("bound the tokens issued by the token endpoint to the recorded ones", zap.String("mock", mock.Name))
+}
@@ -0,0 +1,155 @@
+//go:build linux
+
+package http
+
+import (
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"go.keploy.io/server/v2/config"
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+// globs caches the regexps of the globs of the url rewrites, they're matched against every mock.
+var globs sync.Map
+
+// globRegexp returns the regexp of the glob, whose * and ** are captured.
+func globRegexp(glob string) *regexp.Regexp {
+	if re, ok := globs.Load(glob); ok {
+		return re.(*regexp.Regexp)
+	}
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch {
+		case strings.HasPrefix(glob[i:], "**"):
+			b.WriteString("(.*)")
+			i++
+		case glob[i] == '*':
+			b.WriteString("([^/]*)")
+		case glob[i] == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+	b.WriteString("$")
+	re := regexp.MustCompile(b.String())
+	globs.Store(glob, re)
+	return re
+}
+
+// rewritePath rewrites the path with the first url rewrite whose glob it matches.
+func rewritePath(path string, rewrites []config.URLRewrite) string {
+	for _, rewrite := range rewrites {
+		captures := globRegexp(rewrite.From).FindStringSubmatch(path)
+		if captures == nil {
+			continue
+		}
+		captures = captures[1:]
+		var b strings.Builder
+		for i := 0; i < len(rewrite.To); i++ {
+			if rewrite.To[i] != '*' {
+				b.WriteByte(rewrite.To[i])
+				continue
+			}
+			for i+1 < len(rewrite.To) && rewrite.To[i+1] == '*' {
+				i++
+			}
+			if len(captures) > 0 {
+				b.WriteString(captures[0])
+				captures = captures[1:]
+			}
+		}
+		return b.String()
+	}
+	return path
+}
+
+// httpMocks returns the mocks of the method whose path is the same as the one of the request once they're both
+// rewritten. The mocks are only looked up by their recorded path when there is no url rewrite.
+func httpMocks(mockDb integrations.MockMemDb, method, path string, rules config.HTTPIntegration) ([]*models.Mock, error) {
+	if len(rules.URLRewrites) == 0 {
+		return mockDb.GetUnFilteredMocksByKey(models.HTTP, integrations.HTTPMockKey(method, path))
+	}
+	mocks, err := mockDb.GetUnFilteredMocks()
+	if err != nil {
+		return nil, err
+	}
+	path = rewritePath(path, rules.URLRewrites)
+	var res []*models.Mock
+	for _, mock := range mocks {
+		if mock.Kind != models.HTTP || mock.Spec.HTTPReq == nil || string(mock.Spec.HTTPReq.Method) != method {
+			continue
+		}
+		u, err := url.Parse(mock.Spec.HTTPReq.URL)
+		if err != nil || rewritePath(u.Path, rules.URLRewrites) != path {
+			continue
+		}
+		res = append(res, mock)
+	}
+	return res, nil
+}
+
+// isIgnored reports whether the name is one of the names ignored, the names of the headers are compared
+// case-insensitively.
+func isIgnored(name string, ignored []string, header bool) bool {
+	for _, n := range ignored {
+		if n == name || header && strings.EqualFold(n, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// withoutIgnored returns a copy of the map without the keys ignored.
+func withoutIgnored[V any](m map[string]V, ignored []string, header bool) map[string]V {
+	if len(ignored) == 0 {
+		return m
+	}
+	res := make(map[string]V, len(m))
+	for key, value := range m {
+		if !isIgnored(key, ignored, header) {
+			res[key] = value
+		}
+	}
+	return res
+}
+
+// valueScore counts the headers and the query params of the mock, but the ignored ones, with the same values as
+// the ones of the request.
+func valueScore(mock *models.Mock, header http.Header, query url.Values, rules config.HTTPIntegration) int {
+	score := 0
+	for key, value := range mock.Spec.HTTPReq.Header {
+		if !isIgnored(key, rules.IgnoreHeaders, true) && strings.Join(header.Values(key), ",") == value {
+			score++
+		}
+	}
+	for key, value := range mock.Spec.HTTPReq.URLParams {
+		if !isIgnored(key, rules.IgnoreQueryParams, false) && query.Get(key) == value {
+			score++
+		}
+	}
+	return score
+}
+
+// rankByValues orders the mocks by the headers and the query params they have the same values of as the request,
+// the mocks matched first among the ones matching as much otherwise are then the ones closest to it. The order of
+// the mocks ranked the same is kept.
+func rankByValues(mocks []*models.Mock, input *req, rules config.HTTPIntegration) []*models.Mock {
+	query := input.url.Query()
+	scores := make(map[*models.Mock]int, len(mocks))
+	for _, mock := range mocks {
+		scores[mock] = valueScore(mock, input.header, query, rules)
+	}
+	ranked := append([]*models.Mock{}, mocks...)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return scores[ranked[i]] > scores[ranked[j]]
+	})
+	return ranked
+}
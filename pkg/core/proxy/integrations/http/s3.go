@@ -0,0 +1,78 @@
+//go:build linux
+
+package http
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// s3Object returns the bucket and the key of an S3 request, addressed in the virtual-hosted style
+// (bucket.s3.region.amazonaws.com/key) or in the path style (host/bucket/key) of the other endpoints.
+func s3Object(host string, path string) (string, string) {
+	if h, _, found := strings.Cut(host, ":"); found {
+		host = h
+	}
+	path = strings.TrimPrefix(path, "/")
+	if bucket, rest, ok := strings.Cut(host, ".s3"); ok && bucket != "" && strings.HasSuffix(rest, ".amazonaws.com") {
+		return bucket, path
+	}
+	bucket, key, _ := strings.Cut(path, "/")
+	return bucket, key
+}
+
+// isS3Upload reports whether the request uploads the content of an object or of a part of it.
+func isS3Upload(call *awsCall, method string, body []byte) bool {
+	return call.service == "s3" && method == http.MethodPut && call.key != "" && len(body) > 0
+}
+
+// s3Digest returns the digest of the content of an upload, which is stored instead of the content.
+func s3Digest(header http.Header, body []byte) string {
+	if isAWSChunked(header) {
+		if decoded, err := decodeAWSChunked(body); err == nil {
+			body = decoded
+		}
+	}
+	sum := sha256.Sum256(body)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// isAWSChunked reports whether the body is sent in the aws-chunked encoding of the streaming uploads,
+// where every chunk carries its own signature.
+func isAWSChunked(header http.Header) bool {
+	return strings.Contains(header.Get("Content-Encoding"), "aws-chunked") || strings.HasPrefix(header.Get("X-Amz-Content-Sha256"), "STREAMING-")
+}
+
+// decodeAWSChunked returns the content of an aws-chunked body, the chunks are framed as
+//
+//	hex-size[;chunk-signature=signature]\r\ndata\r\n
+//
+// up to a chunk of size 0, which may be followed by the trailing checksums.
+func decodeAWSChunked(body []byte) ([]byte, error) {
+	var content []byte
+	for {
+		end := bytes.Index(body, []byte("\r\n"))
+		if end == -1 {
+			return nil, errors.New("unterminated aws-chunked chunk header")
+		}
+		sizeHex, _, _ := strings.Cut(string(body[:end]), ";")
+		size, err := strconv.ParseInt(strings.TrimSpace(sizeHex), 16, 64)
+		if err != nil || size < 0 {
+			return nil, errors.New("invalid aws-chunked chunk size")
+		}
+		body = body[end+2:]
+		if size == 0 {
+			return content, nil
+		}
+		if int64(len(body)) < size+2 {
+			return nil, errors.New("truncated aws-chunked chunk")
+		}
+		content = append(content, body[:size]...)
+		body = body[size+2:]
+	}
+}
@@ -0,0 +1,256 @@
+//go:build linux
+
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/util"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+// The server-sent events are streamed in a text/event-stream response which stays open for as long as the
+// server has events to send, so the response is forwarded and recorded event by event instead of being
+// read up to its end. The events are stored with their times in the mock and replayed as a stream.
+
+// timingAccelerated sends the mocked events without waiting for their recorded times
+const timingAccelerated = "accelerated"
+
+// isEventStream reports whether the complete headers of the response declare a text/event-stream body.
+func isEventStream(resp []byte) bool {
+	end := bytes.Index(resp, []byte("\r\n\r\n"))
+	if end == -1 {
+		return false
+	}
+	for _, line := range strings.Split(string(resp[:end]), "\r\n")[1:] {
+		name, value, ok := strings.Cut(line, ":")
+		if !ok || !strings.EqualFold(strings.TrimSpace(name), "Content-Type") {
+			continue
+		}
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(value))
+		return err == nil && mediaType == "text/event-stream"
+	}
+	return false
+}
+
+// eventParser splits the body of an event stream into events as it is received.
+type eventParser struct {
+	start  time.Time
+	buf    []byte
+	events []models.ServerSentEvent
+}
+
+// feed adds a part of the body, the events completed by it are timed with the current time.
+func (p *eventParser) feed(data []byte) {
+	p.buf = bytes.ReplaceAll(append(p.buf, data...), []byte("\r\n"), []byte("\n"))
+	for {
+		end := bytes.Index(p.buf, []byte("\n\n"))
+		if end == -1 {
+			return
+		}
+		block := string(p.buf[:end])
+		p.buf = p.buf[end+2:]
+		if e, ok := parseEvent(block); ok {
+			e.Offset = time.Since(p.start).Milliseconds()
+			p.events = append(p.events, e)
+		}
+	}
+}
+
+// parseEvent parses the fields of an event, the blocks made only of comments (heartbeats) aren't events.
+func parseEvent(block string) (models.ServerSentEvent, bool) {
+	var (
+		e    models.ServerSentEvent
+		data []string
+		ok   bool
+	)
+	for _, line := range strings.Split(block, "\n") {
+		if line == "" || strings.HasPrefix(line, ":") {
+			continue
+		}
+		name, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch name {
+		case "id":
+			e.ID = value
+		case "event":
+			e.Event = value
+		case "data":
+			data = append(data, value)
+		case "retry":
+			e.Retry = value
+		default:
+			continue
+		}
+		ok = true
+	}
+	e.Data = strings.Join(data, "\n")
+	return e, ok
+}
+
+// encodeEvent renders an event in the text/event-stream format.
+func encodeEvent(e models.ServerSentEvent) []byte {
+	var b bytes.Buffer
+	if e.ID != "" {
+		b.WriteString("id: " + e.ID + "\n")
+	}
+	if e.Event != "" {
+		b.WriteString("event: " + e.Event + "\n")
+	}
+	if e.Retry != "" {
+		b.WriteString("retry: " + e.Retry + "\n")
+	}
+	if e.Data != "" || (e.ID == "" && e.Event == "" && e.Retry == "") {
+		for _, line := range strings.Split(e.Data, "\n") {
+			b.WriteString("data: " + line + "\n")
+		}
+	}
+	b.WriteString("\n")
+	return b.Bytes()
+}
+
+// recordEventStream forwards the rest of an event stream to the client and saves the response with its events
+// once the server ends the stream, or once either peer closes the conn. The stream is saved as open when the
+// server didn't end it, it is kept open in test mode as well. io.EOF is returned if the conn is closed.
+func recordEventStream(ctx context.Context, logger *zap.Logger, m *finalHTTP, clientConn, destConn net.Conn, destPort uint, mocks chan<- *models.Mock, opts models.OutgoingOptions) error {
+	end := bytes.Index(m.resp, []byte("\r\n\r\n")) + 4
+	header, body := m.resp[:end], m.resp[end:]
+
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(header)), nil)
+	if err != nil {
+		utils.LogError(logger, err, "failed to parse the headers of the event stream")
+		return err
+	}
+	var chunks *chunkDecoder
+	if len(resp.TransferEncoding) > 0 && resp.TransferEncoding[0] == "chunked" {
+		chunks = &chunkDecoder{}
+	}
+
+	parser := &eventParser{start: m.resTimestampMock}
+	feed := func(data []byte) error {
+		if chunks != nil {
+			var err error
+			if data, err = chunks.decode(data); err != nil {
+				return err
+			}
+		}
+		parser.feed(data)
+		return nil
+	}
+
+	// the server ends the stream with its last chunk, or by closing the conn when it isn't chunked
+	var (
+		streamErr error
+		ended     bool
+	)
+	if streamErr = feed(body); streamErr == nil {
+		for chunks == nil || !chunks.done {
+			data, err := util.ReadBytes(ctx, logger, destConn)
+			if len(data) > 0 {
+				if _, werr := clientConn.Write(data); werr != nil {
+					logger.Debug("the application stopped reading the event stream", zap.Error(werr))
+					streamErr = io.EOF
+					break
+				}
+				if ferr := feed(data); ferr != nil {
+					streamErr = ferr
+					break
+				}
+			}
+			if err != nil {
+				streamErr = err
+				ended = chunks == nil && err == io.EOF
+				break
+			}
+		}
+	}
+	if chunks != nil {
+		ended = chunks.done
+	}
+
+	m.resp = withoutFraming(header)
+	m.events = parser.events
+	m.streamOpen = !ended
+	if len(parser.events) > 0 {
+		m.resTimestampMock = m.resTimestampMock.Add(time.Duration(parser.events[len(parser.events)-1].Offset) * time.Millisecond)
+	}
+	logger.Debug("recorded the event stream", zap.Int("events", len(m.events)), zap.Bool("open", m.streamOpen))
+	err = ParseFinalHTTP(ctx, logger, m, destPort, mocks, opts)
+	if err != nil {
+		utils.LogError(logger, err, "failed to parse the final http request and response")
+		return err
+	}
+	if streamErr != nil && streamErr != io.EOF && ctx.Err() == nil {
+		utils.LogError(logger, streamErr, "failed to forward the event stream")
+	}
+	return streamErr
+}
+
+// withoutFraming removes the headers which frame the body, the events are stored without their framing.
+func withoutFraming(header []byte) []byte {
+	lines := bytes.Split(bytes.TrimSuffix(header, []byte("\r\n\r\n")), []byte("\r\n"))
+	kept := lines[:1]
+	for _, line := range lines[1:] {
+		name, _, _ := bytes.Cut(line, []byte(":"))
+		switch http.CanonicalHeaderKey(string(bytes.TrimSpace(name))) {
+		case "Transfer-Encoding", "Content-Length":
+			continue
+		}
+		kept = append(kept, line)
+	}
+	return append(bytes.Join(kept, []byte("\r\n")), "\r\n\r\n"...)
+}
+
+// writeEventStream writes the mocked event stream to the application, the events are sent at their recorded
+// times unless the timing is accelerated. The chunked framing is used unless the request is HTTP/1.0, whose
+// stream ends with the conn.
+func writeEventStream(ctx context.Context, clientConn net.Conn, statusLine string, header http.Header, resp *models.HTTPResp, chunked bool, opts models.OutgoingOptions) error {
+	header.Del("Content-Length")
+	header.Del("Transfer-Encoding")
+	if chunked {
+		header.Set("Transfer-Encoding", "chunked")
+	}
+
+	var b bytes.Buffer
+	b.WriteString(statusLine)
+	for key, values := range header {
+		for _, value := range values {
+			fmt.Fprintf(&b, "%s: %s\r\n", key, value)
+		}
+	}
+	b.WriteString("\r\n")
+	if _, err := clientConn.Write(b.Bytes()); err != nil {
+		return err
+	}
+
+	start := time.Now()
+	for _, e := range resp.Events {
+		if opts.SSETiming != timingAccelerated {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Until(start.Add(time.Duration(e.Offset) * time.Millisecond))):
+			}
+		}
+		data := encodeEvent(e)
+		if chunked {
+			data = append(append([]byte(strconv.FormatInt(int64(len(data)), 16)+"\r\n"), data...), "\r\n"...)
+		}
+		if _, err := clientConn.Write(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -3,10 +3,10 @@
 package http
 
 import (
-	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net"
@@ -16,6 +16,7 @@ import (
 	"strings"
 	"time"
 
+	"go.keploy.io/server/v2/pkg"
 	"go.keploy.io/server/v2/pkg/core/proxy/util"
 	"go.keploy.io/server/v2/pkg/models"
 	"go.keploy.io/server/v2/utils"
@@ -23,7 +24,39 @@ import (
 )
 
 func handleChunkedRequests(ctx context.Context, logger *zap.Logger, finalReq *[]byte, clientConn, destConn net.Conn) error {
+	err := readRequestHeaders(ctx, logger, finalReq, clientConn, destConn)
+	if err != nil {
+		return err
+	}
+
+	contentLengthHeader, chunked := framing(*finalReq)
+	if chunked {
+		return readChunked(ctx, logger, finalReq, clientConn, destConn)
+	}
 
+	// the rest of the body of the Content-Length given
+	if contentLengthHeader != "" {
+		contentLength, err := strconv.Atoi(contentLengthHeader)
+		if err != nil {
+			utils.LogError(logger, err, "failed to get the content-length header")
+			return fmt.Errorf("failed to handle chunked request")
+		}
+		//Get the length of the body in the request.
+		bodyLength := len(*finalReq) - strings.Index(string(*finalReq), "\r\n\r\n") - 4
+		contentLength -= bodyLength
+		if contentLength > 0 {
+			err := contentLengthRequest(ctx, logger, finalReq, clientConn, destConn, contentLength)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// readRequestHeaders reads the request up to the end of its headers, the clients waiting for a "100 Continue"
+// response send the body only once they get it.
+func readRequestHeaders(ctx context.Context, logger *zap.Logger, finalReq *[]byte, clientConn, destConn net.Conn) error {
 	if hasCompleteHeaders(*finalReq) {
 		logger.Debug("this request has complete headers in the first chunk itself.")
 	}
@@ -49,58 +82,17 @@ func handleChunkedRequests(ctx context.Context, logger *zap.Logger, finalReq *[]
 
 		*finalReq = append(*finalReq, reqHeader...)
 	}
-
-	lines := strings.Split(string(*finalReq), "\n")
-	var contentLengthHeader string
-	var transferEncodingHeader string
-	for _, line := range lines {
-		if strings.HasPrefix(line, "Content-Length:") {
-			contentLengthHeader = strings.TrimSpace(strings.TrimPrefix(line, "Content-Length:"))
-			break
-		} else if strings.HasPrefix(line, "Transfer-Encoding:") {
-			transferEncodingHeader = strings.TrimSpace(strings.TrimPrefix(line, "Transfer-Encoding:"))
-			break
-		}
-	}
-
-	//Handle chunked requests
-	if contentLengthHeader != "" {
-		contentLength, err := strconv.Atoi(contentLengthHeader)
-		if err != nil {
-			utils.LogError(logger, err, "failed to get the content-length header")
-			return fmt.Errorf("failed to handle chunked request")
-		}
-		//Get the length of the body in the request.
-		bodyLength := len(*finalReq) - strings.Index(string(*finalReq), "\r\n\r\n") - 4
-		contentLength -= bodyLength
-		if contentLength > 0 {
-			err := contentLengthRequest(ctx, logger, finalReq, clientConn, destConn, contentLength)
-			if err != nil {
-				return err
-			}
-		}
-	} else if transferEncodingHeader != "" {
-		// check if the initial request is the complete request.
-		if strings.HasSuffix(string(*finalReq), "0\r\n\r\n") {
-			return nil
-		}
-		if transferEncodingHeader == "chunked" {
-			err := chunkedRequest(ctx, logger, finalReq, clientConn, destConn, transferEncodingHeader)
-			if err != nil {
-				return err
-			}
-		}
-	}
 	return nil
 }
 
-func handleChunkedResponses(ctx context.Context, logger *zap.Logger, finalResp *[]byte, clientConn, destConn net.Conn, resp []byte) error {
+func handleChunkedResponses(ctx context.Context, logger *zap.Logger, finalResp *[]byte, clientConn, destConn net.Conn, resp, req []byte) error {
 
 	if hasCompleteHeaders(*finalResp) {
 		logger.Debug("this response has complete headers in the first chunk itself.")
 	}
 
-	for !hasCompleteHeaders(resp) {
+	// the interim responses, e.g. the "103 Early Hints", are forwarded up to the headers of the final response
+	for !hasCompleteHeaders(pkg.StripInterimHTTPResponses(resp)) {
 		logger.Debug("couldn't get complete headers in first chunk so reading more chunks")
 		respHeader, err := util.ReadBytes(ctx, logger, destConn)
 		if err != nil {
@@ -138,19 +130,17 @@ func handleChunkedResponses(ctx context.Context, logger *zap.Logger, finalResp *
 		resp = append(resp, respHeader...)
 	}
 
-	//Getting the content-length or the transfer-encoding header
-	var contentLengthHeader, transferEncodingHeader string
-	lines := strings.Split(string(resp), "\n")
-	for _, line := range lines {
-		if strings.HasPrefix(line, "Content-Length:") {
-			contentLengthHeader = strings.TrimSpace(strings.TrimPrefix(line, "Content-Length:"))
-			break
-		} else if strings.HasPrefix(line, "Transfer-Encoding:") {
-			transferEncodingHeader = strings.TrimSpace(strings.TrimPrefix(line, "Transfer-Encoding:"))
-			break
-		}
+	// the body of an event stream is read by recordEventStream as the events arrive
+	if isEventStream(resp) || isBodiless(req, resp) {
+		return nil
+	}
+
+	contentLengthHeader, chunked := framing(resp)
+	if chunked {
+		return readChunked(ctx, logger, finalResp, destConn, clientConn)
 	}
-	//Handle chunked responses
+
+	// the rest of the body of the Content-Length given
 	if contentLengthHeader != "" {
 		contentLength, err := strconv.Atoi(contentLengthHeader)
 		if err != nil {
@@ -165,23 +155,32 @@ func handleChunkedResponses(ctx context.Context, logger *zap.Logger, finalResp *
 				return err
 			}
 		}
-	} else if transferEncodingHeader != "" {
-		//check if the initial response is the complete response.
-		if strings.HasSuffix(string(*finalResp), "0\r\n\r\n") {
-			return nil
-		}
-		if transferEncodingHeader == "chunked" {
-			err := chunkedResponse(ctx, logger, finalResp, clientConn, destConn)
-			if err != nil {
-				return err
-			}
-		}
 	}
 	return nil
 }
 
+// continueTimeout is how long the clients wait for the "100 Continue" response before they send the body anyway,
+// the servers which ignore the Expect header never send it.
+const continueTimeout = time.Second
+
+// readContinue reads the response of the server to the headers of an "Expect: 100-continue" request. It's empty
+// when the server doesn't answer before the client sends the body anyway.
+func readContinue(ctx context.Context, logger *zap.Logger, destConn net.Conn) ([]byte, error) {
+	if err := destConn.SetReadDeadline(time.Now().Add(continueTimeout)); err != nil {
+		return nil, err
+	}
+	defer func() { _ = destConn.SetReadDeadline(time.Time{}) }()
+	resp, err := util.ReadBytes(ctx, logger, destConn)
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		return resp, nil
+	}
+	return resp, err
+}
+
 // Handled chunked requests when content-length is given.
 func contentLengthRequest(ctx context.Context, logger *zap.Logger, finalReq *[]byte, clientConn, destConn net.Conn, contentLength int) error {
+	// the deadline is only for the body, the conn is kept alive for the next requests
+	defer func() { _ = clientConn.SetReadDeadline(time.Time{}) }()
 	for contentLength > 0 {
 		err := clientConn.SetReadDeadline(time.Now().Add(5 * time.Second))
 		if err != nil {
@@ -219,53 +218,10 @@ func contentLengthRequest(ctx context.Context, logger *zap.Logger, finalReq *[]b
 	return nil
 }
 
-// Handled chunked requests when transfer-encoding is given.
-func chunkedRequest(ctx context.Context, logger *zap.Logger, finalReq *[]byte, clientConn, destConn net.Conn, _ string) error {
-
-	for {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-			//TODO: we have to implement a way to read the buffer chunk wise according to the chunk size (chunk size comes in hexadecimal)
-			// because it can happen that some chunks come after 5 seconds.
-			err := clientConn.SetReadDeadline(time.Now().Add(5 * time.Second))
-			if err != nil {
-				utils.LogError(logger, err, "failed to set the read deadline for the client conn")
-				return err
-			}
-			requestChunked, err := util.ReadBytes(ctx, logger, clientConn)
-			if err != nil {
-				if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
-					break
-				}
-				utils.LogError(logger, nil, "failed to read the response message from the destination server")
-				return err
-			}
-
-			*finalReq = append(*finalReq, requestChunked...)
-			// destConn is nil in case of test mode.
-			if destConn != nil {
-				_, err = destConn.Write(requestChunked)
-				if err != nil {
-					if ctx.Err() != nil {
-						return ctx.Err()
-					}
-					utils.LogError(logger, nil, "failed to write request message to the destination server")
-					return err
-				}
-			}
-
-			//check if the initial request is completed
-			if strings.HasSuffix(string(requestChunked), "0\r\n\r\n") {
-				return nil
-			}
-		}
-	}
-}
-
 // Handled chunked responses when content-length is given.
 func contentLengthResponse(ctx context.Context, logger *zap.Logger, finalResp *[]byte, clientConn, destConn net.Conn, contentLength int) error {
+	// the deadline is only for the body, the conn is kept alive for the next responses
+	defer func() { _ = destConn.SetReadDeadline(time.Time{}) }()
 	isEOF := false
 	for contentLength > 0 {
 		//Set deadline of 5 seconds
@@ -312,67 +268,26 @@ func contentLengthResponse(ctx context.Context, logger *zap.Logger, finalResp *[
 	return nil
 }
 
-// Handled chunked responses when transfer-encoding is given.
-func chunkedResponse(ctx context.Context, logger *zap.Logger, finalResp *[]byte, clientConn, destConn net.Conn) error {
-	isEOF := false
-	for {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-			resp, err := util.ReadBytes(ctx, logger, destConn)
-			if err != nil {
-				if err != io.EOF {
-					utils.LogError(logger, err, "failed to read the response message from the destination server")
-					return err
-				}
-				isEOF = true
-				logger.Debug("received EOF", zap.Error(err))
-				if len(resp) == 0 {
-					logger.Debug("exiting loop as response is complete")
-					break
-				}
-			}
-
-			*finalResp = append(*finalResp, resp...)
-			// write the response message to the user client
-			_, err = clientConn.Write(resp)
-			if err != nil {
-				if ctx.Err() != nil {
-					return ctx.Err()
-				}
-				utils.LogError(logger, nil, "failed to write response message to the user client")
-				return err
-			}
-
-			//In some cases need to write the response to the client
-			// where there is some response before getting the true EOF
-			if isEOF {
-				break
-			}
-
-			if string(resp) == "0\r\n\r\n" {
-				return nil
-			}
-		}
+// decodedBody returns the body decoded from its Content-Encoding, so that the mocks store it readable. The bodies
+// of the codings which aren't supported, or which fail to decode, are stored as they are sent.
+func decodedBody(logger *zap.Logger, body []byte, contentEncoding string) []byte {
+	decoded, err := pkg.DecodeBody(body, contentEncoding)
+	if err != nil {
+		logger.Debug("storing the http body as it is sent", zap.String("encoding", contentEncoding), zap.Error(err))
+		return body
 	}
+	return decoded
 }
 
-// Checks if the response is gzipped
-func isGZipped(check io.ReadCloser, l *zap.Logger) (bool, *bufio.Reader) {
-	bufReader := bufio.NewReader(check)
-	peekedBytes, err := bufReader.Peek(2)
-	if err != nil && err != io.EOF {
-		l.Debug("failed to peek the response", zap.Error(err))
-		return false, nil
-	}
-	if len(peekedBytes) < 2 {
-		return false, nil
-	}
-	if peekedBytes[0] == 0x1f && peekedBytes[1] == 0x8b {
-		return true, bufReader
+// encodedBody returns the body of the mock encoded with its Content-Encoding, as the server sent it.
+func encodedBody(logger *zap.Logger, body []byte, contentEncoding string) ([]byte, error) {
+	encoded, err := pkg.EncodeBody(body, contentEncoding)
+	if errors.Is(err, pkg.ErrUnsupportedEncoding) {
+		// the body was stored as it was sent
+		logger.Debug("replaying the http body as it is recorded", zap.String("encoding", contentEncoding))
+		return body, nil
 	}
-	return false, nil
+	return encoded, err
 }
 
 // hasCompleteHeaders checks if the given byte slice contains the complete HTTP headers
@@ -20,24 +20,79 @@ type integrationType string
 const (
 	HTTP        integrationType = "http"
 	GRPC        integrationType = "grpc"
+	HTTP2       integrationType = "http2"
 	GENERIC     integrationType = "generic"
 	MYSQL       integrationType = "mysql"
 	POSTGRES_V1 integrationType = "postgres_v1"
 	POSTGRES_V2 integrationType = "postgres_v2"
 	MONGO       integrationType = "mongo"
 	REDIS       integrationType = "redis"
+	KAFKA       integrationType = "kafka"
+	MQTT        integrationType = "mqtt"
+	LDAP        integrationType = "ldap"
+	THRIFT      integrationType = "thrift"
+	MSSQL       integrationType = "mssql"
+	MYSQLX      integrationType = "mysqlx"
+	WEBSOCKET   integrationType = "websocket"
+	ZEROMQ      integrationType = "zeromq"
+	AMQP1       integrationType = "amqp1"
+	OPENWIRE    integrationType = "openwire"
+	DNS         integrationType = "dns"
 )
 
 var Registered = make(map[string]Initializer)
 
 type ConditionalDstCfg struct {
-	Addr   string // Destination Addr (ip:port)
-	Port   uint
+	Addr string // Destination Addr (host:port), IPv6 hosts are enclosed in brackets
+	Port uint
+	// IP is the IPv4 or IPv6 address the application connected to, Addr holds the server name instead for the tls conns
+	IP     net.IP
 	TLSCfg *tls.Config
 }
 
+// Network returns the network to dial the destination on. The destination is dialed over the same IP family
+// the application used, so that a dual-stack server name isn't resolved to the other family.
+func (d *ConditionalDstCfg) Network() string {
+	switch {
+	case d.IP == nil:
+		return "tcp"
+	case d.IP.To4() != nil:
+		return "tcp4"
+	default:
+		return "tcp6"
+	}
+}
+
+// TLSUpgrader starts tls on the conns of the protocols asking for it within their own handshake, like the
+// SSLRequest of mysql. The proxy puts it in the context of the integrations under models.TLSUpgraderKey.
+type TLSUpgrader interface {
+	// UpgradeClient terminates the tls of the application on its conn, with a certificate of the keploy CA.
+	UpgradeClient(ctx context.Context, conn net.Conn) (*tls.Conn, error)
+	// UpgradeDest starts tls on the conn to the destination, the certificate of the destination isn't verified.
+	UpgradeDest(ctx context.Context, conn net.Conn, serverName string) (*tls.Conn, error)
+}
+
+// Confidence levels returned by the integrations while detecting the protocol of a connection
+const (
+	ConfidenceNone    = 0
+	ConfidenceLow     = 25
+	ConfidenceMedium  = 50
+	ConfidenceHigh    = 75
+	ConfidenceCertain = 100
+)
+
+// MatchResult is the outcome of the protocol detection on the initial bytes of a connection.
+type MatchResult struct {
+	// Confidence ranges from ConfidenceNone (not this protocol) to ConfidenceCertain.
+	Confidence int
+	// NeedBytes is the total number of bytes the integration needs to be sure about the protocol.
+	// The proxy buffers more data and asks again if it is more than the bytes read so far.
+	NeedBytes int
+}
+
 type Integrations interface {
-	MatchType(ctx context.Context, reqBuf []byte) bool
+	// MatchType scores how likely the initial bytes of the connection belong to the integration's protocol.
+	MatchType(ctx context.Context, reqBuf []byte) MatchResult
 	RecordOutgoing(ctx context.Context, src net.Conn, dst net.Conn, mocks chan<- *models.Mock, opts models.OutgoingOptions) error
 	MockOutgoing(ctx context.Context, src net.Conn, dstCfg *ConditionalDstCfg, mockDb MockMemDb, opts models.OutgoingOptions) error
 }
@@ -49,6 +104,10 @@ func Register(name string, i Initializer) {
 type MockMemDb interface {
 	GetFilteredMocks() ([]*models.Mock, error)
 	GetUnFilteredMocks() ([]*models.Mock, error)
+	// GetFilteredMocksByKey and GetUnFilteredMocksByKey return the mocks of the kind whose request signature
+	// (see MockKey) is the given key, in the same order as the other getters.
+	GetFilteredMocksByKey(kind models.Kind, key string) ([]*models.Mock, error)
+	GetUnFilteredMocksByKey(kind models.Kind, key string) ([]*models.Mock, error)
 	UpdateUnFilteredMock(old *models.Mock, new *models.Mock) bool
 	DeleteFilteredMock(mock models.Mock) bool
 	DeleteUnFilteredMock(mock models.Mock) bool
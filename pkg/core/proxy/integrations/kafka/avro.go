@@ -0,0 +1,287 @@
+//go:build linux
+
+package kafka
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+)
+
+// avroSchema is a parsed avro schema, the logical types are decoded as their underlying type.
+type avroSchema struct {
+	typ      string
+	fields   []avroField
+	symbols  []string
+	items    *avroSchema // items of the arrays, values of the maps
+	branches []*avroSchema
+	size     int
+}
+
+type avroField struct {
+	name   string
+	schema *avroSchema
+}
+
+// avroSchemas caches the parsed schemas by their text
+var avroSchemas sync.Map
+
+func parseAvroSchema(text string) (*avroSchema, error) {
+	if s, ok := avroSchemas.Load(text); ok {
+		return s.(*avroSchema), nil
+	}
+	var raw any
+	if err := json.Unmarshal([]byte(text), &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse the avro schema: %w", err)
+	}
+	s, err := parseAvro(raw, "", make(map[string]*avroSchema))
+	if err != nil {
+		return nil, err
+	}
+	avroSchemas.Store(text, s)
+	return s, nil
+}
+
+func parseAvro(raw any, namespace string, names map[string]*avroSchema) (*avroSchema, error) {
+	switch v := raw.(type) {
+	case string:
+		switch v {
+		case "null", "boolean", "int", "long", "float", "double", "bytes", "string":
+			return &avroSchema{typ: v}, nil
+		}
+		// a reference to a named type
+		if s, ok := names[fullName(v, namespace)]; ok {
+			return s, nil
+		}
+		if s, ok := names[v]; ok {
+			return s, nil
+		}
+		return nil, fmt.Errorf("unknown avro type %q", v)
+	case []any:
+		s := &avroSchema{typ: "union"}
+		for _, branch := range v {
+			b, err := parseAvro(branch, namespace, names)
+			if err != nil {
+				return nil, err
+			}
+			s.branches = append(s.branches, b)
+		}
+		return s, nil
+	case map[string]any:
+		typ, _ := v["type"].(string)
+		if ns, ok := v["namespace"].(string); ok {
+			namespace = ns
+		}
+		name, _ := v["name"].(string)
+		if name != "" {
+			name = fullName(name, namespace)
+			// the named types are qualified by the namespace of their full name
+			if i := strings.LastIndex(name, "."); i >= 0 {
+				namespace = name[:i]
+			}
+		}
+
+		switch typ {
+		case "record", "error":
+			s := &avroSchema{typ: "record"}
+			// registered before the fields, which can refer to the record
+			names[name] = s
+			fields, _ := v["fields"].([]any)
+			for _, f := range fields {
+				field, _ := f.(map[string]any)
+				fieldName, _ := field["name"].(string)
+				fs, err := parseAvro(field["type"], namespace, names)
+				if err != nil {
+					return nil, err
+				}
+				s.fields = append(s.fields, avroField{name: fieldName, schema: fs})
+			}
+			return s, nil
+		case "enum":
+			s := &avroSchema{typ: "enum"}
+			symbols, _ := v["symbols"].([]any)
+			for _, sym := range symbols {
+				str, _ := sym.(string)
+				s.symbols = append(s.symbols, str)
+			}
+			names[name] = s
+			return s, nil
+		case "fixed":
+			size, _ := v["size"].(float64)
+			s := &avroSchema{typ: "fixed", size: int(size)}
+			names[name] = s
+			return s, nil
+		case "array", "map":
+			key := "items"
+			if typ == "map" {
+				key = "values"
+			}
+			items, err := parseAvro(v[key], namespace, names)
+			if err != nil {
+				return nil, err
+			}
+			return &avroSchema{typ: typ, items: items}, nil
+		default:
+			// a primitive type, possibly annotated with a logical type
+			return parseAvro(v["type"], namespace, names)
+		}
+	}
+	return nil, fmt.Errorf("invalid avro schema %v", raw)
+}
+
+func fullName(name, namespace string) string {
+	if strings.Contains(name, ".") || namespace == "" {
+		return name
+	}
+	return namespace + "." + name
+}
+
+// avroReader decodes the avro binary encoding, the first error stops the decoding and is kept in err.
+type avroReader struct {
+	buf []byte
+	off int
+	err error
+}
+
+func (r *avroReader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || r.off+n > len(r.buf) {
+		r.err = errShortBuffer
+		return nil
+	}
+	b := r.buf[r.off : r.off+n]
+	r.off += n
+	return b
+}
+
+// long reads a zigzag encoded variable length integer, which encodes the ints as well.
+func (r *avroReader) long() int64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(r.buf[r.off:])
+	if n <= 0 {
+		r.err = errShortBuffer
+		return 0
+	}
+	r.off += n
+	return v
+}
+
+// blockLen reads the item count of the next block of an array or a map, skipping the size of the block.
+func (r *avroReader) blockLen() int {
+	n := r.long()
+	if n < 0 {
+		r.long() // size of the block in bytes
+		n = -n
+	}
+	if n > int64(len(r.buf)) {
+		// every item takes a byte at least
+		r.err = errShortBuffer
+		return 0
+	}
+	return int(n)
+}
+
+// read decodes a value of the schema into the value marshaled as its json.
+func (r *avroReader) read(s *avroSchema) any {
+	if r.err != nil {
+		return nil
+	}
+	switch s.typ {
+	case "null":
+		return nil
+	case "boolean":
+		b := r.next(1)
+		return b != nil && b[0] != 0
+	case "int", "long":
+		return r.long()
+	case "float":
+		b := r.next(4)
+		if b == nil {
+			return nil
+		}
+		return jsonFloat(float64(math.Float32frombits(binary.LittleEndian.Uint32(b))))
+	case "double":
+		b := r.next(8)
+		if b == nil {
+			return nil
+		}
+		return jsonFloat(math.Float64frombits(binary.LittleEndian.Uint64(b)))
+	case "bytes":
+		return r.next(int(r.long()))
+	case "string":
+		return string(r.next(int(r.long())))
+	case "fixed":
+		return r.next(s.size)
+	case "enum":
+		i := int(r.long())
+		if i < 0 || i >= len(s.symbols) {
+			r.err = fmt.Errorf("avro enum index %d is out of range", i)
+			return nil
+		}
+		return s.symbols[i]
+	case "union":
+		i := int(r.long())
+		if i < 0 || i >= len(s.branches) {
+			r.err = fmt.Errorf("avro union index %d is out of range", i)
+			return nil
+		}
+		return r.read(s.branches[i])
+	case "array":
+		items := []any{}
+		for n := r.blockLen(); n > 0 && r.err == nil; n = r.blockLen() {
+			for i := 0; i < n && r.err == nil; i++ {
+				items = append(items, r.read(s.items))
+			}
+		}
+		return items
+	case "map":
+		m := map[string]any{}
+		for n := r.blockLen(); n > 0 && r.err == nil; n = r.blockLen() {
+			for i := 0; i < n && r.err == nil; i++ {
+				key := string(r.next(int(r.long())))
+				m[key] = r.read(s.items)
+			}
+		}
+		return m
+	case "record":
+		m := make(map[string]any, len(s.fields))
+		for _, f := range s.fields {
+			m[f.name] = r.read(f.schema)
+		}
+		return m
+	}
+	r.err = fmt.Errorf("unknown avro type %q", s.typ)
+	return nil
+}
+
+// jsonFloat keeps the floats json can't represent as strings.
+func jsonFloat(f float64) any {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return fmt.Sprint(f)
+	}
+	return f
+}
+
+// decodeAvro decodes the avro encoded value of the schema into its json.
+func decodeAvro(s *avroSchema, data []byte) (string, error) {
+	r := &avroReader{buf: data}
+	v := r.read(s)
+	if r.err != nil {
+		return "", fmt.Errorf("failed to decode the avro value: %w", r.err)
+	}
+	if r.off != len(data) {
+		return "", fmt.Errorf("the avro value has %d trailing bytes", len(data)-r.off)
+	}
+	res, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(res), nil
+}
@@ -0,0 +1,97 @@
+//go:build linux
+
+package kafka
+
+import (
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+const userSchema = `{"type":"record","name":"User","namespace":"shop","fields":[
+	{"name":"id","type":"long"},
+	{"name":"name","type":"string"},
+	{"name":"tags","type":{"type":"array","items":"string"}},
+	{"name":"kind","type":{"type":"enum","name":"Kind","symbols":["GUEST","MEMBER"]}},
+	{"name":"note","type":["null","string"]},
+	{"name":"friend","type":["null","shop.User"]}
+]}`
+
+func avroString(b []byte, s string) []byte {
+	b = binary.AppendVarint(b, int64(len(s)))
+	return append(b, s...)
+}
+
+// avroUser encodes a user of the userSchema, with a friend of the id when it isn't 0.
+func avroUser(id int64, friend int64) []byte {
+	var b []byte
+	b = binary.AppendVarint(b, id)
+	b = avroString(b, "ada")
+	b = binary.AppendVarint(b, 2) // a block of two tags
+	b = avroString(b, "a")
+	b = avroString(b, "b")
+	b = binary.AppendVarint(b, 0) // the end of the array
+	b = binary.AppendVarint(b, 1) // MEMBER
+	b = binary.AppendVarint(b, 1) // the string branch of the note
+	b = avroString(b, "hi")
+	if friend == 0 {
+		return binary.AppendVarint(b, 0) // no friend
+	}
+	b = binary.AppendVarint(b, 1)
+	return append(b, avroUser(friend, 0)...)
+}
+
+func TestDecodeConfluent(t *testing.T) {
+	schemas := func(id int32) (string, error) {
+		if id == 5 {
+			return userSchema, nil
+		}
+		return "", errors.New("unknown schema")
+	}
+	confluent := func(id uint32, data []byte) []byte {
+		return append(binary.BigEndian.AppendUint32([]byte{0}, id), data...)
+	}
+	tests := []struct {
+		name    string
+		data    []byte
+		want    string
+		decoded bool
+	}{
+		{
+			name:    "record",
+			data:    confluent(5, avroUser(-3, 0)),
+			want:    `{"friend":null,"id":-3,"kind":"MEMBER","name":"ada","note":"hi","tags":["a","b"]}`,
+			decoded: true,
+		},
+		{
+			name:    "recursive record",
+			data:    confluent(5, avroUser(1, 2)),
+			want:    `{"friend":{"friend":null,"id":2,"kind":"MEMBER","name":"ada","note":"hi","tags":["a","b"]},"id":1,"kind":"MEMBER","name":"ada","note":"hi","tags":["a","b"]}`,
+			decoded: true,
+		},
+		{name: "unknown schema", data: confluent(6, avroUser(1, 0)), want: string(confluent(6, avroUser(1, 0)))},
+		{name: "trailing bytes", data: append(confluent(5, avroUser(1, 0)), 0), want: string(append(confluent(5, avroUser(1, 0)), 0))},
+		{name: "cut short", data: confluent(5, avroUser(1, 0))[:8], want: string(confluent(5, avroUser(1, 0))[:8])},
+		{name: "plain text", data: []byte("hello"), want: "hello"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			used := map[int32]string{}
+			got, id, decoded := decodeConfluent(tt.data, schemas, used)
+			if got != tt.want || decoded != tt.decoded {
+				t.Errorf("decodeConfluent() = %s, %v, want %s, %v", got, decoded, tt.want, tt.decoded)
+			}
+			wantUsed := map[int32]string{}
+			if tt.decoded {
+				wantUsed[5] = userSchema
+				if id != 5 {
+					t.Errorf("decodeConfluent() schema id = %d, want 5", id)
+				}
+			}
+			if !reflect.DeepEqual(used, wantUsed) {
+				t.Errorf("decodeConfluent() used the schemas %v, want %v", used, wantUsed)
+			}
+		})
+	}
+}
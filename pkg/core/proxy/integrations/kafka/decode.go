@@ -0,0 +1,90 @@
+//go:build linux
+
+package kafka
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations/util"
+	pUtil "go.keploy.io/server/v2/pkg/core/proxy/util"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+// decodeKafka answers the requests of the client from the mocks. The recorded correlation ids differ from the
+// ones of the client, so the responses are sent with the correlation id of the request they answer.
+func decodeKafka(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientConn net.Conn, _ *integrations.ConditionalDstCfg, mockDb integrations.MockMemDb, _ models.OutgoingOptions) error {
+	logger.Debug("Into the kafka parser in test mode")
+	errCh := make(chan error, 1)
+
+	go func() {
+		defer pUtil.Recover(logger, clientConn, nil)
+		defer close(errCh)
+		client := io.MultiReader(bytes.NewReader(reqBuf), clientConn)
+		for {
+			frame, err := readFrame(client)
+			if err != nil {
+				if err != io.EOF {
+					utils.LogError(logger, err, "failed to read the kafka request from the client")
+				}
+				errCh <- err
+				return
+			}
+			req, err := parseRequest(frame)
+			if err != nil {
+				utils.LogError(logger, err, "failed to parse the kafka request")
+				errCh <- err
+				return
+			}
+
+			mock, err := match(ctx, req, mockDb)
+			if err != nil {
+				utils.LogError(logger, err, "error while matching kafka mocks")
+				errCh <- err
+				return
+			}
+			if mock == nil {
+				// the brokers aren't available while testing, so the unmatched requests can't be passed through
+				err := fmt.Errorf("no kafka mock found for the %s v%d request", req.header.APIName, req.header.APIVersion)
+				utils.LogError(logger, err, "failed to mock the kafka request", zap.Strings("topics", req.topics))
+				errCh <- err
+				return
+			}
+			if !req.expectsResponse() || len(mock.Spec.KafkaResponses) == 0 {
+				continue
+			}
+
+			body, err := util.DecodeBase64(mock.Spec.KafkaResponses[0].Body)
+			if err != nil {
+				utils.LogError(logger, err, "failed to decode the base64 kafka response")
+				errCh <- err
+				return
+			}
+			_, err = clientConn.Write(responseFrame(req.header.CorrelationID, body))
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				utils.LogError(logger, err, "failed to write the kafka response to the client application")
+				errCh <- err
+				return
+			}
+		}
+	}()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-errCh:
+		if err == io.EOF {
+			return nil
+		}
+		return err
+	}
+}
@@ -0,0 +1,212 @@
+//go:build linux
+
+package kafka
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations/util"
+	pUtil "go.keploy.io/server/v2/pkg/core/proxy/util"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+// pendingRequest is a request forwarded to the broker which is waiting for its response.
+type pendingRequest struct {
+	req              *request
+	reqTimestampMock time.Time
+}
+
+// encodeKafka forwards the requests and responses between the client and the broker and records every
+// request with its response as a mock. The clients can have several requests in flight on a connection,
+// so the responses are paired with their requests by the correlation id. The produced records are kept with
+// the mocks, the avro values are decoded with the schema registry when one is configured, and so are the
+// fetched records.
+func encodeKafka(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientConn, destConn net.Conn, mocks chan<- *models.Mock, opts models.OutgoingOptions) error {
+	g, ok := ctx.Value(models.ErrGroupKey).(*errgroup.Group)
+	if !ok {
+		return errors.New("failed to get the error group from the context")
+	}
+	connID, _ := ctx.Value(models.ClientConnectionIDKey).(string)
+	var schemas schemaSource
+	if reg := newRegistry(opts.SchemaRegistry); reg != nil {
+		schemas = reg.schema
+	}
+
+	var mu sync.Mutex
+	pending := make(map[int32]pendingRequest)
+	errCh := make(chan error, 2)
+
+	// Forward the requests from the client to the destination server
+	g.Go(func() error {
+		defer pUtil.Recover(logger, clientConn, destConn)
+		client := io.MultiReader(bytes.NewReader(reqBuf), clientConn)
+		for {
+			frame, err := readFrame(client)
+			if err != nil {
+				if err != io.EOF {
+					utils.LogError(logger, err, "failed to read the kafka request from the client")
+				}
+				errCh <- err
+				return nil
+			}
+			req, err := parseRequest(frame)
+			if err != nil {
+				utils.LogError(logger, err, "failed to parse the kafka request")
+				errCh <- err
+				return nil
+			}
+			logger.Debug("kafka request", zap.String("api", req.header.APIName), zap.Int16("version", req.header.APIVersion), zap.Int32("correlationID", req.header.CorrelationID), zap.Strings("topics", req.topics))
+
+			reqTimestampMock := time.Now()
+			if req.header.APIKey == apiProduce {
+				decodeProduced(logger, req, schemas)
+			}
+			expectsResponse := req.expectsResponse()
+			if expectsResponse {
+				// register the request before forwarding it, so that the response can't arrive first
+				mu.Lock()
+				pending[req.header.CorrelationID] = pendingRequest{req: req, reqTimestampMock: reqTimestampMock}
+				mu.Unlock()
+			}
+
+			_, err = destConn.Write(frame)
+			if err != nil {
+				utils.LogError(logger, err, "failed to write the kafka request to the destination server")
+				errCh <- err
+				return nil
+			}
+
+			if !expectsResponse {
+				saveMock(req, nil, reqTimestampMock, time.Now(), connID, mocks)
+			}
+		}
+	})
+
+	// Forward the responses from the destination server to the client
+	g.Go(func() error {
+		defer pUtil.Recover(logger, clientConn, destConn)
+		for {
+			frame, err := readFrame(destConn)
+			if err != nil {
+				if err != io.EOF {
+					utils.LogError(logger, err, "failed to read the kafka response from the destination server")
+				}
+				errCh <- err
+				return nil
+			}
+
+			_, err = clientConn.Write(frame)
+			if err != nil {
+				utils.LogError(logger, err, "failed to write the kafka response to the client")
+				errCh <- err
+				return nil
+			}
+			resTimestampMock := time.Now()
+
+			correlationID, body, err := parseResponse(frame)
+			if err != nil {
+				utils.LogError(logger, err, "failed to parse the kafka response")
+				errCh <- err
+				return nil
+			}
+			mu.Lock()
+			p, ok := pending[correlationID]
+			delete(pending, correlationID)
+			mu.Unlock()
+			if !ok {
+				logger.Debug("no pending kafka request for the response", zap.Int32("correlationID", correlationID))
+				continue
+			}
+			resp := &models.KafkaResponse{
+				CorrelationID: correlationID,
+				Body:          util.EncodeBase64(body),
+			}
+			if schemas != nil && p.req.header.APIKey == apiFetch {
+				resp.Records = decodeFetched(logger, p.req.header.APIVersion, body, schemas)
+			}
+			saveMock(p.req, resp, p.reqTimestampMock, resTimestampMock, connID, mocks)
+		}
+	})
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-errCh:
+		if err == io.EOF {
+			return nil
+		}
+		return err
+	}
+}
+
+// decodeProduced decodes the records of the produce request, the requests whose records can't be decoded are
+// recorded with their body only.
+func decodeProduced(logger *zap.Logger, req *request, schemas schemaSource) {
+	partitions, err := produceRecords(req)
+	if err != nil {
+		logger.Debug("failed to read the partitions of the produce request", zap.Error(err))
+		return
+	}
+	used := make(map[int32]string)
+	req.records, err = decodeRecords(partitions, schemas, used)
+	if err != nil {
+		logger.Debug("failed to decode the produced records", zap.Error(err))
+	}
+	if len(used) > 0 {
+		req.schemas = used
+	}
+}
+
+// decodeFetched decodes the records of the fetch response.
+func decodeFetched(logger *zap.Logger, version int16, body []byte, schemas schemaSource) []models.KafkaRecord {
+	partitions, err := fetchRecords(version, body)
+	if err != nil {
+		logger.Debug("failed to read the partitions of the fetch response", zap.Error(err))
+		return nil
+	}
+	records, err := decodeRecords(partitions, schemas, make(map[int32]string))
+	if err != nil {
+		logger.Debug("failed to decode the fetched records", zap.Error(err))
+	}
+	return records
+}
+
+// saveMock records the request with its response, the response is nil for the requests without one.
+func saveMock(req *request, resp *models.KafkaResponse, reqTimestampMock, resTimestampMock time.Time, connID string, mocks chan<- *models.Mock) {
+	metadata := map[string]string{
+		"api": req.header.APIName,
+	}
+	// the produced and fetched records belong to the test cases, the rest is the setup of the client
+	if req.header.APIKey != apiProduce && req.header.APIKey != apiFetch {
+		metadata["type"] = "config"
+	}
+
+	var responses []models.KafkaResponse
+	if resp != nil {
+		responses = append(responses, *resp)
+	}
+
+	mocks <- &models.Mock{
+		Version: models.GetVersion(),
+		Name:    "mocks",
+		Kind:    models.Kafka,
+		Spec: models.MockSpec{
+			Metadata:         metadata,
+			KafkaRequests:    []models.KafkaRequest{req.model()},
+			KafkaResponses:   responses,
+			ReqTimestampMock: reqTimestampMock,
+			ResTimestampMock: resTimestampMock,
+		},
+		ConnectionID: connID,
+	}
+}
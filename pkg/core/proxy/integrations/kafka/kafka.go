@@ -0,0 +1,94 @@
+//go:build linux
+
+// Package kafka provides the integration for the kafka wire protocol.
+package kafka
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	"go.keploy.io/server/v2/pkg/core/proxy/util"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+func init() {
+	integrations.Register("kafka", NewKafka)
+}
+
+type Kafka struct {
+	logger *zap.Logger
+}
+
+func NewKafka(logger *zap.Logger) integrations.Integrations {
+	return &Kafka{
+		logger: logger,
+	}
+}
+
+// MatchType checks whether the buffer starts with a plausible kafka request header. The clients usually start
+// with an ApiVersions request which is a stronger signal than the other apis.
+func (k *Kafka) MatchType(_ context.Context, buf []byte) integrations.MatchResult {
+	if len(buf) < 4+requestHeaderSize {
+		return integrations.MatchResult{NeedBytes: 4 + requestHeaderSize}
+	}
+	size := int(binary.BigEndian.Uint32(buf[0:4]))
+	apiKey := int16(binary.BigEndian.Uint16(buf[4:6]))
+	apiVersion := int16(binary.BigEndian.Uint16(buf[6:8]))
+	clientIDLen := int(int16(binary.BigEndian.Uint16(buf[12:14])))
+
+	switch {
+	case size < requestHeaderSize || size > maxFrameSize:
+		return integrations.MatchResult{}
+	case apiKey < 0 || apiKey > maxAPIKey || apiVersion < 0 || apiVersion > maxAPIVersion:
+		return integrations.MatchResult{}
+	case apiKey == apiMetadata && apiVersion == 0:
+		// Metadata v0 predates the ApiVersions request and has the same bytes as the postgres startup message
+		return integrations.MatchResult{}
+	case clientIDLen < -1 || requestHeaderSize+clientIDLen > size:
+		return integrations.MatchResult{}
+	case len(buf) < 4+size:
+		return integrations.MatchResult{Confidence: integrations.ConfidenceLow, NeedBytes: 4 + size}
+	case apiKey == apiAPIVersions:
+		return integrations.MatchResult{Confidence: integrations.ConfidenceHigh}
+	default:
+		return integrations.MatchResult{Confidence: integrations.ConfidenceMedium}
+	}
+}
+
+func (k *Kafka) RecordOutgoing(ctx context.Context, src net.Conn, dst net.Conn, mocks chan<- *models.Mock, opts models.OutgoingOptions) error {
+	logger := k.logger.With(zap.Any("Client IP Address", src.RemoteAddr().String()), zap.Any("Client ConnectionID", ctx.Value(models.ClientConnectionIDKey).(string)), zap.Any("Destination ConnectionID", ctx.Value(models.DestConnectionIDKey).(string)))
+
+	reqBuf, err := util.ReadInitialBuf(ctx, logger, src)
+	if err != nil {
+		utils.LogError(logger, err, "failed to read the initial kafka message")
+		return err
+	}
+
+	err = encodeKafka(ctx, logger, reqBuf, src, dst, mocks, opts)
+	if err != nil {
+		utils.LogError(logger, err, "failed to encode the kafka message into the yaml")
+		return err
+	}
+	return nil
+}
+
+func (k *Kafka) MockOutgoing(ctx context.Context, src net.Conn, dstCfg *integrations.ConditionalDstCfg, mockDb integrations.MockMemDb, opts models.OutgoingOptions) error {
+	logger := k.logger.With(zap.Any("Client IP Address", src.RemoteAddr().String()), zap.Any("Client ConnectionID", ctx.Value(models.ClientConnectionIDKey).(string)), zap.Any("Destination ConnectionID", ctx.Value(models.DestConnectionIDKey).(string)))
+
+	reqBuf, err := util.ReadInitialBuf(ctx, logger, src)
+	if err != nil {
+		utils.LogError(logger, err, "failed to read the initial kafka message")
+		return err
+	}
+
+	err = decodeKafka(ctx, logger, reqBuf, src, dstCfg, mockDb, opts)
+	if err != nil {
+		utils.LogError(logger, err, "failed to decode the kafka message")
+		return err
+	}
+	return nil
+}
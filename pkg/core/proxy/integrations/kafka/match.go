@@ -0,0 +1,132 @@
+//go:build linux
+
+package kafka
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"slices"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations/util"
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+// match finds the mock of the request among the mocks of the same api, version and topics. The mocks recorded
+// during the current test case are preferred. The produce requests whose records equal the recorded ones win,
+// compared after decoding their avro values since the batches differ between the runs in their timestamps
+// and producer ids. Otherwise the body closest to the request wins, since the fetch offsets differ as well. The matched mock is moved behind the others, so that
+// repeated requests like the polls of a consumer are answered with the recorded responses in order.
+func match(ctx context.Context, req *request, mockDb integrations.MockMemDb) (*models.Mock, error) {
+	decoded := false
+	var records []models.KafkaRecord
+	for {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		mocks, err := mockDb.GetUnFilteredMocksByKey(models.Kafka, "")
+		if err != nil {
+			return nil, fmt.Errorf("error while getting unfiltered mocks %v", err)
+		}
+
+		var filteredMocks, unfilteredMocks []*models.Mock
+		for _, mock := range mocks {
+			if mock.Kind != models.Kafka || len(mock.Spec.KafkaRequests) == 0 || !sameRequest(mock.Spec.KafkaRequests[0], req) {
+				continue
+			}
+			if mock.TestModeInfo.IsFiltered {
+				filteredMocks = append(filteredMocks, mock)
+			} else {
+				unfilteredMocks = append(unfilteredMocks, mock)
+			}
+		}
+
+		if req.header.APIKey == apiProduce && !decoded {
+			records = producedRecords(req, slices.Concat(filteredMocks, unfilteredMocks))
+			decoded = true
+		}
+
+		mock := closest(filteredMocks, req, records)
+		if mock == nil {
+			mock = closest(unfilteredMocks, req, records)
+		}
+		if mock == nil {
+			return nil, nil
+		}
+
+		originalMock := *mock
+		mock.TestModeInfo.IsFiltered = false
+		mock.TestModeInfo.SortOrder = math.MaxInt64
+		if !mockDb.UpdateUnFilteredMock(&originalMock, mock) {
+			// the mock was used by another request in the meantime
+			continue
+		}
+		return mock, nil
+	}
+}
+
+// sameRequest reports whether the recorded request has the api, version and topics of the request.
+func sameRequest(recorded models.KafkaRequest, req *request) bool {
+	return recorded.Header.APIKey == req.header.APIKey &&
+		recorded.Header.APIVersion == req.header.APIVersion &&
+		slices.Equal(recorded.Topics, req.topics)
+}
+
+// producedRecords decodes the records of the produce request with the avro schemas recorded by the mocks, the
+// schema registry isn't needed while testing.
+func producedRecords(req *request, mocks []*models.Mock) []models.KafkaRecord {
+	schemas := make(map[int32]string)
+	for _, mock := range mocks {
+		for id, schema := range mock.Spec.KafkaRequests[0].AvroSchemas {
+			schemas[id] = schema
+		}
+	}
+	partitions, err := produceRecords(req)
+	if err != nil {
+		return nil
+	}
+	records, err := decodeRecords(partitions, func(id int32) (string, error) {
+		if schema, ok := schemas[id]; ok {
+			return schema, nil
+		}
+		return "", fmt.Errorf("the schema %d isn't recorded", id)
+	}, make(map[int32]string))
+	if err != nil {
+		return nil
+	}
+	return records
+}
+
+// closest returns the first mock with the same records or the same body as the request, or else the one with
+// the most similar body.
+func closest(mocks []*models.Mock, req *request, records []models.KafkaRecord) *models.Mock {
+	if len(records) > 0 {
+		for _, mock := range mocks {
+			if slices.Equal(mock.Spec.KafkaRequests[0].Records, records) {
+				return mock
+			}
+		}
+	}
+	var best *models.Mock
+	bestSim := -1.0
+	for _, mock := range mocks {
+		body, err := util.DecodeBase64(mock.Spec.KafkaRequests[0].Body)
+		if err != nil {
+			continue
+		}
+		if bytes.Equal(body, req.body) {
+			return mock
+		}
+		if sim := similarity(body, req.body); sim > bestSim {
+			best, bestSim = mock, sim
+		}
+	}
+	return best
+}
+
+func similarity(recorded, body []byte) float64 {
+	k := util.AdaptiveK(len(body), 3, 8, 5)
+	return util.JaccardSimilarity(util.CreateShingles(recorded, k), util.CreateShingles(body, k))
+}
@@ -0,0 +1,256 @@
+//go:build linux
+
+package kafka
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"unicode/utf8"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations/util"
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+// recordBatchHeaderSize is the size of the header of the record batches of the v2 message format
+const recordBatchHeaderSize = 61
+
+// xerialHeader starts the snappy streams of the java clients, which are split into blocks
+var xerialHeader = []byte{0x82, 'S', 'N', 'A', 'P', 'P', 'Y', 0}
+
+var zstdDecoder, _ = zstd.NewReader(nil)
+
+// schemaSource returns the avro schema registered with an id.
+type schemaSource func(id int32) (string, error)
+
+// partitionRecords are the record batches of a topic partition in a produce request or a fetch response.
+type partitionRecords struct {
+	topic     string
+	partition int32
+	batches   []byte
+}
+
+// produceRecords returns the record batches of the partitions of the produce request.
+func produceRecords(req *request) ([]partitionRecords, error) {
+	version := req.header.APIVersion
+	d := req.decoder()
+	if version >= 3 {
+		d.string() // transactional id
+	}
+	d.int16() // acks
+	d.int32() // timeout
+	var res []partitionRecords
+	for i, n := 0, d.arrayLen(); i < n && d.err == nil; i++ {
+		var topic string
+		if version >= 13 {
+			topic = d.uuid()
+		} else {
+			topic = d.string()
+		}
+		for j, parts := 0, d.arrayLen(); j < parts && d.err == nil; j++ {
+			p := partitionRecords{topic: topic, partition: d.int32()}
+			p.batches = d.bytes()
+			res = append(res, p)
+			d.skipTaggedFields()
+		}
+		d.skipTaggedFields()
+	}
+	return res, d.err
+}
+
+// fetchRecords returns the record batches of the partitions of the fetch response, the body starts after the
+// correlation id. The brokers can cut the last batch of a partition short, it's left out.
+func fetchRecords(version int16, body []byte) ([]partitionRecords, error) {
+	d := &decoder{buf: body, compact: version >= flexibleVersions[apiFetch]}
+	d.skipTaggedFields() // the tagged fields of the response header
+	if version >= 1 {
+		d.int32() // throttle time
+	}
+	if version >= 7 {
+		d.int16() // error code
+		d.int32() // session id
+	}
+	var res []partitionRecords
+	for i, n := 0, d.arrayLen(); i < n && d.err == nil; i++ {
+		var topic string
+		if version >= 13 {
+			topic = d.uuid()
+		} else {
+			topic = d.string()
+		}
+		for j, parts := 0, d.arrayLen(); j < parts && d.err == nil; j++ {
+			p := partitionRecords{topic: topic, partition: d.int32()}
+			d.int16() // error code
+			d.int64() // high watermark
+			if version >= 4 {
+				d.int64() // last stable offset
+			}
+			if version >= 5 {
+				d.int64() // log start offset
+			}
+			if version >= 4 {
+				for k, aborted := 0, d.arrayLen(); k < aborted && d.err == nil; k++ {
+					d.int64() // producer id
+					d.int64() // first offset
+					d.skipTaggedFields()
+				}
+			}
+			if version >= 11 {
+				d.int32() // preferred read replica
+			}
+			p.batches = d.bytes()
+			res = append(res, p)
+			d.skipTaggedFields()
+		}
+		d.skipTaggedFields()
+	}
+	return res, d.err
+}
+
+// decodeRecords decodes the records of the partitions. The keys and values in the confluent wire format are
+// decoded with the avro schemas of the source, and the schemas used are added to used. The records read
+// before an error are returned with it.
+func decodeRecords(partitions []partitionRecords, schemas schemaSource, used map[int32]string) ([]models.KafkaRecord, error) {
+	var res []models.KafkaRecord
+	for _, p := range partitions {
+		records, err := recordBatches(p.batches)
+		for _, r := range records {
+			rec := models.KafkaRecord{Topic: p.topic, Partition: p.partition}
+			var keyOK, valueOK bool
+			rec.Key, _, keyOK = decodeConfluent(r.key, schemas, used)
+			rec.Value, rec.SchemaID, valueOK = decodeConfluent(r.value, schemas, used)
+			if (!keyOK && !utf8.Valid(r.key)) || (!valueOK && !utf8.Valid(r.value)) {
+				rec.Binary = true
+				if !keyOK {
+					rec.Key = util.EncodeBase64(r.key)
+				}
+				if !valueOK {
+					rec.Value = util.EncodeBase64(r.value)
+				}
+			}
+			res = append(res, rec)
+		}
+		if err != nil {
+			return res, fmt.Errorf("failed to decode the records of %s-%d: %w", p.topic, p.partition, err)
+		}
+	}
+	return res, nil
+}
+
+// decodeConfluent decodes the data in the confluent wire format into its json, and reports whether it did.
+// The other data is returned as text.
+func decodeConfluent(data []byte, schemas schemaSource, used map[int32]string) (string, int32, bool) {
+	if schemas == nil || len(data) < 5 || data[0] != 0 {
+		return string(data), 0, false
+	}
+	id := int32(binary.BigEndian.Uint32(data[1:5]))
+	text, err := schemas(id)
+	if err != nil {
+		return string(data), 0, false
+	}
+	s, err := parseAvroSchema(text)
+	if err != nil {
+		return string(data), 0, false
+	}
+	js, err := decodeAvro(s, data[5:])
+	if err != nil {
+		return string(data), 0, false
+	}
+	used[id] = text
+	return js, id, true
+}
+
+type rawRecord struct {
+	key   []byte
+	value []byte
+}
+
+// recordBatches reads the records of the record batches of the v2 message format, the control batches of
+// the transactions are left out.
+func recordBatches(data []byte) ([]rawRecord, error) {
+	var res []rawRecord
+	for len(data) >= recordBatchHeaderSize {
+		size := 12 + int(int32(binary.BigEndian.Uint32(data[8:12])))
+		if size < recordBatchHeaderSize {
+			return res, fmt.Errorf("invalid size %d of the record batch", size)
+		}
+		if size > len(data) {
+			// the partial batch at the end of a fetch response
+			break
+		}
+		batch := data[:size]
+		data = data[size:]
+
+		if magic := batch[16]; magic != 2 {
+			return res, fmt.Errorf("the records of the message format v%d aren't decoded", magic)
+		}
+		attributes := binary.BigEndian.Uint16(batch[21:23])
+		if attributes&0x20 != 0 {
+			continue
+		}
+		count := int(int32(binary.BigEndian.Uint32(batch[57:61])))
+		payload, err := decompress(attributes&0x07, batch[recordBatchHeaderSize:])
+		if err != nil {
+			return res, err
+		}
+
+		d := &decoder{buf: payload}
+		for i := 0; i < count && d.err == nil; i++ {
+			d.varint() // length
+			d.int8()   // attributes
+			d.varint() // timestamp delta
+			d.varint() // offset delta
+			var r rawRecord
+			r.key = d.varbytes()
+			r.value = d.varbytes()
+			for h, headers := 0, int(d.varint()); h < headers && d.err == nil; h++ {
+				d.varbytes() // header key
+				d.varbytes() // header value
+			}
+			if d.err == nil {
+				res = append(res, r)
+			}
+		}
+		if d.err != nil {
+			return res, fmt.Errorf("failed to read the records of the batch: %w", d.err)
+		}
+	}
+	return res, nil
+}
+
+// decompress decompresses the records of a batch with the codec of its attributes.
+func decompress(codec uint16, data []byte) ([]byte, error) {
+	switch codec {
+	case 0:
+		return data, nil
+	case 1:
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		return io.ReadAll(io.LimitReader(r, maxFrameSize))
+	case 2:
+		if !bytes.HasPrefix(data, xerialHeader) {
+			return snappy.Decode(nil, data)
+		}
+		// the header is followed by the version and the compatible version
+		d := &decoder{buf: data, off: len(xerialHeader) + 8}
+		var res []byte
+		for d.off < len(d.buf) && d.err == nil {
+			block, err := snappy.Decode(nil, d.next(int(d.int32())))
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, block...)
+		}
+		return res, d.err
+	case 4:
+		return zstdDecoder.DecodeAll(data, nil)
+	}
+	return nil, fmt.Errorf("the records compressed with the codec %d aren't decoded", codec)
+}
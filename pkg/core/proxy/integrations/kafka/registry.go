@@ -0,0 +1,79 @@
+//go:build linux
+
+package kafka
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// registryTimeout bounds the requests to the schema registry, the records are kept undecoded when it's slow
+const registryTimeout = 5 * time.Second
+
+// registry fetches the schemas of the confluent schema registry by their ids. The credentials of the
+// registry can be given in the userinfo of its url.
+type registry struct {
+	url    string
+	client *http.Client
+	// schemas caches the fetched schemas by their ids, the schemas of an id never change
+	schemas sync.Map
+	// failed holds the ids which couldn't be fetched, so that the records of a topic don't wait on the
+	// registry one after the other
+	failed sync.Map
+}
+
+// registries are shared by the conns to the brokers, so that every schema is fetched once
+var registries sync.Map
+
+func newRegistry(url string) *registry {
+	if url == "" {
+		return nil
+	}
+	url = strings.TrimSuffix(url, "/")
+	r, _ := registries.LoadOrStore(url, &registry{url: url, client: &http.Client{Timeout: registryTimeout}})
+	return r.(*registry)
+}
+
+// schema returns the avro schema registered with the id.
+func (r *registry) schema(id int32) (string, error) {
+	if s, ok := r.schemas.Load(id); ok {
+		return s.(string), nil
+	}
+	if err, ok := r.failed.Load(id); ok {
+		return "", err.(error)
+	}
+	s, err := r.fetch(id)
+	if err != nil {
+		r.failed.Store(id, err)
+		return "", err
+	}
+	r.schemas.Store(id, s)
+	return s, nil
+}
+
+func (r *registry) fetch(id int32) (string, error) {
+	resp, err := r.client.Get(fmt.Sprintf("%s/schemas/ids/%d", r.url, id))
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch the schema %d from the schema registry: %w", id, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("the schema registry answered %s for the schema %d", resp.Status, id)
+	}
+	var body struct {
+		Schema     string `json:"schema"`
+		SchemaType string `json:"schemaType"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to parse the schema %d of the schema registry: %w", id, err)
+	}
+	// the registry leaves out the type of the avro schemas
+	if body.SchemaType != "" && body.SchemaType != "AVRO" {
+		return "", fmt.Errorf("the schema %d is a %s schema, only the avro schemas are decoded", id, body.SchemaType)
+	}
+	return body.Schema, nil
+}
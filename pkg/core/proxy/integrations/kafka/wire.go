@@ -0,0 +1,403 @@
+//go:build linux
+
+package kafka
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations/util"
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+// api keys of the requests whose bodies are decoded
+const (
+	apiProduce     int16 = 0
+	apiFetch       int16 = 1
+	apiMetadata    int16 = 3
+	apiAPIVersions int16 = 18
+)
+
+const (
+	// maxAPIKey and maxAPIVersion bound the header values accepted while detecting the protocol
+	maxAPIKey     = 100
+	maxAPIVersion = 20
+	// maxFrameSize is the default socket.request.max.bytes of the brokers
+	maxFrameSize = 100 * 1024 * 1024
+	// requestHeaderSize is the size of the api key, api version, correlation id and client id length
+	requestHeaderSize = 10
+)
+
+var errShortBuffer = errors.New("kafka message is shorter than its fields")
+
+var apiNames = map[int16]string{
+	0:  "Produce",
+	1:  "Fetch",
+	2:  "ListOffsets",
+	3:  "Metadata",
+	8:  "OffsetCommit",
+	9:  "OffsetFetch",
+	10: "FindCoordinator",
+	11: "JoinGroup",
+	12: "Heartbeat",
+	13: "LeaveGroup",
+	14: "SyncGroup",
+	15: "DescribeGroups",
+	16: "ListGroups",
+	17: "SaslHandshake",
+	18: "ApiVersions",
+	19: "CreateTopics",
+	20: "DeleteTopics",
+	22: "InitProducerId",
+	24: "AddPartitionsToTxn",
+	25: "AddOffsetsToTxn",
+	26: "EndTxn",
+	28: "TxnOffsetCommit",
+	32: "DescribeConfigs",
+	36: "SaslAuthenticate",
+	37: "CreatePartitions",
+	60: "DescribeCluster",
+}
+
+// flexibleVersions are the first versions of the decoded apis which use the compact encoding and tagged fields
+var flexibleVersions = map[int16]int16{
+	apiProduce:     9,
+	apiFetch:       12,
+	apiMetadata:    9,
+	apiAPIVersions: 3,
+}
+
+func apiName(key int16) string {
+	if name, ok := apiNames[key]; ok {
+		return name
+	}
+	return fmt.Sprintf("Api%d", key)
+}
+
+// request is a kafka request read from the client.
+type request struct {
+	header models.KafkaRequestHeader
+	// body is everything after the client id, including the tagged fields of the flexible header
+	body   []byte
+	topics []string
+	frame  []byte
+	// records are the decoded records of the produce requests, schemas the avro schemas they were decoded with
+	records []models.KafkaRecord
+	schemas map[int32]string
+}
+
+func (r *request) model() models.KafkaRequest {
+	return models.KafkaRequest{
+		Header:      r.header,
+		Topics:      r.topics,
+		Records:     r.records,
+		AvroSchemas: r.schemas,
+		Body:        util.EncodeBase64(r.body),
+	}
+}
+
+// expectsResponse reports whether the broker answers the request, the produce requests with acks=0 have no response.
+func (r *request) expectsResponse() bool {
+	if r.header.APIKey != apiProduce {
+		return true
+	}
+	d := r.decoder()
+	if r.header.APIVersion >= 3 {
+		d.string() // transactional id
+	}
+	acks := d.int16()
+	return d.err != nil || acks != 0
+}
+
+// decoder returns a decoder of the request body positioned after the tagged fields of the header.
+func (r *request) decoder() *decoder {
+	from, ok := flexibleVersions[r.header.APIKey]
+	d := &decoder{buf: r.body, compact: ok && r.header.APIVersion >= from}
+	if d.compact {
+		d.skipTaggedFields()
+	}
+	return d
+}
+
+// readFrame reads a size delimited kafka message, the returned frame includes the size.
+func readFrame(r io.Reader) ([]byte, error) {
+	size := make([]byte, 4)
+	if _, err := io.ReadFull(r, size); err != nil {
+		return nil, err
+	}
+	n := binary.BigEndian.Uint32(size)
+	if n > maxFrameSize {
+		return nil, fmt.Errorf("kafka message of %d bytes is larger than the maximum of %d bytes", n, maxFrameSize)
+	}
+	frame := make([]byte, 4+n)
+	copy(frame, size)
+	if _, err := io.ReadFull(r, frame[4:]); err != nil {
+		return nil, err
+	}
+	return frame, nil
+}
+
+func parseRequest(frame []byte) (*request, error) {
+	payload := frame[4:]
+	if len(payload) < requestHeaderSize {
+		return nil, errShortBuffer
+	}
+	req := &request{frame: frame}
+	req.header.APIKey = int16(binary.BigEndian.Uint16(payload[0:2]))
+	req.header.APIName = apiName(req.header.APIKey)
+	req.header.APIVersion = int16(binary.BigEndian.Uint16(payload[2:4]))
+	req.header.CorrelationID = int32(binary.BigEndian.Uint32(payload[4:8]))
+
+	// the client id is a nullable string even in the flexible header versions
+	clientIDLen := int(int16(binary.BigEndian.Uint16(payload[8:10])))
+	bodyStart := requestHeaderSize
+	if clientIDLen > 0 {
+		if len(payload) < requestHeaderSize+clientIDLen {
+			return nil, errShortBuffer
+		}
+		req.header.ClientID = string(payload[requestHeaderSize : requestHeaderSize+clientIDLen])
+		bodyStart += clientIDLen
+	}
+	req.body = payload[bodyStart:]
+	// the topics are only informative, the requests whose topics can't be decoded are still recorded
+	req.topics, _ = topics(req)
+	return req, nil
+}
+
+// parseResponse splits the response frame into its correlation id and the rest of the response.
+func parseResponse(frame []byte) (int32, []byte, error) {
+	if len(frame) < 8 {
+		return 0, nil, errShortBuffer
+	}
+	return int32(binary.BigEndian.Uint32(frame[4:8])), frame[8:], nil
+}
+
+// responseFrame builds the response frame of the body for the given correlation id.
+func responseFrame(correlationID int32, body []byte) []byte {
+	frame := make([]byte, 8+len(body))
+	binary.BigEndian.PutUint32(frame[0:4], uint32(4+len(body)))
+	binary.BigEndian.PutUint32(frame[4:8], uint32(correlationID))
+	copy(frame[8:], body)
+	return frame
+}
+
+// topics decodes the topics of the produce, fetch and metadata requests. The topics are identified by their
+// ids instead of their names in the latest versions of produce and fetch.
+func topics(req *request) ([]string, error) {
+	version := req.header.APIVersion
+	d := req.decoder()
+	var res []string
+	switch req.header.APIKey {
+	case apiProduce:
+		if version >= 3 {
+			d.string() // transactional id
+		}
+		d.int16() // acks
+		d.int32() // timeout
+		for i, n := 0, d.arrayLen(); i < n && d.err == nil; i++ {
+			if version >= 13 {
+				res = append(res, d.uuid())
+			} else {
+				res = append(res, d.string())
+			}
+			for j, parts := 0, d.arrayLen(); j < parts && d.err == nil; j++ {
+				d.int32() // partition
+				d.bytes() // records
+				d.skipTaggedFields()
+			}
+			d.skipTaggedFields()
+		}
+	case apiFetch:
+		if version <= 14 {
+			d.int32() // replica id
+		}
+		d.int32() // max wait
+		d.int32() // min bytes
+		if version >= 3 {
+			d.int32() // max bytes
+		}
+		if version >= 4 {
+			d.int8() // isolation level
+		}
+		if version >= 7 {
+			d.int32() // session id
+			d.int32() // session epoch
+		}
+		for i, n := 0, d.arrayLen(); i < n && d.err == nil; i++ {
+			if version >= 13 {
+				res = append(res, d.uuid())
+			} else {
+				res = append(res, d.string())
+			}
+			for j, parts := 0, d.arrayLen(); j < parts && d.err == nil; j++ {
+				d.int32() // partition
+				if version >= 9 {
+					d.int32() // current leader epoch
+				}
+				d.int64() // fetch offset
+				if version >= 12 {
+					d.int32() // last fetched epoch
+				}
+				if version >= 5 {
+					d.int64() // log start offset
+				}
+				d.int32() // partition max bytes
+				if version >= 17 {
+					d.uuid() // replica directory id
+				}
+				d.skipTaggedFields()
+			}
+			d.skipTaggedFields()
+		}
+	case apiMetadata:
+		// a null array asks for all the topics
+		for i, n := 0, d.arrayLen(); i < n && d.err == nil; i++ {
+			if version >= 10 {
+				d.uuid() // topic id
+			}
+			if name := d.string(); name != "" {
+				res = append(res, name)
+			}
+			d.skipTaggedFields()
+		}
+	}
+	return res, d.err
+}
+
+// decoder reads the fields of a kafka message, the first error stops the decoding and is kept in err.
+type decoder struct {
+	buf     []byte
+	off     int
+	compact bool
+	err     error
+}
+
+func (d *decoder) next(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n < 0 || d.off+n > len(d.buf) {
+		d.err = errShortBuffer
+		return nil
+	}
+	b := d.buf[d.off : d.off+n]
+	d.off += n
+	return b
+}
+
+func (d *decoder) int8() int8 {
+	b := d.next(1)
+	if b == nil {
+		return 0
+	}
+	return int8(b[0])
+}
+
+func (d *decoder) int16() int16 {
+	b := d.next(2)
+	if b == nil {
+		return 0
+	}
+	return int16(binary.BigEndian.Uint16(b))
+}
+
+func (d *decoder) int32() int32 {
+	b := d.next(4)
+	if b == nil {
+		return 0
+	}
+	return int32(binary.BigEndian.Uint32(b))
+}
+
+func (d *decoder) int64() int64 {
+	b := d.next(8)
+	if b == nil {
+		return 0
+	}
+	return int64(binary.BigEndian.Uint64(b))
+}
+
+func (d *decoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(d.buf[d.off:])
+	if n <= 0 {
+		d.err = errShortBuffer
+		return 0
+	}
+	d.off += n
+	return v
+}
+
+// varint reads a zigzag encoded variable length integer, used by the fields of the records.
+func (d *decoder) varint() int64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(d.buf[d.off:])
+	if n <= 0 {
+		d.err = errShortBuffer
+		return 0
+	}
+	d.off += n
+	return v
+}
+
+// varbytes reads the bytes of a record field prefixed by their varint length, -1 stands for null.
+func (d *decoder) varbytes() []byte {
+	n := int(d.varint())
+	if n <= 0 {
+		return nil
+	}
+	return d.next(n)
+}
+
+// length reads the length of a string, bytes or array field, -1 stands for null.
+func (d *decoder) length(nonCompact func() int) int {
+	if d.compact {
+		return int(d.uvarint()) - 1
+	}
+	return nonCompact()
+}
+
+func (d *decoder) string() string {
+	n := d.length(func() int { return int(d.int16()) })
+	if n <= 0 {
+		return ""
+	}
+	return string(d.next(n))
+}
+
+func (d *decoder) bytes() []byte {
+	n := d.length(func() int { return int(d.int32()) })
+	if n <= 0 {
+		return nil
+	}
+	return d.next(n)
+}
+
+func (d *decoder) arrayLen() int {
+	return d.length(func() int { return int(d.int32()) })
+}
+
+func (d *decoder) uuid() string {
+	b := d.next(16)
+	if b == nil {
+		return ""
+	}
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// skipTaggedFields skips the tagged fields of the flexible versions, it is a no-op for the older versions.
+func (d *decoder) skipTaggedFields() {
+	if !d.compact {
+		return
+	}
+	for i, n := uint64(0), d.uvarint(); i < n && d.err == nil; i++ {
+		d.uvarint() // tag
+		d.next(int(d.uvarint()))
+	}
+}
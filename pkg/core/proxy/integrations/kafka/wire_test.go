@@ -0,0 +1,139 @@
+//go:build linux
+
+package kafka
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"reflect"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+// recordBatch builds a record batch of the v2 message format of the key and value pairs, compressed with the codec.
+func recordBatch(t *testing.T, codec uint16, pairs ...[2]string) []byte {
+	t.Helper()
+	var records []byte
+	for i, kv := range pairs {
+		var rec []byte
+		rec = append(rec, 0)                     // attributes
+		rec = binary.AppendVarint(rec, 0)        // timestamp delta
+		rec = binary.AppendVarint(rec, int64(i)) // offset delta
+		rec = binary.AppendVarint(rec, int64(len(kv[0])))
+		rec = append(rec, kv[0]...)
+		rec = binary.AppendVarint(rec, int64(len(kv[1])))
+		rec = append(rec, kv[1]...)
+		rec = binary.AppendVarint(rec, 0) // headers
+		records = binary.AppendVarint(records, int64(len(rec)))
+		records = append(records, rec...)
+	}
+	switch codec {
+	case 1:
+		var b bytes.Buffer
+		w := gzip.NewWriter(&b)
+		if _, err := w.Write(records); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		records = b.Bytes()
+	case 4:
+		enc, err := zstd.NewWriter(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		records = enc.EncodeAll(records, nil)
+	}
+	batch := make([]byte, recordBatchHeaderSize, recordBatchHeaderSize+len(records))
+	binary.BigEndian.PutUint32(batch[8:12], uint32(recordBatchHeaderSize-12+len(records)))
+	batch[16] = 2 // magic
+	binary.BigEndian.PutUint16(batch[21:23], codec)
+	binary.BigEndian.PutUint32(batch[57:61], uint32(len(pairs)))
+	return append(batch, records...)
+}
+
+// produceFrame builds a produce request of the version 3 to the partition 0 of the topic.
+func produceFrame(correlationID int32, clientID, topic string, records []byte) []byte {
+	b := make([]byte, 4)
+	b = binary.BigEndian.AppendUint16(b, uint16(apiProduce))
+	b = binary.BigEndian.AppendUint16(b, 3)
+	b = binary.BigEndian.AppendUint32(b, uint32(correlationID))
+	b = binary.BigEndian.AppendUint16(b, uint16(len(clientID)))
+	b = append(b, clientID...)
+	b = binary.BigEndian.AppendUint16(b, 0xffff) // null transactional id
+	b = binary.BigEndian.AppendUint16(b, 1)      // acks
+	b = binary.BigEndian.AppendUint32(b, 30000)  // timeout
+	b = binary.BigEndian.AppendUint32(b, 1)      // topics
+	b = binary.BigEndian.AppendUint16(b, uint16(len(topic)))
+	b = append(b, topic...)
+	b = binary.BigEndian.AppendUint32(b, 1) // partitions
+	b = binary.BigEndian.AppendUint32(b, 0) // partition
+	b = binary.BigEndian.AppendUint32(b, uint32(len(records)))
+	b = append(b, records...)
+	binary.BigEndian.PutUint32(b[0:4], uint32(len(b)-4))
+	return b
+}
+
+func TestProduceRequestRoundTrip(t *testing.T) {
+	for _, codec := range []uint16{0, 1, 4} {
+		batch := recordBatch(t, codec, [2]string{"k1", "v1"}, [2]string{"k2", `{"id":2}`})
+		frame, err := readFrame(bytes.NewReader(produceFrame(7, "client", "orders", batch)))
+		if err != nil {
+			t.Fatalf("readFrame() failed: %v", err)
+		}
+		req, err := parseRequest(frame)
+		if err != nil {
+			t.Fatalf("parseRequest() failed: %v", err)
+		}
+		wantHeader := models.KafkaRequestHeader{APIKey: apiProduce, APIName: "Produce", APIVersion: 3, CorrelationID: 7, ClientID: "client"}
+		if req.header != wantHeader {
+			t.Errorf("parseRequest() header = %+v, want %+v", req.header, wantHeader)
+		}
+		if !reflect.DeepEqual(req.topics, []string{"orders"}) {
+			t.Errorf("parseRequest() topics = %v, want [orders]", req.topics)
+		}
+		if !req.expectsResponse() {
+			t.Error("expectsResponse() = false for a produce request with acks=1")
+		}
+
+		partitions, err := produceRecords(req)
+		if err != nil {
+			t.Fatalf("produceRecords() failed: %v", err)
+		}
+		records, err := decodeRecords(partitions, nil, map[int32]string{})
+		if err != nil {
+			t.Fatalf("decodeRecords() of the codec %d failed: %v", codec, err)
+		}
+		want := []models.KafkaRecord{
+			{Topic: "orders", Key: "k1", Value: "v1"},
+			{Topic: "orders", Key: "k2", Value: `{"id":2}`},
+		}
+		if !reflect.DeepEqual(records, want) {
+			t.Errorf("decodeRecords() of the codec %d = %+v, want %+v", codec, records, want)
+		}
+	}
+}
+
+func TestResponseRoundTrip(t *testing.T) {
+	body := []byte{0, 0, 0, 0, 1, 2, 3}
+	frame, err := readFrame(bytes.NewReader(responseFrame(42, body)))
+	if err != nil {
+		t.Fatalf("readFrame() failed: %v", err)
+	}
+	id, got, err := parseResponse(frame)
+	if err != nil || id != 42 || !bytes.Equal(got, body) {
+		t.Errorf("parseResponse() = %d, % x, %v, want 42, % x", id, got, err, body)
+	}
+}
+
+func TestParseRequestShortFrame(t *testing.T) {
+	frame := produceFrame(1, "client", "orders", nil)
+	if _, err := parseRequest(frame[:4+requestHeaderSize+2]); err == nil {
+		t.Error("parseRequest() of a frame cut in the client id didn't fail")
+	}
+}
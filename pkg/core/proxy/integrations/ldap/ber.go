@@ -0,0 +1,210 @@
+//go:build linux
+
+package ldap
+
+import (
+	"bufio"
+	"errors"
+	"io"
+)
+
+// BER classes of the tags
+const (
+	classUniversal   = 0
+	classApplication = 1
+	classContext     = 2
+)
+
+// universal tags used by ldap
+const (
+	tagBoolean     = 1
+	tagInteger     = 2
+	tagOctetString = 4
+	tagEnumerated  = 10
+	tagSequence    = 16
+)
+
+// maxMessageSize bounds the size of the ldap messages read from the connections
+const maxMessageSize = 64 * 1024 * 1024
+
+var errMalformed = errors.New("malformed BER element")
+
+// element is a BER encoded type-length-value. The ldap messages only use the definite lengths, but the
+// lengths don't have to be in their shortest form unlike DER.
+type element struct {
+	class       int
+	constructed bool
+	tag         int
+	value       []byte
+}
+
+// parseElement parses the first element of the buffer and returns it with the rest of the buffer. The value
+// of the element shares the memory of the buffer.
+func parseElement(buf []byte) (element, []byte, error) {
+	var e element
+	if len(buf) < 2 {
+		return e, nil, errMalformed
+	}
+	e.class = int(buf[0] >> 6)
+	e.constructed = buf[0]&0x20 != 0
+	e.tag = int(buf[0] & 0x1f)
+	i := 1
+	if e.tag == 0x1f {
+		// high tag numbers are encoded in base 128
+		e.tag = 0
+		for {
+			if i >= len(buf) || i > 4 {
+				return e, nil, errMalformed
+			}
+			b := buf[i]
+			i++
+			e.tag = e.tag<<7 | int(b&0x7f)
+			if b&0x80 == 0 {
+				break
+			}
+		}
+	}
+	if i >= len(buf) {
+		return e, nil, errMalformed
+	}
+	length, n, err := parseLength(buf[i:])
+	if err != nil {
+		return e, nil, err
+	}
+	i += n
+	if length > len(buf)-i {
+		return e, nil, errMalformed
+	}
+	e.value = buf[i : i+length]
+	return e, buf[i+length:], nil
+}
+
+// parseLength parses a definite length and returns it with the number of bytes it takes.
+func parseLength(buf []byte) (int, int, error) {
+	if len(buf) == 0 {
+		return 0, 0, errMalformed
+	}
+	if buf[0] < 0x80 {
+		return int(buf[0]), 1, nil
+	}
+	n := int(buf[0] & 0x7f)
+	if n == 0 || n > 4 || len(buf) < 1+n {
+		// the indefinite length (0x80) isn't allowed in ldap
+		return 0, 0, errMalformed
+	}
+	length := 0
+	for _, b := range buf[1 : 1+n] {
+		length = length<<8 | int(b)
+	}
+	if length < 0 || length > maxMessageSize {
+		return 0, 0, errMalformed
+	}
+	return length, 1 + n, nil
+}
+
+// children parses the elements of a constructed element.
+func (e element) children() ([]element, error) {
+	var res []element
+	rest := e.value
+	for len(rest) > 0 {
+		child, r, err := parseElement(rest)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, child)
+		rest = r
+	}
+	return res, nil
+}
+
+func (e element) is(class, tag int) bool {
+	return e.class == class && e.tag == tag
+}
+
+// int parses the value of an INTEGER or ENUMERATED element as a two's complement number.
+func (e element) int() (int64, error) {
+	if len(e.value) == 0 || len(e.value) > 8 {
+		return 0, errMalformed
+	}
+	v := int64(int8(e.value[0]))
+	for _, b := range e.value[1:] {
+		v = v<<8 | int64(b)
+	}
+	return v, nil
+}
+
+// readElement reads a whole BER element from the connection and returns its encoding.
+func readElement(r *bufio.Reader) ([]byte, error) {
+	first, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	raw := []byte{first}
+	if first&0x1f == 0x1f {
+		for {
+			b, err := r.ReadByte()
+			if err != nil {
+				return nil, err
+			}
+			raw = append(raw, b)
+			if b&0x80 == 0 {
+				break
+			}
+			if len(raw) > 5 {
+				return nil, errMalformed
+			}
+		}
+	}
+	lengthStart := len(raw)
+	b, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	raw = append(raw, b)
+	if b >= 0x80 {
+		n := int(b & 0x7f)
+		if n == 0 || n > 4 {
+			return nil, errMalformed
+		}
+		lengthBytes := make([]byte, n)
+		if _, err := io.ReadFull(r, lengthBytes); err != nil {
+			return nil, err
+		}
+		raw = append(raw, lengthBytes...)
+	}
+	header := len(raw)
+	length, _, err := parseLength(raw[lengthStart:])
+	if err != nil {
+		return nil, err
+	}
+	raw = append(raw, make([]byte, length)...)
+	if _, err := io.ReadFull(r, raw[header:]); err != nil {
+		return nil, err
+	}
+	return raw, nil
+}
+
+// encodeLength encodes a definite length in its shortest form.
+func encodeLength(n int) []byte {
+	if n < 0x80 {
+		return []byte{byte(n)}
+	}
+	var b []byte
+	for ; n > 0; n >>= 8 {
+		b = append([]byte{byte(n)}, b...)
+	}
+	return append([]byte{0x80 | byte(len(b))}, b...)
+}
+
+// encodeInteger encodes an INTEGER element in its shortest two's complement form.
+func encodeInteger(v int64) []byte {
+	var b []byte
+	for {
+		b = append([]byte{byte(v)}, b...)
+		v >>= 8
+		if (v == 0 && b[0]&0x80 == 0) || (v == -1 && b[0]&0x80 != 0) {
+			break
+		}
+	}
+	return append([]byte{tagInteger, byte(len(b))}, b...)
+}
@@ -0,0 +1,102 @@
+//go:build linux
+
+package ldap
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations/util"
+	pUtil "go.keploy.io/server/v2/pkg/core/proxy/util"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+// decodeLDAP answers the requests of the client from the mocks, the recorded responses are sent with the
+// message id of the request they answer.
+func decodeLDAP(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientConn net.Conn, _ *integrations.ConditionalDstCfg, mockDb integrations.MockMemDb, _ models.OutgoingOptions) error {
+	logger.Debug("Into the ldap parser in test mode")
+	errCh := make(chan error, 1)
+
+	go func() {
+		defer pUtil.Recover(logger, clientConn, nil)
+		defer close(errCh)
+		client := bufio.NewReader(io.MultiReader(bytes.NewReader(reqBuf), clientConn))
+		for {
+			raw, err := readElement(client)
+			if err != nil {
+				if err != io.EOF {
+					utils.LogError(logger, err, "failed to read the ldap request from the client")
+				}
+				errCh <- err
+				return
+			}
+			req, err := parseMessage(raw)
+			if err != nil {
+				utils.LogError(logger, err, "failed to parse the ldap request")
+				errCh <- err
+				return
+			}
+			if req.op == opUnbindRequest {
+				errCh <- io.EOF
+				return
+			}
+			if !req.hasResponse() {
+				continue
+			}
+
+			mock, err := match(ctx, req, mockDb)
+			if err != nil {
+				utils.LogError(logger, err, "error while matching ldap mocks")
+				errCh <- err
+				return
+			}
+			if mock == nil {
+				err := fmt.Errorf("no ldap mock found for the %s", opName(req.op))
+				utils.LogError(logger, err, "failed to mock the ldap request", zap.String("dn", req.dn), zap.String("filter", req.filter))
+				errCh <- err
+				return
+			}
+
+			for _, resp := range mock.Spec.LDAPResponses {
+				raw, err := util.DecodeBase64(resp.Raw)
+				if err != nil {
+					utils.LogError(logger, err, "failed to decode the base64 ldap response")
+					errCh <- err
+					return
+				}
+				raw, err = withMessageID(raw, req.id)
+				if err != nil {
+					utils.LogError(logger, err, "failed to set the message id of the ldap response")
+					errCh <- err
+					return
+				}
+				_, err = clientConn.Write(raw)
+				if err != nil {
+					if ctx.Err() != nil {
+						return
+					}
+					utils.LogError(logger, err, "failed to write the ldap response to the client application")
+					errCh <- err
+					return
+				}
+			}
+		}
+	}()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-errCh:
+		if err == io.EOF {
+			return nil
+		}
+		return err
+	}
+}
@@ -0,0 +1,183 @@
+//go:build linux
+
+package ldap
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	pUtil "go.keploy.io/server/v2/pkg/core/proxy/util"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+// pendingRequest is a request forwarded to the server along with the responses received for it so far.
+type pendingRequest struct {
+	req              *message
+	responses        []*message
+	reqTimestampMock time.Time
+}
+
+// encodeLDAP forwards the messages between the client and the server and records every request with its
+// responses as a mock. The client can have several operations in progress on a connection, so the responses
+// are paired with their requests by the message id. Once the connection is upgraded with StartTLS, the rest
+// of the traffic is forwarded without being recorded.
+func encodeLDAP(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientConn, destConn net.Conn, mocks chan<- *models.Mock, _ models.OutgoingOptions) error {
+	g, ok := ctx.Value(models.ErrGroupKey).(*errgroup.Group)
+	if !ok {
+		return errors.New("failed to get the error group from the context")
+	}
+	connID, _ := ctx.Value(models.ClientConnectionIDKey).(string)
+
+	var (
+		mu      sync.Mutex
+		pending = make(map[int64]*pendingRequest)
+		// startTLSID is the message id of the StartTLS request, the message ids start from 1
+		startTLSID atomic.Int64
+	)
+	errCh := make(chan error, 2)
+
+	// Forward the requests from the client to the destination server
+	g.Go(func() error {
+		defer pUtil.Recover(logger, clientConn, destConn)
+		client := bufio.NewReader(io.MultiReader(bytes.NewReader(reqBuf), clientConn))
+		for {
+			raw, err := readElement(client)
+			if err != nil {
+				if err != io.EOF {
+					utils.LogError(logger, err, "failed to read the ldap request from the client")
+				}
+				errCh <- err
+				return nil
+			}
+			req, err := parseMessage(raw)
+			if err != nil {
+				utils.LogError(logger, err, "failed to parse the ldap request")
+				errCh <- err
+				return nil
+			}
+			logger.Debug("ldap request", zap.String("operation", opName(req.op)), zap.Int64("messageID", req.id), zap.String("dn", req.dn), zap.String("filter", req.filter))
+
+			isStartTLS := req.op == opExtendedRequest && req.name == startTLSOID
+			if isStartTLS {
+				logger.Warn("the ldap connection is upgraded with StartTLS, the rest of the connection isn't recorded")
+				startTLSID.Store(req.id)
+			} else if req.hasResponse() {
+				// register the request before forwarding it, so that the response can't arrive first
+				mu.Lock()
+				pending[req.id] = &pendingRequest{req: req, reqTimestampMock: time.Now()}
+				mu.Unlock()
+			}
+
+			_, err = destConn.Write(raw)
+			if err != nil {
+				utils.LogError(logger, err, "failed to write the ldap request to the destination server")
+				errCh <- err
+				return nil
+			}
+
+			if isStartTLS {
+				_, err := io.Copy(destConn, client)
+				errCh <- err
+				return nil
+			}
+		}
+	})
+
+	// Forward the responses from the destination server to the client
+	g.Go(func() error {
+		defer pUtil.Recover(logger, clientConn, destConn)
+		server := bufio.NewReader(destConn)
+		for {
+			raw, err := readElement(server)
+			if err != nil {
+				if err != io.EOF {
+					utils.LogError(logger, err, "failed to read the ldap response from the destination server")
+				}
+				errCh <- err
+				return nil
+			}
+
+			_, err = clientConn.Write(raw)
+			if err != nil {
+				utils.LogError(logger, err, "failed to write the ldap response to the client")
+				errCh <- err
+				return nil
+			}
+			resTimestampMock := time.Now()
+
+			resp, err := parseMessage(raw)
+			if err != nil {
+				utils.LogError(logger, err, "failed to parse the ldap response")
+				errCh <- err
+				return nil
+			}
+			if id := startTLSID.Load(); id != 0 && resp.id == id {
+				_, err := io.Copy(clientConn, server)
+				errCh <- err
+				return nil
+			}
+
+			mu.Lock()
+			p, ok := pending[resp.id]
+			if ok {
+				p.responses = append(p.responses, resp)
+				if resp.isFinal() {
+					delete(pending, resp.id)
+				}
+			}
+			mu.Unlock()
+			if !ok {
+				// e.g. the notice of disconnection, which has the message id 0
+				logger.Debug("no pending ldap request for the response", zap.String("operation", opName(resp.op)), zap.Int64("messageID", resp.id))
+				continue
+			}
+			if resp.isFinal() {
+				saveMock(p.req, p.responses, p.reqTimestampMock, resTimestampMock, connID, mocks)
+			}
+		}
+	})
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-errCh:
+		if err == io.EOF {
+			return nil
+		}
+		return err
+	}
+}
+
+func saveMock(req *message, responses []*message, reqTimestampMock, resTimestampMock time.Time, connID string, mocks chan<- *models.Mock) {
+	resps := make([]models.LDAPMessage, 0, len(responses))
+	for _, resp := range responses {
+		resps = append(resps, resp.model())
+	}
+
+	mocks <- &models.Mock{
+		Version: models.GetVersion(),
+		Name:    "mocks",
+		Kind:    models.LDAP,
+		Spec: models.MockSpec{
+			Metadata: map[string]string{
+				"operation": opName(req.op),
+			},
+			LDAPRequests:     []models.LDAPMessage{req.model()},
+			LDAPResponses:    resps,
+			ReqTimestampMock: reqTimestampMock,
+			ResTimestampMock: resTimestampMock,
+		},
+		ConnectionID: connID,
+	}
+}
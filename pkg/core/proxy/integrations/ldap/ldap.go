@@ -0,0 +1,92 @@
+//go:build linux
+
+// Package ldap provides the integration for the ldap protocol.
+package ldap
+
+import (
+	"context"
+	"net"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	"go.keploy.io/server/v2/pkg/core/proxy/util"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+func init() {
+	integrations.Register("ldap", NewLDAP)
+}
+
+type LDAP struct {
+	logger *zap.Logger
+}
+
+func NewLDAP(logger *zap.Logger) integrations.Integrations {
+	return &LDAP{
+		logger: logger,
+	}
+}
+
+// MatchType checks whether the buffer starts with an LDAPMessage carrying a request, the client speaks first.
+func (l *LDAP) MatchType(_ context.Context, buf []byte) integrations.MatchResult {
+	const minMessageSize = 7
+	if len(buf) < minMessageSize {
+		return integrations.MatchResult{NeedBytes: minMessageSize}
+	}
+	if buf[0] != 0x30 {
+		return integrations.MatchResult{}
+	}
+	length, n, err := parseLength(buf[1:])
+	if err != nil {
+		return integrations.MatchResult{}
+	}
+	total := 1 + n + length
+	if len(buf) < total {
+		return integrations.MatchResult{Confidence: integrations.ConfidenceLow, NeedBytes: total}
+	}
+	m, err := parseMessage(buf[:total])
+	if err != nil {
+		return integrations.MatchResult{}
+	}
+	switch m.op {
+	case opBindRequest, opSearchRequest, opModifyRequest, opAddRequest, opDelRequest, opModifyDNRequest,
+		opCompareRequest, opExtendedRequest:
+		return integrations.MatchResult{Confidence: integrations.ConfidenceHigh}
+	}
+	return integrations.MatchResult{}
+}
+
+func (l *LDAP) RecordOutgoing(ctx context.Context, src net.Conn, dst net.Conn, mocks chan<- *models.Mock, opts models.OutgoingOptions) error {
+	logger := l.logger.With(zap.Any("Client IP Address", src.RemoteAddr().String()), zap.Any("Client ConnectionID", ctx.Value(models.ClientConnectionIDKey).(string)), zap.Any("Destination ConnectionID", ctx.Value(models.DestConnectionIDKey).(string)))
+
+	reqBuf, err := util.ReadInitialBuf(ctx, logger, src)
+	if err != nil {
+		utils.LogError(logger, err, "failed to read the initial ldap message")
+		return err
+	}
+
+	err = encodeLDAP(ctx, logger, reqBuf, src, dst, mocks, opts)
+	if err != nil {
+		utils.LogError(logger, err, "failed to encode the ldap message into the yaml")
+		return err
+	}
+	return nil
+}
+
+func (l *LDAP) MockOutgoing(ctx context.Context, src net.Conn, dstCfg *integrations.ConditionalDstCfg, mockDb integrations.MockMemDb, opts models.OutgoingOptions) error {
+	logger := l.logger.With(zap.Any("Client IP Address", src.RemoteAddr().String()), zap.Any("Client ConnectionID", ctx.Value(models.ClientConnectionIDKey).(string)), zap.Any("Destination ConnectionID", ctx.Value(models.DestConnectionIDKey).(string)))
+
+	reqBuf, err := util.ReadInitialBuf(ctx, logger, src)
+	if err != nil {
+		utils.LogError(logger, err, "failed to read the initial ldap message")
+		return err
+	}
+
+	err = decodeLDAP(ctx, logger, reqBuf, src, dstCfg, mockDb, opts)
+	if err != nil {
+		utils.LogError(logger, err, "failed to decode the ldap message")
+		return err
+	}
+	return nil
+}
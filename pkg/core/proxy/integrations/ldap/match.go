@@ -0,0 +1,96 @@
+//go:build linux
+
+package ldap
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations/util"
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+// match finds the mock of the request among the mocks of the same operation on the same entry. The searches
+// also match on their scope and filter, their requested attributes and controls only break the ties. The
+// mocks recorded during the current test case are preferred, and the matched mock is moved behind the
+// others so that the repeated requests get the recorded responses in order.
+func match(ctx context.Context, req *message, mockDb integrations.MockMemDb) (*models.Mock, error) {
+	for {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		mocks, err := mockDb.GetUnFilteredMocksByKey(models.LDAP, "")
+		if err != nil {
+			return nil, fmt.Errorf("error while getting unfiltered mocks %v", err)
+		}
+
+		var filteredMocks, unfilteredMocks []*models.Mock
+		for _, mock := range mocks {
+			if mock.Kind != models.LDAP || len(mock.Spec.LDAPRequests) == 0 || !sameOperation(mock.Spec.LDAPRequests[0], req) {
+				continue
+			}
+			if mock.TestModeInfo.IsFiltered {
+				filteredMocks = append(filteredMocks, mock)
+			} else {
+				unfilteredMocks = append(unfilteredMocks, mock)
+			}
+		}
+
+		mock := closest(filteredMocks, req)
+		if mock == nil {
+			mock = closest(unfilteredMocks, req)
+		}
+		if mock == nil {
+			return nil, nil
+		}
+
+		originalMock := *mock
+		mock.TestModeInfo.IsFiltered = false
+		mock.TestModeInfo.SortOrder = math.MaxInt64
+		if !mockDb.UpdateUnFilteredMock(&originalMock, mock) {
+			// the mock was used by another request in the meantime
+			continue
+		}
+		return mock, nil
+	}
+}
+
+func sameOperation(recorded models.LDAPMessage, req *message) bool {
+	return recorded.Operation == opName(req.op) &&
+		normalizeDN(recorded.DN) == normalizeDN(req.dn) &&
+		recorded.Scope == req.scope &&
+		recorded.Filter == req.filter &&
+		recorded.Name == req.name
+}
+
+// closest returns the first mock with the same message as the request apart from the message id, or else
+// the one with the most similar message.
+func closest(mocks []*models.Mock, req *message) *models.Mock {
+	// re-encode the request as the recorded messages are, the clients don't always use the shortest lengths
+	want, err := withMessageID(req.raw, req.id)
+	if err != nil {
+		want = req.raw
+	}
+	var best *models.Mock
+	bestSim := -1.0
+	for _, mock := range mocks {
+		raw, err := util.DecodeBase64(mock.Spec.LDAPRequests[0].Raw)
+		if err != nil {
+			continue
+		}
+		if raw, err = withMessageID(raw, req.id); err != nil {
+			continue
+		}
+		if bytes.Equal(raw, want) {
+			return mock
+		}
+		k := util.AdaptiveK(len(want), 3, 8, 5)
+		if sim := util.JaccardSimilarity(util.CreateShingles(raw, k), util.CreateShingles(want, k)); sim > bestSim {
+			best, bestSim = mock, sim
+		}
+	}
+	return best
+}
@@ -0,0 +1,423 @@
+//go:build linux
+
+package ldap
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations/util"
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+// application tags of the protocol operations
+const (
+	opBindRequest           = 0
+	opBindResponse          = 1
+	opUnbindRequest         = 2
+	opSearchRequest         = 3
+	opSearchResultEntry     = 4
+	opSearchResultDone      = 5
+	opModifyRequest         = 6
+	opModifyResponse        = 7
+	opAddRequest            = 8
+	opAddResponse           = 9
+	opDelRequest            = 10
+	opDelResponse           = 11
+	opModifyDNRequest       = 12
+	opModifyDNResponse      = 13
+	opCompareRequest        = 14
+	opCompareResponse       = 15
+	opAbandonRequest        = 16
+	opSearchResultReference = 19
+	opExtendedRequest       = 23
+	opExtendedResponse      = 24
+	opIntermediateResponse  = 25
+)
+
+// startTLSOID is the name of the extended operation upgrading the connection to tls
+const startTLSOID = "1.3.6.1.4.1.1466.20037"
+
+var opNames = map[int]string{
+	opBindRequest:           "BindRequest",
+	opBindResponse:          "BindResponse",
+	opUnbindRequest:         "UnbindRequest",
+	opSearchRequest:         "SearchRequest",
+	opSearchResultEntry:     "SearchResultEntry",
+	opSearchResultDone:      "SearchResultDone",
+	opModifyRequest:         "ModifyRequest",
+	opModifyResponse:        "ModifyResponse",
+	opAddRequest:            "AddRequest",
+	opAddResponse:           "AddResponse",
+	opDelRequest:            "DelRequest",
+	opDelResponse:           "DelResponse",
+	opModifyDNRequest:       "ModifyDNRequest",
+	opModifyDNResponse:      "ModifyDNResponse",
+	opCompareRequest:        "CompareRequest",
+	opCompareResponse:       "CompareResponse",
+	opAbandonRequest:        "AbandonRequest",
+	opSearchResultReference: "SearchResultReference",
+	opExtendedRequest:       "ExtendedRequest",
+	opExtendedResponse:      "ExtendedResponse",
+	opIntermediateResponse:  "IntermediateResponse",
+}
+
+var scopes = []string{"baseObject", "singleLevel", "wholeSubtree", "subordinateSubtree"}
+
+// assertionOps are the operators of the attribute value assertion filters by their tags
+var assertionOps = map[int]string{3: "=", 5: ">=", 6: "<=", 8: "~="}
+
+var modifyOperations = []string{"add", "delete", "replace", "increment"}
+
+func opName(op int) string {
+	if name, ok := opNames[op]; ok {
+		return name
+	}
+	return fmt.Sprintf("Operation%d", op)
+}
+
+// message is an ldap message along with the fields decoded from its protocol operation.
+type message struct {
+	id  int64
+	op  int
+	raw []byte
+
+	dn         string
+	scope      string
+	filter     string
+	attributes []string
+	name       string
+	resultCode int
+}
+
+func (m *message) model() models.LDAPMessage {
+	return models.LDAPMessage{
+		MessageID:  m.id,
+		Operation:  opName(m.op),
+		DN:         m.dn,
+		Scope:      m.scope,
+		Filter:     m.filter,
+		Attributes: m.attributes,
+		Name:       m.name,
+		ResultCode: m.resultCode,
+		Raw:        util.EncodeBase64(m.raw),
+	}
+}
+
+// hasResponse reports whether the server answers the request.
+func (m *message) hasResponse() bool {
+	return m.op != opUnbindRequest && m.op != opAbandonRequest
+}
+
+// isFinal reports whether the response ends the answer to a request, the search results come in several
+// messages before the SearchResultDone.
+func (m *message) isFinal() bool {
+	switch m.op {
+	case opSearchResultEntry, opSearchResultReference, opIntermediateResponse:
+		return false
+	}
+	return true
+}
+
+// parseMessage parses an LDAPMessage, the simple bind passwords are masked in the raw message so that they
+// aren't written to the mocks.
+func parseMessage(data []byte) (*message, error) {
+	raw := make([]byte, len(data))
+	copy(raw, data)
+
+	envelope, _, err := parseElement(raw)
+	if err != nil {
+		return nil, err
+	}
+	if !envelope.is(classUniversal, tagSequence) {
+		return nil, errMalformed
+	}
+	id, rest, err := parseElement(envelope.value)
+	if err != nil {
+		return nil, err
+	}
+	op, _, err := parseElement(rest)
+	if err != nil {
+		return nil, err
+	}
+	if !id.is(classUniversal, tagInteger) || op.class != classApplication {
+		return nil, errMalformed
+	}
+
+	m := &message{op: op.tag, raw: raw}
+	if m.id, err = id.int(); err != nil {
+		return nil, err
+	}
+	// the decoded fields are only used for matching, a message which can't be decoded is still forwarded
+	_ = m.decode(op)
+	return m, nil
+}
+
+func (m *message) decode(op element) error {
+	if !op.constructed {
+		// the DelRequest is the entry name and the UnbindRequest is null
+		if m.op == opDelRequest {
+			m.dn = string(op.value)
+		}
+		return nil
+	}
+	fields, err := op.children()
+	if err != nil {
+		return err
+	}
+	if len(fields) == 0 {
+		return errMalformed
+	}
+
+	switch m.op {
+	case opBindRequest:
+		if len(fields) < 3 {
+			return errMalformed
+		}
+		m.dn = string(fields[1].value)
+		auth := fields[2]
+		switch {
+		case auth.is(classContext, 0):
+			m.name = "simple"
+			for i := range auth.value {
+				auth.value[i] = '*'
+			}
+		case auth.is(classContext, 3):
+			if sasl, err := auth.children(); err == nil && len(sasl) > 0 {
+				m.name = string(sasl[0].value)
+			}
+		}
+	case opSearchRequest:
+		if len(fields) < 8 {
+			return errMalformed
+		}
+		m.dn = string(fields[0].value)
+		if scope, err := fields[1].int(); err == nil && scope >= 0 && int(scope) < len(scopes) {
+			m.scope = scopes[scope]
+		}
+		if m.filter, err = filterString(fields[6]); err != nil {
+			return err
+		}
+		attrs, err := fields[7].children()
+		if err != nil {
+			return err
+		}
+		for _, a := range attrs {
+			m.attributes = append(m.attributes, string(a.value))
+		}
+	case opModifyRequest:
+		if len(fields) < 2 {
+			return errMalformed
+		}
+		m.dn = string(fields[0].value)
+		changes, err := fields[1].children()
+		if err != nil {
+			return err
+		}
+		for _, change := range changes {
+			c, err := change.children()
+			if err != nil || len(c) < 2 {
+				return errMalformed
+			}
+			operation, err := c[0].int()
+			if err != nil || operation < 0 || int(operation) >= len(modifyOperations) {
+				return errMalformed
+			}
+			mod, err := c[1].children()
+			if err != nil || len(mod) == 0 {
+				return errMalformed
+			}
+			m.attributes = append(m.attributes, modifyOperations[operation]+":"+strings.ToLower(string(mod[0].value)))
+		}
+	case opAddRequest:
+		if len(fields) < 2 {
+			return errMalformed
+		}
+		m.dn = string(fields[0].value)
+		attrs, err := fields[1].children()
+		if err != nil {
+			return err
+		}
+		for _, attr := range attrs {
+			a, err := attr.children()
+			if err != nil || len(a) == 0 {
+				return errMalformed
+			}
+			m.attributes = append(m.attributes, strings.ToLower(string(a[0].value)))
+		}
+	case opModifyDNRequest:
+		if len(fields) < 2 {
+			return errMalformed
+		}
+		m.dn = string(fields[0].value)
+		m.name = string(fields[1].value)
+	case opCompareRequest:
+		if len(fields) < 2 {
+			return errMalformed
+		}
+		m.dn = string(fields[0].value)
+		ava, err := fields[1].children()
+		if err != nil || len(ava) < 2 {
+			return errMalformed
+		}
+		m.filter = "(" + strings.ToLower(string(ava[0].value)) + "=" + escapeValue(ava[1].value) + ")"
+	case opExtendedRequest:
+		m.name = string(fields[0].value)
+	case opSearchResultEntry:
+		m.dn = string(fields[0].value)
+	case opBindResponse, opSearchResultDone, opModifyResponse, opAddResponse, opDelResponse,
+		opModifyDNResponse, opCompareResponse, opExtendedResponse:
+		code, err := fields[0].int()
+		if err != nil {
+			return err
+		}
+		m.resultCode = int(code)
+		if len(fields) > 1 {
+			m.dn = string(fields[1].value) // matched DN
+		}
+	}
+	return nil
+}
+
+// filterString renders the search filter in its string form. The attribute names are lower cased and the
+// terms of the and/or filters are sorted, so that the equivalent filters have the same string.
+func filterString(f element) (string, error) {
+	if f.class != classContext {
+		return "", errMalformed
+	}
+	switch f.tag {
+	case 0, 1:
+		terms, err := f.children()
+		if err != nil {
+			return "", err
+		}
+		rendered := make([]string, 0, len(terms))
+		for _, t := range terms {
+			s, err := filterString(t)
+			if err != nil {
+				return "", err
+			}
+			rendered = append(rendered, s)
+		}
+		sort.Strings(rendered)
+		op := "&"
+		if f.tag == 1 {
+			op = "|"
+		}
+		return "(" + op + strings.Join(rendered, "") + ")", nil
+	case 2:
+		inner, _, err := parseElement(f.value)
+		if err != nil {
+			return "", err
+		}
+		s, err := filterString(inner)
+		if err != nil {
+			return "", err
+		}
+		return "(!" + s + ")", nil
+	case 3, 5, 6, 8:
+		ava, err := f.children()
+		if err != nil || len(ava) < 2 {
+			return "", errMalformed
+		}
+		return "(" + strings.ToLower(string(ava[0].value)) + assertionOps[f.tag] + escapeValue(ava[1].value) + ")", nil
+	case 4:
+		sub, err := f.children()
+		if err != nil || len(sub) < 2 {
+			return "", errMalformed
+		}
+		parts, err := sub[1].children()
+		if err != nil {
+			return "", err
+		}
+		var initial, final string
+		var middle []string
+		for _, p := range parts {
+			switch p.tag {
+			case 0:
+				initial = escapeValue(p.value)
+			case 1:
+				middle = append(middle, escapeValue(p.value))
+			case 2:
+				final = escapeValue(p.value)
+			}
+		}
+		value := initial + "*"
+		for _, a := range middle {
+			value += a + "*"
+		}
+		return "(" + strings.ToLower(string(sub[0].value)) + "=" + value + final + ")", nil
+	case 7:
+		return "(" + strings.ToLower(string(f.value)) + "=*)", nil
+	case 9:
+		parts, err := f.children()
+		if err != nil {
+			return "", err
+		}
+		var rule, attr, value string
+		dnAttributes := false
+		for _, p := range parts {
+			switch p.tag {
+			case 1:
+				rule = string(p.value)
+			case 2:
+				attr = strings.ToLower(string(p.value))
+			case 3:
+				value = escapeValue(p.value)
+			case 4:
+				dnAttributes = len(p.value) > 0 && p.value[0] != 0
+			}
+		}
+		s := "(" + attr
+		if dnAttributes {
+			s += ":dn"
+		}
+		if rule != "" {
+			s += ":" + rule
+		}
+		return s + ":=" + value + ")", nil
+	}
+	return "", errMalformed
+}
+
+// escapeValue escapes the assertion value as in RFC 4515.
+func escapeValue(v []byte) string {
+	var b strings.Builder
+	for _, c := range v {
+		switch c {
+		case '*', '(', ')', '\\', 0:
+			fmt.Fprintf(&b, "\\%02x", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// normalizeDN lower cases the DN and removes the spaces around its separators.
+func normalizeDN(dn string) string {
+	rdns := strings.Split(strings.ToLower(dn), ",")
+	for i, rdn := range rdns {
+		if k, v, ok := strings.Cut(rdn, "="); ok {
+			rdn = strings.TrimSpace(k) + "=" + strings.TrimSpace(v)
+		}
+		rdns[i] = strings.TrimSpace(rdn)
+	}
+	return strings.Join(rdns, ",")
+}
+
+// withMessageID returns the raw message with its message id replaced.
+func withMessageID(raw []byte, id int64) ([]byte, error) {
+	envelope, _, err := parseElement(raw)
+	if err != nil {
+		return nil, err
+	}
+	_, rest, err := parseElement(envelope.value)
+	if err != nil {
+		return nil, err
+	}
+	content := append(encodeInteger(id), rest...)
+	msg := append([]byte{raw[0]}, encodeLength(len(content))...)
+	return append(msg, content...), nil
+}
@@ -0,0 +1,149 @@
+//go:build linux
+
+package ldap
+
+import (
+	"bufio"
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// tlv encodes an element of the identifier byte with the value of the concatenated parts.
+func tlv(identifier byte, parts ...[]byte) []byte {
+	value := bytes.Join(parts, nil)
+	return append(append([]byte{identifier}, encodeLength(len(value))...), value...)
+}
+
+func octets(identifier byte, s string) []byte {
+	return tlv(identifier, []byte(s))
+}
+
+func ldapMessage(id int64, op []byte) []byte {
+	return tlv(0x30, encodeInteger(id), op)
+}
+
+func TestParseMessage(t *testing.T) {
+	bind := ldapMessage(1, tlv(0x60, encodeInteger(3), octets(0x04, "cn=admin,dc=example,dc=org"), octets(0x80, "secret")))
+	filter := tlv(0xa0, // and
+		tlv(0xa3, octets(0x04, "objectClass"), octets(0x04, "person")),
+		tlv(0xa1, // or
+			tlv(0xa4, octets(0x04, "CN"), tlv(0x30, octets(0x80, "ad"), octets(0x82, "a"))),
+			tlv(0xa3, octets(0x04, "uid"), octets(0x04, "a*b")),
+		),
+	)
+	search := ldapMessage(2, tlv(0x63,
+		octets(0x04, "dc=example,dc=org"),
+		tlv(0x0a, []byte{2}), // wholeSubtree
+		tlv(0x0a, []byte{0}), // never deref aliases
+		encodeInteger(0),     // size limit
+		encodeInteger(0),     // time limit
+		tlv(0x01, []byte{0}), // types only
+		filter,
+		tlv(0x30, octets(0x04, "cn"), octets(0x04, "mail")),
+	))
+	entry := ldapMessage(2, tlv(0x64, octets(0x04, "uid=ada,dc=example,dc=org"), tlv(0x30)))
+	done := ldapMessage(2, tlv(0x65, tlv(0x0a, []byte{32}), octets(0x04, "dc=example,dc=org"), octets(0x04, strings.Repeat("x", 300))))
+	del := ldapMessage(300, octets(0x4a, "uid=ada,dc=example,dc=org"))
+
+	tests := []struct {
+		name string
+		raw  []byte
+		want message
+	}{
+		{
+			name: "simple bind",
+			raw:  bind,
+			want: message{id: 1, op: opBindRequest, dn: "cn=admin,dc=example,dc=org", name: "simple"},
+		},
+		{
+			name: "search",
+			raw:  search,
+			want: message{id: 2, op: opSearchRequest, dn: "dc=example,dc=org", scope: "wholeSubtree",
+				filter: `(&(objectclass=person)(|(cn=ad*a)(uid=a\2ab)))`, attributes: []string{"cn", "mail"}},
+		},
+		{
+			name: "search result entry",
+			raw:  entry,
+			want: message{id: 2, op: opSearchResultEntry, dn: "uid=ada,dc=example,dc=org"},
+		},
+		{
+			name: "search result done of a long length",
+			raw:  done,
+			want: message{id: 2, op: opSearchResultDone, resultCode: 32, dn: "dc=example,dc=org"},
+		},
+		{
+			name: "delete",
+			raw:  del,
+			want: message{id: 300, op: opDelRequest, dn: "uid=ada,dc=example,dc=org"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stream := append(append([]byte(nil), tt.raw...), ldapMessage(9, tlv(0x42))...)
+			r := bufio.NewReader(bytes.NewReader(stream))
+			data, err := readElement(r)
+			if err != nil || !bytes.Equal(data, tt.raw) {
+				t.Fatalf("readElement() = % x, %v, want % x", data, err, tt.raw)
+			}
+			m, err := parseMessage(data)
+			if err != nil {
+				t.Fatalf("parseMessage() failed: %v", err)
+			}
+			got := *m
+			got.raw = nil
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseMessage() = %+v, want %+v", got, tt.want)
+			}
+
+			renumbered, err := withMessageID(m.raw, 70000)
+			if err != nil {
+				t.Fatalf("withMessageID() failed: %v", err)
+			}
+			again, err := parseMessage(renumbered)
+			if err != nil || again.id != 70000 || again.op != m.op || again.dn != m.dn {
+				t.Errorf("parseMessage() of the renumbered message = %+v, %v", again, err)
+			}
+		})
+	}
+}
+
+func TestParseMessageMasksPassword(t *testing.T) {
+	bind := ldapMessage(1, tlv(0x60, encodeInteger(3), octets(0x04, "cn=admin"), octets(0x80, "secret")))
+	m, err := parseMessage(bind)
+	if err != nil {
+		t.Fatalf("parseMessage() failed: %v", err)
+	}
+	if bytes.Contains(m.raw, []byte("secret")) || !bytes.Contains(m.raw, []byte("******")) {
+		t.Errorf("parseMessage() raw = %q, want the password masked", m.raw)
+	}
+	if !bytes.Contains(bind, []byte("secret")) {
+		t.Error("parseMessage() masked the password in the buffer it was given")
+	}
+}
+
+func TestEncodeInteger(t *testing.T) {
+	for _, v := range []int64{0, 1, 127, 128, -1, -128, -129, 70000, 1 << 40} {
+		e, rest, err := parseElement(encodeInteger(v))
+		if err != nil || len(rest) != 0 {
+			t.Fatalf("parseElement() of %d failed: %v", v, err)
+		}
+		if got, err := e.int(); err != nil || got != v {
+			t.Errorf("int() = %d, %v, want %d", got, err, v)
+		}
+	}
+}
+
+func TestParseElementMalformed(t *testing.T) {
+	for _, raw := range [][]byte{
+		{0x30},                         // no length
+		{0x30, 0x05, 0x01},             // value past the buffer
+		{0x30, 0x80, 0x00, 0x00},       // indefinite length
+		{0x30, 0x85, 1, 2, 3, 4, 5, 6}, // length of five bytes
+	} {
+		if _, _, err := parseElement(raw); err == nil {
+			t.Errorf("parseElement(% x) didn't fail", raw)
+		}
+	}
+}
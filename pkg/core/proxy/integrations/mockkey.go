@@ -0,0 +1,66 @@
+//go:build linux
+
+package integrations
+
+import (
+	"net/url"
+	"strings"
+
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+// MockKey returns the signature of the request of the mock. The mocks are indexed on their kind and signature, so
+// that the parsers only go through the mocks which can match a request. The kinds without a signature have an
+// empty key.
+func MockKey(mock *models.Mock) string {
+	switch mock.Kind {
+	case models.HTTP, models.WebSocket:
+		if mock.Spec.HTTPReq == nil {
+			return ""
+		}
+		path := ""
+		if u, err := url.Parse(mock.Spec.HTTPReq.URL); err == nil {
+			path = u.Path
+		}
+		return HTTPMockKey(string(mock.Spec.HTTPReq.Method), path)
+	case models.GRPC_EXPORT:
+		if mock.Spec.GRPCReq == nil {
+			return ""
+		}
+		return GRPCMockKey(mock.Spec.GRPCReq.Headers.PseudoHeaders[":path"])
+	case models.DNS:
+		if mock.Spec.DNSReq == nil {
+			return ""
+		}
+		return DNSMockKey(mock.Spec.DNSReq.Name, mock.Spec.DNSReq.Type)
+	}
+	return ""
+}
+
+// HTTPMockKey is the signature of an http request, the mocks only match the requests with the same method and path.
+func HTTPMockKey(method, path string) string {
+	return method + " " + path
+}
+
+// GRPCMockKey is the signature of a gRPC request, the mocks only match the requests on the same method path.
+func GRPCMockKey(path string) string {
+	return path
+}
+
+// DNSMockKey is the signature of a dns question, the mocks only match the questions of the same type for the same
+// name. The names are compared case-insensitively.
+func DNSMockKey(name, qtype string) string {
+	return strings.ToLower(name) + " " + qtype
+}
+
+// FilterByKey keeps the mocks of the kind with the given signature, for the MockMemDb implementations which
+// don't index the mocks.
+func FilterByKey(mocks []*models.Mock, kind models.Kind, key string) []*models.Mock {
+	var res []*models.Mock
+	for _, mock := range mocks {
+		if mock.Kind == kind && MockKey(mock) == key {
+			res = append(res, mock)
+		}
+	}
+	return res
+}
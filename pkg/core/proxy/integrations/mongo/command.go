@@ -31,6 +31,7 @@ const (
 	Insert            Command = "insert"
 	IsMaster          Command = "isMaster"
 	Ismaster          Command = "ismaster"
+	KillCursors       Command = "killCursors"
 	ListCollections   Command = "listCollections"
 	ListIndexes       Command = "listIndexes"
 	ListDatabases     Command = "listDatabases"
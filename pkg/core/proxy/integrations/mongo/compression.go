@@ -0,0 +1,91 @@
+//go:build linux
+
+package mongo
+
+import (
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/x/bsonx/bsoncore"
+	"go.mongodb.org/mongo-driver/x/mongo/driver"
+	"go.mongodb.org/mongo-driver/x/mongo/driver/wiremessage"
+)
+
+// The names of the compressors negotiated in the hello, they're recorded in the header of the messages compressed.
+// See: https://github.com/mongodb/specifications/blob/master/source/compression/OP_COMPRESSED.md
+var compressorNames = map[wiremessage.CompressorID]string{
+	wiremessage.CompressorNoOp:   "noop",
+	wiremessage.CompressorSnappy: "snappy",
+	wiremessage.CompressorZLib:   "zlib",
+	wiremessage.CompressorZstd:   "zstd",
+}
+
+// decompress returns the wire message compressed in the body of an OP_COMPRESSED, with its original opcode and the
+// request id and the responseTo of the OP_COMPRESSED, and the name of its compressor.
+func decompress(reqID, responseTo int32, body []byte) ([]byte, string, error) {
+	opCode, rem, ok := wiremessage.ReadCompressedOriginalOpCode(body)
+	if !ok {
+		return nil, "", errors.New("malformed OP_COMPRESSED: missing the original opcode")
+	}
+	size, rem, ok := wiremessage.ReadCompressedUncompressedSize(rem)
+	if !ok || size < 0 || size > maxMessageSize {
+		return nil, "", errors.New("malformed OP_COMPRESSED: invalid uncompressed size")
+	}
+	compressorID, rem, ok := wiremessage.ReadCompressedCompressorID(rem)
+	if !ok {
+		return nil, "", errors.New("malformed OP_COMPRESSED: missing the compressor id")
+	}
+	compressor, ok := compressorNames[compressorID]
+	if !ok {
+		return nil, "", fmt.Errorf("unknown compressor id %v of the OP_COMPRESSED", compressorID)
+	}
+
+	msg, err := driver.DecompressPayload(rem, driver.CompressionOpts{
+		Compressor:       compressorID,
+		UncompressedSize: size,
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to decompress the %s OP_COMPRESSED: %v", compressor, err)
+	}
+	wm := wiremessage.AppendHeader(nil, int32(16+len(msg)), reqID, responseTo, opCode)
+	return append(wm, msg...), compressor, nil
+}
+
+// compress returns the wire message compressed in an OP_COMPRESSED with the compressor it was recorded with, the
+// messages recorded uncompressed are returned as they are.
+func compress(wm []byte, compressor string) ([]byte, error) {
+	if compressor == "" {
+		return wm, nil
+	}
+	var compressorID wiremessage.CompressorID
+	found := false
+	for id, name := range compressorNames {
+		if name == compressor {
+			compressorID, found = id, true
+			break
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("unknown compressor %q of the mongo message", compressor)
+	}
+
+	_, reqID, responseTo, opCode, body, ok := wiremessage.ReadHeader(wm)
+	if !ok {
+		return nil, errors.New("malformed wire message: insufficient bytes")
+	}
+	compressed, err := driver.CompressPayload(body, driver.CompressionOpts{
+		Compressor: compressorID,
+		ZlibLevel:  wiremessage.DefaultZlibLevel,
+		ZstdLevel:  wiremessage.DefaultZstdLevel,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to compress the mongo message with %s: %v", compressor, err)
+	}
+
+	idx, buffer := wiremessage.AppendHeaderStart(nil, reqID, responseTo, wiremessage.OpCompressed)
+	buffer = wiremessage.AppendCompressedOriginalOpCode(buffer, opCode)
+	buffer = wiremessage.AppendCompressedUncompressedSize(buffer, int32(len(body)))
+	buffer = wiremessage.AppendCompressedCompressorID(buffer, compressorID)
+	buffer = wiremessage.AppendCompressedCompressedMessage(buffer, compressed)
+	return bsoncore.UpdateLength(buffer, idx, int32(len(buffer[idx:]))), nil
+}
@@ -0,0 +1,316 @@
+//go:build linux
+
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/x/bsonx/bsoncore"
+	"go.uber.org/zap"
+)
+
+// The replies of a find or of an aggregate return the first batch of their cursor and its id, the next batches are
+// fetched with getMore commands sending the id, until the cursor is exhausted, with the id 0, or killed with a
+// killCursors. The change streams, the aggregates starting with a $changeStream stage, and the tailable finds awaiting
+// data keep their cursor open, the server holds each getMore until new events are written or its maxTimeMS is over.
+// See: https://github.com/mongodb/specifications/blob/master/source/find_getmore_killcursors_commands.rst
+
+// defaultAwaitTime is how long the server awaits the next events of a change stream when the getMore has no maxTimeMS.
+const defaultAwaitTime = time.Second
+
+// commandOf returns the document of the single section of the OpMsg, the command of a request or its reply.
+func commandOf(message interface{}) (bson.Raw, bool) {
+	msg, ok := message.(*models.MongoOpMessage)
+	if !ok {
+		return nil, false
+	}
+	for _, section := range msg.Sections {
+		if !strings.HasPrefix(section, "{ SectionSingle msg:") {
+			continue
+		}
+		command, err := extractSectionSingle(section)
+		if err != nil {
+			return nil, false
+		}
+		var doc bson.Raw
+		if err := bson.UnmarshalExtJSON([]byte(command), true, &doc); err != nil {
+			return nil, false
+		}
+		return doc, true
+	}
+	return nil, false
+}
+
+// getMoreOf returns the id of the cursor the request fetches the next batch of, it's false for the other commands.
+func getMoreOf(req models.MongoRequest) (int64, bool) {
+	doc, ok := commandOf(req.Message)
+	if !ok {
+		return 0, false
+	}
+	return doc.Lookup(string(GetMore)).Int64OK()
+}
+
+// killedCursorsOf returns the ids of the cursors the killCursors of the request kills.
+func killedCursorsOf(req models.MongoRequest) []int64 {
+	doc, ok := commandOf(req.Message)
+	if !ok {
+		return nil
+	}
+	if _, err := doc.LookupErr(string(KillCursors)); err != nil {
+		return nil
+	}
+	values, err := doc.Lookup("cursors").Array().Values()
+	if err != nil {
+		return nil
+	}
+	var ids []int64
+	for _, value := range values {
+		if id, ok := value.AsInt64OK(); ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// awaitsData reports whether the command opens a cursor whose getMores await the next events: a change stream, or a
+// tailable find awaiting data.
+func awaitsData(doc bson.Raw) bool {
+	if _, err := doc.LookupErr(string(Aggregate)); err == nil {
+		_, err := doc.LookupErr("pipeline", "0", "$changeStream")
+		return err == nil
+	}
+	tailable, _ := doc.Lookup("tailable").BooleanOK()
+	awaitData, _ := doc.Lookup("awaitData").BooleanOK()
+	return tailable && awaitData
+}
+
+// replayCursors follows the cursors returned to the application, it's shared by the connections since the drivers
+// send the getMores of a cursor on any connection of their pool.
+var replayCursors = newCursors()
+
+// cursor is a cursor returned to the application and not exhausted nor killed yet.
+type cursor struct {
+	ns string
+	// awaitData is whether the getMores of the cursor await the next events, it's the cursor of a change stream
+	awaitData bool
+	// resumeToken is the postBatchResumeToken of the last batch of the change stream
+	resumeToken bson.Raw
+}
+
+// cursors keeps the cursors returned by the replies replayed by their ids. The ids replayed are the ids recorded,
+// each getMore is matched with the next batch recorded for its cursor and the batches are replayed in the order
+// they were fetched in.
+type cursors struct {
+	mu   sync.Mutex
+	open map[int64]*cursor
+}
+
+func newCursors() *cursors {
+	return &cursors{
+		open: make(map[int64]*cursor),
+	}
+}
+
+// match returns the mock of the next batch recorded for the cursor of the getMore of the requests. The batches of the
+// change streams are returned after the time the server awaited the events recorded in them.
+func (c *cursors) match(ctx context.Context, logger *zap.Logger, mongoRequests []models.MongoRequest, mockDb integrations.MockMemDb) (bool, *models.Mock, error) {
+	if len(mongoRequests) != 1 {
+		return false, nil, nil
+	}
+	id, ok := getMoreOf(mongoRequests[0])
+	if !ok {
+		return false, nil, nil
+	}
+	for {
+		if ctx.Err() != nil {
+			return false, nil, ctx.Err()
+		}
+		mocks, err := mockDb.GetFilteredMocksByKey(models.Mongo, "")
+		if err != nil {
+			return false, nil, fmt.Errorf("error while getting tcs mock: %v", err)
+		}
+		var isDeleted bool
+		mock := nextBatch(mocks, id)
+		if mock != nil {
+			isDeleted = mockDb.DeleteFilteredMock(*mock)
+		} else {
+			// the change streams opened by a test keep fetching the events after it
+			mocks, err = mockDb.GetUnFilteredMocksByKey(models.Mongo, "")
+			if err != nil {
+				return false, nil, fmt.Errorf("error while getting config mock: %v", err)
+			}
+			mock = nextBatch(mocks, id)
+			if mock == nil {
+				return false, nil, nil
+			}
+			isDeleted = mockDb.DeleteUnFilteredMock(*mock)
+		}
+		if !isDeleted {
+			continue
+		}
+		replaySessions.bind(mongoRequests[0], mock.Spec.MongoRequests[0])
+
+		c.mu.Lock()
+		cur := c.open[id]
+		c.mu.Unlock()
+		if cur != nil && cur.awaitData && len(mock.Spec.MongoResponses) > 0 {
+			delay := time.Duration(mock.Spec.MongoResponses[0].ReadDelay)
+			logger.Debug("replaying the next batch of the change stream", zap.Int64("cursor", id), zap.Duration("after", delay))
+			select {
+			case <-ctx.Done():
+				return false, nil, ctx.Err()
+			case <-time.After(delay):
+			}
+		}
+		return true, mock, nil
+	}
+}
+
+// nextBatch returns the first batch fetched for the cursor among the mocks.
+func nextBatch(mocks []*models.Mock, id int64) *models.Mock {
+	var next *models.Mock
+	for _, mock := range mocks {
+		if mock.Kind != models.Mongo || len(mock.Spec.MongoRequests) != 1 {
+			continue
+		}
+		if cursorID, ok := getMoreOf(mock.Spec.MongoRequests[0]); !ok || cursorID != id {
+			continue
+		}
+		if next == nil || mock.Spec.ReqTimestampMock.Before(next.Spec.ReqTimestampMock) {
+			next = mock
+		}
+	}
+	return next
+}
+
+// track follows the cursors opened, fetched and killed by the request and its replies.
+func (c *cursors) track(req models.MongoRequest, resps []models.MongoResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, id := range killedCursorsOf(req) {
+		delete(c.open, id)
+	}
+	if len(resps) == 0 {
+		return
+	}
+	reply, ok := commandOf(resps[0].Message)
+	if !ok {
+		return
+	}
+	id, ok := reply.Lookup("cursor", "id").Int64OK()
+	if !ok {
+		return
+	}
+	if id == 0 {
+		if fetched, ok := getMoreOf(req); ok {
+			delete(c.open, fetched)
+		}
+		return
+	}
+
+	cur, ok := c.open[id]
+	if !ok {
+		cur = &cursor{}
+		if command, ok := commandOf(req.Message); ok {
+			cur.awaitData = awaitsData(command)
+		}
+		c.open[id] = cur
+	}
+	if ns, ok := reply.Lookup("cursor", "ns").StringValueOK(); ok {
+		cur.ns = ns
+	}
+	if token, ok := reply.Lookup("cursor", "postBatchResumeToken").DocumentOK(); ok {
+		cur.resumeToken = token
+	}
+}
+
+// answer returns the reply of the requests for the cursors returned in the replay whose requests weren't recorded.
+// The getMores of a change stream whose recorded batches were all replayed await their maxTimeMS and get an empty
+// batch, as the server answers when no events were written, and the killCursors of the cursors returned are
+// answered as killed. The other requests aren't answered, it's nil for them.
+func (c *cursors) answer(ctx context.Context, logger *zap.Logger, mongoRequests []models.MongoRequest) (*opMsg, error) {
+	if len(mongoRequests) != 1 {
+		return nil, nil
+	}
+	doc, ok := commandOf(mongoRequests[0].Message)
+	if !ok {
+		return nil, nil
+	}
+
+	if killed := killedCursorsOf(mongoRequests[0]); len(killed) > 0 {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		for _, id := range killed {
+			if _, ok := c.open[id]; !ok {
+				return nil, nil
+			}
+		}
+		ids := bsoncore.NewArrayBuilder()
+		for _, id := range killed {
+			ids.AppendInt64(id)
+			delete(c.open, id)
+		}
+		empty := bsoncore.NewArrayBuilder().Build()
+		reply := bsoncore.NewDocumentBuilder().
+			AppendArray("cursorsKilled", ids.Build()).
+			AppendArray("cursorsNotFound", empty).
+			AppendArray("cursorsAlive", empty).
+			AppendArray("cursorsUnknown", empty).
+			AppendDouble("ok", 1).
+			Build()
+		return replyMsg(reply, logger), nil
+	}
+
+	id, ok := doc.Lookup(string(GetMore)).Int64OK()
+	if !ok {
+		return nil, nil
+	}
+	c.mu.Lock()
+	cur, ok := c.open[id]
+	var open cursor
+	if ok {
+		open = *cur
+	}
+	c.mu.Unlock()
+	if !ok || !open.awaitData {
+		return nil, nil
+	}
+
+	await := defaultAwaitTime
+	if maxTime, ok := doc.Lookup("maxTimeMS").AsInt64OK(); ok {
+		await = time.Duration(maxTime) * time.Millisecond
+	}
+	logger.Debug("no events are left to replay for the change stream", zap.Int64("cursor", id), zap.Duration("awaiting", await))
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(await):
+	}
+
+	batch := bsoncore.NewDocumentBuilder().
+		AppendArray("nextBatch", bsoncore.NewArrayBuilder().Build())
+	if open.resumeToken != nil {
+		batch.AppendDocument("postBatchResumeToken", bsoncore.Document(open.resumeToken))
+	}
+	batch.AppendInt64("id", id).AppendString("ns", open.ns)
+	reply := bsoncore.NewDocumentBuilder().
+		AppendDocument("cursor", batch.Build()).
+		AppendDouble("ok", 1).
+		Build()
+	return replyMsg(reply, logger), nil
+}
+
+// replyMsg returns the OpMsg of the reply with its single section.
+func replyMsg(reply bsoncore.Document, logger *zap.Logger) *opMsg {
+	return &opMsg{
+		sections: []opMsgSection{&opMsgSectionSingle{msg: reply}},
+		logger:   logger,
+	}
+}
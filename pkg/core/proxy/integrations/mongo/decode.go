@@ -124,7 +124,7 @@ func decodeMongo(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientC
 				var maxMatchScore = 0.0
 				var configMocks []*models.Mock
 				for {
-					configMocks, err = mockDb.GetUnFilteredMocks()
+					configMocks, err = mockDb.GetUnFilteredMocksByKey(models.Mongo, "")
 					if err != nil {
 						utils.LogError(logger, err, "error while getting config mock")
 					}
@@ -238,7 +238,13 @@ func decodeMongo(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientC
 							return
 						}
 						requestID := wiremessage.NextRequestID()
-						heathCheckReplyBuffer := replyMessage.Encode(responseTo, requestID)
+						// the replies are compressed as the request was, see the OP_COMPRESSED spec
+						heathCheckReplyBuffer, err := compress(replyMessage.Encode(responseTo, requestID), mongoRequests[0].Header.Compressor)
+						if err != nil {
+							utils.LogError(logger, err, "failed to compress the recorded OpReply", zap.Any("for request with id", responseTo))
+							errCh <- err
+							return
+						}
 						responseTo = requestID
 						logger.Debug(fmt.Sprintf("the bufffer response is: %v", string(heathCheckReplyBuffer)))
 						_, err = clientConn.Write(heathCheckReplyBuffer)
@@ -263,7 +269,13 @@ func decodeMongo(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientC
 							errCh <- err
 							return
 						}
-						_, err = clientConn.Write(message.Encode(responseTo, wiremessage.NextRequestID()))
+						messageBuffer, err := compress(message.Encode(responseTo, wiremessage.NextRequestID()), mongoRequests[0].Header.Compressor)
+						if err != nil {
+							utils.LogError(logger, err, "failed to compress the recorded OpMsg response", zap.Any("for request with id", responseTo))
+							errCh <- err
+							return
+						}
+						_, err = clientConn.Write(messageBuffer)
 						if err != nil {
 							if ctx.Err() != nil {
 								return
@@ -277,51 +289,94 @@ func decodeMongo(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientC
 			} else {
 				// handle for the non-heartbeat request from the client
 
-				// match the incoming request with the recorded tcsMocks and return a mocked response which matches most with incoming request
-				matched, matchedMock, err := match(ctx, logger, mongoRequests, mockDb)
+				// the getMores are matched with the next batches recorded for their cursors
+				matched, matchedMock, err := replayCursors.match(ctx, logger, mongoRequests, mockDb)
 				if err != nil {
+					if ctx.Err() != nil {
+						return
+					}
 					errCh <- err
-					utils.LogError(logger, err, "error while matching mongo mocks")
+					utils.LogError(logger, err, "error while matching the next batch of the mongo cursor")
 					return
 				}
-				if !matched {
-					logger.Debug("mongo request not matched with any tcsMocks", zap.Any("request", mongoRequests))
-					reqBuf, err = util.PassThrough(ctx, logger, clientConn, dstCfg, requestBuffers)
+				// the batches of the other cursors aren't matched for the getMores, their ids differ
+				if _, isGetMore := getMoreOf(mongoRequests[0]); !matched && !isGetMore {
+					// match the incoming request with the recorded tcsMocks and return a mocked response which matches most with incoming request
+					matched, matchedMock, err = match(ctx, logger, mongoRequests, mockDb, opts.Mongo.QueryMatching)
 					if err != nil {
-						utils.LogError(logger, err, "failed to passthrough the mongo request to the actual database server")
 						errCh <- err
+						utils.LogError(logger, err, "error while matching mongo mocks")
 						return
 					}
-					continue
 				}
-
-				responseTo := mongoRequests[0].Header.RequestID
-				logger.Debug("the mock matched with the current request", zap.Any("mock", matchedMock), zap.Any("responseTo", responseTo))
-
-				// write the mongo response to the client connection from the recorded tcsMocks that most matches the incoming request
-				for _, resp := range matchedMock.Spec.MongoResponses {
-					respMessage := resp.Message.(*models.MongoOpMessage)
-					var expectedRequestSections []string
-					if len(matchedMock.Spec.MongoRequests) > 0 {
-						expectedRequestSections = matchedMock.Spec.MongoRequests[0].Message.(*models.MongoOpMessage).Sections
+				if !matched {
+					// the change streams replayed keep awaiting the next events once the recorded ones were replayed
+					answered, err := replayCursors.answer(ctx, logger, mongoRequests)
+					if err != nil {
+						return
+					}
+					if answered == nil {
+						logger.Debug("mongo request not matched with any tcsMocks", zap.Any("request", mongoRequests))
+						reqBuf, err = util.PassThrough(ctx, logger, clientConn, dstCfg, requestBuffers)
+						if err != nil {
+							utils.LogError(logger, err, "failed to passthrough the mongo request to the actual database server")
+							errCh <- err
+							return
+						}
+						continue
 					}
-					message, err := encodeOpMsg(respMessage, mongoRequest.(*models.MongoOpMessage).Sections, expectedRequestSections, opts.MongoPassword, logger)
+					responseTo := mongoRequests[0].Header.RequestID
+					messageBuffer, err := compress(answered.Encode(responseTo, wiremessage.NextRequestID()), mongoRequests[0].Header.Compressor)
 					if err != nil {
-						utils.LogError(logger, err, "failed to encode the recorded OpMsg response", zap.Any("for request with id", responseTo))
+						utils.LogError(logger, err, "failed to compress the reply of the mongo cursor", zap.Any("for request with id", responseTo))
 						errCh <- err
 						return
 					}
-					requestID := wiremessage.NextRequestID()
-					_, err = clientConn.Write(message.Encode(responseTo, requestID))
+					_, err = clientConn.Write(messageBuffer)
 					if err != nil {
 						if ctx.Err() != nil {
 							return
 						}
-						utils.LogError(logger, err, "failed to write the health check opmsg to mongo client", zap.Any("for request with id", responseTo))
+						utils.LogError(logger, err, "failed to write the reply of the mongo cursor to mongo client", zap.Any("for request with id", responseTo))
 						errCh <- err
 						return
 					}
-					responseTo = requestID
+				} else {
+					responseTo := mongoRequests[0].Header.RequestID
+					logger.Debug("the mock matched with the current request", zap.Any("mock", matchedMock), zap.Any("responseTo", responseTo))
+
+					// write the mongo response to the client connection from the recorded tcsMocks that most matches the incoming request
+					for _, resp := range matchedMock.Spec.MongoResponses {
+						respMessage := resp.Message.(*models.MongoOpMessage)
+						var expectedRequestSections []string
+						if len(matchedMock.Spec.MongoRequests) > 0 {
+							expectedRequestSections = matchedMock.Spec.MongoRequests[0].Message.(*models.MongoOpMessage).Sections
+						}
+						message, err := encodeOpMsg(respMessage, mongoRequest.(*models.MongoOpMessage).Sections, expectedRequestSections, opts.MongoPassword, logger)
+						if err != nil {
+							utils.LogError(logger, err, "failed to encode the recorded OpMsg response", zap.Any("for request with id", responseTo))
+							errCh <- err
+							return
+						}
+						requestID := wiremessage.NextRequestID()
+						messageBuffer, err := compress(message.Encode(responseTo, requestID), mongoRequests[0].Header.Compressor)
+						if err != nil {
+							utils.LogError(logger, err, "failed to compress the recorded OpMsg response", zap.Any("for request with id", responseTo))
+							errCh <- err
+							return
+						}
+						_, err = clientConn.Write(messageBuffer)
+						if err != nil {
+							if ctx.Err() != nil {
+								return
+							}
+							utils.LogError(logger, err, "failed to write the health check opmsg to mongo client", zap.Any("for request with id", responseTo))
+							errCh <- err
+							return
+						}
+						responseTo = requestID
+					}
+					replayCursors.track(mongoRequests[0], matchedMock.Spec.MongoResponses)
 				}
 			}
 			logger.Debug("the length of the requestBuffer after matching: " + strconv.Itoa(len(reqBuf)) + strconv.Itoa(len(requestBuffers[0])))
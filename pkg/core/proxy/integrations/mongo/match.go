@@ -17,14 +17,20 @@ import (
 	"go.uber.org/zap"
 )
 
-// match mathces and returns the best matching mock for the incoming mongo requests.
-func match(ctx context.Context, logger *zap.Logger, mongoRequests []models.MongoRequest, mockDb integrations.MockMemDb) (bool, *models.Mock, error) {
+// match mathces and returns the best matching mock for the incoming mongo requests. The mocks of the same shape are
+// preferred to the ones matching the requests partially when queryMatching is "shape" and no mock matches them
+// exactly.
+func match(ctx context.Context, logger *zap.Logger, mongoRequests []models.MongoRequest, mockDb integrations.MockMemDb, queryMatching string) (bool, *models.Mock, error) {
+	shape, shaped := "", false
+	if queryMatching == queryMatchShape {
+		shape, shaped = requestShape(mongoRequests)
+	}
 	for {
 		select {
 		case <-ctx.Done():
 			return false, nil, ctx.Err()
 		default:
-			mocks, err := mockDb.GetFilteredMocks()
+			mocks, err := mockDb.GetFilteredMocksByKey(models.Mongo, "")
 			if err != nil {
 				return false, nil, fmt.Errorf("error while getting tcs mock: %v", err)
 			}
@@ -37,6 +43,12 @@ func match(ctx context.Context, logger *zap.Logger, mongoRequests []models.Mongo
 			}
 			maxMatchScore := 0.0
 			bestMatchIndex := -1
+			// the mocks of the session and of the transaction bound to the ones of the request are preferred
+			maxConsistentScore := 0.0
+			bestConsistentIndex := -1
+			// the mocks of the same shape as the requests, for the ones drifting from the recording
+			maxShapeScore := 0.0
+			bestShapeIndex := -1
 			// iterate over the tcsMocks and compare the incoming mongo requests with the recorded mongo requests.
 			for tcsIndx, tcsMock := range tcsMocks {
 				if ctx.Err() != nil {
@@ -71,12 +83,36 @@ func match(ctx context.Context, logger *zap.Logger, mongoRequests []models.Mongo
 								maxMatchScore = currentScore
 								bestMatchIndex = tcsIndx
 							}
+							if currentScore <= maxConsistentScore && (!shaped || currentScore <= maxShapeScore) {
+								continue
+							}
+							if !replaySessions.consistent(mongoRequests[i], req) {
+								continue
+							}
+							if currentScore > maxConsistentScore {
+								maxConsistentScore = currentScore
+								bestConsistentIndex = tcsIndx
+							}
+							if shaped && currentScore > maxShapeScore {
+								if recorded, ok := requestShape(tcsMock.Spec.MongoRequests); ok && recorded == shape {
+									maxShapeScore = currentScore
+									bestShapeIndex = tcsIndx
+								}
+							}
 						default:
 							utils.LogError(logger, nil, "the OpCode of the mongo wiremessage is invalid.")
 						}
 					}
 				}
 			}
+			if bestConsistentIndex != -1 {
+				bestMatchIndex = bestConsistentIndex
+				maxMatchScore = maxConsistentScore
+			}
+			if bestShapeIndex != -1 && maxMatchScore < 1 {
+				logger.Debug("matched the mongo request on its shape", zap.String("shape", shape), zap.Float64("score", maxShapeScore))
+				bestMatchIndex = bestShapeIndex
+			}
 			if bestMatchIndex == -1 {
 				return false, nil, nil
 			}
@@ -85,6 +121,7 @@ func match(ctx context.Context, logger *zap.Logger, mongoRequests []models.Mongo
 			if !isDeleted {
 				continue
 			}
+			replaySessions.bind(mongoRequests[0], mock.Spec.MongoRequests[0])
 			return true, mock, nil
 		}
 	}
@@ -182,6 +219,9 @@ func compareOpMsgSection(logger *zap.Logger, expectedSection, actualSection stri
 			utils.LogError(logger, err, "failed to unmarshal the section of incoming request to bson document")
 			return 0
 		}
+		// the sessions change with each run, they're matched apart
+		withoutSessionFields(expected)
+		withoutSessionFields(actual)
 		logger.Debug("the expected and actual msg in the single section.", zap.Any("expected", expected), zap.Any("actual", actual), zap.Any("score", calculateMatchingScore(expected, actual)))
 		return calculateMatchingScore(expected, actual)
 
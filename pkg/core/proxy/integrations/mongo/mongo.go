@@ -36,16 +36,33 @@ func NewMongo(logger *zap.Logger) integrations.Integrations {
 
 // MatchType determines if the outgoing network call is Mongo by comparing the
 // message format with that of a mongo wire message.
-func (m *Mongo) MatchType(_ context.Context, buffer []byte) bool {
-	if len(buffer) < 4 {
-		return false
+func (m *Mongo) MatchType(_ context.Context, buffer []byte) integrations.MatchResult {
+	// the header of a wire message is 16 bytes long
+	if len(buffer) < 16 {
+		return integrations.MatchResult{NeedBytes: 16}
 	}
 	// identifies by the starting 4 bytes of the message, since that
-	// are the length of the message.
-	messageLength := binary.LittleEndian.Uint32(buffer[0:4])
-	return int(messageLength) == len(buffer)
+	// are the length of the message and the opcode at the end of the header.
+	messageLength := int(binary.LittleEndian.Uint32(buffer[0:4]))
+	opCode := wiremessage.OpCode(binary.LittleEndian.Uint32(buffer[12:16]))
+	knownOpCode := opCode == wiremessage.OpMsg || opCode == wiremessage.OpQuery || opCode == wiremessage.OpCompressed
+
+	switch {
+	case messageLength == len(buffer) && knownOpCode:
+		return integrations.MatchResult{Confidence: integrations.ConfidenceCertain}
+	case messageLength == len(buffer):
+		return integrations.MatchResult{Confidence: integrations.ConfidenceMedium}
+	case messageLength > len(buffer) && messageLength <= maxMessageSize && knownOpCode:
+		// the complete message has not been received yet
+		return integrations.MatchResult{Confidence: integrations.ConfidenceMedium, NeedBytes: messageLength}
+	default:
+		return integrations.MatchResult{}
+	}
 }
 
+// maxMessageSize is the default maxMessageSizeBytes of the mongo server
+const maxMessageSize = 48000000
+
 // RecordOutgoing records the outgoing mongo messages of the client connection into the yaml file.
 // The database connection is keep-alive so, this function will be called during the connection establishment.
 func (m *Mongo) RecordOutgoing(ctx context.Context, src net.Conn, dst net.Conn, mocks chan<- *models.Mock, opts models.OutgoingOptions) error {
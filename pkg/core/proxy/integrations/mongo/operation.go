@@ -73,6 +73,15 @@ func Decode(wm []byte, logger *zap.Logger) (Operation, models.MongoHeader, inter
 	)
 
 	switch opCode {
+	case wiremessage.OpCompressed:
+		// the compressed message is decoded as it was sent uncompressed, its header keeps the compressor
+		uncompressed, compressor, err := decompress(reqID, responseTo, wmBody)
+		if err != nil {
+			return nil, messageHeader, &models.MongoOpMessage{}, err
+		}
+		op, messageHeader, mongoMsg, err = Decode(uncompressed, logger)
+		messageHeader.Compressor = compressor
+		return op, messageHeader, mongoMsg, err
 	case wiremessage.OpQuery:
 		// decodeQuery is a helper function to decode the OpQuery operation
 		op, err = decodeQuery(reqID, wmBody)
@@ -611,7 +620,7 @@ func processOpReply(expectedRequest, actualRequest models.MongoRequest, expected
 				return "", false
 			}
 			authMessage = authMessage + ",auth=" + authMechanism
-			authMessageMap.Store(conversationID, authMessage)
+			storeConversation(conversationID, authMessage, actualRequest)
 			// Marshal the new first response for the SCRAM authentication
 			authResponse := base64.StdEncoding.EncodeToString([]byte(newFirstAuthResponse))
 			if authResponse != "" {
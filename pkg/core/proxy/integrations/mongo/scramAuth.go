@@ -81,6 +81,11 @@ func handleScramAuth(actualRequestSections, expectedRequestSections []string, re
 			return "", false, err
 		}
 
+		// the hello of the connection handshake may start the conversation already
+		if _, exists := actualMsg["speculativeAuthenticate"]; exists {
+			return handleSpeculativeAuth(i, actualMsg, expectedRequestSections, responseSection, logger)
+		}
+
 		// Check if the message is for starting the SASL (authentication) process
 		if _, exists := actualMsg["saslStart"]; exists {
 			mechanism, exists := actualMsg["mechanism"]
@@ -227,38 +232,114 @@ func extractMsgFromSection(section string) (map[string]interface{}, error) {
 }
 
 func handleSaslStart(i int, actualMsg map[string]interface{}, expectedRequestSections []string, responseSection string, logger *zap.Logger) (string, bool, error) {
-	actualReqPayload, err := extractAuthPayload(actualMsg)
+	// check to ensure that the matched recorded mongo request contains the auth payload for SCRAM
+	if len(expectedRequestSections) < i+1 {
+		err := errors.New("unrecorded message sections for the recieved auth request")
+		utils.LogError(logger, err, "failed to match the message section payload")
+		return "", false, err
+	}
+
+	expectedMsg, err := extractMsgFromSection(expectedRequestSections[i])
 	if err != nil {
-		utils.LogError(logger, err, "failed to fetch the payload from the recieved mongo request")
+		utils.LogError(logger, err, "failed to extract the section of the recorded mongo request message")
 		return "", false, err
 	}
-	logger.Debug(fmt.Sprint("the payload of the recieved request: ", actualReqPayload))
 
-	// Decode the base64 encoded payload of the recieved mongo request
-	decodedActualReqPayload, err := decodeBase64Str(actualReqPayload)
+	// the payload of the recorded first response of SCRAM authentication
+	var responseMsg map[string]interface{}
+
+	err = json.Unmarshal([]byte(responseSection), &responseMsg)
 	if err != nil {
-		utils.LogError(logger, err, "Error decoding the recieved payload base64 string")
+		utils.LogError(logger, err, "failed to unmarshal string document of OpReply")
 		return "", false, err
 	}
-	logger.Debug(fmt.Sprint("the decoded payload of the actual for the saslstart: ", (string)(decodedActualReqPayload)))
 
-	// check to ensure that the matched recorded mongo request contains the auth payload for SCRAM
-	if len(expectedRequestSections) < i+1 {
-		err = errors.New("unrecorded message sections for the recieved auth request")
-		utils.LogError(logger, err, "failed to match the message section payload")
+	err = startConversation(actualMsg, expectedMsg, responseMsg, logger)
+	if err != nil {
+		return "", false, err
+	}
+
+	// marshal the new first response for the SCRAM authentication
+	newAuthResponse, err := json.Marshal(responseMsg)
+	if err != nil {
+		utils.LogError(logger, err, "failed to marshal the first auth response for SCRAM")
 		return "", false, err
 	}
+	return string(newAuthResponse), true, nil
+}
 
+// handleSpeculativeAuth handles the saslStart sent in the speculativeAuthenticate of the hello, which the drivers
+// send to save a round trip, and which is answered in the speculativeAuthenticate of the hello response.
+// See: https://github.com/mongodb/specifications/blob/master/source/auth/auth.md#speculative-authentication
+func handleSpeculativeAuth(i int, actualMsg map[string]interface{}, expectedRequestSections []string, responseSection string, logger *zap.Logger) (string, bool, error) {
+	actualStart, ok := actualMsg["speculativeAuthenticate"].(map[string]interface{})
+	if !ok {
+		return "", false, nil
+	}
+	if mechanism, ok := actualStart["mechanism"].(string); !ok || !strings.Contains(mechanism, "SCRAM") {
+		return "", false, nil
+	}
+	if len(expectedRequestSections) < i+1 {
+		return "", false, nil
+	}
 	expectedMsg, err := extractMsgFromSection(expectedRequestSections[i])
 	if err != nil {
 		utils.LogError(logger, err, "failed to extract the section of the recorded mongo request message")
 		return "", false, err
 	}
+	expectedStart, ok := expectedMsg["speculativeAuthenticate"].(map[string]interface{})
+	if !ok {
+		return "", false, nil
+	}
+
+	var responseMsg map[string]interface{}
+	err = json.Unmarshal([]byte(responseSection), &responseMsg)
+	if err != nil {
+		utils.LogError(logger, err, "failed to unmarshal string document of OpReply")
+		return "", false, err
+	}
+	// the server answers the hello without the speculativeAuthenticate when the speculative conversation failed,
+	// the drivers then authenticate with a saslStart
+	responseStart, ok := responseMsg["speculativeAuthenticate"].(map[string]interface{})
+	if !ok {
+		return "", false, nil
+	}
+
+	err = startConversation(actualStart, expectedStart, responseStart, logger)
+	if err != nil {
+		return "", false, err
+	}
+
+	newResponse, err := json.Marshal(responseMsg)
+	if err != nil {
+		utils.LogError(logger, err, "failed to marshal the hello response with the speculative SCRAM auth")
+		return "", false, err
+	}
+	return string(newResponse), true, nil
+}
+
+// startConversation updates the recorded response of the saslStart with the nonce of the received saslStart and a
+// new conversationId, and stores the auth message of the new conversation.
+func startConversation(actualMsg, expectedMsg, responseMsg map[string]interface{}, logger *zap.Logger) error {
+	actualReqPayload, err := extractAuthPayload(actualMsg)
+	if err != nil {
+		utils.LogError(logger, err, "failed to fetch the payload from the recieved mongo request")
+		return err
+	}
+	logger.Debug(fmt.Sprint("the payload of the recieved request: ", actualReqPayload))
+
+	// Decode the base64 encoded payload of the recieved mongo request
+	decodedActualReqPayload, err := decodeBase64Str(actualReqPayload)
+	if err != nil {
+		utils.LogError(logger, err, "Error decoding the recieved payload base64 string")
+		return err
+	}
+	logger.Debug(fmt.Sprint("the decoded payload of the actual for the saslstart: ", (string)(decodedActualReqPayload)))
 
 	expectedReqPayload, err := extractAuthPayload(expectedMsg)
 	if err != nil {
 		utils.LogError(logger, err, "failed to fetch the payload from the recorded mongo request")
-		return "", false, err
+		return err
 	}
 	logger.Debug(fmt.Sprint("the payload of the recorded request: ", expectedReqPayload))
 
@@ -266,22 +347,14 @@ func handleSaslStart(i int, actualMsg map[string]interface{}, expectedRequestSec
 	decodedExpectedReqPayload, err := decodeBase64Str(expectedReqPayload)
 	if err != nil {
 		utils.LogError(logger, err, "Error decoding the recorded request payload base64 string")
-		return "", false, err
+		return err
 	}
 	logger.Debug(fmt.Sprint("the decoded payload of the expected for the saslstart: ", (string)(decodedExpectedReqPayload)))
 
-	// the payload of the recorded first response of SCRAM authentication
-	var responseMsg map[string]interface{}
-
-	err = json.Unmarshal([]byte(responseSection), &responseMsg)
-	if err != nil {
-		utils.LogError(logger, err, "failed to unmarshal string document of OpReply")
-		return "", false, err
-	}
 	responsePayload, err := extractAuthPayload(responseMsg)
 	if err != nil {
 		utils.LogError(logger, err, "failed to fetch the payload from the recorded mongo response")
-		return "", false, err
+		return err
 	}
 	logger.Debug(fmt.Sprint("the payload of the recorded response: ", responsePayload))
 
@@ -289,7 +362,7 @@ func handleSaslStart(i int, actualMsg map[string]interface{}, expectedRequestSec
 	decodedResponsePayload, err := decodeBase64Str(responsePayload)
 	if err != nil {
 		utils.LogError(logger, err, "Error decoding the recorded response payload base64 string")
-		return "", false, err
+		return err
 	}
 	logger.Debug(fmt.Sprint("the decoded payload of the repsonse for the saslstart: ", (string)(decodedResponsePayload)))
 
@@ -297,22 +370,22 @@ func handleSaslStart(i int, actualMsg map[string]interface{}, expectedRequestSec
 	// replacing the old client nonce with new client nonce
 	newFirstAuthResponse, err := scram.GenerateServerFirstMessage(decodedExpectedReqPayload, decodedActualReqPayload, decodedResponsePayload, logger)
 	if err != nil {
-		return "", false, err
+		return err
 	}
 	logger.Debug("after replacing the new client nonce in auth response", zap.String("first response", newFirstAuthResponse))
 	// replace the payload with new first response auth
 	responseMsg["payload"].(map[string]interface{})["$binary"].(map[string]interface{})["base64"] = base64.StdEncoding.EncodeToString([]byte(newFirstAuthResponse))
-	responseMsg, err = updateConversationID(responseMsg, int(util.GetNextID()))
+	_, err = updateConversationID(responseMsg, int(util.GetNextID()))
 	if err != nil {
 		utils.LogError(logger, err, "failed to update the conversationId in the sasl start auth message")
-		return "", false, err
+		return err
 	}
 
 	// fetch the conversation id
 	conversationID, err := extractConversationID(responseMsg)
 	if err != nil {
 		utils.LogError(logger, err, "failed to fetch the conversationId for the SCRAM auth from the recorded first response")
-		return "", false, err
+		return err
 	}
 	logger.Debug("fetch the conversationId for the SCRAM authentication", zap.String("cid", conversationID))
 	// generate the auth message from the recieved first request and recorded first response
@@ -323,24 +396,29 @@ func handleSaslStart(i int, actualMsg map[string]interface{}, expectedRequestSec
 	} else {
 		if authMechanism != scramUtil.SCRAM_SHA_1 && authMechanism != scramUtil.SCRAM_SHA_256 {
 			logger.Error("Invalid authentication mechanism", zap.String("authMechanism", authMechanism))
-			return "", false, errors.New("invalid authentication mechanism")
+			return errors.New("invalid authentication mechanism")
 		}
 
 		authMessage = authMessage + ",auth=" + authMechanism
 		// store the auth message in the global map for the conversationId
 	}
 
-	authMessageMap.Store(conversationID, authMessage)
+	storeConversation(conversationID, authMessage, actualMsg)
 
 	logger.Debug("genrate the new auth message for the recieved auth request", zap.String("msg", authMessage))
+	return nil
+}
 
-	// marshal the new first response for the SCRAM authentication
-	newAuthResponse, err := json.Marshal(responseMsg)
-	if err != nil {
-		utils.LogError(logger, err, "failed to marshal the first auth response for SCRAM")
-		return "", false, err
-	}
-	return string(newAuthResponse), true, nil
+// skipEmptyExchangeMap stores whether the client asked to skip the empty exchange ending the conversation for the
+// conversationIds. The server then answers the final message of the client with done set.
+var skipEmptyExchangeMap = sync.Map{}
+
+// storeConversation stores the auth message and the options of the saslStart of the conversation.
+func storeConversation(conversationID, authMessage string, saslStart map[string]interface{}) {
+	authMessageMap.Store(conversationID, authMessage)
+	options, _ := saslStart["options"].(map[string]interface{})
+	skip, _ := options["skipEmptyExchange"].(bool)
+	skipEmptyExchangeMap.Store(conversationID, skip)
 }
 
 // handleSaslContinue processes a SASL continuation message, updates the payload with
@@ -379,14 +457,6 @@ func handleSaslContinue(actualMsg map[string]interface{}, responseSection, mongo
 	}
 	logger.Debug(fmt.Sprint("the decoded payload of the repsonse for the saslContinue: ", (string)(decodedResponsePayload)))
 
-	fields := strings.Split(string(decodedResponsePayload), ",")
-	verifier, err := parseFieldBase64(fields[0], "v")
-	if err != nil {
-		logger.Debug("failed to parse the verifier of final response message", zap.Any("parsing error", err.Error()))
-		return "", false, nil
-	}
-	logger.Debug("the recorded verifier of the auth request", zap.Any("verifier/server-signature", string(verifier)))
-
 	// fetch the conversation id
 	conversationID, err := extractConversationID(actualMsg)
 	if err != nil {
@@ -394,6 +464,34 @@ func handleSaslContinue(actualMsg map[string]interface{}, responseSection, mongo
 		return "", false, err
 	}
 	logger.Debug("fetched conversationId for the SCRAM authentication", zap.String("cid", conversationID))
+	responseMsg["conversationId"] = actualMsg["conversationId"]
+
+	actualPayload, err := extractAuthPayload(actualMsg)
+	if err != nil {
+		utils.LogError(logger, err, "failed to fetch the payload from the recieved saslContinue request")
+		return "", false, err
+	}
+	decodedActualPayload, err := decodeBase64Str(actualPayload)
+	if err != nil {
+		utils.LogError(logger, err, "Error decoding the recieved saslContinue request payload base64 string")
+		return "", false, err
+	}
+
+	// The response follows the step of the request, since the recorded response matched may answer the other step
+	// of a conversation. The client ends the conversation with an empty message once it verified the server proof.
+	if len(decodedActualPayload) == 0 {
+		responseMsg["payload"].(map[string]interface{})["$binary"].(map[string]interface{})["base64"] = ""
+		responseMsg["done"] = true
+		byt, err := json.Marshal(responseMsg)
+		if err != nil {
+			utils.LogError(logger, err, "failed to marshal the updated string document of OpReply")
+			return "", false, err
+		}
+		return string(byt), true, nil
+	}
+	if skip, ok := skipEmptyExchangeMap.Load(conversationID); ok {
+		responseMsg["done"] = skip.(bool)
+	}
 
 	salt := ""
 	itr := 0
@@ -407,7 +505,7 @@ func handleSaslContinue(actualMsg map[string]interface{}, responseSection, mongo
 	}
 
 	// get the salt and iteration from the authMessage to generate salted password
-	fields = strings.Split(authMessageStr, ",")
+	fields := strings.Split(authMessageStr, ",")
 	filteredFields := []string{}
 	for _, part := range fields {
 		if strings.HasPrefix(part, "s=") {
@@ -447,6 +545,15 @@ func handleSaslContinue(actualMsg map[string]interface{}, responseSection, mongo
 		return "", false, err
 	}
 
+	// the server proof is only accepted by the client authenticating with the same password
+	clientFinal := strings.Split(string(decodedActualPayload), ",")
+	if proof, err := parseFieldBase64(clientFinal[len(clientFinal)-1], "p"); err == nil {
+		verified, err := scram.VerifyClientProof(authMessageStr, authType, mongoPassword, salt, itr, proof, logger)
+		if err == nil && !verified {
+			logger.Warn("the mongo client authenticates with another password than the one the mocks are replayed with, set it with the mongoPassword config or the --mongo-password flag")
+		}
+	}
+
 	// tools the payload of the mongo response for the authentication
 	responseMsg["payload"].(map[string]interface{})["$binary"].(map[string]interface{})["base64"] = base64.StdEncoding.EncodeToString([]byte("v=" + newVerifier))
 	byt, err := json.Marshal(responseMsg)
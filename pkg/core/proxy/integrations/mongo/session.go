@@ -0,0 +1,133 @@
+//go:build linux
+
+package mongo
+
+import (
+	"encoding/hex"
+	"sync"
+
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+// sessionFields are the fields the drivers add to the commands for the logical sessions and the causal consistency.
+// Their values change with each run and are left out of the matching: the session ids and the transaction numbers
+// are matched by the sessions bound instead, and the cluster times gossiped and their signatures are stale.
+// See: https://github.com/mongodb/specifications/blob/master/source/sessions/driver-sessions.md
+var sessionFields = []string{"lsid", "txnNumber", "$clusterTime"}
+
+// withoutSessionFields removes the session fields from the command, and the afterClusterTime of its read concern.
+func withoutSessionFields(doc map[string]interface{}) {
+	for _, field := range sessionFields {
+		delete(doc, field)
+	}
+	if readConcern, ok := doc["readConcern"].(map[string]interface{}); ok {
+		delete(readConcern, "afterClusterTime")
+		if len(readConcern) == 0 {
+			delete(doc, "readConcern")
+		}
+	}
+}
+
+// session is the logical session of a command and the number of its transaction, or of its retryable write.
+type session struct {
+	id        string
+	txnNumber int64
+}
+
+// sessionOf returns the session of the command of the request, it's false when the command isn't sent in a session.
+func sessionOf(req models.MongoRequest) (session, bool) {
+	doc, ok := commandOf(req.Message)
+	if !ok {
+		return session{}, false
+	}
+	_, id, ok := doc.Lookup("lsid", "id").BinaryOK()
+	if !ok {
+		return session{}, false
+	}
+	txnNumber, _ := doc.Lookup("txnNumber").Int64OK()
+	return session{id: hex.EncodeToString(id), txnNumber: txnNumber}, true
+}
+
+// replaySessions binds the sessions of the application to the sessions recorded, it's shared by the connections
+// since the drivers pool the sessions apart from the connections.
+var replaySessions = newSessions()
+
+// sessions binds the sessions started in the replay to the sessions recorded, and their transactions to the
+// transactions recorded. Once the first command of a session or of a transaction is matched, its next commands,
+// up to the commitTransaction or the abortTransaction, are matched with the mocks of the same recorded session
+// and transaction.
+type sessions struct {
+	mu sync.Mutex
+	// ids are the recorded sessions by the sessions of the replay, and replayed the other way around
+	ids      map[string]string
+	replayed map[string]string
+	// txns are the recorded transactions by the transactions of the replay, and replayedTxns the other way around
+	txns         map[session]int64
+	replayedTxns map[session]int64
+}
+
+func newSessions() *sessions {
+	return &sessions{
+		ids:          make(map[string]string),
+		replayed:     make(map[string]string),
+		txns:         make(map[session]int64),
+		replayedTxns: make(map[session]int64),
+	}
+}
+
+// consistent reports whether the recorded request can be matched with the request of the replay, by the sessions
+// and the transactions bound so far. The requests sent outside of a session are consistent with any.
+func (s *sessions) consistent(actual, recorded models.MongoRequest) bool {
+	a, ok := sessionOf(actual)
+	if !ok {
+		return true
+	}
+	r, ok := sessionOf(recorded)
+	if !ok {
+		return true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if id, ok := s.ids[a.id]; ok {
+		if id != r.id {
+			return false
+		}
+	} else if _, ok := s.replayed[r.id]; ok {
+		return false
+	}
+	if a.txnNumber == 0 || r.txnNumber == 0 {
+		return a.txnNumber == r.txnNumber
+	}
+	if txn, ok := s.txns[a]; ok {
+		return txn == r.txnNumber
+	}
+	_, ok = s.replayedTxns[r]
+	return !ok
+}
+
+// bind binds the session and the transaction of the request of the replay to the ones of the recorded request it's
+// matched with.
+func (s *sessions) bind(actual, recorded models.MongoRequest) {
+	a, ok := sessionOf(actual)
+	if !ok {
+		return
+	}
+	r, ok := sessionOf(recorded)
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.ids[a.id]; !ok {
+		s.ids[a.id] = r.id
+		s.replayed[r.id] = a.id
+	}
+	if a.txnNumber != 0 && r.txnNumber != 0 {
+		if _, ok := s.txns[a]; !ok {
+			s.txns[a] = r.txnNumber
+			s.replayedTxns[r] = a.txnNumber
+		}
+	}
+}
@@ -0,0 +1,147 @@
+//go:build linux
+
+package mongo
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"go.keploy.io/server/v2/pkg/models"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// the strictness of the fallback for the commands not recorded as they are sent, see config.MongoIntegration
+const (
+	queryMatchExact = "exact"
+	queryMatchShape = "shape"
+)
+
+// masked stands for the values left out of the shapes.
+const masked = "?"
+
+// requestShape returns the shape of the requests: the command, the collection and the database, and the structure
+// of the other fields with their values masked. The requests differing only in the values of their filters, their
+// documents or their options have the same shape, as do the object ids and the dates generated in each run.
+func requestShape(mongoRequests []models.MongoRequest) (string, bool) {
+	var shape strings.Builder
+	for _, req := range mongoRequests {
+		msg, ok := req.Message.(*models.MongoOpMessage)
+		if !ok {
+			return "", false
+		}
+		fmt.Fprintf(&shape, "%d:%d", req.Header.Opcode, msg.FlagBits)
+		for _, section := range msg.Sections {
+			switch {
+			case strings.HasPrefix(section, "{ SectionSingle identifier:"):
+				identifier, msgsStr, err := decodeOpMsgSectionSequence(section)
+				if err != nil {
+					return "", false
+				}
+				// the documents of a sequence are shaped as a set, their number and their order are left out
+				docs := map[string]bool{}
+				for _, msg := range strings.Split(msgsStr, ", ") {
+					var doc bson.D
+					if err := bson.UnmarshalExtJSON([]byte(msg), true, &doc); err != nil {
+						return "", false
+					}
+					docs[valueShape(doc)] = true
+				}
+				fmt.Fprintf(&shape, " %s%s", identifier, setShape(docs))
+			case strings.HasPrefix(section, "{ SectionSingle msg:"):
+				command, err := extractSectionSingle(section)
+				if err != nil {
+					return "", false
+				}
+				var doc bson.D
+				if err := bson.UnmarshalExtJSON([]byte(command), true, &doc); err != nil {
+					return "", false
+				}
+				shape.WriteString(" " + commandShape(doc))
+			default:
+				return "", false
+			}
+		}
+		shape.WriteString(";")
+	}
+	return shape.String(), true
+}
+
+// commandShape returns the shape of the command, its name and its collection, and the database, are kept along with
+// the structure of the other fields. The fields of the sessions are left out, they're matched apart.
+func commandShape(doc bson.D) string {
+	if len(doc) == 0 {
+		return "{}"
+	}
+	fields := []string{}
+	for i, e := range doc {
+		if isSessionField(e.Key) {
+			continue
+		}
+		if s, ok := e.Value.(string); ok && (i == 0 || e.Key == "$db") {
+			fields = append(fields, e.Key+":"+s)
+			continue
+		}
+		if readConcern, ok := e.Value.(bson.D); ok && e.Key == "readConcern" {
+			e.Value = withoutKey(readConcern, "afterClusterTime")
+		}
+		fields = append(fields, e.Key+":"+valueShape(e.Value))
+	}
+	// the command is the first field, the order of the others doesn't matter
+	if len(fields) > 1 {
+		sort.Strings(fields[1:])
+	}
+	return "{" + strings.Join(fields, ",") + "}"
+}
+
+// valueShape returns the structure of the value with the values of its fields masked. The operators of the
+// filters and of the updates are kept since they're the keys of their documents.
+func valueShape(value interface{}) string {
+	switch v := value.(type) {
+	case bson.D:
+		fields := make([]string, 0, len(v))
+		for _, e := range v {
+			fields = append(fields, e.Key+":"+valueShape(e.Value))
+		}
+		sort.Strings(fields)
+		return "{" + strings.Join(fields, ",") + "}"
+	case bson.A:
+		// the lists of values, such as the ones of $in, are shaped by the shapes of their items
+		items := map[string]bool{}
+		for _, item := range v {
+			items[valueShape(item)] = true
+		}
+		return setShape(items)
+	default:
+		return masked
+	}
+}
+
+// setShape returns the shapes in a stable order, each of them once.
+func setShape(shapes map[string]bool) string {
+	items := make([]string, 0, len(shapes))
+	for s := range shapes {
+		items = append(items, s)
+	}
+	sort.Strings(items)
+	return "[" + strings.Join(items, "|") + "]"
+}
+
+func isSessionField(key string) bool {
+	for _, field := range sessionFields {
+		if key == field {
+			return true
+		}
+	}
+	return false
+}
+
+func withoutKey(doc bson.D, key string) bson.D {
+	kept := bson.D{}
+	for _, e := range doc {
+		if e.Key != key {
+			kept = append(kept, e)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,212 @@
+//go:build linux
+
+package mqtt
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations/util"
+	pUtil "go.keploy.io/server/v2/pkg/core/proxy/util"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+// deliveryInterval is how often the mocked broker looks for the messages to deliver to the subscriptions
+const deliveryInterval = 50 * time.Millisecond
+
+// session is the state of a client connection to the mocked broker.
+type session struct {
+	logger     *zap.Logger
+	clientConn net.Conn
+	mockDb     integrations.MockMemDb
+	level      uint8
+
+	// writeMu serializes the acks and the deliveries written to the client
+	writeMu sync.Mutex
+	subMu   sync.Mutex
+	filters []string
+}
+
+// decodeMQTT acts as the broker for the client. The client packets are acknowledged from the mocks with the
+// packet ids of the client, and the messages recorded for the subscriptions of the client are delivered
+// during the test cases they were recorded in.
+func decodeMQTT(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientConn net.Conn, _ *integrations.ConditionalDstCfg, mockDb integrations.MockMemDb, _ models.OutgoingOptions) error {
+	logger.Debug("Into the mqtt parser in test mode")
+	s := &session{
+		logger:     logger,
+		clientConn: clientConn,
+		mockDb:     mockDb,
+	}
+	errCh := make(chan error, 1)
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		defer pUtil.Recover(logger, clientConn, nil)
+		defer close(errCh)
+		client := bufio.NewReader(io.MultiReader(bytes.NewReader(reqBuf), clientConn))
+		for {
+			p, err := readPacket(client, s.level)
+			if err != nil {
+				if err != io.EOF {
+					utils.LogError(logger, err, "failed to read the mqtt packet from the client")
+				}
+				errCh <- err
+				return
+			}
+			if err := s.handle(ctx, p); err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				errCh <- err
+				return
+			}
+		}
+	}()
+
+	go func() {
+		defer pUtil.Recover(logger, clientConn, nil)
+		s.deliver(ctx, done)
+	}()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-errCh:
+		if err == io.EOF {
+			return nil
+		}
+		return err
+	}
+}
+
+func (s *session) handle(ctx context.Context, p *packet) error {
+	switch p.typ {
+	case typePingreq:
+		return s.write(encodePacket(typePingresp<<4, nil))
+	case typePubrec:
+		// the client received a qos 2 delivery, the broker releases it
+		return s.write(encodePacket(typePubrel<<4|0x02, binary.BigEndian.AppendUint16(nil, p.packetID)))
+	case typePuback, typePubcomp, typeDisconnect, typeAuth:
+		return nil
+	case typeConnect:
+		s.level = p.protocolLevel
+	}
+
+	mock, err := match(ctx, p, s.mockDb)
+	if err != nil {
+		utils.LogError(s.logger, err, "error while matching mqtt mocks")
+		return err
+	}
+	if mock == nil {
+		if _, hasAck := p.ack(); !hasAck {
+			s.logger.Debug("no mqtt mock found for the packet without an ack", zap.String("type", typeName(p.typ)), zap.String("topic", p.topic))
+			return nil
+		}
+		err := fmt.Errorf("no mqtt mock found for the %s packet", typeName(p.typ))
+		utils.LogError(s.logger, err, "failed to mock the mqtt packet", zap.String("topic", p.topic), zap.Strings("filters", p.filters))
+		return err
+	}
+
+	for _, resp := range mock.Spec.MQTTResponses {
+		rp, err := decodeRaw(resp.Raw, s.level)
+		if err != nil {
+			utils.LogError(s.logger, err, "failed to decode the recorded mqtt packet")
+			return err
+		}
+		raw := rp.raw
+		if rp.typ != typeConnack {
+			raw = rp.withPacketID(p.packetID)
+		}
+		if err := s.write(raw); err != nil {
+			return err
+		}
+	}
+
+	switch p.typ {
+	case typeSubscribe:
+		s.subMu.Lock()
+		s.filters = append(s.filters, p.filters...)
+		s.subMu.Unlock()
+		// the broker sends the retained messages of the topics right after the subscription
+		for _, raw := range retained(s.mockDb, p.filters) {
+			if err := s.write(raw); err != nil {
+				return err
+			}
+		}
+	case typeUnsubscribe:
+		s.subMu.Lock()
+		for _, f := range p.filters {
+			for i, sub := range s.filters {
+				if sub == f {
+					s.filters = append(s.filters[:i], s.filters[i+1:]...)
+					break
+				}
+			}
+		}
+		s.subMu.Unlock()
+	}
+	return nil
+}
+
+// deliver writes the messages recorded for the subscriptions of the client during the current test case.
+func (s *session) deliver(ctx context.Context, done <-chan struct{}) {
+	ticker := time.NewTicker(deliveryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+
+		s.subMu.Lock()
+		filters := append([]string(nil), s.filters...)
+		s.subMu.Unlock()
+		if len(filters) == 0 {
+			continue
+		}
+
+		deliveries, err := deliveries(s.mockDb, filters)
+		if err != nil {
+			utils.LogError(s.logger, err, "failed to get the mqtt deliveries")
+			return
+		}
+		for _, raw := range deliveries {
+			if err := s.write(raw); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (s *session) write(raw []byte) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	_, err := s.clientConn.Write(raw)
+	if err != nil {
+		utils.LogError(s.logger, err, "failed to write the mqtt packet to the client application")
+	}
+	return err
+}
+
+// decodeRaw decodes a recorded packet.
+func decodeRaw(raw string, level uint8) (*packet, error) {
+	data, err := util.DecodeBase64(raw)
+	if err != nil {
+		return nil, err
+	}
+	return readPacket(bufio.NewReader(bytes.NewReader(data)), level)
+}
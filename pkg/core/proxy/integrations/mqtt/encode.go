@@ -0,0 +1,180 @@
+//go:build linux
+
+package mqtt
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	pUtil "go.keploy.io/server/v2/pkg/core/proxy/util"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+// pendingKey identifies the ack the broker is expected to send for a client packet.
+type pendingKey struct {
+	typ      byte
+	packetID uint16
+}
+
+// pendingPacket is a client packet forwarded to the broker which is waiting for its ack.
+type pendingPacket struct {
+	packet           *packet
+	reqTimestampMock time.Time
+}
+
+// encodeMQTT forwards the packets between the client and the broker. Every client packet is recorded with
+// its ack, paired by the packet id since the client can have several packets in flight. The messages the
+// broker delivers for the subscriptions are recorded as mocks without a request. The pings are only
+// forwarded, they are answered without a mock while testing.
+func encodeMQTT(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientConn, destConn net.Conn, mocks chan<- *models.Mock, _ models.OutgoingOptions) error {
+	g, ok := ctx.Value(models.ErrGroupKey).(*errgroup.Group)
+	if !ok {
+		return errors.New("failed to get the error group from the context")
+	}
+	connID, _ := ctx.Value(models.ClientConnectionIDKey).(string)
+
+	var (
+		mu      sync.Mutex
+		pending = make(map[pendingKey]pendingPacket)
+		level   atomic.Uint32
+	)
+	errCh := make(chan error, 2)
+
+	// Forward the packets from the client to the destination server
+	g.Go(func() error {
+		defer pUtil.Recover(logger, clientConn, destConn)
+		client := bufio.NewReader(io.MultiReader(bytes.NewReader(reqBuf), clientConn))
+		for {
+			p, err := readPacket(client, uint8(level.Load()))
+			if err != nil {
+				if err != io.EOF {
+					utils.LogError(logger, err, "failed to read the mqtt packet from the client")
+				}
+				errCh <- err
+				return nil
+			}
+			logger.Debug("mqtt packet from the client", zap.String("type", typeName(p.typ)), zap.Uint16("packetID", p.packetID), zap.String("topic", p.topic))
+			if p.typ == typeConnect {
+				level.Store(uint32(p.protocolLevel))
+			}
+
+			reqTimestampMock := time.Now()
+			ackType, hasAck := p.ack()
+			if hasAck && p.typ != typePingreq {
+				// register the packet before forwarding it, so that the ack can't arrive first
+				mu.Lock()
+				pending[pendingKey{typ: ackType, packetID: p.packetID}] = pendingPacket{packet: p, reqTimestampMock: reqTimestampMock}
+				mu.Unlock()
+			}
+
+			_, err = destConn.Write(p.raw)
+			if err != nil {
+				utils.LogError(logger, err, "failed to write the mqtt packet to the destination server")
+				errCh <- err
+				return nil
+			}
+
+			// the acks of the delivered messages and the disconnects need no mock, the qos 0 messages are
+			// recorded without a response
+			if p.typ == typePublish && !hasAck {
+				saveMock([]*packet{p}, nil, reqTimestampMock, time.Now(), connID, mocks)
+			}
+		}
+	})
+
+	// Forward the packets from the destination server to the client
+	g.Go(func() error {
+		defer pUtil.Recover(logger, clientConn, destConn)
+		server := bufio.NewReader(destConn)
+		for {
+			p, err := readPacket(server, uint8(level.Load()))
+			if err != nil {
+				if err != io.EOF {
+					utils.LogError(logger, err, "failed to read the mqtt packet from the destination server")
+				}
+				errCh <- err
+				return nil
+			}
+
+			_, err = clientConn.Write(p.raw)
+			if err != nil {
+				utils.LogError(logger, err, "failed to write the mqtt packet to the client")
+				errCh <- err
+				return nil
+			}
+			resTimestampMock := time.Now()
+			logger.Debug("mqtt packet from the server", zap.String("type", typeName(p.typ)), zap.Uint16("packetID", p.packetID), zap.String("topic", p.topic))
+
+			switch p.typ {
+			case typePublish:
+				saveMock(nil, []*packet{p}, resTimestampMock, resTimestampMock, connID, mocks)
+			case typeConnack, typePuback, typePubrec, typePubcomp, typeSuback, typeUnsuback:
+				key := pendingKey{typ: p.typ, packetID: p.packetID}
+				mu.Lock()
+				req, ok := pending[key]
+				delete(pending, key)
+				mu.Unlock()
+				if !ok {
+					logger.Debug("no pending mqtt packet for the ack", zap.String("type", typeName(p.typ)), zap.Uint16("packetID", p.packetID))
+					continue
+				}
+				saveMock([]*packet{req.packet}, []*packet{p}, req.reqTimestampMock, resTimestampMock, connID, mocks)
+			}
+		}
+	})
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-errCh:
+		if err == io.EOF {
+			return nil
+		}
+		return err
+	}
+}
+
+// saveMock records the client packets with the broker packets sent for them. The deliveries of the
+// subscriptions are recorded with the broker packet only.
+func saveMock(requests, responses []*packet, reqTimestampMock, resTimestampMock time.Time, connID string, mocks chan<- *models.Mock) {
+	metadata := make(map[string]string)
+	if len(requests) > 0 {
+		switch requests[0].typ {
+		case typeConnect, typeSubscribe, typeUnsubscribe:
+			metadata["type"] = "config"
+		}
+	}
+
+	var reqs, resps []models.MQTTPacket
+	for _, p := range requests {
+		reqs = append(reqs, p.model())
+	}
+	for _, p := range responses {
+		resps = append(resps, p.model())
+	}
+
+	mocks <- &models.Mock{
+		Version: models.GetVersion(),
+		Name:    "mocks",
+		Kind:    models.MQTT,
+		Spec: models.MockSpec{
+			Metadata:         metadata,
+			MQTTRequests:     reqs,
+			MQTTResponses:    resps,
+			ReqTimestampMock: reqTimestampMock,
+			ResTimestampMock: resTimestampMock,
+		},
+		ConnectionID: connID,
+	}
+}
@@ -0,0 +1,183 @@
+//go:build linux
+
+package mqtt
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations/util"
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+// match finds the mock of the client packet among the mocks of the same packet type and topics. The mocks
+// recorded during the current test case are preferred, and the packet closest to the client packet wins.
+// The connection setup is matched as many times as the client reconnects, the other mocks are moved
+// behind the rest once matched.
+func match(ctx context.Context, p *packet, mockDb integrations.MockMemDb) (*models.Mock, error) {
+	for {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		mocks, err := mockDb.GetUnFilteredMocksByKey(models.MQTT, "")
+		if err != nil {
+			return nil, fmt.Errorf("error while getting unfiltered mocks %v", err)
+		}
+
+		var filteredMocks, unfilteredMocks []*models.Mock
+		for _, mock := range mocks {
+			if mock.Kind != models.MQTT || len(mock.Spec.MQTTRequests) == 0 || !samePacket(mock.Spec.MQTTRequests[0], p) {
+				continue
+			}
+			if mock.TestModeInfo.IsFiltered {
+				filteredMocks = append(filteredMocks, mock)
+			} else {
+				unfilteredMocks = append(unfilteredMocks, mock)
+			}
+		}
+
+		mock := closest(filteredMocks, p)
+		if mock == nil {
+			mock = closest(unfilteredMocks, p)
+		}
+		if mock == nil || mock.Spec.Metadata["type"] == "config" {
+			return mock, nil
+		}
+
+		originalMock := *mock
+		mock.TestModeInfo.IsFiltered = false
+		mock.TestModeInfo.SortOrder = math.MaxInt64
+		if !mockDb.UpdateUnFilteredMock(&originalMock, mock) {
+			// the mock was used by another packet in the meantime
+			continue
+		}
+		return mock, nil
+	}
+}
+
+// samePacket reports whether the recorded packet has the type of the client packet and is about the same topics.
+func samePacket(recorded models.MQTTPacket, p *packet) bool {
+	if recorded.Type != typeName(p.typ) {
+		return false
+	}
+	switch p.typ {
+	case typePublish:
+		return recorded.Topic == p.topic && recorded.QoS == p.qos
+	case typeSubscribe, typeUnsubscribe:
+		return slices.Equal(recorded.Filters, p.filters)
+	}
+	return true
+}
+
+// closest returns the first mock with the same packet body as the client packet, or else the one with the
+// most similar body. The packet ids are part of the bodies, so the exact matches are rare for the acked packets.
+func closest(mocks []*models.Mock, p *packet) *models.Mock {
+	var best *models.Mock
+	bestSim := -1.0
+	for _, mock := range mocks {
+		raw, err := util.DecodeBase64(mock.Spec.MQTTRequests[0].Raw)
+		if err != nil {
+			continue
+		}
+		if bytes.Equal(raw, p.raw) {
+			return mock
+		}
+		k := util.AdaptiveK(len(p.raw), 3, 8, 5)
+		if sim := util.JaccardSimilarity(util.CreateShingles(raw, k), util.CreateShingles(p.raw, k)); sim > bestSim {
+			best, bestSim = mock, sim
+		}
+	}
+	return best
+}
+
+// isDelivery reports whether the mock is a message the broker delivered for a subscription.
+func isDelivery(mock *models.Mock) bool {
+	return mock.Kind == models.MQTT && len(mock.Spec.MQTTRequests) == 0 && len(mock.Spec.MQTTResponses) > 0 &&
+		mock.Spec.MQTTResponses[0].Type == typeName(typePublish)
+}
+
+func subscribed(filters []string, topic string) bool {
+	for _, f := range filters {
+		if topicMatches(f, topic) {
+			return true
+		}
+	}
+	return false
+}
+
+// retained returns the latest retained message recorded for every topic matching the filters. The retained
+// messages are sent on every subscription, so their mocks aren't used up.
+func retained(mockDb integrations.MockMemDb, filters []string) [][]byte {
+	mocks, err := mockDb.GetUnFilteredMocksByKey(models.MQTT, "")
+	if err != nil {
+		return nil
+	}
+	latest := make(map[string]*models.Mock)
+	var topics []string
+	for _, mock := range mocks {
+		if !isDelivery(mock) || !mock.Spec.MQTTResponses[0].Retain {
+			continue
+		}
+		topic := mock.Spec.MQTTResponses[0].Topic
+		if !subscribed(filters, topic) {
+			continue
+		}
+		prev, ok := latest[topic]
+		if !ok {
+			topics = append(topics, topic)
+		}
+		if !ok || mock.Spec.ResTimestampMock.After(prev.Spec.ResTimestampMock) {
+			latest[topic] = mock
+		}
+	}
+	sort.Strings(topics)
+
+	var res [][]byte
+	for _, topic := range topics {
+		if raw, err := util.DecodeBase64(latest[topic].Spec.MQTTResponses[0].Raw); err == nil {
+			res = append(res, raw)
+		}
+	}
+	return res
+}
+
+// deliveries uses up the messages recorded during the current test case for the topics matching the
+// filters, and returns them in the order they were delivered.
+func deliveries(mockDb integrations.MockMemDb, filters []string) ([][]byte, error) {
+	mocks, err := mockDb.GetUnFilteredMocksByKey(models.MQTT, "")
+	if err != nil {
+		return nil, fmt.Errorf("error while getting unfiltered mocks %v", err)
+	}
+	var pending []*models.Mock
+	for _, mock := range mocks {
+		if mock.TestModeInfo.IsFiltered && isDelivery(mock) && !mock.Spec.MQTTResponses[0].Retain &&
+			subscribed(filters, mock.Spec.MQTTResponses[0].Topic) {
+			pending = append(pending, mock)
+		}
+	}
+	sort.SliceStable(pending, func(i, j int) bool {
+		return pending[i].Spec.ResTimestampMock.Before(pending[j].Spec.ResTimestampMock)
+	})
+
+	var res [][]byte
+	for _, mock := range pending {
+		raw, err := util.DecodeBase64(mock.Spec.MQTTResponses[0].Raw)
+		if err != nil {
+			continue
+		}
+		originalMock := *mock
+		mock.TestModeInfo.IsFiltered = false
+		mock.TestModeInfo.SortOrder = math.MaxInt64
+		if !mockDb.UpdateUnFilteredMock(&originalMock, mock) {
+			// delivered on another connection of the same subscription
+			continue
+		}
+		res = append(res, raw)
+	}
+	return res, nil
+}
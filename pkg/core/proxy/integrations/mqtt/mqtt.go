@@ -0,0 +1,91 @@
+//go:build linux
+
+// Package mqtt provides the integration for the mqtt 3.1, 3.1.1 and 5.0 protocols.
+package mqtt
+
+import (
+	"bytes"
+	"context"
+	"net"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	"go.keploy.io/server/v2/pkg/core/proxy/util"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+func init() {
+	integrations.Register("mqtt", NewMQTT)
+}
+
+type MQTT struct {
+	logger *zap.Logger
+}
+
+func NewMQTT(logger *zap.Logger) integrations.Integrations {
+	return &MQTT{
+		logger: logger,
+	}
+}
+
+// MatchType checks for the CONNECT packet, the client must send it first on a connection. Its variable
+// header starts with the protocol name.
+func (m *MQTT) MatchType(_ context.Context, buf []byte) integrations.MatchResult {
+	if len(buf) == 0 {
+		return integrations.MatchResult{NeedBytes: 1}
+	}
+	if buf[0] != typeConnect<<4 {
+		return integrations.MatchResult{}
+	}
+	// skip the remaining length, it takes up to four bytes
+	i := 1
+	for i < len(buf) && buf[i]&0x80 != 0 {
+		i++
+		if i > 4 {
+			return integrations.MatchResult{}
+		}
+	}
+	if i+9 > len(buf) {
+		return integrations.MatchResult{Confidence: integrations.ConfidenceLow, NeedBytes: i + 9}
+	}
+	name := buf[i+1:]
+	if bytes.HasPrefix(name, []byte("\x00\x04MQTT")) || bytes.HasPrefix(name, []byte("\x00\x06MQIsdp")) {
+		return integrations.MatchResult{Confidence: integrations.ConfidenceCertain}
+	}
+	return integrations.MatchResult{}
+}
+
+func (m *MQTT) RecordOutgoing(ctx context.Context, src net.Conn, dst net.Conn, mocks chan<- *models.Mock, opts models.OutgoingOptions) error {
+	logger := m.logger.With(zap.Any("Client IP Address", src.RemoteAddr().String()), zap.Any("Client ConnectionID", ctx.Value(models.ClientConnectionIDKey).(string)), zap.Any("Destination ConnectionID", ctx.Value(models.DestConnectionIDKey).(string)))
+
+	reqBuf, err := util.ReadInitialBuf(ctx, logger, src)
+	if err != nil {
+		utils.LogError(logger, err, "failed to read the initial mqtt message")
+		return err
+	}
+
+	err = encodeMQTT(ctx, logger, reqBuf, src, dst, mocks, opts)
+	if err != nil {
+		utils.LogError(logger, err, "failed to encode the mqtt message into the yaml")
+		return err
+	}
+	return nil
+}
+
+func (m *MQTT) MockOutgoing(ctx context.Context, src net.Conn, dstCfg *integrations.ConditionalDstCfg, mockDb integrations.MockMemDb, opts models.OutgoingOptions) error {
+	logger := m.logger.With(zap.Any("Client IP Address", src.RemoteAddr().String()), zap.Any("Client ConnectionID", ctx.Value(models.ClientConnectionIDKey).(string)), zap.Any("Destination ConnectionID", ctx.Value(models.DestConnectionIDKey).(string)))
+
+	reqBuf, err := util.ReadInitialBuf(ctx, logger, src)
+	if err != nil {
+		utils.LogError(logger, err, "failed to read the initial mqtt message")
+		return err
+	}
+
+	err = decodeMQTT(ctx, logger, reqBuf, src, dstCfg, mockDb, opts)
+	if err != nil {
+		utils.LogError(logger, err, "failed to decode the mqtt message")
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,316 @@
+//go:build linux
+
+package mqtt
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations/util"
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+// control packet types
+const (
+	typeConnect     byte = 1
+	typeConnack     byte = 2
+	typePublish     byte = 3
+	typePuback      byte = 4
+	typePubrec      byte = 5
+	typePubrel      byte = 6
+	typePubcomp     byte = 7
+	typeSubscribe   byte = 8
+	typeSuback      byte = 9
+	typeUnsubscribe byte = 10
+	typeUnsuback    byte = 11
+	typePingreq     byte = 12
+	typePingresp    byte = 13
+	typeDisconnect  byte = 14
+	typeAuth        byte = 15
+)
+
+const (
+	// maxPacketSize is the largest remaining length which can be encoded in a packet
+	maxPacketSize = 268435455
+	// protocolLevel5 is the protocol level of mqtt 5.0, the packets have properties since this level
+	protocolLevel5 = 5
+)
+
+var errMalformed = errors.New("malformed mqtt packet")
+
+var typeNames = map[byte]string{
+	typeConnect:     "CONNECT",
+	typeConnack:     "CONNACK",
+	typePublish:     "PUBLISH",
+	typePuback:      "PUBACK",
+	typePubrec:      "PUBREC",
+	typePubrel:      "PUBREL",
+	typePubcomp:     "PUBCOMP",
+	typeSubscribe:   "SUBSCRIBE",
+	typeSuback:      "SUBACK",
+	typeUnsubscribe: "UNSUBSCRIBE",
+	typeUnsuback:    "UNSUBACK",
+	typePingreq:     "PINGREQ",
+	typePingresp:    "PINGRESP",
+	typeDisconnect:  "DISCONNECT",
+	typeAuth:        "AUTH",
+}
+
+func typeName(t byte) string {
+	if name, ok := typeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("TYPE%d", t)
+}
+
+// packet is an mqtt control packet along with the fields decoded from it.
+type packet struct {
+	typ   byte
+	flags byte
+	body  []byte
+	raw   []byte
+
+	packetID      uint16
+	clientID      string
+	protocolLevel uint8
+	topic         string
+	qos           uint8
+	retain        bool
+	filters       []string
+}
+
+func (p *packet) model() models.MQTTPacket {
+	return models.MQTTPacket{
+		Type:          typeName(p.typ),
+		PacketID:      p.packetID,
+		ClientID:      p.clientID,
+		ProtocolLevel: p.protocolLevel,
+		Topic:         p.topic,
+		QoS:           p.qos,
+		Retain:        p.retain,
+		Filters:       p.filters,
+		Raw:           util.EncodeBase64(p.raw),
+	}
+}
+
+// ack returns the type of the packet the broker acknowledges the client packet with, and whether the ack
+// carries the packet id of the client packet. The packets without an ack return false.
+func (p *packet) ack() (byte, bool) {
+	switch p.typ {
+	case typeConnect:
+		return typeConnack, true
+	case typePublish:
+		switch p.qos {
+		case 1:
+			return typePuback, true
+		case 2:
+			return typePubrec, true
+		}
+	case typePubrel:
+		return typePubcomp, true
+	case typeSubscribe:
+		return typeSuback, true
+	case typeUnsubscribe:
+		return typeUnsuback, true
+	case typePingreq:
+		return typePingresp, true
+	}
+	return 0, false
+}
+
+// readPacket reads a control packet, level is the protocol level of the connection which decides whether
+// the packets have properties.
+func readPacket(r *bufio.Reader, level uint8) (*packet, error) {
+	first, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	raw := []byte{first}
+	length, multiplier := 0, 1
+	for i := 0; ; i++ {
+		if i == 4 {
+			return nil, errMalformed
+		}
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		raw = append(raw, b)
+		length += int(b&0x7f) * multiplier
+		if b&0x80 == 0 {
+			break
+		}
+		multiplier *= 128
+	}
+	if length > maxPacketSize {
+		return nil, errMalformed
+	}
+	header := len(raw)
+	raw = append(raw, make([]byte, length)...)
+	if _, err := io.ReadFull(r, raw[header:]); err != nil {
+		return nil, err
+	}
+
+	p := &packet{typ: first >> 4, flags: first & 0x0f, body: raw[header:], raw: raw}
+	// the decoded fields are only used for matching, a packet which can't be decoded is still forwarded
+	_ = p.decode(level)
+	return p, nil
+}
+
+func (p *packet) decode(level uint8) error {
+	d := &decoder{buf: p.body}
+	switch p.typ {
+	case typeConnect:
+		d.string() // protocol name
+		p.protocolLevel = d.uint8()
+		d.uint8()  // connect flags
+		d.uint16() // keep alive
+		if p.protocolLevel >= protocolLevel5 {
+			d.properties()
+		}
+		p.clientID = d.string()
+	case typePublish:
+		p.qos = (p.flags >> 1) & 0x03
+		p.retain = p.flags&0x01 == 1
+		p.topic = d.string()
+		if p.qos > 0 {
+			p.packetID = d.uint16()
+		}
+	case typeSubscribe, typeUnsubscribe:
+		p.packetID = d.uint16()
+		if level >= protocolLevel5 {
+			d.properties()
+		}
+		for d.err == nil && d.off < len(d.buf) {
+			p.filters = append(p.filters, d.string())
+			if p.typ == typeSubscribe {
+				d.uint8() // subscription options
+			}
+		}
+	case typePuback, typePubrec, typePubrel, typePubcomp, typeSuback, typeUnsuback:
+		p.packetID = d.uint16()
+	}
+	return d.err
+}
+
+// withPacketID returns the raw packet with its packet id replaced, the packet id follows the fixed header
+// in the acks.
+func (p *packet) withPacketID(id uint16) []byte {
+	raw := make([]byte, len(p.raw))
+	copy(raw, p.raw)
+	header := len(p.raw) - len(p.body)
+	if len(p.body) >= 2 {
+		binary.BigEndian.PutUint16(raw[header:header+2], id)
+	}
+	return raw
+}
+
+// encodePacket builds a packet with the given fixed header byte and body.
+func encodePacket(first byte, body []byte) []byte {
+	raw := []byte{first}
+	n := len(body)
+	for {
+		b := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			b |= 0x80
+		}
+		raw = append(raw, b)
+		if n == 0 {
+			break
+		}
+	}
+	return append(raw, body...)
+}
+
+// topicMatches reports whether the topic name matches the topic filter, the shared subscriptions match on
+// the filter after their group.
+func topicMatches(filter, topic string) bool {
+	if strings.HasPrefix(filter, "$share/") {
+		parts := strings.SplitN(filter, "/", 3)
+		if len(parts) < 3 {
+			return false
+		}
+		filter = parts[2]
+	}
+	// the wildcards don't match the topics starting with $
+	if strings.HasPrefix(topic, "$") && !strings.HasPrefix(filter, "$") {
+		return false
+	}
+	fl := strings.Split(filter, "/")
+	tl := strings.Split(topic, "/")
+	for i, f := range fl {
+		if f == "#" {
+			return true
+		}
+		if i >= len(tl) {
+			return false
+		}
+		if f != "+" && f != tl[i] {
+			return false
+		}
+	}
+	return len(fl) == len(tl)
+}
+
+type decoder struct {
+	buf []byte
+	off int
+	err error
+}
+
+func (d *decoder) next(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n < 0 || d.off+n > len(d.buf) {
+		d.err = errMalformed
+		return nil
+	}
+	b := d.buf[d.off : d.off+n]
+	d.off += n
+	return b
+}
+
+func (d *decoder) uint8() uint8 {
+	b := d.next(1)
+	if b == nil {
+		return 0
+	}
+	return b[0]
+}
+
+func (d *decoder) uint16() uint16 {
+	b := d.next(2)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint16(b)
+}
+
+func (d *decoder) string() string {
+	return string(d.next(int(d.uint16())))
+}
+
+// properties skips the properties of an mqtt 5.0 packet.
+func (d *decoder) properties() {
+	n, err := binary.ReadUvarint(d)
+	if err != nil {
+		d.err = errMalformed
+		return
+	}
+	d.next(int(n))
+}
+
+// ReadByte lets the variable byte integers be read with binary.ReadUvarint, they have the same encoding.
+func (d *decoder) ReadByte() (byte, error) {
+	b := d.next(1)
+	if b == nil {
+		return 0, d.err
+	}
+	return b[0], nil
+}
@@ -0,0 +1,143 @@
+//go:build linux
+
+package mqtt
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func mqttString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
+
+func TestPacketRoundTrip(t *testing.T) {
+	connect := func(level uint8) []byte {
+		b := mqttString(nil, "MQTT")
+		b = append(b, level, 0x02, 0, 60) // clean session, keep alive
+		if level >= protocolLevel5 {
+			b = append(b, 3, 0x21, 0, 10) // receive maximum
+		}
+		return encodePacket(typeConnect<<4, mqttString(b, "sensor-1"))
+	}
+	publish := func(payload string) []byte {
+		b := mqttString(nil, "home/kitchen/temp")
+		b = binary.BigEndian.AppendUint16(b, 9)
+		return encodePacket(typePublish<<4|0x03, append(b, payload...)) // qos 1, retained
+	}
+	subscribe := func() []byte {
+		b := binary.BigEndian.AppendUint16(nil, 4)
+		b = append(b, 0) // no properties
+		b = append(mqttString(b, "home/+/temp"), 1)
+		b = append(mqttString(b, "alerts/#"), 0)
+		return encodePacket(typeSubscribe<<4|0x02, b)
+	}
+	tests := []struct {
+		name  string
+		raw   []byte
+		level uint8
+		want  packet
+	}{
+		{
+			name: "connect of mqtt 3.1.1",
+			raw:  connect(4),
+			want: packet{typ: typeConnect, clientID: "sensor-1", protocolLevel: 4},
+		},
+		{
+			name:  "connect of mqtt 5.0 with properties",
+			raw:   connect(protocolLevel5),
+			level: protocolLevel5,
+			want:  packet{typ: typeConnect, clientID: "sensor-1", protocolLevel: protocolLevel5},
+		},
+		{
+			name: "publish of qos 1",
+			raw:  publish("21.5"),
+			want: packet{typ: typePublish, flags: 0x03, packetID: 9, topic: "home/kitchen/temp", qos: 1, retain: true},
+		},
+		{
+			name: "publish of a remaining length over a byte",
+			raw:  publish(strings.Repeat("x", 20000)),
+			want: packet{typ: typePublish, flags: 0x03, packetID: 9, topic: "home/kitchen/temp", qos: 1, retain: true},
+		},
+		{
+			name:  "subscribe of mqtt 5.0",
+			raw:   subscribe(),
+			level: protocolLevel5,
+			want:  packet{typ: typeSubscribe, flags: 0x02, packetID: 4, filters: []string{"home/+/temp", "alerts/#"}},
+		},
+		{
+			name: "puback",
+			raw:  encodePacket(typePuback<<4, []byte{0, 9}),
+			want: packet{typ: typePuback, packetID: 9},
+		},
+		{
+			name: "pingreq",
+			raw:  encodePacket(typePingreq<<4, nil),
+			want: packet{typ: typePingreq},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := readPacket(bufio.NewReader(bytes.NewReader(tt.raw)), tt.level)
+			if err != nil {
+				t.Fatalf("readPacket() failed: %v", err)
+			}
+			if !bytes.Equal(p.raw, tt.raw) {
+				t.Errorf("readPacket() raw = % x, want % x", p.raw, tt.raw)
+			}
+			if !bytes.Equal(encodePacket(p.typ<<4|p.flags, p.body), tt.raw) {
+				t.Error("encodePacket() of the body read doesn't give back the packet")
+			}
+			got := *p
+			got.body, got.raw = nil, nil
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("readPacket() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithPacketID(t *testing.T) {
+	p, err := readPacket(bufio.NewReader(bytes.NewReader(encodePacket(typeSuback<<4, []byte{0, 4, 1}))), 4)
+	if err != nil {
+		t.Fatalf("readPacket() failed: %v", err)
+	}
+	want := encodePacket(typeSuback<<4, []byte{0x12, 0x34, 1})
+	if got := p.withPacketID(0x1234); !bytes.Equal(got, want) {
+		t.Errorf("withPacketID() = % x, want % x", got, want)
+	}
+}
+
+func TestReadPacketMalformedLength(t *testing.T) {
+	raw := []byte{typePublish << 4, 0xff, 0xff, 0xff, 0xff, 0x01}
+	if _, err := readPacket(bufio.NewReader(bytes.NewReader(raw)), 4); err != errMalformed {
+		t.Errorf("readPacket() of a remaining length over 4 bytes = %v, want %v", err, errMalformed)
+	}
+}
+
+func TestTopicMatches(t *testing.T) {
+	tests := []struct {
+		filter, topic string
+		want          bool
+	}{
+		{"home/kitchen/temp", "home/kitchen/temp", true},
+		{"home/+/temp", "home/kitchen/temp", true},
+		{"home/+/temp", "home/kitchen/humidity", false},
+		{"home/#", "home/kitchen/temp", true},
+		{"home/#", "home", true},
+		{"home/+", "home/kitchen/temp", false},
+		{"#", "$SYS/uptime", false},
+		{"$SYS/#", "$SYS/uptime", true},
+		{"$share/group/home/+/temp", "home/kitchen/temp", true},
+	}
+	for _, tt := range tests {
+		if got := topicMatches(tt.filter, tt.topic); got != tt.want {
+			t.Errorf("topicMatches(%q, %q) = %v, want %v", tt.filter, tt.topic, got, tt.want)
+		}
+	}
+}
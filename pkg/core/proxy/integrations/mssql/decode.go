@@ -0,0 +1,95 @@
+//go:build linux
+
+package mssql
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations/util"
+	pUtil "go.keploy.io/server/v2/pkg/core/proxy/util"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+// decodeMSSQL answers the requests of the client from the mocks. The recorded PRELOGIN responses don't
+// offer the encryption, so the client continues with the unencrypted login which is answered from the
+// LOGIN7 mocks.
+func decodeMSSQL(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientConn net.Conn, _ *integrations.ConditionalDstCfg, mockDb integrations.MockMemDb, _ models.OutgoingOptions) error {
+	logger.Debug("Into the mssql parser in test mode")
+	errCh := make(chan error, 1)
+
+	go func() {
+		defer pUtil.Recover(logger, clientConn, nil)
+		defer close(errCh)
+		client := bufio.NewReader(io.MultiReader(bytes.NewReader(reqBuf), clientConn))
+		for {
+			req, err := readMessage(client)
+			if err != nil {
+				if err != io.EOF {
+					utils.LogError(logger, err, "failed to read the tds message from the client")
+				}
+				errCh <- err
+				return
+			}
+			req.decode()
+
+			switch req.typ {
+			case packetAttention:
+				// the replayed responses are complete, there is nothing left to cancel
+				continue
+			case packetPrelogin:
+				if enc := req.encryption(); enc == encryptOn || enc == encryptReq {
+					logger.Warn("the mssql client requires an encrypted connection, which can't be mocked. Disable the encryption in the connection string of the application (e.g. Encrypt=False or encrypt=disable)")
+				}
+			}
+
+			mock, err := match(ctx, req, mockDb)
+			if err != nil {
+				utils.LogError(logger, err, "error while matching mssql mocks")
+				errCh <- err
+				return
+			}
+			if mock == nil {
+				err := fmt.Errorf("no mssql mock found for the %s request", packetName(req.typ))
+				utils.LogError(logger, err, "failed to mock the mssql request", zap.String("query", req.query), zap.String("procedure", req.procedure), zap.Strings("params", req.params))
+				errCh <- err
+				return
+			}
+
+			for _, resp := range mock.Spec.MSSQLResponses {
+				raw, err := util.DecodeBase64(resp.Raw)
+				if err != nil {
+					utils.LogError(logger, err, "failed to decode the base64 tds response")
+					errCh <- err
+					return
+				}
+				_, err = clientConn.Write(raw)
+				if err != nil {
+					if ctx.Err() != nil {
+						return
+					}
+					utils.LogError(logger, err, "failed to write the tds response to the client application")
+					errCh <- err
+					return
+				}
+			}
+		}
+	}()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-errCh:
+		if err == io.EOF {
+			return nil
+		}
+		return err
+	}
+}
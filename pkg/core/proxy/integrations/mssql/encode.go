@@ -0,0 +1,175 @@
+//go:build linux
+
+package mssql
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	pUtil "go.keploy.io/server/v2/pkg/core/proxy/util"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+// pendingRequest is a request forwarded to the server which is waiting for its response.
+type pendingRequest struct {
+	req              *message
+	reqTimestampMock time.Time
+}
+
+// encodeMSSQL forwards the messages between the client and the server and records every request with its
+// response as a mock. Without MARS the server answers the requests in order, so the responses are paired
+// with the oldest pending request. The PRELOGIN exchange is rewritten so that the connection isn't
+// encrypted, which requires a client and a server accepting unencrypted connections.
+func encodeMSSQL(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientConn, destConn net.Conn, mocks chan<- *models.Mock, _ models.OutgoingOptions) error {
+	g, ok := ctx.Value(models.ErrGroupKey).(*errgroup.Group)
+	if !ok {
+		return errors.New("failed to get the error group from the context")
+	}
+	connID, _ := ctx.Value(models.ClientConnectionIDKey).(string)
+
+	var (
+		mu      sync.Mutex
+		pending []pendingRequest
+	)
+	errCh := make(chan error, 2)
+
+	// Forward the requests from the client to the destination server
+	g.Go(func() error {
+		defer pUtil.Recover(logger, clientConn, destConn)
+		client := bufio.NewReader(io.MultiReader(bytes.NewReader(reqBuf), clientConn))
+		for {
+			req, err := readMessage(client)
+			if err != nil {
+				if err != io.EOF {
+					utils.LogError(logger, err, "failed to read the tds message from the client")
+				}
+				errCh <- err
+				return nil
+			}
+			req.decode()
+			logger.Debug("mssql request", zap.String("type", packetName(req.typ)), zap.String("query", req.query), zap.String("procedure", req.procedure))
+
+			if req.typ == packetPrelogin {
+				if enc := req.encryption(); enc == encryptOn || enc == encryptReq {
+					logger.Warn("the mssql client requires an encrypted connection, which can't be recorded. Disable the encryption in the connection string of the application (e.g. Encrypt=False or encrypt=disable)")
+				}
+				if err := req.disableEncryption(); err != nil {
+					utils.LogError(logger, err, "failed to parse the prelogin message of the client")
+				}
+			}
+
+			reqTimestampMock := time.Now()
+			// an attention cancels the running request, the server acknowledges it in the response of that request
+			if req.typ != packetAttention {
+				// register the request before forwarding it, so that the response can't arrive first
+				mu.Lock()
+				pending = append(pending, pendingRequest{req: req, reqTimestampMock: reqTimestampMock})
+				mu.Unlock()
+			}
+
+			_, err = destConn.Write(req.raw)
+			if err != nil {
+				utils.LogError(logger, err, "failed to write the tds message to the destination server")
+				errCh <- err
+				return nil
+			}
+		}
+	})
+
+	// Forward the responses from the destination server to the client
+	g.Go(func() error {
+		defer pUtil.Recover(logger, clientConn, destConn)
+		server := bufio.NewReader(destConn)
+		for {
+			resp, err := readMessage(server)
+			if err != nil {
+				if err != io.EOF {
+					utils.LogError(logger, err, "failed to read the tds message from the destination server")
+				}
+				errCh <- err
+				return nil
+			}
+
+			mu.Lock()
+			var p pendingRequest
+			if len(pending) > 0 {
+				p = pending[0]
+				pending = pending[1:]
+			}
+			mu.Unlock()
+
+			if p.req != nil && p.req.typ == packetPrelogin {
+				if resp.encryption() == encryptReq {
+					logger.Error("the mssql server requires an encrypted connection, which can't be recorded. Allow unencrypted connections on the server, or exclude its port from the mssql integration")
+				}
+				if err := resp.disableEncryption(); err != nil {
+					utils.LogError(logger, err, "failed to parse the prelogin response of the server")
+				}
+			}
+
+			_, err = clientConn.Write(resp.raw)
+			if err != nil {
+				utils.LogError(logger, err, "failed to write the tds message to the client")
+				errCh <- err
+				return nil
+			}
+			resTimestampMock := time.Now()
+
+			if p.req == nil {
+				logger.Debug("no pending mssql request for the response")
+				continue
+			}
+			saveMock(p.req, resp, p.reqTimestampMock, resTimestampMock, connID, mocks)
+		}
+	})
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-errCh:
+		if err == io.EOF {
+			return nil
+		}
+		return err
+	}
+}
+
+func saveMock(req, resp *message, reqTimestampMock, resTimestampMock time.Time, connID string, mocks chan<- *models.Mock) {
+	// the request was forwarded, so the recorded copy can be masked
+	req.maskPassword()
+
+	metadata := map[string]string{
+		"operation": packetName(req.typ),
+	}
+	switch req.typ {
+	case packetPrelogin, packetLogin7:
+		// the handshake is replayed for every connection of the application
+		metadata["type"] = "config"
+	case packetRPC:
+		metadata["procedure"] = req.procedure
+	}
+
+	mocks <- &models.Mock{
+		Version: models.GetVersion(),
+		Name:    "mocks",
+		Kind:    models.MSSQL,
+		Spec: models.MockSpec{
+			Metadata:         metadata,
+			MSSQLRequests:    []models.MSSQLMessage{req.model()},
+			MSSQLResponses:   []models.MSSQLMessage{resp.model()},
+			ReqTimestampMock: reqTimestampMock,
+			ResTimestampMock: resTimestampMock,
+		},
+		ConnectionID: connID,
+	}
+}
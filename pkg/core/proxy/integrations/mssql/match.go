@@ -0,0 +1,94 @@
+//go:build linux
+
+package mssql
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations/util"
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+// match finds the mock of the request among the mocks of the same message type and statement: the logins
+// are matched on the user and the database, the batches on their sql text and the rpcs on the procedure
+// and its statement. The mocks recorded during the current test case are preferred, and the closest
+// parameters win. The PRELOGIN and LOGIN7 mocks are matched as many times as the application connects,
+// the other mocks are moved behind the rest once matched.
+func match(ctx context.Context, req *message, mockDb integrations.MockMemDb) (*models.Mock, error) {
+	params := strings.Join(req.params, "\n")
+	for {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		mocks, err := mockDb.GetUnFilteredMocksByKey(models.MSSQL, "")
+		if err != nil {
+			return nil, fmt.Errorf("error while getting unfiltered mocks %v", err)
+		}
+
+		var filteredMocks, unfilteredMocks []*models.Mock
+		for _, mock := range mocks {
+			if mock.Kind != models.MSSQL || len(mock.Spec.MSSQLRequests) == 0 || !sameStatement(mock.Spec.MSSQLRequests[0], req) {
+				continue
+			}
+			if mock.TestModeInfo.IsFiltered {
+				filteredMocks = append(filteredMocks, mock)
+			} else {
+				unfilteredMocks = append(unfilteredMocks, mock)
+			}
+		}
+
+		mock := closest(filteredMocks, params)
+		if mock == nil {
+			mock = closest(unfilteredMocks, params)
+		}
+		if mock == nil || mock.Spec.Metadata["type"] == "config" {
+			return mock, nil
+		}
+
+		originalMock := *mock
+		mock.TestModeInfo.IsFiltered = false
+		mock.TestModeInfo.SortOrder = math.MaxInt64
+		if !mockDb.UpdateUnFilteredMock(&originalMock, mock) {
+			// the mock was used by another request in the meantime
+			continue
+		}
+		return mock, nil
+	}
+}
+
+// sameStatement reports whether the recorded request is of the type of the request and runs the same statement.
+func sameStatement(recorded models.MSSQLMessage, req *message) bool {
+	if recorded.Type != packetName(req.typ) {
+		return false
+	}
+	switch req.typ {
+	case packetLogin7:
+		return strings.EqualFold(recorded.User, req.user) && strings.EqualFold(recorded.Database, req.database)
+	case packetSQLBatch:
+		return recorded.Query == req.query
+	case packetRPC:
+		return strings.EqualFold(recorded.Procedure, req.procedure) && recorded.Query == req.query
+	}
+	return true
+}
+
+// closest returns the first mock with the same parameters, or else the one with the most similar parameters.
+func closest(mocks []*models.Mock, params string) *models.Mock {
+	var best *models.Mock
+	bestSim := -1.0
+	for _, mock := range mocks {
+		recorded := strings.Join(mock.Spec.MSSQLRequests[0].Params, "\n")
+		if recorded == params {
+			return mock
+		}
+		k := util.AdaptiveK(len(params), 3, 8, 5)
+		if sim := util.JaccardSimilarity(util.CreateShingles([]byte(recorded), k), util.CreateShingles([]byte(params), k)); sim > bestSim {
+			best, bestSim = mock, sim
+		}
+	}
+	return best
+}
@@ -0,0 +1,460 @@
+//go:build linux
+
+package mssql
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations/util"
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+// procNames are the names of the special procedures which the RPCs call by their id.
+var procNames = map[uint16]string{
+	1:  "sp_cursor",
+	2:  "sp_cursoropen",
+	3:  "sp_cursorprepare",
+	4:  "sp_cursorexecute",
+	5:  "sp_cursorprepexec",
+	6:  "sp_cursorunprepare",
+	7:  "sp_cursorfetch",
+	8:  "sp_cursoroption",
+	9:  "sp_cursorclose",
+	10: "sp_executesql",
+	11: "sp_prepare",
+	12: "sp_execute",
+	13: "sp_prepexec",
+	14: "sp_prepexecrpc",
+	15: "sp_unprepare",
+}
+
+// statementParams is the position of the sql statement in the parameters of the procedures which execute
+// one, the statement is matched as the query of the rpc.
+var statementParams = map[string]int{
+	"sp_executesql":     0,
+	"sp_prepare":        2,
+	"sp_prepexec":       2,
+	"sp_cursoropen":     1,
+	"sp_cursorprepare":  2,
+	"sp_cursorprepexec": 3,
+}
+
+// decode fills the fields of the message used for matching. The undecodable parts of a message are left
+// out, the message is matched on what could be decoded.
+func (m *message) decode() {
+	var err error
+	switch m.typ {
+	case packetLogin7:
+		err = m.decodeLogin()
+	case packetSQLBatch:
+		m.query = normalizeSQL(ucs2(skipAllHeaders(m.payload)))
+	case packetRPC:
+		err = m.decodeRPC()
+	}
+	if err != nil {
+		m.params = append(m.params, "<undecoded: "+err.Error()+">")
+	}
+}
+
+// login7 offsets of the variable fields in the LOGIN7 message
+const (
+	loginUserName = 40
+	loginPassword = 44
+	loginAppName  = 48
+	loginDatabase = 68
+	loginMinSize  = 94
+)
+
+func (m *message) decodeLogin() error {
+	if len(m.payload) < loginMinSize {
+		return errors.New("truncated login7 message")
+	}
+	var err error
+	if m.user, err = m.loginField(loginUserName); err != nil {
+		return err
+	}
+	if m.appName, err = m.loginField(loginAppName); err != nil {
+		return err
+	}
+	m.database, err = m.loginField(loginDatabase)
+	return err
+}
+
+// loginFieldBounds returns the bounds of a variable field of the LOGIN7 message from its offset and length.
+func (m *message) loginFieldBounds(at int) (int, int, error) {
+	offset := int(binary.LittleEndian.Uint16(m.payload[at:]))
+	size := int(binary.LittleEndian.Uint16(m.payload[at+2:])) * 2
+	if offset+size > len(m.payload) {
+		return 0, 0, errors.New("login7 field is out of the message")
+	}
+	return offset, offset + size, nil
+}
+
+func (m *message) loginField(at int) (string, error) {
+	start, end, err := m.loginFieldBounds(at)
+	if err != nil {
+		return "", err
+	}
+	return ucs2(m.payload[start:end]), nil
+}
+
+// maskPassword replaces the password in the raw packets of a LOGIN7 message with asterisks, so that the
+// recorded mock doesn't hold the credentials. It must be called after the message was forwarded.
+func (m *message) maskPassword() {
+	if m.typ != packetLogin7 || len(m.payload) < loginMinSize {
+		return
+	}
+	start, end, err := m.loginFieldBounds(loginPassword)
+	if err != nil {
+		return
+	}
+	// the password is sent with the nibbles swapped and xored with 0xa5, this is the encoded '*'
+	for i := start; i+1 < end; i += 2 {
+		m.setPayloadByte(i, 0x07)
+		m.setPayloadByte(i+1, 0xa5)
+	}
+}
+
+// skipAllHeaders skips the ALL_HEADERS of the batches and the rpcs, which are sent since TDS 7.2 and hold
+// e.g. the transaction descriptor.
+func skipAllHeaders(payload []byte) []byte {
+	if len(payload) < 4 {
+		return payload
+	}
+	total := int(binary.LittleEndian.Uint32(payload))
+	if total < 4 || total > len(payload) {
+		return payload
+	}
+	// each header starts with its length, they have to add up to the total
+	for i := 4; i < total; {
+		if i+4 > total {
+			return payload
+		}
+		size := int(binary.LittleEndian.Uint32(payload[i:]))
+		if size < 6 {
+			return payload
+		}
+		i += size
+		if i > total {
+			return payload
+		}
+	}
+	return payload[total:]
+}
+
+func (m *message) decodeRPC() error {
+	d := &decoder{buf: skipAllHeaders(m.payload)}
+	nameLen := d.uint16()
+	if nameLen == 0xffff {
+		id := d.uint16()
+		if name, ok := procNames[id]; ok {
+			m.procedure = name
+		} else {
+			m.procedure = fmt.Sprintf("proc id %d", id)
+		}
+	} else {
+		m.procedure = ucs2(d.bytes(int(nameLen) * 2))
+	}
+	d.uint16() // option flags
+	if d.err != nil {
+		return d.err
+	}
+
+	statement, isStatement := statementParams[m.procedure]
+	for i := 0; len(d.buf) > 0; i++ {
+		// the batch flag separates the rpcs sent in one message, only the first one is decoded
+		if d.buf[0] == 0x80 || d.buf[0] == 0xff {
+			break
+		}
+		name := ucs2(d.bytes(int(d.uint8()) * 2))
+		d.uint8() // status flags
+		value := d.value()
+		if d.err != nil {
+			return d.err
+		}
+		if isStatement && i == statement {
+			m.query = normalizeSQL(value)
+			continue
+		}
+		if name == "" {
+			name = "@" + strconv.Itoa(i)
+		}
+		m.params = append(m.params, name+"="+value)
+	}
+	return nil
+}
+
+// normalizeSQL collapses the whitespace of a statement, which clients and ORMs format differently.
+func normalizeSQL(sql string) string {
+	return strings.Join(strings.Fields(sql), " ")
+}
+
+// ucs2 decodes the little endian UTF-16 strings of TDS.
+func ucs2(b []byte) string {
+	u := make([]uint16, len(b)/2)
+	for i := range u {
+		u[i] = binary.LittleEndian.Uint16(b[i*2:])
+	}
+	return string(utf16.Decode(u))
+}
+
+// The data types of the rpc parameters.
+const (
+	typeNull       = 0x1f
+	typeInt1       = 0x30
+	typeBit        = 0x32
+	typeInt2       = 0x34
+	typeInt4       = 0x38
+	typeDatetime4  = 0x3a
+	typeFloat4     = 0x3b
+	typeMoney      = 0x3c
+	typeDatetime   = 0x3d
+	typeFloat8     = 0x3e
+	typeMoney4     = 0x7a
+	typeInt8       = 0x7f
+	typeGUID       = 0x24
+	typeIntN       = 0x26
+	typeDecimal    = 0x37
+	typeNumeric    = 0x3f
+	typeBitN       = 0x68
+	typeDecimalN   = 0x6a
+	typeNumericN   = 0x6c
+	typeFloatN     = 0x6d
+	typeMoneyN     = 0x6e
+	typeDatetimeN  = 0x6f
+	typeDateN      = 0x28
+	typeTimeN      = 0x29
+	typeDatetime2N = 0x2a
+	typeDTOffsetN  = 0x2b
+	typeVarBinary  = 0xa5
+	typeVarChar    = 0xa7
+	typeBinary     = 0xad
+	typeChar       = 0xaf
+	typeNVarChar   = 0xe7
+	typeNChar      = 0xef
+)
+
+// fixedSizes are the sizes of the fixed length types.
+var fixedSizes = map[byte]int{
+	typeNull:      0,
+	typeInt1:      1,
+	typeBit:       1,
+	typeInt2:      2,
+	typeInt4:      4,
+	typeDatetime4: 4,
+	typeFloat4:    4,
+	typeMoney:     8,
+	typeDatetime:  8,
+	typeFloat8:    8,
+	typeMoney4:    4,
+	typeInt8:      8,
+}
+
+// decoder reads the little endian values of the TDS messages, the first error sticks.
+type decoder struct {
+	buf []byte
+	err error
+}
+
+func (d *decoder) bytes(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n < 0 || n > len(d.buf) {
+		d.err = errors.New("truncated tds message")
+		return nil
+	}
+	b := d.buf[:n]
+	d.buf = d.buf[n:]
+	return b
+}
+
+func (d *decoder) uint8() uint8 {
+	if b := d.bytes(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (d *decoder) uint16() uint16 {
+	if b := d.bytes(2); b != nil {
+		return binary.LittleEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (d *decoder) uint32() uint32 {
+	if b := d.bytes(4); b != nil {
+		return binary.LittleEndian.Uint32(b)
+	}
+	return 0
+}
+
+// value reads the TYPE_INFO of a parameter and its value, and formats the value as text.
+func (d *decoder) value() string {
+	typ := d.uint8()
+	if size, ok := fixedSizes[typ]; ok {
+		if typ == typeNull {
+			return "NULL"
+		}
+		return formatValue(typ, 0, d.bytes(size))
+	}
+
+	switch typ {
+	case typeIntN, typeBitN, typeFloatN, typeMoneyN, typeDatetimeN, typeGUID:
+		d.uint8() // max length
+		return d.byteLenValue(typ, 0)
+	case typeDecimal, typeNumeric, typeDecimalN, typeNumericN:
+		d.uint8() // max length
+		d.uint8() // precision
+		scale := d.uint8()
+		return d.byteLenValue(typ, scale)
+	case typeDateN:
+		return d.byteLenValue(typ, 0)
+	case typeTimeN, typeDatetime2N, typeDTOffsetN:
+		d.uint8() // scale
+		return d.byteLenValue(typ, 0)
+	case typeVarChar, typeChar, typeNVarChar, typeNChar, typeVarBinary, typeBinary:
+		maxLen := d.uint16()
+		if typ != typeVarBinary && typ != typeBinary {
+			d.bytes(5) // collation
+		}
+		if maxLen == 0xffff {
+			return d.plpValue(typ)
+		}
+		size := d.uint16()
+		if size == 0xffff {
+			return "NULL"
+		}
+		return formatValue(typ, 0, d.bytes(int(size)))
+	}
+	if d.err == nil {
+		d.err = fmt.Errorf("unsupported parameter type 0x%02x", typ)
+	}
+	return ""
+}
+
+func (d *decoder) byteLenValue(typ, scale byte) string {
+	size := d.uint8()
+	if size == 0 {
+		return "NULL"
+	}
+	return formatValue(typ, scale, d.bytes(int(size)))
+}
+
+// plpValue reads the chunks of a partially length-prefixed value, which are sent for the max types.
+func (d *decoder) plpValue(typ byte) string {
+	total := d.bytes(8)
+	if d.err != nil {
+		return ""
+	}
+	if binary.LittleEndian.Uint64(total) == math.MaxUint64 {
+		return "NULL"
+	}
+	var value []byte
+	for d.err == nil {
+		size := d.uint32()
+		if size == 0 {
+			break
+		}
+		value = append(value, d.bytes(int(size))...)
+	}
+	return formatValue(typ, 0, value)
+}
+
+// formatValue formats the value of a parameter, the types without a simple text form are shown in hex.
+func formatValue(typ, scale byte, b []byte) string {
+	switch typ {
+	case typeNVarChar, typeNChar:
+		return ucs2(b)
+	case typeVarChar, typeChar:
+		return string(b)
+	case typeBit, typeBitN:
+		return strconv.FormatBool(len(b) > 0 && b[0] != 0)
+	case typeInt1, typeInt2, typeInt4, typeInt8, typeIntN:
+		switch len(b) {
+		case 1:
+			return strconv.Itoa(int(b[0]))
+		case 2:
+			return strconv.Itoa(int(int16(binary.LittleEndian.Uint16(b))))
+		case 4:
+			return strconv.Itoa(int(int32(binary.LittleEndian.Uint32(b))))
+		case 8:
+			return strconv.FormatInt(int64(binary.LittleEndian.Uint64(b)), 10)
+		}
+	case typeFloat4, typeFloat8, typeFloatN:
+		switch len(b) {
+		case 4:
+			return strconv.FormatFloat(float64(math.Float32frombits(binary.LittleEndian.Uint32(b))), 'g', -1, 32)
+		case 8:
+			return strconv.FormatFloat(math.Float64frombits(binary.LittleEndian.Uint64(b)), 'g', -1, 64)
+		}
+	case typeDecimal, typeNumeric, typeDecimalN, typeNumericN:
+		return formatDecimal(b, scale)
+	case typeGUID:
+		if len(b) == 16 {
+			// the first three groups are little endian
+			return fmt.Sprintf("%08X-%04X-%04X-%X-%X", binary.LittleEndian.Uint32(b), binary.LittleEndian.Uint16(b[4:]), binary.LittleEndian.Uint16(b[6:]), b[8:10], b[10:])
+		}
+	}
+	return "0x" + hex.EncodeToString(b)
+}
+
+// formatDecimal formats a decimal sent as its sign followed by the little endian unscaled integer.
+func formatDecimal(b []byte, scale byte) string {
+	if len(b) < 2 || len(b) > 17 {
+		return "0x" + hex.EncodeToString(b)
+	}
+	var digits []byte
+	magnitude := append([]byte(nil), b[1:]...)
+	for {
+		// divide the little endian magnitude by 10 in place
+		zero, rem := true, 0
+		for i := len(magnitude) - 1; i >= 0; i-- {
+			cur := rem<<8 | int(magnitude[i])
+			magnitude[i] = byte(cur / 10)
+			rem = cur % 10
+			if magnitude[i] != 0 {
+				zero = false
+			}
+		}
+		digits = append(digits, byte('0'+rem))
+		if zero {
+			break
+		}
+	}
+	for len(digits) <= int(scale) {
+		digits = append(digits, '0')
+	}
+	var s strings.Builder
+	if b[0] == 0 {
+		s.WriteByte('-')
+	}
+	for i := len(digits) - 1; i >= 0; i-- {
+		if i == int(scale)-1 {
+			s.WriteByte('.')
+		}
+		s.WriteByte(digits[i])
+	}
+	return s.String()
+}
+
+func (m *message) model() models.MSSQLMessage {
+	return models.MSSQLMessage{
+		Type:      packetName(m.typ),
+		User:      m.user,
+		Database:  m.database,
+		AppName:   m.appName,
+		Query:     m.query,
+		Procedure: m.procedure,
+		Params:    m.params,
+		Raw:       util.EncodeBase64(m.raw),
+	}
+}
@@ -0,0 +1,174 @@
+//go:build linux
+
+package mssql
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+	"unicode/utf16"
+)
+
+func utf16le(s string) []byte {
+	var b []byte
+	for _, u := range utf16.Encode([]rune(s)) {
+		b = binary.LittleEndian.AppendUint16(b, u)
+	}
+	return b
+}
+
+// packets splits the payload into the packets of the type, the packets hold at most size bytes of it.
+func packets(typ byte, payload []byte, size int) []byte {
+	var raw []byte
+	for {
+		n := min(size, len(payload))
+		status := byte(0)
+		if n == len(payload) {
+			status = statusEOM
+		}
+		raw = append(raw, typ, status)
+		raw = binary.BigEndian.AppendUint16(raw, uint16(headerSize+n))
+		raw = append(raw, 0, 0, 1, 0) // spid, packet id and window
+		raw = append(raw, payload[:n]...)
+		payload = payload[n:]
+		if status == statusEOM {
+			return raw
+		}
+	}
+}
+
+// allHeaders is the ALL_HEADERS of a batch or an rpc holding the transaction descriptor.
+func allHeaders() []byte {
+	b := binary.LittleEndian.AppendUint32(nil, 22)
+	b = binary.LittleEndian.AppendUint32(b, 18)
+	b = binary.LittleEndian.AppendUint16(b, 2)
+	return append(b, make([]byte, 12)...)
+}
+
+func nvarchar(b []byte, name, value string) []byte {
+	b = append(b, byte(len(name)))
+	b = append(b, utf16le(name)...)
+	b = append(b, 0, typeNVarChar)
+	b = binary.LittleEndian.AppendUint16(b, 8000)
+	b = append(b, 0, 0, 0, 0, 0) // collation
+	b = binary.LittleEndian.AppendUint16(b, uint16(len(value)*2))
+	return append(b, utf16le(value)...)
+}
+
+func readMessages(t *testing.T, raw []byte) *message {
+	t.Helper()
+	m, err := readMessage(bufio.NewReader(bytes.NewReader(raw)))
+	if err != nil {
+		t.Fatalf("readMessage() failed: %v", err)
+	}
+	if !bytes.Equal(m.raw, raw) {
+		t.Errorf("readMessage() raw = % x, want % x", m.raw, raw)
+	}
+	m.decode()
+	return m
+}
+
+func TestSQLBatch(t *testing.T) {
+	payload := append(allHeaders(), utf16le("SELECT *\n  FROM users\tWHERE id = 1")...)
+	m := readMessages(t, packets(packetSQLBatch, payload, 16))
+	if m.typ != packetSQLBatch || m.query != "SELECT * FROM users WHERE id = 1" || len(m.packets) < 2 {
+		t.Errorf("the batch read = %s %q of %d packets", packetName(m.typ), m.query, len(m.packets))
+	}
+}
+
+func TestRPC(t *testing.T) {
+	b := append(allHeaders(), 0xff, 0xff)
+	b = binary.LittleEndian.AppendUint16(b, 10) // sp_executesql
+	b = append(b, 0, 0)                         // option flags
+	b = nvarchar(b, "", "SELECT name FROM users  WHERE id = @id AND price > @price")
+	b = nvarchar(b, "", "@id int, @price decimal(10,2)")
+	b = append(append(b, 3), utf16le("@id")...)
+	b = append(b, 0, typeIntN, 4, 4, 42, 0, 0, 0)
+	b = append(append(b, 6), utf16le("@price")...)
+	b = append(b, 0, typeDecimalN, 17, 10, 2, 5, 0, 0xd2, 0x04, 0, 0) // -12.34
+	b = append(append(b, 3), utf16le("@at")...)
+	b = append(b, 0, typeDatetime2N, 7, 0) // null
+	m := readMessages(t, packets(packetRPC, b, 4096))
+
+	if m.procedure != "sp_executesql" || m.query != "SELECT name FROM users WHERE id = @id AND price > @price" {
+		t.Errorf("the rpc read = %s %q", m.procedure, m.query)
+	}
+	want := []string{"@1=@id int, @price decimal(10,2)", "@id=42", "@price=-12.34", "@at=NULL"}
+	if !reflect.DeepEqual(m.params, want) {
+		t.Errorf("the rpc params = %q, want %q", m.params, want)
+	}
+}
+
+func TestLogin7MaskPassword(t *testing.T) {
+	payload := make([]byte, loginMinSize)
+	field := func(at int, value string) {
+		binary.LittleEndian.PutUint16(payload[at:], uint16(len(payload)))
+		binary.LittleEndian.PutUint16(payload[at+2:], uint16(len(value)))
+		payload = append(payload, utf16le(value)...)
+	}
+	field(loginUserName, "sa")
+	field(loginPassword, "secret")
+	field(loginAppName, "orders")
+	field(loginDatabase, "shop")
+	password := payload[len(payload)-len(utf16le("shop"))-len(utf16le("orders"))-len(utf16le("secret")):][:len(utf16le("secret"))]
+	password = append([]byte(nil), password...)
+
+	m := readMessages(t, packets(packetLogin7, payload, 50))
+	if m.user != "sa" || m.appName != "orders" || m.database != "shop" {
+		t.Errorf("the login read = %q %q %q", m.user, m.appName, m.database)
+	}
+	m.maskPassword()
+	if bytes.Contains(m.raw, password) || bytes.Contains(m.payload, password) {
+		t.Error("maskPassword() left the password in the message")
+	}
+	masked, err := readMessage(bufio.NewReader(bytes.NewReader(m.raw)))
+	if err != nil || !bytes.Equal(masked.payload, m.payload) {
+		t.Errorf("the packets of the masked login don't hold the masked payload: %v", err)
+	}
+}
+
+func TestPreloginDisableEncryption(t *testing.T) {
+	payload := []byte{
+		0x00, 0, 16, 0, 6, // version
+		optionEncryption, 0, 22, 0, 1,
+		optionMARS, 0, 23, 0, 1,
+		optionTerminator,
+		16, 0, 0, 0, 0, 0, // the version
+		encryptOn,
+		1, // MARS
+	}
+	m := readMessages(t, packets(packetPrelogin, payload, 20))
+	if m.encryption() != encryptOn {
+		t.Fatalf("encryption() = %d, want %d", m.encryption(), encryptOn)
+	}
+	if err := m.disableEncryption(); err != nil {
+		t.Fatalf("disableEncryption() failed: %v", err)
+	}
+	rewritten, err := readMessage(bufio.NewReader(bytes.NewReader(m.raw)))
+	if err != nil {
+		t.Fatalf("readMessage() of the rewritten prelogin failed: %v", err)
+	}
+	if rewritten.encryption() != encryptNotSup || rewritten.payload[23] != 0 {
+		t.Errorf("the rewritten prelogin has the encryption %d and MARS %d", rewritten.encryption(), rewritten.payload[23])
+	}
+}
+
+func TestFormatDecimal(t *testing.T) {
+	tests := []struct {
+		b     []byte
+		scale byte
+		want  string
+	}{
+		{[]byte{1, 0xd2, 0x04, 0, 0}, 2, "12.34"},
+		{[]byte{0, 0xd2, 0x04, 0, 0}, 0, "-1234"},
+		{[]byte{1, 5, 0, 0, 0}, 3, "0.005"},
+		{[]byte{1, 0, 0, 0, 0}, 2, "0.00"},
+	}
+	for _, tt := range tests {
+		if got := formatDecimal(tt.b, tt.scale); got != tt.want {
+			t.Errorf("formatDecimal(% x, %d) = %s, want %s", tt.b, tt.scale, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+//go:build linux
+
+// Package mssql provides the integration for Microsoft SQL Server over the TDS protocol.
+package mssql
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	"go.keploy.io/server/v2/pkg/core/proxy/util"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+func init() {
+	integrations.Register("mssql", NewMSSQL)
+}
+
+type MSSQL struct {
+	logger *zap.Logger
+}
+
+func NewMSSQL(logger *zap.Logger) integrations.Integrations {
+	return &MSSQL{
+		logger: logger,
+	}
+}
+
+// MatchType checks for the PRELOGIN packet which starts every TDS connection. The connections with the
+// strict encryption of TDS 8 start with a TLS handshake instead, and are left to the generic integration.
+func (m *MSSQL) MatchType(_ context.Context, buf []byte) integrations.MatchResult {
+	if len(buf) < headerSize {
+		return integrations.MatchResult{NeedBytes: headerSize}
+	}
+	length := int(binary.BigEndian.Uint16(buf[2:4]))
+	if buf[0] != packetPrelogin || buf[1] != statusEOM || length < headerSize+6 || binary.BigEndian.Uint16(buf[4:6]) != 0 {
+		return integrations.MatchResult{}
+	}
+	if len(buf) < length {
+		return integrations.MatchResult{NeedBytes: length}
+	}
+	options, err := preloginOptions(buf[headerSize:length])
+	// the version is the first option of the clients
+	if err != nil || len(options) == 0 || options[0].token != 0 {
+		return integrations.MatchResult{}
+	}
+	return integrations.MatchResult{Confidence: integrations.ConfidenceCertain}
+}
+
+func (m *MSSQL) RecordOutgoing(ctx context.Context, src net.Conn, dst net.Conn, mocks chan<- *models.Mock, opts models.OutgoingOptions) error {
+	logger := m.logger.With(zap.Any("Client IP Address", src.RemoteAddr().String()), zap.Any("Client ConnectionID", ctx.Value(models.ClientConnectionIDKey).(string)), zap.Any("Destination ConnectionID", ctx.Value(models.DestConnectionIDKey).(string)))
+
+	reqBuf, err := util.ReadInitialBuf(ctx, logger, src)
+	if err != nil {
+		utils.LogError(logger, err, "failed to read the initial tds message")
+		return err
+	}
+
+	err = encodeMSSQL(ctx, logger, reqBuf, src, dst, mocks, opts)
+	if err != nil {
+		utils.LogError(logger, err, "failed to encode the mssql message into the yaml")
+		return err
+	}
+	return nil
+}
+
+func (m *MSSQL) MockOutgoing(ctx context.Context, src net.Conn, dstCfg *integrations.ConditionalDstCfg, mockDb integrations.MockMemDb, opts models.OutgoingOptions) error {
+	logger := m.logger.With(zap.Any("Client IP Address", src.RemoteAddr().String()), zap.Any("Client ConnectionID", ctx.Value(models.ClientConnectionIDKey).(string)), zap.Any("Destination ConnectionID", ctx.Value(models.DestConnectionIDKey).(string)))
+
+	reqBuf, err := util.ReadInitialBuf(ctx, logger, src)
+	if err != nil {
+		utils.LogError(logger, err, "failed to read the initial tds message")
+		return err
+	}
+
+	err = decodeMSSQL(ctx, logger, reqBuf, src, dstCfg, mockDb, opts)
+	if err != nil {
+		utils.LogError(logger, err, "failed to decode the mssql message")
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+//go:build linux
+
+package mssql
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// The types of the TDS packets.
+const (
+	packetSQLBatch      = 0x01
+	packetRPC           = 0x03
+	packetTabularResult = 0x04
+	packetAttention     = 0x06
+	packetBulkLoad      = 0x07
+	packetTransaction   = 0x0e
+	packetLogin7        = 0x10
+	packetSSPI          = 0x11
+	packetPrelogin      = 0x12
+)
+
+var packetNames = map[byte]string{
+	packetSQLBatch:      "SQLBatch",
+	packetRPC:           "RPC",
+	packetTabularResult: "TabularResult",
+	packetAttention:     "Attention",
+	packetBulkLoad:      "BulkLoad",
+	packetTransaction:   "TransactionManager",
+	packetLogin7:        "LOGIN7",
+	packetSSPI:          "SSPI",
+	packetPrelogin:      "PRELOGIN",
+}
+
+const (
+	headerSize = 8
+	// statusEOM marks the last packet of a message
+	statusEOM = 0x01
+	// maxMessageSize bounds the reassembled messages, the packets are at most 32KB
+	maxMessageSize = 256 << 20
+)
+
+// The PRELOGIN options rewritten by the proxy, and the values of the encryption option.
+const (
+	optionEncryption = 0x01
+	optionMARS       = 0x04
+	optionTerminator = 0xff
+
+	encryptOff    = 0x00
+	encryptOn     = 0x01
+	encryptNotSup = 0x02
+	encryptReq    = 0x03
+)
+
+// packetName returns the name of the packet type, or its number for the unknown types.
+func packetName(typ byte) string {
+	if name, ok := packetNames[typ]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%02x", typ)
+}
+
+// message is a TDS message reassembled from its packets.
+type message struct {
+	typ     byte
+	raw     []byte
+	payload []byte
+	// packets are the offsets of the packet headers in raw
+	packets []int
+
+	// the decoded fields of the login, the batches and the rpcs
+	user      string
+	database  string
+	appName   string
+	query     string
+	procedure string
+	params    []string
+}
+
+// readMessage reads the packets of a message up to the one marked as its end.
+func readMessage(r *bufio.Reader) (*message, error) {
+	m := &message{}
+	for {
+		header := make([]byte, headerSize)
+		if _, err := io.ReadFull(r, header); err != nil {
+			if len(m.packets) > 0 && err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		length := int(binary.BigEndian.Uint16(header[2:4]))
+		if length < headerSize {
+			return nil, fmt.Errorf("invalid tds packet length %d", length)
+		}
+		if len(m.packets) == 0 {
+			m.typ = header[0]
+		}
+		if len(m.payload)+length > maxMessageSize {
+			return nil, fmt.Errorf("tds message of type %s is larger than %d bytes", packetName(m.typ), maxMessageSize)
+		}
+		data := make([]byte, length-headerSize)
+		if _, err := io.ReadFull(r, data); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		m.packets = append(m.packets, len(m.raw))
+		m.raw = append(append(m.raw, header...), data...)
+		m.payload = append(m.payload, data...)
+		if header[1]&statusEOM != 0 {
+			return m, nil
+		}
+	}
+}
+
+// setPayloadByte changes a byte of the payload in the packets of the message as well.
+func (m *message) setPayloadByte(offset int, b byte) {
+	m.payload[offset] = b
+	for _, start := range m.packets {
+		size := int(binary.BigEndian.Uint16(m.raw[start+2:start+4])) - headerSize
+		if offset < size {
+			m.raw[start+headerSize+offset] = b
+			return
+		}
+		offset -= size
+	}
+}
+
+// preloginOption is an option of a PRELOGIN message, its offset is relative to the payload.
+type preloginOption struct {
+	token  byte
+	offset int
+	length int
+}
+
+// preloginOptions parses the option table of a PRELOGIN message and of its response.
+func preloginOptions(payload []byte) ([]preloginOption, error) {
+	var options []preloginOption
+	for i := 0; ; i += 5 {
+		if i >= len(payload) {
+			return nil, errors.New("the prelogin options aren't terminated")
+		}
+		if payload[i] == optionTerminator {
+			return options, nil
+		}
+		if i+5 > len(payload) {
+			return nil, errors.New("truncated prelogin option")
+		}
+		o := preloginOption{
+			token:  payload[i],
+			offset: int(binary.BigEndian.Uint16(payload[i+1:])),
+			length: int(binary.BigEndian.Uint16(payload[i+3:])),
+		}
+		if o.offset+o.length > len(payload) {
+			return nil, fmt.Errorf("prelogin option %d is out of the message", o.token)
+		}
+		options = append(options, o)
+	}
+}
+
+// encryption returns the value of the encryption option of a PRELOGIN message, encryptNotSup without it.
+func (m *message) encryption() byte {
+	options, err := preloginOptions(m.payload)
+	if err != nil {
+		return encryptNotSup
+	}
+	for _, o := range options {
+		if o.token == optionEncryption && o.length > 0 {
+			return m.payload[o.offset]
+		}
+	}
+	return encryptNotSup
+}
+
+// disableEncryption rewrites a PRELOGIN message or its response so that neither the encryption nor MARS
+// are negotiated. The TLS handshake would otherwise be tunnelled through the following packets, and with
+// MARS the messages are multiplexed over the connection.
+func (m *message) disableEncryption() error {
+	options, err := preloginOptions(m.payload)
+	if err != nil {
+		return err
+	}
+	for _, o := range options {
+		if o.length == 0 {
+			continue
+		}
+		switch o.token {
+		case optionEncryption:
+			m.setPayloadByte(o.offset, encryptNotSup)
+		case optionMARS:
+			m.setPayloadByte(o.offset, 0)
+		}
+	}
+	return nil
+}
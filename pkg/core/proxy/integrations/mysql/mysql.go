@@ -32,9 +32,9 @@ func New(logger *zap.Logger) integrations.Integrations {
 	}
 }
 
-func (m *MySQL) MatchType(_ context.Context, _ []byte) bool {
-	//Returning false here because sql parser is using the ports to check if the packet is mysql or not.
-	return false
+func (m *MySQL) MatchType(_ context.Context, _ []byte) integrations.MatchResult {
+	//Returning no confidence here because sql parser is using the ports to check if the packet is mysql or not.
+	return integrations.MatchResult{}
 }
 
 func (m *MySQL) RecordOutgoing(ctx context.Context, src net.Conn, dst net.Conn, mocks chan<- *models.Mock, opts models.OutgoingOptions) error {
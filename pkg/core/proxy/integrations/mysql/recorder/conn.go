@@ -24,6 +24,9 @@ type handshakeRes struct {
 	requestOperation  string
 	responseOperation string
 	reqTimestamp      time.Time
+	// clientConn and destConn are the conns after the handshake, they are upgraded to tls when the client asked for it
+	clientConn net.Conn
+	destConn   net.Conn
 }
 
 func handleInitialHandshake(ctx context.Context, logger *zap.Logger, clientConn, destConn net.Conn, decodeCtx *wire.DecodeContext) (handshakeRes, error) {
@@ -79,38 +82,47 @@ func handleInitialHandshake(ctx context.Context, logger *zap.Logger, clientConn,
 	})
 
 	// Handshake response from client
-	handshakeResponse, err := mysqlUtils.ReadPacketBuffer(ctx, logger, clientConn)
+	handshakeResponsePkt, err := forwardHandshakeResponse(ctx, logger, clientConn, destConn, decodeCtx)
 	if err != nil {
-		if err == io.EOF {
-			logger.Debug("received request buffer is empty in record mode for mysql call")
-			return res, err
-		}
-		utils.LogError(logger, err, "failed to read handshake response from client")
-
 		return res, err
 	}
 
-	_, err = destConn.Write(handshakeResponse)
-	if err != nil {
-		if ctx.Err() != nil {
-			return res, ctx.Err()
-		}
-		utils.LogError(logger, err, "failed to write handshake response to server")
+	// The client asks for tls with an SSLRequest, the handshake response follows over tls
+	if _, ok := handshakeResponsePkt.Message.(*mysql.SSLRequestPacket); ok {
+		logger.Debug("the client asked for tls, upgrading the mysql connection")
 
-		return res, err
-	}
+		res.req = append(res.req, mysql.Request{
+			PacketBundle: *handshakeResponsePkt,
+		})
 
-	// Decode client handshake response packet
-	handshakeResponsePkt, err := wire.DecodePayload(ctx, logger, handshakeResponse, clientConn, decodeCtx)
-	if err != nil {
-		utils.LogError(logger, err, "failed to decode handshake response packet")
-		return res, err
+		tlsClientConn, serverName, err := wire.UpgradeClient(ctx, clientConn, decodeCtx)
+		if err != nil {
+			utils.LogError(logger, err, "failed to upgrade the client connection to tls")
+			return res, err
+		}
+		destConn, err = wire.UpgradeDest(ctx, destConn, serverName)
+		if err != nil {
+			utils.LogError(logger, err, "failed to upgrade the server connection to tls")
+			return res, err
+		}
+		clientConn = tlsClientConn
+
+		handshakeResponsePkt, err = forwardHandshakeResponse(ctx, logger, clientConn, destConn, decodeCtx)
+		if err != nil {
+			return res, err
+		}
 	}
+	res.clientConn, res.destConn = clientConn, destConn
 
 	res.req = append(res.req, mysql.Request{
 		PacketBundle: *handshakeResponsePkt,
 	})
 
+	// The server goes on with the plugin of the client unless it switches it
+	if pkt, ok := handshakeResponsePkt.Message.(*mysql.HandshakeResponse41Packet); ok && pkt.AuthPluginName != "" {
+		decodeCtx.PluginName = pkt.AuthPluginName
+	}
+
 	// Read the next auth packet,
 	// It can be either auth more data if authentication from both server and client are agreed.(caching_sha2_password)
 	// or auth switch request if the server wants to switch the auth mechanism
@@ -251,6 +263,39 @@ func handleInitialHandshake(ctx context.Context, logger *zap.Logger, clientConn,
 	return res, nil
 }
 
+// forwardHandshakeResponse forwards the handshake response of the client, or its SSLRequest, to the server.
+func forwardHandshakeResponse(ctx context.Context, logger *zap.Logger, clientConn, destConn net.Conn, decodeCtx *wire.DecodeContext) (*mysql.PacketBundle, error) {
+	handshakeResponse, err := mysqlUtils.ReadPacketBuffer(ctx, logger, clientConn)
+	if err != nil {
+		if err == io.EOF {
+			logger.Debug("received request buffer is empty in record mode for mysql call")
+			return nil, err
+		}
+		utils.LogError(logger, err, "failed to read handshake response from client")
+
+		return nil, err
+	}
+
+	_, err = destConn.Write(handshakeResponse)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		utils.LogError(logger, err, "failed to write handshake response to server")
+
+		return nil, err
+	}
+
+	// Decode client handshake response packet
+	handshakeResponsePkt, err := wire.DecodePayload(ctx, logger, handshakeResponse, clientConn, decodeCtx)
+	if err != nil {
+		utils.LogError(logger, err, "failed to decode handshake response packet")
+		return nil, err
+	}
+
+	return handshakeResponsePkt, nil
+}
+
 func setHandshakeResult(res *handshakeRes, authRes handshakeRes) {
 	res.req = append(res.req, authRes.req...)
 	res.resp = append(res.resp, authRes.resp...)
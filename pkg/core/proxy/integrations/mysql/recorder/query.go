@@ -94,20 +94,32 @@ func handleClientQueries(ctx context.Context, logger *zap.Logger, clientConn, de
 }
 
 func handleQueryResponse(ctx context.Context, logger *zap.Logger, clientConn, destConn net.Conn, decodeCtx *wire.DecodeContext) (*mysql.PacketBundle, error) {
-	// read the command response from the destination server
-	commandResp, err := mysqlUtils.ReadPacketBuffer(ctx, logger, destConn)
-	if err != nil {
-		if err != io.EOF {
-			utils.LogError(logger, err, "failed to read command response from the server")
+	var commandResp []byte
+	for {
+		// read the command response from the destination server
+		resp, err := mysqlUtils.ReadPacketBuffer(ctx, logger, destConn)
+		if err != nil {
+			if err != io.EOF {
+				utils.LogError(logger, err, "failed to read command response from the server")
+			}
+			return nil, err
 		}
-		return nil, err
-	}
 
-	// write the command response to the client
-	_, err = clientConn.Write(commandResp)
-	if err != nil {
-		utils.LogError(logger, err, "failed to write command response to the client")
-		return nil, err
+		// write the command response to the client
+		_, err = clientConn.Write(resp)
+		if err != nil {
+			utils.LogError(logger, err, "failed to write command response to the client")
+			return nil, err
+		}
+
+		// The MariaDB servers report the progress of the long commands before their response, the reports
+		// aren't recorded since the mocked server answers at once
+		if decodeCtx.MariaDBCapabilities&mysql.MARIADB_CLIENT_PROGRESS != 0 && mysqlUtils.IsProgressReport(resp) {
+			logger.Debug("skipping the progress report of the MariaDB server")
+			continue
+		}
+		commandResp = resp
+		break
 	}
 
 	//decode the command response packet
@@ -195,7 +207,7 @@ func handlePreparedStmtResponse(ctx context.Context, logger *zap.Logger, clientC
 			}
 
 			// Decode the column definition packet
-			column, _, err := rowscols.DecodeColumn(ctx, logger, colData)
+			column, _, err := rowscols.DecodeColumn(ctx, logger, colData, decodeCtx.MariaDBCapabilities)
 			if err != nil {
 				return nil, fmt.Errorf("failed to decode column definition packet: %w", err)
 			}
@@ -252,7 +264,7 @@ func handlePreparedStmtResponse(ctx context.Context, logger *zap.Logger, clientC
 			}
 
 			// Decode the column definition packet
-			column, _, err := rowscols.DecodeColumn(ctx, logger, colData)
+			column, _, err := rowscols.DecodeColumn(ctx, logger, colData, decodeCtx.MariaDBCapabilities)
 			if err != nil {
 				return nil, fmt.Errorf("failed to decode column definition packet: %w", err)
 			}
@@ -329,7 +341,7 @@ func handleTextResultSet(ctx context.Context, logger *zap.Logger, clientConn, de
 		}
 
 		// Decode the column definition packet
-		column, _, err := rowscols.DecodeColumn(ctx, logger, colData)
+		column, _, err := rowscols.DecodeColumn(ctx, logger, colData, decodeCtx.MariaDBCapabilities)
 		if err != nil {
 			return nil, fmt.Errorf("failed to decode column definition packet: %w", err)
 		}
@@ -440,58 +452,61 @@ func handleBinaryResultSet(ctx context.Context, logger *zap.Logger, clientConn,
 	colCount := binaryResultSet.ColumnCount
 
 	logger.Debug("ColCount in handleBinaryResultSet: ", zap.Any("ColCount", colCount))
-	// Read the column definition packets
-	for i := uint64(0); i < colCount; i++ {
-		// Read the column definition packet
-		colData, err := mysqlUtils.ReadPacketBuffer(ctx, logger, destConn)
-		if err != nil {
-			if err != io.EOF {
-				utils.LogError(logger, err, "failed to read column definition packet")
+	// The MariaDB servers leave out the column definitions cached by the client, they were taken from the prepared statement
+	if binaryResultSet.MetadataFollows == nil || *binaryResultSet.MetadataFollows != 0 {
+		// Read the column definition packets
+		for i := uint64(0); i < colCount; i++ {
+			// Read the column definition packet
+			colData, err := mysqlUtils.ReadPacketBuffer(ctx, logger, destConn)
+			if err != nil {
+				if err != io.EOF {
+					utils.LogError(logger, err, "failed to read column definition packet")
+				}
+				return nil, err
 			}
-			return nil, err
+
+			// Write the column definition packet to the client
+			_, err = clientConn.Write(colData)
+			if err != nil {
+				utils.LogError(logger, err, "failed to write column definition packet")
+				return nil, err
+			}
+
+			// Decode the column definition packet
+			column, _, err := rowscols.DecodeColumn(ctx, logger, colData, decodeCtx.MariaDBCapabilities)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode column definition packet: %w", err)
+			}
+
+			binaryResultSet.Columns = append(binaryResultSet.Columns, column)
 		}
 
-		// Write the column definition packet to the client
-		_, err = clientConn.Write(colData)
+		logger.Debug("Columns: ", zap.Any("Columns", binaryResultSet.Columns))
+
+		// Read the EOF packet for column definition
+		eofData, err := mysqlUtils.ReadPacketBuffer(ctx, logger, destConn)
 		if err != nil {
-			utils.LogError(logger, err, "failed to write column definition packet")
+			if err != io.EOF {
+				utils.LogError(logger, err, "failed to read EOF packet for column definition")
+			}
 			return nil, err
 		}
 
-		// Decode the column definition packet
-		column, _, err := rowscols.DecodeColumn(ctx, logger, colData)
+		// Write the EOF packet for column definition to the client
+		_, err = clientConn.Write(eofData)
 		if err != nil {
-			return nil, fmt.Errorf("failed to decode column definition packet: %w", err)
+			utils.LogError(logger, err, "failed to write EOF packet for column definition to the client")
+			return nil, err
 		}
 
-		binaryResultSet.Columns = append(binaryResultSet.Columns, column)
-	}
-
-	logger.Debug("Columns: ", zap.Any("Columns", binaryResultSet.Columns))
-
-	// Read the EOF packet for column definition
-	eofData, err := mysqlUtils.ReadPacketBuffer(ctx, logger, destConn)
-	if err != nil {
-		if err != io.EOF {
-			utils.LogError(logger, err, "failed to read EOF packet for column definition")
+		// Validate the EOF packet for column definition
+		if !mysqlUtils.IsEOFPacket(eofData) {
+			return nil, fmt.Errorf("expected EOF packet for column definition, got %v, while handling BinaryProtocolResultSet", eofData)
 		}
-		return nil, err
-	}
-
-	// Write the EOF packet for column definition to the client
-	_, err = clientConn.Write(eofData)
-	if err != nil {
-		utils.LogError(logger, err, "failed to write EOF packet for column definition to the client")
-		return nil, err
-	}
 
-	// Validate the EOF packet for column definition
-	if !mysqlUtils.IsEOFPacket(eofData) {
-		return nil, fmt.Errorf("expected EOF packet for column definition, got %v, while handling BinaryProtocolResultSet", eofData)
+		binaryResultSet.EOFAfterColumns = eofData
 	}
 
-	binaryResultSet.EOFAfterColumns = eofData
-
 	// Read the row data packets
 rowLoop:
 	for {
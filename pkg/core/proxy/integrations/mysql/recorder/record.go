@@ -13,6 +13,7 @@ import (
 	"golang.org/x/sync/errgroup"
 
 	"go.keploy.io/server/v2/pkg/core/proxy/integrations/mysql/wire"
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations/mysql/wire/phase/query/preparedstmt"
 	pUtil "go.keploy.io/server/v2/pkg/core/proxy/util"
 	"go.keploy.io/server/v2/pkg/models"
 	"go.keploy.io/server/v2/pkg/models/mysql"
@@ -49,7 +50,7 @@ func Record(ctx context.Context, logger *zap.Logger, clientConn, destConn net.Co
 			// Map for storing server greetings (inc capabilities, auth plugin, etc) per initial handshake (per connection)
 			ServerGreetings: wire.NewGreetings(),
 			// Map for storing prepared statements per connection
-			PreparedStatements: make(map[uint32]*mysql.StmtPrepareOkPacket),
+			PreparedStatements: make(map[uint32]*preparedstmt.Statement),
 		}
 		decodeCtx.LastOp.Store(clientConn, wire.RESET) //resetting last command for new loop
 
@@ -63,6 +64,9 @@ func Record(ctx context.Context, logger *zap.Logger, clientConn, destConn net.Co
 		requests = append(requests, result.req...)
 		responses = append(responses, result.resp...)
 
+		// the conns are upgraded to tls when the client asked for it during the handshake
+		clientConn, destConn := result.clientConn, result.destConn
+
 		reqTimestamp := result.reqTimestamp
 
 		recordMock(ctx, requests, responses, "config", result.requestOperation, result.responseOperation, mocks, reqTimestamp)
@@ -74,8 +78,17 @@ func Record(ctx context.Context, logger *zap.Logger, clientConn, destConn net.Co
 		lstOp, _ := decodeCtx.LastOp.Load(clientConn)
 		logger.Debug("last operation after initial handshake", zap.Any("last operation", lstOp))
 
+		// the packets of the command phase are wrapped in compressed packets when the handshake negotiated it
+		clientPeer, destPeer := clientConn, destConn
+		if algorithm := wire.Compression(clientConn, decodeCtx); algorithm != "" {
+			logger.Debug("the mysql connection is compressed", zap.Any("algorithm", algorithm))
+			clientPeer = wire.NewCompressedConn(clientConn, algorithm, decodeCtx.ZstdCompressionLevel)
+			destPeer = wire.NewCompressedConn(destConn, algorithm, decodeCtx.ZstdCompressionLevel)
+			decodeCtx.Rebind(clientConn, clientPeer)
+		}
+
 		// handle the client-server interaction (command phase)
-		err = handleClientQueries(ctx, logger, clientConn, destConn, mocks, decodeCtx)
+		err = handleClientQueries(ctx, logger, clientPeer, destPeer, mocks, decodeCtx)
 		if err != nil {
 			if err != io.EOF {
 				utils.LogError(logger, err, "failed to handle client queries")
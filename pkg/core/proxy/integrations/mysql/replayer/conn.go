@@ -3,7 +3,9 @@
 package replayer
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
 	"fmt"
 	"net"
 
@@ -22,7 +24,7 @@ type reqResp struct {
 }
 
 // Replay mode
-func simulateInitialHandshake(ctx context.Context, logger *zap.Logger, clientConn net.Conn, mocks []*models.Mock, mockDb integrations.MockMemDb, decodeCtx *wire.DecodeContext) error {
+func simulateInitialHandshake(ctx context.Context, logger *zap.Logger, clientConn net.Conn, mocks []*models.Mock, mockDb integrations.MockMemDb, decodeCtx *wire.DecodeContext, opts models.OutgoingOptions) (net.Conn, error) {
 	// Get the mock for initial handshake
 	initialHandshakeMock := mocks[0]
 
@@ -32,13 +34,13 @@ func simulateInitialHandshake(ctx context.Context, logger *zap.Logger, clientCon
 
 	if len(resp) == 0 || len(req) == 0 {
 		utils.LogError(logger, nil, "no mysql mocks found for initial handshake")
-		return nil
+		return clientConn, nil
 	}
 
 	handshake, ok := resp[0].Message.(*mysql.HandshakeV10Packet)
 	if !ok {
 		utils.LogError(logger, nil, "failed to assert handshake packet")
-		return nil
+		return clientConn, nil
 	}
 
 	// Store the server greetings
@@ -51,182 +53,276 @@ func simulateInitialHandshake(ctx context.Context, logger *zap.Logger, clientCon
 	buf, err := wire.EncodeToBinary(ctx, logger, &resp[0].PacketBundle, clientConn, decodeCtx)
 	if err != nil {
 		utils.LogError(logger, err, "failed to encode handshake packet")
-		return err
+		return nil, err
 	}
 
 	// Write the initial handshake to the client
 	_, err = clientConn.Write(buf)
 	if err != nil {
 		if ctx.Err() != nil {
-			return ctx.Err()
+			return nil, ctx.Err()
 		}
 		utils.LogError(logger, err, "failed to write server greetings to the client")
 
-		return err
+		return nil, err
 	}
 
 	// Read the client request
-	handshakeResponseBuf, err := mysqlUtils.ReadPacketBuffer(ctx, logger, clientConn)
+	pkt, err := readHandshakeResponse(ctx, logger, clientConn, decodeCtx)
 	if err != nil {
-		utils.LogError(logger, err, "failed to read handshake response from client")
-		return err
+		return nil, err
 	}
 
-	// Decode the handshakeResponse
-	pkt, err := wire.DecodePayload(ctx, logger, handshakeResponseBuf, clientConn, decodeCtx)
-	if err != nil {
-		utils.LogError(logger, err, "failed to decode handshake response from client")
-		return err
+	// The client asks for tls with an SSLRequest, the handshake response follows over tls
+	_, clientTLS := pkt.Message.(*mysql.SSLRequestPacket)
+	if clientTLS {
+		logger.Debug("the client asked for tls, upgrading the mysql connection")
+		clientConn, _, err = wire.UpgradeClient(ctx, clientConn, decodeCtx)
+		if err != nil {
+			utils.LogError(logger, err, "failed to upgrade the client connection to tls")
+			return nil, err
+		}
+
+		pkt, err = readHandshakeResponse(ctx, logger, clientConn, decodeCtx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// The SSLRequest takes a sequence id, so the recorded packets are shifted when only one of the recorded
+	// client and the client asked for tls
+	recordedTLS := req[0].Header.Type == mysql.SSLRequest
+	if recordedTLS {
+		req = req[1:]
+		if len(req) == 0 {
+			utils.LogError(logger, nil, "no mysql mocks found for the handshake response after the ssl request")
+			return clientConn, nil
+		}
+	}
+	if clientTLS != recordedTLS {
+		shift := 1
+		if recordedTLS {
+			shift = -1
+		}
+		shifted := shiftSequence(reqResp{req: req, resp: resp[1:]}, shift)
+		req, resp = shifted.req, append([]mysql.Response{resp[0]}, shifted.resp...)
 	}
 
-	_, ok = pkt.Message.(*mysql.HandshakeResponse41Packet)
+	actual, ok := pkt.Message.(*mysql.HandshakeResponse41Packet)
 	if !ok {
 		utils.LogError(logger, nil, "failed to assert actual handshake response packet")
-		return nil
+		return clientConn, nil
 	}
 
 	// Get the handshake response from the mock
-	_, ok = req[0].Message.(*mysql.HandshakeResponse41Packet)
+	recorded, ok := req[0].Message.(*mysql.HandshakeResponse41Packet)
 	if !ok {
 		utils.LogError(logger, nil, "failed to assert mock handshake response packet")
-		return nil
+		return clientConn, nil
 	}
 
 	// Match the handshake response from the client with the mock
 	logger.Debug("matching handshake response", zap.Any("actual", pkt), zap.Any("mock", req[0].PacketBundle))
-	err = matchHanshakeResponse41(ctx, logger, req[0].PacketBundle, *pkt)
+	err = matchHanshakeResponse41(ctx, logger, req[0].PacketBundle, *pkt, opts.MySQL.IgnoreConnectionAttributes)
 	if err != nil {
 		utils.LogError(logger, err, "error while matching handshakeResponse41")
-		return err
+		return nil, err
 	}
 
 	// Get the next response in order to find the auth mechanism
 	if len(resp) < 2 {
 		utils.LogError(logger, nil, "no mysql mocks found for auth mechanism")
-		return nil
+		return clientConn, nil
 	}
 
-	// Get the next packet to decide the auth mechanism or auth switching
-	// For Native password: next packet is Ok/Err
-	// For CachingSha2 password: next packet is AuthMoreData
-
-	authDecider := resp[1].PacketBundle.Header.Type
-
-	var authSwitched bool
+	// The mocks of the auth mechanism, they follow the auth switch when the server switched the auth mechanism
+	authMocks := reqResp{req: req[1:], resp: resp[1:]}
+	// seqShift is the difference between the sequence ids of the client and the recorded ones, when the
+	// auth switch is skipped or added for a client of another plugin than the recorded client
+	seqShift := 0
 
 	// Check if the next packet is AuthSwitchRequest
 	// Server sends AuthSwitchRequest when it wants to switch the auth mechanism
-	if authDecider == mysql.AuthStatusToString(mysql.AuthSwitchRequest) {
-		logger.Debug("Auth switch request found, switching the auth mechanism")
-		authSwitched = true
-
-		// Get the AuthSwitchRequest packet
-		authSwithReqPkt, ok := resp[1].Message.(*mysql.AuthSwitchRequestPacket)
-		if !ok {
-			utils.LogError(logger, nil, "failed to assert auth switch request packet")
-			return nil
+	if authSwitchReqPkt, ok := resp[1].Message.(*mysql.AuthSwitchRequestPacket); ok {
+		if len(req) < 2 || req[1].Header.Type != mysql.AuthSwithResponse {
+			utils.LogError(logger, nil, "no mysql mocks found for auth switch response")
+			return nil, fmt.Errorf("no mysql mocks found for auth switch response")
 		}
+		authMocks = reqResp{req: req[2:], resp: resp[2:]}
 
 		// Change the auth plugin name
-		decodeCtx.PluginName = authSwithReqPkt.PluginName
-
-		// Encode the AuthSwitchRequest packet
-		buf, err = wire.EncodeToBinary(ctx, logger, &resp[1].PacketBundle, clientConn, decodeCtx)
-		if err != nil {
-			utils.LogError(logger, err, "failed to encode auth switch request packet")
-			return err
-		}
+		decodeCtx.PluginName = authSwitchReqPkt.PluginName
 
-		// Write the AuthSwitchRequest packet to the client
-		_, err = clientConn.Write(buf)
-		if err != nil {
-			if ctx.Err() != nil {
-				return ctx.Err()
+		if actual.AuthPluginName == authSwitchReqPkt.PluginName {
+			// The client starts with the plugin the server switched to, so the server doesn't switch it
+			logger.Debug("the client already uses the switched auth mechanism, skipping the auth switch", zap.String("plugin", actual.AuthPluginName))
+			seqShift = -2
+		} else {
+			logger.Debug("Auth switch request found, switching the auth mechanism")
+			err := simulateAuthSwitch(ctx, logger, clientConn, resp[1].PacketBundle, req[1].Header.Header.SequenceID, decodeCtx)
+			if err != nil {
+				return nil, err
 			}
-			utils.LogError(logger, err, "failed to write auth switch request to the client")
-			return err
 		}
-
-		// Read the auth switch response from the client
-		authSwitchRespBuf, err := mysqlUtils.ReadPacketBuffer(ctx, logger, clientConn)
+	} else if plugin := authPlugin(recorded, handshake); actual.AuthPluginName != plugin {
+		// The server authenticated the recorded client with its own plugin, so the client of another plugin
+		// is switched to it with the scramble of the server greetings
+		logger.Debug("the client uses another auth mechanism than the recorded client, switching the auth mechanism", zap.String("actual", actual.AuthPluginName), zap.String("recorded", plugin))
+		decodeCtx.PluginName = plugin
+		authSwitchReq := newAuthSwitchRequest(plugin, handshake.AuthPluginData, pkt.Header.Header.SequenceID+1)
+		err := simulateAuthSwitch(ctx, logger, clientConn, authSwitchReq, authSwitchReq.Header.Header.SequenceID+1, decodeCtx)
 		if err != nil {
-			utils.LogError(logger, err, "failed to read auth switch response from the client")
-			return err
+			return nil, err
 		}
+		seqShift = 2
+	}
+
+	// Get the next packet to decide the auth mechanism
+	// For Native password: next packet is Ok/Err
+	// For CachingSha2 password: next packet is AuthMoreData
+	if len(authMocks.resp) < 1 {
+		utils.LogError(logger, nil, "no mysql mocks found for auth mechanism after auth switch request")
+		return clientConn, nil
+	}
+	if seqShift != 0 {
+		authMocks = shiftSequence(authMocks, seqShift)
+	}
 
-		// Get the packet from the buffer
-		authSwitchRespPkt, err := mysqlUtils.BytesToMySQLPacket(authSwitchRespBuf)
+	switch authMocks.resp[0].PacketBundle.Header.Type {
+	case mysql.StatusToString(mysql.OK):
+		// It means we need to simulate the native password
+		err := simulateNativePassword(ctx, logger, clientConn, authMocks, initialHandshakeMock, mockDb, decodeCtx)
 		if err != nil {
-			utils.LogError(logger, err, "failed to convert auth switch response to packet")
-			return err
+			utils.LogError(logger, err, "failed to simulate native password")
+			return nil, err
 		}
 
-		if len(req) < 2 {
-			utils.LogError(logger, nil, "no mysql mocks found for auth switch response")
-			return fmt.Errorf("no mysql mocks found for auth switch response")
+	case mysql.AuthStatusToString(mysql.AuthMoreData):
+		// It means we need to simulate the caching_sha2_password
+		err := simulateCacheSha2Password(ctx, logger, clientConn, authMocks, initialHandshakeMock, mockDb, decodeCtx)
+		if err != nil {
+			utils.LogError(logger, err, "failed to simulate caching_sha2_password")
+			return nil, err
 		}
+	}
 
-		// Get the auth switch response from the mock
-		authSwitchRespMock := req[1].PacketBundle
+	return clientConn, nil
+}
 
-		if authSwitchRespMock.Header.Type != mysql.AuthSwithResponse {
-			utils.LogError(logger, nil, "expected auth switch response mock not found", zap.Any("found", authSwitchRespMock.Header.Type))
-			return fmt.Errorf("expected %s but found %s", mysql.AuthSwithResponse, authSwitchRespMock.Header.Type)
-		}
+// simulateAuthSwitch sends the AuthSwitchRequest to the client and reads its AuthSwitchResponse.
+func simulateAuthSwitch(ctx context.Context, logger *zap.Logger, clientConn net.Conn, authSwitchReq mysql.PacketBundle, respSequenceID uint8, decodeCtx *wire.DecodeContext) error {
+	// Encode the AuthSwitchRequest packet
+	buf, err := wire.EncodeToBinary(ctx, logger, &authSwitchReq, clientConn, decodeCtx)
+	if err != nil {
+		utils.LogError(logger, err, "failed to encode auth switch request packet")
+		return err
+	}
 
-		// Since auth switch response data can be different, we should just check the sequence number
-		if authSwitchRespMock.Header.Header.SequenceID != authSwitchRespPkt.Header.SequenceID {
-			utils.LogError(logger, nil, "sequence number mismatch for auth switch response", zap.Any("expected", authSwitchRespMock.Header.Header.SequenceID), zap.Any("actual", authSwitchRespPkt.Header.SequenceID))
-			return fmt.Errorf("sequence number mismatch for auth switch response")
+	// Write the AuthSwitchRequest packet to the client
+	_, err = clientConn.Write(buf)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
 		}
+		utils.LogError(logger, err, "failed to write auth switch request to the client")
+		return err
+	}
 
-		logger.Debug("auth mechanism switched successfully")
+	// Read the auth switch response from the client
+	authSwitchRespBuf, err := mysqlUtils.ReadPacketBuffer(ctx, logger, clientConn)
+	if err != nil {
+		utils.LogError(logger, err, "failed to read auth switch response from the client")
+		return err
+	}
 
-		// Get the next packet to decide the auth mechanism
-		if len(resp) < 3 {
-			utils.LogError(logger, nil, "no mysql mocks found for auth mechanism after auth switch request")
-			return nil
-		}
+	// Get the packet from the buffer
+	authSwitchRespPkt, err := mysqlUtils.BytesToMySQLPacket(authSwitchRespBuf)
+	if err != nil {
+		utils.LogError(logger, err, "failed to convert auth switch response to packet")
+		return err
+	}
 
-		authDecider = resp[2].PacketBundle.Header.Type
+	// Since auth switch response data can be different, we should just check the sequence number
+	if authSwitchRespPkt.Header.SequenceID != respSequenceID {
+		utils.LogError(logger, nil, "sequence number mismatch for auth switch response", zap.Any("expected", respSequenceID), zap.Any("actual", authSwitchRespPkt.Header.SequenceID))
+		return fmt.Errorf("sequence number mismatch for auth switch response")
 	}
 
-	switch authDecider {
-	case mysql.StatusToString(mysql.OK):
-		var nativePassMocks reqResp
-		if authSwitched {
-			nativePassMocks.resp = resp[2:]
-		} else {
-			nativePassMocks.resp = resp[1:]
-		}
+	logger.Debug("auth mechanism switched successfully")
+	return nil
+}
 
-		// It means we need to simulate the native password
-		err := simulateNativePassword(ctx, logger, clientConn, nativePassMocks, initialHandshakeMock, mockDb, decodeCtx)
-		if err != nil {
-			utils.LogError(logger, err, "failed to simulate native password")
-			return err
-		}
+// readHandshakeResponse reads the handshake response of the client, or its SSLRequest.
+func readHandshakeResponse(ctx context.Context, logger *zap.Logger, clientConn net.Conn, decodeCtx *wire.DecodeContext) (*mysql.PacketBundle, error) {
+	handshakeResponseBuf, err := mysqlUtils.ReadPacketBuffer(ctx, logger, clientConn)
+	if err != nil {
+		utils.LogError(logger, err, "failed to read handshake response from client")
+		return nil, err
+	}
 
-	case mysql.AuthStatusToString(mysql.AuthMoreData):
+	// Decode the handshakeResponse
+	pkt, err := wire.DecodePayload(ctx, logger, handshakeResponseBuf, clientConn, decodeCtx)
+	if err != nil {
+		utils.LogError(logger, err, "failed to decode handshake response from client")
+		return nil, err
+	}
+	return pkt, nil
+}
 
-		var cacheSha2PassMock reqResp
-		if authSwitched {
-			cacheSha2PassMock.req = req[2:]
-			cacheSha2PassMock.resp = resp[2:]
-		} else {
-			cacheSha2PassMock.req = req[1:]
-			cacheSha2PassMock.resp = resp[1:]
-		}
+// authPlugin returns the plugin the server authenticated the recorded client with when it didn't switch it,
+// which is the plugin of the client or else the one of the server greetings.
+func authPlugin(recorded *mysql.HandshakeResponse41Packet, handshake *mysql.HandshakeV10Packet) string {
+	if recorded.AuthPluginName != "" {
+		return recorded.AuthPluginName
+	}
+	return handshake.AuthPluginName
+}
 
-		// It means we need to simulate the caching_sha2_password
-		err := simulateCacheSha2Password(ctx, logger, clientConn, cacheSha2PassMock, initialHandshakeMock, mockDb, decodeCtx)
-		if err != nil {
-			utils.LogError(logger, err, "failed to simulate caching_sha2_password")
-			return err
-		}
+// newAuthSwitchRequest returns the AuthSwitchRequest switching the client to the plugin, the scramble of the
+// server greetings is sent again with its null terminator like the servers do.
+func newAuthSwitchRequest(plugin string, scramble []byte, sequenceID uint8) mysql.PacketBundle {
+	pluginData := append(append([]byte{}, bytes.TrimRight(scramble, "\x00")...), 0x00)
+	return mysql.PacketBundle{
+		Header: &mysql.PacketInfo{
+			Header: &mysql.Header{
+				PayloadLength: uint32(1 + len(plugin) + 1 + len(pluginData)),
+				SequenceID:    sequenceID,
+			},
+			Type: mysql.AuthStatusToString(mysql.AuthSwitchRequest),
+		},
+		Message: &mysql.AuthSwitchRequestPacket{
+			StatusTag:  mysql.AuthSwitchRequest,
+			PluginName: plugin,
+			PluginData: base64.RawStdEncoding.EncodeToString(pluginData),
+		},
 	}
+}
 
-	return nil
+// shiftSequence returns the mocks with their sequence ids shifted, the headers are copied since the mocks
+// are shared by the conns.
+func shiftSequence(mocks reqResp, shift int) reqResp {
+	shifted := func(bundle mysql.PacketBundle) mysql.PacketBundle {
+		header := *bundle.Header.Header
+		header.SequenceID = uint8(int(header.SequenceID) + shift)
+		info := *bundle.Header
+		info.Header = &header
+		bundle.Header = &info
+		return bundle
+	}
+
+	res := reqResp{
+		req:  make([]mysql.Request, len(mocks.req)),
+		resp: make([]mysql.Response, len(mocks.resp)),
+	}
+	for i, r := range mocks.req {
+		res.req[i] = r
+		res.req[i].PacketBundle = shifted(r.PacketBundle)
+	}
+	for i, r := range mocks.resp {
+		res.resp[i] = r
+		res.resp[i].PacketBundle = shifted(r.PacketBundle)
+	}
+	return res
 }
 
 func simulateNativePassword(ctx context.Context, logger *zap.Logger, clientConn net.Conn, nativePassMocks reqResp, initialHandshakeMock *models.Mock, mockDb integrations.MockMemDb, decodeCtx *wire.DecodeContext) error {
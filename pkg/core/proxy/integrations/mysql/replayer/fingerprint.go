@@ -0,0 +1,196 @@
+//go:build linux
+
+package replayer
+
+import (
+	"strings"
+)
+
+// The strictness of the fallback matching of the queries which aren't recorded as they are sent.
+const (
+	// queryMatchExact matches the queries on their text only
+	queryMatchExact = "exact"
+	// queryMatchNormalized ignores the whitespace, the comments and the case outside of the literals
+	queryMatchNormalized = "normalized"
+	// queryMatchFingerprint ignores the values of the literals and the size of the IN lists as well
+	queryMatchFingerprint = "fingerprint"
+)
+
+// fingerprint returns the normalized text of a query for the strictness, the queries with the same
+// fingerprint differ only by what the strictness ignores. It is empty for the exact matching.
+func fingerprint(query, strictness string) string {
+	switch strictness {
+	case queryMatchNormalized:
+		return strings.Join(tokenize(query, false), " ")
+	case queryMatchFingerprint, "":
+		return strings.Join(collapseLists(tokenize(query, true)), " ")
+	}
+	return ""
+}
+
+// tokenize splits a query into its words, literals and punctuation, without the whitespace and the comments.
+// The words are lower cased, the literals are replaced with ? when they are masked.
+func tokenize(query string, mask bool) []string {
+	var tokens []string
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '#' || (c == '-' && strings.HasPrefix(query[i:], "-- ")):
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				return tokens
+			}
+			i += end + 1
+		case strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				return tokens
+			}
+			i += end + 4
+		case c == '\'' || c == '"' || c == '`':
+			end := quoteEnd(query, i)
+			// the quoted identifiers aren't literals
+			if mask && c != '`' {
+				tokens = append(tokens, "?")
+			} else {
+				tokens = append(tokens, query[i:end])
+			}
+			i = end
+		case isDigit(c) || (c == '.' && i+1 < len(query) && isDigit(query[i+1])):
+			end := i + 1
+			for end < len(query) && (isWordChar(query[end]) || query[end] == '.' ||
+				((query[end] == '+' || query[end] == '-') && (query[end-1] == 'e' || query[end-1] == 'E'))) {
+				end++
+			}
+			if mask {
+				tokens = append(tokens, "?")
+			} else {
+				tokens = append(tokens, strings.ToLower(query[i:end]))
+			}
+			i = end
+		case isWordChar(c) || c == '@' || c >= 0x80:
+			end := i + 1
+			for end < len(query) && (isWordChar(query[end]) || query[end] == '@' || query[end] == '.' || query[end] >= 0x80) {
+				end++
+			}
+			tokens = append(tokens, strings.ToLower(query[i:end]))
+			i = end
+		default:
+			end := i + 1
+			for _, op := range []string{"<=>", "<=", ">=", "<>", "!=", ":=", "||", "&&", "<<", ">>", "->>", "->"} {
+				if strings.HasPrefix(query[i:], op) {
+					end = i + len(op)
+					break
+				}
+			}
+			tokens = append(tokens, query[i:end])
+			i = end
+		}
+	}
+	return tokens
+}
+
+// quoteEnd returns the index after the quote closing the literal starting at i, the quotes are escaped by
+// doubling them or with a backslash.
+func quoteEnd(query string, i int) int {
+	quote := query[i]
+	for j := i + 1; j < len(query); j++ {
+		switch query[j] {
+		case '\\':
+			if quote != '`' {
+				j++
+			}
+		case quote:
+			if j+1 < len(query) && query[j+1] == quote {
+				j++
+				continue
+			}
+			return j + 1
+		}
+	}
+	return len(query)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isWordChar(c byte) bool {
+	return isDigit(c) || c == '_' || c == '$' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// collapseLists replaces the IN lists of masked literals with a single ?+, and the rows of a VALUES list
+// which are alike with a single row followed by +, so that the queries with more or less values match.
+func collapseLists(tokens []string) []string {
+	var out []string
+	for i := 0; i < len(tokens); i++ {
+		out = append(out, tokens[i])
+		switch tokens[i] {
+		case "in":
+			if end, ok := placeholderList(tokens, i+1); ok {
+				out = append(out, "(", "?+", ")")
+				i = end - 1
+			}
+		case "values", "value":
+			row, ok := rowEnd(tokens, i+1)
+			if !ok {
+				continue
+			}
+			first := tokens[i+1 : row]
+			end := row
+			for end+1 < len(tokens) && tokens[end] == "," {
+				next, ok := rowEnd(tokens, end+1)
+				if !ok || strings.Join(tokens[end+1:next], " ") != strings.Join(first, " ") {
+					break
+				}
+				end = next
+			}
+			out = append(out, first...)
+			out = append(out, "+")
+			i = end - 1
+		}
+	}
+	return out
+}
+
+// placeholderList returns the index after a list of masked literals, ( ? , ? ... ) starting at i.
+func placeholderList(tokens []string, i int) (int, bool) {
+	if i >= len(tokens) || tokens[i] != "(" {
+		return 0, false
+	}
+	for j := i + 1; j+1 < len(tokens); j += 2 {
+		if tokens[j] != "?" {
+			return 0, false
+		}
+		switch tokens[j+1] {
+		case ")":
+			return j + 2, true
+		case ",":
+		default:
+			return 0, false
+		}
+	}
+	return 0, false
+}
+
+// rowEnd returns the index after the parenthesized row starting at i.
+func rowEnd(tokens []string, i int) (int, bool) {
+	if i >= len(tokens) || tokens[i] != "(" {
+		return 0, false
+	}
+	depth := 0
+	for j := i; j < len(tokens); j++ {
+		switch tokens[j] {
+		case "(":
+			depth++
+		case ")":
+			depth--
+			if depth == 0 {
+				return j + 1, true
+			}
+		}
+	}
+	return 0, false
+}
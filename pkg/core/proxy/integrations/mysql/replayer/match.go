@@ -11,7 +11,7 @@ import (
 
 	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
 	"go.keploy.io/server/v2/pkg/core/proxy/integrations/mysql/wire"
-	intgUtil "go.keploy.io/server/v2/pkg/core/proxy/integrations/util"
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations/mysql/wire/phase/query/preparedstmt"
 	"go.keploy.io/server/v2/pkg/models"
 	"go.keploy.io/server/v2/pkg/models/mysql"
 	"go.keploy.io/server/v2/utils"
@@ -33,7 +33,7 @@ func matchHeader(expected, actual mysql.Header) bool {
 	return true
 }
 
-func matchHanshakeResponse41(_ context.Context, _ *zap.Logger, expected, actual mysql.PacketBundle) error {
+func matchHanshakeResponse41(_ context.Context, _ *zap.Logger, expected, actual mysql.PacketBundle, ignoredAttributes []string) error {
 	// Match the type
 	if expected.Header.Type != actual.Header.Type {
 		return fmt.Errorf("type mismatch for handshake response")
@@ -74,8 +74,9 @@ func matchHanshakeResponse41(_ context.Context, _ *zap.Logger, expected, actual
 		return fmt.Errorf("username mismatch for handshake response, expected: %s, actual: %s", exp.Username, act.Username)
 	}
 
-	// Match the AuthResponse
-	if string(exp.AuthResponse) != string(act.AuthResponse) {
+	// Match the AuthResponse, it is scrambled by the plugin of the client so it is only matched for the same plugin.
+	// The clients of another plugin are switched to the recorded one while simulating the handshake.
+	if exp.AuthPluginName == act.AuthPluginName && string(exp.AuthResponse) != string(act.AuthResponse) {
 		return fmt.Errorf("auth response mismatch for handshake response, expected: %s, actual: %s", string(exp.AuthResponse), string(act.AuthResponse))
 	}
 
@@ -84,22 +85,11 @@ func matchHanshakeResponse41(_ context.Context, _ *zap.Logger, expected, actual
 		return fmt.Errorf("database mismatch for handshake response, expected: %s, actual: %s", exp.Database, act.Database)
 	}
 
-	// Match the AuthPluginName
-	if exp.AuthPluginName != act.AuthPluginName {
-		return fmt.Errorf("auth plugin name mismatch for handshake response, expected: %s, actual: %s", exp.AuthPluginName, act.AuthPluginName)
+	// Match the ConnectionAttributes, the ones depending on the machine of the client are ignored
+	if err := matchConnectionAttributes(exp.ConnectionAttributes, act.ConnectionAttributes, ignoredAttributes); err != nil {
+		return err
 	}
 
-	// // Match the ConnectionAttributes
-	// if len(exp.ConnectionAttributes) != len(act.ConnectionAttributes) {
-	// 	return fmt.Errorf("connection attributes length mismatch for handshake response, expected: %d, actual: %d", len(exp.ConnectionAttributes), len(act.ConnectionAttributes))
-	// }
-
-	// for key, value := range exp.ConnectionAttributes {
-	// 	if act.ConnectionAttributes[key] != value && key != "_pid" {
-	// 		return fmt.Errorf("connection attributes mismatch for handshake response, expected: %s, actual: %s", value, act.ConnectionAttributes[key])
-	// 	}
-	// }
-
 	// Match the ZstdCompressionLevel
 	if exp.ZstdCompressionLevel != act.ZstdCompressionLevel {
 		return fmt.Errorf("zstd compression level mismatch for handshake response")
@@ -108,7 +98,7 @@ func matchHanshakeResponse41(_ context.Context, _ *zap.Logger, expected, actual
 	return nil
 }
 
-func matchCommand(ctx context.Context, logger *zap.Logger, req mysql.Request, mockDb integrations.MockMemDb, decodeCtx *wire.DecodeContext) (*mysql.Response, bool, error) {
+func matchCommand(ctx context.Context, logger *zap.Logger, req mysql.Request, mockDb integrations.MockMemDb, decodeCtx *wire.DecodeContext, opts models.OutgoingOptions) (*mysql.Response, bool, error) {
 
 	for {
 
@@ -117,7 +107,7 @@ func matchCommand(ctx context.Context, logger *zap.Logger, req mysql.Request, mo
 		}
 
 		// Get the tcs mocks from the mockDb
-		unfiltered, err := mockDb.GetUnFilteredMocks()
+		mocks, err := mockDb.GetUnFilteredMocksByKey(models.MySQL, "")
 		if err != nil {
 			if ctx.Err() != nil {
 				return nil, false, ctx.Err()
@@ -126,9 +116,6 @@ func matchCommand(ctx context.Context, logger *zap.Logger, req mysql.Request, mo
 			return nil, false, err
 		}
 
-		// Get the mysql mocks
-		mocks := intgUtil.GetMockByKind(unfiltered, "MySQL")
-
 		if len(mocks) == 0 {
 			if ctx.Err() != nil {
 				return nil, false, ctx.Err()
@@ -239,7 +226,7 @@ func matchCommand(ctx context.Context, logger *zap.Logger, req mysql.Request, mo
 					}
 				// case mysql.CommandStatusToString(mysql.COM_STMT_SEND_LONG_DATA):
 				case mysql.CommandStatusToString(mysql.COM_QUERY):
-					matchCount := matchQueryPacket(ctx, logger, mockReq.PacketBundle, req.PacketBundle)
+					matchCount := matchQueryPacket(ctx, logger, mockReq.PacketBundle, req.PacketBundle, opts.MySQL.IgnoreSessionVariables)
 					if matchCount > maxMatchedCount {
 						maxMatchedCount = matchCount
 						matchedResp = &mock.Spec.MySQLResponses[0]
@@ -254,7 +241,7 @@ func matchCommand(ctx context.Context, logger *zap.Logger, req mysql.Request, mo
 						matchedMock = mock
 					}
 				case mysql.CommandStatusToString(mysql.COM_STMT_EXECUTE):
-					matchCount := matchStmtExecutePacket(ctx, logger, mockReq.PacketBundle, req.PacketBundle)
+					matchCount := matchStmtExecutePacket(ctx, logger, mockReq.PacketBundle, req.PacketBundle, opts.MySQL.QueryMatching)
 					if matchCount > maxMatchedCount {
 						maxMatchedCount = matchCount
 						matchedResp = &mock.Spec.MySQLResponses[0]
@@ -263,6 +250,15 @@ func matchCommand(ctx context.Context, logger *zap.Logger, req mysql.Request, mo
 				}
 			}
 		}
+		// The queries which aren't recorded as they are sent fall back to the ones with the same fingerprint,
+		// before the ones matched on their header only
+		if maxMatchedCount < 3 {
+			if mock, resp := matchQueryFingerprint(req, tcsMocks, opts.MySQL.QueryMatching); mock != nil {
+				logger.Debug("matched the query on its fingerprint", zap.String("strictness", opts.MySQL.QueryMatching))
+				matchedMock, matchedResp = mock, resp
+			}
+		}
+
 		if matchedResp == nil {
 			logger.Debug("No matching mock found for the command", zap.Any("command", req))
 
@@ -283,8 +279,18 @@ func matchCommand(ctx context.Context, logger *zap.Logger, req mysql.Request, mo
 				logger.Error("failed to type assert the StmtPrepareOkPacket")
 				return nil, false, fmt.Errorf("failed to type assert the StmtPrepareOkPacket")
 			}
+			prepareReq, _ := req.Message.(*mysql.StmtPreparePacket)
+
+			// The statement gets an id of this connection, the recorded one can be used by another statement
+			// prepared on it. The mock is shared, so the response is copied.
+			prepareOk := *prepareOkResp
+			prepareOk.StatementID = decodeCtx.NewStatementID()
+			resp := *matchedResp
+			resp.Message = &prepareOk
+			matchedResp = &resp
+
 			// This prepared statement will be used in the further execute statement packets
-			decodeCtx.PreparedStatements[prepareOkResp.StatementID] = prepareOkResp
+			decodeCtx.PreparedStatements[prepareOk.StatementID] = &preparedstmt.Statement{Query: prepareReq.Query, PrepareOk: &prepareOk}
 		}
 
 		// Delete the matched mock from the mockDb
@@ -299,6 +305,44 @@ func matchCommand(ctx context.Context, logger *zap.Logger, req mysql.Request, mo
 	}
 }
 
+// matchQueryFingerprint returns the first mock of a query or of a prepared statement with the same fingerprint
+// as the request, with its response.
+func matchQueryFingerprint(req mysql.Request, mocks []*models.Mock, strictness string) (*models.Mock, *mysql.Response) {
+	if strictness == queryMatchExact {
+		return nil, nil
+	}
+	query, ok := requestQuery(req.PacketBundle)
+	if !ok {
+		return nil, nil
+	}
+	fp := fingerprint(query, strictness)
+	for _, mock := range mocks {
+		if len(mock.Spec.MySQLResponses) == 0 {
+			continue
+		}
+		for _, mockReq := range mock.Spec.MySQLRequests {
+			if mockReq.Header.Type != req.Header.Type {
+				continue
+			}
+			if recorded, ok := requestQuery(mockReq.PacketBundle); ok && fingerprint(recorded, strictness) == fp {
+				return mock, &mock.Spec.MySQLResponses[0]
+			}
+		}
+	}
+	return nil, nil
+}
+
+// requestQuery returns the sql of a COM_QUERY or of a COM_STMT_PREPARE.
+func requestQuery(bundle mysql.PacketBundle) (string, bool) {
+	switch msg := bundle.Message.(type) {
+	case *mysql.QueryPacket:
+		return msg.Query, true
+	case *mysql.StmtPreparePacket:
+		return msg.Query, true
+	}
+	return "", false
+}
+
 func matchClosePacket(_ context.Context, _ *zap.Logger, expected, actual mysql.PacketBundle) int {
 	matchCount := 0
 	// Match the type and return zero if the types are not equal
@@ -319,7 +363,7 @@ func matchClosePacket(_ context.Context, _ *zap.Logger, expected, actual mysql.P
 	return matchCount
 }
 
-func matchQueryPacket(_ context.Context, _ *zap.Logger, expected, actual mysql.PacketBundle) int {
+func matchQueryPacket(_ context.Context, _ *zap.Logger, expected, actual mysql.PacketBundle, ignoredVariables []string) int {
 	matchCount := 0
 	// Match the type and return zero if the types are not equal
 	if expected.Header.Type != actual.Header.Type {
@@ -335,6 +379,10 @@ func matchQueryPacket(_ context.Context, _ *zap.Logger, expected, actual mysql.P
 	// Match the query for query packet
 	if expectedMessage.Query == actualMessage.Query {
 		matchCount++
+	} else if matchSetStatements(expectedMessage.Query, actualMessage.Query, ignoredVariables) {
+		// The SET statements of the ignored session variables are matched as the same query, their payload
+		// length differs with the values
+		return 3
 	}
 	return matchCount
 }
@@ -359,27 +407,40 @@ func matchPreparePacket(_ context.Context, _ *zap.Logger, expected, actual mysql
 	return matchCount
 }
 
-func matchStmtExecutePacket(_ context.Context, _ *zap.Logger, expected, actual mysql.PacketBundle) int {
+func matchStmtExecutePacket(_ context.Context, _ *zap.Logger, expected, actual mysql.PacketBundle, strictness string) int {
 	matchCount := 0
 
 	// Match the type and return zero if the types are not equal
 	if expected.Header.Type != actual.Header.Type {
 		return 0
 	}
+	expectedMessage, _ := expected.Message.(*mysql.StmtExecutePacket)
+	actualMessage, _ := actual.Message.(*mysql.StmtExecutePacket)
+
+	// Match the query of the statement, the statement ids are given out anew by the mocked server. The mocks
+	// recorded without the query are matched on the statement id. The statements prepared from a query with
+	// the same fingerprint match with a lower count.
+	if expectedMessage.Query != "" && actualMessage.Query != "" {
+		switch {
+		case expectedMessage.Query == actualMessage.Query:
+			matchCount += 2
+		case strictness != queryMatchExact && fingerprint(expectedMessage.Query, strictness) == fingerprint(actualMessage.Query, strictness):
+			matchCount++
+		default:
+			return 0
+		}
+	} else if expectedMessage.StatementID == actualMessage.StatementID {
+		matchCount++
+	}
+
 	// Match the header
 	if matchHeader(*expected.Header.Header, *actual.Header.Header) {
 		matchCount += 2
 	}
-	expectedMessage, _ := expected.Message.(*mysql.StmtExecutePacket)
-	actualMessage, _ := actual.Message.(*mysql.StmtExecutePacket)
 	// Match the status
 	if expectedMessage.Status == actualMessage.Status {
 		matchCount++
 	}
-	// Match the statementID
-	if expectedMessage.StatementID == actualMessage.StatementID {
-		matchCount++
-	}
 	// Match the flags
 	if expectedMessage.Flags == actualMessage.Flags {
 		matchCount++
@@ -393,21 +454,11 @@ func matchStmtExecutePacket(_ context.Context, _ *zap.Logger, expected, actual m
 		matchCount++
 	}
 
-	// Match the newParamsBindFlag
-	if expectedMessage.NewParamsBindFlag == actualMessage.NewParamsBindFlag {
-		matchCount++
-	}
-
-	// Match the parameters
+	// Match the parameters, the bound values weigh more than the rest of the packet
 	if len(expectedMessage.Parameters) == len(actualMessage.Parameters) {
 		for i := range expectedMessage.Parameters {
-			expectedParam := expectedMessage.Parameters[i]
-			actualParam := actualMessage.Parameters[i]
-			if expectedParam.Type == actualParam.Type &&
-				expectedParam.Name == actualParam.Name &&
-				expectedParam.Unsigned == actualParam.Unsigned &&
-				bytes.Equal(expectedParam.Value, actualParam.Value) {
-				matchCount++
+			if matchParameter(expectedMessage.Parameters[i], actualMessage.Parameters[i]) {
+				matchCount += 2
 			}
 		}
 	}
@@ -415,6 +466,18 @@ func matchStmtExecutePacket(_ context.Context, _ *zap.Logger, expected, actual m
 	return matchCount
 }
 
+// matchParameter reports whether the parameters are bound to the same value. The drivers bind the types again
+// only when they change, so the flag of the binding isn't matched.
+func matchParameter(expected, actual mysql.Parameter) bool {
+	if expected.Null || actual.Null {
+		return expected.Null == actual.Null
+	}
+	return expected.Type == actual.Type &&
+		expected.Name == actual.Name &&
+		expected.Unsigned == actual.Unsigned &&
+		bytes.Equal(expected.Value, actual.Value)
+}
+
 // matching for utility commands
 func matchQuitPacket(_ context.Context, _ *zap.Logger, expected, actual mysql.PacketBundle) int {
 	matchCount := 0
@@ -66,7 +66,7 @@ func simulateCommandPhase(ctx context.Context, logger *zap.Logger, clientConn ne
 			}
 
 			// Match the request with the mock
-			resp, ok, err := matchCommand(ctx, logger, req, mockDb, decodeCtx)
+			resp, ok, err := matchCommand(ctx, logger, req, mockDb, decodeCtx, opts)
 			if err != nil {
 				if err == io.EOF {
 					return io.EOF
@@ -10,7 +10,7 @@ import (
 
 	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
 	"go.keploy.io/server/v2/pkg/core/proxy/integrations/mysql/wire"
-	intgUtil "go.keploy.io/server/v2/pkg/core/proxy/integrations/util"
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations/mysql/wire/phase/query/preparedstmt"
 	pUtil "go.keploy.io/server/v2/pkg/core/proxy/util"
 	"go.keploy.io/server/v2/pkg/models"
 	"go.keploy.io/server/v2/pkg/models/mysql"
@@ -23,15 +23,13 @@ import (
 func Replay(ctx context.Context, logger *zap.Logger, clientConn net.Conn, _ *integrations.ConditionalDstCfg, mockDb integrations.MockMemDb, opts models.OutgoingOptions) error {
 	errCh := make(chan error, 1)
 
-	unfiltered, err := mockDb.GetUnFilteredMocks()
+	// Get the mysql mocks
+	mocks, err := mockDb.GetUnFilteredMocksByKey(models.MySQL, "")
 	if err != nil {
 		utils.LogError(logger, err, "failed to get unfiltered mocks")
 		return err
 	}
 
-	// Get the mysql mocks
-	mocks := intgUtil.GetMockByKind(unfiltered, "MySQL")
-
 	if len(mocks) == 0 {
 		utils.LogError(logger, nil, "no mysql mocks found")
 		return nil
@@ -61,14 +59,15 @@ func Replay(ctx context.Context, logger *zap.Logger, clientConn net.Conn, _ *int
 			// Map for storing server greetings (inc capabilities, auth plugin, etc) per initial handshake (per connection)
 			ServerGreetings: wire.NewGreetings(),
 			// Map for storing prepared statements per connection
-			PreparedStatements: make(map[uint32]*mysql.StmtPrepareOkPacket),
+			PreparedStatements: make(map[uint32]*preparedstmt.Statement),
 			PluginName:         string(mysql.CachingSha2), // Only supported plugin for now
 		}
 		decodeCtx.LastOp.Store(clientConn, wire.RESET) //resetting last command for new loop
 
 		// Simulate the initial client-server handshake (connection phase)
 
-		err := simulateInitialHandshake(ctx, logger, clientConn, configMocks, mockDb, decodeCtx)
+		// the conn is upgraded to tls when the client asks for it during the handshake
+		clientConn, err := simulateInitialHandshake(ctx, logger, clientConn, configMocks, mockDb, decodeCtx, opts)
 		if err != nil {
 			utils.LogError(logger, err, "failed to simulate initial handshake")
 			errCh <- err
@@ -77,8 +76,16 @@ func Replay(ctx context.Context, logger *zap.Logger, clientConn net.Conn, _ *int
 
 		logger.Debug("Initial handshake completed successfully")
 
+		// the packets of the command phase are wrapped in compressed packets when the handshake negotiated it
+		clientPeer := clientConn
+		if algorithm := wire.Compression(clientConn, decodeCtx); algorithm != "" {
+			logger.Debug("the mysql connection is compressed", zap.Any("algorithm", algorithm))
+			clientPeer = wire.NewCompressedConn(clientConn, algorithm, decodeCtx.ZstdCompressionLevel)
+			decodeCtx.Rebind(clientConn, clientPeer)
+		}
+
 		// Simulate the client-server interaction (command phase)
-		err = simulateCommandPhase(ctx, logger, clientConn, mockDb, decodeCtx, opts)
+		err = simulateCommandPhase(ctx, logger, clientPeer, mockDb, decodeCtx, opts)
 		if err != nil {
 			if err != io.EOF {
 				utils.LogError(logger, err, "failed to simulate command phase")
@@ -0,0 +1,157 @@
+//go:build linux
+
+package replayer
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ref: https://dev.mysql.com/doc/refman/8.0/en/set-variable.html
+
+// ignoreAll in the ignored connection attributes or session variables ignores all of them
+const ignoreAll = "*"
+
+func isIgnored(ignored []string, name string) bool {
+	for _, n := range ignored {
+		if n == ignoreAll || strings.EqualFold(n, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchConnectionAttributes matches the CLIENT_CONNECT_ATTRS of the handshake responses, except the ignored ones.
+// A missing attribute is matched as an empty one.
+func matchConnectionAttributes(expected, actual map[string]string, ignored []string) error {
+	for key, value := range expected {
+		if !isIgnored(ignored, key) && actual[key] != value {
+			return fmt.Errorf("connection attribute %s mismatch for handshake response, expected: %s, actual: %s", key, value, actual[key])
+		}
+	}
+	for key, value := range actual {
+		if _, ok := expected[key]; !ok && !isIgnored(ignored, key) && value != "" {
+			return fmt.Errorf("connection attribute %s mismatch for handshake response, expected: , actual: %s", key, value)
+		}
+	}
+	return nil
+}
+
+// assignment is a variable set by a SET statement, with its value as written in the query
+type assignment struct {
+	name  string
+	value string
+}
+
+// setAssignments returns the session variables assigned by a SET statement, ok is false for the other queries
+// and for the statements which set something else than session variables.
+func setAssignments(query string) ([]assignment, bool) {
+	query = strings.TrimSpace(strings.TrimRight(strings.TrimSpace(query), ";"))
+	if len(query) < 4 || !strings.EqualFold(query[:4], "SET ") {
+		return nil, false
+	}
+	body := strings.TrimSpace(query[4:])
+
+	// SET NAMES and SET CHARACTER SET are a statement of their own
+	upper := strings.ToUpper(body)
+	for _, name := range []string{"NAMES", "CHARACTER SET", "CHARSET"} {
+		if strings.HasPrefix(upper, name+" ") {
+			return []assignment{{name: strings.ToLower(name), value: strings.TrimSpace(body[len(name):])}}, true
+		}
+	}
+
+	var assignments []assignment
+	for _, expr := range splitTopLevel(body) {
+		name, value, ok := cutAssignment(expr)
+		if !ok {
+			return nil, false
+		}
+		name, ok = sessionVariable(name)
+		if !ok {
+			return nil, false
+		}
+		assignments = append(assignments, assignment{name: name, value: value})
+	}
+	return assignments, len(assignments) > 0
+}
+
+// splitTopLevel splits the assignments of a SET statement on the commas outside of the quotes and the parentheses.
+func splitTopLevel(body string) []string {
+	var exprs []string
+	var quote byte
+	depth, start := 0, 0
+	for i := 0; i < len(body); i++ {
+		c := body[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote != '`' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == ',' && depth == 0:
+			exprs = append(exprs, strings.TrimSpace(body[start:i]))
+			start = i + 1
+		}
+	}
+	return append(exprs, strings.TrimSpace(body[start:]))
+}
+
+func cutAssignment(expr string) (string, string, bool) {
+	i := strings.Index(expr, "=")
+	if i <= 0 {
+		return "", "", false
+	}
+	name := strings.TrimSuffix(expr[:i], ":")
+	return strings.TrimSpace(name), strings.TrimSpace(expr[i+1:]), true
+}
+
+// sessionVariable returns the name of the variable without its SESSION or LOCAL scope, the global and the
+// persisted variables aren't session variables.
+func sessionVariable(name string) (string, bool) {
+	lower := strings.ToLower(name)
+	for _, scope := range []string{"global ", "persist ", "persist_only ", "@@global.", "@@persist.", "@@persist_only."} {
+		if strings.HasPrefix(lower, scope) {
+			return "", false
+		}
+	}
+	for _, scope := range []string{"session ", "local ", "@@session.", "@@local.", "@@"} {
+		if strings.HasPrefix(lower, scope) {
+			lower = strings.TrimSpace(lower[len(scope):])
+			break
+		}
+	}
+	lower = strings.Trim(lower, "`")
+	return lower, lower != ""
+}
+
+// matchSetStatements reports whether two SET statements assign the same session variables, with the same values
+// but for the ignored variables.
+func matchSetStatements(expected, actual string, ignored []string) bool {
+	if len(ignored) == 0 {
+		return false
+	}
+	exp, ok := setAssignments(expected)
+	if !ok {
+		return false
+	}
+	act, ok := setAssignments(actual)
+	if !ok || len(exp) != len(act) {
+		return false
+	}
+	for i := range exp {
+		if exp[i].name != act[i].name {
+			return false
+		}
+		if exp[i].value != act[i].value && !isIgnored(ignored, exp[i].name) {
+			return false
+		}
+	}
+	return true
+}
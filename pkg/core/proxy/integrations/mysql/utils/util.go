@@ -159,6 +159,12 @@ func ReadLengthEncodedInteger(b []byte) (num uint64, isNull bool, n int) {
 	return uint64(b[0]), false, 1
 }
 
+// IsProgressReport reports whether the packet is a progress report of a MariaDB server, an ERR packet with the
+// error code 0xFFFF.
+func IsProgressReport(data []byte) bool {
+	return len(data) >= 7 && data[4] == 0xFF && data[5] == 0xFF && data[6] == 0xFF
+}
+
 func IsEOFPacket(data []byte) bool {
 	if len(data) < 5 {
 		return false // Packet is too short to be valid
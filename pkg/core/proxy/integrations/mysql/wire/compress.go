@@ -0,0 +1,216 @@
+//go:build linux
+
+package wire
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+	"go.keploy.io/server/v2/pkg/models/mysql"
+)
+
+// ref: https://dev.mysql.com/doc/dev/mysql-server/latest/page_protocol_basic_compression.html
+
+type CompressionAlgorithm string
+
+const (
+	Zlib CompressionAlgorithm = "zlib"
+	Zstd CompressionAlgorithm = "zstd"
+)
+
+const (
+	// compressedHeaderSize is the size of the header of the compressed packets, the compressed length,
+	// the compressed sequence id and the uncompressed length
+	compressedHeaderSize = 7
+	// minCompressLength is the length below which the payloads are sent uncompressed, like the servers do
+	minCompressLength    = 50
+	maxCompressedPayload = 1<<24 - 1
+	defaultZstdLevel     = 3
+)
+
+// Compression returns the compression algorithm negotiated by the handshake of the client conn, it is empty when
+// the conn isn't compressed. The servers prefer zlib when the client announces both algorithms.
+func Compression(clientConn net.Conn, decodeCtx *DecodeContext) CompressionAlgorithm {
+	sg, ok := decodeCtx.ServerGreetings.Load(clientConn)
+	if !ok {
+		return ""
+	}
+	capabilities := sg.CapabilityFlags & decodeCtx.ClientCapabilities
+	switch {
+	case capabilities&mysql.CLIENT_COMPRESS != 0:
+		return Zlib
+	case capabilities&mysql.CLIENT_ZSTD_COMPRESSION_ALGORITHM != 0:
+		return Zstd
+	}
+	return ""
+}
+
+// Rebind moves the state kept for the client conn to the conn wrapping it.
+func (d *DecodeContext) Rebind(clientConn, conn net.Conn) {
+	if sg, ok := d.ServerGreetings.Load(clientConn); ok {
+		d.ServerGreetings.Store(conn, sg)
+	}
+	if lastOp, ok := d.LastOp.Load(clientConn); ok {
+		d.LastOp.Store(conn, lastOp)
+	}
+}
+
+// CompressedConn reads and writes the packets of a conn speaking the compressed protocol. The reads return the
+// packets unwrapped from the compressed packets, and every write is sent as a compressed packet.
+type CompressedConn struct {
+	net.Conn
+	algorithm CompressionAlgorithm
+	level     int
+
+	readMu  sync.Mutex
+	pending []byte
+
+	mu sync.Mutex
+	// seq is the compressed sequence id of the next compressed packet, it is counted apart from the sequence
+	// ids of the packets and starts again with every command
+	seq byte
+}
+
+// NewCompressedConn wraps the conn in the compressed protocol, the level is used by zstd only.
+func NewCompressedConn(conn net.Conn, algorithm CompressionAlgorithm, level byte) *CompressedConn {
+	c := &CompressedConn{Conn: conn, algorithm: algorithm, level: int(level)}
+	if c.level == 0 {
+		c.level = defaultZstdLevel
+	}
+	return c
+}
+
+func (c *CompressedConn) Read(p []byte) (int, error) {
+	c.readMu.Lock()
+	defer c.readMu.Unlock()
+	for len(c.pending) == 0 {
+		payload, err := c.readCompressed()
+		if err != nil {
+			return 0, err
+		}
+		c.pending = payload
+	}
+	n := copy(p, c.pending)
+	c.pending = c.pending[n:]
+	return n, nil
+}
+
+// readCompressed reads a compressed packet and returns its uncompressed payload.
+func (c *CompressedConn) readCompressed() ([]byte, error) {
+	header := make([]byte, compressedHeaderSize)
+	if _, err := io.ReadFull(c.Conn, header); err != nil {
+		return nil, err
+	}
+	compressedLength := int(header[0]) | int(header[1])<<8 | int(header[2])<<16
+	uncompressedLength := int(header[4]) | int(header[5])<<8 | int(header[6])<<16
+
+	payload := make([]byte, compressedLength)
+	if _, err := io.ReadFull(c.Conn, payload); err != nil {
+		if err == io.EOF {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.seq = header[3] + 1
+	c.mu.Unlock()
+
+	// the payloads shorter than minCompressLength are sent as they are
+	if uncompressedLength == 0 {
+		return payload, nil
+	}
+	uncompressed, err := c.decompress(payload, uncompressedLength)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress the %s compressed mysql packet: %w", c.algorithm, err)
+	}
+	return uncompressed, nil
+}
+
+func (c *CompressedConn) decompress(payload []byte, length int) ([]byte, error) {
+	var r io.ReadCloser
+	switch c.algorithm {
+	case Zstd:
+		dec, err := zstd.NewReader(bytes.NewReader(payload))
+		if err != nil {
+			return nil, err
+		}
+		r = dec.IOReadCloser()
+	default:
+		zr, err := zlib.NewReader(bytes.NewReader(payload))
+		if err != nil {
+			return nil, err
+		}
+		r = zr
+	}
+	defer r.Close()
+
+	uncompressed := make([]byte, length)
+	if _, err := io.ReadFull(r, uncompressed); err != nil {
+		return nil, err
+	}
+	return uncompressed, nil
+}
+
+// Write sends the packets in compressed packets. The packets with the sequence id 0 start a command, so the
+// compressed sequence ids start again from them.
+func (c *CompressedConn) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(p) >= 4 && p[3] == 0 {
+		c.seq = 0
+	}
+
+	for written := 0; written < len(p); {
+		chunk := p[written:min(len(p), written+maxCompressedPayload)]
+		payload, uncompressedLength := chunk, 0
+		if len(chunk) >= minCompressLength {
+			compressed, err := c.compress(chunk)
+			if err != nil {
+				return written, fmt.Errorf("failed to compress the mysql packet with %s: %w", c.algorithm, err)
+			}
+			if len(compressed) < len(chunk) {
+				payload, uncompressedLength = compressed, len(chunk)
+			}
+		}
+
+		header := []byte{
+			byte(len(payload)), byte(len(payload) >> 8), byte(len(payload) >> 16),
+			c.seq,
+			byte(uncompressedLength), byte(uncompressedLength >> 8), byte(uncompressedLength >> 16),
+		}
+		if _, err := c.Conn.Write(append(header, payload...)); err != nil {
+			return written, err
+		}
+		c.seq++
+		written += len(chunk)
+	}
+	return len(p), nil
+}
+
+func (c *CompressedConn) compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch c.algorithm {
+	case Zstd:
+		enc, err := zstd.NewWriter(&buf, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(c.level)))
+		if err != nil {
+			return nil, err
+		}
+		w = enc
+	default:
+		w = zlib.NewWriter(&buf)
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
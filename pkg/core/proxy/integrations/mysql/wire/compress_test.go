@@ -0,0 +1,111 @@
+//go:build linux
+
+package wire
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+)
+
+// bufferConn is a conn reading back what was written to it.
+type bufferConn struct {
+	net.Conn
+	buf bytes.Buffer
+}
+
+func (c *bufferConn) Read(p []byte) (int, error)  { return c.buf.Read(p) }
+func (c *bufferConn) Write(p []byte) (int, error) { return c.buf.Write(p) }
+
+func packet(seq byte, payload []byte) []byte {
+	n := len(payload)
+	return append([]byte{byte(n), byte(n >> 8), byte(n >> 16), seq}, payload...)
+}
+
+func TestCompressedConnRoundTrip(t *testing.T) {
+	query := packet(0, append([]byte{0x03}, bytes.Repeat([]byte("SELECT 1 UNION "), 20)...))
+	ping := packet(0, []byte{0x0e})
+	rows := packet(1, bytes.Repeat([]byte{0xab}, maxCompressedPayload+10))
+
+	for _, algorithm := range []CompressionAlgorithm{Zlib, Zstd} {
+		t.Run(string(algorithm), func(t *testing.T) {
+			conn := &bufferConn{}
+			c := NewCompressedConn(conn, algorithm, 0)
+			for _, p := range [][]byte{query, ping} {
+				if _, err := c.Write(p); err != nil {
+					t.Fatalf("Write() failed: %v", err)
+				}
+			}
+			wire := conn.buf.Bytes()
+			// the query is compressed and the ping isn't, both start a command
+			if wire[3] != 0 || wire[4] == 0 {
+				t.Errorf("the header of the compressed query = % x", wire[:compressedHeaderSize])
+			}
+			header := wire[len(wire)-compressedHeaderSize-len(ping):][:compressedHeaderSize]
+			if !bytes.Equal(header, []byte{byte(len(ping)), 0, 0, 0, 0, 0, 0}) {
+				t.Errorf("the header of the uncompressed ping = % x", header)
+			}
+
+			if _, err := c.Write(rows); err != nil {
+				t.Fatalf("Write() of the rows failed: %v", err)
+			}
+			got := make([]byte, len(query)+len(ping)+len(rows))
+			if _, err := io.ReadFull(NewCompressedConn(conn, algorithm, 0), got); err != nil {
+				t.Fatalf("Read() failed: %v", err)
+			}
+			want := bytes.Join([][]byte{query, ping, rows}, nil)
+			if !bytes.Equal(got, want) {
+				t.Errorf("the packets read back differ from the packets written")
+			}
+		})
+	}
+}
+
+func TestCompressedConnSequence(t *testing.T) {
+	conn := &bufferConn{}
+	c := NewCompressedConn(conn, Zlib, 0)
+	r := NewCompressedConn(&bufferConn{}, Zlib, 0)
+
+	// the replies continue the compressed sequence ids of the command they answer
+	r.Conn.(*bufferConn).buf.Write([]byte{1, 0, 0, 4, 0, 0, 0, 0x0e})
+	if _, err := io.ReadFull(r, make([]byte, 1)); err != nil {
+		t.Fatalf("Read() failed: %v", err)
+	}
+	if _, err := r.Write(packet(1, []byte{0})); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	if seq := r.Conn.(*bufferConn).buf.Bytes()[3]; seq != 5 {
+		t.Errorf("the compressed sequence id of the reply = %d, want 5", seq)
+	}
+
+	if _, err := c.Write(packet(1, []byte{0})); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	if _, err := c.Write(packet(2, []byte{0})); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	if b := conn.buf.Bytes(); b[3] != 0 || b[compressedHeaderSize+5+3] != 1 {
+		t.Errorf("the compressed sequence ids = %d, %d, want 0, 1", b[3], b[compressedHeaderSize+5+3])
+	}
+}
+
+func TestCompressedConnMalformed(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  []byte
+	}{
+		{"truncated header", []byte{5, 0, 0}},
+		{"truncated payload", []byte{5, 0, 0, 0, 0, 0, 0, 1}},
+		{"corrupted payload", []byte{3, 0, 0, 0, 60, 0, 0, 1, 2, 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := &bufferConn{}
+			conn.buf.Write(tt.raw)
+			if _, err := NewCompressedConn(conn, Zlib, 0).Read(make([]byte, 64)); err == nil || err == io.EOF {
+				t.Errorf("Read() of % x = %v, want an error", tt.raw, err)
+			}
+		})
+	}
+}
@@ -129,11 +129,20 @@ func handleQueryStmtResponse(ctx context.Context, logger *zap.Logger, packet mys
 			pktType = string(mysql.Text)
 		}
 
-		pkt, err := query.DecodeResultSetMetadata(ctx, logger, payload, rowType)
+		pkt, err := query.DecodeResultSetMetadata(ctx, logger, payload, rowType, decodeCtx.MariaDBCapabilities)
 		if err != nil {
 			return parsedPacket, fmt.Errorf("failed to decode result set: %w", err)
 		}
 
+		// The column definitions left out by the MariaDB servers are the ones cached with the prepared statement
+		if rs, ok := pkt.(*mysql.BinaryProtocolResultSet); ok && rs.MetadataFollows != nil && *rs.MetadataFollows == 0 {
+			stmt, ok := decodeCtx.PreparedStatements[decodeCtx.executing]
+			if !ok || stmt.PrepareOk == nil {
+				return parsedPacket, fmt.Errorf("the column definitions of the prepared statement with ID %d are not cached", decodeCtx.executing)
+			}
+			rs.Columns = stmt.PrepareOk.ColumnDefs
+		}
+
 		// Do not change the last operation if the packet is a result set, it will be changed when the result set is fully received
 		setPacketInfo(ctx, parsedPacket, pkt, pktType, clientConn, lastOp, decodeCtx)
 	}
@@ -166,6 +175,19 @@ func decodePacket(ctx context.Context, logger *zap.Logger, packet mysql.Packet,
 
 	logger.Debug("payload info", zap.Any("last operation", lastOp), zap.Any("payload type", payloadType))
 
+	// The client asks for tls with an SSLRequest before the handshakeResponse41, which is sent over tls afterwards.
+	if lastOp == mysql.HandshakeV10 && connection.IsSSLRequest(payload) {
+		logger.Debug("SSLRequest packet", zap.Any("Type", payloadType))
+		pkt, err := connection.DecodeSSLRequest(ctx, payload)
+		if err != nil {
+			return parsedPacket, fmt.Errorf("failed to decode SSLRequest packet: %w", err)
+		}
+
+		// The last operation stays the same, since the handshakeResponse41 is still to come
+		setPacketInfo(ctx, parsedPacket, pkt, mysql.SSLRequest, clientConn, mysql.HandshakeV10, decodeCtx)
+		return parsedPacket, nil
+	}
+
 	// Handle handshakeResponse41 separately, because its status is not defined and can be changed with the client capabilities.
 	if lastOp == mysql.HandshakeV10 {
 		logger.Debug("HandshakeResponse41 packet", zap.Any("Type", payloadType))
@@ -178,6 +200,8 @@ func decodePacket(ctx context.Context, logger *zap.Logger, packet mysql.Packet,
 
 		// Store the client capabilities to use it later
 		decodeCtx.ClientCapabilities = pkt.CapabilityFlags
+		decodeCtx.ZstdCompressionLevel = pkt.ZstdCompressionLevel
+		decodeCtx.MariaDBCapabilities = sg.MariaDBCapabilityFlags & pkt.MariaDBCapabilityFlags
 
 		logger.Debug("HandshakeResponse41 decoded", zap.Any("parsed packet", parsedPacket))
 
@@ -217,7 +241,7 @@ func decodePacket(ctx context.Context, logger *zap.Logger, packet mysql.Packet,
 			// Do not change the last operation if the packet is a prepared statement, it will be changed when the prepared statement is fully received
 			setPacketInfo(ctx, parsedPacket, pkt, "COM_STMT_PREPARE_OK", clientConn, lastOp, decodeCtx)
 			// Store the prepared statement to use it later
-			decodeCtx.PreparedStatements[pkt.StatementID] = pkt
+			decodeCtx.PreparedStatements[pkt.StatementID] = &preparedstmt.Statement{Query: decodeCtx.preparing, PrepareOk: pkt}
 			logger.Debug("Prepared statement stored", zap.Any("statementId", pkt.StatementID), zap.Any("prepared statement", pkt))
 			logger.Debug("COM_STMT_PREPARE_OK decoded", zap.Any("parsed packet", parsedPacket))
 
@@ -379,16 +403,18 @@ func decodePacket(ctx context.Context, logger *zap.Logger, packet mysql.Packet,
 		}
 
 		setPacketInfo(ctx, parsedPacket, pkt, mysql.CommandStatusToString(mysql.COM_STMT_PREPARE), clientConn, mysql.COM_STMT_PREPARE, decodeCtx)
+		decodeCtx.preparing = pkt.Query
 		logger.Debug("COM_STMT_PREPARE decoded", zap.Any("parsed packet", parsedPacket))
 
 	case payloadType == mysql.COM_STMT_EXECUTE:
 		logger.Debug("COM_STMT_EXECUTE packet", zap.Any("Type", payloadType))
-		pkt, err := preparedstmt.DecodeStmtExecute(ctx, logger, payload, decodeCtx.PreparedStatements)
+		pkt, err := preparedstmt.DecodeStmtExecute(ctx, logger, payload, decodeCtx.PreparedStatements, decodeCtx.ClientCapabilities)
 		if err != nil {
 			return parsedPacket, fmt.Errorf("failed to decode COM_STMT_EXECUTE packet: %w", err)
 		}
 
 		setPacketInfo(ctx, parsedPacket, pkt, mysql.CommandStatusToString(mysql.COM_STMT_EXECUTE), clientConn, mysql.COM_STMT_EXECUTE, decodeCtx)
+		decodeCtx.executing = pkt.StatementID
 		logger.Debug("COM_STMT_EXECUTE decoded", zap.Any("parsed packet", parsedPacket))
 
 	// case payloadType == mysql.COM_STMT_FETCH:
@@ -128,7 +128,7 @@ func EncodeToBinary(ctx context.Context, logger *zap.Logger, packet *mysql.Packe
 			return nil, fmt.Errorf("Expected StmtPrepareOkPacket, got %T", packet.Message)
 		}
 
-		data, err = preparedstmt.EncodePrepareOk(ctx, logger, pkt)
+		data, err = preparedstmt.EncodePrepareOk(ctx, logger, pkt, decodeCtx.MariaDBCapabilities)
 		if err != nil {
 			return nil, fmt.Errorf("error encoding StmtPrepareOkPacket: %v", err)
 		}
@@ -141,7 +141,7 @@ func EncodeToBinary(ctx context.Context, logger *zap.Logger, packet *mysql.Packe
 			return nil, fmt.Errorf("Expected TextResultSet, got %T", packet.Message)
 		}
 
-		data, err = query.EncodeTextResultSet(ctx, logger, pkt)
+		data, err = query.EncodeTextResultSet(ctx, logger, pkt, decodeCtx.MariaDBCapabilities)
 		if err != nil {
 			return nil, fmt.Errorf("error encoding TextResultSet: %v", err)
 		}
@@ -152,7 +152,7 @@ func EncodeToBinary(ctx context.Context, logger *zap.Logger, packet *mysql.Packe
 			return nil, fmt.Errorf("Expected BinaryProtocolResultSet, got %T", packet.Message)
 		}
 
-		data, err = query.EncodeBinaryResultSet(ctx, logger, pkt)
+		data, err = query.EncodeBinaryResultSet(ctx, logger, pkt, decodeCtx.MariaDBCapabilities)
 		if err != nil {
 			return nil, fmt.Errorf("error encoding BinaryProtocolResultSet: %v", err)
 		}
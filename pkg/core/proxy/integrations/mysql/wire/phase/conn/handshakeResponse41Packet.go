@@ -35,6 +35,11 @@ func DecodeHandshakeResponse41(_ context.Context, _ *zap.Logger, data []byte) (*
 	copy(packet.Filler[:], data[:23])
 	data = data[23:]
 
+	// The MariaDB clients send their capabilities in the last 4 bytes of the filler
+	if packet.CapabilityFlags&mysql.CLIENT_MYSQL == 0 {
+		packet.MariaDBCapabilityFlags = binary.LittleEndian.Uint32(packet.Filler[19:])
+	}
+
 	idx := bytes.IndexByte(data, 0x00)
 	if idx == -1 {
 		return nil, errors.New("malformed handshake response packet: missing null terminator for Username")
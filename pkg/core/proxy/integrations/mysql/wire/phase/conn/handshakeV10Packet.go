@@ -77,7 +77,14 @@ func DecodeHandshakeV10(_ context.Context, _ *zap.Logger, data []byte) (*mysql.H
 		data = data[1:] // constant 0x00
 	}
 
-	data = data[10:] // Skip 10 bytes reserved (all 0s)
+	if len(data) < 10 {
+		return nil, fmt.Errorf("handshake packet too short for the reserved bytes")
+	}
+	// The MariaDB servers send their capabilities in the last 4 bytes of the reserved ones
+	if packet.CapabilityFlags&mysql.CLIENT_MYSQL == 0 {
+		packet.MariaDBCapabilityFlags = binary.LittleEndian.Uint32(data[6:10])
+	}
+	data = data[10:] // Skip 10 bytes reserved (all 0s for mysql)
 
 	if authPluginDataLen > 8 {
 		lenToRead := min(authPluginDataLen-8, len(data))
@@ -149,8 +156,12 @@ func EncodeHandshakeV10(_ context.Context, _ *zap.Logger, packet *mysql.Handshak
 		buf.WriteByte(0x00)
 	}
 
-	// Reserved (10 zero bytes)
-	buf.Write(make([]byte, 10))
+	// Reserved (10 zero bytes), the last 4 bytes are the capabilities of the MariaDB servers
+	reserved := make([]byte, 10)
+	if packet.CapabilityFlags&mysql.CLIENT_MYSQL == 0 {
+		binary.LittleEndian.PutUint32(reserved[6:], packet.MariaDBCapabilityFlags)
+	}
+	buf.Write(reserved)
 
 	// Auth-plugin-data-part-2 (remaining auth data)
 	if packet.CapabilityFlags&mysql.CLIENT_PLUGIN_AUTH != 0 && len(packet.AuthPluginData) > 8 {
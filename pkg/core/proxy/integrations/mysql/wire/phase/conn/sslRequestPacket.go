@@ -0,0 +1,57 @@
+//go:build linux
+
+package conn
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"go.keploy.io/server/v2/pkg/models/mysql"
+)
+
+//ref: https://dev.mysql.com/doc/dev/mysql-server/latest/page_protocol_connection_phase_packets_protocol_ssl_request.html
+
+// sslRequestLength is the length of the SSLRequest, the beginning of the HandshakeResponse41 up to the username
+const sslRequestLength = 32
+
+// IsSSLRequest reports whether the payload sent by the client after the server greetings is an SSLRequest.
+func IsSSLRequest(data []byte) bool {
+	return len(data) == sslRequestLength && binary.LittleEndian.Uint32(data[:4])&mysql.CLIENT_SSL != 0
+}
+
+func DecodeSSLRequest(_ context.Context, data []byte) (*mysql.SSLRequestPacket, error) {
+	if len(data) < sslRequestLength {
+		return nil, errors.New("ssl request packet too short")
+	}
+
+	packet := &mysql.SSLRequestPacket{
+		CapabilityFlags: binary.LittleEndian.Uint32(data[:4]),
+		MaxPacketSize:   binary.LittleEndian.Uint32(data[4:8]),
+		CharacterSet:    data[8],
+	}
+	copy(packet.Filler[:], data[9:32])
+
+	return packet, nil
+}
+
+func EncodeSSLRequest(_ context.Context, packet *mysql.SSLRequestPacket) ([]byte, error) {
+	buf := new(bytes.Buffer)
+
+	if err := binary.Write(buf, binary.LittleEndian, packet.CapabilityFlags); err != nil {
+		return nil, fmt.Errorf("failed to write CapabilityFlags for SSLRequestPacket: %w", err)
+	}
+	if err := binary.Write(buf, binary.LittleEndian, packet.MaxPacketSize); err != nil {
+		return nil, fmt.Errorf("failed to write MaxPacketSize for SSLRequestPacket: %w", err)
+	}
+	if err := buf.WriteByte(packet.CharacterSet); err != nil {
+		return nil, fmt.Errorf("failed to write CharacterSet for SSLRequestPacket: %w", err)
+	}
+	if _, err := buf.Write(packet.Filler[:]); err != nil {
+		return nil, fmt.Errorf("failed to write Filler for SSLRequestPacket: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
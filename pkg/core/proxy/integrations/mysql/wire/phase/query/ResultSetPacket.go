@@ -22,7 +22,7 @@ const (
 	Text
 )
 
-func DecodeResultSetMetadata(ctx context.Context, logger *zap.Logger, data []byte, rowType RowType) (interface{}, error) {
+func DecodeResultSetMetadata(ctx context.Context, logger *zap.Logger, data []byte, rowType RowType, mariaDBCapabilities uint32) (interface{}, error) {
 
 	// Decode the column count (No need to get the header as well as it is already decoded by the caller function)
 	colCount, err := rowscols.DecodeColumnCount(ctx, logger, data)
@@ -30,42 +30,65 @@ func DecodeResultSetMetadata(ctx context.Context, logger *zap.Logger, data []byt
 		return nil, fmt.Errorf("failed to decode column count packet: %w", err)
 	}
 
+	// The MariaDB servers tell whether the column definitions follow after the column count
+	var metadataFollows *byte
+	if mariaDBCapabilities&mysql.MARIADB_CLIENT_CACHE_METADATA != 0 {
+		_, _, n := utils.ReadLengthEncodedInteger(data)
+		if n >= len(data) {
+			return nil, fmt.Errorf("column count packet too short for the metadata follows flag")
+		}
+		follows := data[n]
+		metadataFollows = &follows
+	}
+
 	switch rowType {
 	case Binary:
 		return &mysql.BinaryProtocolResultSet{
-			ColumnCount: colCount,
+			ColumnCount:     colCount,
+			MetadataFollows: metadataFollows,
 		}, nil
 	case Text:
 		return &mysql.TextResultSet{
-			ColumnCount: colCount,
+			ColumnCount:     colCount,
+			MetadataFollows: metadataFollows,
 		}, nil
 	}
 	return nil, nil
 }
 
-func EncodeTextResultSet(ctx context.Context, logger *zap.Logger, resultSet *mysql.TextResultSet) ([]byte, error) {
+// skipsColumns reports whether the column definitions were left out by the MariaDB server.
+func skipsColumns(metadataFollows *byte) bool {
+	return metadataFollows != nil && *metadataFollows == 0
+}
+
+func EncodeTextResultSet(ctx context.Context, logger *zap.Logger, resultSet *mysql.TextResultSet, mariaDBCapabilities uint32) ([]byte, error) {
 	buf := new(bytes.Buffer)
 
 	// Encode the column count
 	if err := utils.WriteLengthEncodedInteger(buf, resultSet.ColumnCount); err != nil {
 		return nil, fmt.Errorf("failed to write column count for text resultset: %w", err)
 	}
+	if resultSet.MetadataFollows != nil {
+		buf.WriteByte(*resultSet.MetadataFollows)
+	}
 
-	// Encode the column definition packets
-	for _, column := range resultSet.Columns {
-		columnBytes, err := rowscols.EncodeColumn(ctx, logger, column)
-		if err != nil {
-			return nil, fmt.Errorf("failed to encode column for text resultset: %w", err)
-		}
-		if _, err := buf.Write(columnBytes); err != nil {
-			return nil, fmt.Errorf("failed to write column for text resultset: %w", err)
+	if !skipsColumns(resultSet.MetadataFollows) {
+		// Encode the column definition packets
+		for _, column := range resultSet.Columns {
+			columnBytes, err := rowscols.EncodeColumn(ctx, logger, column, mariaDBCapabilities)
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode column for text resultset: %w", err)
+			}
+			if _, err := buf.Write(columnBytes); err != nil {
+				return nil, fmt.Errorf("failed to write column for text resultset: %w", err)
+			}
 		}
-	}
 
-	// Write the EOF packet after columns if present
-	if len(resultSet.EOFAfterColumns) != 0 {
-		if _, err := buf.Write(resultSet.EOFAfterColumns); err != nil {
-			return nil, fmt.Errorf("failed to write EOF packet after columns for text resultset: %w", err)
+		// Write the EOF packet after columns if present
+		if len(resultSet.EOFAfterColumns) != 0 {
+			if _, err := buf.Write(resultSet.EOFAfterColumns); err != nil {
+				return nil, fmt.Errorf("failed to write EOF packet after columns for text resultset: %w", err)
+			}
 		}
 	}
 
@@ -89,28 +112,34 @@ func EncodeTextResultSet(ctx context.Context, logger *zap.Logger, resultSet *mys
 	return buf.Bytes(), nil
 }
 
-func EncodeBinaryResultSet(ctx context.Context, logger *zap.Logger, resultSet *mysql.BinaryProtocolResultSet) ([]byte, error) {
+func EncodeBinaryResultSet(ctx context.Context, logger *zap.Logger, resultSet *mysql.BinaryProtocolResultSet, mariaDBCapabilities uint32) ([]byte, error) {
 	buf := new(bytes.Buffer)
 
 	// Encode the column count
 	if err := utils.WriteLengthEncodedInteger(buf, resultSet.ColumnCount); err != nil {
 		return nil, fmt.Errorf("failed to write column count: %w", err)
 	}
+	if resultSet.MetadataFollows != nil {
+		buf.WriteByte(*resultSet.MetadataFollows)
+	}
 
-	// Encode the column definition packets
-	for _, column := range resultSet.Columns {
-		columnBytes, err := rowscols.EncodeColumn(ctx, logger, column)
-		if err != nil {
-			return nil, fmt.Errorf("failed to encode column: %w", err)
+	// The columns left out by the MariaDB server are only kept to encode the rows
+	if !skipsColumns(resultSet.MetadataFollows) {
+		// Encode the column definition packets
+		for _, column := range resultSet.Columns {
+			columnBytes, err := rowscols.EncodeColumn(ctx, logger, column, mariaDBCapabilities)
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode column: %w", err)
+			}
+			if _, err := buf.Write(columnBytes); err != nil {
+				return nil, fmt.Errorf("failed to write column: %w", err)
+			}
 		}
-		if _, err := buf.Write(columnBytes); err != nil {
-			return nil, fmt.Errorf("failed to write column: %w", err)
-		}
-	}
 
-	// Write the EOF packet after columns
-	if _, err := buf.Write(resultSet.EOFAfterColumns); err != nil {
-		return nil, fmt.Errorf("failed to write EOF packet after columns: %w", err)
+		// Write the EOF packet after columns
+		if _, err := buf.Write(resultSet.EOFAfterColumns); err != nil {
+			return nil, fmt.Errorf("failed to write EOF packet after columns: %w", err)
+		}
 	}
 
 	// Encode each row data packet
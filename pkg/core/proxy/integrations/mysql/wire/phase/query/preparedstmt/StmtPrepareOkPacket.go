@@ -47,7 +47,7 @@ func DecodePrepareOk(_ context.Context, _ *zap.Logger, data []byte) (*mysql.Stmt
 	return response, nil
 }
 
-func EncodePrepareOk(ctx context.Context, logger *zap.Logger, packet *mysql.StmtPrepareOkPacket) ([]byte, error) {
+func EncodePrepareOk(ctx context.Context, logger *zap.Logger, packet *mysql.StmtPrepareOkPacket, mariaDBCapabilities uint32) ([]byte, error) {
 	buf := new(bytes.Buffer)
 
 	// Encode the Prepare OK packet
@@ -61,7 +61,7 @@ func EncodePrepareOk(ctx context.Context, logger *zap.Logger, packet *mysql.Stmt
 
 	// Encode parameter definitions if present
 	for _, paramDef := range packet.ParamDefs {
-		paramBytes, err := rowscols.EncodeColumn(ctx, logger, paramDef)
+		paramBytes, err := rowscols.EncodeColumn(ctx, logger, paramDef, mariaDBCapabilities)
 		if err != nil {
 			return nil, fmt.Errorf("failed to encode parameter definition: %w", err)
 		}
@@ -79,7 +79,7 @@ func EncodePrepareOk(ctx context.Context, logger *zap.Logger, packet *mysql.Stmt
 
 	// Encode column definitions if present
 	for _, columnDef := range packet.ColumnDefs {
-		columnBytes, err := rowscols.EncodeColumn(ctx, logger, columnDef)
+		columnBytes, err := rowscols.EncodeColumn(ctx, logger, columnDef, mariaDBCapabilities)
 		if err != nil {
 			return nil, fmt.Errorf("failed to encode column definition: %w", err)
 		}
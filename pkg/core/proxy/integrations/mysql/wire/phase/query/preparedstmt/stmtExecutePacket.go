@@ -15,7 +15,19 @@ import (
 
 // COM_STMT_EXECUTE: https://dev.mysql.com/doc/dev/mysql-server/latest/page_protocol_com_stmt_execute.html
 
-func DecodeStmtExecute(_ context.Context, _ *zap.Logger, data []byte, preparedStmts map[uint32]*mysql.StmtPrepareOkPacket) (*mysql.StmtExecutePacket, error) {
+// parameterCountAvailable is the flag of the executes sending the parameter count with the query attributes
+const parameterCountAvailable = 0x08
+
+// Statement is a statement prepared on a connection. The executes are decoded with its parameter count, and with
+// the parameter types bound by the previous execute when they aren't bound again.
+type Statement struct {
+	Query     string
+	PrepareOk *mysql.StmtPrepareOkPacket
+	// Types are the parameters last bound, without their values
+	Types []mysql.Parameter
+}
+
+func DecodeStmtExecute(_ context.Context, _ *zap.Logger, data []byte, preparedStmts map[uint32]*Statement, clientCapabilities uint32) (*mysql.StmtExecutePacket, error) {
 	if len(data) < 10 {
 		return &mysql.StmtExecutePacket{}, fmt.Errorf("packet length too short for COM_STMT_EXECUTE")
 	}
@@ -24,42 +36,41 @@ func DecodeStmtExecute(_ context.Context, _ *zap.Logger, data []byte, preparedSt
 
 	packet := &mysql.StmtExecutePacket{}
 
-	// Read Status
-	if pos+1 > len(data) {
-		return nil, io.ErrUnexpectedEOF
-	}
 	//data[0] is COM_STMT_EXECUTE (0x17)
 	packet.Status = data[pos]
 	pos++
 
 	// Read StatementID
-	if pos+4 > len(data) {
-		return nil, io.ErrUnexpectedEOF
-	}
 	packet.StatementID = binary.LittleEndian.Uint32(data[pos : pos+4])
 	pos += 4
 
-	numParams, ok := preparedStmts[packet.StatementID]
-	if !ok && numParams == nil {
+	stmt, ok := preparedStmts[packet.StatementID]
+	if !ok || stmt.PrepareOk == nil {
 		return nil, fmt.Errorf("prepared statement with ID %d not found", packet.StatementID)
 	}
 
-	packet.ParameterCount = int(numParams.NumParams)
+	packet.Query = stmt.Query
+	packet.ParameterCount = int(stmt.PrepareOk.NumParams)
 
 	// Read Flags
-	if pos+1 > len(data) {
-		return nil, io.ErrUnexpectedEOF
-	}
 	packet.Flags = data[pos]
 	pos++
 
 	// Read IterationCount
-	if pos+4 > len(data) {
-		return nil, io.ErrUnexpectedEOF
-	}
 	packet.IterationCount = binary.LittleEndian.Uint32(data[pos : pos+4])
 	pos += 4
 
+	// The query attributes are sent as parameters after the ones of the statement
+	queryAttributes := clientCapabilities&mysql.CLIENT_QUERY_ATTRIBUTES != 0
+	if queryAttributes && (packet.ParameterCount > 0 || packet.Flags&parameterCountAvailable != 0) {
+		if pos >= len(data) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		count, _, n := utils.ReadLengthEncodedInteger(data[pos:])
+		packet.ParameterCount = int(count)
+		pos += n
+	}
+
 	if packet.ParameterCount <= 0 {
 		return packet, nil
 	}
@@ -81,9 +92,9 @@ func DecodeStmtExecute(_ context.Context, _ *zap.Logger, data []byte, preparedSt
 	packet.NewParamsBindFlag = data[pos]
 	pos++
 
-	// Read Parameters if NewParamsBindFlag is set
+	// Read the parameter types if they are bound again, or else reuse the ones bound by the previous execute
+	packet.Parameters = make([]mysql.Parameter, packet.ParameterCount)
 	if packet.NewParamsBindFlag == 1 {
-		packet.Parameters = make([]mysql.Parameter, packet.ParameterCount)
 		for i := 0; i < packet.ParameterCount; i++ {
 			if pos+2 > len(data) {
 				return nil, io.ErrUnexpectedEOF
@@ -91,22 +102,69 @@ func DecodeStmtExecute(_ context.Context, _ *zap.Logger, data []byte, preparedSt
 			packet.Parameters[i].Type = binary.LittleEndian.Uint16(data[pos : pos+2])
 			packet.Parameters[i].Unsigned = (data[pos+1] & 0x80) != 0 // Check if the highest bit is set
 			pos += 2
+			if queryAttributes {
+				name, _, n, err := utils.ReadLengthEncodedString(data[pos:])
+				if err != nil {
+					return nil, io.ErrUnexpectedEOF
+				}
+				packet.Parameters[i].Name = string(name)
+				pos += n
+			}
+		}
+		stmt.Types = append([]mysql.Parameter(nil), packet.Parameters...)
+	} else {
+		if len(stmt.Types) != packet.ParameterCount {
+			return nil, fmt.Errorf("the parameter types of the prepared statement with ID %d are not bound", packet.StatementID)
 		}
+		copy(packet.Parameters, stmt.Types)
 	}
 
-	// Read Parameter Values
+	// Read Parameter Values, the null parameters have no value
 	for i := 0; i < packet.ParameterCount; i++ {
-		if pos >= len(data) {
-			return nil, io.ErrUnexpectedEOF
+		if packet.NullBitmap[i/8]&(1<<(i%8)) != 0 {
+			packet.Parameters[i].Null = true
+			continue
 		}
-		length, _, n := utils.ReadLengthEncodedInteger(data[pos:])
-		pos += n
-		if pos+int(length) > len(data) {
-			return nil, io.ErrUnexpectedEOF
+		value, n, err := readParameterValue(data[pos:], mysql.FieldType(packet.Parameters[i].Type))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the value of the parameter %d: %w", i, err)
 		}
-		packet.Parameters[i].Value = data[pos : pos+int(length)]
-		pos += int(length)
+		packet.Parameters[i].Value = value
+		pos += n
 	}
 
 	return packet, nil
 }
+
+// readParameterValue reads a value of the binary protocol, the numbers have a fixed size while the dates and
+// the times are prefixed by their size, and the rest by their length.
+func readParameterValue(data []byte, fieldType mysql.FieldType) ([]byte, int, error) {
+	size := 0
+	switch fieldType {
+	case mysql.FieldTypeNULL:
+		return nil, 0, nil
+	case mysql.FieldTypeTiny:
+		size = 1
+	case mysql.FieldTypeShort, mysql.FieldTypeYear:
+		size = 2
+	case mysql.FieldTypeLong, mysql.FieldTypeInt24, mysql.FieldTypeFloat:
+		size = 4
+	case mysql.FieldTypeLongLong, mysql.FieldTypeDouble:
+		size = 8
+	case mysql.FieldTypeDate, mysql.FieldTypeDateTime, mysql.FieldTypeTimestamp, mysql.FieldTypeTime:
+		if len(data) < 1 || len(data) < 1+int(data[0]) {
+			return nil, 0, io.ErrUnexpectedEOF
+		}
+		return data[1 : 1+int(data[0])], 1 + int(data[0]), nil
+	default:
+		value, _, n, err := utils.ReadLengthEncodedString(data)
+		if err != nil {
+			return nil, 0, io.ErrUnexpectedEOF
+		}
+		return value, n, nil
+	}
+	if len(data) < size {
+		return nil, 0, io.ErrUnexpectedEOF
+	}
+	return data[:size], size, nil
+}
@@ -15,7 +15,7 @@ import (
 
 //ref: https://dev.mysql.com/doc/dev/mysql-server/latest/page_protocol_com_query_response_text_resultset_column_definition.html
 
-func DecodeColumn(_ context.Context, _ *zap.Logger, b []byte) (*mysql.ColumnDefinition41, int, error) {
+func DecodeColumn(_ context.Context, _ *zap.Logger, b []byte, mariaDBCapabilities uint32) (*mysql.ColumnDefinition41, int, error) {
 	packet := &mysql.ColumnDefinition41{
 		Header: mysql.Header{},
 	}
@@ -77,6 +77,16 @@ func DecodeColumn(_ context.Context, _ *zap.Logger, b []byte) (*mysql.ColumnDefi
 	packet.OrgName = string(orgName)
 	pos += n
 
+	// The MariaDB servers send the extended type info of the column, like json or uuid, after its names
+	if mariaDBCapabilities&mysql.MARIADB_CLIENT_EXTENDED_METADATA != 0 {
+		extendedMetadata, _, n, err := utils.ReadLengthEncodedString(b[pos:])
+		if err != nil {
+			return nil, pos, err
+		}
+		packet.ExtendedMetadata = extendedMetadata
+		pos += n
+	}
+
 	// skip [0x0c] (length of fixed-length fields)
 	packet.FixedLength = 0x0c
 	pos++
@@ -124,17 +134,9 @@ func DecodeColumn(_ context.Context, _ *zap.Logger, b []byte) (*mysql.ColumnDefi
 	return packet, pos, nil
 }
 
-func EncodeColumn(_ context.Context, _ *zap.Logger, packet *mysql.ColumnDefinition41) ([]byte, error) {
+func EncodeColumn(_ context.Context, _ *zap.Logger, packet *mysql.ColumnDefinition41, mariaDBCapabilities uint32) ([]byte, error) {
 	buf := new(bytes.Buffer)
 
-	// Write packet header
-	if err := utils.WriteUint24(buf, packet.Header.PayloadLength); err != nil {
-		return nil, fmt.Errorf("failed to write PayloadLength: %w", err)
-	}
-
-	if err := buf.WriteByte(packet.Header.SequenceID); err != nil {
-		return nil, fmt.Errorf("failed to write SequenceID: %w", err)
-	}
 	// Write Catalog
 	if err := utils.WriteLengthEncodedString(buf, packet.Catalog); err != nil {
 		return nil, fmt.Errorf("failed to write Catalog: %w", err)
@@ -163,6 +165,13 @@ func EncodeColumn(_ context.Context, _ *zap.Logger, packet *mysql.ColumnDefiniti
 		return nil, fmt.Errorf("failed to write OrgName: %w", err)
 	}
 
+	// Write the extended metadata of the MariaDB servers
+	if mariaDBCapabilities&mysql.MARIADB_CLIENT_EXTENDED_METADATA != 0 {
+		if err := utils.WriteLengthEncodedString(buf, string(packet.ExtendedMetadata)); err != nil {
+			return nil, fmt.Errorf("failed to write ExtendedMetadata: %w", err)
+		}
+	}
+
 	// Write FixedLength (0x0c)
 	if err := buf.WriteByte(packet.FixedLength); err != nil {
 		return nil, fmt.Errorf("failed to write FixedLength: %w", err)
@@ -205,5 +214,16 @@ func EncodeColumn(_ context.Context, _ *zap.Logger, packet *mysql.ColumnDefiniti
 		}
 	}
 
-	return buf.Bytes(), nil
+	// Write packet header, the payload length follows the payload since the extended metadata depends on the
+	// capabilities of the client
+	header := new(bytes.Buffer)
+	if err := utils.WriteUint24(header, uint32(buf.Len())); err != nil {
+		return nil, fmt.Errorf("failed to write PayloadLength: %w", err)
+	}
+
+	if err := header.WriteByte(packet.Header.SequenceID); err != nil {
+		return nil, fmt.Errorf("failed to write SequenceID: %w", err)
+	}
+
+	return append(header.Bytes(), buf.Bytes()...), nil
 }
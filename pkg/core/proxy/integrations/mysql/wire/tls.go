@@ -0,0 +1,50 @@
+//go:build linux
+
+package wire
+
+import (
+	"context"
+	"errors"
+	"net"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+// ref: https://dev.mysql.com/doc/dev/mysql-server/latest/page_protocol_connection_phase.html#sect_protocol_connection_phase_initial_handshake_ssl_handshake
+
+func tlsUpgrader(ctx context.Context) (integrations.TLSUpgrader, error) {
+	upgrader, ok := ctx.Value(models.TLSUpgraderKey).(integrations.TLSUpgrader)
+	if !ok {
+		return nil, errors.New("failed to get the tls upgrader from the context")
+	}
+	return upgrader, nil
+}
+
+// UpgradeClient starts tls with the client after its SSLRequest, the state kept for the client conn is moved
+// to the tls conn. It returns the server name the client asked for as well.
+func UpgradeClient(ctx context.Context, clientConn net.Conn, decodeCtx *DecodeContext) (net.Conn, string, error) {
+	upgrader, err := tlsUpgrader(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	tlsConn, err := upgrader.UpgradeClient(ctx, clientConn)
+	if err != nil {
+		return nil, "", err
+	}
+	decodeCtx.Rebind(clientConn, tlsConn)
+	return tlsConn, tlsConn.ConnectionState().ServerName, nil
+}
+
+// UpgradeDest starts tls with the server after the SSLRequest of the client was forwarded to it.
+func UpgradeDest(ctx context.Context, destConn net.Conn, serverName string) (net.Conn, error) {
+	upgrader, err := tlsUpgrader(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tlsConn, err := upgrader.UpgradeDest(ctx, destConn, serverName)
+	if err != nil {
+		return nil, err
+	}
+	return tlsConn, nil
+}
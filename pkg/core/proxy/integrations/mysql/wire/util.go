@@ -8,6 +8,7 @@ import (
 	"net"
 	"sync"
 
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations/mysql/wire/phase/query/preparedstmt"
 	"go.keploy.io/server/v2/pkg/models"
 	"go.keploy.io/server/v2/pkg/models/mysql"
 )
@@ -17,10 +18,27 @@ const RESET = 0x00
 type DecodeContext struct {
 	Mode               models.Mode
 	LastOp             *LastOperation
-	PreparedStatements map[uint32]*mysql.StmtPrepareOkPacket
+	PreparedStatements map[uint32]*preparedstmt.Statement
 	ServerGreetings    *ServerGreetings
 	ClientCapabilities uint32
-	PluginName         string
+	// ZstdCompressionLevel is the level of the zstd compression asked for by the client
+	ZstdCompressionLevel byte
+	PluginName           string
+	// MariaDBCapabilities are the MariaDB capabilities both the server and the client announced
+	MariaDBCapabilities uint32
+	// preparing is the query of the COM_STMT_PREPARE waiting for its COM_STMT_PREPARE_OK
+	preparing string
+	// executing is the statement id of the last COM_STMT_EXECUTE
+	executing uint32
+	// lastStatementID is the last statement id given out by the mocked server
+	lastStatementID uint32
+}
+
+// NewStatementID gives out the id of a statement prepared with the mocked server. The recorded ids can come from
+// several connections, so they aren't unique on the connection of the client.
+func (d *DecodeContext) NewStatementID() uint32 {
+	d.lastStatementID++
+	return d.lastStatementID
 }
 
 // This map is used to store the last operation that was performed on a connection.
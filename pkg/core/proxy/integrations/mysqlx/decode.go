@@ -0,0 +1,121 @@
+//go:build linux
+
+package mysqlx
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations/util"
+	pUtil "go.keploy.io/server/v2/pkg/core/proxy/util"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+// decodeMySQLX answers the requests of the client from the mocks. When the mock of the tls capability set by
+// the client accepted it, the client conn is upgraded to tls with a certificate of the keploy CA.
+func decodeMySQLX(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientConn net.Conn, _ *integrations.ConditionalDstCfg, mockDb integrations.MockMemDb, _ models.OutgoingOptions) error {
+	logger.Debug("Into the mysqlx parser in test mode")
+	errCh := make(chan error, 1)
+
+	go func() {
+		defer pUtil.Recover(logger, clientConn, nil)
+		defer close(errCh)
+		client := bufio.NewReader(io.MultiReader(bytes.NewReader(reqBuf), clientConn))
+		s := newSession()
+		for {
+			f, err := readFrame(client)
+			if err != nil {
+				if err != io.EOF {
+					utils.LogError(logger, err, "failed to read the mysqlx message from the client")
+				}
+				errCh <- err
+				return
+			}
+			req := decodeClient(f, s)
+
+			if req.typ == clientCapabilitiesSet && req.hasCapability("compression") {
+				if err := refuseCompression(logger, clientConn); err != nil {
+					errCh <- err
+					return
+				}
+				continue
+			}
+
+			mock, err := match(ctx, req, mockDb)
+			if err != nil {
+				utils.LogError(logger, err, "error while matching mysqlx mocks")
+				errCh <- err
+				return
+			}
+			if mock == nil {
+				err := fmt.Errorf("no mysqlx mock found for the %s request", req.model.Type)
+				utils.LogError(logger, err, "failed to mock the mysqlx request", zap.String("stmt", req.model.Stmt), zap.String("collection", req.model.Collection), zap.String("criteria", req.model.Criteria), zap.Strings("args", req.model.Args))
+				errCh <- err
+				return
+			}
+
+			var responses []*message
+			for _, resp := range mock.Spec.MySQLXResponses {
+				raw, err := util.DecodeBase64(resp.Raw)
+				if err != nil {
+					utils.LogError(logger, err, "failed to decode the base64 mysqlx response")
+					errCh <- err
+					return
+				}
+				_, err = clientConn.Write(raw)
+				if err != nil {
+					if ctx.Err() != nil {
+						return
+					}
+					utils.LogError(logger, err, "failed to write the mysqlx response to the client application")
+					errCh <- err
+					return
+				}
+				if len(raw) > headerSize {
+					responses = append(responses, &message{frame: &frame{typ: raw[headerSize], raw: raw}})
+				}
+			}
+
+			if !startsTLS(req, responses) {
+				continue
+			}
+			if client.Buffered() > 0 {
+				err := errors.New("the mysqlx client sent data before the tls handshake")
+				utils.LogError(logger, err, "failed to upgrade the mysqlx connection to tls")
+				errCh <- err
+				return
+			}
+			upgrader, ok := ctx.Value(models.TLSUpgraderKey).(integrations.TLSUpgrader)
+			if !ok {
+				errCh <- errors.New("failed to get the tls upgrader from the context")
+				return
+			}
+			tlsClient, err := upgrader.UpgradeClient(ctx, clientConn)
+			if err != nil {
+				utils.LogError(logger, err, "failed to upgrade the mysqlx client connection to tls")
+				errCh <- err
+				return
+			}
+			clientConn = tlsClient
+			client = bufio.NewReader(clientConn)
+		}
+	}()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-errCh:
+		if err == io.EOF {
+			return nil
+		}
+		return err
+	}
+}
@@ -0,0 +1,186 @@
+//go:build linux
+
+package mysqlx
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	pUtil "go.keploy.io/server/v2/pkg/core/proxy/util"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+// encodeMySQLX forwards the messages between the client and the server and records every request with its
+// response as a mock. The server answers the requests in order, with a response ending with a terminal
+// message, so the requests are forwarded one at a time. The connection is upgraded to tls on both sides when
+// the client sets the tls capability.
+func encodeMySQLX(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientConn, destConn net.Conn, mocks chan<- *models.Mock, _ models.OutgoingOptions) error {
+	g, ok := ctx.Value(models.ErrGroupKey).(*errgroup.Group)
+	if !ok {
+		return errors.New("failed to get the error group from the context")
+	}
+	connID, _ := ctx.Value(models.ClientConnectionIDKey).(string)
+	errCh := make(chan error, 1)
+
+	g.Go(func() error {
+		defer pUtil.Recover(logger, clientConn, destConn)
+		defer close(errCh)
+
+		client := bufio.NewReader(io.MultiReader(bytes.NewReader(reqBuf), clientConn))
+		server := bufio.NewReader(destConn)
+		s := newSession()
+		for {
+			f, err := readFrame(client)
+			if err != nil {
+				if err != io.EOF {
+					utils.LogError(logger, err, "failed to read the mysqlx message from the client")
+				}
+				errCh <- err
+				return nil
+			}
+			req := decodeClient(f, s)
+			logger.Debug("mysqlx request", zap.String("type", req.model.Type), zap.String("stmt", req.model.Stmt), zap.String("collection", req.model.Collection), zap.String("criteria", req.model.Criteria))
+
+			if req.typ == clientCapabilitiesSet && req.hasCapability("compression") {
+				if err := refuseCompression(logger, clientConn); err != nil {
+					errCh <- err
+					return nil
+				}
+				continue
+			}
+
+			reqTimestampMock := time.Now()
+			_, err = destConn.Write(req.raw)
+			if err != nil {
+				utils.LogError(logger, err, "failed to write the mysqlx message to the destination server")
+				errCh <- err
+				return nil
+			}
+
+			var responses []*message
+			for {
+				rf, err := readFrame(server)
+				if err != nil {
+					if err != io.EOF {
+						utils.LogError(logger, err, "failed to read the mysqlx message from the destination server")
+					}
+					errCh <- err
+					return nil
+				}
+				_, err = clientConn.Write(rf.raw)
+				if err != nil {
+					utils.LogError(logger, err, "failed to write the mysqlx message to the client")
+					errCh <- err
+					return nil
+				}
+				responses = append(responses, decodeServer(rf))
+				if isTerminal(rf.typ) {
+					break
+				}
+			}
+			resTimestampMock := time.Now()
+			saveMock(req, responses, reqTimestampMock, resTimestampMock, connID, mocks)
+
+			if !startsTLS(req, responses) {
+				continue
+			}
+			if client.Buffered() > 0 {
+				err := errors.New("the mysqlx client sent data before the tls handshake")
+				utils.LogError(logger, err, "failed to upgrade the mysqlx connection to tls")
+				errCh <- err
+				return nil
+			}
+			upgrader, ok := ctx.Value(models.TLSUpgraderKey).(integrations.TLSUpgrader)
+			if !ok {
+				errCh <- errors.New("failed to get the tls upgrader from the context")
+				return nil
+			}
+			tlsClient, err := upgrader.UpgradeClient(ctx, clientConn)
+			if err != nil {
+				utils.LogError(logger, err, "failed to upgrade the mysqlx client connection to tls")
+				errCh <- err
+				return nil
+			}
+			tlsDest, err := upgrader.UpgradeDest(ctx, destConn, tlsClient.ConnectionState().ServerName)
+			if err != nil {
+				utils.LogError(logger, err, "failed to upgrade the mysqlx destination connection to tls")
+				errCh <- err
+				return nil
+			}
+			clientConn, destConn = tlsClient, tlsDest
+			client, server = bufio.NewReader(clientConn), bufio.NewReader(destConn)
+		}
+	})
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-errCh:
+		if err == io.EOF {
+			return nil
+		}
+		return err
+	}
+}
+
+// startsTLS reports whether the tls capability set by the client was accepted by the server, the tls
+// handshake follows the Ok.
+func startsTLS(req *message, responses []*message) bool {
+	return req.typ == clientCapabilitiesSet && req.hasCapability("tls") &&
+		len(responses) > 0 && responses[len(responses)-1].typ == serverOk
+}
+
+// refuseCompression answers the client setting the compression capability with the error of the servers
+// which don't support it, so that the client continues without compression. The compressed messages aren't
+// recorded.
+func refuseCompression(logger *zap.Logger, clientConn net.Conn) error {
+	logger.Debug("refusing the compression of the mysqlx connection")
+	_, err := clientConn.Write(errorFrame(5002, "HY000", "Capability 'compression' doesn't exist").raw)
+	if err != nil {
+		utils.LogError(logger, err, "failed to write the mysqlx message to the client")
+	}
+	return err
+}
+
+func saveMock(req *message, responses []*message, reqTimestampMock, resTimestampMock time.Time, connID string, mocks chan<- *models.Mock) {
+	// the request was forwarded, so the recorded copy can be masked
+	req.maskPassword()
+
+	metadata := map[string]string{
+		"operation": req.model.Type,
+	}
+	switch req.typ {
+	case clientCapabilitiesGet, clientCapabilitiesSet, clientAuthenticateStart, clientAuthenticateCont:
+		// the handshake is replayed for every connection of the application
+		metadata["type"] = "config"
+	}
+
+	resps := make([]models.MySQLXMessage, 0, len(responses))
+	for _, resp := range responses {
+		resps = append(resps, resp.toModel())
+	}
+
+	mocks <- &models.Mock{
+		Version: models.GetVersion(),
+		Name:    "mocks",
+		Kind:    models.MySQLX,
+		Spec: models.MockSpec{
+			Metadata:         metadata,
+			MySQLXRequests:   []models.MySQLXMessage{req.toModel()},
+			MySQLXResponses:  resps,
+			ReqTimestampMock: reqTimestampMock,
+			ResTimestampMock: resTimestampMock,
+		},
+		ConnectionID: connID,
+	}
+}
@@ -0,0 +1,285 @@
+//go:build linux
+
+package mysqlx
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// field is a field of a protobuf message, the varints and the fixed size values are kept in v.
+type field struct {
+	num protowire.Number
+	v   uint64
+	b   []byte
+}
+
+type fields []field
+
+// parseFields reads the fields of a protobuf message without its descriptor.
+func parseFields(b []byte) (fields, error) {
+	var fs fields
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+		f := field{num: num}
+		switch typ {
+		case protowire.VarintType:
+			f.v, n = protowire.ConsumeVarint(b)
+		case protowire.Fixed32Type:
+			var v uint32
+			v, n = protowire.ConsumeFixed32(b)
+			f.v = uint64(v)
+		case protowire.Fixed64Type:
+			f.v, n = protowire.ConsumeFixed64(b)
+		case protowire.BytesType:
+			f.b, n = protowire.ConsumeBytes(b)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+		fs = append(fs, f)
+	}
+	return fs, nil
+}
+
+// msg returns the fields of the last embedded message with the number, nil when it is missing or malformed.
+func (fs fields) msg(num protowire.Number) fields {
+	for i := len(fs) - 1; i >= 0; i-- {
+		if fs[i].num == num {
+			sub, err := parseFields(fs[i].b)
+			if err != nil {
+				return nil
+			}
+			return sub
+		}
+	}
+	return nil
+}
+
+// msgs returns the fields of every embedded message with the number.
+func (fs fields) msgs(num protowire.Number) []fields {
+	var subs []fields
+	for _, f := range fs {
+		if f.num != num {
+			continue
+		}
+		if sub, err := parseFields(f.b); err == nil {
+			subs = append(subs, sub)
+		}
+	}
+	return subs
+}
+
+func (fs fields) str(num protowire.Number) string {
+	for i := len(fs) - 1; i >= 0; i-- {
+		if fs[i].num == num {
+			return string(fs[i].b)
+		}
+	}
+	return ""
+}
+
+func (fs fields) uint(num protowire.Number) uint64 {
+	v, _ := fs.lookup(num)
+	return v
+}
+
+func (fs fields) lookup(num protowire.Number) (uint64, bool) {
+	for i := len(fs) - 1; i >= 0; i-- {
+		if fs[i].num == num {
+			return fs[i].v, true
+		}
+	}
+	return 0, false
+}
+
+// The expressions of the CRUD messages are written as text, in a form close to the one of the X DevAPI.
+// ref: https://dev.mysql.com/doc/dev/mysql-server/latest/mysqlx__expr_8proto.html
+
+// The types of Mysqlx.Expr.Expr.
+const (
+	exprIdent       = 1
+	exprLiteral     = 2
+	exprVariable    = 3
+	exprFuncCall    = 4
+	exprOperator    = 5
+	exprPlaceholder = 6
+	exprObject      = 7
+	exprArray       = 8
+)
+
+// The types of Mysqlx.Datatypes.Scalar.
+const (
+	scalarSint   = 1
+	scalarUint   = 2
+	scalarNull   = 3
+	scalarOctets = 4
+	scalarDouble = 5
+	scalarFloat  = 6
+	scalarBool   = 7
+	scalarText   = 8
+)
+
+// The types of Mysqlx.Datatypes.Any.
+const (
+	anyScalar = 1
+	anyObject = 2
+	anyArray  = 3
+)
+
+func exprString(fs fields) string {
+	switch fs.uint(1) {
+	case exprIdent:
+		return columnIdentifier(fs.msg(2))
+	case exprLiteral:
+		return scalarString(fs.msg(4))
+	case exprVariable:
+		return "@" + fs.str(3)
+	case exprFuncCall:
+		call := fs.msg(5)
+		name := call.msg(1)
+		fn := name.str(1)
+		if schema := name.str(2); schema != "" {
+			fn = schema + "." + fn
+		}
+		return fn + "(" + strings.Join(exprStrings(call.msgs(2)), ", ") + ")"
+	case exprOperator:
+		op := fs.msg(6)
+		params := exprStrings(op.msgs(2))
+		if len(params) == 2 {
+			return "(" + params[0] + " " + op.str(1) + " " + params[1] + ")"
+		}
+		return op.str(1) + "(" + strings.Join(params, ", ") + ")"
+	case exprPlaceholder:
+		return ":" + strconv.FormatUint(fs.uint(7), 10)
+	case exprObject:
+		var flds []string
+		for _, f := range fs.msg(8).msgs(1) {
+			flds = append(flds, strconv.Quote(f.str(1))+": "+exprString(f.msg(2)))
+		}
+		return "{" + strings.Join(flds, ", ") + "}"
+	case exprArray:
+		return "[" + strings.Join(exprStrings(fs.msg(9).msgs(1)), ", ") + "]"
+	}
+	return fmt.Sprintf("<unknown expression %d>", fs.uint(1))
+}
+
+func exprStrings(exprs []fields) []string {
+	strs := make([]string, 0, len(exprs))
+	for _, e := range exprs {
+		strs = append(strs, exprString(e))
+	}
+	return strs
+}
+
+// columnIdentifier writes a column as schema.table.name, followed by its document path as name->$.path. The
+// fields of the documents have no name, only the path.
+func columnIdentifier(fs fields) string {
+	var parts []string
+	for _, num := range []protowire.Number{4, 3, 2} {
+		if s := fs.str(num); s != "" {
+			parts = append(parts, s)
+		}
+	}
+	ident := strings.Join(parts, ".")
+	path := fs.msgs(1)
+	if len(path) == 0 {
+		return ident
+	}
+	if ident != "" {
+		ident += "->"
+	}
+	return ident + documentPath(path)
+}
+
+// The types of Mysqlx.Expr.DocumentPathItem.
+const (
+	pathMember             = 1
+	pathMemberAsterisk     = 2
+	pathArrayIndex         = 3
+	pathArrayIndexAsterisk = 4
+	pathDoubleAsterisk     = 5
+)
+
+func documentPath(items []fields) string {
+	var b strings.Builder
+	b.WriteString("$")
+	for _, item := range items {
+		switch item.uint(1) {
+		case pathMember:
+			b.WriteString("." + item.str(2))
+		case pathMemberAsterisk:
+			b.WriteString(".*")
+		case pathArrayIndex:
+			b.WriteString("[" + strconv.FormatUint(item.uint(3), 10) + "]")
+		case pathArrayIndexAsterisk:
+			b.WriteString("[*]")
+		case pathDoubleAsterisk:
+			b.WriteString("**")
+		}
+	}
+	return b.String()
+}
+
+func scalarString(fs fields) string {
+	switch fs.uint(1) {
+	case scalarSint:
+		return strconv.FormatInt(protowire.DecodeZigZag(fs.uint(2)), 10)
+	case scalarUint:
+		return strconv.FormatUint(fs.uint(3), 10)
+	case scalarNull:
+		return "NULL"
+	case scalarOctets:
+		return bytesString([]byte(fs.msg(5).str(1)))
+	case scalarDouble:
+		return strconv.FormatFloat(math.Float64frombits(fs.uint(6)), 'g', -1, 64)
+	case scalarFloat:
+		return strconv.FormatFloat(float64(math.Float32frombits(uint32(fs.uint(7)))), 'g', -1, 32)
+	case scalarBool:
+		return strconv.FormatBool(fs.uint(8) != 0)
+	case scalarText:
+		return strconv.Quote(fs.msg(9).str(1))
+	}
+	return fmt.Sprintf("<unknown scalar %d>", fs.uint(1))
+}
+
+// bytesString quotes the octets which are text, the others are written in hex.
+func bytesString(b []byte) string {
+	if utf8.Valid(b) {
+		return strconv.Quote(string(b))
+	}
+	return "x'" + hex.EncodeToString(b) + "'"
+}
+
+func anyString(fs fields) string {
+	switch fs.uint(1) {
+	case anyScalar:
+		return scalarString(fs.msg(2))
+	case anyObject:
+		var flds []string
+		for _, f := range fs.msg(3).msgs(1) {
+			flds = append(flds, strconv.Quote(f.str(1))+": "+anyString(f.msg(2)))
+		}
+		return "{" + strings.Join(flds, ", ") + "}"
+	case anyArray:
+		var values []string
+		for _, v := range fs.msg(4).msgs(1) {
+			values = append(values, anyString(v))
+		}
+		return "[" + strings.Join(values, ", ") + "]"
+	}
+	return fmt.Sprintf("<unknown any %d>", fs.uint(1))
+}
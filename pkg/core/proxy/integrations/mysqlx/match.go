@@ -0,0 +1,97 @@
+//go:build linux
+
+package mysqlx
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations/util"
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+// match finds the mock of the request among the mocks of the same message type and statement: the statements
+// are matched on their text, the CRUD messages on their collection and their expressions, and the executes
+// on the statement they execute. The mocks recorded during the current test case are preferred, and the
+// closest arguments win. The handshake mocks are matched as many times as the application connects, the
+// other mocks are moved behind the rest once matched.
+func match(ctx context.Context, req *message, mockDb integrations.MockMemDb) (*models.Mock, error) {
+	stmt := statement(req.model)
+	args := arguments(req.model)
+	for {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		mocks, err := mockDb.GetUnFilteredMocksByKey(models.MySQLX, "")
+		if err != nil {
+			return nil, fmt.Errorf("error while getting unfiltered mocks %v", err)
+		}
+
+		var filteredMocks, unfilteredMocks []*models.Mock
+		for _, mock := range mocks {
+			if mock.Kind != models.MySQLX || len(mock.Spec.MySQLXRequests) == 0 || statement(mock.Spec.MySQLXRequests[0]) != stmt {
+				continue
+			}
+			if mock.TestModeInfo.IsFiltered {
+				filteredMocks = append(filteredMocks, mock)
+			} else {
+				unfilteredMocks = append(unfilteredMocks, mock)
+			}
+		}
+
+		mock := closest(filteredMocks, args)
+		if mock == nil {
+			mock = closest(unfilteredMocks, args)
+		}
+		if mock == nil || mock.Spec.Metadata["type"] == "config" {
+			return mock, nil
+		}
+
+		originalMock := *mock
+		mock.TestModeInfo.IsFiltered = false
+		mock.TestModeInfo.SortOrder = math.MaxInt64
+		if !mockDb.UpdateUnFilteredMock(&originalMock, mock) {
+			// the mock was used by another request in the meantime
+			continue
+		}
+		return mock, nil
+	}
+}
+
+// statement returns the fields of a request which are the same for the requests of a mock. The values of the
+// placeholders, the rows inserted and the changes of the updates are compared by their similarity.
+func statement(m models.MySQLXMessage) string {
+	return strings.Join([]string{
+		m.Type, m.Mechanism, m.User, m.Prepared, m.Namespace, m.Stmt, m.Schema, m.Collection, m.DataModel,
+		strings.Join(m.Projection, ", "), m.Criteria, strings.Join(m.Grouping, ", "), strings.Join(m.Order, ", "), m.Limit,
+	}, "\n")
+}
+
+func arguments(m models.MySQLXMessage) string {
+	var args []string
+	args = append(args, m.Capabilities...)
+	args = append(args, m.Rows...)
+	args = append(args, m.Operations...)
+	args = append(args, m.Args...)
+	return strings.Join(args, "\n")
+}
+
+// closest returns the first mock with the same arguments, or else the one with the most similar arguments.
+func closest(mocks []*models.Mock, args string) *models.Mock {
+	var best *models.Mock
+	bestSim := -1.0
+	for _, mock := range mocks {
+		recorded := arguments(mock.Spec.MySQLXRequests[0])
+		if recorded == args {
+			return mock
+		}
+		k := util.AdaptiveK(len(args), 3, 8, 5)
+		if sim := util.JaccardSimilarity(util.CreateShingles([]byte(recorded), k), util.CreateShingles([]byte(args), k)); sim > bestSim {
+			best, bestSim = mock, sim
+		}
+	}
+	return best
+}
@@ -0,0 +1,358 @@
+//go:build linux
+
+package mysqlx
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations/util"
+	"go.keploy.io/server/v2/pkg/models"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// message is a decoded X Protocol message, the fields used for matching are set from its payload.
+type message struct {
+	*frame
+	client bool
+	model  models.MySQLXMessage
+}
+
+// The data models of the CRUD messages.
+var dataModels = map[uint64]string{1: "DOCUMENT", 2: "TABLE"}
+
+// The operations of Mysqlx.Crud.UpdateOperation.
+var updateTypes = map[uint64]string{
+	1: "SET",
+	2: "ITEM_REMOVE",
+	3: "ITEM_SET",
+	4: "ITEM_REPLACE",
+	5: "ITEM_MERGE",
+	6: "ARRAY_INSERT",
+	7: "ARRAY_APPEND",
+	8: "MERGE_PATCH",
+}
+
+// The messages which can be prepared, by their type in Mysqlx.Prepare.Prepare.OneOfMessage.
+var preparedTypes = map[uint64]byte{
+	1: clientCrudFind,
+	2: clientCrudInsert,
+	3: clientCrudUpdate,
+	4: clientCrudDelete,
+	5: clientStmtExecute,
+}
+
+// session keeps the statements prepared and the cursors opened on a connection, the executes are matched on
+// the statement they execute since the ids are chosen by the client.
+type session struct {
+	prepared map[uint32]models.MySQLXMessage
+	cursors  map[uint32]models.MySQLXMessage
+}
+
+func newSession() *session {
+	return &session{
+		prepared: make(map[uint32]models.MySQLXMessage),
+		cursors:  make(map[uint32]models.MySQLXMessage),
+	}
+}
+
+// decodeClient decodes a message of the client. The undecodable parts of a message are left out, the message
+// is matched on what could be decoded.
+func decodeClient(f *frame, s *session) *message {
+	m := &message{frame: f, client: true}
+	m.model.Type = clientName(f.typ)
+	fs, err := parseFields(f.payload)
+	if err != nil {
+		m.model.Args = append(m.model.Args, "<undecoded: "+err.Error()+">")
+		return m
+	}
+
+	switch f.typ {
+	case clientCapabilitiesSet:
+		m.model.Capabilities = capabilities(fs.msg(1))
+	case clientAuthenticateStart:
+		m.model.Mechanism = fs.str(1)
+		_, m.model.User = authUser([]byte(fs.str(2)))
+	case clientAuthenticateCont:
+		_, m.model.User = authUser([]byte(fs.str(1)))
+	case clientStmtExecute, clientCrudFind, clientCrudInsert, clientCrudUpdate, clientCrudDelete:
+		decodeStatement(&m.model, f.typ, fs)
+	case clientCrudCreateView, clientCrudModifyView, clientCrudDropView:
+		decodeCollection(&m.model, fs.msg(1))
+	case clientPrepare:
+		m.model.StmtID = uint32(fs.uint(1))
+		stmt := fs.msg(2)
+		typ, ok := preparedTypes[stmt.uint(1)]
+		if !ok {
+			break
+		}
+		m.model.Prepared = clientName(typ)
+		// the prepared message is the field following the type, in the order of the types
+		decodeStatement(&m.model, typ, stmt.msg(protowire.Number(stmt.uint(1)+1)))
+		s.prepared[m.model.StmtID] = m.model
+	case clientPrepareExecute:
+		m.model = executeModel(m.model.Type, fs, s)
+	case clientPrepareDeallocate:
+		m.model.StmtID = uint32(fs.uint(1))
+		m.model.Prepared = s.prepared[m.model.StmtID].Prepared
+		delete(s.prepared, m.model.StmtID)
+	case clientCursorOpen:
+		// the cursors are opened on a Prepare.Execute, the only message of Mysqlx.Cursor.Open.OneOfMessage
+		m.model = executeModel(m.model.Type, fs.msg(4).msg(2), s)
+		m.model.CursorID = uint32(fs.uint(1))
+		s.cursors[m.model.CursorID] = m.model
+	case clientCursorFetch, clientCursorClose:
+		m.model = cursorModel(m.model.Type, uint32(fs.uint(1)), s)
+		if f.typ == clientCursorClose {
+			delete(s.cursors, m.model.CursorID)
+		}
+	}
+	return m
+}
+
+// executeModel returns the model of an execute, with the fields of the statement it executes.
+func executeModel(typ string, fs fields, s *session) models.MySQLXMessage {
+	model := s.prepared[uint32(fs.uint(1))]
+	model.Type = typ
+	model.StmtID = uint32(fs.uint(1))
+	model.Args = nil
+	for _, arg := range fs.msgs(2) {
+		model.Args = append(model.Args, anyString(arg))
+	}
+	return model
+}
+
+func cursorModel(typ string, cursorID uint32, s *session) models.MySQLXMessage {
+	model := s.cursors[cursorID]
+	model.Type = typ
+	model.CursorID = cursorID
+	return model
+}
+
+// decodeStatement sets the fields of a Sql.StmtExecute or of a CRUD message.
+func decodeStatement(model *models.MySQLXMessage, typ byte, fs fields) {
+	switch typ {
+	case clientStmtExecute:
+		model.Stmt = fs.str(1)
+		for _, arg := range fs.msgs(2) {
+			model.Args = append(model.Args, anyString(arg))
+		}
+		if ns := fs.str(3); ns != "" && ns != "sql" {
+			model.Namespace = ns
+		}
+	case clientCrudFind:
+		decodeCollection(model, fs.msg(2))
+		model.DataModel = dataModels[fs.uint(3)]
+		for _, p := range fs.msgs(4) {
+			model.Projection = append(model.Projection, alias(exprString(p.msg(1)), p.str(2)))
+		}
+		model.Criteria = criteria(fs, 5)
+		model.Limit = limit(fs.msg(6), fs.msg(14))
+		model.Order = order(fs.msgs(7))
+		model.Grouping = exprStrings(fs.msgs(8))
+		if having := criteria(fs, 9); having != "" {
+			model.Grouping = append(model.Grouping, "HAVING "+having)
+		}
+		model.Args = scalars(fs.msgs(11))
+	case clientCrudInsert:
+		decodeCollection(model, fs.msg(1))
+		model.DataModel = dataModels[fs.uint(2)]
+		for _, c := range fs.msgs(3) {
+			name := c.str(1)
+			if path := c.msgs(3); len(path) > 0 {
+				name += "->" + documentPath(path)
+			}
+			model.Projection = append(model.Projection, alias(name, c.str(2)))
+		}
+		for _, row := range fs.msgs(4) {
+			model.Rows = append(model.Rows, "("+strings.Join(exprStrings(row.msgs(1)), ", ")+")")
+		}
+		model.Args = scalars(fs.msgs(5))
+		if fs.uint(6) != 0 {
+			model.Operations = append(model.Operations, "UPSERT")
+		}
+	case clientCrudUpdate:
+		decodeCollection(model, fs.msg(2))
+		model.DataModel = dataModels[fs.uint(3)]
+		model.Criteria = criteria(fs, 4)
+		model.Limit = limit(fs.msg(5), fs.msg(9))
+		model.Order = order(fs.msgs(6))
+		for _, op := range fs.msgs(7) {
+			operation := updateTypes[op.uint(2)] + " " + columnIdentifier(op.msg(1))
+			if value := op.msg(3); value != nil {
+				operation += " = " + exprString(value)
+			}
+			model.Operations = append(model.Operations, operation)
+		}
+		model.Args = scalars(fs.msgs(8))
+	case clientCrudDelete:
+		decodeCollection(model, fs.msg(1))
+		model.DataModel = dataModels[fs.uint(2)]
+		model.Criteria = criteria(fs, 3)
+		model.Limit = limit(fs.msg(4), fs.msg(7))
+		model.Order = order(fs.msgs(5))
+		model.Args = scalars(fs.msgs(6))
+	}
+}
+
+func decodeCollection(model *models.MySQLXMessage, fs fields) {
+	model.Collection = fs.str(1)
+	model.Schema = fs.str(2)
+}
+
+func criteria(fs fields, num protowire.Number) string {
+	if expr := fs.msg(num); expr != nil {
+		return exprString(expr)
+	}
+	return ""
+}
+
+func alias(expr, alias string) string {
+	if alias == "" {
+		return expr
+	}
+	return expr + " AS " + alias
+}
+
+// limit writes the limit of a CRUD message, which is a Mysqlx.Crud.Limit or a Mysqlx.Crud.LimitExpr.
+func limit(l, expr fields) string {
+	switch {
+	case l != nil:
+		s := strconv.FormatUint(l.uint(1), 10)
+		if offset, ok := l.lookup(2); ok {
+			s += " OFFSET " + strconv.FormatUint(offset, 10)
+		}
+		return s
+	case expr != nil:
+		s := criteria(expr, 1)
+		if offset := criteria(expr, 2); offset != "" {
+			s += " OFFSET " + offset
+		}
+		return s
+	}
+	return ""
+}
+
+func order(orders []fields) []string {
+	var strs []string
+	for _, o := range orders {
+		s := exprString(o.msg(1))
+		if o.uint(2) == 2 {
+			s += " DESC"
+		}
+		strs = append(strs, s)
+	}
+	return strs
+}
+
+func scalars(values []fields) []string {
+	var strs []string
+	for _, v := range values {
+		strs = append(strs, scalarString(v))
+	}
+	return strs
+}
+
+// capabilities writes the capabilities of a Mysqlx.Connection.Capabilities as name=value.
+func capabilities(fs fields) []string {
+	var caps []string
+	for _, c := range fs.msgs(1) {
+		caps = append(caps, c.str(1)+"="+anyString(c.msg(2)))
+	}
+	return caps
+}
+
+// hasCapability reports whether the client sets the capability in a Connection.CapabilitiesSet.
+func (m *message) hasCapability(name string) bool {
+	for _, c := range m.model.Capabilities {
+		if strings.HasPrefix(c, name+"=") {
+			return true
+		}
+	}
+	return false
+}
+
+// authUser returns the schema and the user of the authentication data, which is schema\0user\0secret for
+// every mechanism.
+func authUser(data []byte) (string, string) {
+	parts := bytes.SplitN(data, []byte{0}, 3)
+	if len(parts) < 3 {
+		return "", ""
+	}
+	return string(parts[0]), string(parts[1])
+}
+
+// maskPassword replaces the password of a PLAIN authentication with asterisks, so that the recorded mock
+// doesn't hold the credentials. It must be called after the message was forwarded.
+func (m *message) maskPassword() {
+	if m.typ != clientAuthenticateStart || m.model.Mechanism != "PLAIN" {
+		return
+	}
+	fs, err := parseFields(m.payload)
+	if err != nil {
+		return
+	}
+	parts := bytes.SplitN([]byte(fs.str(2)), []byte{0}, 3)
+	if len(parts) < 3 {
+		return
+	}
+	parts[2] = bytes.Repeat([]byte{'*'}, len(parts[2]))
+
+	var payload []byte
+	for _, f := range fs {
+		switch f.num {
+		case 2:
+			payload = protowire.AppendTag(payload, f.num, protowire.BytesType)
+			payload = protowire.AppendBytes(payload, bytes.Join(parts, []byte{0}))
+		case 1, 3:
+			payload = protowire.AppendTag(payload, f.num, protowire.BytesType)
+			payload = protowire.AppendBytes(payload, f.b)
+		}
+	}
+	m.frame = newFrame(m.typ, payload)
+}
+
+// decodeServer decodes a message of the server, only the columns and the errors are decoded out of the
+// resultsets.
+func decodeServer(f *frame) *message {
+	m := &message{frame: f}
+	m.model.Type = serverName(f.typ)
+	fs, err := parseFields(f.payload)
+	if err != nil {
+		return m
+	}
+	switch f.typ {
+	case serverOk:
+		m.model.Message = fs.str(1)
+	case serverError:
+		m.model.Code = uint32(fs.uint(2))
+		m.model.Message = fs.str(3)
+		m.model.SQLState = fs.str(4)
+	case serverCapabilities:
+		m.model.Capabilities = capabilities(fs)
+	case serverColumnMetaData:
+		m.model.Column = fs.str(2)
+	}
+	return m
+}
+
+func (m *message) toModel() models.MySQLXMessage {
+	model := m.model
+	model.Raw = util.EncodeBase64(m.raw)
+	return model
+}
+
+// errorFrame is the error the proxy answers with to the requests it doesn't forward, with the severity ERROR.
+func errorFrame(code uint32, sqlState, msg string) *frame {
+	var payload []byte
+	payload = protowire.AppendTag(payload, 1, protowire.VarintType)
+	payload = protowire.AppendVarint(payload, 0)
+	payload = protowire.AppendTag(payload, 2, protowire.VarintType)
+	payload = protowire.AppendVarint(payload, uint64(code))
+	payload = protowire.AppendTag(payload, 3, protowire.BytesType)
+	payload = protowire.AppendString(payload, msg)
+	payload = protowire.AppendTag(payload, 4, protowire.BytesType)
+	payload = protowire.AppendString(payload, sqlState)
+	return newFrame(serverError, payload)
+}
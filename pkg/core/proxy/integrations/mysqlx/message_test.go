@@ -0,0 +1,222 @@
+//go:build linux
+
+package mysqlx
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+
+	"go.keploy.io/server/v2/pkg/models"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func varint(num protowire.Number, v uint64) []byte {
+	b := protowire.AppendTag(nil, num, protowire.VarintType)
+	return protowire.AppendVarint(b, v)
+}
+
+func embed(num protowire.Number, fields ...[]byte) []byte {
+	b := protowire.AppendTag(nil, num, protowire.BytesType)
+	return protowire.AppendBytes(b, bytes.Join(fields, nil))
+}
+
+func str(num protowire.Number, s string) []byte {
+	return embed(num, []byte(s))
+}
+
+func uintScalar(num protowire.Number, v uint64) []byte {
+	return embed(num, varint(1, scalarUint), varint(3, v))
+}
+
+func textScalar(num protowire.Number, s string) []byte {
+	return embed(num, varint(1, scalarText), embed(9, str(1, s)))
+}
+
+func anyOf(scalar []byte) []byte {
+	return embed(2, varint(1, anyScalar), scalar)
+}
+
+// readClient reads the frame of the message and decodes it as a message of the client.
+func readClient(t *testing.T, s *session, typ byte, fields ...[]byte) models.MySQLXMessage {
+	t.Helper()
+	raw := newFrame(typ, bytes.Join(fields, nil)).raw
+	f, err := readFrame(bufio.NewReader(bytes.NewReader(raw)))
+	if err != nil {
+		t.Fatalf("readFrame() failed: %v", err)
+	}
+	if !bytes.Equal(f.raw, raw) {
+		t.Errorf("readFrame() raw = % x, want % x", f.raw, raw)
+	}
+	return decodeClient(f, s).model
+}
+
+func TestDecodeClient(t *testing.T) {
+	tests := []struct {
+		name   string
+		typ    byte
+		fields [][]byte
+		want   models.MySQLXMessage
+	}{
+		{
+			name: "statement",
+			typ:  clientStmtExecute,
+			fields: [][]byte{
+				str(1, "SELECT * FROM users WHERE id = ? AND name = ?"),
+				anyOf(uintScalar(2, 7)),
+				anyOf(textScalar(2, "alice")),
+				anyOf(embed(2, varint(1, scalarNull))),
+			},
+			want: models.MySQLXMessage{
+				Type: "Sql.StmtExecute",
+				Stmt: "SELECT * FROM users WHERE id = ? AND name = ?",
+				Args: []string{"7", `"alice"`, "NULL"},
+			},
+		},
+		{
+			name: "find",
+			typ:  clientCrudFind,
+			fields: [][]byte{
+				embed(2, str(1, "users"), str(2, "shop")),
+				varint(3, 1),
+				embed(5, varint(1, exprOperator), embed(6,
+					str(1, "=="),
+					embed(2, varint(1, exprIdent), embed(2, embed(1, varint(1, pathMember), str(2, "age")))),
+					embed(2, varint(1, exprPlaceholder), varint(7, 0)),
+				)),
+				embed(6, varint(1, 10), varint(2, 5)),
+				embed(7, embed(1, varint(1, exprIdent), embed(2, str(2, "name"))), varint(2, 2)),
+				uintScalar(11, 30),
+			},
+			want: models.MySQLXMessage{
+				Type:       "Crud.Find",
+				Schema:     "shop",
+				Collection: "users",
+				DataModel:  "DOCUMENT",
+				Criteria:   "($.age == :0)",
+				Grouping:   []string{},
+				Limit:      "10 OFFSET 5",
+				Order:      []string{"name DESC"},
+				Args:       []string{"30"},
+			},
+		},
+		{
+			name: "insert",
+			typ:  clientCrudInsert,
+			fields: [][]byte{
+				embed(1, str(1, "users"), str(2, "shop")),
+				varint(2, 2),
+				embed(3, str(1, "name")),
+				embed(4, embed(1, varint(1, exprLiteral), textScalar(4, "bob"))),
+			},
+			want: models.MySQLXMessage{
+				Type:       "Crud.Insert",
+				Schema:     "shop",
+				Collection: "users",
+				DataModel:  "TABLE",
+				Projection: []string{"name"},
+				Rows:       []string{`("bob")`},
+			},
+		},
+		{
+			name:   "authentication",
+			typ:    clientAuthenticateStart,
+			fields: [][]byte{str(1, "PLAIN"), str(2, "shop\x00root\x00secret")},
+			want:   models.MySQLXMessage{Type: "Session.AuthenticateStart", Mechanism: "PLAIN", User: "root"},
+		},
+		{
+			name:   "malformed payload",
+			typ:    clientStmtExecute,
+			fields: [][]byte{{0x0a, 0x05, 'a'}},
+			want:   models.MySQLXMessage{Type: "Sql.StmtExecute", Args: []string{"<undecoded: unexpected EOF>"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := readClient(t, newSession(), tt.typ, tt.fields...); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("decodeClient() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPreparedStatements(t *testing.T) {
+	s := newSession()
+	stmt := embed(6, str(1, "SELECT * FROM users WHERE id = ?"))
+	prepared := readClient(t, s, clientPrepare, varint(1, 3), embed(2, varint(1, 5), stmt))
+	if prepared.Prepared != "Sql.StmtExecute" || prepared.Stmt != "SELECT * FROM users WHERE id = ?" {
+		t.Fatalf("the prepare decoded = %+v", prepared)
+	}
+
+	execute := embed(2, varint(1, 3), anyOf(uintScalar(2, 7)))
+	opened := readClient(t, s, clientCursorOpen, varint(1, 9), embed(4, varint(1, 1), execute))
+	want := models.MySQLXMessage{
+		Type:     "Cursor.Open",
+		Prepared: "Sql.StmtExecute",
+		Stmt:     "SELECT * FROM users WHERE id = ?",
+		StmtID:   3,
+		CursorID: 9,
+		Args:     []string{"7"},
+	}
+	if !reflect.DeepEqual(opened, want) {
+		t.Errorf("the cursor opened = %+v, want %+v", opened, want)
+	}
+
+	want.Type = "Cursor.Fetch"
+	if fetched := readClient(t, s, clientCursorFetch, varint(1, 9)); !reflect.DeepEqual(fetched, want) {
+		t.Errorf("the fetch = %+v, want %+v", fetched, want)
+	}
+	readClient(t, s, clientCursorClose, varint(1, 9))
+	if deallocated := readClient(t, s, clientPrepareDeallocate, varint(1, 3)); deallocated.Prepared != "Sql.StmtExecute" {
+		t.Errorf("the deallocate = %+v", deallocated)
+	}
+	if len(s.prepared) != 0 || len(s.cursors) != 0 {
+		t.Errorf("the session keeps %d statements and %d cursors", len(s.prepared), len(s.cursors))
+	}
+}
+
+func TestMaskPassword(t *testing.T) {
+	m := decodeClient(newFrame(clientAuthenticateStart, bytes.Join([][]byte{
+		str(1, "PLAIN"), str(2, "shop\x00root\x00secret"), str(3, "init"),
+	}, nil)), newSession())
+	m.maskPassword()
+	if bytes.Contains(m.raw, []byte("secret")) {
+		t.Fatalf("maskPassword() left the password in % x", m.raw)
+	}
+	f, err := readFrame(bufio.NewReader(bytes.NewReader(m.raw)))
+	if err != nil {
+		t.Fatalf("readFrame() of the masked message failed: %v", err)
+	}
+	fs, err := parseFields(f.payload)
+	if err != nil || fs.str(1) != "PLAIN" || fs.str(2) != "shop\x00root\x00******" || fs.str(3) != "init" {
+		t.Errorf("the masked message = %q %q %q, %v", fs.str(1), fs.str(2), fs.str(3), err)
+	}
+}
+
+func TestDecodeServer(t *testing.T) {
+	got := decodeServer(errorFrame(1045, "HY000", "access denied")).model
+	want := models.MySQLXMessage{Type: "Error", Code: 1045, SQLState: "HY000", Message: "access denied"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("decodeServer() = %+v, want %+v", got, want)
+	}
+}
+
+func TestReadFrameMalformed(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  []byte
+	}{
+		{"message without a type", []byte{0, 0, 0, 0}},
+		{"oversized message", binary.LittleEndian.AppendUint32(nil, maxMessageSize+1)},
+		{"truncated message", []byte{5, 0, 0, 0, clientStmtExecute, 0x0a}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := readFrame(bufio.NewReader(bytes.NewReader(tt.raw))); err == nil {
+				t.Errorf("readFrame(% x) succeeded", tt.raw)
+			}
+		})
+	}
+}
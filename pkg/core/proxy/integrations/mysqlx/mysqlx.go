@@ -0,0 +1,116 @@
+//go:build linux
+
+// Package mysqlx provides the integration for the X Protocol of mysql, used by MySQL Shell and the X DevAPI
+// connectors on the port 33060.
+package mysqlx
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	"go.keploy.io/server/v2/pkg/core/proxy/util"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+func init() {
+	integrations.Register("mysqlx", NewMySQLX)
+}
+
+type MySQLX struct {
+	logger *zap.Logger
+}
+
+func NewMySQLX(logger *zap.Logger) integrations.Integrations {
+	return &MySQLX{
+		logger: logger,
+	}
+}
+
+// maxDetectMessageSize bounds the first message read for the detection, the capabilities and the start of the
+// authentication are small
+const maxDetectMessageSize = 64 * 1024
+
+// MatchType checks for the first message of the X Protocol clients, which start by getting or setting the
+// capabilities of the connection, or by authenticating right away.
+func (x *MySQLX) MatchType(_ context.Context, buf []byte) integrations.MatchResult {
+	if len(buf) < headerSize+1 {
+		return integrations.MatchResult{NeedBytes: headerSize + 1}
+	}
+	length := int(binary.LittleEndian.Uint32(buf))
+	typ := buf[headerSize]
+	switch typ {
+	case clientCapabilitiesGet:
+		if length == 1 {
+			return integrations.MatchResult{Confidence: integrations.ConfidenceHigh}
+		}
+		return integrations.MatchResult{}
+	case clientCapabilitiesSet, clientAuthenticateStart:
+	default:
+		return integrations.MatchResult{}
+	}
+	if length < 2 || length > maxDetectMessageSize {
+		return integrations.MatchResult{}
+	}
+	if len(buf) < headerSize+length {
+		return integrations.MatchResult{Confidence: integrations.ConfidenceLow, NeedBytes: headerSize + length}
+	}
+	fs, err := parseFields(buf[headerSize+1 : headerSize+length])
+	if err != nil {
+		return integrations.MatchResult{}
+	}
+	if typ == clientAuthenticateStart {
+		switch fs.str(1) {
+		case "MYSQL41", "SHA256_MEMORY", "PLAIN", "EXTERNAL":
+			return integrations.MatchResult{Confidence: integrations.ConfidenceCertain}
+		}
+		return integrations.MatchResult{}
+	}
+	caps := fs.msg(1).msgs(1)
+	if len(caps) == 0 {
+		return integrations.MatchResult{}
+	}
+	for _, c := range caps {
+		if c.str(1) == "" {
+			return integrations.MatchResult{}
+		}
+	}
+	return integrations.MatchResult{Confidence: integrations.ConfidenceHigh}
+}
+
+func (x *MySQLX) RecordOutgoing(ctx context.Context, src net.Conn, dst net.Conn, mocks chan<- *models.Mock, opts models.OutgoingOptions) error {
+	logger := x.logger.With(zap.Any("Client IP Address", src.RemoteAddr().String()), zap.Any("Client ConnectionID", ctx.Value(models.ClientConnectionIDKey).(string)), zap.Any("Destination ConnectionID", ctx.Value(models.DestConnectionIDKey).(string)))
+
+	reqBuf, err := util.ReadInitialBuf(ctx, logger, src)
+	if err != nil {
+		utils.LogError(logger, err, "failed to read the initial mysqlx message")
+		return err
+	}
+
+	err = encodeMySQLX(ctx, logger, reqBuf, src, dst, mocks, opts)
+	if err != nil {
+		utils.LogError(logger, err, "failed to encode the mysqlx message into the yaml")
+		return err
+	}
+	return nil
+}
+
+func (x *MySQLX) MockOutgoing(ctx context.Context, src net.Conn, dstCfg *integrations.ConditionalDstCfg, mockDb integrations.MockMemDb, opts models.OutgoingOptions) error {
+	logger := x.logger.With(zap.Any("Client IP Address", src.RemoteAddr().String()), zap.Any("Client ConnectionID", ctx.Value(models.ClientConnectionIDKey).(string)), zap.Any("Destination ConnectionID", ctx.Value(models.DestConnectionIDKey).(string)))
+
+	reqBuf, err := util.ReadInitialBuf(ctx, logger, src)
+	if err != nil {
+		utils.LogError(logger, err, "failed to read the initial mysqlx message")
+		return err
+	}
+
+	err = decodeMySQLX(ctx, logger, reqBuf, src, dstCfg, mockDb, opts)
+	if err != nil {
+		utils.LogError(logger, err, "failed to decode the mysqlx message")
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+//go:build linux
+
+package mysqlx
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ref: https://dev.mysql.com/doc/dev/mysql-server/latest/page_mysqlx_protocol_messages.html
+
+// The types of the messages sent by the client.
+const (
+	clientCapabilitiesGet   = 1
+	clientCapabilitiesSet   = 2
+	clientClose             = 3
+	clientAuthenticateStart = 4
+	clientAuthenticateCont  = 5
+	clientSessionReset      = 6
+	clientSessionClose      = 7
+	clientStmtExecute       = 12
+	clientCrudFind          = 17
+	clientCrudInsert        = 18
+	clientCrudUpdate        = 19
+	clientCrudDelete        = 20
+	clientExpectOpen        = 24
+	clientExpectClose       = 25
+	clientCrudCreateView    = 30
+	clientCrudModifyView    = 31
+	clientCrudDropView      = 32
+	clientPrepare           = 40
+	clientPrepareExecute    = 41
+	clientPrepareDeallocate = 42
+	clientCursorOpen        = 43
+	clientCursorClose       = 44
+	clientCursorFetch       = 45
+	clientCompression       = 46
+)
+
+var clientNames = map[byte]string{
+	clientCapabilitiesGet:   "Connection.CapabilitiesGet",
+	clientCapabilitiesSet:   "Connection.CapabilitiesSet",
+	clientClose:             "Connection.Close",
+	clientAuthenticateStart: "Session.AuthenticateStart",
+	clientAuthenticateCont:  "Session.AuthenticateContinue",
+	clientSessionReset:      "Session.Reset",
+	clientSessionClose:      "Session.Close",
+	clientStmtExecute:       "Sql.StmtExecute",
+	clientCrudFind:          "Crud.Find",
+	clientCrudInsert:        "Crud.Insert",
+	clientCrudUpdate:        "Crud.Update",
+	clientCrudDelete:        "Crud.Delete",
+	clientExpectOpen:        "Expect.Open",
+	clientExpectClose:       "Expect.Close",
+	clientCrudCreateView:    "Crud.CreateView",
+	clientCrudModifyView:    "Crud.ModifyView",
+	clientCrudDropView:      "Crud.DropView",
+	clientPrepare:           "Prepare.Prepare",
+	clientPrepareExecute:    "Prepare.Execute",
+	clientPrepareDeallocate: "Prepare.Deallocate",
+	clientCursorOpen:        "Cursor.Open",
+	clientCursorClose:       "Cursor.Close",
+	clientCursorFetch:       "Cursor.Fetch",
+	clientCompression:       "Connection.Compression",
+}
+
+// The types of the messages sent by the server.
+const (
+	serverOk                      = 0
+	serverError                   = 1
+	serverCapabilities            = 2
+	serverAuthenticateContinue    = 3
+	serverAuthenticateOk          = 4
+	serverNotice                  = 11
+	serverColumnMetaData          = 12
+	serverRow                     = 13
+	serverFetchDone               = 14
+	serverFetchSuspended          = 15
+	serverFetchDoneMoreResultsets = 16
+	serverStmtExecuteOk           = 17
+	serverFetchDoneMoreOutParams  = 18
+	serverCompression             = 19
+)
+
+var serverNames = map[byte]string{
+	serverOk:                      "Ok",
+	serverError:                   "Error",
+	serverCapabilities:            "Connection.Capabilities",
+	serverAuthenticateContinue:    "Session.AuthenticateContinue",
+	serverAuthenticateOk:          "Session.AuthenticateOk",
+	serverNotice:                  "Notice.Frame",
+	serverColumnMetaData:          "Resultset.ColumnMetaData",
+	serverRow:                     "Resultset.Row",
+	serverFetchDone:               "Resultset.FetchDone",
+	serverFetchSuspended:          "Resultset.FetchSuspended",
+	serverFetchDoneMoreResultsets: "Resultset.FetchDoneMoreResultsets",
+	serverStmtExecuteOk:           "Sql.StmtExecuteOk",
+	serverFetchDoneMoreOutParams:  "Resultset.FetchDoneMoreOutParams",
+	serverCompression:             "Connection.Compression",
+}
+
+const (
+	headerSize = 4
+	// maxMessageSize bounds the messages read, the servers default mysqlx_max_allowed_packet to 64MB
+	maxMessageSize = 1 << 30
+)
+
+// frame is a message of the X Protocol, the little endian length of the type and the payload, the type and
+// the protobuf encoded payload.
+type frame struct {
+	typ     byte
+	payload []byte
+	raw     []byte
+}
+
+func readFrame(r *bufio.Reader) (*frame, error) {
+	header := make([]byte, headerSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	length := binary.LittleEndian.Uint32(header)
+	if length == 0 {
+		return nil, errors.New("mysqlx message without a type")
+	}
+	if length > maxMessageSize {
+		return nil, fmt.Errorf("mysqlx message of %d bytes is too large", length)
+	}
+	raw := make([]byte, headerSize+int(length))
+	copy(raw, header)
+	if _, err := io.ReadFull(r, raw[headerSize:]); err != nil {
+		if err == io.EOF {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return &frame{typ: raw[headerSize], payload: raw[headerSize+1:], raw: raw}, nil
+}
+
+// newFrame encodes a message sent by the proxy itself.
+func newFrame(typ byte, payload []byte) *frame {
+	raw := make([]byte, headerSize+1, headerSize+1+len(payload))
+	binary.LittleEndian.PutUint32(raw, uint32(len(payload)+1))
+	raw[headerSize] = typ
+	raw = append(raw, payload...)
+	return &frame{typ: typ, payload: raw[headerSize+1:], raw: raw}
+}
+
+func clientName(typ byte) string {
+	if name, ok := clientNames[typ]; ok {
+		return name
+	}
+	return fmt.Sprintf("Client(%d)", typ)
+}
+
+func serverName(typ byte) string {
+	if name, ok := serverNames[typ]; ok {
+		return name
+	}
+	return fmt.Sprintf("Server(%d)", typ)
+}
+
+// isTerminal reports whether the message of the server ends the response of a request. The resultsets end
+// with a StmtExecuteOk, or with a FetchSuspended when the rows of a cursor are fetched in batches. The notices
+// can be sent at any time and are part of the response being received.
+func isTerminal(typ byte) bool {
+	switch typ {
+	case serverOk, serverError, serverCapabilities, serverAuthenticateContinue, serverAuthenticateOk,
+		serverStmtExecuteOk, serverFetchSuspended:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,297 @@
+//go:build linux
+
+package openwire
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations/util"
+	pUtil "go.keploy.io/server/v2/pkg/core/proxy/util"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+// deliveryInterval is how often the mocked broker looks for the messages to dispatch to the consumers
+const deliveryInterval = 50 * time.Millisecond
+
+// consumer is a consumer of the client, the dispatches to it carry the encoding of its id.
+type consumer struct {
+	id          []byte
+	destination string
+}
+
+// session is the state of a client conn to the mocked broker.
+type session struct {
+	logger     *zap.Logger
+	clientConn net.Conn
+	mockDb     integrations.MockMemDb
+
+	// writeMu serializes the commands written to the client
+	writeMu   sync.Mutex
+	lastWrite time.Time
+	mu        sync.Mutex
+	version   int32
+	// inactivity is the time the client waits for a command before closing the conn, 0 when it doesn't
+	inactivity time.Duration
+	consumers  map[string]*consumer // by the encoding of their ids
+}
+
+// decodeOpenWire acts as the broker for the client. The WireFormatInfo of the client is answered with the
+// recorded one of the broker, which turns off the tight encoding and the cache. The commands requiring a
+// response are answered from the mocks with the correlation id of the client command, and the messages
+// recorded for the destinations the client consumes from are dispatched to its consumers during the test
+// cases they were recorded in. The keepalives are dealt with by the mocked broker itself.
+func decodeOpenWire(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientConn net.Conn, _ *integrations.ConditionalDstCfg, mockDb integrations.MockMemDb, _ models.OutgoingOptions) error {
+	logger.Debug("Into the openwire parser in test mode")
+	s := &session{
+		logger:     logger,
+		clientConn: clientConn,
+		mockDb:     mockDb,
+		lastWrite:  time.Now(),
+		consumers:  make(map[string]*consumer),
+	}
+	client := bufio.NewReader(io.MultiReader(bytes.NewReader(reqBuf), clientConn))
+	if err := s.negotiate(ctx, client); err != nil {
+		return err
+	}
+
+	errCh := make(chan error, 1)
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		defer pUtil.Recover(logger, clientConn, nil)
+		defer close(errCh)
+		for {
+			c, err := readCommand(client)
+			if err != nil {
+				if err != io.EOF {
+					utils.LogError(logger, err, "failed to read the openwire command from the client")
+				}
+				errCh <- err
+				return
+			}
+			if err := s.handle(ctx, c); err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				errCh <- err
+				return
+			}
+		}
+	}()
+
+	go func() {
+		defer pUtil.Recover(logger, clientConn, nil)
+		s.deliver(ctx, done)
+	}()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-errCh:
+		if err == io.EOF {
+			return nil
+		}
+		return err
+	}
+}
+
+// negotiate answers the WireFormatInfo of the client with the recorded WireFormatInfo and BrokerInfo.
+func (s *session) negotiate(ctx context.Context, client *bufio.Reader) error {
+	info, err := readCommand(client)
+	if err != nil {
+		utils.LogError(s.logger, err, "failed to read the openwire WireFormatInfo from the client")
+		return err
+	}
+	clientFormat, err := parseWireFormat(info.raw)
+	if err != nil {
+		utils.LogError(s.logger, err, "failed to parse the openwire WireFormatInfo of the client")
+		return err
+	}
+	// the recorded WireFormatInfo of the client was rewritten like this
+	info.raw = looseWireFormat(info.raw)
+
+	mock, err := match(ctx, info, s.mockDb)
+	if err != nil {
+		utils.LogError(s.logger, err, "error while matching openwire mocks")
+		return err
+	}
+	if mock == nil {
+		err := fmt.Errorf("no openwire mock found for the WireFormatInfo")
+		utils.LogError(s.logger, err, "failed to mock the openwire handshake")
+		return err
+	}
+
+	s.version = clientFormat.version
+	inactivity := clientFormat.inactivity
+	for _, resp := range mock.Spec.OpenWireResponses {
+		rc, err := decodeRaw(resp.Raw)
+		if err != nil {
+			utils.LogError(s.logger, err, "failed to decode the recorded openwire command")
+			return err
+		}
+		if rc.typ == typeWireFormatInfo {
+			brokerFormat, err := parseWireFormat(rc.raw)
+			if err != nil {
+				utils.LogError(s.logger, err, "failed to parse the recorded openwire WireFormatInfo")
+				return err
+			}
+			s.version = min(s.version, brokerFormat.version)
+			if inactivity <= 0 || (brokerFormat.inactivity > 0 && brokerFormat.inactivity < inactivity) {
+				inactivity = brokerFormat.inactivity
+			}
+		}
+		if err := s.write(rc.raw); err != nil {
+			return err
+		}
+	}
+	if inactivity > 0 {
+		s.inactivity = time.Duration(inactivity) * time.Millisecond
+	}
+	return nil
+}
+
+func (s *session) handle(ctx context.Context, c *command) error {
+	s.mu.Lock()
+	c.parse(s.version)
+	switch c.typ {
+	case typeConsumerInfo:
+		if c.consumerID != nil {
+			s.consumers[string(c.consumerID)] = &consumer{id: c.consumerID, destination: c.destination}
+		}
+	case typeRemoveInfo:
+		delete(s.consumers, string(c.consumerID))
+	}
+	s.mu.Unlock()
+
+	if c.typ == typeKeepAliveInfo {
+		if c.responseRequired {
+			return s.write(keepAlive(0))
+		}
+		return nil
+	}
+
+	mock, err := match(ctx, c, s.mockDb)
+	if err != nil {
+		utils.LogError(s.logger, err, "error while matching openwire mocks")
+		return err
+	}
+	if mock == nil {
+		if !c.responseRequired {
+			s.logger.Debug("no openwire mock found for the command", zap.String("type", commandName(c.typ)), zap.String("destination", c.destination))
+			return nil
+		}
+		err := fmt.Errorf("no openwire mock found for the %s command", commandName(c.typ))
+		utils.LogError(s.logger, err, "failed to mock the openwire command", zap.String("destination", c.destination))
+		return err
+	}
+
+	for _, resp := range mock.Spec.OpenWireResponses {
+		rc, err := decodeRaw(resp.Raw)
+		if err != nil {
+			utils.LogError(s.logger, err, "failed to decode the recorded openwire command")
+			return err
+		}
+		if err := s.write(rc.withCorrelationID(c.commandID)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deliver dispatches the messages recorded during the current test case to the consumers of the client, and
+// keeps the conn alive within the inactivity duration of the client.
+func (s *session) deliver(ctx context.Context, done <-chan struct{}) {
+	ticker := time.NewTicker(deliveryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+
+		s.mu.Lock()
+		inactivity := s.inactivity
+		consumers := make([]consumer, 0, len(s.consumers))
+		for _, c := range s.consumers {
+			consumers = append(consumers, *c)
+		}
+		s.mu.Unlock()
+
+		if inactivity > 0 {
+			s.writeMu.Lock()
+			idle := time.Since(s.lastWrite) > inactivity/3
+			s.writeMu.Unlock()
+			if idle {
+				if err := s.write(keepAlive(0)); err != nil {
+					return
+				}
+			}
+		}
+		if len(consumers) == 0 {
+			continue
+		}
+
+		consumerOf := func(destination string) *consumer {
+			for i := range consumers {
+				if sameDestination(destination, consumers[i].destination) {
+					return &consumers[i]
+				}
+			}
+			return nil
+		}
+		mocks, err := dispatches(s.mockDb, func(destination string) bool { return consumerOf(destination) != nil })
+		if err != nil {
+			utils.LogError(s.logger, err, "failed to get the openwire dispatches")
+			return
+		}
+		for _, mock := range mocks {
+			recorded := mock.Spec.OpenWireResponses[0]
+			rc, err := decodeRaw(recorded.Raw)
+			if err != nil {
+				utils.LogError(s.logger, err, "failed to decode the recorded openwire command")
+				return
+			}
+			s.mu.Lock()
+			rc.parse(s.version)
+			s.mu.Unlock()
+			if err := s.write(rc.withConsumerID(consumerOf(recorded.Destination).id)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (s *session) write(raw []byte) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	_, err := s.clientConn.Write(raw)
+	if err != nil {
+		utils.LogError(s.logger, err, "failed to write the openwire command to the client application")
+		return err
+	}
+	s.lastWrite = time.Now()
+	return nil
+}
+
+// decodeRaw decodes a recorded command.
+func decodeRaw(raw string) (*command, error) {
+	data, err := util.DecodeBase64(raw)
+	if err != nil {
+		return nil, err
+	}
+	return readCommand(bufio.NewReader(bytes.NewReader(data)))
+}
@@ -0,0 +1,246 @@
+//go:build linux
+
+package openwire
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	pUtil "go.keploy.io/server/v2/pkg/core/proxy/util"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+// pendingCommand is a client command forwarded to the broker which is waiting for its response.
+type pendingCommand struct {
+	requests         []models.OpenWireCommand
+	responses        []models.OpenWireCommand
+	reqTimestampMock time.Time
+}
+
+// recorder is the state of a recorded conn, shared by the two directions.
+type recorder struct {
+	mu      sync.Mutex
+	version int32
+	// handshake is the WireFormatInfo of the client waiting for the WireFormatInfo and the BrokerInfo of the broker
+	handshake *pendingCommand
+	pending   map[int32]*pendingCommand // by the command ids of the client
+}
+
+// encodeOpenWire forwards the commands between the client and the broker. The WireFormatInfo of both peers
+// is rewritten to turn off the tight encoding, the cache and the disabling of the size prefix, so that the
+// commands of the conn can be decoded on their own. The client commands requiring a response are recorded
+// with it, the messages the client sends without one are recorded alone, and the messages the broker
+// dispatches to the consumers of the client are recorded as mocks without a request. The keepalives and the
+// acknowledgements are only forwarded.
+func encodeOpenWire(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientConn, destConn net.Conn, mocks chan<- *models.Mock, _ models.OutgoingOptions) error {
+	g, ok := ctx.Value(models.ErrGroupKey).(*errgroup.Group)
+	if !ok {
+		return errors.New("failed to get the error group from the context")
+	}
+	connID, _ := ctx.Value(models.ClientConnectionIDKey).(string)
+
+	client := bufio.NewReader(io.MultiReader(bytes.NewReader(reqBuf), clientConn))
+	// the WireFormatInfo of the client is registered before reading from the broker, which sends its own
+	// WireFormatInfo as soon as the conn is accepted
+	info, err := readCommand(client)
+	if err != nil {
+		utils.LogError(logger, err, "failed to read the openwire WireFormatInfo from the client")
+		return err
+	}
+	info.raw = looseWireFormat(info.raw)
+	clientFormat, err := parseWireFormat(info.raw)
+	if err != nil {
+		utils.LogError(logger, err, "failed to parse the openwire WireFormatInfo of the client")
+		return err
+	}
+	_, err = destConn.Write(info.raw)
+	if err != nil {
+		utils.LogError(logger, err, "failed to write the openwire WireFormatInfo to the destination server")
+		return err
+	}
+	rec := &recorder{
+		version:   clientFormat.version,
+		handshake: &pendingCommand{requests: []models.OpenWireCommand{info.model()}, reqTimestampMock: time.Now()},
+		pending:   make(map[int32]*pendingCommand),
+	}
+	errCh := make(chan error, 2)
+
+	// Forward the commands from the client to the destination server
+	g.Go(func() error {
+		defer pUtil.Recover(logger, clientConn, destConn)
+		for {
+			c, err := readCommand(client)
+			if err != nil {
+				if err != io.EOF {
+					utils.LogError(logger, err, "failed to read the openwire command from the client")
+				}
+				errCh <- err
+				return nil
+			}
+
+			// register the command before forwarding it, so that the response can't arrive first
+			alone := rec.clientCommand(c, time.Now())
+			logger.Debug("openwire command from the client", zap.String("type", commandName(c.typ)), zap.Int32("commandId", c.commandID))
+
+			_, err = destConn.Write(c.raw)
+			if err != nil {
+				utils.LogError(logger, err, "failed to write the openwire command to the destination server")
+				errCh <- err
+				return nil
+			}
+			if alone != nil {
+				saveMock(alone.requests, nil, alone.reqTimestampMock, time.Now(), connID, mocks)
+			}
+		}
+	})
+
+	// Forward the commands from the destination server to the client
+	g.Go(func() error {
+		defer pUtil.Recover(logger, clientConn, destConn)
+		server := bufio.NewReader(destConn)
+		for {
+			c, err := readCommand(server)
+			if err != nil {
+				if err != io.EOF {
+					utils.LogError(logger, err, "failed to read the openwire command from the destination server")
+				}
+				errCh <- err
+				return nil
+			}
+			if c.typ == typeWireFormatInfo {
+				c.raw = looseWireFormat(c.raw)
+			}
+
+			_, err = clientConn.Write(c.raw)
+			if err != nil {
+				utils.LogError(logger, err, "failed to write the openwire command to the client")
+				errCh <- err
+				return nil
+			}
+			resTimestampMock := time.Now()
+
+			for _, p := range rec.serverCommand(logger, c) {
+				reqTimestampMock := p.reqTimestampMock
+				if len(p.requests) == 0 {
+					reqTimestampMock = resTimestampMock
+				}
+				saveMock(p.requests, p.responses, reqTimestampMock, resTimestampMock, connID, mocks)
+			}
+		}
+	})
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-errCh:
+		if err == io.EOF {
+			return nil
+		}
+		return err
+	}
+}
+
+// clientCommand registers the client command waiting for its response, and returns the messages sent without
+// one, which are recorded alone.
+func (r *recorder) clientCommand(c *command, now time.Time) *pendingCommand {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c.parse(r.version)
+	switch {
+	case c.typ == typeKeepAliveInfo:
+		return nil
+	case c.responseRequired:
+		r.pending[c.commandID] = &pendingCommand{requests: []models.OpenWireCommand{c.model()}, reqTimestampMock: now}
+	case isMessage(c.typ):
+		return &pendingCommand{requests: []models.OpenWireCommand{c.model()}, reqTimestampMock: now}
+	}
+	return nil
+}
+
+// serverCommand pairs the broker command with the client command it responds to, and returns the commands
+// completed by it. The dispatches of the broker are returned on their own.
+func (r *recorder) serverCommand(logger *zap.Logger, c *command) []*pendingCommand {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var res []*pendingCommand
+	if r.handshake != nil {
+		switch c.typ {
+		case typeWireFormatInfo:
+			if wf, err := parseWireFormat(c.raw); err == nil && wf.version < r.version {
+				r.version = wf.version
+			}
+			r.handshake.responses = append(r.handshake.responses, c.model())
+			return nil
+		case typeBrokerInfo:
+			r.handshake.responses = append(r.handshake.responses, c.model())
+			res = append(res, r.handshake)
+			r.handshake = nil
+			return res
+		}
+		// the broker doesn't announce itself
+		res = append(res, r.handshake)
+		r.handshake = nil
+	}
+
+	c.parse(r.version)
+	logger.Debug("openwire command from the server", zap.String("type", commandName(c.typ)), zap.Int32("correlationId", c.correlationID))
+	switch {
+	case isResponse(c.typ):
+		p, ok := r.pending[c.correlationID]
+		if !ok {
+			return res
+		}
+		delete(r.pending, c.correlationID)
+		p.responses = append(p.responses, c.model())
+		res = append(res, p)
+	case c.typ == typeMessageDispatch:
+		res = append(res, &pendingCommand{responses: []models.OpenWireCommand{c.model()}})
+	}
+	return res
+}
+
+// saveMock records the client command with the broker commands sent for it. The dispatches of the broker are
+// recorded with the broker command only.
+func saveMock(requests, responses []models.OpenWireCommand, reqTimestampMock, resTimestampMock time.Time, connID string, mocks chan<- *models.Mock) {
+	metadata := make(map[string]string)
+	// the messages belong to the test cases, the rest is the setup of the client
+	if len(requests) > 0 && !isMessageType(requests[0].Type) {
+		metadata["type"] = "config"
+	}
+
+	mocks <- &models.Mock{
+		Version: models.GetVersion(),
+		Name:    "mocks",
+		Kind:    models.OpenWire,
+		Spec: models.MockSpec{
+			Metadata:          metadata,
+			OpenWireRequests:  requests,
+			OpenWireResponses: responses,
+			ReqTimestampMock:  reqTimestampMock,
+			ResTimestampMock:  resTimestampMock,
+		},
+		ConnectionID: connID,
+	}
+}
+
+// isMessageType reports whether the recorded command is a message.
+func isMessageType(name string) bool {
+	for typ := typeMessage; typ <= typeBlobMessage; typ++ {
+		if commandNames[typ] == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,131 @@
+//go:build linux
+
+package openwire
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"sort"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations/util"
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+// match finds the mock of the client command among the mocks of the same type and destination. The mocks
+// recorded during the current test case are preferred, and the command closest to the client command wins,
+// the messages of the same body first. The setup of the conn is matched as many times as the client
+// reconnects, the messages are moved behind the rest once matched.
+func match(ctx context.Context, c *command, mockDb integrations.MockMemDb) (*models.Mock, error) {
+	for {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		mocks, err := mockDb.GetUnFilteredMocksByKey(models.OpenWire, "")
+		if err != nil {
+			return nil, fmt.Errorf("error while getting unfiltered mocks %v", err)
+		}
+
+		var filteredMocks, unfilteredMocks []*models.Mock
+		for _, mock := range mocks {
+			if mock.Kind != models.OpenWire || len(mock.Spec.OpenWireRequests) == 0 || !sameCommand(mock.Spec.OpenWireRequests[0], c) {
+				continue
+			}
+			if mock.TestModeInfo.IsFiltered {
+				filteredMocks = append(filteredMocks, mock)
+			} else {
+				unfilteredMocks = append(unfilteredMocks, mock)
+			}
+		}
+
+		mock := closest(filteredMocks, c)
+		if mock == nil {
+			mock = closest(unfilteredMocks, c)
+		}
+		if mock == nil || mock.Spec.Metadata["type"] == "config" {
+			return mock, nil
+		}
+		if !use(mockDb, mock) {
+			// the mock was used by another command in the meantime
+			continue
+		}
+		return mock, nil
+	}
+}
+
+// sameCommand reports whether the recorded command has the type and the destination of the client command.
+func sameCommand(recorded models.OpenWireCommand, c *command) bool {
+	return recorded.Type == commandName(c.typ) && sameDestination(recorded.Destination, c.destination)
+}
+
+// closest returns the first mock with the same command as the client command, or else the one with the most
+// similar command. The ids of the conns, the sessions and the messages are generated anew on every run, so
+// the messages of the same body are only preferred.
+func closest(mocks []*models.Mock, c *command) *models.Mock {
+	var best *models.Mock
+	bestSim := -1.0
+	for _, mock := range mocks {
+		recorded := mock.Spec.OpenWireRequests[0]
+		raw, err := util.DecodeBase64(recorded.Raw)
+		if err != nil {
+			continue
+		}
+		if bytes.Equal(raw, c.raw) {
+			return mock
+		}
+		k := util.AdaptiveK(len(c.raw), 3, 8, 5)
+		sim := util.JaccardSimilarity(util.CreateShingles(raw, k), util.CreateShingles(c.raw, k))
+		if c.body != nil && recorded.Body == c.model().Body {
+			sim++
+		}
+		if sim > bestSim {
+			best, bestSim = mock, sim
+		}
+	}
+	return best
+}
+
+// use moves the mock behind the others, it reports false when the mock was used in the meantime.
+func use(mockDb integrations.MockMemDb, mock *models.Mock) bool {
+	originalMock := *mock
+	mock.TestModeInfo.IsFiltered = false
+	mock.TestModeInfo.SortOrder = math.MaxInt64
+	return mockDb.UpdateUnFilteredMock(&originalMock, mock)
+}
+
+// isDispatch reports whether the mock is a message the broker dispatched to a consumer of the client.
+func isDispatch(mock *models.Mock) bool {
+	return mock.Kind == models.OpenWire && len(mock.Spec.OpenWireRequests) == 0 && len(mock.Spec.OpenWireResponses) > 0 &&
+		mock.Spec.OpenWireResponses[0].Type == commandNames[typeMessageDispatch]
+}
+
+// dispatches uses up the messages recorded during the current test case for the destinations the client
+// consumes from, and returns them in the order they were dispatched.
+func dispatches(mockDb integrations.MockMemDb, consuming func(destination string) bool) ([]*models.Mock, error) {
+	mocks, err := mockDb.GetUnFilteredMocksByKey(models.OpenWire, "")
+	if err != nil {
+		return nil, fmt.Errorf("error while getting unfiltered mocks %v", err)
+	}
+
+	var pending []*models.Mock
+	for _, mock := range mocks {
+		if mock.TestModeInfo.IsFiltered && isDispatch(mock) && consuming(mock.Spec.OpenWireResponses[0].Destination) {
+			pending = append(pending, mock)
+		}
+	}
+	sort.SliceStable(pending, func(i, j int) bool {
+		return pending[i].Spec.ResTimestampMock.Before(pending[j].Spec.ResTimestampMock)
+	})
+
+	var res []*models.Mock
+	for _, mock := range pending {
+		if !use(mockDb, mock) {
+			// dispatched on another conn consuming from the same destination
+			continue
+		}
+		res = append(res, mock)
+	}
+	return res, nil
+}
@@ -0,0 +1,80 @@
+//go:build linux
+
+// Package openwire provides the integration for the openwire protocol of the activemq classic brokers, spoken
+// by their jms clients.
+package openwire
+
+import (
+	"context"
+	"net"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	"go.keploy.io/server/v2/pkg/core/proxy/util"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+func init() {
+	integrations.Register("openwire", NewOpenWire)
+}
+
+type OpenWire struct {
+	logger *zap.Logger
+}
+
+func NewOpenWire(logger *zap.Logger) integrations.Integrations {
+	return &OpenWire{
+		logger: logger,
+	}
+}
+
+// MatchType checks for the WireFormatInfo the client starts with, its type and a boolean are followed by the
+// magic "ActiveMQ" after the size of the command.
+func (o *OpenWire) MatchType(_ context.Context, buf []byte) integrations.MatchResult {
+	const size = 14
+	if len(buf) < size {
+		if len(buf) > 4 && buf[4] != typeWireFormatInfo {
+			return integrations.MatchResult{}
+		}
+		return integrations.MatchResult{Confidence: integrations.ConfidenceLow, NeedBytes: size}
+	}
+	if buf[4] != typeWireFormatInfo || buf[5] != 1 || string(buf[6:size]) != magic {
+		return integrations.MatchResult{}
+	}
+	return integrations.MatchResult{Confidence: integrations.ConfidenceCertain}
+}
+
+func (o *OpenWire) RecordOutgoing(ctx context.Context, src net.Conn, dst net.Conn, mocks chan<- *models.Mock, opts models.OutgoingOptions) error {
+	logger := o.logger.With(zap.Any("Client IP Address", src.RemoteAddr().String()), zap.Any("Client ConnectionID", ctx.Value(models.ClientConnectionIDKey).(string)), zap.Any("Destination ConnectionID", ctx.Value(models.DestConnectionIDKey).(string)))
+
+	reqBuf, err := util.ReadInitialBuf(ctx, logger, src)
+	if err != nil {
+		utils.LogError(logger, err, "failed to read the initial openwire message")
+		return err
+	}
+
+	err = encodeOpenWire(ctx, logger, reqBuf, src, dst, mocks, opts)
+	if err != nil {
+		utils.LogError(logger, err, "failed to encode the openwire message into the yaml")
+		return err
+	}
+	return nil
+}
+
+func (o *OpenWire) MockOutgoing(ctx context.Context, src net.Conn, dstCfg *integrations.ConditionalDstCfg, mockDb integrations.MockMemDb, opts models.OutgoingOptions) error {
+	logger := o.logger.With(zap.Any("Client IP Address", src.RemoteAddr().String()), zap.Any("Client ConnectionID", ctx.Value(models.ClientConnectionIDKey).(string)), zap.Any("Destination ConnectionID", ctx.Value(models.DestConnectionIDKey).(string)))
+
+	reqBuf, err := util.ReadInitialBuf(ctx, logger, src)
+	if err != nil {
+		utils.LogError(logger, err, "failed to read the initial openwire message")
+		return err
+	}
+
+	err = decodeOpenWire(ctx, logger, reqBuf, src, dstCfg, mockDb, opts)
+	if err != nil {
+		utils.LogError(logger, err, "failed to decode the openwire message")
+		return err
+	}
+	return nil
+}
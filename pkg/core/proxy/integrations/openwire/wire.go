@@ -0,0 +1,404 @@
+//go:build linux
+
+package openwire
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations/util"
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+const (
+	// maxFrameSize is the default maxFrameSize of the brokers
+	maxFrameSize = 100 * 1024 * 1024
+	// magic follows the type and a boolean in the WireFormatInfo, the first command of both peers
+	magic = "ActiveMQ"
+	// defaultInactivity is the MaxInactivityDuration of the peers which leave it out, in milliseconds
+	defaultInactivity = 30000
+)
+
+// the types of the commands and of the data structures nested in them
+const (
+	typeWireFormatInfo    byte = 1
+	typeBrokerInfo        byte = 2
+	typeConsumerInfo      byte = 5
+	typeProducerInfo      byte = 6
+	typeDestinationInfo   byte = 8
+	typeKeepAliveInfo     byte = 10
+	typeRemoveInfo        byte = 12
+	typeMessageDispatch   byte = 21
+	typeMessage           byte = 23
+	typeTextMessage       byte = 28
+	typeBlobMessage       byte = 29
+	typeResponse          byte = 30
+	typeIntegerResponse   byte = 34
+	typeQueue             byte = 100
+	typeTopic             byte = 101
+	typeTempQueue         byte = 102
+	typeTempTopic         byte = 103
+	typeMessageID         byte = 110
+	typeLocalTransaction  byte = 111
+	typeXATransactionID   byte = 112
+	typeConnectionID      byte = 120
+	typeSessionID         byte = 121
+	typeConsumerID        byte = 122
+	typeProducerID        byte = 123
+	typeNull              byte = 0
+	firstMessageIDVersion      = 10 // the message ids have a text view from the version 10
+)
+
+var commandNames = map[byte]string{
+	1:  "WireFormatInfo",
+	2:  "BrokerInfo",
+	3:  "ConnectionInfo",
+	4:  "SessionInfo",
+	5:  "ConsumerInfo",
+	6:  "ProducerInfo",
+	7:  "TransactionInfo",
+	8:  "DestinationInfo",
+	9:  "RemoveSubscriptionInfo",
+	10: "KeepAliveInfo",
+	11: "ShutdownInfo",
+	12: "RemoveInfo",
+	14: "ControlCommand",
+	15: "FlushCommand",
+	16: "ConnectionError",
+	17: "ConsumerControl",
+	18: "ConnectionControl",
+	19: "ProducerAck",
+	20: "MessagePull",
+	21: "MessageDispatch",
+	22: "MessageAck",
+	23: "Message",
+	24: "BytesMessage",
+	25: "MapMessage",
+	26: "ObjectMessage",
+	27: "StreamMessage",
+	28: "TextMessage",
+	29: "BlobMessage",
+	30: "Response",
+	31: "ExceptionResponse",
+	32: "DataResponse",
+	33: "DataArrayResponse",
+	34: "IntegerResponse",
+}
+
+var destinationSchemes = map[byte]string{
+	typeQueue:     "queue://",
+	typeTopic:     "topic://",
+	typeTempQueue: "temp-queue://",
+	typeTempTopic: "temp-topic://",
+}
+
+var errShortBuffer = errors.New("openwire command is shorter than its fields")
+
+func commandName(typ byte) string {
+	if name, ok := commandNames[typ]; ok {
+		return name
+	}
+	return fmt.Sprintf("Command%d", typ)
+}
+
+func isMessage(typ byte) bool {
+	return typ >= typeMessage && typ <= typeBlobMessage
+}
+
+func isResponse(typ byte) bool {
+	return typ >= typeResponse && typ <= typeIntegerResponse
+}
+
+// command is a command read from a peer, raw is the command with its size prefix. The conns are negotiated to
+// the loose encoding without the cache, so the fields follow each other in their order.
+type command struct {
+	typ              byte
+	commandID        int32
+	responseRequired bool
+	correlationID    int32
+	destination      string
+	body             []byte
+	textBody         bool
+	// consumerID is the encoding of the consumer id of the consumers, the removals and the dispatches, at
+	// consumerIDOffset in raw
+	consumerID       []byte
+	consumerIDOffset int
+	raw              []byte
+}
+
+func readCommand(r *bufio.Reader) (*command, error) {
+	size := make([]byte, 4)
+	if _, err := io.ReadFull(r, size); err != nil {
+		return nil, err
+	}
+	n := binary.BigEndian.Uint32(size)
+	if n == 0 || n > maxFrameSize {
+		return nil, fmt.Errorf("invalid openwire command size %d", n)
+	}
+	raw := make([]byte, 4+n)
+	copy(raw, size)
+	if _, err := io.ReadFull(r, raw[4:]); err != nil {
+		if err == io.EOF {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return &command{typ: raw[4], raw: raw}, nil
+}
+
+// parse decodes the fields of the command used for matching, the commands whose fields can't be decoded are
+// still matched on their type and their encoding.
+func (c *command) parse(version int32) {
+	if c.typ == typeWireFormatInfo || len(c.raw) < 10 {
+		return
+	}
+	d := &decoder{buf: c.raw, off: 5, version: version}
+	c.commandID = d.int32()
+	c.responseRequired = d.bool()
+	switch {
+	case isResponse(c.typ):
+		c.correlationID = d.int32()
+	case c.typ == typeConsumerInfo:
+		c.consumerIDOffset = d.off
+		d.nested()
+		c.consumerID = d.since(c.consumerIDOffset)
+		d.bool() // browser
+		_, c.destination = d.nested()
+	case c.typ == typeProducerInfo, c.typ == typeDestinationInfo:
+		d.nested() // the producer or the connection id
+		_, c.destination = d.nested()
+	case c.typ == typeRemoveInfo:
+		c.consumerIDOffset = d.off
+		if typ, _ := d.nested(); typ == typeConsumerID {
+			c.consumerID = d.since(c.consumerIDOffset)
+		}
+	case c.typ == typeMessageDispatch:
+		c.consumerIDOffset = d.off
+		d.nested()
+		c.consumerID = d.since(c.consumerIDOffset)
+		_, c.destination = d.nested()
+		if d.bool() {
+			typ := d.byte()
+			d.int32() // command id
+			d.bool()  // response required
+			c.message(d, typ)
+		}
+	case isMessage(c.typ):
+		c.message(d, c.typ)
+	}
+	if d.err != nil {
+		c.consumerID = nil
+	}
+}
+
+// message decodes the destination and the content of a message, the decoder is after its command fields.
+func (c *command) message(d *decoder, typ byte) {
+	d.nested() // producer id
+	_, dest := d.nested()
+	if c.destination == "" {
+		c.destination = dest
+	}
+	d.nested() // transaction id
+	d.nested() // original destination
+	d.nested() // message id
+	d.nested() // original transaction id
+	d.string() // group id
+	d.int32()  // group sequence
+	d.string() // correlation id
+	d.bool()   // persistent
+	d.int64()  // expiration
+	d.byte()   // priority
+	d.nested() // reply to
+	d.int64()  // timestamp
+	d.string() // type
+	content := d.byteSequence()
+	if d.err != nil {
+		return
+	}
+	c.body = content
+	// the text messages hold their text prefixed by its length
+	if typ == typeTextMessage && len(content) >= 4 && int(binary.BigEndian.Uint32(content)) == len(content)-4 {
+		c.body, c.textBody = content[4:], true
+	}
+}
+
+func (c *command) model() models.OpenWireCommand {
+	m := models.OpenWireCommand{
+		Type:             commandName(c.typ),
+		CommandID:        c.commandID,
+		ResponseRequired: c.responseRequired,
+		CorrelationID:    c.correlationID,
+		Destination:      c.destination,
+		Raw:              util.EncodeBase64(c.raw),
+	}
+	if c.body != nil {
+		if c.textBody || utf8.Valid(c.body) {
+			m.Body = string(c.body)
+		} else {
+			m.Body, m.Binary = util.EncodeBase64(c.body), true
+		}
+	}
+	return m
+}
+
+// withCorrelationID returns the response answering the command of the id.
+func (c *command) withCorrelationID(id int32) []byte {
+	raw := append([]byte(nil), c.raw...)
+	if isResponse(c.typ) && len(raw) >= 14 {
+		binary.BigEndian.PutUint32(raw[10:14], uint32(id))
+	}
+	return raw
+}
+
+// withConsumerID returns the dispatch with the encoding of the consumer id replaced.
+func (c *command) withConsumerID(id []byte) []byte {
+	if c.consumerID == nil {
+		return c.raw
+	}
+	end := c.consumerIDOffset + len(c.consumerID)
+	raw := make([]byte, 0, len(c.raw)-len(c.consumerID)+len(id))
+	raw = append(raw, c.raw[:c.consumerIDOffset]...)
+	raw = append(raw, id...)
+	raw = append(raw, c.raw[end:]...)
+	binary.BigEndian.PutUint32(raw[0:4], uint32(len(raw)-4))
+	return raw
+}
+
+// keepAlive builds the KeepAliveInfo sent to keep the conn alive, and to answer the ones of the peer.
+func keepAlive(commandID int32) []byte {
+	raw := []byte{0, 0, 0, 6, typeKeepAliveInfo, 0, 0, 0, 0, 0}
+	binary.BigEndian.PutUint32(raw[5:9], uint32(commandID))
+	return raw
+}
+
+// decoder reads the fields of the loose encoding, the first error stops the decoding and is kept in err.
+type decoder struct {
+	buf     []byte
+	off     int
+	version int32
+	err     error
+}
+
+func (d *decoder) next(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n < 0 || d.off+n > len(d.buf) {
+		d.err = errShortBuffer
+		return nil
+	}
+	b := d.buf[d.off : d.off+n]
+	d.off += n
+	return b
+}
+
+func (d *decoder) since(start int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	return d.buf[start:d.off]
+}
+
+func (d *decoder) byte() byte {
+	b := d.next(1)
+	if b == nil {
+		return 0
+	}
+	return b[0]
+}
+
+func (d *decoder) bool() bool {
+	return d.byte() != 0
+}
+
+func (d *decoder) int32() int32 {
+	b := d.next(4)
+	if b == nil {
+		return 0
+	}
+	return int32(binary.BigEndian.Uint32(b))
+}
+
+func (d *decoder) int64() int64 {
+	b := d.next(8)
+	if b == nil {
+		return 0
+	}
+	return int64(binary.BigEndian.Uint64(b))
+}
+
+// string reads a nullable string, a boolean followed by a string of java's modified utf-8.
+func (d *decoder) string() string {
+	if !d.bool() {
+		return ""
+	}
+	b := d.next(2)
+	if b == nil {
+		return ""
+	}
+	return string(d.next(int(binary.BigEndian.Uint16(b))))
+}
+
+// byteSequence reads a nullable byte sequence, a boolean followed by the size and the bytes.
+func (d *decoder) byteSequence() []byte {
+	if !d.bool() {
+		return nil
+	}
+	return d.next(int(d.int32()))
+}
+
+// nested reads a nullable data structure, and returns its type and the uri of the destinations. Only the
+// data structures of the fields decoded before the content of the messages are known.
+func (d *decoder) nested() (byte, string) {
+	if !d.bool() {
+		return typeNull, ""
+	}
+	typ := d.byte()
+	switch typ {
+	case typeQueue, typeTopic, typeTempQueue, typeTempTopic:
+		return typ, destinationSchemes[typ] + d.string()
+	case typeConnectionID:
+		d.string()
+	case typeSessionID:
+		d.string()
+		d.int64()
+	case typeConsumerID, typeProducerID:
+		d.string()
+		d.int64()
+		d.int64()
+	case typeMessageID:
+		if d.version >= firstMessageIDVersion {
+			d.string() // text view
+		}
+		d.nested() // producer id
+		d.int64()  // producer sequence id
+		d.int64()  // broker sequence id
+	case typeLocalTransaction:
+		d.int64()
+		d.nested() // connection id
+	case typeXATransactionID:
+		d.int32()        // format id
+		d.byteSequence() // global transaction id
+		d.byteSequence() // branch qualifier
+	default:
+		if d.err == nil {
+			d.err = fmt.Errorf("unknown openwire data structure %d", typ)
+		}
+	}
+	return typ, ""
+}
+
+// sameDestination reports whether the destinations are the same, the temporary destinations are named by the
+// conns creating them so any two of the same kind are the same.
+func sameDestination(recorded, dest string) bool {
+	if recorded == dest {
+		return true
+	}
+	scheme, _, _ := strings.Cut(recorded, "://")
+	return strings.HasPrefix(scheme, "temp-") && strings.HasPrefix(dest, scheme+"://")
+}
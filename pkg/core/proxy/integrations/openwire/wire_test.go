@@ -0,0 +1,240 @@
+//go:build linux
+
+package openwire
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+// encoder writes the fields of the loose encoding.
+type encoder struct {
+	b []byte
+}
+
+func (e *encoder) byte(v byte) *encoder { e.b = append(e.b, v); return e }
+
+func (e *encoder) bool(v bool) *encoder {
+	if v {
+		return e.byte(1)
+	}
+	return e.byte(0)
+}
+
+func (e *encoder) int32(v int32) *encoder {
+	e.b = binary.BigEndian.AppendUint32(e.b, uint32(v))
+	return e
+}
+
+func (e *encoder) int64(v int64) *encoder {
+	e.b = binary.BigEndian.AppendUint64(e.b, uint64(v))
+	return e
+}
+
+func (e *encoder) string(v string) *encoder {
+	e.bool(true)
+	e.b = binary.BigEndian.AppendUint16(e.b, uint16(len(v)))
+	e.b = append(e.b, v...)
+	return e
+}
+
+func (e *encoder) bytes(v []byte) *encoder {
+	e.bool(true).int32(int32(len(v)))
+	e.b = append(e.b, v...)
+	return e
+}
+
+func (e *encoder) null() *encoder { return e.bool(false) }
+
+func (e *encoder) consumerID(connection string, session, value int64) *encoder {
+	return e.bool(true).byte(typeConsumerID).string(connection).int64(session).int64(value)
+}
+
+func (e *encoder) producerID(connection string) *encoder {
+	return e.bool(true).byte(typeProducerID).string(connection).int64(1).int64(1)
+}
+
+func (e *encoder) destination(typ byte, name string) *encoder {
+	return e.bool(true).byte(typ).string(name)
+}
+
+// message writes the fields of a message after its command fields.
+func (e *encoder) message(dest string, content []byte) *encoder {
+	e.producerID("ID:host-1").destination(typeQueue, dest)
+	e.null().null() // transaction id and original destination
+	e.bool(true).byte(typeMessageID).string("ID:host-1:1:1:1").producerID("ID:host-1").int64(1).int64(0)
+	e.null()                             // original transaction id
+	e.null().int32(0).null()             // group id, group sequence and correlation id
+	e.bool(true).int64(0).byte(4).null() // persistent, expiration, priority and reply to
+	e.int64(1700000000000).null()        // timestamp and type
+	return e.bytes(content)
+}
+
+// frame prefixes the command of the type with its size.
+func frame(typ byte, commandID int32, fields func(e *encoder)) []byte {
+	e := (&encoder{}).byte(typ).int32(commandID).bool(true)
+	if fields != nil {
+		fields(e)
+	}
+	return append(binary.BigEndian.AppendUint32(nil, uint32(len(e.b))), e.b...)
+}
+
+func textContent(s string) []byte {
+	return append(binary.BigEndian.AppendUint32(nil, uint32(len(s))), s...)
+}
+
+func read(t *testing.T, raw []byte) *command {
+	t.Helper()
+	c, err := readCommand(bufio.NewReader(bytes.NewReader(raw)))
+	if err != nil {
+		t.Fatalf("readCommand() failed: %v", err)
+	}
+	if !bytes.Equal(c.raw, raw) {
+		t.Errorf("readCommand() raw = % x, want % x", c.raw, raw)
+	}
+	c.parse(12)
+	return c
+}
+
+func TestCommandRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  []byte
+		want string
+		dest string
+		body string
+	}{
+		{
+			name: "text message",
+			raw:  frame(typeTextMessage, 7, func(e *encoder) { e.message("orders", textContent(`{"id":1}`)) }),
+			want: "TextMessage",
+			dest: "queue://orders",
+			body: `{"id":1}`,
+		},
+		{
+			name: "binary message",
+			raw:  frame(typeMessage, 8, func(e *encoder) { e.message("orders", []byte{0xff, 0x00}) }),
+			want: "Message",
+			dest: "queue://orders",
+			body: "/wA=",
+		},
+		{
+			name: "consumer",
+			raw: frame(typeConsumerInfo, 9, func(e *encoder) {
+				e.consumerID("ID:host-1", 1, 1).bool(false).destination(typeTopic, "prices")
+			}),
+			want: "ConsumerInfo",
+			dest: "topic://prices",
+		},
+		{
+			name: "producer of a temporary queue",
+			raw: frame(typeProducerInfo, 10, func(e *encoder) {
+				e.producerID("ID:host-1").destination(typeTempQueue, "ID:host-1:1:1")
+			}),
+			want: "ProducerInfo",
+			dest: "temp-queue://ID:host-1:1:1",
+		},
+		{
+			name: "keep alive",
+			raw:  keepAlive(11),
+			want: "KeepAliveInfo",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := read(t, tt.raw)
+			m := c.model()
+			if m.Type != tt.want || m.Destination != tt.dest || m.Body != tt.body {
+				t.Errorf("model() = %s %q %q, want %s %q %q", m.Type, m.Destination, m.Body, tt.want, tt.dest, tt.body)
+			}
+			recorded, err := decodeRaw(m.Raw)
+			if err != nil {
+				t.Fatalf("decodeRaw() failed: %v", err)
+			}
+			recorded.parse(12)
+			if got := recorded.model(); !reflect.DeepEqual(got, m) {
+				t.Errorf("the model of the recorded command = %+v, want %+v", got, m)
+			}
+		})
+	}
+}
+
+func TestWithConsumerID(t *testing.T) {
+	dispatch := frame(typeMessageDispatch, 0, func(e *encoder) {
+		e.consumerID("ID:recorded", 1, 1).destination(typeQueue, "orders")
+		e.bool(true).byte(typeTextMessage).int32(3).bool(false).message("orders", textContent("hi"))
+		e.int32(0) // redelivery counter
+	})
+	c := read(t, dispatch)
+	if c.destination != "queue://orders" || string(c.body) != "hi" || c.consumerID == nil {
+		t.Fatalf("the dispatch read = %q %q, consumer id % x", c.destination, c.body, c.consumerID)
+	}
+
+	id := (&encoder{}).consumerID("ID:replayed-conn", 2, 5).b
+	rewritten := read(t, c.withConsumerID(id))
+	if !bytes.Equal(rewritten.consumerID, id) || rewritten.destination != c.destination || !bytes.Equal(rewritten.body, c.body) {
+		t.Errorf("the rewritten dispatch = %q %q, consumer id % x", rewritten.destination, rewritten.body, rewritten.consumerID)
+	}
+}
+
+func TestWithCorrelationID(t *testing.T) {
+	c := read(t, frame(typeResponse, 0, func(e *encoder) { e.int32(7) }))
+	if c.correlationID != 7 {
+		t.Fatalf("the correlation id = %d, want 7", c.correlationID)
+	}
+	if got := read(t, c.withCorrelationID(42)); got.correlationID != 42 {
+		t.Errorf("withCorrelationID() correlation id = %d, want 42", got.correlationID)
+	}
+}
+
+func TestWireFormat(t *testing.T) {
+	props := (&encoder{}).int32(3)
+	prop := func(key string, typ byte, value ...byte) {
+		props.b = binary.BigEndian.AppendUint16(props.b, uint16(len(key)))
+		props.b = append(append(append(props.b, key...), typ), value...)
+	}
+	prop("TightEncodingEnabled", propBoolean, 1)
+	prop("MaxInactivityDuration", propLong, 0, 0, 0, 0, 0, 0, 0x27, 0x10)
+	prop("CacheEnabled", propBoolean, 1)
+	e := (&encoder{}).byte(typeWireFormatInfo).bool(true)
+	e.b = append(e.b, magic...)
+	e.int32(12).bytes(props.b)
+	raw := append(binary.BigEndian.AppendUint32(nil, uint32(len(e.b))), e.b...)
+
+	wf, err := parseWireFormat(raw)
+	if err != nil || wf != (wireFormat{version: 12, inactivity: 10000}) {
+		t.Fatalf("parseWireFormat() = %+v, %v", wf, err)
+	}
+	loose := map[string]byte{}
+	if err := properties(looseWireFormat(raw), func(key string, typ byte, value []byte) {
+		if typ == propBoolean {
+			loose[key] = value[0]
+		}
+	}); err != nil {
+		t.Fatalf("properties() failed: %v", err)
+	}
+	if want := map[string]byte{"TightEncodingEnabled": 0, "CacheEnabled": 0}; !reflect.DeepEqual(loose, want) {
+		t.Errorf("the options of looseWireFormat() = %v, want %v", loose, want)
+	}
+}
+
+func TestReadCommandMalformed(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  []byte
+	}{
+		{"empty command", []byte{0, 0, 0, 0}},
+		{"oversized command", binary.BigEndian.AppendUint32(nil, maxFrameSize+1)},
+		{"truncated command", []byte{0, 0, 0, 6, typeKeepAliveInfo, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := readCommand(bufio.NewReader(bytes.NewReader(tt.raw))); err == nil {
+				t.Errorf("readCommand(% x) succeeded", tt.raw)
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+//go:build linux
+
+package openwire
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// wireFormatHeaderSize is the size of the size, the type, the magic with its boolean and the version
+const wireFormatHeaderSize = 18
+
+// the types of the values of the marshalled properties
+const (
+	propNull      byte = 0
+	propBoolean   byte = 1
+	propByte      byte = 2
+	propChar      byte = 3
+	propShort     byte = 4
+	propInteger   byte = 5
+	propLong      byte = 6
+	propDouble    byte = 7
+	propFloat     byte = 8
+	propString    byte = 9
+	propByteArray byte = 10
+	propMap       byte = 11
+	propList      byte = 12
+	propBigString byte = 13
+)
+
+// looseOptions are the options of the wire format turned off on both peers, so that the commands are sent in
+// the loose encoding without the cache and with their size. The tight encoding packs the booleans of a
+// command ahead of its fields, and the cache replaces the data structures sent before by their index in the
+// cache of the conn. The peers use an option only when both of them announce it.
+var looseOptions = []string{"TightEncodingEnabled", "CacheEnabled", "SizePrefixDisabled"}
+
+// wireFormat is the WireFormatInfo a peer starts the conn with.
+type wireFormat struct {
+	version int32
+	// inactivity is the time in milliseconds the peer waits for a command before closing the conn
+	inactivity int64
+}
+
+func isWireFormat(raw []byte) bool {
+	return len(raw) >= wireFormatHeaderSize && raw[4] == typeWireFormatInfo && raw[5] == 1 && string(raw[6:14]) == magic
+}
+
+func parseWireFormat(raw []byte) (wireFormat, error) {
+	if !isWireFormat(raw) {
+		return wireFormat{}, fmt.Errorf("the openwire conn doesn't start with a WireFormatInfo")
+	}
+	wf := wireFormat{version: int32(binary.BigEndian.Uint32(raw[14:18])), inactivity: defaultInactivity}
+	err := properties(raw, func(key string, typ byte, value []byte) {
+		if key == "MaxInactivityDuration" && typ == propLong {
+			wf.inactivity = int64(binary.BigEndian.Uint64(value))
+		}
+	})
+	return wf, err
+}
+
+// looseWireFormat returns the WireFormatInfo with the looseOptions turned off, the booleans are rewritten in place.
+func looseWireFormat(raw []byte) []byte {
+	res := append([]byte(nil), raw...)
+	// the values are slices of res
+	_ = properties(res, func(key string, typ byte, value []byte) {
+		for _, option := range looseOptions {
+			if key == option && typ == propBoolean {
+				value[0] = 0
+			}
+		}
+	})
+	return res
+}
+
+// properties calls fn with the marshalled properties of the WireFormatInfo, the values are slices of raw.
+func properties(raw []byte, fn func(key string, typ byte, value []byte)) error {
+	d := &decoder{buf: raw, off: wireFormatHeaderSize}
+	props := d.byteSequence()
+	if d.err != nil {
+		return d.err
+	}
+	p := &decoder{buf: props}
+	for i, n := 0, int(p.int32()); i < n && p.err == nil; i++ {
+		key := string(p.next(int(uint16(p.int16()))))
+		typ := p.byte()
+		start := p.off
+		p.skipProperty(typ)
+		if p.err == nil {
+			fn(key, typ, props[start:p.off])
+		}
+	}
+	return p.err
+}
+
+func (d *decoder) int16() int16 {
+	b := d.next(2)
+	if b == nil {
+		return 0
+	}
+	return int16(binary.BigEndian.Uint16(b))
+}
+
+// skipProperty skips a value of the marshalled properties.
+func (d *decoder) skipProperty(typ byte) {
+	switch typ {
+	case propNull:
+	case propBoolean, propByte:
+		d.next(1)
+	case propChar, propShort:
+		d.next(2)
+	case propInteger, propFloat:
+		d.next(4)
+	case propLong, propDouble:
+		d.next(8)
+	case propString:
+		d.next(int(uint16(d.int16())))
+	case propByteArray, propBigString:
+		d.next(int(d.int32()))
+	case propMap:
+		for i, n := 0, int(d.int32()); i < n && d.err == nil; i++ {
+			d.next(int(uint16(d.int16())))
+			d.skipProperty(d.byte())
+		}
+	case propList:
+		for i, n := 0, int(d.int32()); i < n && d.err == nil; i++ {
+			d.skipProperty(d.byte())
+		}
+	default:
+		if d.err == nil {
+			d.err = fmt.Errorf("unknown openwire property type %d", typ)
+		}
+	}
+}
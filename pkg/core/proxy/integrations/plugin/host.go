@@ -0,0 +1,377 @@
+//go:build linux
+
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.keploy.io/server/v2/config"
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+// startTimeout is the time given to a plugin to start serving on its socket
+const startTimeout = 10 * time.Second
+
+// Plugin is the keploy side of an out-of-process protocol parser. It satisfies integrations.Integrations.
+type Plugin struct {
+	logger  *zap.Logger
+	name    string
+	path    string
+	sockDir string
+	cmd     *exec.Cmd
+	client  *rpc.Client
+	callID  uint64
+}
+
+// Load starts every executable present in the given directory as a protocol plugin.
+// The plugin processes are stopped once the context is cancelled.
+func Load(ctx context.Context, logger *zap.Logger, dir string) (map[string]integrations.Integrations, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the plugin directory %s: %w", dir, err)
+	}
+
+	plugins := make(map[string]integrations.Integrations)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || info.Mode()&0111 == 0 {
+			logger.Debug("skipping non executable file in the plugin directory", zap.String("file", entry.Name()))
+			continue
+		}
+
+		p, err := start(ctx, logger, config.Plugin{Path: filepath.Join(dir, entry.Name())})
+		if err != nil {
+			utils.LogError(logger, err, "failed to start the protocol plugin", zap.String("plugin", entry.Name()))
+			continue
+		}
+		if _, ok := plugins[p.name]; ok {
+			logger.Warn("a plugin with the same name is already loaded, skipping", zap.String("plugin", p.name), zap.String("path", p.path))
+			p.stop()
+			continue
+		}
+		plugins[p.name] = p
+		logger.Info("loaded protocol plugin", zap.String("plugin", p.name), zap.String("path", p.path))
+	}
+	return plugins, nil
+}
+
+// LoadConfigured starts the plugins registered in the config, the plugins which fail to start are skipped.
+// The plugin processes are stopped once the context is cancelled.
+func LoadConfigured(ctx context.Context, logger *zap.Logger, cfgs []config.Plugin) map[string]integrations.Integrations {
+	plugins := make(map[string]integrations.Integrations)
+	for _, cfg := range cfgs {
+		p, err := start(ctx, logger, cfg)
+		if err != nil {
+			utils.LogError(logger, err, "failed to start the protocol plugin", zap.String("path", cfg.Path))
+			continue
+		}
+		if _, ok := plugins[p.name]; ok {
+			logger.Warn("a plugin with the same name is already loaded, skipping", zap.String("plugin", p.name), zap.String("path", p.path))
+			p.stop()
+			continue
+		}
+		plugins[p.name] = p
+		logger.Info("loaded protocol plugin", zap.String("plugin", p.name), zap.String("path", p.path))
+	}
+	return plugins
+}
+
+func start(ctx context.Context, logger *zap.Logger, cfg config.Plugin) (*Plugin, error) {
+	path := cfg.Path
+	sockDir, err := os.MkdirTemp("", "keploy-plugin-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the socket directory: %w", err)
+	}
+	sock := filepath.Join(sockDir, "plugin.sock")
+
+	cmd := exec.Command(path, cfg.Args...)
+	cmd.Env = os.Environ()
+	for k, v := range cfg.Env {
+		cmd.Env = append(cmd.Env, k+"="+v)
+	}
+	cmd.Env = append(cmd.Env, EnvSocket+"="+sock, EnvMagicCookie+"="+MagicCookie)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		_ = os.RemoveAll(sockDir)
+		return nil, err
+	}
+
+	p := &Plugin{
+		logger:  logger,
+		path:    path,
+		sockDir: sockDir,
+		cmd:     cmd,
+	}
+
+	conn, err := dialWithRetry(ctx, sock, startTimeout)
+	if err != nil {
+		p.stop()
+		return nil, fmt.Errorf("plugin did not start serving on %s: %w", sock, err)
+	}
+	p.client = jsonrpc.NewClient(conn)
+
+	var info InfoReply
+	if err := p.client.Call(pluginService+".Info", &Empty{}, &info); err != nil {
+		p.stop()
+		return nil, fmt.Errorf("failed to fetch the plugin info: %w", err)
+	}
+	if info.ProtocolVersion != ProtocolVersion {
+		p.stop()
+		return nil, fmt.Errorf("plugin protocol version %d is not supported, expected %d", info.ProtocolVersion, ProtocolVersion)
+	}
+	if cfg.Name != "" {
+		info.Name = cfg.Name
+	}
+	if info.Name == "" {
+		info.Name = filepath.Base(path)
+	}
+	p.name = info.Name
+	p.logger = logger.With(zap.String("plugin", p.name))
+
+	go func() {
+		<-ctx.Done()
+		p.stop()
+	}()
+	return p, nil
+}
+
+func (p *Plugin) stop() {
+	if p.client != nil {
+		_ = p.client.Close()
+	}
+	if p.cmd.Process != nil {
+		_ = p.cmd.Process.Kill()
+		_ = p.cmd.Wait()
+	}
+	if err := os.RemoveAll(p.sockDir); err != nil {
+		p.logger.Debug("failed to remove the plugin socket directory", zap.String("dir", p.sockDir), zap.Error(err))
+	}
+}
+
+func (p *Plugin) MatchType(_ context.Context, reqBuf []byte) integrations.MatchResult {
+	var res integrations.MatchResult
+	err := p.client.Call(pluginService+".MatchType", &MatchTypeArgs{ReqBuf: reqBuf}, &res)
+	if err != nil {
+		p.logger.Debug("failed to call MatchType on the plugin", zap.Error(err))
+		return integrations.MatchResult{}
+	}
+	return res
+}
+
+func (p *Plugin) RecordOutgoing(ctx context.Context, src net.Conn, dst net.Conn, mocks chan<- *models.Mock, opts models.OutgoingOptions) error {
+	args := p.connArgs(ctx, src, opts)
+	args.DstSocket = p.sockPath("dst")
+
+	var bridges []*bridge
+	defer func() {
+		for _, b := range bridges {
+			b.close()
+		}
+	}()
+
+	for sock, conn := range map[string]net.Conn{args.SrcSocket: src, args.DstSocket: dst} {
+		b, err := newBridge(p.logger, sock, conn)
+		if err != nil {
+			utils.LogError(p.logger, err, "failed to bridge the connection to the plugin")
+			return err
+		}
+		bridges = append(bridges, b)
+	}
+
+	host, err := serveHost(p.logger, args.HostSocket, &Host{mocks: mocks})
+	if err != nil {
+		utils.LogError(p.logger, err, "failed to serve the host services to the plugin")
+		return err
+	}
+	defer host.close()
+
+	return p.call(ctx, pluginService+".RecordOutgoing", args)
+}
+
+func (p *Plugin) MockOutgoing(ctx context.Context, src net.Conn, dstCfg *integrations.ConditionalDstCfg, mockDb integrations.MockMemDb, opts models.OutgoingOptions) error {
+	args := &MockOutgoingArgs{
+		ConnArgs: *p.connArgs(ctx, src, opts),
+		DstCfg: DstCfg{
+			Addr: dstCfg.Addr,
+			Port: dstCfg.Port,
+			TLS:  dstCfg.TLSCfg != nil,
+		},
+	}
+	if dstCfg.IP != nil {
+		args.DstCfg.IP = dstCfg.IP.String()
+	}
+	if dstCfg.TLSCfg != nil {
+		args.DstCfg.ServerName = dstCfg.TLSCfg.ServerName
+	}
+
+	b, err := newBridge(p.logger, args.SrcSocket, src)
+	if err != nil {
+		utils.LogError(p.logger, err, "failed to bridge the connection to the plugin")
+		return err
+	}
+	defer b.close()
+
+	host, err := serveHost(p.logger, args.HostSocket, &Host{mockDb: mockDb})
+	if err != nil {
+		utils.LogError(p.logger, err, "failed to serve the host services to the plugin")
+		return err
+	}
+	defer host.close()
+
+	return p.call(ctx, pluginService+".MockOutgoing", args)
+}
+
+func (p *Plugin) connArgs(ctx context.Context, src net.Conn, opts models.OutgoingOptions) *ConnArgs {
+	args := &ConnArgs{
+		SrcSocket:  p.sockPath("src"),
+		HostSocket: p.sockPath("host"),
+		Opts:       opts,
+	}
+	if src.RemoteAddr() != nil {
+		args.SrcAddr = src.RemoteAddr().String()
+	}
+	if id, ok := ctx.Value(models.ClientConnectionIDKey).(string); ok {
+		args.ClientConnID = id
+	}
+	if id, ok := ctx.Value(models.DestConnectionIDKey).(string); ok {
+		args.DestConnID = id
+	}
+	return args
+}
+
+func (p *Plugin) sockPath(kind string) string {
+	return filepath.Join(p.sockDir, fmt.Sprintf("%d-%s.sock", atomic.AddUint64(&p.callID, 1), kind))
+}
+
+// call invokes the plugin and waits until either the plugin returns or the context is done.
+func (p *Plugin) call(ctx context.Context, method string, args interface{}) error {
+	done := p.client.Go(method, args, &Empty{}, make(chan *rpc.Call, 1)).Done
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case call := <-done:
+		if call.Error != nil && call.Error.Error() != io.EOF.Error() {
+			return fmt.Errorf("plugin %s failed: %w", p.name, call.Error)
+		}
+		return nil
+	}
+}
+
+// bridge copies the data between a proxied connection and a unix socket dialed by the plugin.
+type bridge struct {
+	logger *zap.Logger
+	path   string
+	ln     net.Listener
+	mu     sync.Mutex
+	peer   net.Conn
+}
+
+func newBridge(logger *zap.Logger, path string, conn net.Conn) (*bridge, error) {
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	b := &bridge{logger: logger, path: path, ln: ln}
+	go func() {
+		defer utils.Recover(logger)
+		peer, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		b.mu.Lock()
+		b.peer = peer
+		b.mu.Unlock()
+
+		go func() {
+			defer utils.Recover(logger)
+			_, _ = io.Copy(peer, conn)
+		}()
+		_, _ = io.Copy(conn, peer)
+	}()
+	return b, nil
+}
+
+func (b *bridge) close() {
+	_ = b.ln.Close()
+	b.mu.Lock()
+	if b.peer != nil {
+		_ = b.peer.Close()
+	}
+	b.mu.Unlock()
+	if err := os.Remove(b.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		b.logger.Debug("failed to remove the plugin socket", zap.String("path", b.path), zap.Error(err))
+	}
+}
+
+// hostServer serves the Host rpc service to a single plugin call.
+type hostServer struct {
+	logger *zap.Logger
+	path   string
+	ln     net.Listener
+}
+
+func serveHost(logger *zap.Logger, path string, h *Host) (*hostServer, error) {
+	srv := rpc.NewServer()
+	if err := srv.RegisterName(hostService, h); err != nil {
+		return nil, err
+	}
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		defer utils.Recover(logger)
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go srv.ServeCodec(jsonrpc.NewServerCodec(conn))
+		}
+	}()
+	return &hostServer{logger: logger, path: path, ln: ln}, nil
+}
+
+func (s *hostServer) close() {
+	_ = s.ln.Close()
+	if err := os.Remove(s.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		s.logger.Debug("failed to remove the plugin socket", zap.String("path", s.path), zap.Error(err))
+	}
+}
+
+func dialWithRetry(ctx context.Context, path string, timeout time.Duration) (net.Conn, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		conn, err := net.Dial("unix", path)
+		if err == nil {
+			return conn, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
@@ -0,0 +1,83 @@
+//go:build linux
+
+// Package plugin provides out-of-process protocol parsers for the proxy.
+//
+// A plugin is a standalone executable that serves the integrations.Integrations
+// interface over json-rpc on a unix socket. Keploy starts every executable found
+// in the configured plugin directory, and the connections handed to the plugin are
+// bridged over per-call unix sockets, so that organizations can ship proprietary
+// protocol parsers without forking keploy. The reasons for json-rpc over gRPC are
+// given in the README of the package.
+package plugin
+
+import (
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+// ProtocolVersion is bumped whenever the rpc contract between keploy and the plugins changes.
+const ProtocolVersion = 1
+
+// environment variables passed by keploy to the plugin process
+const (
+	EnvSocket      = "KEPLOY_PLUGIN_SOCKET"
+	EnvMagicCookie = "KEPLOY_PLUGIN_MAGIC_COOKIE"
+	// MagicCookie is used by the plugin to verify that it is started by keploy and not directly by the user.
+	MagicCookie = "d3a6bf3c-keploy-protocol-plugin"
+)
+
+// rpc service names
+const (
+	pluginService = "Plugin"
+	hostService   = "Host"
+)
+
+// InfoReply describes the plugin to keploy.
+type InfoReply struct {
+	Name            string
+	ProtocolVersion int
+}
+
+type MatchTypeArgs struct {
+	ReqBuf []byte
+}
+
+// ConnArgs holds the unix sockets on which the proxied connections and the host services are bridged.
+type ConnArgs struct {
+	ClientConnID string
+	DestConnID   string
+	// SrcAddr is the remote address of the actual client connection
+	SrcAddr string
+	// SrcSocket is the unix socket bridged to the application connection
+	SrcSocket string
+	// DstSocket is the unix socket bridged to the destination connection (record mode only)
+	DstSocket string
+	// HostSocket serves the mocks channel (record mode) or the mock db (test mode)
+	HostSocket string
+	Opts       models.OutgoingOptions
+}
+
+// DstCfg is the serializable form of integrations.ConditionalDstCfg.
+type DstCfg struct {
+	Addr       string
+	Port       uint
+	IP         string
+	TLS        bool
+	ServerName string
+}
+
+type MockOutgoingArgs struct {
+	ConnArgs
+	DstCfg DstCfg
+}
+
+type MockArgs struct {
+	Mock *models.Mock
+}
+
+type UpdateMockArgs struct {
+	Old *models.Mock
+	New *models.Mock
+}
+
+// Empty is used for the rpc calls which don't need any arguments or reply.
+type Empty struct{}
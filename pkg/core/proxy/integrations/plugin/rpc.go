@@ -0,0 +1,126 @@
+//go:build linux
+
+package plugin
+
+import (
+	"errors"
+	"net/rpc"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+// Host is served by keploy for the duration of a single plugin call. It gives the plugin
+// access to the mocks channel in record mode and to the mock db in test mode.
+type Host struct {
+	mocks  chan<- *models.Mock
+	mockDb integrations.MockMemDb
+}
+
+var errNoMockDb = errors.New("mock db is only available in test mode")
+
+func (h *Host) PutMock(args *MockArgs, _ *Empty) error {
+	if h.mocks == nil {
+		return errors.New("mocks can only be recorded in record mode")
+	}
+	h.mocks <- args.Mock
+	return nil
+}
+
+func (h *Host) GetFilteredMocks(_ *Empty, reply *[]*models.Mock) error {
+	if h.mockDb == nil {
+		return errNoMockDb
+	}
+	mocks, err := h.mockDb.GetFilteredMocks()
+	*reply = mocks
+	return err
+}
+
+func (h *Host) GetUnFilteredMocks(_ *Empty, reply *[]*models.Mock) error {
+	if h.mockDb == nil {
+		return errNoMockDb
+	}
+	mocks, err := h.mockDb.GetUnFilteredMocks()
+	*reply = mocks
+	return err
+}
+
+func (h *Host) UpdateUnFilteredMock(args *UpdateMockArgs, reply *bool) error {
+	if h.mockDb == nil {
+		return errNoMockDb
+	}
+	*reply = h.mockDb.UpdateUnFilteredMock(args.Old, args.New)
+	return nil
+}
+
+func (h *Host) DeleteFilteredMock(args *MockArgs, reply *bool) error {
+	if h.mockDb == nil {
+		return errNoMockDb
+	}
+	*reply = h.mockDb.DeleteFilteredMock(*args.Mock)
+	return nil
+}
+
+func (h *Host) DeleteUnFilteredMock(args *MockArgs, reply *bool) error {
+	if h.mockDb == nil {
+		return errNoMockDb
+	}
+	*reply = h.mockDb.DeleteUnFilteredMock(*args.Mock)
+	return nil
+}
+
+func (h *Host) FlagMockAsUsed(args *MockArgs, _ *Empty) error {
+	if h.mockDb == nil {
+		return errNoMockDb
+	}
+	return h.mockDb.FlagMockAsUsed(*args.Mock)
+}
+
+// remoteMockDb is the plugin side of the Host service, it satisfies integrations.MockMemDb.
+type remoteMockDb struct {
+	client *rpc.Client
+}
+
+func (r *remoteMockDb) GetFilteredMocks() ([]*models.Mock, error) {
+	var mocks []*models.Mock
+	err := r.client.Call(hostService+".GetFilteredMocks", &Empty{}, &mocks)
+	return mocks, err
+}
+
+func (r *remoteMockDb) GetUnFilteredMocks() ([]*models.Mock, error) {
+	var mocks []*models.Mock
+	err := r.client.Call(hostService+".GetUnFilteredMocks", &Empty{}, &mocks)
+	return mocks, err
+}
+
+func (r *remoteMockDb) GetFilteredMocksByKey(kind models.Kind, key string) ([]*models.Mock, error) {
+	mocks, err := r.GetFilteredMocks()
+	return integrations.FilterByKey(mocks, kind, key), err
+}
+
+func (r *remoteMockDb) GetUnFilteredMocksByKey(kind models.Kind, key string) ([]*models.Mock, error) {
+	mocks, err := r.GetUnFilteredMocks()
+	return integrations.FilterByKey(mocks, kind, key), err
+}
+
+func (r *remoteMockDb) UpdateUnFilteredMock(old *models.Mock, new *models.Mock) bool {
+	var ok bool
+	err := r.client.Call(hostService+".UpdateUnFilteredMock", &UpdateMockArgs{Old: old, New: new}, &ok)
+	return err == nil && ok
+}
+
+func (r *remoteMockDb) DeleteFilteredMock(mock models.Mock) bool {
+	var ok bool
+	err := r.client.Call(hostService+".DeleteFilteredMock", &MockArgs{Mock: &mock}, &ok)
+	return err == nil && ok
+}
+
+func (r *remoteMockDb) DeleteUnFilteredMock(mock models.Mock) bool {
+	var ok bool
+	err := r.client.Call(hostService+".DeleteUnFilteredMock", &MockArgs{Mock: &mock}, &ok)
+	return err == nil && ok
+}
+
+func (r *remoteMockDb) FlagMockAsUsed(mock models.Mock) error {
+	return r.client.Call(hostService+".FlagMockAsUsed", &MockArgs{Mock: &mock}, &Empty{})
+}
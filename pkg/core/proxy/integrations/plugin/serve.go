@@ -0,0 +1,220 @@
+//go:build linux
+
+package plugin
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"os"
+	"sync"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+)
+
+// Serve is called from the main function of a plugin executable. It serves the given
+// integration to keploy and blocks until keploy closes the connection.
+func Serve(name string, initializer integrations.Initializer) error {
+	if os.Getenv(EnvMagicCookie) != MagicCookie {
+		return errors.New("this binary is a keploy protocol plugin and must be started by keploy")
+	}
+	sock := os.Getenv(EnvSocket)
+	if sock == "" {
+		return fmt.Errorf("%s is not set", EnvSocket)
+	}
+
+	logger, err := zap.NewProduction()
+	if err != nil {
+		return err
+	}
+
+	srv := rpc.NewServer()
+	err = srv.RegisterName(pluginService, &server{
+		name:   name,
+		logger: logger,
+		impl:   initializer(logger),
+	})
+	if err != nil {
+		return err
+	}
+
+	ln, err := net.Listen("unix", sock)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = ln.Close()
+	}()
+
+	// keploy opens a single control connection to the plugin
+	conn, err := ln.Accept()
+	if err != nil {
+		return err
+	}
+	srv.ServeCodec(jsonrpc.NewServerCodec(conn))
+	return nil
+}
+
+// server is the plugin side of the Plugin rpc service.
+type server struct {
+	name   string
+	logger *zap.Logger
+	impl   integrations.Integrations
+}
+
+func (s *server) Info(_ *Empty, reply *InfoReply) error {
+	reply.Name = s.name
+	reply.ProtocolVersion = ProtocolVersion
+	return nil
+}
+
+func (s *server) MatchType(args *MatchTypeArgs, reply *integrations.MatchResult) error {
+	*reply = s.impl.MatchType(context.Background(), args.ReqBuf)
+	return nil
+}
+
+func (s *server) RecordOutgoing(args *ConnArgs, _ *Empty) error {
+	sess, err := s.newSession(args)
+	if err != nil {
+		return err
+	}
+	defer sess.close()
+
+	dst, err := dial(args.DstSocket, "")
+	if err != nil {
+		return err
+	}
+	sess.conns = append(sess.conns, dst)
+
+	mocks := make(chan *models.Mock, 100)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer utils.Recover(s.logger)
+		for mock := range mocks {
+			if err := sess.host.Call(hostService+".PutMock", &MockArgs{Mock: mock}, &Empty{}); err != nil {
+				utils.LogError(s.logger, err, "failed to send the recorded mock to keploy")
+			}
+		}
+	}()
+
+	err = s.impl.RecordOutgoing(sess.ctx, sess.src, dst, mocks, args.Opts)
+	sess.wait()
+	close(mocks)
+	wg.Wait()
+	return err
+}
+
+func (s *server) MockOutgoing(args *MockOutgoingArgs, _ *Empty) error {
+	sess, err := s.newSession(&args.ConnArgs)
+	if err != nil {
+		return err
+	}
+	defer sess.close()
+
+	dstCfg := &integrations.ConditionalDstCfg{
+		Addr: args.DstCfg.Addr,
+		Port: args.DstCfg.Port,
+		IP:   net.ParseIP(args.DstCfg.IP),
+	}
+	if args.DstCfg.TLS {
+		dstCfg.TLSCfg = &tls.Config{
+			InsecureSkipVerify: true,
+			ServerName:         args.DstCfg.ServerName,
+		}
+	}
+
+	err = s.impl.MockOutgoing(sess.ctx, sess.src, dstCfg, &remoteMockDb{client: sess.host}, args.Opts)
+	sess.wait()
+	return err
+}
+
+// session holds the resources of a single RecordOutgoing/MockOutgoing call.
+type session struct {
+	logger *zap.Logger
+	ctx    context.Context
+	cancel context.CancelFunc
+	g      *errgroup.Group
+	src    net.Conn
+	host   *rpc.Client
+	conns  []net.Conn
+}
+
+func (s *server) newSession(args *ConnArgs) (*session, error) {
+	src, err := dial(args.SrcSocket, args.SrcAddr)
+	if err != nil {
+		return nil, err
+	}
+	hostConn, err := net.Dial("unix", args.HostSocket)
+	if err != nil {
+		_ = src.Close()
+		return nil, err
+	}
+
+	// the integrations expect the same context values as the ones set by the proxy
+	ctx, cancel := context.WithCancel(context.Background())
+	g, ctx := errgroup.WithContext(ctx)
+	ctx = context.WithValue(ctx, models.ErrGroupKey, g)
+	ctx = context.WithValue(ctx, models.ClientConnectionIDKey, args.ClientConnID)
+	ctx = context.WithValue(ctx, models.DestConnectionIDKey, args.DestConnID)
+
+	return &session{
+		logger: s.logger,
+		ctx:    ctx,
+		cancel: cancel,
+		g:      g,
+		src:    src,
+		host:   jsonrpc.NewClient(hostConn),
+		conns:  []net.Conn{src},
+	}, nil
+}
+
+func (sess *session) wait() {
+	sess.cancel()
+	if err := sess.g.Wait(); err != nil {
+		sess.logger.Debug("failed to wait for the integration goroutines", zap.Error(err))
+	}
+}
+
+func (sess *session) close() {
+	sess.cancel()
+	for _, c := range sess.conns {
+		_ = c.Close()
+	}
+	_ = sess.host.Close()
+}
+
+// conn reports the address of the actual client instead of the unix socket.
+type conn struct {
+	net.Conn
+	remote net.Addr
+}
+
+func (c *conn) RemoteAddr() net.Addr {
+	return c.remote
+}
+
+type addr string
+
+func (a addr) Network() string { return "tcp" }
+func (a addr) String() string  { return string(a) }
+
+func dial(sock, remote string) (net.Conn, error) {
+	c, err := net.Dial("unix", sock)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial %s: %w", sock, err)
+	}
+	if remote == "" {
+		remote = sock
+	}
+	return &conn{Conn: c, remote: addr(remote)}, nil
+}
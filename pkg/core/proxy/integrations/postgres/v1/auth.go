@@ -0,0 +1,243 @@
+//go:build linux
+
+package v1
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgproto3/v2"
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations/scram"
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations/util"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.uber.org/zap"
+)
+
+// defaultIterations is the iteration count of the servers, used when the recorded exchange isn't in the mocks
+const defaultIterations = 4096
+
+// scramAuth replays the authentication of the connections whose startup was answered with an AuthenticationSASL.
+// The messages of SCRAM-SHA-256 depend on the nonce of the client, and the client verifies the signature of the
+// server computed from the password, so the exchange is generated with the configured password and the salt
+// and the iterations recorded. Without a password, the connections are authenticated right away, as by a server
+// trusting the client.
+type scramAuth struct {
+	password string
+	// step is the authentication message the client answers, 0 when the connection isn't authenticating
+	step        int32
+	salt        string
+	iterations  int
+	clientFirst string
+	serverFirst string
+	// authenticated is the response recorded after the authentication, from the AuthenticationOk to the
+	// ReadyForQuery, and mock is the mock it's recorded in
+	authenticated models.Frontend
+	mock          *models.Mock
+}
+
+func newScramAuth(password string) *scramAuth {
+	return &scramAuth{password: password}
+}
+
+// authenticate answers the startup message and the SASL messages of the client, it reports false for the other
+// requests and for the startups which weren't authenticated with SASL while recording.
+func (a *scramAuth) authenticate(logger *zap.Logger, request []byte, mockDb integrations.MockMemDb) (bool, []byte, error) {
+	switch {
+	case isStartupPacket(request):
+		return a.start(logger, mockDb)
+	case a.step != 0 && len(request) > 5 && request[0] == 'p':
+		if a.step == AuthTypeSASL {
+			resp, err := a.serverFirstMessage(logger, request[5:])
+			return true, resp, err
+		}
+		resp, err := a.serverFinalMessage(logger, request[5:])
+		return true, resp, err
+	}
+	return false, nil, nil
+}
+
+func (a *scramAuth) start(logger *zap.Logger, mockDb integrations.MockMemDb) (bool, []byte, error) {
+	mocks, err := mockDb.GetUnFilteredMocksByKey(models.Postgres, "")
+	if err != nil {
+		return false, nil, fmt.Errorf("error while getting tcs mocks %v", err)
+	}
+
+	var startup *models.Mock
+	for _, mock := range mocks {
+		if mock.Kind != models.Postgres {
+			continue
+		}
+		if startup == nil && isSASLStartup(mock) {
+			startup = mock
+		}
+		if a.mock == nil {
+			if resp, ok := authenticatedResponse(mock); ok {
+				a.authenticated, a.mock = resp, mock
+			}
+		}
+		if a.salt == "" {
+			a.salt, a.iterations = recordedSalt(mock)
+		}
+	}
+	if startup == nil || a.mock == nil {
+		return false, nil, nil
+	}
+	for _, mock := range []*models.Mock{startup, a.mock} {
+		if err := mockDb.FlagMockAsUsed(*mock); err != nil {
+			logger.Error("failed to flag mock as used", zap.Error(err))
+		}
+	}
+
+	if a.password == "" {
+		logger.Debug("authenticating the postgres connection without the SASL exchange, the password isn't configured")
+		resp, err := a.authenticationOk(logger)
+		return true, resp, err
+	}
+	logger.Debug("replaying the SCRAM-SHA-256 authentication of the postgres connection", zap.String("mock", startup.Name))
+	a.step = AuthTypeSASL
+	// the mechanisms with channel binding aren't offered, the tls connection of the client isn't the recorded one
+	return true, (&pgproto3.AuthenticationSASL{AuthMechanisms: []string{util.SCRAM_SHA_256}}).Encode(nil), nil
+}
+
+// serverFirstMessage answers the client-first-message with the nonce of the client followed by a new one, and the
+// salt and the iterations of the password.
+func (a *scramAuth) serverFirstMessage(logger *zap.Logger, body []byte) ([]byte, error) {
+	var msg pgproto3.SASLInitialResponse
+	if err := msg.Decode(body); err != nil {
+		return nil, fmt.Errorf("failed to decode the SASLInitialResponse: %v", err)
+	}
+	if msg.AuthMechanism != util.SCRAM_SHA_256 {
+		return nil, fmt.Errorf("unsupported SASL mechanism %q", msg.AuthMechanism)
+	}
+	a.clientFirst = string(msg.Data)
+	clientNonce := scramAttribute(a.clientFirst, "r")
+	if clientNonce == "" {
+		return nil, errors.New("the client-first-message has no nonce")
+	}
+
+	nonce := make([]byte, 18)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	if a.salt == "" {
+		salt := make([]byte, 16)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, err
+		}
+		a.salt, a.iterations = base64.StdEncoding.EncodeToString(salt), defaultIterations
+	}
+	a.serverFirst = fmt.Sprintf("r=%s%s,s=%s,i=%d", clientNonce, base64.StdEncoding.EncodeToString(nonce), a.salt, a.iterations)
+	logger.Debug("the server-first-message of the SCRAM authentication", zap.String("message", a.serverFirst))
+
+	a.step = AuthTypeSASLContinue
+	return (&pgproto3.AuthenticationSASLContinue{Data: []byte(a.serverFirst)}).Encode(nil), nil
+}
+
+// serverFinalMessage answers the client-final-message with the signature of the server over the exchange, followed
+// by the recorded response of the authentication. The proof of the client isn't verified.
+func (a *scramAuth) serverFinalMessage(logger *zap.Logger, body []byte) ([]byte, error) {
+	a.step = 0
+	var msg pgproto3.SASLResponse
+	if err := msg.Decode(body); err != nil {
+		return nil, fmt.Errorf("failed to decode the SASLResponse: %v", err)
+	}
+	clientFinal := string(msg.Data)
+	proof := strings.LastIndex(clientFinal, ",p=")
+	if proof < 0 {
+		return nil, errors.New("the client-final-message has no proof")
+	}
+
+	salt, err := base64.StdEncoding.DecodeString(a.salt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode the salt: %v", err)
+	}
+	authMessage := clientFirstBare(a.clientFirst) + "," + a.serverFirst + "," + clientFinal[:proof]
+	verifier, err := scram.GenerateServerFinalMessage(authMessage, util.SCRAM_SHA_256, a.password, string(salt), a.iterations, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := (&pgproto3.AuthenticationSASLFinal{Data: []byte("v=" + verifier)}).Encode(nil)
+	ok, err := a.authenticationOk(logger)
+	if err != nil {
+		return nil, err
+	}
+	return append(resp, ok...), nil
+}
+
+// authenticationOk returns the AuthenticationOk followed by the parameters, the key and the ReadyForQuery recorded.
+func (a *scramAuth) authenticationOk(logger *zap.Logger) ([]byte, error) {
+	rest, err := postgresDecoderFrontend(a.authenticated)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode the recorded authentication response: %v", err)
+	}
+	logger.Debug("authenticated the postgres connection", zap.String("mock", a.mock.Name))
+	return append((&pgproto3.AuthenticationOk{}).Encode(nil), rest...), nil
+}
+
+// isSASLStartup reports whether the server asked for a SASL authentication on the startup of the mock.
+func isSASLStartup(mock *models.Mock) bool {
+	return len(mock.Spec.PostgresRequests) == 1 && mock.Spec.PostgresRequests[0].Identfier == "StartupRequest" &&
+		len(mock.Spec.PostgresResponses) > 0 && mock.Spec.PostgresResponses[0].AuthType == AuthTypeSASL
+}
+
+// authenticatedResponse returns the response of the mock ending the authentication without its authentication
+// messages, the AuthenticationSASLFinal is recorded along with the AuthenticationOk.
+func authenticatedResponse(mock *models.Mock) (models.Frontend, bool) {
+	for _, resp := range mock.Spec.PostgresResponses {
+		if resp.AuthType != AuthTypeOk || len(resp.PacketTypes) == 0 || resp.PacketTypes[0] != "R" ||
+			resp.PacketTypes[len(resp.PacketTypes)-1] != "Z" {
+			continue
+		}
+		var packets []string
+		for _, packet := range resp.PacketTypes {
+			if packet != "R" {
+				packets = append(packets, packet)
+			}
+		}
+		resp.PacketTypes = packets
+		resp.Payload = ""
+		return resp, true
+	}
+	return models.Frontend{}, false
+}
+
+// recordedSalt returns the salt and the iterations of the server-first-message recorded in the mock.
+func recordedSalt(mock *models.Mock) (string, int) {
+	for _, resp := range mock.Spec.PostgresResponses {
+		if len(resp.AuthenticationSASLContinue.Data) == 0 {
+			continue
+		}
+		serverFirst := string(resp.AuthenticationSASLContinue.Data)
+		iterations, err := strconv.Atoi(scramAttribute(serverFirst, "i"))
+		if salt := scramAttribute(serverFirst, "s"); salt != "" && err == nil && iterations > 0 {
+			return salt, iterations
+		}
+	}
+	return "", 0
+}
+
+// scramAttribute returns the value of the attribute of a SCRAM message, the attributes are separated by commas.
+func scramAttribute(msg, name string) string {
+	for _, attr := range strings.Split(msg, ",") {
+		if value, ok := strings.CutPrefix(attr, name+"="); ok {
+			return value
+		}
+	}
+	return ""
+}
+
+// clientFirstBare returns the client-first-message without its gs2 header, the channel binding flag and the
+// authorization identity each followed by a comma.
+func clientFirstBare(clientFirst string) string {
+	parts := strings.SplitN(clientFirst, ",", 3)
+	if len(parts) < 3 {
+		return clientFirst
+	}
+	return parts[2]
+}
@@ -0,0 +1,547 @@
+//go:build linux
+
+package v1
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jackc/pgproto3/v2"
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations/util"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.uber.org/zap"
+)
+
+// The sign of the binary numerics, ref: https://github.com/postgres/postgres/blob/master/src/backend/utils/adt/numeric.c
+const (
+	numericPos  = 0x0000
+	numericNeg  = 0x4000
+	numericNaN  = 0xC000
+	numericPInf = 0xD000
+	numericNInf = 0xF000
+)
+
+// b64Prefix marks the values of the rows kept as they are sent
+const b64Prefix = "b64:"
+
+// The layouts of the timestamps in their text format, the timestamptz are written in UTC
+const (
+	timestampLayout   = "2006-01-02 15:04:05.999999"
+	timestamptzLayout = "2006-01-02 15:04:05.999999-07"
+)
+
+// statementDescriptions are the columns of the statements described on a connection, by the name of the statement.
+// The drivers describe a statement once and bind it without asking for its columns again.
+type statementDescriptions struct {
+	statements map[string][]pgproto3.FieldDescription
+	// portals are the columns of the portals bound, the next rows of a portal are fetched without a Bind
+	portals map[string][]models.ResultColumn
+}
+
+func newStatementDescriptions() *statementDescriptions {
+	return &statementDescriptions{
+		statements: make(map[string][]pgproto3.FieldDescription),
+		portals:    make(map[string][]models.ResultColumn),
+	}
+}
+
+// forget drops the descriptions of the statements parsed again by the request.
+func (d *statementDescriptions) forget(req *models.Backend) {
+	for _, parse := range req.Parses {
+		delete(d.statements, parse.Name)
+	}
+}
+
+// describe keeps the columns of the statement described by the request, answered with the RowDescription of the
+// response.
+func (d *statementDescriptions) describe(req *models.Backend, resp *models.Frontend) {
+	if req == nil || countPackets(resp.PacketTypes, "T") != 1 {
+		return
+	}
+	for _, describe := range req.Describes {
+		if describe.ObjectType == 'S' {
+			d.statements[describe.Name] = append([]pgproto3.FieldDescription(nil), resp.RowDescription.Fields...)
+		}
+	}
+}
+
+// resultColumns returns the types and the formats of the columns of the rows of the response, taken from the
+// RowDescription of the response or else from the statement bound by the request and the result formats of its
+// Bind, or from the portal the request executes. It's nil when the rows aren't of a single statement.
+func (d *statementDescriptions) resultColumns(req *models.Backend, resp *models.Frontend) []models.ResultColumn {
+	if len(resp.DataRows) == 0 || (req != nil && len(req.Binds) > 1) {
+		return nil
+	}
+	var columns []models.ResultColumn
+	switch countPackets(resp.PacketTypes, "T") {
+	case 1:
+		for _, field := range resp.RowDescription.Fields {
+			columns = append(columns, models.ResultColumn{DataTypeOID: field.DataTypeOID, Format: field.Format})
+		}
+	case 0:
+		if req == nil {
+			return nil
+		}
+		if len(req.Binds) == 0 {
+			// the next rows of a portal bound before
+			if !isContinuation(req) || len(req.Executes) == 0 {
+				return nil
+			}
+			columns = d.portals[req.Executes[0].Portal]
+			break
+		}
+		bind := req.Binds[0]
+		fields, ok := d.statements[bind.PreparedStatement]
+		if !ok {
+			return nil
+		}
+		for i, field := range fields {
+			columns = append(columns, models.ResultColumn{DataTypeOID: field.DataTypeOID, Format: formatCode(bind.ResultFormatCodes, i)})
+		}
+	default:
+		return nil
+	}
+	if len(columns) == 0 {
+		return nil
+	}
+	for _, row := range resp.DataRows {
+		if len(row.RowValues) != len(columns) {
+			return nil
+		}
+	}
+	if req != nil && len(req.Binds) == 1 {
+		d.portals[req.Binds[0].DestinationPortal] = columns
+	}
+	return columns
+}
+
+// lastRequest returns the last request decoded, the response follows it.
+func lastRequest(reqs []models.Backend) *models.Backend {
+	for i := len(reqs) - 1; i >= 0; i-- {
+		if len(reqs[i].PacketTypes) > 0 {
+			return &reqs[i]
+		}
+	}
+	return nil
+}
+
+func countPackets(packets []string, packet string) int {
+	n := 0
+	for _, p := range packets {
+		if p == packet {
+			n++
+		}
+	}
+	return n
+}
+
+// readableRows replaces the values of the binary columns of the rows with their text format. The values which
+// wouldn't be encoded back to the same bytes are left as they're sent.
+func readableRows(resp *models.Frontend) {
+	for r := range resp.DataRows {
+		row := &resp.DataRows[r]
+		for i, column := range resp.ResultColumns {
+			if column.Format != pgproto3.BinaryFormat || i >= len(row.Values) || len(row.Values[i]) == 0 {
+				continue
+			}
+			if text, ok := decodeColumn(row.Values[i], column.DataTypeOID); ok {
+				row.RowValues[i] = text
+			}
+		}
+	}
+}
+
+// encodeRowValues returns the values of a row as they're sent, the binary columns are encoded from their text
+// format. The values are returned in the form the DataRow encodes them from.
+func encodeRowValues(values []string, columns []models.ResultColumn) []string {
+	if len(columns) == 0 {
+		return values
+	}
+	encoded := make([]string, len(values))
+	copy(encoded, values)
+	for i, column := range columns {
+		if i >= len(encoded) || encoded[i] == "" || strings.HasPrefix(encoded[i], b64Prefix) {
+			continue
+		}
+		if column.Format == pgproto3.BinaryFormat {
+			if value, ok := encodeColumn(encoded[i], column.DataTypeOID); ok {
+				encoded[i] = b64Prefix + util.EncodeBase64(value)
+			}
+			continue
+		}
+		if !pgproto3.IsAsciiPrintable(encoded[i]) {
+			encoded[i] = b64Prefix + util.EncodeBase64([]byte(encoded[i]))
+		}
+	}
+	return encoded
+}
+
+// withResultFormats returns the responses with their rows in the formats the request binds the results in, the
+// formats of the application may differ from the recorded ones. The values of the rows are kept in their text
+// format, so they're sent in either format.
+func withResultFormats(responses []models.Frontend, requests [][]byte, logger *zap.Logger) []models.Frontend {
+	req := decodePgRequest(bytes.Join(requests, nil), logger)
+	if req == nil || len(req.Binds) != 1 {
+		return responses
+	}
+	bind := req.Binds[0]
+
+	var adapted []models.Frontend
+	for idx, resp := range responses {
+		if len(resp.ResultColumns) == 0 || resp.Payload != "" {
+			continue
+		}
+		changed := false
+		columns := make([]models.ResultColumn, len(resp.ResultColumns))
+		for i, column := range resp.ResultColumns {
+			columns[i] = column
+			if format := formatCode(bind.ResultFormatCodes, i); format != column.Format {
+				columns[i].Format = format
+				changed = true
+			}
+		}
+		if !changed {
+			continue
+		}
+		if adapted == nil {
+			adapted = append([]models.Frontend(nil), responses...)
+		}
+		resp.ResultColumns = columns
+		if countPackets(resp.PacketTypes, "T") == 1 && len(resp.RowDescription.Fields) == len(columns) {
+			fields := make([]pgproto3.FieldDescription, len(columns))
+			copy(fields, resp.RowDescription.Fields)
+			for i := range fields {
+				fields[i].Format = columns[i].Format
+			}
+			resp.RowDescription.Fields = fields
+		}
+		adapted[idx] = resp
+		logger.Debug("changed the formats of the result columns to the ones of the request")
+	}
+	if adapted == nil {
+		return responses
+	}
+	return adapted
+}
+
+// decodeColumn returns the text format of a value of the type in the binary format. It reports false for the types
+// not decoded, and for the values whose text isn't encoded back to the same bytes.
+func decodeColumn(value []byte, oid uint32) (string, bool) {
+	text, ok := binaryToText(value, oid)
+	if !ok || text == "" || strings.HasPrefix(text, b64Prefix) {
+		return "", false
+	}
+	encoded, ok := encodeColumn(text, oid)
+	if !ok || !bytes.Equal(encoded, value) {
+		return "", false
+	}
+	return text, true
+}
+
+func binaryToText(value []byte, oid uint32) (string, bool) {
+	switch oid {
+	case oidBool:
+		if len(value) == 1 {
+			if value[0] != 0 {
+				return "t", true
+			}
+			return "f", true
+		}
+	case oidInt2:
+		if len(value) == 2 {
+			return strconv.FormatInt(int64(int16(binary.BigEndian.Uint16(value))), 10), true
+		}
+	case oidInt4:
+		if len(value) == 4 {
+			return strconv.FormatInt(int64(int32(binary.BigEndian.Uint32(value))), 10), true
+		}
+	case oidOID:
+		if len(value) == 4 {
+			return strconv.FormatUint(uint64(binary.BigEndian.Uint32(value)), 10), true
+		}
+	case oidInt8:
+		if len(value) == 8 {
+			return strconv.FormatInt(int64(binary.BigEndian.Uint64(value)), 10), true
+		}
+	case oidFloat4:
+		if len(value) == 4 {
+			return formatFloat(float64(math.Float32frombits(binary.BigEndian.Uint32(value))), 32), true
+		}
+	case oidFloat8:
+		if len(value) == 8 {
+			return formatFloat(math.Float64frombits(binary.BigEndian.Uint64(value)), 64), true
+		}
+	case oidNumeric:
+		return numericToText(value)
+	case oidText, oidVarchar, oidBpchar, oidName, oidJSON:
+		if utf8.Valid(value) {
+			return string(value), true
+		}
+	case oidJSONB:
+		// the binary jsonb is the text prefixed with the version of the format
+		if len(value) > 0 && value[0] == 1 && utf8.Valid(value[1:]) {
+			return string(value[1:]), true
+		}
+	case oidBytea:
+		return `\x` + hex.EncodeToString(value), true
+	case oidUUID:
+		if len(value) == 16 {
+			h := hex.EncodeToString(value)
+			return h[:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:], true
+		}
+	case oidDate:
+		if len(value) == 4 {
+			switch days := int32(binary.BigEndian.Uint32(value)); days {
+			case math.MaxInt32:
+				return "infinity", true
+			case math.MinInt32:
+				return "-infinity", true
+			default:
+				return postgresEpoch.AddDate(0, 0, int(days)).Format("2006-01-02"), true
+			}
+		}
+	case oidTimestamp, oidTimestamptz:
+		if len(value) == 8 {
+			switch micros := int64(binary.BigEndian.Uint64(value)); micros {
+			case math.MaxInt64:
+				return "infinity", true
+			case math.MinInt64:
+				return "-infinity", true
+			default:
+				ts := postgresEpoch.Add(time.Duration(micros) * time.Microsecond)
+				if oid == oidTimestamptz {
+					return ts.Format(timestamptzLayout), true
+				}
+				return ts.Format(timestampLayout), true
+			}
+		}
+	}
+	return "", false
+}
+
+// encodeColumn returns the binary format of a value of the type in the text format, it reports false for the types
+// not encoded and for the values which aren't of the type.
+func encodeColumn(text string, oid uint32) ([]byte, bool) {
+	switch oid {
+	case oidBool:
+		switch strings.ToLower(text) {
+		case "t", "true":
+			return []byte{1}, true
+		case "f", "false":
+			return []byte{0}, true
+		}
+	case oidInt2:
+		if n, err := strconv.ParseInt(text, 10, 16); err == nil {
+			return binary.BigEndian.AppendUint16(nil, uint16(n)), true
+		}
+	case oidInt4:
+		if n, err := strconv.ParseInt(text, 10, 32); err == nil {
+			return binary.BigEndian.AppendUint32(nil, uint32(n)), true
+		}
+	case oidOID:
+		if n, err := strconv.ParseUint(text, 10, 32); err == nil {
+			return binary.BigEndian.AppendUint32(nil, uint32(n)), true
+		}
+	case oidInt8:
+		if n, err := strconv.ParseInt(text, 10, 64); err == nil {
+			return binary.BigEndian.AppendUint64(nil, uint64(n)), true
+		}
+	case oidFloat4:
+		if f, err := strconv.ParseFloat(text, 32); err == nil {
+			return binary.BigEndian.AppendUint32(nil, math.Float32bits(float32(f))), true
+		}
+	case oidFloat8:
+		if f, err := strconv.ParseFloat(text, 64); err == nil {
+			return binary.BigEndian.AppendUint64(nil, math.Float64bits(f)), true
+		}
+	case oidNumeric:
+		return textToNumeric(text)
+	case oidText, oidVarchar, oidBpchar, oidName, oidJSON:
+		return []byte(text), true
+	case oidJSONB:
+		return append([]byte{1}, text...), true
+	case oidBytea:
+		if h, ok := strings.CutPrefix(text, `\x`); ok {
+			if value, err := hex.DecodeString(h); err == nil {
+				return value, true
+			}
+		}
+	case oidUUID:
+		if value, err := hex.DecodeString(strings.ReplaceAll(strings.Trim(text, "{}"), "-", "")); err == nil && len(value) == 16 {
+			return value, true
+		}
+	case oidDate:
+		switch text {
+		case "infinity":
+			return binary.BigEndian.AppendUint32(nil, math.MaxInt32), true
+		case "-infinity":
+			return binary.BigEndian.AppendUint32(nil, 1<<31), true
+		}
+		if date, err := time.Parse("2006-01-02", text); err == nil {
+			days := date.Sub(postgresEpoch).Hours() / 24
+			return binary.BigEndian.AppendUint32(nil, uint32(int32(math.Round(days)))), true
+		}
+	case oidTimestamp, oidTimestamptz:
+		switch text {
+		case "infinity":
+			return binary.BigEndian.AppendUint64(nil, math.MaxInt64), true
+		case "-infinity":
+			return binary.BigEndian.AppendUint64(nil, 1<<63), true
+		}
+		layouts := []string{timestampLayout}
+		if oid == oidTimestamptz {
+			layouts = []string{timestamptzLayout, "2006-01-02 15:04:05.999999-07:00", "2006-01-02 15:04:05.999999-07:00:00"}
+		}
+		for _, layout := range layouts {
+			if ts, err := time.Parse(layout, text); err == nil {
+				micros := ts.Sub(postgresEpoch).Microseconds()
+				return binary.BigEndian.AppendUint64(nil, uint64(micros)), true
+			}
+		}
+	}
+	return nil, false
+}
+
+// formatFloat returns the text format of a float, the infinities are spelled out.
+func formatFloat(f float64, bits int) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "Infinity"
+	case math.IsInf(f, -1):
+		return "-Infinity"
+	}
+	return strconv.FormatFloat(f, 'g', -1, bits)
+}
+
+// numericToText returns the text format of a binary numeric, the digits are in base 10000 with the weight of the
+// first one and the scale of the value.
+func numericToText(value []byte) (string, bool) {
+	if len(value) < 8 {
+		return "", false
+	}
+	ndigits := int(binary.BigEndian.Uint16(value))
+	weight := int(int16(binary.BigEndian.Uint16(value[2:])))
+	sign := binary.BigEndian.Uint16(value[4:])
+	dscale := int(binary.BigEndian.Uint16(value[6:]))
+	if len(value) != 8+2*ndigits {
+		return "", false
+	}
+	switch sign {
+	case numericNaN:
+		return "NaN", true
+	case numericPInf:
+		return "Infinity", true
+	case numericNInf:
+		return "-Infinity", true
+	case numericPos, numericNeg:
+	default:
+		return "", false
+	}
+	for i := 0; i < ndigits; i++ {
+		if binary.BigEndian.Uint16(value[8+2*i:]) > 9999 {
+			return "", false
+		}
+	}
+	digit := func(i int) int {
+		if i < 0 || i >= ndigits {
+			return 0
+		}
+		return int(binary.BigEndian.Uint16(value[8+2*i:]))
+	}
+
+	var sb strings.Builder
+	if sign == numericNeg {
+		sb.WriteByte('-')
+	}
+	if weight < 0 {
+		sb.WriteByte('0')
+	}
+	for i := 0; i <= weight; i++ {
+		if i == 0 {
+			sb.WriteString(strconv.Itoa(digit(i)))
+		} else {
+			sb.WriteString(padDigit(digit(i)))
+		}
+	}
+	if dscale > 0 {
+		var frac strings.Builder
+		for i := weight + 1; frac.Len() < dscale; i++ {
+			frac.WriteString(padDigit(digit(i)))
+		}
+		sb.WriteByte('.')
+		sb.WriteString(frac.String()[:dscale])
+	}
+	return sb.String(), true
+}
+
+// textToNumeric returns the binary format of a numeric in the text format, without the leading and the trailing
+// zero digits.
+func textToNumeric(text string) ([]byte, bool) {
+	var sign uint16
+	switch text {
+	case "NaN":
+		sign = numericNaN
+	case "Infinity":
+		sign = numericPInf
+	case "-Infinity":
+		sign = numericNInf
+	}
+	if sign != 0 {
+		buf := make([]byte, 8)
+		binary.BigEndian.PutUint16(buf[4:], sign)
+		return buf, true
+	}
+
+	if neg, ok := strings.CutPrefix(text, "-"); ok {
+		sign, text = numericNeg, neg
+	}
+	intPart, fracPart, _ := strings.Cut(text, ".")
+	if intPart == "" || strings.Trim(intPart+fracPart, "0123456789") != "" {
+		return nil, false
+	}
+	dscale := len(fracPart)
+
+	// the digits are grouped by 4 from the decimal point
+	intPart = strings.Repeat("0", (4-len(intPart)%4)%4) + intPart
+	fracPart += strings.Repeat("0", (4-len(fracPart)%4)%4)
+	var digits []uint16
+	for i := 0; i < len(intPart); i += 4 {
+		d, _ := strconv.Atoi(intPart[i : i+4])
+		digits = append(digits, uint16(d))
+	}
+	for i := 0; i < len(fracPart); i += 4 {
+		d, _ := strconv.Atoi(fracPart[i : i+4])
+		digits = append(digits, uint16(d))
+	}
+	weight := len(intPart)/4 - 1
+	for len(digits) > 0 && digits[0] == 0 {
+		digits = digits[1:]
+		weight--
+	}
+	for len(digits) > 0 && digits[len(digits)-1] == 0 {
+		digits = digits[:len(digits)-1]
+	}
+	if len(digits) == 0 {
+		weight, sign = 0, numericPos
+	}
+
+	buf := binary.BigEndian.AppendUint16(nil, uint16(len(digits)))
+	buf = binary.BigEndian.AppendUint16(buf, uint16(int16(weight)))
+	buf = binary.BigEndian.AppendUint16(buf, sign)
+	buf = binary.BigEndian.AppendUint16(buf, uint16(dscale))
+	for _, d := range digits {
+		buf = binary.BigEndian.AppendUint16(buf, d)
+	}
+	return buf, true
+}
+
+func padDigit(d int) string {
+	s := strconv.Itoa(d)
+	return strings.Repeat("0", 4-len(s)) + s
+}
@@ -0,0 +1,126 @@
+//go:build linux
+
+package v1
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations/util"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.uber.org/zap"
+)
+
+// copyIn collects the messages the client streams after the CopyInResponse of a COPY FROM STDIN. They're read
+// in as many buffers as the client writes them in, and answered together once the client ends the copy with a
+// CopyDone or a CopyFail.
+type copyIn struct {
+	active bool
+	data   []byte
+}
+
+// ended reports whether the client ended the copy.
+func (c *copyIn) ended() bool {
+	types, _ := messageTypes(c.data)
+	return bytes.IndexByte(types, 'c') != -1 || bytes.IndexByte(types, 'f') != -1
+}
+
+// messageTypes returns the types of the whole messages at the start of a buffer, and the length they span.
+func messageTypes(buf []byte) ([]byte, int) {
+	var types []byte
+	i := 0
+	for i+5 <= len(buf) {
+		length := int(binary.BigEndian.Uint32(buf[i+1:])) + 1
+		if length < 5 || i+length > len(buf) {
+			break
+		}
+		types = append(types, buf[i])
+		i += length
+	}
+	return types, i
+}
+
+// startsCopyIn reports whether the response asks the client for the rows of a COPY FROM STDIN.
+func startsCopyIn(resp []byte) bool {
+	types, _ := messageTypes(resp)
+	return bytes.IndexByte(types, 'G') != -1
+}
+
+// requestBytes returns the messages of the requests of a mock as they were sent.
+func requestBytes(mock *models.Mock) []byte {
+	var buf []byte
+	for _, req := range mock.Spec.PostgresRequests {
+		var encoded []byte
+		var err error
+		if req.Payload != "" {
+			encoded, err = util.DecodeBase64(req.Payload)
+		} else {
+			encoded, err = postgresDecoderBackend(req)
+		}
+		if err != nil {
+			return nil
+		}
+		buf = append(buf, encoded...)
+	}
+	return buf
+}
+
+// matchCopyData returns the responses of the mock recorded for the rows the client copied. The mocks whose requests
+// start with the copy messages are compared with the rows, the mock with the same rows or else with the most
+// similar ones is matched. The mocks recorded during the current test are preferred.
+func matchCopyData(ctx context.Context, logger *zap.Logger, data []byte, mockDb integrations.MockMemDb) ([]models.Frontend, error) {
+	for {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		mocks, err := mockDb.GetUnFilteredMocksByKey(models.Postgres, "")
+		if err != nil {
+			return nil, fmt.Errorf("error while getting tcs mocks %v", err)
+		}
+
+		var matchedMock *models.Mock
+		mxSim := -1.0
+		for _, mock := range mocks {
+			if mock.Kind != models.Postgres {
+				continue
+			}
+			recorded := requestBytes(mock)
+			types, _ := messageTypes(recorded)
+			if len(types) == 0 || (types[0] != 'd' && types[0] != 'c' && types[0] != 'f') {
+				continue
+			}
+			if matchedMock != nil && matchedMock.TestModeInfo.IsFiltered && !mock.TestModeInfo.IsFiltered {
+				// the mocks of the current test are ahead of the others
+				break
+			}
+			if bytes.Equal(recorded, data) {
+				matchedMock, mxSim = mock, 1
+				break
+			}
+			if similarity := fuzzyCheck(recorded, data); similarity > mxSim {
+				matchedMock, mxSim = mock, similarity
+			}
+		}
+		if matchedMock == nil {
+			return nil, nil
+		}
+		logger.Debug("Matched the copy data", zap.String("mock", matchedMock.Name), zap.Float64("similarity", mxSim))
+
+		if matchedMock.TestModeInfo.IsFiltered {
+			originalMatchedMock := *matchedMock
+			matchedMock.TestModeInfo.IsFiltered = false
+			matchedMock.TestModeInfo.SortOrder = math.MaxInt
+			//UpdateUnFilteredMock also marks the mock as used
+			if !mockDb.UpdateUnFilteredMock(&originalMatchedMock, matchedMock) {
+				continue
+			}
+		} else if err := mockDb.FlagMockAsUsed(*matchedMock); err != nil {
+			logger.Error("failed to flag mock as used", zap.Error(err))
+		}
+		return matchedMock.Spec.PostgresResponses, nil
+	}
+}
@@ -4,7 +4,9 @@
 package v1
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net"
@@ -20,14 +22,23 @@ import (
 	"go.uber.org/zap"
 )
 
-func decodePostgres(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientConn net.Conn, dstCfg *integrations.ConditionalDstCfg, mockDb integrations.MockMemDb, _ models.OutgoingOptions) error {
+func decodePostgres(ctx context.Context, logger *zap.Logger, reqBuf []byte, conn net.Conn, dstCfg *integrations.ConditionalDstCfg, mockDb integrations.MockMemDb, opts models.OutgoingOptions) error {
 	pgRequests := [][]byte{reqBuf}
+	// the notifications of the channels the client listens on are written along with the responses
+	clientConn := &notifyConn{Conn: conn}
+	auth := newScramAuth(opts.Postgres.Password)
+	var copying copyIn
+	var fetched batches
+	// streaming replays the changes streamed after a START_REPLICATION
+	var streaming replicationStream
 	errCh := make(chan error, 1)
 
 	go func(errCh chan error, pgRequests [][]byte) {
 		defer pUtil.Recover(logger, clientConn, nil)
 		// close should be called from the producer of the channel
 		defer close(errCh)
+		defer notifications.remove(clientConn)
+		defer streaming.stop()
 		for {
 			// Since protocol packets have to be parsed for checking stream end,
 			// clientConnection have deadline for read to determine the end of stream.
@@ -59,22 +70,101 @@ func decodePostgres(ctx context.Context, logger *zap.Logger, reqBuf []byte, clie
 			if len(pgRequests) == 0 {
 				continue
 			}
-			var mutex sync.Mutex
-			matched, pgResponses, err := matchingReadablePG(ctx, logger, &mutex, pgRequests, mockDb)
-			if err != nil {
-				errCh <- fmt.Errorf("error while matching tcs mocks %v", err)
-				return
+
+			// the authentication is answered from the recorded startup, the SCRAM messages depend on the client
+			if len(pgRequests) == 1 {
+				authenticated, resp, err := auth.authenticate(logger, pgRequests[0], mockDb)
+				if err != nil {
+					utils.LogError(logger, err, "failed to authenticate the postgres connection")
+					errCh <- err
+					return
+				}
+				if authenticated {
+					_, err = clientConn.Write(resp)
+					if err != nil && err != io.EOF && strings.Contains(err.Error(), "use of closed network connection") {
+						utils.LogError(logger, err, "failed to write the response message to the client application")
+						errCh <- err
+					}
+					pgRequests = [][]byte{}
+					continue
+				}
 			}
 
-			if !matched {
-				logger.Debug("MISMATCHED REQ is" + string(pgRequests[0]))
-				_, err = pUtil.PassThrough(ctx, logger, clientConn, dstCfg, pgRequests)
+			if streaming.active() {
+				// the standby status updates aren't answered, the client ends the stream with a CopyDone
+				if isStandbyFeedback(bytes.Join(pgRequests, nil)) {
+					pgRequests = [][]byte{}
+					continue
+				}
+				streaming.stop()
+			}
+
+			var pgResponses []models.Frontend
+			var matchedMock *models.Mock
+			if copying.active {
+				// the rows of the copy are matched once the client ends it
+				for _, req := range pgRequests {
+					copying.data = append(copying.data, req...)
+				}
+				pgRequests = [][]byte{}
+				if !copying.ended() {
+					continue
+				}
+				pgResponses, err = matchCopyData(ctx, logger, copying.data, mockDb)
 				if err != nil {
-					utils.LogError(logger, err, "failed to pass the request", zap.Any("request packets", len(pgRequests)))
+					errCh <- fmt.Errorf("error while matching the copy data %v", err)
+					return
+				}
+				if pgResponses == nil {
+					err := errors.New("no postgres mock found for the copy data")
+					utils.LogError(logger, err, "failed to mock the copy from stdin", zap.Int("copy data", len(copying.data)))
 					errCh <- err
+					return
 				}
-				continue
+				copying = copyIn{}
+			} else {
+				var mutex sync.Mutex
+				var matched bool
+				// the next rows of a portal or a cursor are fetched with the same request for each batch
+				var err error
+				matchedMock, err = fetched.next(ctx, logger, pgRequests, mockDb)
+				if err != nil {
+					errCh <- fmt.Errorf("error while matching the next batch of the portal %v", err)
+					return
+				}
+				if matchedMock != nil {
+					matched, pgResponses = true, matchedMock.Spec.PostgresResponses
+				} else {
+					matched, pgResponses, matchedMock, err = matchingReadablePG(ctx, logger, &mutex, pgRequests, mockDb)
+					if err != nil {
+						errCh <- fmt.Errorf("error while matching tcs mocks %v", err)
+						return
+					}
+				}
+				fetched.matched(matchedMock)
+
+				// the recordings made behind a connection pooler differ in the statements the pooler sends and resets
+				if !matched && opts.Postgres.Pooler {
+					pgResponses, err = matchPooled(ctx, logger, pgRequests, mockDb)
+					if err != nil {
+						errCh <- fmt.Errorf("error while matching the pooled request %v", err)
+						return
+					}
+					matched = pgResponses != nil
+				}
+
+				if !matched {
+					logger.Debug("MISMATCHED REQ is" + string(pgRequests[0]))
+					_, err = pUtil.PassThrough(ctx, logger, clientConn, dstCfg, pgRequests)
+					if err != nil {
+						utils.LogError(logger, err, "failed to pass the request", zap.Any("request packets", len(pgRequests)))
+						errCh <- err
+					}
+					continue
+				}
+				pgResponses = withResultFormats(volatiles.rewrite(pgResponses), pgRequests, logger)
 			}
+			replicating := false
 			for _, pgResponse := range pgResponses {
 				encoded, err := util.DecodeBase64(pgResponse.Payload)
 				if len(pgResponse.PacketTypes) > 0 && len(pgResponse.Payload) == 0 {
@@ -84,12 +174,22 @@ func decodePostgres(ctx context.Context, logger *zap.Logger, reqBuf []byte, clie
 					utils.LogError(logger, err, "failed to decode the response message in proxy for postgres dependency")
 					errCh <- err
 				}
+				// the client streams the rows of a COPY FROM STDIN after the CopyInResponse
+				if startsCopyIn(encoded) {
+					copying.active = true
+				}
+				replicating = replicating || startsReplication(encoded)
 				_, err = clientConn.Write(encoded)
 				if err != nil && err != io.EOF && strings.Contains(err.Error(), "use of closed network connection") {
 					utils.LogError(logger, err, "failed to write the response message to the client application")
 					errCh <- err
 				}
 			}
+			// the server streams the changes after the CopyBothResponse of a START_REPLICATION
+			if replicating && matchedMock != nil {
+				streaming.start(ctx, logger, clientConn, mockDb, matchedMock)
+			}
+			notifications.track(clientConn, pgRequests)
 			// Clear the buffer for the next dependency call
 			pgRequests = [][]byte{}
 		}
@@ -107,8 +207,9 @@ func decodePostgres(ctx context.Context, logger *zap.Logger, reqBuf []byte, clie
 }
 
 type QueryData struct {
-	PrepIdentifier string `json:"PrepIdentifier" yaml:"PrepIdentifier"`
-	Query          string `json:"Query" yaml:"Query"`
+	PrepIdentifier string   `json:"PrepIdentifier" yaml:"PrepIdentifier"`
+	Query          string   `json:"Query" yaml:"Query"`
+	ParameterOIDs  []uint32 `json:"ParameterOIDs" yaml:"ParameterOIDs"`
 }
 
 type PrepMap map[string][]QueryData
@@ -131,7 +232,8 @@ func getRecordPrepStatement(allMocks []*models.Mock) PrepMap {
 						if strings.Contains(req.Parses[p].Name, "S_") || strings.Contains(req.Parses[p].Name, "s") {
 							psMap[req.Parses[p].Query] = req.Parses[p].Name
 							querydata = append(querydata, QueryData{PrepIdentifier: req.Parses[p].Name,
-								Query: req.Parses[p].Query,
+								Query:         req.Parses[p].Query,
+								ParameterOIDs: req.Parses[p].ParameterOIDs,
 							})
 
 						}
@@ -3,11 +3,12 @@
 package v1
 
 import (
+	"bytes"
 	"context"
 	"encoding/binary"
-	"fmt"
 	"io"
 	"net"
+	"slices"
 	"strconv"
 	"time"
 
@@ -105,6 +106,17 @@ func encodePostgres(ctx context.Context, logger *zap.Logger, reqBuf []byte, clie
 	}()
 
 	prevChunkWasReq := false
+	// authType is the last authentication message of the server, the SASL messages of the client follow it
+	var authType int32
+	// reqPartial and respPartial are set when the last buffer of the client or of the server ended in the middle
+	// of a message
+	reqPartial, respPartial := false, false
+	// described are the columns of the statements described, the rows of the statements bound later are typed by them
+	described := newStatementDescriptions()
+	// replicating is set while the server streams the changes of a START_REPLICATION, stream holds the rest of the
+	// message split across the buffers streamed
+	replicating := false
+	var stream []byte
 	logger.Debug("the iteration for the pg request starts", zap.Any("pgReqs", len(pgRequests)), zap.Any("pgResps", len(pgResponses)))
 
 	reqTimestampMock := time.Now()
@@ -141,6 +153,14 @@ func encodePostgres(ctx context.Context, logger *zap.Logger, reqBuf []byte, clie
 				return err
 			}
 
+			if replicating {
+				// the standby status updates aren't answered, the client ends the stream with a CopyDone
+				if isStandbyFeedback(buffer) {
+					continue
+				}
+				replicating, stream = false, nil
+			}
+
 			logger.Debug("the iteration for the pg request ends with no of pgReqs:" + strconv.Itoa(len(pgRequests)) + " and pgResps: " + strconv.Itoa(len(pgResponses)))
 			if !prevChunkWasReq && len(pgRequests) > 0 && len(pgResponses) > 0 {
 				metadata := make(map[string]string)
@@ -164,26 +184,48 @@ func encodePostgres(ctx context.Context, logger *zap.Logger, reqBuf []byte, clie
 			}
 
 			bufStr := util.EncodeBase64(buffer)
+			// the buffers following a message split across them, the rows of a copy, are kept as they are sent
+			continued := reqPartial
 			if bufStr != "" {
 				pg := NewBackend()
 				var msg pgproto3.FrontendMessage
 
-				if !isStartupPacket(buffer) && len(buffer) > 5 {
+				if continued {
+					pgRequests = append(pgRequests, models.Backend{
+						Identfier: "ClientRequest",
+						Length:    uint32(len(buffer)),
+						Payload:   bufStr,
+					})
+				}
+
+				if !isStartupPacket(buffer) && len(buffer) > 5 && !continued {
 					bufferCopy := buffer
-					for i := 0; i < len(bufferCopy)-5; {
+					partial := false
+					i := 0
+					for i < len(bufferCopy)-5 {
 						pg.BackendWrapper.BodyLen = int(binary.BigEndian.Uint32(buffer[i+1:])) - 4
-						if len(buffer) < (i + pg.BackendWrapper.BodyLen + 5) {
-							utils.LogError(logger, nil, "failed to translate the postgres request message due to shorter network packet buffer. Length of buffer is "+fmt.Sprint(len(buffer))+" buffer value :"+string(buffer)+" and pg.BackendWrapper.BodyLen is "+fmt.Sprint(pg.BackendWrapper.BodyLen))
+						if pg.BackendWrapper.BodyLen < 0 || len(buffer) < (i+pg.BackendWrapper.BodyLen+5) {
+							logger.Debug("the request message continues in the next network packet buffer", zap.Any("buffer", len(buffer)), zap.Any("body_len", pg.BackendWrapper.BodyLen))
+							partial = true
 							break
 						}
 						pg.BackendWrapper.MsgType = buffer[i]
+						// the password messages are decoded by the authentication the server asked for
+						pg.BackendWrapper.AuthType = authType
 
 						msg, err = pg.translateToReadableBackend(buffer[i:(i + pg.BackendWrapper.BodyLen + 5)])
 						if err != nil && buffer[i] != 112 {
 							utils.LogError(logger, err, "failed to translate the request message to readable")
 						}
 						if pg.BackendWrapper.MsgType == 'p' {
-							pg.BackendWrapper.PasswordMessage = *msg.(*pgproto3.PasswordMessage)
+							switch m := msg.(type) {
+							case *pgproto3.SASLInitialResponse:
+								pg.BackendWrapper.SASLInitialResponse = *m
+							case *pgproto3.SASLResponse:
+								pg.BackendWrapper.SASLResponse = *m
+							case *pgproto3.PasswordMessage:
+								pg.BackendWrapper.PasswordMessage = *m
+							}
 						}
 
 						if pg.BackendWrapper.MsgType == 'P' {
@@ -196,6 +238,10 @@ func encodePostgres(ctx context.Context, logger *zap.Logger, reqBuf []byte, clie
 							pg.BackendWrapper.Binds = append(pg.BackendWrapper.Binds, pg.BackendWrapper.Bind)
 						}
 
+						if pg.BackendWrapper.MsgType == 'D' {
+							pg.BackendWrapper.Describes = append(pg.BackendWrapper.Describes, pg.BackendWrapper.Describe)
+						}
+
 						if pg.BackendWrapper.MsgType == 'E' {
 							pg.BackendWrapper.Execute = *msg.(*pgproto3.Execute)
 							pg.BackendWrapper.Executes = append(pg.BackendWrapper.Executes, pg.BackendWrapper.Execute)
@@ -205,6 +251,9 @@ func encodePostgres(ctx context.Context, logger *zap.Logger, reqBuf []byte, clie
 
 						i += 5 + pg.BackendWrapper.BodyLen
 					}
+					// the messages without a body are left out of the loop, the rest shorter than a header is split
+					partial = partial || (len(buffer)-i < 5 && len(buffer) != i)
+					reqPartial = partial
 
 					pgMock := &models.Backend{
 						PacketTypes: pg.BackendWrapper.PacketTypes,
@@ -220,6 +269,7 @@ func encodePostgres(ctx context.Context, logger *zap.Logger, reqBuf []byte, clie
 						CopyDone:            pg.BackendWrapper.CopyDone,
 						CopyFail:            pg.BackendWrapper.CopyFail,
 						Describe:            pg.BackendWrapper.Describe,
+						Describes:           pg.BackendWrapper.Describes,
 						Execute:             pg.BackendWrapper.Execute,
 						Executes:            pg.BackendWrapper.Executes,
 						Flush:               pg.BackendWrapper.Flush,
@@ -242,15 +292,17 @@ func encodePostgres(ctx context.Context, logger *zap.Logger, reqBuf []byte, clie
 						logger.Debug("failed to decode the response message in proxy for postgres dependency", zap.Error(err))
 					}
 
-					if len(afterEncoded) != len(buffer) && len(pgMock.PacketTypes) > 0 && pgMock.PacketTypes[0] != "p" {
+					// the copy data is kept as it's sent, the mock holds a single CopyData
+					if partial || ((len(afterEncoded) != len(buffer) || slices.Contains(pgMock.PacketTypes, "d")) && len(pgMock.PacketTypes) > 0 && pgMock.PacketTypes[0] != "p") {
 						logger.Debug("the length of the encoded buffer is not equal to the length of the original buffer", zap.Any("after_encoded", len(afterEncoded)), zap.Any("buffer", len(buffer)))
 						pgMock.Payload = bufStr
 					}
+					described.forget(pgMock)
 					pgRequests = append(pgRequests, *pgMock)
 
 				}
 
-				if isStartupPacket(buffer) {
+				if !continued && isStartupPacket(buffer) {
 					pgMock := &models.Backend{
 						Identfier: "StartupRequest",
 						Payload:   bufStr,
@@ -259,12 +311,77 @@ func encodePostgres(ctx context.Context, logger *zap.Logger, reqBuf []byte, clie
 				}
 			}
 			prevChunkWasReq = true
+			respPartial = false
 		case buffer := <-destBuffChan:
+			// the notifications of the channels listened on are sent by the server on its own, they're recorded
+			// apart from the responses and replayed after the statement raising them
+			if !respPartial && isNotification(buffer) {
+				_, err := clientConn.Write(buffer)
+				if err != nil {
+					utils.LogError(logger, err, "failed to write response message to the client")
+					return err
+				}
+				mocks <- notificationMock(ctx, buffer)
+				continue
+			}
+
+			if replicating {
+				_, err := clientConn.Write(buffer)
+				if err != nil {
+					utils.LogError(logger, err, "failed to write response message to the client")
+					return err
+				}
+				stream = append(stream, buffer...)
+				if _, length := messageTypes(stream); length > 0 {
+					mocks <- replicationMock(ctx, stream[:length])
+					stream = append([]byte(nil), stream[length:]...)
+				}
+				continue
+			}
+
 			if prevChunkWasReq {
 				// store the request timestamp
 				reqTimestampMock = time.Now()
 			}
 
+			// the server streams the changes of a START_REPLICATION after its CopyBothResponse, the messages
+			// streamed are recorded apart from the response
+			if !respPartial && len(pgRequests) > 0 && len(pgResponses) == 0 && startsReplication(buffer) {
+				_, err := clientConn.Write(buffer)
+				if err != nil {
+					utils.LogError(logger, err, "failed to write response message to the client")
+					return err
+				}
+				length := messageLength(buffer)
+				mocks <- &models.Mock{
+					Version: models.GetVersion(),
+					Name:    "mocks",
+					Kind:    models.Postgres,
+					Spec: models.MockSpec{
+						PostgresRequests: pgRequests,
+						PostgresResponses: []models.Frontend{{
+							PacketTypes: []string{"W"},
+							Identfier:   "ServerResponse",
+							Length:      uint32(length),
+							Payload:     util.EncodeBase64(buffer[:length]),
+						}},
+						ReqTimestampMock: reqTimestampMock,
+						ResTimestampMock: time.Now(),
+						Metadata:         map[string]string{"type": "config"},
+					},
+					ConnectionID: ctx.Value(models.ClientConnectionIDKey).(string),
+				}
+				pgRequests = []models.Backend{}
+				replicating, prevChunkWasReq = true, false
+
+				stream = append([]byte(nil), buffer[length:]...)
+				if _, length := messageTypes(stream); length > 0 {
+					mocks <- replicationMock(ctx, stream[:length])
+					stream = append([]byte(nil), stream[length:]...)
+				}
+				continue
+			}
+
 			// Write the response message to the client
 			_, err := clientConn.Write(buffer)
 			if err != nil {
@@ -274,27 +391,46 @@ func encodePostgres(ctx context.Context, logger *zap.Logger, reqBuf []byte, clie
 
 			bufStr := util.EncodeBase64(buffer)
 
+			// the buffers following a message split across them are kept as they are sent
+			continued := respPartial
+			reqPartial = false
 			if bufStr != "" {
 				pg := NewFrontend()
-				if !isStartupPacket(buffer) && len(buffer) > 5 && bufStr != "Tg==" {
+				if !isStartupPacket(buffer) && len(buffer) > 5 && bufStr != "Tg==" && !continued {
 					bufferCopy := buffer
 
 					//Saving list of packets in case of multiple packets in a single buffer steam
 					ps := make([]pgproto3.ParameterStatus, 0)
 					var dataRows []pgproto3.DataRow
-
-					for i := 0; i < len(bufferCopy)-5; {
+					var errorResponses []pgproto3.ErrorResponse
+					var noticeResponses []pgproto3.NoticeResponse
+
+					// partial is set when the buffer doesn't end on a message, the large copy data and resultsets
+					// are split across the buffers
+					partial := false
+					i := 0
+					for i+5 <= len(bufferCopy) {
 						pg.FrontendWrapper.MsgType = buffer[i]
 						pg.FrontendWrapper.BodyLen = int(binary.BigEndian.Uint32(buffer[i+1:])) - 4
+						if pg.FrontendWrapper.BodyLen < 0 || len(buffer) < (i+pg.FrontendWrapper.BodyLen+5) {
+							logger.Debug("the response message continues in the next network packet buffer", zap.Any("buffer", len(buffer)), zap.Any("body_len", pg.FrontendWrapper.BodyLen))
+							partial = true
+							break
+						}
 						msg, err := pg.translateToReadableResponse(logger, buffer[i:(i+pg.FrontendWrapper.BodyLen+5)])
 						if err != nil {
 							utils.LogError(logger, err, "failed to translate the response message to readable")
+							partial = true
 							break
 						}
 
 						pg.FrontendWrapper.PacketTypes = append(pg.FrontendWrapper.PacketTypes, string(pg.FrontendWrapper.MsgType))
 						i += 5 + pg.FrontendWrapper.BodyLen
 
+						if pg.FrontendWrapper.MsgType == 'R' {
+							authType = pg.FrontendWrapper.AuthType
+						}
+
 						if pg.FrontendWrapper.ParameterStatus.Name != "" {
 							ps = append(ps, pg.FrontendWrapper.ParameterStatus)
 						}
@@ -304,25 +440,41 @@ func encodePostgres(ctx context.Context, logger *zap.Logger, reqBuf []byte, clie
 							pg.FrontendWrapper.CommandComplete.CommandTag = []byte{}
 							pg.FrontendWrapper.CommandCompletes = append(pg.FrontendWrapper.CommandCompletes, pg.FrontendWrapper.CommandComplete)
 						}
+						// a response may hold several notices, and an error for each of the pipelined syncs
+						switch pg.FrontendWrapper.MsgType {
+						case 'E':
+							errorResponses = append(errorResponses, *msg.(*pgproto3.ErrorResponse))
+						case 'N':
+							noticeResponses = append(noticeResponses, *msg.(*pgproto3.NoticeResponse))
+						}
 						if pg.FrontendWrapper.MsgType == 'D' && pg.FrontendWrapper.DataRow.RowValues != nil {
 							// Create a new slice for each DataRow
 							valuesCopy := make([]string, len(pg.FrontendWrapper.DataRow.RowValues))
 							copy(valuesCopy, pg.FrontendWrapper.DataRow.RowValues)
 
+							// the values are decoded into the same slice for each DataRow
+							values := make([][]byte, len(pg.FrontendWrapper.DataRow.Values))
+							copy(values, pg.FrontendWrapper.DataRow.Values)
+
 							row := pgproto3.DataRow{
 								RowValues: valuesCopy, // Use the copy of the values
-								Values:    pg.FrontendWrapper.DataRow.Values,
+								Values:    values,
 							}
 							dataRows = append(dataRows, row)
 						}
 					}
 
+					partial = partial || i != len(buffer)
+					respPartial = partial
+
 					if len(ps) > 0 {
 						pg.FrontendWrapper.ParameterStatusCombined = ps
 					}
 					if len(dataRows) > 0 {
 						pg.FrontendWrapper.DataRows = dataRows
 					}
+					pg.FrontendWrapper.ErrorResponses = errorResponses
+					pg.FrontendWrapper.NoticeResponses = noticeResponses
 
 					// from here take the msg and append its readable form to the pgResponses
 					pgMock := &models.Frontend{
@@ -350,10 +502,10 @@ func encodePostgres(ctx context.Context, logger *zap.Logger, reqBuf []byte, clie
 						DataRow:                         pg.FrontendWrapper.DataRow,
 						DataRows:                        pg.FrontendWrapper.DataRows,
 						EmptyQueryResponse:              pg.FrontendWrapper.EmptyQueryResponse,
-						ErrorResponse:                   pg.FrontendWrapper.ErrorResponse,
+						ErrorResponses:                  pg.FrontendWrapper.ErrorResponses,
 						FunctionCallResponse:            pg.FrontendWrapper.FunctionCallResponse,
 						NoData:                          pg.FrontendWrapper.NoData,
-						NoticeResponse:                  pg.FrontendWrapper.NoticeResponse,
+						NoticeResponses:                 pg.FrontendWrapper.NoticeResponses,
 						NotificationResponse:            pg.FrontendWrapper.NotificationResponse,
 						ParameterDescription:            pg.FrontendWrapper.ParameterDescription,
 						ParameterStatusCombined:         pg.FrontendWrapper.ParameterStatusCombined,
@@ -365,18 +517,29 @@ func encodePostgres(ctx context.Context, logger *zap.Logger, reqBuf []byte, clie
 						AuthType:                        pg.FrontendWrapper.AuthType,
 					}
 
+					// the binary values of the rows are kept in the text format of their types
+					req := lastRequest(pgRequests)
+					described.describe(req, pgMock)
+					if columns := described.resultColumns(req, pgMock); columns != nil {
+						pgMock.ResultColumns = columns
+						readableRows(pgMock)
+					}
+
 					afterEncoded, err := postgresDecoderFrontend(*pgMock)
 					if err != nil {
 						logger.Debug("failed to decode the response message in proxy for postgres dependency", zap.Error(err))
 					}
-					if len(afterEncoded) != len(buffer) && len(pgMock.PacketTypes) > 0 && pgMock.PacketTypes[0] != "R" {
+					// the errors and the notices which don't encode back to the bytes sent by the server are replayed
+					// from the payload, so that their unknown fields are kept as they were
+					reordered := (len(errorResponses) > 0 || len(noticeResponses) > 0) && !bytes.Equal(afterEncoded, buffer)
+					if partial || reordered || ((len(afterEncoded) != len(buffer) || slices.Contains(pgMock.PacketTypes, "d")) && len(pgMock.PacketTypes) > 0 && pgMock.PacketTypes[0] != "R") {
 						logger.Debug("the length of the encoded buffer is not equal to the length of the original buffer", zap.Any("after_encoded", len(afterEncoded)), zap.Any("buffer", len(buffer)))
 						pgMock.Payload = bufStr
 					}
 					pgResponses = append(pgResponses, *pgMock)
 				}
 
-				if bufStr == "Tg==" || len(buffer) <= 5 {
+				if bufStr == "Tg==" || len(buffer) <= 5 || continued {
 
 					pgMock := &models.Frontend{
 						Payload: bufStr,
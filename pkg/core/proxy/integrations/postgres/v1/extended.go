@@ -0,0 +1,160 @@
+//go:build linux
+
+package v1
+
+import (
+	"reflect"
+
+	"go.keploy.io/server/v2/pkg/models"
+	"go.uber.org/zap"
+)
+
+// boundStatement is an execution of the extended query protocol, the SQL of the statement bound and the values of
+// its parameters decoded by their types. The names of the statements are left out, the drivers name them by the
+// order they're prepared in.
+type boundStatement struct {
+	query string
+	oids  []uint32
+	// params are the values of the parameters, the timestamps are left empty
+	params []string
+}
+
+// boundStatements returns the statements bound by a request, with their SQL taken from the Parse of the request or
+// from the statements prepared on the connection before. It reports false when the request binds no statement or
+// a statement whose SQL isn't known.
+func boundStatements(req *models.Backend, prepared []QueryData) ([]boundStatement, bool) {
+	parsed := map[string]QueryData{}
+	var stmts []boundStatement
+	var b, p int
+	for _, packet := range req.PacketTypes {
+		switch packet {
+		case "P":
+			if p >= len(req.Parses) {
+				return nil, false
+			}
+			parse := req.Parses[p]
+			parsed[parse.Name] = QueryData{PrepIdentifier: parse.Name, Query: parse.Query, ParameterOIDs: parse.ParameterOIDs}
+			p++
+		case "B":
+			if b >= len(req.Binds) {
+				return nil, false
+			}
+			bind := req.Binds[b]
+			b++
+			stmt, ok := parsed[bind.PreparedStatement]
+			if !ok {
+				stmt, ok = preparedStatement(prepared, bind.PreparedStatement)
+			}
+			if !ok {
+				return nil, false
+			}
+
+			bound := boundStatement{query: stmt.Query, oids: stmt.ParameterOIDs}
+			for i, value := range bind.Parameters {
+				var oid uint32
+				if i < len(stmt.ParameterOIDs) {
+					oid = stmt.ParameterOIDs[i]
+				}
+				param := decodeParameter(value, oid, parameterFormat(bind, i))
+				if isTimestampType(oid) || isTimestamp([]byte(param)) {
+					param = ""
+				}
+				bound.params = append(bound.params, param)
+			}
+			stmts = append(stmts, bound)
+		}
+	}
+	return stmts, len(stmts) > 0
+}
+
+// preparedStatement returns the statement last prepared with the name.
+func preparedStatement(prepared []QueryData, name string) (QueryData, bool) {
+	for i := len(prepared) - 1; i >= 0; i-- {
+		if prepared[i].PrepIdentifier == name {
+			return prepared[i], true
+		}
+	}
+	return QueryData{}, false
+}
+
+// findExtendedQueryMatch returns the mock whose request sends the same messages and binds the same statements as the
+// request, matched on their SQL and on the decoded values of their parameters. It returns the first mock with all
+// the values equal, or else the one with the most values equal when partial matches are allowed.
+func findExtendedQueryMatch(tcsMocks []*models.Mock, requestBuffers [][]byte, logger *zap.Logger, connectionID string, recordedPrep PrepMap, partial bool) int {
+	actualPgReq := decodePgRequest(requestBuffers[0], logger)
+	if actualPgReq == nil {
+		return -1
+	}
+	actual, ok := boundStatements(actualPgReq, testmap[connectionID])
+	if !ok {
+		return -1
+	}
+
+	mxIdx, mxEqual := -1, -1
+	templatedIdx := -1
+	var templated []boundStatement
+	for idx, mock := range tcsMocks {
+		// merging the mocks as well before comparing
+		mock.Spec.PostgresRequests = mergeMocks(mock.Spec.PostgresRequests, logger)
+		if len(mock.Spec.PostgresRequests) != 1 || !reflect.DeepEqual(mock.Spec.PostgresRequests[0].PacketTypes, actualPgReq.PacketTypes) {
+			continue
+		}
+		expected, ok := boundStatements(&mock.Spec.PostgresRequests[0], recordedPrep[mock.ConnectionID])
+		if !ok {
+			continue
+		}
+		equal, total, ok := compareBoundStatements(expected, actual)
+		if !ok {
+			continue
+		}
+		if equal == total {
+			logger.Debug("Matched the bound statements of the extended query", zap.String("mock", mock.Name))
+			return idx
+		}
+		if templatedIdx == -1 && volatiles.equivalent(expected, actual) {
+			templatedIdx, templated = idx, expected
+		}
+		if equal > mxEqual {
+			mxIdx, mxEqual = idx, equal
+		}
+	}
+	// the statements sending the values generated in the replay are matched for the values recorded
+	if templatedIdx != -1 {
+		volatiles.bind(templated, actual)
+		logger.Debug("Matched the bound statements of the extended query with the generated values rewritten", zap.String("mock", tcsMocks[templatedIdx].Name))
+		return templatedIdx
+	}
+	if !partial {
+		return -1
+	}
+	if mxIdx != -1 {
+		logger.Debug("Matched the statements of the extended query with the closest parameters", zap.String("mock", tcsMocks[mxIdx].Name), zap.Int("equal parameters", mxEqual))
+	}
+	return mxIdx
+}
+
+// compareBoundStatements returns how many of the parameters of the statements are equal, and the number of
+// parameters. It reports false when the statements differ in their SQL or in the types of their parameters.
+func compareBoundStatements(expected, actual []boundStatement) (int, int, bool) {
+	if len(expected) != len(actual) {
+		return 0, 0, false
+	}
+	var equal, total int
+	for i := range expected {
+		if expected[i].query != actual[i].query || len(expected[i].params) != len(actual[i].params) {
+			return 0, 0, false
+		}
+		for j := range expected[i].params {
+			// the parameters without a type are typed by the server
+			if j < len(expected[i].oids) && j < len(actual[i].oids) && expected[i].oids[j] != 0 && actual[i].oids[j] != 0 &&
+				expected[i].oids[j] != actual[i].oids[j] {
+				return 0, 0, false
+			}
+			total++
+			if expected[i].params[j] == actual[i].params[j] {
+				equal++
+			}
+		}
+	}
+	return equal, total, true
+}
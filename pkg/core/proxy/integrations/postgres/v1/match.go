@@ -40,7 +40,7 @@ func getTestPS(reqBuff [][]byte, logger *zap.Logger, ConnectionID string) {
 		for _, header := range actualPgReq.PacketTypes {
 			if header == "P" {
 				if (strings.Contains(actualPgReq.Parses[p].Name, "S_") || strings.Contains(actualPgReq.Parses[p].Name, "s")) && !IsValuePresent(ConnectionID, actualPgReq.Parses[p].Name) {
-					querydata = append(querydata, QueryData{PrepIdentifier: actualPgReq.Parses[p].Name, Query: actualPgReq.Parses[p].Query})
+					querydata = append(querydata, QueryData{PrepIdentifier: actualPgReq.Parses[p].Name, Query: actualPgReq.Parses[p].Query, ParameterOIDs: actualPgReq.Parses[p].ParameterOIDs})
 				}
 				p++
 			}
@@ -67,24 +67,24 @@ func IsValuePresent(connectionid string, value string) bool {
 	return false
 }
 
-func matchingReadablePG(ctx context.Context, logger *zap.Logger, mutex *sync.Mutex, requestBuffers [][]byte, mockDb integrations.MockMemDb) (bool, []models.Frontend, error) {
+func matchingReadablePG(ctx context.Context, logger *zap.Logger, mutex *sync.Mutex, requestBuffers [][]byte, mockDb integrations.MockMemDb) (bool, []models.Frontend, *models.Mock, error) {
 	for {
 		select {
 		case <-ctx.Done():
-			return false, nil, ctx.Err()
+			return false, nil, nil, ctx.Err()
 		default:
 
-			mocks, err := mockDb.GetUnFilteredMocks()
+			mocks, err := mockDb.GetUnFilteredMocksByKey(models.Postgres, "")
 			var tcsMocks []*models.Mock
 
 			for _, mock := range mocks {
-				if mock.Kind != "Postgres" {
+				if mock.Kind != "Postgres" || isNotificationMock(mock) || isReplicationMock(mock) {
 					continue
 				}
 				tcsMocks = append(tcsMocks, mock)
 			}
 			if err != nil {
-				return false, nil, fmt.Errorf("error while getting tcs mocks %v", err)
+				return false, nil, nil, fmt.Errorf("error while getting tcs mocks %v", err)
 			}
 
 			ConnectionID := ctx.Value(models.ClientConnectionIDKey).(string)
@@ -110,7 +110,7 @@ func matchingReadablePG(ctx context.Context, logger *zap.Logger, mutex *sync.Mut
 
 			for _, mock := range tcsMocks {
 				if ctx.Err() != nil {
-					return false, nil, ctx.Err()
+					return false, nil, nil, ctx.Err()
 				}
 				if mock == nil {
 					continue
@@ -140,7 +140,7 @@ func matchingReadablePG(ctx context.Context, logger *zap.Logger, mutex *sync.Mut
 							ssl := models.Frontend{
 								Payload: "Tg==",
 							}
-							return true, []models.Frontend{ssl}, nil
+							return true, []models.Frontend{ssl}, nil, nil
 						case initMock.Spec.PostgresRequests[requestIndex].Identfier == "StartupRequest" && isStartupPacket(reqBuff) && initMock.Spec.PostgresRequests[requestIndex].Payload != "AAAACATSFi8=" && initMock.Spec.PostgresResponses[requestIndex].AuthType == 10:
 							logger.Debug("CHANGING TO MD5 for Response", zap.String("mock", initMock.Name), zap.String("Req", bufStr))
 							res := make([]models.Frontend, len(initMock.Spec.PostgresResponses))
@@ -150,7 +150,7 @@ func matchingReadablePG(ctx context.Context, logger *zap.Logger, mutex *sync.Mut
 							if err != nil {
 								logger.Error("failed to flag mock as used", zap.Error(err))
 							}
-							return true, res, nil
+							return true, res, &initMock, nil
 						case len(encodedMock) > 0 && encodedMock[0] == 'p' && initMock.Spec.PostgresRequests[requestIndex].PacketTypes[0] == "p" && reqBuff[0] == 'p':
 							logger.Debug("CHANGING TO MD5 for Request and Response", zap.String("mock", initMock.Name), zap.String("Req", bufStr))
 
@@ -213,7 +213,7 @@ func matchingReadablePG(ctx context.Context, logger *zap.Logger, mutex *sync.Mut
 							if err != nil {
 								logger.Error("failed to flag mock as used", zap.Error(err))
 							}
-							return true, res, nil
+							return true, res, &initMock, nil
 						}
 
 					}
@@ -231,14 +231,22 @@ func matchingReadablePG(ctx context.Context, logger *zap.Logger, mutex *sync.Mut
 			// give more priority to sorted like if you find more than 0.5 in sorted then return that
 			if len(sortedTcsMocks) > 0 {
 				sorted = true
-				idx1, newMock := findPGStreamMatch(sortedTcsMocks, requestBuffers, logger, sorted, ConnectionID, recordedPrep)
-				if idx1 != -1 {
+				// the statements of the extended queries are matched on their SQL and their parameters first
+				if idx1 := findExtendedQueryMatch(sortedTcsMocks, requestBuffers, logger, ConnectionID, recordedPrep, false); idx1 != -1 {
 					matched = true
 					matchedMock = tcsMocks[idx1]
-					if newMock != nil {
-						matchedMock = newMock
+				}
+
+				if !matched {
+					idx1, newMock := findPGStreamMatch(sortedTcsMocks, requestBuffers, logger, sorted, ConnectionID, recordedPrep)
+					if idx1 != -1 {
+						matched = true
+						matchedMock = tcsMocks[idx1]
+						if newMock != nil {
+							matchedMock = newMock
+						}
+						logger.Debug("Matched In Sorted PG Matching Stream", zap.String("mock", matchedMock.Name))
 					}
-					logger.Debug("Matched In Sorted PG Matching Stream", zap.String("mock", matchedMock.Name))
 				}
 
 				idx = findBinaryStreamMatch(logger, sortedTcsMocks, requestBuffers, sorted)
@@ -250,14 +258,31 @@ func matchingReadablePG(ctx context.Context, logger *zap.Logger, mutex *sync.Mut
 
 			if !matched {
 				sorted = false
-				idx1, newMock := findPGStreamMatch(tcsMocks, requestBuffers, logger, sorted, ConnectionID, recordedPrep)
-				if idx1 != -1 {
+				if idx1 := findExtendedQueryMatch(tcsMocks, requestBuffers, logger, ConnectionID, recordedPrep, false); idx1 != -1 {
 					matched = true
 					matchedMock = tcsMocks[idx1]
-					if newMock != nil {
-						matchedMock = newMock
+				}
+
+				var newMock *models.Mock
+				if !matched {
+					var idx1 int
+					idx1, newMock = findPGStreamMatch(tcsMocks, requestBuffers, logger, sorted, ConnectionID, recordedPrep)
+					if idx1 != -1 {
+						matched = true
+						matchedMock = tcsMocks[idx1]
+						if newMock != nil {
+							matchedMock = newMock
+						}
+						logger.Debug("Matched In Unsorted PG Matching Stream", zap.String("mock", matchedMock.Name))
+					}
+				}
+
+				// the statements with other parameters are closer than the similar bytes
+				if !matched {
+					if idx1 := findExtendedQueryMatch(tcsMocks, requestBuffers, logger, ConnectionID, recordedPrep, true); idx1 != -1 {
+						matched = true
+						matchedMock = tcsMocks[idx1]
 					}
-					logger.Debug("Matched In Unsorted PG Matching Stream", zap.String("mock", matchedMock.Name))
 				}
 				idx = findBinaryStreamMatch(logger, tcsMocks, requestBuffers, sorted)
 				// check if the validate the query with the matched mock
@@ -297,9 +322,10 @@ func matchingReadablePG(ctx context.Context, logger *zap.Logger, mutex *sync.Mut
 				if err != nil {
 					logger.Error("failed to flag mock as used", zap.Error(err))
 				}
-				return true, matchedMock.Spec.PostgresResponses, nil
+				notifications.trigger(logger, mockDb, mocks, matchedMock)
+				return true, matchedMock.Spec.PostgresResponses, matchedMock, nil
 			}
-			return false, nil, nil
+			return false, nil, nil, nil
 		}
 	}
 }
@@ -0,0 +1,323 @@
+//go:build linux
+
+package v1
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgproto3/v2"
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations/util"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.uber.org/zap"
+)
+
+// notifications replays the notifications to the connections listening on their channels, they're shared by all the
+// connections since the notifications are raised by a connection and received by the others.
+var notifications = newNotifier()
+
+// listenStatement matches the LISTEN and the UNLISTEN statements of a query and the channel they name.
+var listenStatement = regexp.MustCompile(`(?i)(?:^|;)\s*(un)?listen\s+(\*|"(?:[^"]|"")*"|[^\s;]+)`)
+
+// notifyConn is a client connection the notifications are written to. The writes are serialized, the notifications
+// are written between the messages of the responses.
+type notifyConn struct {
+	net.Conn
+	mu sync.Mutex
+}
+
+func (c *notifyConn) Write(b []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.Conn.Write(b)
+}
+
+// notifier keeps the connections listening on each channel, and schedules the notifications recorded once the
+// statement which raised them is replayed.
+type notifier struct {
+	mu        sync.Mutex
+	listeners map[string]map[*notifyConn]struct{}
+	// scheduled are the notification mocks already scheduled, a notification is delivered once
+	scheduled map[string]bool
+}
+
+func newNotifier() *notifier {
+	return &notifier{
+		listeners: make(map[string]map[*notifyConn]struct{}),
+		scheduled: make(map[string]bool),
+	}
+}
+
+// track registers the connection for the channels listened on by the requests, and unregisters it for the channels
+// unlistened.
+func (n *notifier) track(conn *notifyConn, requests [][]byte) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for _, query := range queries(requests) {
+		for _, stmt := range listenStatement.FindAllStringSubmatch(query, -1) {
+			channel := channelName(stmt[2])
+			if stmt[1] != "" {
+				n.unlisten(conn, channel)
+				continue
+			}
+			if n.listeners[channel] == nil {
+				n.listeners[channel] = make(map[*notifyConn]struct{})
+			}
+			n.listeners[channel][conn] = struct{}{}
+		}
+	}
+}
+
+// remove unregisters the connection for all the channels, once it's closed.
+func (n *notifier) remove(conn *notifyConn) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.unlisten(conn, "*")
+}
+
+func (n *notifier) unlisten(conn *notifyConn, channel string) {
+	for name, conns := range n.listeners {
+		if channel == "*" || channel == name {
+			delete(conns, conn)
+		}
+	}
+}
+
+// trigger schedules the notifications raised by the statement of the mock matched. They're delivered after the
+// time they were received in after the response of the statement while recording.
+func (n *notifier) trigger(logger *zap.Logger, mockDb integrations.MockMemDb, mocks []*models.Mock, matched *models.Mock) {
+	var pids map[uint32]string
+	for _, mock := range mocks {
+		if !isNotificationMock(mock) {
+			continue
+		}
+		if pids == nil {
+			pids = backendPIDs(mocks)
+		}
+		stmt := triggeringStatement(mock, mocks, pids)
+		if stmt == nil || stmt.Name != matched.Name || stmt.ConnectionID != matched.ConnectionID {
+			continue
+		}
+
+		key := mock.Name + mock.Spec.ResTimestampMock.String()
+		n.mu.Lock()
+		if n.scheduled[key] {
+			n.mu.Unlock()
+			continue
+		}
+		n.scheduled[key] = true
+		n.mu.Unlock()
+
+		delay := mock.Spec.ResTimestampMock.Sub(stmt.Spec.ResTimestampMock)
+		if delay < 0 {
+			delay = 0
+		}
+		logger.Debug("scheduled the postgres notification", zap.String("mock", mock.Name), zap.String("statement", stmt.Name), zap.Duration("delay", delay))
+		notification := mock
+		time.AfterFunc(delay, func() {
+			n.deliver(logger, mockDb, notification)
+		})
+	}
+}
+
+// deliver writes the notifications of the mock to the connections listening on their channels.
+func (n *notifier) deliver(logger *zap.Logger, mockDb integrations.MockMemDb, mock *models.Mock) {
+	buf, err := util.DecodeBase64(mock.Spec.PostgresResponses[0].Payload)
+	if err != nil {
+		logger.Error("failed to decode the postgres notification", zap.String("mock", mock.Name), zap.Error(err))
+		return
+	}
+
+	delivered := false
+	// each of the notifications goes to the listeners of its channel
+	for len(buf) > 0 {
+		msgLen := messageLength(buf)
+		if msgLen == 0 {
+			break
+		}
+		msg := buf[:msgLen]
+		buf = buf[msgLen:]
+
+		var notification pgproto3.NotificationResponse
+		if err := notification.Decode(msg[5:]); err != nil {
+			logger.Error("failed to decode the postgres notification", zap.String("mock", mock.Name), zap.Error(err))
+			return
+		}
+		n.mu.Lock()
+		var conns []*notifyConn
+		for conn := range n.listeners[notification.Channel] {
+			conns = append(conns, conn)
+		}
+		n.mu.Unlock()
+
+		for _, conn := range conns {
+			if _, err := conn.Write(msg); err != nil {
+				logger.Debug("failed to write the postgres notification to the client", zap.String("channel", notification.Channel), zap.Error(err))
+				continue
+			}
+			delivered = true
+		}
+		if len(conns) == 0 {
+			logger.Debug("no connection is listening on the channel of the postgres notification", zap.String("channel", notification.Channel))
+		}
+	}
+
+	if delivered {
+		if err := mockDb.FlagMockAsUsed(*mock); err != nil {
+			logger.Error("failed to flag mock as used", zap.Error(err))
+		}
+	}
+}
+
+// isNotification reports whether the buffer holds only notifications, the server sends them on its own while the
+// connection is idle.
+func isNotification(buf []byte) bool {
+	types, length := messageTypes(buf)
+	if len(types) == 0 || length != len(buf) {
+		return false
+	}
+	for _, t := range types {
+		if t != 'A' {
+			return false
+		}
+	}
+	return true
+}
+
+// notificationMock returns the mock of the notifications received, without a request. The time they're received
+// at is recorded in the timestamps of the mock.
+func notificationMock(ctx context.Context, buf []byte) *models.Mock {
+	resp := models.Frontend{
+		Identfier: "ServerResponse",
+		Length:    uint32(len(buf)),
+		Payload:   util.EncodeBase64(buf),
+		MsgType:   'A',
+	}
+	var channels []string
+	for rest := buf; len(rest) > 0; {
+		msgLen := messageLength(rest)
+		if msgLen == 0 {
+			break
+		}
+		var notification pgproto3.NotificationResponse
+		if err := notification.Decode(rest[5:msgLen]); err == nil {
+			resp.NotificationResponse = notification
+			if !slices.Contains(channels, notification.Channel) {
+				channels = append(channels, notification.Channel)
+			}
+		}
+		resp.PacketTypes = append(resp.PacketTypes, "A")
+		rest = rest[msgLen:]
+	}
+
+	now := time.Now()
+	return &models.Mock{
+		Version: models.GetVersion(),
+		Name:    "mocks",
+		Kind:    models.Postgres,
+		Spec: models.MockSpec{
+			PostgresResponses: []models.Frontend{resp},
+			ReqTimestampMock:  now,
+			ResTimestampMock:  now,
+			Metadata: map[string]string{
+				"type":   "config",
+				"notify": strings.Join(channels, ","),
+			},
+		},
+		ConnectionID: ctx.Value(models.ClientConnectionIDKey).(string),
+	}
+}
+
+// isNotificationMock reports whether the mock holds the notifications received by a connection.
+func isNotificationMock(mock *models.Mock) bool {
+	return mock.Spec.Metadata["notify"] != "" && len(mock.Spec.PostgresRequests) == 0 && len(mock.Spec.PostgresResponses) == 1
+}
+
+// backendPIDs returns the connections of the mocks by the process id of their server, sent in the BackendKeyData
+// of the startup.
+func backendPIDs(mocks []*models.Mock) map[uint32]string {
+	pids := make(map[uint32]string)
+	for _, mock := range mocks {
+		for _, resp := range mock.Spec.PostgresResponses {
+			if resp.BackendKeyData.ProcessID != 0 {
+				pids[resp.BackendKeyData.ProcessID] = mock.ConnectionID
+			}
+		}
+	}
+	return pids
+}
+
+// triggeringStatement returns the mock of the statement which raised the notification, the last one sent before it by
+// the connection notifying. The notifications raised by the connections which aren't recorded are triggered by the
+// last statement of the connection listening.
+func triggeringStatement(notification *models.Mock, mocks []*models.Mock, pids map[uint32]string) *models.Mock {
+	conn := notification.ConnectionID
+	if c, ok := pids[notification.Spec.PostgresResponses[0].NotificationResponse.PID]; ok {
+		conn = c
+	}
+	var stmt *models.Mock
+	for _, mock := range mocks {
+		if mock.ConnectionID != conn || len(mock.Spec.PostgresRequests) == 0 ||
+			mock.Spec.ReqTimestampMock.After(notification.Spec.ResTimestampMock) {
+			continue
+		}
+		if stmt == nil || mock.Spec.ReqTimestampMock.After(stmt.Spec.ReqTimestampMock) {
+			stmt = mock
+		}
+	}
+	return stmt
+}
+
+// queries returns the SQL of the simple queries and of the statements parsed in the requests.
+func queries(requests [][]byte) []string {
+	var sqls []string
+	for _, req := range requests {
+		for len(req) > 0 {
+			msgLen := messageLength(req)
+			if msgLen == 0 {
+				break
+			}
+			switch req[0] {
+			case 'Q':
+				var query pgproto3.Query
+				if err := query.Decode(req[5:msgLen]); err == nil {
+					sqls = append(sqls, query.String)
+				}
+			case 'P':
+				var parse pgproto3.Parse
+				if err := parse.Decode(req[5:msgLen]); err == nil {
+					sqls = append(sqls, parse.Query)
+				}
+			}
+			req = req[msgLen:]
+		}
+	}
+	return sqls
+}
+
+// channelName returns the name of the channel of a LISTEN, the names which aren't quoted are folded to lower case.
+func channelName(ident string) string {
+	if strings.HasPrefix(ident, `"`) && strings.HasSuffix(ident, `"`) && len(ident) > 1 {
+		return strings.ReplaceAll(ident[1:len(ident)-1], `""`, `"`)
+	}
+	return strings.ToLower(ident)
+}
+
+// messageLength returns the length of the message at the start of the buffer, 0 when it isn't whole.
+func messageLength(buf []byte) int {
+	if len(buf) < 5 {
+		return 0
+	}
+	length := int(binary.BigEndian.Uint32(buf[1:])) + 1
+	if length < 5 || length > len(buf) {
+		return 0
+	}
+	return length
+}
@@ -0,0 +1,162 @@
+//go:build linux
+
+package v1
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgproto3/v2"
+)
+
+// The oids of the types of the parameters decoded, ref: https://github.com/postgres/postgres/blob/master/src/include/catalog/pg_type.dat
+const (
+	oidBool        = 16
+	oidBytea       = 17
+	oidName        = 19
+	oidInt8        = 20
+	oidInt2        = 21
+	oidInt4        = 23
+	oidText        = 25
+	oidOID         = 26
+	oidJSON        = 114
+	oidFloat4      = 700
+	oidFloat8      = 701
+	oidBpchar      = 1042
+	oidVarchar     = 1043
+	oidDate        = 1082
+	oidTimestamp   = 1114
+	oidTimestamptz = 1184
+	oidNumeric     = 1700
+	oidUUID        = 2950
+	oidJSONB       = 3802
+)
+
+// postgresEpoch is the origin of the dates and the timestamps in the binary format
+var postgresEpoch = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// parameterFormat returns the format code of the i-th parameter of a bind, no format codes means all of them are
+// text and a single one applies to all of them.
+func parameterFormat(bind pgproto3.Bind, i int) int16 {
+	return formatCode(bind.ParameterFormatCodes, i)
+}
+
+// formatCode returns the i-th of the format codes of a message, the parameters or the result columns.
+func formatCode(codes []int16, i int) int16 {
+	switch len(codes) {
+	case 0:
+		return 0
+	case 1:
+		return codes[0]
+	}
+	if i < len(codes) {
+		return codes[i]
+	}
+	return 0
+}
+
+// decodeParameter returns the value of a parameter in the text format of its type, so that the values sent in the
+// binary format and in the text format compare equal. The parameters of the types not decoded, or whose type is
+// inferred by the server, are returned as they are sent.
+func decodeParameter(value []byte, oid uint32, format int16) string {
+	if value == nil {
+		return "NULL"
+	}
+	if format == 0 {
+		return normalizeText(string(value), oid)
+	}
+
+	switch oid {
+	case oidBool:
+		if len(value) == 1 {
+			return strconv.FormatBool(value[0] != 0)
+		}
+	case oidInt2:
+		if len(value) == 2 {
+			return strconv.FormatInt(int64(int16(binary.BigEndian.Uint16(value))), 10)
+		}
+	case oidInt4:
+		if len(value) == 4 {
+			return strconv.FormatInt(int64(int32(binary.BigEndian.Uint32(value))), 10)
+		}
+	case oidOID:
+		if len(value) == 4 {
+			return strconv.FormatUint(uint64(binary.BigEndian.Uint32(value)), 10)
+		}
+	case oidInt8:
+		if len(value) == 8 {
+			return strconv.FormatInt(int64(binary.BigEndian.Uint64(value)), 10)
+		}
+	case oidFloat4:
+		if len(value) == 4 {
+			return strconv.FormatFloat(float64(math.Float32frombits(binary.BigEndian.Uint32(value))), 'g', -1, 32)
+		}
+	case oidFloat8:
+		if len(value) == 8 {
+			return strconv.FormatFloat(math.Float64frombits(binary.BigEndian.Uint64(value)), 'g', -1, 64)
+		}
+	case oidText, oidVarchar, oidBpchar, oidName, oidJSON:
+		return string(value)
+	case oidJSONB:
+		// the binary jsonb is the text prefixed with the version of the format
+		if len(value) > 0 && value[0] == 1 {
+			return string(value[1:])
+		}
+	case oidBytea:
+		return `\x` + hex.EncodeToString(value)
+	case oidUUID:
+		if len(value) == 16 {
+			return hex.EncodeToString(value)
+		}
+	case oidDate:
+		if len(value) == 4 {
+			days := int32(binary.BigEndian.Uint32(value))
+			return postgresEpoch.AddDate(0, 0, int(days)).Format("2006-01-02")
+		}
+	case oidTimestamp, oidTimestamptz:
+		if len(value) == 8 {
+			micros := int64(binary.BigEndian.Uint64(value))
+			return postgresEpoch.Add(time.Duration(micros) * time.Microsecond).Format("2006-01-02 15:04:05.999999")
+		}
+	}
+	return `\x` + hex.EncodeToString(value)
+}
+
+// normalizeText returns the canonical form of the values whose types have several text representations.
+func normalizeText(value string, oid uint32) string {
+	switch oid {
+	case oidBool:
+		switch strings.ToLower(strings.TrimSpace(value)) {
+		case "t", "true", "y", "yes", "on", "1":
+			return "true"
+		case "f", "false", "n", "no", "off", "0":
+			return "false"
+		}
+	case oidInt2, oidInt4, oidInt8, oidOID:
+		if n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64); err == nil {
+			return strconv.FormatInt(n, 10)
+		}
+	case oidFloat4, oidFloat8:
+		bits := 64
+		if oid == oidFloat4 {
+			bits = 32
+		}
+		if f, err := strconv.ParseFloat(strings.TrimSpace(value), bits); err == nil {
+			return strconv.FormatFloat(f, 'g', -1, bits)
+		}
+	case oidUUID:
+		return strings.ToLower(strings.ReplaceAll(strings.Trim(value, "{}"), "-", ""))
+	case oidBytea:
+		return strings.ToLower(value)
+	}
+	return value
+}
+
+// isTimestampType reports whether the parameter is a timestamp, their values change from run to run.
+func isTimestampType(oid uint32) bool {
+	return oid == oidTimestamp || oid == oidTimestamptz
+}
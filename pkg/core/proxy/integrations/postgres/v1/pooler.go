@@ -0,0 +1,207 @@
+//go:build linux
+
+package v1
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgproto3/v2"
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations/util"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.uber.org/zap"
+)
+
+// resetStatement matches the statements resetting the state of a session, the poolers and the pools of the drivers
+// send them when a connection is handed to another client, whether a connection is reused depends on the timing.
+var resetStatement = regexp.MustCompile(`(?i)^\s*(discard\s+(all|plans|sequences|temp|temporary)|reset\s+(all|[\w.]+)|deallocate\s+(prepare\s+)?all|close\s+all|unlisten\s+\*)\s*;?\s*$`)
+
+// deallocateStatement matches the reset statements deallocating the prepared statements of the session.
+var deallocateStatement = regexp.MustCompile(`(?i)^\s*(discard|deallocate(\s+prepare)?)\s+all\b`)
+
+// matchPooled matches the requests which differ from the recorded ones by the differences the connection poolers
+// bring, pgbouncer in transaction mode hands the connections of the server to the clients transaction by
+// transaction. It's used once the request doesn't match otherwise:
+//   - the statements resetting the session which weren't recorded are answered as the server does
+//   - the startups are matched on their user and database, the other parameters are set by the pooler
+//   - the statements prepared again, or no longer prepared, are matched on the statements they bind
+func matchPooled(ctx context.Context, logger *zap.Logger, requestBuffers [][]byte, mockDb integrations.MockMemDb) ([]models.Frontend, error) {
+	request := bytes.Join(requestBuffers, nil)
+	connectionID := ctx.Value(models.ClientConnectionIDKey).(string)
+
+	if isStartupPacket(request) {
+		return matchPooledStartup(logger, request, mockDb)
+	}
+	req := decodePgRequest(request, logger)
+	if req == nil {
+		return nil, nil
+	}
+	if resp, ok := resetResponse(req); ok {
+		logger.Debug("answered the statement resetting the session", zap.String("query", req.Query.String))
+		if deallocateStatement.MatchString(req.Query.String) {
+			// the statements prepared on the connection are deallocated
+			delete(testmap, connectionID)
+		}
+		return []models.Frontend{resp}, nil
+	}
+	return matchReprepared(logger, req, connectionID, mockDb)
+}
+
+// resetResponse returns the response of the server to a statement resetting the session.
+func resetResponse(req *models.Backend) (models.Frontend, bool) {
+	if len(req.PacketTypes) != 1 || req.PacketTypes[0] != "Q" {
+		return models.Frontend{}, false
+	}
+	stmt := resetStatement.FindStringSubmatch(req.Query.String)
+	if stmt == nil {
+		return models.Frontend{}, false
+	}
+	words := strings.Fields(strings.ToUpper(stmt[1]))
+	var tag string
+	switch words[0] {
+	case "DISCARD":
+		tag = "DISCARD " + strings.TrimSuffix(words[1], "ORARY")
+	case "DEALLOCATE":
+		tag = "DEALLOCATE ALL"
+	case "CLOSE":
+		tag = "CLOSE CURSOR ALL"
+	default:
+		tag = words[0]
+	}
+
+	var buf []byte
+	buf = (&pgproto3.CommandComplete{CommandTag: []byte(tag)}).Encode(buf)
+	buf = (&pgproto3.ReadyForQuery{TxStatus: 'I'}).Encode(buf)
+	return models.Frontend{Payload: util.EncodeBase64(buf)}, true
+}
+
+// matchPooledStartup returns the response of the startup recorded for the same user and database.
+func matchPooledStartup(logger *zap.Logger, request []byte, mockDb integrations.MockMemDb) ([]models.Frontend, error) {
+	var actual pgproto3.StartupMessage
+	if err := actual.Decode(request[4:]); err != nil {
+		return nil, nil
+	}
+	mocks, err := mockDb.GetUnFilteredMocksByKey(models.Postgres, "")
+	if err != nil {
+		return nil, fmt.Errorf("error while getting tcs mocks %v", err)
+	}
+	for _, mock := range mocks {
+		if mock.Kind != models.Postgres || len(mock.Spec.PostgresRequests) != 1 || mock.Spec.PostgresRequests[0].Identfier != "StartupRequest" {
+			continue
+		}
+		recorded, err := util.DecodeBase64(mock.Spec.PostgresRequests[0].Payload)
+		if err != nil || !isStartupPacket(recorded) {
+			continue
+		}
+		var startup pgproto3.StartupMessage
+		if err := startup.Decode(recorded[4:]); err != nil {
+			continue
+		}
+		if startup.Parameters["user"] != actual.Parameters["user"] || startup.Parameters["database"] != actual.Parameters["database"] {
+			continue
+		}
+		logger.Debug("Matched the startup on its user and database", zap.String("mock", mock.Name))
+		if err := mockDb.FlagMockAsUsed(*mock); err != nil {
+			logger.Error("failed to flag mock as used", zap.Error(err))
+		}
+		return mock.Spec.PostgresResponses, nil
+	}
+	return nil, nil
+}
+
+// matchReprepared returns the response of the mock binding the same statements with the same parameters as the
+// request, with or without parsing them first. The ParseCompletes of the response are fitted to the Parses of the
+// request.
+func matchReprepared(logger *zap.Logger, req *models.Backend, connectionID string, mockDb integrations.MockMemDb) ([]models.Frontend, error) {
+	actual, ok := boundStatements(req, testmap[connectionID])
+	if !ok {
+		return nil, nil
+	}
+	mocks, err := mockDb.GetUnFilteredMocksByKey(models.Postgres, "")
+	if err != nil {
+		return nil, fmt.Errorf("error while getting tcs mocks %v", err)
+	}
+	recordedPrep := getRecordPrepStatement(mocks)
+
+	for _, mock := range mocks {
+		if mock.Kind != models.Postgres || isNotificationMock(mock) || isReplicationMock(mock) {
+			continue
+		}
+		recordedReq := decodePgRequest(requestBytes(mock), logger)
+		if recordedReq == nil || !slices.Equal(withoutParses(recordedReq.PacketTypes), withoutParses(req.PacketTypes)) {
+			continue
+		}
+		expected, ok := boundStatements(recordedReq, recordedPrep[mock.ConnectionID])
+		if !ok {
+			continue
+		}
+		if equal, total, ok := compareBoundStatements(expected, actual); !ok || equal != total {
+			continue
+		}
+
+		resp, err := fitParseCompletes(mock.Spec.PostgresResponses, countPackets(req.PacketTypes, "P")-countPackets(recordedReq.PacketTypes, "P"))
+		if err != nil {
+			logger.Debug("failed to fit the response to the parses of the request", zap.String("mock", mock.Name), zap.Error(err))
+			continue
+		}
+		logger.Debug("Matched the statements prepared again", zap.String("mock", mock.Name))
+		if err := mockDb.FlagMockAsUsed(*mock); err != nil {
+			logger.Error("failed to flag mock as used", zap.Error(err))
+		}
+		return resp, nil
+	}
+	return nil, nil
+}
+
+// withoutParses returns the messages of a request without its Parses.
+func withoutParses(packets []string) []string {
+	var rest []string
+	for _, packet := range packets {
+		if packet != "P" {
+			rest = append(rest, packet)
+		}
+	}
+	return rest
+}
+
+// fitParseCompletes returns the response with ParseCompletes added to its start or removed from it, for the Parses
+// the request has more or less than the recorded one.
+func fitParseCompletes(responses []models.Frontend, diff int) ([]models.Frontend, error) {
+	if diff == 0 {
+		return responses, nil
+	}
+	var buf []byte
+	for _, resp := range responses {
+		encoded, err := util.DecodeBase64(resp.Payload)
+		if len(resp.PacketTypes) > 0 && len(resp.Payload) == 0 {
+			encoded, err = postgresDecoderFrontend(resp)
+		}
+		if err != nil {
+			return nil, err
+		}
+		buf = append(buf, encoded...)
+	}
+
+	var fitted []byte
+	for ; diff > 0; diff-- {
+		fitted = (&pgproto3.ParseComplete{}).Encode(fitted)
+	}
+	for len(buf) > 0 {
+		msgLen := messageLength(buf)
+		if msgLen == 0 {
+			return nil, fmt.Errorf("the response ends with a partial message")
+		}
+		if buf[0] == '1' && diff < 0 {
+			diff++
+		} else {
+			fitted = append(fitted, buf[:msgLen]...)
+		}
+		buf = buf[msgLen:]
+	}
+	return []models.Frontend{{Payload: util.EncodeBase64(fitted)}}, nil
+}
@@ -0,0 +1,103 @@
+//go:build linux
+
+package v1
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"regexp"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.uber.org/zap"
+)
+
+// fetchStatement matches the queries fetching the rows of a cursor declared before.
+var fetchStatement = regexp.MustCompile(`(?i)^\s*(fetch|move)\b`)
+
+// batches follows the rows of the portals and of the cursors fetched in batches on a connection. The requests
+// fetching the next rows of a portal, an Execute with a row limit after the PortalSuspended, are the same for each
+// batch and so are the FETCH queries of a cursor. The batches are matched in the order they were recorded in, from
+// the mocks recorded on the connection of the mock matched last.
+type batches struct {
+	last *models.Mock
+}
+
+// matched keeps the mock matched for the request of the connection.
+func (b *batches) matched(mock *models.Mock) {
+	if mock != nil {
+		b.last = mock
+	}
+}
+
+// isContinuation reports whether the request fetches the next rows of a portal bound or of a cursor declared by
+// an earlier request.
+func isContinuation(req *models.Backend) bool {
+	if req == nil {
+		return false
+	}
+	executes := false
+	for _, packet := range req.PacketTypes {
+		switch packet {
+		case "B", "P":
+			return false
+		case "E":
+			executes = true
+		case "Q":
+			return fetchStatement.MatchString(req.Query.String)
+		}
+	}
+	return executes
+}
+
+// next returns the mock of the batch following the mock matched last, the first one recorded after it on its
+// connection with the same request. It's nil when the request isn't a continuation or no such mock is left.
+func (b *batches) next(ctx context.Context, logger *zap.Logger, requestBuffers [][]byte, mockDb integrations.MockMemDb) (*models.Mock, error) {
+	if b.last == nil || !isContinuation(decodePgRequest(bytes.Join(requestBuffers, nil), logger)) {
+		return nil, nil
+	}
+	actual := bytes.Join(requestBuffers, nil)
+
+	for {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		mocks, err := mockDb.GetUnFilteredMocksByKey(models.Postgres, "")
+		if err != nil {
+			return nil, fmt.Errorf("error while getting tcs mocks %v", err)
+		}
+
+		var matchedMock *models.Mock
+		for _, mock := range mocks {
+			if mock.Kind != models.Postgres || isNotificationMock(mock) || isReplicationMock(mock) || mock.ConnectionID != b.last.ConnectionID ||
+				!mock.Spec.ReqTimestampMock.After(b.last.Spec.ReqTimestampMock) {
+				continue
+			}
+			if matchedMock != nil && !mock.Spec.ReqTimestampMock.Before(matchedMock.Spec.ReqTimestampMock) {
+				continue
+			}
+			if bytes.Equal(requestBytes(mock), actual) {
+				matchedMock = mock
+			}
+		}
+		if matchedMock == nil {
+			return nil, nil
+		}
+		logger.Debug("Matched the next batch of the portal", zap.String("mock", matchedMock.Name), zap.String("after", b.last.Name))
+
+		if matchedMock.TestModeInfo.IsFiltered {
+			originalMatchedMock := *matchedMock
+			matchedMock.TestModeInfo.IsFiltered = false
+			matchedMock.TestModeInfo.SortOrder = math.MaxInt
+			//UpdateUnFilteredMock also marks the mock as used
+			if !mockDb.UpdateUnFilteredMock(&originalMatchedMock, matchedMock) {
+				continue
+			}
+		} else if err := mockDb.FlagMockAsUsed(*matchedMock); err != nil {
+			logger.Error("failed to flag mock as used", zap.Error(err))
+		}
+		return matchedMock, nil
+	}
+}
@@ -32,21 +32,30 @@ func NewPostgresV1(logger *zap.Logger) integrations.Integrations {
 
 // MatchType determines if the outgoing network call is Postgres by comparing the
 // message format with that of a Postgres text message.
-func (p *PostgresV1) MatchType(_ context.Context, reqBuf []byte) bool {
+func (p *PostgresV1) MatchType(_ context.Context, reqBuf []byte) integrations.MatchResult {
 	const ProtocolVersion = 0x00030000 // Protocol version 3.0
+	const SSLRequestCode = 80877103
 
 	if len(reqBuf) < 8 {
 		// Not enough data for a complete header
-		return false
+		return integrations.MatchResult{NeedBytes: 8}
 	}
 
-	// The first four bytes are the message length, but we don't need to check those
-	// The next four bytes are the protocol version
+	// The first four bytes are the message length and the next four bytes are the protocol version
+	length := binary.BigEndian.Uint32(reqBuf[0:4])
 	version := binary.BigEndian.Uint32(reqBuf[4:8])
-	if version == 80877103 {
-		return true
+	switch {
+	case version == SSLRequestCode && length == 8:
+		return integrations.MatchResult{Confidence: integrations.ConfidenceCertain}
+	case version == SSLRequestCode:
+		return integrations.MatchResult{Confidence: integrations.ConfidenceHigh}
+	case version == ProtocolVersion && int(length) == len(reqBuf):
+		return integrations.MatchResult{Confidence: integrations.ConfidenceCertain}
+	case version == ProtocolVersion:
+		return integrations.MatchResult{Confidence: integrations.ConfidenceHigh, NeedBytes: int(length)}
+	default:
+		return integrations.MatchResult{}
 	}
-	return version == ProtocolVersion
 }
 
 func (p *PostgresV1) RecordOutgoing(ctx context.Context, src net.Conn, dst net.Conn, mocks chan<- *models.Mock, opts models.OutgoingOptions) error {
@@ -57,6 +66,19 @@ func (p *PostgresV1) RecordOutgoing(ctx context.Context, src net.Conn, dst net.C
 		utils.LogError(logger, err, "failed to read the initial postgres message")
 		return err
 	}
+	// the startup follows the SSLRequest, over tls when the server accepts it
+	if isSSLRequest(reqBuf) {
+		src, dst, err = recordSSLRequest(ctx, logger, reqBuf, src, dst, mocks)
+		if err != nil {
+			utils.LogError(logger, err, "failed to handle the postgres SSLRequest")
+			return err
+		}
+		reqBuf, err = util.ReadInitialBuf(ctx, logger, src)
+		if err != nil {
+			utils.LogError(logger, err, "failed to read the initial postgres message")
+			return err
+		}
+	}
 	err = encodePostgres(ctx, logger, reqBuf, src, dst, mocks, opts)
 	if err != nil {
 		// TODO: why debug log?
@@ -74,6 +96,21 @@ func (p *PostgresV1) MockOutgoing(ctx context.Context, src net.Conn, dstCfg *int
 		utils.LogError(logger, err, "failed to read the initial postgres message")
 		return err
 	}
+	if isSSLRequest(reqBuf) {
+		var upgraded bool
+		src, upgraded, err = mockSSLRequest(ctx, logger, src, mockDb)
+		if err != nil {
+			utils.LogError(logger, err, "failed to handle the postgres SSLRequest")
+			return err
+		}
+		if upgraded {
+			reqBuf, err = util.ReadInitialBuf(ctx, logger, src)
+			if err != nil {
+				utils.LogError(logger, err, "failed to read the initial postgres message")
+				return err
+			}
+		}
+	}
 
 	err = decodePostgres(ctx, logger, reqBuf, src, dstCfg, mockDb, opts)
 	if err != nil {
@@ -0,0 +1,165 @@
+//go:build linux
+
+package v1
+
+import (
+	"context"
+	"net"
+	"sort"
+	"time"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations/util"
+	pUtil "go.keploy.io/server/v2/pkg/core/proxy/util"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.uber.org/zap"
+)
+
+// ref: https://www.postgresql.org/docs/current/protocol-replication.html
+
+// The connections opened with the replication parameter send the commands of the replication protocol as simple
+// queries, IDENTIFY_SYSTEM and CREATE_REPLICATION_SLOT are answered with rows and are recorded as the other queries.
+// The server answers START_REPLICATION with a CopyBothResponse and streams the changes in CopyData from then on,
+// the XLogData of the changes and the keepalives, while the client sends its standby status updates in CopyData.
+// The messages streamed are recorded apart from the responses, in the order and at the time they're received, and
+// the status updates aren't recorded since the server doesn't answer them.
+
+// startsReplication reports whether the response starts the streaming of a START_REPLICATION.
+func startsReplication(resp []byte) bool {
+	types, _ := messageTypes(resp)
+	return len(types) > 0 && types[0] == 'W'
+}
+
+// isStandbyFeedback reports whether the request holds only the CopyData of the client while the changes are
+// streamed, its standby status updates and hot standby feedback.
+func isStandbyFeedback(req []byte) bool {
+	types, length := messageTypes(req)
+	if len(types) == 0 || length != len(req) {
+		return false
+	}
+	for _, t := range types {
+		if t != 'd' {
+			return false
+		}
+	}
+	return true
+}
+
+// replicationMock returns the mock of the messages streamed by the server, without a request. The time they're
+// received at is recorded in the timestamps of the mock.
+func replicationMock(ctx context.Context, buf []byte) *models.Mock {
+	resp := models.Frontend{
+		Identfier: "ServerResponse",
+		Length:    uint32(len(buf)),
+		Payload:   util.EncodeBase64(buf),
+	}
+	types, _ := messageTypes(buf)
+	for _, t := range types {
+		resp.PacketTypes = append(resp.PacketTypes, string(t))
+	}
+
+	now := time.Now()
+	return &models.Mock{
+		Version: models.GetVersion(),
+		Name:    "mocks",
+		Kind:    models.Postgres,
+		Spec: models.MockSpec{
+			PostgresResponses: []models.Frontend{resp},
+			ReqTimestampMock:  now,
+			ResTimestampMock:  now,
+			Metadata: map[string]string{
+				"type":        "config",
+				"replication": "stream",
+			},
+		},
+		ConnectionID: ctx.Value(models.ClientConnectionIDKey).(string),
+	}
+}
+
+// isReplicationMock reports whether the mock holds the messages streamed to a connection replicating.
+func isReplicationMock(mock *models.Mock) bool {
+	return mock.Spec.Metadata["replication"] != "" && len(mock.Spec.PostgresRequests) == 0 && len(mock.Spec.PostgresResponses) == 1
+}
+
+// replicationStream replays the messages streamed after a START_REPLICATION until the client ends the copy.
+type replicationStream struct {
+	cancel context.CancelFunc
+}
+
+// active reports whether the messages are streamed to the client.
+func (s *replicationStream) active() bool {
+	return s.cancel != nil
+}
+
+// stop stops streaming the messages, the client ended the copy or closed the connection.
+func (s *replicationStream) stop() {
+	if s.cancel != nil {
+		s.cancel()
+		s.cancel = nil
+	}
+}
+
+// start streams the messages recorded on the connection of the START_REPLICATION matched, from its response until
+// the next request of the connection, at the pace they were received at.
+func (s *replicationStream) start(ctx context.Context, logger *zap.Logger, clientConn net.Conn, mockDb integrations.MockMemDb, started *models.Mock) {
+	mocks, err := mockDb.GetUnFilteredMocksByKey(models.Postgres, "")
+	if err != nil {
+		logger.Error("failed to get the mocks streamed by the replication", zap.Error(err))
+		return
+	}
+
+	// the stream ends with the next request of the connection, the CopyDone of the client
+	var end time.Time
+	for _, mock := range mocks {
+		if mock.ConnectionID != started.ConnectionID || len(mock.Spec.PostgresRequests) == 0 ||
+			!mock.Spec.ReqTimestampMock.After(started.Spec.ReqTimestampMock) {
+			continue
+		}
+		if end.IsZero() || mock.Spec.ReqTimestampMock.Before(end) {
+			end = mock.Spec.ReqTimestampMock
+		}
+	}
+	var stream []*models.Mock
+	for _, mock := range mocks {
+		if !isReplicationMock(mock) || mock.ConnectionID != started.ConnectionID ||
+			mock.Spec.ReqTimestampMock.Before(started.Spec.ReqTimestampMock) || (!end.IsZero() && mock.Spec.ReqTimestampMock.After(end)) {
+			continue
+		}
+		stream = append(stream, mock)
+	}
+	sort.SliceStable(stream, func(i, j int) bool {
+		return stream[i].Spec.ReqTimestampMock.Before(stream[j].Spec.ReqTimestampMock)
+	})
+	logger.Debug("streaming the recorded replication", zap.String("mock", started.Name), zap.Int("messages", len(stream)))
+
+	ctx, s.cancel = context.WithCancel(ctx)
+	go func() {
+		defer pUtil.Recover(logger, clientConn, nil)
+		prev := started.Spec.ResTimestampMock
+		for _, mock := range stream {
+			delay := mock.Spec.ReqTimestampMock.Sub(prev)
+			prev = mock.Spec.ReqTimestampMock
+			if delay < 0 {
+				delay = 0
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(delay):
+			}
+
+			buf, err := util.DecodeBase64(mock.Spec.PostgresResponses[0].Payload)
+			if err != nil {
+				logger.Error("failed to decode the streamed replication message", zap.String("mock", mock.Name), zap.Error(err))
+				return
+			}
+			if _, err := clientConn.Write(buf); err != nil {
+				logger.Debug("failed to write the streamed replication message to the client", zap.Error(err))
+				return
+			}
+			if err := mockDb.FlagMockAsUsed(*mock); err != nil {
+				logger.Error("failed to flag mock as used", zap.Error(err))
+			}
+		}
+	}()
+}
@@ -0,0 +1,134 @@
+//go:build linux
+
+package v1
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"time"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations/util"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.uber.org/zap"
+)
+
+// ref: https://www.postgresql.org/docs/current/protocol-flow.html#PROTOCOL-FLOW-SSL
+
+// sslAccepted is the answer of the servers starting tls after the SSLRequest, the servers without tls answer 'N'
+const sslAccepted = 'S'
+
+// isSSLRequest reports whether the message is the SSLRequest the clients send before the startup to ask for tls.
+func isSSLRequest(buf []byte) bool {
+	return len(buf) == 8 && binary.BigEndian.Uint32(buf) == 8 && binary.BigEndian.Uint32(buf[4:]) == sslRequestNumber
+}
+
+func tlsUpgrader(ctx context.Context) (integrations.TLSUpgrader, error) {
+	upgrader, ok := ctx.Value(models.TLSUpgraderKey).(integrations.TLSUpgrader)
+	if !ok {
+		return nil, errors.New("failed to get the tls upgrader from the context")
+	}
+	return upgrader, nil
+}
+
+// recordSSLRequest forwards the SSLRequest of the client to the server and records the answer. When the server
+// accepts it, tls is started with the client with a certificate of the keploy CA and with the server, so that the
+// messages following the handshake are recorded decrypted. It returns the conns the startup is read from and
+// written to.
+func recordSSLRequest(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientConn, destConn net.Conn, mocks chan<- *models.Mock) (net.Conn, net.Conn, error) {
+	reqTimestampMock := time.Now()
+	if _, err := destConn.Write(reqBuf); err != nil {
+		return nil, nil, err
+	}
+	resp := make([]byte, 1)
+	if _, err := io.ReadFull(destConn, resp); err != nil {
+		return nil, nil, err
+	}
+	if _, err := clientConn.Write(resp); err != nil {
+		return nil, nil, err
+	}
+
+	mocks <- &models.Mock{
+		Version: models.GetVersion(),
+		Name:    "mocks",
+		Kind:    models.Postgres,
+		Spec: models.MockSpec{
+			PostgresRequests: []models.Backend{{
+				Identfier: "StartupRequest",
+				Length:    uint32(len(reqBuf)),
+				Payload:   util.EncodeBase64(reqBuf),
+			}},
+			PostgresResponses: []models.Frontend{{
+				Payload: util.EncodeBase64(resp),
+			}},
+			ReqTimestampMock: reqTimestampMock,
+			ResTimestampMock: time.Now(),
+			Metadata:         map[string]string{"type": "config"},
+		},
+		ConnectionID: ctx.Value(models.ClientConnectionIDKey).(string),
+	}
+
+	if resp[0] != sslAccepted {
+		logger.Debug("the postgres server doesn't support tls", zap.String("answer", string(resp)))
+		return clientConn, destConn, nil
+	}
+
+	upgrader, err := tlsUpgrader(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	tlsClient, err := upgrader.UpgradeClient(ctx, clientConn)
+	if err != nil {
+		return nil, nil, err
+	}
+	tlsDest, err := upgrader.UpgradeDest(ctx, destConn, tlsClient.ConnectionState().ServerName)
+	if err != nil {
+		return nil, nil, err
+	}
+	logger.Debug("started tls on the postgres connection")
+	return tlsClient, tlsDest, nil
+}
+
+// mockSSLRequest answers the SSLRequest of the client as the server did while recording. It starts tls with the
+// client when the server accepted it, and reports whether it did. The SSLRequests which weren't accepted are left
+// to the matching of the mocks, which answer them with 'N'.
+func mockSSLRequest(ctx context.Context, logger *zap.Logger, clientConn net.Conn, mockDb integrations.MockMemDb) (net.Conn, bool, error) {
+	mocks, err := mockDb.GetUnFilteredMocksByKey(models.Postgres, "")
+	if err != nil {
+		return nil, false, err
+	}
+	sslRequest := util.EncodeBase64(binary.BigEndian.AppendUint32(binary.BigEndian.AppendUint32(nil, 8), sslRequestNumber))
+	accepted := util.EncodeBase64([]byte{sslAccepted})
+
+	var mock *models.Mock
+	for _, m := range mocks {
+		if m.Kind == models.Postgres && len(m.Spec.PostgresRequests) == 1 && len(m.Spec.PostgresResponses) == 1 &&
+			m.Spec.PostgresRequests[0].Payload == sslRequest && m.Spec.PostgresResponses[0].Payload == accepted {
+			mock = m
+			break
+		}
+	}
+	if mock == nil {
+		return clientConn, false, nil
+	}
+	if err := mockDb.FlagMockAsUsed(*mock); err != nil {
+		logger.Error("failed to flag mock as used", zap.Error(err))
+	}
+
+	if _, err := clientConn.Write([]byte{sslAccepted}); err != nil {
+		return nil, false, err
+	}
+	upgrader, err := tlsUpgrader(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	tlsClient, err := upgrader.UpgradeClient(ctx, clientConn)
+	if err != nil {
+		return nil, false, err
+	}
+	logger.Debug("started tls on the postgres connection", zap.String("mock", mock.Name))
+	return tlsClient, true, nil
+}
@@ -307,6 +307,9 @@ func parseAuthType(buffer []byte) (int32, error) {
 }
 
 func isStartupPacket(packet []byte) bool {
+	if len(packet) < 8 {
+		return false
+	}
 	protocolVersion := binary.BigEndian.Uint32(packet[4:8])
 	// printStartupPacketDetails(packet)
 	return protocolVersion == 196608 // 3.0 in PostgreSQL
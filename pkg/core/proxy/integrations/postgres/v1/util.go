@@ -19,7 +19,7 @@ func postgresDecoderFrontend(response models.Frontend) ([]byte, error) {
 	var resbuffer []byte
 	// list of packets available in the buffer
 	packets := response.PacketTypes
-	var cc, dtr, ps int = 0, 0, 0
+	var cc, dtr, ps, er, nr int = 0, 0, 0, 0, 0
 	for _, packet := range packets {
 		var msg pgproto3.BackendMessage
 
@@ -54,30 +54,13 @@ func postgresDecoderFrontend(response models.Frontend) ([]byte, error) {
 			}
 		case string('D'):
 			msg = &pgproto3.DataRow{
-				RowValues: response.DataRows[dtr].RowValues,
+				RowValues: encodeRowValues(response.DataRows[dtr].RowValues, response.ResultColumns),
 				Values:    response.DataRows[dtr].Values,
 			}
 			dtr++
 		case string('E'):
-			msg = &pgproto3.ErrorResponse{
-				Severity:         response.ErrorResponse.Severity,
-				Code:             response.ErrorResponse.Code,
-				Message:          response.ErrorResponse.Message,
-				Detail:           response.ErrorResponse.Detail,
-				Hint:             response.ErrorResponse.Hint,
-				Position:         response.ErrorResponse.Position,
-				InternalPosition: response.ErrorResponse.InternalPosition,
-				InternalQuery:    response.ErrorResponse.InternalQuery,
-				Where:            response.ErrorResponse.Where,
-				SchemaName:       response.ErrorResponse.SchemaName,
-				TableName:        response.ErrorResponse.TableName,
-				ColumnName:       response.ErrorResponse.ColumnName,
-				DataTypeName:     response.ErrorResponse.DataTypeName,
-				ConstraintName:   response.ErrorResponse.ConstraintName,
-				File:             response.ErrorResponse.File,
-				Line:             response.ErrorResponse.Line,
-				Routine:          response.ErrorResponse.Routine,
-			}
+			msg = errorResponse(response, er)
+			er++
 		case string('G'):
 			msg = &pgproto3.CopyInResponse{
 				OverallFormat:     response.CopyInResponse.OverallFormat,
@@ -98,25 +81,8 @@ func postgresDecoderFrontend(response models.Frontend) ([]byte, error) {
 		case string('n'):
 			msg = &pgproto3.NoData{}
 		case string('N'):
-			msg = &pgproto3.NoticeResponse{
-				Severity:         response.NoticeResponse.Severity,
-				Code:             response.NoticeResponse.Code,
-				Message:          response.NoticeResponse.Message,
-				Detail:           response.NoticeResponse.Detail,
-				Hint:             response.NoticeResponse.Hint,
-				Position:         response.NoticeResponse.Position,
-				InternalPosition: response.NoticeResponse.InternalPosition,
-				InternalQuery:    response.NoticeResponse.InternalQuery,
-				Where:            response.NoticeResponse.Where,
-				SchemaName:       response.NoticeResponse.SchemaName,
-				TableName:        response.NoticeResponse.TableName,
-				ColumnName:       response.NoticeResponse.ColumnName,
-				DataTypeName:     response.NoticeResponse.DataTypeName,
-				ConstraintName:   response.NoticeResponse.ConstraintName,
-				File:             response.NoticeResponse.File,
-				Line:             response.NoticeResponse.Line,
-				Routine:          response.NoticeResponse.Routine,
-			}
+			msg = noticeResponse(response, nr)
+			nr++
 
 		case string('R'):
 			switch response.AuthType {
@@ -184,13 +150,31 @@ func postgresDecoderFrontend(response models.Frontend) ([]byte, error) {
 	return resbuffer, nil
 }
 
+// errorResponse returns the i-th error of the response, with all the fields the server sent.
+func errorResponse(response models.Frontend, i int) *pgproto3.ErrorResponse {
+	msg := response.ErrorResponse
+	if i < len(response.ErrorResponses) {
+		msg = response.ErrorResponses[i]
+	}
+	return &msg
+}
+
+// noticeResponse returns the i-th notice of the response, with all the fields the server sent.
+func noticeResponse(response models.Frontend, i int) *pgproto3.NoticeResponse {
+	msg := response.NoticeResponse
+	if i < len(response.NoticeResponses) {
+		msg = response.NoticeResponses[i]
+	}
+	return &msg
+}
+
 func postgresDecoderBackend(request models.Backend) ([]byte, error) {
 	// take each object , try to make it frontend or backend message so that it can call it's corresponding encode function
 	// and then append it to the buffer, for a particular mock ..
 
 	var reqbuffer []byte
 	// list of packets available in the buffer
-	var b, e, p = 0, 0, 0
+	var b, d, e, p = 0, 0, 0, 0
 	packets := request.PacketTypes
 	for _, packet := range packets {
 		var msg pgproto3.FrontendMessage
@@ -210,10 +194,15 @@ func postgresDecoderBackend(request models.Backend) ([]byte, error) {
 				Name:        request.Close.Name,
 			}
 		case string('D'):
+			describe := request.Describe
+			if d < len(request.Describes) {
+				describe = request.Describes[d]
+			}
 			msg = &pgproto3.Describe{
-				ObjectType: request.Describe.ObjectType,
-				Name:       request.Describe.Name,
+				ObjectType: describe.ObjectType,
+				Name:       describe.Name,
 			}
+			d++
 		case string('E'):
 			msg = &pgproto3.Execute{
 				Portal:  request.Executes[e].Portal,
@@ -221,16 +210,16 @@ func postgresDecoderBackend(request models.Backend) ([]byte, error) {
 			}
 			e++
 		case string('F'):
-			// *msg.(*pgproto3.Flush) = request.Flush
-			msg = &pgproto3.Flush{}
-		case string('f'):
-			// *msg.(*pgproto3.FunctionCall) = request.FunctionCall
 			msg = &pgproto3.FunctionCall{
 				Function:         request.FunctionCall.Function,
 				Arguments:        request.FunctionCall.Arguments,
 				ArgFormatCodes:   request.FunctionCall.ArgFormatCodes,
 				ResultFormatCode: request.FunctionCall.ResultFormatCode,
 			}
+		case string('f'):
+			msg = &pgproto3.CopyFail{
+				Message: request.CopyFail.Message,
+			}
 		case string('d'):
 			msg = &pgproto3.CopyData{
 				Data: request.CopyData.Data,
@@ -238,9 +227,7 @@ func postgresDecoderBackend(request models.Backend) ([]byte, error) {
 		case string('c'):
 			msg = &pgproto3.CopyDone{}
 		case string('H'):
-			msg = &pgproto3.CopyFail{
-				Message: request.CopyFail.Message,
-			}
+			msg = &pgproto3.Flush{}
 		case string('P'):
 			msg = &pgproto3.Parse{
 				Name:          request.Parses[p].Name,
@@ -412,6 +399,10 @@ func decodePgRequest(buffer []byte, logger *zap.Logger) *models.Backend {
 				pg.BackendWrapper.Binds = append(pg.BackendWrapper.Binds, pg.BackendWrapper.Bind)
 			}
 
+			if pg.BackendWrapper.MsgType == 'D' {
+				pg.BackendWrapper.Describes = append(pg.BackendWrapper.Describes, pg.BackendWrapper.Describe)
+			}
+
 			if pg.BackendWrapper.MsgType == 'E' {
 				pg.BackendWrapper.Execute = *msg.(*pgproto3.Execute)
 				pg.BackendWrapper.Executes = append(pg.BackendWrapper.Executes, pg.BackendWrapper.Execute)
@@ -436,6 +427,7 @@ func decodePgRequest(buffer []byte, logger *zap.Logger) *models.Backend {
 			CopyDone:            pg.BackendWrapper.CopyDone,
 			CopyFail:            pg.BackendWrapper.CopyFail,
 			Describe:            pg.BackendWrapper.Describe,
+			Describes:           pg.BackendWrapper.Describes,
 			Execute:             pg.BackendWrapper.Execute,
 			Executes:            pg.BackendWrapper.Executes,
 			Flush:               pg.BackendWrapper.Flush,
@@ -0,0 +1,171 @@
+//go:build linux
+
+package v1
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/jackc/pgproto3/v2"
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+// volatiles keeps the values generated by the server which were sent to the clients, it's shared by the connections
+// since an id returned on a connection is sent on the others.
+var volatiles = newTemplates()
+
+var uuidValue = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// templates rewrites the values the server generates, the serial and the identity ids and the uuids of
+// gen_random_uuid, consistently across the queries depending on them. The mocks of a query sending a generated
+// value were recorded with the value generated then, which differs from the one returned in the replay when the
+// statement generating it was recorded more than once and another of its mocks was matched. The query sending a
+// returned value in place of a recorded one binds them: the recorded value is matched for the returned one in the
+// requests and rewritten to it in the rows of the responses. The timestamps of now() and of the clocks are already
+// left out of the parameters matched.
+type templates struct {
+	mu sync.Mutex
+	// returned are the generated values sent to the clients
+	returned map[string]bool
+	// bound are the returned values by the recorded values they stand for
+	bound map[string]string
+	// recorded are the recorded values by the returned values standing for them
+	recorded map[string]string
+}
+
+func newTemplates() *templates {
+	return &templates{
+		returned: make(map[string]bool),
+		bound:    make(map[string]string),
+		recorded: make(map[string]string),
+	}
+}
+
+// equivalent reports whether the statements differ only in the generated values, each of the recorded values
+// standing for the value returned in its place.
+func (t *templates) equivalent(expected, actual []boundStatement) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	pairs := map[string]string{}
+	templated := false
+	for i := range expected {
+		for j := range expected[i].params {
+			e, a := expected[i].params[j], actual[i].params[j]
+			if e == a {
+				continue
+			}
+			if !t.standsFor(e, a) || (pairs[e] != "" && pairs[e] != a) {
+				return false
+			}
+			pairs[e] = a
+			templated = true
+		}
+	}
+	return templated
+}
+
+// standsFor reports whether the recorded value is bound, or can be bound, to the value returned in its place.
+func (t *templates) standsFor(recorded, returned string) bool {
+	if v, ok := t.bound[recorded]; ok {
+		return v == returned
+	}
+	if _, ok := t.recorded[returned]; ok || !t.returned[returned] || t.returned[recorded] {
+		return false
+	}
+	return sameKind(recorded, returned)
+}
+
+// bind binds the recorded values of the statements to the values returned in their place.
+func (t *templates) bind(expected, actual []boundStatement) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for i := range expected {
+		for j := range expected[i].params {
+			e, a := expected[i].params[j], actual[i].params[j]
+			if e != a {
+				t.bound[e] = a
+				t.recorded[a] = e
+			}
+		}
+	}
+}
+
+// rewrite returns the responses with the recorded values of their generated columns rewritten to the values bound
+// to them, and keeps the values sent as returned. The responses sent as they were recorded are left as they are.
+func (t *templates) rewrite(responses []models.Frontend) []models.Frontend {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	rewritten := make([]models.Frontend, len(responses))
+	for i, resp := range responses {
+		rewritten[i] = resp
+		if resp.Payload != "" || len(resp.DataRows) == 0 {
+			continue
+		}
+		columns := volatileColumns(resp)
+		if columns == nil {
+			continue
+		}
+		rows := make([]pgproto3.DataRow, len(resp.DataRows))
+		for j, row := range resp.DataRows {
+			values := make([]string, len(row.RowValues))
+			copy(values, row.RowValues)
+			for k, value := range values {
+				if k >= len(columns) || !columns[k] || value == "" || strings.HasPrefix(value, b64Prefix) {
+					continue
+				}
+				if v, ok := t.bound[value]; ok {
+					values[k] = v
+				}
+				t.returned[values[k]] = true
+			}
+			rows[j] = pgproto3.DataRow{RowValues: values, Values: row.Values}
+		}
+		rewritten[i].DataRows = rows
+	}
+	return rewritten
+}
+
+// volatileColumns returns which columns of the rows of the response are generated by the server: the uuids, and the
+// integers named id or ending with _id. The names are known from the RowDescription of the response, only the uuids
+// are known from the columns of the statements described before.
+func volatileColumns(resp models.Frontend) []bool {
+	var columns []bool
+	volatile := false
+	if countPackets(resp.PacketTypes, "T") == 1 {
+		for _, field := range resp.RowDescription.Fields {
+			name := strings.ToLower(string(field.Name))
+			v := field.DataTypeOID == oidUUID ||
+				(isIntegerType(field.DataTypeOID) && (name == "id" || strings.HasSuffix(name, "_id")))
+			columns = append(columns, v)
+			volatile = volatile || v
+		}
+	} else {
+		for _, column := range resp.ResultColumns {
+			v := column.DataTypeOID == oidUUID
+			columns = append(columns, v)
+			volatile = volatile || v
+		}
+	}
+	if !volatile {
+		return nil
+	}
+	return columns
+}
+
+func isIntegerType(oid uint32) bool {
+	return oid == oidInt2 || oid == oidInt4 || oid == oidInt8
+}
+
+// sameKind reports whether both the values are uuids or both are integers.
+func sameKind(a, b string) bool {
+	if uuidValue.MatchString(a) {
+		return uuidValue.MatchString(b)
+	}
+	_, errA := strconv.ParseInt(a, 10, 64)
+	_, errB := strconv.ParseInt(b, 10, 64)
+	return errA == nil && errB == nil
+}
@@ -0,0 +1,190 @@
+//go:build linux
+
+package redis
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"strings"
+
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+// nodePort returns the port of the cluster node the mock was recorded on, or "" for the mocks recorded before
+// the node was kept.
+func nodePort(mock *models.Mock) string {
+	_, port, err := net.SplitHostPort(mock.Spec.Metadata["node"])
+	if err != nil {
+		return ""
+	}
+	return port
+}
+
+// preferNode moves the mocks recorded on the node listening on the port ahead of the others, so that a
+// command sent to several nodes of a cluster gets the reply of the node it is sent to, e.g. the value from
+// the node owning the key and a MOVED redirect from the others. The nodes are told apart by their ports,
+// since their addresses are rewritten to the host the client connected to while testing.
+func preferNode(mocks []*models.Mock, port string) []*models.Mock {
+	if port == "" {
+		return mocks
+	}
+	res := make([]*models.Mock, 0, len(mocks))
+	var others []*models.Mock
+	for _, mock := range mocks {
+		if nodePort(mock) == port {
+			res = append(res, mock)
+		} else {
+			others = append(others, mock)
+		}
+	}
+	return append(res, others...)
+}
+
+// rewriteNodes points the node addresses announced by the reply to the host the client connected to, so that
+// the conns the client opens to the other nodes of the cluster reach the proxy as well. The MOVED and ASK
+// redirects are rewritten along with the replies of CLUSTER SLOTS, CLUSTER SHARDS and CLUSTER NODES.
+func rewriteNodes(cmd *value, raw []byte, host string) []byte {
+	if host == "" || len(raw) == 0 || (raw[0] != '-' && cmd.command() != "CLUSTER") {
+		return raw
+	}
+	reply, err := readValue(bufio.NewReader(bytes.NewReader(raw)))
+	if err != nil {
+		return raw
+	}
+
+	changed := false
+	if reply.typ == '-' {
+		changed = rewriteRedirect(reply, host)
+	} else if args := cmd.args(); cmd.command() == "CLUSTER" && len(args) > 0 {
+		switch strings.ToUpper(args[0]) {
+		case "SLOTS":
+			changed = rewriteSlots(reply, host)
+		case "SHARDS":
+			changed = rewriteShards(reply, host)
+		case "NODES", "REPLICAS", "SLAVES":
+			changed = rewriteNodesText(reply, host)
+		}
+	}
+	if !changed {
+		return raw
+	}
+	return reply.encode()
+}
+
+// rewriteRedirect rewrites the errors like "MOVED 3999 10.0.0.2:6381" and "ASK 3999 10.0.0.2:6381".
+func rewriteRedirect(reply *value, host string) bool {
+	fields := strings.Fields(reply.str)
+	if len(fields) != 3 || (fields[0] != "MOVED" && fields[0] != "ASK") {
+		return false
+	}
+	_, port, err := net.SplitHostPort(fields[2])
+	if err != nil {
+		return false
+	}
+	fields[2] = net.JoinHostPort(host, port)
+	reply.str = strings.Join(fields, " ")
+	return true
+}
+
+// rewriteSlots rewrites the nodes of the slot ranges, each node is sent as its ip, port, id and, from redis 7,
+// a map of its hostname.
+func rewriteSlots(reply *value, host string) bool {
+	changed := false
+	for _, slots := range reply.elems {
+		if len(slots.elems) < 3 {
+			continue
+		}
+		for _, node := range slots.elems[2:] {
+			if len(node.elems) == 0 || !announced(node.elems[0].str) {
+				continue
+			}
+			node.elems[0].str = host
+			changed = true
+			if len(node.elems) > 3 {
+				rewriteFields(node.elems[3], host, "hostname")
+			}
+		}
+	}
+	return changed
+}
+
+// rewriteShards rewrites the nodes of the shards, which are sent as maps.
+func rewriteShards(reply *value, host string) bool {
+	changed := false
+	for _, shard := range reply.elems {
+		for i := 0; i+1 < len(shard.elems); i += 2 {
+			if shard.elems[i].str != "nodes" {
+				continue
+			}
+			for _, node := range shard.elems[i+1].elems {
+				if rewriteFields(node, host, "ip", "endpoint", "hostname") {
+					changed = true
+				}
+			}
+		}
+	}
+	return changed
+}
+
+// rewriteFields rewrites the values of the keys of a map, sent as an array of keys and values by RESP2.
+func rewriteFields(m *value, host string, keys ...string) bool {
+	changed := false
+	for i := 0; i+1 < len(m.elems); i += 2 {
+		for _, key := range keys {
+			if m.elems[i].str == key && announced(m.elems[i+1].str) {
+				m.elems[i+1].str = host
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+// rewriteNodesText rewrites the address field of the lines "<id> <ip:port@cport[,hostname]> <flags> ...".
+func rewriteNodesText(reply *value, host string) bool {
+	if reply.typ != '$' && reply.typ != '=' && reply.typ != '+' {
+		return false
+	}
+	prefix := ""
+	text := reply.str
+	if reply.typ == '=' && len(text) >= 4 {
+		// the verbatim strings start with their format, e.g. txt:
+		prefix, text = text[:4], text[4:]
+	}
+
+	changed := false
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		fields := strings.Split(line, " ")
+		if len(fields) < 2 {
+			continue
+		}
+		addr, bus, found := strings.Cut(fields[1], "@")
+		nodeHost, port, err := net.SplitHostPort(addr)
+		if err != nil || !announced(nodeHost) {
+			continue
+		}
+		addr = net.JoinHostPort(host, port)
+		if found {
+			cport, _, hasHostname := strings.Cut(bus, ",")
+			bus = cport
+			if hasHostname {
+				bus += "," + host
+			}
+			addr += "@" + bus
+		}
+		fields[1] = addr
+		lines[i] = strings.Join(fields, " ")
+		changed = true
+	}
+	if changed {
+		reply.str = prefix + strings.Join(lines, "\n")
+	}
+	return changed
+}
+
+// announced reports whether the node announced an address, the nodes without one send an empty address or "?".
+func announced(host string) bool {
+	return host != "" && host != "?"
+}
@@ -4,9 +4,14 @@
 package redis
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"fmt"
 	"io"
 	"net"
+	"strings"
+	"sync"
 	"time"
 
 	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
@@ -17,102 +22,70 @@ import (
 	"go.uber.org/zap"
 )
 
+// deliveryInterval is how often the mocked server looks for the messages to push to the client
+const deliveryInterval = 50 * time.Millisecond
+
+// session is the state of a client connection to the mocked server.
+type session struct {
+	logger     *zap.Logger
+	clientConn net.Conn
+	dstCfg     *integrations.ConditionalDstCfg
+	mockDb     integrations.MockMemDb
+
+	// writeMu serializes the replies and the pushes written to the client
+	writeMu sync.Mutex
+	mu      sync.Mutex
+	subs    subscriptions
+	// resp3 is set once the client switched to RESP3 with HELLO 3
+	resp3 bool
+	// tracking is set while the client asked for the invalidations of the keys it read
+	tracking bool
+}
+
+// decodeRedis answers the commands of the client with the replies recorded for them, one command at a time.
+// The messages recorded for the subscriptions of the client, and the invalidations when it tracks its keys,
+// are pushed to the client during the test cases they were recorded in. The commands sent to a node of a cluster
+// get the replies of that node.
 func decodeRedis(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientConn net.Conn, dstCfg *integrations.ConditionalDstCfg, mockDb integrations.MockMemDb, _ models.OutgoingOptions) error {
-	redisRequests := [][]byte{reqBuf}
 	logger.Debug("Into the redis parser in test mode")
+	s := &session{
+		logger:     logger,
+		clientConn: clientConn,
+		dstCfg:     dstCfg,
+		mockDb:     mockDb,
+		subs:       make(subscriptions),
+	}
 	errCh := make(chan error, 1)
+	done := make(chan struct{})
+	defer close(done)
 
-	go func(errCh chan error, redisRequests [][]byte) {
+	go func() {
 		defer pUtil.Recover(logger, clientConn, nil)
 		defer close(errCh)
+		client := bufio.NewReader(io.MultiReader(bytes.NewReader(reqBuf), clientConn))
 		for {
-
-			// Read the stream of request packets from the client
-			for {
-				if len(redisRequests) > 0 {
-					break
-				}
-				err := clientConn.SetReadDeadline(time.Now().Add(10 * time.Millisecond))
-				if err != nil {
-					utils.LogError(logger, err, "failed to set the read deadline for the client conn")
-					return
-				}
-				buffer, err := pUtil.ReadBytes(ctx, logger, clientConn)
-				if netErr, ok := err.(net.Error); !(ok && netErr.Timeout()) && err != nil && err.Error() != "EOF" {
-					logger.Debug("failed to read the request message in proxy for redis dependency")
-					return
-				}
-				if netErr, ok := err.(net.Error); (ok && netErr.Timeout()) || (err != nil && err.Error() == "EOF") {
-					logger.Debug("timeout for client read in redis or EOF")
-					break
-				}
-				if len(buffer) > 0 {
-					redisRequests = append(redisRequests, buffer)
-					break
-				}
-			}
-
-			if len(redisRequests) == 0 {
-				logger.Debug("redis request buffer is empty")
-				continue
-			}
-
-			// Fuzzy match to get the best matched redis mock
-			matched, redisResponses, err := fuzzyMatch(ctx, redisRequests, mockDb)
+			cmd, err := readValue(client)
 			if err != nil {
-				utils.LogError(logger, err, "error while matching redis mocks")
-			}
-
-			if !matched {
-				err := clientConn.SetReadDeadline(time.Time{})
-				if err != nil {
-					utils.LogError(logger, err, "failed to set the read deadline for the client conn")
-					return
-				}
-
-				logger.Debug("redisRequests before pass through:", zap.Any("length", len(redisRequests)))
-				for _, redReq := range redisRequests {
-					logger.Debug("redisRequests:", zap.Any("h", string(redReq)))
+				if err != io.EOF {
+					utils.LogError(logger, err, "failed to read the redis command from the client")
 				}
-
-				reqBuffer, err := pUtil.PassThrough(ctx, logger, clientConn, dstCfg, redisRequests)
-				if err != nil {
-					utils.LogError(logger, err, "failed to passthrough the redis request")
-					return
-				}
-
-				redisRequests = [][]byte{}
-				logger.Debug("request buffer after pass through in redis:", zap.Any("buffer", string(reqBuffer)))
-				if len(reqBuffer) > 0 {
-					redisRequests = [][]byte{reqBuffer}
-				}
-				logger.Debug("length of redisRequests after passThrough:", zap.Any("length", len(redisRequests)))
-				continue
+				errCh <- err
+				return
 			}
-			for _, redisResponse := range redisResponses {
-				encoded := []byte(redisResponse.Message[0].Data)
-				if redisResponse.Message[0].Type != models.String {
-					encoded, err = util.DecodeBase64(redisResponse.Message[0].Data)
-					if err != nil {
-						utils.LogError(logger, err, "failed to decode the base64 response")
-						return
-					}
-				}
-				_, err := clientConn.Write(encoded)
-				if err != nil {
-					if ctx.Err() != nil {
-						return
-					}
-					utils.LogError(logger, err, "failed to write the response message to the client application")
+			if err := s.handle(ctx, cmd); err != nil {
+				if ctx.Err() != nil {
 					return
 				}
+				errCh <- err
+				return
 			}
-
-			// Clear the redisRequests buffer for the next dependency call
-			redisRequests = [][]byte{}
-			logger.Debug("redisRequests after the iteration:", zap.Any("length", len(redisRequests)))
 		}
-	}(errCh, redisRequests)
+	}()
+
+	go func() {
+		defer pUtil.Recover(logger, clientConn, nil)
+		s.deliver(ctx, done)
+	}()
 
 	select {
 	case <-ctx.Done():
@@ -124,3 +97,141 @@ func decodeRedis(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientC
 		return err
 	}
 }
+
+func (s *session) handle(ctx context.Context, cmd *value) error {
+	// Fuzzy match to get the best matched redis mock
+	matched, redisResponses, err := fuzzyMatch(ctx, [][]byte{cmd.raw}, s.mockDb, fmt.Sprint(s.dstCfg.Port))
+	if err != nil {
+		utils.LogError(s.logger, err, "error while matching redis mocks")
+	}
+
+	if !matched {
+		s.logger.Debug("no redis mock found for the command, passing it through", zap.String("command", cmd.command()))
+		s.writeMu.Lock()
+		_, err := pUtil.PassThrough(ctx, s.logger, s.clientConn, s.dstCfg, [][]byte{cmd.raw})
+		s.writeMu.Unlock()
+		if err != nil {
+			utils.LogError(s.logger, err, "failed to passthrough the redis request")
+			return err
+		}
+		return nil
+	}
+
+	for _, redisResponse := range redisResponses {
+		encoded := []byte(redisResponse.Message[0].Data)
+		if redisResponse.Message[0].Type != models.String {
+			encoded, err = util.DecodeBase64(redisResponse.Message[0].Data)
+			if err != nil {
+				utils.LogError(s.logger, err, "failed to decode the base64 response")
+				return err
+			}
+		}
+		encoded = rewriteNodes(cmd, encoded, s.host())
+		if err := s.write(encoded); err != nil {
+			return err
+		}
+		if isSubscriptionCommand(cmd) {
+			// the subscriptions of the client are the ones confirmed by the recorded replies
+			if reply, err := readValue(bufio.NewReader(bytes.NewReader(encoded))); err == nil {
+				s.mu.Lock()
+				s.subs.apply(reply)
+				s.mu.Unlock()
+			}
+		}
+	}
+
+	args := cmd.args()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch cmd.command() {
+	case "HELLO":
+		if len(args) > 0 {
+			s.resp3 = args[0] == "3"
+		}
+	case "CLIENT":
+		if len(args) > 1 && strings.EqualFold(args[0], "TRACKING") {
+			s.tracking = strings.EqualFold(args[1], "ON")
+		}
+	}
+	return nil
+}
+
+// deliver pushes the messages recorded during the current test case to the client.
+func (s *session) deliver(ctx context.Context, done <-chan struct{}) {
+	ticker := time.NewTicker(deliveryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+
+		s.mu.Lock()
+		listening := s.subs.active() || s.tracking
+		s.mu.Unlock()
+		if !listening {
+			continue
+		}
+
+		deliveries, err := deliveries(s.mockDb, s.delivers)
+		if err != nil {
+			utils.LogError(s.logger, err, "failed to get the redis deliveries")
+			return
+		}
+		for _, raw := range deliveries {
+			if err := s.write(s.pushed(raw)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// delivers reports whether the recorded push is meant for the client.
+func (s *session) delivers(push *value) bool {
+	if len(push.elems) == 0 {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kind := strings.ToLower(push.elems[0].str)
+	if messageKinds[kind] {
+		return s.subs.delivers(push)
+	}
+	return kind == "invalidate" && s.tracking
+}
+
+// pushed returns the push in the protocol of the client, the RESP2 clients get the messages as arrays.
+func (s *session) pushed(raw []byte) []byte {
+	if len(raw) == 0 || (raw[0] != '>' && raw[0] != '*') {
+		return raw
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res := append([]byte(nil), raw...)
+	res[0] = '*'
+	if s.resp3 {
+		res[0] = '>'
+	}
+	return res
+}
+
+// host returns the host the client connected to, the node addresses of the cluster are rewritten to it.
+func (s *session) host() string {
+	if s.dstCfg.IP == nil {
+		return ""
+	}
+	return s.dstCfg.IP.String()
+}
+
+func (s *session) write(raw []byte) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	_, err := s.clientConn.Write(raw)
+	if err != nil {
+		utils.LogError(s.logger, err, "failed to write the response message to the client application")
+	}
+	return err
+}
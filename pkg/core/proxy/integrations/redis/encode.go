@@ -3,10 +3,13 @@
 package redis
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"errors"
 	"io"
 	"net"
+	"sync"
 	"time"
 
 	"golang.org/x/sync/errgroup"
@@ -17,122 +20,118 @@ import (
 	"go.uber.org/zap"
 )
 
-func encodeRedis(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientConn, destConn net.Conn, mocks chan<- *models.Mock, _ models.OutgoingOptions) error {
-
-	var redisRequests []models.Payload
-	var redisResponses []models.Payload
-
-	bufStr := string(reqBuf)
-	dataType := models.String
-
-	if bufStr != "" {
-		redisRequests = append(redisRequests, models.Payload{
-			Origin: models.FromClient,
-			Message: []models.OutputBinary{
-				{
-					Type: dataType,
-					Data: bufStr,
-				},
-			},
-		})
-	}
-	_, err := destConn.Write(reqBuf)
-	if err != nil {
-		utils.LogError(logger, err, "failed to write request message to the destination server")
-		return err
-	}
-	errCh := make(chan error, 1)
+// pendingCommand is a command forwarded to the server which is waiting for its replies.
+type pendingCommand struct {
+	cmd              *value
+	replies          []*value
+	expected         int
+	reqTimestampMock time.Time
+}
 
+// encodeRedis forwards the commands and the replies between the client and the server. The server replies
+// in the order of the commands, so every command is recorded with the replies sent for it, and the messages
+// the server pushes on its own, like the pub/sub messages of the subscriptions or the RESP3 invalidations,
+// are recorded as mocks without a request. The MOVED and ASK redirects of a cluster are recorded as they are,
+// the client follows them on new conns to the other nodes which are recorded with their own node.
+func encodeRedis(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientConn, destConn net.Conn, mocks chan<- *models.Mock, _ models.OutgoingOptions) error {
 	g, ok := ctx.Value(models.ErrGroupKey).(*errgroup.Group)
 	if !ok {
 		return errors.New("failed to get the error group from the context")
 	}
 
-	reqTimestampMock := time.Now()
+	var (
+		mu      sync.Mutex
+		pending []*pendingCommand
+	)
+	// the node of a cluster the client connected to, the client opens a conn per node it is redirected to
+	node := destConn.RemoteAddr().String()
+	errCh := make(chan error, 2)
 
-	// Read and process responses from the destination server
+	// Forward the commands from the client to the destination server
 	g.Go(func() error {
 		defer pUtil.Recover(logger, clientConn, destConn)
-		defer close(errCh)
-
+		client := bufio.NewReader(io.MultiReader(bytes.NewReader(reqBuf), clientConn))
 		for {
-			// Read the response from the destination server
-			resp, err := pUtil.ReadBytes(ctx, logger, destConn)
+			cmd, err := readValue(client)
 			if err != nil {
-				if err == io.EOF {
-					logger.Debug("Response complete, exiting the loop.")
-					// if there is any buffer left before EOF, we must send it to the client and save this as mock
-					if len(resp) != 0 {
-						resTimestampMock := time.Now()
-						_, err = clientConn.Write(resp)
-						if err != nil {
-							utils.LogError(logger, err, "failed to write response message to the client")
-							errCh <- err
-							return nil
-						}
-						processBuffer(resp, models.FromServer, &redisResponses)
-						saveMock(redisRequests, redisResponses, reqTimestampMock, resTimestampMock, mocks)
-					}
-					break
+				if err != io.EOF {
+					utils.LogError(logger, err, "failed to read the redis command from the client")
 				}
-				utils.LogError(logger, err, "failed to read the response message from the destination server")
 				errCh <- err
 				return nil
 			}
 
-			// Write the response message to the client
-			_, err = clientConn.Write(resp)
+			// register the command before forwarding it, so that the reply can't arrive first
+			mu.Lock()
+			pending = append(pending, &pendingCommand{cmd: cmd, reqTimestampMock: time.Now()})
+			mu.Unlock()
+
+			_, err = destConn.Write(cmd.raw)
 			if err != nil {
-				utils.LogError(logger, err, "failed to write response message to the client")
+				utils.LogError(logger, err, "failed to write request message to the destination server")
 				errCh <- err
 				return nil
 			}
+		}
+	})
 
-			resTimestampMock := time.Now()
-			processBuffer(resp, models.FromServer, &redisResponses)
-
-			// Save the mock with both request and response
-			if len(redisRequests) > 0 && len(redisResponses) > 0 {
-				saveMock(redisRequests, redisResponses, reqTimestampMock, resTimestampMock, mocks)
-				redisRequests = []models.Payload{}
-				redisResponses = []models.Payload{}
-			}
-
-			// Read the next request from the client
-			reqBuf, err = pUtil.ReadBytes(ctx, logger, clientConn)
+	// Forward the replies and the pushes from the destination server to the client
+	g.Go(func() error {
+		defer pUtil.Recover(logger, clientConn, destConn)
+		server := bufio.NewReader(destConn)
+		subs := make(subscriptions)
+		for {
+			v, err := readValue(server)
 			if err != nil {
 				if err != io.EOF {
-					utils.LogError(logger, err, "failed to read the request message from the client")
-					errCh <- err
-					return nil
+					utils.LogError(logger, err, "failed to read the redis reply from the destination server")
 				}
 				errCh <- err
 				return nil
 			}
 
-			bufStr := string(reqBuf)
-			dataType := models.String
-
-			if bufStr != "" {
-				redisRequests = append(redisRequests, models.Payload{
-					Origin: models.FromClient,
-					Message: []models.OutputBinary{
-						{
-							Type: dataType,
-							Data: bufStr,
-						},
-					},
-				})
-			}
-			_, err = destConn.Write(reqBuf)
+			_, err = clientConn.Write(v.raw)
 			if err != nil {
-				utils.LogError(logger, err, "failed to write request message to the destination server")
+				utils.LogError(logger, err, "failed to write response message to the client")
 				errCh <- err
 				return nil
 			}
-			reqTimestampMock = time.Now()
+			resTimestampMock := time.Now()
+
+			kind := pushKind(v, subs.active())
+			if isDeliveryKind(kind) {
+				logger.Debug("redis push from the server", zap.String("kind", kind))
+				saveMock(nil, []*value{v}, resTimestampMock, resTimestampMock, node, mocks)
+				continue
+			}
+			mu.Lock()
+			if len(pending) == 0 {
+				mu.Unlock()
+				if kind != "" {
+					subs.apply(v)
+				}
+				logger.Debug("no pending redis command for the reply", zap.String("reply", string(v.raw)))
+				continue
+			}
+			head := pending[0]
+			if head.expected == 0 {
+				// counted before the first reply changes the subscriptions
+				head.expected = expectedReplies(head.cmd, subs)
+			}
+			head.replies = append(head.replies, v)
+			done := len(head.replies) >= head.expected
+			if done {
+				pending = pending[1:]
+			}
+			mu.Unlock()
+			if kind != "" || isSubscriptionCommand(head.cmd) {
+				subs.apply(v)
+			}
+
+			if done {
+				saveMock([]*value{head.cmd}, head.replies, head.reqTimestampMock, resTimestampMock, node, mocks)
+			}
 		}
-		return nil
 	})
 
 	select {
@@ -146,42 +145,40 @@ func encodeRedis(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientC
 	}
 }
 
-func processBuffer(buffer []byte, origin models.OriginType, payloads *[]models.Payload) {
-	bufStr := string(buffer)
-	buffDataType := models.String
-
-	if bufStr != "" {
-		*payloads = append(*payloads, models.Payload{
-			Origin: origin,
-			Message: []models.OutputBinary{
-				{
-					Type: buffDataType,
-					Data: bufStr,
-				},
-			},
-		})
+// saveMock records the commands with the replies sent for them, along with the address of the server. The
+// pushes are recorded with the server value only.
+func saveMock(requests, responses []*value, reqTimestampMock, resTimestampMock time.Time, node string, mocks chan<- *models.Mock) {
+	metadata := map[string]string{"node": node}
+	if len(requests) > 0 {
+		metadata["type"] = "config"
 	}
-}
-
-func saveMock(requests, responses []models.Payload, reqTimestampMock, resTimestampMock time.Time, mocks chan<- *models.Mock) {
-	redisRequestsCopy := make([]models.Payload, len(requests))
-	redisResponsesCopy := make([]models.Payload, len(responses))
-	copy(redisResponsesCopy, responses)
-	copy(redisRequestsCopy, requests)
-
-	metadata := make(map[string]string)
-	metadata["type"] = "config"
 
 	mocks <- &models.Mock{
 		Version: models.GetVersion(),
 		Name:    "mocks",
 		Kind:    models.REDIS,
 		Spec: models.MockSpec{
-			RedisRequests:    redisRequestsCopy,
-			RedisResponses:   redisResponsesCopy,
+			RedisRequests:    payloads(requests, models.FromClient),
+			RedisResponses:   payloads(responses, models.FromServer),
 			ReqTimestampMock: reqTimestampMock,
 			ResTimestampMock: resTimestampMock,
 			Metadata:         metadata,
 		},
 	}
 }
+
+func payloads(values []*value, origin models.OriginType) []models.Payload {
+	var res []models.Payload
+	for _, v := range values {
+		res = append(res, models.Payload{
+			Origin: origin,
+			Message: []models.OutputBinary{
+				{
+					Type: models.String,
+					Data: string(v.raw),
+				},
+			},
+		})
+	}
+	return res
+}
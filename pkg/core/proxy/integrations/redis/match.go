@@ -3,9 +3,12 @@
 package redis
 
 import (
+	"bufio"
 	"context"
 	"fmt"
 	"math"
+	"sort"
+	"strings"
 
 	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
 
@@ -19,13 +22,13 @@ import (
 // If a match is found, it returns the corresponding response mock and a boolean value indicating success.
 // If no match is found, it returns false and a nil response.
 // If an error occurs during the matching process, it returns an error.
-func fuzzyMatch(ctx context.Context, reqBuff [][]byte, mockDb integrations.MockMemDb) (bool, []models.Payload, error) {
+func fuzzyMatch(ctx context.Context, reqBuff [][]byte, mockDb integrations.MockMemDb, port string) (bool, []models.Payload, error) {
 	for {
 		select {
 		case <-ctx.Done():
 			return false, nil, ctx.Err()
 		default:
-			mocks, err := mockDb.GetUnFilteredMocks()
+			mocks, err := mockDb.GetUnFilteredMocksByKey(models.REDIS, "")
 			if err != nil {
 				return false, nil, fmt.Errorf("error while getting unfiltered mocks %v", err)
 			}
@@ -44,6 +47,9 @@ func fuzzyMatch(ctx context.Context, reqBuff [][]byte, mockDb integrations.MockM
 				}
 			}
 
+			filteredMocks = preferNode(filteredMocks, port)
+			unfilteredMocks = preferNode(unfilteredMocks, port)
+
 			index := findExactMatch(filteredMocks, reqBuff)
 
 			if index == -1 {
@@ -149,3 +155,50 @@ func findExactMatch(tcsMocks []*models.Mock, reqBuffs [][]byte) int {
 	}
 	return -1
 }
+
+// isDelivery reports whether the mock is a value the server pushed on its own.
+func isDelivery(mock *models.Mock) bool {
+	return mock.Kind == models.REDIS && len(mock.Spec.RedisRequests) == 0 && len(mock.Spec.RedisResponses) > 0
+}
+
+// deliveries uses up the pushes recorded during the current test case which are meant for the client, and
+// returns them in the order they were pushed.
+func deliveries(mockDb integrations.MockMemDb, delivers func(*value) bool) ([][]byte, error) {
+	mocks, err := mockDb.GetUnFilteredMocksByKey(models.REDIS, "")
+	if err != nil {
+		return nil, fmt.Errorf("error while getting unfiltered mocks %v", err)
+	}
+
+	type delivery struct {
+		mock *models.Mock
+		raw  []byte
+	}
+	var pending []delivery
+	for _, mock := range mocks {
+		if !mock.TestModeInfo.IsFiltered || !isDelivery(mock) {
+			continue
+		}
+		data := mock.Spec.RedisResponses[0].Message[0].Data
+		push, err := readValue(bufio.NewReader(strings.NewReader(data)))
+		if err != nil || !delivers(push) {
+			continue
+		}
+		pending = append(pending, delivery{mock: mock, raw: []byte(data)})
+	}
+	sort.SliceStable(pending, func(i, j int) bool {
+		return pending[i].mock.Spec.ResTimestampMock.Before(pending[j].mock.Spec.ResTimestampMock)
+	})
+
+	var res [][]byte
+	for _, d := range pending {
+		originalMock := *d.mock
+		d.mock.TestModeInfo.IsFiltered = false
+		d.mock.TestModeInfo.SortOrder = math.MaxInt64
+		if !mockDb.UpdateUnFilteredMock(&originalMock, d.mock) {
+			// delivered on another conn of the same subscription
+			continue
+		}
+		res = append(res, d.raw)
+	}
+	return res, nil
+}
@@ -0,0 +1,121 @@
+//go:build linux
+
+package redis
+
+import (
+	"strings"
+)
+
+// the kinds of the replies confirming the subscriptions, and the kinds of the messages delivered for them
+var (
+	subscriptionKinds = map[string]bool{
+		"subscribe": true, "psubscribe": true, "ssubscribe": true,
+		"unsubscribe": true, "punsubscribe": true, "sunsubscribe": true,
+	}
+	messageKinds = map[string]bool{"message": true, "pmessage": true, "smessage": true}
+)
+
+// pushKind returns the kind of the value when the server sent it out of band or as part of the pub/sub
+// protocol, e.g. message or subscribe, and "" for the plain replies. The RESP3 servers send these as push
+// values, the RESP2 servers as arrays which can only be told apart from the replies on a subscribed conn.
+func pushKind(v *value, subscribed bool) string {
+	if len(v.elems) == 0 {
+		return ""
+	}
+	kind := strings.ToLower(v.elems[0].str)
+	switch v.typ {
+	case '>':
+		return kind
+	case '*':
+		if subscribed && (subscriptionKinds[kind] || messageKinds[kind]) {
+			return kind
+		}
+	}
+	return ""
+}
+
+// isDeliveryKind reports whether the server sends the pushes of the kind on its own rather than as the reply
+// to a command.
+func isDeliveryKind(kind string) bool {
+	return kind != "" && !subscriptionKinds[kind]
+}
+
+// subscriptions are the channels, the patterns and the shard channels a conn is subscribed to, keyed by
+// the kind of their subscribe reply.
+type subscriptions map[string]map[string]bool
+
+// apply updates the subscriptions with a subscribe or unsubscribe reply.
+func (s subscriptions) apply(reply *value) {
+	if len(reply.elems) < 2 {
+		return
+	}
+	kind := strings.ToLower(reply.elems[0].str)
+	if !subscriptionKinds[kind] {
+		return
+	}
+	channel := reply.elems[1]
+	if strings.Contains(kind, "unsubscribe") {
+		if !channel.null {
+			delete(s[subscribeKind(kind)], channel.str)
+		}
+		return
+	}
+	if s[kind] == nil {
+		s[kind] = make(map[string]bool)
+	}
+	s[kind][channel.str] = true
+}
+
+func (s subscriptions) active() bool {
+	for _, channels := range s {
+		if len(channels) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// delivers reports whether the message is sent for one of the subscriptions. The pattern messages name the
+// pattern they matched, so the patterns are compared as they are.
+func (s subscriptions) delivers(msg *value) bool {
+	if len(msg.elems) < 3 {
+		return false
+	}
+	switch strings.ToLower(msg.elems[0].str) {
+	case "message":
+		return s["subscribe"][msg.elems[1].str]
+	case "pmessage":
+		return s["psubscribe"][msg.elems[1].str]
+	case "smessage":
+		return s["ssubscribe"][msg.elems[1].str]
+	}
+	return false
+}
+
+// subscribeKind returns the kind of the subscriptions an unsubscribe kind removes, e.g. psubscribe for punsubscribe.
+func subscribeKind(kind string) string {
+	if strings.HasPrefix(kind, "un") {
+		return kind[2:]
+	}
+	return kind[:1] + kind[3:]
+}
+
+// expectedReplies returns the number of replies the server sends for the command. The subscription commands
+// are confirmed once per channel, and once per subscribed channel when they unsubscribe from all of them.
+func expectedReplies(cmd *value, subs subscriptions) int {
+	if !isSubscriptionCommand(cmd) {
+		return 1
+	}
+	if n := len(cmd.args()); n > 0 {
+		return n
+	}
+	kind := strings.ToLower(cmd.command())
+	if strings.Contains(kind, "unsubscribe") {
+		return max(1, len(subs[subscribeKind(kind)]))
+	}
+	return 1
+}
+
+func isSubscriptionCommand(cmd *value) bool {
+	return subscriptionKinds[strings.ToLower(cmd.command())]
+}
@@ -3,6 +3,7 @@
 package redis
 
 import (
+	"bytes"
 	"context"
 	"net"
 
@@ -27,18 +28,39 @@ func NewRedis(logger *zap.Logger) integrations.Integrations {
 	}
 }
 
-func (r *Redis) MatchType(_ context.Context, buf []byte) bool {
+func (r *Redis) MatchType(_ context.Context, buf []byte) integrations.MatchResult {
 	if len(buf) == 0 {
-		return false
+		return integrations.MatchResult{NeedBytes: 1}
 	}
 
 	// Check the first byte to determine the RESP data type
 	switch buf[0] {
-	case '+', '-', ':', '$', '*', '_', '#', ',', '(', '!', '=', '%', '~', '>':
-		return true
+	case '*':
+		// commands are sent as an array of bulk strings e.g. "*1\r\n$4\r\nPING\r\n"
+		if isRESPArrayHeader(buf) {
+			return integrations.MatchResult{Confidence: integrations.ConfidenceHigh}
+		}
+		return integrations.MatchResult{Confidence: integrations.ConfidenceLow, NeedBytes: len(buf) + 1}
+	case '+', '-', ':', '$', '_', '#', ',', '(', '!', '=', '%', '~', '>':
+		// a single byte is a weak signal, a lot of binary protocols can start with these bytes as well
+		return integrations.MatchResult{Confidence: integrations.ConfidenceLow}
 	default:
+		return integrations.MatchResult{}
+	}
+}
+
+// isRESPArrayHeader reports whether the buffer starts with a complete RESP array header followed by a bulk string.
+func isRESPArrayHeader(buf []byte) bool {
+	end := bytes.Index(buf, []byte("\r\n"))
+	if end < 2 {
 		return false
 	}
+	for _, c := range buf[1:end] {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return len(buf) > end+2 && buf[end+2] == '$'
 }
 
 func (r *Redis) RecordOutgoing(ctx context.Context, src net.Conn, dst net.Conn, mocks chan<- *models.Mock, opts models.OutgoingOptions) error {
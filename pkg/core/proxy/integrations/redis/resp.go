@@ -0,0 +1,260 @@
+//go:build linux
+
+package redis
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// maxBulkSize is the largest bulk string redis accepts
+const maxBulkSize = 512 << 20
+
+// maxElements bounds the number of elements of an aggregate, a larger count means the stream isn't RESP
+const maxElements = 1 << 24
+
+var errMalformed = errors.New("malformed RESP value")
+
+// value is a RESP2 or RESP3 value along with the bytes it was read from. The maps are kept as their keys
+// and values in turn, and the attributes sent before a value are kept in its raw bytes only.
+type value struct {
+	typ   byte
+	str   string
+	elems []*value
+	null  bool
+	raw   []byte
+}
+
+// readValue reads the next value of the stream. The inline commands, sent without the RESP framing by
+// telnet-like clients, are read as arrays of their words.
+func readValue(r *bufio.Reader) (*value, error) {
+	first, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	switch first {
+	case '+', '-', ':', '$', '*', '_', '#', ',', '(', '!', '=', '%', '~', '>', '|':
+	default:
+		if err := r.UnreadByte(); err != nil {
+			return nil, err
+		}
+		return readInline(r)
+	}
+
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+	v := &value{typ: first, raw: append([]byte{first}, line...)}
+	body := string(line[:len(line)-2])
+
+	switch first {
+	case '+', '-', ':', '#', ',', '(':
+		v.str = body
+	case '_':
+		v.null = true
+	case '$', '!', '=':
+		if body == "?" {
+			return v, readStreamedString(r, v)
+		}
+		n, err := strconv.Atoi(body)
+		if err != nil || n > maxBulkSize {
+			return nil, errMalformed
+		}
+		if n < 0 {
+			// the null bulk string of RESP2
+			v.null = true
+			return v, nil
+		}
+		data := make([]byte, n+2)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+		if !bytes.HasSuffix(data, []byte("\r\n")) {
+			return nil, errMalformed
+		}
+		v.str = string(data[:n])
+		v.raw = append(v.raw, data...)
+	case '*', '~', '>', '%', '|':
+		if body == "?" {
+			return v, readStreamedAggregate(r, v)
+		}
+		n, err := strconv.Atoi(body)
+		if err != nil || n > maxElements {
+			return nil, errMalformed
+		}
+		if n < 0 {
+			// the null array of RESP2
+			v.null = true
+			return v, nil
+		}
+		if first == '%' || first == '|' {
+			n *= 2
+		}
+		for i := 0; i < n; i++ {
+			elem, err := readValue(r)
+			if err != nil {
+				return nil, err
+			}
+			v.elems = append(v.elems, elem)
+			v.raw = append(v.raw, elem.raw...)
+		}
+	}
+
+	if first == '|' {
+		// the attribute is sent ahead of the value it describes
+		next, err := readValue(r)
+		if err != nil {
+			return nil, err
+		}
+		next.raw = append(v.raw, next.raw...)
+		return next, nil
+	}
+	return v, nil
+}
+
+// readStreamedString reads the chunks of a RESP3 string of unknown length, up to the chunk of size 0.
+func readStreamedString(r *bufio.Reader, v *value) error {
+	var sb strings.Builder
+	for {
+		first, err := r.ReadByte()
+		if err != nil {
+			return err
+		}
+		if first != ';' {
+			return errMalformed
+		}
+		line, err := readLine(r)
+		if err != nil {
+			return err
+		}
+		v.raw = append(append(v.raw, first), line...)
+		n, err := strconv.Atoi(string(line[:len(line)-2]))
+		if err != nil || n < 0 || n > maxBulkSize {
+			return errMalformed
+		}
+		if n == 0 {
+			v.str = sb.String()
+			return nil
+		}
+		data := make([]byte, n+2)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return err
+		}
+		sb.Write(data[:n])
+		v.raw = append(v.raw, data...)
+	}
+}
+
+// readStreamedAggregate reads the elements of a RESP3 aggregate of unknown length, up to its end marker.
+func readStreamedAggregate(r *bufio.Reader, v *value) error {
+	for {
+		next, err := r.Peek(1)
+		if err != nil {
+			return err
+		}
+		if next[0] == '.' {
+			line, err := readLine(r)
+			if err != nil {
+				return err
+			}
+			v.raw = append(v.raw, line...)
+			return nil
+		}
+		elem, err := readValue(r)
+		if err != nil {
+			return err
+		}
+		v.elems = append(v.elems, elem)
+		v.raw = append(v.raw, elem.raw...)
+	}
+}
+
+func readInline(r *bufio.Reader) (*value, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+	v := &value{typ: '*', raw: line}
+	for _, word := range strings.Fields(string(line)) {
+		v.elems = append(v.elems, &value{typ: '$', str: word})
+	}
+	return v, nil
+}
+
+// readLine reads a line along with its CRLF.
+func readLine(r *bufio.Reader) ([]byte, error) {
+	line, err := r.ReadBytes('\n')
+	if err != nil {
+		if err == io.EOF && len(line) > 0 {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	if len(line) < 2 || line[len(line)-2] != '\r' {
+		return nil, errMalformed
+	}
+	return line, nil
+}
+
+// command returns the upper cased name of the command sent as the value.
+func (v *value) command() string {
+	if v.typ != '*' || len(v.elems) == 0 {
+		return ""
+	}
+	return strings.ToUpper(v.elems[0].str)
+}
+
+// args returns the arguments of the command sent as the value.
+func (v *value) args() []string {
+	if v.typ != '*' || len(v.elems) < 2 {
+		return nil
+	}
+	args := make([]string, 0, len(v.elems)-1)
+	for _, elem := range v.elems[1:] {
+		args = append(args, elem.str)
+	}
+	return args
+}
+
+// encode returns the RESP bytes of the value, for the values changed after they were read. The attributes
+// aren't kept, and the streamed values are encoded with their length.
+func (v *value) encode() []byte {
+	var buf []byte
+	switch v.typ {
+	case '_':
+		return []byte("_\r\n")
+	case '$', '!', '=':
+		if v.null {
+			return []byte("$-1\r\n")
+		}
+		buf = append(buf, v.typ)
+		buf = strconv.AppendInt(buf, int64(len(v.str)), 10)
+		buf = append(buf, "\r\n"...)
+		buf = append(buf, v.str...)
+		return append(buf, "\r\n"...)
+	case '*', '~', '>', '%', '|':
+		if v.null {
+			return []byte("*-1\r\n")
+		}
+		n := len(v.elems)
+		if v.typ == '%' || v.typ == '|' {
+			n /= 2
+		}
+		buf = append(buf, v.typ)
+		buf = strconv.AppendInt(buf, int64(n), 10)
+		buf = append(buf, "\r\n"...)
+		for _, elem := range v.elems {
+			buf = append(buf, elem.encode()...)
+		}
+		return buf
+	default:
+		buf = append(buf, v.typ)
+		buf = append(buf, v.str...)
+		return append(buf, "\r\n"...)
+	}
+}
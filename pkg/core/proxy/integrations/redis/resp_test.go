@@ -0,0 +1,125 @@
+//go:build linux
+
+package redis
+
+import (
+	"bufio"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func readString(t *testing.T, s string) *value {
+	t.Helper()
+	v, err := readValue(bufio.NewReader(strings.NewReader(s)))
+	if err != nil {
+		t.Fatalf("readValue(%q) failed: %v", s, err)
+	}
+	if string(v.raw) != s {
+		t.Errorf("readValue(%q) raw = %q", s, v.raw)
+	}
+	return v
+}
+
+func TestValueRoundTrip(t *testing.T) {
+	tests := []string{
+		"+OK\r\n",
+		"-ERR unknown command\r\n",
+		":42\r\n",
+		"$5\r\nhello\r\n",
+		"$0\r\n\r\n",
+		"$-1\r\n",
+		"*-1\r\n",
+		"*2\r\n$3\r\nGET\r\n$3\r\nkey\r\n",
+		"_\r\n",
+		"#t\r\n",
+		",3.14\r\n",
+		"(3492890328409238509324850943850943825024385\r\n",
+		"!21\r\nSYNTAX invalid syntax\r\n",
+		"=15\r\ntxt:Some string\r\n",
+		"%2\r\n+server\r\n$5\r\nredis\r\n+proto\r\n:3\r\n",
+		"~2\r\n:1\r\n:2\r\n",
+		">3\r\n$7\r\nmessage\r\n$4\r\nnews\r\n$2\r\nhi\r\n",
+	}
+	for _, raw := range tests {
+		if got := string(readString(t, raw).encode()); got != raw {
+			t.Errorf("encode() = %q, want %q", got, raw)
+		}
+	}
+}
+
+func TestReadValueResp3(t *testing.T) {
+	t.Run("streamed string", func(t *testing.T) {
+		v := readString(t, "$?\r\n;4\r\nHell\r\n;2\r\no!\r\n;0\r\n")
+		if v.str != "Hello!" || string(v.encode()) != "$6\r\nHello!\r\n" {
+			t.Errorf("the streamed string = %q encoded as %q", v.str, v.encode())
+		}
+	})
+	t.Run("streamed aggregate", func(t *testing.T) {
+		v := readString(t, "*?\r\n:1\r\n:2\r\n.\r\n")
+		if len(v.elems) != 2 || string(v.encode()) != "*2\r\n:1\r\n:2\r\n" {
+			t.Errorf("the streamed aggregate = %q", v.encode())
+		}
+	})
+	t.Run("attribute", func(t *testing.T) {
+		v := readString(t, "|1\r\n+ttl\r\n:3600\r\n$3\r\nbar\r\n")
+		if v.typ != '$' || v.str != "bar" {
+			t.Errorf("the value after the attribute = %c %q", v.typ, v.str)
+		}
+	})
+	t.Run("inline command", func(t *testing.T) {
+		v := readString(t, "set key  value\r\n")
+		if v.command() != "SET" || !reflect.DeepEqual(v.args(), []string{"key", "value"}) {
+			t.Errorf("the inline command = %s %q", v.command(), v.args())
+		}
+	})
+}
+
+func TestReadValueMalformed(t *testing.T) {
+	tests := []string{
+		"$5\r\nhello!!",
+		"$x\r\n",
+		"$536870913\r\n",
+		"*16777217\r\n",
+		"+OK\n",
+		"$?\r\n:4\r\n",
+		"*2\r\n:1\r\n",
+	}
+	for _, raw := range tests {
+		if v, err := readValue(bufio.NewReader(strings.NewReader(raw))); err == nil {
+			t.Errorf("readValue(%q) = %q, want an error", raw, v.raw)
+		}
+	}
+}
+
+func TestSubscriptions(t *testing.T) {
+	subs := subscriptions{}
+	subscribe := readString(t, "*3\r\n$9\r\nSUBSCRIBE\r\n$4\r\nnews\r\n$6\r\nsports\r\n")
+	if n := expectedReplies(subscribe, subs); n != 2 {
+		t.Errorf("expectedReplies() of the subscribe = %d, want 2", n)
+	}
+	subs.apply(readString(t, "*3\r\n$9\r\nsubscribe\r\n$4\r\nnews\r\n:1\r\n"))
+	subs.apply(readString(t, ">3\r\n$10\r\npsubscribe\r\n$2\r\nn*\r\n:2\r\n"))
+
+	news := readString(t, ">3\r\n$7\r\nmessage\r\n$4\r\nnews\r\n$2\r\nhi\r\n")
+	if kind := pushKind(news, false); kind != "message" || !isDeliveryKind(kind) || !subs.delivers(news) {
+		t.Errorf("the message of the subscribed channel has the kind %q", kind)
+	}
+	pattern := readString(t, "*4\r\n$8\r\npmessage\r\n$2\r\nn*\r\n$3\r\nnow\r\n$2\r\nhi\r\n")
+	if pushKind(pattern, false) != "" || pushKind(pattern, true) != "pmessage" || !subs.delivers(pattern) {
+		t.Error("the pattern message isn't delivered on the subscribed conn")
+	}
+	if other := readString(t, ">3\r\n$7\r\nmessage\r\n$5\r\nother\r\n$2\r\nhi\r\n"); subs.delivers(other) {
+		t.Error("the message of another channel is delivered")
+	}
+
+	unsubscribe := readString(t, "*1\r\n$12\r\nPUNSUBSCRIBE\r\n")
+	if n := expectedReplies(unsubscribe, subs); n != 1 {
+		t.Errorf("expectedReplies() of the punsubscribe = %d, want 1", n)
+	}
+	subs.apply(readString(t, ">3\r\n$12\r\npunsubscribe\r\n$2\r\nn*\r\n:1\r\n"))
+	subs.apply(readString(t, "*3\r\n$11\r\nunsubscribe\r\n$4\r\nnews\r\n:0\r\n"))
+	if subs.active() {
+		t.Errorf("the subscriptions are active after the unsubscribes: %v", subs)
+	}
+}
@@ -4,6 +4,7 @@
 package scram
 
 import (
+	"crypto/hmac"
 	"encoding/base64"
 	"errors"
 	"fmt"
@@ -20,6 +21,49 @@ import (
 // GenerateServerFinalMessage generates the server's final message (i.e., the server proof)
 // for SCRAM authentication, using a default password and given authentication message, mechanism, salt, iteration count.
 func GenerateServerFinalMessage(authMessage, mechanism, password, salt string, itr int, logger *zap.Logger) (string, error) {
+	hashGen, saltedPassword, err := saltPassword(authMessage, mechanism, password, salt, itr, logger)
+	if err != nil {
+		return "", err
+	}
+
+	// Compute the server key using HMAC with the derived salted password and the string "Server Key".
+	serverKey := computeHMAC(hashGen, saltedPassword, []byte("Server Key"))
+	logger.Debug("generating the server using the salted password", zap.Any("server key", serverKey))
+
+	// Compute the server signature (server proof) using HMAC with the server key and the provided authMessage.
+	serverSignature := computeHMAC(hashGen, serverKey, []byte(authMessage))
+	logger.Debug("the new server proof for the second auth request", zap.Any("server signature", base64.StdEncoding.EncodeToString(serverSignature)), zap.Any("derived from auth message", authMessage))
+
+	return base64.StdEncoding.EncodeToString(serverSignature), nil
+}
+
+// VerifyClientProof reports whether the proof sent by the client in its final message was computed from the
+// password, i.e. whether the client authenticates with the password the server proof is generated from.
+func VerifyClientProof(authMessage, mechanism, password, salt string, itr int, proof []byte, logger *zap.Logger) (bool, error) {
+	hashGen, saltedPassword, err := saltPassword(authMessage, mechanism, password, salt, itr, logger)
+	if err != nil {
+		return false, err
+	}
+
+	// The proof is the client key xored with the client signature, the signature of the auth message by the
+	// stored key, which is the hash of the client key.
+	clientKey := computeHMAC(hashGen, saltedPassword, []byte("Client Key"))
+	h := hashGen()
+	h.Write(clientKey)
+	storedKey := h.Sum(nil)
+	clientSignature := computeHMAC(hashGen, storedKey, []byte(authMessage))
+	if len(proof) != len(clientSignature) {
+		return false, nil
+	}
+	expected := make([]byte, len(clientKey))
+	for i := range clientKey {
+		expected[i] = clientKey[i] ^ clientSignature[i]
+	}
+	return hmac.Equal(expected, proof), nil
+}
+
+// saltPassword returns the hash function of the mechanism and the salted password of the user of the auth message.
+func saltPassword(authMessage, mechanism, password, salt string, itr int, logger *zap.Logger) (scram.HashGeneratorFcn, []byte, error) {
 	var (
 		// Declare a variable to hold the hash generation function based on the chosen mechanism.
 		hashGen scram.HashGeneratorFcn
@@ -29,7 +73,7 @@ func GenerateServerFinalMessage(authMessage, mechanism, password, salt string, i
 
 	username, err := extractUsername(authMessage)
 	if err != nil {
-		return "", err
+		return nil, nil, err
 	}
 
 	// Switch based on the provided mechanism to determine the hash function to be used.
@@ -41,11 +85,11 @@ func GenerateServerFinalMessage(authMessage, mechanism, password, salt string, i
 		hashGen = scram.SHA256
 		passwordDigest, err = stringprep.SASLprep.Prepare(password)
 		if err != nil {
-			return "", fmt.Errorf("error SASLprepping password for SCRAM-SHA-256 with password: %s. error: %v", password, err.Error())
+			return nil, nil, fmt.Errorf("error SASLprepping password for SCRAM-SHA-256 with password: %s. error: %v", password, err.Error())
 		}
 	default:
 		// If the mechanism isn't supported, return an error.
-		return "", errors.New("unsupported authentication mechanism by keploy")
+		return nil, nil, errors.New("unsupported authentication mechanism by keploy")
 	}
 
 	// Get the hash function instance based on the determined generator.
@@ -56,16 +100,7 @@ func GenerateServerFinalMessage(authMessage, mechanism, password, salt string, i
 	logger.Debug("the input for generating the salted password", zap.Any("normalised password", passwordDigest), zap.Any("salt", salt), zap.Any("iteration", itr), zap.Any("hash size", h.Size()), zap.Any("mechanism", mechanism))
 	saltedPassword := pbkdf2.Key([]byte(passwordDigest), []byte(salt), itr, h.Size(), hashGen)
 	logger.Debug("after generating the salted password", zap.Any("salted password", saltedPassword))
-
-	// Compute the server key using HMAC with the derived salted password and the string "Server Key".
-	serverKey := computeHMAC(hashGen, saltedPassword, []byte("Server Key"))
-	logger.Debug("generating the server using the salted password", zap.Any("server key", serverKey))
-
-	// Compute the server signature (server proof) using HMAC with the server key and the provided authMessage.
-	serverSignature := computeHMAC(hashGen, serverKey, []byte(authMessage))
-	logger.Debug("the new server proof for the second auth request", zap.Any("server signature", base64.StdEncoding.EncodeToString(serverSignature)), zap.Any("derived from auth message", authMessage))
-
-	return base64.StdEncoding.EncodeToString(serverSignature), nil
+	return hashGen, saltedPassword, nil
 }
 
 // GenerateServerFirstMessage generates the server's first response message for SCRAM authentication.
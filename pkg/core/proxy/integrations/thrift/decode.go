@@ -0,0 +1,92 @@
+//go:build linux
+
+package thrift
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations/util"
+	pUtil "go.keploy.io/server/v2/pkg/core/proxy/util"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+// decodeThrift answers the calls of the client from the mocks, the recorded replies are sent with the
+// sequence id of the call they answer.
+func decodeThrift(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientConn net.Conn, _ *integrations.ConditionalDstCfg, mockDb integrations.MockMemDb, _ models.OutgoingOptions) error {
+	logger.Debug("Into the thrift parser in test mode")
+	errCh := make(chan error, 1)
+
+	go func() {
+		defer pUtil.Recover(logger, clientConn, nil)
+		defer close(errCh)
+		client := &stream{r: io.MultiReader(bytes.NewReader(reqBuf), clientConn)}
+		for {
+			call, err := client.next()
+			if err != nil {
+				if err != io.EOF {
+					utils.LogError(logger, err, "failed to read the thrift message from the client")
+				}
+				errCh <- err
+				return
+			}
+
+			mock, err := match(ctx, call, mockDb)
+			if err != nil {
+				utils.LogError(logger, err, "error while matching thrift mocks")
+				errCh <- err
+				return
+			}
+			if mock == nil {
+				if call.typ == typeOneway {
+					logger.Debug("no thrift mock found for the oneway call", zap.String("method", call.method))
+					continue
+				}
+				err := fmt.Errorf("no thrift mock found for the call of %s", call.method)
+				utils.LogError(logger, err, "failed to mock the thrift call", zap.String("args", call.bodyJSON()))
+				errCh <- err
+				return
+			}
+
+			for _, resp := range mock.Spec.ThriftResponses {
+				raw, err := util.DecodeBase64(resp.Raw)
+				if err != nil {
+					utils.LogError(logger, err, "failed to decode the base64 thrift reply")
+					errCh <- err
+					return
+				}
+				reply, _, err := parseMessage(raw)
+				if err != nil {
+					utils.LogError(logger, err, "failed to parse the recorded thrift reply")
+					errCh <- err
+					return
+				}
+				_, err = clientConn.Write(reply.withSeqID(call.seqID))
+				if err != nil {
+					if ctx.Err() != nil {
+						return
+					}
+					utils.LogError(logger, err, "failed to write the thrift reply to the client application")
+					errCh <- err
+					return
+				}
+			}
+		}
+	}()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-errCh:
+		if err == io.EOF {
+			return nil
+		}
+		return err
+	}
+}
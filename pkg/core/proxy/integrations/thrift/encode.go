@@ -0,0 +1,160 @@
+//go:build linux
+
+package thrift
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	pUtil "go.keploy.io/server/v2/pkg/core/proxy/util"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+// pendingCall is a call forwarded to the server which is waiting for its reply.
+type pendingCall struct {
+	call             *message
+	reqTimestampMock time.Time
+}
+
+// encodeThrift forwards the messages between the client and the server and records every call with its
+// reply as a mock. The replies are paired with the calls by their sequence id, and in the order of the
+// calls for the clients which reuse the sequence ids.
+func encodeThrift(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientConn, destConn net.Conn, mocks chan<- *models.Mock, _ models.OutgoingOptions) error {
+	g, ok := ctx.Value(models.ErrGroupKey).(*errgroup.Group)
+	if !ok {
+		return errors.New("failed to get the error group from the context")
+	}
+	connID, _ := ctx.Value(models.ClientConnectionIDKey).(string)
+
+	var (
+		mu      sync.Mutex
+		pending []pendingCall
+	)
+	errCh := make(chan error, 2)
+
+	// Forward the calls from the client to the destination server
+	g.Go(func() error {
+		defer pUtil.Recover(logger, clientConn, destConn)
+		client := &stream{r: io.MultiReader(bytes.NewReader(reqBuf), clientConn)}
+		for {
+			call, err := client.next()
+			if err != nil {
+				if err != io.EOF {
+					utils.LogError(logger, err, "failed to read the thrift message from the client")
+				}
+				errCh <- err
+				return nil
+			}
+			logger.Debug("thrift call", zap.String("method", call.method), zap.String("type", typeName(call.typ)), zap.Int32("seqID", call.seqID))
+
+			reqTimestampMock := time.Now()
+			if call.typ == typeCall {
+				// register the call before forwarding it, so that the reply can't arrive first
+				mu.Lock()
+				pending = append(pending, pendingCall{call: call, reqTimestampMock: reqTimestampMock})
+				mu.Unlock()
+			}
+
+			_, err = destConn.Write(call.raw)
+			if err != nil {
+				utils.LogError(logger, err, "failed to write the thrift message to the destination server")
+				errCh <- err
+				return nil
+			}
+
+			if call.typ == typeOneway {
+				saveMock(call, nil, reqTimestampMock, time.Now(), connID, mocks)
+			}
+		}
+	})
+
+	// Forward the replies from the destination server to the client
+	g.Go(func() error {
+		defer pUtil.Recover(logger, clientConn, destConn)
+		server := &stream{r: destConn}
+		for {
+			reply, err := server.next()
+			if err != nil {
+				if err != io.EOF {
+					utils.LogError(logger, err, "failed to read the thrift message from the destination server")
+				}
+				errCh <- err
+				return nil
+			}
+
+			_, err = clientConn.Write(reply.raw)
+			if err != nil {
+				utils.LogError(logger, err, "failed to write the thrift message to the client")
+				errCh <- err
+				return nil
+			}
+			resTimestampMock := time.Now()
+
+			mu.Lock()
+			idx := -1
+			for i, p := range pending {
+				if p.call.seqID == reply.seqID {
+					idx = i
+					break
+				}
+			}
+			if idx == -1 && len(pending) == 1 {
+				// e.g. the exceptions for the unknown methods, some servers don't echo their sequence id
+				idx = 0
+			}
+			var p pendingCall
+			if idx != -1 {
+				p = pending[idx]
+				pending = append(pending[:idx], pending[idx+1:]...)
+			}
+			mu.Unlock()
+			if idx == -1 {
+				logger.Debug("no pending thrift call for the reply", zap.String("method", reply.method), zap.Int32("seqID", reply.seqID))
+				continue
+			}
+			saveMock(p.call, reply, p.reqTimestampMock, resTimestampMock, connID, mocks)
+		}
+	})
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-errCh:
+		if err == io.EOF {
+			return nil
+		}
+		return err
+	}
+}
+
+func saveMock(call, reply *message, reqTimestampMock, resTimestampMock time.Time, connID string, mocks chan<- *models.Mock) {
+	var responses []models.ThriftMessage
+	if reply != nil {
+		responses = append(responses, reply.model())
+	}
+
+	mocks <- &models.Mock{
+		Version: models.GetVersion(),
+		Name:    "mocks",
+		Kind:    models.Thrift,
+		Spec: models.MockSpec{
+			Metadata: map[string]string{
+				"method": call.method,
+			},
+			ThriftRequests:   []models.ThriftMessage{call.model()},
+			ThriftResponses:  responses,
+			ReqTimestampMock: reqTimestampMock,
+			ResTimestampMock: resTimestampMock,
+		},
+		ConnectionID: connID,
+	}
+}
@@ -0,0 +1,80 @@
+//go:build linux
+
+package thrift
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations/util"
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+// match finds the mock of the call among the mocks of the same method. The arguments are compared as
+// decoded structs, so the sequence ids and the order of the fields, the set elements and the map entries
+// don't matter. The mocks recorded during the current test case are preferred, and the matched mock is
+// moved behind the others so that the repeated calls get the recorded replies in order.
+func match(ctx context.Context, call *message, mockDb integrations.MockMemDb) (*models.Mock, error) {
+	args := call.bodyJSON()
+	for {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		mocks, err := mockDb.GetUnFilteredMocksByKey(models.Thrift, "")
+		if err != nil {
+			return nil, fmt.Errorf("error while getting unfiltered mocks %v", err)
+		}
+
+		var filteredMocks, unfilteredMocks []*models.Mock
+		for _, mock := range mocks {
+			if mock.Kind != models.Thrift || len(mock.Spec.ThriftRequests) == 0 {
+				continue
+			}
+			recorded := mock.Spec.ThriftRequests[0]
+			if recorded.Method != call.method || recorded.Type != typeName(call.typ) {
+				continue
+			}
+			if mock.TestModeInfo.IsFiltered {
+				filteredMocks = append(filteredMocks, mock)
+			} else {
+				unfilteredMocks = append(unfilteredMocks, mock)
+			}
+		}
+
+		mock := closest(filteredMocks, args)
+		if mock == nil {
+			mock = closest(unfilteredMocks, args)
+		}
+		if mock == nil {
+			return nil, nil
+		}
+
+		originalMock := *mock
+		mock.TestModeInfo.IsFiltered = false
+		mock.TestModeInfo.SortOrder = math.MaxInt64
+		if !mockDb.UpdateUnFilteredMock(&originalMock, mock) {
+			// the mock was used by another call in the meantime
+			continue
+		}
+		return mock, nil
+	}
+}
+
+// closest returns the first mock with the same arguments, or else the one with the most similar arguments.
+func closest(mocks []*models.Mock, args string) *models.Mock {
+	var best *models.Mock
+	bestSim := -1.0
+	for _, mock := range mocks {
+		recorded := mock.Spec.ThriftRequests[0].Body
+		if recorded == args {
+			return mock
+		}
+		k := util.AdaptiveK(len(args), 3, 8, 5)
+		if sim := util.JaccardSimilarity(util.CreateShingles([]byte(recorded), k), util.CreateShingles([]byte(args), k)); sim > bestSim {
+			best, bestSim = mock, sim
+		}
+	}
+	return best
+}
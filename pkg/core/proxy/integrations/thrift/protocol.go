@@ -0,0 +1,600 @@
+//go:build linux
+
+package thrift
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"unicode/utf8"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations/util"
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+const (
+	protocolBinary  = "binary"
+	protocolCompact = "compact"
+)
+
+// message types
+const (
+	typeCall      byte = 1
+	typeReply     byte = 2
+	typeException byte = 3
+	typeOneway    byte = 4
+)
+
+var typeNames = map[byte]string{
+	typeCall:      "call",
+	typeReply:     "reply",
+	typeException: "exception",
+	typeOneway:    "oneway",
+}
+
+// field types of the binary protocol
+const (
+	ttStop   byte = 0
+	ttBool   byte = 2
+	ttByte   byte = 3
+	ttDouble byte = 4
+	ttI16    byte = 6
+	ttI32    byte = 8
+	ttI64    byte = 10
+	ttString byte = 11
+	ttStruct byte = 12
+	ttMap    byte = 13
+	ttSet    byte = 14
+	ttList   byte = 15
+	ttUUID   byte = 16
+)
+
+// compactTypes maps the field types of the compact protocol to the ones of the binary protocol
+var compactTypes = map[byte]byte{
+	1:  ttBool, // true
+	2:  ttBool, // false
+	3:  ttByte,
+	4:  ttI16,
+	5:  ttI32,
+	6:  ttI64,
+	7:  ttDouble,
+	8:  ttString,
+	9:  ttList,
+	10: ttSet,
+	11: ttMap,
+	12: ttStruct,
+	13: ttUUID,
+}
+
+const (
+	binaryVersion1   = 0x80010000
+	binaryVersionMsk = 0xffff0000
+	compactID        = 0x82
+	compactVersion   = 1
+	// maxMessageSize bounds the size of the messages buffered from the connections
+	maxMessageSize = 64 * 1024 * 1024
+	// maxDepth bounds the nesting of the decoded structs
+	maxDepth = 64
+)
+
+var (
+	errShortBuffer = errors.New("thrift message is incomplete")
+	errMalformed   = errors.New("malformed thrift message")
+)
+
+// message is a thrift message along with its decoded header and struct.
+type message struct {
+	protocol string
+	framed   bool
+	// strict is unset for the binary messages without the version in the header
+	strict bool
+	method string
+	typ    byte
+	seqID  int32
+	body   interface{}
+	// payload is the message without its frame, the struct starts at bodyStart
+	payload   []byte
+	bodyStart int
+	raw       []byte
+}
+
+func typeName(t byte) string {
+	if name, ok := typeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("type%d", t)
+}
+
+func (m *message) model() models.ThriftMessage {
+	return models.ThriftMessage{
+		Method:   m.method,
+		Type:     typeName(m.typ),
+		SeqID:    m.seqID,
+		Protocol: m.protocol,
+		Framed:   m.framed,
+		Body:     m.bodyJSON(),
+		Raw:      util.EncodeBase64(m.raw),
+	}
+}
+
+// bodyJSON returns the struct of the message as json, the fields are keyed by their ids.
+func (m *message) bodyJSON() string {
+	data, err := json.Marshal(m.body)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// parseMessage parses the first message of the buffer and returns it with its size. It returns errShortBuffer
+// if the buffer doesn't hold the whole message yet.
+func parseMessage(buf []byte) (*message, int, error) {
+	if len(buf) == 0 {
+		return nil, 0, errShortBuffer
+	}
+	// the framed and the binary messages are longer than 8 bytes, the compact ones may not be
+	if buf[0] != compactID && len(buf) < 8 {
+		return nil, 0, errShortBuffer
+	}
+	if buf[0] == compactID || binary.BigEndian.Uint32(buf)&binaryVersionMsk == binaryVersion1 {
+		m, err := parsePayload(buf)
+		if err != nil {
+			return nil, 0, err
+		}
+		m.raw = append([]byte(nil), m.payload...)
+		m.payload = m.raw
+		return m, len(m.raw), nil
+	}
+
+	// the framed transport prefixes the messages with their size
+	if size := int(int32(binary.BigEndian.Uint32(buf))); isFrame(buf, size) {
+		if len(buf) < 4+size {
+			return nil, 0, errShortBuffer
+		}
+		m, err := parsePayload(buf[4 : 4+size])
+		if err != nil {
+			if err == errShortBuffer {
+				// the frame is complete, so the message can't be
+				return nil, 0, errMalformed
+			}
+			return nil, 0, err
+		}
+		m.framed = true
+		m.raw = append([]byte(nil), buf[:4+size]...)
+		m.payload = m.raw[4:]
+		return m, len(m.raw), nil
+	}
+
+	// the old binary messages start with the method name
+	m, err := parsePayload(buf)
+	if err != nil {
+		return nil, 0, err
+	}
+	m.raw = append([]byte(nil), m.payload...)
+	m.payload = m.raw
+	return m, len(m.raw), nil
+}
+
+// isFrame reports whether the buffer of at least 8 bytes starts with a frame of the given size, the payload
+// of a frame starts with a versioned message header.
+func isFrame(buf []byte, size int) bool {
+	if size <= 0 || size > maxMessageSize {
+		return false
+	}
+	return buf[4] == compactID || binary.BigEndian.Uint32(buf[4:])&binaryVersionMsk == binaryVersion1
+}
+
+// parsePayload parses an unframed message, the payload of the returned message is cut at its end.
+func parsePayload(buf []byte) (*message, error) {
+	if len(buf) == 0 || (buf[0] != compactID && len(buf) < 4) {
+		return nil, errShortBuffer
+	}
+	d := &decoder{buf: buf}
+	m := &message{}
+	switch {
+	case buf[0] == compactID:
+		m.protocol = protocolCompact
+		d.compact = true
+		d.next(1)
+		b := d.byte()
+		if d.err != nil {
+			return nil, d.err
+		}
+		if b&0x1f != compactVersion {
+			return nil, errMalformed
+		}
+		m.typ = b >> 5
+		m.seqID = int32(d.uvarint())
+		m.method = d.string()
+	case binary.BigEndian.Uint32(buf)&binaryVersionMsk == binaryVersion1:
+		m.protocol = protocolBinary
+		m.strict = true
+		m.typ = byte(d.i32())
+		m.method = d.string()
+		m.seqID = d.i32()
+	default:
+		m.protocol = protocolBinary
+		n := int(int32(binary.BigEndian.Uint32(buf)))
+		if n <= 0 || n > 1024 {
+			return nil, errMalformed
+		}
+		m.method = d.string()
+		if d.err == nil && !utf8.ValidString(m.method) {
+			return nil, errMalformed
+		}
+		m.typ = d.byte()
+		m.seqID = d.i32()
+	}
+	if d.err != nil {
+		return nil, d.err
+	}
+	if _, ok := typeNames[m.typ]; !ok {
+		return nil, errMalformed
+	}
+	m.bodyStart = d.off
+	m.body = d.readStruct(0)
+	if d.err != nil {
+		return nil, d.err
+	}
+	m.payload = buf[:d.off]
+	return m, nil
+}
+
+// stream reads the messages from a connection, the unframed messages have no size so the bytes are buffered
+// until a whole message is decoded.
+type stream struct {
+	r   io.Reader
+	buf []byte
+}
+
+func (s *stream) next() (*message, error) {
+	chunk := make([]byte, 32*1024)
+	for {
+		if len(s.buf) > 0 {
+			m, n, err := parseMessage(s.buf)
+			if err == nil {
+				s.buf = s.buf[n:]
+				return m, nil
+			}
+			if err != errShortBuffer {
+				return nil, err
+			}
+			if len(s.buf) > maxMessageSize {
+				return nil, errMalformed
+			}
+		}
+		n, err := s.r.Read(chunk)
+		s.buf = append(s.buf, chunk[:n]...)
+		if err != nil && n == 0 {
+			if err == io.EOF && len(s.buf) > 0 {
+				return nil, io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+	}
+}
+
+// withSeqID returns the raw message with its sequence id replaced.
+func (m *message) withSeqID(seqID int32) []byte {
+	var payload []byte
+	switch {
+	case m.protocol == protocolCompact:
+		payload = []byte{compactID, m.typ<<5 | compactVersion}
+		payload = binary.AppendUvarint(payload, uint64(uint32(seqID)))
+		payload = binary.AppendUvarint(payload, uint64(len(m.method)))
+		payload = append(payload, m.method...)
+	case m.strict:
+		payload = binary.BigEndian.AppendUint32(nil, binaryVersion1|uint32(m.typ))
+		payload = binary.BigEndian.AppendUint32(payload, uint32(len(m.method)))
+		payload = append(payload, m.method...)
+		payload = binary.BigEndian.AppendUint32(payload, uint32(seqID))
+	default:
+		payload = binary.BigEndian.AppendUint32(nil, uint32(len(m.method)))
+		payload = append(payload, m.method...)
+		payload = append(payload, m.typ)
+		payload = binary.BigEndian.AppendUint32(payload, uint32(seqID))
+	}
+	payload = append(payload, m.payload[m.bodyStart:]...)
+	if !m.framed {
+		return payload
+	}
+	return append(binary.BigEndian.AppendUint32(nil, uint32(len(payload))), payload...)
+}
+
+// decoder reads the values of a message. The first error stops the decoding and is kept in err.
+type decoder struct {
+	buf     []byte
+	off     int
+	compact bool
+	err     error
+}
+
+func (d *decoder) next(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n < 0 || n > maxMessageSize {
+		d.err = errMalformed
+		return nil
+	}
+	if d.off+n > len(d.buf) {
+		d.err = errShortBuffer
+		return nil
+	}
+	b := d.buf[d.off : d.off+n]
+	d.off += n
+	return b
+}
+
+func (d *decoder) byte() byte {
+	b := d.next(1)
+	if b == nil {
+		return 0
+	}
+	return b[0]
+}
+
+func (d *decoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(d.buf[d.off:])
+	switch {
+	case n == 0:
+		d.err = errShortBuffer
+		return 0
+	case n < 0:
+		d.err = errMalformed
+		return 0
+	}
+	d.off += n
+	return v
+}
+
+// varint reads a zigzag encoded integer of the compact protocol.
+func (d *decoder) varint() int64 {
+	v := d.uvarint()
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+func (d *decoder) i16() int16 {
+	if d.compact {
+		return int16(d.varint())
+	}
+	b := d.next(2)
+	if b == nil {
+		return 0
+	}
+	return int16(binary.BigEndian.Uint16(b))
+}
+
+func (d *decoder) i32() int32 {
+	if d.compact {
+		return int32(d.varint())
+	}
+	b := d.next(4)
+	if b == nil {
+		return 0
+	}
+	return int32(binary.BigEndian.Uint32(b))
+}
+
+func (d *decoder) i64() int64 {
+	if d.compact {
+		return d.varint()
+	}
+	b := d.next(8)
+	if b == nil {
+		return 0
+	}
+	return int64(binary.BigEndian.Uint64(b))
+}
+
+func (d *decoder) double() float64 {
+	b := d.next(8)
+	if b == nil {
+		return 0
+	}
+	if d.compact {
+		return math.Float64frombits(binary.LittleEndian.Uint64(b))
+	}
+	return math.Float64frombits(binary.BigEndian.Uint64(b))
+}
+
+func (d *decoder) bytes() []byte {
+	var n int
+	if d.compact {
+		n = int(d.uvarint())
+	} else {
+		n = int(d.i32())
+	}
+	return d.next(n)
+}
+
+func (d *decoder) string() string {
+	return string(d.bytes())
+}
+
+// size reads the size of a collection.
+func (d *decoder) size() int {
+	var n int
+	if d.compact {
+		n = int(d.uvarint())
+	} else {
+		n = int(d.i32())
+	}
+	if n < 0 || n > len(d.buf) {
+		// every element takes at least a byte
+		if d.err == nil {
+			d.err = errMalformed
+		}
+		return 0
+	}
+	return n
+}
+
+// readStruct reads a struct into a map keyed by the field ids.
+func (d *decoder) readStruct(depth int) map[string]interface{} {
+	if depth > maxDepth {
+		d.err = errMalformed
+		return nil
+	}
+	fields := make(map[string]interface{})
+	var lastID int16
+	for d.err == nil {
+		header := d.byte()
+		if header == ttStop || d.err != nil {
+			break
+		}
+		var typ byte
+		var id int16
+		var value interface{}
+		if d.compact {
+			var ok bool
+			if typ, ok = compactTypes[header&0x0f]; !ok {
+				d.err = errMalformed
+				break
+			}
+			if delta := int16(header >> 4); delta != 0 {
+				id = lastID + delta
+			} else {
+				id = d.i16()
+			}
+			lastID = id
+			if typ == ttBool {
+				// the value of the bool fields is in their type
+				value = header&0x0f == 1
+			}
+		} else {
+			typ = header
+			id = d.i16()
+		}
+		if value == nil {
+			value = d.readValue(typ, depth+1)
+		}
+		fields[strconv.Itoa(int(id))] = value
+	}
+	return fields
+}
+
+func (d *decoder) readValue(typ byte, depth int) interface{} {
+	switch typ {
+	case ttBool:
+		b := d.byte()
+		if d.compact {
+			return b == 1
+		}
+		return b != 0
+	case ttByte:
+		return int8(d.byte())
+	case ttI16:
+		return d.i16()
+	case ttI32:
+		return d.i32()
+	case ttI64:
+		return d.i64()
+	case ttDouble:
+		return d.double()
+	case ttString:
+		b := d.bytes()
+		if utf8.Valid(b) {
+			return string(b)
+		}
+		// encoded as base64 by json
+		return append([]byte(nil), b...)
+	case ttUUID:
+		b := d.next(16)
+		if b == nil {
+			return nil
+		}
+		return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+	case ttStruct:
+		return d.readStruct(depth)
+	case ttList, ttSet:
+		elemType, n := d.listHeader()
+		elems := make([]interface{}, 0, n)
+		for i := 0; i < n && d.err == nil; i++ {
+			elems = append(elems, d.readValue(elemType, depth+1))
+		}
+		if typ == ttSet {
+			sortCanonical(elems)
+		}
+		return elems
+	case ttMap:
+		keyType, valueType, n := d.mapHeader()
+		entries := make([]interface{}, 0, n)
+		for i := 0; i < n && d.err == nil; i++ {
+			k := d.readValue(keyType, depth+1)
+			v := d.readValue(valueType, depth+1)
+			entries = append(entries, []interface{}{k, v})
+		}
+		sortCanonical(entries)
+		return entries
+	}
+	d.err = errMalformed
+	return nil
+}
+
+func (d *decoder) listHeader() (byte, int) {
+	if !d.compact {
+		elemType := d.byte()
+		return elemType, d.size()
+	}
+	header := d.byte()
+	elemType, ok := compactTypes[header&0x0f]
+	if !ok && d.err == nil {
+		d.err = errMalformed
+	}
+	n := int(header >> 4)
+	if n == 15 {
+		n = d.size()
+	}
+	return elemType, n
+}
+
+func (d *decoder) mapHeader() (byte, byte, int) {
+	if !d.compact {
+		keyType := d.byte()
+		valueType := d.byte()
+		return keyType, valueType, d.size()
+	}
+	n := d.size()
+	if n == 0 {
+		return 0, 0, 0
+	}
+	types := d.byte()
+	keyType, ok1 := compactTypes[types>>4]
+	valueType, ok2 := compactTypes[types&0x0f]
+	if (!ok1 || !ok2) && d.err == nil {
+		d.err = errMalformed
+	}
+	return keyType, valueType, n
+}
+
+// sortCanonical sorts the elements of the sets and the entries of the maps by their json, their order on
+// the wire isn't meaningful.
+func sortCanonical(values []interface{}) {
+	keys := make([]string, len(values))
+	for i, v := range values {
+		data, _ := json.Marshal(v)
+		keys[i] = string(data)
+	}
+	sort.Sort(byKey{values: values, keys: keys})
+}
+
+type byKey struct {
+	values []interface{}
+	keys   []string
+}
+
+func (b byKey) Len() int           { return len(b.values) }
+func (b byKey) Less(i, j int) bool { return b.keys[i] < b.keys[j] }
+func (b byKey) Swap(i, j int) {
+	b.values[i], b.values[j] = b.values[j], b.values[i]
+	b.keys[i], b.keys[j] = b.keys[j], b.keys[i]
+}
@@ -0,0 +1,151 @@
+//go:build linux
+
+package thrift
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"testing/iotest"
+)
+
+const wantBody = `{"1":7,"2":"ada","3":[1,2],"4":[["a",1],["b",2]],"5":{"1":true}}`
+
+// binaryStruct encodes the struct of wantBody in the binary protocol, the map entries out of order.
+func binaryStruct() []byte {
+	str := func(b []byte, s string) []byte {
+		b = binary.BigEndian.AppendUint32(b, uint32(len(s)))
+		return append(b, s...)
+	}
+	b := []byte{ttI32, 0, 1}
+	b = binary.BigEndian.AppendUint32(b, 7)
+	b = append(b, ttString, 0, 2)
+	b = str(b, "ada")
+	b = append(b, ttList, 0, 3, ttI32)
+	b = binary.BigEndian.AppendUint32(b, 2)
+	b = binary.BigEndian.AppendUint32(b, 1)
+	b = binary.BigEndian.AppendUint32(b, 2)
+	b = append(b, ttMap, 0, 4, ttString, ttI64)
+	b = binary.BigEndian.AppendUint32(b, 2)
+	b = binary.BigEndian.AppendUint64(str(b, "b"), 2)
+	b = binary.BigEndian.AppendUint64(str(b, "a"), 1)
+	b = append(b, ttStruct, 0, 5, ttBool, 0, 1, 1, ttStop)
+	return append(b, ttStop)
+}
+
+// compactStruct encodes the struct of wantBody in the compact protocol.
+func compactStruct() []byte {
+	str := func(b []byte, s string) []byte {
+		b = binary.AppendUvarint(b, uint64(len(s)))
+		return append(b, s...)
+	}
+	b := []byte{1<<4 | 5}
+	b = binary.AppendUvarint(b, 14) // zigzag of 7
+	b = append(b, 1<<4|8)
+	b = str(b, "ada")
+	b = append(b, 1<<4|9, 2<<4|5, 2, 4) // a list of two i32, 1 and 2 in zigzag
+	b = append(b, 1<<4|11, 2, 8<<4|6)   // a map of two string to i64 entries
+	b = append(str(b, "b"), 4)
+	b = append(str(b, "a"), 2)
+	b = append(b, 1<<4|12, 1<<4|1, 0) // a struct of a true bool
+	return append(b, 0)
+}
+
+func strictMessage(method string, typ byte, seqID int32, body []byte) []byte {
+	b := binary.BigEndian.AppendUint32(nil, binaryVersion1|uint32(typ))
+	b = binary.BigEndian.AppendUint32(b, uint32(len(method)))
+	b = append(b, method...)
+	b = binary.BigEndian.AppendUint32(b, uint32(seqID))
+	return append(b, body...)
+}
+
+func oldMessage(method string, typ byte, seqID int32, body []byte) []byte {
+	b := binary.BigEndian.AppendUint32(nil, uint32(len(method)))
+	b = append(b, method...)
+	b = append(b, typ)
+	b = binary.BigEndian.AppendUint32(b, uint32(seqID))
+	return append(b, body...)
+}
+
+func compactMessage(method string, typ byte, seqID int32, body []byte) []byte {
+	b := []byte{compactID, typ<<5 | compactVersion}
+	b = binary.AppendUvarint(b, uint64(seqID))
+	b = binary.AppendUvarint(b, uint64(len(method)))
+	b = append(b, method...)
+	return append(b, body...)
+}
+
+func framed(payload []byte) []byte {
+	return append(binary.BigEndian.AppendUint32(nil, uint32(len(payload))), payload...)
+}
+
+func TestParseMessageRoundTrip(t *testing.T) {
+	tests := []struct {
+		name     string
+		raw      []byte
+		protocol string
+		framed   bool
+	}{
+		{name: "strict binary", raw: strictMessage("getUser", typeCall, 5, binaryStruct()), protocol: protocolBinary},
+		{name: "old binary", raw: oldMessage("getUser", typeCall, 5, binaryStruct()), protocol: protocolBinary},
+		{name: "framed binary", raw: framed(strictMessage("getUser", typeCall, 5, binaryStruct())), protocol: protocolBinary, framed: true},
+		{name: "compact", raw: compactMessage("getUser", typeCall, 5, compactStruct()), protocol: protocolCompact},
+		{name: "framed compact", raw: framed(compactMessage("getUser", typeCall, 5, compactStruct())), protocol: protocolCompact, framed: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// a message followed by the start of the next one
+			m, n, err := parseMessage(append(append([]byte(nil), tt.raw...), tt.raw[:3]...))
+			if err != nil {
+				t.Fatalf("parseMessage() failed: %v", err)
+			}
+			if n != len(tt.raw) || !bytes.Equal(m.raw, tt.raw) {
+				t.Errorf("parseMessage() read %d bytes, want %d", n, len(tt.raw))
+			}
+			if m.method != "getUser" || m.typ != typeCall || m.seqID != 5 || m.protocol != tt.protocol || m.framed != tt.framed {
+				t.Errorf("parseMessage() = %s %s %d %s framed %v", m.method, typeName(m.typ), m.seqID, m.protocol, m.framed)
+			}
+			if body := m.bodyJSON(); body != wantBody {
+				t.Errorf("bodyJSON() = %s, want %s", body, wantBody)
+			}
+
+			again, _, err := parseMessage(m.withSeqID(-9))
+			if err != nil {
+				t.Fatalf("parseMessage() of the message with its sequence id replaced failed: %v", err)
+			}
+			if again.seqID != -9 || again.method != m.method || again.framed != m.framed || again.bodyJSON() != wantBody {
+				t.Errorf("parseMessage() of the message with its sequence id replaced = %s %d framed %v %s", again.method, again.seqID, again.framed, again.bodyJSON())
+			}
+
+			if _, _, err := parseMessage(tt.raw[:len(tt.raw)-1]); err != errShortBuffer {
+				t.Errorf("parseMessage() of a cut message = %v, want %v", err, errShortBuffer)
+			}
+		})
+	}
+}
+
+func TestStream(t *testing.T) {
+	first := strictMessage("getUser", typeCall, 1, binaryStruct())
+	second := compactMessage("ping", typeOneway, 2, []byte{0})
+	s := &stream{r: iotest.OneByteReader(bytes.NewReader(append(append([]byte(nil), first...), second...)))}
+	for _, want := range []string{"getUser", "ping"} {
+		m, err := s.next()
+		if err != nil || m.method != want {
+			t.Fatalf("next() = %+v, %v, want the %s message", m, err, want)
+		}
+	}
+	if _, err := s.next(); err == nil {
+		t.Error("next() after the last message didn't fail")
+	}
+}
+
+func TestParseMessageMalformed(t *testing.T) {
+	// a list claiming more elements than the message holds
+	body := append([]byte{ttList, 0, 1, ttI32}, 0x7f, 0xff, 0xff, 0xff)
+	if _, _, err := parseMessage(strictMessage("getUser", typeCall, 1, body)); err != errMalformed {
+		t.Errorf("parseMessage() of an oversized list = %v, want %v", err, errMalformed)
+	}
+	if _, _, err := parseMessage(strictMessage("getUser", 9, 1, []byte{ttStop})); err != errMalformed {
+		t.Errorf("parseMessage() of an unknown message type = %v, want %v", err, errMalformed)
+	}
+}
@@ -0,0 +1,92 @@
+//go:build linux
+
+// Package thrift provides the integration for the thrift rpc over the binary and compact protocols.
+package thrift
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	"go.keploy.io/server/v2/pkg/core/proxy/util"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+func init() {
+	integrations.Register("thrift", NewThrift)
+}
+
+type Thrift struct {
+	logger *zap.Logger
+}
+
+func NewThrift(logger *zap.Logger) integrations.Integrations {
+	return &Thrift{
+		logger: logger,
+	}
+}
+
+// MatchType checks for the header of a call, framed or not. The old binary messages without a version in
+// their header aren't detected, their port has to be configured for the integration.
+func (t *Thrift) MatchType(_ context.Context, buf []byte) integrations.MatchResult {
+	if len(buf) < 8 {
+		return integrations.MatchResult{NeedBytes: 8}
+	}
+	if res := matchHeader(buf); res.Confidence > integrations.ConfidenceNone {
+		return res
+	}
+	if size := int(int32(binary.BigEndian.Uint32(buf))); isFrame(buf, size) {
+		return matchHeader(buf[4:])
+	}
+	return integrations.MatchResult{}
+}
+
+// matchHeader checks whether the buffer of at least 4 bytes starts with the versioned header of a call.
+func matchHeader(buf []byte) integrations.MatchResult {
+	isCall := func(t byte) bool { return t == typeCall || t == typeOneway }
+	switch {
+	case binary.BigEndian.Uint32(buf)&binaryVersionMsk == binaryVersion1 && buf[2] == 0 && isCall(buf[3]):
+		return integrations.MatchResult{Confidence: integrations.ConfidenceHigh}
+	case buf[0] == compactID && buf[1]&0x1f == compactVersion && isCall(buf[1]>>5):
+		// two bytes are a weaker signal than the four of the binary protocol
+		return integrations.MatchResult{Confidence: integrations.ConfidenceMedium}
+	}
+	return integrations.MatchResult{}
+}
+
+func (t *Thrift) RecordOutgoing(ctx context.Context, src net.Conn, dst net.Conn, mocks chan<- *models.Mock, opts models.OutgoingOptions) error {
+	logger := t.logger.With(zap.Any("Client IP Address", src.RemoteAddr().String()), zap.Any("Client ConnectionID", ctx.Value(models.ClientConnectionIDKey).(string)), zap.Any("Destination ConnectionID", ctx.Value(models.DestConnectionIDKey).(string)))
+
+	reqBuf, err := util.ReadInitialBuf(ctx, logger, src)
+	if err != nil {
+		utils.LogError(logger, err, "failed to read the initial thrift message")
+		return err
+	}
+
+	err = encodeThrift(ctx, logger, reqBuf, src, dst, mocks, opts)
+	if err != nil {
+		utils.LogError(logger, err, "failed to encode the thrift message into the yaml")
+		return err
+	}
+	return nil
+}
+
+func (t *Thrift) MockOutgoing(ctx context.Context, src net.Conn, dstCfg *integrations.ConditionalDstCfg, mockDb integrations.MockMemDb, opts models.OutgoingOptions) error {
+	logger := t.logger.With(zap.Any("Client IP Address", src.RemoteAddr().String()), zap.Any("Client ConnectionID", ctx.Value(models.ClientConnectionIDKey).(string)), zap.Any("Destination ConnectionID", ctx.Value(models.DestConnectionIDKey).(string)))
+
+	reqBuf, err := util.ReadInitialBuf(ctx, logger, src)
+	if err != nil {
+		utils.LogError(logger, err, "failed to read the initial thrift message")
+		return err
+	}
+
+	err = decodeThrift(ctx, logger, reqBuf, src, dstCfg, mockDb, opts)
+	if err != nil {
+		utils.LogError(logger, err, "failed to decode the thrift message")
+		return err
+	}
+	return nil
+}
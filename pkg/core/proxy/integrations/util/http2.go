@@ -0,0 +1,79 @@
+//go:build linux
+
+package util
+
+import (
+	"bytes"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
+)
+
+// HTTP2ClientPreface is the connection preface sent by every HTTP/2 client
+var HTTP2ClientPreface = []byte(http2.ClientPreface)
+
+// HTTP2RequestHeaders decodes the header fields of the first request sent on an HTTP/2 conn,
+// complete is false when the buffer doesn't contain the whole header block of the request yet.
+func HTTP2RequestHeaders(buf []byte) (fields []hpack.HeaderField, complete bool) {
+	if !bytes.HasPrefix(buf, HTTP2ClientPreface) {
+		return nil, false
+	}
+	buf = buf[len(HTTP2ClientPreface):]
+
+	var block []byte
+	inHeaders := false
+	for len(buf) >= 9 {
+		length := int(buf[0])<<16 | int(buf[1])<<8 | int(buf[2])
+		typ, flags := http2.FrameType(buf[3]), http2.Flags(buf[4])
+		if len(buf) < 9+length {
+			return nil, false
+		}
+		payload := buf[9 : 9+length]
+		buf = buf[9+length:]
+
+		switch {
+		case typ == http2.FrameHeaders && !inHeaders:
+			if flags.Has(http2.FlagHeadersPadded) {
+				if len(payload) < 1 || int(payload[0]) >= len(payload) {
+					return nil, true
+				}
+				payload = payload[1 : len(payload)-int(payload[0])]
+			}
+			if flags.Has(http2.FlagHeadersPriority) {
+				if len(payload) < 5 {
+					return nil, true
+				}
+				payload = payload[5:]
+			}
+			block = append(block, payload...)
+			inHeaders = true
+		case typ == http2.FrameContinuation && inHeaders:
+			block = append(block, payload...)
+		case inHeaders:
+			// the header block must not be interleaved with the other frames
+			return nil, true
+		default:
+			// SETTINGS, WINDOW_UPDATE and PRIORITY frames precede the first request
+			continue
+		}
+
+		if flags.Has(http2.FlagHeadersEndHeaders) {
+			fields, err := hpack.NewDecoder(4096, nil).DecodeFull(block)
+			if err != nil {
+				return nil, true
+			}
+			return fields, true
+		}
+	}
+	return nil, false
+}
+
+// HTTP2Header returns the value of the header field with the given (lowercase) name.
+func HTTP2Header(fields []hpack.HeaderField, name string) (string, bool) {
+	for _, f := range fields {
+		if f.Name == name {
+			return f.Value, true
+		}
+	}
+	return "", false
+}
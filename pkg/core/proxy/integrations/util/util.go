@@ -19,6 +19,16 @@ func IsASCII(s string) bool {
 	return true
 }
 
+// IsASCIIBytes is the same as IsASCII but works on the raw buffer, without copying it into a string.
+func IsASCIIBytes(b []byte) bool {
+	for _, c := range b {
+		if c > unicode.MaxASCII {
+			return false
+		}
+	}
+	return true
+}
+
 func DecodeBase64(encoded string) ([]byte, error) {
 	// Decode the base64 encoded string to buffer
 	data, err := base64.StdEncoding.DecodeString(encoded)
@@ -0,0 +1,27 @@
+//go:build linux
+
+package util
+
+import (
+	"bufio"
+	"bytes"
+	"net/http"
+	"strings"
+)
+
+// WebSocketUpgrade reports whether the buffer starts with the handshake of a websocket i.e. a GET request
+// asking to upgrade the conn to the websocket protocol, complete is false until the whole header of the
+// request has been received.
+func WebSocketUpgrade(buf []byte) (upgrade bool, complete bool) {
+	if !bytes.HasPrefix(buf, []byte("GET ")) {
+		return false, true
+	}
+	if !bytes.Contains(buf, []byte("\r\n\r\n")) {
+		return false, false
+	}
+	req, err := http.ReadRequest(bufio.NewReader(bytes.NewReader(buf)))
+	if err != nil {
+		return false, true
+	}
+	return strings.EqualFold(req.Header.Get("Upgrade"), "websocket"), true
+}
@@ -0,0 +1,241 @@
+//go:build linux
+
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"go.keploy.io/server/v2/pkg"
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	pUtil "go.keploy.io/server/v2/pkg/core/proxy/util"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+// timingImmediate sends the recorded server frames without waiting for their recorded gaps
+const timingImmediate = "immediate"
+
+// session is a websocket of the application served from a mock.
+type session struct {
+	logger     *zap.Logger
+	clientConn net.Conn
+
+	// writeMu serializes the frames written to the client
+	writeMu   sync.Mutex
+	closeSent bool
+}
+
+func (s *session) write(f []byte) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	_, err := s.clientConn.Write(f)
+	return err
+}
+
+// sendClose writes a close frame unless one was already sent, the peers send a single close frame each.
+func (s *session) sendClose(payload []byte) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	if s.closeSent {
+		return nil
+	}
+	s.closeSent = true
+	_, err := s.clientConn.Write(encodeFrame(true, opClose, payload))
+	return err
+}
+
+// decodeWebSocket completes the handshake of the application from the recorded one and replays the recorded
+// frames in order: each client frame is waited for before the frames the server sent after it are written.
+// The pings of the application are answered and its close frame is echoed, the conn stays open until the
+// application closes it.
+func decodeWebSocket(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientConn net.Conn, _ *integrations.ConditionalDstCfg, mockDb integrations.MockMemDb, opts models.OutgoingOptions) error {
+	client := bufio.NewReader(io.MultiReader(bytes.NewReader(reqBuf), clientConn))
+	rawReq, err := readHeader(client)
+	if err != nil {
+		utils.LogError(logger, err, "failed to read the websocket handshake from the client")
+		return err
+	}
+	req, err := http.ReadRequest(bufio.NewReader(bytes.NewReader(rawReq)))
+	if err != nil {
+		utils.LogError(logger, err, "failed to parse the websocket handshake")
+		return err
+	}
+
+	mock, err := match(ctx, req, mockDb)
+	if err != nil {
+		utils.LogError(logger, err, "error while matching websocket mocks")
+		return err
+	}
+	if mock == nil {
+		err := fmt.Errorf("no websocket mock found for %s", req.URL.String())
+		utils.LogError(logger, err, "failed to mock the websocket handshake")
+		return err
+	}
+
+	s := &session{
+		logger:     logger,
+		clientConn: clientConn,
+	}
+	if err := s.write(handshakeResponse(mock.Spec.HTTPResp, req)); err != nil {
+		utils.LogError(logger, err, "failed to write the websocket handshake response to the client")
+		return err
+	}
+	if mock.Spec.HTTPResp.StatusCode != http.StatusSwitchingProtocols {
+		// the server refused the upgrade when recording, the application gets the same answer
+		return nil
+	}
+
+	// the frames of the client are read in the background so that its pings and close are always answered
+	frames := make(chan *frame, 16)
+	errCh := make(chan error, 1)
+	go func() {
+		defer pUtil.Recover(logger, clientConn, nil)
+		defer close(frames)
+		for {
+			f, err := readFrame(client)
+			if err != nil {
+				errCh <- err
+				return
+			}
+			switch f.opcode {
+			case opPing:
+				err = s.write(encodeFrame(true, opPong, f.payload))
+			case opPong:
+			case opClose:
+				err = s.sendClose(f.payload)
+				if err == nil {
+					err = io.EOF
+				}
+			default:
+				select {
+				case frames <- f:
+				case <-ctx.Done():
+					errCh <- ctx.Err()
+					return
+				}
+			}
+			if err != nil {
+				errCh <- err
+				return
+			}
+		}
+	}()
+
+	err = s.replay(ctx, mock.Spec.WebSocketFrames, frames, opts.WebSocketTiming != timingImmediate)
+	if err != nil {
+		if err == io.EOF {
+			return nil
+		}
+		utils.LogError(logger, err, "failed to replay the websocket frames", zap.String("url", req.URL.String()))
+		return err
+	}
+
+	// every recorded frame has been replayed, the conn is kept until the application closes it
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case _, ok := <-frames:
+			if ok {
+				logger.Debug("no more websocket frames recorded for the frame of the client", zap.String("url", req.URL.String()))
+				continue
+			}
+			if err := <-errCh; err != io.EOF {
+				return err
+			}
+			return nil
+		}
+	}
+}
+
+// replay writes the recorded server frames, waiting for the application to send the recorded client frames in
+// between. The gaps between the recorded frames are kept when timed is set.
+func (s *session) replay(ctx context.Context, recorded []models.WebSocketFrame, frames <-chan *frame, timed bool) error {
+	last := time.Now()
+	var lastOffset int64
+	for i, m := range recorded {
+		if m.From == pkg.WsFromClient {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case f, ok := <-frames:
+				if !ok {
+					// the application closed the websocket before the end of the recording
+					return io.EOF
+				}
+				if payload, err := pkg.WebSocketFramePayload(m); err == nil && !bytes.Equal(payload, f.payload) {
+					s.logger.Debug("the websocket frame of the client differs from the recorded one", zap.Int("frame", i))
+				}
+			}
+			last, lastOffset = time.Now(), m.Offset
+			continue
+		}
+
+		op, ok := pkg.WebSocketOpcode(m.Opcode)
+		if !ok {
+			return fmt.Errorf("invalid opcode %q of the recorded websocket frame %d", m.Opcode, i)
+		}
+		payload, err := pkg.WebSocketFramePayload(m)
+		if err != nil {
+			return fmt.Errorf("failed to decode the payload of the recorded websocket frame %d: %v", i, err)
+		}
+
+		if timed && m.Offset > lastOffset {
+			wait := time.Until(last.Add(time.Duration(m.Offset-lastOffset) * time.Millisecond))
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(wait):
+			}
+		}
+		last, lastOffset = time.Now(), m.Offset
+
+		if op == opClose {
+			err = s.sendClose(payload)
+		} else {
+			err = s.write(encodeFrame(m.Fin, op, payload))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// handshakeResponse renders the recorded handshake response, the Sec-WebSocket-Accept header is computed from
+// the key sent by the application.
+func handshakeResponse(resp *models.HTTPResp, req *http.Request) []byte {
+	header := pkg.ToHTTPHeader(resp.Header)
+	if resp.StatusCode == http.StatusSwitchingProtocols {
+		header.Set("Sec-WebSocket-Accept", acceptKey(req.Header.Get("Sec-WebSocket-Key")))
+	} else {
+		// the body of a refused handshake isn't recorded
+		header.Del("Transfer-Encoding")
+		header.Set("Content-Length", "0")
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "HTTP/1.1 %d %s\r\n", resp.StatusCode, http.StatusText(resp.StatusCode))
+	keys := make([]string, 0, len(header))
+	for key := range header {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		for _, value := range header[key] {
+			fmt.Fprintf(&b, "%s: %s\r\n", key, value)
+		}
+	}
+	b.WriteString("\r\n")
+	return b.Bytes()
+}
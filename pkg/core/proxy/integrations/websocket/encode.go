@@ -0,0 +1,213 @@
+//go:build linux
+
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.keploy.io/server/v2/pkg"
+	pUtil "go.keploy.io/server/v2/pkg/core/proxy/util"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+)
+
+// recording holds the frames exchanged on a websocket, they are appended by both directions of the conn.
+type recording struct {
+	mu     sync.Mutex
+	start  time.Time
+	last   time.Time
+	frames []models.WebSocketFrame
+}
+
+func (r *recording) add(f *frame, from string, text bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.last = time.Now()
+	r.frames = append(r.frames, f.model(from, text, r.last.Sub(r.start)))
+}
+
+// encodeWebSocket forwards the handshake and then the frames between the client and the server. The frames are
+// recorded in the order they were forwarded, the whole websocket is saved as a single mock once either peer
+// closes the conn. The pings and the pongs are only forwarded, they are answered without a mock while testing.
+func encodeWebSocket(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientConn, destConn net.Conn, mocks chan<- *models.Mock, _ models.OutgoingOptions) error {
+	client := bufio.NewReader(io.MultiReader(bytes.NewReader(reqBuf), clientConn))
+	server := bufio.NewReader(destConn)
+
+	rawReq, err := readHeader(client)
+	if err != nil {
+		utils.LogError(logger, err, "failed to read the websocket handshake from the client")
+		return err
+	}
+	reqTimestampMock := time.Now()
+	_, err = destConn.Write(rawReq)
+	if err != nil {
+		utils.LogError(logger, err, "failed to write the websocket handshake to the destination server")
+		return err
+	}
+
+	rawResp, err := readHeader(server)
+	if err != nil {
+		utils.LogError(logger, err, "failed to read the websocket handshake response from the destination server")
+		return err
+	}
+	_, err = clientConn.Write(rawResp)
+	if err != nil {
+		utils.LogError(logger, err, "failed to write the websocket handshake response to the client")
+		return err
+	}
+
+	req, err := http.ReadRequest(bufio.NewReader(bytes.NewReader(rawReq)))
+	if err != nil {
+		utils.LogError(logger, err, "failed to parse the websocket handshake")
+		return err
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(rawResp)), req)
+	if err != nil {
+		utils.LogError(logger, err, "failed to parse the websocket handshake response")
+		return err
+	}
+
+	g, ok := ctx.Value(models.ErrGroupKey).(*errgroup.Group)
+	if !ok {
+		return errors.New("failed to get the error group from the context")
+	}
+	errCh := make(chan error, 2)
+
+	// the server refused the upgrade, the rest of the conn is forwarded without being recorded
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		logger.Debug("the server refused the websocket upgrade", zap.Int("status", resp.StatusCode))
+		g.Go(func() error {
+			defer pUtil.Recover(logger, clientConn, destConn)
+			_, err := io.Copy(destConn, client)
+			errCh <- err
+			return nil
+		})
+		g.Go(func() error {
+			defer pUtil.Recover(logger, clientConn, destConn)
+			_, err := io.Copy(clientConn, server)
+			errCh <- err
+			return nil
+		})
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-errCh:
+			return err
+		}
+	}
+
+	rec := &recording{start: time.Now()}
+	rec.last = rec.start
+	relay := func(r *bufio.Reader, w net.Conn, from string) error {
+		// whether the message the continuation frames belong to is a text message
+		text := false
+		for {
+			f, err := readFrame(r)
+			if err != nil {
+				return err
+			}
+			_, err = w.Write(f.raw)
+			if err != nil {
+				return err
+			}
+			switch f.opcode {
+			case opPing, opPong:
+				continue
+			case opText:
+				text = true
+			case opBinary:
+				text = false
+			}
+			rec.add(f, from, text || f.opcode == opClose)
+		}
+	}
+
+	// Forward the frames from the client to the destination server
+	g.Go(func() error {
+		defer pUtil.Recover(logger, clientConn, destConn)
+		err := relay(client, destConn, pkg.WsFromClient)
+		if err != nil && err != io.EOF {
+			utils.LogError(logger, err, "failed to forward the websocket frames of the client")
+		}
+		errCh <- err
+		return nil
+	})
+
+	// Forward the frames from the destination server to the client
+	g.Go(func() error {
+		defer pUtil.Recover(logger, clientConn, destConn)
+		err := relay(server, clientConn, pkg.WsFromServer)
+		if err != nil && err != io.EOF {
+			utils.LogError(logger, err, "failed to forward the websocket frames of the server")
+		}
+		errCh <- err
+		return nil
+	})
+
+	select {
+	case <-ctx.Done():
+		err = ctx.Err()
+	case err = <-errCh:
+		if err == io.EOF {
+			err = nil
+		}
+	}
+
+	rec.mu.Lock()
+	frames := append([]models.WebSocketFrame(nil), rec.frames...)
+	resTimestampMock := rec.last
+	rec.mu.Unlock()
+
+	mock := &models.Mock{
+		Version: models.GetVersion(),
+		Name:    "mocks",
+		Kind:    models.WebSocket,
+		Spec: models.MockSpec{
+			Metadata: map[string]string{
+				"name":      "WebSocket",
+				"type":      models.HTTPClient,
+				"operation": req.Method,
+			},
+			HTTPReq: &models.HTTPReq{
+				Method:     models.Method(req.Method),
+				ProtoMajor: req.ProtoMajor,
+				ProtoMinor: req.ProtoMinor,
+				URL:        req.URL.String(),
+				Header:     pkg.ToYamlHTTPHeader(req.Header),
+				URLParams:  pkg.URLParams(req),
+			},
+			HTTPResp: &models.HTTPResp{
+				StatusCode:    resp.StatusCode,
+				StatusMessage: http.StatusText(resp.StatusCode),
+				ProtoMajor:    resp.ProtoMajor,
+				ProtoMinor:    resp.ProtoMinor,
+				Header:        pkg.ToYamlHTTPHeader(resp.Header),
+			},
+			WebSocketFrames:  frames,
+			Created:          time.Now().Unix(),
+			ReqTimestampMock: reqTimestampMock,
+			ResTimestampMock: resTimestampMock,
+		},
+	}
+	select {
+	case mocks <- mock:
+	case <-ctx.Done():
+		// the websockets usually stay open until the recording stops, the mock is kept if the channel has room
+		select {
+		case mocks <- mock:
+		default:
+			logger.Warn("failed to save the websocket mock, the recording is stopping", zap.String("url", req.URL.String()))
+		}
+	}
+	return err
+}
@@ -0,0 +1,175 @@
+//go:build linux
+
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.keploy.io/server/v2/pkg"
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+// the opcodes of the frames
+const (
+	opContinuation byte = 0x0
+	opText         byte = 0x1
+	opBinary       byte = 0x2
+	opClose        byte = 0x8
+	opPing         byte = 0x9
+	opPong         byte = 0xa
+)
+
+// maxPayloadSize bounds the payload of a frame, a larger length is taken as a corrupted stream
+const maxPayloadSize = 64 << 20
+
+// acceptGUID is appended to the key of the client to compute the Sec-WebSocket-Accept header of the handshake
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// frame is a websocket frame, the payload is unmasked and raw is the frame as it was sent.
+type frame struct {
+	fin     bool
+	opcode  byte
+	payload []byte
+	raw     []byte
+}
+
+// control reports whether the frame is a close, ping or pong frame.
+func (f *frame) control() bool {
+	return f.opcode&0x8 != 0
+}
+
+// readFrame reads the next frame from the reader and unmasks its payload.
+func readFrame(r *bufio.Reader) (*frame, error) {
+	raw := make([]byte, 2, 14)
+	if _, err := io.ReadFull(r, raw); err != nil {
+		return nil, err
+	}
+	f := &frame{
+		fin:    raw[0]&0x80 != 0,
+		opcode: raw[0] & 0x0f,
+	}
+	masked := raw[1]&0x80 != 0
+
+	length := uint64(raw[1] & 0x7f)
+	switch length {
+	case 126:
+		ext := make([]byte, 2)
+		if _, err := io.ReadFull(r, ext); err != nil {
+			return nil, unexpected(err)
+		}
+		raw = append(raw, ext...)
+		length = uint64(binary.BigEndian.Uint16(ext))
+	case 127:
+		ext := make([]byte, 8)
+		if _, err := io.ReadFull(r, ext); err != nil {
+			return nil, unexpected(err)
+		}
+		raw = append(raw, ext...)
+		length = binary.BigEndian.Uint64(ext)
+	}
+	if length > maxPayloadSize {
+		return nil, fmt.Errorf("the websocket frame payload of %d bytes exceeds the limit of %d bytes", length, maxPayloadSize)
+	}
+
+	var key []byte
+	if masked {
+		key = make([]byte, 4)
+		if _, err := io.ReadFull(r, key); err != nil {
+			return nil, unexpected(err)
+		}
+		raw = append(raw, key...)
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, unexpected(err)
+	}
+	f.raw = append(raw, payload...)
+
+	if masked {
+		unmasked := make([]byte, length)
+		for i := range payload {
+			unmasked[i] = payload[i] ^ key[i%4]
+		}
+		payload = unmasked
+	}
+	f.payload = payload
+	return f, nil
+}
+
+// unexpected turns the end of the stream within a frame into an error.
+func unexpected(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// encodeFrame encodes an unmasked frame, the frames sent to the client by a server are never masked.
+func encodeFrame(fin bool, op byte, payload []byte) []byte {
+	b0 := op
+	if fin {
+		b0 |= 0x80
+	}
+	out := []byte{b0}
+	switch n := len(payload); {
+	case n < 126:
+		out = append(out, byte(n))
+	case n <= 0xffff:
+		out = append(out, 126)
+		out = binary.BigEndian.AppendUint16(out, uint16(n))
+	default:
+		out = append(out, 127)
+		out = binary.BigEndian.AppendUint64(out, uint64(n))
+	}
+	return append(out, payload...)
+}
+
+// model converts the frame to its recorded form. The payloads of the text messages are stored as text,
+// the others as base64.
+func (f *frame) model(from string, text bool, offset time.Duration) models.WebSocketFrame {
+	return pkg.WebSocketFrameModel(&pkg.WebSocketRawFrame{Fin: f.fin, Opcode: f.opcode, Payload: f.payload}, from, text, offset)
+}
+
+// acceptKey computes the Sec-WebSocket-Accept header the server answers the Sec-WebSocket-Key of the client with.
+func acceptKey(key string) string {
+	h := sha1.New()
+	h.Write([]byte(strings.TrimSpace(key) + acceptGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// readHeader reads a request or a response up to the end of its header. The Sec-WebSocket-Extensions headers
+// are dropped, so that no extension such as permessage-deflate is negotiated and the payloads are recorded
+// as the application sees them.
+func readHeader(r *bufio.Reader) ([]byte, error) {
+	var buf bytes.Buffer
+	for {
+		line, err := r.ReadSlice('\n')
+		if err != nil {
+			if err == bufio.ErrBufferFull {
+				return nil, fmt.Errorf("the websocket handshake header line is too long")
+			}
+			if buf.Len() > 0 {
+				return nil, unexpected(err)
+			}
+			return nil, err
+		}
+		name, _, _ := bytes.Cut(line, []byte(":"))
+		if http.CanonicalHeaderKey(string(bytes.TrimSpace(name))) == "Sec-Websocket-Extensions" {
+			continue
+		}
+		buf.Write(line)
+		if len(bytes.TrimSpace(line)) == 0 {
+			return buf.Bytes(), nil
+		}
+	}
+}
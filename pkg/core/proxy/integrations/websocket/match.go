@@ -0,0 +1,81 @@
+//go:build linux
+
+package websocket
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+// match finds the mock of the websocket among the ones opened with the same path. The mocks recorded during the
+// current test case are preferred, and among them the one with the same url and subprotocols. A websocket is
+// replayed once, its mock is moved behind the rest once matched.
+func match(ctx context.Context, req *http.Request, mockDb integrations.MockMemDb) (*models.Mock, error) {
+	key := integrations.HTTPMockKey(req.Method, req.URL.Path)
+	for {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		mocks, err := mockDb.GetUnFilteredMocksByKey(models.WebSocket, key)
+		if err != nil {
+			return nil, fmt.Errorf("error while getting unfiltered mocks %v", err)
+		}
+
+		var filteredMocks, unfilteredMocks []*models.Mock
+		for _, mock := range mocks {
+			if mock.Kind != models.WebSocket || mock.Spec.HTTPReq == nil || mock.Spec.HTTPResp == nil {
+				continue
+			}
+			if mock.TestModeInfo.IsFiltered {
+				filteredMocks = append(filteredMocks, mock)
+			} else {
+				unfilteredMocks = append(unfilteredMocks, mock)
+			}
+		}
+
+		mock := closest(filteredMocks, req)
+		if mock == nil {
+			mock = closest(unfilteredMocks, req)
+		}
+		if mock == nil {
+			return nil, nil
+		}
+
+		originalMock := *mock
+		mock.TestModeInfo.IsFiltered = false
+		mock.TestModeInfo.SortOrder = math.MaxInt64
+		if !mockDb.UpdateUnFilteredMock(&originalMock, mock) {
+			// the mock was used by another websocket in the meantime
+			continue
+		}
+		return mock, nil
+	}
+}
+
+// closest returns the first mock with the most of the url and the subprotocols of the request in common.
+func closest(mocks []*models.Mock, req *http.Request) *models.Mock {
+	protocols := strings.Join(req.Header.Values("Sec-Websocket-Protocol"), ",")
+	var (
+		best      *models.Mock
+		bestScore = -1
+	)
+	for _, mock := range mocks {
+		score := 0
+		if mock.Spec.HTTPReq.URL == req.URL.String() {
+			score += 2
+		}
+		if mock.Spec.HTTPReq.Header["Sec-Websocket-Protocol"] == protocols {
+			score++
+		}
+		if score > bestScore {
+			best, bestScore = mock, score
+		}
+	}
+	return best
+}
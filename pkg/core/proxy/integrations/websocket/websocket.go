@@ -0,0 +1,78 @@
+//go:build linux
+
+// Package websocket provides the integration for the websockets opened by the application (RFC 6455).
+package websocket
+
+import (
+	"context"
+	"net"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	intgUtils "go.keploy.io/server/v2/pkg/core/proxy/integrations/util"
+	"go.keploy.io/server/v2/pkg/core/proxy/util"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+func init() {
+	integrations.Register("websocket", NewWebSocket)
+}
+
+type WebSocket struct {
+	logger *zap.Logger
+}
+
+func NewWebSocket(logger *zap.Logger) integrations.Integrations {
+	return &WebSocket{
+		logger: logger,
+	}
+}
+
+// MatchType checks for the handshake of a websocket, the HTTP/1.1 GET request with the `Upgrade: websocket` header.
+// The other GET requests are left to the http integration.
+func (w *WebSocket) MatchType(_ context.Context, buf []byte) integrations.MatchResult {
+	upgrade, complete := intgUtils.WebSocketUpgrade(buf)
+	switch {
+	case upgrade:
+		return integrations.MatchResult{Confidence: integrations.ConfidenceCertain}
+	case !complete:
+		return integrations.MatchResult{Confidence: integrations.ConfidenceMedium, NeedBytes: len(buf) + 1}
+	default:
+		return integrations.MatchResult{}
+	}
+}
+
+func (w *WebSocket) RecordOutgoing(ctx context.Context, src net.Conn, dst net.Conn, mocks chan<- *models.Mock, opts models.OutgoingOptions) error {
+	logger := w.logger.With(zap.Any("Client IP Address", src.RemoteAddr().String()), zap.Any("Client ConnectionID", ctx.Value(models.ClientConnectionIDKey).(string)), zap.Any("Destination ConnectionID", ctx.Value(models.DestConnectionIDKey).(string)))
+
+	reqBuf, err := util.ReadInitialBuf(ctx, logger, src)
+	if err != nil {
+		utils.LogError(logger, err, "failed to read the initial websocket message")
+		return err
+	}
+
+	err = encodeWebSocket(ctx, logger, reqBuf, src, dst, mocks, opts)
+	if err != nil {
+		utils.LogError(logger, err, "failed to encode the websocket message into the yaml")
+		return err
+	}
+	return nil
+}
+
+func (w *WebSocket) MockOutgoing(ctx context.Context, src net.Conn, dstCfg *integrations.ConditionalDstCfg, mockDb integrations.MockMemDb, opts models.OutgoingOptions) error {
+	logger := w.logger.With(zap.Any("Client IP Address", src.RemoteAddr().String()), zap.Any("Client ConnectionID", ctx.Value(models.ClientConnectionIDKey).(string)), zap.Any("Destination ConnectionID", ctx.Value(models.DestConnectionIDKey).(string)))
+
+	reqBuf, err := util.ReadInitialBuf(ctx, logger, src)
+	if err != nil {
+		utils.LogError(logger, err, "failed to read the initial websocket message")
+		return err
+	}
+
+	err = decodeWebSocket(ctx, logger, reqBuf, src, dstCfg, mockDb, opts)
+	if err != nil {
+		utils.LogError(logger, err, "failed to decode the websocket message")
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,247 @@
+//go:build linux
+
+package zeromq
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	pUtil "go.keploy.io/server/v2/pkg/core/proxy/util"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+// deliveryInterval is how often the mocked publisher looks for the messages to deliver to the subscriptions
+const deliveryInterval = 50 * time.Millisecond
+
+// session is the state of a client conn to the mocked server.
+type session struct {
+	logger     *zap.Logger
+	clientConn net.Conn
+	mockDb     integrations.MockMemDb
+	mechanism  string
+	socketType string
+
+	// writeMu serializes the replies and the deliveries written to the client
+	writeMu sync.Mutex
+	subMu   sync.Mutex
+	filters [][]byte
+}
+
+// decodeZeroMQ acts as the server of the client. The handshake is answered from the recorded one, the messages
+// of the client are answered with the recorded replies and the messages published during the test cases are
+// delivered to the subscriptions of the client.
+func decodeZeroMQ(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientConn net.Conn, _ *integrations.ConditionalDstCfg, mockDb integrations.MockMemDb, _ models.OutgoingOptions) error {
+	logger.Debug("Into the zeromq parser in test mode")
+	client := bufio.NewReader(io.MultiReader(bytes.NewReader(reqBuf), clientConn))
+
+	g, err := exchangeGreeting(client, clientConn)
+	if err != nil {
+		utils.LogError(logger, err, "failed to exchange the zmtp greeting with the client")
+		return err
+	}
+	if g.major < 3 || (g.mechanism != mechanismNull && g.mechanism != mechanismPlain) {
+		err := fmt.Errorf("the zeromq conns of ZMTP %d.%d with the %s mechanism can't be mocked", g.major, g.minor, g.mechanism)
+		utils.LogError(logger, err, "failed to mock the zeromq conn")
+		return err
+	}
+
+	s := &session{
+		logger:     logger,
+		clientConn: clientConn,
+		mockDb:     mockDb,
+		mechanism:  g.mechanism,
+	}
+	errCh := make(chan error, 1)
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		defer pUtil.Recover(logger, clientConn, nil)
+		defer close(errCh)
+		for {
+			m, err := readMessage(client)
+			if err != nil {
+				if err != io.EOF {
+					utils.LogError(logger, err, "failed to read the zmtp message from the client")
+				}
+				errCh <- err
+				return
+			}
+			if err := s.handle(ctx, m); err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				errCh <- err
+				return
+			}
+		}
+	}()
+
+	go func() {
+		defer pUtil.Recover(logger, clientConn, nil)
+		s.deliver(ctx, done)
+	}()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-errCh:
+		if err == io.EOF {
+			return nil
+		}
+		return err
+	}
+}
+
+// exchangeGreeting reads the greeting of the client and answers it in the steps the clients wait for: the
+// signature with the major version, and then the rest with the mechanism of the client.
+func exchangeGreeting(client *bufio.Reader, clientConn net.Conn) (greeting, error) {
+	b := make([]byte, greetingSize)
+	if _, err := io.ReadFull(client, b[:signatureSize]); err != nil {
+		return greeting{}, err
+	}
+	if b[0] != 0xff || b[signatureSize-1] != 0x7f {
+		return greeting{}, fmt.Errorf("invalid zmtp signature %x", b[:signatureSize])
+	}
+	if _, err := clientConn.Write(append(signature(), 3)); err != nil {
+		return greeting{}, err
+	}
+	if _, err := io.ReadFull(client, b[signatureSize:]); err != nil {
+		return greeting{}, unexpected(err)
+	}
+
+	g := parseGreeting(b)
+	if g.major < 3 {
+		return g, nil
+	}
+	reply := greeting{major: 3, minor: min(g.minor, 1), mechanism: g.mechanism, asServer: true}
+	_, err := clientConn.Write(reply.encode()[signatureSize+1:])
+	return g, err
+}
+
+func (s *session) handle(ctx context.Context, m *message) error {
+	switch m.command {
+	case cmdReady, cmdInitiate:
+		props, err := decodeMetadata(m.data)
+		if err != nil {
+			utils.LogError(s.logger, err, "failed to decode the zmtp metadata of the client")
+			return err
+		}
+		s.socketType = socketTypeOf(props)
+		return s.write(encodeCommand(cmdReady, encodeMetadata(serverReady(s.mockDb, s.socketType, s.mechanism))))
+	case cmdHello:
+		// any credentials are accepted
+		return s.write(encodeCommand(cmdWelcome, nil))
+	case cmdPing:
+		// the pong carries the context of the ping, after its ttl
+		var pingContext []byte
+		if len(m.data) > 2 {
+			pingContext = m.data[2:]
+		}
+		return s.write(encodeCommand(cmdPong, pingContext))
+	case cmdError:
+		return fmt.Errorf("the client sent an error: %q", m.data)
+	}
+
+	if topic, subscribe, ok := m.subscription(); ok && (m.command != "" || subscriber(s.socketType)) {
+		s.subscribe(topic, subscribe)
+		return nil
+	}
+	if m.command != "" {
+		return nil
+	}
+
+	mock, err := match(ctx, m, s.socketType, s.mockDb)
+	if err != nil {
+		utils.LogError(s.logger, err, "error while matching zeromq mocks")
+		return err
+	}
+	if mock == nil {
+		if oneWay(s.socketType) {
+			s.logger.Debug("no zeromq mock found for the message of the sending socket", zap.String("socketType", s.socketType))
+			return nil
+		}
+		err := fmt.Errorf("no zeromq mock found for the message of the %s socket", s.socketType)
+		utils.LogError(s.logger, err, "failed to mock the zeromq message")
+		return err
+	}
+
+	for _, resp := range mock.Spec.ZeroMQResponses {
+		raw, err := encodeMessage(resp)
+		if err != nil {
+			utils.LogError(s.logger, err, "failed to encode the recorded zeromq message")
+			return err
+		}
+		if err := s.write(raw); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *session) subscribe(topic []byte, subscribe bool) {
+	s.subMu.Lock()
+	defer s.subMu.Unlock()
+	if subscribe {
+		s.filters = append(s.filters, topic)
+		return
+	}
+	for i, f := range s.filters {
+		if bytes.Equal(f, topic) {
+			s.filters = append(s.filters[:i], s.filters[i+1:]...)
+			return
+		}
+	}
+}
+
+// deliver writes the messages published during the current test case on the subscriptions of the client.
+func (s *session) deliver(ctx context.Context, done <-chan struct{}) {
+	ticker := time.NewTicker(deliveryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+
+		s.subMu.Lock()
+		filters := append([][]byte(nil), s.filters...)
+		s.subMu.Unlock()
+		if len(filters) == 0 {
+			continue
+		}
+
+		deliveries, err := deliveries(s.mockDb, s.socketType, filters)
+		if err != nil {
+			utils.LogError(s.logger, err, "failed to get the zeromq deliveries")
+			return
+		}
+		for _, raw := range deliveries {
+			if err := s.write(raw); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (s *session) write(raw []byte) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	_, err := s.clientConn.Write(raw)
+	if err != nil {
+		utils.LogError(s.logger, err, "failed to write the zmtp message to the client application")
+	}
+	return err
+}
@@ -0,0 +1,261 @@
+//go:build linux
+
+package zeromq
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	pUtil "go.keploy.io/server/v2/pkg/core/proxy/util"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+// pendingMessage is a message of the client waiting for the reply of the server.
+type pendingMessage struct {
+	message          *message
+	reqTimestampMock time.Time
+}
+
+// recorder holds the state of a recorded conn, it is updated by both directions of the conn.
+type recorder struct {
+	mu         sync.Mutex
+	connID     string
+	mocks      chan<- *models.Mock
+	mechanism  string
+	socketType string
+	// the commands of the security handshake, it's saved once both peers are ready
+	handshakeReqs  []models.ZeroMQMessage
+	handshakeResps []models.ZeroMQMessage
+	clientReady    bool
+	serverReady    bool
+	handshakeTime  time.Time
+	pending        []pendingMessage
+}
+
+// encodeZeroMQ forwards the greetings, the handshake and then the messages between the client and the server.
+// The handshake is recorded as a config mock. The messages of the request-reply sockets are recorded with the
+// reply of the server, paired in order, the messages published to a subscriber are recorded as mocks without
+// a request and the messages of the sockets which only send are recorded without a reply. The conns secured
+// with CURVE are forwarded without being recorded, their handshake and messages are encrypted.
+func encodeZeroMQ(ctx context.Context, logger *zap.Logger, reqBuf []byte, clientConn, destConn net.Conn, mocks chan<- *models.Mock, _ models.OutgoingOptions) error {
+	g, ok := ctx.Value(models.ErrGroupKey).(*errgroup.Group)
+	if !ok {
+		return errors.New("failed to get the error group from the context")
+	}
+	connID, _ := ctx.Value(models.ClientConnectionIDKey).(string)
+
+	rec := &recorder{connID: connID, mocks: mocks}
+	errCh := make(chan error, 2)
+
+	// Forward the greeting and the messages from the client to the destination server
+	g.Go(func() error {
+		defer pUtil.Recover(logger, clientConn, destConn)
+		client := bufio.NewReader(io.MultiReader(bytes.NewReader(reqBuf), clientConn))
+		errCh <- rec.relay(logger, client, destConn, true)
+		return nil
+	})
+
+	// Forward the greeting and the messages from the destination server to the client
+	g.Go(func() error {
+		defer pUtil.Recover(logger, clientConn, destConn)
+		errCh <- rec.relay(logger, bufio.NewReader(destConn), clientConn, false)
+		return nil
+	})
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-errCh:
+		if err == io.EOF {
+			return nil
+		}
+		return err
+	}
+}
+
+// relay forwards the greeting and the messages of a peer to the other peer, recording them on the way.
+func (rec *recorder) relay(logger *zap.Logger, r *bufio.Reader, w net.Conn, fromClient bool) error {
+	peer := "server"
+	if fromClient {
+		peer = "client"
+	}
+
+	g, err := relayGreeting(r, w)
+	if err != nil {
+		if err != io.EOF {
+			utils.LogError(logger, err, "failed to forward the zmtp greeting of the "+peer)
+		}
+		return err
+	}
+	if g.major < 3 || (g.mechanism != mechanismNull && g.mechanism != mechanismPlain) {
+		if fromClient {
+			logger.Warn("forwarding the zeromq conn without recording it, only the NULL and PLAIN mechanisms of ZMTP 3.x are supported",
+				zap.Uint8("version", g.major), zap.String("mechanism", g.mechanism))
+		}
+		_, err := io.Copy(w, r)
+		if err == nil {
+			err = io.EOF
+		}
+		return err
+	}
+	if fromClient {
+		rec.mu.Lock()
+		rec.mechanism = g.mechanism
+		rec.mu.Unlock()
+	}
+
+	for {
+		m, err := readMessage(r)
+		if err != nil {
+			if err != io.EOF {
+				utils.LogError(logger, err, "failed to read the zmtp message of the "+peer)
+			}
+			return err
+		}
+		_, err = w.Write(m.raw)
+		if err != nil {
+			utils.LogError(logger, err, "failed to forward the zmtp message of the "+peer)
+			return err
+		}
+		logger.Debug("zmtp message", zap.String("from", peer), zap.String("command", m.command), zap.Int("frames", len(m.frames)))
+		if fromClient {
+			rec.fromClient(m)
+		} else {
+			rec.fromServer(m)
+		}
+	}
+}
+
+// relayGreeting forwards the greeting of a peer in the steps it is sent in: the signature, the major version and
+// then the rest. A peer can wait for the signature and the version of the other peer before sending the rest.
+func relayGreeting(r *bufio.Reader, w net.Conn) (greeting, error) {
+	b := make([]byte, greetingSize)
+	for _, step := range [][2]int{{0, signatureSize}, {signatureSize, signatureSize + 1}, {signatureSize + 1, greetingSize}} {
+		if _, err := io.ReadFull(r, b[step[0]:step[1]]); err != nil {
+			if step[0] > 0 {
+				return greeting{}, unexpected(err)
+			}
+			return greeting{}, err
+		}
+		if _, err := w.Write(b[step[0]:step[1]]); err != nil {
+			return greeting{}, err
+		}
+		if step[0] == signatureSize && b[signatureSize] < 3 {
+			// the older versions have another greeting, the rest of the conn is only forwarded
+			return greeting{major: b[signatureSize]}, nil
+		}
+	}
+	return parseGreeting(b), nil
+}
+
+func (rec *recorder) fromClient(m *message) {
+	now := time.Now()
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	switch m.command {
+	case cmdReady, cmdHello, cmdInitiate:
+		rec.handshakeReqs = append(rec.handshakeReqs, m.model())
+		if rec.handshakeTime.IsZero() {
+			rec.handshakeTime = now
+		}
+		if m.command == cmdReady || m.command == cmdInitiate {
+			rec.clientReady = true
+			if props, err := decodeMetadata(m.data); err == nil {
+				rec.socketType = socketTypeOf(props)
+			}
+		}
+		rec.saveHandshake(now)
+		return
+	case cmdPing, cmdPong, cmdError:
+		return
+	}
+
+	if _, _, ok := m.subscription(); ok && (m.command != "" || subscriber(rec.socketType)) {
+		rec.save([]models.ZeroMQMessage{m.model()}, nil, now, now, true)
+		return
+	}
+	if m.command != "" {
+		return
+	}
+	if oneWay(rec.socketType) {
+		rec.save([]models.ZeroMQMessage{m.model()}, nil, now, now, false)
+		return
+	}
+	rec.pending = append(rec.pending, pendingMessage{message: m, reqTimestampMock: now})
+}
+
+func (rec *recorder) fromServer(m *message) {
+	now := time.Now()
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	switch m.command {
+	case cmdReady, cmdWelcome, cmdError:
+		rec.handshakeResps = append(rec.handshakeResps, m.model())
+		if m.command == cmdReady {
+			rec.serverReady = true
+		}
+		rec.saveHandshake(now)
+		return
+	case "":
+	default:
+		return
+	}
+
+	// the subscribers of a publishing application send only their subscriptions
+	if oneWay(rec.socketType) {
+		return
+	}
+	if len(rec.pending) == 0 || subscriber(rec.socketType) {
+		rec.save(nil, []models.ZeroMQMessage{m.model()}, now, now, false)
+		return
+	}
+	req := rec.pending[0]
+	rec.pending = rec.pending[1:]
+	rec.save([]models.ZeroMQMessage{req.message.model()}, []models.ZeroMQMessage{m.model()}, req.reqTimestampMock, now, false)
+}
+
+// saveHandshake saves the handshake once both peers are ready, the caller holds the lock.
+func (rec *recorder) saveHandshake(now time.Time) {
+	if !rec.clientReady || !rec.serverReady {
+		return
+	}
+	rec.save(rec.handshakeReqs, rec.handshakeResps, rec.handshakeTime, now, true)
+	rec.clientReady, rec.serverReady = false, false
+	rec.handshakeReqs, rec.handshakeResps = nil, nil
+}
+
+// save sends the mock of the messages, the caller holds the lock.
+func (rec *recorder) save(reqs, resps []models.ZeroMQMessage, reqTimestampMock, resTimestampMock time.Time, config bool) {
+	metadata := map[string]string{
+		"socketType": rec.socketType,
+		"mechanism":  rec.mechanism,
+	}
+	if config {
+		metadata["type"] = "config"
+	}
+	rec.mocks <- &models.Mock{
+		Version: models.GetVersion(),
+		Name:    "mocks",
+		Kind:    models.ZeroMQ,
+		Spec: models.MockSpec{
+			Metadata:         metadata,
+			ZeroMQRequests:   reqs,
+			ZeroMQResponses:  resps,
+			ReqTimestampMock: reqTimestampMock,
+			ResTimestampMock: resTimestampMock,
+		},
+		ConnectionID: rec.connID,
+	}
+}
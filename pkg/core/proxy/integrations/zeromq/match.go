@@ -0,0 +1,160 @@
+//go:build linux
+
+package zeromq
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"sort"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations/util"
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+// match finds the mock of a message of the client among the messages recorded for the same socket type, the
+// messages of the sockets which only send are matched among the mocks without a reply. The mocks recorded
+// during the current test case are preferred, and the message closest to the one of the client wins. The
+// mocks are moved behind the rest once matched.
+func match(ctx context.Context, m *message, socketType string, mockDb integrations.MockMemDb) (*models.Mock, error) {
+	for {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		mocks, err := mockDb.GetUnFilteredMocksByKey(models.ZeroMQ, "")
+		if err != nil {
+			return nil, fmt.Errorf("error while getting unfiltered mocks %v", err)
+		}
+
+		var filteredMocks, unfilteredMocks []*models.Mock
+		for _, mock := range mocks {
+			if mock.Kind != models.ZeroMQ || mock.Spec.Metadata["type"] == "config" || mock.Spec.Metadata["socketType"] != socketType ||
+				len(mock.Spec.ZeroMQRequests) == 0 || mock.Spec.ZeroMQRequests[0].Command != "" || (len(mock.Spec.ZeroMQResponses) == 0) != oneWay(socketType) {
+				continue
+			}
+			if mock.TestModeInfo.IsFiltered {
+				filteredMocks = append(filteredMocks, mock)
+			} else {
+				unfilteredMocks = append(unfilteredMocks, mock)
+			}
+		}
+
+		raw := encodeFrames(m.frames)
+		mock := closest(filteredMocks, raw)
+		if mock == nil {
+			mock = closest(unfilteredMocks, raw)
+		}
+		if mock == nil {
+			return nil, nil
+		}
+
+		originalMock := *mock
+		mock.TestModeInfo.IsFiltered = false
+		mock.TestModeInfo.SortOrder = math.MaxInt64
+		if !mockDb.UpdateUnFilteredMock(&originalMock, mock) {
+			// the mock was used by another message in the meantime
+			continue
+		}
+		return mock, nil
+	}
+}
+
+// closest returns the first mock with the same frames as the message, or else the one with the most similar frames.
+func closest(mocks []*models.Mock, raw []byte) *models.Mock {
+	var best *models.Mock
+	bestSim := -1.0
+	for _, mock := range mocks {
+		parts, err := frames(mock.Spec.ZeroMQRequests[0])
+		if err != nil {
+			continue
+		}
+		recorded := encodeFrames(parts)
+		if bytes.Equal(recorded, raw) {
+			return mock
+		}
+		k := util.AdaptiveK(len(raw), 3, 8, 5)
+		if sim := util.JaccardSimilarity(util.CreateShingles(recorded, k), util.CreateShingles(raw, k)); sim > bestSim {
+			best, bestSim = mock, sim
+		}
+	}
+	return best
+}
+
+// serverReady returns the properties of the READY command recorded from the server for the socket type and the
+// mechanism, or else the properties of a server with the matching socket type. The handshake is replayed as many
+// times as the application connects.
+func serverReady(mockDb integrations.MockMemDb, socketType, mechanism string) map[string]string {
+	mocks, err := mockDb.GetUnFilteredMocksByKey(models.ZeroMQ, "")
+	if err == nil {
+		for _, mock := range mocks {
+			if mock.Kind != models.ZeroMQ || mock.Spec.Metadata["type"] != "config" || mock.Spec.Metadata["socketType"] != socketType ||
+				mock.Spec.Metadata["mechanism"] != mechanism {
+				continue
+			}
+			for _, resp := range mock.Spec.ZeroMQResponses {
+				if resp.Command == cmdReady {
+					return resp.Properties
+				}
+			}
+		}
+	}
+	return map[string]string{socketTypeProperty: peerTypes[socketType]}
+}
+
+// isDelivery reports whether the mock is a message the publisher sent to the subscriber.
+func isDelivery(mock *models.Mock) bool {
+	return mock.Kind == models.ZeroMQ && mock.Spec.Metadata["type"] != "config" && len(mock.Spec.ZeroMQRequests) == 0 &&
+		len(mock.Spec.ZeroMQResponses) > 0 && mock.Spec.ZeroMQResponses[0].Command == ""
+}
+
+func subscribed(filters [][]byte, topic []byte) bool {
+	for _, f := range filters {
+		if bytes.HasPrefix(topic, f) {
+			return true
+		}
+	}
+	return false
+}
+
+// deliveries uses up the messages published during the current test case on the topics matching the
+// subscriptions, and returns them in the order they were published.
+func deliveries(mockDb integrations.MockMemDb, socketType string, filters [][]byte) ([][]byte, error) {
+	mocks, err := mockDb.GetUnFilteredMocksByKey(models.ZeroMQ, "")
+	if err != nil {
+		return nil, fmt.Errorf("error while getting unfiltered mocks %v", err)
+	}
+
+	type delivery struct {
+		mock *models.Mock
+		raw  []byte
+	}
+	var pending []delivery
+	for _, mock := range mocks {
+		if !mock.TestModeInfo.IsFiltered || !isDelivery(mock) || mock.Spec.Metadata["socketType"] != socketType {
+			continue
+		}
+		parts, err := frames(mock.Spec.ZeroMQResponses[0])
+		if err != nil || len(parts) == 0 || !subscribed(filters, parts[0]) {
+			continue
+		}
+		pending = append(pending, delivery{mock: mock, raw: encodeFrames(parts)})
+	}
+	sort.SliceStable(pending, func(i, j int) bool {
+		return pending[i].mock.Spec.ResTimestampMock.Before(pending[j].mock.Spec.ResTimestampMock)
+	})
+
+	var res [][]byte
+	for _, d := range pending {
+		originalMock := *d.mock
+		d.mock.TestModeInfo.IsFiltered = false
+		d.mock.TestModeInfo.SortOrder = math.MaxInt64
+		if !mockDb.UpdateUnFilteredMock(&originalMock, d.mock) {
+			// delivered on another conn of the same subscription
+			continue
+		}
+		res = append(res, d.raw)
+	}
+	return res, nil
+}
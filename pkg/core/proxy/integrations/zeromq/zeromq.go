@@ -0,0 +1,78 @@
+//go:build linux
+
+// Package zeromq provides the integration for the ZeroMQ sockets speaking ZMTP 3.x.
+package zeromq
+
+import (
+	"context"
+	"net"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	"go.keploy.io/server/v2/pkg/core/proxy/util"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+func init() {
+	integrations.Register("zeromq", NewZeroMQ)
+}
+
+type ZeroMQ struct {
+	logger *zap.Logger
+}
+
+func NewZeroMQ(logger *zap.Logger) integrations.Integrations {
+	return &ZeroMQ{
+		logger: logger,
+	}
+}
+
+// MatchType checks for the signature of the greeting. The peers send the signature alone and wait for the
+// signature of the other peer before the rest of the greeting, so it's all there is to detect.
+func (z *ZeroMQ) MatchType(_ context.Context, buf []byte) integrations.MatchResult {
+	if len(buf) == 0 || buf[0] != 0xff {
+		return integrations.MatchResult{}
+	}
+	if len(buf) < signatureSize {
+		return integrations.MatchResult{Confidence: integrations.ConfidenceLow, NeedBytes: signatureSize}
+	}
+	if buf[9] != 0x7f {
+		return integrations.MatchResult{}
+	}
+	return integrations.MatchResult{Confidence: integrations.ConfidenceCertain}
+}
+
+func (z *ZeroMQ) RecordOutgoing(ctx context.Context, src net.Conn, dst net.Conn, mocks chan<- *models.Mock, opts models.OutgoingOptions) error {
+	logger := z.logger.With(zap.Any("Client IP Address", src.RemoteAddr().String()), zap.Any("Client ConnectionID", ctx.Value(models.ClientConnectionIDKey).(string)), zap.Any("Destination ConnectionID", ctx.Value(models.DestConnectionIDKey).(string)))
+
+	reqBuf, err := util.ReadInitialBuf(ctx, logger, src)
+	if err != nil {
+		utils.LogError(logger, err, "failed to read the initial zeromq message")
+		return err
+	}
+
+	err = encodeZeroMQ(ctx, logger, reqBuf, src, dst, mocks, opts)
+	if err != nil {
+		utils.LogError(logger, err, "failed to encode the zeromq message into the yaml")
+		return err
+	}
+	return nil
+}
+
+func (z *ZeroMQ) MockOutgoing(ctx context.Context, src net.Conn, dstCfg *integrations.ConditionalDstCfg, mockDb integrations.MockMemDb, opts models.OutgoingOptions) error {
+	logger := z.logger.With(zap.Any("Client IP Address", src.RemoteAddr().String()), zap.Any("Client ConnectionID", ctx.Value(models.ClientConnectionIDKey).(string)), zap.Any("Destination ConnectionID", ctx.Value(models.DestConnectionIDKey).(string)))
+
+	reqBuf, err := util.ReadInitialBuf(ctx, logger, src)
+	if err != nil {
+		utils.LogError(logger, err, "failed to read the initial zeromq message")
+		return err
+	}
+
+	err = decodeZeroMQ(ctx, logger, reqBuf, src, dstCfg, mockDb, opts)
+	if err != nil {
+		utils.LogError(logger, err, "failed to decode the zeromq message")
+		return err
+	}
+	return nil
+}
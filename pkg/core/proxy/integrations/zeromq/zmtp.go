@@ -0,0 +1,379 @@
+//go:build linux
+
+package zeromq
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+const (
+	// signatureSize is the size of the signature which starts the greeting, the peers send it before the rest
+	signatureSize = 10
+	// greetingSize is the size of the whole greeting
+	greetingSize = 64
+	// mechanismSize is the size of the null padded name of the security mechanism in the greeting
+	mechanismSize = 20
+	// maxFrameSize bounds the size of a frame, a larger size is taken as a corrupted stream
+	maxFrameSize = 64 << 20
+)
+
+// the flags of a frame
+const (
+	flagMore    byte = 0x01
+	flagLong    byte = 0x02
+	flagCommand byte = 0x04
+)
+
+// the commands with a special meaning for the integration
+const (
+	cmdReady     = "READY"
+	cmdHello     = "HELLO"
+	cmdWelcome   = "WELCOME"
+	cmdInitiate  = "INITIATE"
+	cmdError     = "ERROR"
+	cmdSubscribe = "SUBSCRIBE"
+	cmdCancel    = "CANCEL"
+	cmdPing      = "PING"
+	cmdPong      = "PONG"
+)
+
+// the security mechanisms which can be recorded, the CURVE handshakes are encrypted with the keys of the peers
+const (
+	mechanismNull  = "NULL"
+	mechanismPlain = "PLAIN"
+)
+
+// socketTypeProperty is the property of the READY command naming the type of the socket
+const socketTypeProperty = "Socket-Type"
+
+// greeting is the greeting sent by a peer at the start of a conn.
+type greeting struct {
+	major     byte
+	minor     byte
+	mechanism string
+	asServer  bool
+}
+
+func parseGreeting(b []byte) greeting {
+	return greeting{
+		major:     b[10],
+		minor:     b[11],
+		mechanism: string(bytes.TrimRight(b[12:12+mechanismSize], "\x00")),
+		asServer:  b[32] == 1,
+	}
+}
+
+// signature is the start of the greeting, the padding is the legacy length of an identity frame.
+func signature() []byte {
+	return []byte{0xff, 0, 0, 0, 0, 0, 0, 0, 1, 0x7f}
+}
+
+func (g greeting) encode() []byte {
+	b := make([]byte, greetingSize)
+	copy(b, signature())
+	b[10], b[11] = g.major, g.minor
+	copy(b[12:12+mechanismSize], g.mechanism)
+	if g.asServer {
+		b[32] = 1
+	}
+	return b
+}
+
+// frame is a frame of a message or a command, raw is the frame as it was sent.
+type frame struct {
+	flags byte
+	body  []byte
+	raw   []byte
+}
+
+func readFrame(r *bufio.Reader) (*frame, error) {
+	flags, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	raw := []byte{flags}
+
+	var size uint64
+	if flags&flagLong != 0 {
+		ext := make([]byte, 8)
+		if _, err := io.ReadFull(r, ext); err != nil {
+			return nil, unexpected(err)
+		}
+		raw = append(raw, ext...)
+		size = binary.BigEndian.Uint64(ext)
+	} else {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, unexpected(err)
+		}
+		raw = append(raw, b)
+		size = uint64(b)
+	}
+	if size > maxFrameSize {
+		return nil, fmt.Errorf("the zmtp frame of %d bytes exceeds the limit of %d bytes", size, maxFrameSize)
+	}
+
+	body := make([]byte, size)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, unexpected(err)
+	}
+	return &frame{flags: flags, body: body, raw: append(raw, body...)}, nil
+}
+
+// unexpected turns the end of the stream within a frame into an error.
+func unexpected(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+func encodeFrame(flags byte, body []byte) []byte {
+	if len(body) > 0xff {
+		b := []byte{flags | flagLong}
+		b = binary.BigEndian.AppendUint64(b, uint64(len(body)))
+		return append(b, body...)
+	}
+	return append([]byte{flags, byte(len(body))}, body...)
+}
+
+// message is a command or a multipart message sent by a peer.
+type message struct {
+	command string
+	// data is the body of the command after its name
+	data   []byte
+	frames [][]byte
+	raw    []byte
+}
+
+// readMessage reads the frames of the next message, or the next command.
+func readMessage(r *bufio.Reader) (*message, error) {
+	m := &message{}
+	for {
+		f, err := readFrame(r)
+		if err != nil {
+			if err == io.EOF && len(m.raw) > 0 {
+				return nil, io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		m.raw = append(m.raw, f.raw...)
+
+		if f.flags&flagCommand != 0 {
+			if len(m.frames) > 0 {
+				return nil, fmt.Errorf("zmtp command within a multipart message")
+			}
+			if len(f.body) == 0 || len(f.body) < 1+int(f.body[0]) {
+				return nil, fmt.Errorf("invalid zmtp command of %d bytes", len(f.body))
+			}
+			m.command = string(f.body[1 : 1+f.body[0]])
+			m.data = f.body[1+f.body[0]:]
+			return m, nil
+		}
+		m.frames = append(m.frames, f.body)
+		if f.flags&flagMore == 0 {
+			return m, nil
+		}
+	}
+}
+
+func encodeCommand(name string, data []byte) []byte {
+	body := append([]byte{byte(len(name))}, name...)
+	return encodeFrame(flagCommand, append(body, data...))
+}
+
+func encodeFrames(frames [][]byte) []byte {
+	var b []byte
+	for i, f := range frames {
+		var flags byte
+		if i < len(frames)-1 {
+			flags = flagMore
+		}
+		b = append(b, encodeFrame(flags, f)...)
+	}
+	return b
+}
+
+// decodeMetadata decodes the properties of the READY and INITIATE commands.
+func decodeMetadata(b []byte) (map[string]string, error) {
+	props := make(map[string]string)
+	for len(b) > 0 {
+		n := int(b[0])
+		if len(b) < 1+n+4 {
+			return nil, fmt.Errorf("truncated zmtp metadata property")
+		}
+		name := string(b[1 : 1+n])
+		size := int(binary.BigEndian.Uint32(b[1+n:]))
+		b = b[1+n+4:]
+		if len(b) < size {
+			return nil, fmt.Errorf("truncated value of the zmtp metadata property %q", name)
+		}
+		props[name] = string(b[:size])
+		b = b[size:]
+	}
+	return props, nil
+}
+
+func encodeMetadata(props map[string]string) []byte {
+	names := make([]string, 0, len(props))
+	for name := range props {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b []byte
+	for _, name := range names {
+		b = append(b, byte(len(name)))
+		b = append(b, name...)
+		b = binary.BigEndian.AppendUint32(b, uint32(len(props[name])))
+		b = append(b, props[name]...)
+	}
+	return b
+}
+
+// socketTypeOf returns the socket type of the READY or INITIATE properties, the property names are case insensitive.
+func socketTypeOf(props map[string]string) string {
+	for name, value := range props {
+		if strings.EqualFold(name, socketTypeProperty) {
+			return strings.ToUpper(value)
+		}
+	}
+	return ""
+}
+
+// subscription decodes the subscriptions and the cancellations sent to a publisher, as commands since
+// ZMTP 3.1 and as messages starting with 1 or 0 before.
+func (m *message) subscription() (topic []byte, subscribe bool, ok bool) {
+	switch {
+	case m.command == cmdSubscribe:
+		return m.data, true, true
+	case m.command == cmdCancel:
+		return m.data, false, true
+	case m.command == "" && len(m.frames) == 1 && len(m.frames[0]) > 0 && m.frames[0][0] <= 1:
+		return m.frames[0][1:], m.frames[0][0] == 1, true
+	}
+	return nil, false, false
+}
+
+// model converts the message to its recorded form, the password of the PLAIN HELLO command isn't recorded.
+func (m *message) model() models.ZeroMQMessage {
+	rec := models.ZeroMQMessage{Command: m.command}
+	switch m.command {
+	case "":
+		for _, f := range m.frames {
+			rec.Frames = append(rec.Frames, frameModel(f))
+		}
+	case cmdReady, cmdInitiate:
+		if props, err := decodeMetadata(m.data); err == nil {
+			rec.Properties = props
+		}
+	case cmdHello:
+		if len(m.data) > 0 && len(m.data) >= 1+int(m.data[0]) {
+			rec.Properties = map[string]string{"Username": string(m.data[1 : 1+m.data[0]])}
+		}
+	default:
+		if len(m.data) > 0 {
+			rec.Frames = []models.ZeroMQFrame{frameModel(m.data)}
+		}
+	}
+	return rec
+}
+
+func frameModel(b []byte) models.ZeroMQFrame {
+	if isText(b) {
+		return models.ZeroMQFrame{Data: string(b)}
+	}
+	return models.ZeroMQFrame{Binary: base64.StdEncoding.EncodeToString(b)}
+}
+
+// isText reports whether the frame is printable text, the other frames are recorded as base64.
+func isText(b []byte) bool {
+	if !utf8.Valid(b) {
+		return false
+	}
+	for _, c := range b {
+		if c < 0x20 && c != '\n' && c != '\r' && c != '\t' {
+			return false
+		}
+	}
+	return true
+}
+
+func framePayload(f models.ZeroMQFrame) ([]byte, error) {
+	if f.Binary != "" {
+		return base64.StdEncoding.DecodeString(f.Binary)
+	}
+	return []byte(f.Data), nil
+}
+
+// frames returns the payloads of the recorded frames.
+func frames(rec models.ZeroMQMessage) ([][]byte, error) {
+	res := make([][]byte, 0, len(rec.Frames))
+	for _, f := range rec.Frames {
+		b, err := framePayload(f)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, b)
+	}
+	return res, nil
+}
+
+// encodeMessage encodes a recorded message or command to be sent to the application.
+func encodeMessage(rec models.ZeroMQMessage) ([]byte, error) {
+	parts, err := frames(rec)
+	if err != nil {
+		return nil, err
+	}
+	switch rec.Command {
+	case "":
+		return encodeFrames(parts), nil
+	case cmdReady, cmdInitiate:
+		return encodeCommand(rec.Command, encodeMetadata(rec.Properties)), nil
+	default:
+		var data []byte
+		if len(parts) > 0 {
+			data = parts[0]
+		}
+		return encodeCommand(rec.Command, data), nil
+	}
+}
+
+// peerTypes are the socket types the servers of the mocked conns answer with when no handshake was recorded.
+var peerTypes = map[string]string{
+	"REQ":    "REP",
+	"REP":    "REQ",
+	"DEALER": "ROUTER",
+	"ROUTER": "DEALER",
+	"PUB":    "SUB",
+	"SUB":    "PUB",
+	"XPUB":   "XSUB",
+	"XSUB":   "XPUB",
+	"PUSH":   "PULL",
+	"PULL":   "PUSH",
+	"PAIR":   "PAIR",
+}
+
+// oneWay reports whether the sockets of the type only send messages, they get no replies.
+func oneWay(socketType string) bool {
+	switch socketType {
+	case "PUB", "XPUB", "PUSH":
+		return true
+	}
+	return false
+}
+
+// subscriber reports whether the sockets of the type subscribe to the messages of a publisher.
+func subscriber(socketType string) bool {
+	return socketType == "SUB" || socketType == "XSUB"
+}
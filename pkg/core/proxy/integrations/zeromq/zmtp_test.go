@@ -0,0 +1,123 @@
+//go:build linux
+
+package zeromq
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestGreetingRoundTrip(t *testing.T) {
+	g := greeting{major: 3, minor: 1, mechanism: mechanismPlain, asServer: true}
+	b := g.encode()
+	if len(b) != greetingSize || !bytes.HasPrefix(b, signature()) {
+		t.Fatalf("encode() = % x", b)
+	}
+	if got := parseGreeting(b); got != g {
+		t.Errorf("parseGreeting() = %+v, want %+v", got, g)
+	}
+}
+
+func TestMessageRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  []byte
+	}{
+		{"multipart message", encodeFrames([][]byte{[]byte("topic"), {}, {0x00, 0xff, 0x10}})},
+		{"long frame", encodeFrames([][]byte{bytes.Repeat([]byte("a"), 300)})},
+		{"ready", encodeCommand(cmdReady, encodeMetadata(map[string]string{socketTypeProperty: "REQ", "Identity": ""}))},
+		{"subscribe", encodeCommand(cmdSubscribe, []byte("news."))},
+		{"ping", encodeCommand(cmdPing, []byte{0, 10})},
+		{"command without data", encodeCommand(cmdPong, nil)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := readMessage(bufio.NewReader(bytes.NewReader(tt.raw)))
+			if err != nil {
+				t.Fatalf("readMessage() failed: %v", err)
+			}
+			if !bytes.Equal(m.raw, tt.raw) {
+				t.Errorf("readMessage() raw = % x, want % x", m.raw, tt.raw)
+			}
+			b, err := encodeMessage(m.model())
+			if err != nil {
+				t.Fatalf("encodeMessage() failed: %v", err)
+			}
+			if !bytes.Equal(b, tt.raw) {
+				t.Errorf("encodeMessage() = % x, want % x", b, tt.raw)
+			}
+		})
+	}
+}
+
+func TestHelloModelDropsPassword(t *testing.T) {
+	data := append([]byte{5}, "alice"...)
+	data = append(append(data, 6), "secret"...)
+	m, err := readMessage(bufio.NewReader(bytes.NewReader(encodeCommand(cmdHello, data))))
+	if err != nil {
+		t.Fatalf("readMessage() failed: %v", err)
+	}
+	rec := m.model()
+	if want := map[string]string{"Username": "alice"}; !reflect.DeepEqual(rec.Properties, want) || len(rec.Frames) != 0 {
+		t.Errorf("model() = %+v, want the properties %v", rec, want)
+	}
+}
+
+func TestSubscription(t *testing.T) {
+	tests := []struct {
+		name      string
+		raw       []byte
+		topic     string
+		subscribe bool
+		ok        bool
+	}{
+		{"subscribe command", encodeCommand(cmdSubscribe, []byte("a")), "a", true, true},
+		{"cancel command", encodeCommand(cmdCancel, []byte("a")), "a", false, true},
+		{"legacy subscribe", encodeFrames([][]byte{append([]byte{1}, "b"...)}), "b", true, true},
+		{"legacy cancel", encodeFrames([][]byte{append([]byte{0}, "b"...)}), "b", false, true},
+		{"message", encodeFrames([][]byte{[]byte("b")}), "", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := readMessage(bufio.NewReader(bytes.NewReader(tt.raw)))
+			if err != nil {
+				t.Fatalf("readMessage() failed: %v", err)
+			}
+			topic, subscribe, ok := m.subscription()
+			if string(topic) != tt.topic || subscribe != tt.subscribe || ok != tt.ok {
+				t.Errorf("subscription() = %q, %v, %v, want %q, %v, %v", topic, subscribe, ok, tt.topic, tt.subscribe, tt.ok)
+			}
+		})
+	}
+}
+
+func TestReadMessageMalformed(t *testing.T) {
+	oversized := binary.BigEndian.AppendUint64([]byte{flagLong}, maxFrameSize+1)
+	tests := []struct {
+		name string
+		raw  []byte
+	}{
+		{"oversized frame", oversized},
+		{"truncated frame", []byte{0, 5, 'a'}},
+		{"truncated multipart message", encodeFrame(flagMore, []byte("a"))},
+		{"command within a multipart message", append(encodeFrame(flagMore, []byte("a")), encodeCommand(cmdPing, nil)...)},
+		{"command with a truncated name", encodeFrame(flagCommand, []byte{5, 'P'})},
+		{"truncated metadata", encodeCommand(cmdReady, []byte{11, 'S'})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := readMessage(bufio.NewReader(bytes.NewReader(tt.raw)))
+			if err == nil {
+				_, err = decodeMetadata(m.data)
+			}
+			if err == nil || errors.Is(err, io.EOF) {
+				t.Errorf("readMessage() of % x = %v, want a malformed message error", tt.raw, err)
+			}
+		})
+	}
+}
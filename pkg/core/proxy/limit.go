@@ -0,0 +1,76 @@
+//go:build linux
+
+package proxy
+
+import (
+	"fmt"
+	"net"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+)
+
+// connStats counts the proxied connections, they are exposed by the admin api.
+type connStats struct {
+	active   int64
+	peak     int64
+	accepted int64
+	rejected int64
+}
+
+// ConnStats is the snapshot of the proxied connection counters.
+type ConnStats struct {
+	Active   int64  `json:"active"`
+	Peak     int64  `json:"peak"`
+	Accepted int64  `json:"accepted"`
+	Rejected int64  `json:"rejected"`
+	Max      uint32 `json:"max"`
+}
+
+// admitConn reserves a slot for a new connection, it fails when the proxy already handles maxConns connections.
+func (p *Proxy) admitConn() bool {
+	active := atomic.AddInt64(&p.connStats.active, 1)
+	if p.maxConns > 0 && active > int64(p.maxConns) {
+		atomic.AddInt64(&p.connStats.active, -1)
+		return false
+	}
+	atomic.AddInt64(&p.connStats.accepted, 1)
+	for {
+		peak := atomic.LoadInt64(&p.connStats.peak)
+		if active <= peak || atomic.CompareAndSwapInt64(&p.connStats.peak, peak, active) {
+			return true
+		}
+	}
+}
+
+func (p *Proxy) releaseConn() {
+	atomic.AddInt64(&p.connStats.active, -1)
+}
+
+// rejectConn closes a connection over the limit right away, so that the application gets an error it can retry on
+// instead of a connection which hangs.
+func (p *Proxy) rejectConn(conn net.Conn) {
+	rejected := atomic.AddInt64(&p.connStats.rejected, 1)
+	if tcpConn, ok := conn.(*net.TCPConn); ok {
+		// reset the connection instead of waiting for the application to close it
+		_ = tcpConn.SetLinger(0)
+	}
+	if err := conn.Close(); err != nil {
+		p.logger.Debug("failed to close the rejected connection", zap.Error(err))
+	}
+	if rejected == 1 {
+		p.logger.Warn(fmt.Sprintf("the application opened more than %d concurrent connections, the connections over the limit are rejected", p.maxConns), zap.String("src", conn.RemoteAddr().String()))
+		return
+	}
+	p.logger.Debug("rejected a connection over the limit", zap.Int64("rejected", rejected), zap.String("src", conn.RemoteAddr().String()))
+}
+
+func (p *Proxy) snapshotConnStats() ConnStats {
+	return ConnStats{
+		Active:   atomic.LoadInt64(&p.connStats.active),
+		Peak:     atomic.LoadInt64(&p.connStats.peak),
+		Accepted: atomic.LoadInt64(&p.connStats.accepted),
+		Rejected: atomic.LoadInt64(&p.connStats.rejected),
+		Max:      p.maxConns,
+	}
+}
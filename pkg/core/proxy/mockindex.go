@@ -0,0 +1,96 @@
+//go:build linux
+
+package proxy
+
+import (
+	"sort"
+	"sync"
+
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+type indexKey struct {
+	kind models.Kind
+	key  string
+}
+
+// mockIndex indexes the mocks of a TreeDb on their kind and request signature. It only holds the keys of the
+// mocks in the tree, so the tree stays the source of truth for the mocks themselves.
+type mockIndex struct {
+	mutex   sync.RWMutex
+	buckets map[indexKey]map[models.TestModeInfo]struct{}
+	keys    map[models.TestModeInfo]indexKey
+}
+
+func newMockIndex() *mockIndex {
+	return &mockIndex{
+		buckets: map[indexKey]map[models.TestModeInfo]struct{}{},
+		keys:    map[models.TestModeInfo]indexKey{},
+	}
+}
+
+func (idx *mockIndex) insert(mock *models.Mock) {
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+	idx.insertLocked(mock)
+}
+
+func (idx *mockIndex) insertLocked(mock *models.Mock) {
+	k := indexKey{kind: mock.Kind, key: integrations.MockKey(mock)}
+	bucket, ok := idx.buckets[k]
+	if !ok {
+		bucket = map[models.TestModeInfo]struct{}{}
+		idx.buckets[k] = bucket
+	}
+	bucket[mock.TestModeInfo] = struct{}{}
+	idx.keys[mock.TestModeInfo] = k
+}
+
+func (idx *mockIndex) delete(info models.TestModeInfo) {
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+	idx.deleteLocked(info)
+}
+
+func (idx *mockIndex) deleteLocked(info models.TestModeInfo) {
+	k, ok := idx.keys[info]
+	if !ok {
+		return
+	}
+	delete(idx.keys, info)
+	delete(idx.buckets[k], info)
+	if len(idx.buckets[k]) == 0 {
+		delete(idx.buckets, k)
+	}
+}
+
+func (idx *mockIndex) update(old models.TestModeInfo, mock *models.Mock) {
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+	idx.deleteLocked(old)
+	idx.insertLocked(mock)
+}
+
+func (idx *mockIndex) deleteAll() {
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+	idx.buckets = map[indexKey]map[models.TestModeInfo]struct{}{}
+	idx.keys = map[models.TestModeInfo]indexKey{}
+}
+
+// lookup returns the tree keys of the mocks with the given kind and signature, in the order of the tree.
+func (idx *mockIndex) lookup(kind models.Kind, key string) []models.TestModeInfo {
+	idx.mutex.RLock()
+	bucket := idx.buckets[indexKey{kind: kind, key: key}]
+	infos := make([]models.TestModeInfo, 0, len(bucket))
+	for info := range bucket {
+		infos = append(infos, info)
+	}
+	idx.mutex.RUnlock()
+
+	sort.Slice(infos, func(i, j int) bool {
+		return customComparator(infos[i], infos[j]) < 0
+	})
+	return infos
+}
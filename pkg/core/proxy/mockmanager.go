@@ -16,76 +16,151 @@ import (
 type MockManager struct {
 	filtered      *TreeDb
 	unfiltered    *TreeDb
+	filteredIdx   *mockIndex
+	unfilteredIdx *mockIndex
+	// store keeps the mocks of both the trees within the memory budget of the manager
+	store         *mockStore
 	logger        *zap.Logger
 	consumedMocks sync.Map
 }
 
-func NewMockManager(filtered, unfiltered *TreeDb, logger *zap.Logger) *MockManager {
+// NewMockManager creates the mock manager of a session. When memLimit is positive, the mocks over that many
+// bytes are evicted from memory to a temporary file and read back when they are looked up.
+func NewMockManager(filtered, unfiltered *TreeDb, memLimit int64, logger *zap.Logger) *MockManager {
 	return &MockManager{
 		filtered:      filtered,
 		unfiltered:    unfiltered,
+		filteredIdx:   newMockIndex(),
+		unfilteredIdx: newMockIndex(),
+		store:         newMockStore(memLimit, logger),
 		logger:        logger,
 		consumedMocks: sync.Map{},
 	}
 }
 
 func (m *MockManager) SetFilteredMocks(mocks []*models.Mock) {
+	for _, obj := range m.filtered.getAll() {
+		m.release(obj)
+	}
 	m.filtered.deleteAll()
+	m.filteredIdx.deleteAll()
 	for index, mock := range mocks {
 		mock.TestModeInfo.SortOrder = index
 		mock.TestModeInfo.ID = index
-		m.filtered.insert(mock.TestModeInfo, mock)
+		m.filtered.insert(mock.TestModeInfo, m.store.add(mock))
+		m.filteredIdx.insert(mock)
 	}
 }
 
 func (m *MockManager) SetUnFilteredMocks(mocks []*models.Mock) {
+	for _, obj := range m.unfiltered.getAll() {
+		m.release(obj)
+	}
 	m.unfiltered.deleteAll()
+	m.unfilteredIdx.deleteAll()
 	for index, mock := range mocks {
 		mock.TestModeInfo.SortOrder = index
 		mock.TestModeInfo.ID = index
-		m.unfiltered.insert(mock.TestModeInfo, mock)
+		m.unfiltered.insert(mock.TestModeInfo, m.store.add(mock))
+		m.unfilteredIdx.insert(mock)
 	}
 }
 
+// SetMocks replaces both the filtered and the unfiltered mocks, the spill file of the previous mocks is reused.
+func (m *MockManager) SetMocks(filtered, unFiltered []*models.Mock) {
+	m.store.reset()
+	m.SetFilteredMocks(filtered)
+	m.SetUnFilteredMocks(unFiltered)
+}
+
 func (m *MockManager) GetFilteredMocks() ([]*models.Mock, error) {
-	var tcsMocks []*models.Mock
-	mocks := m.filtered.getAll()
-	//sending copy of mocks instead of actual mocks
-	mockCopy, err := localMock(mocks)
-	if err != nil {
-		return nil, fmt.Errorf("expected mock instance, got %v", m)
+	return m.getAll(m.filtered)
+}
+
+func (m *MockManager) GetUnFilteredMocks() ([]*models.Mock, error) {
+	return m.getAll(m.unfiltered)
+}
+
+func (m *MockManager) getAll(db *TreeDb) ([]*models.Mock, error) {
+	var mocks []*models.Mock
+	for _, obj := range db.getAll() {
+		mock, err := m.load(obj, true)
+		if err != nil {
+			return nil, err
+		}
+		//sending copy of mocks instead of actual mocks
+		mockCopy := *mock
+		mocks = append(mocks, &mockCopy)
 	}
-	for _, m := range mockCopy {
-		tcsMocks = append(tcsMocks, &m)
+	return mocks, nil
+}
+
+// GetFilteredMocksByKey returns the filtered mocks of the kind with the given request signature.
+func (m *MockManager) GetFilteredMocksByKey(kind models.Kind, key string) ([]*models.Mock, error) {
+	return m.lookupMocks(m.filtered, m.filteredIdx, kind, key)
+}
+
+// GetUnFilteredMocksByKey returns the unfiltered mocks of the kind with the given request signature.
+func (m *MockManager) GetUnFilteredMocksByKey(kind models.Kind, key string) ([]*models.Mock, error) {
+	return m.lookupMocks(m.unfiltered, m.unfilteredIdx, kind, key)
+}
+
+func (m *MockManager) lookupMocks(db *TreeDb, idx *mockIndex, kind models.Kind, key string) ([]*models.Mock, error) {
+	var mocks []*models.Mock
+	for _, info := range idx.lookup(kind, key) {
+		obj, ok := db.get(info)
+		if !ok {
+			// deleted since the lookup
+			continue
+		}
+		mock, err := m.load(obj, false)
+		if err != nil {
+			return nil, err
+		}
+		//sending copy of mocks instead of actual mocks
+		mockCopy := *mock
+		mocks = append(mocks, &mockCopy)
 	}
-	return tcsMocks, nil
+	return mocks, nil
 }
 
-func (m *MockManager) GetUnFilteredMocks() ([]*models.Mock, error) {
-	var configMocks []*models.Mock
-	mocks := m.unfiltered.getAll()
-	//sending copy of mocks instead of actual mocks
-	mockCopy, err := localMock(mocks)
-	if err != nil {
-		return nil, fmt.Errorf("expected mock instance, got %v", m)
+// load returns the mock of a tree value, reading it back from the spill file if it was evicted.
+func (m *MockManager) load(obj interface{}, peek bool) (*models.Mock, error) {
+	sm, ok := obj.(*storedMock)
+	if !ok {
+		return nil, fmt.Errorf("expected mock instance, got %v", obj)
 	}
-	for _, m := range mockCopy {
-		configMocks = append(configMocks, &m)
+	return m.store.get(sm, peek)
+}
+
+// release releases the memory accounted for a tree value which was removed from its tree.
+func (m *MockManager) release(obj interface{}) {
+	if sm, ok := obj.(*storedMock); ok {
+		m.store.remove(sm)
 	}
-	return configMocks, nil
+}
+
+// Close removes the spill file of the mocks, the manager must not be used after it.
+func (m *MockManager) Close() {
+	m.store.close()
 }
 
 func (m *MockManager) UpdateUnFilteredMock(old *models.Mock, new *models.Mock) bool {
-	updated := m.unfiltered.update(old.TestModeInfo, new.TestModeInfo, new)
-	if updated {
-		// mark the unfiltered mock as used for the current simulated test-case
-		go func() {
-			if err := m.FlagMockAsUsed(*old); err != nil {
-				m.logger.Error("failed to flag mock as used", zap.Error(err))
-			}
-		}()
+	sm := m.store.add(new)
+	obj, updated := m.unfiltered.update(old.TestModeInfo, new.TestModeInfo, sm)
+	if !updated {
+		m.store.remove(sm)
+		return false
 	}
-	return updated
+	m.release(obj)
+	m.unfilteredIdx.update(old.TestModeInfo, new)
+	// mark the unfiltered mock as used for the current simulated test-case
+	go func() {
+		if err := m.FlagMockAsUsed(*old); err != nil {
+			m.logger.Error("failed to flag mock as used", zap.Error(err))
+		}
+	}()
+	return true
 }
 
 func (m *MockManager) FlagMockAsUsed(mock models.Mock) error {
@@ -97,8 +172,10 @@ func (m *MockManager) FlagMockAsUsed(mock models.Mock) error {
 }
 
 func (m *MockManager) DeleteFilteredMock(mock models.Mock) bool {
-	isDeleted := m.filtered.delete(mock.TestModeInfo)
+	obj, isDeleted := m.filtered.delete(mock.TestModeInfo)
 	if isDeleted {
+		m.release(obj)
+		m.filteredIdx.delete(mock.TestModeInfo)
 		go func() {
 			if err := m.FlagMockAsUsed(mock); err != nil {
 				m.logger.Error("failed to flag mock as used", zap.Error(err))
@@ -109,8 +186,10 @@ func (m *MockManager) DeleteFilteredMock(mock models.Mock) bool {
 }
 
 func (m *MockManager) DeleteUnFilteredMock(mock models.Mock) bool {
-	isDeleted := m.unfiltered.delete(mock.TestModeInfo)
+	obj, isDeleted := m.unfiltered.delete(mock.TestModeInfo)
 	if isDeleted {
+		m.release(obj)
+		m.unfilteredIdx.delete(mock.TestModeInfo)
 		go func() {
 			if err := m.FlagMockAsUsed(mock); err != nil {
 				m.logger.Error("failed to flag mock as used", zap.Error(err))
@@ -0,0 +1,216 @@
+//go:build linux
+
+package proxy
+
+import (
+	"bytes"
+	"container/list"
+	"encoding/gob"
+	"fmt"
+	"os"
+	"sync"
+
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/pkg/models/mysql"
+	"go.uber.org/zap"
+)
+
+func init() {
+	// the concrete types of the mongo and mysql messages, so that their mocks can be written to the spill file
+	for _, msg := range []interface{}{
+		&models.MongoOpMessage{}, &models.MongoOpQuery{}, &models.MongoOpReply{},
+		&mysql.HandshakeV10Packet{}, &mysql.HandshakeResponse41Packet{}, &mysql.AuthSwitchRequestPacket{},
+		&mysql.AuthMoreDataPacket{}, &mysql.AuthNextFactorPacket{}, &mysql.OKPacket{}, &mysql.ERRPacket{},
+		&mysql.EOFPacket{}, &mysql.QuitPacket{}, &mysql.InitDBPacket{}, &mysql.StatisticsPacket{},
+		&mysql.DebugPacket{}, &mysql.PingPacket{}, &mysql.ChangeUserPacket{}, &mysql.ResetConnectionPacket{},
+		&mysql.QueryPacket{}, &mysql.TextResultSet{}, &mysql.BinaryProtocolResultSet{}, &mysql.StmtPreparePacket{},
+		&mysql.StmtPrepareOkPacket{}, &mysql.StmtExecutePacket{}, &mysql.StmtClosePacket{},
+		&mysql.StmtResetPacket{}, &mysql.StmtSendLongDataPacket{},
+	} {
+		gob.Register(msg)
+	}
+}
+
+// typeInfoSize is the size of the type descriptors repeated by every encoded mock, it isn't accounted for in
+// the memory usage of the mocks.
+var typeInfoSize = sync.OnceValue(func() int64 {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&models.Mock{}); err != nil {
+		return 0
+	}
+	return int64(buf.Len())
+})
+
+// storedMock is the value kept in the TreeDb for a mock. The mock is dropped from memory when it is evicted
+// and read back from its copy in the spill file on the next lookup.
+type storedMock struct {
+	mock *models.Mock
+	name string
+	// size is the encoded size of the mock without the type descriptors, used as an estimate of its memory usage
+	size   int64
+	offset int64
+	length int64
+	// elem is the element of the mock in the lru of the store, only the mocks with a copy in the spill file
+	// are in it since the others can't be evicted
+	elem *list.Element
+	// gen is the generation of the store the mock was added in, the spill file is reused after a reset
+	gen uint64
+}
+
+// mockStore keeps the mocks of a MockManager within a memory budget. Every mock gets a copy in a temporary
+// spill file when a budget is set, and the least recently used mocks over the budget are evicted from memory.
+// Without a budget the mocks simply stay in memory.
+type mockStore struct {
+	mutex  sync.Mutex
+	logger *zap.Logger
+	limit  int64
+	size   int64
+	// lru holds the resident mocks which can be evicted, the most recently used at the front
+	lru  *list.List
+	file *os.File
+	end  int64
+	gen  uint64
+}
+
+func newMockStore(limit int64, logger *zap.Logger) *mockStore {
+	return &mockStore{
+		logger: logger,
+		limit:  limit,
+		lru:    list.New(),
+	}
+}
+
+// add wraps the mock to be kept in a TreeDb, evicting the cold mocks if the budget is exceeded.
+func (s *mockStore) add(mock *models.Mock) *storedMock {
+	sm := &storedMock{mock: mock, name: mock.Name}
+	if s.limit <= 0 {
+		return sm
+	}
+
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(mock)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	sm.gen = s.gen
+	if err == nil {
+		err = s.write(sm, buf.Bytes())
+	}
+	if err != nil {
+		// keep the mock in memory, it just can't be evicted
+		s.logger.Debug("failed to spill the mock to disk, keeping it in memory", zap.String("mock", mock.Name), zap.Error(err))
+		return sm
+	}
+	s.size += sm.size
+	sm.elem = s.lru.PushFront(sm)
+	s.evict()
+	return sm
+}
+
+func (s *mockStore) write(sm *storedMock, data []byte) error {
+	if s.file == nil {
+		file, err := os.CreateTemp("", "keploy-mocks-*")
+		if err != nil {
+			return err
+		}
+		s.file, s.end = file, 0
+	}
+	if _, err := s.file.WriteAt(data, s.end); err != nil {
+		return err
+	}
+	sm.offset, sm.length = s.end, int64(len(data))
+	sm.size = max(sm.length-typeInfoSize(), 1)
+	s.end += sm.length
+	return nil
+}
+
+// evict drops the least recently used mocks from memory until the store is back within its budget.
+func (s *mockStore) evict() {
+	for s.size > s.limit {
+		elem := s.lru.Back()
+		if elem == nil {
+			return
+		}
+		sm := elem.Value.(*storedMock)
+		s.lru.Remove(elem)
+		sm.elem = nil
+		sm.mock = nil
+		s.size -= sm.size
+	}
+}
+
+// get returns the mock, reading it back from the spill file if it was evicted. The mocks read back are made
+// resident again unless peek is set, which is used when all the mocks are listed so that a full scan doesn't
+// evict the mocks in use.
+func (s *mockStore) get(sm *storedMock, peek bool) (*models.Mock, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if sm.mock != nil {
+		if sm.elem != nil && !peek {
+			s.lru.MoveToFront(sm.elem)
+		}
+		return sm.mock, nil
+	}
+	if s.file == nil || sm.gen != s.gen {
+		return nil, fmt.Errorf("the mock %s is no longer in the spill file", sm.name)
+	}
+
+	data := make([]byte, sm.length)
+	if _, err := s.file.ReadAt(data, sm.offset); err != nil {
+		return nil, fmt.Errorf("failed to read the mock %s from the spill file: %v", sm.name, err)
+	}
+	mock := &models.Mock{}
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(mock); err != nil {
+		return nil, fmt.Errorf("failed to decode the mock %s from the spill file: %v", sm.name, err)
+	}
+	if peek {
+		return mock, nil
+	}
+	sm.mock = mock
+	s.size += sm.size
+	sm.elem = s.lru.PushFront(sm)
+	s.evict()
+	return mock, nil
+}
+
+// remove releases the memory accounted for a mock which was removed from its TreeDb.
+func (s *mockStore) remove(sm *storedMock) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if sm.elem != nil {
+		s.lru.Remove(sm.elem)
+		sm.elem = nil
+		s.size -= sm.size
+	}
+}
+
+// reset forgets all the mocks, the spill file is truncated to be reused by the next mocks.
+func (s *mockStore) reset() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for elem := s.lru.Front(); elem != nil; elem = elem.Next() {
+		elem.Value.(*storedMock).elem = nil
+	}
+	s.lru.Init()
+	s.size = 0
+	s.gen++
+	if s.file != nil {
+		if err := s.file.Truncate(0); err != nil {
+			s.logger.Debug("failed to truncate the spill file of the mocks", zap.Error(err))
+		}
+		s.end = 0
+	}
+}
+
+// close removes the spill file, the evicted mocks can't be read back after it.
+func (s *mockStore) close() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.file == nil {
+		return
+	}
+	name := s.file.Name()
+	_ = s.file.Close()
+	_ = os.Remove(name)
+	s.file = nil
+}
@@ -4,11 +4,22 @@ package proxy
 
 import (
 	// import all the integrations
+	_ "go.keploy.io/server/v2/pkg/core/proxy/integrations/amqp1"
+	_ "go.keploy.io/server/v2/pkg/core/proxy/integrations/dns"
 	_ "go.keploy.io/server/v2/pkg/core/proxy/integrations/generic"
 	_ "go.keploy.io/server/v2/pkg/core/proxy/integrations/grpc"
 	_ "go.keploy.io/server/v2/pkg/core/proxy/integrations/http"
+	_ "go.keploy.io/server/v2/pkg/core/proxy/integrations/kafka"
+	_ "go.keploy.io/server/v2/pkg/core/proxy/integrations/ldap"
 	_ "go.keploy.io/server/v2/pkg/core/proxy/integrations/mongo"
+	_ "go.keploy.io/server/v2/pkg/core/proxy/integrations/mqtt"
+	_ "go.keploy.io/server/v2/pkg/core/proxy/integrations/mssql"
 	_ "go.keploy.io/server/v2/pkg/core/proxy/integrations/mysql"
+	_ "go.keploy.io/server/v2/pkg/core/proxy/integrations/mysqlx"
+	_ "go.keploy.io/server/v2/pkg/core/proxy/integrations/openwire"
 	_ "go.keploy.io/server/v2/pkg/core/proxy/integrations/postgres/v1"
 	_ "go.keploy.io/server/v2/pkg/core/proxy/integrations/redis"
+	_ "go.keploy.io/server/v2/pkg/core/proxy/integrations/thrift"
+	_ "go.keploy.io/server/v2/pkg/core/proxy/integrations/websocket"
+	_ "go.keploy.io/server/v2/pkg/core/proxy/integrations/zeromq"
 )
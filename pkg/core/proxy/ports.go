@@ -0,0 +1,110 @@
+//go:build linux
+
+package proxy
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"go.keploy.io/server/v2/config"
+	"go.uber.org/zap"
+)
+
+// fallbackPorts is the number of ports after the configured one which are tried when no range is configured.
+const fallbackPorts = 100
+
+// SelectPorts checks that the proxy and dns ports of the config can be listened on, and picks free ports of
+// their ranges in place of the ones in use. The config is updated with the selected ports, so it has to be
+// called before the hooks and the proxy are created since both use them.
+func SelectPorts(logger *zap.Logger, cfg *config.Config) error {
+	// the ports which are already taken by keploy itself
+	taken := map[uint32]bool{}
+	if cfg.ProxyAdminPort != 0 {
+		taken[cfg.ProxyAdminPort] = true
+	}
+
+	port, err := selectPort("proxy", cfg.ProxyPort, cfg.ProxyPortRange, taken, false)
+	if err != nil {
+		return err
+	}
+	if port != cfg.ProxyPort {
+		logger.Warn("the proxy port is already in use, using another port", zap.Uint32("port", cfg.ProxyPort), zap.Uint32("selected", port))
+		cfg.ProxyPort = port
+	}
+	taken[port] = true
+
+	port, err = selectPort("dns", cfg.DNSPort, cfg.DNSPortRange, taken, true)
+	if err != nil {
+		return err
+	}
+	if port != cfg.DNSPort {
+		logger.Warn("the dns port is already in use, using another port", zap.Uint32("port", cfg.DNSPort), zap.Uint32("selected", port))
+		cfg.DNSPort = port
+	}
+	return nil
+}
+
+// selectPort returns the port if it is free, or the first free port of the range otherwise. The ports after
+// the given one are tried when no range is set.
+func selectPort(name string, port uint32, portRange string, taken map[uint32]bool, udp bool) (uint32, error) {
+	if !taken[port] && isPortFree(port, udp) {
+		return port, nil
+	}
+
+	first, last := port+1, port+fallbackPorts
+	if portRange != "" {
+		var err error
+		first, last, err = parsePortRange(portRange)
+		if err != nil {
+			return 0, fmt.Errorf("invalid %s port range %q: %v", name, portRange, err)
+		}
+	}
+	for p := first; p <= last && p <= 65535; p++ {
+		if !taken[p] && isPortFree(p, udp) {
+			return p, nil
+		}
+	}
+	return 0, fmt.Errorf("the %s port %d is already in use and no free port was found in %d-%d, free the port or configure another %s port range", name, port, first, last, name)
+}
+
+// parsePortRange parses a range of ports written as "first-last".
+func parsePortRange(portRange string) (uint32, uint32, error) {
+	bounds := strings.SplitN(portRange, "-", 2)
+	if len(bounds) != 2 {
+		return 0, 0, fmt.Errorf("expected first-last")
+	}
+	first, err := strconv.ParseUint(strings.TrimSpace(bounds[0]), 10, 16)
+	if err != nil {
+		return 0, 0, err
+	}
+	last, err := strconv.ParseUint(strings.TrimSpace(bounds[1]), 10, 16)
+	if err != nil {
+		return 0, 0, err
+	}
+	if first == 0 || first > last {
+		return 0, 0, fmt.Errorf("the first port must be positive and not greater than the last one")
+	}
+	return uint32(first), uint32(last), nil
+}
+
+// isPortFree reports whether the tcp port, and the udp one if asked, can be listened on. The listeners of
+// the proxy set SO_REUSEPORT, but the check doesn't so that a port shared with another process is detected.
+func isPortFree(port uint32, udp bool) bool {
+	addr := fmt.Sprintf(":%v", port)
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return false
+	}
+	_ = ln.Close()
+	if !udp {
+		return true
+	}
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return false
+	}
+	_ = conn.Close()
+	return true
+}
@@ -11,6 +11,7 @@ import (
 	"fmt"
 	"io"
 	"net"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -21,26 +22,53 @@ import (
 	"go.keploy.io/server/v2/config"
 	"go.keploy.io/server/v2/pkg/core"
 	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
+	"go.keploy.io/server/v2/pkg/core/proxy/integrations/plugin"
 
 	"go.keploy.io/server/v2/pkg/core/proxy/util"
 	"go.keploy.io/server/v2/pkg/models"
 	"go.keploy.io/server/v2/utils"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
 type Proxy struct {
 	logger *zap.Logger
 
-	IP4     string
-	IP6     string
-	Port    uint32
-	DNSPort uint32
+	IP4       string
+	IP6       string
+	Port      uint32
+	DNSPort   uint32
+	AdminPort uint32
 
 	DestInfo     core.DestInfo
 	Integrations map[string]integrations.Integrations
+	// pluginDir is the directory from which the out-of-process protocol parsers are loaded
+	pluginDir string
+	// plugins are the out-of-process protocol parsers registered in the config
+	plugins []config.Plugin
+	// integrationsCfg holds the disabled integrations and the per port detection order
+	integrationsCfg config.Integrations
 
 	MockManagers sync.Map
 
+	// connections holds the live proxied connections, exposed by the admin api
+	connections sync.Map
+	// maxConns bounds the concurrent proxied connections, the connections over it are rejected
+	maxConns  uint32
+	connStats connStats
+	// resolver resolves the server names dialed in record mode
+	resolver *resolver
+	// dnsNames holds the names resolved by the dns server, to match the conns against the bypassed domains
+	dnsNames dnsNames
+	// recordedDNS holds the questions recorded in the current record session, a question is recorded once
+	recordedDNS sync.Map
+	// mcMutex guards the mock channels of the sessions against their closing, for the senders which outlive the
+	// conns of the proxy like the dns server
+	mcMutex  sync.RWMutex
+	mcClosed bool
+	// mockMemLimit is the memory budget in bytes of the mocks of a session, the mocks over it are spilled to disk
+	mockMemLimit int64
+
 	sessions *core.Sessions
 
 	connMutex *sync.Mutex
@@ -57,27 +85,88 @@ type Proxy struct {
 }
 
 func New(logger *zap.Logger, info core.DestInfo, opts *config.Config) *Proxy {
-	return &Proxy{
-		logger:       logger,
-		Port:         opts.ProxyPort, // default: 16789
-		DNSPort:      opts.DNSPort,   // default: 26789
-		IP4:          "127.0.0.1",    // default: "127.0.0.1" <-> (2130706433)
-		IP6:          "::1",          //default: "::1" <-> ([4]uint32{0000, 0000, 0000, 0001})
-		ipMutex:      &sync.Mutex{},
-		connMutex:    &sync.Mutex{},
-		DestInfo:     info,
-		sessions:     core.NewSessions(),
-		MockManagers: sync.Map{},
-		Integrations: make(map[string]integrations.Integrations),
-	}
+	p := &Proxy{
+		logger:          logger,
+		Port:            opts.ProxyPort, // default: 16789
+		DNSPort:         opts.DNSPort,   // default: 26789
+		AdminPort:       opts.ProxyAdminPort,
+		maxConns:        opts.MaxProxyConns,
+		mockMemLimit:    int64(opts.Test.MockMemLimit) << 20,
+		IP4:             "127.0.0.1", // default: "127.0.0.1" <-> (2130706433)
+		IP6:             "::1",       //default: "::1" <-> ([4]uint32{0000, 0000, 0000, 0001})
+		ipMutex:         &sync.Mutex{},
+		connMutex:       &sync.Mutex{},
+		DestInfo:        info,
+		sessions:        core.NewSessions(),
+		MockManagers:    sync.Map{},
+		Integrations:    make(map[string]integrations.Integrations),
+		pluginDir:       opts.PluginDir,
+		plugins:         opts.Plugins,
+		integrationsCfg: opts.Integrations,
+	}
+	p.resolver = newResolver(p.logger, opts.Resolver)
+
+	if p.AdminPort != 0 {
+		// record the errors logged for the proxied connections to expose them in the admin api
+		p.logger = logger.WithOptions(zap.WrapCore(func(c zapcore.Core) zapcore.Core {
+			return zapcore.NewTee(c, &connErrorCore{LevelEnabler: zapcore.ErrorLevel, proxy: p})
+		}))
+	}
+	return p
 }
 
-func (p *Proxy) InitIntegrations(_ context.Context) error {
+func (p *Proxy) InitIntegrations(ctx context.Context) error {
 	// initialize the integrations
 	for parserType, parser := range integrations.Registered {
+		if p.isDisabled(parserType) {
+			p.logger.Info("integration is disabled in the config", zap.String("integration", parserType))
+			continue
+		}
 		prs := parser(p.logger)
 		p.Integrations[parserType] = prs
 	}
+
+	if p.pluginDir != "" || len(p.plugins) > 0 {
+		err := p.loadPlugins(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	p.validateIntegrationsCfg()
+	return nil
+}
+
+func (p *Proxy) loadPlugins(ctx context.Context) error {
+
+	// load the out-of-process protocol parsers, the ones of the config first so that they win over the
+	// executables of the directory with the same name
+	plugins := plugin.LoadConfigured(ctx, p.logger, p.plugins)
+	if p.pluginDir != "" {
+		dirPlugins, err := plugin.Load(ctx, p.logger, p.pluginDir)
+		if err != nil {
+			utils.LogError(p.logger, err, "failed to load the protocol plugins", zap.String("dir", p.pluginDir))
+			return err
+		}
+		for name, prs := range dirPlugins {
+			if _, ok := plugins[name]; ok {
+				p.logger.Warn("a plugin with the same name is registered in the config, skipping", zap.String("plugin", name))
+				continue
+			}
+			plugins[name] = prs
+		}
+	}
+	for name, prs := range plugins {
+		if p.isDisabled(name) {
+			p.logger.Info("protocol plugin is disabled in the config", zap.String("plugin", name))
+			continue
+		}
+		if _, ok := p.Integrations[name]; ok {
+			p.logger.Warn("protocol plugin has the same name as a built-in integration, skipping", zap.String("plugin", name))
+			continue
+		}
+		p.Integrations[name] = prs
+	}
 	return nil
 }
 
@@ -121,6 +210,18 @@ func (p *Proxy) StartProxy(ctx context.Context, opts core.ProxyOptions) error {
 		p.IP6 = opts.DNSIPv6Addr
 	}
 
+	// start the admin api for debugging the live connections
+	if p.AdminPort != 0 {
+		g.Go(func() error {
+			defer utils.Recover(p.logger)
+			err := p.startAdminServer(ctx)
+			if err != nil {
+				utils.LogError(p.logger, err, "failed to start the proxy admin api")
+			}
+			return nil
+		})
+	}
+
 	// start the TCP DNS server
 	p.logger.Debug("Starting Tcp Dns Server for handling Dns queries over TCP")
 	g.Go(func() error {
@@ -208,11 +309,14 @@ func (p *Proxy) start(ctx context.Context) error {
 			p.logger.Debug("failed to handle the client connection", zap.Error(err))
 		}
 		//closing all the mock channels (if any in record mode)
+		p.mcMutex.Lock()
+		p.mcClosed = true
 		for _, mc := range p.sessions.GetAllMC() {
 			if mc != nil {
 				close(mc)
 			}
 		}
+		p.mcMutex.Unlock()
 
 		if string(p.nsswitchData) != "" {
 			// reset the hosts config in nsswitch.conf of the system (in test mode)
@@ -247,8 +351,13 @@ func (p *Proxy) start(ctx context.Context) error {
 			return err
 		// handle the client connection
 		case clientConn := <-clientConnCh:
+			if !p.admitConn() {
+				p.rejectConn(clientConn)
+				continue
+			}
 			clientConnErrGrp.Go(func() error {
 				defer util.Recover(p.logger, clientConn, nil)
+				defer p.releaseConn()
 				err := p.handleConnection(clientConnCtx, clientConn)
 				if err != nil && err != io.EOF {
 					utils.LogError(p.logger, err, "failed to handle the client connection")
@@ -302,21 +411,27 @@ func (p *Proxy) handleConnection(ctx context.Context, srcConn net.Conn) error {
 		return err
 	}
 
-	var dstAddr string
+	var dstIP net.IP
 
 	if destInfo.Version == 4 {
-		dstAddr = fmt.Sprintf("%v:%v", util.ToIP4AddressStr(destInfo.IPv4Addr), destInfo.Port)
+		dstIP = net.ParseIP(util.ToIP4AddressStr(destInfo.IPv4Addr))
 		p.logger.Debug("", zap.Any("DestIp4", destInfo.IPv4Addr), zap.Any("DestPort", destInfo.Port))
 	} else if destInfo.Version == 6 {
-		dstAddr = fmt.Sprintf("[%v]:%v", util.ToIPv6AddressStr(destInfo.IPv6Addr), destInfo.Port)
+		dstIP = util.ToIPv6Address(destInfo.IPv6Addr)
 		p.logger.Debug("", zap.Any("DestIp6", destInfo.IPv6Addr), zap.Any("DestPort", destInfo.Port))
 	}
+	// JoinHostPort encloses the IPv6 addresses in brackets
+	dstAddr := net.JoinHostPort(dstIP.String(), fmt.Sprint(destInfo.Port))
+
+	live := p.trackConn(clientConnID, srcConn, dstAddr, rule.Mode)
+	defer p.untrackConn(live)
 
 	// This is used to handle the parser errors
 	parserErrGrp, parserCtx := errgroup.WithContext(ctx)
 	parserCtx = context.WithValue(parserCtx, models.ErrGroupKey, parserErrGrp)
 	parserCtx = context.WithValue(parserCtx, models.ClientConnectionIDKey, fmt.Sprint(clientConnID))
 	parserCtx = context.WithValue(parserCtx, models.DestConnectionIDKey, fmt.Sprint(destConnID))
+	parserCtx = context.WithValue(parserCtx, models.TLSUpgraderKey, integrations.TLSUpgrader(&tlsUpgrader{p: p}))
 	parserCtx, parserCtxCancel := context.WithCancel(parserCtx)
 	defer func() {
 		parserCtxCancel()
@@ -362,8 +477,17 @@ func (p *Proxy) handleConnection(ctx context.Context, srcConn net.Conn) error {
 		return nil
 	}
 
+	// integrations configured for the destination port, in their detection order
+	portIntegrations := p.portIntegrations(uint(destInfo.Port))
+
 	//checking for the destination port of "mysql"
-	if destInfo.Port == 3306 {
+	if _, ok := p.Integrations[string(integrations.MYSQL)]; ok && len(portIntegrations) > 0 && portIntegrations[0] == string(integrations.MYSQL) {
+		live.setProtocol(string(integrations.MYSQL))
+		srcConn = &tapConn{Conn: srcConn, info: live}
+
+		opts := rule.OutgoingOptions
+		opts.MySQL = p.integrationsCfg.MySQL
+
 		if rule.Mode != models.MODE_TEST {
 			dstConn, err = net.Dial("tcp", dstAddr)
 			if err != nil {
@@ -371,7 +495,7 @@ func (p *Proxy) handleConnection(ctx context.Context, srcConn net.Conn) error {
 				return err
 			}
 			// Record the outgoing message into a mock
-			err := p.Integrations["mysql"].RecordOutgoing(parserCtx, srcConn, dstConn, rule.MC, rule.OutgoingOptions)
+			err := p.Integrations["mysql"].RecordOutgoing(parserCtx, srcConn, dstConn, rule.MC, opts)
 			if err != nil {
 				utils.LogError(p.logger, err, "failed to record the outgoing message")
 				return err
@@ -386,7 +510,7 @@ func (p *Proxy) handleConnection(ctx context.Context, srcConn net.Conn) error {
 		}
 
 		//mock the outgoing message
-		err := p.Integrations["mysql"].MockOutgoing(parserCtx, srcConn, &integrations.ConditionalDstCfg{Addr: dstAddr}, m.(*MockManager), rule.OutgoingOptions)
+		err := p.Integrations["mysql"].MockOutgoing(parserCtx, srcConn, &integrations.ConditionalDstCfg{Addr: dstAddr, Port: uint(destInfo.Port), IP: dstIP}, &countingMockDb{MockMemDb: m.(*MockManager), info: live}, opts)
 		if err != nil {
 			utils.LogError(p.logger, err, "failed to mock the outgoing message")
 			return err
@@ -415,14 +539,69 @@ func (p *Proxy) handleConnection(ctx context.Context, srcConn net.Conn) error {
 	}
 
 	isTLS := isTLSHandshake(testBuffer)
+	// the application protocol negotiated with the client, the destination is dialed with the same one
+	var alpn string
 	if isTLS {
-		srcConn, err = p.handleTLSConnection(srcConn)
+		srcConn, err = p.handleTLSConnection(srcConn, func(hello *tls.ClientHelloInfo) []string {
+			if !slices.Contains(hello.SupportedProtos, "h2") {
+				return nil
+			}
+			if rule.Mode == models.MODE_TEST {
+				return []string{"h2", "http/1.1"}
+			}
+			// h2 is offered to the client only if the destination negotiates it as well,
+			// the conn dialed for it is then used for the destination.
+			host := hello.ServerName
+			if host == "" {
+				host = dstIP.String()
+			}
+			cfg := &tls.Config{InsecureSkipVerify: true, ServerName: hello.ServerName, NextProtos: []string{"h2", "http/1.1"}}
+			conn, err := p.dialTLS(parserCtx, "tcp", net.JoinHostPort(host, fmt.Sprint(destInfo.Port)), cfg)
+			if err != nil {
+				p.logger.Debug("failed to dial the destination to negotiate the application protocol", zap.Any("server address", dstAddr), zap.Error(err))
+				return nil
+			}
+			dstConn = conn
+			if conn.(*tls.Conn).ConnectionState().NegotiatedProtocol == "h2" {
+				return []string{"h2", "http/1.1"}
+			}
+			return nil
+		})
 		if err != nil {
 			utils.LogError(p.logger, err, "failed to handle TLS conn")
 			return err
 		}
+		alpn = srcConn.(*tls.Conn).ConnectionState().NegotiatedProtocol
+	}
+
+	// forward the calls to the bypassed domains without recording them
+	if rule.Mode == models.MODE_RECORD {
+		serverName := ""
+		if isTLS {
+			serverName = dstURL
+		}
+		if host, ok := p.bypassedHost(serverName, dstIP, rule.OutgoingOptions.BypassDomains); ok {
+			p.logger.Debug("forwarding the conn to a bypassed domain without recording it", zap.String("host", host), zap.String("dstAddr", dstAddr))
+			live.setProtocol("bypass")
+			switch {
+			case dstConn != nil:
+			case isTLS:
+				cfg := &tls.Config{InsecureSkipVerify: true, ServerName: serverName}
+				dstConn, err = p.dialTLS(parserCtx, "tcp", dstAddr, cfg)
+			default:
+				dstConn, err = net.Dial("tcp", dstAddr)
+			}
+			if err != nil {
+				utils.LogError(p.logger, err, "failed to dial the conn to destination server", zap.Any("proxy port", p.Port), zap.Any("server address", dstAddr))
+				return err
+			}
+			return p.globalPassThrough(parserCtx, srcConn, dstConn)
+		}
 	}
 
+	// record the (decrypted) bytes exchanged with the application for the admin api
+	srcConn = &tapConn{Conn: srcConn, info: live}
+
 	// attempt to read conn until buffer is either filled or conn is closed
 	initialBuf, err := util.ReadInitialBuf(parserCtx, p.logger, srcConn)
 	if err != nil {
@@ -430,6 +609,14 @@ func (p *Proxy) handleConnection(ctx context.Context, srcConn net.Conn) error {
 		return err
 	}
 
+	// score the integrations against the initial buffer, reading more bytes if any integration needs them
+	var parserType string
+	if len(portIntegrations) > 0 {
+		parserType = p.matchPortIntegrations(parserCtx, portIntegrations, initialBuf)
+	} else {
+		parserType, initialBuf = p.detectProtocol(parserCtx, srcConn, initialBuf)
+	}
+
 	//update the src connection to have the initial buffer
 	srcConn = &Conn{
 		Conn:   srcConn,
@@ -451,6 +638,7 @@ func (p *Proxy) handleConnection(ctx context.Context, srcConn net.Conn) error {
 	logger := p.logger.With(zap.Any("Client IP Address", srcConn.RemoteAddr().String()), zap.Any("Client ConnectionID", clientID), zap.Any("Destination IP Address", dstAddr), zap.Any("Destination ConnectionID", destID))
 	dstCfg := &integrations.ConditionalDstCfg{
 		Port: uint(destInfo.Port),
+		IP:   dstIP,
 	}
 
 	//make new connection to the destination server
@@ -460,10 +648,18 @@ func (p *Proxy) handleConnection(ctx context.Context, srcConn net.Conn) error {
 			InsecureSkipVerify: true,
 			ServerName:         dstURL,
 		}
+		if alpn != "" {
+			cfg.NextProtos = []string{alpn}
+		}
 
-		addr := fmt.Sprintf("%v:%v", dstURL, destInfo.Port)
-		if rule.Mode != models.MODE_TEST {
-			dstConn, err = tls.Dial("tcp", addr, cfg)
+		// the conns without a server name are dialed on the address the application connected to
+		host := dstURL
+		if host == "" {
+			host = dstIP.String()
+		}
+		addr := net.JoinHostPort(host, fmt.Sprint(destInfo.Port))
+		if rule.Mode != models.MODE_TEST && dstConn == nil {
+			dstConn, err = p.dialTLS(parserCtx, dstCfg.Network(), addr, cfg)
 			if err != nil {
 				utils.LogError(logger, err, "failed to dial the conn to destination server", zap.Any("proxy port", p.Port), zap.Any("server address", dstAddr))
 				return err
@@ -491,42 +687,41 @@ func (p *Proxy) handleConnection(ctx context.Context, srcConn net.Conn) error {
 		return err
 	}
 
-	generic := true
+	parser, ok := p.Integrations[parserType]
+	if !ok {
+		logger.Debug("The external dependency is not supported. Hence using generic parser")
+		parserType = string(integrations.GENERIC)
+		parser = p.Integrations[parserType]
+	} else {
+		logger.Debug("detected the protocol of the external dependency", zap.String("parser", parserType))
+	}
+	live.setProtocol(parserType)
 
-	//Checking for all the parsers.
-	for _, parser := range p.Integrations {
-		if parser.MatchType(parserCtx, initialBuf) {
-			if rule.Mode == models.MODE_RECORD {
-				err := parser.RecordOutgoing(parserCtx, srcConn, dstConn, rule.MC, rule.OutgoingOptions)
-				if err != nil {
-					utils.LogError(logger, err, "failed to record the outgoing message")
-					return err
-				}
-			} else {
-				err := parser.MockOutgoing(parserCtx, srcConn, dstCfg, m.(*MockManager), rule.OutgoingOptions)
-				if err != nil && err != io.EOF {
-					utils.LogError(logger, err, "failed to mock the outgoing message")
-					return err
-				}
-			}
-			generic = false
-		}
+	opts := rule.OutgoingOptions
+	opts.Framing = p.framing(uint(destInfo.Port))
+	opts.SchemaRegistry = p.integrationsCfg.Kafka.SchemaRegistry
+	opts.MySQL = p.integrationsCfg.MySQL
+	opts.Postgres = p.integrationsCfg.Postgres
+	if opts.Mongo.QueryMatching == "" {
+		opts.Mongo = p.integrationsCfg.Mongo
+	}
+	opts.HTTP = config.HTTPIntegration{
+		IgnoreHeaders:     append(append([]string{}, p.integrationsCfg.HTTP.IgnoreHeaders...), opts.HTTP.IgnoreHeaders...),
+		IgnoreQueryParams: append(append([]string{}, p.integrationsCfg.HTTP.IgnoreQueryParams...), opts.HTTP.IgnoreQueryParams...),
+		URLRewrites:       append(append([]config.URLRewrite{}, p.integrationsCfg.HTTP.URLRewrites...), opts.HTTP.URLRewrites...),
 	}
 
-	if generic {
-		logger.Debug("The external dependency is not supported. Hence using generic parser")
-		if rule.Mode == models.MODE_RECORD {
-			err := p.Integrations["generic"].RecordOutgoing(parserCtx, srcConn, dstConn, rule.MC, rule.OutgoingOptions)
-			if err != nil {
-				utils.LogError(logger, err, "failed to record the outgoing message")
-				return err
-			}
-		} else {
-			err := p.Integrations["generic"].MockOutgoing(parserCtx, srcConn, dstCfg, m.(*MockManager), rule.OutgoingOptions)
-			if err != nil {
-				utils.LogError(logger, err, "failed to mock the outgoing message")
-				return err
-			}
+	if rule.Mode == models.MODE_RECORD {
+		err := parser.RecordOutgoing(parserCtx, srcConn, dstConn, rule.MC, opts)
+		if err != nil {
+			utils.LogError(logger, err, "failed to record the outgoing message")
+			return err
+		}
+	} else {
+		err := parser.MockOutgoing(parserCtx, srcConn, dstCfg, &countingMockDb{MockMemDb: m.(*MockManager), info: live}, opts)
+		if err != nil && err != io.EOF {
+			utils.LogError(logger, err, "failed to mock the outgoing message")
+			return err
 		}
 	}
 	return nil
@@ -553,6 +748,12 @@ func (p *Proxy) StopProxyServer(ctx context.Context) {
 		}
 	}
 
+	// remove the spill files of the mocks
+	p.MockManagers.Range(func(_, m interface{}) bool {
+		m.(*MockManager).Close()
+		return true
+	})
+
 	// stop dns servers
 	err := p.stopDNSServers(ctx)
 	if err != nil {
@@ -563,15 +764,22 @@ func (p *Proxy) StopProxyServer(ctx context.Context) {
 	p.logger.Info("proxy stopped...")
 }
 
-func (p *Proxy) Record(_ context.Context, id uint64, mocks chan<- *models.Mock, opts models.OutgoingOptions) error {
+func (p *Proxy) Record(ctx context.Context, id uint64, mocks chan<- *models.Mock, opts models.OutgoingOptions) error {
 	p.sessions.Set(id, &core.Session{
 		ID:              id,
 		Mode:            models.MODE_RECORD,
 		MC:              mocks,
+		Ctx:             ctx,
 		OutgoingOptions: opts,
 	})
 
-	p.MockManagers.Store(id, NewMockManager(NewTreeDb(customComparator), NewTreeDb(customComparator), p.logger))
+	p.setMockManager(id)
+
+	// the questions are recorded again in the new session
+	p.recordedDNS.Range(func(key, _ interface{}) bool {
+		p.recordedDNS.Delete(key)
+		return true
+	})
 
 	////set the new proxy ip:port for a new session
 	//err := p.setProxyIP(opts.DnsIPv4Addr, opts.DnsIPv6Addr)
@@ -582,13 +790,14 @@ func (p *Proxy) Record(_ context.Context, id uint64, mocks chan<- *models.Mock,
 	return nil
 }
 
-func (p *Proxy) Mock(_ context.Context, id uint64, opts models.OutgoingOptions) error {
+func (p *Proxy) Mock(ctx context.Context, id uint64, opts models.OutgoingOptions) error {
 	p.sessions.Set(id, &core.Session{
 		ID:              id,
 		Mode:            models.MODE_TEST,
+		Ctx:             ctx,
 		OutgoingOptions: opts,
 	})
-	p.MockManagers.Store(id, NewMockManager(NewTreeDb(customComparator), NewTreeDb(customComparator), p.logger))
+	p.setMockManager(id)
 
 	if !opts.Mocking {
 		p.logger.Info("🔀 Mocking is disabled, the response will be fetched from the actual service")
@@ -612,6 +821,14 @@ func (p *Proxy) Mock(_ context.Context, id uint64, opts models.OutgoingOptions)
 	return nil
 }
 
+// setMockManager creates the mock manager of the session, the manager of the previous session with the same id is closed.
+func (p *Proxy) setMockManager(id uint64) {
+	m := NewMockManager(NewTreeDb(customComparator), NewTreeDb(customComparator), p.mockMemLimit, p.logger)
+	if old, loaded := p.MockManagers.Swap(id, m); loaded {
+		old.(*MockManager).Close()
+	}
+}
+
 func (p *Proxy) SetMocks(_ context.Context, id uint64, filtered []*models.Mock, unFiltered []*models.Mock) error {
 	//session, ok := p.sessions.Get(id)
 	//if !ok {
@@ -619,8 +836,7 @@ func (p *Proxy) SetMocks(_ context.Context, id uint64, filtered []*models.Mock,
 	//}
 	m, ok := p.MockManagers.Load(id)
 	if ok {
-		m.(*MockManager).SetFilteredMocks(filtered)
-		m.(*MockManager).SetUnFilteredMocks(unFiltered)
+		m.(*MockManager).SetMocks(filtered, unFiltered)
 	}
 
 	return nil
@@ -634,3 +850,17 @@ func (p *Proxy) GetConsumedMocks(_ context.Context, id uint64) ([]string, error)
 	}
 	return m.(*MockManager).GetConsumedMocks(), nil
 }
+
+// dialTLS dials the destination over tls, its server name is resolved by the resolver of the proxy.
+func (p *Proxy) dialTLS(ctx context.Context, network, addr string, cfg *tls.Config) (net.Conn, error) {
+	conn, err := p.resolver.dial(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+	tlsConn := tls.Client(conn, cfg)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return tlsConn, nil
+}
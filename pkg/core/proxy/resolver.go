@@ -0,0 +1,194 @@
+//go:build linux
+
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/miekg/dns"
+	"go.keploy.io/server/v2/config"
+	"go.uber.org/zap"
+)
+
+// resolver resolves the destinations dialed by the proxy, as per the resolver config. The static hosts are
+// checked first, the other names are resolved by the configured dns servers or by the system resolver.
+type resolver struct {
+	logger   *zap.Logger
+	hosts    map[string][]net.IPAddr
+	servers  []string
+	next     uint32
+	r        *net.Resolver
+	cacheTTL time.Duration
+
+	mutex sync.Mutex
+	cache map[string]resolved
+}
+
+type resolved struct {
+	addrs   []net.IPAddr
+	expires time.Time
+}
+
+// newResolver creates the resolver of the config, the invalid static hosts are skipped.
+func newResolver(logger *zap.Logger, cfg config.Resolver) *resolver {
+	r := &resolver{
+		logger:   logger,
+		hosts:    map[string][]net.IPAddr{},
+		r:        net.DefaultResolver,
+		cacheTTL: cfg.CacheTTL,
+		cache:    map[string]resolved{},
+	}
+
+	for host, ip := range cfg.Hosts {
+		addr := net.ParseIP(ip)
+		if addr == nil {
+			logger.Warn("skipping the host with an invalid ip in the resolver config", zap.String("host", host), zap.String("ip", ip))
+			continue
+		}
+		host = normalizeHost(host)
+		r.hosts[host] = append(r.hosts[host], net.IPAddr{IP: addr})
+	}
+
+	for _, server := range cfg.Servers {
+		if _, _, err := net.SplitHostPort(server); err != nil {
+			// the default dns port
+			server = net.JoinHostPort(server, "53")
+		}
+		r.servers = append(r.servers, server)
+	}
+	if len(r.servers) > 0 {
+		r.r = &net.Resolver{
+			PreferGo: true,
+			Dial:     r.dialServer,
+		}
+	}
+	return r
+}
+
+func normalizeHost(host string) string {
+	return strings.ToLower(strings.TrimSuffix(host, "."))
+}
+
+// dialServer dials the configured dns servers in turn, in place of the ones of resolv.conf.
+func (r *resolver) dialServer(ctx context.Context, network, _ string) (net.Conn, error) {
+	var d net.Dialer
+	var err error
+	start := atomic.AddUint32(&r.next, 1)
+	for i := range r.servers {
+		server := r.servers[(int(start)+i)%len(r.servers)]
+		var conn net.Conn
+		conn, err = d.DialContext(ctx, network, server)
+		if err == nil {
+			return conn, nil
+		}
+		r.logger.Debug("failed to dial the dns server", zap.String("server", server), zap.Error(err))
+	}
+	return nil, err
+}
+
+// lookup returns the addresses of the host, the ip addresses are returned as is.
+func (r *resolver) lookup(ctx context.Context, host string) ([]net.IPAddr, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return []net.IPAddr{{IP: ip}}, nil
+	}
+	host = normalizeHost(host)
+	if addrs, ok := r.hosts[host]; ok {
+		return addrs, nil
+	}
+
+	if r.cacheTTL > 0 {
+		r.mutex.Lock()
+		entry, ok := r.cache[host]
+		r.mutex.Unlock()
+		if ok && time.Now().Before(entry.expires) {
+			return entry.addrs, nil
+		}
+	}
+
+	addrs, err := r.r.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.cacheTTL > 0 {
+		r.mutex.Lock()
+		r.cache[host] = resolved{addrs: addrs, expires: time.Now().Add(r.cacheTTL)}
+		r.mutex.Unlock()
+	}
+	return addrs, nil
+}
+
+// resolvConf is the config of the system resolver, its servers answer the questions other than the addresses
+// when the resolver config has no servers.
+const resolvConf = "/etc/resolv.conf"
+
+// exchange asks the question to the configured dns servers in turn, or else to the servers of the system. It
+// answers the questions for the records other than the addresses, e.g. SRV and TXT.
+func (r *resolver) exchange(ctx context.Context, q dns.Question) (*dns.Msg, error) {
+	servers := r.servers
+	if len(servers) == 0 {
+		cfg, err := dns.ClientConfigFromFile(resolvConf)
+		if err != nil {
+			return nil, err
+		}
+		for _, server := range cfg.Servers {
+			servers = append(servers, net.JoinHostPort(server, cfg.Port))
+		}
+	}
+
+	query := new(dns.Msg)
+	query.SetQuestion(dns.Fqdn(q.Name), q.Qtype)
+	query.Question[0].Qclass = q.Qclass
+
+	err := errors.New("no dns server configured")
+	for _, server := range servers {
+		c := &dns.Client{Net: "udp"}
+		var resp *dns.Msg
+		resp, _, err = c.ExchangeContext(ctx, query, server)
+		if err == nil && resp.Truncated {
+			// the answer didn't fit in a datagram
+			c.Net = "tcp"
+			resp, _, err = c.ExchangeContext(ctx, query, server)
+		}
+		if err == nil {
+			return resp, nil
+		}
+		r.logger.Debug("failed to exchange the dns query with the server", zap.String("server", server), zap.Error(err))
+	}
+	return nil, err
+}
+
+// dial resolves the host of the address and dials its addresses in turn, only the addresses of the family
+// of the network are dialed for the tcp4 and tcp6 networks.
+func (r *resolver) dial(ctx context.Context, network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	addrs, err := r.lookup(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	var d net.Dialer
+	err = fmt.Errorf("no %s address found for %s", network, host)
+	for _, addr := range addrs {
+		isIPv4 := addr.IP.To4() != nil
+		if (network == "tcp4" && !isIPv4) || (network == "tcp6" && isIPv4) {
+			continue
+		}
+		var conn net.Conn
+		conn, err = d.DialContext(ctx, network, net.JoinHostPort(addr.IP.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+	}
+	return nil, err
+}
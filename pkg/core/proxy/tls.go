@@ -3,10 +3,10 @@
 package proxy
 
 import (
+	"context"
 	"crypto/tls"
 	"net"
 
-	"github.com/cloudflare/cfssl/helpers"
 	"go.keploy.io/server/v2/utils"
 )
 
@@ -17,24 +17,25 @@ func isTLSHandshake(data []byte) bool {
 	return data[0] == 0x16 && data[1] == 0x03 && (data[2] == 0x00 || data[2] == 0x01 || data[2] == 0x02 || data[2] == 0x03)
 }
 
-func (p *Proxy) handleTLSConnection(conn net.Conn) (net.Conn, error) {
+// handleTLSConnection terminates the tls of the application, nextProtos returns the application protocols
+// (ALPN) offered to the client for its hello.
+func (p *Proxy) handleTLSConnection(conn net.Conn, nextProtos func(hello *tls.ClientHelloInfo) []string) (net.Conn, error) {
 	//Load the CA certificate and private key
-
-	var err error
-	caPrivKey, err = helpers.ParsePrivateKeyPEM(caPKey)
-	if err != nil {
-		utils.LogError(p.logger, err, "Failed to parse CA private key")
-		return nil, err
-	}
-	caCertParsed, err = helpers.ParseCertificatePEM(caCrt)
+	err := loadCA()
 	if err != nil {
-		utils.LogError(p.logger, err, "Failed to parse CA certificate")
+		utils.LogError(p.logger, err, "failed to load the CA")
 		return nil, err
 	}
 
 	// Create a TLS configuration
 	config := &tls.Config{
 		GetCertificate: certForClient,
+		GetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			return &tls.Config{
+				GetCertificate: certForClient,
+				NextProtos:     nextProtos(hello),
+			}, nil
+		},
 	}
 
 	// Wrap the TCP conn with TLS
@@ -52,3 +53,24 @@ func (p *Proxy) handleTLSConnection(conn net.Conn) (net.Conn, error) {
 	// Here, we simply close the conn
 	return tlsConn, nil
 }
+
+// tlsUpgrader starts tls in the middle of the conns for the integrations.
+type tlsUpgrader struct {
+	p *Proxy
+}
+
+func (u *tlsUpgrader) UpgradeClient(_ context.Context, conn net.Conn) (*tls.Conn, error) {
+	tlsConn, err := u.p.handleTLSConnection(conn, func(*tls.ClientHelloInfo) []string { return nil })
+	if err != nil {
+		return nil, err
+	}
+	return tlsConn.(*tls.Conn), nil
+}
+
+func (u *tlsUpgrader) UpgradeDest(ctx context.Context, conn net.Conn, serverName string) (*tls.Conn, error) {
+	tlsConn := tls.Client(conn, &tls.Config{InsecureSkipVerify: true, ServerName: serverName})
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		return nil, err
+	}
+	return tlsConn, nil
+}
@@ -47,27 +47,35 @@ func (db *TreeDb) insert(key interface{}, obj interface{}) {
 	db.rbt.Put(key, obj)
 }
 
-func (db *TreeDb) delete(key interface{}) bool {
+func (db *TreeDb) get(key interface{}) (interface{}, bool) {
 	db.mutex.Lock()
 	defer db.mutex.Unlock()
-	_, found := db.rbt.Get(key)
+	return db.rbt.Get(key)
+}
+
+// delete removes the object of the key, it returns the removed object.
+func (db *TreeDb) delete(key interface{}) (interface{}, bool) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	obj, found := db.rbt.Get(key)
 	if !found {
-		return false
+		return nil, false
 	}
 	db.rbt.Remove(key)
-	return true
+	return obj, true
 }
 
-func (db *TreeDb) update(oldKey interface{}, newKey interface{}, newObj interface{}) bool {
+// update replaces the object of the old key with the new one, it returns the replaced object.
+func (db *TreeDb) update(oldKey interface{}, newKey interface{}, newObj interface{}) (interface{}, bool) {
 	db.mutex.Lock()
 	defer db.mutex.Unlock()
-	_, found := db.rbt.Get(oldKey)
+	obj, found := db.rbt.Get(oldKey)
 	if !found {
-		return false
+		return nil, false
 	}
 	db.rbt.Remove(oldKey)
 	db.rbt.Put(newKey, newObj)
-	return true
+	return obj, true
 }
 
 func (db *TreeDb) deleteAll() {
@@ -13,10 +13,10 @@ import (
 	"io"
 	"os"
 	"os/exec"
+	"sync"
 	"sync/atomic"
 	"time"
 
-	"github.com/getsentry/sentry-go"
 	"go.keploy.io/server/v2/pkg/core/proxy/integrations"
 	"go.keploy.io/server/v2/pkg/models"
 	"golang.org/x/sync/errgroup"
@@ -101,6 +101,25 @@ func ReadInitialBuf(ctx context.Context, logger *zap.Logger, conn net.Conn) ([]b
 	return initialBuf, nil
 }
 
+// readChunkSize is the size of the chunks read from the connections. The chunks are pooled, so that the
+// proxy doesn't allocate a new buffer for every read. ReadBytes takes a read shorter than a chunk as the end of
+// the message, so the size also decides where the messages are split and mustn't be changed.
+const readChunkSize = 1024
+
+var chunkPool = sync.Pool{
+	New: func() interface{} {
+		buf := make([]byte, readChunkSize)
+		return &buf
+	},
+}
+
+// readResult is the outcome of a single read done by the reader goroutine.
+type readResult struct {
+	n     int
+	err   error
+	chunk *[]byte
+}
+
 // ReadBytes function is utilized to read the complete message from the reader until the end of the file (EOF).
 // It returns the content as a byte array.
 func ReadBytes(ctx context.Context, logger *zap.Logger, reader io.Reader) ([]byte, error) {
@@ -108,12 +127,8 @@ func ReadBytes(ctx context.Context, logger *zap.Logger, reader io.Reader) ([]byt
 	const maxEmptyReads = 5
 	emptyReads := 0
 
-	// Channel to communicate read results
-	readResult := make(chan struct {
-		n   int
-		err error
-		buf []byte
-	})
+	// Channel to communicate read results, buffered so that the reader never blocks once we stopped waiting
+	results := make(chan readResult, 1)
 
 	g, ctx := errgroup.WithContext(ctx)
 
@@ -122,23 +137,20 @@ func ReadBytes(ctx context.Context, logger *zap.Logger, reader io.Reader) ([]byt
 		if err != nil {
 			utils.LogError(logger, err, "failed to read the request message in proxy")
 		}
-		close(readResult)
+		close(results)
 	}()
 
 	for {
 		// Start a goroutine to perform the read operation
 		g.Go(func() error {
 			defer Recover(logger, nil, nil)
-			buf := make([]byte, 1024)
-			n, err := reader.Read(buf)
+			chunk := chunkPool.Get().(*[]byte)
+			n, err := reader.Read(*chunk)
 			if ctx.Err() != nil {
+				chunkPool.Put(chunk)
 				return nil
 			}
-			readResult <- struct {
-				n   int
-				err error
-				buf []byte
-			}{n, err, buf}
+			results <- readResult{n: n, err: err, chunk: chunk}
 			return nil
 		})
 
@@ -146,11 +158,18 @@ func ReadBytes(ctx context.Context, logger *zap.Logger, reader io.Reader) ([]byt
 		select {
 		case <-ctx.Done():
 			return buffer, ctx.Err()
-		case result := <-readResult:
+		case result := <-results:
 			if result.n > 0 {
-				buffer = append(buffer, result.buf[:result.n]...)
+				if buffer == nil && result.n < readChunkSize {
+					// the whole message fits in one chunk, copy it out with the exact size
+					buffer = make([]byte, result.n)
+					copy(buffer, (*result.chunk)[:result.n])
+				} else {
+					buffer = append(buffer, (*result.chunk)[:result.n]...)
+				}
 				emptyReads = 0 // Reset the counter because we got some data
 			}
+			chunkPool.Put(result.chunk)
 
 			if result.err != nil {
 				if result.err == io.EOF {
@@ -163,7 +182,7 @@ func ReadBytes(ctx context.Context, logger *zap.Logger, reader io.Reader) ([]byt
 				}
 				return buffer, result.err
 			}
-			if result.n < len(result.buf) {
+			if result.n < readChunkSize {
 				return buffer, nil
 			}
 		}
@@ -173,16 +192,14 @@ func ReadBytes(ctx context.Context, logger *zap.Logger, reader io.Reader) ([]byt
 // ReadRequiredBytes ReadBytes function is utilized to read the required number of bytes from the reader.
 // It returns the content as a byte array.
 func ReadRequiredBytes(ctx context.Context, logger *zap.Logger, reader io.Reader, numBytes int) ([]byte, error) {
-	var buffer []byte
+	// the size is known upfront, so the reads go straight into the returned buffer
+	buffer := make([]byte, numBytes)
+	read := 0
 	const maxEmptyReads = 5
 	emptyReads := 0
 
-	// Channel to communicate read results
-	readResult := make(chan struct {
-		n   int
-		err error
-		buf []byte
-	})
+	// Channel to communicate read results, buffered so that the reader never blocks once we stopped waiting
+	results := make(chan readResult, 1)
 
 	g, ctx := errgroup.WithContext(ctx)
 
@@ -191,37 +208,30 @@ func ReadRequiredBytes(ctx context.Context, logger *zap.Logger, reader io.Reader
 		if err != nil {
 			utils.LogError(logger, err, "failed to read the request message in proxy")
 		}
-		close(readResult)
+		close(results)
 	}()
 
-	for numBytes > 0 {
+	for read < numBytes {
+		dst := buffer[read:]
 		// Start a goroutine to perform the read operation
 		g.Go(func() error {
 			defer Recover(logger, nil, nil)
-			buf := make([]byte, numBytes)
-			n, err := reader.Read(buf)
+			n, err := reader.Read(dst)
 			if ctx.Err() != nil {
 				return nil
 			}
-			readResult <- struct {
-				n   int
-				err error
-				buf []byte
-			}{n, err, buf}
+			results <- readResult{n: n, err: err}
 			return nil
 		})
 
-		// Use a select statement to wait for either the read result or context cancellation with timeout
+		// Use a select statement to wait for either the read result or context cancellation
 		select {
 		case <-ctx.Done():
-			return buffer, ctx.Err()
-		// case <-time.After(5 * time.Second):
-		// 	logger.Error("timeout occurred while reading the packet")
-		// 	return buffer, context.DeadlineExceeded
-		case result := <-readResult:
+			// cap the slice, the pending read may still write past it
+			return buffer[:read:read], ctx.Err()
+		case result := <-results:
 			if result.n > 0 {
-				buffer = append(buffer, result.buf[:result.n]...)
-				numBytes -= result.n
+				read += result.n
 				emptyReads = 0 // Reset the counter because we got some data
 			}
 
@@ -229,16 +239,12 @@ func ReadRequiredBytes(ctx context.Context, logger *zap.Logger, reader io.Reader
 				if result.err == io.EOF {
 					emptyReads++
 					if emptyReads >= maxEmptyReads {
-						return buffer, result.err // Multiple EOFs in a row, probably a true EOF
+						return buffer[:read], result.err // Multiple EOFs in a row, probably a true EOF
 					}
 					time.Sleep(time.Millisecond * 100) // Sleep before trying again
 					continue
 				}
-				return buffer, result.err
-			}
-
-			if result.n == numBytes {
-				return buffer, nil
+				return buffer[:read], result.err
 			}
 		}
 	}
@@ -282,7 +288,7 @@ func PassThrough(ctx context.Context, logger *zap.Logger, clientConn net.Conn, d
 	if dstCfg.TLSCfg != nil {
 		logger.Debug("trying to establish a TLS connection with the destination server", zap.Any("Destination Addr", dstCfg.Addr))
 
-		destConn, err = tls.Dial("tcp", dstCfg.Addr, dstCfg.TLSCfg)
+		destConn, err = tls.Dial(dstCfg.Network(), dstCfg.Addr, dstCfg.TLSCfg)
 		if err != nil {
 			utils.LogError(logger, err, "failed to dial the conn to destination server", zap.Any("server address", dstCfg.Addr))
 			return nil, err
@@ -290,7 +296,7 @@ func PassThrough(ctx context.Context, logger *zap.Logger, clientConn net.Conn, d
 		logger.Debug("TLS connection established with the destination server", zap.Any("Destination Addr", destConn.RemoteAddr().String()))
 	} else {
 		logger.Debug("trying to establish a connection with the destination server", zap.Any("Destination Addr", dstCfg.Addr))
-		destConn, err = net.Dial("tcp", dstCfg.Addr)
+		destConn, err = net.Dial(dstCfg.Network(), dstCfg.Addr)
 		if err != nil {
 			utils.LogError(logger, err, "failed to dial the destination server")
 			return nil, err
@@ -366,6 +372,19 @@ func ToIP4AddressStr(ip uint32) string {
 	return fmt.Sprintf("%d.%d.%d.%d", firstByte, secondByte, thirdByte, fourthByte)
 }
 
+// ToIPv6Address converts the IPv6 address from its kernel representation, the IPv4-mapped addresses
+// (::ffff:a.b.c.d) reported for the IPv4 conns of the dual-stack sockets are returned in their IPv4 form.
+func ToIPv6Address(ip [4]uint32) net.IP {
+	ipBytes := make(net.IP, net.IPv6len)
+	for i := 0; i < 4; i++ {
+		binary.BigEndian.PutUint32(ipBytes[i*4:], ip[i])
+	}
+	if ipv4 := ipBytes.To4(); ipv4 != nil {
+		return ipv4
+	}
+	return ipBytes
+}
+
 func ToIPv6AddressStr(ip [4]uint32) string {
 	// construct a byte slice
 	ipBytes := make([]byte, 16) // IPv6 address is 128 bits or 16 bytes long
@@ -518,7 +537,7 @@ func Recover(logger *zap.Logger, client, dest net.Conn) {
 		return
 	}
 
-	sentry.Flush(2 * time.Second)
+	utils.SentryFlush(2 * time.Second)
 	if r := recover(); r != nil {
 		logger.Error("Recovered from panic in parser, closing active connections")
 		if client != nil {
@@ -543,6 +562,6 @@ func Recover(logger *zap.Logger, client, dest net.Conn) {
 			}
 		}
 		utils.HandleRecovery(logger, r, "Recovered from panic")
-		sentry.Flush(time.Second * 2)
+		utils.SentryFlush(time.Second * 2)
 	}
 }
@@ -135,5 +135,7 @@ type Session struct {
 	Mode models.Mode
 	TC   chan<- *models.TestCase
 	MC   chan<- *models.Mock
+	// Ctx is done once the session is over, nothing is sent on its channels then
+	Ctx context.Context
 	models.OutgoingOptions
 }
@@ -0,0 +1,87 @@
+package pkg
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ErrUnsupportedEncoding is returned for the content codings the bodies can't be decoded from, such as br. The
+// bodies are recorded as they are sent then, and replayed as they were recorded.
+var ErrUnsupportedEncoding = errors.New("unsupported content encoding")
+
+// contentCodings returns the codings of the Content-Encoding in the order they were applied to the body.
+func contentCodings(contentEncoding string) []string {
+	var codings []string
+	for _, coding := range strings.Split(contentEncoding, ",") {
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding == "" || coding == "identity" {
+			continue
+		}
+		codings = append(codings, coding)
+	}
+	return codings
+}
+
+// DecodeBody returns the body decoded from the codings of its Content-Encoding, so that the bodies are stored
+// readable in the mocks and the test cases. The body is returned as it is when it has no Content-Encoding.
+func DecodeBody(body []byte, contentEncoding string) ([]byte, error) {
+	codings := contentCodings(contentEncoding)
+	for i := len(codings) - 1; i >= 0; i-- {
+		var err error
+		switch codings[i] {
+		case "gzip", "x-gzip":
+			var r *gzip.Reader
+			if r, err = gzip.NewReader(bytes.NewReader(body)); err == nil {
+				body, err = io.ReadAll(r)
+			}
+		case "deflate":
+			// the deflate coding is a zlib stream, some servers send the raw deflate data instead
+			var r io.ReadCloser
+			if r, err = zlib.NewReader(bytes.NewReader(body)); err == nil {
+				body, err = io.ReadAll(r)
+			} else {
+				body, err = io.ReadAll(flate.NewReader(bytes.NewReader(body)))
+			}
+		default:
+			return nil, fmt.Errorf("%w: %s", ErrUnsupportedEncoding, codings[i])
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode the %s body: %w", codings[i], err)
+		}
+	}
+	return body, nil
+}
+
+// EncodeBody returns the body encoded with the codings of its Content-Encoding, it undoes DecodeBody for the
+// bodies replayed.
+func EncodeBody(body []byte, contentEncoding string) ([]byte, error) {
+	codings := contentCodings(contentEncoding)
+	for _, coding := range codings {
+		if coding != "gzip" && coding != "x-gzip" && coding != "deflate" {
+			return nil, fmt.Errorf("%w: %s", ErrUnsupportedEncoding, coding)
+		}
+	}
+	for _, coding := range codings {
+		var b bytes.Buffer
+		var w io.WriteCloser
+		if coding == "deflate" {
+			w = zlib.NewWriter(&b)
+		} else {
+			w = gzip.NewWriter(&b)
+		}
+		if _, err := w.Write(body); err != nil {
+			return nil, fmt.Errorf("failed to encode the %s body: %w", coding, err)
+		}
+		if err := w.Close(); err != nil {
+			return nil, fmt.Errorf("failed to encode the %s body: %w", coding, err)
+		}
+		body = b.Bytes()
+	}
+	return body, nil
+}
@@ -0,0 +1,187 @@
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/protocolbuffers/protoscope"
+	"go.uber.org/zap"
+	"golang.org/x/net/http2"
+
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+)
+
+// The gRPC messages are sent length-prefixed on the HTTP/2 streams: a compression flag, the length of the message
+// and the protobuf encoded message, which is stored decoded by protoscope as the proto definitions aren't known.
+// See: https://github.com/grpc/grpc/blob/master/doc/PROTOCOL-HTTP2.md
+
+// SplitGrpcMessages decodes the complete messages at the start of buf, the bytes of the message that isn't
+// complete yet are returned with them.
+func SplitGrpcMessages(buf []byte) ([]models.GrpcLengthPrefixedMessage, []byte) {
+	var msgs []models.GrpcLengthPrefixedMessage
+	for len(buf) >= 5 {
+		end := 5 + int(binary.BigEndian.Uint32(buf[1:5]))
+		if len(buf) < end {
+			break
+		}
+		msgs = append(msgs, GrpcMessageFromPayload(buf[:end]))
+		buf = buf[end:]
+	}
+	return msgs, buf
+}
+
+// GrpcMessageFromPayload decodes a length-prefixed message.
+func GrpcMessageFromPayload(data []byte) models.GrpcLengthPrefixedMessage {
+	msg := models.GrpcLengthPrefixedMessage{}
+
+	// If the body is not length prefixed, we return the default value.
+	if len(data) < 5 {
+		return msg
+	}
+
+	// The first byte is the compression flag.
+	msg.CompressionFlag = uint(data[0])
+
+	// The next 4 bytes are message length.
+	msg.MessageLength = binary.BigEndian.Uint32(data[1:5])
+
+	// Use protoscope to decode the message.
+	msg.DecodedData = protoscope.Write(data[5:], protoscope.WriterOptions{})
+
+	return msg
+}
+
+// GrpcPayloadFromMessage encodes the message length-prefixed.
+func GrpcPayloadFromMessage(msg models.GrpcLengthPrefixedMessage) ([]byte, error) {
+	scanner := protoscope.NewScanner(msg.DecodedData)
+	encodedData, err := scanner.Exec()
+	if err != nil {
+		return nil, fmt.Errorf("could not encode grpc msg using protoscope: %v", err)
+	}
+
+	// Note that the encoded length is present in the msg, but it is also equal to the len of encodedData.
+	// We should give the preference to the length of encodedData, since the mocks might have been altered.
+
+	// Reserve 1 byte for compression flag, 4 bytes for length capture.
+	payload := make([]byte, 1+4)
+	payload[0] = uint8(msg.CompressionFlag)
+	binary.BigEndian.PutUint32(payload[1:5], uint32(len(encodedData)))
+	payload = append(payload, encodedData...)
+
+	return payload, nil
+}
+
+// CompactGrpcCall keeps the bodies of the unary calls only, the messages are kept for the streaming calls.
+func CompactGrpcCall(req *models.GrpcReq, resp *models.GrpcResp) {
+	if len(req.Messages) == 1 && len(resp.Messages) == 1 {
+		req.Messages, resp.Messages = nil, nil
+	}
+}
+
+// GrpcCallURL returns the url of the gRPC call with the headers, the url of the http request of its test case.
+func GrpcCallURL(headers models.GrpcHeaders) string {
+	u := url.URL{Scheme: "http", Host: headers.PseudoHeaders[":authority"], Path: headers.PseudoHeaders[":path"]}
+	return u.String()
+}
+
+// SimulateGRPC makes the gRPC call of the test case to the application and returns its response. The call is made
+// over HTTP/2, to the url of the http request of the test case which is the one of the recorded call.
+func SimulateGRPC(ctx context.Context, tc *models.TestCase, testSet string, logger *zap.Logger, apiTimeout uint64) (*models.GrpcResp, error) {
+	logger.Info("starting test for of", zap.Any("test case", models.HighlightString(tc.Name)), zap.Any("test set", models.HighlightString(testSet)))
+
+	msgs := tc.GrpcReq.Messages
+	if len(msgs) == 0 && tc.GrpcReq.Body != (models.GrpcLengthPrefixedMessage{}) {
+		msgs = []models.GrpcLengthPrefixedMessage{tc.GrpcReq.Body}
+	}
+	var body []byte
+	for _, msg := range msgs {
+		payload, err := GrpcPayloadFromMessage(msg)
+		if err != nil {
+			utils.LogError(logger, err, "failed to encode the grpc message of the testcase")
+			return nil, err
+		}
+		body = append(body, payload...)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tc.HTTPReq.URL, bytes.NewReader(body))
+	if err != nil {
+		utils.LogError(logger, err, "failed to create a grpc request from the yaml document")
+		return nil, err
+	}
+	for key, value := range tc.GrpcReq.Headers.OrdinaryHeaders {
+		req.Header.Set(key, value)
+	}
+	if authority := tc.GrpcReq.Headers.PseudoHeaders[":authority"]; authority != "" {
+		req.Host = authority
+	}
+	req.Header.Set("keploy-test-id", tc.Name)
+	req.Header.Set("keploy-test-set-id", testSet)
+	logger.Debug(fmt.Sprintf("Sending grpc request to user app:%v", req))
+
+	client := &http.Client{
+		Timeout: time.Second * time.Duration(apiTimeout),
+		Transport: &http2.Transport{
+			// the calls are made with prior knowledge of HTTP/2, without TLS unless the call was recorded over
+			// https, whose certificates aren't verified
+			AllowHTTP: true,
+			DialTLSContext: func(ctx context.Context, network, addr string, cfg *tls.Config) (net.Conn, error) {
+				if req.URL.Scheme == "https" {
+					cfg = cfg.Clone()
+					cfg.InsecureSkipVerify = true
+					dialer := tls.Dialer{Config: cfg}
+					return dialer.DialContext(ctx, network, addr)
+				}
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, network, addr)
+			},
+		},
+	}
+	httpResp, err := client.Do(req)
+	if err != nil {
+		utils.LogError(logger, err, "failed to send testcase grpc request to app")
+		return nil, err
+	}
+	defer func() {
+		if err := httpResp.Body.Close(); err != nil {
+			utils.LogError(logger, err, "failed to close the grpc response body")
+		}
+	}()
+	respBody, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		utils.LogError(logger, err, "failed reading grpc response body")
+		return nil, err
+	}
+
+	resp := models.NewGrpcStream(0).GrpcResp
+	headers, trailers := &resp.Headers, &resp.Trailers
+	// the calls failed right away are answered with the trailers only, which are read as the headers
+	if len(respBody) == 0 && len(httpResp.Trailer) == 0 && httpResp.Header.Get("grpc-status") != "" {
+		headers = trailers
+	}
+	headers.PseudoHeaders[":status"] = strconv.Itoa(httpResp.StatusCode)
+	for key, values := range httpResp.Header {
+		headers.OrdinaryHeaders[strings.ToLower(key)] = strings.Join(values, ",")
+	}
+	for key, values := range httpResp.Trailer {
+		trailers.OrdinaryHeaders[strings.ToLower(key)] = strings.Join(values, ",")
+	}
+	resp.Messages, _ = SplitGrpcMessages(respBody)
+	if len(resp.Messages) > 0 {
+		resp.Body = resp.Messages[0]
+	}
+	if len(tc.GrpcReq.Messages) == 0 && len(resp.Messages) == 1 {
+		resp.Messages = nil
+	}
+	return &resp, nil
+}
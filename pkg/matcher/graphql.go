@@ -0,0 +1,585 @@
+package matcher
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// GraphQLRequest is a GraphQL request sent as a json POST body, normalized for matching.
+type GraphQLRequest struct {
+	OperationName string
+	// Query is the canonical form of the query document, the hash of the persisted queries
+	Query string
+	// Variables are the variables as json with sorted keys
+	Variables string
+}
+
+// ParseGraphQLRequest parses the json body of a GraphQL request. The query document is normalized, so the
+// whitespace, the comments and the order of the selections, the arguments and the definitions don't
+// matter.
+func ParseGraphQLRequest(body []byte) (*GraphQLRequest, error) {
+	var raw struct {
+		Query         *string     `json:"query"`
+		OperationName string      `json:"operationName"`
+		Variables     interface{} `json:"variables"`
+		Extensions    struct {
+			PersistedQuery struct {
+				Hash string `json:"sha256Hash"`
+			} `json:"persistedQuery"`
+		} `json:"extensions"`
+	}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&raw); err != nil {
+		return nil, err
+	}
+
+	req := &GraphQLRequest{OperationName: raw.OperationName}
+	switch {
+	case raw.Query != nil && *raw.Query != "":
+		doc, err := parseGraphQL(*raw.Query)
+		if err != nil {
+			return nil, err
+		}
+		req.Query = doc.canonical
+		if req.OperationName == "" && len(doc.operations) == 1 {
+			req.OperationName = doc.operations[0]
+		}
+	case raw.Extensions.PersistedQuery.Hash != "":
+		req.Query = "persisted:" + raw.Extensions.PersistedQuery.Hash
+	default:
+		return nil, errors.New("not a graphql request")
+	}
+
+	variables, err := json.Marshal(raw.Variables)
+	if err != nil {
+		return nil, err
+	}
+	req.Variables = string(variables)
+	return req, nil
+}
+
+// graphQLDocument is a parsed query document.
+type graphQLDocument struct {
+	canonical string
+	// operations are the names of the operations of the document
+	operations []string
+}
+
+// graphQLParser parses the executable documents of GraphQL, and prints the canonical form of every
+// definition while parsing it.
+type graphQLParser struct {
+	tokens []string
+	pos    int
+}
+
+func parseGraphQL(query string) (*graphQLDocument, error) {
+	tokens, err := graphQLTokens(query)
+	if err != nil {
+		return nil, err
+	}
+	p := &graphQLParser{tokens: tokens}
+	doc := &graphQLDocument{}
+	var definitions []string
+	for p.pos < len(p.tokens) {
+		definition, name, err := p.definition()
+		if err != nil {
+			return nil, err
+		}
+		definitions = append(definitions, definition)
+		if name != "" && !strings.HasPrefix(definition, "fragment ") {
+			doc.operations = append(doc.operations, name)
+		}
+	}
+	if len(definitions) == 0 {
+		return nil, errors.New("empty graphql document")
+	}
+	sort.Strings(definitions)
+	doc.canonical = strings.Join(definitions, " ")
+	return doc, nil
+}
+
+func (p *graphQLParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *graphQLParser) next() string {
+	token := p.peek()
+	p.pos++
+	return token
+}
+
+func (p *graphQLParser) expect(token string) error {
+	if got := p.next(); got != token {
+		return fmt.Errorf("expected %q in the graphql document, got %q", token, got)
+	}
+	return nil
+}
+
+func (p *graphQLParser) name() (string, error) {
+	token := p.next()
+	if !isGraphQLName(token) {
+		return "", fmt.Errorf("expected a name in the graphql document, got %q", token)
+	}
+	return token, nil
+}
+
+// definition parses an operation or a fragment, and returns its canonical form with its name.
+func (p *graphQLParser) definition() (string, string, error) {
+	if p.peek() == "{" {
+		set, err := p.selectionSet()
+		return "query " + set, "", err
+	}
+	switch kind := p.next(); kind {
+	case "fragment":
+		name, err := p.name()
+		if err != nil {
+			return "", "", err
+		}
+		if err := p.expect("on"); err != nil {
+			return "", "", err
+		}
+		typ, err := p.name()
+		if err != nil {
+			return "", "", err
+		}
+		directives, err := p.directives()
+		if err != nil {
+			return "", "", err
+		}
+		set, err := p.selectionSet()
+		return "fragment " + name + " on " + typ + directives + " " + set, name, err
+	case "query", "mutation", "subscription":
+		var name, variables string
+		if isGraphQLName(p.peek()) {
+			name = p.next()
+		}
+		if p.peek() == "(" {
+			var err error
+			if variables, err = p.variableDefinitions(); err != nil {
+				return "", "", err
+			}
+		}
+		directives, err := p.directives()
+		if err != nil {
+			return "", "", err
+		}
+		set, err := p.selectionSet()
+		definition := kind
+		if name != "" {
+			definition += " " + name
+		}
+		return definition + variables + directives + " " + set, name, err
+	default:
+		return "", "", fmt.Errorf("unexpected %q in the graphql document", kind)
+	}
+}
+
+func (p *graphQLParser) variableDefinitions() (string, error) {
+	p.next()
+	var definitions []string
+	for p.peek() != ")" {
+		if err := p.expect("$"); err != nil {
+			return "", err
+		}
+		name, err := p.name()
+		if err != nil {
+			return "", err
+		}
+		if err := p.expect(":"); err != nil {
+			return "", err
+		}
+		typ, err := p.typeRef()
+		if err != nil {
+			return "", err
+		}
+		definition := "$" + name + ":" + typ
+		if p.peek() == "=" {
+			p.next()
+			value, err := p.value()
+			if err != nil {
+				return "", err
+			}
+			definition += "=" + value
+		}
+		directives, err := p.directives()
+		if err != nil {
+			return "", err
+		}
+		definitions = append(definitions, definition+directives)
+	}
+	p.next()
+	sort.Strings(definitions)
+	return "(" + strings.Join(definitions, ",") + ")", nil
+}
+
+func (p *graphQLParser) typeRef() (string, error) {
+	var typ string
+	if p.peek() == "[" {
+		p.next()
+		inner, err := p.typeRef()
+		if err != nil {
+			return "", err
+		}
+		if err := p.expect("]"); err != nil {
+			return "", err
+		}
+		typ = "[" + inner + "]"
+	} else {
+		name, err := p.name()
+		if err != nil {
+			return "", err
+		}
+		typ = name
+	}
+	if p.peek() == "!" {
+		p.next()
+		typ += "!"
+	}
+	return typ, nil
+}
+
+// selectionSet parses a selection set, its selections are sorted as their order doesn't change the result.
+func (p *graphQLParser) selectionSet() (string, error) {
+	if err := p.expect("{"); err != nil {
+		return "", err
+	}
+	var selections []string
+	for p.peek() != "}" {
+		if p.peek() == "" {
+			return "", errors.New("unterminated selection set in the graphql document")
+		}
+		selection, err := p.selection()
+		if err != nil {
+			return "", err
+		}
+		selections = append(selections, selection)
+	}
+	p.next()
+	sort.Strings(selections)
+	return "{" + strings.Join(selections, " ") + "}", nil
+}
+
+func (p *graphQLParser) selection() (string, error) {
+	if p.peek() == "..." {
+		p.next()
+		// a fragment spread, or an inline fragment with an optional type condition
+		if isGraphQLName(p.peek()) && p.peek() != "on" {
+			name := p.next()
+			directives, err := p.directives()
+			return "..." + name + directives, err
+		}
+		selection := "..."
+		if p.peek() == "on" {
+			p.next()
+			typ, err := p.name()
+			if err != nil {
+				return "", err
+			}
+			selection += " on " + typ
+		}
+		directives, err := p.directives()
+		if err != nil {
+			return "", err
+		}
+		set, err := p.selectionSet()
+		return selection + directives + " " + set, err
+	}
+
+	name, err := p.name()
+	if err != nil {
+		return "", err
+	}
+	field := name
+	if p.peek() == ":" {
+		p.next()
+		if name, err = p.name(); err != nil {
+			return "", err
+		}
+		field += ":" + name
+	}
+	if p.peek() == "(" {
+		arguments, err := p.arguments()
+		if err != nil {
+			return "", err
+		}
+		field += arguments
+	}
+	directives, err := p.directives()
+	if err != nil {
+		return "", err
+	}
+	field += directives
+	if p.peek() == "{" {
+		set, err := p.selectionSet()
+		if err != nil {
+			return "", err
+		}
+		field += " " + set
+	}
+	return field, nil
+}
+
+// arguments parses the arguments of a field or a directive, sorted by their names.
+func (p *graphQLParser) arguments() (string, error) {
+	p.next()
+	var arguments []string
+	for p.peek() != ")" {
+		name, err := p.name()
+		if err != nil {
+			return "", err
+		}
+		if err := p.expect(":"); err != nil {
+			return "", err
+		}
+		value, err := p.value()
+		if err != nil {
+			return "", err
+		}
+		arguments = append(arguments, name+":"+value)
+	}
+	p.next()
+	sort.Strings(arguments)
+	return "(" + strings.Join(arguments, ",") + ")", nil
+}
+
+func (p *graphQLParser) directives() (string, error) {
+	var directives string
+	for p.peek() == "@" {
+		p.next()
+		name, err := p.name()
+		if err != nil {
+			return "", err
+		}
+		directives += " @" + name
+		if p.peek() == "(" {
+			arguments, err := p.arguments()
+			if err != nil {
+				return "", err
+			}
+			directives += arguments
+		}
+	}
+	return directives, nil
+}
+
+func (p *graphQLParser) value() (string, error) {
+	switch token := p.next(); token {
+	case "$":
+		name, err := p.name()
+		return "$" + name, err
+	case "[":
+		var values []string
+		for p.peek() != "]" {
+			if p.peek() == "" {
+				return "", errors.New("unterminated list in the graphql document")
+			}
+			value, err := p.value()
+			if err != nil {
+				return "", err
+			}
+			values = append(values, value)
+		}
+		p.next()
+		return "[" + strings.Join(values, ",") + "]", nil
+	case "{":
+		var fields []string
+		for p.peek() != "}" {
+			name, err := p.name()
+			if err != nil {
+				return "", err
+			}
+			if err := p.expect(":"); err != nil {
+				return "", err
+			}
+			value, err := p.value()
+			if err != nil {
+				return "", err
+			}
+			fields = append(fields, name+":"+value)
+		}
+		p.next()
+		sort.Strings(fields)
+		return "{" + strings.Join(fields, ",") + "}", nil
+	case "", "(", ")", "]", "}", ":", "=", "@", "!", "...", "|", "&":
+		return "", fmt.Errorf("expected a value in the graphql document, got %q", token)
+	default:
+		// the numbers, the strings, the booleans, null and the enum values
+		return token, nil
+	}
+}
+
+// graphQLTokens splits a GraphQL document into its tokens, the whitespace, the commas and the comments
+// are insignificant.
+func graphQLTokens(query string) ([]string, error) {
+	var tokens []string
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			i++
+		case c == '#':
+			for i < len(query) && query[i] != '\n' && query[i] != '\r' {
+				i++
+			}
+		case strings.HasPrefix(query[i:], "..."):
+			tokens = append(tokens, "...")
+			i += 3
+		case strings.IndexByte("!$&():=@[]{}|", c) != -1:
+			tokens = append(tokens, string(c))
+			i++
+		case strings.HasPrefix(query[i:], `"""`):
+			end := strings.Index(query[i+3:], `"""`)
+			for end != -1 && query[i+3+end-1] == '\\' {
+				next := strings.Index(query[i+3+end+1:], `"""`)
+				if next == -1 {
+					end = -1
+					break
+				}
+				end += next + 1
+			}
+			if end == -1 {
+				return nil, errors.New("unterminated block string in the graphql document")
+			}
+			tokens = append(tokens, query[i:i+3+end+3])
+			i += 3 + end + 3
+		case c == '"':
+			j := i + 1
+			for ; j < len(query) && query[j] != '"'; j++ {
+				if query[j] == '\\' {
+					j++
+				}
+			}
+			if j >= len(query) {
+				return nil, errors.New("unterminated string in the graphql document")
+			}
+			tokens = append(tokens, query[i:j+1])
+			i = j + 1
+		case c == '-' || c == '_' || c == '.' || c == '+' || isAlphanumeric(c):
+			j := i + 1
+			for j < len(query) && (isAlphanumeric(query[j]) || strings.IndexByte("_.+-", query[j]) != -1) {
+				j++
+			}
+			tokens = append(tokens, query[i:j])
+			i = j
+		default:
+			return nil, fmt.Errorf("unexpected character %q in the graphql document", c)
+		}
+	}
+	return tokens, nil
+}
+
+func isAlphanumeric(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+func isGraphQLName(token string) bool {
+	if token == "" || token[0] >= '0' && token[0] <= '9' {
+		return false
+	}
+	for i := 0; i < len(token); i++ {
+		if !isAlphanumeric(token[i]) && token[i] != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+// FieldDiff is a value which differs between the expected and the actual json bodies.
+type FieldDiff struct {
+	Path     string
+	Expected string
+	Actual   string
+}
+
+// JSONFieldDiffs returns the values which differ between two json bodies by their field path, e.g.
+// data.user.orders[1].total for a GraphQL response. The noisy fields are left out.
+func JSONFieldDiffs(expected, actual string, noise map[string][]string) ([]FieldDiff, error) {
+	var exp, act interface{}
+	if err := json.Unmarshal([]byte(expected), &exp); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(actual), &act); err != nil {
+		return nil, err
+	}
+	var diffs []FieldDiff
+	collectFieldDiffs("", exp, act, noise, &diffs)
+	return diffs, nil
+}
+
+func collectFieldDiffs(path string, exp, act interface{}, noise map[string][]string, diffs *[]FieldDiff) {
+	if path != "" {
+		// the noise of the arrays applies to their elements as well
+		if _, ok := CheckStringExist(strings.ToLower(stripIndexes(path)), noise); ok {
+			return
+		}
+	}
+	expMap, expIsMap := exp.(map[string]interface{})
+	actMap, actIsMap := act.(map[string]interface{})
+	if expIsMap && actIsMap {
+		keys := make([]string, 0, len(expMap))
+		for key := range expMap {
+			keys = append(keys, key)
+		}
+		for key := range actMap {
+			if _, ok := expMap[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			child := key
+			if path != "" {
+				child = path + "." + key
+			}
+			collectFieldDiffs(child, expMap[key], actMap[key], noise, diffs)
+		}
+		return
+	}
+	expSlice, expIsSlice := exp.([]interface{})
+	actSlice, actIsSlice := act.([]interface{})
+	if expIsSlice && actIsSlice && len(expSlice) == len(actSlice) {
+		for i := range expSlice {
+			collectFieldDiffs(fmt.Sprintf("%s[%d]", path, i), expSlice[i], actSlice[i], noise, diffs)
+		}
+		return
+	}
+	if !reflect.DeepEqual(exp, act) {
+		*diffs = append(*diffs, FieldDiff{Path: path, Expected: fieldValue(exp), Actual: fieldValue(act)})
+	}
+}
+
+func stripIndexes(path string) string {
+	var b strings.Builder
+	depth := 0
+	for _, c := range path {
+		switch {
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+		case depth == 0:
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
+
+func fieldValue(v interface{}) string {
+	if v == nil {
+		return "null"
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
@@ -0,0 +1,139 @@
+// Package grpc for grpc matching
+package grpc
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/k0kubun/pp/v3"
+	"go.uber.org/zap"
+
+	matcherUtils "go.keploy.io/server/v2/pkg/matcher"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+)
+
+// Match compares the response of the gRPC call replayed with the recorded one. The status code of the result is
+// the grpc-status of the calls, the headers are the ordinary headers and trailers of the responses and the body is
+// the messages, decoded by protoscope, one per line.
+func Match(tc *models.TestCase, actualResponse *models.GrpcResp, noiseConfig map[string]map[string][]string, logger *zap.Logger) (bool, *models.Result) {
+	expBody, actBody := messages(tc.GrpcResp), messages(*actualResponse)
+	res := &models.Result{
+		StatusCode: models.IntResult{
+			Normal:   false,
+			Expected: status(tc.GrpcResp),
+			Actual:   status(*actualResponse),
+		},
+		BodyResult: []models.BodyResult{{
+			Normal:   false,
+			Type:     models.BodyTypePlain,
+			Expected: expBody,
+			Actual:   actBody,
+		}},
+	}
+
+	headerNoise := map[string][]string{}
+	for field, regexArr := range noiseConfig["header"] {
+		headerNoise[strings.ToLower(field)] = regexArr
+	}
+	for field, regexArr := range tc.Noise {
+		a := strings.Split(field, ".")
+		if a[0] == "header" {
+			headerNoise[strings.ToLower(a[len(a)-1])] = regexArr
+		}
+	}
+
+	pass := true
+	if !matcherUtils.Contains(matcherUtils.MapToArray(tc.Noise), "body") && expBody != actBody {
+		pass = false
+	}
+	res.BodyResult[0].Normal = pass
+
+	hRes := &[]models.HeaderResult{}
+	if !matcherUtils.CompareHeaders(headers(tc.GrpcResp), headers(*actualResponse), hRes, headerNoise) {
+		pass = false
+	}
+	res.HeadersResult = *hRes
+
+	if res.StatusCode.Expected == res.StatusCode.Actual {
+		res.StatusCode.Normal = true
+	} else {
+		pass = false
+	}
+
+	newLogger := pp.New()
+	newLogger.WithLineInfo = false
+	if !pass {
+		logDiffs := matcherUtils.NewDiffsPrinter(tc.Name)
+		newLogger.SetColorScheme(models.GetFailingColorScheme())
+		logs := newLogger.Sprintf("Testrun failed for testcase with id: %s\n\n--------------------------------------------------------------------\n\n", tc.Name)
+
+		if !res.StatusCode.Normal {
+			logDiffs.PushStatusDiff(fmt.Sprint(res.StatusCode.Expected), fmt.Sprint(res.StatusCode.Actual))
+		}
+		for _, j := range res.HeadersResult {
+			if !j.Normal {
+				logDiffs.PushHeaderDiff(fmt.Sprint(j.Expected.Value), fmt.Sprint(j.Actual.Value), j.Expected.Key, headerNoise)
+			}
+		}
+		if !res.BodyResult[0].Normal {
+			logDiffs.PushBodyDiff(expBody, actBody, map[string][]string{})
+		}
+
+		_, err := newLogger.Printf(logs)
+		if err != nil {
+			utils.LogError(logger, err, "failed to print the logs")
+		}
+		err = logDiffs.Render()
+		if err != nil {
+			utils.LogError(logger, err, "failed to render the diffs")
+		}
+	} else {
+		newLogger.SetColorScheme(models.GetPassingColorScheme())
+		_, err := newLogger.Printf(newLogger.Sprintf("Testrun passed for testcase with id: %s\n\n--------------------------------------------------------------------\n\n", tc.Name))
+		if err != nil {
+			utils.LogError(logger, err, "failed to print the logs")
+		}
+	}
+	return pass, res
+}
+
+// status returns the grpc-status of the response, it's sent in the trailers or in the headers of the trailers
+// only responses.
+func status(resp models.GrpcResp) int {
+	value, ok := resp.Trailers.OrdinaryHeaders["grpc-status"]
+	if !ok {
+		value = resp.Headers.OrdinaryHeaders["grpc-status"]
+	}
+	code, err := strconv.Atoi(value)
+	if err != nil {
+		return -1
+	}
+	return code
+}
+
+// headers returns the ordinary headers and trailers of the response.
+func headers(resp models.GrpcResp) http.Header {
+	h := http.Header{}
+	for _, fields := range []map[string]string{resp.Headers.OrdinaryHeaders, resp.Trailers.OrdinaryHeaders} {
+		for k, v := range fields {
+			h[strings.ToLower(k)] = []string{v}
+		}
+	}
+	return h
+}
+
+// messages returns the decoded messages of the response, one per line.
+func messages(resp models.GrpcResp) string {
+	msgs := resp.Messages
+	if len(msgs) == 0 && resp.Body != (models.GrpcLengthPrefixedMessage{}) {
+		msgs = []models.GrpcLengthPrefixedMessage{resp.Body}
+	}
+	decoded := make([]string, 0, len(msgs))
+	for _, msg := range msgs {
+		decoded = append(decoded, strings.TrimSpace(msg.DecodedData))
+	}
+	return strings.Join(decoded, "\n")
+}
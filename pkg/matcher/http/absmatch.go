@@ -176,11 +176,15 @@ func CompareHTTPReq(tcs1, tcs2 *models.TestCase, _ models.GlobalNoise, ignoreOrd
 	bodyType1 := models.BodyTypePlain
 	if json.Valid([]byte(tcs1.HTTPReq.Body)) {
 		bodyType1 = models.BodyTypeJSON
+	} else if matcher.IsXML(tcs1.HTTPReq.Body) {
+		bodyType1 = models.BodyTypeXML
 	}
 
 	bodyType2 := models.BodyTypePlain
 	if json.Valid([]byte(tcs2.HTTPReq.Body)) {
 		bodyType2 = models.BodyTypeJSON
+	} else if matcher.IsXML(tcs2.HTTPReq.Body) {
+		bodyType2 = models.BodyTypeXML
 	}
 
 	if bodyType1 != bodyType2 {
@@ -194,7 +198,15 @@ func CompareHTTPReq(tcs1, tcs2 *models.TestCase, _ models.GlobalNoise, ignoreOrd
 	// stores the json body after removing the noise
 	cleanExp, cleanAct := tcs1.HTTPReq.Body, tcs2.HTTPReq.Body
 	var jsonComparisonResult matcher.JSONComparisonResult
-	if !matcher.Contains(matcher.MapToArray(reqBodyNoise), "body") && bodyType1 == models.BodyTypeJSON {
+	gql1, gqlErr1 := matcher.ParseGraphQLRequest([]byte(tcs1.HTTPReq.Body))
+	gql2, gqlErr2 := matcher.ParseGraphQLRequest([]byte(tcs2.HTTPReq.Body))
+	if gqlErr1 == nil && gqlErr2 == nil {
+		// the GraphQL requests are compared on their operation, normalized query and variables
+		if *gql1 != *gql2 {
+			pass = false
+			bodyRes = false
+		}
+	} else if !matcher.Contains(matcher.MapToArray(reqBodyNoise), "body") && bodyType1 == models.BodyTypeJSON {
 		//validate the stored json
 		validatedJSON, err := matcher.ValidateAndMarshalJSON(logger, &cleanExp, &cleanAct)
 		if err != nil {
@@ -223,6 +235,18 @@ func CompareHTTPReq(tcs1, tcs2 *models.TestCase, _ models.GlobalNoise, ignoreOrd
 		// debug log for cleanExp and cleanAct
 		logger.Debug("cleanExp", zap.Any("", cleanExp))
 		logger.Debug("cleanAct", zap.Any("", cleanAct))
+	} else if !matcher.Contains(matcher.MapToArray(reqBodyNoise), "body") && bodyType1 == models.BodyTypeXML {
+		// the xml bodies are compared canonicalized, regardless of their prefixes, attribute order and whitespace
+		equal, err := matcher.XMLDiffWithNoiseControl(tcs1.HTTPReq.Body, tcs2.HTTPReq.Body, reqBodyNoise)
+		if err != nil {
+			logger.Error("failed to compare xml", zap.Error(err))
+			reqCompare.BodyResult.Normal = false
+			return false, reqCompare
+		}
+		if !equal {
+			pass = false
+			bodyRes = false
+		}
 	} else {
 		if !matcher.Contains(matcher.MapToArray(reqBodyNoise), "body") && tcs1.HTTPReq.Body != tcs2.HTTPReq.Body {
 			pass = false
@@ -308,11 +332,15 @@ func CompareHTTPResp(tcs1, tcs2 *models.TestCase, noiseConfig models.GlobalNoise
 	bodyType1 := models.BodyTypePlain
 	if json.Valid([]byte(tcs1.HTTPResp.Body)) {
 		bodyType1 = models.BodyTypeJSON
+	} else if matcher.IsXML(tcs1.HTTPResp.Body) {
+		bodyType1 = models.BodyTypeXML
 	}
 
 	bodyType2 := models.BodyTypePlain
 	if json.Valid([]byte(tcs2.HTTPResp.Body)) {
 		bodyType2 = models.BodyTypeJSON
+	} else if matcher.IsXML(tcs2.HTTPResp.Body) {
+		bodyType2 = models.BodyTypeXML
 	}
 
 	if bodyType1 != bodyType2 {
@@ -355,6 +383,18 @@ func CompareHTTPResp(tcs1, tcs2 *models.TestCase, noiseConfig models.GlobalNoise
 		// debug log for cleanExp and cleanAct
 		logger.Debug("cleanExp", zap.Any("", cleanExp))
 		logger.Debug("cleanAct", zap.Any("", cleanAct))
+	} else if !matcher.Contains(matcher.MapToArray(noise), "body") && bodyType1 == models.BodyTypeXML {
+		// the xml bodies are compared canonicalized, regardless of their prefixes, attribute order and whitespace
+		equal, err := matcher.XMLDiffWithNoiseControl(tcs1.HTTPResp.Body, tcs2.HTTPResp.Body, bodyNoise)
+		if err != nil {
+			logger.Error("failed to compare xml", zap.Error(err))
+			respCompare.BodyResult.Normal = false
+			return false, respCompare
+		}
+		if !equal {
+			pass = false
+			bodyRes = false
+		}
 	} else {
 		if !matcher.Contains(matcher.MapToArray(noise), "body") && tcs1.HTTPResp.Body != tcs2.HTTPResp.Body {
 			pass = false
@@ -18,9 +18,21 @@ import (
 )
 
 func Match(tc *models.TestCase, actualResponse *models.HTTPResp, noiseConfig map[string]map[string][]string, ignoreOrdering bool, logger *zap.Logger) (bool, *models.Result) {
+	// the body truncated when it was recorded is compared by the size and the digest of the whole body, the prefixes
+	// of the bodies are the ones compared and shown in the diffs
+	truncationMismatch := false
+	if tc.HTTPResp.Truncated != nil {
+		actual := *actualResponse
+		var truncated *models.BodyTruncation
+		actual.Body, truncated = pkg.TruncateBody(actual.Body, len(tc.HTTPResp.Body))
+		truncationMismatch = truncated == nil || *truncated != *tc.HTTPResp.Truncated
+		actualResponse = &actual
+	}
 	bodyType := models.BodyTypePlain
 	if json.Valid([]byte(actualResponse.Body)) {
 		bodyType = models.BodyTypeJSON
+	} else if matcherUtils.IsXML(actualResponse.Body) {
+		bodyType = models.BodyTypeXML
 	}
 	pass := true
 	hRes := &[]models.HeaderResult{}
@@ -83,11 +95,22 @@ func Match(tc *models.TestCase, actualResponse *models.HTTPResp, noiseConfig map
 		// debug log for cleanExp and cleanAct
 		logger.Debug("cleanExp", zap.Any("", cleanExp))
 		logger.Debug("cleanAct", zap.Any("", cleanAct))
+	} else if !matcherUtils.Contains(matcherUtils.MapToArray(noise), "body") && bodyType == models.BodyTypeXML {
+		// the xml bodies are compared canonicalized, regardless of their prefixes, attribute order and whitespace
+		var err error
+		pass, err = matcherUtils.XMLDiffWithNoiseControl(cleanExp, cleanAct, bodyNoise)
+		if err != nil {
+			logger.Debug("failed to compare the xml bodies", zap.Error(err))
+			pass = false
+		}
 	} else {
 		if !matcherUtils.Contains(matcherUtils.MapToArray(noise), "body") && tc.HTTPResp.Body != actualResponse.Body {
 			pass = false
 		}
 	}
+	if truncationMismatch && !matcherUtils.Contains(matcherUtils.MapToArray(noise), "body") {
+		pass = false
+	}
 
 	res.BodyResult[0].Normal = pass
 
@@ -139,7 +162,14 @@ func Match(tc *models.TestCase, actualResponse *models.HTTPResp, noiseConfig map
 			}
 		}
 		if !res.BodyResult[0].Normal {
-			if json.Valid([]byte(actualResponse.Body)) {
+			_, gqlErr := matcherUtils.ParseGraphQLRequest([]byte(tc.HTTPReq.Body))
+			fieldDiffs, fieldErr := matcherUtils.JSONFieldDiffs(tc.HTTPResp.Body, actualResponse.Body, bodyNoise)
+			if gqlErr == nil && fieldErr == nil && len(fieldDiffs) > 0 {
+				// the GraphQL responses are diffed per field path of their data and errors
+				for _, diff := range fieldDiffs {
+					logDiffs.PushFieldDiff(diff)
+				}
+			} else if json.Valid([]byte(actualResponse.Body)) {
 				patch, err := jsondiff.Compare(tc.HTTPResp.Body, actualResponse.Body)
 				if err != nil {
 					logger.Warn("failed to compute json diff", zap.Error(err))
@@ -184,6 +214,15 @@ func Match(tc *models.TestCase, actualResponse *models.HTTPResp, noiseConfig map
 					}
 					logDiffs.PushBodyDiff(fmt.Sprint(op.OldValue), fmt.Sprint(op.Value), bodyNoise)
 				}
+			} else if bodyType == models.BodyTypeXML {
+				// the canonical forms only differ where the documents do
+				expXML, expErr := matcherUtils.CanonicalizeXML(tc.HTTPResp.Body)
+				actXML, actErr := matcherUtils.CanonicalizeXML(actualResponse.Body)
+				if expErr == nil && actErr == nil {
+					logDiffs.PushBodyDiff(expXML.String(), actXML.String(), bodyNoise)
+				} else {
+					logDiffs.PushBodyDiff(fmt.Sprint(tc.HTTPResp.Body), fmt.Sprint(actualResponse.Body), bodyNoise)
+				}
 			} else {
 				logDiffs.PushBodyDiff(fmt.Sprint(tc.HTTPResp.Body), fmt.Sprint(actualResponse.Body), bodyNoise)
 			}
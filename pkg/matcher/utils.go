@@ -283,6 +283,7 @@ type DiffsPrinter struct {
 	text                  string
 	typeExp               string
 	typeAct               string
+	fieldDiffs            []FieldDiff
 }
 
 func (d *DiffsPrinter) SetHasarrayIndexMismatch(has bool) {
@@ -290,7 +291,7 @@ func (d *DiffsPrinter) SetHasarrayIndexMismatch(has bool) {
 }
 
 func NewDiffsPrinter(testCase string) DiffsPrinter {
-	return DiffsPrinter{testCase, "", "", map[string]string{}, map[string]string{}, "", "", map[string][]string{}, map[string][]string{}, false, "", "", ""}
+	return DiffsPrinter{testCase, "", "", map[string]string{}, map[string]string{}, "", "", map[string][]string{}, map[string][]string{}, false, "", "", "", nil}
 }
 func (d *DiffsPrinter) PushTypeDiff(exp, act string) {
 	d.typeExp, d.typeAct = exp, act
@@ -312,6 +313,11 @@ func (d *DiffsPrinter) PushBodyDiff(exp, act string, noise map[string][]string)
 	d.bodyExp, d.bodyAct, d.bodyNoise = exp, act, noise
 }
 
+// PushFieldDiff adds the diff of a field of the body, the field diffs are rendered instead of the body diff.
+func (d *DiffsPrinter) PushFieldDiff(diff FieldDiff) {
+	d.fieldDiffs = append(d.fieldDiffs, diff)
+}
+
 // Render will display and colorize diffs side-by-side
 func (d *DiffsPrinter) Render() error {
 	diffs := []string{}
@@ -321,7 +327,11 @@ func (d *DiffsPrinter) Render() error {
 	}
 
 	diffs = append(diffs, sprintDiffHeader(d.headerExp, d.headerAct))
-	if len(d.bodyExp) != 0 || len(d.bodyAct) != 0 {
+	if len(d.fieldDiffs) != 0 {
+		for _, diff := range d.fieldDiffs {
+			diffs = append(diffs, sprintDiff(diff.Expected, diff.Actual, "body."+diff.Path))
+		}
+	} else if len(d.bodyExp) != 0 || len(d.bodyAct) != 0 {
 		bE, bA := []byte(d.bodyExp), []byte(d.bodyAct)
 		if json.Valid(bE) && json.Valid(bA) {
 			difference, err := sprintJSONDiff(bE, bA, "body", d.bodyNoise)
@@ -0,0 +1,159 @@
+// Package websocket for websocket matching
+package websocket
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/k0kubun/pp/v3"
+	"go.uber.org/zap"
+
+	"go.keploy.io/server/v2/pkg"
+	matcherUtils "go.keploy.io/server/v2/pkg/matcher"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+)
+
+// Match compares the websocket session replayed with the recorded one. The status code and the headers of the
+// result are the ones of the handshake responses, the body results are the frames the server sent, in order.
+// The json payloads of the text frames are compared with the body noise of the test case.
+func Match(tc *models.TestCase, actualResponse *models.HTTPResp, actualFrames []models.WebSocketFrame, noiseConfig map[string]map[string][]string, ignoreOrdering bool, logger *zap.Logger) (bool, *models.Result) {
+	res := &models.Result{
+		StatusCode: models.IntResult{
+			Normal:   false,
+			Expected: tc.HTTPResp.StatusCode,
+			Actual:   actualResponse.StatusCode,
+		},
+	}
+
+	bodyNoise := map[string][]string{}
+	for field, regexArr := range noiseConfig["body"] {
+		bodyNoise[strings.ToLower(field)] = regexArr
+	}
+	headerNoise := map[string][]string{}
+	for field, regexArr := range noiseConfig["header"] {
+		headerNoise[strings.ToLower(field)] = regexArr
+	}
+	for field, regexArr := range tc.Noise {
+		a := strings.Split(field, ".")
+		if len(a) > 1 && a[0] == "body" {
+			bodyNoise[strings.ToLower(strings.Join(a[1:], "."))] = regexArr
+		} else if a[0] == "header" {
+			headerNoise[strings.ToLower(a[len(a)-1])] = regexArr
+		}
+	}
+
+	pass, bodyPass := true, true
+	expected, actual := serverFrames(tc.WebSocketFrames), serverFrames(actualFrames)
+	ignoreBody := matcherUtils.Contains(matcherUtils.MapToArray(tc.Noise), "body")
+	for i, exp := range expected {
+		result := models.BodyResult{
+			Type:     models.BodyTypePlain,
+			Expected: payload(exp),
+		}
+		var act models.WebSocketFrame
+		if i < len(actual) {
+			act = actual[i]
+			result.Actual = payload(act)
+		}
+		if json.Valid([]byte(exp.Data)) {
+			result.Type = models.BodyTypeJSON
+		}
+		result.Normal = ignoreBody || (i < len(actual) && exp.Opcode == act.Opcode && matchPayload(logger, exp, act, bodyNoise, ignoreOrdering))
+		if !result.Normal {
+			pass, bodyPass = false, false
+		}
+		res.BodyResult = append(res.BodyResult, result)
+	}
+
+	hRes := &[]models.HeaderResult{}
+	if !matcherUtils.CompareHeaders(pkg.ToHTTPHeader(tc.HTTPResp.Header), pkg.ToHTTPHeader(actualResponse.Header), hRes, headerNoise) {
+		pass = false
+	}
+	res.HeadersResult = *hRes
+
+	if tc.HTTPResp.StatusCode == actualResponse.StatusCode {
+		res.StatusCode.Normal = true
+	} else {
+		pass = false
+	}
+
+	newLogger := pp.New()
+	newLogger.WithLineInfo = false
+	if !pass {
+		logDiffs := matcherUtils.NewDiffsPrinter(tc.Name)
+		newLogger.SetColorScheme(models.GetFailingColorScheme())
+		logs := newLogger.Sprintf("Testrun failed for testcase with id: %s\n\n--------------------------------------------------------------------\n\n", tc.Name)
+
+		if !res.StatusCode.Normal {
+			logDiffs.PushStatusDiff(fmt.Sprint(res.StatusCode.Expected), fmt.Sprint(res.StatusCode.Actual))
+		}
+		for _, j := range res.HeadersResult {
+			if !j.Normal {
+				logDiffs.PushHeaderDiff(fmt.Sprint(j.Expected.Value), fmt.Sprint(j.Actual.Value), j.Expected.Key, headerNoise)
+			}
+		}
+		if !bodyPass {
+			logDiffs.PushBodyDiff(sprintFrames(expected), sprintFrames(actual), bodyNoise)
+		}
+
+		_, err := newLogger.Printf(logs)
+		if err != nil {
+			utils.LogError(logger, err, "failed to print the logs")
+		}
+		err = logDiffs.Render()
+		if err != nil {
+			utils.LogError(logger, err, "failed to render the diffs")
+		}
+	} else {
+		newLogger.SetColorScheme(models.GetPassingColorScheme())
+		_, err := newLogger.Printf(newLogger.Sprintf("Testrun passed for testcase with id: %s\n\n--------------------------------------------------------------------\n\n", tc.Name))
+		if err != nil {
+			utils.LogError(logger, err, "failed to print the logs")
+		}
+	}
+	return pass, res
+}
+
+// matchPayload compares the payloads of the frames, the json payloads without their noisy fields.
+func matchPayload(logger *zap.Logger, exp, act models.WebSocketFrame, bodyNoise map[string][]string, ignoreOrdering bool) bool {
+	if exp.Binary != "" || act.Binary != "" || !json.Valid([]byte(exp.Data)) {
+		return exp.Data == act.Data && exp.Binary == act.Binary
+	}
+	cleanExp, cleanAct := exp.Data, act.Data
+	validatedJSON, err := matcherUtils.ValidateAndMarshalJSON(logger, &cleanExp, &cleanAct)
+	if err != nil || !validatedJSON.IsIdentical() {
+		return false
+	}
+	result, err := matcherUtils.JSONDiffWithNoiseControl(validatedJSON, bodyNoise, ignoreOrdering)
+	return err == nil && result.IsExact()
+}
+
+// serverFrames returns the frames sent by the server.
+func serverFrames(frames []models.WebSocketFrame) []models.WebSocketFrame {
+	var out []models.WebSocketFrame
+	for _, f := range frames {
+		if f.From == pkg.WsFromServer {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// payload returns the payload of the frame as it's recorded, the text or the base64 of the binary payloads.
+func payload(f models.WebSocketFrame) string {
+	if f.Binary != "" {
+		return f.Binary
+	}
+	return f.Data
+}
+
+// sprintFrames returns the frames one per line, with their opcode.
+func sprintFrames(frames []models.WebSocketFrame) string {
+	lines := make([]string, 0, len(frames))
+	for _, f := range frames {
+		lines = append(lines, f.Opcode+": "+payload(f))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,306 @@
+package matcher
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// XMLNode is an element of a canonicalized xml document. The names are qualified by their namespace URI
+// rather than by their prefix, the attributes are sorted and the text is trimmed, so that documents which
+// only differ in their prefixes, attribute order or indentation are equal.
+type XMLNode struct {
+	Space    string
+	Local    string
+	Attrs    []xml.Attr
+	Text     string
+	Children []*XMLNode
+}
+
+// IsXML reports whether the body is a well-formed xml document.
+func IsXML(body string) bool {
+	_, err := CanonicalizeXML(body)
+	return err == nil
+}
+
+// CanonicalizeXML parses the xml document into its canonical tree. The namespace declarations, the
+// comments and the processing instructions are dropped.
+func CanonicalizeXML(body string) (*XMLNode, error) {
+	decoder := xml.NewDecoder(strings.NewReader(body))
+	var (
+		root  *XMLNode
+		stack []*XMLNode
+		text  []*bytes.Buffer
+	)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			node := &XMLNode{Space: t.Name.Space, Local: t.Name.Local}
+			for _, attr := range t.Attr {
+				if attr.Name.Space == "xmlns" || (attr.Name.Space == "" && attr.Name.Local == "xmlns") {
+					continue
+				}
+				node.Attrs = append(node.Attrs, attr)
+			}
+			sort.Slice(node.Attrs, func(i, j int) bool {
+				if node.Attrs[i].Name.Space != node.Attrs[j].Name.Space {
+					return node.Attrs[i].Name.Space < node.Attrs[j].Name.Space
+				}
+				return node.Attrs[i].Name.Local < node.Attrs[j].Name.Local
+			})
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.Children = append(parent.Children, node)
+			} else if root != nil {
+				return nil, errors.New("xml document has more than one root element")
+			} else {
+				root = node
+			}
+			stack = append(stack, node)
+			text = append(text, &bytes.Buffer{})
+		case xml.EndElement:
+			node := stack[len(stack)-1]
+			node.Text = strings.TrimSpace(text[len(text)-1].String())
+			stack, text = stack[:len(stack)-1], text[:len(text)-1]
+		case xml.CharData:
+			if len(text) > 0 {
+				text[len(text)-1].Write(t)
+			} else if len(bytes.TrimSpace(t)) > 0 {
+				return nil, errors.New("xml document has text outside of its root element")
+			}
+		}
+	}
+	if root == nil {
+		return nil, errors.New("xml document has no root element")
+	}
+	return root, nil
+}
+
+// String serializes the canonical tree, the names are written as {namespace}local.
+func (n *XMLNode) String() string {
+	var b strings.Builder
+	n.write(&b, 0)
+	return b.String()
+}
+
+func (n *XMLNode) write(b *strings.Builder, depth int) {
+	indent := strings.Repeat("  ", depth)
+	b.WriteString(indent + "<" + qualifiedName(n.Space, n.Local))
+	for _, attr := range n.Attrs {
+		b.WriteString(" " + qualifiedName(attr.Name.Space, attr.Name.Local) + "=" + strconv.Quote(attr.Value))
+	}
+	b.WriteString(">")
+	if n.Text != "" {
+		b.WriteString(n.Text)
+	}
+	if len(n.Children) > 0 {
+		b.WriteString("\n")
+		for _, child := range n.Children {
+			child.write(b, depth+1)
+		}
+		b.WriteString(indent)
+	}
+	b.WriteString("</" + qualifiedName(n.Space, n.Local) + ">\n")
+}
+
+func qualifiedName(space, local string) string {
+	if space == "" {
+		return local
+	}
+	return "{" + space + "}" + local
+}
+
+// xpathStep is a step of the XPath subset of the xml noise rules: an element name, * for any element,
+// @name or @* for the attributes, and an optional 1-based [position] among the siblings of the same name.
+type xpathStep struct {
+	descendant bool
+	name       string
+	position   int
+}
+
+// pathStep is the step of a node of the document, matched against the xpath steps.
+type pathStep struct {
+	name     string
+	position int
+}
+
+// isXPath reports whether a body noise rule is an XPath rule rather than a json key.
+func isXPath(rule string) bool {
+	return strings.HasPrefix(rule, "/")
+}
+
+// parseXPath parses absolute XPath expressions like /Envelope/Body//Timestamp or //Header/@id. The
+// prefixes of the names are ignored as the rules have no namespace bindings, and the names are compared
+// case-insensitively as the noise rules are lowercased.
+func parseXPath(rule string) ([]xpathStep, error) {
+	var steps []xpathStep
+	for rule != "" {
+		if !strings.HasPrefix(rule, "/") {
+			return nil, errors.New("xpath steps must be separated by /")
+		}
+		step := xpathStep{}
+		if strings.HasPrefix(rule, "//") {
+			step.descendant = true
+			rule = rule[2:]
+		} else {
+			rule = rule[1:]
+		}
+		end := strings.Index(rule, "/")
+		if end == -1 {
+			end = len(rule)
+		}
+		name := rule[:end]
+		rule = rule[end:]
+
+		if open := strings.Index(name, "["); open != -1 {
+			if !strings.HasSuffix(name, "]") {
+				return nil, errors.New("unterminated xpath predicate")
+			}
+			position, err := strconv.Atoi(name[open+1 : len(name)-1])
+			if err != nil || position < 1 {
+				return nil, errors.New("only the position predicates are supported in the xpath noise rules")
+			}
+			step.position = position
+			name = name[:open]
+		}
+		attr := strings.HasPrefix(name, "@")
+		name = strings.TrimPrefix(name, "@")
+		if i := strings.Index(name, ":"); i != -1 {
+			name = name[i+1:]
+		}
+		if name == "" {
+			return nil, errors.New("empty xpath step")
+		}
+		if attr {
+			name = "@" + name
+		}
+		step.name = strings.ToLower(name)
+		steps = append(steps, step)
+	}
+	if len(steps) == 0 {
+		return nil, errors.New("empty xpath")
+	}
+	return steps, nil
+}
+
+func (s xpathStep) matches(p pathStep) bool {
+	if s.position != 0 && s.position != p.position {
+		return false
+	}
+	switch s.name {
+	case "*":
+		return !strings.HasPrefix(p.name, "@")
+	case "@*":
+		return strings.HasPrefix(p.name, "@")
+	}
+	return s.name == p.name
+}
+
+func matchXPath(steps []xpathStep, path []pathStep) bool {
+	if len(steps) == 0 {
+		return len(path) == 0
+	}
+	if len(path) == 0 {
+		return false
+	}
+	if steps[0].descendant {
+		// the step may select any of the remaining nodes
+		for i := range path {
+			if steps[0].matches(path[i]) && matchXPath(steps[1:], path[i+1:]) {
+				return true
+			}
+		}
+		return false
+	}
+	return steps[0].matches(path[0]) && matchXPath(steps[1:], path[1:])
+}
+
+// xmlNoise are the parsed XPath rules of the body noise, with the regexes of the values to ignore.
+type xmlNoise struct {
+	steps   []xpathStep
+	regexes []string
+}
+
+// isNoisy reports whether the node at the path is noisy for the expected value.
+func isNoisy(noise []xmlNoise, path []pathStep, value string) bool {
+	for _, n := range noise {
+		if !matchXPath(n.steps, path) {
+			continue
+		}
+		if len(n.regexes) == 0 {
+			return true
+		}
+		if ok, _ := MatchesAnyRegex(value, n.regexes); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// XMLDiffWithNoiseControl compares the canonical trees of two xml documents. The body noise rules which
+// are XPath expressions ignore the selected elements with their content, or the selected attributes,
+// entirely when they have no regexes, or else when their expected value matches one of the regexes.
+func XMLDiffWithNoiseControl(expected, actual string, noise map[string][]string) (bool, error) {
+	exp, err := CanonicalizeXML(expected)
+	if err != nil {
+		return false, err
+	}
+	act, err := CanonicalizeXML(actual)
+	if err != nil {
+		return false, err
+	}
+	var rules []xmlNoise
+	for rule, regexes := range noise {
+		if !isXPath(rule) {
+			continue
+		}
+		steps, err := parseXPath(rule)
+		if err != nil {
+			return false, errors.New("invalid xpath noise rule " + rule + ": " + err.Error())
+		}
+		rules = append(rules, xmlNoise{steps: steps, regexes: regexes})
+	}
+	return matchXMLNodes(exp, act, []pathStep{{name: strings.ToLower(exp.Local), position: 1}}, rules), nil
+}
+
+func matchXMLNodes(exp, act *XMLNode, path []pathStep, noise []xmlNoise) bool {
+	if exp.Space != act.Space || exp.Local != act.Local {
+		return false
+	}
+	if isNoisy(noise, path, exp.Text) {
+		return true
+	}
+	if exp.Text != act.Text || len(exp.Attrs) != len(act.Attrs) || len(exp.Children) != len(act.Children) {
+		return false
+	}
+	for i, attr := range exp.Attrs {
+		if attr.Name != act.Attrs[i].Name {
+			return false
+		}
+		attrPath := append(path[:len(path):len(path)], pathStep{name: "@" + strings.ToLower(attr.Name.Local), position: 1})
+		if attr.Value != act.Attrs[i].Value && !isNoisy(noise, attrPath, attr.Value) {
+			return false
+		}
+	}
+	positions := make(map[string]int)
+	for i, child := range exp.Children {
+		name := strings.ToLower(child.Local)
+		positions[name]++
+		childPath := append(path[:len(path):len(path)], pathStep{name: name, position: positions[name]})
+		if !matchXMLNodes(child, act.Children[i], childPath, noise) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+)
+
+type AMQP1Schema struct {
+	Metadata         map[string]string `json:"metadata" yaml:"metadata"`
+	Requests         []AMQP1Frame      `json:"requests,omitempty" yaml:"requests,omitempty"`
+	Responses        []AMQP1Frame      `json:"responses,omitempty" yaml:"responses,omitempty"`
+	ReqTimestampMock time.Time         `json:"reqTimestampMock,omitempty" yaml:"reqTimestampMock,omitempty"`
+	ResTimestampMock time.Time         `json:"resTimestampMock,omitempty" yaml:"resTimestampMock,omitempty"`
+}
+
+// AMQP1Frame is an amqp 1.0 frame or a protocol header. The fields used for matching are decoded, the whole
+// frame is kept in Raw as base64.
+type AMQP1Frame struct {
+	// Performative names the frame, e.g. open, attach, transfer or sasl-init, the protocol headers are "header"
+	Performative string `json:"performative" yaml:"performative"`
+	Channel      uint16 `json:"channel,omitempty" yaml:"channel,omitempty"`
+	// Link, Role and Address are set for the frames of a link. The role is the one of the client, and the address
+	// is the source address of the links receiving from the broker and the target address of the others
+	Link    string `json:"link,omitempty" yaml:"link,omitempty"`
+	Role    string `json:"role,omitempty" yaml:"role,omitempty"`
+	Address string `json:"address,omitempty" yaml:"address,omitempty"`
+	// Mechanism is the mechanism chosen by the sasl-init frames
+	Mechanism string `json:"mechanism,omitempty" yaml:"mechanism,omitempty"`
+	// Message is the message of the last transfer frame of a delivery
+	Message *AMQP1Message `json:"message,omitempty" yaml:"message,omitempty"`
+	Raw     string        `json:"raw" yaml:"raw"`
+}
+
+// AMQP1Message is a message of a transfer. The body is kept as text, or as base64 when it isn't valid utf-8.
+type AMQP1Message struct {
+	MessageID     string            `json:"message_id,omitempty" yaml:"message_id,omitempty"`
+	CorrelationID string            `json:"correlation_id,omitempty" yaml:"correlation_id,omitempty"`
+	To            string            `json:"to,omitempty" yaml:"to,omitempty"`
+	Subject       string            `json:"subject,omitempty" yaml:"subject,omitempty"`
+	ReplyTo       string            `json:"reply_to,omitempty" yaml:"reply_to,omitempty"`
+	ContentType   string            `json:"content_type,omitempty" yaml:"content_type,omitempty"`
+	Properties    map[string]string `json:"properties,omitempty" yaml:"properties,omitempty"`
+	Body          string            `json:"body,omitempty" yaml:"body,omitempty"`
+	Binary        bool              `json:"binary,omitempty" yaml:"binary,omitempty"`
+}
@@ -1,11 +1,17 @@
 // Package models provides data models for the keploy.
 package models
 
+import "go.keploy.io/server/v2/config"
+
 type TestSet struct {
 	PreScript    string                 `json:"pre_script" bson:"pre_script" yaml:"preScript"`
 	PostScript   string                 `json:"post_script" bson:"post_script" yaml:"postScript"`
 	Template     map[string]interface{} `json:"template" bson:"template" yaml:"template"`
 	MockRegistry *MockRegistry          `yaml:"mockRegistry" bson:"mock_registry" json:"mockRegistry,omitempty"`
+	// MongoQueryMatching overrides the queryMatching of the mongo integration for the test set
+	MongoQueryMatching string `json:"mongo_query_matching,omitempty" bson:"mongo_query_matching,omitempty" yaml:"mongoQueryMatching,omitempty"`
+	// HTTPMatching adds the rules of the test set to the ones of the http integration
+	HTTPMatching *config.HTTPIntegration `json:"http_matching,omitempty" bson:"http_matching,omitempty" yaml:"httpMatching,omitempty"`
 }
 
 type MockRegistry struct {
@@ -126,3 +126,6 @@ type contextKey string
 const ErrGroupKey contextKey = "errGroup"
 const ClientConnectionIDKey contextKey = "clientConnectionId"
 const DestConnectionIDKey contextKey = "destConnectionId"
+
+// TLSUpgraderKey holds the integrations.TLSUpgrader of the conn, for the protocols starting tls mid-conn
+const TLSUpgraderKey contextKey = "tlsUpgrader"
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+)
+
+type DNSSchema struct {
+	Metadata         map[string]string `json:"metadata" yaml:"metadata"`
+	Request          DNSRequest        `json:"req" yaml:"req"`
+	Response         DNSResponse       `json:"resp" yaml:"resp"`
+	ReqTimestampMock time.Time         `json:"reqTimestampMock,omitempty" yaml:"reqTimestampMock,omitempty"`
+	ResTimestampMock time.Time         `json:"resTimestampMock,omitempty" yaml:"resTimestampMock,omitempty"`
+}
+
+// DNSRequest is the question of a dns query, the name is fully qualified and the type and class are named as in
+// the zone files (A, SRV, TXT, IN).
+type DNSRequest struct {
+	Name  string `json:"name" yaml:"name"`
+	Type  string `json:"type" yaml:"type"`
+	Class string `json:"class,omitempty" yaml:"class,omitempty"`
+}
+
+// DNSResponse is the answer to a dns query. The records are kept in their zone file presentation format, e.g.
+// "_ldap._tcp.example.com. 300 IN SRV 0 5 389 ldap.example.com.".
+type DNSResponse struct {
+	Rcode       string   `json:"rcode" yaml:"rcode"`
+	Answers     []string `json:"answers,omitempty" yaml:"answers,omitempty"`
+	Authorities []string `json:"authorities,omitempty" yaml:"authorities,omitempty"`
+	Additionals []string `json:"additionals,omitempty" yaml:"additionals,omitempty"`
+}
@@ -5,10 +5,12 @@ import (
 )
 
 type GrpcSpec struct {
-	GrpcReq          GrpcReq   `json:"grpcReq" yaml:"grpcReq"`
-	GrpcResp         GrpcResp  `json:"grpcResp" yaml:"grpcResp"`
-	ReqTimestampMock time.Time `json:"reqTimestampMock" yaml:"reqTimestampMock,omitempty"`
-	ResTimestampMock time.Time `json:"resTimestampMock" yaml:"resTimestampMock,omitempty"`
+	GrpcReq          GrpcReq                `json:"grpcReq" yaml:"grpcReq"`
+	GrpcResp         GrpcResp               `json:"grpcResp" yaml:"grpcResp"`
+	Assertions       map[string]interface{} `json:"assertions,omitempty" yaml:"assertions,omitempty"` // noise of the test cases
+	Created          int64                  `json:"created,omitempty" yaml:"created,omitempty"`
+	ReqTimestampMock time.Time              `json:"reqTimestampMock" yaml:"reqTimestampMock,omitempty"`
+	ResTimestampMock time.Time              `json:"resTimestampMock" yaml:"resTimestampMock,omitempty"`
 }
 
 type GrpcHeaders struct {
@@ -22,15 +24,20 @@ type GrpcLengthPrefixedMessage struct {
 	DecodedData     string `json:"decoded_data" yaml:"decoded_data"`
 }
 
+// The messages of the streaming calls are stored in Messages in the order they were sent, Body keeps the
+// first one. A call is streaming if the request or the response has Messages, the unary ones only have a Body.
+
 type GrpcReq struct {
-	Headers GrpcHeaders               `json:"headers" yaml:"headers"`
-	Body    GrpcLengthPrefixedMessage `json:"body" yaml:"body"`
+	Headers  GrpcHeaders                 `json:"headers" yaml:"headers"`
+	Body     GrpcLengthPrefixedMessage   `json:"body" yaml:"body"`
+	Messages []GrpcLengthPrefixedMessage `json:"messages,omitempty" yaml:"messages,omitempty"`
 }
 
 type GrpcResp struct {
-	Headers  GrpcHeaders               `json:"headers" yaml:"headers"`
-	Body     GrpcLengthPrefixedMessage `json:"body" yaml:"body"`
-	Trailers GrpcHeaders               `json:"trailers" yaml:"trailers"`
+	Headers  GrpcHeaders                 `json:"headers" yaml:"headers"`
+	Body     GrpcLengthPrefixedMessage   `json:"body" yaml:"body"`
+	Messages []GrpcLengthPrefixedMessage `json:"messages,omitempty" yaml:"messages,omitempty"`
+	Trailers GrpcHeaders                 `json:"trailers" yaml:"trailers"`
 }
 
 // GrpcStream is a helper function to combine the request-response model in a single struct.
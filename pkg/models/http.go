@@ -7,16 +7,20 @@ import (
 type Method string
 
 type HTTPReq struct {
-	Method     Method            `json:"method" yaml:"method"`
-	ProtoMajor int               `json:"proto_major" yaml:"proto_major"` // e.g. 1
-	ProtoMinor int               `json:"proto_minor" yaml:"proto_minor"` // e.g. 0
-	URL        string            `json:"url" yaml:"url"`
-	URLParams  map[string]string `json:"url_params" yaml:"url_params,omitempty"`
-	Header     map[string]string `json:"header" yaml:"header"`
-	Body       string            `json:"body" yaml:"body"`
-	Binary     string            `json:"binary" yaml:"binary,omitempty"`
-	Form       []FormData        `json:"form" yaml:"form,omitempty"`
-	Timestamp  time.Time         `json:"timestamp" yaml:"timestamp"`
+	Method       Method            `json:"method" yaml:"method"`
+	ProtoMajor   int               `json:"proto_major" yaml:"proto_major"` // e.g. 1
+	ProtoMinor   int               `json:"proto_minor" yaml:"proto_minor"` // e.g. 0
+	URL          string            `json:"url" yaml:"url"`
+	URLParams    map[string]string `json:"url_params" yaml:"url_params,omitempty"`
+	Header       map[string]string `json:"header" yaml:"header"`
+	Body         string            `json:"body" yaml:"body"`
+	BodyDigest   string            `json:"body_digest,omitempty" yaml:"body_digest,omitempty"` // stored instead of the body of the S3 uploads
+	Binary       string            `json:"binary" yaml:"binary,omitempty"`
+	Form         []FormData        `json:"form" yaml:"form,omitempty"`
+	InfluxPoints []InfluxPoint     `json:"influx_points,omitempty" yaml:"influx_points,omitempty"` // the points of an InfluxDB write
+	Trailer      map[string]string `json:"trailer,omitempty" yaml:"trailer,omitempty"`             // the trailer fields sent after a chunked body
+	Truncated    *BodyTruncation   `json:"truncated,omitempty" yaml:"truncated,omitempty"`         // set when the body stored is the prefix of a larger body
+	Timestamp    time.Time         `json:"timestamp" yaml:"timestamp"`
 }
 
 type HTTPSchema struct {
@@ -34,6 +38,18 @@ type FormData struct {
 	Key    string   `json:"key" bson:"key" yaml:"key"`
 	Values []string `json:"values" bson:"values,omitempty" yaml:"values,omitempty"`
 	Paths  []string `json:"paths" bson:"paths,omitempty" yaml:"paths,omitempty"`
+	// Files are the files uploaded under the key in a multipart/form-data body
+	Files []FormFile `json:"files,omitempty" bson:"files,omitempty" yaml:"files,omitempty"`
+}
+
+// FormFile is a file uploaded in a multipart/form-data body. Its content is stored apart from the yaml, in the blob
+// file at Path, relative to the test set, named by the sha256 of the content.
+type FormFile struct {
+	Name        string `json:"name" bson:"name" yaml:"name"`
+	ContentType string `json:"content_type,omitempty" bson:"content_type,omitempty" yaml:"content_type,omitempty"`
+	Hash        string `json:"hash" bson:"hash" yaml:"hash"`
+	Path        string `json:"path,omitempty" bson:"path,omitempty" yaml:"path,omitempty"`
+	Content     string `json:"content,omitempty" bson:"-" yaml:"-"`
 }
 
 type HTTPResp struct {
@@ -44,5 +60,26 @@ type HTTPResp struct {
 	ProtoMajor    int               `json:"proto_major" yaml:"proto_major"`
 	ProtoMinor    int               `json:"proto_minor" yaml:"proto_minor"`
 	Binary        string            `json:"binary" yaml:"binary,omitempty"`
+	Events        []ServerSentEvent `json:"events,omitempty" yaml:"events,omitempty"`       // the events of a text/event-stream response, recorded in place of its body
+	Chunks        []int             `json:"chunks,omitempty" yaml:"chunks,omitempty"`       // the sizes of the chunks of a chunked response, replayed in the same framing
+	Trailer       map[string]string `json:"trailer,omitempty" yaml:"trailer,omitempty"`     // the trailer fields sent after a chunked body
+	Truncated     *BodyTruncation   `json:"truncated,omitempty" yaml:"truncated,omitempty"` // set when the body stored is the prefix of a larger body
 	Timestamp     time.Time         `json:"timestamp" yaml:"timestamp"`
 }
+
+// BodyTruncation is the whole body of a request or a response whose body was truncated to the size limit of the
+// capture. The body is compared by its size and its digest in place of its content.
+type BodyTruncation struct {
+	Size   int    `json:"size" yaml:"size"`
+	Digest string `json:"digest" yaml:"digest"`
+}
+
+// ServerSentEvent is an event of a text/event-stream response. Offset is the time of the event in milliseconds
+// since the headers of the response.
+type ServerSentEvent struct {
+	ID     string `json:"id,omitempty" yaml:"id,omitempty"`
+	Event  string `json:"event,omitempty" yaml:"event,omitempty"`
+	Data   string `json:"data,omitempty" yaml:"data,omitempty"`
+	Retry  string `json:"retry,omitempty" yaml:"retry,omitempty"`
+	Offset int64  `json:"offset" yaml:"offset"`
+}
@@ -0,0 +1,10 @@
+package models
+
+// InfluxPoint is a point of the line protocol written to InfluxDB. The values of the fields are kept as
+// written, e.g. 1i for an integer or "on" for a string.
+type InfluxPoint struct {
+	Measurement string            `json:"measurement" yaml:"measurement"`
+	Tags        map[string]string `json:"tags,omitempty" yaml:"tags,omitempty"`
+	Fields      map[string]string `json:"fields" yaml:"fields"`
+	Timestamp   string            `json:"timestamp,omitempty" yaml:"timestamp,omitempty"`
+}
@@ -19,10 +19,31 @@ type OutgoingOptions struct {
 	SQLDelay       time.Duration // This is the same as Application delay.
 	FallBackOnMiss bool          // this enables to pass the request to the actual server if no mock is found during test mode.
 	Mocking        bool          // used to enable/disable mocking
+	BypassDomains  []string      // domains whose calls are forwarded without being recorded as mocks (record mode)
+	// WebSocketTiming is "recorded" to replay the server frames with their recorded gaps, or "immediate"
+	WebSocketTiming string
+	// SSETiming is "recorded" to replay the server-sent events at their recorded times, or "accelerated"
+	SSETiming string
+	// Framing is the framing rule of the destination port, the generic integration records and matches its frames
+	Framing *config.Framing
+	// SchemaRegistry is the url of the schema registry the kafka integration decodes the avro records with
+	SchemaRegistry string
+	// MySQL is the matching configuration of the mysql integration
+	MySQL config.MySQLIntegration
+	// Postgres is the authentication configuration of the postgres integration
+	Postgres config.PostgresIntegration
+	// Mongo is the matching configuration of the mongo integration, the one of the test set when it's set
+	Mongo config.MongoIntegration
+	// HTTP is the matching configuration of the http integration, with the rules of the test set added
+	HTTP config.HTTPIntegration
 }
 
 type IncomingOptions struct {
 	Filters []config.Filter
+	// Capture is the timing and the buffer sizes of the capture of the incoming calls
+	Capture config.Capture
+	// TLSIngress is the https servers of the app whose tls is terminated to capture their calls
+	TLSIngress []config.TLSIngress
 }
 
 type SetupOptions struct {
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+)
+
+type KafkaSchema struct {
+	Metadata         map[string]string `json:"metadata" yaml:"metadata"`
+	Requests         []KafkaRequest    `json:"requests" yaml:"requests"`
+	Responses        []KafkaResponse   `json:"responses,omitempty" yaml:"responses,omitempty"`
+	ReqTimestampMock time.Time         `json:"reqTimestampMock,omitempty" yaml:"reqTimestampMock,omitempty"`
+	ResTimestampMock time.Time         `json:"resTimestampMock,omitempty" yaml:"resTimestampMock,omitempty"`
+}
+
+// KafkaRequestHeader is the header of a kafka request, the client id is the only field of the flexible
+// header versions which is kept.
+type KafkaRequestHeader struct {
+	APIKey        int16  `json:"api_key" yaml:"api_key"`
+	APIName       string `json:"api_name" yaml:"api_name"`
+	APIVersion    int16  `json:"api_version" yaml:"api_version"`
+	CorrelationID int32  `json:"correlation_id" yaml:"correlation_id"`
+	ClientID      string `json:"client_id,omitempty" yaml:"client_id,omitempty"`
+}
+
+// KafkaRequest is a kafka request, its body is stored as base64 since it is only partly decoded.
+type KafkaRequest struct {
+	Header KafkaRequestHeader `json:"header" yaml:"header"`
+	// Topics are the topics the request is about, for the apis whose topics are decoded
+	Topics []string `json:"topics,omitempty" yaml:"topics,omitempty"`
+	// Records are the records of the produce requests, AvroSchemas the schemas their values were decoded with
+	Records     []KafkaRecord    `json:"records,omitempty" yaml:"records,omitempty"`
+	AvroSchemas map[int32]string `json:"avro_schemas,omitempty" yaml:"avro_schemas,omitempty"`
+	Body        string           `json:"body" yaml:"body"`
+}
+
+// KafkaResponse is a kafka response, its body holds everything after the correlation id as base64.
+type KafkaResponse struct {
+	CorrelationID int32 `json:"correlation_id" yaml:"correlation_id"`
+	// Records are the records of the fetch responses, they are only kept to be read since the body is replayed
+	Records []KafkaRecord `json:"records,omitempty" yaml:"records,omitempty"`
+	Body    string        `json:"body" yaml:"body"`
+}
+
+// KafkaRecord is a record of a topic partition. The values in the confluent wire format, a zero byte and the
+// id of their schema in the schema registry, are decoded with their avro schema into json. The other keys
+// and values are kept as text, or as base64 when they aren't valid utf-8.
+type KafkaRecord struct {
+	Topic     string `json:"topic" yaml:"topic"`
+	Partition int32  `json:"partition" yaml:"partition"`
+	Key       string `json:"key,omitempty" yaml:"key,omitempty"`
+	SchemaID  int32  `json:"schema_id,omitempty" yaml:"schema_id,omitempty"`
+	Value     string `json:"value,omitempty" yaml:"value,omitempty"`
+	// Binary is set when the key and the value which aren't decoded are base64
+	Binary bool `json:"binary,omitempty" yaml:"binary,omitempty"`
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+)
+
+type LDAPSchema struct {
+	Metadata         map[string]string `json:"metadata" yaml:"metadata"`
+	Requests         []LDAPMessage     `json:"requests" yaml:"requests"`
+	Responses        []LDAPMessage     `json:"responses" yaml:"responses"`
+	ReqTimestampMock time.Time         `json:"reqTimestampMock,omitempty" yaml:"reqTimestampMock,omitempty"`
+	ResTimestampMock time.Time         `json:"resTimestampMock,omitempty" yaml:"resTimestampMock,omitempty"`
+}
+
+// LDAPMessage is an ldap message. The fields used for matching are decoded from the protocol operation, the
+// whole BER encoded message is kept in Raw as base64 with the simple bind passwords masked.
+type LDAPMessage struct {
+	MessageID int64  `json:"message_id" yaml:"message_id"`
+	Operation string `json:"operation" yaml:"operation"`
+	// DN is the entry of the operation, the base of a search or the name of a bind
+	DN    string `json:"dn,omitempty" yaml:"dn,omitempty"`
+	Scope string `json:"scope,omitempty" yaml:"scope,omitempty"`
+	// Filter is the search filter in the RFC 4515 string form, with the attribute names in lower case and the
+	// and/or terms sorted. It holds the assertion of the compare requests.
+	Filter     string   `json:"filter,omitempty" yaml:"filter,omitempty"`
+	Attributes []string `json:"attributes,omitempty" yaml:"attributes,omitempty"`
+	// Name is the authentication mechanism of a bind, the new RDN of a modify DN or the OID of an extended operation
+	Name       string `json:"name,omitempty" yaml:"name,omitempty"`
+	ResultCode int    `json:"result_code,omitempty" yaml:"result_code,omitempty"`
+	Raw        string `json:"raw" yaml:"raw"`
+}
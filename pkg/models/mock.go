@@ -42,6 +42,27 @@ type MockSpec struct {
 	GRPCResp          *GrpcResp         `json:"grpcResponse,omitempty" bson:"grpc_resp,omitempty"`
 	MySQLRequests     []mysql.Request   `json:"MySqlRequests,omitempty" bson:"my_sql_requests,omitempty"`
 	MySQLResponses    []mysql.Response  `json:"MySqlResponses,omitempty" bson:"my_sql_responses,omitempty"`
+	KafkaRequests     []KafkaRequest    `json:"KafkaRequests,omitempty" bson:"kafka_requests,omitempty"`
+	KafkaResponses    []KafkaResponse   `json:"KafkaResponses,omitempty" bson:"kafka_responses,omitempty"`
+	MQTTRequests      []MQTTPacket      `json:"MQTTRequests,omitempty" bson:"mqtt_requests,omitempty"`
+	MQTTResponses     []MQTTPacket      `json:"MQTTResponses,omitempty" bson:"mqtt_responses,omitempty"`
+	LDAPRequests      []LDAPMessage     `json:"LDAPRequests,omitempty" bson:"ldap_requests,omitempty"`
+	LDAPResponses     []LDAPMessage     `json:"LDAPResponses,omitempty" bson:"ldap_responses,omitempty"`
+	ThriftRequests    []ThriftMessage   `json:"ThriftRequests,omitempty" bson:"thrift_requests,omitempty"`
+	ThriftResponses   []ThriftMessage   `json:"ThriftResponses,omitempty" bson:"thrift_responses,omitempty"`
+	MSSQLRequests     []MSSQLMessage    `json:"MSSQLRequests,omitempty" bson:"mssql_requests,omitempty"`
+	MSSQLResponses    []MSSQLMessage    `json:"MSSQLResponses,omitempty" bson:"mssql_responses,omitempty"`
+	MySQLXRequests    []MySQLXMessage   `json:"MySQLXRequests,omitempty" bson:"mysqlx_requests,omitempty"`
+	MySQLXResponses   []MySQLXMessage   `json:"MySQLXResponses,omitempty" bson:"mysqlx_responses,omitempty"`
+	WebSocketFrames   []WebSocketFrame  `json:"WebSocketFrames,omitempty" bson:"websocket_frames,omitempty"`
+	ZeroMQRequests    []ZeroMQMessage   `json:"ZeroMQRequests,omitempty" bson:"zeromq_requests,omitempty"`
+	ZeroMQResponses   []ZeroMQMessage   `json:"ZeroMQResponses,omitempty" bson:"zeromq_responses,omitempty"`
+	AMQP1Requests     []AMQP1Frame      `json:"AMQP1Requests,omitempty" bson:"amqp1_requests,omitempty"`
+	AMQP1Responses    []AMQP1Frame      `json:"AMQP1Responses,omitempty" bson:"amqp1_responses,omitempty"`
+	OpenWireRequests  []OpenWireCommand `json:"OpenWireRequests,omitempty" bson:"openwire_requests,omitempty"`
+	OpenWireResponses []OpenWireCommand `json:"OpenWireResponses,omitempty" bson:"openwire_responses,omitempty"`
+	DNSReq            *DNSRequest       `json:"DNSRequest,omitempty" bson:"dns_req,omitempty"`
+	DNSResp           *DNSResponse      `json:"DNSResponse,omitempty" bson:"dns_resp,omitempty"`
 	ReqTimestampMock  time.Time         `json:"ReqTimestampMock,omitempty" bson:"req_timestamp_mock,omitempty"`
 	ResTimestampMock  time.Time         `json:"ResTimestampMock,omitempty" bson:"res_timestamp_mock,omitempty"`
 }
@@ -59,6 +59,9 @@ type MongoHeader struct {
 	RequestID  int32              `json:"requestId" yaml:"requestId" bson:"request_id"`
 	ResponseTo int32              `json:"responseTo" yaml:"responseTo" bson:"response_to"`
 	Opcode     wiremessage.OpCode `json:"Opcode" yaml:"Opcode" bson:"opcode"`
+	// Compressor is the compressor of the OP_COMPRESSED the message was sent in, the message is kept uncompressed
+	// with its original opcode. It's empty for the messages sent uncompressed.
+	Compressor string `json:"compressor,omitempty" yaml:"compressor,omitempty" bson:"compressor,omitempty"`
 }
 
 type MongoRequest struct {
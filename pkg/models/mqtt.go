@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+)
+
+type MQTTSchema struct {
+	Metadata         map[string]string `json:"metadata" yaml:"metadata"`
+	Requests         []MQTTPacket      `json:"requests,omitempty" yaml:"requests,omitempty"`
+	Responses        []MQTTPacket      `json:"responses,omitempty" yaml:"responses,omitempty"`
+	ReqTimestampMock time.Time         `json:"reqTimestampMock,omitempty" yaml:"reqTimestampMock,omitempty"`
+	ResTimestampMock time.Time         `json:"resTimestampMock,omitempty" yaml:"resTimestampMock,omitempty"`
+}
+
+// MQTTPacket is an mqtt control packet. The fields used for matching are decoded, the whole packet is kept
+// in Raw as base64.
+type MQTTPacket struct {
+	Type     string `json:"type" yaml:"type"`
+	PacketID uint16 `json:"packet_id,omitempty" yaml:"packet_id,omitempty"`
+	// ClientID and ProtocolLevel are set for the CONNECT packets, the level is 4 for 3.1.1 and 5 for 5.0
+	ClientID      string `json:"client_id,omitempty" yaml:"client_id,omitempty"`
+	ProtocolLevel uint8  `json:"protocol_level,omitempty" yaml:"protocol_level,omitempty"`
+	// Topic, QoS and Retain are set for the PUBLISH packets
+	Topic  string `json:"topic,omitempty" yaml:"topic,omitempty"`
+	QoS    uint8  `json:"qos,omitempty" yaml:"qos,omitempty"`
+	Retain bool   `json:"retain,omitempty" yaml:"retain,omitempty"`
+	// Filters are the topic filters of the SUBSCRIBE and UNSUBSCRIBE packets
+	Filters []string `json:"filters,omitempty" yaml:"filters,omitempty"`
+	Raw     string   `json:"raw" yaml:"raw"`
+}
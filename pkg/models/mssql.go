@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+)
+
+type MSSQLSchema struct {
+	Metadata         map[string]string `json:"metadata" yaml:"metadata"`
+	Requests         []MSSQLMessage    `json:"requests" yaml:"requests"`
+	Responses        []MSSQLMessage    `json:"responses" yaml:"responses"`
+	ReqTimestampMock time.Time         `json:"reqTimestampMock,omitempty" yaml:"reqTimestampMock,omitempty"`
+	ResTimestampMock time.Time         `json:"resTimestampMock,omitempty" yaml:"resTimestampMock,omitempty"`
+}
+
+// MSSQLMessage is a TDS message of SQL Server. The fields used for matching are decoded from the message, the
+// packets of the message are kept in Raw as base64 with the login passwords masked.
+type MSSQLMessage struct {
+	// Type is one of PRELOGIN, LOGIN7, SQLBatch, RPC and TabularResult
+	Type string `json:"type" yaml:"type"`
+	// User, Database and AppName are set for the LOGIN7 messages
+	User     string `json:"user,omitempty" yaml:"user,omitempty"`
+	Database string `json:"database,omitempty" yaml:"database,omitempty"`
+	AppName  string `json:"app_name,omitempty" yaml:"app_name,omitempty"`
+	// Query is the text of a SQL batch or the statement executed by an RPC like sp_executesql
+	Query     string `json:"query,omitempty" yaml:"query,omitempty"`
+	Procedure string `json:"procedure,omitempty" yaml:"procedure,omitempty"`
+	// Params are the other parameters of an RPC as name=value
+	Params []string `json:"params,omitempty" yaml:"params,omitempty"`
+	Raw    string   `json:"raw" yaml:"raw"`
+}
@@ -25,7 +25,10 @@ type LocalInFileRequestPacket struct {
 
 // TextResultSet is used as a response packet for COM_QUERY
 type TextResultSet struct {
-	ColumnCount     uint64                `yaml:"columnCount"`
+	ColumnCount uint64 `yaml:"columnCount"`
+	// MetadataFollows is sent by the MariaDB servers with MARIADB_CLIENT_CACHE_METADATA, the column definitions
+	// are left out when it is 0 since the client cached them with the prepared statement, nil otherwise
+	MetadataFollows *byte                 `yaml:"metadataFollows,omitempty"`
 	Columns         []*ColumnDefinition41 `yaml:"columns"`
 	EOFAfterColumns []byte                `yaml:"eofAfterColumns"`
 	Rows            []*TextRow            `yaml:"rows"`
@@ -34,7 +37,10 @@ type TextResultSet struct {
 
 // BinaryProtocolResultSet is used as a response packet for COM_STMT_EXECUTE
 type BinaryProtocolResultSet struct {
-	ColumnCount     uint64                `yaml:"columnCount"`
+	ColumnCount uint64 `yaml:"columnCount"`
+	// MetadataFollows is the same as the one of the TextResultSet, the MariaDB servers only leave out the column
+	// definitions of the executes
+	MetadataFollows *byte                 `yaml:"metadataFollows,omitempty"`
 	Columns         []*ColumnDefinition41 `yaml:"columns"`
 	EOFAfterColumns []byte                `yaml:"eofAfterColumns"`
 	Rows            []*BinaryRow          `yaml:"rows"`
@@ -69,6 +75,8 @@ type ColumnDefinition41 struct {
 	Decimals     byte   `yaml:"decimals"`
 	Filler       []byte `yaml:"filler"`
 	DefaultValue string `yaml:"defaultValue"`
+	// ExtendedMetadata is the extended type info sent by the MariaDB servers with MARIADB_CLIENT_EXTENDED_METADATA
+	ExtendedMetadata []byte `yaml:"extendedMetadata,omitempty,flow"`
 }
 
 //Rows
@@ -118,8 +126,10 @@ type StmtPrepareOkPacket struct {
 // COM_STMT_EXECUTE packet
 
 type StmtExecutePacket struct {
-	Status            byte        `yaml:"status"`
-	StatementID       uint32      `yaml:"statement_id"`
+	Status      byte   `yaml:"status"`
+	StatementID uint32 `yaml:"statement_id"`
+	// Query is the sql of the prepared statement, the statement ids are given out by the server
+	Query             string      `yaml:"query,omitempty"`
 	Flags             byte        `yaml:"flags"`
 	IterationCount    uint32      `yaml:"iteration_count"`
 	ParameterCount    int         `yaml:"parameter_count"`
@@ -132,7 +142,9 @@ type Parameter struct {
 	Type     uint16 `yaml:"type"`
 	Unsigned bool   `yaml:"unsigned"`
 	Name     string `yaml:"name,omitempty"`
-	Value    []byte `yaml:"value"`
+	Null     bool   `yaml:"null,omitempty"`
+	// Value is the value in the binary protocol, without the length of the strings
+	Value []byte `yaml:"value"`
 }
 
 // COM_STMT_FETCH packet is not currently supported because its response involves multi-resultset
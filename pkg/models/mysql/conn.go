@@ -16,6 +16,8 @@ type HandshakeV10Packet struct {
 	CharacterSet    uint8  `yaml:"character_set"`
 	StatusFlags     uint16 `yaml:"status_flags"`
 	AuthPluginName  string `yaml:"auth_plugin_name"`
+	// MariaDBCapabilityFlags are sent by the MariaDB servers in the last bytes of the reserved ones
+	MariaDBCapabilityFlags uint32 `yaml:"mariadb_capability_flags,omitempty"`
 }
 
 // HandshakeResponse41Packet represents the response packet sent by the client to the server after receiving the HandshakeV10Packet
@@ -30,6 +32,17 @@ type HandshakeResponse41Packet struct {
 	AuthPluginName       string            `yaml:"auth_plugin_name"`
 	ConnectionAttributes map[string]string `yaml:"connection_attributes,omitempty"`
 	ZstdCompressionLevel byte              `yaml:"zstdcompressionlevel"`
+	// MariaDBCapabilityFlags are sent by the MariaDB clients in the last bytes of the filler, they are kept in the filler as well
+	MariaDBCapabilityFlags uint32 `yaml:"mariadb_capability_flags,omitempty"`
+}
+
+// SSLRequestPacket represents the packet sent by the client instead of the HandshakeResponse41Packet to start tls,
+// the HandshakeResponse41Packet is sent over tls afterwards
+type SSLRequestPacket struct {
+	CapabilityFlags uint32   `yaml:"capability_flags"`
+	MaxPacketSize   uint32   `yaml:"max_packet_size"`
+	CharacterSet    uint8    `yaml:"character_set"`
+	Filler          [23]byte `yaml:"filler,omitempty,flow"`
 }
 
 // Authentication Packets
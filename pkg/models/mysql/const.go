@@ -21,6 +21,7 @@ const (
 // Some constants for MySQL
 const (
 	HandshakeResponse41 = "HandshakeResponse41"
+	SSLRequest          = "SSLRequest"
 	COM_STMT_PREPARE_OK = "COM_STMT_PREPARE_OK"
 )
 
@@ -88,6 +89,22 @@ const (
 	CLIENT_REMEMBER_OPTIONS
 )
 
+// CLIENT_MYSQL is the flag MariaDB names CLIENT_LONG_PASSWORD, the MariaDB servers and clients turn it off to
+// send their MariaDB capability flags in the bytes reserved by mysql.
+const CLIENT_MYSQL = CLIENT_LONG_PASSWORD
+
+// MariaDB Capability Flags, the bits 32 to 63 of the capabilities
+const (
+	// https://mariadb.com/kb/en/connection/#capabilities
+
+	MARIADB_CLIENT_PROGRESS uint32 = 1 << iota
+	MARIADB_CLIENT_COM_MULTI
+	MARIADB_CLIENT_STMT_BULK_OPERATIONS
+	MARIADB_CLIENT_EXTENDED_METADATA
+	MARIADB_CLIENT_CACHE_METADATA
+	MARIADB_CLIENT_BULK_UNIT_RESULTS
+)
+
 type FieldType byte
 
 // Field Types
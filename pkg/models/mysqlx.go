@@ -0,0 +1,56 @@
+package models
+
+import (
+	"time"
+)
+
+type MySQLXSchema struct {
+	Metadata         map[string]string `json:"metadata" yaml:"metadata"`
+	Requests         []MySQLXMessage   `json:"requests" yaml:"requests"`
+	Responses        []MySQLXMessage   `json:"responses" yaml:"responses"`
+	ReqTimestampMock time.Time         `json:"reqTimestampMock,omitempty" yaml:"reqTimestampMock,omitempty"`
+	ResTimestampMock time.Time         `json:"resTimestampMock,omitempty" yaml:"resTimestampMock,omitempty"`
+}
+
+// MySQLXMessage is a message of the protobuf based X Protocol of mysql. The fields used for matching are decoded
+// from the message, with the expressions of the CRUD messages written as text. The whole message is kept in Raw
+// as base64 with the plain passwords masked.
+type MySQLXMessage struct {
+	// Type names the message, e.g. Sql.StmtExecute, Crud.Find or Resultset.Row
+	Type string `json:"type" yaml:"type"`
+	// Mechanism and User are set for the authentication messages
+	Mechanism string `json:"mechanism,omitempty" yaml:"mechanism,omitempty"`
+	User      string `json:"user,omitempty" yaml:"user,omitempty"`
+	// Capabilities are the capabilities set by the client or announced by the server, as name=value
+	Capabilities []string `json:"capabilities,omitempty" yaml:"capabilities,omitempty"`
+	// Prepared is the type of the statement prepared, for the prepare, the execute and the cursor messages which
+	// hold the fields of the statement prepared as well
+	Prepared string `json:"prepared,omitempty" yaml:"prepared,omitempty"`
+	// Namespace is the namespace of a Sql.StmtExecute when it isn't sql, e.g. mysqlx for the admin commands
+	Namespace string `json:"namespace,omitempty" yaml:"namespace,omitempty"`
+	Stmt      string `json:"stmt,omitempty" yaml:"stmt,omitempty"`
+	// Schema and Collection are the target of the CRUD messages, DataModel is DOCUMENT or TABLE
+	Schema     string   `json:"schema,omitempty" yaml:"schema,omitempty"`
+	Collection string   `json:"collection,omitempty" yaml:"collection,omitempty"`
+	DataModel  string   `json:"data_model,omitempty" yaml:"data_model,omitempty"`
+	Projection []string `json:"projection,omitempty" yaml:"projection,omitempty"`
+	Criteria   string   `json:"criteria,omitempty" yaml:"criteria,omitempty"`
+	Grouping   []string `json:"grouping,omitempty" yaml:"grouping,omitempty"`
+	Order      []string `json:"order,omitempty" yaml:"order,omitempty"`
+	Limit      string   `json:"limit,omitempty" yaml:"limit,omitempty"`
+	// Rows are the rows inserted, Operations the changes of an update
+	Rows       []string `json:"rows,omitempty" yaml:"rows,omitempty"`
+	Operations []string `json:"operations,omitempty" yaml:"operations,omitempty"`
+	// Args are the arguments bound to the placeholders of the statement
+	Args []string `json:"args,omitempty" yaml:"args,omitempty"`
+	// StmtID and CursorID are chosen by the client, they aren't matched
+	StmtID   uint32 `json:"stmt_id,omitempty" yaml:"stmt_id,omitempty"`
+	CursorID uint32 `json:"cursor_id,omitempty" yaml:"cursor_id,omitempty"`
+	// Column is the name of the column of a Resultset.ColumnMetaData
+	Column string `json:"column,omitempty" yaml:"column,omitempty"`
+	// Code, SQLState and Message are set for the errors, Message for the Ok messages as well
+	Code     uint32 `json:"code,omitempty" yaml:"code,omitempty"`
+	SQLState string `json:"sql_state,omitempty" yaml:"sql_state,omitempty"`
+	Message  string `json:"message,omitempty" yaml:"message,omitempty"`
+	Raw      string `json:"raw" yaml:"raw"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+)
+
+type OpenWireSchema struct {
+	Metadata         map[string]string `json:"metadata" yaml:"metadata"`
+	Requests         []OpenWireCommand `json:"requests,omitempty" yaml:"requests,omitempty"`
+	Responses        []OpenWireCommand `json:"responses,omitempty" yaml:"responses,omitempty"`
+	ReqTimestampMock time.Time         `json:"reqTimestampMock,omitempty" yaml:"reqTimestampMock,omitempty"`
+	ResTimestampMock time.Time         `json:"resTimestampMock,omitempty" yaml:"resTimestampMock,omitempty"`
+}
+
+// OpenWireCommand is an openwire command of the activemq classic brokers. The fields used for matching are
+// decoded, the whole command is kept in Raw as base64.
+type OpenWireCommand struct {
+	// Type names the command, e.g. ConnectionInfo, TextMessage or Response
+	Type             string `json:"type" yaml:"type"`
+	CommandID        int32  `json:"command_id,omitempty" yaml:"command_id,omitempty"`
+	ResponseRequired bool   `json:"response_required,omitempty" yaml:"response_required,omitempty"`
+	// CorrelationID is the command id of the command the responses answer
+	CorrelationID int32 `json:"correlation_id,omitempty" yaml:"correlation_id,omitempty"`
+	// Destination is the destination of the producers, the consumers and the messages, e.g. queue://orders
+	Destination string `json:"destination,omitempty" yaml:"destination,omitempty"`
+	// Body is the content of the messages as text, or as base64 when Binary is set
+	Body   string `json:"body,omitempty" yaml:"body,omitempty"`
+	Binary bool   `json:"binary,omitempty" yaml:"binary,omitempty"`
+	Raw    string `json:"raw" yaml:"raw"`
+}
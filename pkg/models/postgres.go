@@ -34,6 +34,7 @@ type Backend struct {
 	CopyData            pgproto3.CopyData            `json:"copy_data,omitempty" yaml:"copy_data,omitempty"`
 	CopyDone            pgproto3.CopyDone            `json:"copy_done,omitempty" yaml:"copy_done,omitempty"`
 	Describe            pgproto3.Describe            `json:"describe,omitempty" yaml:"describe,omitempty"`
+	Describes           []pgproto3.Describe          `json:"describes,omitempty" yaml:"describes,omitempty"`
 	Execute             pgproto3.Execute             `yaml:"-"`
 	Executes            []pgproto3.Execute           `json:"execute,omitempty" yaml:"execute,omitempty"`
 	Flush               pgproto3.Flush               `json:"flush,omitempty" yaml:"flush,omitempty"`
@@ -100,6 +101,19 @@ type Frontend struct {
 	AuthType                        int32                                    `json:"auth_type" yaml:"auth_type"`
 	// AuthMechanism                   string                                   `json:"auth_mechanism,omitempty" yaml:"auth_mechanism,omitempty"`
 	BodyLen int `json:"body_len,omitempty" yaml:"body_len,omitempty"`
+	// ErrorResponses and NoticeResponses are the errors and the notices of the response in the order they're sent,
+	// with all their fields. ErrorResponse and NoticeResponse are read from the mocks recorded before them.
+	ErrorResponses  []pgproto3.ErrorResponse  `json:"error_responses,omitempty" yaml:"error_responses,omitempty"`
+	NoticeResponses []pgproto3.NoticeResponse `json:"notice_responses,omitempty" yaml:"notice_responses,omitempty"`
+	// ResultColumns are the types and the formats of the columns of the DataRows, the values of the binary columns
+	// are kept in their text format
+	ResultColumns []ResultColumn `json:"result_columns,omitempty" yaml:"result_columns,omitempty,flow"`
+}
+
+// ResultColumn is the type and the format of a column of the rows of a response.
+type ResultColumn struct {
+	DataTypeOID uint32 `json:"data_type_oid" yaml:"data_type_oid"`
+	Format      int16  `json:"format" yaml:"format"`
 }
 
 type StartupPacket struct {
@@ -29,10 +29,22 @@ const (
 	Postgres       Kind     = "Postgres"
 	GRPC_EXPORT    Kind     = "gRPC"
 	Mongo          Kind     = "Mongo"
+	Kafka          Kind     = "Kafka"
+	MQTT           Kind     = "MQTT"
+	LDAP           Kind     = "LDAP"
+	Thrift         Kind     = "Thrift"
+	MSSQL          Kind     = "MSSQL"
+	MySQLX         Kind     = "MySQLX"
+	WebSocket      Kind     = "WebSocket"
+	ZeroMQ         Kind     = "ZeroMQ"
+	AMQP1          Kind     = "AMQP1"
+	OpenWire       Kind     = "OpenWire"
+	DNS            Kind     = "DNS"
 	BodyTypeUtf8   BodyType = "utf-8"
 	BodyTypeBinary BodyType = "binary"
 	BodyTypePlain  BodyType = "PLAIN"
 	BodyTypeJSON   BodyType = "JSON"
+	BodyTypeXML    BodyType = "XML"
 	BodyTypeError  BodyType = "ERROR"
 )
 
@@ -48,11 +60,13 @@ type TestCase struct {
 	AllKeys  map[string][]string `json:"all_keys" bson:"all_keys"`
 	GrpcResp GrpcResp            `json:"grpcResp" bson:"grpcResp"`
 	GrpcReq  GrpcReq             `json:"grpcReq" bson:"grpcReq"`
-	Anchors  map[string][]string `json:"anchors" bson:"anchors"`
-	Noise    map[string][]string `json:"noise" bson:"noise"`
-	Mocks    []*Mock             `json:"mocks" bson:"mocks"`
-	Type     string              `json:"type" bson:"type"`
-	Curl     string              `json:"curl" bson:"curl"`
+	// WebSocketFrames are the frames of the websocket opened by the request, after its handshake
+	WebSocketFrames []WebSocketFrame    `json:"websocket_frames,omitempty" bson:"websocket_frames,omitempty"`
+	Anchors         map[string][]string `json:"anchors" bson:"anchors"`
+	Noise           map[string][]string `json:"noise" bson:"noise"`
+	Mocks           []*Mock             `json:"mocks" bson:"mocks"`
+	Type            string              `json:"type" bson:"type"`
+	Curl            string              `json:"curl" bson:"curl"`
 }
 
 func (tc *TestCase) GetKind() string {
@@ -36,8 +36,12 @@ type TestResult struct {
 	TestCaseID   string     `json:"testCaseID" yaml:"test_case_id"`
 	Req          HTTPReq    `json:"req" yaml:"req,omitempty"`
 	Res          HTTPResp   `json:"resp" yaml:"resp,omitempty"`
-	Noise        Noise      `json:"noise" yaml:"noise,omitempty"`
-	Result       Result     `json:"result" yaml:"result"`
+	GrpcReq      GrpcReq    `json:"grpcReq,omitempty" yaml:"grpc_req,omitempty"`
+	GrpcRes      GrpcResp   `json:"grpcResp,omitempty" yaml:"grpc_resp,omitempty"`
+	// Frames are the websocket frames exchanged with the application
+	Frames []WebSocketFrame `json:"frames,omitempty" yaml:"frames,omitempty"`
+	Noise  Noise            `json:"noise" yaml:"noise,omitempty"`
+	Result Result           `json:"result" yaml:"result"`
 }
 
 func (tr *TestResult) GetKind() string {
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+)
+
+type ThriftSchema struct {
+	Metadata         map[string]string `json:"metadata" yaml:"metadata"`
+	Requests         []ThriftMessage   `json:"requests" yaml:"requests"`
+	Responses        []ThriftMessage   `json:"responses,omitempty" yaml:"responses,omitempty"`
+	ReqTimestampMock time.Time         `json:"reqTimestampMock,omitempty" yaml:"reqTimestampMock,omitempty"`
+	ResTimestampMock time.Time         `json:"resTimestampMock,omitempty" yaml:"resTimestampMock,omitempty"`
+}
+
+// ThriftMessage is a thrift rpc message, the whole message is kept in Raw as base64 along with its frame.
+type ThriftMessage struct {
+	Method string `json:"method" yaml:"method"`
+	// Type is one of call, reply, exception and oneway
+	Type     string `json:"type" yaml:"type"`
+	SeqID    int32  `json:"seq_id" yaml:"seq_id"`
+	Protocol string `json:"protocol" yaml:"protocol"`
+	Framed   bool   `json:"framed,omitempty" yaml:"framed,omitempty"`
+	// Body is the arguments or the result struct as json, the fields are keyed by their ids
+	Body string `json:"body,omitempty" yaml:"body,omitempty"`
+	Raw  string `json:"raw" yaml:"raw"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+)
+
+type WebSocketSchema struct {
+	Metadata         map[string]string      `json:"metadata" yaml:"metadata"`
+	Request          HTTPReq                `json:"req" yaml:"req"`
+	Response         HTTPResp               `json:"resp" yaml:"resp"`
+	Frames           []WebSocketFrame       `json:"frames,omitempty" yaml:"frames,omitempty"`
+	Assertions       map[string]interface{} `json:"assertions,omitempty" yaml:"assertions,omitempty"` // noise of the test cases
+	Created          int64                  `json:"created,omitempty" yaml:"created,omitempty"`
+	ReqTimestampMock time.Time              `json:"reqTimestampMock,omitempty" yaml:"reqTimestampMock,omitempty"`
+	ResTimestampMock time.Time              `json:"resTimestampMock,omitempty" yaml:"resTimestampMock,omitempty"`
+}
+
+// WebSocketFrame is a frame exchanged on a websocket after the handshake. The text payloads are kept in Data,
+// the other payloads in Binary as base64. Offset is the time of the frame in milliseconds since the handshake.
+type WebSocketFrame struct {
+	From   string `json:"from" yaml:"from"`
+	Opcode string `json:"opcode" yaml:"opcode"`
+	Fin    bool   `json:"fin" yaml:"fin"`
+	Data   string `json:"data,omitempty" yaml:"data,omitempty"`
+	Binary string `json:"binary,omitempty" yaml:"binary,omitempty"`
+	Offset int64  `json:"offset" yaml:"offset"`
+}
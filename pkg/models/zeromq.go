@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+)
+
+type ZeroMQSchema struct {
+	Metadata         map[string]string `json:"metadata" yaml:"metadata"`
+	Requests         []ZeroMQMessage   `json:"requests,omitempty" yaml:"requests,omitempty"`
+	Responses        []ZeroMQMessage   `json:"responses,omitempty" yaml:"responses,omitempty"`
+	ReqTimestampMock time.Time         `json:"reqTimestampMock,omitempty" yaml:"reqTimestampMock,omitempty"`
+	ResTimestampMock time.Time         `json:"resTimestampMock,omitempty" yaml:"resTimestampMock,omitempty"`
+}
+
+// ZeroMQMessage is a zmtp command or a multipart message. The commands are named by Command, the properties
+// of the READY, INITIATE and HELLO commands are decoded into Properties and the body of the other commands is
+// kept as a single frame. The frames of a message have no Command.
+type ZeroMQMessage struct {
+	Command    string            `json:"command,omitempty" yaml:"command,omitempty"`
+	Properties map[string]string `json:"properties,omitempty" yaml:"properties,omitempty"`
+	Frames     []ZeroMQFrame     `json:"frames,omitempty" yaml:"frames,omitempty"`
+}
+
+// ZeroMQFrame is a frame of a message, the text frames are kept in Data and the others in Binary as base64.
+type ZeroMQFrame struct {
+	Data   string `json:"data,omitempty" yaml:"data,omitempty"`
+	Binary string `json:"binary,omitempty" yaml:"binary,omitempty"`
+}
@@ -0,0 +1,141 @@
+package pkg
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"strconv"
+	"strings"
+
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+// multipartBoundary returns the boundary of the multipart/form-data Content-Type.
+func multipartBoundary(contentType string) (string, bool) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType != "multipart/form-data" || params["boundary"] == "" {
+		return "", false
+	}
+	return params["boundary"], true
+}
+
+// IsMultipartForm reports whether the Content-Type is multipart/form-data.
+func IsMultipartForm(contentType string) bool {
+	_, ok := multipartBoundary(contentType)
+	return ok
+}
+
+// ParseMultipartForm returns the parts of the multipart/form-data body by their names, in the order they're first
+// sent in. The boundary, which the clients pick at random, is left out so that the forms sent anew compare equal.
+func ParseMultipartForm(body []byte, contentType string) ([]models.FormData, error) {
+	boundary, ok := multipartBoundary(contentType)
+	if !ok {
+		return nil, errors.New("the body isn't multipart/form-data")
+	}
+	var form []models.FormData
+	index := map[string]int{}
+	reader := multipart.NewReader(bytes.NewReader(body), boundary)
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return form, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the multipart form: %w", err)
+		}
+		data, err := io.ReadAll(part)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the part %q of the multipart form: %w", part.FormName(), err)
+		}
+		name := part.FormName()
+		i, ok := index[name]
+		if !ok {
+			i = len(form)
+			index[name] = i
+			form = append(form, models.FormData{Key: name})
+		}
+		if part.FileName() == "" {
+			form[i].Values = append(form[i].Values, string(data))
+			continue
+		}
+		sum := sha256.Sum256(data)
+		form[i].Files = append(form[i].Files, models.FormFile{
+			Name:        part.FileName(),
+			ContentType: part.Header.Get("Content-Type"),
+			Hash:        hex.EncodeToString(sum[:]),
+			Content:     string(data),
+		})
+	}
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// EncodeMultipartForm returns the multipart/form-data body of the form, framed with the boundary of the Content-Type.
+// The files are sent with their content, which is loaded from their blob files along with the form.
+func EncodeMultipartForm(form []models.FormData, contentType string) ([]byte, error) {
+	boundary, ok := multipartBoundary(contentType)
+	if !ok {
+		return nil, errors.New("the body isn't multipart/form-data")
+	}
+	var b bytes.Buffer
+	writer := multipart.NewWriter(&b)
+	if err := writer.SetBoundary(boundary); err != nil {
+		return nil, err
+	}
+	for _, field := range form {
+		for _, value := range field.Values {
+			if err := writer.WriteField(field.Key, value); err != nil {
+				return nil, err
+			}
+		}
+		for _, file := range field.Files {
+			header := textproto.MIMEHeader{}
+			header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`, quoteEscaper.Replace(field.Key), quoteEscaper.Replace(file.Name)))
+			if file.ContentType != "" {
+				header.Set("Content-Type", file.ContentType)
+			}
+			part, err := writer.CreatePart(header)
+			if err != nil {
+				return nil, err
+			}
+			if _, err := io.WriteString(part, file.Content); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// FormParts returns the parts of the multipart form as comparable strings by their names. The values are kept in the
+// order they were sent, and the files are told apart by their names and the sha256 of their content.
+func FormParts(form []models.FormData) map[string]string {
+	parts := make(map[string]string, len(form))
+	for _, field := range form {
+		var b strings.Builder
+		for _, value := range field.Values {
+			b.WriteString("value " + strconv.Quote(value) + "\n")
+		}
+		for _, path := range field.Paths {
+			b.WriteString("path " + strconv.Quote(path) + "\n")
+		}
+		for _, file := range field.Files {
+			hash := file.Hash
+			if hash == "" {
+				sum := sha256.Sum256([]byte(file.Content))
+				hash = hex.EncodeToString(sum[:])
+			}
+			b.WriteString("file " + strconv.Quote(file.Name) + " " + hash + "\n")
+		}
+		parts[field.Key] += b.String()
+	}
+	return parts
+}
@@ -0,0 +1,92 @@
+package pkg
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"reflect"
+	"testing"
+
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+func TestMultipartFormRoundTrip(t *testing.T) {
+	hash := func(content string) string {
+		sum := sha256.Sum256([]byte(content))
+		return hex.EncodeToString(sum[:])
+	}
+	form := []models.FormData{
+		{Key: "title", Values: []string{"first", "second"}},
+		{Key: "avatar", Files: []models.FormFile{
+			{Name: `a "quoted".png`, ContentType: "image/png", Hash: hash("\x89PNG"), Content: "\x89PNG"},
+			{Name: "b.txt", Hash: hash("text"), Content: "text"},
+		}},
+	}
+	contentType := "multipart/form-data; boundary=keploy-boundary"
+
+	body, err := EncodeMultipartForm(form, contentType)
+	if err != nil {
+		t.Fatalf("EncodeMultipartForm() failed: %v", err)
+	}
+	got, err := ParseMultipartForm(body, contentType)
+	if err != nil {
+		t.Fatalf("ParseMultipartForm() failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, form) {
+		t.Errorf("ParseMultipartForm() = %+v, want %+v", got, form)
+	}
+
+	if _, err := ParseMultipartForm(body, "application/json"); err == nil {
+		t.Error("ParseMultipartForm() of a json body didn't fail")
+	}
+}
+
+func TestFormParts(t *testing.T) {
+	file := func(name, content string) models.FormData {
+		return models.FormData{Key: "f", Files: []models.FormFile{{Name: name, Content: content}}}
+	}
+	tests := []struct {
+		name string
+		a, b []models.FormData
+		same bool
+	}{
+		{
+			name: "parts in any order",
+			a:    []models.FormData{{Key: "a", Values: []string{"1"}}, {Key: "b", Values: []string{"2"}}},
+			b:    []models.FormData{{Key: "b", Values: []string{"2"}}, {Key: "a", Values: []string{"1"}}},
+			same: true,
+		},
+		{
+			name: "values in another order",
+			a:    []models.FormData{{Key: "a", Values: []string{"1", "2"}}},
+			b:    []models.FormData{{Key: "a", Values: []string{"2", "1"}}},
+		},
+		{
+			name: "file hashed from its content",
+			a:    []models.FormData{file("a.txt", "abc")},
+			b:    []models.FormData{{Key: "f", Files: []models.FormFile{{Name: "a.txt", Hash: "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"}}}},
+			same: true,
+		},
+		{
+			name: "file content",
+			a:    []models.FormData{file("a.txt", "abc")},
+			b:    []models.FormData{file("a.txt", "abd")},
+		},
+		{
+			name: "file name",
+			a:    []models.FormData{file("a.txt", "abc")},
+			b:    []models.FormData{file("b.txt", "abc")},
+		},
+		{
+			name: "value and file of the same content",
+			a:    []models.FormData{{Key: "f", Values: []string{"abc"}}},
+			b:    []models.FormData{file("", "abc")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if same := reflect.DeepEqual(FormParts(tt.a), FormParts(tt.b)); same != tt.same {
+				t.Errorf("FormParts() equal = %v, want %v: %q, %q", same, tt.same, FormParts(tt.a), FormParts(tt.b))
+			}
+		})
+	}
+}
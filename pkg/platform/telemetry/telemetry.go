@@ -4,7 +4,9 @@ package telemetry
 import (
 	"bytes"
 	"net/http"
+	"os"
 	"runtime"
+	"sync"
 	"time"
 
 	"go.keploy.io/server/v2/pkg/models"
@@ -20,11 +22,16 @@ type Telemetry struct {
 	InstallationID string
 	KeployVersion  string
 	GlobalMap      map[string]interface{}
-	client         *http.Client
+	// LocalPath is the file to which the events are appended (one json per line) instead of being sent
+	LocalPath string
+	client    *http.Client
+	fileMu    sync.Mutex
 }
 
 type Options struct {
 	Enabled        bool
+	Offline        bool
+	LocalPath      string
 	Version        string
 	GlobalMap      map[string]interface{}
 	InstallationID string
@@ -33,6 +40,8 @@ type Options struct {
 func NewTelemetry(logger *zap.Logger, opt Options) *Telemetry {
 	return &Telemetry{
 		Enabled:        opt.Enabled,
+		OffMode:        opt.Offline,
+		LocalPath:      opt.LocalPath,
 		logger:         logger,
 		KeployVersion:  opt.Version,
 		GlobalMap:      opt.GlobalMap,
@@ -42,7 +51,7 @@ func NewTelemetry(logger *zap.Logger, opt Options) *Telemetry {
 }
 
 func (tel *Telemetry) Ping() {
-	if !tel.Enabled {
+	if !tel.Enabled || tel.OffMode {
 		return
 	}
 	go func() {
@@ -89,47 +98,79 @@ func (tel *Telemetry) RecordedTestCaseMock(mockType string) {
 }
 
 func (tel *Telemetry) SendTelemetry(eventType string, output ...map[string]interface{}) {
-	if tel.Enabled {
-		event := models.TeleEvent{
-			EventType: eventType,
-			CreatedAt: time.Now().Unix(),
-		}
-		event.Meta = make(map[string]interface{})
-		if len(output) != 0 {
-			event.Meta = output[0]
-		}
+	sendRemote := tel.Enabled && !tel.OffMode
+	if !sendRemote && tel.LocalPath == "" {
+		return
+	}
 
-		if tel.GlobalMap != nil {
-			event.Meta["global-map"] = tel.GlobalMap
-		}
+	event := models.TeleEvent{
+		EventType: eventType,
+		CreatedAt: time.Now().Unix(),
+	}
+	event.Meta = make(map[string]interface{})
+	if len(output) != 0 {
+		event.Meta = output[0]
+	}
 
-		event.InstallationID = tel.InstallationID
-		event.OS = runtime.GOOS
-		event.KeployVersion = tel.KeployVersion
-		event.Arch = runtime.GOARCH
-		bin, err := marshalEvent(event, tel.logger)
-		if err != nil {
-			tel.logger.Debug("failed to marshal event", zap.Error(err))
-			return
-		}
+	if tel.GlobalMap != nil {
+		event.Meta["global-map"] = tel.GlobalMap
+	}
 
-		req, err := http.NewRequest(http.MethodPost, teleURL, bytes.NewBuffer(bin))
-		if err != nil {
-			tel.logger.Debug("failed to create request for analytics", zap.Error(err))
-			return
-		}
+	event.InstallationID = tel.InstallationID
+	event.OS = runtime.GOOS
+	event.KeployVersion = tel.KeployVersion
+	event.Arch = runtime.GOARCH
+	bin, err := marshalEvent(event, tel.logger)
+	if err != nil {
+		tel.logger.Debug("failed to marshal event", zap.Error(err))
+		return
+	}
 
-		req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	if tel.LocalPath != "" {
+		tel.writeLocal(bin)
+	}
 
-		resp, err := tel.client.Do(req)
-		if err != nil {
-			tel.logger.Debug("failed to send request for analytics", zap.Error(err))
-			return
-		}
-		_, err = unmarshalResp(resp, tel.logger)
-		if err != nil {
-			tel.logger.Debug("failed to unmarshal response", zap.Error(err))
-			return
+	if !sendRemote {
+		return
+	}
+
+	req, err := http.NewRequest(http.MethodPost, teleURL, bytes.NewBuffer(bin))
+	if err != nil {
+		tel.logger.Debug("failed to create request for analytics", zap.Error(err))
+		return
+	}
+
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+
+	resp, err := tel.client.Do(req)
+	if err != nil {
+		tel.logger.Debug("failed to send request for analytics", zap.Error(err))
+		return
+	}
+	_, err = unmarshalResp(resp, tel.logger)
+	if err != nil {
+		tel.logger.Debug("failed to unmarshal response", zap.Error(err))
+		return
+	}
+}
+
+// writeLocal appends the marshalled event to the local telemetry file.
+func (tel *Telemetry) writeLocal(bin []byte) {
+	tel.fileMu.Lock()
+	defer tel.fileMu.Unlock()
+
+	f, err := os.OpenFile(tel.LocalPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		tel.logger.Debug("failed to open the local telemetry file", zap.String("path", tel.LocalPath), zap.Error(err))
+		return
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			tel.logger.Debug("failed to close the local telemetry file", zap.String("path", tel.LocalPath), zap.Error(err))
 		}
+	}()
+
+	if _, err := f.Write(append(bin, '\n')); err != nil {
+		tel.logger.Debug("failed to write event to the local telemetry file", zap.String("path", tel.LocalPath), zap.Error(err))
 	}
 }
@@ -0,0 +1,77 @@
+package yaml
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+// WriteBlob writes the blob file unless it's already written, through a temporary file so that a blob file is
+// never read partially written. The blobs are named by the sha256 of their content, so the identical ones are
+// stored once.
+func WriteBlob(dir, name, data string) error {
+	blobPath := filepath.Join(dir, name)
+	if _, err := os.Stat(blobPath); err == nil {
+		return nil
+	}
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return fmt.Errorf("failed to create the blob directory %s: %v", dir, err)
+	}
+	tmp, err := os.CreateTemp(dir, "."+name+"-*")
+	if err != nil {
+		return fmt.Errorf("failed to create the blob file %s: %v", blobPath, err)
+	}
+	if _, err := tmp.WriteString(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write the blob file %s: %v", blobPath, err)
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write the blob file %s: %v", blobPath, err)
+	}
+	return os.Rename(tmp.Name(), blobPath)
+}
+
+// StoreFormFiles writes the files of the multipart form to the blob directory, a directory of the test set, and
+// returns a copy of the form referencing them by their path in the test set. The form itself is left as it is.
+func StoreFormFiles(dir string, form []models.FormData) ([]models.FormData, error) {
+	stored := make([]models.FormData, len(form))
+	for i, field := range form {
+		stored[i] = field
+		if len(field.Files) == 0 {
+			continue
+		}
+		stored[i].Files = make([]models.FormFile, len(field.Files))
+		for j, file := range field.Files {
+			if file.Content != "" || file.Path == "" {
+				if err := WriteBlob(dir, file.Hash, file.Content); err != nil {
+					return nil, err
+				}
+				file.Path = filepath.ToSlash(filepath.Join(filepath.Base(dir), file.Hash))
+			}
+			file.Content = ""
+			stored[i].Files[j] = file
+		}
+	}
+	return stored, nil
+}
+
+// LoadFormFiles reads the content of the files of the multipart form from their blob files in the test set.
+func LoadFormFiles(testSetDir string, form []models.FormData) error {
+	for i := range form {
+		for j, file := range form[i].Files {
+			if file.Path == "" {
+				continue
+			}
+			data, err := os.ReadFile(filepath.Join(testSetDir, filepath.FromSlash(filepath.Clean("/"+file.Path))))
+			if err != nil {
+				return fmt.Errorf("failed to read the file %s of the form field %s: %v", file.Name, form[i].Key, err)
+			}
+			form[i].Files[j].Content = string(data)
+		}
+	}
+	return nil
+}
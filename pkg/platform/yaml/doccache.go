@@ -0,0 +1,118 @@
+package yaml
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"sync"
+	"unsafe"
+
+	yamlLib "gopkg.in/yaml.v3"
+)
+
+// DefaultDocCacheSize is the default bound of the memory held by the documents cached by ParseDoc.
+const DefaultDocCacheSize = 64 << 20
+
+type cachedDoc struct {
+	key  [sha256.Size]byte
+	doc  *NetworkTrafficDoc
+	size int
+}
+
+// docLRU keeps the parsed documents by the hash of their content, the least recently used ones are evicted once
+// the estimated size of the parsed documents grows past maxSize.
+type docLRU struct {
+	sync.Mutex
+	lru     *list.List
+	items   map[[sha256.Size]byte]*list.Element
+	size    int
+	maxSize int
+}
+
+var docCache = &docLRU{
+	lru:     list.New(),
+	items:   map[[sha256.Size]byte]*list.Element{},
+	maxSize: DefaultDocCacheSize,
+}
+
+// SetDocCacheSize bounds the memory held by the documents cached by ParseDoc to size bytes, the caching is disabled
+// when it's 0.
+func SetDocCacheSize(size int) {
+	docCache.Lock()
+	defer docCache.Unlock()
+	docCache.maxSize = size
+	docCache.evict()
+}
+
+// ParseDoc parses a yaml document into a NetworkTrafficDoc. The parsed documents are cached for the lifetime of
+// the process, so that the documents shared by the test sets, or read again by a later run, are only parsed once.
+// The returned document is shared and must not be modified. It returns nil for an empty document.
+func ParseDoc(data []byte) (*NetworkTrafficDoc, error) {
+	key := sha256.Sum256(data)
+
+	docCache.Lock()
+	if el, ok := docCache.items[key]; ok {
+		docCache.lru.MoveToFront(el)
+		doc := el.Value.(*cachedDoc).doc
+		docCache.Unlock()
+		return doc, nil
+	}
+	docCache.Unlock()
+
+	doc, err := DecodeDoc(data)
+	if doc == nil || err != nil {
+		return doc, err
+	}
+
+	size := docSize(doc)
+	docCache.Lock()
+	defer docCache.Unlock()
+	if _, ok := docCache.items[key]; !ok && size <= docCache.maxSize {
+		docCache.items[key] = docCache.lru.PushFront(&cachedDoc{key: key, doc: doc, size: size})
+		docCache.size += size
+		docCache.evict()
+	}
+	return doc, nil
+}
+
+// DecodeDoc parses a yaml document into a NetworkTrafficDoc without caching it, for the documents which are read
+// once. It returns nil for an empty document.
+func DecodeDoc(data []byte) (*NetworkTrafficDoc, error) {
+	var doc *NetworkTrafficDoc
+	err := yamlLib.NewDecoder(bytes.NewReader(data)).Decode(&doc)
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// evict drops the least recently used documents until the cache is within its bound, the lock must be held.
+func (c *docLRU) evict() {
+	for c.size > c.maxSize {
+		oldest := c.lru.Back()
+		item := oldest.Value.(*cachedDoc)
+		c.lru.Remove(oldest)
+		delete(c.items, item.key)
+		c.size -= item.size
+	}
+}
+
+// docSize estimates the memory held by the parsed document. The nodes of the spec take several times the size of
+// their yaml, so the nodes are counted along with their strings.
+func docSize(doc *NetworkTrafficDoc) int {
+	return int(unsafe.Sizeof(*doc)) + len(doc.Version) + len(doc.Kind) + len(doc.Name) + len(doc.Curl) +
+		len(doc.ConnectionID) + nodeSize(&doc.Spec)
+}
+
+func nodeSize(n *yamlLib.Node) int {
+	size := len(n.Tag) + len(n.Value) + len(n.Anchor) + len(n.HeadComment) + len(n.LineComment) + len(n.FootComment)
+	for _, child := range n.Content {
+		size += int(unsafe.Sizeof(child)+unsafe.Sizeof(*child)) + nodeSize(child)
+	}
+	return size
+}
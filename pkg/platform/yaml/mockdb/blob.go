@@ -0,0 +1,222 @@
+package mockdb
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/pkg/platform/yaml"
+)
+
+// blobThreshold is the size above which a section of a mongo message is stored in a blob file instead of the mocks
+// file, the files uploaded in the multipart forms of the http mocks are stored in blob files whatever their size. The GridFS chunks and the documents up to the 16MB limit are written as megabytes of base64, which make the
+// mocks file too large to be opened.
+const blobThreshold = 1 << 20
+
+// blobPrefix starts the sections stored in a blob file, it's followed by the sha256 of the section naming the file.
+const blobPrefix = "{ SectionBlob sha256: "
+
+// blobDir returns the directory of the blob files of the mocks file, the blobs are named by their content so the
+// identical sections are stored once.
+func blobDir(path, mockFileName string) string {
+	return filepath.Join(path, mockFileName+"-blobs")
+}
+
+// storeBlobs writes the large sections of the mongo messages of the mock, or the files of the multipart form of the
+// http mock, to blob files and returns a copy of the mock referencing them, the mock itself is left as it is.
+func storeBlobs(dir string, mock *models.Mock) (*models.Mock, error) {
+	if mock.Kind == models.HTTP && mock.Spec.HTTPReq != nil && len(mock.Spec.HTTPReq.Form) > 0 {
+		form, err := yaml.StoreFormFiles(dir, mock.Spec.HTTPReq.Form)
+		if err != nil {
+			return nil, err
+		}
+		stored := *mock
+		req := *mock.Spec.HTTPReq
+		req.Form = form
+		stored.Spec.HTTPReq = &req
+		return &stored, nil
+	}
+	if mock.Kind != models.Mongo {
+		return mock, nil
+	}
+	stored := *mock
+	var err error
+	stored.Spec.MongoRequests, err = storeRequestBlobs(dir, mock.Spec.MongoRequests)
+	if err != nil {
+		return nil, err
+	}
+	stored.Spec.MongoResponses, err = storeResponseBlobs(dir, mock.Spec.MongoResponses)
+	if err != nil {
+		return nil, err
+	}
+	return &stored, nil
+}
+
+func storeRequestBlobs(dir string, requests []models.MongoRequest) ([]models.MongoRequest, error) {
+	stored := make([]models.MongoRequest, len(requests))
+	for i, req := range requests {
+		stored[i] = req
+		msg, err := storeMessageBlobs(dir, req.Message)
+		if err != nil {
+			return nil, err
+		}
+		stored[i].Message = msg
+	}
+	return stored, nil
+}
+
+func storeResponseBlobs(dir string, responses []models.MongoResponse) ([]models.MongoResponse, error) {
+	stored := make([]models.MongoResponse, len(responses))
+	for i, resp := range responses {
+		stored[i] = resp
+		msg, err := storeMessageBlobs(dir, resp.Message)
+		if err != nil {
+			return nil, err
+		}
+		stored[i].Message = msg
+	}
+	return stored, nil
+}
+
+// storeMessageBlobs returns the OpMsg with its large sections replaced by the references of their blob files.
+func storeMessageBlobs(dir string, message interface{}) (interface{}, error) {
+	msg, ok := message.(*models.MongoOpMessage)
+	if !ok {
+		return message, nil
+	}
+	var stored *models.MongoOpMessage
+	for i, section := range msg.Sections {
+		if len(section) <= blobThreshold {
+			continue
+		}
+		if stored == nil {
+			copied := *msg
+			copied.Sections = append([]string(nil), msg.Sections...)
+			stored = &copied
+		}
+		sum := sha256.Sum256([]byte(section))
+		name := hex.EncodeToString(sum[:])
+		if err := yaml.WriteBlob(dir, name, section); err != nil {
+			return nil, err
+		}
+		stored.Sections[i] = blobPrefix + name + " }"
+	}
+	if stored == nil {
+		return message, nil
+	}
+	return stored, nil
+}
+
+// loadBlobs replaces the references of the sections of the mocks by the sections read from their blob files.
+func loadBlobs(dir string, mocks []*models.Mock) error {
+	for _, mock := range mocks {
+		if mock.Kind != models.Mongo {
+			continue
+		}
+		for _, req := range mock.Spec.MongoRequests {
+			if err := loadMessageBlobs(dir, req.Message); err != nil {
+				return fmt.Errorf("failed to load the blobs of the mock %s: %v", mock.Name, err)
+			}
+		}
+		for _, resp := range mock.Spec.MongoResponses {
+			if err := loadMessageBlobs(dir, resp.Message); err != nil {
+				return fmt.Errorf("failed to load the blobs of the mock %s: %v", mock.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+func loadMessageBlobs(dir string, message interface{}) error {
+	msg, ok := message.(*models.MongoOpMessage)
+	if !ok {
+		return nil
+	}
+	for i, section := range msg.Sections {
+		name, ok := blobName(section)
+		if !ok {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return err
+		}
+		msg.Sections[i] = string(data)
+	}
+	return nil
+}
+
+// blobName returns the name of the blob file the section references.
+func blobName(section string) (string, bool) {
+	if !strings.HasPrefix(section, blobPrefix) || !strings.HasSuffix(section, " }") {
+		return "", false
+	}
+	name := strings.TrimSuffix(strings.TrimPrefix(section, blobPrefix), " }")
+	// the names are the hex sha256 of the sections, which keeps the references inside the blob directory
+	if _, err := hex.DecodeString(name); err != nil || len(name) != 2*sha256.Size {
+		return "", false
+	}
+	return name, true
+}
+
+// blobNames returns the names of the blob files referenced by the mocks.
+func blobNames(mocks []*models.Mock) map[string]bool {
+	names := map[string]bool{}
+	add := func(message interface{}) {
+		msg, ok := message.(*models.MongoOpMessage)
+		if !ok {
+			return
+		}
+		for _, section := range msg.Sections {
+			if name, ok := blobName(section); ok {
+				names[name] = true
+			}
+		}
+	}
+	for _, mock := range mocks {
+		// the files of the multipart forms are named by their hashes as well
+		if mock.Kind == models.HTTP && mock.Spec.HTTPReq != nil {
+			for _, field := range mock.Spec.HTTPReq.Form {
+				for _, file := range field.Files {
+					names[file.Hash] = true
+				}
+			}
+			continue
+		}
+		if mock.Kind != models.Mongo {
+			continue
+		}
+		for _, req := range mock.Spec.MongoRequests {
+			add(req.Message)
+		}
+		for _, resp := range mock.Spec.MongoResponses {
+			add(resp.Message)
+		}
+	}
+	return names
+}
+
+// removeUnusedBlobs removes the blob files which aren't referenced by the mocks anymore.
+func removeUnusedBlobs(dir string, mocks []*models.Mock) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	used := blobNames(mocks)
+	for _, entry := range entries {
+		if entry.IsDir() || used[entry.Name()] {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, entry.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
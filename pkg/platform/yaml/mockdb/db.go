@@ -10,6 +10,7 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -25,6 +26,9 @@ type MockYaml struct {
 	MockName  string
 	Logger    *zap.Logger
 	idCounter int64
+	// mu guards the appends to the mock files and the cached indexes
+	mu      sync.Mutex
+	indexes map[string]*mockIndex
 }
 
 func New(Logger *zap.Logger, mockPath string, mockName string) *MockYaml {
@@ -33,6 +37,7 @@ func New(Logger *zap.Logger, mockPath string, mockName string) *MockYaml {
 		MockName:  mockName,
 		Logger:    Logger,
 		idCounter: -1,
+		indexes:   map[string]*mockIndex{},
 	}
 }
 
@@ -91,53 +96,38 @@ func (ys *MockYaml) UpdateMocks(ctx context.Context, testSetID string, mockNames
 	}
 	ys.Logger.Debug("logging the names of the used mocks", zap.Any("mockNames", newMocks), zap.Any("for testset", testSetID))
 
-	// remove the old mock yaml file
+	// remove the old mock yaml file along with its index
 	err = os.Remove(filepath.Join(path, mockFileName+".yaml"))
 	if err != nil {
 		return err
 	}
+	if err := os.Remove(indexPath(path, mockFileName)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
 
 	// write the new mocks to the new yaml file
 	for _, newMock := range newMocks {
-		mockYaml, err := EncodeMock(newMock, ys.Logger)
-		if err != nil {
-			utils.LogError(ys.Logger, err, "failed to encode the mock to yaml", zap.Any("mock", newMock.Name), zap.Any("for testset", testSetID))
-			return err
-		}
-		data, err = yamlLib.Marshal(&mockYaml)
-		if err != nil {
-			utils.LogError(ys.Logger, err, "failed to marshal the mock to yaml", zap.Any("mock", newMock.Name), zap.Any("for testset", testSetID))
-			return err
-		}
-		err = yaml.WriteFile(ctx, ys.Logger, path, mockFileName, data, true)
+		err = ys.writeMock(ctx, path, mockFileName, newMock)
 		if err != nil {
 			utils.LogError(ys.Logger, err, "failed to write the mock to yaml", zap.Any("mock", newMock.Name), zap.Any("for testset", testSetID))
 			return err
 		}
 	}
+	// the blobs of the mocks removed are removed along with them
+	if err := removeUnusedBlobs(blobDir(path, mockFileName), newMocks); err != nil {
+		utils.LogError(ys.Logger, err, "failed to remove the unused blobs of the mocks", zap.Any("for testset", testSetID))
+	}
 	return nil
 }
 
 func (ys *MockYaml) InsertMock(ctx context.Context, mock *models.Mock, testSetID string) error {
 	mock.Name = fmt.Sprint("mock-", ys.getNextID())
-	mockYaml, err := EncodeMock(mock, ys.Logger)
-	if err != nil {
-		return err
-	}
 	mockPath := filepath.Join(ys.MockPath, testSetID)
 	mockFileName := ys.MockName
 	if mockFileName == "" {
 		mockFileName = "mocks"
 	}
-	data, err := yamlLib.Marshal(&mockYaml)
-	if err != nil {
-		return err
-	}
-	err = yaml.WriteFile(ctx, ys.Logger, mockPath, mockFileName, data, true)
-	if err != nil {
-		return err
-	}
-	return nil
+	return ys.writeMock(ctx, mockPath, mockFileName, mock)
 }
 
 func (ys *MockYaml) GetFilteredMocks(ctx context.Context, testSetID string, afterTime time.Time, beforeTime time.Time) ([]*models.Mock, error) {
@@ -156,48 +146,15 @@ func (ys *MockYaml) GetFilteredMocks(ctx context.Context, testSetID string, afte
 	}
 
 	if _, err := os.Stat(mockPath); err == nil {
-		var mockYamls []*yaml.NetworkTrafficDoc
-		data, err := yaml.ReadFile(ctx, ys.Logger, path, mockFileName)
-		if err != nil {
-			utils.LogError(ys.Logger, err, "failed to read the mocks from config yaml", zap.Any("session", filepath.Base(path)))
-			return nil, err
-		}
-		dec := yamlLib.NewDecoder(bytes.NewReader(data))
-		for {
-			var doc *yaml.NetworkTrafficDoc
-			err := dec.Decode(&doc)
-			if errors.Is(err, io.EOF) {
-				break
-			}
-			if err != nil {
-				return nil, fmt.Errorf("failed to decode the yaml file documents. error: %v", err.Error())
-			}
-			mockYamls = append(mockYamls, doc)
-		}
-		mocks, err := decodeMocks(mockYamls, ys.Logger)
+		// only the mocks which can pass the filters are read and decoded
+		mocks, err := ys.loadMocks(ctx, path, mockFileName, func(e indexEntry) bool {
+			return !unfilteredKinds[e.Kind] && e.Type != "config" && e.inWindow(afterTime, beforeTime)
+		})
 		if err != nil {
 			utils.LogError(ys.Logger, err, "failed to decode the config mocks from yaml docs", zap.Any("session", filepath.Base(path)))
 			return nil, err
 		}
-
-		for _, mock := range mocks {
-			isFilteredMock := true
-			switch mock.Kind {
-			case "Generic":
-				isFilteredMock = false
-			case "Postgres":
-				isFilteredMock = false
-			case "Http":
-				isFilteredMock = false
-			case "Redis":
-				isFilteredMock = false
-			case "MySQL":
-				isFilteredMock = false
-			}
-			if mock.Spec.Metadata["type"] != "config" && isFilteredMock {
-				tcsMocks = append(tcsMocks, mock)
-			}
-		}
+		tcsMocks = append(tcsMocks, mocks...)
 	}
 	filteredTcsMocks, _ = ys.filterByTimeStamp(ctx, tcsMocks, afterTime, beforeTime, ys.Logger)
 
@@ -225,47 +182,14 @@ func (ys *MockYaml) GetUnFilteredMocks(ctx context.Context, testSetID string, af
 	}
 
 	if _, err := os.Stat(mockPath); err == nil {
-		var mockYamls []*yaml.NetworkTrafficDoc
-		data, err := yaml.ReadFile(ctx, ys.Logger, path, mockName)
-		if err != nil {
-			utils.LogError(ys.Logger, err, "failed to read the mocks from config yaml", zap.Any("session", filepath.Base(path)))
-			return nil, err
-		}
-		dec := yamlLib.NewDecoder(bytes.NewReader(data))
-		for {
-			var doc *yaml.NetworkTrafficDoc
-			err := dec.Decode(&doc)
-			if errors.Is(err, io.EOF) {
-				break
-			}
-			if err != nil {
-				return nil, fmt.Errorf("failed to decode the yaml file documents. error: %v", err.Error())
-			}
-			mockYamls = append(mockYamls, doc)
-		}
-		mocks, err := decodeMocks(mockYamls, ys.Logger)
+		mocks, err := ys.loadMocks(ctx, path, mockName, func(e indexEntry) bool {
+			return unfilteredKinds[e.Kind] || e.Type == "config"
+		})
 		if err != nil {
 			utils.LogError(ys.Logger, err, "failed to decode the config mocks from yaml docs", zap.Any("session", filepath.Base(path)))
 			return nil, err
 		}
-		for _, mock := range mocks {
-			isUnFilteredMock := false
-			switch mock.Kind {
-			case "Generic":
-				isUnFilteredMock = true
-			case "Postgres":
-				isUnFilteredMock = true
-			case "Http":
-				isUnFilteredMock = true
-			case "Redis":
-				isUnFilteredMock = true
-			case "MySQL":
-				isUnFilteredMock = true
-			}
-			if mock.Spec.Metadata["type"] == "config" || isUnFilteredMock {
-				configMocks = append(configMocks, mock)
-			}
-		}
+		configMocks = append(configMocks, mocks...)
 	}
 
 	filteredMocks, unfilteredMocks := ys.filterByTimeStamp(ctx, configMocks, afterTime, beforeTime, ys.Logger)
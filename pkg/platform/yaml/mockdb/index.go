@@ -0,0 +1,370 @@
+package mockdb
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/pkg/platform/yaml"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+	yamlLib "gopkg.in/yaml.v3"
+)
+
+// indexEntry locates a mock document in the mocks file and keeps the fields needed to select the mocks
+// without decoding them.
+type indexEntry struct {
+	Name     string      `json:"name"`
+	Kind     models.Kind `json:"kind"`
+	Type     string      `json:"type,omitempty"`
+	Key      string      `json:"key,omitempty"`
+	Offset   int64       `json:"offset"`
+	Length   int64       `json:"length"`
+	ReqTime  time.Time   `json:"reqTime,omitempty"`
+	RespTime time.Time   `json:"respTime,omitempty"`
+}
+
+// mockIndex is the index of a mocks file, along with the state of the file it was loaded for.
+type mockIndex struct {
+	entries []indexEntry
+	size    int64
+	modTime time.Time
+}
+
+var errStaleIndex = errors.New("the mock index is out of date")
+
+// unfilteredKinds are the kinds which are always returned as unfiltered mocks.
+var unfilteredKinds = map[models.Kind]bool{
+	models.GENERIC:   true,
+	models.Postgres:  true,
+	models.HTTP:      true,
+	models.REDIS:     true,
+	models.MySQL:     true,
+	models.Kafka:     true,
+	models.MQTT:      true,
+	models.LDAP:      true,
+	models.Thrift:    true,
+	models.MSSQL:     true,
+	models.MySQLX:    true,
+	models.WebSocket: true,
+	models.ZeroMQ:    true,
+	models.AMQP1:     true,
+	models.OpenWire:  true,
+	models.DNS:       true,
+}
+
+func indexPath(path, mockFileName string) string {
+	return filepath.Join(path, "."+mockFileName+".index")
+}
+
+// newIndexEntry builds the index entry of a mock written at the given offset of the mocks file.
+func newIndexEntry(mock *models.Mock, offset, length int64) indexEntry {
+	entry := indexEntry{
+		Name:     mock.Name,
+		Kind:     mock.Kind,
+		Type:     mock.Spec.Metadata["type"],
+		Offset:   offset,
+		Length:   length,
+		ReqTime:  mock.Spec.ReqTimestampMock,
+		RespTime: mock.Spec.ResTimestampMock,
+	}
+	switch {
+	case mock.Spec.HTTPReq != nil:
+		entry.Key = string(mock.Spec.HTTPReq.Method) + " " + mock.Spec.HTTPReq.URL
+	case mock.Spec.GRPCReq != nil:
+		entry.Key = mock.Spec.GRPCReq.Headers.PseudoHeaders[":path"]
+	}
+	return entry
+}
+
+// writeMock appends the mock to the mocks file and records it in the index. The index is only kept up
+// to date if it covers the whole file, otherwise it gets rebuilt on the next read.
+func (ys *MockYaml) writeMock(ctx context.Context, path, mockFileName string, mock *models.Mock) error {
+	mock, err := storeBlobs(blobDir(path, mockFileName), mock)
+	if err != nil {
+		return err
+	}
+	mockYaml, err := EncodeMock(mock, ys.Logger)
+	if err != nil {
+		return err
+	}
+	data, err := yamlLib.Marshal(&mockYaml)
+	if err != nil {
+		return err
+	}
+
+	ys.mu.Lock()
+	defer ys.mu.Unlock()
+
+	yamlPath := filepath.Join(path, mockFileName+".yaml")
+	idxPath := indexPath(path, mockFileName)
+	indexed := true
+	if info, err := os.Stat(yamlPath); err == nil && info.Size() > 0 {
+		if _, err := os.Stat(idxPath); err != nil {
+			indexed = false
+		}
+	}
+
+	err = yaml.WriteFile(ctx, ys.Logger, path, mockFileName, data, true)
+	if err != nil {
+		return err
+	}
+	if !indexed {
+		return nil
+	}
+
+	info, err := os.Stat(yamlPath)
+	if err != nil {
+		return err
+	}
+	entry := newIndexEntry(mock, info.Size()-int64(len(data)), int64(len(data)))
+	if err := appendIndexEntry(idxPath, entry); err != nil {
+		ys.Logger.Debug("failed to update the mock index, it will be rebuilt on the next read", zap.String("path", idxPath), zap.Error(err))
+		// a partial index would silently hide the mocks, drop it instead
+		_ = os.Remove(idxPath)
+	}
+	return nil
+}
+
+func appendIndexEntry(idxPath string, entry indexEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(idxPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0777)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(append(line, '\n'))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// getIndex returns the index of the mocks file, it is read from the index file when that is up to date and
+// rebuilt from the mocks file otherwise. The index is cached until the mocks file changes.
+func (ys *MockYaml) getIndex(ctx context.Context, path, mockFileName string) (*mockIndex, error) {
+	yamlPath := filepath.Join(path, mockFileName+".yaml")
+	info, err := os.Stat(yamlPath)
+	if err != nil {
+		return nil, err
+	}
+
+	ys.mu.Lock()
+	defer ys.mu.Unlock()
+
+	if idx, ok := ys.indexes[yamlPath]; ok && idx.size == info.Size() && idx.modTime.Equal(info.ModTime()) {
+		return idx, nil
+	}
+
+	idxPath := indexPath(path, mockFileName)
+	entries, err := readIndex(idxPath, info)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			ys.Logger.Debug("rebuilding the mock index", zap.String("path", idxPath), zap.Error(err))
+		}
+		entries, err = ys.buildIndex(ctx, path, mockFileName)
+		if err != nil {
+			return nil, err
+		}
+		if err := writeIndex(idxPath, entries); err != nil {
+			ys.Logger.Debug("failed to write the mock index", zap.String("path", idxPath), zap.Error(err))
+			_ = os.Remove(idxPath)
+		}
+	}
+
+	idx := &mockIndex{entries: entries, size: info.Size(), modTime: info.ModTime()}
+	ys.indexes[yamlPath] = idx
+	return idx, nil
+}
+
+// readIndex reads the index file, it fails with errStaleIndex if the index doesn't cover the mocks file as is.
+func readIndex(idxPath string, yamlInfo os.FileInfo) ([]indexEntry, error) {
+	file, err := os.Open(idxPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.ModTime().Before(yamlInfo.ModTime()) {
+		return nil, errStaleIndex
+	}
+
+	var entries []indexEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry indexEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("failed to decode the mock index: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		if yamlInfo.Size() == 0 {
+			return entries, nil
+		}
+		return nil, errStaleIndex
+	}
+	last := entries[len(entries)-1]
+	if last.Offset+last.Length != yamlInfo.Size() {
+		return nil, errStaleIndex
+	}
+	return entries, nil
+}
+
+func writeIndex(idxPath string, entries []indexEntry) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, entry := range entries {
+		if err := enc.Encode(entry); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(idxPath, buf.Bytes(), 0777)
+}
+
+// buildIndex scans the mocks file for the documents and decodes them once to index them.
+func (ys *MockYaml) buildIndex(ctx context.Context, path, mockFileName string) ([]indexEntry, error) {
+	data, err := yaml.ReadFile(ctx, ys.Logger, path, mockFileName)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []indexEntry
+	for _, span := range splitDocuments(data) {
+		// the whole file is decoded to index it, its documents aren't cached since only the lazily loaded ones are read again
+		doc, err := yaml.DecodeDoc(data[span[0]:span[1]])
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode the yaml file documents. error: %v", err.Error())
+		}
+		if doc == nil {
+			continue
+		}
+		offset, length := int64(span[0]), int64(span[1]-span[0])
+		mocks, err := decodeMocks([]*yaml.NetworkTrafficDoc{doc}, ys.Logger)
+		if err != nil {
+			return nil, err
+		}
+		if len(mocks) == 0 {
+			// the mock is skipped while decoding anyway, only its kind matters
+			entries = append(entries, indexEntry{Name: doc.Name, Kind: doc.Kind, Offset: offset, Length: length})
+			continue
+		}
+		entries = append(entries, newIndexEntry(mocks[0], offset, length))
+	}
+	return entries, nil
+}
+
+// splitDocuments returns the [start, end) spans of the yaml documents in data. The documents are separated
+// by "---" lines, which can't occur inside a document written by keploy since the nested values are indented.
+func splitDocuments(data []byte) [][2]int {
+	var spans [][2]int
+	start := 0
+	for pos := 0; pos < len(data); {
+		end := bytes.IndexByte(data[pos:], '\n')
+		next := len(data)
+		if end >= 0 {
+			next = pos + end + 1
+		}
+		line := bytes.TrimRight(data[pos:next], "\r\n")
+		if bytes.Equal(line, []byte("---")) {
+			if pos > start {
+				spans = append(spans, [2]int{start, pos})
+			}
+			start = next
+		}
+		pos = next
+	}
+	if start < len(data) {
+		spans = append(spans, [2]int{start, len(data)})
+	}
+	return spans
+}
+
+func decodeDocument(data []byte) (*yaml.NetworkTrafficDoc, error) {
+	doc, err := yaml.ParseDoc(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode the yaml file documents. error: %v", err.Error())
+	}
+	return doc, nil
+}
+
+// loadMocks decodes the mocks of the entries accepted by keep, reading only their documents from the mocks file.
+func (ys *MockYaml) loadMocks(ctx context.Context, path, mockFileName string, keep func(indexEntry) bool) ([]*models.Mock, error) {
+	idx, err := ys.getIndex(ctx, path, mockFileName)
+	if err != nil {
+		return nil, err
+	}
+
+	yamlPath := filepath.Join(path, mockFileName+".yaml")
+	file, err := os.Open(yamlPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the file: %v", err)
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			utils.LogError(ys.Logger, err, "failed to close file", zap.String("file", yamlPath))
+		}
+	}()
+
+	var docs []*yaml.NetworkTrafficDoc
+	var buf []byte
+	for _, entry := range idx.entries {
+		if !keep(entry) {
+			continue
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if int64(cap(buf)) < entry.Length {
+			buf = make([]byte, entry.Length)
+		}
+		buf = buf[:entry.Length]
+		if _, err := file.ReadAt(buf, entry.Offset); err != nil {
+			return nil, fmt.Errorf("failed to read the mock %s: %v", entry.Name, err)
+		}
+		doc, err := decodeDocument(buf)
+		if err != nil {
+			return nil, err
+		}
+		if doc != nil {
+			docs = append(docs, doc)
+		}
+	}
+	mocks, err := decodeMocks(docs, ys.Logger)
+	if err != nil {
+		return nil, err
+	}
+	if err := loadBlobs(blobDir(path, mockFileName), mocks); err != nil {
+		return nil, err
+	}
+	return mocks, nil
+}
+
+// inWindow reports whether the mock of the entry can be in the given time window, the mocks without
+// timestamps are always kept, as in filterByTimeStamp.
+func (e indexEntry) inWindow(afterTime, beforeTime time.Time) bool {
+	if afterTime.IsZero() || beforeTime.IsZero() {
+		return true
+	}
+	if e.ReqTime.IsZero() || e.RespTime.IsZero() {
+		return true
+	}
+	return e.ReqTime.After(afterTime) && e.RespTime.Before(beforeTime)
+}
@@ -103,6 +103,154 @@ func EncodeMock(mock *models.Mock, logger *zap.Logger) (*yaml.NetworkTrafficDoc,
 			utils.LogError(logger, err, "failed to marshal the redis input-output as yaml")
 			return nil, err
 		}
+	case models.Kafka:
+		kafkaSpec := models.KafkaSchema{
+			Metadata:         mock.Spec.Metadata,
+			Requests:         mock.Spec.KafkaRequests,
+			Responses:        mock.Spec.KafkaResponses,
+			ReqTimestampMock: mock.Spec.ReqTimestampMock,
+			ResTimestampMock: mock.Spec.ResTimestampMock,
+		}
+		err := yamlDoc.Spec.Encode(kafkaSpec)
+		if err != nil {
+			utils.LogError(logger, err, "failed to marshal the kafka input-output as yaml")
+			return nil, err
+		}
+	case models.MQTT:
+		mqttSpec := models.MQTTSchema{
+			Metadata:         mock.Spec.Metadata,
+			Requests:         mock.Spec.MQTTRequests,
+			Responses:        mock.Spec.MQTTResponses,
+			ReqTimestampMock: mock.Spec.ReqTimestampMock,
+			ResTimestampMock: mock.Spec.ResTimestampMock,
+		}
+		err := yamlDoc.Spec.Encode(mqttSpec)
+		if err != nil {
+			utils.LogError(logger, err, "failed to marshal the mqtt input-output as yaml")
+			return nil, err
+		}
+	case models.LDAP:
+		ldapSpec := models.LDAPSchema{
+			Metadata:         mock.Spec.Metadata,
+			Requests:         mock.Spec.LDAPRequests,
+			Responses:        mock.Spec.LDAPResponses,
+			ReqTimestampMock: mock.Spec.ReqTimestampMock,
+			ResTimestampMock: mock.Spec.ResTimestampMock,
+		}
+		err := yamlDoc.Spec.Encode(ldapSpec)
+		if err != nil {
+			utils.LogError(logger, err, "failed to marshal the ldap input-output as yaml")
+			return nil, err
+		}
+	case models.Thrift:
+		thriftSpec := models.ThriftSchema{
+			Metadata:         mock.Spec.Metadata,
+			Requests:         mock.Spec.ThriftRequests,
+			Responses:        mock.Spec.ThriftResponses,
+			ReqTimestampMock: mock.Spec.ReqTimestampMock,
+			ResTimestampMock: mock.Spec.ResTimestampMock,
+		}
+		err := yamlDoc.Spec.Encode(thriftSpec)
+		if err != nil {
+			utils.LogError(logger, err, "failed to marshal the thrift input-output as yaml")
+			return nil, err
+		}
+	case models.MSSQL:
+		mssqlSpec := models.MSSQLSchema{
+			Metadata:         mock.Spec.Metadata,
+			Requests:         mock.Spec.MSSQLRequests,
+			Responses:        mock.Spec.MSSQLResponses,
+			ReqTimestampMock: mock.Spec.ReqTimestampMock,
+			ResTimestampMock: mock.Spec.ResTimestampMock,
+		}
+		err := yamlDoc.Spec.Encode(mssqlSpec)
+		if err != nil {
+			utils.LogError(logger, err, "failed to marshal the mssql input-output as yaml")
+			return nil, err
+		}
+	case models.MySQLX:
+		mysqlxSpec := models.MySQLXSchema{
+			Metadata:         mock.Spec.Metadata,
+			Requests:         mock.Spec.MySQLXRequests,
+			Responses:        mock.Spec.MySQLXResponses,
+			ReqTimestampMock: mock.Spec.ReqTimestampMock,
+			ResTimestampMock: mock.Spec.ResTimestampMock,
+		}
+		err := yamlDoc.Spec.Encode(mysqlxSpec)
+		if err != nil {
+			utils.LogError(logger, err, "failed to marshal the mysqlx input-output as yaml")
+			return nil, err
+		}
+	case models.WebSocket:
+		wsSpec := models.WebSocketSchema{
+			Metadata:         mock.Spec.Metadata,
+			Request:          *mock.Spec.HTTPReq,
+			Response:         *mock.Spec.HTTPResp,
+			Frames:           mock.Spec.WebSocketFrames,
+			ReqTimestampMock: mock.Spec.ReqTimestampMock,
+			ResTimestampMock: mock.Spec.ResTimestampMock,
+		}
+		err := yamlDoc.Spec.Encode(wsSpec)
+		if err != nil {
+			utils.LogError(logger, err, "failed to marshal the websocket input-output as yaml")
+			return nil, err
+		}
+	case models.ZeroMQ:
+		zeromqSpec := models.ZeroMQSchema{
+			Metadata:         mock.Spec.Metadata,
+			Requests:         mock.Spec.ZeroMQRequests,
+			Responses:        mock.Spec.ZeroMQResponses,
+			ReqTimestampMock: mock.Spec.ReqTimestampMock,
+			ResTimestampMock: mock.Spec.ResTimestampMock,
+		}
+		err := yamlDoc.Spec.Encode(zeromqSpec)
+		if err != nil {
+			utils.LogError(logger, err, "failed to marshal the zeromq input-output as yaml")
+			return nil, err
+		}
+	case models.AMQP1:
+		amqpSpec := models.AMQP1Schema{
+			Metadata:         mock.Spec.Metadata,
+			Requests:         mock.Spec.AMQP1Requests,
+			Responses:        mock.Spec.AMQP1Responses,
+			ReqTimestampMock: mock.Spec.ReqTimestampMock,
+			ResTimestampMock: mock.Spec.ResTimestampMock,
+		}
+		err := yamlDoc.Spec.Encode(amqpSpec)
+		if err != nil {
+			utils.LogError(logger, err, "failed to marshal the amqp input-output as yaml")
+			return nil, err
+		}
+	case models.OpenWire:
+		openwireSpec := models.OpenWireSchema{
+			Metadata:         mock.Spec.Metadata,
+			Requests:         mock.Spec.OpenWireRequests,
+			Responses:        mock.Spec.OpenWireResponses,
+			ReqTimestampMock: mock.Spec.ReqTimestampMock,
+			ResTimestampMock: mock.Spec.ResTimestampMock,
+		}
+		err := yamlDoc.Spec.Encode(openwireSpec)
+		if err != nil {
+			utils.LogError(logger, err, "failed to marshal the openwire input-output as yaml")
+			return nil, err
+		}
+	case models.DNS:
+		dnsSpec := models.DNSSchema{
+			Metadata:         mock.Spec.Metadata,
+			ReqTimestampMock: mock.Spec.ReqTimestampMock,
+			ResTimestampMock: mock.Spec.ResTimestampMock,
+		}
+		if mock.Spec.DNSReq != nil {
+			dnsSpec.Request = *mock.Spec.DNSReq
+		}
+		if mock.Spec.DNSResp != nil {
+			dnsSpec.Response = *mock.Spec.DNSResp
+		}
+		err := yamlDoc.Spec.Encode(dnsSpec)
+		if err != nil {
+			utils.LogError(logger, err, "failed to marshal the dns input-output as yaml")
+			return nil, err
+		}
 	case models.Postgres:
 		// case models.PostgresV2:
 
@@ -267,6 +415,161 @@ func decodeMocks(yamlMocks []*yaml.NetworkTrafficDoc, logger *zap.Logger) ([]*mo
 				ReqTimestampMock: redisSpec.ReqTimestampMock,
 				ResTimestampMock: redisSpec.ResTimestampMock,
 			}
+		case models.Kafka:
+			kafkaSpec := models.KafkaSchema{}
+			err := m.Spec.Decode(&kafkaSpec)
+			if err != nil {
+				utils.LogError(logger, err, "failed to unmarshal a yaml doc into kafka mock", zap.Any("mock name", m.Name))
+				return nil, err
+			}
+			mock.Spec = models.MockSpec{
+				Metadata:         kafkaSpec.Metadata,
+				KafkaRequests:    kafkaSpec.Requests,
+				KafkaResponses:   kafkaSpec.Responses,
+				ReqTimestampMock: kafkaSpec.ReqTimestampMock,
+				ResTimestampMock: kafkaSpec.ResTimestampMock,
+			}
+		case models.MQTT:
+			mqttSpec := models.MQTTSchema{}
+			err := m.Spec.Decode(&mqttSpec)
+			if err != nil {
+				utils.LogError(logger, err, "failed to unmarshal a yaml doc into mqtt mock", zap.Any("mock name", m.Name))
+				return nil, err
+			}
+			mock.Spec = models.MockSpec{
+				Metadata:         mqttSpec.Metadata,
+				MQTTRequests:     mqttSpec.Requests,
+				MQTTResponses:    mqttSpec.Responses,
+				ReqTimestampMock: mqttSpec.ReqTimestampMock,
+				ResTimestampMock: mqttSpec.ResTimestampMock,
+			}
+		case models.LDAP:
+			ldapSpec := models.LDAPSchema{}
+			err := m.Spec.Decode(&ldapSpec)
+			if err != nil {
+				utils.LogError(logger, err, "failed to unmarshal a yaml doc into ldap mock", zap.Any("mock name", m.Name))
+				return nil, err
+			}
+			mock.Spec = models.MockSpec{
+				Metadata:         ldapSpec.Metadata,
+				LDAPRequests:     ldapSpec.Requests,
+				LDAPResponses:    ldapSpec.Responses,
+				ReqTimestampMock: ldapSpec.ReqTimestampMock,
+				ResTimestampMock: ldapSpec.ResTimestampMock,
+			}
+		case models.Thrift:
+			thriftSpec := models.ThriftSchema{}
+			err := m.Spec.Decode(&thriftSpec)
+			if err != nil {
+				utils.LogError(logger, err, "failed to unmarshal a yaml doc into thrift mock", zap.Any("mock name", m.Name))
+				return nil, err
+			}
+			mock.Spec = models.MockSpec{
+				Metadata:         thriftSpec.Metadata,
+				ThriftRequests:   thriftSpec.Requests,
+				ThriftResponses:  thriftSpec.Responses,
+				ReqTimestampMock: thriftSpec.ReqTimestampMock,
+				ResTimestampMock: thriftSpec.ResTimestampMock,
+			}
+		case models.MSSQL:
+			mssqlSpec := models.MSSQLSchema{}
+			err := m.Spec.Decode(&mssqlSpec)
+			if err != nil {
+				utils.LogError(logger, err, "failed to unmarshal a yaml doc into mssql mock", zap.Any("mock name", m.Name))
+				return nil, err
+			}
+			mock.Spec = models.MockSpec{
+				Metadata:         mssqlSpec.Metadata,
+				MSSQLRequests:    mssqlSpec.Requests,
+				MSSQLResponses:   mssqlSpec.Responses,
+				ReqTimestampMock: mssqlSpec.ReqTimestampMock,
+				ResTimestampMock: mssqlSpec.ResTimestampMock,
+			}
+		case models.MySQLX:
+			mysqlxSpec := models.MySQLXSchema{}
+			err := m.Spec.Decode(&mysqlxSpec)
+			if err != nil {
+				utils.LogError(logger, err, "failed to unmarshal a yaml doc into mysqlx mock", zap.Any("mock name", m.Name))
+				return nil, err
+			}
+			mock.Spec = models.MockSpec{
+				Metadata:         mysqlxSpec.Metadata,
+				MySQLXRequests:   mysqlxSpec.Requests,
+				MySQLXResponses:  mysqlxSpec.Responses,
+				ReqTimestampMock: mysqlxSpec.ReqTimestampMock,
+				ResTimestampMock: mysqlxSpec.ResTimestampMock,
+			}
+		case models.WebSocket:
+			wsSpec := models.WebSocketSchema{}
+			err := m.Spec.Decode(&wsSpec)
+			if err != nil {
+				utils.LogError(logger, err, "failed to unmarshal a yaml doc into websocket mock", zap.Any("mock name", m.Name))
+				return nil, err
+			}
+			mock.Spec = models.MockSpec{
+				Metadata:         wsSpec.Metadata,
+				HTTPReq:          &wsSpec.Request,
+				HTTPResp:         &wsSpec.Response,
+				WebSocketFrames:  wsSpec.Frames,
+				ReqTimestampMock: wsSpec.ReqTimestampMock,
+				ResTimestampMock: wsSpec.ResTimestampMock,
+			}
+		case models.ZeroMQ:
+			zeromqSpec := models.ZeroMQSchema{}
+			err := m.Spec.Decode(&zeromqSpec)
+			if err != nil {
+				utils.LogError(logger, err, "failed to unmarshal a yaml doc into zeromq mock", zap.Any("mock name", m.Name))
+				return nil, err
+			}
+			mock.Spec = models.MockSpec{
+				Metadata:         zeromqSpec.Metadata,
+				ZeroMQRequests:   zeromqSpec.Requests,
+				ZeroMQResponses:  zeromqSpec.Responses,
+				ReqTimestampMock: zeromqSpec.ReqTimestampMock,
+				ResTimestampMock: zeromqSpec.ResTimestampMock,
+			}
+		case models.AMQP1:
+			amqpSpec := models.AMQP1Schema{}
+			err := m.Spec.Decode(&amqpSpec)
+			if err != nil {
+				utils.LogError(logger, err, "failed to unmarshal a yaml doc into amqp mock", zap.Any("mock name", m.Name))
+				return nil, err
+			}
+			mock.Spec = models.MockSpec{
+				Metadata:         amqpSpec.Metadata,
+				AMQP1Requests:    amqpSpec.Requests,
+				AMQP1Responses:   amqpSpec.Responses,
+				ReqTimestampMock: amqpSpec.ReqTimestampMock,
+				ResTimestampMock: amqpSpec.ResTimestampMock,
+			}
+		case models.OpenWire:
+			openwireSpec := models.OpenWireSchema{}
+			err := m.Spec.Decode(&openwireSpec)
+			if err != nil {
+				utils.LogError(logger, err, "failed to unmarshal a yaml doc into openwire mock", zap.Any("mock name", m.Name))
+				return nil, err
+			}
+			mock.Spec = models.MockSpec{
+				Metadata:          openwireSpec.Metadata,
+				OpenWireRequests:  openwireSpec.Requests,
+				OpenWireResponses: openwireSpec.Responses,
+				ReqTimestampMock:  openwireSpec.ReqTimestampMock,
+				ResTimestampMock:  openwireSpec.ResTimestampMock,
+			}
+		case models.DNS:
+			dnsSpec := models.DNSSchema{}
+			err := m.Spec.Decode(&dnsSpec)
+			if err != nil {
+				utils.LogError(logger, err, "failed to unmarshal a yaml doc into dns mock", zap.Any("mock name", m.Name))
+				return nil, err
+			}
+			mock.Spec = models.MockSpec{
+				Metadata:         dnsSpec.Metadata,
+				DNSReq:           &dnsSpec.Request,
+				DNSResp:          &dnsSpec.Response,
+				ReqTimestampMock: dnsSpec.ReqTimestampMock,
+				ResTimestampMock: dnsSpec.ResTimestampMock,
+			}
 
 		case models.Postgres:
 			// case models.PostgresV2:
@@ -339,6 +642,15 @@ func decodeMySQLMessage(_ context.Context, logger *zap.Logger, yamlSpec *mysql.S
 			}
 			req.Message = msg
 
+		case mysql.SSLRequest:
+			msg := &mysql.SSLRequestPacket{}
+			err := v.Message.Decode(msg)
+			if err != nil {
+				utils.LogError(logger, err, "failed to unmarshal yaml document into mysql SSLRequestPacket")
+				return nil, err
+			}
+			req.Message = msg
+
 		case mysql.CachingSha2PasswordToString(mysql.RequestPublicKey):
 			var msg string
 			err := v.Message.Decode(&msg)
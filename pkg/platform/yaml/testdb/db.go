@@ -19,12 +19,15 @@ import (
 
 type TestYaml struct {
 	TcsPath string
-	logger  *zap.Logger
+	// Layout is the layout the new test cases are written in, LayoutFlat or LayoutEndpoint
+	Layout string
+	logger *zap.Logger
 }
 
-func New(logger *zap.Logger, tcsPath string) *TestYaml {
+func New(logger *zap.Logger, tcsPath, layout string) *TestYaml {
 	return &TestYaml{
 		TcsPath: tcsPath,
+		Layout:  layout,
 		logger:  logger,
 	}
 }
@@ -61,14 +64,37 @@ func (ts *TestYaml) GetTestCases(ctx context.Context, testSetID string) ([]*mode
 		ts.logger.Debug("no tests are recorded for the session", zap.String("index", testSetID))
 		return nil, nil
 	}
-	dir, err := yaml.ReadDir(TestPath, fs.ModePerm)
+	for _, dir := range testDirs(TestPath) {
+		dirTcs, err := ts.readTestCases(ctx, dir)
+		if err != nil {
+			return nil, err
+		}
+		tcs = append(tcs, dirTcs...)
+	}
+	for _, tc := range tcs {
+		if err := yaml.LoadFormFiles(filepath.Join(ts.TcsPath, testSetID), tc.HTTPReq.Form); err != nil {
+			utils.LogError(ts.logger, err, "failed to read the files of the multipart form of the testcase", zap.String("testcase", tc.Name))
+			return nil, err
+		}
+	}
+	sort.SliceStable(tcs, func(i, j int) bool {
+		return tcs[i].HTTPReq.Timestamp.Before(tcs[j].HTTPReq.Timestamp)
+	})
+	return tcs, nil
+}
+
+// readTestCases reads the test cases of a directory of the tests directory.
+func (ts *TestYaml) readTestCases(ctx context.Context, path string) ([]*models.TestCase, error) {
+	tcs := []*models.TestCase{}
+	dir, err := yaml.ReadDir(path, fs.ModePerm)
 	if err != nil {
-		utils.LogError(ts.logger, err, "failed to open the directory containing yaml testcases", zap.Any("path", TestPath))
+		utils.LogError(ts.logger, err, "failed to open the directory containing yaml testcases", zap.Any("path", path))
 		return nil, err
 	}
+	defer dir.Close()
 	files, err := dir.ReadDir(0)
 	if err != nil {
-		utils.LogError(ts.logger, err, "failed to read the file names of yaml testcases", zap.Any("path", TestPath))
+		utils.LogError(ts.logger, err, "failed to read the file names of yaml testcases", zap.Any("path", path))
 		return nil, err
 	}
 	for _, j := range files {
@@ -77,17 +103,20 @@ func (ts *TestYaml) GetTestCases(ctx context.Context, testSetID string) ([]*mode
 		}
 
 		name := strings.TrimSuffix(j.Name(), filepath.Ext(j.Name()))
-		data, err := yaml.ReadFile(ctx, ts.logger, TestPath, name)
+		data, err := yaml.ReadFile(ctx, ts.logger, path, name)
 		if err != nil {
 			utils.LogError(ts.logger, err, "failed to read the testcase from yaml")
 			return nil, err
 		}
-		var testCase *yaml.NetworkTrafficDoc
-		err = yamlLib.Unmarshal(data, &testCase)
+		testCase, err := yaml.ParseDoc(data)
 		if err != nil {
 			utils.LogError(ts.logger, err, "failed to unmarshall YAML data")
 			return nil, err
 		}
+		if testCase == nil {
+			ts.logger.Debug("skipping the empty testcase file", zap.String("file", j.Name()))
+			continue
+		}
 
 		tc, err := Decode(testCase, ts.logger)
 		if err != nil {
@@ -96,9 +125,6 @@ func (ts *TestYaml) GetTestCases(ctx context.Context, testSetID string) ([]*mode
 		}
 		tcs = append(tcs, tc)
 	}
-	sort.SliceStable(tcs, func(i, j int) bool {
-		return tcs[i].HTTPReq.Timestamp.Before(tcs[j].HTTPReq.Timestamp)
-	})
 	return tcs, nil
 }
 
@@ -117,7 +143,7 @@ func (ts *TestYaml) upsert(ctx context.Context, testSetID string, tc *models.Tes
 	tcsPath := filepath.Join(ts.TcsPath, testSetID, "tests")
 	var tcsName string
 	if tc.Name == "" {
-		lastIndx, err := yaml.FindLastIndex(tcsPath, ts.logger)
+		lastIndx, err := findLastIndex(tcsPath, ts.logger)
 		if err != nil {
 			return tcsInfo{name: "", path: tcsPath}, err
 		}
@@ -125,28 +151,74 @@ func (ts *TestYaml) upsert(ctx context.Context, testSetID string, tc *models.Tes
 	} else {
 		tcsName = tc.Name
 	}
-	yamlTc, err := EncodeTestcase(*tc, ts.logger)
+	tcsPath = ts.testCaseDir(tcsPath, tcsName, tc)
+	err := ts.write(ctx, testSetID, tcsPath, tcsName, tc)
+	return tcsInfo{name: tcsName, path: tcsPath}, err
+}
+
+// InsertTestCases inserts a batch of test cases. The unnamed test cases are numbered after the last test case
+// of the test set, which is looked up once for the whole batch.
+func (ts *TestYaml) InsertTestCases(ctx context.Context, tcs []*models.TestCase, testSetID string) (int, error) {
+	tcsPath := filepath.Join(ts.TcsPath, testSetID, "tests")
+	nextIndx := -1
+	for i, tc := range tcs {
+		tcsName := tc.Name
+		if tcsName == "" {
+			if nextIndx < 0 {
+				lastIndx, err := findLastIndex(tcsPath, ts.logger)
+				if err != nil {
+					return i, err
+				}
+				nextIndx = lastIndx
+			}
+			tcsName = fmt.Sprintf("test-%v", nextIndx)
+			nextIndx++
+		}
+		dir := ts.testCaseDir(tcsPath, tcsName, tc)
+		if err := ts.write(ctx, testSetID, dir, tcsName, tc); err != nil {
+			return i, err
+		}
+		ts.logger.Info("🟠 Keploy has captured test cases for the user's application.", zap.String("path", dir), zap.String("testcase name", tcsName))
+	}
+	return len(tcs), nil
+}
+
+func (ts *TestYaml) write(ctx context.Context, testSetID, tcsPath, tcsName string, tc *models.TestCase) error {
+	stored := *tc
+	// the files uploaded in the multipart forms are stored in the blob files of the test set
+	if len(tc.HTTPReq.Form) > 0 {
+		form, err := yaml.StoreFormFiles(filepath.Join(ts.TcsPath, testSetID, blobDir), tc.HTTPReq.Form)
+		if err != nil {
+			utils.LogError(ts.logger, err, "failed to write the files of the multipart form of the testcase")
+			return err
+		}
+		stored.HTTPReq.Form = form
+	}
+	yamlTc, err := EncodeTestcase(stored, ts.logger)
 	if err != nil {
-		return tcsInfo{name: tcsName, path: tcsPath}, err
+		return err
 	}
 	yamlTc.Name = tcsName
 	data, err := yamlLib.Marshal(&yamlTc)
 	if err != nil {
-		return tcsInfo{name: tcsName, path: tcsPath}, err
+		return err
 	}
 	err = yaml.WriteFile(ctx, ts.logger, tcsPath, tcsName, data, false)
 	if err != nil {
 		utils.LogError(ts.logger, err, "failed to write testcase yaml file")
-		return tcsInfo{name: tcsName, path: tcsPath}, err
+		return err
 	}
-
-	return tcsInfo{name: tcsName, path: tcsPath}, nil
+	return nil
 }
 
 func (ts *TestYaml) DeleteTests(ctx context.Context, testSetID string, testCaseIDs []string) error {
 	path := filepath.Join(ts.TcsPath, testSetID, "tests")
 	for _, testCaseID := range testCaseIDs {
-		err := yaml.DeleteFile(ctx, ts.logger, path, testCaseID)
+		dir := path
+		if found, ok := locate(path, testCaseID); ok {
+			dir = found
+		}
+		err := yaml.DeleteFile(ctx, ts.logger, dir, testCaseID)
 		if err != nil {
 			ts.logger.Error("failed to delete the testcase", zap.String("testcase id", testCaseID), zap.String("testset id", testSetID))
 			return err
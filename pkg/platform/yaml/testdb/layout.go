@@ -0,0 +1,110 @@
+package testdb
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/pkg/platform/yaml"
+	"go.uber.org/zap"
+)
+
+// The layouts of the test cases in the tests directory of a test set. The test cases are read from both
+// layouts whichever is configured, so that a test set can be switched from one to the other.
+const (
+	// LayoutFlat keeps the test cases as one numbered list
+	LayoutFlat = "flat"
+	// LayoutEndpoint groups the test cases into a subdirectory per endpoint, named after its method and path
+	LayoutEndpoint = "endpoint"
+)
+
+// blobDir is the directory of the test set the files uploaded in the multipart forms of the test cases are stored in,
+// next to the tests directory.
+const blobDir = "tests-blobs"
+
+// maxEndpointDirLen keeps the endpoint directory names within the file name limits of the file systems.
+const maxEndpointDirLen = 128
+
+var unsafeDirChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// endpointDir returns the name of the directory of the endpoint of the test case, e.g. GET_api_users for
+// a GET request to /api/users.
+func endpointDir(tc *models.TestCase) string {
+	path := "/"
+	if u, err := url.Parse(tc.HTTPReq.URL); err == nil && u.Path != "" {
+		path = u.Path
+	}
+	name := strings.Trim(unsafeDirChars.ReplaceAllString(strings.ReplaceAll(path, "/", "_"), "-"), "_-.")
+	if name == "" {
+		name = "root"
+	}
+	method := strings.ToUpper(string(tc.HTTPReq.Method))
+	switch tc.Kind {
+	case models.GRPC_EXPORT:
+		method = "GRPC"
+	case models.WebSocket:
+		method = "WS"
+	}
+	if method == "" {
+		method = "ANY"
+	}
+	dir := method + "_" + name
+	if len(dir) > maxEndpointDirLen {
+		dir = dir[:maxEndpointDirLen]
+	}
+	return dir
+}
+
+// testDirs returns the tests directory and its endpoint subdirectories.
+func testDirs(tcsPath string) []string {
+	dirs := []string{tcsPath}
+	entries, err := os.ReadDir(tcsPath)
+	if err != nil {
+		return dirs
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			dirs = append(dirs, filepath.Join(tcsPath, entry.Name()))
+		}
+	}
+	return dirs
+}
+
+// findLastIndex returns the index after the last test case of the tests directory, the test cases are
+// numbered across the endpoints so that their names stay unique in the test set.
+func findLastIndex(tcsPath string, logger *zap.Logger) (int, error) {
+	lastIndx := 1
+	for _, dir := range testDirs(tcsPath) {
+		indx, err := yaml.FindLastIndex(dir, logger)
+		if err != nil {
+			return 0, err
+		}
+		lastIndx = max(lastIndx, indx)
+	}
+	return lastIndx, nil
+}
+
+// locate returns the directory of the test case file with the given name, if it exists in either layout.
+func locate(tcsPath, name string) (string, bool) {
+	for _, dir := range testDirs(tcsPath) {
+		if _, err := os.Stat(filepath.Join(dir, name+".yaml")); err == nil {
+			return dir, true
+		}
+	}
+	return "", false
+}
+
+// testCaseDir returns the directory the test case is written to. An existing test case is updated where it
+// is, the new ones are placed as per the layout.
+func (ts *TestYaml) testCaseDir(tcsPath, name string, tc *models.TestCase) string {
+	if dir, ok := locate(tcsPath, name); ok {
+		return dir
+	}
+	if ts.Layout == LayoutEndpoint {
+		return filepath.Join(tcsPath, endpointDir(tc))
+	}
+	return tcsPath
+}
@@ -0,0 +1,331 @@
+// Package redact removes the secrets from the recorded test cases and mocks.
+package redact
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.keploy.io/server/v2/config"
+	"go.keploy.io/server/v2/pkg"
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+// Detectors are the built-in patterns of the well known secrets, by name
+var Detectors = map[string]*regexp.Regexp{
+	"jwt":            regexp.MustCompile(`eyJ[A-Za-z0-9_-]{2,}\.eyJ[A-Za-z0-9_-]{2,}\.[A-Za-z0-9_-]{2,}`),
+	"bearer-token":   regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9._~+/-]{8,}=*`),
+	"aws-access-key": regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`),
+	"github-token":   regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}\b`),
+	"slack-token":    regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}\b`),
+	"stripe-key":     regexp.MustCompile(`\b[rs]k_(?:live|test)_[A-Za-z0-9]{16,}\b`),
+	"google-api-key": regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`),
+	"private-key":    regexp.MustCompile(`-----BEGIN (?:[A-Z]+ )*PRIVATE KEY-----`),
+}
+
+// Finding is a secret found by the redactor.
+type Finding struct {
+	Location string // e.g. req.header.Authorization or resp.body
+	Rule     string // name of the rule which matched
+}
+
+type rule struct {
+	name string
+	re   *regexp.Regexp
+}
+
+// Redactor applies the redaction rules of the config.
+type Redactor struct {
+	headers     map[string]bool
+	jsonPaths   []jsonPath
+	rules       []rule
+	replacement string
+}
+
+// New compiles the redaction rules of the config.
+func New(cfg config.Redact) (*Redactor, error) {
+	r := &Redactor{
+		headers:     make(map[string]bool, len(cfg.Headers)),
+		replacement: cfg.Replacement,
+	}
+	for _, h := range cfg.Headers {
+		r.headers[strings.ToLower(h)] = true
+	}
+	for _, p := range cfg.JSONPaths {
+		path, err := parseJSONPath(p)
+		if err != nil {
+			return nil, err
+		}
+		r.jsonPaths = append(r.jsonPaths, path)
+	}
+	for _, p := range cfg.Patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %q: %w", p, err)
+		}
+		r.rules = append(r.rules, rule{name: "pattern " + p, re: re})
+	}
+	for _, d := range cfg.Detectors {
+		re, ok := Detectors[d]
+		if !ok {
+			return nil, fmt.Errorf("unknown secret detector %q, must be one of %s", d, strings.Join(DetectorNames(), ", "))
+		}
+		r.rules = append(r.rules, rule{name: d, re: re})
+	}
+	return r, nil
+}
+
+// NewScanner returns a redactor which uses the rules of the config along with all the built-in detectors.
+func NewScanner(cfg config.Redact) (*Redactor, error) {
+	cfg.Detectors = DetectorNames()
+	return New(cfg)
+}
+
+// DetectorNames returns the sorted names of the built-in detectors.
+func DetectorNames() []string {
+	names := make([]string, 0, len(Detectors))
+	for name := range Detectors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Enabled reports whether any rule is configured.
+func (r *Redactor) Enabled() bool {
+	return len(r.headers) > 0 || len(r.jsonPaths) > 0 || len(r.rules) > 0
+}
+
+// visitor is called for every value containing a secret, it returns the value to store.
+type visitor func(location, rule, value string) string
+
+// TestCase redacts the secrets of the test case in place and reports whether anything was redacted.
+func (r *Redactor) TestCase(tc *models.TestCase) bool {
+	redacted := false
+	r.testCase(tc, func(_, _, _ string) string {
+		redacted = true
+		return r.replacement
+	})
+	if redacted && tc.Curl != "" {
+		tc.Curl = pkg.MakeCurlCommand(string(tc.HTTPReq.Method), tc.HTTPReq.URL, tc.HTTPReq.Header, tc.HTTPReq.Body)
+	}
+	return redacted
+}
+
+// Mock redacts the secrets of the mock in place and reports whether anything was redacted.
+func (r *Redactor) Mock(mock *models.Mock) bool {
+	redacted := false
+	r.mock(mock, func(_, _, _ string) string {
+		redacted = true
+		return r.replacement
+	})
+	return redacted
+}
+
+// ScanTestCase returns the secrets present in the test case without modifying it.
+func (r *Redactor) ScanTestCase(tc *models.TestCase) []Finding {
+	var findings []Finding
+	r.testCase(tc, func(location, rule, value string) string {
+		findings = append(findings, Finding{Location: location, Rule: rule})
+		return value
+	})
+	return findings
+}
+
+// ScanMock returns the secrets present in the mock without modifying it. The payloads of the
+// protocols other than http are scanned as a whole.
+func (r *Redactor) ScanMock(mock *models.Mock) []Finding {
+	var findings []Finding
+	visit := func(location, rule, value string) string {
+		findings = append(findings, Finding{Location: location, Rule: rule})
+		return value
+	}
+	if mock.Kind == models.HTTP {
+		r.mock(mock, visit)
+		return findings
+	}
+	spec, err := json.Marshal(mock.Spec)
+	if err == nil {
+		r.text("spec", string(spec), visit)
+	}
+	return findings
+}
+
+func (r *Redactor) testCase(tc *models.TestCase, visit visitor) {
+	r.httpReq("req", &tc.HTTPReq, visit)
+	r.httpResp("resp", &tc.HTTPResp, visit)
+}
+
+func (r *Redactor) mock(mock *models.Mock, visit visitor) {
+	if mock.Spec.HTTPReq != nil {
+		r.httpReq("req", mock.Spec.HTTPReq, visit)
+	}
+	if mock.Spec.HTTPResp != nil {
+		r.httpResp("resp", mock.Spec.HTTPResp, visit)
+	}
+	r.payloads("requests", mock.Spec.GenericRequests, visit)
+	r.payloads("responses", mock.Spec.GenericResponses, visit)
+	r.payloads("requests", mock.Spec.RedisRequests, visit)
+	r.payloads("responses", mock.Spec.RedisResponses, visit)
+}
+
+func (r *Redactor) httpReq(prefix string, req *models.HTTPReq, visit visitor) {
+	req.URL = r.text(prefix+".url", req.URL, visit)
+	for k, v := range req.URLParams {
+		req.URLParams[k] = r.text(prefix+".url_params."+k, v, visit)
+	}
+	r.header(prefix+".header", req.Header, visit)
+	req.Body = r.body(prefix+".body", req.Body, visit)
+}
+
+func (r *Redactor) httpResp(prefix string, resp *models.HTTPResp, visit visitor) {
+	r.header(prefix+".header", resp.Header, visit)
+	resp.Body = r.body(prefix+".body", resp.Body, visit)
+}
+
+func (r *Redactor) header(prefix string, header map[string]string, visit visitor) {
+	for k, v := range header {
+		location := prefix + "." + k
+		if r.headers[strings.ToLower(k)] {
+			if v != "" && v != r.replacement {
+				header[k] = visit(location, "header "+k, v)
+			}
+			continue
+		}
+		header[k] = r.text(location, v, visit)
+	}
+}
+
+// payloads redacts the text messages of the generic payloads, the binary ones are left as is.
+func (r *Redactor) payloads(prefix string, payloads []models.Payload, visit visitor) {
+	for i := range payloads {
+		for j := range payloads[i].Message {
+			msg := &payloads[i].Message[j]
+			if msg.Type == "utf-8" {
+				msg.Data = r.text(fmt.Sprintf("%s[%d].message[%d]", prefix, i, j), msg.Data, visit)
+			}
+		}
+	}
+}
+
+func (r *Redactor) body(location, body string, visit visitor) string {
+	if len(r.jsonPaths) > 0 && body != "" {
+		var doc interface{}
+		dec := json.NewDecoder(strings.NewReader(body))
+		dec.UseNumber()
+		if err := dec.Decode(&doc); err == nil {
+			changed := false
+			for _, path := range r.jsonPaths {
+				doc = path.apply(doc, func(value interface{}) interface{} {
+					if s, ok := value.(string); ok && s == r.replacement {
+						return value
+					}
+					s := fmt.Sprint(value)
+					res := visit(location+path.raw, "jsonPath "+path.raw, s)
+					if res == s {
+						return value
+					}
+					changed = true
+					return res
+				})
+			}
+			if changed {
+				var buf bytes.Buffer
+				enc := json.NewEncoder(&buf)
+				enc.SetEscapeHTML(false)
+				if err := enc.Encode(doc); err == nil {
+					body = strings.TrimSuffix(buf.String(), "\n")
+				}
+			}
+		}
+	}
+	return r.text(location, body, visit)
+}
+
+// text redacts every match of the patterns and the detectors in the value.
+func (r *Redactor) text(location, value string, visit visitor) string {
+	for _, rl := range r.rules {
+		value = rl.re.ReplaceAllStringFunc(value, func(match string) string {
+			return visit(location, rl.name, match)
+		})
+	}
+	return value
+}
+
+// jsonPath is a simple json path made of field names, array indexes and wildcards, e.g. $.items[*].token
+type jsonPath struct {
+	raw      string
+	segments []string // field name, array index or "*"
+}
+
+func parseJSONPath(p string) (jsonPath, error) {
+	path := jsonPath{raw: strings.TrimPrefix(p, "$")}
+	rest := path.raw
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end == -1 {
+				end = len(rest)
+			}
+			if end == 0 {
+				return jsonPath{}, fmt.Errorf("invalid json path %q: empty field name", p)
+			}
+			path.segments = append(path.segments, rest[:end])
+			rest = rest[end:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end == -1 {
+				return jsonPath{}, fmt.Errorf("invalid json path %q: missing ]", p)
+			}
+			seg := strings.Trim(rest[1:end], `'"`)
+			if seg == "" {
+				return jsonPath{}, fmt.Errorf("invalid json path %q: empty index", p)
+			}
+			path.segments = append(path.segments, seg)
+			rest = rest[end+1:]
+		default:
+			return jsonPath{}, fmt.Errorf("invalid json path %q: must start with $", p)
+		}
+	}
+	if len(path.segments) == 0 {
+		return jsonPath{}, fmt.Errorf("invalid json path %q: no field selected", p)
+	}
+	return path, nil
+}
+
+// apply replaces the values selected by the path with the result of fn.
+func (p jsonPath) apply(doc interface{}, fn func(interface{}) interface{}) interface{} {
+	return applySegments(doc, p.segments, fn)
+}
+
+func applySegments(node interface{}, segments []string, fn func(interface{}) interface{}) interface{} {
+	if len(segments) == 0 {
+		return fn(node)
+	}
+	seg, rest := segments[0], segments[1:]
+	switch n := node.(type) {
+	case map[string]interface{}:
+		if seg == "*" {
+			for k, v := range n {
+				n[k] = applySegments(v, rest, fn)
+			}
+		} else if v, ok := n[seg]; ok {
+			n[seg] = applySegments(v, rest, fn)
+		}
+	case []interface{}:
+		if seg == "*" {
+			for i, v := range n {
+				n[i] = applySegments(v, rest, fn)
+			}
+		} else if i, err := strconv.Atoi(seg); err == nil && i >= 0 && i < len(n) {
+			n[i] = applySegments(n[i], rest, fn)
+		}
+	}
+	return node
+}
@@ -0,0 +1,319 @@
+// Package dedup provides the detection of the duplicate test cases across the recorded test sets.
+package dedup
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"facette.io/natsort"
+	"github.com/olekukonko/tablewriter"
+	"go.keploy.io/server/v2/config"
+	"go.keploy.io/server/v2/pkg"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+// TestCaseRef identifies a test case of a test set.
+type TestCaseRef struct {
+	TestSet string
+	Name    string
+}
+
+func (r TestCaseRef) String() string {
+	return r.TestSet + "/" + r.Name
+}
+
+// Group is a test case kept along with the older test cases found to duplicate it.
+type Group struct {
+	Endpoint   string
+	Kept       TestCaseRef
+	Duplicates []TestCaseRef
+}
+
+type dedup struct {
+	logger *zap.Logger
+	testDB TestDB
+	config *config.Config
+}
+
+func New(logger *zap.Logger, testDB TestDB, config *config.Config) Service {
+	return &dedup{
+		logger: logger,
+		testDB: testDB,
+		config: config,
+	}
+}
+
+type entry struct {
+	ref TestCaseRef
+	tc  *models.TestCase
+}
+
+// Dedup groups the test cases of the selected test sets (all of them by default) by their request, and finds
+// the test cases of a group which expect an equivalent response. The newest of them is kept.
+func (d *dedup) Dedup(ctx context.Context) ([]Group, error) {
+	testSets, err := d.testDB.GetAllTestSetIDs(ctx)
+	if err != nil {
+		utils.LogError(d.logger, err, "failed to get the test sets")
+		return nil, err
+	}
+	natsort.Sort(testSets)
+
+	bySignature := map[string][]entry{}
+	var signatures []string
+	for _, testSet := range testSets {
+		if _, ok := d.config.Test.SelectedTests[testSet]; !ok && len(d.config.Test.SelectedTests) != 0 {
+			continue
+		}
+		testCases, err := d.testDB.GetTestCases(ctx, testSet)
+		if err != nil {
+			utils.LogError(d.logger, err, "failed to get the test cases", zap.String("testSet", testSet))
+			return nil, err
+		}
+		for _, tc := range testCases {
+			sig := signature(tc)
+			if _, ok := bySignature[sig]; !ok {
+				signatures = append(signatures, sig)
+			}
+			bySignature[sig] = append(bySignature[sig], entry{ref: TestCaseRef{TestSet: testSet, Name: tc.Name}, tc: tc})
+		}
+	}
+
+	var groups []Group
+	for _, sig := range signatures {
+		entries := bySignature[sig]
+		if len(entries) < 2 {
+			continue
+		}
+		sort.SliceStable(entries, func(i, j int) bool {
+			return recordedAt(entries[i].tc) > recordedAt(entries[j].tc)
+		})
+
+		var kept []entry
+		var sigGroups []Group
+		for _, e := range entries {
+			matched := false
+			for i, k := range kept {
+				if equivalent(k.tc, e.tc) {
+					sigGroups[i].Duplicates = append(sigGroups[i].Duplicates, e.ref)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				kept = append(kept, e)
+				sigGroups = append(sigGroups, Group{Endpoint: pkg.Endpoint(e.tc), Kept: e.ref})
+			}
+		}
+		for _, g := range sigGroups {
+			if len(g.Duplicates) > 0 {
+				groups = append(groups, g)
+			}
+		}
+	}
+
+	if len(groups) == 0 {
+		d.logger.Info("no duplicate test cases found")
+		return groups, nil
+	}
+	d.print(groups)
+
+	if !d.config.Dedup.Remove {
+		d.logger.Info("run with --remove to delete the duplicate test cases")
+		return groups, nil
+	}
+	return groups, d.remove(ctx, groups)
+}
+
+// remove deletes the duplicate test cases of the groups, the mocks of the test sets are left as is.
+func (d *dedup) remove(ctx context.Context, groups []Group) error {
+	byTestSet := map[string][]string{}
+	removed := 0
+	for _, g := range groups {
+		for _, dup := range g.Duplicates {
+			byTestSet[dup.TestSet] = append(byTestSet[dup.TestSet], dup.Name)
+			removed++
+		}
+	}
+	for testSet, names := range byTestSet {
+		if err := d.testDB.DeleteTests(ctx, testSet, names); err != nil {
+			utils.LogError(d.logger, err, "failed to delete the duplicate test cases", zap.String("testSet", testSet))
+			return err
+		}
+	}
+	d.logger.Info("removed the duplicate test cases", zap.Int("count", removed))
+	return nil
+}
+
+func (d *dedup) print(groups []Group) {
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Endpoint", "Kept", "Duplicates"})
+	total := 0
+	for _, g := range groups {
+		dups := make([]string, 0, len(g.Duplicates))
+		for _, dup := range g.Duplicates {
+			dups = append(dups, dup.String())
+		}
+		total += len(dups)
+		table.Append([]string{g.Endpoint, g.Kept.String(), strings.Join(dups, "\n")})
+	}
+	table.SetFooter([]string{"", "Total", strconv.Itoa(total)})
+	table.Render()
+}
+
+// signature identifies the request of the test case: its method, path, query, body and multipart form. The headers
+// are left out since they mostly differ by values like dates and trace ids.
+func signature(tc *models.TestCase) string {
+	if tc.Kind == models.GRPC_EXPORT {
+		return string(tc.Kind) + " " + tc.GrpcReq.Headers.PseudoHeaders[":path"] + "\n" + canonical(tc.GrpcReq.Body.DecodedData)
+	}
+	path, query := tc.HTTPReq.URL, ""
+	if u, err := url.Parse(tc.HTTPReq.URL); err == nil {
+		// Encode sorts the query params by key
+		path, query = u.Path, u.Query().Encode()
+	}
+	body := canonical(tc.HTTPReq.Body)
+	if tc.HTTPReq.Truncated != nil {
+		// the truncated bodies are told apart by the whole body, their prefixes may be shared
+		body = "truncated " + strconv.Itoa(tc.HTTPReq.Truncated.Size) + " " + tc.HTTPReq.Truncated.Digest
+	}
+	sig := string(tc.Kind) + " " + string(tc.HTTPReq.Method) + " " + path + "?" + query + "\n" + body
+	if len(tc.HTTPReq.Form) > 0 {
+		sig += "\n" + form(tc)
+	}
+	if tc.Kind == models.WebSocket {
+		// the websockets are told apart by what their clients sent
+		sig += "\n" + frames(tc, pkg.WsFromClient)
+	}
+	return sig
+}
+
+// form returns the parts of the multipart form of the request sorted by their names, the clients send the parts in
+// any order.
+func form(tc *models.TestCase) string {
+	parts := pkg.FormParts(tc.HTTPReq.Form)
+	names := make([]string, 0, len(parts))
+	for name := range parts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	for _, name := range names {
+		b.WriteString("form " + strconv.Quote(name) + "\n" + parts[name])
+	}
+	return b.String()
+}
+
+// frames returns the websocket frames the peer sent, one per line.
+func frames(tc *models.TestCase, from string) string {
+	var lines []string
+	for _, f := range tc.WebSocketFrames {
+		if f.From == from {
+			lines = append(lines, f.Opcode+" "+canonical(f.Data)+f.Binary)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// canonical returns the json body re-encoded with its keys sorted, the other bodies as is.
+func canonical(body string) string {
+	var v interface{}
+	if json.Unmarshal([]byte(body), &v) != nil {
+		return body
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return body
+	}
+	return string(data)
+}
+
+// equivalent reports whether the test cases expect the same response, the fields marked as noisy in either
+// of them are left out.
+func equivalent(a, b *models.TestCase) bool {
+	if a.Kind == models.GRPC_EXPORT || b.Kind == models.GRPC_EXPORT {
+		return a.Kind == b.Kind && canonical(a.GrpcResp.Body.DecodedData) == canonical(b.GrpcResp.Body.DecodedData)
+	}
+	if a.Kind == models.WebSocket && frames(a, pkg.WsFromServer) != frames(b, pkg.WsFromServer) {
+		return false
+	}
+	if a.HTTPResp.StatusCode != b.HTTPResp.StatusCode {
+		return false
+	}
+	noisy := noiseOf(a, b)
+	if !noisy("header.content-type") && header(a, "Content-Type") != header(b, "Content-Type") {
+		return false
+	}
+	if noisy("body") {
+		return true
+	}
+	if a.HTTPResp.Truncated != nil || b.HTTPResp.Truncated != nil {
+		// the bodies truncated when they were recorded are compared by the size and the digest of the whole body
+		return pkg.WholeBody(a.HTTPResp.Body, a.HTTPResp.Truncated) == pkg.WholeBody(b.HTTPResp.Body, b.HTTPResp.Truncated)
+	}
+	var av, bv interface{}
+	if json.Unmarshal([]byte(a.HTTPResp.Body), &av) != nil || json.Unmarshal([]byte(b.HTTPResp.Body), &bv) != nil {
+		return a.HTTPResp.Body == b.HTTPResp.Body
+	}
+	return reflect.DeepEqual(dropNoise("body", av, noisy), dropNoise("body", bv, noisy))
+}
+
+// dropNoise removes the noisy fields from the decoded json value, the noise keys don't index the arrays so
+// they apply to all the elements.
+func dropNoise(key string, v interface{}, noisy func(string) bool) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(val))
+		for k, child := range val {
+			childKey := key + "." + k
+			if noisy(childKey) {
+				continue
+			}
+			out[k] = dropNoise(childKey, child, noisy)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, child := range val {
+			out[i] = dropNoise(key, child, noisy)
+		}
+		return out
+	}
+	return v
+}
+
+func noiseOf(tcs ...*models.TestCase) func(string) bool {
+	noise := map[string]bool{}
+	for _, tc := range tcs {
+		for k := range tc.Noise {
+			noise[strings.ToLower(k)] = true
+		}
+	}
+	return func(key string) bool {
+		return noise[strings.ToLower(key)]
+	}
+}
+
+func header(tc *models.TestCase, name string) string {
+	for k, v := range tc.HTTPResp.Header {
+		if strings.EqualFold(k, name) {
+			return v
+		}
+	}
+	return ""
+}
+
+// recordedAt returns when the test case was recorded, as unix nanoseconds.
+func recordedAt(tc *models.TestCase) int64 {
+	if !tc.HTTPReq.Timestamp.IsZero() {
+		return tc.HTTPReq.Timestamp.UnixNano()
+	}
+	return tc.Created * 1e9
+}
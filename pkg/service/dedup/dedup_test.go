@@ -0,0 +1,206 @@
+package dedup
+
+import (
+	"testing"
+
+	"go.keploy.io/server/v2/pkg"
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+func httpTestCase(method, url, body string) *models.TestCase {
+	tc := &models.TestCase{Kind: models.HTTP}
+	tc.HTTPReq.Method = models.Method(method)
+	tc.HTTPReq.URL = url
+	tc.HTTPReq.Body = body
+	tc.HTTPResp.StatusCode = 200
+	tc.HTTPResp.Header = map[string]string{"Content-Type": "application/json"}
+	return tc
+}
+
+func withForm(tc *models.TestCase, form ...models.FormData) *models.TestCase {
+	tc.HTTPReq.Form = form
+	return tc
+}
+
+func withReqTruncated(tc *models.TestCase, body string, size int, digest string) *models.TestCase {
+	tc.HTTPReq.Body = body
+	tc.HTTPReq.Truncated = &models.BodyTruncation{Size: size, Digest: digest}
+	return tc
+}
+
+func TestSignature(t *testing.T) {
+	avatar := func(name, hash string) models.FormData {
+		return models.FormData{Key: "avatar", Files: []models.FormFile{{Name: name, Hash: hash}}}
+	}
+	tests := []struct {
+		name string
+		a, b *models.TestCase
+		same bool
+	}{
+		{
+			name: "query params in any order",
+			a:    httpTestCase("GET", "http://localhost/users?a=1&b=2", ""),
+			b:    httpTestCase("GET", "http://localhost/users?b=2&a=1", ""),
+			same: true,
+		},
+		{
+			name: "json body keys in any order",
+			a:    httpTestCase("POST", "http://localhost/users", `{"name":"a","age":1}`),
+			b:    httpTestCase("POST", "http://localhost/users", `{"age":1,"name":"a"}`),
+			same: true,
+		},
+		{
+			name: "different json bodies",
+			a:    httpTestCase("POST", "http://localhost/users", `{"name":"a"}`),
+			b:    httpTestCase("POST", "http://localhost/users", `{"name":"b"}`),
+		},
+		{
+			name: "different methods",
+			a:    httpTestCase("GET", "http://localhost/users", ""),
+			b:    httpTestCase("DELETE", "http://localhost/users", ""),
+		},
+		{
+			name: "form parts in any order",
+			a: withForm(httpTestCase("POST", "http://localhost/upload", ""),
+				models.FormData{Key: "title", Values: []string{"x"}}, avatar("a.png", "h1")),
+			b: withForm(httpTestCase("POST", "http://localhost/upload", ""),
+				avatar("a.png", "h1"), models.FormData{Key: "title", Values: []string{"x"}}),
+			same: true,
+		},
+		{
+			name: "different form values",
+			a:    withForm(httpTestCase("POST", "http://localhost/upload", ""), models.FormData{Key: "title", Values: []string{"x"}}),
+			b:    withForm(httpTestCase("POST", "http://localhost/upload", ""), models.FormData{Key: "title", Values: []string{"y"}}),
+		},
+		{
+			name: "different file content",
+			a:    withForm(httpTestCase("POST", "http://localhost/upload", ""), avatar("a.png", "h1")),
+			b:    withForm(httpTestCase("POST", "http://localhost/upload", ""), avatar("a.png", "h2")),
+		},
+		{
+			name: "different file names",
+			a:    withForm(httpTestCase("POST", "http://localhost/upload", ""), avatar("a.png", "h1")),
+			b:    withForm(httpTestCase("POST", "http://localhost/upload", ""), avatar("b.png", "h1")),
+		},
+		{
+			name: "file content hashed when loaded without its hash",
+			a:    withForm(httpTestCase("POST", "http://localhost/upload", ""), models.FormData{Key: "f", Files: []models.FormFile{{Name: "a", Content: "abc"}}}),
+			b:    withForm(httpTestCase("POST", "http://localhost/upload", ""), models.FormData{Key: "f", Files: []models.FormFile{{Name: "a", Content: "abd"}}}),
+		},
+		{
+			name: "truncated bodies sharing their prefix",
+			a:    withReqTruncated(httpTestCase("POST", "http://localhost/upload", ""), "abc", 10, "sha256:1"),
+			b:    withReqTruncated(httpTestCase("POST", "http://localhost/upload", ""), "abc", 10, "sha256:2"),
+		},
+		{
+			name: "truncated bodies of the same whole body",
+			a:    withReqTruncated(httpTestCase("POST", "http://localhost/upload", ""), "abc", 10, "sha256:1"),
+			b:    withReqTruncated(httpTestCase("POST", "http://localhost/upload", ""), "abc", 10, "sha256:1"),
+			same: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if same := signature(tt.a) == signature(tt.b); same != tt.same {
+				t.Errorf("signatures equal = %v, want %v\n%s\n%s", same, tt.same, signature(tt.a), signature(tt.b))
+			}
+		})
+	}
+}
+
+func TestEquivalent(t *testing.T) {
+	resp := func(status int, body string, noise ...string) *models.TestCase {
+		tc := httpTestCase("GET", "http://localhost/users", "")
+		tc.HTTPResp.StatusCode = status
+		tc.HTTPResp.Body = body
+		tc.Noise = map[string][]string{}
+		for _, n := range noise {
+			tc.Noise[n] = []string{}
+		}
+		return tc
+	}
+	truncated := func(body, whole string) *models.TestCase {
+		tc := resp(200, body)
+		tc.HTTPResp.Truncated = &models.BodyTruncation{Size: len(whole), Digest: pkg.BodyDigest(whole)}
+		return tc
+	}
+	tests := []struct {
+		name string
+		a, b *models.TestCase
+		want bool
+	}{
+		{
+			name: "same json in any key order",
+			a:    resp(200, `{"id":1,"name":"a"}`),
+			b:    resp(200, `{"name":"a","id":1}`),
+			want: true,
+		},
+		{
+			name: "different status codes",
+			a:    resp(200, `{}`),
+			b:    resp(404, `{}`),
+		},
+		{
+			name: "different json fields",
+			a:    resp(200, `{"id":1}`),
+			b:    resp(200, `{"id":2}`),
+		},
+		{
+			name: "noisy field in either test case",
+			a:    resp(200, `{"id":1,"at":"x"}`, "body.at"),
+			b:    resp(200, `{"id":1,"at":"y"}`),
+			want: true,
+		},
+		{
+			name: "noisy field within arrays",
+			a:    resp(200, `{"items":[{"id":1,"at":"x"}]}`, "body.items.at"),
+			b:    resp(200, `{"items":[{"id":1,"at":"y"}]}`),
+			want: true,
+		},
+		{
+			name: "noisy body",
+			a:    resp(200, `plain a`, "body"),
+			b:    resp(200, `plain b`),
+			want: true,
+		},
+		{
+			name: "noise is case insensitive",
+			a:    resp(200, `{"At":"x"}`, "BODY.AT"),
+			b:    resp(200, `{"At":"y"}`),
+			want: true,
+		},
+		{
+			name: "truncated bodies of the same whole body",
+			a:    truncated("abc", "abcdef"),
+			b:    truncated("abc", "abcdef"),
+			want: true,
+		},
+		{
+			name: "truncated bodies sharing their prefix",
+			a:    truncated("abc", "abcdef"),
+			b:    truncated("abc", "abcxyz"),
+		},
+		{
+			name: "truncated body and the whole body",
+			a:    truncated("abc", "abcdef"),
+			b:    resp(200, "abcdef"),
+			want: true,
+		},
+		{
+			name: "truncated body with a noisy body",
+			a:    truncated("abc", "abcdef"),
+			b:    resp(200, "other", "body"),
+			want: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := equivalent(tt.a, tt.b); got != tt.want {
+				t.Errorf("equivalent() = %v, want %v", got, tt.want)
+			}
+			if got := equivalent(tt.b, tt.a); got != tt.want {
+				t.Errorf("equivalent() of the swapped test cases = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,19 @@
+package dedup
+
+import (
+	"context"
+
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+// Service finds the duplicate test cases across the recorded test sets
+type Service interface {
+	// Dedup reports the duplicate test cases, and removes them when configured, keeping the newest of each group
+	Dedup(ctx context.Context) ([]Group, error)
+}
+
+type TestDB interface {
+	GetAllTestSetIDs(ctx context.Context) ([]string, error)
+	GetTestCases(ctx context.Context, testSetID string) ([]*models.TestCase, error)
+	DeleteTests(ctx context.Context, testSetID string, testCaseIDs []string) error
+}
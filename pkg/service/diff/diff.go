@@ -0,0 +1,518 @@
+// Package diff provides the structural comparison of two recorded test sets.
+package diff
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	"go.keploy.io/server/v2/pkg"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+// Change is how an endpoint or the mocks of an operation changed from the base test set to the other one.
+type Change string
+
+const (
+	Added     Change = "added"
+	Removed   Change = "removed"
+	Changed   Change = "changed"
+	Unchanged Change = "unchanged"
+)
+
+// EndpointDiff compares the test cases of an endpoint in both test sets.
+type EndpointDiff struct {
+	Endpoint string
+	Base     int // number of test cases in the base test set
+	Other    int
+	Change   Change
+	// TestCases are the pairs of test cases whose expected responses differ, the test cases of an endpoint
+	// are paired in the order they were recorded
+	TestCases []TestCaseDiff
+}
+
+// TestCaseDiff lists the differences between the multipart forms and the expected responses of a pair of test cases.
+type TestCaseDiff struct {
+	Base    string
+	Other   string
+	Form    []string // e.g. "changed avatar", the parts of the multipart forms of the requests
+	Status  [2]int   // base and other status codes, set only when they differ
+	Headers []string // e.g. "changed Content-Type"
+	Body    []string // e.g. "changed users[0].name"
+}
+
+// MockDiff compares the number of mocks of an operation in both test sets.
+type MockDiff struct {
+	Kind      models.Kind
+	Operation string // "METHOD /path" of the http mocks, the grpc method or the type of the other mocks
+	Base      int
+	Other     int
+	Change    Change
+}
+
+// Report is the comparison of the other test set against the base one.
+type Report struct {
+	Base      string
+	Other     string
+	Endpoints []EndpointDiff
+	Mocks     []MockDiff
+}
+
+// Identical reports whether no difference was found between the test sets.
+func (r *Report) Identical() bool {
+	for _, e := range r.Endpoints {
+		if e.Change != Unchanged {
+			return false
+		}
+	}
+	for _, m := range r.Mocks {
+		if m.Change != Unchanged {
+			return false
+		}
+	}
+	return true
+}
+
+type differ struct {
+	logger *zap.Logger
+	testDB TestDB
+	mockDB MockDB
+	out    io.Writer
+}
+
+func New(logger *zap.Logger, testDB TestDB, mockDB MockDB) Service {
+	return &differ{
+		logger: logger,
+		testDB: testDB,
+		mockDB: mockDB,
+		out:    os.Stdout,
+	}
+}
+
+func (d *differ) Diff(ctx context.Context, base, other string) (*Report, error) {
+	testSets, err := d.testDB.GetAllTestSetIDs(ctx)
+	if err != nil {
+		utils.LogError(d.logger, err, "failed to get the test sets")
+		return nil, err
+	}
+	for _, testSet := range []string{base, other} {
+		if !slices.Contains(testSets, testSet) {
+			return nil, fmt.Errorf("test set %s not found", testSet)
+		}
+	}
+
+	report := &Report{Base: base, Other: other}
+
+	baseTcs, err := d.testDB.GetTestCases(ctx, base)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the test cases of %s: %w", base, err)
+	}
+	otherTcs, err := d.testDB.GetTestCases(ctx, other)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the test cases of %s: %w", other, err)
+	}
+	report.Endpoints = diffTestCases(baseTcs, otherTcs)
+
+	baseMocks, err := d.mocks(ctx, base)
+	if err != nil {
+		return nil, err
+	}
+	otherMocks, err := d.mocks(ctx, other)
+	if err != nil {
+		return nil, err
+	}
+	report.Mocks = diffMocks(baseMocks, otherMocks)
+
+	if err := d.print(report); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+func (d *differ) mocks(ctx context.Context, testSet string) ([]*models.Mock, error) {
+	mocks, err := pkg.LoadAllMocks(ctx, d.mockDB, testSet)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the mocks of %s: %w", testSet, err)
+	}
+	return mocks, nil
+}
+
+// diffTestCases groups the test cases of both test sets by endpoint and compares the expected responses of
+// the test cases paired within each endpoint.
+func diffTestCases(baseTcs, otherTcs []*models.TestCase) []EndpointDiff {
+	baseByEndpoint, otherByEndpoint := map[string][]*models.TestCase{}, map[string][]*models.TestCase{}
+	for _, tc := range baseTcs {
+		baseByEndpoint[pkg.Endpoint(tc)] = append(baseByEndpoint[pkg.Endpoint(tc)], tc)
+	}
+	for _, tc := range otherTcs {
+		otherByEndpoint[pkg.Endpoint(tc)] = append(otherByEndpoint[pkg.Endpoint(tc)], tc)
+	}
+
+	var diffs []EndpointDiff
+	for _, e := range keys(baseByEndpoint, otherByEndpoint) {
+		b, o := baseByEndpoint[e], otherByEndpoint[e]
+		diff := EndpointDiff{Endpoint: e, Base: len(b), Other: len(o), Change: Unchanged}
+		switch {
+		case len(b) == 0:
+			diff.Change = Added
+		case len(o) == 0:
+			diff.Change = Removed
+		default:
+			for i := 0; i < len(b) && i < len(o); i++ {
+				if tcDiff, ok := diffTestCase(b[i], o[i]); ok {
+					diff.TestCases = append(diff.TestCases, tcDiff)
+				}
+			}
+			if len(b) != len(o) || len(diff.TestCases) > 0 {
+				diff.Change = Changed
+			}
+		}
+		diffs = append(diffs, diff)
+	}
+	return diffs
+}
+
+// diffTestCase compares the multipart forms of the requests and the expected responses of the test cases, the
+// fields marked as noisy in either of them are skipped.
+func diffTestCase(base, other *models.TestCase) (TestCaseDiff, bool) {
+	diff := TestCaseDiff{Base: base.Name, Other: other.Name}
+	noisy := noiseOf(base, other)
+	diff.Form = diffForm(base.HTTPReq.Form, other.HTTPReq.Form)
+
+	if base.HTTPResp.StatusCode != other.HTTPResp.StatusCode {
+		diff.Status = [2]int{base.HTTPResp.StatusCode, other.HTTPResp.StatusCode}
+	}
+
+	headers := map[string]bool{}
+	for k := range base.HTTPResp.Header {
+		headers[k] = true
+	}
+	for k := range other.HTTPResp.Header {
+		headers[k] = true
+	}
+	for _, k := range sortedKeys(headers) {
+		if noisy("header." + k) {
+			continue
+		}
+		bv, inBase := base.HTTPResp.Header[k]
+		ov, inOther := other.HTTPResp.Header[k]
+		switch {
+		case !inBase:
+			diff.Headers = append(diff.Headers, "added "+k)
+		case !inOther:
+			diff.Headers = append(diff.Headers, "removed "+k)
+		case bv != ov:
+			diff.Headers = append(diff.Headers, "changed "+k)
+		}
+	}
+
+	switch {
+	case noisy("body"):
+	case base.HTTPResp.Truncated != nil || other.HTTPResp.Truncated != nil:
+		// the bodies truncated when they were recorded are compared by the size and the digest of the whole body
+		if pkg.WholeBody(base.HTTPResp.Body, base.HTTPResp.Truncated) != pkg.WholeBody(other.HTTPResp.Body, other.HTTPResp.Truncated) {
+			diff.Body = []string{"changed body"}
+		}
+	default:
+		diff.Body = diffBody(base.HTTPResp.Body, other.HTTPResp.Body, noisy)
+	}
+
+	changed := len(diff.Form) > 0 || diff.Status != [2]int{} || len(diff.Headers) > 0 || len(diff.Body) > 0
+	return diff, changed
+}
+
+// diffForm compares the parts of the multipart forms by their names, the files by their names and content.
+func diffForm(base, other []models.FormData) []string {
+	b, o := pkg.FormParts(base), pkg.FormParts(other)
+	names := map[string]bool{}
+	for name := range b {
+		names[name] = true
+	}
+	for name := range o {
+		names[name] = true
+	}
+	var changes []string
+	for _, name := range sortedKeys(names) {
+		bv, inBase := b[name]
+		ov, inOther := o[name]
+		switch {
+		case !inBase:
+			changes = append(changes, "added "+name)
+		case !inOther:
+			changes = append(changes, "removed "+name)
+		case bv != ov:
+			changes = append(changes, "changed "+name)
+		}
+	}
+	return changes
+}
+
+// diffBody compares the json bodies field by field, the other bodies are compared as a whole.
+func diffBody(base, other string, noisy func(string) bool) []string {
+	if base == other {
+		return nil
+	}
+	var b, o interface{}
+	if json.Unmarshal([]byte(base), &b) != nil || json.Unmarshal([]byte(other), &o) != nil {
+		return []string{"changed body"}
+	}
+	var changes []string
+	diffJSON("", b, o, noisy, &changes)
+	return changes
+}
+
+var arrayIndex = regexp.MustCompile(`\[\d+\]`)
+
+// diffJSON appends the paths of the values which differ between the decoded json values, e.g.
+// "changed users[0].name". The noise is matched against the paths without the array indexes, as recorded.
+func diffJSON(path string, base, other interface{}, noisy func(string) bool, changes *[]string) {
+	noiseKey := "body"
+	if path != "" {
+		noiseKey += "." + arrayIndex.ReplaceAllString(path, "")
+	}
+	if noisy(noiseKey) {
+		return
+	}
+	name := path
+	if name == "" {
+		name = "body"
+	}
+
+	switch b := base.(type) {
+	case map[string]interface{}:
+		o, ok := other.(map[string]interface{})
+		if !ok {
+			*changes = append(*changes, "changed "+name)
+			return
+		}
+		fields := map[string]bool{}
+		for k := range b {
+			fields[k] = true
+		}
+		for k := range o {
+			fields[k] = true
+		}
+		for _, k := range sortedKeys(fields) {
+			child := k
+			if path != "" {
+				child = path + "." + k
+			}
+			bv, inBase := b[k]
+			ov, inOther := o[k]
+			switch {
+			case !inBase:
+				if !noisy("body." + arrayIndex.ReplaceAllString(child, "")) {
+					*changes = append(*changes, "added "+child)
+				}
+			case !inOther:
+				if !noisy("body." + arrayIndex.ReplaceAllString(child, "")) {
+					*changes = append(*changes, "removed "+child)
+				}
+			default:
+				diffJSON(child, bv, ov, noisy, changes)
+			}
+		}
+	case []interface{}:
+		o, ok := other.([]interface{})
+		if !ok {
+			*changes = append(*changes, "changed "+name)
+			return
+		}
+		for i := 0; i < len(b) || i < len(o); i++ {
+			child := path + "[" + strconv.Itoa(i) + "]"
+			switch {
+			case i >= len(b):
+				*changes = append(*changes, "added "+child)
+			case i >= len(o):
+				*changes = append(*changes, "removed "+child)
+			default:
+				diffJSON(child, b[i], o[i], noisy, changes)
+			}
+		}
+	default:
+		if !reflect.DeepEqual(base, other) {
+			*changes = append(*changes, "changed "+name)
+		}
+	}
+}
+
+// noiseOf returns the check of the fields marked as noisy in either test case, the noise keys are
+// compared case insensitively like in the matching of the responses.
+func noiseOf(tcs ...*models.TestCase) func(string) bool {
+	noise := map[string]bool{}
+	for _, tc := range tcs {
+		for k := range tc.Noise {
+			noise[strings.ToLower(k)] = true
+		}
+	}
+	return func(key string) bool {
+		return noise[strings.ToLower(key)]
+	}
+}
+
+// diffMocks compares the number of mocks of each operation in both test sets.
+func diffMocks(baseMocks, otherMocks []*models.Mock) []MockDiff {
+	type op struct {
+		kind      models.Kind
+		operation string
+	}
+	counts := map[op][2]int{}
+	for i, mocks := range [][]*models.Mock{baseMocks, otherMocks} {
+		for _, mock := range mocks {
+			key := op{mock.Kind, operation(mock)}
+			c := counts[key]
+			c[i]++
+			counts[key] = c
+		}
+	}
+
+	diffs := make([]MockDiff, 0, len(counts))
+	for key, c := range counts {
+		diff := MockDiff{Kind: key.kind, Operation: key.operation, Base: c[0], Other: c[1], Change: Unchanged}
+		switch {
+		case c[0] == 0:
+			diff.Change = Added
+		case c[1] == 0:
+			diff.Change = Removed
+		case c[0] != c[1]:
+			diff.Change = Changed
+		}
+		diffs = append(diffs, diff)
+	}
+	sort.Slice(diffs, func(i, j int) bool {
+		if diffs[i].Kind != diffs[j].Kind {
+			return diffs[i].Kind < diffs[j].Kind
+		}
+		return diffs[i].Operation < diffs[j].Operation
+	})
+	return diffs
+}
+
+// operation returns what the mock is for, the mocks of the same operation are counted together.
+func operation(mock *models.Mock) string {
+	switch {
+	case mock.Spec.HTTPReq != nil:
+		return string(mock.Spec.HTTPReq.Method) + " " + pkg.URLPath(mock.Spec.HTTPReq.URL)
+	case mock.Spec.GRPCReq != nil:
+		return mock.Spec.GRPCReq.Headers.PseudoHeaders[":path"]
+	case mock.Spec.DNSReq != nil:
+		return mock.Spec.DNSReq.Type + " " + mock.Spec.DNSReq.Name
+	case len(mock.Spec.KafkaRequests) > 0:
+		return mock.Spec.KafkaRequests[0].Header.APIName
+	case len(mock.Spec.MQTTRequests) > 0:
+		return mock.Spec.MQTTRequests[0].Type
+	case len(mock.Spec.MQTTResponses) > 0:
+		return mock.Spec.MQTTResponses[0].Type + " (delivery)"
+	case len(mock.Spec.LDAPRequests) > 0:
+		return mock.Spec.LDAPRequests[0].Operation
+	case len(mock.Spec.ThriftRequests) > 0:
+		return mock.Spec.ThriftRequests[0].Method
+	case len(mock.Spec.MSSQLRequests) > 0:
+		return mock.Spec.MSSQLRequests[0].Type
+	case len(mock.Spec.ZeroMQRequests) > 0:
+		return zeroMQOperation(mock.Spec.ZeroMQRequests[0])
+	case len(mock.Spec.ZeroMQResponses) > 0:
+		return zeroMQOperation(mock.Spec.ZeroMQResponses[0]) + " (delivery)"
+	case len(mock.Spec.AMQP1Requests) > 0:
+		return mock.Spec.AMQP1Requests[0].Performative
+	case len(mock.Spec.AMQP1Responses) > 0:
+		return mock.Spec.AMQP1Responses[0].Performative + " (delivery)"
+	case len(mock.Spec.OpenWireRequests) > 0:
+		return mock.Spec.OpenWireRequests[0].Type
+	case len(mock.Spec.OpenWireResponses) > 0:
+		return mock.Spec.OpenWireResponses[0].Type + " (delivery)"
+	case mock.Spec.Metadata["type"] != "":
+		return mock.Spec.Metadata["type"]
+	}
+	return "-"
+}
+
+func (d *differ) print(report *Report) error {
+	if _, err := fmt.Fprintf(d.out, "Comparing %s (base) with %s\n\n", report.Base, report.Other); err != nil {
+		return err
+	}
+
+	table := tablewriter.NewWriter(d.out)
+	table.SetHeader([]string{"Endpoint", report.Base, report.Other, "Change"})
+	for _, e := range report.Endpoints {
+		table.Append([]string{e.Endpoint, strconv.Itoa(e.Base), strconv.Itoa(e.Other), string(e.Change)})
+	}
+	table.Render()
+
+	for _, e := range report.Endpoints {
+		for _, tc := range e.TestCases {
+			var b strings.Builder
+			fmt.Fprintf(&b, "\n%s: %s -> %s\n", e.Endpoint, tc.Base, tc.Other)
+			for _, f := range tc.Form {
+				fmt.Fprintf(&b, "  form: %s\n", f)
+			}
+			if tc.Status != [2]int{} {
+				fmt.Fprintf(&b, "  status: %d -> %d\n", tc.Status[0], tc.Status[1])
+			}
+			for _, h := range tc.Headers {
+				fmt.Fprintf(&b, "  header: %s\n", h)
+			}
+			for _, c := range tc.Body {
+				fmt.Fprintf(&b, "  body: %s\n", c)
+			}
+			if _, err := io.WriteString(d.out, b.String()); err != nil {
+				return err
+			}
+		}
+	}
+
+	if len(report.Mocks) > 0 {
+		if _, err := fmt.Fprintln(d.out); err != nil {
+			return err
+		}
+		table = tablewriter.NewWriter(d.out)
+		table.SetHeader([]string{"Protocol", "Operation", report.Base, report.Other, "Change"})
+		for _, m := range report.Mocks {
+			table.Append([]string{string(m.Kind), m.Operation, strconv.Itoa(m.Base), strconv.Itoa(m.Other), string(m.Change)})
+		}
+		table.Render()
+	}
+
+	if report.Identical() {
+		d.logger.Info("no differences found between the test sets", zap.String("base", report.Base), zap.String("other", report.Other))
+	}
+	return nil
+}
+
+// zeroMQOperation names a zeromq command by its name, the messages have no name.
+func zeroMQOperation(m models.ZeroMQMessage) string {
+	if m.Command != "" {
+		return m.Command
+	}
+	return "message"
+}
+
+func keys(maps ...map[string][]*models.TestCase) []string {
+	set := map[string]bool{}
+	for _, m := range maps {
+		for k := range m {
+			set[k] = true
+		}
+	}
+	return sortedKeys(set)
+}
+
+func sortedKeys(set map[string]bool) []string {
+	out := make([]string, 0, len(set))
+	for k := range set {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,170 @@
+package diff
+
+import (
+	"reflect"
+	"testing"
+
+	"go.keploy.io/server/v2/pkg"
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+func testCase(status int, body string, noise ...string) *models.TestCase {
+	tc := &models.TestCase{Kind: models.HTTP}
+	tc.HTTPResp.StatusCode = status
+	tc.HTTPResp.Body = body
+	tc.Noise = map[string][]string{}
+	for _, n := range noise {
+		tc.Noise[n] = []string{}
+	}
+	return tc
+}
+
+func withHeader(tc *models.TestCase, key, value string) *models.TestCase {
+	if tc.HTTPResp.Header == nil {
+		tc.HTTPResp.Header = map[string]string{}
+	}
+	tc.HTTPResp.Header[key] = value
+	return tc
+}
+
+func withForm(tc *models.TestCase, form ...models.FormData) *models.TestCase {
+	tc.HTTPReq.Form = form
+	return tc
+}
+
+func truncated(body, whole string) *models.TestCase {
+	tc := testCase(200, body)
+	tc.HTTPResp.Truncated = &models.BodyTruncation{Size: len(whole), Digest: pkg.BodyDigest(whole)}
+	return tc
+}
+
+func TestDiffTestCase(t *testing.T) {
+	file := func(key, name, hash string) models.FormData {
+		return models.FormData{Key: key, Files: []models.FormFile{{Name: name, Hash: hash}}}
+	}
+	tests := []struct {
+		name        string
+		base, other *models.TestCase
+		want        TestCaseDiff
+	}{
+		{
+			name:  "same json in any key order",
+			base:  testCase(200, `{"a":1,"b":2}`),
+			other: testCase(200, `{"b":2,"a":1}`),
+		},
+		{
+			name:  "status codes",
+			base:  testCase(200, `{}`),
+			other: testCase(404, `{}`),
+			want:  TestCaseDiff{Status: [2]int{200, 404}},
+		},
+		{
+			name:  "headers added, removed and changed",
+			base:  withHeader(withHeader(testCase(200, ""), "Content-Type", "application/json"), "Etag", "1"),
+			other: withHeader(withHeader(testCase(200, ""), "Content-Type", "text/plain"), "Location", "/"),
+			want:  TestCaseDiff{Headers: []string{"changed Content-Type", "removed Etag", "added Location"}},
+		},
+		{
+			name:  "noisy header",
+			base:  withHeader(testCase(200, "", "header.Date"), "Date", "Mon"),
+			other: withHeader(testCase(200, ""), "Date", "Tue"),
+		},
+		{
+			name:  "json fields added, removed and changed",
+			base:  testCase(200, `{"users":[{"name":"a"}],"page":1}`),
+			other: testCase(200, `{"users":[{"name":"b"},{"name":"c"}],"next":2}`),
+			want:  TestCaseDiff{Body: []string{"added next", "removed page", "changed users[0].name", "added users[1]"}},
+		},
+		{
+			name:  "noisy field in either test case",
+			base:  testCase(200, `{"a":1,"at":"x"}`),
+			other: testCase(200, `{"a":1,"at":"y"}`, "body.at"),
+		},
+		{
+			name:  "noisy field within arrays",
+			base:  testCase(200, `{"users":[{"id":1,"at":"x"}]}`, "body.users.at"),
+			other: testCase(200, `{"users":[{"id":1,"at":"y"}]}`),
+		},
+		{
+			name:  "form parts added, removed and changed",
+			base:  withForm(testCase(200, ""), models.FormData{Key: "title", Values: []string{"x"}}, file("avatar", "a.png", "h1")),
+			other: withForm(testCase(200, ""), models.FormData{Key: "title", Values: []string{"y"}}, file("doc", "a.pdf", "h2")),
+			want:  TestCaseDiff{Form: []string{"removed avatar", "added doc", "changed title"}},
+		},
+		{
+			name:  "form parts in any order",
+			base:  withForm(testCase(200, ""), models.FormData{Key: "title", Values: []string{"x"}}, file("avatar", "a.png", "h1")),
+			other: withForm(testCase(200, ""), file("avatar", "a.png", "h1"), models.FormData{Key: "title", Values: []string{"x"}}),
+		},
+		{
+			name:  "form file content",
+			base:  withForm(testCase(200, ""), file("avatar", "a.png", "h1")),
+			other: withForm(testCase(200, ""), file("avatar", "a.png", "h2")),
+			want:  TestCaseDiff{Form: []string{"changed avatar"}},
+		},
+		{
+			name:  "plain bodies",
+			base:  testCase(200, "a"),
+			other: testCase(200, "b"),
+			want:  TestCaseDiff{Body: []string{"changed body"}},
+		},
+		{
+			name:  "noisy body",
+			base:  testCase(200, "a", "body"),
+			other: testCase(200, "b"),
+		},
+		{
+			name:  "truncated bodies of the same whole body",
+			base:  truncated("abc", "abcdef"),
+			other: truncated("abc", "abcdef"),
+		},
+		{
+			name:  "truncated bodies sharing their prefix",
+			base:  truncated("abc", "abcdef"),
+			other: truncated("abc", "abcxyz"),
+			want:  TestCaseDiff{Body: []string{"changed body"}},
+		},
+		{
+			name:  "truncated body and the whole body",
+			base:  truncated("abc", "abcdef"),
+			other: testCase(200, "abcdef"),
+		},
+		{
+			name:  "truncated body and another whole body",
+			base:  truncated(`{"a":`, `{"a":1}`),
+			other: testCase(200, `{"a":2}`),
+			want:  TestCaseDiff{Body: []string{"changed body"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, changed := diffTestCase(tt.base, tt.other)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diffTestCase() = %+v, want %+v", got, tt.want)
+			}
+			if want := !reflect.DeepEqual(tt.want, TestCaseDiff{}); changed != want {
+				t.Errorf("diffTestCase() changed = %v, want %v", changed, want)
+			}
+		})
+	}
+}
+
+func TestDiffTestCases(t *testing.T) {
+	get := func(url string) *models.TestCase {
+		tc := testCase(200, "")
+		tc.HTTPReq.Method = models.Method("GET")
+		tc.HTTPReq.URL = url
+		return tc
+	}
+	base := []*models.TestCase{get("http://localhost/users?page=1"), get("http://localhost/users?page=2"), get("http://localhost/orders")}
+	other := []*models.TestCase{get("http://localhost/users"), get("http://localhost/items")}
+
+	got := map[string]Change{}
+	for _, d := range diffTestCases(base, other) {
+		got[d.Endpoint] = d.Change
+	}
+	want := map[string]Change{"GET /users": Changed, "GET /orders": Removed, "GET /items": Added}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("diffTestCases() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,157 @@
+// Package generate provides the generation of the test sets from api descriptions, without recording any traffic.
+// The generated test cases without an expected response are meant to be re-recorded against the application
+// to capture the responses and the mocks.
+package generate
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.keploy.io/server/v2/config"
+	"go.keploy.io/server/v2/pkg"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+// request is a request synthesized from an api description.
+type request struct {
+	name       string // operation the request comes from, only used for logging
+	method     string
+	url        string
+	header     map[string]string
+	body       string
+	statusCode int       // expected status code, if known
+	response   *response // expected response, if known
+}
+
+type response struct {
+	header map[string]string
+	body   string
+}
+
+type generator struct {
+	logger *zap.Logger
+	testDB TestDB
+	config *config.Config
+}
+
+func New(logger *zap.Logger, testDB TestDB, config *config.Config) Service {
+	return &generator{
+		logger: logger,
+		testDB: testDB,
+		config: config,
+	}
+}
+
+// FromOpenAPI generates a new test set holding a test case for every operation of the OpenAPI spec.
+func (g *generator) FromOpenAPI(ctx context.Context) (string, error) {
+	spec, err := loadOpenAPI(g.config.Generate.Spec)
+	if err != nil {
+		utils.LogError(g.logger, err, "failed to load the openapi spec", zap.String("spec", g.config.Generate.Spec))
+		return "", err
+	}
+	baseURL := g.config.Generate.BaseURL
+	if baseURL == "" {
+		baseURL = spec.baseURL()
+		if baseURL == "" {
+			baseURL = defaultBaseURL
+			g.logger.Warn("no absolute server url found in the spec, use --base-url to set the url of the application", zap.String("using", baseURL))
+		}
+	}
+	reqs, err := spec.requests(baseURL)
+	if err != nil {
+		utils.LogError(g.logger, err, "failed to generate the requests from the openapi spec")
+		return "", err
+	}
+	return g.insert(ctx, reqs)
+}
+
+// FromPostman generates a new test set holding a test case for every request of the Postman collection. The requests
+// with a saved example response can be run as is, the other ones are to be re-recorded.
+func (g *generator) FromPostman(ctx context.Context) (string, error) {
+	collection, err := loadPostman(g.config.Generate.Collection, g.config.Generate.Environment)
+	if err != nil {
+		utils.LogError(g.logger, err, "failed to load the postman collection", zap.String("collection", g.config.Generate.Collection))
+		return "", err
+	}
+	reqs, err := collection.requests()
+	if err != nil {
+		utils.LogError(g.logger, err, "failed to generate the requests from the postman collection")
+		return "", err
+	}
+	for _, req := range reqs {
+		if unresolved := postmanVar.FindAllString(req.url+req.body, -1); len(unresolved) > 0 {
+			g.logger.Warn("unresolved variables in the request, pass the postman environment with --env", zap.String("request", req.name), zap.Strings("variables", unresolved))
+		}
+	}
+	return g.insert(ctx, reqs)
+}
+
+// insert writes the requests as the test cases of a new test set.
+func (g *generator) insert(ctx context.Context, reqs []request) (string, error) {
+	if len(reqs) == 0 {
+		return "", fmt.Errorf("no operation found to generate the test cases from")
+	}
+	testSetIDs, err := g.testDB.GetAllTestSetIDs(ctx)
+	if err != nil {
+		utils.LogError(g.logger, err, "failed to get the test sets")
+		return "", err
+	}
+	testSetID := pkg.NextID(testSetIDs, models.TestSetPattern)
+
+	now := time.Now()
+	for _, req := range reqs {
+		statusCode := req.statusCode
+		if statusCode == 0 {
+			statusCode = http.StatusOK
+		}
+		tc := &models.TestCase{
+			Version: models.GetVersion(),
+			Kind:    models.HTTP,
+			Created: now.Unix(),
+			HTTPReq: models.HTTPReq{
+				Method:     models.Method(req.method),
+				ProtoMajor: 1,
+				ProtoMinor: 1,
+				URL:        req.url,
+				Header:     req.header,
+				Body:       req.body,
+				Timestamp:  now,
+			},
+			HTTPResp: models.HTTPResp{
+				StatusCode:    statusCode,
+				StatusMessage: http.StatusText(statusCode),
+				Header:        map[string]string{},
+				ProtoMajor:    1,
+				ProtoMinor:    1,
+				Timestamp:     now,
+			},
+			Noise: map[string][]string{},
+		}
+		if req.response != nil {
+			tc.HTTPResp.Header = req.response.header
+			tc.HTTPResp.Body = req.response.body
+		}
+		if err := g.testDB.InsertTestCase(ctx, tc, testSetID); err != nil {
+			utils.LogError(g.logger, err, "failed to insert the test case", zap.String("operation", req.name))
+			return "", err
+		}
+		g.logger.Debug("generated the test case", zap.String("operation", req.name), zap.String("url", req.url))
+	}
+	withResponse := 0
+	for _, req := range reqs {
+		if req.response != nil {
+			withResponse++
+		}
+	}
+	if withResponse == len(reqs) {
+		g.logger.Info(fmt.Sprintf("generated %d test cases in %s", len(reqs), testSetID))
+		return testSetID, nil
+	}
+	g.logger.Info(fmt.Sprintf("generated %d test cases in %s, %d of them have no expected response, re-record them against the application to capture the responses and the mocks", len(reqs), testSetID, len(reqs)-withResponse),
+		zap.String("e.g.", fmt.Sprintf(`keploy rerecord -c "<app command>" -t %s`, testSetID)))
+	return testSetID, nil
+}
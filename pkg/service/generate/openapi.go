@@ -0,0 +1,548 @@
+package generate
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const defaultBaseURL = "http://localhost:8080"
+
+// maxDepth bounds the nesting of the synthesized bodies.
+const maxDepth = 8
+
+var methods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// openAPI is a loosely typed OpenAPI 3.x (or swagger 2.0) document, kept as a generic tree so that the
+// references and the schema keywords which aren't modeled in models.OpenAPI can be followed.
+type openAPI struct {
+	doc      map[string]interface{}
+	visiting map[string]bool // references being expanded, to stop on the recursive schemas
+}
+
+func loadOpenAPI(path string) (*openAPI, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	// yaml is a superset of json, both formats of the spec are decoded the same way
+	var raw interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse the spec: %w", err)
+	}
+	doc, _ := stringKeys(raw).(map[string]interface{})
+	if _, ok := doc["paths"].(map[string]interface{}); !ok {
+		return nil, fmt.Errorf("%s is not an openapi spec, no paths found", path)
+	}
+	return &openAPI{doc: doc, visiting: map[string]bool{}}, nil
+}
+
+func (o *openAPI) isSwagger() bool {
+	_, ok := o.doc["swagger"]
+	return ok
+}
+
+// baseURL returns the first absolute server url of the spec, with its variables set to their defaults.
+func (o *openAPI) baseURL() string {
+	if o.isSwagger() {
+		host, _ := o.doc["host"].(string)
+		if host == "" {
+			return ""
+		}
+		scheme := "http"
+		if schemes, ok := o.doc["schemes"].([]interface{}); ok && len(schemes) > 0 {
+			scheme = fmt.Sprint(schemes[0])
+		}
+		basePath, _ := o.doc["basePath"].(string)
+		return scheme + "://" + host + strings.TrimSuffix(basePath, "/")
+	}
+	servers, _ := o.doc["servers"].([]interface{})
+	for _, s := range servers {
+		server, ok := s.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		u, _ := server["url"].(string)
+		vars, _ := server["variables"].(map[string]interface{})
+		for name, v := range vars {
+			if v, ok := v.(map[string]interface{}); ok {
+				u = strings.ReplaceAll(u, "{"+name+"}", fmt.Sprint(v["default"]))
+			}
+		}
+		if strings.HasPrefix(u, "http://") || strings.HasPrefix(u, "https://") {
+			return strings.TrimSuffix(u, "/")
+		}
+	}
+	return ""
+}
+
+// requests synthesizes a request for every operation of the spec, in the order of the paths.
+func (o *openAPI) requests(baseURL string) ([]request, error) {
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base url %q: %w", baseURL, err)
+	}
+	// relative servers of the spec are resolved against the base url
+	if !o.isSwagger() && o.baseURL() == "" {
+		if servers, ok := o.doc["servers"].([]interface{}); ok && len(servers) > 0 {
+			if server, ok := servers[0].(map[string]interface{}); ok {
+				if u, ok := server["url"].(string); ok && strings.HasPrefix(u, "/") {
+					base.Path = strings.TrimSuffix(base.Path, "/") + strings.TrimSuffix(u, "/")
+				}
+			}
+		}
+	}
+
+	paths := o.doc["paths"].(map[string]interface{})
+	var reqs []request
+	for _, path := range sortedKeys(paths) {
+		item, _ := o.resolve(paths[path])
+		if item == nil {
+			continue
+		}
+		for _, method := range methods {
+			op, ok := item[method].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			params := append(o.list(item["parameters"]), o.list(op["parameters"])...)
+			req := o.request(base, path, strings.ToUpper(method), op, params)
+			reqs = append(reqs, req)
+		}
+	}
+	return reqs, nil
+}
+
+func (o *openAPI) request(base *url.URL, path, method string, op map[string]interface{}, params []map[string]interface{}) request {
+	req := request{
+		name:   method + " " + path,
+		method: method,
+		header: map[string]string{},
+	}
+	if id, ok := op["operationId"].(string); ok && id != "" {
+		req.name = id
+	}
+
+	query := url.Values{}
+	form := url.Values{}
+	var cookies []string
+	var body interface{}
+	hasBody := false
+	for _, p := range params {
+		name, _ := p["name"].(string)
+		in, _ := p["in"].(string)
+		required, _ := p["required"].(bool)
+		switch in {
+		case "path":
+			path = strings.ReplaceAll(path, "{"+name+"}", url.PathEscape(o.paramValues(p)[0]))
+		case "query":
+			if required || p["example"] != nil || p["examples"] != nil {
+				for _, v := range o.paramValues(p) {
+					query.Add(name, v)
+				}
+			}
+		case "header":
+			if required {
+				req.header[name] = strings.Join(o.paramValues(p), ",")
+			}
+		case "cookie":
+			if required {
+				cookies = append(cookies, name+"="+o.paramValues(p)[0])
+			}
+		case "body": // swagger 2.0
+			body, hasBody = o.example(p["schema"], 0), true
+		case "formData": // swagger 2.0
+			if required || p["example"] != nil {
+				for _, v := range o.paramValues(p) {
+					form.Add(name, v)
+				}
+			}
+		}
+	}
+	if len(cookies) > 0 {
+		req.header["Cookie"] = strings.Join(cookies, "; ")
+	}
+
+	u := *base
+	u.Path = strings.TrimSuffix(u.Path, "/") + path
+	u.RawQuery = query.Encode()
+	req.url = u.String()
+
+	if o.isSwagger() {
+		contentType := o.mediaType(op["consumes"], o.doc["consumes"])
+		switch {
+		case hasBody:
+			req.header["Content-Type"] = contentType
+			req.body = encodeBody(contentType, body)
+		case len(form) > 0:
+			req.header["Content-Type"] = "application/x-www-form-urlencoded"
+			req.body = form.Encode()
+		}
+		if accept := o.mediaType(op["produces"], o.doc["produces"]); accept != "" {
+			req.header["Accept"] = accept
+		}
+	} else {
+		if rb, _ := o.resolve(op["requestBody"]); rb != nil {
+			content, _ := rb["content"].(map[string]interface{})
+			if contentType := pickMediaType(content); contentType != "" {
+				media, _ := o.resolve(content[contentType])
+				body = o.mediaExample(media)
+				if body != nil {
+					req.header["Content-Type"] = contentType
+					req.body = encodeBody(contentType, body)
+				}
+			}
+		}
+	}
+
+	responses, _ := op["responses"].(map[string]interface{})
+	for _, code := range sortedKeys(responses) {
+		status, err := strconv.Atoi(code)
+		if err != nil || status < 200 || status >= 300 {
+			continue
+		}
+		req.statusCode = status
+		if resp, _ := o.resolve(responses[code]); resp != nil && !o.isSwagger() {
+			content, _ := resp["content"].(map[string]interface{})
+			if accept := pickMediaType(content); accept != "" {
+				req.header["Accept"] = accept
+			}
+		}
+		break
+	}
+	return req
+}
+
+// resolve follows the local $ref of the node, if any, and returns the referenced object along with the reference.
+func (o *openAPI) resolve(node interface{}) (map[string]interface{}, string) {
+	m, ok := node.(map[string]interface{})
+	if !ok {
+		return nil, ""
+	}
+	ref, ok := m["$ref"].(string)
+	if !ok {
+		return m, ""
+	}
+	if !strings.HasPrefix(ref, "#/") {
+		// only the references local to the spec are supported
+		return nil, ref
+	}
+	var cur interface{} = o.doc
+	for _, part := range strings.Split(ref[2:], "/") {
+		part = strings.ReplaceAll(strings.ReplaceAll(part, "~1", "/"), "~0", "~")
+		next, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil, ref
+		}
+		cur = next[part]
+	}
+	target, _ := o.resolve(cur)
+	return target, ref
+}
+
+func (o *openAPI) list(node interface{}) []map[string]interface{} {
+	items, _ := node.([]interface{})
+	var res []map[string]interface{}
+	for _, item := range items {
+		if m, _ := o.resolve(item); m != nil {
+			res = append(res, m)
+		}
+	}
+	return res
+}
+
+// paramValues returns the example values of the parameter, at least one.
+func (o *openAPI) paramValues(p map[string]interface{}) []string {
+	v, ok := p["example"]
+	if !ok {
+		if examples, ok := p["examples"].(map[string]interface{}); ok && len(examples) > 0 {
+			example, _ := o.resolve(examples[sortedKeys(examples)[0]])
+			v = example["value"]
+		}
+	}
+	if v == nil {
+		schema := p["schema"]
+		if schema == nil {
+			// swagger 2.0 keeps the schema keywords on the parameter itself
+			schema = p
+		}
+		v = o.example(schema, 0)
+	}
+	if items, ok := v.([]interface{}); ok {
+		var res []string
+		for _, item := range items {
+			res = append(res, fmt.Sprint(item))
+		}
+		if len(res) > 0 {
+			return res
+		}
+		return []string{""}
+	}
+	if v == nil {
+		return []string{""}
+	}
+	return []string{fmt.Sprint(v)}
+}
+
+func (o *openAPI) mediaExample(media map[string]interface{}) interface{} {
+	if media == nil {
+		return nil
+	}
+	if v, ok := media["example"]; ok {
+		return v
+	}
+	if examples, ok := media["examples"].(map[string]interface{}); ok && len(examples) > 0 {
+		example, _ := o.resolve(examples[sortedKeys(examples)[0]])
+		if v, ok := example["value"]; ok {
+			return v
+		}
+	}
+	return o.example(media["schema"], 0)
+}
+
+// mediaType returns the preferred media type of the swagger 2.0 consumes/produces lists.
+func (o *openAPI) mediaType(lists ...interface{}) string {
+	for _, l := range lists {
+		items, _ := l.([]interface{})
+		content := map[string]interface{}{}
+		for _, item := range items {
+			content[fmt.Sprint(item)] = nil
+		}
+		if t := pickMediaType(content); t != "" {
+			return t
+		}
+	}
+	return "application/json"
+}
+
+// example synthesizes a value matching the schema, preferring the examples, defaults and enums of the spec.
+func (o *openAPI) example(node interface{}, depth int) interface{} {
+	schema, ref := o.resolve(node)
+	if schema == nil || depth > maxDepth {
+		return nil
+	}
+	if ref != "" {
+		if o.visiting[ref] {
+			return nil
+		}
+		o.visiting[ref] = true
+		defer delete(o.visiting, ref)
+	}
+
+	if v, ok := schema["example"]; ok {
+		return v
+	}
+	if examples, ok := schema["examples"].([]interface{}); ok && len(examples) > 0 {
+		return examples[0]
+	}
+	if v, ok := schema["default"]; ok {
+		return v
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok && len(enum) > 0 {
+		return enum[0]
+	}
+	if v, ok := schema["const"]; ok {
+		return v
+	}
+	if allOf, ok := schema["allOf"].([]interface{}); ok {
+		merged := map[string]interface{}{}
+		for _, s := range allOf {
+			if obj, ok := o.example(s, depth+1).(map[string]interface{}); ok {
+				for k, v := range obj {
+					merged[k] = v
+				}
+			}
+		}
+		return merged
+	}
+	for _, key := range []string{"oneOf", "anyOf"} {
+		if alternatives, ok := schema[key].([]interface{}); ok && len(alternatives) > 0 {
+			return o.example(alternatives[0], depth+1)
+		}
+	}
+
+	switch schemaType(schema) {
+	case "object":
+		obj := map[string]interface{}{}
+		props, _ := schema["properties"].(map[string]interface{})
+		for name, prop := range props {
+			if p, _ := o.resolve(prop); p != nil {
+				if readOnly, _ := p["readOnly"].(bool); readOnly {
+					continue
+				}
+			}
+			if v := o.example(prop, depth+1); v != nil {
+				obj[name] = v
+			}
+		}
+		return obj
+	case "array":
+		arr := []interface{}{}
+		if v := o.example(schema["items"], depth+1); v != nil {
+			arr = append(arr, v)
+		}
+		return arr
+	case "integer":
+		if v, ok := schema["minimum"]; ok {
+			return v
+		}
+		return 1
+	case "number":
+		if v, ok := schema["minimum"]; ok {
+			return v
+		}
+		return 1.5
+	case "boolean":
+		return true
+	case "string":
+		return stringExample(schema)
+	}
+	return nil
+}
+
+func schemaType(schema map[string]interface{}) string {
+	switch t := schema["type"].(type) {
+	case string:
+		return t
+	case []interface{}: // openapi 3.1 allows a list of types
+		for _, v := range t {
+			if s := fmt.Sprint(v); s != "null" {
+				return s
+			}
+		}
+	}
+	if _, ok := schema["properties"]; ok {
+		return "object"
+	}
+	if _, ok := schema["items"]; ok {
+		return "array"
+	}
+	return ""
+}
+
+func stringExample(schema map[string]interface{}) string {
+	format, _ := schema["format"].(string)
+	switch format {
+	case "date-time":
+		return "2024-01-01T00:00:00Z"
+	case "date":
+		return "2024-01-01"
+	case "time":
+		return "00:00:00"
+	case "email":
+		return "user@example.com"
+	case "uuid":
+		return "3fa85f64-5717-4562-b3fc-2c963f66afa6"
+	case "uri", "url":
+		return "https://example.com"
+	case "hostname":
+		return "example.com"
+	case "ipv4":
+		return "127.0.0.1"
+	case "ipv6":
+		return "::1"
+	case "byte":
+		return "ZXhhbXBsZQ=="
+	case "password":
+		return "password"
+	}
+	s := "string"
+	if minLength, ok := schema["minLength"].(int); ok && minLength > len(s) {
+		s += strings.Repeat("x", minLength-len(s))
+	}
+	if maxLength, ok := schema["maxLength"].(int); ok && maxLength < len(s) {
+		s = s[:maxLength]
+	}
+	return s
+}
+
+// pickMediaType prefers json, then the url encoded forms, then the first media type in the order of the names.
+func pickMediaType(content map[string]interface{}) string {
+	if len(content) == 0 {
+		return ""
+	}
+	keys := sortedKeys(content)
+	for _, k := range keys {
+		if k == "application/json" {
+			return k
+		}
+	}
+	for _, k := range keys {
+		if strings.Contains(k, "json") {
+			return k
+		}
+	}
+	for _, k := range keys {
+		if k == "application/x-www-form-urlencoded" {
+			return k
+		}
+	}
+	return keys[0]
+}
+
+// encodeBody encodes the example value according to the content type.
+func encodeBody(contentType string, v interface{}) string {
+	switch {
+	case strings.Contains(contentType, "json"):
+		data, err := json.Marshal(v)
+		if err != nil {
+			return ""
+		}
+		return string(data)
+	case contentType == "application/x-www-form-urlencoded":
+		if obj, ok := v.(map[string]interface{}); ok {
+			form := url.Values{}
+			for _, k := range sortedKeys(obj) {
+				form.Set(k, fmt.Sprint(obj[k]))
+			}
+			return form.Encode()
+		}
+	}
+	if s, ok := v.(string); ok {
+		return s
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+// stringKeys converts the maps with non-string keys (e.g. the unquoted status codes of the responses) into
+// maps keyed by strings.
+func stringKeys(node interface{}) interface{} {
+	switch n := node.(type) {
+	case map[string]interface{}:
+		for k, v := range n {
+			n[k] = stringKeys(v)
+		}
+		return n
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(n))
+		for k, v := range n {
+			m[fmt.Sprint(k)] = stringKeys(v)
+		}
+		return m
+	case []interface{}:
+		for i, v := range n {
+			n[i] = stringKeys(v)
+		}
+		return n
+	}
+	return node
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
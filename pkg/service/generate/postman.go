@@ -0,0 +1,391 @@
+package generate
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// postmanCollection is the subset of the Postman collection v2.x format used to generate the test cases.
+type postmanCollection struct {
+	Info struct {
+		Name string `json:"name"`
+	} `json:"info"`
+	Item     []postmanItem     `json:"item"`
+	Variable []postmanVariable `json:"variable"`
+	Auth     *postmanAuth      `json:"auth"`
+}
+
+// postmanItem is either a folder (with items) or a request.
+type postmanItem struct {
+	Name     string            `json:"name"`
+	Item     []postmanItem     `json:"item"`
+	Request  *postmanRequest   `json:"request"`
+	Response []postmanResponse `json:"response"`
+	Auth     *postmanAuth      `json:"auth"`
+	Variable []postmanVariable `json:"variable"`
+}
+
+type postmanRequest struct {
+	Method string       `json:"method"`
+	Header []postmanKV  `json:"header"`
+	URL    postmanURL   `json:"url"`
+	Body   *postmanBody `json:"body"`
+	Auth   *postmanAuth `json:"auth"`
+}
+
+// UnmarshalJSON handles the requests given as a bare url string.
+func (r *postmanRequest) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		r.Method = http.MethodGet
+		r.URL.Raw = s
+		return nil
+	}
+	type plain postmanRequest
+	return json.Unmarshal(data, (*plain)(r))
+}
+
+type postmanURL struct {
+	Raw   string      `json:"raw"`
+	Query []postmanKV `json:"query"`
+}
+
+// UnmarshalJSON handles the urls given as a bare string.
+func (u *postmanURL) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		u.Raw = s
+		return nil
+	}
+	type plain postmanURL
+	return json.Unmarshal(data, (*plain)(u))
+}
+
+type postmanKV struct {
+	Key      string `json:"key"`
+	Value    string `json:"value"`
+	Type     string `json:"type"`
+	Disabled bool   `json:"disabled"`
+}
+
+type postmanVariable struct {
+	Key     string      `json:"key"`
+	Value   interface{} `json:"value"`
+	Enabled *bool       `json:"enabled"`
+}
+
+type postmanBody struct {
+	Mode       string      `json:"mode"`
+	Raw        string      `json:"raw"`
+	URLEncoded []postmanKV `json:"urlencoded"`
+	FormData   []postmanKV `json:"formdata"`
+	GraphQL    *struct {
+		Query     string `json:"query"`
+		Variables string `json:"variables"`
+	} `json:"graphql"`
+	Options struct {
+		Raw struct {
+			Language string `json:"language"`
+		} `json:"raw"`
+	} `json:"options"`
+}
+
+type postmanAuth struct {
+	Type   string      `json:"type"`
+	Bearer []postmanKV `json:"bearer"`
+	Basic  []postmanKV `json:"basic"`
+	APIKey []postmanKV `json:"apikey"`
+}
+
+type postmanResponse struct {
+	Code   int         `json:"code"`
+	Header []postmanKV `json:"header"`
+	Body   string      `json:"body"`
+}
+
+type postmanEnvironment struct {
+	Values []postmanVariable `json:"values"`
+}
+
+var postmanVar = regexp.MustCompile(`{{\s*([^{}]+?)\s*}}`)
+
+// postman converts a Postman collection into requests, resolving the variables of the environment,
+// of the collection and of the folders, and applying the inherited auth.
+type postman struct {
+	collection postmanCollection
+	env        map[string]string
+}
+
+func loadPostman(collectionPath, envPath string) (*postman, error) {
+	data, err := os.ReadFile(collectionPath)
+	if err != nil {
+		return nil, err
+	}
+	p := &postman{env: map[string]string{}}
+	if err := json.Unmarshal(data, &p.collection); err != nil {
+		return nil, fmt.Errorf("failed to parse the postman collection: %w", err)
+	}
+	if envPath != "" {
+		data, err := os.ReadFile(envPath)
+		if err != nil {
+			return nil, err
+		}
+		var env postmanEnvironment
+		if err := json.Unmarshal(data, &env); err != nil {
+			return nil, fmt.Errorf("failed to parse the postman environment: %w", err)
+		}
+		addVariables(p.env, env.Values)
+	}
+	return p, nil
+}
+
+// addVariables adds the enabled variables to vars, without overriding the ones already set.
+func addVariables(vars map[string]string, variables []postmanVariable) {
+	for _, v := range variables {
+		if v.Enabled != nil && !*v.Enabled {
+			continue
+		}
+		if _, ok := vars[v.Key]; ok || v.Value == nil {
+			continue
+		}
+		vars[v.Key] = fmt.Sprint(v.Value)
+	}
+}
+
+// requests returns a request for every item of the collection, depth first. The environment takes precedence
+// over the variables of the collection, as in postman.
+func (p *postman) requests() ([]request, error) {
+	vars := make(map[string]string, len(p.env))
+	for k, v := range p.env {
+		vars[k] = v
+	}
+	addVariables(vars, p.collection.Variable)
+
+	var reqs []request
+	for _, item := range p.collection.Item {
+		reqs = p.walk(reqs, item, "", vars, p.collection.Auth)
+	}
+	return reqs, nil
+}
+
+func (p *postman) walk(reqs []request, item postmanItem, folder string, parentVars map[string]string, parentAuth *postmanAuth) []request {
+	vars := parentVars
+	if len(item.Variable) > 0 {
+		vars = make(map[string]string, len(parentVars))
+		for k, v := range parentVars {
+			vars[k] = v
+		}
+		// the variables of the folders are scoped to the folder, they don't override the environment
+		addVariables(vars, item.Variable)
+	}
+	auth := parentAuth
+	if item.Auth != nil && item.Auth.Type != "inherit" {
+		auth = item.Auth
+	}
+	name := item.Name
+	if folder != "" {
+		name = folder + "/" + item.Name
+	}
+
+	if item.Request == nil {
+		for _, child := range item.Item {
+			reqs = p.walk(reqs, child, name, vars, auth)
+		}
+		return reqs
+	}
+	if item.Request.Auth != nil && item.Request.Auth.Type != "inherit" {
+		auth = item.Request.Auth
+	}
+	return append(reqs, p.request(name, item, vars, auth))
+}
+
+func (p *postman) request(name string, item postmanItem, vars map[string]string, auth *postmanAuth) request {
+	r := item.Request
+	resolve := func(s string) string { return resolveVariables(s, vars) }
+
+	method := strings.ToUpper(r.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+	req := request{
+		name:   name,
+		method: method,
+		header: map[string]string{},
+	}
+	for _, h := range r.Header {
+		if !h.Disabled {
+			req.header[resolve(h.Key)] = resolve(h.Value)
+		}
+	}
+
+	rawURL := resolve(r.URL.Raw)
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "http://" + rawURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		u = &url.URL{Scheme: "http", Host: "localhost", Path: rawURL}
+	}
+	if len(r.URL.Query) > 0 {
+		// the structured query takes precedence over the raw url, since it knows about the disabled params
+		query := url.Values{}
+		for _, q := range r.URL.Query {
+			if !q.Disabled {
+				query.Add(resolve(q.Key), resolve(q.Value))
+			}
+		}
+		u.RawQuery = query.Encode()
+	}
+
+	if auth != nil {
+		switch auth.Type {
+		case "bearer":
+			req.header["Authorization"] = "Bearer " + resolve(authValue(auth.Bearer, "token"))
+		case "basic":
+			credentials := resolve(authValue(auth.Basic, "username")) + ":" + resolve(authValue(auth.Basic, "password"))
+			req.header["Authorization"] = "Basic " + base64.StdEncoding.EncodeToString([]byte(credentials))
+		case "apikey":
+			key, value := resolve(authValue(auth.APIKey, "key")), resolve(authValue(auth.APIKey, "value"))
+			if authValue(auth.APIKey, "in") == "query" {
+				query := u.Query()
+				query.Set(key, value)
+				u.RawQuery = query.Encode()
+			} else if key != "" {
+				req.header[key] = value
+			}
+		}
+	}
+	req.url = u.String()
+
+	if r.Body != nil {
+		switch r.Body.Mode {
+		case "raw":
+			req.body = resolve(r.Body.Raw)
+			if !hasHeader(req.header, "Content-Type") {
+				switch r.Body.Options.Raw.Language {
+				case "json":
+					req.header["Content-Type"] = "application/json"
+				case "xml":
+					req.header["Content-Type"] = "application/xml"
+				case "html":
+					req.header["Content-Type"] = "text/html"
+				case "javascript":
+					req.header["Content-Type"] = "application/javascript"
+				default:
+					req.header["Content-Type"] = "text/plain"
+				}
+			}
+		case "urlencoded":
+			form := url.Values{}
+			for _, kv := range r.Body.URLEncoded {
+				if !kv.Disabled {
+					form.Add(resolve(kv.Key), resolve(kv.Value))
+				}
+			}
+			req.body = form.Encode()
+			req.header["Content-Type"] = "application/x-www-form-urlencoded"
+		case "formdata":
+			// only the text fields can be replayed, the files aren't part of the collection
+			form := url.Values{}
+			for _, kv := range r.Body.FormData {
+				if !kv.Disabled && kv.Type != "file" {
+					form.Add(resolve(kv.Key), resolve(kv.Value))
+				}
+			}
+			req.body = form.Encode()
+			req.header["Content-Type"] = "application/x-www-form-urlencoded"
+		case "graphql":
+			if r.Body.GraphQL != nil {
+				payload := map[string]interface{}{"query": resolve(r.Body.GraphQL.Query)}
+				if variables := resolve(r.Body.GraphQL.Variables); strings.TrimSpace(variables) != "" {
+					payload["variables"] = json.RawMessage(variables)
+				}
+				if data, err := json.Marshal(payload); err == nil {
+					req.body = string(data)
+				}
+				req.header["Content-Type"] = "application/json"
+			}
+		}
+	}
+
+	// a saved example response makes the test case runnable as is
+	if len(item.Response) > 0 {
+		resp := item.Response[0]
+		req.statusCode = resp.Code
+		req.response = &response{header: map[string]string{}, body: resp.Body}
+		for _, h := range resp.Header {
+			if !h.Disabled {
+				req.response.header[h.Key] = h.Value
+			}
+		}
+	}
+	return req
+}
+
+func authValue(kvs []postmanKV, key string) string {
+	for _, kv := range kvs {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return ""
+}
+
+func hasHeader(header map[string]string, name string) bool {
+	for k := range header {
+		if strings.EqualFold(k, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveVariables replaces the {{variables}} with their values, the dynamic variables of postman
+// (e.g. {{$guid}}) are replaced with fresh values. Unknown variables are left as is.
+func resolveVariables(s string, vars map[string]string) string {
+	// variables may refer to other variables, the depth is bounded to stop on the cycles
+	for i := 0; i < 5 && strings.Contains(s, "{{"); i++ {
+		resolved := postmanVar.ReplaceAllStringFunc(s, func(match string) string {
+			name := postmanVar.FindStringSubmatch(match)[1]
+			if v, ok := vars[name]; ok {
+				return v
+			}
+			if v, ok := dynamicVariable(name); ok {
+				return v
+			}
+			return match
+		})
+		if resolved == s {
+			break
+		}
+		s = resolved
+	}
+	return s
+}
+
+func dynamicVariable(name string) (string, bool) {
+	switch name {
+	case "$guid", "$randomUUID":
+		return uuid.NewString(), true
+	case "$timestamp":
+		return strconv.FormatInt(time.Now().Unix(), 10), true
+	case "$isoTimestamp":
+		return time.Now().UTC().Format(time.RFC3339), true
+	case "$randomInt":
+		return strconv.Itoa(rand.Intn(1001)), true
+	case "$randomBoolean":
+		return strconv.FormatBool(rand.Intn(2) == 1), true
+	}
+	return "", false
+}
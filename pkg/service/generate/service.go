@@ -0,0 +1,18 @@
+package generate
+
+import (
+	"context"
+
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+// Service generates test sets without recording any traffic
+type Service interface {
+	FromOpenAPI(ctx context.Context) (string, error)
+	FromPostman(ctx context.Context) (string, error)
+}
+
+type TestDB interface {
+	GetAllTestSetIDs(ctx context.Context) ([]string, error)
+	InsertTestCase(ctx context.Context, tc *models.TestCase, testSetID string) error
+}
@@ -0,0 +1,62 @@
+// Package inspect provides the pretty-printing of the recorded test cases and mocks.
+package inspect
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"go.keploy.io/server/v2/pkg"
+	"go.uber.org/zap"
+)
+
+type inspector struct {
+	logger *zap.Logger
+	testDB TestDB
+	mockDB MockDB
+	out    io.Writer
+}
+
+func New(logger *zap.Logger, testDB TestDB, mockDB MockDB) Service {
+	return &inspector{
+		logger: logger,
+		testDB: testDB,
+		mockDB: mockDB,
+		out:    os.Stdout,
+	}
+}
+
+func (i *inspector) Inspect(ctx context.Context, target string) error {
+	idx := strings.LastIndex(target, "/")
+	if idx <= 0 || idx == len(target)-1 {
+		return fmt.Errorf("invalid target %q, must be of the form <test-set>/<test-case or mock name> e.g. test-set-1/test-5", target)
+	}
+	testSetID, name := target[:idx], target[idx+1:]
+
+	testCases, err := i.testDB.GetTestCases(ctx, testSetID)
+	if err != nil {
+		return fmt.Errorf("failed to get the test cases of %s: %w", testSetID, err)
+	}
+	for _, tc := range testCases {
+		if tc.Name == name {
+			r := &renderer{w: i.out}
+			r.testCase(tc)
+			return r.err
+		}
+	}
+
+	mocks, err := pkg.LoadAllMocks(ctx, i.mockDB, testSetID)
+	if err != nil {
+		return fmt.Errorf("failed to get the mocks of %s: %w", testSetID, err)
+	}
+	for _, mock := range mocks {
+		if mock.Name == name {
+			r := &renderer{w: i.out}
+			r.mock(mock)
+			return r.err
+		}
+	}
+	return fmt.Errorf("no test case or mock named %s found in %s", name, testSetID)
+}
@@ -0,0 +1,779 @@
+package inspect
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/pkg/models/mysql"
+	"gopkg.in/yaml.v3"
+)
+
+// renderer writes the human readable form of the test cases and mocks. The first write error is kept in err.
+type renderer struct {
+	w      io.Writer
+	indent int
+	err    error
+}
+
+func (r *renderer) line(format string, args ...interface{}) {
+	if r.err != nil {
+		return
+	}
+	text := fmt.Sprintf(format, args...)
+	pad := strings.Repeat("  ", r.indent)
+	for _, l := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		if _, err := fmt.Fprintln(r.w, pad+l); err != nil {
+			r.err = err
+			return
+		}
+	}
+}
+
+func (r *renderer) section(title string, fn func()) {
+	r.line("%s", title)
+	r.indent++
+	fn()
+	r.indent--
+}
+
+func (r *renderer) testCase(tc *models.TestCase) {
+	r.line("Test case %s (%s)", tc.Name, tc.Kind)
+	if tc.Created != 0 {
+		r.line("Recorded at %s", time.Unix(tc.Created, 0).Format(time.RFC3339))
+	}
+	r.line("")
+
+	switch tc.Kind {
+	case models.GRPC_EXPORT:
+		r.section("Request", func() {
+			r.grpcHeaders(tc.GrpcReq.Headers)
+			r.line("")
+			r.text(tc.GrpcReq.Body.DecodedData)
+		})
+		r.line("")
+		r.section("Response", func() {
+			r.grpcHeaders(tc.GrpcResp.Headers)
+			r.line("")
+			r.text(tc.GrpcResp.Body.DecodedData)
+			if len(tc.GrpcResp.Trailers.OrdinaryHeaders) > 0 || len(tc.GrpcResp.Trailers.PseudoHeaders) > 0 {
+				r.line("")
+				r.line("Trailers:")
+				r.grpcHeaders(tc.GrpcResp.Trailers)
+			}
+		})
+	case models.WebSocket:
+		r.section("Handshake", func() { r.httpReq(&tc.HTTPReq) })
+		r.section("Handshake response", func() { r.httpResp(&tc.HTTPResp) })
+		for _, f := range tc.WebSocketFrames {
+			r.webSocketFrame(f)
+		}
+	default:
+		r.section("Request", func() { r.httpReq(&tc.HTTPReq) })
+		r.line("")
+		r.section("Response", func() { r.httpResp(&tc.HTTPResp) })
+	}
+
+	if len(tc.Noise) > 0 {
+		r.line("")
+		r.section("Noise", func() {
+			for _, k := range sortedKeys(tc.Noise) {
+				r.line("%s", k)
+			}
+		})
+	}
+}
+
+func (r *renderer) mock(mock *models.Mock) {
+	r.line("Mock %s (%s)", mock.Name, mock.Kind)
+	if !mock.Spec.ReqTimestampMock.IsZero() {
+		r.line("Recorded at %s, took %s", mock.Spec.ReqTimestampMock.Format(time.RFC3339Nano), mock.Spec.ResTimestampMock.Sub(mock.Spec.ReqTimestampMock))
+	}
+	for _, k := range sortedKeys(mock.Spec.Metadata) {
+		r.line("%s: %s", k, mock.Spec.Metadata[k])
+	}
+	r.line("")
+
+	spec := &mock.Spec
+	switch mock.Kind {
+	case models.HTTP:
+		if spec.HTTPReq != nil {
+			r.section("Request", func() { r.httpReq(spec.HTTPReq) })
+			r.line("")
+		}
+		if spec.HTTPResp != nil {
+			r.section("Response", func() { r.httpResp(spec.HTTPResp) })
+		}
+	case models.GRPC_EXPORT:
+		if spec.GRPCReq != nil {
+			r.section("Request", func() {
+				r.grpcHeaders(spec.GRPCReq.Headers)
+				r.grpcMessages(spec.GRPCReq.Body, spec.GRPCReq.Messages)
+			})
+		}
+		if spec.GRPCResp != nil {
+			r.section("Response", func() {
+				r.grpcHeaders(spec.GRPCResp.Headers)
+				r.grpcMessages(spec.GRPCResp.Body, spec.GRPCResp.Messages)
+			})
+		}
+	case models.Postgres:
+		for i := range spec.PostgresRequests {
+			r.postgresRequest(&spec.PostgresRequests[i])
+		}
+		for i := range spec.PostgresResponses {
+			r.postgresResponse(&spec.PostgresResponses[i])
+		}
+	case models.MySQL:
+		for _, req := range spec.MySQLRequests {
+			r.mysqlPacket("→", req.PacketBundle, "")
+		}
+		for _, resp := range spec.MySQLResponses {
+			r.mysqlPacket("←", resp.PacketBundle, resp.Payload)
+		}
+	case models.Mongo:
+		for _, req := range spec.MongoRequests {
+			r.mongoMessage("→", req.Header, req.Message)
+		}
+		for _, resp := range spec.MongoResponses {
+			r.mongoMessage("←", resp.Header, resp.Message)
+		}
+	case models.REDIS:
+		r.payloads(spec.RedisRequests)
+		r.payloads(spec.RedisResponses)
+	case models.Kafka:
+		for _, req := range spec.KafkaRequests {
+			h := req.Header
+			r.section(fmt.Sprintf("→ %s v%d (correlation %d, client %q)", h.APIName, h.APIVersion, h.CorrelationID, h.ClientID), func() {
+				if len(req.Topics) > 0 {
+					r.line("topics: %s", strings.Join(req.Topics, ", "))
+				}
+				r.base64(req.Body)
+			})
+		}
+		for _, resp := range spec.KafkaResponses {
+			r.section(fmt.Sprintf("← correlation %d", resp.CorrelationID), func() {
+				r.base64(resp.Body)
+			})
+		}
+	case models.MQTT:
+		for _, p := range spec.MQTTRequests {
+			r.mqttPacket("→", p)
+		}
+		for _, p := range spec.MQTTResponses {
+			r.mqttPacket("←", p)
+		}
+	case models.LDAP:
+		for _, m := range spec.LDAPRequests {
+			r.ldapMessage("→", m)
+		}
+		for _, m := range spec.LDAPResponses {
+			r.ldapMessage("←", m)
+		}
+	case models.Thrift:
+		for _, m := range spec.ThriftRequests {
+			r.thriftMessage("→", m)
+		}
+		for _, m := range spec.ThriftResponses {
+			r.thriftMessage("←", m)
+		}
+	case models.MSSQL:
+		for _, m := range spec.MSSQLRequests {
+			r.mssqlMessage("→", m)
+		}
+		for _, m := range spec.MSSQLResponses {
+			r.mssqlMessage("←", m)
+		}
+	case models.MySQLX:
+		for _, m := range spec.MySQLXRequests {
+			r.mysqlxMessage("→", m)
+		}
+		for _, m := range spec.MySQLXResponses {
+			r.mysqlxMessage("←", m)
+		}
+	case models.ZeroMQ:
+		for _, m := range spec.ZeroMQRequests {
+			r.zeroMQMessage("→", m)
+		}
+		for _, m := range spec.ZeroMQResponses {
+			r.zeroMQMessage("←", m)
+		}
+	case models.AMQP1:
+		for _, f := range spec.AMQP1Requests {
+			r.amqp1Frame("→", f)
+		}
+		for _, f := range spec.AMQP1Responses {
+			r.amqp1Frame("←", f)
+		}
+	case models.OpenWire:
+		for _, c := range spec.OpenWireRequests {
+			r.openWireCommand("→", c)
+		}
+		for _, c := range spec.OpenWireResponses {
+			r.openWireCommand("←", c)
+		}
+	case models.DNS:
+		if spec.DNSReq != nil {
+			r.line("→ %s %s %s", spec.DNSReq.Class, spec.DNSReq.Type, spec.DNSReq.Name)
+		}
+		if spec.DNSResp != nil {
+			r.dnsResponse(spec.DNSResp)
+		}
+	case models.WebSocket:
+		if spec.HTTPReq != nil {
+			r.section("Handshake", func() { r.httpReq(spec.HTTPReq) })
+		}
+		if spec.HTTPResp != nil {
+			r.section("Handshake response", func() { r.httpResp(spec.HTTPResp) })
+		}
+		for _, f := range spec.WebSocketFrames {
+			r.webSocketFrame(f)
+		}
+	default:
+		r.payloads(spec.GenericRequests)
+		r.payloads(spec.GenericResponses)
+	}
+}
+
+func (r *renderer) httpReq(req *models.HTTPReq) {
+	r.line("%s %s HTTP/%d.%d", req.Method, req.URL, req.ProtoMajor, req.ProtoMinor)
+	r.headers(req.Header)
+	if req.Body != "" {
+		r.line("")
+		r.body(req.Body, req.Header)
+	}
+	for _, f := range req.Form {
+		if len(f.Values) > 0 || len(f.Files) == 0 {
+			r.line("form %s: %s", f.Key, strings.Join(f.Values, ", "))
+		}
+		for _, file := range f.Files {
+			r.line("form %s: file %s (%s, sha256 %s)", f.Key, file.Name, file.ContentType, file.Hash)
+		}
+	}
+}
+
+func (r *renderer) httpResp(resp *models.HTTPResp) {
+	r.line("HTTP/%d.%d %d %s", resp.ProtoMajor, resp.ProtoMinor, resp.StatusCode, resp.StatusMessage)
+	r.headers(resp.Header)
+	if resp.Body != "" {
+		r.line("")
+		r.body(resp.Body, resp.Header)
+	}
+	for _, e := range resp.Events {
+		r.line("")
+		r.line("event %q at %dms", e.Event, e.Offset)
+		if e.ID != "" {
+			r.line("id: %s", e.ID)
+		}
+		r.text(e.Data)
+	}
+}
+
+func (r *renderer) headers(header map[string]string) {
+	for _, k := range sortedKeys(header) {
+		r.line("%s: %s", k, header[k])
+	}
+}
+
+func (r *renderer) grpcHeaders(h models.GrpcHeaders) {
+	r.headers(h.PseudoHeaders)
+	r.headers(h.OrdinaryHeaders)
+}
+
+// grpcMessages prints the body of a unary call or every message of a streaming one.
+func (r *renderer) grpcMessages(body models.GrpcLengthPrefixedMessage, msgs []models.GrpcLengthPrefixedMessage) {
+	if len(msgs) == 0 {
+		r.text(body.DecodedData)
+		return
+	}
+	for i, msg := range msgs {
+		r.line("message %d:", i+1)
+		r.text(msg.DecodedData)
+	}
+}
+
+// body decompresses the body if needed and prints it as indented json, text or a hex dump.
+func (r *renderer) body(body string, header map[string]string) {
+	data := []byte(body)
+	for k, v := range header {
+		if strings.EqualFold(k, "Content-Encoding") {
+			if d, ok := decompress(data, strings.ToLower(v)); ok {
+				r.line("(decoded from %s)", v)
+				data = d
+			}
+		}
+	}
+	r.bytes(data)
+}
+
+func (r *renderer) text(s string) {
+	r.bytes([]byte(s))
+}
+
+func (r *renderer) bytes(data []byte) {
+	var pretty bytes.Buffer
+	if json.Valid(data) && json.Indent(&pretty, data, "", "  ") == nil {
+		r.line("%s", pretty.String())
+		return
+	}
+	if isPrintable(data) {
+		r.line("%s", string(data))
+		return
+	}
+	r.line("%s", hex.Dump(data))
+}
+
+func (r *renderer) payloads(payloads []models.Payload) {
+	for _, p := range payloads {
+		arrow := "→"
+		if p.Origin == models.FromServer {
+			arrow = "←"
+		}
+		for _, msg := range p.Message {
+			data := []byte(msg.Data)
+			if msg.Type == "binary" {
+				decoded, err := base64.StdEncoding.DecodeString(msg.Data)
+				if err == nil {
+					data = decoded
+				}
+			}
+			if d, ok := decompress(data, ""); ok {
+				data = d
+			}
+			r.section(fmt.Sprintf("%s %d bytes", arrow, len(data)), func() { r.bytes(data) })
+		}
+	}
+}
+
+func (r *renderer) postgresRequest(b *models.Backend) {
+	r.section("→ "+strings.Join(b.PacketTypes, ", "), func() {
+		rendered := false
+		if len(b.StartupMessage.Parameters) > 0 {
+			rendered = true
+			for _, k := range sortedKeys(b.StartupMessage.Parameters) {
+				r.line("startup %s=%s", k, b.StartupMessage.Parameters[k])
+			}
+		}
+		if b.Query.String != "" {
+			rendered = true
+			r.line("query: %s", b.Query.String)
+		}
+		for _, p := range b.Parses {
+			rendered = true
+			r.line("parse %q: %s", p.Name, p.Query)
+		}
+		for _, bind := range b.Binds {
+			rendered = true
+			r.line("bind portal=%q statement=%q params=[%s]", bind.DestinationPortal, bind.PreparedStatement, strings.Join(values(bind.Parameters), ", "))
+		}
+		for _, e := range b.Executes {
+			rendered = true
+			r.line("execute portal=%q maxRows=%d", e.Portal, e.MaxRows)
+		}
+		if !rendered && b.Payload != "" {
+			r.base64(b.Payload)
+		}
+	})
+}
+
+func (r *renderer) postgresResponse(f *models.Frontend) {
+	r.section("← "+strings.Join(f.PacketTypes, ", "), func() {
+		rendered := false
+		if len(f.RowDescription.Fields) > 0 {
+			rendered = true
+			var names []string
+			for _, field := range f.RowDescription.Fields {
+				names = append(names, string(field.Name))
+			}
+			r.line("columns: %s", strings.Join(names, " | "))
+		}
+		for _, row := range f.DataRows {
+			rendered = true
+			r.line("row: %s", strings.Join(values(row.Values), " | "))
+		}
+		for _, c := range f.CommandCompletes {
+			rendered = true
+			r.line("complete: %s", c.CommandTag)
+		}
+		for _, p := range f.ParameterStatusCombined {
+			rendered = true
+			r.line("parameter %s=%s", p.Name, p.Value)
+		}
+		if f.ErrorResponse.Message != "" {
+			rendered = true
+			r.line("error: %s %s %s", f.ErrorResponse.Severity, f.ErrorResponse.Code, f.ErrorResponse.Message)
+		}
+		if f.ReadyForQuery.TxStatus != 0 {
+			rendered = true
+			r.line("ready for query (tx status %c)", f.ReadyForQuery.TxStatus)
+		}
+		if !rendered && f.Payload != "" {
+			r.base64(f.Payload)
+		}
+	})
+}
+
+func (r *renderer) mysqlPacket(arrow string, bundle mysql.PacketBundle, payload string) {
+	title := arrow
+	if bundle.Header != nil {
+		title += " " + bundle.Header.Type
+	}
+	r.section(title, func() {
+		if bundle.Message == nil {
+			if payload != "" {
+				r.base64(payload)
+			}
+			return
+		}
+		r.yaml(bundle.Message)
+	})
+}
+
+func (r *renderer) mongoMessage(arrow string, header *models.MongoHeader, message interface{}) {
+	title := arrow
+	if header != nil {
+		title = fmt.Sprintf("%s %s (request %d, response to %d)", arrow, header.Opcode, header.RequestID, header.ResponseTo)
+	}
+	r.section(title, func() {
+		switch m := message.(type) {
+		case *models.MongoOpMessage:
+			for _, s := range m.Sections {
+				r.line("%s", s)
+			}
+		case *models.MongoOpReply:
+			for _, d := range m.Documents {
+				r.text(d)
+			}
+		case *models.MongoOpQuery:
+			r.line("%s", m.FullCollectionName)
+			r.text(m.Query)
+		default:
+			r.yaml(message)
+		}
+	})
+}
+
+func (r *renderer) mqttPacket(arrow string, p models.MQTTPacket) {
+	title := arrow + " " + p.Type
+	if p.PacketID != 0 {
+		title += fmt.Sprintf(" (packet %d)", p.PacketID)
+	}
+	r.section(title, func() {
+		switch {
+		case p.ClientID != "":
+			r.line("client id: %s, protocol level %d", p.ClientID, p.ProtocolLevel)
+		case p.Topic != "":
+			r.line("topic: %s, qos %d, retain %t", p.Topic, p.QoS, p.Retain)
+		case len(p.Filters) > 0:
+			r.line("filters: %s", strings.Join(p.Filters, ", "))
+		}
+		r.base64(p.Raw)
+	})
+}
+
+func (r *renderer) ldapMessage(arrow string, m models.LDAPMessage) {
+	r.section(fmt.Sprintf("%s %s (message %d)", arrow, m.Operation, m.MessageID), func() {
+		if m.DN != "" {
+			r.line("dn: %s", m.DN)
+		}
+		if m.Scope != "" {
+			r.line("scope: %s", m.Scope)
+		}
+		if m.Filter != "" {
+			r.line("filter: %s", m.Filter)
+		}
+		if len(m.Attributes) > 0 {
+			r.line("attributes: %s", strings.Join(m.Attributes, ", "))
+		}
+		if m.Name != "" {
+			r.line("name: %s", m.Name)
+		}
+		if m.ResultCode != 0 {
+			r.line("result code: %d", m.ResultCode)
+		}
+	})
+}
+
+func (r *renderer) thriftMessage(arrow string, m models.ThriftMessage) {
+	r.section(fmt.Sprintf("%s %s %s (seq %d, %s)", arrow, m.Type, m.Method, m.SeqID, m.Protocol), func() {
+		if m.Body != "" {
+			r.text(m.Body)
+		}
+	})
+}
+
+func (r *renderer) mysqlxMessage(arrow string, m models.MySQLXMessage) {
+	r.section(arrow+" "+m.Type, func() {
+		if m.Mechanism != "" || m.User != "" {
+			r.line("mechanism %s, user %s", m.Mechanism, m.User)
+		}
+		for _, c := range m.Capabilities {
+			r.line("%s", c)
+		}
+		if m.Prepared != "" {
+			r.line("prepared: %s", m.Prepared)
+		}
+		if m.Stmt != "" {
+			r.line("%s", m.Stmt)
+		}
+		if m.Collection != "" {
+			r.line("collection: %s.%s (%s)", m.Schema, m.Collection, m.DataModel)
+		}
+		if len(m.Projection) > 0 {
+			r.line("projection: %s", strings.Join(m.Projection, ", "))
+		}
+		if m.Criteria != "" {
+			r.line("criteria: %s", m.Criteria)
+		}
+		if len(m.Grouping) > 0 {
+			r.line("grouping: %s", strings.Join(m.Grouping, ", "))
+		}
+		if len(m.Order) > 0 {
+			r.line("order: %s", strings.Join(m.Order, ", "))
+		}
+		if m.Limit != "" {
+			r.line("limit: %s", m.Limit)
+		}
+		for _, row := range m.Rows {
+			r.line("row: %s", row)
+		}
+		for _, op := range m.Operations {
+			r.line("%s", op)
+		}
+		if len(m.Args) > 0 {
+			r.line("args: %s", strings.Join(m.Args, ", "))
+		}
+		if m.Column != "" {
+			r.line("column: %s", m.Column)
+		}
+		if m.Code != 0 {
+			r.line("error %d (%s): %s", m.Code, m.SQLState, m.Message)
+		} else if m.Message != "" {
+			r.line("%s", m.Message)
+		}
+		if m.Type == "Resultset.Row" {
+			r.base64(m.Raw)
+		}
+	})
+}
+
+func (r *renderer) mssqlMessage(arrow string, m models.MSSQLMessage) {
+	r.section(arrow+" "+m.Type, func() {
+		if m.User != "" || m.Database != "" {
+			r.line("user %s, database %s, app %s", m.User, m.Database, m.AppName)
+		}
+		if m.Procedure != "" {
+			r.line("procedure: %s", m.Procedure)
+		}
+		if m.Query != "" {
+			r.line("%s", m.Query)
+		}
+		for _, p := range m.Params {
+			r.line("%s", p)
+		}
+		if m.Type == "TabularResult" {
+			r.base64(m.Raw)
+		}
+	})
+}
+
+func (r *renderer) webSocketFrame(f models.WebSocketFrame) {
+	arrow := "→"
+	if f.From == "server" {
+		arrow = "←"
+	}
+	title := fmt.Sprintf("%s %s at %dms", arrow, f.Opcode, f.Offset)
+	if !f.Fin {
+		title += " (fragment)"
+	}
+	r.section(title, func() {
+		if f.Data != "" {
+			r.text(f.Data)
+		}
+		if f.Binary != "" {
+			r.base64(f.Binary)
+		}
+	})
+}
+
+func (r *renderer) dnsResponse(resp *models.DNSResponse) {
+	r.section("← "+resp.Rcode, func() {
+		for _, section := range []struct {
+			name    string
+			records []string
+		}{
+			{"answers", resp.Answers},
+			{"authorities", resp.Authorities},
+			{"additionals", resp.Additionals},
+		} {
+			if len(section.records) == 0 {
+				continue
+			}
+			r.line("%s:", section.name)
+			for _, record := range section.records {
+				r.line("  %s", record)
+			}
+		}
+	})
+}
+
+func (r *renderer) amqp1Frame(arrow string, f models.AMQP1Frame) {
+	title := arrow + " " + f.Performative
+	if f.Link != "" {
+		title += fmt.Sprintf(" (%s %s)", f.Role, f.Link)
+	}
+	r.section(title, func() {
+		if f.Address != "" {
+			r.line("address: %s", f.Address)
+		}
+		if f.Mechanism != "" {
+			r.line("mechanism: %s", f.Mechanism)
+		}
+		if m := f.Message; m != nil {
+			if m.Subject != "" {
+				r.line("subject: %s", m.Subject)
+			}
+			for _, k := range sortedKeys(m.Properties) {
+				r.line("%s: %s", k, m.Properties[k])
+			}
+			if m.Binary {
+				r.base64(m.Body)
+			} else {
+				r.text(m.Body)
+			}
+			return
+		}
+		r.base64(f.Raw)
+	})
+}
+
+func (r *renderer) openWireCommand(arrow string, c models.OpenWireCommand) {
+	title := arrow + " " + c.Type
+	if c.Destination != "" {
+		title += " " + c.Destination
+	}
+	r.section(title, func() {
+		if c.CorrelationID != 0 {
+			r.line("correlation id: %d", c.CorrelationID)
+		}
+		if c.Body == "" {
+			r.base64(c.Raw)
+			return
+		}
+		if c.Binary {
+			r.base64(c.Body)
+		} else {
+			r.text(c.Body)
+		}
+	})
+}
+
+func (r *renderer) zeroMQMessage(arrow string, m models.ZeroMQMessage) {
+	title := arrow + " message"
+	if m.Command != "" {
+		title = arrow + " " + m.Command
+	}
+	r.section(title, func() {
+		for _, k := range sortedKeys(m.Properties) {
+			r.line("%s: %s", k, m.Properties[k])
+		}
+		for i, f := range m.Frames {
+			if len(m.Frames) > 1 {
+				r.line("frame %d:", i+1)
+			}
+			if f.Binary != "" {
+				r.base64(f.Binary)
+			} else {
+				r.text(f.Data)
+			}
+		}
+	})
+}
+
+func (r *renderer) yaml(v interface{}) {
+	data, err := yaml.Marshal(v)
+	if err != nil {
+		r.line("%v", v)
+		return
+	}
+	r.line("%s", string(data))
+}
+
+func (r *renderer) base64(payload string) {
+	data, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		r.line("%s", payload)
+		return
+	}
+	r.line("%s", hex.Dump(data))
+}
+
+// values renders the column values or parameters as text when printable and as hex otherwise.
+func values(vals [][]byte) []string {
+	out := make([]string, 0, len(vals))
+	for _, v := range vals {
+		switch {
+		case v == nil:
+			out = append(out, "NULL")
+		case isPrintable(v):
+			out = append(out, string(v))
+		default:
+			out = append(out, "0x"+hex.EncodeToString(v))
+		}
+	}
+	return out
+}
+
+// decompress decodes gzip and zlib compressed data, detected from the encoding or from the magic bytes.
+func decompress(data []byte, encoding string) ([]byte, bool) {
+	var rd io.Reader
+	var err error
+	switch {
+	case encoding == "gzip" || (encoding == "" && len(data) > 2 && data[0] == 0x1f && data[1] == 0x8b):
+		rd, err = gzip.NewReader(bytes.NewReader(data))
+	case encoding == "deflate" || (encoding == "" && len(data) > 2 && data[0] == 0x78 && (data[1] == 0x01 || data[1] == 0x9c || data[1] == 0xda)):
+		rd, err = zlib.NewReader(bytes.NewReader(data))
+	default:
+		return nil, false
+	}
+	if err != nil {
+		return nil, false
+	}
+	out, err := io.ReadAll(rd)
+	if err != nil {
+		return nil, false
+	}
+	return out, true
+}
+
+func isPrintable(data []byte) bool {
+	if !utf8.Valid(data) {
+		return false
+	}
+	for _, c := range string(data) {
+		if !unicode.IsPrint(c) && !unicode.IsSpace(c) {
+			return false
+		}
+	}
+	return true
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,23 @@
+package inspect
+
+import (
+	"context"
+	"time"
+
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+// Service pretty-prints a recorded test case or mock
+type Service interface {
+	// Inspect prints the test case or mock identified by <test-set>/<name>
+	Inspect(ctx context.Context, target string) error
+}
+
+type TestDB interface {
+	GetTestCases(ctx context.Context, testSetID string) ([]*models.TestCase, error)
+}
+
+type MockDB interface {
+	GetFilteredMocks(ctx context.Context, testSetID string, afterTime time.Time, beforeTime time.Time) ([]*models.Mock, error)
+	GetUnFilteredMocks(ctx context.Context, testSetID string, afterTime time.Time, beforeTime time.Time) ([]*models.Mock, error)
+}
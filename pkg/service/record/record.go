@@ -13,12 +13,20 @@ import (
 	"go.keploy.io/server/v2/config"
 	"go.keploy.io/server/v2/pkg"
 	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/pkg/redact"
 
 	"go.keploy.io/server/v2/utils"
 	"go.uber.org/zap"
 	"golang.org/x/sync/errgroup"
 )
 
+const (
+	// testBatchSize is the number of recorded test cases which are saved together.
+	testBatchSize = 50
+	// testFlushInterval is how long the recorded test cases can wait before they are saved.
+	testFlushInterval = time.Second
+)
+
 type Recorder struct {
 	logger          *zap.Logger
 	testDB          TestDB
@@ -41,6 +49,18 @@ func New(logger *zap.Logger, testDB TestDB, mockDB MockDB, telemetry Telemetry,
 
 func (r *Recorder) Start(ctx context.Context, reRecord bool) error {
 
+	// merging the repository level noise, filters and bypass rules into the config
+	if err := config.ApplyDefaults(r.config); err != nil {
+		utils.LogError(r.logger, err, "failed to apply the default config")
+		return err
+	}
+
+	redactor, err := redact.New(r.config.Redact)
+	if err != nil {
+		utils.LogError(r.logger, err, "failed to compile the redaction rules")
+		return err
+	}
+
 	// creating error group to manage proper shutdown of all the go routines and to propagate the error to the caller
 	errGrp, _ := errgroup.WithContext(ctx)
 	ctx = context.WithValue(ctx, models.ErrGroupKey, errGrp)
@@ -101,7 +121,7 @@ func (r *Recorder) Start(ctx context.Context, reRecord bool) error {
 	defer close(insertTestErrChan)
 	defer close(insertMockErrChan)
 
-	newTestSetID, err := r.GetNextTestSetID(ctx)
+	newTestSetID, err = r.GetNextTestSetID(ctx)
 	if err != nil {
 		stopReason = "failed to get new test-set id"
 		utils.LogError(r.logger, err, stopReason)
@@ -137,24 +157,66 @@ func (r *Recorder) Start(ctx context.Context, reRecord bool) error {
 	}
 
 	errGrp.Go(func() error {
-		for testCase := range frames.Incoming {
-			err := r.testDB.InsertTestCase(ctx, testCase, newTestSetID)
-			if err != nil {
-				if ctx.Err() == context.Canceled {
+		// the test cases are written in batches, so that the capture doesn't wait for a file write per test case
+		ticker := time.NewTicker(testFlushInterval)
+		defer ticker.Stop()
+		batch := make([]*models.TestCase, 0, testBatchSize)
+		// flush writes the batch, a test case which fails to be written is dropped and the ones after it are
+		// still written. The first error is returned.
+		flush := func(ctx context.Context) error {
+			var firstErr error
+			pending := batch
+			for len(pending) > 0 {
+				n, err := r.testDB.InsertTestCases(ctx, pending, newTestSetID)
+				testCount += n
+				for i := 0; i < n; i++ {
+					r.telemetry.RecordedTestAndMocks()
+				}
+				if err == nil {
+					break
+				}
+				if firstErr == nil {
+					firstErr = err
+				}
+				pending = pending[n+1:]
+			}
+			batch = batch[:0]
+			return firstErr
+		}
+		for {
+			select {
+			case testCase, ok := <-frames.Incoming:
+				if !ok {
+					// the buffered test cases are saved even if the recording is being stopped
+					if err := flush(context.WithoutCancel(ctx)); err != nil {
+						utils.LogError(r.logger, err, "failed to save the recorded test cases")
+					}
+					return nil
+				}
+				if redactor.TestCase(testCase) {
+					r.logger.Debug("redacted secrets from the test case", zap.String("testcase", testCase.Name))
+				}
+				batch = append(batch, testCase)
+				if len(batch) < testBatchSize {
 					continue
 				}
+			case <-ticker.C:
+			}
+			if ctx.Err() != nil {
+				// keep the test cases for the final flush
+				continue
+			}
+			if err := flush(ctx); err != nil && ctx.Err() != context.Canceled {
 				insertTestErrChan <- err
-			} else {
-
-				testCount++
-				r.telemetry.RecordedTestAndMocks()
 			}
 		}
-		return nil
 	})
 
 	errGrp.Go(func() error {
 		for mock := range frames.Outgoing {
+			if redactor.Mock(mock) {
+				r.logger.Debug("redacted secrets from the mock", zap.String("mock", mock.Name))
+			}
 			err := r.mockDB.InsertMock(ctx, mock, newTestSetID)
 			if err != nil {
 				if ctx.Err() == context.Canceled {
@@ -266,7 +328,9 @@ func (r *Recorder) Instrument(ctx context.Context) (uint64, error) {
 
 func (r *Recorder) GetTestAndMockChans(ctx context.Context, appID uint64) (FrameChan, error) {
 	incomingOpts := models.IncomingOptions{
-		Filters: r.config.Record.Filters,
+		Filters:    r.config.Record.Filters,
+		Capture:    r.config.Record.Capture,
+		TLSIngress: r.config.Record.TLSIngress,
 	}
 	incomingChan, err := r.instrumentation.GetIncoming(ctx, appID, incomingOpts)
 	if err != nil {
@@ -277,6 +341,7 @@ func (r *Recorder) GetTestAndMockChans(ctx context.Context, appID uint64) (Frame
 		Rules:          r.config.BypassRules,
 		MongoPassword:  r.config.Test.MongoPassword,
 		FallBackOnMiss: r.config.Test.FallBackOnMiss,
+		BypassDomains:  r.config.Record.BypassDomains,
 	}
 	outgoingChan, err := r.instrumentation.GetOutgoing(ctx, appID, outgoingOpts)
 	if err != nil {
@@ -25,7 +25,7 @@ type Service interface {
 
 type TestDB interface {
 	GetAllTestSetIDs(ctx context.Context) ([]string, error)
-	InsertTestCase(ctx context.Context, tc *models.TestCase, testSetID string) error
+	InsertTestCases(ctx context.Context, tcs []*models.TestCase, testSetID string) (int, error)
 	// GetTestCases(ctx context.Context, testID string) ([]*models.TestCase, error)
 }
 
@@ -0,0 +1,145 @@
+package replay
+
+import (
+	"context"
+	"fmt"
+
+	"facette.io/natsort"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+// runResults maps the test cases of a test run, as <test-set>/<test-case>, to their status.
+type runResults map[string]models.TestStatus
+
+// results reads the statuses of the test cases of the test sets in the given test run, the test sets without
+// a report in the run are skipped.
+func (r *Replayer) results(ctx context.Context, testRunID string, testSets []string) runResults {
+	results := runResults{}
+	for _, testSet := range testSets {
+		report, err := r.reportDB.GetReport(ctx, testRunID, testSet)
+		if err != nil || report == nil {
+			r.logger.Debug("no report of the test set in the test run", zap.String("testRunID", testRunID), zap.String("testSet", testSet), zap.Error(err))
+			continue
+		}
+		for _, test := range report.Tests {
+			results[testSet+"/"+test.TestCaseID] = test.Status
+		}
+	}
+	return results
+}
+
+// previousRuns returns the test runs before the given one, the latest first.
+func (r *Replayer) previousRuns(ctx context.Context, testRunID string) ([]string, error) {
+	testRunIDs, err := r.reportDB.GetAllTestRunIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	natsort.Sort(testRunIDs)
+	var runs []string
+	for i := len(testRunIDs) - 1; i >= 0; i-- {
+		if testRunIDs[i] != testRunID {
+			runs = append(runs, testRunIDs[i])
+		}
+	}
+	return runs, nil
+}
+
+// checkQualityGates checks the results of the test run against the quality gates of the config, the failed
+// gates are logged. It reports whether the test run passed all the gates.
+func (r *Replayer) checkQualityGates(ctx context.Context, testRunID string, testSets []string) bool {
+	gates := r.config.Test.QualityGates
+	current := r.results(ctx, testRunID, testSets)
+
+	var failures []string
+
+	if gates.MinPassRate > 0 {
+		passed, total := 0, 0
+		for _, status := range current {
+			switch status {
+			case models.TestStatusPassed:
+				passed++
+				total++
+			case models.TestStatusFailed:
+				total++
+			}
+		}
+		passRate := 100.0
+		if total > 0 {
+			passRate = float64(passed) * 100 / float64(total)
+		}
+		if passRate < gates.MinPassRate {
+			failures = append(failures, fmt.Sprintf("pass rate %.2f%% is below the minimum of %.2f%%", passRate, gates.MinPassRate))
+		}
+	}
+
+	var previous []string
+	if gates.NoNewFailures || gates.MaxFlaky >= 0 {
+		var err error
+		previous, err = r.previousRuns(ctx, testRunID)
+		if err != nil {
+			utils.LogError(r.logger, err, "failed to get the previous test runs for the quality gates")
+			return false
+		}
+	}
+
+	if gates.NoNewFailures {
+		baselineRun := gates.BaselineRun
+		if baselineRun == "" && len(previous) > 0 {
+			baselineRun = previous[0]
+		}
+		if baselineRun == "" {
+			r.logger.Warn("no baseline test run to compare against, every failed test case counts as a new failure")
+		}
+		baseline := runResults{}
+		if baselineRun != "" {
+			baseline = r.results(ctx, baselineRun, testSets)
+		}
+		var newFailures []string
+		for test, status := range current {
+			if status == models.TestStatusFailed && baseline[test] != models.TestStatusFailed {
+				newFailures = append(newFailures, test)
+			}
+		}
+		if len(newFailures) > 0 {
+			natsort.Sort(newFailures)
+			failures = append(failures, fmt.Sprintf("%d test cases failed which didn't fail in the baseline run %s: %v", len(newFailures), baselineRun, newFailures))
+		}
+	}
+
+	if gates.MaxFlaky >= 0 {
+		window := max(gates.FlakyWindow, 2)
+		runs := []runResults{current}
+		for _, run := range previous {
+			if len(runs) == window {
+				break
+			}
+			runs = append(runs, r.results(ctx, run, testSets))
+		}
+		var flaky []string
+		for test := range current {
+			var passed, failed bool
+			for _, run := range runs {
+				passed = passed || run[test] == models.TestStatusPassed
+				failed = failed || run[test] == models.TestStatusFailed
+			}
+			if passed && failed {
+				flaky = append(flaky, test)
+			}
+		}
+		if len(flaky) > gates.MaxFlaky {
+			natsort.Sort(flaky)
+			failures = append(failures, fmt.Sprintf("%d flaky test cases within the last %d test runs, more than the maximum of %d: %v", len(flaky), len(runs), gates.MaxFlaky, flaky))
+		}
+	}
+
+	if len(failures) > 0 {
+		for _, failure := range failures {
+			r.logger.Error("quality gate failed: " + failure)
+		}
+		return false
+	}
+	r.logger.Info("all the quality gates passed", zap.String("testRunID", testRunID))
+	return true
+}
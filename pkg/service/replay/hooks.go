@@ -47,6 +47,20 @@ func (h *Hooks) SimulateRequest(ctx context.Context, _ uint64, tc *models.TestCa
 	return nil, nil
 }
 
+func (h *Hooks) SimulateGRPC(ctx context.Context, _ uint64, tc *models.TestCase, testSetID string) (*models.GrpcResp, error) {
+	h.logger.Debug("Before simulating the grpc call", zap.Any("Test case", tc))
+	resp, err := pkg.SimulateGRPC(ctx, tc, testSetID, h.logger, h.cfg.Test.APITimeout)
+	h.logger.Debug("After simulating the grpc call", zap.Any("test case id", tc.Name))
+	return resp, err
+}
+
+func (h *Hooks) SimulateWebSocket(ctx context.Context, _ uint64, tc *models.TestCase, testSetID string) (*models.HTTPResp, []models.WebSocketFrame, error) {
+	h.logger.Debug("Before simulating the websocket", zap.Any("Test case", tc))
+	resp, frames, err := pkg.SimulateWebSocket(ctx, tc, testSetID, h.logger, h.cfg.Test.APITimeout)
+	h.logger.Debug("After simulating the websocket", zap.Any("test case id", tc.Name))
+	return resp, frames, err
+}
+
 func (h *Hooks) AfterTestSetRun(ctx context.Context, testSetID string, status bool) error {
 
 	if h.cfg.Test.DisableMockUpload {
@@ -107,17 +121,22 @@ func (h *Hooks) AfterTestSetRun(ctx context.Context, testSetID string, status bo
 		}
 
 		// create ts config
-		var prescript, postscript string
+		var prescript, postscript, mongoQueryMatching string
 		var template map[string]interface{}
+		var httpMatching *config.HTTPIntegration
 		if tsConfig != nil {
 			prescript = tsConfig.PreScript
 			postscript = tsConfig.PostScript
 			template = tsConfig.Template
+			mongoQueryMatching = tsConfig.MongoQueryMatching
+			httpMatching = tsConfig.HTTPMatching
 		}
 		tsConfig = &models.TestSet{
-			PreScript:  prescript,
-			PostScript: postscript,
-			Template:   template,
+			PreScript:          prescript,
+			PostScript:         postscript,
+			Template:           template,
+			MongoQueryMatching: mongoQueryMatching,
+			HTTPMatching:       httpMatching,
 			MockRegistry: &models.MockRegistry{
 				Mock: mockHash,
 				App:  h.cfg.AppName,
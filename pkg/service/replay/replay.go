@@ -20,7 +20,9 @@ import (
 	"go.keploy.io/server/v2/config"
 	"go.keploy.io/server/v2/pkg"
 	matcherUtils "go.keploy.io/server/v2/pkg/matcher"
+	grpcMatcher "go.keploy.io/server/v2/pkg/matcher/grpc"
 	httpMatcher "go.keploy.io/server/v2/pkg/matcher/http"
+	wsMatcher "go.keploy.io/server/v2/pkg/matcher/websocket"
 	"go.keploy.io/server/v2/pkg/models"
 	"go.keploy.io/server/v2/pkg/platform/coverage"
 	"go.keploy.io/server/v2/pkg/platform/coverage/golang"
@@ -77,6 +79,12 @@ func NewReplayer(logger *zap.Logger, testDB TestDB, mockDB MockDB, reportDB Repo
 
 func (r *Replayer) Start(ctx context.Context) error {
 
+	// merging the repository level noise, filters and bypass rules into the config
+	if err := config.ApplyDefaults(r.config); err != nil {
+		utils.LogError(r.logger, err, "failed to apply the default config")
+		return err
+	}
+
 	// creating error group to manage proper shutdown of all the go routines and to propagate the error to the caller
 	g, ctx := errgroup.WithContext(ctx)
 	ctx = context.WithValue(ctx, models.ErrGroupKey, g)
@@ -318,6 +326,11 @@ func (r *Replayer) Start(ctx context.Context) error {
 		}
 	}
 
+	// the quality gates decide the result of a complete test run when they are set
+	if !abortTestRun && r.config.Test.QualityGates.Enabled() {
+		testRunResult = r.checkQualityGates(ctx, testRunID, testSets)
+	}
+
 	// return non-zero error code so that pipeline processes
 	// know that there is a failure in tests
 	if !testRunResult {
@@ -572,7 +585,7 @@ func (r *Replayer) RunTestSet(ctx context.Context, testSetID string, testRunID s
 
 		if _, ok := ignoredTests[testCase.Name]; ok {
 			testCaseResult := &models.TestResult{
-				Kind:         models.HTTP,
+				Kind:         testCase.Kind,
 				Name:         testSetID,
 				Status:       models.TestStatusIgnored,
 				TestCaseID:   testCase.Name,
@@ -645,13 +658,29 @@ func (r *Replayer) RunTestSet(ctx context.Context, testSetID string, testRunID s
 			testCase.HTTPReq.URL, err = utils.ReplacePort(testCase.HTTPReq.URL, strconv.Itoa(int(r.config.Test.Port)))
 		}
 
+		// the templates of the response headers are lost once the test case is rendered to simulate the request
+		headerTemplates := responseHeaderTemplates(testCase.HTTPResp.Header)
+
 		started := time.Now().UTC()
-		resp, loopErr := HookImpl.SimulateRequest(runTestSetCtx, appID, testCase, testSetID)
+		var resp *models.HTTPResp
+		var grpcResp *models.GrpcResp
+		var frames []models.WebSocketFrame
+		switch testCase.Kind {
+		case models.GRPC_EXPORT:
+			grpcResp, loopErr = HookImpl.SimulateGRPC(runTestSetCtx, appID, testCase, testSetID)
+		case models.WebSocket:
+			resp, frames, loopErr = HookImpl.SimulateWebSocket(runTestSetCtx, appID, testCase, testSetID)
+		default:
+			resp, loopErr = HookImpl.SimulateRequest(runTestSetCtx, appID, testCase, testSetID)
+		}
 		if loopErr != nil {
 			utils.LogError(r.logger, err, "failed to simulate request")
 			failure++
 			continue
 		}
+		if resp != nil {
+			updateHeaderTemplates(headerTemplates, resp.Header)
+		}
 
 		var consumedMocks []string
 		if r.instrument {
@@ -666,7 +695,18 @@ func (r *Replayer) RunTestSet(ctx context.Context, testSetID string, testRunID s
 			}
 		}
 
-		testPass, testResult = r.compareResp(testCase, resp, testSetID)
+		switch testCase.Kind {
+		case models.GRPC_EXPORT:
+			testPass, testResult = r.compareGrpcResp(testCase, grpcResp, testSetID)
+		case models.WebSocket:
+			testPass, testResult = r.compareWebSocket(testCase, resp, frames, testSetID)
+		default:
+			testPass, testResult = r.compareResp(testCase, resp, testSetID)
+			if testCase.HTTPResp.Truncated != nil {
+				// the response is reported truncated like the recorded one, which it's normalized to
+				resp.Body, resp.Truncated = pkg.TruncateBody(resp.Body, len(testCase.HTTPResp.Body))
+			}
+		}
 		if !testPass {
 			// log the consumed mocks during the test run of the test case for test set
 			r.logger.Info("result", zap.Any("testcase id", models.HighlightFailingString(testCase.Name)), zap.Any("testset id", models.HighlightFailingString(testSetID)), zap.Any("passed", models.HighlightFailingString(testPass)))
@@ -685,7 +725,7 @@ func (r *Replayer) RunTestSet(ctx context.Context, testSetID string, testRunID s
 
 		if testResult != nil {
 			testCaseResult := &models.TestResult{
-				Kind:       models.HTTP,
+				Kind:       testCase.Kind,
 				Name:       testSetID,
 				Status:     testStatus,
 				Started:    started.Unix(),
@@ -703,12 +743,19 @@ func (r *Replayer) RunTestSet(ctx context.Context, testSetID string, testRunID s
 					Form:       testCase.HTTPReq.Form,
 					Timestamp:  testCase.HTTPReq.Timestamp,
 				},
-				Res:          *resp,
 				TestCasePath: filepath.Join(r.config.Path, testSetID),
 				MockPath:     filepath.Join(r.config.Path, testSetID, "mocks.yaml"),
 				Noise:        testCase.Noise,
 				Result:       *testResult,
 			}
+			if resp != nil {
+				testCaseResult.Res = *resp
+			}
+			if grpcResp != nil {
+				testCaseResult.GrpcReq = testCase.GrpcReq
+				testCaseResult.GrpcRes = *grpcResp
+			}
+			testCaseResult.Frames = frames
 			loopErr = r.reportDB.InsertTestCaseResult(runTestSetCtx, testRunID, testSetID, testCaseResult)
 			if loopErr != nil {
 				utils.LogError(r.logger, err, "failed to insert test case result")
@@ -835,9 +882,11 @@ func (r *Replayer) RunTestSet(ctx context.Context, testSetID string, testRunID s
 		// Write the templatized values to the yaml.
 		if len(utils.TemplatizedValues) > 0 {
 			err = r.testSetConf.Write(ctx, testSetID, &models.TestSet{
-				PreScript:  conf.PreScript,
-				PostScript: conf.PostScript,
-				Template:   utils.TemplatizedValues,
+				PreScript:          conf.PreScript,
+				PostScript:         conf.PostScript,
+				Template:           utils.TemplatizedValues,
+				MongoQueryMatching: conf.MongoQueryMatching,
+				HTTPMatching:       conf.HTTPMatching,
 			})
 			if err != nil {
 				utils.LogError(r.logger, err, "failed to write the templatized values to the yaml")
@@ -876,12 +925,25 @@ func (r *Replayer) SetupOrUpdateMocks(ctx context.Context, appID uint64, testSet
 	}
 
 	if action == Start {
+		// the test set overrides the matching of the integrations configured for all of them
+		mongo := config.MongoIntegration{}
+		httpMatching := config.HTTPIntegration{}
+		if conf, err := r.testSetConf.Read(ctx, testSetID); err == nil && conf != nil {
+			mongo.QueryMatching = conf.MongoQueryMatching
+			if conf.HTTPMatching != nil {
+				httpMatching = *conf.HTTPMatching
+			}
+		}
 		err = r.instrumentation.MockOutgoing(ctx, appID, models.OutgoingOptions{
-			Rules:          r.config.BypassRules,
-			MongoPassword:  r.config.Test.MongoPassword,
-			SQLDelay:       time.Duration(r.config.Test.Delay),
-			FallBackOnMiss: r.config.Test.FallBackOnMiss,
-			Mocking:        r.config.Test.Mocking,
+			Rules:           r.config.BypassRules,
+			MongoPassword:   r.config.Test.MongoPassword,
+			SQLDelay:        time.Duration(r.config.Test.Delay),
+			FallBackOnMiss:  r.config.Test.FallBackOnMiss,
+			Mocking:         r.config.Test.Mocking,
+			WebSocketTiming: r.config.Test.WebSocketTiming,
+			SSETiming:       r.config.Test.SSETiming,
+			Mongo:           mongo,
+			HTTP:            httpMatching,
 		})
 		if err != nil {
 			utils.LogError(r.logger, err, "failed to mock outgoing")
@@ -918,6 +980,24 @@ func (r *Replayer) compareResp(tc *models.TestCase, actualResponse *models.HTTPR
 	return httpMatcher.Match(tc, actualResponse, noiseConfig, r.config.Test.IgnoreOrdering, r.logger)
 }
 
+func (r *Replayer) compareGrpcResp(tc *models.TestCase, actualResponse *models.GrpcResp, testSetID string) (bool, *models.Result) {
+
+	noiseConfig := r.config.Test.GlobalNoise.Global
+	if tsNoise, ok := r.config.Test.GlobalNoise.Testsets[testSetID]; ok {
+		noiseConfig = LeftJoinNoise(r.config.Test.GlobalNoise.Global, tsNoise)
+	}
+	return grpcMatcher.Match(tc, actualResponse, noiseConfig, r.logger)
+}
+
+func (r *Replayer) compareWebSocket(tc *models.TestCase, actualResponse *models.HTTPResp, actualFrames []models.WebSocketFrame, testSetID string) (bool, *models.Result) {
+
+	noiseConfig := r.config.Test.GlobalNoise.Global
+	if tsNoise, ok := r.config.Test.GlobalNoise.Testsets[testSetID]; ok {
+		noiseConfig = LeftJoinNoise(r.config.Test.GlobalNoise.Global, tsNoise)
+	}
+	return wsMatcher.Match(tc, actualResponse, actualFrames, noiseConfig, r.config.Test.IgnoreOrdering, r.logger)
+}
+
 func (r *Replayer) printSummary(_ context.Context, _ bool) {
 	if totalTests > 0 {
 		testSuiteNames := make([]string, 0, len(completeTestReport))
@@ -1102,7 +1182,19 @@ func (r *Replayer) NormalizeTestCases(ctx context.Context, testRun string, testS
 		if testCaseResultMap[testCase.Name].Status == models.TestStatusPassed {
 			continue
 		}
-		testCase.HTTPResp = testCaseResultMap[testCase.Name].Res
+		switch testCase.Kind {
+		case models.GRPC_EXPORT:
+			testCase.GrpcResp = testCaseResultMap[testCase.Name].GrpcRes
+		case models.WebSocket:
+			// the session is normalized to the frames exchanged with the application, it keeps the time it
+			// ended at so that its mocks are still the ones of the whole session
+			timestamp := testCase.HTTPResp.Timestamp
+			testCase.HTTPResp = testCaseResultMap[testCase.Name].Res
+			testCase.HTTPResp.Timestamp = timestamp
+			testCase.WebSocketFrames = testCaseResultMap[testCase.Name].Frames
+		default:
+			testCase.HTTPResp = testCaseResultMap[testCase.Name].Res
+		}
 		err = r.testDB.UpdateTestCase(ctx, testCase, testSetID)
 		if err != nil {
 			return fmt.Errorf("failed to update test case: %w", err)
@@ -78,6 +78,8 @@ type Telemetry interface {
 
 type TestHooks interface {
 	SimulateRequest(ctx context.Context, appID uint64, tc *models.TestCase, testSetID string) (*models.HTTPResp, error)
+	SimulateGRPC(ctx context.Context, appID uint64, tc *models.TestCase, testSetID string) (*models.GrpcResp, error)
+	SimulateWebSocket(ctx context.Context, appID uint64, tc *models.TestCase, testSetID string) (*models.HTTPResp, []models.WebSocketFrame, error)
 	BeforeTestSetRun(ctx context.Context, testSetID string) error
 	AfterTestSetRun(ctx context.Context, testSetID string, status bool) error
 	AfterTestRun(ctx context.Context, testRunID string, testSetIDs []string, coverage models.TestCoverage) error // hook executed after running all the test-sets
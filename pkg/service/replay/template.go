@@ -11,10 +11,12 @@ import (
 	"strconv"
 	"strings"
 	"text/template"
+	"unicode"
 
 	matcher "go.keploy.io/server/v2/pkg/matcher"
 
 	"github.com/7sDream/geko"
+	"go.keploy.io/server/v2/pkg"
 	"go.keploy.io/server/v2/pkg/models"
 	"go.keploy.io/server/v2/utils"
 	"go.uber.org/zap"
@@ -166,6 +168,27 @@ func (r *Replayer) Templatize(ctx context.Context) error {
 			tcs[i].HTTPResp.Body = string(jsonData)
 		}
 
+		// CASE:5
+		// Compare the response headers of ith testcase with the i+1->n requests, e.g. a token or a session cookie issued in a header.
+		for i := 0; i < len(tcs)-1; i++ {
+			for j := i + 1; j < len(tcs); j++ {
+				addHeaderTemplates(r.logger, tcs[i], tcs[j])
+			}
+		}
+
+		// CASE:6
+		// Mark the values freshly generated by every run (timestamps, uuids, request ids) in the responses as noise,
+		// the ones flowing into the next requests are already templatized above. A value is only taken as generated
+		// when it changed between the recordings of the same request, the values which look generated but were
+		// recorded once may as well be stored ones.
+		noisyFields := 0
+		for _, group := range sameRequests(tcs) {
+			noisyFields += addGeneratedNoise(group)
+		}
+
+		// The mocks are left as recorded: the proxy matches them against the outgoing calls of the app without
+		// rendering any template, so a templatized mock would no longer match the calls it was recorded from.
+
 		// Updating all the testcases.
 		for _, tc := range tcs {
 			tc.HTTPReq.Body = removeQuotesInTemplates(tc.HTTPReq.Body)
@@ -189,6 +212,7 @@ func (r *Replayer) Templatize(ctx context.Context) error {
 			utils.LogError(r.logger, err, "failed to write test set")
 			return err
 		}
+		r.logger.Info("templatized the test set", zap.String("testSet", testSetID), zap.Int("templates", len(utils.TemplatizedValues)), zap.Int("noisy fields", noisyFields))
 	}
 
 	return nil
@@ -614,3 +638,274 @@ func removeDoubleQuotes(tempMap map[string]interface{}) {
 		}
 	}
 }
+
+// volatileHeaders are the response headers whose values change on every run.
+var volatileHeaders = map[string]bool{
+	"date":             true,
+	"expires":          true,
+	"last-modified":    true,
+	"etag":             true,
+	"age":              true,
+	"set-cookie":       true,
+	"x-request-id":     true,
+	"x-correlation-id": true,
+	"x-trace-id":       true,
+	"traceparent":      true,
+}
+
+var uuidRegex = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// isGenerated reports whether the value looks freshly generated, i.e. a timestamp or a uuid.
+func isGenerated(val string) bool {
+	return uuidRegex.MatchString(strings.TrimSpace(val)) || pkg.IsTime(val)
+}
+
+// isToken reports whether the header value looks like an identifier or a secret worth templatizing: long enough,
+// without spaces and made of both letters and digits.
+func isToken(val string) bool {
+	if len(val) < 8 || strings.ContainsAny(val, " ,") {
+		return false
+	}
+	return strings.ContainsAny(val, "0123456789") && strings.IndexFunc(val, unicode.IsLetter) != -1
+}
+
+// templateKey removes the characters which can't be used in a template key.
+func templateKey(name string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' {
+			return r
+		}
+		return -1
+	}, name)
+}
+
+// splitHeaderTemplate splits the response header value holding a single template, e.g. "sid={{string .sid }}; Path=/",
+// into the text before the template, the template key and the text after it.
+func splitHeaderTemplate(val string) (string, string, string, bool) {
+	start := strings.Index(val, "{{")
+	end := strings.Index(val, "}}")
+	if start == -1 || end < start {
+		return "", "", "", false
+	}
+	fields := strings.Fields(val[start+2 : end])
+	if len(fields) != 2 || !strings.HasPrefix(fields[1], ".") {
+		return "", "", "", false
+	}
+	return val[:start], strings.TrimPrefix(fields[1], "."), val[end+2:], true
+}
+
+// addHeaderTemplates templatizes the values of the response headers of src which are sent back in the headers,
+// the url or the body of the request of dst.
+func addHeaderTemplates(logger *zap.Logger, src, dst *models.TestCase) {
+	for name, val := range src.HTTPResp.Header {
+		var prefix, key, suffix, token string
+		p, k, sfx, templatized := splitHeaderTemplate(val)
+		if templatized {
+			// already templatized by a previous request
+			v, ok := utils.TemplatizedValues[k].(string)
+			if !ok {
+				continue
+			}
+			prefix, key, suffix, token = p, k, sfx, v
+		} else {
+			token = val
+			keyName := name
+			if strings.EqualFold(name, "Set-Cookie") {
+				// only the value of the cookie is sent back, e.g. sid=abc; Path=/ is sent as Cookie: sid=abc
+				cookie := strings.SplitN(val, ";", 2)[0]
+				parts := strings.SplitN(cookie, "=", 2)
+				if len(parts) != 2 {
+					continue
+				}
+				keyName, token = parts[0], parts[1]
+				prefix, suffix = parts[0]+"=", strings.TrimPrefix(val, cookie)
+			}
+			if !isToken(token) || isGenerated(token) {
+				continue
+			}
+			key = templateKey(keyName)
+			if key == "" {
+				continue
+			}
+		}
+		// the headers of dst are compared with their rendered values
+		header := make(map[string]string, len(dst.HTTPReq.Header))
+		for hk, hv := range dst.HTTPReq.Header {
+			if rendered, err := renderIfTemplatized(hv); err == nil {
+				if s, ok := rendered.(string); ok {
+					header[hk] = s
+				}
+			}
+		}
+		used := strings.Contains(dst.HTTPReq.URL, token) || strings.Contains(dst.HTTPReq.Body, token)
+		for _, hv := range header {
+			used = used || strings.Contains(hv, token)
+		}
+		if !used {
+			continue
+		}
+		if !templatized {
+			key = insertUnique(key, token, utils.TemplatizedValues)
+		}
+		template := fmt.Sprintf("{{string .%v }}", key)
+		for hk, hv := range header {
+			if strings.Contains(hv, token) {
+				dst.HTTPReq.Header[hk] = strings.ReplaceAll(hv, token, template)
+			}
+		}
+		dst.HTTPReq.URL = strings.ReplaceAll(dst.HTTPReq.URL, token, template)
+		dst.HTTPReq.Body = strings.ReplaceAll(dst.HTTPReq.Body, token, template)
+		src.HTTPResp.Header[name] = prefix + template + suffix
+		// the header is checked by updating the template value with the actual one, not by comparing it
+		if src.Noise == nil {
+			src.Noise = map[string][]string{}
+		}
+		src.Noise["header."+name] = []string{}
+		logger.Debug("templatized the response header", zap.String("header", name), zap.String("testcase", src.Name), zap.String("used by", dst.Name))
+	}
+}
+
+// sameRequests groups the test cases sending the same request, in the order they were recorded. Only the requests
+// recorded more than once are returned.
+func sameRequests(tcs []*models.TestCase) [][]*models.TestCase {
+	var keys []string
+	byRequest := map[string][]*models.TestCase{}
+	for _, tc := range tcs {
+		key := string(tc.HTTPReq.Method) + " " + tc.HTTPReq.URL + "\n" + tc.HTTPReq.Body
+		if _, ok := byRequest[key]; !ok {
+			keys = append(keys, key)
+		}
+		byRequest[key] = append(byRequest[key], tc)
+	}
+	var groups [][]*models.TestCase
+	for _, key := range keys {
+		if len(byRequest[key]) > 1 {
+			groups = append(groups, byRequest[key])
+		}
+	}
+	return groups
+}
+
+// addGeneratedNoise marks the fields of the responses to the same request which look freshly generated and whose
+// values differ between the test cases as noise. It returns the number of fields added to the noise.
+func addGeneratedNoise(tcs []*models.TestCase) int {
+	fields := make([]map[string][]string, len(tcs))
+	for i, tc := range tcs {
+		fields[i] = responseFields(tc)
+	}
+
+	added := 0
+	for field := range fields[0] {
+		// the volatile headers hold generated values whatever they look like, e.g. an etag
+		name, isHeader := strings.CutPrefix(field, "header.")
+		volatile := isHeader && volatileHeaders[strings.ToLower(name)]
+		changed, generated := false, true
+		for i := range tcs {
+			values, ok := fields[i][field]
+			if !ok {
+				generated = false
+				break
+			}
+			changed = changed || strings.Join(values, "\x00") != strings.Join(fields[0][field], "\x00")
+			for _, v := range values {
+				generated = generated && (volatile || isGenerated(v))
+			}
+		}
+		if !changed || !generated {
+			continue
+		}
+		for _, tc := range tcs {
+			if tc.Noise == nil {
+				tc.Noise = map[string][]string{}
+			}
+			if _, ok := tc.Noise[field]; !ok {
+				tc.Noise[field] = []string{}
+				added++
+			}
+		}
+	}
+	return added
+}
+
+// responseFields returns the values of the response headers and of the json body fields of the test case by their
+// noise keys, the templatized values are left out. The values of a field within arrays are listed in order.
+func responseFields(tc *models.TestCase) map[string][]string {
+	fields := map[string][]string{}
+	for name, val := range tc.HTTPResp.Header {
+		if !strings.Contains(val, "{{") {
+			fields["header."+name] = []string{val}
+		}
+	}
+
+	var body interface{}
+	dec := json.NewDecoder(strings.NewReader(tc.HTTPResp.Body))
+	dec.UseNumber()
+	if tc.HTTPResp.Body == "" || dec.Decode(&body) != nil {
+		return fields
+	}
+	var walk func(path string, node interface{})
+	walk = func(path string, node interface{}) {
+		switch v := node.(type) {
+		case map[string]interface{}:
+			for k, child := range v {
+				field := k
+				if path != "" {
+					field = path + "." + k
+				}
+				walk(field, child)
+			}
+		case []interface{}:
+			// the noise of the arrays applies to all the elements
+			for _, child := range v {
+				walk(path, child)
+			}
+		case string:
+			if path != "" && !strings.Contains(v, "{{") {
+				fields["body."+path] = append(fields["body."+path], v)
+			}
+		case json.Number:
+			if path != "" {
+				fields["body."+path] = append(fields["body."+path], v.String())
+			}
+		}
+	}
+	walk("", body)
+	return fields
+}
+
+// headerTemplate is a response header holding a template, whose value is updated with the actual response.
+type headerTemplate struct {
+	prefix, key, suffix string
+}
+
+// responseHeaderTemplates returns the templates of the response headers by header name. It must be called before
+// the test case is rendered.
+func responseHeaderTemplates(header map[string]string) map[string]headerTemplate {
+	templates := map[string]headerTemplate{}
+	for name, val := range header {
+		if prefix, key, suffix, ok := splitHeaderTemplate(val); ok {
+			templates[name] = headerTemplate{prefix: prefix, key: key, suffix: suffix}
+		}
+	}
+	return templates
+}
+
+// updateHeaderTemplates sets the template values to the ones of the actual response headers, so that the next
+// requests are sent with them.
+func updateHeaderTemplates(templates map[string]headerTemplate, header map[string]string) {
+	for name, t := range templates {
+		for k, val := range header {
+			if !strings.EqualFold(k, name) || !strings.HasPrefix(val, t.prefix) {
+				continue
+			}
+			val = strings.TrimPrefix(val, t.prefix)
+			if t.suffix != "" {
+				// the suffix may change between the runs (e.g. the expiry of a cookie), only its separator is used
+				if idx := strings.IndexByte(val, t.suffix[0]); idx != -1 {
+					val = val[:idx]
+				}
+			}
+			utils.TemplatizedValues[t.key] = val
+		}
+	}
+}
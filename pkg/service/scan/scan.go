@@ -0,0 +1,100 @@
+// Package scan provides the audit of the recorded test sets for leaked secrets.
+package scan
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/olekukonko/tablewriter"
+	"go.keploy.io/server/v2/config"
+	"go.keploy.io/server/v2/pkg"
+	"go.keploy.io/server/v2/pkg/redact"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+// Finding is a secret found in a test case or a mock.
+type Finding struct {
+	TestSet  string
+	Kind     string // testcase or mock
+	Name     string
+	Location string
+	Rule     string
+}
+
+type scanner struct {
+	logger *zap.Logger
+	testDB TestDB
+	mockDB MockDB
+	config *config.Config
+}
+
+func New(logger *zap.Logger, testDB TestDB, mockDB MockDB, config *config.Config) Service {
+	return &scanner{
+		logger: logger,
+		testDB: testDB,
+		mockDB: mockDB,
+		config: config,
+	}
+}
+
+// Scan looks for secrets in the selected test sets (all of them by default) using the redaction rules
+// of the config along with all the built-in detectors, and prints the findings.
+func (s *scanner) Scan(ctx context.Context) ([]Finding, error) {
+	r, err := redact.NewScanner(s.config.Redact)
+	if err != nil {
+		utils.LogError(s.logger, err, "failed to compile the redaction rules")
+		return nil, err
+	}
+
+	testSets, err := s.testDB.GetAllTestSetIDs(ctx)
+	if err != nil {
+		utils.LogError(s.logger, err, "failed to get the test sets")
+		return nil, err
+	}
+
+	var findings []Finding
+	for _, testSet := range testSets {
+		if _, ok := s.config.Test.SelectedTests[testSet]; !ok && len(s.config.Test.SelectedTests) != 0 {
+			continue
+		}
+
+		testCases, err := s.testDB.GetTestCases(ctx, testSet)
+		if err != nil {
+			utils.LogError(s.logger, err, "failed to get the test cases", zap.String("testSet", testSet))
+			return nil, err
+		}
+		for _, tc := range testCases {
+			for _, f := range r.ScanTestCase(tc) {
+				findings = append(findings, Finding{TestSet: testSet, Kind: "testcase", Name: tc.Name, Location: f.Location, Rule: f.Rule})
+			}
+		}
+
+		mocks, err := pkg.LoadAllMocks(ctx, s.mockDB, testSet)
+		if err != nil {
+			utils.LogError(s.logger, err, "failed to get the mocks", zap.String("testSet", testSet))
+			return nil, err
+		}
+		for _, mock := range mocks {
+			for _, f := range r.ScanMock(mock) {
+				findings = append(findings, Finding{TestSet: testSet, Kind: "mock", Name: mock.Name, Location: f.Location, Rule: f.Rule})
+			}
+		}
+	}
+
+	if len(findings) == 0 {
+		s.logger.Info("no secrets found in the test sets")
+		return nil, nil
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Test-set", "Kind", "Name", "Location", "Rule"})
+	table.SetAutoMergeCells(true)
+	for _, f := range findings {
+		table.Append([]string{f.TestSet, f.Kind, f.Name, f.Location, f.Rule})
+	}
+	table.Render()
+	s.logger.Warn(fmt.Sprintf("found %d secrets in the test sets, add redaction rules to the config and re-record them", len(findings)))
+	return findings, nil
+}
@@ -0,0 +1,234 @@
+// Package stats provides the summary of the recorded test sets.
+package stats
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"go.keploy.io/server/v2/config"
+	"go.keploy.io/server/v2/pkg"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+// TestSetStats is the summary of a single test set.
+type TestSetStats struct {
+	Name      string
+	TestCases int
+	Endpoints map[string]int // "METHOD /path" or the grpc method, to the number of test cases
+	Mocks     map[models.Kind]int
+	Size      int64 // bytes on disk
+	Oldest    time.Time
+	Newest    time.Time
+}
+
+// Summary is the summary of all the selected test sets.
+type Summary struct {
+	TestSets  []*TestSetStats
+	TestCases int
+	Endpoints map[string]int
+	Mocks     map[models.Kind]int
+	Size      int64
+	Oldest    time.Time
+	Newest    time.Time
+}
+
+type stats struct {
+	logger *zap.Logger
+	testDB TestDB
+	mockDB MockDB
+	config *config.Config
+}
+
+func New(logger *zap.Logger, testDB TestDB, mockDB MockDB, config *config.Config) Service {
+	return &stats{
+		logger: logger,
+		testDB: testDB,
+		mockDB: mockDB,
+		config: config,
+	}
+}
+
+// Stats collects the summary of the selected test sets (all of them by default) and prints it.
+func (s *stats) Stats(ctx context.Context) (*Summary, error) {
+	testSets, err := s.testDB.GetAllTestSetIDs(ctx)
+	if err != nil {
+		utils.LogError(s.logger, err, "failed to get the test sets")
+		return nil, err
+	}
+	sort.Strings(testSets)
+
+	summary := &Summary{
+		Endpoints: map[string]int{},
+		Mocks:     map[models.Kind]int{},
+	}
+	for _, testSet := range testSets {
+		if _, ok := s.config.Test.SelectedTests[testSet]; !ok && len(s.config.Test.SelectedTests) != 0 {
+			continue
+		}
+		ts, err := s.testSet(ctx, testSet)
+		if err != nil {
+			return nil, err
+		}
+		summary.TestSets = append(summary.TestSets, ts)
+		summary.TestCases += ts.TestCases
+		summary.Size += ts.Size
+		for e, n := range ts.Endpoints {
+			summary.Endpoints[e] += n
+		}
+		for k, n := range ts.Mocks {
+			summary.Mocks[k] += n
+		}
+		summary.Oldest, summary.Newest = span(summary.Oldest, summary.Newest, ts.Oldest)
+		summary.Oldest, summary.Newest = span(summary.Oldest, summary.Newest, ts.Newest)
+	}
+
+	if len(summary.TestSets) == 0 {
+		s.logger.Info("No test-sets found. Please record testcases using keploy record command")
+		return summary, nil
+	}
+	s.print(summary)
+	return summary, nil
+}
+
+func (s *stats) testSet(ctx context.Context, testSet string) (*TestSetStats, error) {
+	ts := &TestSetStats{
+		Name:      testSet,
+		Endpoints: map[string]int{},
+		Mocks:     map[models.Kind]int{},
+	}
+
+	testCases, err := s.testDB.GetTestCases(ctx, testSet)
+	if err != nil {
+		utils.LogError(s.logger, err, "failed to get the test cases", zap.String("testSet", testSet))
+		return nil, err
+	}
+	ts.TestCases = len(testCases)
+	for _, tc := range testCases {
+		ts.Endpoints[pkg.Endpoint(tc)]++
+		if tc.Created != 0 {
+			ts.Oldest, ts.Newest = span(ts.Oldest, ts.Newest, time.Unix(tc.Created, 0))
+		}
+	}
+
+	mocks, err := pkg.LoadAllMocks(ctx, s.mockDB, testSet)
+	if err != nil {
+		utils.LogError(s.logger, err, "failed to get the mocks", zap.String("testSet", testSet))
+		return nil, err
+	}
+	for _, mock := range mocks {
+		ts.Mocks[mock.Kind]++
+		if !mock.Spec.ReqTimestampMock.IsZero() {
+			ts.Oldest, ts.Newest = span(ts.Oldest, ts.Newest, mock.Spec.ReqTimestampMock)
+		}
+	}
+
+	ts.Size, err = dirSize(filepath.Join(s.config.Path, testSet))
+	if err != nil {
+		s.logger.Warn("failed to get the size of the test set", zap.String("testSet", testSet), zap.Error(err))
+	}
+	return ts, nil
+}
+
+func (s *stats) print(summary *Summary) {
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Test-set", "Tests", "Endpoints", "Mocks", "Size", "Oldest", "Newest"})
+	for _, ts := range summary.TestSets {
+		table.Append([]string{ts.Name, strconv.Itoa(ts.TestCases), strconv.Itoa(len(ts.Endpoints)), strconv.Itoa(total(ts.Mocks)), humanSize(ts.Size), date(ts.Oldest), date(ts.Newest)})
+	}
+	table.SetFooter([]string{"Total", strconv.Itoa(summary.TestCases), strconv.Itoa(len(summary.Endpoints)), strconv.Itoa(total(summary.Mocks)), humanSize(summary.Size), date(summary.Oldest), date(summary.Newest)})
+	table.Render()
+
+	if len(summary.Mocks) > 0 {
+		fmt.Println()
+		kinds := make([]string, 0, len(summary.Mocks))
+		for k := range summary.Mocks {
+			kinds = append(kinds, string(k))
+		}
+		sort.Strings(kinds)
+		table = tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"Protocol", "Mocks"})
+		for _, k := range kinds {
+			table.Append([]string{k, strconv.Itoa(summary.Mocks[models.Kind(k)])})
+		}
+		table.Render()
+	}
+
+	if len(summary.Endpoints) > 0 {
+		fmt.Println()
+		endpoints := make([]string, 0, len(summary.Endpoints))
+		for e := range summary.Endpoints {
+			endpoints = append(endpoints, e)
+		}
+		sort.Strings(endpoints)
+		table = tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"Endpoint", "Tests"})
+		for _, e := range endpoints {
+			table.Append([]string{e, strconv.Itoa(summary.Endpoints[e])})
+		}
+		table.Render()
+	}
+}
+
+// span widens the [oldest, newest] range to include t.
+func span(oldest, newest, t time.Time) (time.Time, time.Time) {
+	if t.IsZero() {
+		return oldest, newest
+	}
+	if oldest.IsZero() || t.Before(oldest) {
+		oldest = t
+	}
+	if newest.IsZero() || t.After(newest) {
+		newest = t
+	}
+	return oldest, newest
+}
+
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.Walk(dir, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
+
+func total(counts map[models.Kind]int) int {
+	n := 0
+	for _, c := range counts {
+		n += c
+	}
+	return n
+}
+
+func humanSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+func date(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
@@ -8,6 +8,7 @@ import (
 type Service interface {
 	Update(ctx context.Context) error
 	CreateConfig(ctx context.Context, filePath string, config string) error
+	CreateConfigInteractive(ctx context.Context, dir string, filePath string) error
 	SendTelemetry(event string, output ...map[string]interface{})
 	Login(ctx context.Context) bool
 }
@@ -0,0 +1,405 @@
+package tools
+
+import (
+	"bufio"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.keploy.io/server/v2/config"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+// composeFiles are the docker compose file names looked up by the config wizard, in order of preference
+var composeFiles = []string{"docker-compose.yml", "docker-compose.yaml", "compose.yml", "compose.yaml"}
+
+// ProjectInfo is what the config wizard could infer about the application in a directory.
+type ProjectInfo struct {
+	Language       config.Language
+	ComposeFile    string
+	Services       []ComposeService
+	Networks       []string
+	ExposedPorts   []uint32 // ports exposed in the Dockerfile
+	ListeningPorts []uint32 // ports on which a process is listening on this machine
+}
+
+// ComposeService is a service declared in the docker compose file.
+type ComposeService struct {
+	Name          string
+	ContainerName string
+	Ports         []uint32 // container ports published by the service
+}
+
+type composeFile struct {
+	Services map[string]struct {
+		ContainerName string      `yaml:"container_name"`
+		Ports         []yaml.Node `yaml:"ports"`
+	} `yaml:"services"`
+	Networks map[string]interface{} `yaml:"networks"`
+}
+
+// DetectProject inspects the directory for the language, the docker compose file and the ports of the application.
+func DetectProject(logger *zap.Logger, dir string) ProjectInfo {
+	var info ProjectInfo
+
+	languageMarkers := []struct {
+		file     string
+		language config.Language
+	}{
+		{"go.mod", "go"},
+		{"pom.xml", "java"},
+		{"build.gradle", "java"},
+		{"build.gradle.kts", "java"},
+		{"package.json", "javascript"},
+		{"requirements.txt", "python"},
+		{"pyproject.toml", "python"},
+		{"Pipfile", "python"},
+	}
+	for _, m := range languageMarkers {
+		if utils.CheckFileExists(filepath.Join(dir, m.file)) {
+			info.Language = m.language
+			break
+		}
+	}
+
+	for _, name := range composeFiles {
+		path := filepath.Join(dir, name)
+		if !utils.CheckFileExists(path) {
+			continue
+		}
+		info.ComposeFile = name
+		services, networks, err := readComposeFile(path)
+		if err != nil {
+			logger.Warn("failed to parse the docker compose file", zap.String("file", path), zap.Error(err))
+			break
+		}
+		info.Services = services
+		info.Networks = networks
+		break
+	}
+
+	info.ExposedPorts = readExposedPorts(filepath.Join(dir, "Dockerfile"))
+	info.ListeningPorts = listeningPorts()
+	return info
+}
+
+func readComposeFile(path string) ([]ComposeService, []string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	var c composeFile
+	if err := yaml.Unmarshal(data, &c); err != nil {
+		return nil, nil, err
+	}
+
+	var services []ComposeService
+	for name, svc := range c.Services {
+		s := ComposeService{Name: name, ContainerName: svc.ContainerName}
+		for _, p := range svc.Ports {
+			if port := composePort(p); port != 0 {
+				s.Ports = append(s.Ports, port)
+			}
+		}
+		services = append(services, s)
+	}
+	sort.Slice(services, func(i, j int) bool {
+		return services[i].Name < services[j].Name
+	})
+
+	var networks []string
+	for name := range c.Networks {
+		networks = append(networks, name)
+	}
+	sort.Strings(networks)
+	return services, networks, nil
+}
+
+// composePort returns the container port of both the short ("8080:80/tcp") and the long ({target: 80}) port syntax.
+func composePort(n yaml.Node) uint32 {
+	var target string
+	switch n.Kind {
+	case yaml.ScalarNode:
+		parts := strings.Split(n.Value, ":")
+		target = strings.Split(parts[len(parts)-1], "/")[0]
+	case yaml.MappingNode:
+		var long struct {
+			Target string `yaml:"target"`
+		}
+		if err := n.Decode(&long); err != nil {
+			return 0
+		}
+		target = long.Target
+	}
+	// port ranges are not supported
+	port, err := strconv.ParseUint(strings.TrimSpace(target), 10, 16)
+	if err != nil {
+		return 0
+	}
+	return uint32(port)
+}
+
+func readExposedPorts(dockerfile string) []uint32 {
+	f, err := os.Open(dockerfile)
+	if err != nil {
+		return nil
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	var ports []uint32
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || !strings.EqualFold(fields[0], "EXPOSE") {
+			continue
+		}
+		for _, f := range fields[1:] {
+			port, err := strconv.ParseUint(strings.Split(f, "/")[0], 10, 16)
+			if err == nil {
+				ports = append(ports, uint32(port))
+			}
+		}
+	}
+	return ports
+}
+
+// listeningPorts returns the tcp ports in the LISTEN state, read from procfs.
+func listeningPorts() []uint32 {
+	seen := make(map[uint32]bool)
+	var ports []uint32
+	for _, file := range []string{"/proc/net/tcp", "/proc/net/tcp6"} {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		lines := strings.Split(string(data), "\n")
+		for _, line := range lines[1:] {
+			fields := strings.Fields(line)
+			// fields[1] is the local address (ip:port in hex) and fields[3] is the state, 0A is LISTEN
+			if len(fields) < 4 || fields[3] != "0A" {
+				continue
+			}
+			addr := strings.Split(fields[1], ":")
+			b, err := hex.DecodeString(addr[len(addr)-1])
+			if err != nil || len(b) != 2 {
+				continue
+			}
+			port := uint32(b[0])<<8 | uint32(b[1])
+			if !seen[port] {
+				seen[port] = true
+				ports = append(ports, port)
+			}
+		}
+	}
+	sort.Slice(ports, func(i, j int) bool { return ports[i] < ports[j] })
+	return ports
+}
+
+// CreateConfigInteractive inspects the directory, asks a few questions and writes a complete config file.
+func (t *Tools) CreateConfigInteractive(ctx context.Context, dir string, filePath string) error {
+	return t.configWizard(ctx, dir, filePath, os.Stdin, os.Stdout)
+}
+
+func (t *Tools) configWizard(ctx context.Context, dir string, filePath string, in io.Reader, out io.Writer) error {
+	info := DetectProject(t.logger, dir)
+
+	defaultCfg, err := config.Merge(config.InternalConfig, config.GetDefaultConfig())
+	if err != nil {
+		return fmt.Errorf("failed to create default config string: %w", err)
+	}
+	var cfg config.Config
+	if err := yaml.Unmarshal([]byte(defaultCfg), &cfg); err != nil {
+		return fmt.Errorf("failed to parse the default config: %w", err)
+	}
+
+	fmt.Fprintln(out, "Detected in the current directory:")
+	fmt.Fprintf(out, "  language: %s\n", orNone(string(info.Language)))
+	fmt.Fprintf(out, "  docker compose file: %s\n", orNone(info.ComposeFile))
+	for _, s := range info.Services {
+		fmt.Fprintf(out, "    service %s (container: %s, ports: %v)\n", s.Name, orNone(s.ContainerName), s.Ports)
+	}
+	if len(info.ExposedPorts) > 0 {
+		fmt.Fprintf(out, "  ports exposed in the Dockerfile: %v\n", info.ExposedPorts)
+	}
+	if len(info.ListeningPorts) > 0 {
+		fmt.Fprintf(out, "  ports currently listening: %v\n", info.ListeningPorts)
+	}
+	fmt.Fprintln(out)
+
+	reader := bufio.NewReader(in)
+	ask := func(question, def string) (string, error) {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		if def != "" {
+			fmt.Fprintf(out, "%s [%s]: ", question, def)
+		} else {
+			fmt.Fprintf(out, "%s: ", question)
+		}
+		answer, err := reader.ReadString('\n')
+		if err != nil && !(err == io.EOF && answer != "") {
+			return "", err
+		}
+		answer = strings.TrimSpace(answer)
+		if answer == "" {
+			return def, nil
+		}
+		return answer, nil
+	}
+	askUint := func(question string, def uint64) (uint64, error) {
+		for {
+			answer, err := ask(question, strconv.FormatUint(def, 10))
+			if err != nil {
+				return 0, err
+			}
+			v, err := strconv.ParseUint(answer, 10, 32)
+			if err == nil {
+				return v, nil
+			}
+			fmt.Fprintln(out, "please enter a number")
+		}
+	}
+
+	cfg.Command, err = ask("Command to start the application", suggestCommand(info))
+	if err != nil {
+		return err
+	}
+	isDocker := utils.IsDockerCmd(utils.FindDockerCmd(cfg.Command))
+
+	var appPorts []uint32
+	if isDocker {
+		defContainer := ""
+		if svc := appService(info.Services); svc != nil {
+			defContainer = svc.ContainerName
+			if defContainer == "" {
+				defContainer = svc.Name
+			}
+			appPorts = svc.Ports
+		}
+		cfg.ContainerName, err = ask("Name of the application's docker container", defContainer)
+		if err != nil {
+			return err
+		}
+		defNetwork := "keploy-network"
+		if len(info.Networks) > 0 {
+			defNetwork = info.Networks[0]
+		}
+		cfg.NetworkName, err = ask("Name of the application's docker network", defNetwork)
+		if err != nil {
+			return err
+		}
+		cfg.BuildDelay, err = askUint("Seconds to wait for the docker containers to build", 60)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(appPorts) == 0 {
+		appPorts = append(appPorts, info.ExposedPorts...)
+	}
+	if len(appPorts) == 0 {
+		appPorts = append(appPorts, info.ListeningPorts...)
+	}
+	defPort := uint64(0)
+	if len(appPorts) > 0 {
+		defPort = uint64(appPorts[0])
+	}
+	port, err := askUint("Port on which the application listens", defPort)
+	if err != nil {
+		return err
+	}
+	cfg.Port = uint32(port)
+
+	defDelay := uint64(5)
+	if isDocker {
+		defDelay = 10
+	}
+	cfg.Test.Delay, err = askUint("Seconds to wait for the application to start before running the tests", defDelay)
+	if err != nil {
+		return err
+	}
+
+	filters, err := ask("Paths to exclude from recording, comma separated (e.g. /health,/metrics)", "")
+	if err != nil {
+		return err
+	}
+	cfg.Record.Filters = nil
+	for _, path := range strings.Split(filters, ",") {
+		if path = strings.TrimSpace(path); path != "" {
+			cfg.Record.Filters = append(cfg.Record.Filters, config.Filter{BypassRule: config.BypassRule{Path: path}})
+		}
+	}
+
+	language, err := ask("Language of the application (go, java, python, javascript)", string(info.Language))
+	if err != nil {
+		return err
+	}
+	if language != "" {
+		if err := cfg.Test.Language.Set(language); err != nil {
+			t.logger.Warn("ignoring the language of the application", zap.Error(err))
+		}
+	}
+	// coverage is only computed for the native applications
+	cfg.Test.SkipCoverage = isDocker
+
+	data, err := yaml.Marshal(&cfg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal the config: %w", err)
+	}
+	return t.CreateConfig(ctx, filePath, string(data))
+}
+
+// appService guesses the service of the application, i.e. the first service which publishes a port and has a container name.
+func appService(services []ComposeService) *ComposeService {
+	var candidate *ComposeService
+	for i := range services {
+		s := &services[i]
+		if len(s.Ports) == 0 {
+			continue
+		}
+		if s.ContainerName != "" {
+			return s
+		}
+		if candidate == nil {
+			candidate = s
+		}
+	}
+	return candidate
+}
+
+func suggestCommand(info ProjectInfo) string {
+	if info.ComposeFile != "" {
+		if info.ComposeFile == composeFiles[0] {
+			return "docker compose up"
+		}
+		return "docker compose -f " + info.ComposeFile + " up"
+	}
+	switch info.Language {
+	case "go":
+		return "go run ."
+	case "javascript":
+		return "npm start"
+	case "python":
+		return "python3 app.py"
+	case "java":
+		return "mvn spring-boot:run"
+	}
+	return ""
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "none"
+	}
+	return s
+}
@@ -0,0 +1,141 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+)
+
+type key int
+
+const (
+	keyNone key = iota
+	keyUp
+	keyDown
+	keyLeft
+	keyRight
+	keyPageUp
+	keyPageDown
+	keyEnter
+	keyTab
+	keyQuit
+	keyRune
+)
+
+// screen draws the ui on the alternate screen buffer of the terminal and reads the keys in raw mode.
+type screen struct {
+	in    *os.File
+	out   *os.File
+	state *term.State
+}
+
+func newScreen() (*screen, error) {
+	if !term.IsTerminal(int(os.Stdin.Fd())) || !term.IsTerminal(int(os.Stdout.Fd())) {
+		return nil, errors.New("keploy ui must be run in an interactive terminal")
+	}
+	return &screen{in: os.Stdin, out: os.Stdout}, nil
+}
+
+// enter switches to the alternate screen buffer and the raw mode.
+func (s *screen) enter() error {
+	state, err := term.MakeRaw(int(s.in.Fd()))
+	if err != nil {
+		return fmt.Errorf("failed to set the terminal in raw mode: %w", err)
+	}
+	s.state = state
+	_, err = s.out.WriteString("\x1b[?1049h\x1b[?25l")
+	return err
+}
+
+// leave restores the terminal as it was before entering.
+func (s *screen) leave() {
+	_, _ = s.out.WriteString("\x1b[?25h\x1b[?1049l")
+	if s.state != nil {
+		_ = term.Restore(int(s.in.Fd()), s.state)
+		s.state = nil
+	}
+}
+
+func (s *screen) size() (int, int) {
+	w, h, err := term.GetSize(int(s.out.Fd()))
+	if err != nil || w <= 0 || h <= 0 {
+		return 80, 24
+	}
+	return w, h
+}
+
+// draw replaces the content of the screen with the given lines.
+func (s *screen) draw(lines []string) error {
+	var b strings.Builder
+	b.WriteString("\x1b[H\x1b[2J")
+	b.WriteString(strings.Join(lines, "\r\n"))
+	_, err := s.out.WriteString(b.String())
+	return err
+}
+
+// readKey blocks until a key is pressed.
+func (s *screen) readKey() (key, rune, error) {
+	buf := make([]byte, 16)
+	n, err := s.in.Read(buf)
+	if err != nil {
+		return keyNone, 0, err
+	}
+	b := buf[:n]
+	switch {
+	case len(b) >= 3 && b[0] == 0x1b && b[1] == '[':
+		switch b[2] {
+		case 'A':
+			return keyUp, 0, nil
+		case 'B':
+			return keyDown, 0, nil
+		case 'C':
+			return keyRight, 0, nil
+		case 'D':
+			return keyLeft, 0, nil
+		case '5':
+			return keyPageUp, 0, nil
+		case '6':
+			return keyPageDown, 0, nil
+		}
+		return keyNone, 0, nil
+	case b[0] == 0x1b:
+		return keyNone, 0, nil
+	case b[0] == '\r' || b[0] == '\n':
+		return keyEnter, 0, nil
+	case b[0] == '\t':
+		return keyTab, 0, nil
+	case b[0] == 3 || b[0] == 4: // ctrl+c, ctrl+d
+		return keyQuit, 0, nil
+	}
+	r, _ := utf8.DecodeRune(b)
+	return keyRune, r, nil
+}
+
+// ANSI styles used by the ui
+const (
+	styleReset   = "\x1b[0m"
+	styleReverse = "\x1b[7m"
+	styleBold    = "\x1b[1m"
+	styleDim     = "\x1b[2m"
+	styleRed     = "\x1b[31m"
+	styleGreen   = "\x1b[32m"
+	styleYellow  = "\x1b[33m"
+)
+
+// fit truncates or pads the plain text to exactly the given width.
+func fit(text string, width int) string {
+	text = strings.ReplaceAll(text, "\t", "    ")
+	n := utf8.RuneCountInString(text)
+	if n > width {
+		r := []rune(text)
+		if width <= 1 {
+			return string(r[:width])
+		}
+		return string(r[:width-1]) + "…"
+	}
+	return text + strings.Repeat(" ", width-n)
+}
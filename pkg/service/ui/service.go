@@ -0,0 +1,23 @@
+package ui
+
+import (
+	"context"
+
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+// Service is the terminal ui for browsing and running the test sets
+type Service interface {
+	// Start shows the ui until the user quits. The testArgs are forwarded to the keploy test command.
+	Start(ctx context.Context, testArgs []string) error
+}
+
+type TestDB interface {
+	GetAllTestSetIDs(ctx context.Context) ([]string, error)
+	GetTestCases(ctx context.Context, testSetID string) ([]*models.TestCase, error)
+}
+
+type ReportDB interface {
+	GetAllTestRunIDs(ctx context.Context) ([]string, error)
+	GetReport(ctx context.Context, testRunID string, testSetID string) (*models.TestReport, error)
+}
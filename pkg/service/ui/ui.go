@@ -0,0 +1,533 @@
+// Package ui provides a terminal ui for browsing and running the recorded test sets.
+package ui
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.keploy.io/server/v2/config"
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+	"go.uber.org/zap"
+)
+
+const helpLine = "↑/↓ move  ←/→ collapse/expand  space select  r run  f re-run failed  tab request/response  J/K scroll  R reload  q quit"
+
+type testCase struct {
+	tc     *models.TestCase
+	status models.TestStatus
+}
+
+type testSet struct {
+	id       string
+	cases    []*testCase
+	status   string // status of the test set in its latest run
+	run      string // latest test run of the test set
+	expanded bool
+}
+
+// row is a line of the list, either a test set or one of its test cases.
+type row struct {
+	set *testSet
+	tc  *testCase
+}
+
+func (r row) id() string {
+	if r.tc == nil {
+		return r.set.id
+	}
+	return r.set.id + "/" + r.tc.tc.Name
+}
+
+type tui struct {
+	logger   *zap.Logger
+	testDB   TestDB
+	reportDB ReportDB
+	config   *config.Config
+
+	sets          []*testSet
+	rows          []row
+	cursor        int
+	offset        int
+	selected      map[string]bool
+	showResponse  bool
+	previewOffset int
+	message       string
+}
+
+func New(logger *zap.Logger, testDB TestDB, reportDB ReportDB, config *config.Config) Service {
+	return &tui{
+		logger:   logger,
+		testDB:   testDB,
+		reportDB: reportDB,
+		config:   config,
+		selected: make(map[string]bool),
+	}
+}
+
+func (t *tui) Start(ctx context.Context, testArgs []string) error {
+	scr, err := newScreen()
+	if err != nil {
+		return err
+	}
+	if err := t.load(ctx); err != nil {
+		utils.LogError(t.logger, err, "failed to load the test sets")
+		return err
+	}
+	if len(t.sets) == 0 {
+		t.logger.Info("No test-sets found. Please record testcases using keploy record command")
+		return nil
+	}
+
+	if err := scr.enter(); err != nil {
+		return err
+	}
+	defer scr.leave()
+
+	for {
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err := scr.draw(t.render(scr.size())); err != nil {
+			return err
+		}
+		k, r, err := scr.readKey()
+		if err != nil {
+			return err
+		}
+		_, h := scr.size()
+		page := t.listHeight(h)
+
+		switch {
+		case k == keyQuit || (k == keyRune && (r == 'q' || r == 'Q')):
+			return nil
+		case k == keyUp || (k == keyRune && r == 'k'):
+			t.move(-1)
+		case k == keyDown || (k == keyRune && r == 'j'):
+			t.move(1)
+		case k == keyPageUp:
+			t.move(-page)
+		case k == keyPageDown:
+			t.move(page)
+		case k == keyRight || k == keyEnter:
+			t.expand(true)
+		case k == keyLeft:
+			t.expand(false)
+		case k == keyTab:
+			t.showResponse = !t.showResponse
+			t.previewOffset = 0
+		case k == keyRune && r == ' ':
+			t.toggleSelection()
+		case k == keyRune && r == 'J':
+			t.previewOffset++
+		case k == keyRune && r == 'K' && t.previewOffset > 0:
+			t.previewOffset--
+		case k == keyRune && r == 'R':
+			t.reload(ctx)
+		case k == keyRune && r == 'r':
+			t.runTests(ctx, scr, testArgs, t.selection())
+		case k == keyRune && r == 'f':
+			t.runTests(ctx, scr, testArgs, t.failed())
+		}
+	}
+}
+
+// load reads the test sets, their test cases and the status of their latest run.
+func (t *tui) load(ctx context.Context) error {
+	ids, err := t.testDB.GetAllTestSetIDs(ctx)
+	if err != nil {
+		return err
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return lessByIndex(ids[i], ids[j])
+	})
+
+	expanded := make(map[string]bool)
+	for _, s := range t.sets {
+		expanded[s.id] = s.expanded
+	}
+
+	runs, err := t.reportDB.GetAllTestRunIDs(ctx)
+	if err != nil {
+		t.logger.Debug("failed to get the test runs", zap.Error(err))
+	}
+	// latest test run first
+	sort.Slice(runs, func(i, j int) bool {
+		return lessByIndex(runs[j], runs[i])
+	})
+
+	t.sets = nil
+	for _, id := range ids {
+		tcs, err := t.testDB.GetTestCases(ctx, id)
+		if err != nil {
+			return fmt.Errorf("failed to get the test cases of %s: %w", id, err)
+		}
+		set := &testSet{id: id, expanded: expanded[id]}
+		for _, tc := range tcs {
+			set.cases = append(set.cases, &testCase{tc: tc})
+		}
+		t.setStatus(ctx, set, runs)
+		t.sets = append(t.sets, set)
+	}
+	t.buildRows()
+	return nil
+}
+
+func (t *tui) setStatus(ctx context.Context, set *testSet, runs []string) {
+	for _, run := range runs {
+		report, err := t.reportDB.GetReport(ctx, run, set.id)
+		if err != nil || report == nil {
+			continue
+		}
+		set.run = run
+		set.status = report.Status
+		results := make(map[string]models.TestStatus, len(report.Tests))
+		for _, res := range report.Tests {
+			results[res.TestCaseID] = res.Status
+		}
+		for _, tc := range set.cases {
+			tc.status = results[tc.tc.Name]
+		}
+		return
+	}
+}
+
+func (t *tui) reload(ctx context.Context) {
+	if err := t.load(ctx); err != nil {
+		t.message = "failed to reload: " + err.Error()
+		return
+	}
+	t.message = "reloaded"
+}
+
+func (t *tui) buildRows() {
+	var current string
+	if t.cursor < len(t.rows) {
+		current = t.rows[t.cursor].id()
+	}
+	t.rows = t.rows[:0]
+	for _, s := range t.sets {
+		t.rows = append(t.rows, row{set: s})
+		if s.expanded {
+			for _, tc := range s.cases {
+				t.rows = append(t.rows, row{set: s, tc: tc})
+			}
+		}
+	}
+	t.cursor = 0
+	for i, r := range t.rows {
+		if r.id() == current {
+			t.cursor = i
+			break
+		}
+	}
+}
+
+func (t *tui) move(delta int) {
+	t.cursor += delta
+	if t.cursor < 0 {
+		t.cursor = 0
+	}
+	if t.cursor >= len(t.rows) {
+		t.cursor = len(t.rows) - 1
+	}
+	t.previewOffset = 0
+}
+
+func (t *tui) expand(expand bool) {
+	r := t.rows[t.cursor]
+	if r.tc != nil && !expand {
+		// collapsing from a test case moves the cursor to its test set
+		for i, other := range t.rows {
+			if other.tc == nil && other.set == r.set {
+				t.cursor = i
+				break
+			}
+		}
+	}
+	if r.tc == nil && r.set.expanded == expand {
+		return
+	}
+	r.set.expanded = expand
+	t.buildRows()
+}
+
+func (t *tui) toggleSelection() {
+	id := t.rows[t.cursor].id()
+	if t.selected[id] {
+		delete(t.selected, id)
+	} else {
+		t.selected[id] = true
+	}
+	t.move(1)
+}
+
+// selection returns the selected rows, or the row under the cursor when nothing is selected.
+func (t *tui) selection() []row {
+	var rows []row
+	for _, s := range t.sets {
+		if t.selected[s.id] {
+			rows = append(rows, row{set: s})
+		}
+		for _, tc := range s.cases {
+			r := row{set: s, tc: tc}
+			if t.selected[r.id()] {
+				rows = append(rows, r)
+			}
+		}
+	}
+	if len(rows) == 0 && len(t.rows) > 0 {
+		rows = append(rows, t.rows[t.cursor])
+	}
+	return rows
+}
+
+// failed returns the test cases which failed in the latest run of their test set.
+func (t *tui) failed() []row {
+	var rows []row
+	for _, s := range t.sets {
+		for _, tc := range s.cases {
+			if tc.status == models.TestStatusFailed {
+				rows = append(rows, row{set: s, tc: tc})
+			}
+		}
+	}
+	return rows
+}
+
+// runTests runs the keploy test command for the given rows, outside of the ui.
+func (t *tui) runTests(ctx context.Context, scr *screen, testArgs []string, rows []row) {
+	if len(rows) == 0 {
+		t.message = "nothing to run"
+		return
+	}
+
+	wholeSets := make(map[string]bool)
+	var sets []string
+	for _, r := range rows {
+		if r.tc == nil && !wholeSets[r.set.id] {
+			wholeSets[r.set.id] = true
+			sets = append(sets, r.set.id)
+		}
+	}
+	cases := make(map[string][]string)
+	var caseSets []string
+	for _, r := range rows {
+		if r.tc == nil || wholeSets[r.set.id] {
+			continue
+		}
+		if _, ok := cases[r.set.id]; !ok {
+			caseSets = append(caseSets, r.set.id)
+		}
+		cases[r.set.id] = append(cases[r.set.id], r.tc.tc.Name)
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		t.message = "failed to find the keploy executable: " + err.Error()
+		return
+	}
+	args := []string{"test", "--path", filepath.Dir(t.config.Path)}
+	if len(sets) > 0 {
+		args = append(args, "--test-sets", strings.Join(sets, ","))
+	}
+	if len(caseSets) > 0 {
+		var tests []string
+		for _, s := range caseSets {
+			tests = append(tests, s+":"+strings.Join(cases[s], " "))
+		}
+		args = append(args, "--tests", strings.Join(tests, ","))
+	}
+	args = append(args, testArgs...)
+
+	scr.leave()
+	fmt.Printf("Running: keploy %s\n\n", strings.Join(args, " "))
+	cmd := exec.CommandContext(ctx, exe, args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	runErr := cmd.Run()
+	fmt.Print("\nPress enter to return to keploy ui")
+	_, _ = fmt.Scanln()
+
+	if err := scr.enter(); err != nil {
+		t.message = "failed to restore the ui: " + err.Error()
+		return
+	}
+	t.reload(ctx)
+	if runErr != nil {
+		t.message = "keploy test failed: " + runErr.Error()
+		return
+	}
+	t.selected = make(map[string]bool)
+	t.message = "run completed"
+}
+
+func (t *tui) listHeight(h int) int {
+	n := (h - 3) / 2
+	if n < 3 {
+		n = 3
+	}
+	return n
+}
+
+// render lays out the list of the test sets on the top half and the preview of the row under the cursor below.
+func (t *tui) render(w, h int) []string {
+	listHeight := t.listHeight(h)
+	if t.cursor < t.offset {
+		t.offset = t.cursor
+	}
+	if t.cursor >= t.offset+listHeight {
+		t.offset = t.cursor - listHeight + 1
+	}
+
+	lines := []string{styleBold + fit(fmt.Sprintf("keploy ui  %d test sets  %s", len(t.sets), filepath.Dir(t.config.Path)), w) + styleReset}
+	for i := t.offset; i < t.offset+listHeight; i++ {
+		if i >= len(t.rows) {
+			lines = append(lines, "")
+			continue
+		}
+		lines = append(lines, t.renderRow(t.rows[i], i == t.cursor, w))
+	}
+
+	preview := t.preview()
+	previewHeight := h - len(lines) - 2
+	title := " request "
+	if t.showResponse {
+		title = " response "
+	}
+	lines = append(lines, styleDim+fit("──"+title+strings.Repeat("─", w), w)+styleReset)
+	if t.previewOffset > len(preview) {
+		t.previewOffset = len(preview)
+	}
+	for i := t.previewOffset; i < t.previewOffset+previewHeight; i++ {
+		if i >= len(preview) {
+			lines = append(lines, "")
+			continue
+		}
+		lines = append(lines, fit(preview[i], w))
+	}
+
+	footer := helpLine
+	if t.message != "" {
+		footer = t.message + "  |  " + helpLine
+	}
+	lines = append(lines, styleReverse+fit(footer, w)+styleReset)
+	return lines
+}
+
+func (t *tui) renderRow(r row, current bool, w int) string {
+	mark := " "
+	if t.selected[r.id()] {
+		mark = "*"
+	}
+
+	var text, status, label string
+	if r.tc == nil {
+		arrow := "▸"
+		if r.set.expanded {
+			arrow = "▾"
+		}
+		text = fmt.Sprintf("%s %s %s (%d)", mark, arrow, r.set.id, len(r.set.cases))
+		status = r.set.status
+		label = status
+		if r.set.run != "" {
+			label += " in " + r.set.run
+		}
+	} else {
+		tc := r.tc.tc
+		text = fmt.Sprintf("%s     %s  %s %s", mark, tc.Name, tc.HTTPReq.Method, tc.HTTPReq.URL)
+		if tc.Kind != models.HTTP {
+			text = fmt.Sprintf("%s     %s  %s", mark, tc.Name, tc.Kind)
+		}
+		status = string(r.tc.status)
+		label = status
+	}
+
+	statusWidth := 24
+	if w < 2*statusWidth {
+		statusWidth = 0
+	}
+	line := fit(text, w-statusWidth) + fit(label, statusWidth)
+	if current {
+		return styleReverse + line + styleReset
+	}
+	switch models.TestStatus(strings.ToUpper(status)) {
+	case models.TestStatusFailed:
+		return styleRed + line + styleReset
+	case models.TestStatusPassed:
+		return styleGreen + line + styleReset
+	case models.TestStatusIgnored:
+		return styleYellow + line + styleReset
+	}
+	return line
+}
+
+// preview returns the request or the response of the test case under the cursor.
+func (t *tui) preview() []string {
+	if len(t.rows) == 0 {
+		return nil
+	}
+	r := t.rows[t.cursor]
+	if r.tc == nil {
+		lines := []string{fmt.Sprintf("%d test cases", len(r.set.cases))}
+		if r.set.run != "" {
+			lines = append(lines, fmt.Sprintf("latest run: %s (%s)", r.set.run, r.set.status))
+		}
+		return lines
+	}
+
+	tc := r.tc.tc
+	var lines []string
+	if tc.Kind != models.HTTP {
+		var v interface{} = tc.GrpcReq
+		if t.showResponse {
+			v = tc.GrpcResp
+		}
+		data, _ := json.MarshalIndent(v, "", "  ")
+		return strings.Split(string(data), "\n")
+	}
+
+	var header map[string]string
+	var body string
+	if t.showResponse {
+		lines = append(lines, fmt.Sprintf("%d %s", tc.HTTPResp.StatusCode, tc.HTTPResp.StatusMessage))
+		header, body = tc.HTTPResp.Header, tc.HTTPResp.Body
+	} else {
+		lines = append(lines, fmt.Sprintf("%s %s", tc.HTTPReq.Method, tc.HTTPReq.URL))
+		header, body = tc.HTTPReq.Header, tc.HTTPReq.Body
+	}
+	keys := make([]string, 0, len(header))
+	for k := range header {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		lines = append(lines, k+": "+header[k])
+	}
+	lines = append(lines, "")
+
+	var pretty bytes.Buffer
+	if json.Indent(&pretty, []byte(body), "", "  ") == nil {
+		body = pretty.String()
+	}
+	return append(lines, strings.Split(body, "\n")...)
+}
+
+// lessByIndex orders the ids like test-set-2 and test-set-10 by their numeric suffix.
+func lessByIndex(a, b string) bool {
+	ia, errA := strconv.Atoi(a[strings.LastIndex(a, "-")+1:])
+	ib, errB := strconv.Atoi(b[strings.LastIndex(b, "-")+1:])
+	if errA != nil || errB != nil {
+		return a < b
+	}
+	return ia < ib
+}
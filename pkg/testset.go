@@ -0,0 +1,49 @@
+package pkg
+
+import (
+	"context"
+	"net/url"
+	"time"
+
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+// MockReader reads the mocks of a test set, split between the filtered and unfiltered kinds.
+type MockReader interface {
+	GetFilteredMocks(ctx context.Context, testSetID string, afterTime time.Time, beforeTime time.Time) ([]*models.Mock, error)
+	GetUnFilteredMocks(ctx context.Context, testSetID string, afterTime time.Time, beforeTime time.Time) ([]*models.Mock, error)
+}
+
+// LoadAllMocks returns all the mocks of the test set, of both the filtered and unfiltered kinds.
+func LoadAllMocks(ctx context.Context, mockDB MockReader, testSetID string) ([]*models.Mock, error) {
+	// zero timestamps return all the mocks of the test set
+	mocks, err := mockDB.GetFilteredMocks(ctx, testSetID, time.Time{}, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	unfilteredMocks, err := mockDB.GetUnFilteredMocks(ctx, testSetID, time.Time{}, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	return append(mocks, unfilteredMocks...), nil
+}
+
+// Endpoint returns the method and the path of the http test case, or the method of the grpc one.
+func Endpoint(tc *models.TestCase) string {
+	if tc.Kind == models.GRPC_EXPORT {
+		return "gRPC " + tc.GrpcReq.Headers.PseudoHeaders[":path"]
+	}
+	return string(tc.HTTPReq.Method) + " " + URLPath(tc.HTTPReq.URL)
+}
+
+// URLPath returns the path of the url, "/" when it has none.
+func URLPath(rawURL string) string {
+	path := rawURL
+	if u, err := url.Parse(rawURL); err == nil {
+		path = u.Path
+	}
+	if path == "" {
+		path = "/"
+	}
+	return path
+}
@@ -0,0 +1,74 @@
+package pkg
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"unicode/utf8"
+
+	"go.keploy.io/server/v2/pkg/models"
+)
+
+// BodyDigest returns the digest a truncated body is compared by.
+func BodyDigest(body string) string {
+	sum := sha256.Sum256([]byte(body))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// WholeBody returns the size and the digest of the whole body, the truncated bodies have them recorded along with
+// their prefix.
+func WholeBody(body string, truncated *models.BodyTruncation) models.BodyTruncation {
+	if truncated != nil {
+		return *truncated
+	}
+	return models.BodyTruncation{Size: len(body), Digest: BodyDigest(body)}
+}
+
+// TruncateBody cuts the body to the limit in bytes, without splitting its last character, and returns the size and
+// the digest of the whole body along with it. The body is returned as it is when it's within the limit, or the
+// limit is 0.
+func TruncateBody(body string, limit int) (string, *models.BodyTruncation) {
+	if limit <= 0 || len(body) <= limit {
+		return body, nil
+	}
+	end := limit
+	for end > 0 && end > limit-utf8.UTFMax && !utf8.RuneStart(body[end]) {
+		end--
+	}
+	return body[:end], &models.BodyTruncation{Size: len(body), Digest: BodyDigest(body)}
+}
+
+// ReadTruncatedBody reads the body truncated to the limit like TruncateBody, without holding more than the limit
+// in memory: the rest of the body is only hashed and counted.
+func ReadTruncatedBody(r io.Reader, limit int) (string, *models.BodyTruncation, error) {
+	if limit <= 0 {
+		body, err := io.ReadAll(r)
+		return string(body), nil, err
+	}
+	hash := sha256.New()
+	// one byte over the limit is kept, to cut the body without splitting its last character
+	prefix := make([]byte, 0, min(limit+1, 32<<10))
+	size := 0
+	buf := make([]byte, 32<<10)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			hash.Write(buf[:n])
+			size += n
+			if keep := limit + 1 - len(prefix); keep > 0 {
+				prefix = append(prefix, buf[:min(n, keep)]...)
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", nil, err
+		}
+	}
+	if size <= limit {
+		return string(prefix), nil, nil
+	}
+	body, _ := TruncateBody(string(prefix), limit)
+	return body, &models.BodyTruncation{Size: size, Digest: "sha256:" + hex.EncodeToString(hash.Sum(nil))}, nil
+}
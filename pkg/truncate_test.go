@@ -0,0 +1,42 @@
+package pkg
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTruncateBody(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		limit     int
+		want      string
+		truncated bool
+	}{
+		{name: "no limit", body: "abcdef", limit: 0, want: "abcdef"},
+		{name: "within the limit", body: "abc", limit: 3, want: "abc"},
+		{name: "over the limit", body: "abcdef", limit: 4, want: "abcd", truncated: true},
+		{name: "last character kept whole", body: "aé€", limit: 4, want: "aé", truncated: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, truncated := TruncateBody(tt.body, tt.limit)
+			if got != tt.want {
+				t.Errorf("TruncateBody() = %q, want %q", got, tt.want)
+			}
+			if (truncated != nil) != tt.truncated {
+				t.Fatalf("TruncateBody() truncated = %v, want %v", truncated != nil, tt.truncated)
+			}
+			if truncated == nil {
+				return
+			}
+			if whole := WholeBody(tt.body, nil); *truncated != whole {
+				t.Errorf("TruncateBody() truncation = %+v, want the one of the whole body %+v", *truncated, whole)
+			}
+			body, readTruncated, err := ReadTruncatedBody(strings.NewReader(tt.body), tt.limit)
+			if err != nil || body != got || *readTruncated != *truncated {
+				t.Errorf("ReadTruncatedBody() = %q, %+v, %v, want %q, %+v", body, readTruncated, err, got, *truncated)
+			}
+		})
+	}
+}
@@ -5,7 +5,9 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/fs"
@@ -121,7 +123,26 @@ func SimulateHTTP(ctx context.Context, tc *models.TestCase, testSet string, logg
 	}
 
 	logger.Info("starting test for of", zap.Any("test case", models.HighlightString(tc.Name)), zap.Any("test set", models.HighlightString(testSet)))
-	req, err := http.NewRequestWithContext(ctx, string(tc.HTTPReq.Method), tc.HTTPReq.URL, bytes.NewBufferString(tc.HTTPReq.Body))
+	if tc.HTTPReq.Truncated != nil {
+		logger.Warn("the request body was truncated when it was recorded, the truncated body is sent", zap.Any("test case", tc.Name), zap.Int("size", tc.HTTPReq.Truncated.Size), zap.Int("sent", len(tc.HTTPReq.Body)))
+	}
+	// the body is stored decoded, it's sent encoded as the application received it
+	reqBody := []byte(tc.HTTPReq.Body)
+	if len(tc.HTTPReq.Form) > 0 && len(reqBody) == 0 {
+		var err error
+		reqBody, err = EncodeMultipartForm(tc.HTTPReq.Form, tc.HTTPReq.Header["Content-Type"])
+		if err != nil {
+			utils.LogError(logger, err, "failed to encode the multipart form of the testcase")
+			return nil, err
+		}
+	}
+	if encoded, err := EncodeBody(reqBody, tc.HTTPReq.Header["Content-Encoding"]); err == nil {
+		reqBody = encoded
+	} else if !errors.Is(err, ErrUnsupportedEncoding) {
+		utils.LogError(logger, err, "failed to encode the testcase request body")
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, string(tc.HTTPReq.Method), tc.HTTPReq.URL, bytes.NewReader(reqBody))
 	if err != nil {
 		utils.LogError(logger, err, "failed to create a http request from the yaml document")
 		return nil, err
@@ -146,6 +167,16 @@ func SimulateHTTP(ctx context.Context, tc *models.TestCase, testSet string, logg
 	_, hasAcceptEncoding := req.Header["Accept-Encoding"]
 	disableCompression := !hasAcceptEncoding
 
+	// the requests recorded with "Expect: 100-continue" wait for the interim response of the app before the body
+	// is sent, as they did, the ones the app answers without it don't send the body
+	expectContinueTimeout := time.Duration(0)
+	if strings.EqualFold(req.Header.Get("Expect"), "100-continue") {
+		expectContinueTimeout = time.Second
+	}
+
+	// the apps serving https themselves are called over tls, their certificates aren't verified
+	tlsConfig := &tls.Config{InsecureSkipVerify: true}
+
 	keepAlive, ok := req.Header["Connection"]
 	if ok && strings.EqualFold(keepAlive[0], "keep-alive") {
 		logger.Debug("simulating request with conn:keep-alive")
@@ -155,7 +186,9 @@ func SimulateHTTP(ctx context.Context, tc *models.TestCase, testSet string, logg
 				return http.ErrUseLastResponse
 			},
 			Transport: &http.Transport{
-				DisableCompression: disableCompression,
+				DisableCompression:    disableCompression,
+				ExpectContinueTimeout: expectContinueTimeout,
+				TLSClientConfig:       tlsConfig,
 			},
 		}
 	} else if ok && strings.EqualFold(keepAlive[0], "close") {
@@ -166,8 +199,10 @@ func SimulateHTTP(ctx context.Context, tc *models.TestCase, testSet string, logg
 				return http.ErrUseLastResponse
 			},
 			Transport: &http.Transport{
-				DisableKeepAlives:  true,
-				DisableCompression: disableCompression,
+				DisableKeepAlives:     true,
+				DisableCompression:    disableCompression,
+				ExpectContinueTimeout: expectContinueTimeout,
+				TLSClientConfig:       tlsConfig,
 			},
 		}
 	} else {
@@ -178,9 +213,11 @@ func SimulateHTTP(ctx context.Context, tc *models.TestCase, testSet string, logg
 				return http.ErrUseLastResponse
 			},
 			Transport: &http.Transport{
-				DisableKeepAlives:  false,
-				MaxIdleConns:       1,
-				DisableCompression: disableCompression,
+				DisableKeepAlives:     false,
+				MaxIdleConns:          1,
+				DisableCompression:    disableCompression,
+				ExpectContinueTimeout: expectContinueTimeout,
+				TLSClientConfig:       tlsConfig,
 			},
 		}
 	}
@@ -196,6 +233,10 @@ func SimulateHTTP(ctx context.Context, tc *models.TestCase, testSet string, logg
 		utils.LogError(logger, errReadRespBody, "failed reading response body")
 		return nil, err
 	}
+	// the body is compared decoded, as the recorded one is stored
+	if decoded, err := DecodeBody(respBody, httpResp.Header.Get("Content-Encoding")); err == nil {
+		respBody = decoded
+	}
 
 	resp = &models.HTTPResp{
 		StatusCode: httpResp.StatusCode,
@@ -207,8 +248,12 @@ func SimulateHTTP(ctx context.Context, tc *models.TestCase, testSet string, logg
 }
 
 func ParseHTTPRequest(requestBytes []byte) (*http.Request, error) {
-	// Parse the request using the http.ReadRequest function
-	request, err := http.ReadRequest(bufio.NewReader(bytes.NewReader(requestBytes)))
+	return ReadHTTPRequest(bufio.NewReader(bytes.NewReader(requestBytes)))
+}
+
+// ReadHTTPRequest reads the request from the reader, its body is read from the reader as it's consumed.
+func ReadHTTPRequest(r *bufio.Reader) (*http.Request, error) {
+	request, err := http.ReadRequest(r)
 	if err != nil {
 		return nil, err
 	}
@@ -217,14 +262,64 @@ func ParseHTTPRequest(requestBytes []byte) (*http.Request, error) {
 	return request, nil
 }
 
+// ParseHTTPResponse parses the final response to the request, the interim responses sent before it (like the
+// "100 Continue" of an "Expect: 100-continue" request) are skipped.
 func ParseHTTPResponse(data []byte, request *http.Request) (*http.Response, error) {
-	buffer := bytes.NewBuffer(data)
-	reader := bufio.NewReader(buffer)
-	response, err := http.ReadResponse(reader, request)
-	if err != nil {
-		return nil, err
+	return ReadHTTPResponse(bufio.NewReader(bytes.NewReader(data)), request)
+}
+
+// ReadHTTPResponse reads the final response to the request from the reader, the interim responses are skipped.
+// The body of the response is read from the reader as it's consumed.
+func ReadHTTPResponse(r *bufio.Reader, request *http.Request) (*http.Response, error) {
+	for {
+		response, err := http.ReadResponse(r, request)
+		if err != nil {
+			return nil, err
+		}
+		if response.StatusCode < 100 || response.StatusCode >= 200 || response.StatusCode == http.StatusSwitchingProtocols {
+			return response, nil
+		}
 	}
-	return response, nil
+}
+
+// IsInterimHTTPResponse reports whether the data is a complete interim (1xx) http response, the "101 Switching
+// Protocols" response isn't one since the conn is no longer http after it.
+func IsInterimHTTPResponse(data []byte) bool {
+	end := bytes.Index(data, []byte("\r\n\r\n"))
+	if end < 0 {
+		return false
+	}
+	line, _, _ := bytes.Cut(data[:end], []byte("\r\n"))
+	fields := strings.Fields(string(line))
+	if len(fields) < 2 || !strings.HasPrefix(fields[0], "HTTP/1.") {
+		return false
+	}
+	code, err := strconv.Atoi(fields[1])
+	return err == nil && code >= 100 && code < 200 && code != http.StatusSwitchingProtocols
+}
+
+// StripInterimHTTPResponses returns the data without the interim http responses at its start.
+func StripInterimHTTPResponses(data []byte) []byte {
+	for IsInterimHTTPResponse(data) {
+		data = data[bytes.Index(data, []byte("\r\n\r\n"))+4:]
+	}
+	return data
+}
+
+// ExpectsContinue reports whether the request headers ask for a "100 Continue" response before the body is
+// sent, and the body wasn't sent along with them anyway.
+func ExpectsContinue(data []byte) bool {
+	end := bytes.Index(data, []byte("\r\n\r\n"))
+	if end < 0 || len(data) > end+4 {
+		return false
+	}
+	for _, line := range strings.Split(string(data[:end]), "\r\n")[1:] {
+		name, value, ok := strings.Cut(line, ":")
+		if ok && strings.EqualFold(strings.TrimSpace(name), "Expect") {
+			return strings.EqualFold(strings.TrimSpace(value), "100-continue")
+		}
+	}
+	return false
 }
 
 func MakeCurlCommand(method string, url string, header map[string]string, body string) string {
@@ -0,0 +1,365 @@
+package pkg
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+
+	"go.keploy.io/server/v2/pkg/models"
+	"go.keploy.io/server/v2/utils"
+)
+
+// The websockets exchange frames once the server accepted the handshake, the frames of the clients are masked.
+// The text messages are recorded as text, the other messages as base64, the pings and the pongs aren't recorded.
+// See: https://www.rfc-editor.org/rfc/rfc6455#section-5
+
+// the opcodes of the websocket frames
+const (
+	WsOpContinuation byte = 0x0
+	WsOpText         byte = 0x1
+	WsOpBinary       byte = 0x2
+	WsOpClose        byte = 0x8
+	WsOpPing         byte = 0x9
+	WsOpPong         byte = 0xa
+)
+
+// the peers which send the websocket frames
+const (
+	WsFromClient = "client"
+	WsFromServer = "server"
+)
+
+// maxWebSocketPayloadSize bounds the payload of a frame, a larger length is taken as a corrupted stream.
+const maxWebSocketPayloadSize = 64 << 20
+
+var wsOpcodeNames = map[byte]string{
+	WsOpContinuation: "continuation",
+	WsOpText:         "text",
+	WsOpBinary:       "binary",
+	WsOpClose:        "close",
+	WsOpPing:         "ping",
+	WsOpPong:         "pong",
+}
+
+// WebSocketOpcodeName returns the name the opcode is recorded with.
+func WebSocketOpcodeName(op byte) string {
+	if name, ok := wsOpcodeNames[op]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%x", op)
+}
+
+// WebSocketOpcode returns the opcode of a recorded name.
+func WebSocketOpcode(name string) (byte, bool) {
+	for op, n := range wsOpcodeNames {
+		if n == name {
+			return op, true
+		}
+	}
+	return 0, false
+}
+
+// WebSocketRawFrame is a frame as it's sent, with its payload unmasked.
+type WebSocketRawFrame struct {
+	Fin     bool
+	Opcode  byte
+	Payload []byte
+}
+
+// ParseWebSocketFrame reads the frame at the start of buf, it returns the number of bytes of the frame or 0 if
+// the frame isn't complete yet.
+func ParseWebSocketFrame(buf []byte) (*WebSocketRawFrame, int, error) {
+	if len(buf) < 2 {
+		return nil, 0, nil
+	}
+	f := &WebSocketRawFrame{
+		Fin:    buf[0]&0x80 != 0,
+		Opcode: buf[0] & 0x0f,
+	}
+	masked := buf[1]&0x80 != 0
+
+	n := 2
+	length := uint64(buf[1] & 0x7f)
+	switch length {
+	case 126:
+		if len(buf) < n+2 {
+			return nil, 0, nil
+		}
+		length = uint64(binary.BigEndian.Uint16(buf[n:]))
+		n += 2
+	case 127:
+		if len(buf) < n+8 {
+			return nil, 0, nil
+		}
+		length = binary.BigEndian.Uint64(buf[n:])
+		n += 8
+	}
+	if length > maxWebSocketPayloadSize {
+		return nil, 0, fmt.Errorf("the websocket frame payload of %d bytes exceeds the limit of %d bytes", length, maxWebSocketPayloadSize)
+	}
+
+	var key []byte
+	if masked {
+		if len(buf) < n+4 {
+			return nil, 0, nil
+		}
+		key = buf[n : n+4]
+		n += 4
+	}
+	if uint64(len(buf)-n) < length {
+		return nil, 0, nil
+	}
+
+	f.Payload = make([]byte, length)
+	copy(f.Payload, buf[n:])
+	if masked {
+		for i := range f.Payload {
+			f.Payload[i] ^= key[i%4]
+		}
+	}
+	return f, n + int(length), nil
+}
+
+// EncodeWebSocketFrame encodes a frame, the frames of the clients are masked with a random key.
+func EncodeWebSocketFrame(fin bool, op byte, payload []byte, masked bool) ([]byte, error) {
+	b0 := op
+	if fin {
+		b0 |= 0x80
+	}
+	var b1 byte
+	if masked {
+		b1 = 0x80
+	}
+	out := []byte{b0}
+	switch n := len(payload); {
+	case n < 126:
+		out = append(out, b1|byte(n))
+	case n <= 0xffff:
+		out = append(out, b1|126)
+		out = binary.BigEndian.AppendUint16(out, uint16(n))
+	default:
+		out = append(out, b1|127)
+		out = binary.BigEndian.AppendUint64(out, uint64(n))
+	}
+	if !masked {
+		return append(out, payload...), nil
+	}
+
+	key := make([]byte, 4)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate the websocket masking key: %w", err)
+	}
+	out = append(out, key...)
+	for i, b := range payload {
+		out = append(out, b^key[i%4])
+	}
+	return out, nil
+}
+
+// WebSocketFrameModel converts the frame to its recorded form. The payloads of the text messages are stored as
+// text, the others as base64.
+func WebSocketFrameModel(f *WebSocketRawFrame, from string, text bool, offset time.Duration) models.WebSocketFrame {
+	m := models.WebSocketFrame{
+		From:   from,
+		Opcode: WebSocketOpcodeName(f.Opcode),
+		Fin:    f.Fin,
+		Offset: offset.Milliseconds(),
+	}
+	if text && utf8.Valid(f.Payload) {
+		m.Data = string(f.Payload)
+	} else if len(f.Payload) > 0 {
+		m.Binary = base64.StdEncoding.EncodeToString(f.Payload)
+	}
+	return m
+}
+
+// WebSocketFramePayload returns the payload of a recorded frame.
+func WebSocketFramePayload(m models.WebSocketFrame) ([]byte, error) {
+	if m.Binary != "" {
+		return base64.StdEncoding.DecodeString(m.Binary)
+	}
+	return []byte(m.Data), nil
+}
+
+// WebSocketText tracks whether the frames of a peer belong to a text message, the continuation frames carry
+// the opcode of the first frame of their message.
+type WebSocketText struct {
+	text bool
+}
+
+// Of reports whether the payload of the frame is text.
+func (t *WebSocketText) Of(f *WebSocketRawFrame) bool {
+	switch f.Opcode {
+	case WsOpText:
+		t.text = true
+	case WsOpBinary:
+		t.text = false
+	case WsOpClose:
+		return true
+	}
+	return t.text
+}
+
+// SimulateWebSocket opens the websocket of the test case on the application and replays the recorded session: the
+// frames of the client are sent in order, and each recorded frame of the server is waited for before the next
+// frame of the client is sent. It returns the handshake response and the frames exchanged, the session stops
+// at the first frame the application doesn't send in time.
+func SimulateWebSocket(ctx context.Context, tc *models.TestCase, testSet string, logger *zap.Logger, apiTimeout uint64) (*models.HTTPResp, []models.WebSocketFrame, error) {
+	logger.Info("starting test for of", zap.Any("test case", models.HighlightString(tc.Name)), zap.Any("test set", models.HighlightString(testSet)))
+	timeout := time.Second * time.Duration(apiTimeout)
+
+	u, err := url.Parse(tc.HTTPReq.URL)
+	if err != nil {
+		utils.LogError(logger, err, "failed to parse the url of the websocket testcase")
+		return nil, nil, err
+	}
+	addr, port := u.Host, "80"
+	if u.Scheme == "https" {
+		port = "443"
+	}
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), port)
+	}
+	dialer := net.Dialer{Timeout: timeout}
+	var conn net.Conn
+	if u.Scheme == "https" {
+		// the sessions recorded over https are opened over tls, the certificate of the app isn't verified
+		tlsDialer := tls.Dialer{NetDialer: &dialer, Config: &tls.Config{InsecureSkipVerify: true, ServerName: u.Hostname()}}
+		conn, err = tlsDialer.DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		utils.LogError(logger, err, "failed to connect to the app for the websocket testcase")
+		return nil, nil, err
+	}
+	defer func() {
+		if err := conn.Close(); err != nil {
+			logger.Debug("failed to close the websocket conn", zap.Error(err))
+		}
+	}()
+	stop := context.AfterFunc(ctx, func() {
+		_ = conn.Close()
+	})
+	defer stop()
+
+	req, err := http.NewRequestWithContext(ctx, string(tc.HTTPReq.Method), tc.HTTPReq.URL, nil)
+	if err != nil {
+		utils.LogError(logger, err, "failed to create the websocket handshake from the yaml document")
+		return nil, nil, err
+	}
+	req.Header = ToHTTPHeader(tc.HTTPReq.Header)
+	req.Header.Set("keploy-test-id", tc.Name)
+	req.Header.Set("keploy-test-set-id", testSet)
+	logger.Debug(fmt.Sprintf("Sending websocket handshake to user app:%v", req))
+
+	err = conn.SetDeadline(time.Now().Add(timeout))
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := req.Write(conn); err != nil {
+		utils.LogError(logger, err, "failed to send the websocket handshake to the app")
+		return nil, nil, err
+	}
+	r := bufio.NewReader(conn)
+	httpResp, err := http.ReadResponse(r, req)
+	if err != nil {
+		utils.LogError(logger, err, "failed to read the websocket handshake response")
+		return nil, nil, err
+	}
+	resp := &models.HTTPResp{
+		StatusCode:    httpResp.StatusCode,
+		StatusMessage: http.StatusText(httpResp.StatusCode),
+		ProtoMajor:    httpResp.ProtoMajor,
+		ProtoMinor:    httpResp.ProtoMinor,
+		Header:        ToYamlHTTPHeader(httpResp.Header),
+	}
+	if httpResp.StatusCode != http.StatusSwitchingProtocols {
+		return resp, nil, nil
+	}
+
+	start := time.Now()
+	var (
+		frames []models.WebSocketFrame
+		buf    []byte
+		text   WebSocketText
+	)
+	// next reads the next frame of the server, its pings are answered
+	next := func() (*WebSocketRawFrame, error) {
+		chunk := make([]byte, 32<<10)
+		for {
+			f, n, err := ParseWebSocketFrame(buf)
+			if err != nil {
+				return nil, err
+			}
+			if n == 0 {
+				read, err := r.Read(chunk)
+				if err != nil {
+					return nil, err
+				}
+				buf = append(buf, chunk[:read]...)
+				continue
+			}
+			buf = buf[n:]
+			switch f.Opcode {
+			case WsOpPing:
+				pong, err := EncodeWebSocketFrame(true, WsOpPong, f.Payload, true)
+				if err == nil {
+					_, err = conn.Write(pong)
+				}
+				if err != nil {
+					return nil, err
+				}
+			case WsOpPong:
+			default:
+				return f, nil
+			}
+		}
+	}
+
+	for i, m := range tc.WebSocketFrames {
+		if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+			return resp, frames, err
+		}
+		if m.From == WsFromClient {
+			op, ok := WebSocketOpcode(m.Opcode)
+			if !ok {
+				return resp, frames, fmt.Errorf("invalid opcode %q of the recorded websocket frame %d", m.Opcode, i)
+			}
+			payload, err := WebSocketFramePayload(m)
+			if err != nil {
+				return resp, frames, fmt.Errorf("failed to decode the payload of the recorded websocket frame %d: %v", i, err)
+			}
+			data, err := EncodeWebSocketFrame(m.Fin, op, payload, true)
+			if err != nil {
+				return resp, frames, err
+			}
+			if _, err := conn.Write(data); err != nil {
+				logger.Debug("failed to send the websocket frame of the client to the app", zap.Int("frame", i), zap.Error(err))
+				break
+			}
+			m.Offset = time.Since(start).Milliseconds()
+			frames = append(frames, m)
+			continue
+		}
+
+		f, err := next()
+		if err != nil {
+			logger.Debug("the app didn't send the recorded websocket frame", zap.Int("frame", i), zap.Error(err))
+			break
+		}
+		frames = append(frames, WebSocketFrameModel(f, WsFromServer, text.Of(f), time.Since(start)))
+	}
+	return resp, frames, nil
+}
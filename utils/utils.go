@@ -19,6 +19,7 @@ import (
 	"runtime/debug"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -240,6 +241,12 @@ func CheckFileExists(path string) bool {
 var Version string
 var VersionIdenitfier string
 
+// SentryDSN is the dsn of sentry the panics are reported to, it's injected during the build like the version.
+var SentryDSN string
+
+// sentryEnabled is set once sentry is initialised, the panics aren't reported to sentry otherwise.
+var sentryEnabled atomic.Bool
+
 func attachLogFileToSentry(logger *zap.Logger, logFilePath string) error {
 	file, err := os.Open(logFilePath)
 	if err != nil {
@@ -265,11 +272,13 @@ func attachLogFileToSentry(logger *zap.Logger, logFilePath string) error {
 
 // HandleRecovery handles the common logic for recovering from a panic.
 func HandleRecovery(logger *zap.Logger, r interface{}, errMsg string) {
-	err := attachLogFileToSentry(logger, "./keploy-logs.txt")
-	if err != nil {
-		LogError(logger, err, "failed to attach log file to sentry")
+	if sentryEnabled.Load() {
+		err := attachLogFileToSentry(logger, "./keploy-logs.txt")
+		if err != nil {
+			LogError(logger, err, "failed to attach log file to sentry")
+		}
+		sentry.CaptureException(errors.New(fmt.Sprint(r)))
 	}
-	sentry.CaptureException(errors.New(fmt.Sprint(r)))
 	// Get the stack trace
 	stackTrace := debug.Stack()
 	LogError(logger, nil, errMsg, zap.String("stack trace", string(stackTrace)))
@@ -282,14 +291,14 @@ func Recover(logger *zap.Logger) {
 		fmt.Println(Emoji + "Failed to recover from panic. Logger is nil.")
 		return
 	}
-	sentry.Flush(2 * time.Second)
+	SentryFlush(2 * time.Second)
 	if r := recover(); r != nil {
 		HandleRecovery(logger, r, "Recovered from panic")
 		err := Stop(logger, fmt.Sprintf("Recovered from: %s", r))
 		if err != nil {
 			LogError(logger, err, "failed to stop the global context")
 		}
-		sentry.Flush(2 * time.Second)
+		SentryFlush(2 * time.Second)
 	}
 }
 
@@ -475,13 +484,27 @@ func Keys(m map[string][]string) []string {
 	return keys
 }
 
+// SentryInit initialises sentry with the dsn, the panics are reported to it from then on. Nothing is reported when
+// the dsn is empty.
 func SentryInit(logger *zap.Logger, dsn string) {
+	if dsn == "" {
+		return
+	}
 	err := sentry.Init(sentry.ClientOptions{
 		Dsn:              dsn,
 		TracesSampleRate: 1.0,
 	})
 	if err != nil {
 		logger.Debug("Could not initialise sentry.", zap.Error(err))
+		return
+	}
+	sentryEnabled.Store(true)
+}
+
+// SentryFlush waits up to the timeout for the events to be sent to sentry, if it was initialised.
+func SentryFlush(timeout time.Duration) {
+	if sentryEnabled.Load() {
+		sentry.Flush(timeout)
 	}
 }
 